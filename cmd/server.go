@@ -19,13 +19,16 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/docker/docker/pkg/fileutils"
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/pkg/errors"
 	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/core/terraform"
 	"github.com/runatlantis/atlantis/server/events/vcs/bitbucketcloud"
 	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/i18n"
 	"github.com/runatlantis/atlantis/server/logging"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -37,34 +40,60 @@ import (
 // 3. Add your flag's description etc. to the stringFlags, intFlags, or boolFlags slices.
 const (
 	// Flag names.
+	ADHostnameFlag             = "azuredevops-hostname"
 	ADWebhookPasswordFlag      = "azuredevops-webhook-password" // nolint: gosec
 	ADWebhookUserFlag          = "azuredevops-webhook-user"
 	ADTokenFlag                = "azuredevops-token" // nolint: gosec
 	ADUserFlag                 = "azuredevops-user"
 	AllowForkPRsFlag           = "allow-fork-prs"
 	AllowRepoConfigFlag        = "allow-repo-config"
+	APISecretFlag              = "api-secret" // nolint: gosec
+	ApplyConfirmationWindow    = "apply-confirmation-window"
 	AtlantisURLFlag            = "atlantis-url"
+	AuditWebhookURLFlag        = "audit-webhook-url"
 	AutomergeFlag              = "automerge"
 	AutoplanFileListFlag       = "autoplan-file-list"
+	AutoplanModulesFlag        = "autoplan-modules"
 	BitbucketBaseURLFlag       = "bitbucket-base-url"
 	BitbucketTokenFlag         = "bitbucket-token"
 	BitbucketUserFlag          = "bitbucket-user"
 	BitbucketWebhookSecretFlag = "bitbucket-webhook-secret"
 	ConfigFlag                 = "config"
+	CheckoutDepthFlag          = "checkout-depth"
 	CheckoutStrategyFlag       = "checkout-strategy"
+	CommandQueueWorkersFlag    = "command-queue-workers"
+	CommandTimeoutFlag         = "command-timeout"
 	DataDirFlag                = "data-dir"
+	DataDirMaxSizeMBFlag       = "data-dir-max-size-mb"
+	DefaultTFDistributionFlag  = "default-tf-distribution"
 	DefaultTFVersionFlag       = "default-tf-version"
+	DefaultTofuVersionFlag     = "default-tofu-version"
+	DefaultTGVersionFlag       = "default-tg-version"
 	DisableApplyAllFlag        = "disable-apply-all"
 	DisableApplyFlag           = "disable-apply"
 	DisableAutoplanFlag        = "disable-autoplan"
 	DisableMarkdownFoldingFlag = "disable-markdown-folding"
 	DisableRepoLockingFlag     = "disable-repo-locking"
+	EnableAuditLogFlag         = "enable-audit-log"
+	EnableCloneCacheFlag       = "enable-clone-cache"
+	EnableCommandQueueFlag     = "enable-command-queue"
+	EnableGHChecksFlag         = "enable-gh-checks"
+	EnableGHDeploymentsFlag    = "enable-gh-deployments"
+	EnableHAModeFlag           = "enable-ha-mode"
+	EnableLockQueueFlag        = "enable-lock-queue"
 	EnablePolicyChecksFlag     = "enable-policy-checks"
+	EnableProgressCommentsFlag = "enable-progress-comments"
+	EnableProjectStatusesFlag  = "enable-project-statuses"
 	EnableRegExpCmdFlag        = "enable-regexp-cmd"
+	EnableStateCmdFlag         = "enable-state-cmd"
+	EnableStructuredPlanFlag   = "enable-structured-plan-output"
 	GHHostnameFlag             = "gh-hostname"
+	GHMergeableIgnoreFlag      = "gh-mergeable-ignore-contexts"
+	GHRequireStatusContexts    = "gh-require-status-contexts"
 	GHTokenFlag                = "gh-token"
 	GHUserFlag                 = "gh-user"
 	GHAppIDFlag                = "gh-app-id"
+	GHAppKeyFlag               = "gh-app-key"
 	GHAppKeyFileFlag           = "gh-app-key-file"
 	GHAppSlugFlag              = "gh-app-slug"
 	GHOrganizationFlag         = "gh-org"
@@ -74,10 +103,22 @@ const (
 	GitlabUserFlag             = "gitlab-user"
 	GitlabWebhookSecretFlag    = "gitlab-webhook-secret" // nolint: gosec
 	HidePrevPlanComments       = "hide-prev-plan-comments"
+	LocaleFlag                 = "locale"
+	LockingDBType              = "locking-db-type"
+	LockTTLFlag                = "lock-ttl"
 	LogLevelFlag               = "log-level"
 	ParallelPoolSize           = "parallel-pool-size"
 	AllowDraftPRs              = "allow-draft-prs"
+	PlanStorageFlag            = "plan-storage"
+	PlanStorageBucketFlag      = "plan-storage-bucket"
+	PlanStoragePrefixFlag      = "plan-storage-prefix"
 	PortFlag                   = "port"
+	RedisDB                    = "redis-db"
+	RedisHost                  = "redis-host"
+	RedisPassword              = "redis-password"
+	RedisPort                  = "redis-port"
+	RedisTLSEnabled            = "redis-tls-enabled"
+	RedisInsecureSkipVerify    = "redis-insecure-skip-verify"
 	RepoConfigFlag             = "repo-config"
 	RepoConfigJSONFlag         = "repo-config-json"
 	// RepoWhitelistFlag is deprecated for RepoAllowlistFlag.
@@ -86,6 +127,7 @@ const (
 	RequireApprovalFlag        = "require-approval"
 	RequireMergeableFlag       = "require-mergeable"
 	SilenceNoProjectsFlag      = "silence-no-projects"
+	SilenceNoChangePlansFlag   = "silence-no-change-plans"
 	SilenceForkPRErrorsFlag    = "silence-fork-pr-errors"
 	SilenceVCSStatusNoPlans    = "silence-vcs-status-no-plans"
 	SilenceAllowlistErrorsFlag = "silence-allowlist-errors"
@@ -96,29 +138,72 @@ const (
 	SSLCertFileFlag            = "ssl-cert-file"
 	SSLKeyFileFlag             = "ssl-key-file"
 	TFDownloadURLFlag          = "tf-download-url"
+	TFMaxRetriesFlag           = "tf-max-retries"
+	TFNetrcCredentialsFlag     = "tf-netrc-credentials"
+	TFRegistryCredentialsFlag  = "tf-registry-credentials"
+	TFRetryBackoffFlag         = "tf-retry-backoff"
+	TofuDownloadURLFlag        = "tofu-download-url"
+	TracingOTLPEndpointFlag    = "tracing-otlp-endpoint"
+	TracingOTLPHeadersFlag     = "tracing-otlp-headers"
+	TracingOTLPInsecureFlag    = "tracing-otlp-insecure"
 	VCSStatusName              = "vcs-status-name"
+	VCSAPIMaxRetriesFlag       = "vcs-api-max-retries"
+	VCSHostsConfigFlag         = "vcs-hosts-config"
 	TFEHostnameFlag            = "tfe-hostname"
 	TFETokenFlag               = "tfe-token"
+	UploadLargeCommentsFlag    = "upload-large-comments"
+	WarmProvidersFlag          = "warm-providers"
+	WarmTFVersionsFlag         = "warm-tf-versions"
+	WebBasicAuthFlag           = "web-basic-auth"
+	WebOIDCClientIDFlag        = "web-oidc-client-id"
+	WebOIDCClientSecretFlag    = "web-oidc-client-secret" // nolint: gosec
+	WebOIDCIssuerURLFlag       = "web-oidc-issuer-url"
+	WebOperatorsFlag           = "web-operators"
+	WebPasswordFlag            = "web-password"       // nolint: gosec
+	WebSessionSecretFlag       = "web-session-secret" // nolint: gosec
+	WebUsernameFlag            = "web-username"
+	WebViewersFlag             = "web-viewers"
+	WorkspaceGCIntervalFlag    = "workspace-gc-interval"
+	WorkspaceGCMaxAgeFlag      = "workspace-gc-max-age"
+	WorkingDirLockTimeoutFlag  = "working-dir-lock-timeout"
 	WriteGitCredsFlag          = "write-git-creds"
 
 	// NOTE: Must manually set these as defaults in the setDefaults function.
-	DefaultADBasicUser      = ""
-	DefaultADBasicPassword  = ""
-	DefaultAutoplanFileList = "**/*.tf,**/*.tfvars,**/*.tfvars.json,**/terragrunt.hcl"
-	DefaultCheckoutStrategy = "branch"
-	DefaultBitbucketBaseURL = bitbucketcloud.BaseURL
-	DefaultDataDir          = "~/.atlantis"
-	DefaultGHHostname       = "github.com"
-	DefaultGitlabHostname   = "gitlab.com"
-	DefaultLogLevel         = "info"
-	DefaultParallelPoolSize = 15
-	DefaultPort             = 4141
-	DefaultTFDownloadURL    = "https://releases.hashicorp.com"
-	DefaultTFEHostname      = "app.terraform.io"
-	DefaultVCSStatusName    = "atlantis"
+	DefaultADBasicUser             = ""
+	DefaultADBasicPassword         = ""
+	DefaultADHostname              = "dev.azure.com"
+	DefaultAutoplanFileList        = "**/*.tf,**/*.tfvars,**/*.tfvars.json,**/terragrunt.hcl"
+	DefaultCheckoutStrategy        = "branch"
+	DefaultCommandQueueWorkers     = 10
+	DefaultBitbucketBaseURL        = bitbucketcloud.BaseURL
+	DefaultDataDir                 = "~/.atlantis"
+	DefaultGHHostname              = "github.com"
+	DefaultGitlabHostname          = "gitlab.com"
+	DefaultLocale                  = i18n.English
+	DefaultLockingDBType           = "boltdb"
+	DefaultLogLevel                = "info"
+	DefaultParallelPoolSize        = 15
+	DefaultPlanStorage             = "local"
+	DefaultPort                    = 4141
+	DefaultRedisDB                 = 0
+	DefaultRedisPort               = 6379
+	DefaultRedisTLSEnabled         = false
+	DefaultRedisInsecureSkipVerify = false
+	DefaultTFDistribution          = "terraform"
+	DefaultTFDownloadURL           = "https://releases.hashicorp.com"
+	DefaultTofuDownloadURL         = "https://github.com/opentofu/opentofu/releases/download"
+	DefaultTFRetryBackoff          = "10s"
+	DefaultVCSAPIMaxRetries        = 5
+	DefaultTFEHostname             = "app.terraform.io"
+	DefaultVCSStatusName           = "atlantis"
 )
 
 var stringFlags = map[string]stringFlag{
+	ADHostnameFlag: {
+		description: "Hostname of your Azure DevOps Server installation. If using dev.azure.com, no need to set." +
+			" Include the virtual directory if your server uses one, ex. ado.example.com/tfs.",
+		defaultValue: DefaultADHostname,
+	},
 	ADTokenFlag: {
 		description: "Azure DevOps token of API user. Can also be specified via the ATLANTIS_AZUREDEVOPS_TOKEN environment variable.",
 	},
@@ -137,9 +222,23 @@ var stringFlags = map[string]stringFlag{
 		description:  "Azure DevOps basic HTTP authentication username for inbound webhooks.",
 		defaultValue: "",
 	},
+	APISecretFlag: {
+		description: "Secret used to validate requests made to the /api endpoints." +
+			" Requests must set the X-Atlantis-Token header to this value." +
+			" If not specified, the API endpoints are disabled." +
+			" Can also be specified via the ATLANTIS_API_SECRET environment variable.",
+	},
+	ApplyConfirmationWindow: {
+		description: "If set, applies must be confirmed by commenting 'atlantis apply --confirm' within this long, ex. 10m." +
+			" Until then, 'atlantis apply' only comments which plans it would apply. Auto-applies don't need to be confirmed.",
+	},
 	AtlantisURLFlag: {
 		description: "URL that Atlantis can be reached at. Defaults to http://$(hostname):$port where $port is from --" + PortFlag + ". Supports a base path ex. https://example.com/basepath.",
 	},
+	AuditWebhookURLFlag: {
+		description: "URL to POST a JSON audit event to for every comment command that's run." +
+			" Can be used to forward the audit log to an external system.",
+	},
 	AutoplanFileListFlag: {
 		description: "Comma separated list of file patterns that Atlantis will use to check if a directory contains modified files that should trigger project planning." +
 			" Patterns use the dockerignore (https://docs.docker.com/engine/reference/builder/#dockerignore-file) syntax." +
@@ -160,7 +259,7 @@ var stringFlags = map[string]stringFlag{
 		defaultValue: DefaultBitbucketBaseURL,
 	},
 	BitbucketWebhookSecretFlag: {
-		description: "Secret used to validate Bitbucket webhooks. Only Bitbucket Server supports webhook secrets." +
+		description: "Secret used to validate Bitbucket webhooks." +
 			" SECURITY WARNING: If not specified, Atlantis won't be able to validate that the incoming webhook call came from Bitbucket. " +
 			"This means that an attacker could spoof calls to Atlantis and cause it to perform malicious actions. " +
 			"Should be specified via the ATLANTIS_BITBUCKET_WEBHOOK_SECRET environment variable.",
@@ -174,6 +273,11 @@ var stringFlags = map[string]stringFlag{
 			" after the pull request is merged.",
 		defaultValue: "branch",
 	},
+	CommandTimeoutFlag: {
+		description: "How long each terraform command, ex. plan or apply, can run before it's interrupted, ex. 2h." +
+			" Terraform is killed if it hasn't exited five minutes after being interrupted." +
+			" Steps can set a shorter timeout in their workflow. If not set, terraform can run forever.",
+	},
 	ConfigFlag: {
 		description: "Path to yaml config file where flag values can also be set.",
 	},
@@ -185,6 +289,17 @@ var stringFlags = map[string]stringFlag{
 		description:  "Hostname of your Github Enterprise installation. If using github.com, no need to set.",
 		defaultValue: DefaultGHHostname,
 	},
+	GHMergeableIgnoreFlag: {
+		description: "Comma-separated status contexts that don't stop a GitHub pull request blocked by branch protection from being mergeable, ex. atlantis/apply." +
+			" Per-project statuses, ex. atlantis/apply: project1, are ignored with their context." +
+			" Lets the mergeable apply requirement be used when Atlantis's own statuses are required checks." +
+			" Requires the token to be able to read branch protection.",
+	},
+	GHRequireStatusContexts: {
+		description: "Comma-separated status contexts that are made required checks of a GitHub repo's default branch the first time its pull requests trigger Atlantis, ex. atlantis/apply." +
+			" Lets repos be onboarded with an organization webhook without setting up their branch protection." +
+			" Requires the token to be able to administer the repos.",
+	},
 	GHUserFlag: {
 		description:  "GitHub username of API user.",
 		defaultValue: "",
@@ -192,6 +307,10 @@ var stringFlags = map[string]stringFlag{
 	GHTokenFlag: {
 		description: "GitHub token of API user. Can also be specified via the ATLANTIS_GH_TOKEN environment variable.",
 	},
+	GHAppKeyFlag: {
+		description:  "The GitHub App's private key. Can also be specified via the ATLANTIS_GH_APP_KEY environment variable. Mutually exclusive with --" + GHAppKeyFileFlag + ".",
+		defaultValue: "",
+	},
 	GHAppKeyFileFlag: {
 		description:  "A path to a file containing the GitHub App's private key",
 		defaultValue: "",
@@ -225,10 +344,42 @@ var stringFlags = map[string]stringFlag{
 			"This means that an attacker could spoof calls to Atlantis and cause it to perform malicious actions. " +
 			"Should be specified via the ATLANTIS_GITLAB_WEBHOOK_SECRET environment variable.",
 	},
+	LocaleFlag: {
+		description: "Locale of the comments and commit statuses Atlantis writes on pull requests. One of " + strings.Join(i18n.Locales, ", ") + "." +
+			" Repos can override it with locale in the server-side repo config.",
+		defaultValue: DefaultLocale,
+	},
+	LockingDBType: {
+		description: "The locking database type to use for storing plan and apply locks. Either boltdb or redis." +
+			" Use redis to share locks between multiple Atlantis instances.",
+		defaultValue: DefaultLockingDBType,
+	},
+	LockTTLFlag: {
+		description: "How long a project lock can be held before it expires, ex. 168h. Expired locks are deleted along with their plans" +
+			" and their pull requests are commented on. If not set, locks are held until their pull requests are closed or they're deleted.",
+	},
 	LogLevelFlag: {
 		description:  "Log level. Either debug, info, warn, or error.",
 		defaultValue: DefaultLogLevel,
 	},
+	PlanStorageFlag: {
+		description: "Where to store planfiles. Either local, s3 or gcs." +
+			" Use s3 or gcs so plans survive restarts when the data dir is on ephemeral storage.",
+		defaultValue: DefaultPlanStorage,
+	},
+	PlanStorageBucketFlag: {
+		description: "The bucket to store planfiles in when using a plan storage of 's3' or 'gcs'.",
+	},
+	PlanStoragePrefixFlag: {
+		description: "Prefix for the keys of planfiles stored in --" + PlanStorageBucketFlag + ".",
+	},
+	RedisHost: {
+		description: "The Redis Hostname for when using a Locking DB type of 'redis'.",
+	},
+	RedisPassword: {
+		description: "The Redis Password for when using a Locking DB type of 'redis'." +
+			" Should be specified via the ATLANTIS_REDIS_PASSWORD environment variable.",
+	},
 	RepoConfigFlag: {
 		description: "Path to a repo config file, used to customize how Atlantis runs on each repo. See runatlantis.io/docs for more details.",
 	},
@@ -239,7 +390,8 @@ var stringFlags = map[string]stringFlag{
 		description: "Comma separated list of repositories that Atlantis will operate on. " +
 			"The format is {hostname}/{owner}/{repo}, ex. github.com/runatlantis/atlantis. '*' matches any characters until the next comma. Examples: " +
 			"all repos: '*' (not secure), an entire hostname: 'internalgithub.com/*' or an organization: 'github.com/runatlantis/*'." +
-			" For Bitbucket Server, {owner} is the name of the project (not the key).",
+			" For Bitbucket Server, {owner} is the name of the project (not the key)." +
+			" Entries can be restricted by appending any of '|plan-only', '|no-autoplan' and '|max-parallel=<n>', ex. 'github.com/contractors/*|plan-only'.",
 	},
 	RepoWhitelistFlag: {
 		description: "[Deprecated for --repo-allowlist].",
@@ -258,6 +410,32 @@ var stringFlags = map[string]stringFlag{
 		description:  "Base URL to download Terraform versions from.",
 		defaultValue: DefaultTFDownloadURL,
 	},
+	TFNetrcCredentialsFlag: {
+		description: "Comma-separated hostname=user:token credentials for hosts that modules are downloaded from over HTTPS, ex. git.company.com=atlantis:xxx." +
+			" They're added to ~/.netrc, which is set as the NETRC env var when terraform runs." +
+			" Should be specified via the ATLANTIS_TF_NETRC_CREDENTIALS environment variable for security.",
+	},
+	TFRegistryCredentialsFlag: {
+		description: "Comma-separated hostname=token credentials for private module registries, ex. artifactory.company.com=xxx." +
+			" They're written to the generated ~/.terraformrc file and set as TF_TOKEN_<host> env vars when terraform runs." +
+			" Should be specified via the ATLANTIS_TF_REGISTRY_CREDENTIALS environment variable for security.",
+	},
+	TFRetryBackoffFlag: {
+		description: fmt.Sprintf("How long to wait before retrying an init or plan step that failed with a transient error, ex. 10s."+
+			" The wait doubles after each retry. Only used if --%s is set.", TFMaxRetriesFlag),
+		defaultValue: DefaultTFRetryBackoff,
+	},
+	TofuDownloadURLFlag: {
+		description:  "Base URL to download OpenTofu versions from.",
+		defaultValue: DefaultTofuDownloadURL,
+	},
+	TracingOTLPEndpointFlag: {
+		description: "Host and port of an OpenTelemetry collector's OTLP HTTP receiver, ex. otel-collector:4318." +
+			" If set, traces of webhooks, commands, projects, clones, steps and terraform runs are exported to it.",
+	},
+	TracingOTLPHeadersFlag: {
+		description: "Comma-separated key=value headers to send when exporting traces, ex. for authentication.",
+	},
 	TFEHostnameFlag: {
 		description:  "Hostname of your Terraform Enterprise installation. If using Terraform Cloud no need to set.",
 		defaultValue: DefaultTFEHostname,
@@ -267,14 +445,83 @@ var stringFlags = map[string]stringFlag{
 			" Only set if using TFC/E as a remote backend." +
 			" Should be specified via the ATLANTIS_TFE_TOKEN environment variable for security.",
 	},
+	WarmProvidersFlag: {
+		description: "Comma-separated providers to install into the plugin cache on startup and when POST /api/warm is called, ex. hashicorp/aws@~> 5.0,hashicorp/null." +
+			" Each is a source address optionally followed by @ and a version constraint that doesn't contain commas.",
+	},
+	WarmTFVersionsFlag: {
+		description: "Comma-separated terraform versions to download on startup and when POST /api/warm is called, in addition to the default version, ex. 1.5.7,1.6.6." +
+			" They're versions of --" + DefaultTFDistributionFlag + ".",
+	},
+	WebOIDCClientIDFlag: {
+		description: "Client ID of Atlantis in the OIDC provider set by --" + WebOIDCIssuerURLFlag + ".",
+	},
+	WebOIDCClientSecretFlag: {
+		description: "Client secret of Atlantis in the OIDC provider set by --" + WebOIDCIssuerURLFlag + "." +
+			" Should be specified via the ATLANTIS_WEB_OIDC_CLIENT_SECRET environment variable.",
+	},
+	WebOIDCIssuerURLFlag: {
+		description: "Issuer URL of an OpenID Connect provider, ex. https://accounts.google.com, that users must log in with to use the web UI." +
+			" Its redirect URL must be set to the --" + AtlantisURLFlag + " followed by /auth/callback.",
+	},
+	WebOperatorsFlag: {
+		description: "Comma-separated users, by email or username, and OIDC groups that can delete locks, discard and plan pull requests, and lock applies in the web UI." +
+			" Use * for every user that can log in. The --" + WebUsernameFlag + " user is always an operator.",
+	},
+	WebPasswordFlag: {
+		description: "Password of the --" + WebUsernameFlag + " user." +
+			" Should be specified via the ATLANTIS_WEB_PASSWORD environment variable.",
+	},
+	WebSessionSecretFlag: {
+		description: "Secret used to sign web UI sessions. Set it to the same value on every instance so sessions work across them and survive restarts." +
+			" If not set, a random secret is generated on startup.",
+	},
+	WebUsernameFlag: {
+		description: "Username that can log in to the web UI when --" + WebBasicAuthFlag + " is set. Requires --" + WebPasswordFlag + ".",
+	},
+	WebViewersFlag: {
+		description: "Comma-separated users and OIDC groups that can view locks, pull requests and history in the web UI." +
+			" If not set, every user that can log in can view.",
+	},
+	WorkspaceGCIntervalFlag: {
+		description: "How often to delete the working dirs, locks and plans of pull requests that are closed or older than --" + WorkspaceGCMaxAgeFlag + "," +
+			" ex. 1h. This cleans up after pull requests whose close event was missed. If not set, pull requests are only cleaned up when they're closed.",
+	},
+	WorkspaceGCMaxAgeFlag: {
+		description: "How long a pull request's working dir can go unmodified before --" + WorkspaceGCIntervalFlag + " deletes it even if the pull request is open, ex. 720h." +
+			" If not set, only the working dirs of closed pull requests are deleted.",
+	},
+	WorkingDirLockTimeoutFlag: {
+		description: "How long a command waits for another command running in the same pull request's working dir to finish, ex. 5m." +
+			" Waiting commands run in the order they were started and give up if the pull request is closed." +
+			" If not set, the command fails immediately and has to be rerun.",
+	},
+	DefaultTFDistributionFlag: {
+		description: "Distribution of terraform to run projects with unless the repo config sets terraform_distribution." +
+			" Either terraform or opentofu. --" + DefaultTFVersionFlag + " is a version of this distribution.",
+		defaultValue: DefaultTFDistribution,
+	},
 	DefaultTFVersionFlag: {
 		description: "Terraform version to default to (ex. v0.12.0). Will download if not yet on disk." +
 			" If not set, Atlantis uses the terraform binary in its PATH.",
 	},
+	DefaultTofuVersionFlag: {
+		description: "OpenTofu version to default to (ex. v1.6.0) when --" + DefaultTFDistributionFlag + " isn't opentofu." +
+			" Projects that use opentofu without setting terraform_version or required_version run with it." +
+			" If not set, Atlantis uses the tofu binary in its PATH, or else the newest OpenTofu release.",
+	},
+	DefaultTGVersionFlag: {
+		description: "Terragrunt version used by the terragrunt workflow step (ex. v0.35.0). Will download if not yet on disk." +
+			" If not set, Atlantis uses the terragrunt binary in its PATH.",
+	},
 	VCSStatusName: {
 		description:  "Name used to identify Atlantis for pull request statuses.",
 		defaultValue: DefaultVCSStatusName,
 	},
+	VCSHostsConfigFlag: {
+		description: "Path to a YAML file of additional GitHub and GitLab hosts, ex. a second GitHub Enterprise instance, each with its own credentials," +
+			" webhook secret and optional repo allowlist. See runatlantis.io/docs for more details.",
+	},
 }
 
 var boolFlags = map[string]boolFlag{
@@ -293,6 +540,11 @@ var boolFlags = map[string]boolFlag{
 		description:  "Automatically merge pull requests when all plans are successfully applied.",
 		defaultValue: false,
 	},
+	AutoplanModulesFlag: {
+		description: "Autoplan the projects that call a local module, ex. via source = \"../modules/vpc\", when that module is modified." +
+			" Requires parsing all Terraform files in the repo.",
+		defaultValue: false,
+	},
 	DisableApplyAllFlag: {
 		description:  "Disable \"atlantis apply\" command without any flags (i.e. apply all). A specific project/workspace/directory has to be specified for applies.",
 		defaultValue: false,
@@ -308,23 +560,83 @@ var boolFlags = map[string]boolFlag{
 	DisableRepoLockingFlag: {
 		description: "Disable atlantis locking repos",
 	},
+	EnableAuditLogFlag: {
+		description: "Record every comment command that's run, who ran it and its result in the Atlantis database." +
+			" The audit log can be read from the /api/audit endpoint.",
+		defaultValue: false,
+	},
+	EnableCloneCacheFlag: {
+		description: "Keep a bare clone of each repo in the data dir that's updated before every clone and that clones borrow objects from" +
+			" with git clone --reference, so only new objects are fetched over the network.",
+		defaultValue: false,
+	},
+	EnableCommandQueueFlag: {
+		description: "Persist comment commands and autoplans before running them so that commands interrupted by a restart are run again on startup." +
+			" Commands are run by a pool of --command-queue-workers workers.",
+		defaultValue: false,
+	},
+	EnableGHChecksFlag: {
+		description: "Create a GitHub check run per project with the plan output and a button to re-plan." +
+			" Requires GitHub App credentials, otherwise only commit statuses are used.",
+		defaultValue: false,
+	},
+	EnableGHDeploymentsFlag: {
+		description:  "Create a GitHub deployment for each project apply, to an environment named after the project or its workspace.",
+		defaultValue: false,
+	},
+	EnableHAModeFlag: {
+		description: "Run multiple Atlantis instances behind a load balancer. Webhook deliveries are only handled once across all instances." +
+			" Requires --locking-db-type=redis and a remote --plan-storage.",
+		defaultValue: false,
+	},
+	EnableLockQueueFlag: {
+		description: "Queue plans that are blocked by another pull request's lock and run them once the lock is released." +
+			" The queue is held in memory so queued plans are lost on restart.",
+		defaultValue: false,
+	},
 	EnablePolicyChecksFlag: {
 		description:  "Enable atlantis to run user defined policy checks.  This is explicitly disabled for TFE/TFC backends since plan files are inaccessible.",
 		defaultValue: false,
 	},
+	EnableProgressCommentsFlag: {
+		description: "React to comment commands as soon as they're received and post a comment showing the command is running that's edited with its results instead of posting a new comment." +
+			" VCS support is limited to: GitHub, GitLab.",
+		defaultValue: false,
+	},
+	EnableProjectStatusesFlag: {
+		description:  "Set a commit status for each project's plan, policy check and apply, ex. atlantis/plan: dir/workspace, in addition to the status for the whole command.",
+		defaultValue: false,
+	},
 	EnableRegExpCmdFlag: {
 		description:  "Enable Atlantis to use regular expressions on plan/apply commands when \"-p\" flag is passed with it.",
 		defaultValue: false,
 	},
+	EnableStateCmdFlag: {
+		description:  "Enable the destructive 'atlantis state rm' and 'atlantis state mv' commands.",
+		defaultValue: false,
+	},
+	EnableStructuredPlanFlag: {
+		description: "Also run 'terraform show -json' on each plan and comment the resources to change grouped by action instead of the raw plan output." +
+			" Requires Terraform >= 0.12.",
+		defaultValue: false,
+	},
 	AllowDraftPRs: {
-		description:  "Enable autoplan for Github Draft Pull Requests",
+		description:  "Enable autoplan for draft pull requests (GitHub and Azure DevOps) and draft merge requests (GitLab).",
 		defaultValue: false,
 	},
 	HidePrevPlanComments: {
 		description: "Hide previous plan comments to reduce clutter in the PR. " +
-			"VCS support is limited to: GitHub.",
+			"Comments are minimized on GitHub, collapsed on GitLab, resolved on Azure DevOps and deleted on Bitbucket.",
 		defaultValue: false,
 	},
+	RedisTLSEnabled: {
+		description:  "Enable TLS on the connection to Redis with a min TLS version of 1.2.",
+		defaultValue: DefaultRedisTLSEnabled,
+	},
+	RedisInsecureSkipVerify: {
+		description:  "Controls whether the Redis client verifies the Redis server's certificate chain and host name. If true, accepts any certificate presented by the server and any host name in that certificate.",
+		defaultValue: DefaultRedisInsecureSkipVerify,
+	},
 	RequireApprovalFlag: {
 		description:  "Require pull requests to be \"Approved\" before allowing the apply command to be run.",
 		defaultValue: false,
@@ -339,6 +651,10 @@ var boolFlags = map[string]boolFlag{
 		description:  "Silences Atlants from responding to PRs when it finds no projects.",
 		defaultValue: false,
 	},
+	SilenceNoChangePlansFlag: {
+		description:  "Leaves projects whose plans have no changes out of plan comments, and doesn't comment if every plan has no changes. Can be overridden per repo with silence_no_change_plans in the server-side repo config.",
+		defaultValue: false,
+	},
 	SilenceForkPRErrorsFlag: {
 		description:  "Silences the posting of fork pull requests not allowed error comments.",
 		defaultValue: false,
@@ -360,6 +676,20 @@ var boolFlags = map[string]boolFlag{
 		description:  "Toggle off folding in markdown output.",
 		defaultValue: false,
 	},
+	TracingOTLPInsecureFlag: {
+		description:  "Export traces to --" + TracingOTLPEndpointFlag + " over HTTP instead of HTTPS.",
+		defaultValue: false,
+	},
+	UploadLargeCommentsFlag: {
+		description: "Upload comments that are too long for GitHub or GitLab as a secret gist or private project snippet and link to it from a truncated comment," +
+			" instead of splitting them into multiple comments. Creating gists requires --gh-user/--gh-token rather than a GitHub app.",
+		defaultValue: false,
+	},
+	WebBasicAuthFlag: {
+		description: "Require the --" + WebUsernameFlag + " and --" + WebPasswordFlag + " basic auth credentials to use the web UI." +
+			" Can be used as a fallback to or instead of OIDC.",
+		defaultValue: false,
+	},
 	WriteGitCredsFlag: {
 		description: "Write out a .git-credentials file with the provider user and token to allow cloning private modules over HTTPS or SSH." +
 			" This writes secrets to disk and should only be enabled in a secure environment.",
@@ -371,6 +701,20 @@ var boolFlags = map[string]boolFlag{
 	},
 }
 var intFlags = map[string]intFlag{
+	CheckoutDepthFlag: {
+		description: "How many commits of the base and head branches to fetch when --" + CheckoutStrategyFlag + " is merge." +
+			" More history is fetched as needed to find where the branch branched off. If 0, their full history is fetched.",
+		defaultValue: 0,
+	},
+	DataDirMaxSizeMBFlag: {
+		description: "Max disk space in megabytes that the data dir can use. Once it's exceeded, new plans are rejected with a pull request comment" +
+			" and the working dirs of closed pull requests are cleaned up (see --" + WorkspaceGCIntervalFlag + "). If 0, there's no limit.",
+		defaultValue: 0,
+	},
+	CommandQueueWorkersFlag: {
+		description:  "Number of commands that are run at the same time when --enable-command-queue is set.",
+		defaultValue: DefaultCommandQueueWorkers,
+	},
 	ParallelPoolSize: {
 		description:  "Max size of the wait group that runs parallel plans and applies (if enabled).",
 		defaultValue: DefaultParallelPoolSize,
@@ -379,6 +723,25 @@ var intFlags = map[string]intFlag{
 		description:  "Port to bind to.",
 		defaultValue: DefaultPort,
 	},
+	RedisDB: {
+		description:  "The Redis Database to use when using a Locking DB type of 'redis'.",
+		defaultValue: DefaultRedisDB,
+	},
+	RedisPort: {
+		description:  "The Redis Port for when using a Locking DB type of 'redis'.",
+		defaultValue: DefaultRedisPort,
+	},
+	TFMaxRetriesFlag: {
+		description: "Max number of times an init or plan step is retried when it fails with a transient error," +
+			" ex. provider throttling, registry timeouts or \"connection reset by peer\". Retries are listed in the plan comment." +
+			" Defaults to 0, which disables retries.",
+		defaultValue: 0,
+	},
+	VCSAPIMaxRetriesFlag: {
+		description: "Max number of times a VCS API request is retried when it's rate limited or fails with a server error." +
+			" Retries back off exponentially and honor the rate limit headers of GitHub and GitLab. Set to -1 to disable retries.",
+		defaultValue: DefaultVCSAPIMaxRetries,
+	},
 }
 
 var int64Flags = map[string]int64Flag{
@@ -569,6 +932,7 @@ func (s *ServerCmd) run() error {
 		AtlantisURLFlag:         AtlantisURLFlag,
 		AtlantisVersion:         s.AtlantisVersion,
 		DefaultTFVersionFlag:    DefaultTFVersionFlag,
+		DefaultTGVersionFlag:    DefaultTGVersionFlag,
 		RepoConfigJSONFlag:      RepoConfigJSONFlag,
 		SilenceForkPRErrorsFlag: SilenceForkPRErrorsFlag,
 	})
@@ -588,6 +952,9 @@ func (s *ServerCmd) setDefaults(c *server.UserConfig) {
 	if c.DataDir == "" {
 		c.DataDir = DefaultDataDir
 	}
+	if c.AzureDevopsHostname == "" {
+		c.AzureDevopsHostname = DefaultADHostname
+	}
 	if c.GithubHostname == "" {
 		c.GithubHostname = DefaultGHHostname
 	}
@@ -597,18 +964,45 @@ func (s *ServerCmd) setDefaults(c *server.UserConfig) {
 	if c.BitbucketBaseURL == "" {
 		c.BitbucketBaseURL = DefaultBitbucketBaseURL
 	}
+	if c.Locale == "" {
+		c.Locale = DefaultLocale
+	}
+	if c.LockingDBType == "" {
+		c.LockingDBType = DefaultLockingDBType
+	}
 	if c.LogLevel == "" {
 		c.LogLevel = DefaultLogLevel
 	}
+	if c.CommandQueueWorkers == 0 {
+		c.CommandQueueWorkers = DefaultCommandQueueWorkers
+	}
 	if c.ParallelPoolSize == 0 {
 		c.ParallelPoolSize = DefaultParallelPoolSize
 	}
+	if c.PlanStorage == "" {
+		c.PlanStorage = DefaultPlanStorage
+	}
 	if c.Port == 0 {
 		c.Port = DefaultPort
 	}
+	if c.RedisPort == 0 {
+		c.RedisPort = DefaultRedisPort
+	}
 	if c.TFDownloadURL == "" {
 		c.TFDownloadURL = DefaultTFDownloadURL
 	}
+	if c.TofuDownloadURL == "" {
+		c.TofuDownloadURL = DefaultTofuDownloadURL
+	}
+	if c.TFRetryBackoff == "" {
+		c.TFRetryBackoff = DefaultTFRetryBackoff
+	}
+	if c.DefaultTFDistribution == "" {
+		c.DefaultTFDistribution = DefaultTFDistribution
+	}
+	if c.VCSAPIMaxRetries == 0 {
+		c.VCSAPIMaxRetries = DefaultVCSAPIMaxRetries
+	}
 	if c.VCSStatusName == "" {
 		c.VCSStatusName = DefaultVCSStatusName
 	}
@@ -628,6 +1022,60 @@ func (s *ServerCmd) validate(userConfig server.UserConfig) error {
 		return errors.New("invalid checkout strategy: not one of branch or merge")
 	}
 
+	if !i18n.Supported(userConfig.Locale) {
+		return fmt.Errorf("invalid --%s: not one of %s", LocaleFlag, strings.Join(i18n.Locales, ", "))
+	}
+
+	lockingDBType := userConfig.LockingDBType
+	if lockingDBType != "boltdb" && lockingDBType != "redis" {
+		return errors.New("invalid locking db type: not one of boltdb or redis")
+	}
+	if lockingDBType == "redis" && userConfig.RedisHost == "" {
+		return fmt.Errorf("--%s must be set when --%s is redis", RedisHost, LockingDBType)
+	}
+
+	tfDistribution := userConfig.DefaultTFDistribution
+	if tfDistribution != valid.TerraformDistribution && tfDistribution != valid.OpenTofuDistribution {
+		return errors.New("invalid default terraform distribution: not one of terraform or opentofu")
+	}
+
+	if userConfig.CheckoutDepth < 0 {
+		return fmt.Errorf("--%s must not be negative", CheckoutDepthFlag)
+	}
+	if userConfig.DataDirMaxSizeMB < 0 {
+		return fmt.Errorf("--%s must not be negative", DataDirMaxSizeMBFlag)
+	}
+	if userConfig.TFMaxRetries < 0 {
+		return fmt.Errorf("--%s must not be negative", TFMaxRetriesFlag)
+	}
+
+	planStorage := userConfig.PlanStorage
+	if planStorage != "local" && planStorage != "s3" && planStorage != "gcs" {
+		return errors.New("invalid plan storage: not one of local, s3 or gcs")
+	}
+	if planStorage != "local" && userConfig.PlanStorageBucket == "" {
+		return fmt.Errorf("--%s must be set when --%s is %s", PlanStorageBucketFlag, PlanStorageFlag, planStorage)
+	}
+
+	// In HA mode, instances share locks and plans so any instance can handle
+	// any command.
+	if userConfig.EnableHAMode && lockingDBType != "redis" {
+		return fmt.Errorf("--%s must be redis when --%s is set", LockingDBType, EnableHAModeFlag)
+	}
+	if userConfig.EnableHAMode && planStorage == "local" {
+		return fmt.Errorf("--%s must be s3 or gcs when --%s is set", PlanStorageFlag, EnableHAModeFlag)
+	}
+	// The queue is shared through the database so instances would replay
+	// each other's in-progress commands when they start.
+	if userConfig.EnableHAMode && userConfig.EnableCommandQueue {
+		return fmt.Errorf("--%s can't be used with --%s", EnableCommandQueueFlag, EnableHAModeFlag)
+	}
+	// The lock queue is held in memory so a lock released on one instance
+	// wouldn't run plans queued on another.
+	if userConfig.EnableHAMode && userConfig.EnableLockQueue {
+		return fmt.Errorf("--%s can't be used with --%s", EnableLockQueueFlag, EnableHAModeFlag)
+	}
+
 	if (userConfig.SSLKeyFile == "") != (userConfig.SSLCertFile == "") {
 		return fmt.Errorf("--%s and --%s are both required for ssl", SSLKeyFileFlag, SSLCertFileFlag)
 	}
@@ -638,13 +1086,17 @@ func (s *ServerCmd) validate(userConfig server.UserConfig) error {
 	// 3. bitbucket user and token set
 	// 4. azuredevops user and token set
 	// 5. any combination of the above
+	if userConfig.GithubAppKey != "" && userConfig.GithubAppKeyFile != "" {
+		return fmt.Errorf("--%s and --%s cannot both be set", GHAppKeyFlag, GHAppKeyFileFlag)
+	}
+	githubAppKeySet := userConfig.GithubAppKey != "" || userConfig.GithubAppKeyFile != ""
 	vcsErr := fmt.Errorf("--%s/--%s or --%s/--%s or --%s/--%s or --%s/--%s or --%s/--%s must be set", GHUserFlag, GHTokenFlag, GHAppIDFlag, GHAppKeyFileFlag, GitlabUserFlag, GitlabTokenFlag, BitbucketUserFlag, BitbucketTokenFlag, ADUserFlag, ADTokenFlag)
-	if ((userConfig.GithubUser == "") != (userConfig.GithubToken == "")) || ((userConfig.GithubAppID == 0) != (userConfig.GithubAppKey == "")) || ((userConfig.GitlabUser == "") != (userConfig.GitlabToken == "")) || ((userConfig.BitbucketUser == "") != (userConfig.BitbucketToken == "")) || ((userConfig.AzureDevopsUser == "") != (userConfig.AzureDevopsToken == "")) {
+	if ((userConfig.GithubUser == "") != (userConfig.GithubToken == "")) || ((userConfig.GithubAppID == 0) == githubAppKeySet) || ((userConfig.GitlabUser == "") != (userConfig.GitlabToken == "")) || ((userConfig.BitbucketUser == "") != (userConfig.BitbucketToken == "")) || ((userConfig.AzureDevopsUser == "") != (userConfig.AzureDevopsToken == "")) {
 		return vcsErr
 	}
 	// At this point, we know that there can't be a single user/token without
 	// its partner, but we haven't checked if any user/token is set at all.
-	if userConfig.GithubAppID == 0 && userConfig.GithubUser == "" && userConfig.GitlabUser == "" && userConfig.BitbucketUser == "" && userConfig.AzureDevopsUser == "" {
+	if userConfig.GithubAppID == 0 && userConfig.GithubUser == "" && userConfig.GitlabUser == "" && userConfig.BitbucketUser == "" && userConfig.AzureDevopsUser == "" && userConfig.VCSHostsConfig == "" {
 		return vcsErr
 	}
 
@@ -655,6 +1107,9 @@ func (s *ServerCmd) validate(userConfig server.UserConfig) error {
 	if userConfig.RepoAllowlist != "" && userConfig.RepoWhitelist != "" {
 		return fmt.Errorf("both --%s and --%s cannot be set–use --%s", RepoAllowlistFlag, RepoWhitelistFlag, RepoAllowlistFlag)
 	}
+	if strings.Contains(userConfig.AzureDevopsHostname, "://") {
+		return fmt.Errorf("--%s cannot contain ://, should be a hostname only", ADHostnameFlag)
+	}
 	if strings.Contains(userConfig.RepoWhitelist, "://") {
 		return fmt.Errorf("--%s cannot contain ://, should be hostnames only", RepoWhitelistFlag)
 	}
@@ -665,10 +1120,6 @@ func (s *ServerCmd) validate(userConfig server.UserConfig) error {
 		return fmt.Errorf("both --%s and --%s cannot be set–use --%s", SilenceAllowlistErrorsFlag, SilenceWhitelistErrorsFlag, SilenceAllowlistErrorsFlag)
 	}
 
-	if userConfig.BitbucketBaseURL == DefaultBitbucketBaseURL && userConfig.BitbucketWebhookSecret != "" {
-		return fmt.Errorf("--%s cannot be specified for Bitbucket Cloud because it is not supported by Bitbucket", BitbucketWebhookSecretFlag)
-	}
-
 	parsed, err := url.Parse(userConfig.BitbucketBaseURL)
 	if err != nil {
 		return fmt.Errorf("error parsing --%s flag value %q: %s", BitbucketWebhookSecretFlag, userConfig.BitbucketBaseURL, err)
@@ -695,9 +1146,49 @@ func (s *ServerCmd) validate(userConfig server.UserConfig) error {
 		}
 	}
 
+	if userConfig.WebOIDCIssuerURL != "" && (userConfig.WebOIDCClientID == "" || userConfig.WebOIDCClientSecret == "") {
+		return fmt.Errorf("--%s and --%s must be set when using --%s", WebOIDCClientIDFlag, WebOIDCClientSecretFlag, WebOIDCIssuerURLFlag)
+	}
+	if userConfig.WebBasicAuth && (userConfig.WebUsername == "" || userConfig.WebPassword == "") {
+		return fmt.Errorf("--%s and --%s must both be set when using --%s", WebUsernameFlag, WebPasswordFlag, WebBasicAuthFlag)
+	}
+	if !userConfig.WebBasicAuth && (userConfig.WebUsername != "" || userConfig.WebPassword != "") {
+		return fmt.Errorf("--%s must be set to use --%s and --%s", WebBasicAuthFlag, WebUsernameFlag, WebPasswordFlag)
+	}
+
 	if userConfig.TFEHostname != DefaultTFEHostname && userConfig.TFEToken == "" {
 		return fmt.Errorf("if setting --%s, must set --%s", TFEHostnameFlag, TFETokenFlag)
 	}
+	registryCredentials, err := terraform.ParseRegistryCredentials(userConfig.TFRegistryCredentials)
+	if err != nil {
+		return errors.Wrapf(err, "invalid --%s", TFRegistryCredentialsFlag)
+	}
+	if _, ok := registryCredentials[userConfig.TFEHostname]; ok && userConfig.TFEToken != "" {
+		return fmt.Errorf("--%s can't contain credentials for %s since they're set by --%s", TFRegistryCredentialsFlag, userConfig.TFEHostname, TFETokenFlag)
+	}
+	if _, err := terraform.ParseNetrcCredentials(userConfig.TFNetrcCredentials); err != nil {
+		return errors.Wrapf(err, "invalid --%s", TFNetrcCredentialsFlag)
+	}
+
+	for _, flag := range []struct {
+		name  string
+		value string
+	}{
+		{WorkspaceGCIntervalFlag, userConfig.WorkspaceGCInterval},
+		{WorkspaceGCMaxAgeFlag, userConfig.WorkspaceGCMaxAge},
+		{LockTTLFlag, userConfig.LockTTL},
+		{WorkingDirLockTimeoutFlag, userConfig.WorkingDirLockTimeout},
+		{CommandTimeoutFlag, userConfig.CommandTimeout},
+		{ApplyConfirmationWindow, userConfig.ApplyConfirmationWindow},
+		{TFRetryBackoffFlag, userConfig.TFRetryBackoff},
+	} {
+		if flag.value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(flag.value); err != nil || d <= 0 {
+			return fmt.Errorf("invalid --%s: must be a positive duration, ex. 24h", flag.name)
+		}
+	}
 
 	_, patternErr := fileutils.NewPatternMatcher(strings.Split(userConfig.AutoplanFileList, ","))
 	if patternErr != nil {
@@ -51,30 +51,43 @@ func (s *ServerStarterMock) Start() error {
 // Adding a new flag? Add it to this slice for testing in alphabetical
 // order.
 var testFlags = map[string]interface{}{
+	ADHostnameFlag:             "ad-hostname",
 	ADTokenFlag:                "ad-token",
 	ADUserFlag:                 "ad-user",
 	ADWebhookPasswordFlag:      "ad-wh-pass",
 	ADWebhookUserFlag:          "ad-wh-user",
 	AtlantisURLFlag:            "url",
+	AuditWebhookURLFlag:        "https://audit.example.com",
 	AllowForkPRsFlag:           true,
+	APISecretFlag:              "api-secret",
+	ApplyConfirmationWindow:    "10m",
 	AllowRepoConfigFlag:        true,
 	AutomergeFlag:              true,
 	AutoplanFileListFlag:       "**/*.tf,**/*.yml",
+	AutoplanModulesFlag:        true,
 	BitbucketBaseURLFlag:       "https://bitbucket-base-url.com",
 	BitbucketTokenFlag:         "bitbucket-token",
 	BitbucketUserFlag:          "bitbucket-user",
 	BitbucketWebhookSecretFlag: "bitbucket-secret",
 	CheckoutStrategyFlag:       "merge",
+	CheckoutDepthFlag:          50,
 	DataDirFlag:                "/path",
+	DataDirMaxSizeMBFlag:       10240,
+	DefaultTFDistributionFlag:  "opentofu",
 	DefaultTFVersionFlag:       "v0.11.0",
+	DefaultTofuVersionFlag:     "v1.6.0",
+	DefaultTGVersionFlag:       "v0.35.0",
 	DisableApplyAllFlag:        true,
 	DisableApplyFlag:           true,
 	DisableMarkdownFoldingFlag: true,
 	DisableRepoLockingFlag:     true,
 	GHHostnameFlag:             "ghhostname",
+	GHMergeableIgnoreFlag:      "atlantis/apply",
+	GHRequireStatusContexts:    "atlantis/plan,atlantis/apply",
 	GHTokenFlag:                "token",
 	GHUserFlag:                 "user",
 	GHAppIDFlag:                int64(0),
+	GHAppKeyFlag:               "",
 	GHAppKeyFileFlag:           "",
 	GHAppSlugFlag:              "atlantis",
 	GHOrganizationFlag:         "",
@@ -83,14 +96,29 @@ var testFlags = map[string]interface{}{
 	GitlabTokenFlag:            "gitlab-token",
 	GitlabUserFlag:             "gitlab-user",
 	GitlabWebhookSecretFlag:    "gitlab-secret",
+	LocaleFlag:                 "ja",
+	LockingDBType:              "boltdb",
+	LockTTLFlag:                "168h",
 	LogLevelFlag:               "debug",
 	AllowDraftPRs:              true,
+	PlanStorageFlag:            "local",
+	PlanStorageBucketFlag:      "plan-bucket",
+	PlanStoragePrefixFlag:      "atlantis/plans",
 	PortFlag:                   8181,
 	ParallelPoolSize:           100,
+	CommandQueueWorkersFlag:    5,
+	CommandTimeoutFlag:         "2h",
+	RedisDB:                    0,
+	RedisHost:                  "redis-host",
+	RedisInsecureSkipVerify:    false,
+	RedisPassword:              "redis-password",
+	RedisPort:                  6379,
+	RedisTLSEnabled:            false,
 	RepoAllowlistFlag:          "github.com/runatlantis/atlantis",
 	RequireApprovalFlag:        true,
 	RequireMergeableFlag:       true,
 	SilenceNoProjectsFlag:      false,
+	SilenceNoChangePlansFlag:   true,
 	SilenceForkPRErrorsFlag:    true,
 	SilenceAllowlistErrorsFlag: true,
 	SilenceVCSStatusNoPlans:    true,
@@ -99,13 +127,49 @@ var testFlags = map[string]interface{}{
 	SSLCertFileFlag:            "cert-file",
 	SSLKeyFileFlag:             "key-file",
 	TFDownloadURLFlag:          "https://my-hostname.com",
+	TFMaxRetriesFlag:           2,
+	TFNetrcCredentialsFlag:     "git.company.com=atlantis:netrc-token",
+	TFRegistryCredentialsFlag:  "registry.company.com=registry-token",
+	TFRetryBackoffFlag:         "30s",
+	TofuDownloadURLFlag:        "https://my-tofu-hostname.com",
+	TracingOTLPEndpointFlag:    "otel-collector:4318",
+	TracingOTLPHeadersFlag:     "x-api-key=secret",
+	TracingOTLPInsecureFlag:    true,
 	TFEHostnameFlag:            "my-hostname",
 	TFETokenFlag:               "my-token",
+	UploadLargeCommentsFlag:    true,
 	VCSStatusName:              "my-status",
+	VCSAPIMaxRetriesFlag:       3,
+	VCSHostsConfigFlag:         "vcs-hosts.yaml",
+	WarmProvidersFlag:          "hashicorp/aws@~> 5.0",
+	WarmTFVersionsFlag:         "1.5.7",
+	WebOIDCClientIDFlag:        "client-id",
+	WebOIDCClientSecretFlag:    "client-secret",
+	WebOIDCIssuerURLFlag:       "https://issuer.example.com",
+	WebOperatorsFlag:           "admins",
+	WebPasswordFlag:            "web-password",
+	WebSessionSecretFlag:       "session-secret",
+	WebUsernameFlag:            "web-user",
+	WebViewersFlag:             "engineers",
+	WorkspaceGCIntervalFlag:    "1h",
+	WorkspaceGCMaxAgeFlag:      "720h",
+	WorkingDirLockTimeoutFlag:  "5m",
 	WriteGitCredsFlag:          true,
 	DisableAutoplanFlag:        true,
+	EnableAuditLogFlag:         true,
+	EnableCloneCacheFlag:       true,
+	EnableCommandQueueFlag:     true,
+	EnableGHChecksFlag:         false,
+	EnableGHDeploymentsFlag:    true,
+	EnableHAModeFlag:           false,
+	EnableLockQueueFlag:        true,
 	EnablePolicyChecksFlag:     false,
+	EnableProgressCommentsFlag: true,
+	EnableProjectStatusesFlag:  true,
 	EnableRegExpCmdFlag:        false,
+	EnableStructuredPlanFlag:   false,
+	EnableStateCmdFlag:         false,
+	WebBasicAuthFlag:           true,
 }
 
 func TestExecute_Defaults(t *testing.T) {
@@ -293,6 +357,14 @@ func TestExecute_ValidateLogLevel(t *testing.T) {
 	}
 }
 
+func TestExecute_ValidateLocale(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		LocaleFlag: "fr",
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, "invalid --locale: not one of en, ja", err)
+}
+
 func TestExecute_ValidateCheckoutStrategy(t *testing.T) {
 	c := setupWithDefaults(map[string]interface{}{
 		CheckoutStrategyFlag: "invalid",
@@ -301,6 +373,117 @@ func TestExecute_ValidateCheckoutStrategy(t *testing.T) {
 	ErrEquals(t, "invalid checkout strategy: not one of branch or merge", err)
 }
 
+func TestExecute_ValidateLockingDBType(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		LockingDBType: "invalid",
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, "invalid locking db type: not one of boltdb or redis", err)
+}
+
+func TestExecute_RedisRequiresHost(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		LockingDBType: "redis",
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, "--redis-host must be set when --locking-db-type is redis", err)
+}
+
+func TestExecute_ValidateDefaultTFDistribution(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		DefaultTFDistributionFlag: "invalid",
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, "invalid default terraform distribution: not one of terraform or opentofu", err)
+}
+
+func TestExecute_ValidatePlanStorage(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		PlanStorageFlag: "invalid",
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, "invalid plan storage: not one of local, s3 or gcs", err)
+}
+
+func TestExecute_RemotePlanStorageRequiresBucket(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		PlanStorageFlag: "s3",
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, "--plan-storage-bucket must be set when --plan-storage is s3", err)
+}
+
+func TestExecute_HAModeRequiresRedis(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		EnableHAModeFlag: true,
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, "--locking-db-type must be redis when --enable-ha-mode is set", err)
+}
+
+func TestExecute_HAModeRequiresRemotePlanStorage(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		EnableHAModeFlag: true,
+		LockingDBType:    "redis",
+		RedisHost:        "localhost",
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, "--plan-storage must be s3 or gcs when --enable-ha-mode is set", err)
+}
+
+func TestExecute_HAModeCommandQueue(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		EnableHAModeFlag:       true,
+		EnableCommandQueueFlag: true,
+		LockingDBType:          "redis",
+		RedisHost:              "localhost",
+		PlanStorageFlag:        "s3",
+		PlanStorageBucketFlag:  "bucket",
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, "--enable-command-queue can't be used with --enable-ha-mode", err)
+}
+
+func TestExecute_HAModeLockQueue(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		EnableHAModeFlag:      true,
+		EnableLockQueueFlag:   true,
+		LockingDBType:         "redis",
+		RedisHost:             "localhost",
+		PlanStorageFlag:       "s3",
+		PlanStorageBucketFlag: "bucket",
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, "--enable-lock-queue can't be used with --enable-ha-mode", err)
+}
+
+func TestExecute_WebOIDCRequiresClient(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		WebOIDCIssuerURLFlag: "https://issuer.example.com",
+		WebOIDCClientIDFlag:  "client-id",
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, "--web-oidc-client-id and --web-oidc-client-secret must be set when using --web-oidc-issuer-url", err)
+}
+
+func TestExecute_WebBasicAuthRequiresCredentials(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		WebBasicAuthFlag: true,
+		WebUsernameFlag:  "admin",
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, "--web-username and --web-password must both be set when using --web-basic-auth", err)
+}
+
+func TestExecute_WebUsernameRequiresBasicAuth(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		WebUsernameFlag: "admin",
+		WebPasswordFlag: "password",
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, "--web-basic-auth must be set to use --web-username and --web-password", err)
+}
+
 func TestExecute_ValidateSSLConfig(t *testing.T) {
 	expErr := "--ssl-key-file and --ssl-cert-file are both required for ssl"
 	cases := []struct {
@@ -410,6 +593,13 @@ func TestExecute_ValidateVCSConfig(t *testing.T) {
 			},
 			true,
 		},
+		{
+			"just github app key contents set",
+			map[string]interface{}{
+				GHAppKeyFlag: "key",
+			},
+			true,
+		},
 		{
 			"just gitlab user set",
 			map[string]interface{}{
@@ -471,6 +661,14 @@ func TestExecute_ValidateVCSConfig(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"github app and key contents set and should be successful",
+			map[string]interface{}{
+				GHAppIDFlag:  "1",
+				GHAppKeyFlag: "key",
+			},
+			false,
+		},
 		{
 			"gitlab user and gitlab token set and should be successful",
 			map[string]interface{}{
@@ -495,6 +693,13 @@ func TestExecute_ValidateVCSConfig(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"just vcs hosts config set",
+			map[string]interface{}{
+				VCSHostsConfigFlag: "vcs-hosts.yaml",
+			},
+			false,
+		},
 		{
 			"all set should be successful",
 			map[string]interface{}{
@@ -582,6 +787,17 @@ func TestExecute_GithubApp(t *testing.T) {
 	Equals(t, int64(1), passedConfig.GithubAppID)
 }
 
+func TestExecute_GithubAppKeyAndKeyFile(t *testing.T) {
+	c := setup(map[string]interface{}{
+		GHAppKeyFlag:      "key",
+		GHAppKeyFileFlag:  "key.pem",
+		GHAppIDFlag:       "1",
+		RepoAllowlistFlag: "*",
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, "--gh-app-key and --gh-app-key-file cannot both be set", err)
+}
+
 func TestExecute_GitlabUser(t *testing.T) {
 	t.Log("Should remove the @ from the gitlab username if it's passed.")
 	c := setup(map[string]interface{}{
@@ -621,7 +837,7 @@ func TestExecute_ADUser(t *testing.T) {
 	Equals(t, "user", passedConfig.AzureDevopsUser)
 }
 
-// If using bitbucket cloud, webhook secrets are not supported.
+// Bitbucket Cloud supports webhook secrets.
 func TestExecute_BitbucketCloudWithWebhookSecret(t *testing.T) {
 	c := setup(map[string]interface{}{
 		BitbucketUserFlag:          "user",
@@ -630,7 +846,8 @@ func TestExecute_BitbucketCloudWithWebhookSecret(t *testing.T) {
 		BitbucketWebhookSecretFlag: "my secret",
 	}, t)
 	err := c.Execute()
-	ErrEquals(t, "--bitbucket-webhook-secret cannot be specified for Bitbucket Cloud because it is not supported by Bitbucket", err)
+	Ok(t, err)
+	Equals(t, "my secret", passedConfig.BitbucketWebhookSecret)
 }
 
 // Base URL must have a scheme.
@@ -689,6 +906,63 @@ func TestExecute_TFEHostnameOnly(t *testing.T) {
 	ErrEquals(t, "if setting --tfe-hostname, must set --tfe-token", err)
 }
 
+func TestExecute_InvalidTFRegistryCredentials(t *testing.T) {
+	c := setup(map[string]interface{}{
+		GHUserFlag:                "user",
+		GHTokenFlag:               "token",
+		RepoAllowlistFlag:         "github.com",
+		TFRegistryCredentialsFlag: "registry.company.com",
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, "invalid --tf-registry-credentials: credentials must be in the form host=token", err)
+}
+
+func TestExecute_InvalidTFNetrcCredentials(t *testing.T) {
+	c := setup(map[string]interface{}{
+		GHUserFlag:             "user",
+		GHTokenFlag:            "token",
+		RepoAllowlistFlag:      "github.com",
+		TFNetrcCredentialsFlag: "git.company.com=netrc-token",
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, "invalid --tf-netrc-credentials: credentials must be in the form host=user:token", err)
+}
+
+// --tf-registry-credentials can't also set the credentials --tfe-token sets.
+func TestExecute_TFRegistryCredentialsForTFEHostname(t *testing.T) {
+	c := setup(map[string]interface{}{
+		GHUserFlag:                "user",
+		GHTokenFlag:               "token",
+		RepoAllowlistFlag:         "github.com",
+		TFETokenFlag:              "tfe-token",
+		TFRegistryCredentialsFlag: "app.terraform.io=other-token",
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, "--tf-registry-credentials can't contain credentials for app.terraform.io since they're set by --tfe-token", err)
+}
+
+func TestExecute_InvalidWorkspaceGCInterval(t *testing.T) {
+	c := setup(map[string]interface{}{
+		GHUserFlag:              "user",
+		GHTokenFlag:             "token",
+		RepoAllowlistFlag:       "github.com",
+		WorkspaceGCIntervalFlag: "daily",
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, "invalid --workspace-gc-interval: must be a positive duration, ex. 24h", err)
+}
+
+func TestExecute_InvalidLockTTL(t *testing.T) {
+	c := setup(map[string]interface{}{
+		GHUserFlag:        "user",
+		GHTokenFlag:       "token",
+		RepoAllowlistFlag: "github.com",
+		LockTTLFlag:       "-1h",
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, "invalid --lock-ttl: must be a positive duration, ex. 24h", err)
+}
+
 // Can't use both --repo-allowlist and --repo-whitelist
 func TestExecute_BothAllowAndWhitelist(t *testing.T) {
 	c := setup(map[string]interface{}{
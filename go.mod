@@ -2,13 +2,19 @@ module github.com/runatlantis/atlantis
 
 go 1.16
 
-replace google.golang.org/grpc => google.golang.org/grpc v1.29.1
+// The OTLP trace exporter's generated protos (go.opentelemetry.io/proto/otlp
+// v0.9.0) need grpc.SupportPackageIsVersion7, added in v1.32, and
+// google.golang.org/api v0.44.0, used by GCS plan storage, requires v1.36.1.
+// v1.37.1 is the version go.opentelemetry.io/proto/otlp requires.
+replace google.golang.org/grpc => google.golang.org/grpc v1.37.1
 
 require (
+	cloud.google.com/go/storage v1.10.0
 	github.com/Laisky/graphql v1.0.5
 	github.com/Masterminds/sprig/v3 v3.2.2
 	github.com/agext/levenshtein v1.2.3 // indirect
-	github.com/aws/aws-sdk-go v1.31.15 // indirect
+	github.com/alicebob/miniredis/v2 v2.14.3
+	github.com/aws/aws-sdk-go v1.31.15
 	github.com/bradleyfalzon/ghinstallation v1.1.1
 	github.com/briandowns/spinner v0.0.0-20170614154858-48dbb65d7bd5
 	github.com/davecgh/go-spew v1.1.2-0.20180830191138-d8f796af33cc
@@ -20,9 +26,9 @@ require (
 	github.com/go-ozzo/ozzo-validation v0.0.0-20170913164239-85dcd8368eba
 	github.com/go-playground/locales v0.12.1 // indirect
 	github.com/go-playground/universal-translator v0.16.0 // indirect
+	github.com/go-redis/redis/v8 v8.11.4
 	github.com/go-test/deep v1.0.7
 	github.com/google/go-github/v31 v31.0.0
-	github.com/google/uuid v1.1.2-0.20200519141726-cb32006e483f // indirect
 	github.com/gorilla/mux v1.8.0
 	github.com/gorilla/websocket v1.4.2 // indirect
 	github.com/hashicorp/go-getter v1.5.5
@@ -39,7 +45,6 @@ require (
 	github.com/mitchellh/go-homedir v1.1.0
 	github.com/mohae/deepcopy v0.0.0-20170603005431-491d3605edfb
 	github.com/nlopes/slack v0.4.0
-	github.com/onsi/ginkgo v1.14.0 // indirect
 	github.com/petergtz/pegomock v2.9.0+incompatible
 	github.com/pkg/errors v0.9.1
 	github.com/remeh/sizedwaitgroup v1.0.0
@@ -55,10 +60,16 @@ require (
 	github.com/xanzy/go-gitlab v0.50.1
 	github.com/zclconf/go-cty v1.5.1 // indirect
 	go.etcd.io/bbolt v1.3.6
+	go.opentelemetry.io/otel v1.0.1
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp v1.0.1
+	go.opentelemetry.io/otel/sdk v1.0.1
+	go.opentelemetry.io/otel/trace v1.0.1
 	go.uber.org/zap v1.18.1
 	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
+	google.golang.org/api v0.44.0
 	gopkg.in/go-playground/assert.v1 v1.2.1 // indirect
 	gopkg.in/go-playground/validator.v9 v9.31.0
 	gopkg.in/yaml.v2 v2.4.0
+	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
 	gotest.tools v2.2.0+incompatible // indirect
 )
@@ -0,0 +1,1042 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/runatlantis/atlantis/server/core/locking"
+	"github.com/runatlantis/atlantis/server/core/modules"
+	"github.com/runatlantis/atlantis/server/core/terraform"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// atlantisTokenHeader is the header that must contain the --api-secret for
+// requests to the API to be accepted.
+const atlantisTokenHeader = "X-Atlantis-Token"
+
+// APIController handles requests to run plan and apply without a pull request
+// comment, ex. from CI systems or scheduled jobs.
+type APIController struct {
+	APISecret                 []byte
+	Locker                    locking.Locker
+	Logger                    logging.SimpleLogging
+	Parser                    events.EventParsing
+	ProjectCommandBuilder     events.ProjectCommandBuilder
+	ProjectPlanCommandRunner  events.ProjectPlanCommandRunner
+	ProjectApplyCommandRunner events.ProjectApplyCommandRunner
+	RepoAllowlistChecker      *events.RepoAllowlistChecker
+	VCSClient                 vcs.Client
+	Drainer                   *events.Drainer
+	DB                        locking.Backend
+	WorkingDir                events.WorkingDir
+	// GlobalCfgReloader is nil unless --repo-config is set.
+	GlobalCfgReloader *events.GlobalCfgReloader
+	Warmer            *terraform.Warmer
+	TerraformClient   *terraform.DefaultClient
+	RepoRegistry      *events.RepoRegistry
+	// CommandRunner holds API runs to the restrictions comment commands are
+	// subject to, ex. allowed apply teams, and audits them.
+	CommandRunner *events.DefaultCommandRunner
+}
+
+// APIRequest is the JSON body accepted by the API endpoints.
+type APIRequest struct {
+	// Repository is the full name of the repo, ex. runatlantis/atlantis.
+	Repository string `json:"repository"`
+	// Ref is the branch, tag or commit to run against.
+	Ref string `json:"ref"`
+	// Type is the VCS host type of the repo, ex. Github or Gitlab.
+	Type string `json:"type"`
+	// PR is an optional pull request number that the run is associated
+	// with. It's used to scope locks and working directories.
+	PR int `json:"pr"`
+	// Projects are the names of projects in the repo's atlantis.yaml to run.
+	Projects []string `json:"projects"`
+	// Paths are the directories and workspaces to run.
+	Paths []APIRequestPath `json:"paths"`
+}
+
+// APIRequestPath is a directory and workspace to run a command in.
+type APIRequestPath struct {
+	Directory string `json:"directory"`
+	Workspace string `json:"workspace"`
+}
+
+// APIResponse is the JSON body returned by the API endpoints.
+type APIResponse struct {
+	ProjectResults []APIProjectResult `json:"project_results"`
+}
+
+// APIProjectResult is the result of running a command for a single project.
+type APIProjectResult struct {
+	Directory   string `json:"directory"`
+	Workspace   string `json:"workspace"`
+	ProjectName string `json:"project_name,omitempty"`
+	// Output is the terraform output if the command succeeded.
+	Output string `json:"output,omitempty"`
+	// Failure is set if the command couldn't be run, ex. because of a lock.
+	Failure string `json:"failure,omitempty"`
+	// Error is set if the command errored.
+	Error string `json:"error,omitempty"`
+}
+
+// APIHistoryResponse is the JSON body returned by the history endpoint.
+type APIHistoryResponse struct {
+	History []APIHistoryEntry `json:"history"`
+}
+
+// APIHistoryEntry is the output of running plan, policy check or apply for a
+// single project at a specific commit.
+type APIHistoryEntry struct {
+	Repository  string    `json:"repository"`
+	PR          int       `json:"pr"`
+	PRURL       string    `json:"pr_url"`
+	HeadCommit  string    `json:"head_commit"`
+	Directory   string    `json:"directory"`
+	Workspace   string    `json:"workspace"`
+	ProjectName string    `json:"project_name,omitempty"`
+	Command     string    `json:"command"`
+	Status      string    `json:"status"`
+	Output      string    `json:"output"`
+	Time        time.Time `json:"time"`
+}
+
+// APIPullsResponse is the JSON response of the GET /api/pulls endpoint.
+type APIPullsResponse struct {
+	Pulls []APIPull `json:"pulls"`
+}
+
+// APIPull is an open pull request and the status of each of its projects.
+type APIPull struct {
+	Repository string           `json:"repository"`
+	PR         int              `json:"pr"`
+	PRURL      string           `json:"pr_url"`
+	Author     string           `json:"author"`
+	HeadCommit string           `json:"head_commit"`
+	Projects   []APIPullProject `json:"projects"`
+	// ApplyCompleteness is how much of the pull request has been applied. It's
+	// what automerge decides whether to merge the pull request from.
+	ApplyCompleteness APIApplyCompleteness `json:"apply_completeness"`
+}
+
+// APIApplyCompleteness is how much of an open pull request has been applied.
+type APIApplyCompleteness struct {
+	Complete bool   `json:"complete"`
+	Applied  int    `json:"applied"`
+	Total    int    `json:"total"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// APIPullProject is the status of a project in an open pull request.
+type APIPullProject struct {
+	Directory   string `json:"directory"`
+	Workspace   string `json:"workspace"`
+	ProjectName string `json:"project_name,omitempty"`
+	Status      string `json:"status"`
+}
+
+// APILocksResponse is the JSON response of the GET /api/locks endpoint.
+type APILocksResponse struct {
+	Locks []APILock `json:"locks"`
+}
+
+// APILock is a lock held by a pull request on a project.
+type APILock struct {
+	ID         string    `json:"id"`
+	Repository string    `json:"repository"`
+	Path       string    `json:"path"`
+	Workspace  string    `json:"workspace"`
+	PR         int       `json:"pr"`
+	PRURL      string    `json:"pr_url"`
+	User       string    `json:"user"`
+	Time       time.Time `json:"time"`
+}
+
+// APIAuditResponse is the JSON body returned by the audit endpoint.
+type APIAuditResponse struct {
+	Events []models.AuditEvent `json:"events"`
+}
+
+// APIDepGraphResponse is the JSON body returned by the depgraph endpoint.
+type APIDepGraphResponse struct {
+	Repository string `json:"repository"`
+	PR         int    `json:"pr"`
+	// Modules maps the dir of each local module to how it's used.
+	Modules map[string]APIDepGraphModule `json:"modules"`
+}
+
+// APIDepGraphModule describes how a local module is used.
+type APIDepGraphModule struct {
+	// Callers are the dirs that call the module directly.
+	Callers []string `json:"callers"`
+	// Projects are the dirs of the root modules that call the module,
+	// directly or through other modules. They're planned when the module is
+	// modified.
+	Projects []string `json:"projects"`
+}
+
+// APITerraformVersionsResponse is the JSON response of the GET
+// /api/terraform-versions endpoint.
+type APITerraformVersionsResponse struct {
+	Distribution string `json:"distribution"`
+	// DefaultVersion is empty if Distribution isn't the default distribution.
+	DefaultVersion string `json:"default_version,omitempty"`
+	// Installed are the versions that don't need to be downloaded, newest
+	// first.
+	Installed []string `json:"installed"`
+	// Available are the released versions that aren't installed, newest
+	// first.
+	Available []string `json:"available"`
+}
+
+// APIInstallTerraformVersionRequest is the JSON body accepted by the POST
+// /api/terraform-versions endpoint.
+type APIInstallTerraformVersionRequest struct {
+	// Distribution is the distribution to install, ex. opentofu. If it's
+	// empty, the default distribution is used.
+	Distribution string `json:"distribution"`
+	Version      string `json:"version"`
+}
+
+// APIRepoRequest is the JSON body accepted by the POST /api/repos endpoint.
+type APIRepoRequest struct {
+	// Repository is the full name of the repo, ex. runatlantis/atlantis.
+	Repository string `json:"repository"`
+	// Type is the VCS host type of the repo, ex. Github or Gitlab.
+	Type string `json:"type"`
+	// PlanOnly, NoAutoplan and MaxParallel are the restrictions that can be
+	// set on --repo-allowlist entries.
+	PlanOnly    bool `json:"plan_only"`
+	NoAutoplan  bool `json:"no_autoplan"`
+	MaxParallel int  `json:"max_parallel"`
+	// CreateWebhook is true if Atlantis should create the webhook that sends
+	// the repo's events to it.
+	CreateWebhook bool `json:"create_webhook"`
+}
+
+// APIReposResponse is the JSON response of the GET /api/repos endpoint.
+type APIReposResponse struct {
+	Repos []APIRepo `json:"repos"`
+}
+
+// APIRepo is a repo that was registered through the API.
+type APIRepo struct {
+	ID          string    `json:"id"`
+	Repository  string    `json:"repository"`
+	Hostname    string    `json:"hostname"`
+	PlanOnly    bool      `json:"plan_only"`
+	NoAutoplan  bool      `json:"no_autoplan"`
+	MaxParallel int       `json:"max_parallel"`
+	WebhookID   int64     `json:"webhook_id,omitempty"`
+	Time        time.Time `json:"time"`
+}
+
+// Plan is the POST /api/plan route. It runs plan for the requested projects
+// and responds with the results.
+func (a *APIController) Plan(w http.ResponseWriter, r *http.Request) {
+	request, ctx, code, err := a.apiParseAndValidate(r)
+	if err != nil {
+		a.apiReportError(w, code, err)
+		return
+	}
+	opDone, ok := a.startOp(ctx, models.PlanCommand)
+	if !ok {
+		a.apiReportError(w, http.StatusServiceUnavailable, fmt.Errorf("atlantis server is shutting down, please try again later"))
+		return
+	}
+	defer opDone()
+	cmdDone, err := a.CommandRunner.StartAPICommand(ctx, &events.CommentCommand{Name: models.PlanCommand})
+	if err != nil {
+		a.apiReportError(w, http.StatusForbidden, err)
+		return
+	}
+	defer cmdDone()
+	var results []models.ProjectResult
+	defer func() { a.unlock(results) }()
+
+	results, err = a.apiPlan(request, ctx)
+	if err != nil {
+		ctx.Result = &events.CommandResult{Error: err}
+		a.apiReportError(w, http.StatusInternalServerError, err)
+		return
+	}
+	ctx.Result = &events.CommandResult{ProjectResults: results}
+	a.respondWithResults(w, results)
+}
+
+// Apply is the POST /api/apply route. It runs plan and then apply for the
+// requested projects and responds with the apply results.
+func (a *APIController) Apply(w http.ResponseWriter, r *http.Request) {
+	request, ctx, code, err := a.apiParseAndValidate(r)
+	if err != nil {
+		a.apiReportError(w, code, err)
+		return
+	}
+	opDone, ok := a.startOp(ctx, models.ApplyCommand)
+	if !ok {
+		a.apiReportError(w, http.StatusServiceUnavailable, fmt.Errorf("atlantis server is shutting down, please try again later"))
+		return
+	}
+	defer opDone()
+	cmdDone, err := a.CommandRunner.StartAPICommand(ctx, &events.CommentCommand{Name: models.ApplyCommand})
+	if err != nil {
+		a.apiReportError(w, http.StatusForbidden, err)
+		return
+	}
+	defer cmdDone()
+	var planResults []models.ProjectResult
+	defer func() { a.unlock(planResults) }()
+
+	// We must first make the plan for all projects.
+	planResults, err = a.apiPlan(request, ctx)
+	if err != nil {
+		ctx.Result = &events.CommandResult{Error: err}
+		a.apiReportError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if hasErrors(planResults) {
+		ctx.Result = &events.CommandResult{ProjectResults: planResults}
+		a.respondWithResults(w, planResults)
+		return
+	}
+
+	// We can now run the apply.
+	results, err := a.apiApply(request, ctx)
+	if err != nil {
+		ctx.Result = &events.CommandResult{Error: err}
+		a.apiReportError(w, http.StatusInternalServerError, err)
+		return
+	}
+	ctx.Result = &events.CommandResult{ProjectResults: results}
+	a.respondWithResults(w, results)
+}
+
+// History is the GET /api/history route. It responds with the saved plan,
+// policy check and apply outputs for the pull request given by the repository
+// and pr query parameters. If pr isn't set, the history for every pull
+// request in the repository is returned.
+func (a *APIController) History(w http.ResponseWriter, r *http.Request) {
+	if code, err := a.apiValidateSecret(r); err != nil {
+		a.apiReportError(w, code, err)
+		return
+	}
+	repo := r.URL.Query().Get("repository")
+	if repo == "" {
+		a.apiReportError(w, http.StatusBadRequest, fmt.Errorf("missing required query parameter repository"))
+		return
+	}
+	var pullNum int
+	if pr := r.URL.Query().Get("pr"); pr != "" {
+		var err error
+		if pullNum, err = strconv.Atoi(pr); err != nil {
+			a.apiReportError(w, http.StatusBadRequest, fmt.Errorf("invalid pr %q: %s", pr, err))
+			return
+		}
+	}
+
+	history, err := a.DB.GetProjectHistory(repo, pullNum)
+	if err != nil {
+		a.apiReportError(w, http.StatusInternalServerError, err)
+		return
+	}
+	response := APIHistoryResponse{History: []APIHistoryEntry{}}
+	for _, h := range history {
+		response.History = append(response.History, APIHistoryEntry{
+			Repository:  h.Pull.BaseRepo.FullName,
+			PR:          h.Pull.Num,
+			PRURL:       h.Pull.URL,
+			HeadCommit:  h.Pull.HeadCommit,
+			Directory:   h.RepoRelDir,
+			Workspace:   h.Workspace,
+			ProjectName: h.ProjectName,
+			Command:     h.Command.String(),
+			Status:      h.Status.String(),
+			Output:      h.Output,
+			Time:        h.Time,
+		})
+	}
+	data, err := json.Marshal(response)
+	if err != nil {
+		a.apiReportError(w, http.StatusInternalServerError, err)
+		return
+	}
+	a.respond(w, logging.Info, http.StatusOK, string(data))
+}
+
+// PullResults is the GET /api/pulls/{id}/results route. It responds with the
+// latest plan and policy check results of pull request id in the repository
+// given by the repository query parameter, formatted as SARIF or JUnit XML
+// depending on the format query parameter.
+func (a *APIController) PullResults(w http.ResponseWriter, r *http.Request) {
+	if code, err := a.apiValidateSecret(r); err != nil {
+		a.apiReportError(w, code, err)
+		return
+	}
+	repo := r.URL.Query().Get("repository")
+	if repo == "" {
+		a.apiReportError(w, http.StatusBadRequest, fmt.Errorf("missing required query parameter repository"))
+		return
+	}
+	id := mux.Vars(r)["id"]
+	pullNum, err := strconv.Atoi(id)
+	if err != nil || pullNum <= 0 {
+		a.apiReportError(w, http.StatusBadRequest, fmt.Errorf("invalid pull request number %q", id))
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = events.SARIFExportFormat
+	}
+	var export func([]models.ProjectHistory) ([]byte, error)
+	var contentType string
+	switch format {
+	case events.SARIFExportFormat:
+		export, contentType = events.ExportSARIF, "application/sarif+json"
+	case events.JUnitExportFormat:
+		export, contentType = events.ExportJUnit, "application/xml"
+	default:
+		a.apiReportError(w, http.StatusBadRequest, fmt.Errorf("invalid format %q, must be %q or %q", format, events.SARIFExportFormat, events.JUnitExportFormat))
+		return
+	}
+
+	history, err := a.DB.GetProjectHistory(repo, pullNum)
+	if err != nil {
+		a.apiReportError(w, http.StatusInternalServerError, err)
+		return
+	}
+	data, err := export(history)
+	if err != nil {
+		a.apiReportError(w, http.StatusInternalServerError, err)
+		return
+	}
+	a.Logger.Info("api response %d: exported %s results for %s#%d", http.StatusOK, format, repo, pullNum)
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(data) // nolint: errcheck
+}
+
+// Pulls is the GET /api/pulls route. It responds with every open pull request
+// Atlantis has run commands on and the plan and apply status of its projects.
+func (a *APIController) Pulls(w http.ResponseWriter, r *http.Request) {
+	if code, err := a.apiValidateSecret(r); err != nil {
+		a.apiReportError(w, code, err)
+		return
+	}
+	statuses, err := a.DB.ListPullStatuses()
+	if err != nil {
+		a.apiReportError(w, http.StatusInternalServerError, err)
+		return
+	}
+	response := APIPullsResponse{Pulls: []APIPull{}}
+	for _, s := range statuses {
+		pull := APIPull{
+			Repository: s.Pull.BaseRepo.FullName,
+			PR:         s.Pull.Num,
+			PRURL:      s.Pull.URL,
+			Author:     s.Pull.Author,
+			HeadCommit: s.Pull.HeadCommit,
+			Projects:   []APIPullProject{},
+		}
+		for _, p := range s.Projects {
+			pull.Projects = append(pull.Projects, APIPullProject{
+				Directory:   p.RepoRelDir,
+				Workspace:   p.Workspace,
+				ProjectName: p.ProjectName,
+				Status:      p.Status.String(),
+			})
+		}
+		completeness := s.ApplyCompleteness()
+		pull.ApplyCompleteness = APIApplyCompleteness{
+			Complete: completeness.Complete,
+			Applied:  completeness.Applied,
+			Total:    completeness.Total,
+			Reason:   completeness.Reason,
+		}
+		response.Pulls = append(response.Pulls, pull)
+	}
+	data, err := json.Marshal(response)
+	if err != nil {
+		a.apiReportError(w, http.StatusInternalServerError, err)
+		return
+	}
+	a.respond(w, logging.Info, http.StatusOK, string(data))
+}
+
+// Locks is the GET /api/locks route. It responds with every project lock,
+// ordered by repository, path and workspace.
+func (a *APIController) Locks(w http.ResponseWriter, r *http.Request) {
+	if code, err := a.apiValidateSecret(r); err != nil {
+		a.apiReportError(w, code, err)
+		return
+	}
+	locks, err := a.Locker.List()
+	if err != nil {
+		a.apiReportError(w, http.StatusInternalServerError, err)
+		return
+	}
+	response := APILocksResponse{Locks: []APILock{}}
+	for id, l := range locks {
+		response.Locks = append(response.Locks, APILock{
+			ID:         id,
+			Repository: l.Project.RepoFullName,
+			Path:       l.Project.Path,
+			Workspace:  l.Workspace,
+			PR:         l.Pull.Num,
+			PRURL:      l.Pull.URL,
+			User:       l.User.Username,
+			Time:       l.Time,
+		})
+	}
+	sort.Slice(response.Locks, func(i, j int) bool {
+		return response.Locks[i].ID < response.Locks[j].ID
+	})
+	data, err := json.Marshal(response)
+	if err != nil {
+		a.apiReportError(w, http.StatusInternalServerError, err)
+		return
+	}
+	a.respond(w, logging.Info, http.StatusOK, string(data))
+}
+
+// Reload is the POST /api/reload route. It reloads the server-side repo
+// config from --repo-config. If the file isn't valid the current config is
+// kept.
+func (a *APIController) Reload(w http.ResponseWriter, r *http.Request) {
+	if code, err := a.apiValidateSecret(r); err != nil {
+		a.apiReportError(w, code, err)
+		return
+	}
+	if a.GlobalCfgReloader == nil {
+		a.apiReportError(w, http.StatusBadRequest, fmt.Errorf("server-side repo config can only be reloaded when it's set with --repo-config"))
+		return
+	}
+	if err := a.GlobalCfgReloader.Reload(); err != nil {
+		a.apiReportError(w, http.StatusBadRequest, err)
+		return
+	}
+	a.respond(w, logging.Info, http.StatusOK, `{"reloaded":true}`)
+}
+
+// Warm is the POST /api/warm route. It downloads the default terraform
+// version and the versions and providers set by --warm-tf-versions and
+// --warm-providers if they haven't been downloaded yet. It responds once
+// they're all downloaded.
+func (a *APIController) Warm(w http.ResponseWriter, r *http.Request) {
+	if code, err := a.apiValidateSecret(r); err != nil {
+		a.apiReportError(w, code, err)
+		return
+	}
+	if err := a.Warmer.Warm(r.Context()); err != nil {
+		a.apiReportError(w, http.StatusInternalServerError, err)
+		return
+	}
+	a.respond(w, logging.Info, http.StatusOK, `{"warmed":true}`)
+}
+
+// TerraformVersions is the GET /api/terraform-versions route. It responds
+// with the versions of the distribution given by the distribution query
+// parameter, or the default distribution, that are installed and that can be
+// installed.
+func (a *APIController) TerraformVersions(w http.ResponseWriter, r *http.Request) {
+	if code, err := a.apiValidateSecret(r); err != nil {
+		a.apiReportError(w, code, err)
+		return
+	}
+	d := r.URL.Query().Get("distribution")
+	if err := validateTFDistribution(d); err != nil {
+		a.apiReportError(w, http.StatusBadRequest, err)
+		return
+	}
+	versions, err := a.TerraformClient.ListVersions(d)
+	if err != nil {
+		a.apiReportError(w, http.StatusInternalServerError, err)
+		return
+	}
+	response := APITerraformVersionsResponse{
+		Distribution: versions.Distribution,
+		Installed:    versionStrings(versions.Installed),
+		Available:    versionStrings(versions.Available),
+	}
+	if versions.Default != nil {
+		response.DefaultVersion = versions.Default.String()
+	}
+	data, err := json.Marshal(response)
+	if err != nil {
+		a.apiReportError(w, http.StatusInternalServerError, err)
+		return
+	}
+	a.respond(w, logging.Info, http.StatusOK, string(data))
+}
+
+// InstallTerraformVersion is the POST /api/terraform-versions route. It
+// downloads the requested version into the data dir, if it isn't installed
+// already, and responds once it's installed.
+func (a *APIController) InstallTerraformVersion(w http.ResponseWriter, r *http.Request) {
+	if code, err := a.apiValidateSecret(r); err != nil {
+		a.apiReportError(w, code, err)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		a.apiReportError(w, http.StatusBadRequest, fmt.Errorf("failed to read request: %s", err))
+		return
+	}
+	var request APIInstallTerraformVersionRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		a.apiReportError(w, http.StatusBadRequest, fmt.Errorf("failed to parse request: %s", err))
+		return
+	}
+	v, err := parseTFInstallRequest(request.Distribution, request.Version)
+	if err != nil {
+		a.apiReportError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := a.TerraformClient.EnsureVersion(a.Logger, request.Distribution, v); err != nil {
+		a.apiReportError(w, http.StatusInternalServerError, err)
+		return
+	}
+	a.respond(w, logging.Info, http.StatusOK, `{"installed":true}`)
+}
+
+// Audit is the GET /api/audit route. It responds with every event in the
+// audit log, oldest first. The audit log is only written to if
+// --enable-audit-log is set.
+func (a *APIController) Audit(w http.ResponseWriter, r *http.Request) {
+	if code, err := a.apiValidateSecret(r); err != nil {
+		a.apiReportError(w, code, err)
+		return
+	}
+	events, err := a.DB.ListAuditEvents()
+	if err != nil {
+		a.apiReportError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if events == nil {
+		events = []models.AuditEvent{}
+	}
+	data, err := json.Marshal(APIAuditResponse{Events: events})
+	if err != nil {
+		a.apiReportError(w, http.StatusInternalServerError, err)
+		return
+	}
+	a.respond(w, logging.Info, http.StatusOK, string(data))
+}
+
+// DepGraph is the GET /api/depgraph route. It responds with the local module
+// dependency graph of the pull request's cloned repo. It's meant for
+// debugging which projects will be autoplanned when a module changes.
+func (a *APIController) DepGraph(w http.ResponseWriter, r *http.Request) {
+	if code, err := a.apiValidateSecret(r); err != nil {
+		a.apiReportError(w, code, err)
+		return
+	}
+	repo := r.URL.Query().Get("repository")
+	if repo == "" {
+		a.apiReportError(w, http.StatusBadRequest, fmt.Errorf("missing required query parameter repository"))
+		return
+	}
+	// The repository is part of the path of its working dir.
+	if err := models.ValidateRepoFullName(repo); err != nil {
+		a.apiReportError(w, http.StatusBadRequest, err)
+		return
+	}
+	pr := r.URL.Query().Get("pr")
+	if pr == "" {
+		a.apiReportError(w, http.StatusBadRequest, fmt.Errorf("missing required query parameter pr"))
+		return
+	}
+	pullNum, err := strconv.Atoi(pr)
+	if err != nil {
+		a.apiReportError(w, http.StatusBadRequest, fmt.Errorf("invalid pr %q: %s", pr, err))
+		return
+	}
+
+	baseRepo := models.Repo{FullName: repo}
+	repoDir, err := a.WorkingDir.GetWorkingDir(baseRepo, models.PullRequest{BaseRepo: baseRepo, Num: pullNum}, events.DefaultWorkspace)
+	if err != nil {
+		a.apiReportError(w, http.StatusNotFound, fmt.Errorf("repository %s pull request %d has not been cloned: %s", repo, pullNum, err))
+		return
+	}
+	graph, err := modules.BuildDependencyGraph(a.Logger, repoDir)
+	if err != nil {
+		a.apiReportError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	response := APIDepGraphResponse{
+		Repository: repo,
+		PR:         pullNum,
+		Modules:    make(map[string]APIDepGraphModule),
+	}
+	for moduleDir, callers := range graph.Callers {
+		projects := graph.Dependents(moduleDir)
+		if projects == nil {
+			projects = []string{}
+		}
+		response.Modules[moduleDir] = APIDepGraphModule{
+			Callers:  callers,
+			Projects: projects,
+		}
+	}
+	data, err := json.Marshal(response)
+	if err != nil {
+		a.apiReportError(w, http.StatusInternalServerError, err)
+		return
+	}
+	a.respond(w, logging.Info, http.StatusOK, string(data))
+}
+
+// RegisterRepo is the POST /api/repos route. It allowlists the repo in the
+// request with its restrictions and, if create_webhook is set, creates the
+// webhook that sends its events to Atlantis. The repo stays registered when
+// Atlantis restarts.
+func (a *APIController) RegisterRepo(w http.ResponseWriter, r *http.Request) {
+	if code, err := a.apiValidateSecret(r); err != nil {
+		a.apiReportError(w, code, err)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		a.apiReportError(w, http.StatusBadRequest, fmt.Errorf("failed to read request: %s", err))
+		return
+	}
+	var request APIRepoRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		a.apiReportError(w, http.StatusBadRequest, fmt.Errorf("failed to parse request: %s", err))
+		return
+	}
+	if request.MaxParallel < 0 {
+		a.apiReportError(w, http.StatusBadRequest, fmt.Errorf("max_parallel must not be negative"))
+		return
+	}
+	repo, code, err := a.apiParseRepo(request.Repository, request.Type)
+	if err != nil {
+		a.apiReportError(w, code, err)
+		return
+	}
+	registered, err := a.RepoRegistry.Register(repo, events.RepoRestrictions{
+		PlanOnly:    request.PlanOnly,
+		NoAutoplan:  request.NoAutoplan,
+		MaxParallel: request.MaxParallel,
+	}, request.CreateWebhook)
+	if err != nil {
+		a.apiReportError(w, http.StatusInternalServerError, err)
+		return
+	}
+	data, err := json.Marshal(newAPIRepo(registered))
+	if err != nil {
+		a.apiReportError(w, http.StatusInternalServerError, err)
+		return
+	}
+	a.respond(w, logging.Info, http.StatusOK, string(data))
+}
+
+// Repos is the GET /api/repos route. It responds with the repos registered
+// through the API, ordered by ID. Repos allowlisted by --repo-allowlist
+// aren't included.
+func (a *APIController) Repos(w http.ResponseWriter, r *http.Request) {
+	if code, err := a.apiValidateSecret(r); err != nil {
+		a.apiReportError(w, code, err)
+		return
+	}
+	repos, err := a.RepoRegistry.List()
+	if err != nil {
+		a.apiReportError(w, http.StatusInternalServerError, err)
+		return
+	}
+	response := APIReposResponse{Repos: []APIRepo{}}
+	for _, repo := range repos {
+		response.Repos = append(response.Repos, newAPIRepo(repo))
+	}
+	data, err := json.Marshal(response)
+	if err != nil {
+		a.apiReportError(w, http.StatusInternalServerError, err)
+		return
+	}
+	a.respond(w, logging.Info, http.StatusOK, string(data))
+}
+
+// UnregisterRepo is the DELETE /api/repos route. It removes the repo given
+// by the repository and type query parameters from the allowlist and deletes
+// the webhook that was created when it was registered.
+func (a *APIController) UnregisterRepo(w http.ResponseWriter, r *http.Request) {
+	if code, err := a.apiValidateSecret(r); err != nil {
+		a.apiReportError(w, code, err)
+		return
+	}
+	repo, code, err := a.apiParseRepo(r.URL.Query().Get("repository"), r.URL.Query().Get("type"))
+	if err != nil {
+		a.apiReportError(w, code, err)
+		return
+	}
+	ok, err := a.RepoRegistry.Unregister(repo)
+	if err != nil {
+		a.apiReportError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !ok {
+		a.apiReportError(w, http.StatusNotFound, fmt.Errorf("repo %s is not registered", repo.FullName))
+		return
+	}
+	a.respond(w, logging.Info, http.StatusOK, `{"unregistered":true}`)
+}
+
+// apiParseRepo returns the repo with fullName on the VCS host of vcsType.
+func (a *APIController) apiParseRepo(fullName string, vcsType string) (models.Repo, int, error) {
+	if fullName == "" || vcsType == "" {
+		return models.Repo{}, http.StatusBadRequest, fmt.Errorf("repository and type are required")
+	}
+	vcsHostType, err := models.NewVCSHostType(vcsType)
+	if err != nil {
+		return models.Repo{}, http.StatusBadRequest, err
+	}
+	cloneURL, err := a.VCSClient.GetCloneURL(vcsHostType, fullName)
+	if err != nil {
+		return models.Repo{}, http.StatusInternalServerError, err
+	}
+	repo, err := a.Parser.ParseAPIPlanRequest(vcsHostType, fullName, cloneURL)
+	if err != nil {
+		return models.Repo{}, http.StatusBadRequest, fmt.Errorf("failed to parse request: %s", err)
+	}
+	return repo, http.StatusOK, nil
+}
+
+func newAPIRepo(repo models.RegisteredRepo) APIRepo {
+	return APIRepo{
+		ID:          repo.ID(),
+		Repository:  repo.FullName,
+		Hostname:    repo.Hostname,
+		PlanOnly:    repo.PlanOnly,
+		NoAutoplan:  repo.NoAutoplan,
+		MaxParallel: repo.MaxParallel,
+		WebhookID:   repo.WebhookID,
+		Time:        repo.Time,
+	}
+}
+
+func (a *APIController) apiPlan(request *APIRequest, ctx *events.CommandContext) ([]models.ProjectResult, error) {
+	cmds, err := a.getCommands(request, ctx, models.PlanCommand, a.ProjectCommandBuilder.BuildPlanCommands)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []models.ProjectResult
+	for _, cmd := range cmds {
+		results = append(results, a.ProjectPlanCommandRunner.Plan(cmd))
+	}
+	return results, nil
+}
+
+func (a *APIController) apiApply(request *APIRequest, ctx *events.CommandContext) ([]models.ProjectResult, error) {
+	cmds, err := a.getCommands(request, ctx, models.ApplyCommand, a.ProjectCommandBuilder.BuildApplyCommands)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []models.ProjectResult
+	for _, cmd := range cmds {
+		results = append(results, a.ProjectApplyCommandRunner.Apply(cmd))
+	}
+	return results, nil
+}
+
+// getCommands builds the project commands for every project and path in
+// request as if they'd been specified in a comment.
+func (a *APIController) getCommands(request *APIRequest, ctx *events.CommandContext, name models.CommandName, build func(*events.CommandContext, *events.CommentCommand) ([]models.ProjectCommandContext, error)) ([]models.ProjectCommandContext, error) {
+	var comments []*events.CommentCommand
+	for _, project := range request.Projects {
+		comments = append(comments, &events.CommentCommand{
+			Name:        name,
+			ProjectName: project,
+		})
+	}
+	for _, path := range request.Paths {
+		comments = append(comments, &events.CommentCommand{
+			Name:       name,
+			RepoRelDir: strings.TrimRight(path.Directory, "/"),
+			Workspace:  path.Workspace,
+		})
+	}
+
+	var cmds []models.ProjectCommandContext
+	for _, comment := range comments {
+		projectCmds, err := build(ctx, comment)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build command: %s", err)
+		}
+		cmds = append(cmds, projectCmds...)
+	}
+	return cmds, nil
+}
+
+func (a *APIController) apiValidateSecret(r *http.Request) (int, error) {
+	if len(a.APISecret) == 0 {
+		return http.StatusBadRequest, fmt.Errorf("ignoring request since API is disabled")
+	}
+
+	// Validate the secret token. It can be sent as a bearer token so requests
+	// can use the same Authorization header as other APIs.
+	secret := r.Header.Get(atlantisTokenHeader)
+	if auth := r.Header.Get("Authorization"); secret == "" && strings.HasPrefix(auth, "Bearer ") {
+		secret = strings.TrimPrefix(auth, "Bearer ")
+	}
+	if subtle.ConstantTimeCompare([]byte(secret), a.APISecret) != 1 {
+		return http.StatusUnauthorized, fmt.Errorf("header %s or Authorization bearer token did not match expected secret", atlantisTokenHeader)
+	}
+	return http.StatusOK, nil
+}
+
+func (a *APIController) apiParseAndValidate(r *http.Request) (*APIRequest, *events.CommandContext, int, error) {
+	if code, err := a.apiValidateSecret(r); err != nil {
+		return nil, nil, code, err
+	}
+
+	// Parse the JSON payload.
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, nil, http.StatusBadRequest, fmt.Errorf("failed to read request: %s", err)
+	}
+	var request APIRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		return nil, nil, http.StatusBadRequest, fmt.Errorf("failed to parse request: %s", err)
+	}
+	if request.Repository == "" || request.Ref == "" || request.Type == "" {
+		return nil, nil, http.StatusBadRequest, fmt.Errorf("request %q is missing fields: repository, ref and type are required", string(body))
+	}
+	if len(request.Projects) == 0 && len(request.Paths) == 0 {
+		return nil, nil, http.StatusBadRequest, fmt.Errorf("request %q must specify at least one of projects or paths", string(body))
+	}
+
+	vcsHostType, err := models.NewVCSHostType(request.Type)
+	if err != nil {
+		return nil, nil, http.StatusBadRequest, err
+	}
+	cloneURL, err := a.VCSClient.GetCloneURL(vcsHostType, request.Repository)
+	if err != nil {
+		return nil, nil, http.StatusInternalServerError, err
+	}
+	baseRepo, err := a.Parser.ParseAPIPlanRequest(vcsHostType, request.Repository, cloneURL)
+	if err != nil {
+		return nil, nil, http.StatusBadRequest, fmt.Errorf("failed to parse request: %s", err)
+	}
+
+	// Check if the repo is allowlisted.
+	if !a.RepoAllowlistChecker.IsAllowlisted(baseRepo.FullName, baseRepo.VCSHost.Hostname) {
+		return nil, nil, http.StatusForbidden, fmt.Errorf("repo not allowlisted")
+	}
+
+	return &request, &events.CommandContext{
+		HeadRepo: baseRepo,
+		Pull: models.PullRequest{
+			Num:        request.PR,
+			BaseBranch: request.Ref,
+			HeadBranch: request.Ref,
+			HeadCommit: request.Ref,
+			BaseRepo:   baseRepo,
+			State:      models.OpenPullState,
+		},
+		Log: a.Logger,
+	}, http.StatusOK, nil
+}
+
+// startOp starts tracking an API run with the drainer. It returns false if
+// Atlantis is shutting down.
+func (a *APIController) startOp(ctx *events.CommandContext, cmdName models.CommandName) (func(), bool) {
+	return a.Drainer.StartOperation(events.Operation{
+		Repo:    ctx.Pull.BaseRepo.FullName,
+		PullNum: ctx.Pull.Num,
+		Command: cmdName.String(),
+	})
+}
+
+// unlock releases the locks created by an API run's plans since there is no
+// pull request that would release them once it's closed. Locks the pull
+// request already held, ex. from comment commands, are kept.
+func (a *APIController) unlock(results []models.ProjectResult) {
+	for _, r := range results {
+		if r.CreatedLock == "" {
+			continue
+		}
+		if _, err := a.Locker.Unlock(r.CreatedLock); err != nil {
+			a.Logger.Err("unable to release lock %s: %s", r.CreatedLock, err)
+		}
+	}
+}
+
+func (a *APIController) respondWithResults(w http.ResponseWriter, results []models.ProjectResult) {
+	response := APIResponse{ProjectResults: []APIProjectResult{}}
+	for _, r := range results {
+		res := APIProjectResult{
+			Directory:   r.RepoRelDir,
+			Workspace:   r.Workspace,
+			ProjectName: r.ProjectName,
+			Failure:     r.Failure,
+		}
+		if r.Error != nil {
+			res.Error = r.Error.Error()
+		}
+		if r.PlanSuccess != nil {
+			res.Output = r.PlanSuccess.TerraformOutput
+		}
+		if r.ApplySuccess != "" {
+			res.Output = r.ApplySuccess
+		}
+		response.ProjectResults = append(response.ProjectResults, res)
+	}
+
+	code := http.StatusOK
+	if hasErrors(results) {
+		code = http.StatusInternalServerError
+	}
+	data, err := json.Marshal(response)
+	if err != nil {
+		a.apiReportError(w, http.StatusInternalServerError, err)
+		return
+	}
+	a.respond(w, logging.Info, code, string(data))
+}
+
+func (a *APIController) apiReportError(w http.ResponseWriter, code int, err error) {
+	a.Logger.Warn("api response %d: %s", code, err)
+	response, _ := json.Marshal(map[string]string{
+		"error": err.Error(),
+	})
+	a.write(w, code, string(response))
+}
+
+// respond is a helper function to respond and log the response. lvl is the log
+// level to log a summary of the response at, code is the HTTP response code.
+// The response itself can include terraform output and other large or
+// sensitive data so it's only logged at debug.
+func (a *APIController) respond(w http.ResponseWriter, lvl logging.LogLevel, responseCode int, response string) {
+	a.Logger.Log(lvl, "api response %d: %d bytes", responseCode, len(response))
+	a.write(w, responseCode, response)
+}
+
+// write writes response as the JSON body of the response.
+func (a *APIController) write(w http.ResponseWriter, responseCode int, response string) {
+	a.Logger.Debug("api response body: %s", response)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(responseCode)
+	fmt.Fprintln(w, response)
+}
+
+func hasErrors(results []models.ProjectResult) bool {
+	for _, r := range results {
+		if r.Error != nil || r.Failure != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,777 @@
+package controllers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/controllers"
+	"github.com/runatlantis/atlantis/server/core/db"
+	lockingmocks "github.com/runatlantis/atlantis/server/core/locking/mocks"
+	"github.com/runatlantis/atlantis/server/core/terraform"
+	tfmocks "github.com/runatlantis/atlantis/server/core/terraform/mocks"
+	tfmatchers "github.com/runatlantis/atlantis/server/core/terraform/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events"
+	. "github.com/runatlantis/atlantis/server/events/mocks"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/models"
+	vcsmocks "github.com/runatlantis/atlantis/server/events/vcs/mocks"
+	vcsmatchers "github.com/runatlantis/atlantis/server/events/vcs/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/yaml"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+const atlantisTokenHeader = "X-Atlantis-Token"
+const atlantisToken = "token"
+
+func TestAPIController_Plan(t *testing.T) {
+	ac, projectCommandBuilder, projectCommandRunner := setup(t)
+	body, _ := json.Marshal(controllers.APIRequest{
+		Repository: "Repo",
+		Ref:        "main",
+		Type:       "Gitlab",
+		Projects:   []string{"default"},
+	})
+	req, _ := http.NewRequest("POST", "", bytes.NewBuffer(body))
+	req.Header.Set(atlantisTokenHeader, atlantisToken)
+	w := httptest.NewRecorder()
+	ac.Plan(w, req)
+	ResponseContains(t, w, http.StatusOK, "tf out")
+	projectCommandBuilder.VerifyWasCalledOnce().BuildPlanCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand())
+	projectCommandRunner.VerifyWasCalledOnce().Plan(matchers.AnyModelsProjectCommandContext())
+}
+
+func TestAPIController_Apply(t *testing.T) {
+	ac, projectCommandBuilder, projectCommandRunner := setup(t)
+	body, _ := json.Marshal(controllers.APIRequest{
+		Repository: "Repo",
+		Ref:        "main",
+		Type:       "Gitlab",
+		Paths: []controllers.APIRequestPath{
+			{Directory: "dir/", Workspace: "default"},
+		},
+	})
+	req, _ := http.NewRequest("POST", "", bytes.NewBuffer(body))
+	req.Header.Set(atlantisTokenHeader, atlantisToken)
+	w := httptest.NewRecorder()
+	ac.Apply(w, req)
+	ResponseContains(t, w, http.StatusOK, "applied")
+	projectCommandBuilder.VerifyWasCalledOnce().BuildPlanCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand())
+	projectCommandBuilder.VerifyWasCalledOnce().BuildApplyCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand())
+	projectCommandRunner.VerifyWasCalledOnce().Plan(matchers.AnyModelsProjectCommandContext())
+	projectCommandRunner.VerifyWasCalledOnce().Apply(matchers.AnyModelsProjectCommandContext())
+
+	_, comment := projectCommandBuilder.VerifyWasCalledOnce().BuildApplyCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand()).GetCapturedArguments()
+	Equals(t, "dir", comment.RepoRelDir)
+}
+
+func TestAPIController_ApplyPlanOnlyRepo(t *testing.T) {
+	ac, projectCommandBuilder, _ := setup(t)
+	var err error
+	ac.CommandRunner.RepoAllowlistChecker, err = events.NewRepoAllowlistChecker("gitlab.com/Repo|plan-only")
+	Ok(t, err)
+	body, _ := json.Marshal(controllers.APIRequest{
+		Repository: "Repo",
+		Ref:        "main",
+		Type:       "Gitlab",
+		Paths: []controllers.APIRequestPath{
+			{Directory: ".", Workspace: "default"},
+		},
+	})
+	req, _ := http.NewRequest("POST", "", bytes.NewBuffer(body))
+	req.Header.Set(atlantisTokenHeader, atlantisToken)
+	w := httptest.NewRecorder()
+	ac.Apply(w, req)
+	ResponseContains(t, w, http.StatusForbidden, "apply is disabled for this repo, it is restricted to plan-only")
+	projectCommandBuilder.VerifyWasCalled(Never()).BuildPlanCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand())
+}
+
+func TestAPIController_ApplyAllowedApplyTeams(t *testing.T) {
+	ac, projectCommandBuilder, _ := setup(t)
+	ac.CommandRunner.GlobalCfg.Set(valid.GlobalCfg{
+		Repos: []valid.Repo{
+			{
+				IDRegex:           regexp.MustCompile(".*"),
+				AllowedApplyTeams: []string{"platform"},
+			},
+		},
+	})
+	body, _ := json.Marshal(controllers.APIRequest{
+		Repository: "Repo",
+		Ref:        "main",
+		Type:       "Gitlab",
+		Projects:   []string{"default"},
+	})
+	req, _ := http.NewRequest("POST", "", bytes.NewBuffer(body))
+	req.Header.Set(atlantisTokenHeader, atlantisToken)
+	w := httptest.NewRecorder()
+	ac.Apply(w, req)
+	ResponseContains(t, w, http.StatusForbidden, "apply is restricted to members of these teams on this repo")
+	projectCommandBuilder.VerifyWasCalled(Never()).BuildPlanCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand())
+
+	t.Log("plan is still allowed")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "", bytes.NewBuffer(body))
+	req.Header.Set(atlantisTokenHeader, atlantisToken)
+	ac.Plan(w, req)
+	ResponseContains(t, w, http.StatusOK, "tf out")
+}
+
+func TestAPIController_PlanReleasesOnlyItsLocks(t *testing.T) {
+	ac, _, projectCommandRunner := setup(t)
+	locker := lockingmocks.NewMockLocker()
+	ac.Locker = locker
+	When(projectCommandRunner.Plan(matchers.AnyModelsProjectCommandContext())).ThenReturn(models.ProjectResult{
+		RepoRelDir:  "dir",
+		Workspace:   "default",
+		PlanSuccess: &models.PlanSuccess{TerraformOutput: "tf out"},
+		CreatedLock: "Repo/dir/default",
+	}).ThenReturn(models.ProjectResult{
+		RepoRelDir:  "held",
+		Workspace:   "default",
+		PlanSuccess: &models.PlanSuccess{TerraformOutput: "tf out"},
+	})
+
+	body, _ := json.Marshal(controllers.APIRequest{
+		Repository: "Repo",
+		Ref:        "main",
+		Type:       "Gitlab",
+		PR:         1,
+		Paths: []controllers.APIRequestPath{
+			{Directory: "dir", Workspace: "default"},
+			{Directory: "held", Workspace: "default"},
+		},
+	})
+	req, _ := http.NewRequest("POST", "", bytes.NewBuffer(body))
+	req.Header.Set(atlantisTokenHeader, atlantisToken)
+	w := httptest.NewRecorder()
+	ac.Plan(w, req)
+	ResponseContains(t, w, http.StatusOK, "tf out")
+	locker.VerifyWasCalledOnce().Unlock("Repo/dir/default")
+	locker.VerifyWasCalledOnce().Unlock(AnyString())
+	locker.VerifyWasCalled(Never()).UnlockByPull(AnyString(), AnyInt())
+}
+
+func TestAPIController_ApplyPlanFailed(t *testing.T) {
+	ac, projectCommandBuilder, projectCommandRunner := setup(t)
+	When(projectCommandRunner.Plan(matchers.AnyModelsProjectCommandContext())).ThenReturn(models.ProjectResult{
+		Failure: "locked",
+	})
+	body, _ := json.Marshal(controllers.APIRequest{
+		Repository: "Repo",
+		Ref:        "main",
+		Type:       "Gitlab",
+		Projects:   []string{"default"},
+	})
+	req, _ := http.NewRequest("POST", "", bytes.NewBuffer(body))
+	req.Header.Set(atlantisTokenHeader, atlantisToken)
+	w := httptest.NewRecorder()
+	ac.Apply(w, req)
+	ResponseContains(t, w, http.StatusInternalServerError, "locked")
+	projectCommandBuilder.VerifyWasCalled(Never()).BuildApplyCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand())
+}
+
+func TestAPIController_Errors(t *testing.T) {
+	validBody, _ := json.Marshal(controllers.APIRequest{
+		Repository: "Repo",
+		Ref:        "main",
+		Type:       "Gitlab",
+		Projects:   []string{"default"},
+	})
+	cases := []struct {
+		description string
+		token       string
+		body        string
+		expCode     int
+		expBody     string
+	}{
+		{
+			"wrong token",
+			"wrong",
+			string(validBody),
+			http.StatusUnauthorized,
+			"did not match expected secret",
+		},
+		{
+			"invalid json",
+			atlantisToken,
+			"{",
+			http.StatusBadRequest,
+			"failed to parse request",
+		},
+		{
+			"missing fields",
+			atlantisToken,
+			`{"repository": "Repo", "projects": ["default"]}`,
+			http.StatusBadRequest,
+			"repository, ref and type are required",
+		},
+		{
+			"no projects or paths",
+			atlantisToken,
+			`{"repository": "Repo", "ref": "main", "type": "Gitlab"}`,
+			http.StatusBadRequest,
+			"must specify at least one of projects or paths",
+		},
+		{
+			"invalid type",
+			atlantisToken,
+			`{"repository": "Repo", "ref": "main", "type": "Svn", "projects": ["default"]}`,
+			http.StatusBadRequest,
+			"is not a valid type",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			ac, _, _ := setup(t)
+			req, _ := http.NewRequest("POST", "", bytes.NewBufferString(c.body))
+			req.Header.Set(atlantisTokenHeader, c.token)
+			w := httptest.NewRecorder()
+			ac.Plan(w, req)
+			ResponseContains(t, w, c.expCode, c.expBody)
+		})
+	}
+}
+
+func TestAPIController_Disabled(t *testing.T) {
+	ac, _, _ := setup(t)
+	ac.APISecret = nil
+	req, _ := http.NewRequest("POST", "", bytes.NewBufferString("{}"))
+	w := httptest.NewRecorder()
+	ac.Plan(w, req)
+	ResponseContains(t, w, http.StatusBadRequest, "API is disabled")
+}
+
+func TestAPIController_History(t *testing.T) {
+	ac, _, _ := setup(t)
+	db := lockingmocks.NewMockBackend()
+	ac.DB = db
+	When(db.GetProjectHistory("owner/repo", 1)).ThenReturn([]models.ProjectHistory{
+		{
+			Pull: models.PullRequest{
+				Num:        1,
+				HeadCommit: "sha",
+				URL:        "url",
+				BaseRepo:   models.Repo{FullName: "owner/repo"},
+			},
+			Workspace:  "default",
+			RepoRelDir: ".",
+			Command:    models.ApplyCommand,
+			Status:     models.AppliedPlanStatus,
+			Output:     "Apply complete!",
+			Time:       time.Date(2021, 11, 1, 12, 0, 0, 0, time.UTC),
+		},
+	}, nil)
+
+	req, _ := http.NewRequest("GET", "/api/history?repository=owner/repo&pr=1", nil)
+	req.Header.Set(atlantisTokenHeader, atlantisToken)
+	w := httptest.NewRecorder()
+	ac.History(w, req)
+	ResponseContains(t, w, http.StatusOK, `{"history":[{"repository":"owner/repo","pr":1,"pr_url":"url","head_commit":"sha","directory":".","workspace":"default","command":"apply","status":"applied","output":"Apply complete!","time":"2021-11-01T12:00:00Z"}]}`)
+
+	t.Log("the repository is required")
+	req, _ = http.NewRequest("GET", "/api/history", nil)
+	req.Header.Set(atlantisTokenHeader, atlantisToken)
+	w = httptest.NewRecorder()
+	ac.History(w, req)
+	ResponseContains(t, w, http.StatusBadRequest, "missing required query parameter repository")
+
+	t.Log("the token is required")
+	req, _ = http.NewRequest("GET", "/api/history?repository=owner/repo", nil)
+	w = httptest.NewRecorder()
+	ac.History(w, req)
+	ResponseContains(t, w, http.StatusUnauthorized, "did not match expected secret")
+	db.VerifyWasCalledOnce().GetProjectHistory(AnyString(), AnyInt())
+}
+
+func TestAPIController_PullResults(t *testing.T) {
+	ac, _, _ := setup(t)
+	db := lockingmocks.NewMockBackend()
+	ac.DB = db
+	When(db.GetProjectHistory("owner/repo", 1)).ThenReturn([]models.ProjectHistory{
+		{
+			Pull:       models.PullRequest{Num: 1, HeadCommit: "sha", BaseRepo: models.Repo{FullName: "owner/repo"}},
+			Workspace:  "default",
+			RepoRelDir: ".",
+			Command:    models.PolicyCheckCommand,
+			Status:     models.ErroredPolicyCheckStatus,
+			Output:     "FAIL - deny",
+			Time:       time.Date(2021, 11, 1, 12, 0, 0, 0, time.UTC),
+		},
+	}, nil)
+	get := func(url string, id string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", url, nil)
+		req.Header.Set(atlantisTokenHeader, atlantisToken)
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		w := httptest.NewRecorder()
+		ac.PullResults(w, req)
+		return w
+	}
+
+	t.Log("results are exported as SARIF by default")
+	w := get("/api/pulls/1/results?repository=owner/repo", "1")
+	ResponseContains(t, w, http.StatusOK, `"ruleId": "atlantis/policy_check"`)
+	Equals(t, "application/sarif+json", w.Result().Header.Get("Content-Type"))
+
+	w = get("/api/pulls/1/results?repository=owner/repo&format=junit", "1")
+	ResponseContains(t, w, http.StatusOK, `<failure message="Policy Check failed at commit sha">FAIL - deny</failure>`)
+	Equals(t, "application/xml", w.Result().Header.Get("Content-Type"))
+
+	t.Log("the format must be sarif or junit")
+	w = get("/api/pulls/1/results?repository=owner/repo&format=html", "1")
+	ResponseContains(t, w, http.StatusBadRequest, `invalid format \"html\"`)
+
+	t.Log("the repository is required")
+	w = get("/api/pulls/1/results", "1")
+	ResponseContains(t, w, http.StatusBadRequest, "missing required query parameter repository")
+
+	t.Log("the pull request must be a number")
+	w = get("/api/pulls/abc/results?repository=owner/repo", "abc")
+	ResponseContains(t, w, http.StatusBadRequest, `invalid pull request number \"abc\"`)
+	db.VerifyWasCalled(Times(2)).GetProjectHistory(AnyString(), AnyInt())
+}
+
+func TestAPIController_BearerToken(t *testing.T) {
+	ac, _, _ := setup(t)
+	db := lockingmocks.NewMockBackend()
+	ac.DB = db
+
+	req, _ := http.NewRequest("GET", "/api/pulls", nil)
+	req.Header.Set("Authorization", "Bearer "+atlantisToken)
+	w := httptest.NewRecorder()
+	ac.Pulls(w, req)
+	ResponseContains(t, w, http.StatusOK, `{"pulls":[]}`)
+
+	t.Log("a wrong bearer token is rejected")
+	req.Header.Set("Authorization", "Bearer wrong")
+	w = httptest.NewRecorder()
+	ac.Pulls(w, req)
+	ResponseContains(t, w, http.StatusUnauthorized, "did not match expected secret")
+}
+
+func TestAPIController_Locks(t *testing.T) {
+	ac, _, _ := setup(t)
+	locker := lockingmocks.NewMockLocker()
+	ac.Locker = locker
+	When(locker.List()).ThenReturn(map[string]models.ProjectLock{
+		"owner/repo/b/default": {
+			Project:   models.Project{RepoFullName: "owner/repo", Path: "b"},
+			Workspace: "default",
+			Pull:      models.PullRequest{Num: 2, URL: "url2"},
+			User:      models.User{Username: "jdoe"},
+			Time:      time.Date(2021, 11, 1, 12, 0, 0, 0, time.UTC),
+		},
+		"owner/repo/a/default": {
+			Project:   models.Project{RepoFullName: "owner/repo", Path: "a"},
+			Workspace: "default",
+			Pull:      models.PullRequest{Num: 1, URL: "url1"},
+			User:      models.User{Username: "lkysow"},
+			Time:      time.Date(2021, 11, 1, 12, 0, 0, 0, time.UTC),
+		},
+	}, nil)
+
+	req, _ := http.NewRequest("GET", "/api/locks", nil)
+	req.Header.Set(atlantisTokenHeader, atlantisToken)
+	w := httptest.NewRecorder()
+	ac.Locks(w, req)
+	ResponseContains(t, w, http.StatusOK, `{"locks":[{"id":"owner/repo/a/default","repository":"owner/repo","path":"a","workspace":"default","pr":1,"pr_url":"url1","user":"lkysow","time":"2021-11-01T12:00:00Z"},{"id":"owner/repo/b/default","repository":"owner/repo","path":"b","workspace":"default","pr":2,"pr_url":"url2","user":"jdoe","time":"2021-11-01T12:00:00Z"}]}`)
+
+	t.Log("the token is required")
+	req, _ = http.NewRequest("GET", "/api/locks", nil)
+	w = httptest.NewRecorder()
+	ac.Locks(w, req)
+	ResponseContains(t, w, http.StatusUnauthorized, "did not match expected secret")
+}
+
+func TestAPIController_Reload(t *testing.T) {
+	ac, _, _ := setup(t)
+
+	t.Log("without --repo-config there's nothing to reload")
+	req, _ := http.NewRequest("POST", "/api/reload", nil)
+	req.Header.Set(atlantisTokenHeader, atlantisToken)
+	w := httptest.NewRecorder()
+	ac.Reload(w, req)
+	ResponseContains(t, w, http.StatusBadRequest, "can only be reloaded when it's set with --repo-config")
+
+	tmpDir, cleanup := TempDir(t)
+	defer cleanup()
+	path := filepath.Join(tmpDir, "repos.yaml")
+	Ok(t, ioutil.WriteFile(path, []byte("repos:\n- id: /.*/\n  allowed_apply_teams: [platform]\n"), 0600))
+	store := valid.NewGlobalCfgStore(valid.GlobalCfg{})
+	ac.GlobalCfgReloader = &events.GlobalCfgReloader{
+		Path:            path,
+		ParserValidator: &yaml.ParserValidator{},
+		Store:           store,
+		Logger:          logging.NewNoopLogger(t),
+	}
+
+	w = httptest.NewRecorder()
+	ac.Reload(w, req)
+	ResponseContains(t, w, http.StatusOK, `{"reloaded":true}`)
+	Equals(t, []string{"platform"}, store.Get().AllowedApplyTeams("github.com/owner/repo", "main"))
+
+	t.Log("an invalid file is reported")
+	Ok(t, ioutil.WriteFile(path, []byte("repos:\n- id: /.*/\n  workflow: missing\n"), 0600))
+	w = httptest.NewRecorder()
+	ac.Reload(w, req)
+	ResponseContains(t, w, http.StatusBadRequest, "parsing "+path+" file")
+
+	t.Log("the token is required")
+	req, _ = http.NewRequest("POST", "/api/reload", nil)
+	w = httptest.NewRecorder()
+	ac.Reload(w, req)
+	ResponseContains(t, w, http.StatusUnauthorized, "did not match expected secret")
+}
+
+func TestAPIController_Warm(t *testing.T) {
+	ac, _, _ := setup(t)
+	tfClient := tfmocks.NewMockClient()
+	ac.Warmer = &terraform.Warmer{
+		Client: tfClient,
+		Logger: logging.NewNoopLogger(t),
+	}
+
+	req, _ := http.NewRequest("POST", "/api/warm", nil)
+	req.Header.Set(atlantisTokenHeader, atlantisToken)
+	w := httptest.NewRecorder()
+	ac.Warm(w, req)
+	ResponseContains(t, w, http.StatusOK, `{"warmed":true}`)
+	tfClient.VerifyWasCalledOnce().EnsureVersion(tfmatchers.AnyLoggingSimpleLogging(), EqString(""), tfmatchers.EqPtrToGoVersionVersion(nil))
+
+	t.Log("failures are reported")
+	When(tfClient.EnsureVersion(tfmatchers.AnyLoggingSimpleLogging(), AnyString(), tfmatchers.AnyPtrToGoVersionVersion())).
+		ThenReturn(errors.New("download failed"))
+	w = httptest.NewRecorder()
+	ac.Warm(w, req)
+	ResponseContains(t, w, http.StatusInternalServerError, "warming failed: download failed")
+
+	t.Log("the token is required")
+	req, _ = http.NewRequest("POST", "/api/warm", nil)
+	w = httptest.NewRecorder()
+	ac.Warm(w, req)
+	ResponseContains(t, w, http.StatusUnauthorized, "did not match expected secret")
+}
+
+func TestAPIController_TerraformVersions(t *testing.T) {
+	ac, _, _ := setup(t)
+	client, _, cleanup := newTerraformClient(t)
+	defer cleanup()
+	ac.TerraformClient = client
+
+	req, _ := http.NewRequest("GET", "/api/terraform-versions", nil)
+	req.Header.Set(atlantisTokenHeader, atlantisToken)
+	w := httptest.NewRecorder()
+	ac.TerraformVersions(w, req)
+	ResponseContains(t, w, http.StatusOK, `{"distribution":"terraform","default_version":"1.5.7","installed":["1.5.7"],"available":["1.6.0","1.4.6"]}`)
+
+	req, _ = http.NewRequest("GET", "/api/terraform-versions?distribution=other", nil)
+	req.Header.Set(atlantisTokenHeader, atlantisToken)
+	w = httptest.NewRecorder()
+	ac.TerraformVersions(w, req)
+	ResponseContains(t, w, http.StatusBadRequest, `invalid distribution \"other\"`)
+
+	t.Log("the token is required")
+	req, _ = http.NewRequest("GET", "/api/terraform-versions", nil)
+	w = httptest.NewRecorder()
+	ac.TerraformVersions(w, req)
+	ResponseContains(t, w, http.StatusUnauthorized, "did not match expected secret")
+}
+
+func TestAPIController_InstallTerraformVersion(t *testing.T) {
+	ac, _, _ := setup(t)
+	client, _, cleanup := newTerraformClient(t)
+	defer cleanup()
+	ac.TerraformClient = client
+
+	req, _ := http.NewRequest("POST", "/api/terraform-versions", bytes.NewBufferString(`{"version": "1.6.0"}`))
+	req.Header.Set(atlantisTokenHeader, atlantisToken)
+	w := httptest.NewRecorder()
+	ac.InstallTerraformVersion(w, req)
+	ResponseContains(t, w, http.StatusOK, `{"installed":true}`)
+
+	req, _ = http.NewRequest("GET", "/api/terraform-versions", nil)
+	req.Header.Set(atlantisTokenHeader, atlantisToken)
+	w = httptest.NewRecorder()
+	ac.TerraformVersions(w, req)
+	ResponseContains(t, w, http.StatusOK, `"installed":["1.6.0","1.5.7"],"available":["1.4.6"]`)
+
+	req, _ = http.NewRequest("POST", "/api/terraform-versions", bytes.NewBufferString(`{"distribution": "opentofu"}`))
+	req.Header.Set(atlantisTokenHeader, atlantisToken)
+	w = httptest.NewRecorder()
+	ac.InstallTerraformVersion(w, req)
+	ResponseContains(t, w, http.StatusBadRequest, "version is required")
+
+	t.Log("the token is required")
+	req, _ = http.NewRequest("POST", "/api/terraform-versions", bytes.NewBufferString(`{"version": "1.6.0"}`))
+	w = httptest.NewRecorder()
+	ac.InstallTerraformVersion(w, req)
+	ResponseContains(t, w, http.StatusUnauthorized, "did not match expected secret")
+}
+
+func TestAPIController_Audit(t *testing.T) {
+	ac, _, _ := setup(t)
+	db := lockingmocks.NewMockBackend()
+	ac.DB = db
+
+	t.Log("an empty audit log is an empty list")
+	req, _ := http.NewRequest("GET", "/api/audit", nil)
+	req.Header.Set(atlantisTokenHeader, atlantisToken)
+	w := httptest.NewRecorder()
+	ac.Audit(w, req)
+	ResponseContains(t, w, http.StatusOK, `{"events":[]}`)
+
+	When(db.ListAuditEvents()).ThenReturn([]models.AuditEvent{
+		{
+			Time:       time.Date(2021, 11, 1, 12, 0, 0, 0, time.UTC),
+			DurationMS: 1500,
+			Username:   "lkysow",
+			Repo:       "owner/repo",
+			PullNum:    1,
+			Command:    "plan",
+			Result:     models.AuditResultSuccess,
+		},
+	}, nil)
+	w = httptest.NewRecorder()
+	ac.Audit(w, req)
+	ResponseContains(t, w, http.StatusOK, `{"events":[{"time":"2021-11-01T12:00:00Z","duration_ms":1500,"user":"lkysow","repository":"owner/repo","pr":1,"command":"plan","result":"success"}]}`)
+
+	t.Log("the token is required")
+	req, _ = http.NewRequest("GET", "/api/audit", nil)
+	w = httptest.NewRecorder()
+	ac.Audit(w, req)
+	ResponseContains(t, w, http.StatusUnauthorized, "did not match expected secret")
+}
+
+func TestAPIController_Repos(t *testing.T) {
+	ac, _, _ := setup(t)
+	tmp, cleanup := TempDir(t)
+	t.Cleanup(cleanup)
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	ac.RepoRegistry = &events.RepoRegistry{
+		DB:               boltDB,
+		AllowlistChecker: ac.RepoAllowlistChecker,
+		Logger:           logging.NewNoopLogger(t),
+	}
+
+	t.Log("repos are registered with their restrictions")
+	body, _ := json.Marshal(controllers.APIRepoRequest{
+		Repository:  "Repo",
+		Type:        "Gitlab",
+		PlanOnly:    true,
+		MaxParallel: 2,
+	})
+	req, _ := http.NewRequest("POST", "/api/repos", bytes.NewBuffer(body))
+	req.Header.Set(atlantisTokenHeader, atlantisToken)
+	w := httptest.NewRecorder()
+	ac.RegisterRepo(w, req)
+	ResponseContains(t, w, http.StatusOK, `"id":"gitlab.com/Repo","repository":"Repo","hostname":"gitlab.com","plan_only":true,"no_autoplan":false,"max_parallel":2`)
+
+	req, _ = http.NewRequest("GET", "/api/repos", nil)
+	req.Header.Set(atlantisTokenHeader, atlantisToken)
+	w = httptest.NewRecorder()
+	ac.Repos(w, req)
+	ResponseContains(t, w, http.StatusOK, `{"repos":[{"id":"gitlab.com/Repo"`)
+
+	t.Log("webhooks can't be created without a client for the host")
+	body, _ = json.Marshal(controllers.APIRepoRequest{Repository: "Repo", Type: "Gitlab", CreateWebhook: true})
+	req, _ = http.NewRequest("POST", "/api/repos", bytes.NewBuffer(body))
+	req.Header.Set(atlantisTokenHeader, atlantisToken)
+	w = httptest.NewRecorder()
+	ac.RegisterRepo(w, req)
+	ResponseContains(t, w, http.StatusInternalServerError, "webhooks can't be created on gitlab.com repos")
+
+	t.Log("repository and type are required")
+	body, _ = json.Marshal(controllers.APIRepoRequest{Repository: "Repo"})
+	req, _ = http.NewRequest("POST", "/api/repos", bytes.NewBuffer(body))
+	req.Header.Set(atlantisTokenHeader, atlantisToken)
+	w = httptest.NewRecorder()
+	ac.RegisterRepo(w, req)
+	ResponseContains(t, w, http.StatusBadRequest, "repository and type are required")
+
+	t.Log("repos are unregistered")
+	req, _ = http.NewRequest("DELETE", "/api/repos?repository=Repo&type=Gitlab", nil)
+	req.Header.Set(atlantisTokenHeader, atlantisToken)
+	w = httptest.NewRecorder()
+	ac.UnregisterRepo(w, req)
+	ResponseContains(t, w, http.StatusOK, `{"unregistered":true}`)
+	w = httptest.NewRecorder()
+	ac.UnregisterRepo(w, req)
+	ResponseContains(t, w, http.StatusNotFound, "repo Repo is not registered")
+
+	req, _ = http.NewRequest("GET", "/api/repos", nil)
+	req.Header.Set(atlantisTokenHeader, atlantisToken)
+	w = httptest.NewRecorder()
+	ac.Repos(w, req)
+	ResponseContains(t, w, http.StatusOK, `{"repos":[]}`)
+
+	t.Log("the token is required")
+	req, _ = http.NewRequest("GET", "/api/repos", nil)
+	w = httptest.NewRecorder()
+	ac.Repos(w, req)
+	ResponseContains(t, w, http.StatusUnauthorized, "did not match expected secret")
+}
+
+func TestAPIController_DepGraph(t *testing.T) {
+	ac, _, _ := setup(t)
+	repoDir, cleanup := DirStructure(t, map[string]interface{}{
+		"project1": map[string]interface{}{
+			"main.tf": `module "network" { source = "../modules/network" }`,
+		},
+		"modules": map[string]interface{}{
+			"network": map[string]interface{}{
+				"main.tf": `module "vpc" { source = "../vpc" }`,
+			},
+			"vpc": map[string]interface{}{
+				"main.tf": nil,
+			},
+		},
+	})
+	defer cleanup()
+	workingDir := NewMockWorkingDir()
+	When(workingDir.GetWorkingDir(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString())).ThenReturn(repoDir, nil)
+	ac.WorkingDir = workingDir
+
+	req, _ := http.NewRequest("GET", "/api/depgraph?repository=owner/repo&pr=1", nil)
+	req.Header.Set(atlantisTokenHeader, atlantisToken)
+	w := httptest.NewRecorder()
+	ac.DepGraph(w, req)
+	ResponseContains(t, w, http.StatusOK, `{"repository":"owner/repo","pr":1,"modules":{"modules/network":{"callers":["project1"],"projects":["project1"]},"modules/vpc":{"callers":["modules/network"],"projects":["project1"]}}}`)
+	_, pull, workspace := workingDir.VerifyWasCalledOnce().GetWorkingDir(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString()).GetCapturedArguments()
+	Equals(t, 1, pull.Num)
+	Equals(t, "default", workspace)
+
+	t.Log("the pr is required")
+	req, _ = http.NewRequest("GET", "/api/depgraph?repository=owner/repo", nil)
+	req.Header.Set(atlantisTokenHeader, atlantisToken)
+	w = httptest.NewRecorder()
+	ac.DepGraph(w, req)
+	ResponseContains(t, w, http.StatusBadRequest, "missing required query parameter pr")
+
+	t.Log("the repository can't escape the data dir")
+	req, _ = http.NewRequest("GET", "/api/depgraph?repository=../../etc&pr=1", nil)
+	req.Header.Set(atlantisTokenHeader, atlantisToken)
+	w = httptest.NewRecorder()
+	ac.DepGraph(w, req)
+	ResponseContains(t, w, http.StatusBadRequest, "invalid repo format")
+	workingDir.VerifyWasCalledOnce().GetWorkingDir(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString())
+
+	t.Log("the pull request must have been cloned")
+	When(workingDir.GetWorkingDir(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString())).ThenReturn("", errors.New("not found"))
+	req, _ = http.NewRequest("GET", "/api/depgraph?repository=owner/repo&pr=2", nil)
+	req.Header.Set(atlantisTokenHeader, atlantisToken)
+	w = httptest.NewRecorder()
+	ac.DepGraph(w, req)
+	ResponseContains(t, w, http.StatusNotFound, "repository owner/repo pull request 2 has not been cloned")
+
+	t.Log("the token is required")
+	req, _ = http.NewRequest("GET", "/api/depgraph?repository=owner/repo&pr=1", nil)
+	w = httptest.NewRecorder()
+	ac.DepGraph(w, req)
+	ResponseContains(t, w, http.StatusUnauthorized, "did not match expected secret")
+}
+
+func setup(t *testing.T) (controllers.APIController, *MockProjectCommandBuilder, *MockProjectCommandRunner) {
+	RegisterMockTestingT(t)
+	locker := lockingmocks.NewMockLocker()
+	logger := logging.NewNoopLogger(t)
+	parser := NewMockEventParsing()
+	vcsClient := vcsmocks.NewMockClient()
+	repoAllowlistChecker, err := events.NewRepoAllowlistChecker("*")
+	Ok(t, err)
+
+	projectCommandBuilder := NewMockProjectCommandBuilder()
+	When(projectCommandBuilder.BuildPlanCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand())).
+		ThenReturn([]models.ProjectCommandContext{{CommandName: models.PlanCommand}}, nil)
+	When(projectCommandBuilder.BuildApplyCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand())).
+		ThenReturn([]models.ProjectCommandContext{{CommandName: models.ApplyCommand}}, nil)
+
+	projectCommandRunner := NewMockProjectCommandRunner()
+	When(projectCommandRunner.Plan(matchers.AnyModelsProjectCommandContext())).ThenReturn(models.ProjectResult{
+		PlanSuccess: &models.PlanSuccess{
+			TerraformOutput: "tf out",
+		},
+	})
+	When(projectCommandRunner.Apply(matchers.AnyModelsProjectCommandContext())).ThenReturn(models.ProjectResult{
+		ApplySuccess: "applied",
+	})
+
+	When(vcsClient.GetCloneURL(vcsmatchers.AnyModelsVCSHostType(), AnyString())).ThenReturn("https://gitlab.com/Repo.git", nil)
+	When(parser.ParseAPIPlanRequest(matchers.AnyModelsVCSHostType(), AnyString(), AnyString())).ThenReturn(models.Repo{
+		FullName: "Repo",
+		VCSHost: models.VCSHost{
+			Hostname: "gitlab.com",
+			Type:     models.Gitlab,
+		},
+	}, nil)
+
+	ac := controllers.APIController{
+		APISecret:                 []byte(atlantisToken),
+		Locker:                    locker,
+		Logger:                    logger,
+		Parser:                    parser,
+		ProjectCommandBuilder:     projectCommandBuilder,
+		ProjectPlanCommandRunner:  projectCommandRunner,
+		ProjectApplyCommandRunner: projectCommandRunner,
+		RepoAllowlistChecker:      repoAllowlistChecker,
+		VCSClient:                 vcsClient,
+		Drainer:                   &events.Drainer{},
+		CommandRunner: &events.DefaultCommandRunner{
+			GlobalCfg:            valid.NewGlobalCfgStore(valid.GlobalCfg{}),
+			RepoAllowlistChecker: repoAllowlistChecker,
+		},
+	}
+	return ac, projectCommandBuilder, projectCommandRunner
+}
+
+func TestAPIController_Pulls(t *testing.T) {
+	ac, _, _ := setup(t)
+	db := lockingmocks.NewMockBackend()
+	ac.DB = db
+
+	t.Log("no pull requests is an empty list")
+	req, _ := http.NewRequest("GET", "/api/pulls", nil)
+	req.Header.Set(atlantisTokenHeader, atlantisToken)
+	w := httptest.NewRecorder()
+	ac.Pulls(w, req)
+	ResponseContains(t, w, http.StatusOK, `{"pulls":[]}`)
+
+	When(db.ListPullStatuses()).ThenReturn([]models.PullStatus{
+		{
+			Pull: models.PullRequest{
+				Num:        1,
+				HeadCommit: "sha",
+				URL:        "url",
+				Author:     "lkysow",
+				BaseRepo:   models.Repo{FullName: "owner/repo"},
+			},
+			Projects: []models.ProjectStatus{
+				{
+					Workspace:  "default",
+					RepoRelDir: ".",
+					Status:     models.PlannedPlanStatus,
+				},
+			},
+		},
+	}, nil)
+	w = httptest.NewRecorder()
+	ac.Pulls(w, req)
+	ResponseContains(t, w, http.StatusOK, `{"pulls":[{"repository":"owner/repo","pr":1,"pr_url":"url","author":"lkysow","head_commit":"sha","projects":[{"directory":".","workspace":"default","status":"planned"}],"apply_completeness":{"complete":false,"applied":0,"total":1,"reason":"project at dir \".\", workspace \"default\" has status \"planned\""}}]}`)
+
+	t.Log("the token is required")
+	req, _ = http.NewRequest("GET", "/api/pulls", nil)
+	w = httptest.NewRecorder()
+	ac.Pulls(w, req)
+	ResponseContains(t, w, http.StatusUnauthorized, "did not match expected secret")
+}
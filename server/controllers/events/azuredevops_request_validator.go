@@ -1,11 +1,12 @@
 package events
 
 import (
+	"crypto/subtle"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"mime"
 	"net/http"
-
-	"github.com/mcdafydd/go-azuredevops/azuredevops"
 )
 
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_azuredevops_request_validator.go AzureDevopsRequestValidator
@@ -37,21 +38,29 @@ func (d *DefaultAzureDevopsRequestValidator) Validate(r *http.Request, user []by
 }
 
 func (d *DefaultAzureDevopsRequestValidator) validateWithBasicAuth(r *http.Request, user []byte, pass []byte) ([]byte, error) {
-	payload, err := azuredevops.ValidatePayload(r, user, pass)
+	payload, err := d.validateWithoutBasicAuth(r)
 	if err != nil {
 		return nil, err
 	}
+	username, password, ok := r.BasicAuth()
+	if !ok || subtle.ConstantTimeCompare(user, []byte(username)) != 1 || subtle.ConstantTimeCompare(pass, []byte(password)) != 1 {
+		return nil, errors.New("ValidatePayload authentication failed")
+	}
 	return payload, nil
 }
 
 func (d *DefaultAzureDevopsRequestValidator) validateWithoutBasicAuth(r *http.Request) ([]byte, error) {
+	// Azure DevOps Server versions differ in how they format the
+	// Content-Type, ex. "application/json;charset=utf-8", so we parse it
+	// rather than matching exact strings.
 	ct := r.Header.Get("Content-Type")
-	if ct == "application/json" || ct == "application/json; charset=utf-8" {
-		payload, err := ioutil.ReadAll(r.Body)
-		if err != nil {
-			return nil, fmt.Errorf("could not read body: %s", err)
-		}
-		return payload, nil
+	mediaType, _, err := mime.ParseMediaType(ct)
+	if err != nil || mediaType != "application/json" {
+		return nil, fmt.Errorf("webhook request has unsupported Content-Type %q", ct)
 	}
-	return nil, fmt.Errorf("webhook request has unsupported Content-Type %q", ct)
+	payload, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read body: %s", err)
+	}
+	return payload, nil
 }
@@ -67,3 +67,18 @@ func TestAzureDevopsValidate_WithoutSecretJSON(t *testing.T) {
 	Ok(t, err)
 	Equals(t, `{"yo":true}`, string(bs))
 }
+
+func TestAzureDevopsValidate_WithBasicAuthContentTypeVariant(t *testing.T) {
+	t.Log("Azure DevOps Server can send the charset without a space, this should still be accepted")
+	RegisterMockTestingT(t)
+	g := events.DefaultAzureDevopsRequestValidator{}
+	buf := bytes.NewBufferString(`{"yo":true}`)
+	req, err := http.NewRequest("POST", "http://localhost/event", buf)
+	Ok(t, err)
+	req.Header.Set("Authorization", "Basic dXNlcjpwYXNz") // user:pass
+	req.Header.Set("Content-Type", "application/json;charset=UTF-8")
+
+	bs, err := g.Validate(req, []byte("user"), []byte("pass"))
+	Ok(t, err)
+	Equals(t, `{"yo":true}`, string(bs))
+}
@@ -14,9 +14,12 @@
 package events
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/google/go-github/v31/github"
@@ -29,6 +32,7 @@ import (
 	"github.com/runatlantis/atlantis/server/events/vcs/bitbucketcloud"
 	"github.com/runatlantis/atlantis/server/events/vcs/bitbucketserver"
 	"github.com/runatlantis/atlantis/server/logging"
+	"github.com/runatlantis/atlantis/server/tracing"
 	gitlab "github.com/xanzy/go-gitlab"
 )
 
@@ -39,14 +43,51 @@ const azuredevopsHeader = "Request-Id"
 // bitbucketEventTypeHeader is the same in both cloud and server.
 const bitbucketEventTypeHeader = "X-Event-Key"
 const bitbucketCloudRequestIDHeader = "X-Request-UUID"
+const bitbucketCloudSignatureHeader = "X-Hub-Signature"
 const bitbucketServerRequestIDHeader = "X-Request-ID"
 const bitbucketServerSignatureHeader = "X-Hub-Signature"
+const githubDeliveryHeader = "X-Github-Delivery"
+const gitlabEventUUIDHeader = "X-Gitlab-Event-UUID"
+const githubEnterpriseHostHeader = "X-Github-Enterprise-Host"
+const gitlabInstanceHeader = "X-Gitlab-Instance"
+
+// DeliveryClaimer records which webhook deliveries have been handled so that a
+// delivery that is retried, or received by more than one Atlantis instance, is
+// only handled once.
+type DeliveryClaimer interface {
+	// ClaimDelivery returns false if the delivery with id was already claimed.
+	ClaimDelivery(id string) (bool, error)
+	// ReleaseDelivery deletes the claim on the delivery with id so it's
+	// handled again if it's redelivered.
+	ReleaseDelivery(id string) error
+}
+
+// claimingResponseWriter records the deliveries claimed while handling a
+// request and the status code it was responded to with.
+type claimingResponseWriter struct {
+	http.ResponseWriter
+	status  int
+	claimed []string
+}
+
+func (c *claimingResponseWriter) WriteHeader(code int) {
+	if c.status == 0 {
+		c.status = code
+	}
+	c.ResponseWriter.WriteHeader(code)
+}
 
 // VCSEventsController handles all webhook requests which signify 'events' in the
 // VCS host, ex. GitHub.
 type VCSEventsController struct {
 	CommandRunner events.CommandRunner
 	PullCleaner   events.PullCleaner
+	// BaseBranchUpdater handles pushes to the branches that pull requests
+	// will be merged into.
+	BaseBranchUpdater events.BaseBranchUpdater
+	// AutoApplier, if set, applies pull requests of repos that set
+	// auto_apply when they're approved.
+	AutoApplier   events.AutoApplier
 	Logger        logging.SimpleLogging
 	Parser        events.EventParsing
 	CommentParser events.CommentParsing
@@ -60,7 +101,12 @@ type VCSEventsController struct {
 	// GitlabWebhookSecret is the secret added to this webhook via the GitLab
 	// UI that identifies this call as coming from GitLab. If empty, no
 	// request validation is done.
-	GitlabWebhookSecret  []byte
+	GitlabWebhookSecret []byte
+	// HostWebhookSecrets maps the hostnames of additional GitHub and GitLab
+	// hosts to their webhook secrets. Those hosts say which host sent a
+	// webhook in a header so it's validated with that host's secret instead
+	// of GithubWebhookSecret or GitlabWebhookSecret.
+	HostWebhookSecrets   map[string][]byte
 	RepoAllowlistChecker *events.RepoAllowlistChecker
 	// SilenceAllowlistErrors controls whether we write an error comment on
 	// pull requests from non-allowlisted repos.
@@ -84,17 +130,53 @@ type VCSEventsController struct {
 	// Azure DevOps Team Project. If empty, no request validation is done.
 	AzureDevopsWebhookBasicPassword []byte
 	AzureDevopsRequestValidator     AzureDevopsRequestValidator
+	// DeliveryClaimer, if set, is used to ignore webhook deliveries and
+	// comments that were already handled, ex. because the VCS host redelivered
+	// a webhook that timed out or another Atlantis instance in HA mode
+	// received it.
+	DeliveryClaimer DeliveryClaimer
+	// ProgressComments is true if comment commands should be acknowledged
+	// with a reaction as soon as they're received, on VCS hosts that support
+	// it.
+	ProgressComments bool
+	// GithubRepoOnboarder, if set, sets up the branch protection of
+	// allowlisted GitHub repos when their pull requests first trigger
+	// Atlantis.
+	GithubRepoOnboarder *events.GithubRepoOnboarder
 }
 
-// Post handles POST webhook requests.
+// receivedReaction is the reaction added to comment commands when they're
+// received.
+const receivedReaction = "eyes"
+
+// Post handles POST webhook requests. The commands they trigger are traced
+// as children of the webhook's span.
 func (e *VCSEventsController) Post(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "webhook")
+	defer span.End()
+
+	if e.DeliveryClaimer != nil {
+		cw := &claimingResponseWriter{ResponseWriter: w}
+		defer func() {
+			if p := recover(); p != nil {
+				e.releaseDeliveries(cw.claimed)
+				panic(p)
+			}
+			if cw.status >= 400 {
+				e.releaseDeliveries(cw.claimed)
+			}
+		}()
+		w = cw
+	}
+
 	if r.Header.Get(githubHeader) != "" {
 		if !e.supportsHost(models.Github) {
 			e.respond(w, logging.Debug, http.StatusBadRequest, "Ignoring request since not configured to support GitHub")
 			return
 		}
 		e.Logger.Debug("handling GitHub post")
-		e.handleGithubPost(w, r)
+		span.SetAttributes(tracing.VCSHostKey.String(models.Github.String()))
+		e.handleGithubPost(ctx, w, r)
 		return
 	} else if r.Header.Get(gitlabHeader) != "" {
 		if !e.supportsHost(models.Gitlab) {
@@ -102,7 +184,8 @@ func (e *VCSEventsController) Post(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		e.Logger.Debug("handling GitLab post")
-		e.handleGitlabPost(w, r)
+		span.SetAttributes(tracing.VCSHostKey.String(models.Gitlab.String()))
+		e.handleGitlabPost(ctx, w, r)
 		return
 	} else if r.Header.Get(bitbucketEventTypeHeader) != "" {
 		// Bitbucket Cloud and Server use the same event type header but they
@@ -113,7 +196,8 @@ func (e *VCSEventsController) Post(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 			e.Logger.Debug("handling Bitbucket Cloud post")
-			e.handleBitbucketCloudPost(w, r)
+			span.SetAttributes(tracing.VCSHostKey.String(models.BitbucketCloud.String()))
+			e.handleBitbucketCloudPost(ctx, w, r)
 			return
 		} else if r.Header.Get(bitbucketServerRequestIDHeader) != "" {
 			if !e.supportsHost(models.BitbucketServer) {
@@ -121,7 +205,8 @@ func (e *VCSEventsController) Post(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 			e.Logger.Debug("handling Bitbucket Server post")
-			e.handleBitbucketServerPost(w, r)
+			span.SetAttributes(tracing.VCSHostKey.String(models.BitbucketServer.String()))
+			e.handleBitbucketServerPost(ctx, w, r)
 			return
 		}
 	} else if r.Header.Get(azuredevopsHeader) != "" {
@@ -130,59 +215,93 @@ func (e *VCSEventsController) Post(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		e.Logger.Debug("handling AzureDevops post")
-		e.handleAzureDevopsPost(w, r)
+		span.SetAttributes(tracing.VCSHostKey.String(models.AzureDevops.String()))
+		e.handleAzureDevopsPost(ctx, w, r)
 		return
 	}
 	e.respond(w, logging.Debug, http.StatusBadRequest, "Ignoring request")
 }
 
-func (e *VCSEventsController) handleGithubPost(w http.ResponseWriter, r *http.Request) {
+func (e *VCSEventsController) handleGithubPost(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 	// Validate the request against the optional webhook secret.
-	payload, err := e.GithubRequestValidator.Validate(r, e.GithubWebhookSecret)
+	host := e.webhookHost(r, githubEnterpriseHostHeader)
+	payload, err := e.GithubRequestValidator.Validate(r, e.webhookSecret(host, e.GithubWebhookSecret))
 	if err != nil {
 		e.respond(w, logging.Warn, http.StatusBadRequest, err.Error())
 		return
 	}
+	var repoPayload struct {
+		Repository struct {
+			CloneURL string `json:"clone_url"`
+		} `json:"repository"`
+	}
+	if err := json.Unmarshal(payload, &repoPayload); err == nil && !e.repoOnWebhookHost(host, repoPayload.Repository.CloneURL) {
+		e.respond(w, logging.Warn, http.StatusBadRequest, "Repo %s is not on the VCS host that sent this webhook", repoPayload.Repository.CloneURL)
+		return
+	}
 	e.Logger.Debug("request valid")
 
-	githubReqID := "X-Github-Delivery=" + r.Header.Get("X-Github-Delivery")
+	if e.isDuplicateDelivery(w, models.Github, host, r.Header.Get(githubDeliveryHeader)) {
+		return
+	}
+
+	githubReqID := "X-Github-Delivery=" + r.Header.Get(githubDeliveryHeader)
 	event, _ := github.ParseWebHook(github.WebHookType(r), payload)
 	switch event := event.(type) {
 	case *github.IssueCommentEvent:
 		e.Logger.Debug("handling as comment event")
-		e.HandleGithubCommentEvent(w, event, githubReqID)
+		e.HandleGithubCommentEvent(ctx, w, event, githubReqID)
 	case *github.PullRequestEvent:
 		e.Logger.Debug("handling as pull request event")
-		e.HandleGithubPullRequestEvent(w, event, githubReqID)
+		e.HandleGithubPullRequestEvent(ctx, w, event, githubReqID)
+	case *github.CheckRunEvent:
+		e.Logger.Debug("handling as check run event")
+		e.HandleGithubCheckRunEvent(ctx, w, event, githubReqID)
+	case *github.PushEvent:
+		e.Logger.Debug("handling as push event")
+		e.HandleGithubPushEvent(w, event, githubReqID)
+	case *github.PullRequestReviewEvent:
+		e.Logger.Debug("handling as pull request review event")
+		e.HandleGithubPullRequestReviewEvent(w, event, githubReqID)
 	default:
 		e.respond(w, logging.Debug, http.StatusOK, "Ignoring unsupported event %s", githubReqID)
 	}
 }
 
-func (e *VCSEventsController) handleBitbucketCloudPost(w http.ResponseWriter, r *http.Request) {
+func (e *VCSEventsController) handleBitbucketCloudPost(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 	eventType := r.Header.Get(bitbucketEventTypeHeader)
 	reqID := r.Header.Get(bitbucketCloudRequestIDHeader)
+	sig := r.Header.Get(bitbucketCloudSignatureHeader)
 	defer r.Body.Close() // nolint: errcheck
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		e.respond(w, logging.Error, http.StatusBadRequest, "Unable to read body: %s %s=%s", err, bitbucketCloudRequestIDHeader, reqID)
 		return
 	}
+	if len(e.BitbucketWebhookSecret) > 0 {
+		if err := bitbucketcloud.ValidateSignature(body, sig, e.BitbucketWebhookSecret); err != nil {
+			e.respond(w, logging.Warn, http.StatusBadRequest, errors.Wrap(err, "request did not pass validation").Error())
+			return
+		}
+	}
+	if e.isDuplicateDelivery(w, models.BitbucketCloud, "", reqID) {
+		return
+	}
 	switch eventType {
 	case bitbucketcloud.PullCreatedHeader, bitbucketcloud.PullUpdatedHeader, bitbucketcloud.PullFulfilledHeader, bitbucketcloud.PullRejectedHeader:
 		e.Logger.Debug("handling as pull request state changed event")
-		e.handleBitbucketCloudPullRequestEvent(w, eventType, body, reqID)
+		e.handleBitbucketCloudPullRequestEvent(ctx, w, eventType, body, reqID)
 		return
 	case bitbucketcloud.PullCommentCreatedHeader:
 		e.Logger.Debug("handling as comment created event")
-		e.HandleBitbucketCloudCommentEvent(w, body, reqID)
+		e.HandleBitbucketCloudCommentEvent(ctx, w, body, reqID)
 		return
 	default:
 		e.respond(w, logging.Debug, http.StatusOK, "Ignoring unsupported event type %s %s=%s", eventType, bitbucketCloudRequestIDHeader, reqID)
 	}
 }
 
-func (e *VCSEventsController) handleBitbucketServerPost(w http.ResponseWriter, r *http.Request) {
+func (e *VCSEventsController) handleBitbucketServerPost(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 	eventType := r.Header.Get(bitbucketEventTypeHeader)
 	reqID := r.Header.Get(bitbucketServerRequestIDHeader)
 	sig := r.Header.Get(bitbucketServerSignatureHeader)
@@ -204,21 +323,24 @@ func (e *VCSEventsController) handleBitbucketServerPost(w http.ResponseWriter, r
 			return
 		}
 	}
+	if e.isDuplicateDelivery(w, models.BitbucketServer, "", reqID) {
+		return
+	}
 	switch eventType {
 	case bitbucketserver.PullCreatedHeader, bitbucketserver.PullMergedHeader, bitbucketserver.PullDeclinedHeader, bitbucketserver.PullDeletedHeader:
 		e.Logger.Debug("handling as pull request state changed event")
-		e.handleBitbucketServerPullRequestEvent(w, eventType, body, reqID)
+		e.handleBitbucketServerPullRequestEvent(ctx, w, eventType, body, reqID)
 		return
 	case bitbucketserver.PullCommentCreatedHeader:
 		e.Logger.Debug("handling as comment created event")
-		e.HandleBitbucketServerCommentEvent(w, body, reqID)
+		e.HandleBitbucketServerCommentEvent(ctx, w, body, reqID)
 		return
 	default:
 		e.respond(w, logging.Debug, http.StatusOK, "Ignoring unsupported event type %s %s=%s", eventType, bitbucketServerRequestIDHeader, reqID)
 	}
 }
 
-func (e *VCSEventsController) handleAzureDevopsPost(w http.ResponseWriter, r *http.Request) {
+func (e *VCSEventsController) handleAzureDevopsPost(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 	// Validate the request against the optional basic auth username and password.
 	payload, err := e.AzureDevopsRequestValidator.Validate(r, e.AzureDevopsWebhookBasicUser, e.AzureDevopsWebhookBasicPassword)
 	if err != nil {
@@ -233,13 +355,17 @@ func (e *VCSEventsController) handleAzureDevopsPost(w http.ResponseWriter, r *ht
 		e.respond(w, logging.Error, http.StatusBadRequest, "Failed parsing webhook: %v %s", err, azuredevopsReqID)
 		return
 	}
+	// The event ID is the same when Azure DevOps retries a delivery.
+	if e.isDuplicateDelivery(w, models.AzureDevops, "", event.ID) {
+		return
+	}
 	switch event.PayloadType {
 	case azuredevops.PullRequestCommentedEvent:
 		e.Logger.Debug("handling as pull request commented event")
-		e.HandleAzureDevopsPullRequestCommentedEvent(w, event, azuredevopsReqID)
+		e.HandleAzureDevopsPullRequestCommentedEvent(ctx, w, event, azuredevopsReqID)
 	case azuredevops.PullRequestEvent:
 		e.Logger.Debug("handling as pull request event")
-		e.HandleAzureDevopsPullRequestEvent(w, event, azuredevopsReqID)
+		e.HandleAzureDevopsPullRequestEvent(ctx, w, event, azuredevopsReqID)
 	default:
 		e.respond(w, logging.Debug, http.StatusOK, "Ignoring unsupported event: %v %s", event.PayloadType, azuredevopsReqID)
 	}
@@ -247,7 +373,7 @@ func (e *VCSEventsController) handleAzureDevopsPost(w http.ResponseWriter, r *ht
 
 // HandleGithubCommentEvent handles comment events from GitHub where Atlantis
 // commands can come from. It's exported to make testing easier.
-func (e *VCSEventsController) HandleGithubCommentEvent(w http.ResponseWriter, event *github.IssueCommentEvent, githubReqID string) {
+func (e *VCSEventsController) HandleGithubCommentEvent(ctx context.Context, w http.ResponseWriter, event *github.IssueCommentEvent, githubReqID string) {
 	if event.GetAction() != "created" {
 		e.respond(w, logging.Debug, http.StatusOK, "Ignoring comment event since action was not created %s", githubReqID)
 		return
@@ -261,30 +387,158 @@ func (e *VCSEventsController) HandleGithubCommentEvent(w http.ResponseWriter, ev
 
 	// We pass in nil for maybeHeadRepo because the head repo data isn't
 	// available in the GithubIssueComment event.
-	e.handleCommentEvent(w, baseRepo, nil, nil, user, pullNum, event.Comment.GetBody(), models.Github)
+	e.handleCommentEvent(ctx, w, baseRepo, nil, nil, user, pullNum, event.Comment.GetID(), "", event.Comment.GetBody(), models.Github)
+}
+
+// HandleGithubCheckRunEvent handles check run events from GitHub. When a user
+// re-runs a check run created by Atlantis, or clicks its re-plan button, we
+// re-plan that project. It's exported to make testing easier.
+func (e *VCSEventsController) HandleGithubCheckRunEvent(ctx context.Context, w http.ResponseWriter, event *github.CheckRunEvent, githubReqID string) {
+	action := event.GetAction()
+	if action != "rerequested" && !(action == "requested_action" && event.GetRequestedAction().Identifier == events.PlanCheckRunAction) {
+		e.respond(w, logging.Debug, http.StatusOK, "Ignoring check run event since action was not rerequested or a plan request %s", githubReqID)
+		return
+	}
+
+	baseRepo, user, pullNum, err := e.Parser.ParseGithubCheckRunEvent(event)
+	if err != nil {
+		e.respond(w, logging.Error, http.StatusBadRequest, "Failed parsing event: %v %s", err, githubReqID)
+		return
+	}
+	cmd, err := events.NewCheckRunPlanCommand(event.GetCheckRun().GetExternalID())
+	if err != nil {
+		e.respond(w, logging.Debug, http.StatusOK, "Ignoring check run event since it wasn't created by Atlantis: %s %s", err, githubReqID)
+		return
+	}
+	e.Logger.Info("parsed check run event as %s", cmd)
+
+	if !e.RepoAllowlistChecker.IsAllowlisted(baseRepo.FullName, baseRepo.VCSHost.Hostname) {
+		e.commentNotAllowlisted(baseRepo, pullNum)
+		e.respond(w, logging.Warn, http.StatusForbidden, "Repo not allowlisted")
+		return
+	}
+
+	e.Logger.Debug("executing command")
+	fmt.Fprintln(w, "Processing...")
+	if !e.TestingMode {
+		// Respond with success and then actually execute the command asynchronously.
+		go e.CommandRunner.RunCommentCommand(tracing.Detach(ctx), baseRepo, nil, nil, user, pullNum, cmd)
+	} else {
+		// When testing we want to wait for everything to complete.
+		e.CommandRunner.RunCommentCommand(tracing.Detach(ctx), baseRepo, nil, nil, user, pullNum, cmd)
+	}
+}
+
+// HandleGithubPushEvent handles push events from GitHub. Pushes to the branch
+// that open pull requests will be merged into make their plans stale. It's
+// exported to make testing easier.
+func (e *VCSEventsController) HandleGithubPushEvent(w http.ResponseWriter, event *github.PushEvent, githubReqID string) {
+	baseRepo, branch, user, err := e.Parser.ParseGithubPushEvent(event)
+	if err != nil {
+		e.respond(w, logging.Error, http.StatusBadRequest, "Failed parsing event: %v %s", err, githubReqID)
+		return
+	}
+	e.handlePushEvent(w, baseRepo, branch, user)
+}
+
+// handlePushEvent updates the plans of the open pull requests into branch.
+func (e *VCSEventsController) handlePushEvent(w http.ResponseWriter, baseRepo models.Repo, branch string, user models.User) {
+	if branch == "" {
+		e.respond(w, logging.Debug, http.StatusOK, "Ignoring push event since it wasn't a push to a branch")
+		return
+	}
+	if !e.RepoAllowlistChecker.IsAllowlisted(baseRepo.FullName, baseRepo.VCSHost.Hostname) {
+		e.respond(w, logging.Debug, http.StatusForbidden,
+			"Ignoring push event from non-allowlisted repo \"%s/%s\"",
+			baseRepo.VCSHost.Hostname, baseRepo.FullName)
+		return
+	}
+
+	fmt.Fprintln(w, "Processing...")
+	e.Logger.Info("handling push to %s/%s", baseRepo.FullName, branch)
+	update := func() {
+		if err := e.BaseBranchUpdater.UpdateBaseBranch(baseRepo, branch, user); err != nil {
+			e.Logger.Err("handling push to %s/%s: %s", baseRepo.FullName, branch, err)
+		}
+	}
+	if !e.TestingMode {
+		// Respond with success and then update the pull requests
+		// asynchronously since re-planning can take a while.
+		go update()
+	} else {
+		// When testing we want to wait for everything to complete.
+		update()
+	}
+}
+
+// HandleGithubPullRequestReviewEvent handles review events from GitHub.
+// Approving a pull request auto-applies it if its repo sets auto_apply. It's
+// exported to make testing easier.
+func (e *VCSEventsController) HandleGithubPullRequestReviewEvent(w http.ResponseWriter, event *github.PullRequestReviewEvent, githubReqID string) {
+	if event.GetAction() != "submitted" || !strings.EqualFold(event.GetReview().GetState(), "approved") {
+		e.respond(w, logging.Debug, http.StatusOK, "Ignoring pull request review event since it wasn't an approval %s", githubReqID)
+		return
+	}
+	pull, baseRepo, headRepo, err := e.Parser.ParseGithubPull(event.GetPullRequest())
+	if err != nil {
+		e.respond(w, logging.Error, http.StatusBadRequest, "Error parsing pull data: %s %s", err, githubReqID)
+		return
+	}
+	user := models.User{Username: event.GetReview().GetUser().GetLogin()}
+	e.handleApprovalEvent(w, baseRepo, headRepo, pull, user)
+}
+
+// handleApprovalEvent auto-applies pull now that it's been approved by user.
+func (e *VCSEventsController) handleApprovalEvent(w http.ResponseWriter, baseRepo models.Repo, headRepo models.Repo, pull models.PullRequest, user models.User) {
+	if e.AutoApplier == nil {
+		e.respond(w, logging.Debug, http.StatusOK, "Ignoring approval event since auto-apply isn't configured")
+		return
+	}
+	if !e.RepoAllowlistChecker.IsAllowlisted(baseRepo.FullName, baseRepo.VCSHost.Hostname) {
+		e.respond(w, logging.Debug, http.StatusForbidden,
+			"Ignoring approval event from non-allowlisted repo \"%s/%s\"",
+			baseRepo.VCSHost.Hostname, baseRepo.FullName)
+		return
+	}
+
+	fmt.Fprintln(w, "Processing...")
+	e.Logger.Info("handling approval of %s#%d by %s", baseRepo.FullName, pull.Num, user.Username)
+	apply := func() {
+		if err := e.AutoApplier.MaybeApply(baseRepo, headRepo, pull, user); err != nil {
+			e.Logger.Err("auto-applying %s#%d: %s", baseRepo.FullName, pull.Num, err)
+		}
+	}
+	if !e.TestingMode {
+		// Respond with success and then apply asynchronously since applying
+		// can take a while.
+		go apply()
+	} else {
+		// When testing we want to wait for everything to complete.
+		apply()
+	}
 }
 
 // HandleBitbucketCloudCommentEvent handles comment events from Bitbucket.
-func (e *VCSEventsController) HandleBitbucketCloudCommentEvent(w http.ResponseWriter, body []byte, reqID string) {
+func (e *VCSEventsController) HandleBitbucketCloudCommentEvent(ctx context.Context, w http.ResponseWriter, body []byte, reqID string) {
 	pull, baseRepo, headRepo, user, comment, err := e.Parser.ParseBitbucketCloudPullCommentEvent(body)
 	if err != nil {
 		e.respond(w, logging.Error, http.StatusBadRequest, "Error parsing pull data: %s %s=%s", err, bitbucketCloudRequestIDHeader, reqID)
 		return
 	}
-	e.handleCommentEvent(w, baseRepo, &headRepo, &pull, user, pull.Num, comment, models.BitbucketCloud)
+	e.handleCommentEvent(ctx, w, baseRepo, &headRepo, &pull, user, pull.Num, 0, "", comment, models.BitbucketCloud)
 }
 
 // HandleBitbucketServerCommentEvent handles comment events from Bitbucket.
-func (e *VCSEventsController) HandleBitbucketServerCommentEvent(w http.ResponseWriter, body []byte, reqID string) {
+func (e *VCSEventsController) HandleBitbucketServerCommentEvent(ctx context.Context, w http.ResponseWriter, body []byte, reqID string) {
 	pull, baseRepo, headRepo, user, comment, err := e.Parser.ParseBitbucketServerPullCommentEvent(body)
 	if err != nil {
 		e.respond(w, logging.Error, http.StatusBadRequest, "Error parsing pull data: %s %s=%s", err, bitbucketCloudRequestIDHeader, reqID)
 		return
 	}
-	e.handleCommentEvent(w, baseRepo, &headRepo, &pull, user, pull.Num, comment, models.BitbucketCloud)
+	e.handleCommentEvent(ctx, w, baseRepo, &headRepo, &pull, user, pull.Num, 0, "", comment, models.BitbucketCloud)
 }
 
-func (e *VCSEventsController) handleBitbucketCloudPullRequestEvent(w http.ResponseWriter, eventType string, body []byte, reqID string) {
+func (e *VCSEventsController) handleBitbucketCloudPullRequestEvent(ctx context.Context, w http.ResponseWriter, eventType string, body []byte, reqID string) {
 	pull, baseRepo, headRepo, user, err := e.Parser.ParseBitbucketCloudPullEvent(body)
 	if err != nil {
 		e.respond(w, logging.Error, http.StatusBadRequest, "Error parsing pull data: %s %s=%s", err, bitbucketCloudRequestIDHeader, reqID)
@@ -292,10 +546,10 @@ func (e *VCSEventsController) handleBitbucketCloudPullRequestEvent(w http.Respon
 	}
 	pullEventType := e.Parser.GetBitbucketCloudPullEventType(eventType)
 	e.Logger.Info("identified event as type %q", pullEventType.String())
-	e.handlePullRequestEvent(w, baseRepo, headRepo, pull, user, pullEventType)
+	e.handlePullRequestEvent(ctx, w, baseRepo, headRepo, pull, user, pullEventType)
 }
 
-func (e *VCSEventsController) handleBitbucketServerPullRequestEvent(w http.ResponseWriter, eventType string, body []byte, reqID string) {
+func (e *VCSEventsController) handleBitbucketServerPullRequestEvent(ctx context.Context, w http.ResponseWriter, eventType string, body []byte, reqID string) {
 	pull, baseRepo, headRepo, user, err := e.Parser.ParseBitbucketServerPullEvent(body)
 	if err != nil {
 		e.respond(w, logging.Error, http.StatusBadRequest, "Error parsing pull data: %s %s=%s", err, bitbucketServerRequestIDHeader, reqID)
@@ -303,23 +557,26 @@ func (e *VCSEventsController) handleBitbucketServerPullRequestEvent(w http.Respo
 	}
 	pullEventType := e.Parser.GetBitbucketServerPullEventType(eventType)
 	e.Logger.Info("identified event as type %q", pullEventType.String())
-	e.handlePullRequestEvent(w, baseRepo, headRepo, pull, user, pullEventType)
+	e.handlePullRequestEvent(ctx, w, baseRepo, headRepo, pull, user, pullEventType)
 }
 
 // HandleGithubPullRequestEvent will delete any locks associated with the pull
 // request if the event is a pull request closed event. It's exported to make
 // testing easier.
-func (e *VCSEventsController) HandleGithubPullRequestEvent(w http.ResponseWriter, pullEvent *github.PullRequestEvent, githubReqID string) {
+func (e *VCSEventsController) HandleGithubPullRequestEvent(ctx context.Context, w http.ResponseWriter, pullEvent *github.PullRequestEvent, githubReqID string) {
 	pull, pullEventType, baseRepo, headRepo, user, err := e.Parser.ParseGithubPullEvent(pullEvent)
 	if err != nil {
 		e.respond(w, logging.Error, http.StatusBadRequest, "Error parsing pull data: %s %s", err, githubReqID)
 		return
 	}
 	e.Logger.Info("identified event as type %q", pullEventType.String())
-	e.handlePullRequestEvent(w, baseRepo, headRepo, pull, user, pullEventType)
+	if e.GithubRepoOnboarder != nil && e.RepoAllowlistChecker.IsAllowlisted(baseRepo.FullName, baseRepo.VCSHost.Hostname) {
+		e.GithubRepoOnboarder.Onboard(baseRepo, pullEvent.GetRepo().GetDefaultBranch())
+	}
+	e.handlePullRequestEvent(ctx, w, baseRepo, headRepo, pull, user, pullEventType)
 }
 
-func (e *VCSEventsController) handlePullRequestEvent(w http.ResponseWriter, baseRepo models.Repo, headRepo models.Repo, pull models.PullRequest, user models.User, eventType models.PullRequestEventType) {
+func (e *VCSEventsController) handlePullRequestEvent(ctx context.Context, w http.ResponseWriter, baseRepo models.Repo, headRepo models.Repo, pull models.PullRequest, user models.User, eventType models.PullRequestEventType) {
 	if !e.RepoAllowlistChecker.IsAllowlisted(baseRepo.FullName, baseRepo.VCSHost.Hostname) {
 		// If the repo isn't allowlisted and we receive an opened pull request
 		// event we comment back on the pull request that the repo isn't
@@ -345,10 +602,10 @@ func (e *VCSEventsController) handlePullRequestEvent(w http.ResponseWriter, base
 
 		e.Logger.Info("executing autoplan")
 		if !e.TestingMode {
-			go e.CommandRunner.RunAutoplanCommand(baseRepo, headRepo, pull, user)
+			go e.CommandRunner.RunAutoplanCommand(tracing.Detach(ctx), baseRepo, headRepo, pull, user)
 		} else {
 			// When testing we want to wait for everything to complete.
-			e.CommandRunner.RunAutoplanCommand(baseRepo, headRepo, pull, user)
+			e.CommandRunner.RunAutoplanCommand(tracing.Detach(ctx), baseRepo, headRepo, pull, user)
 		}
 		return
 	case models.ClosedPullEvent:
@@ -367,43 +624,96 @@ func (e *VCSEventsController) handlePullRequestEvent(w http.ResponseWriter, base
 	}
 }
 
-func (e *VCSEventsController) handleGitlabPost(w http.ResponseWriter, r *http.Request) {
-	event, err := e.GitlabRequestParserValidator.ParseAndValidate(r, e.GitlabWebhookSecret)
+func (e *VCSEventsController) handleGitlabPost(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	host := e.webhookHost(r, gitlabInstanceHeader)
+	event, err := e.GitlabRequestParserValidator.ParseAndValidate(r, e.webhookSecret(host, e.GitlabWebhookSecret))
 	if err != nil {
 		e.respond(w, logging.Warn, http.StatusBadRequest, err.Error())
 		return
 	}
-	e.Logger.Debug("request valid")
+	// Group webhooks send the events of every project in the group and its
+	// subgroups so the project is always taken from the event, not the
+	// webhook.
+	var project, cloneURL string
+	switch event := event.(type) {
+	case gitlab.MergeCommentEvent:
+		project, cloneURL = event.Project.PathWithNamespace, event.Project.GitHTTPURL
+	case gitlab.MergeEvent:
+		project, cloneURL = event.Project.PathWithNamespace, event.Project.GitHTTPURL
+	case gitlab.PushEvent:
+		project, cloneURL = event.Project.PathWithNamespace, event.Project.GitHTTPURL
+	}
+	if cloneURL != "" && !e.repoOnWebhookHost(host, cloneURL) {
+		e.respond(w, logging.Warn, http.StatusBadRequest, "Repo %s is not on the VCS host that sent this webhook", cloneURL)
+		return
+	}
+	e.Logger.Debug("request valid for project %q", project)
+	if e.isDuplicateDelivery(w, models.Gitlab, host, r.Header.Get(gitlabEventUUIDHeader)) {
+		return
+	}
 
 	switch event := event.(type) {
 	case gitlab.MergeCommentEvent:
 		e.Logger.Debug("handling as comment event")
-		e.HandleGitlabCommentEvent(w, event)
+		e.HandleGitlabCommentEvent(ctx, w, event)
 	case gitlab.MergeEvent:
 		e.Logger.Debug("handling as pull request event")
-		e.HandleGitlabMergeRequestEvent(w, event)
+		e.HandleGitlabMergeRequestEvent(ctx, w, event)
+	case gitlab.PushEvent:
+		e.Logger.Debug("handling as push event")
+		e.HandleGitlabPushEvent(w, event)
 	case gitlab.CommitCommentEvent:
 		e.Logger.Debug("comments on commits are not supported, only comments on merge requests")
 		e.respond(w, logging.Debug, http.StatusOK, "Ignoring comment on commit event")
+	case GitlabGroupEvent:
+		e.respond(w, logging.Debug, http.StatusOK, "Ignoring group event %q", event.EventName)
 	default:
 		e.respond(w, logging.Debug, http.StatusOK, "Ignoring unsupported event")
 	}
 
 }
 
+// HandleGitlabPushEvent handles push events from GitLab. Pushes to the branch
+// that open merge requests will be merged into make their plans stale. It's
+// exported to make testing easier.
+func (e *VCSEventsController) HandleGitlabPushEvent(w http.ResponseWriter, event gitlab.PushEvent) {
+	baseRepo, branch, user, err := e.Parser.ParseGitlabPushEvent(event)
+	if err != nil {
+		e.respond(w, logging.Error, http.StatusBadRequest, "Failed parsing event: %v", err)
+		return
+	}
+	e.handlePushEvent(w, baseRepo, branch, user)
+}
+
 // HandleGitlabCommentEvent handles comment events from GitLab where Atlantis
 // commands can come from. It's exported to make testing easier.
-func (e *VCSEventsController) HandleGitlabCommentEvent(w http.ResponseWriter, event gitlab.MergeCommentEvent) {
+func (e *VCSEventsController) HandleGitlabCommentEvent(ctx context.Context, w http.ResponseWriter, event gitlab.MergeCommentEvent) {
 	// todo: can gitlab return the pull request here too?
 	baseRepo, headRepo, user, err := e.Parser.ParseGitlabMergeRequestCommentEvent(event)
 	if err != nil {
 		e.respond(w, logging.Error, http.StatusBadRequest, "Error parsing webhook: %s", err)
 		return
 	}
-	e.handleCommentEvent(w, baseRepo, &headRepo, nil, user, event.MergeRequest.IID, event.ObjectAttributes.Note, models.Gitlab)
+	e.handleCommentEvent(ctx, w, baseRepo, &headRepo, nil, user, event.MergeRequest.IID, int64(event.ObjectAttributes.ID), gitlabDiscussionID(event), event.ObjectAttributes.Note, models.Gitlab)
+}
+
+// gitlabDiscussionID returns the id of the thread the comment was made in, ex.
+// a diff discussion, or an empty string if it's a top-level comment. GitLab
+// gives top-level comments a discussion id too but replying to it would turn
+// them into threads.
+func gitlabDiscussionID(event gitlab.MergeCommentEvent) string {
+	switch event.ObjectAttributes.Type {
+	case "DiffNote", "DiscussionNote":
+		return event.ObjectAttributes.DiscussionID
+	}
+	return ""
 }
 
-func (e *VCSEventsController) handleCommentEvent(w http.ResponseWriter, baseRepo models.Repo, maybeHeadRepo *models.Repo, maybePull *models.PullRequest, user models.User, pullNum int, comment string, vcsHost models.VCSHostType) {
+// handleCommentEvent runs the command in comment. commentID is the comment's
+// id or 0 if the VCS host doesn't support reacting to comments. discussionID
+// is the id of the thread the comment was made in, which replies are posted
+// to, or empty for top-level comments.
+func (e *VCSEventsController) handleCommentEvent(ctx context.Context, w http.ResponseWriter, baseRepo models.Repo, maybeHeadRepo *models.Repo, maybePull *models.PullRequest, user models.User, pullNum int, commentID int64, discussionID string, comment string, vcsHost models.VCSHostType) {
 	parseResult := e.CommentParser.Parse(comment, vcsHost)
 	if parseResult.Ignore {
 		truncated := comment
@@ -423,50 +733,78 @@ func (e *VCSEventsController) handleCommentEvent(w http.ResponseWriter, baseRepo
 		e.respond(w, logging.Warn, http.StatusForbidden, "Repo not allowlisted")
 		return
 	}
+	// The same comment can arrive in deliveries with different ids, ex. from
+	// both a repo and an organization webhook, or from hosts that don't send
+	// delivery ids, so each comment's command is only run once. Comment ids
+	// are only unique per host so the repo's hostname is part of the key.
+	if commentID != 0 && e.isDuplicateDelivery(w, vcsHost, baseRepo.VCSHost.Hostname, fmt.Sprintf("comment/%d", commentID)) {
+		return
+	}
 
 	// If the command isn't valid or doesn't require processing, ex.
 	// "atlantis help" then we just comment back immediately.
 	// We do this here rather than earlier because we need access to the pull
 	// variable to comment back on the pull request.
 	if parseResult.CommentResponse != "" {
-		if err := e.VCSClient.CreateComment(baseRepo, pullNum, parseResult.CommentResponse, ""); err != nil {
+		var err error
+		if discussionID != "" {
+			_, err = e.VCSClient.CreateReply(baseRepo, pullNum, discussionID, parseResult.CommentResponse, "")
+		} else {
+			err = e.VCSClient.CreateComment(baseRepo, pullNum, parseResult.CommentResponse, "")
+		}
+		if err != nil {
 			e.Logger.Err("unable to comment on pull request: %s", err)
 		}
 		e.respond(w, logging.Info, http.StatusOK, "Commenting back on pull request")
 		return
 	}
 
+	if parseResult.Command != nil {
+		parseResult.Command.CommentID = commentID
+		parseResult.Command.DiscussionID = discussionID
+	}
+	if e.ProgressComments && commentID != 0 && e.VCSClient.SupportsCommentUpdates(baseRepo) {
+		if err := e.VCSClient.ReactToComment(baseRepo, pullNum, commentID, receivedReaction); err != nil {
+			e.Logger.Warn("unable to react to comment: %s", err)
+		}
+	}
+
 	e.Logger.Debug("executing command")
 	fmt.Fprintln(w, "Processing...")
 	if !e.TestingMode {
 		// Respond with success and then actually execute the command asynchronously.
 		// We use a goroutine so that this function returns and the connection is
 		// closed.
-		go e.CommandRunner.RunCommentCommand(baseRepo, maybeHeadRepo, maybePull, user, pullNum, parseResult.Command)
+		go e.CommandRunner.RunCommentCommand(tracing.Detach(ctx), baseRepo, maybeHeadRepo, maybePull, user, pullNum, parseResult.Command)
 	} else {
 		// When testing we want to wait for everything to complete.
-		e.CommandRunner.RunCommentCommand(baseRepo, maybeHeadRepo, maybePull, user, pullNum, parseResult.Command)
+		e.CommandRunner.RunCommentCommand(tracing.Detach(ctx), baseRepo, maybeHeadRepo, maybePull, user, pullNum, parseResult.Command)
 	}
 }
 
 // HandleGitlabMergeRequestEvent will delete any locks associated with the pull
 // request if the event is a merge request closed event. It's exported to make
 // testing easier.
-func (e *VCSEventsController) HandleGitlabMergeRequestEvent(w http.ResponseWriter, event gitlab.MergeEvent) {
+func (e *VCSEventsController) HandleGitlabMergeRequestEvent(ctx context.Context, w http.ResponseWriter, event gitlab.MergeEvent) {
 	pull, pullEventType, baseRepo, headRepo, user, err := e.Parser.ParseGitlabMergeRequestEvent(event)
 	if err != nil {
 		e.respond(w, logging.Error, http.StatusBadRequest, "Error parsing webhook: %s", err)
 		return
 	}
+	// Approvals are reported as an update to the merge request.
+	if event.ObjectAttributes.Action == "approved" {
+		e.handleApprovalEvent(w, baseRepo, headRepo, pull, user)
+		return
+	}
 	e.Logger.Info("identified event as type %q", pullEventType.String())
-	e.handlePullRequestEvent(w, baseRepo, headRepo, pull, user, pullEventType)
+	e.handlePullRequestEvent(ctx, w, baseRepo, headRepo, pull, user, pullEventType)
 }
 
 // HandleAzureDevopsPullRequestCommentedEvent handles comment events from Azure DevOps where Atlantis
 // commands can come from. It's exported to make testing easier.
 // Sometimes we may want data from the parent azuredevops.Event struct, so we handle type checking here.
 // Requires Resource Version 2.0 of the Pull Request Commented On webhook payload.
-func (e *VCSEventsController) HandleAzureDevopsPullRequestCommentedEvent(w http.ResponseWriter, event *azuredevops.Event, azuredevopsReqID string) {
+func (e *VCSEventsController) HandleAzureDevopsPullRequestCommentedEvent(ctx context.Context, w http.ResponseWriter, event *azuredevops.Event, azuredevopsReqID string) {
 	resource, ok := event.Resource.(*azuredevops.GitPullRequestWithComment)
 	if !ok || event.PayloadType != azuredevops.PullRequestCommentedEvent {
 		e.respond(w, logging.Error, http.StatusBadRequest, "Event.Resource is nil or received bad event type %v; %s", event.Resource, azuredevopsReqID)
@@ -491,13 +829,13 @@ func (e *VCSEventsController) HandleAzureDevopsPullRequestCommentedEvent(w http.
 		e.respond(w, logging.Error, http.StatusBadRequest, "Error parsing pull request repository field: %s; %s", err, azuredevopsReqID)
 		return
 	}
-	e.handleCommentEvent(w, baseRepo, nil, nil, user, resource.PullRequest.GetPullRequestID(), string(strippedComment), models.AzureDevops)
+	e.handleCommentEvent(ctx, w, baseRepo, nil, nil, user, resource.PullRequest.GetPullRequestID(), 0, "", string(strippedComment), models.AzureDevops)
 }
 
 // HandleAzureDevopsPullRequestEvent will delete any locks associated with the pull
 // request if the event is a pull request closed event. It's exported to make
 // testing easier.
-func (e *VCSEventsController) HandleAzureDevopsPullRequestEvent(w http.ResponseWriter, event *azuredevops.Event, azuredevopsReqID string) {
+func (e *VCSEventsController) HandleAzureDevopsPullRequestEvent(ctx context.Context, w http.ResponseWriter, event *azuredevops.Event, azuredevopsReqID string) {
 	prText := event.Message.GetText()
 	ignoreEvents := []string{
 		"changed the reviewer list",
@@ -521,7 +859,48 @@ func (e *VCSEventsController) HandleAzureDevopsPullRequestEvent(w http.ResponseW
 		return
 	}
 	e.Logger.Info("identified event as type %q", pullEventType.String())
-	e.handlePullRequestEvent(w, baseRepo, headRepo, pull, user, pullEventType)
+	e.handlePullRequestEvent(ctx, w, baseRepo, headRepo, pull, user, pullEventType)
+}
+
+// isDuplicateDelivery claims the webhook delivery with id from the vcsHost
+// instance hostname and returns true, after responding, if it shouldn't be
+// handled because it was already claimed or we couldn't check. hostname is
+// empty for the default instance of vcsHost. Deliveries are always handled if
+// DeliveryClaimer isn't set or the VCS host didn't send a delivery id.
+func (e *VCSEventsController) isDuplicateDelivery(w http.ResponseWriter, vcsHost models.VCSHostType, hostname string, id string) bool {
+	if e.DeliveryClaimer == nil || id == "" {
+		return false
+	}
+	// Ids are only unique per instance, ex. two GitHub Enterprise hosts can
+	// send the same comment id.
+	key := fmt.Sprintf("%s/%s", vcsHost, id)
+	if hostname != "" {
+		key = fmt.Sprintf("%s/%s/%s", vcsHost, hostname, id)
+	}
+	claimed, err := e.DeliveryClaimer.ClaimDelivery(key)
+	if err != nil {
+		// Respond with an error so the VCS host retries the delivery.
+		e.respond(w, logging.Error, http.StatusServiceUnavailable, "Unable to claim delivery %s: %s", id, err)
+		return true
+	}
+	if !claimed {
+		e.respond(w, logging.Info, http.StatusOK, "Ignoring delivery %s since it was already handled", id)
+		return true
+	}
+	if cw, ok := w.(*claimingResponseWriter); ok {
+		cw.claimed = append(cw.claimed, key)
+	}
+	return false
+}
+
+// releaseDeliveries releases the claims on deliveries whose handling failed
+// so that they're handled when the VCS host, or an operator, redelivers them.
+func (e *VCSEventsController) releaseDeliveries(keys []string) {
+	for _, key := range keys {
+		if err := e.DeliveryClaimer.ReleaseDelivery(key); err != nil {
+			e.Logger.Err("unable to release delivery %s: %s", key, err)
+		}
+	}
 }
 
 // supportsHost returns true if h is in e.SupportedVCSHosts and false otherwise.
@@ -534,6 +913,47 @@ func (e *VCSEventsController) supportsHost(h models.VCSHostType) bool {
 	return false
 }
 
+// webhookHost returns the hostname of the additional VCS host that sent a
+// webhook, according to hostHeader, or an empty string if the webhook was
+// sent by the host configured with flags.
+func (e *VCSEventsController) webhookHost(r *http.Request, hostHeader string) string {
+	host := r.Header.Get(hostHeader)
+	// GitLab sends its URL rather than just its hostname.
+	if u, err := url.Parse(host); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	host = strings.ToLower(host)
+	if _, ok := e.HostWebhookSecrets[host]; ok {
+		return host
+	}
+	return ""
+}
+
+// webhookSecret returns the secret for webhooks sent by host, or
+// defaultSecret if host is empty.
+func (e *VCSEventsController) webhookSecret(host string, defaultSecret []byte) []byte {
+	if host == "" {
+		return defaultSecret
+	}
+	return e.HostWebhookSecrets[host]
+}
+
+// repoOnWebhookHost returns true if the repo cloned from cloneURL is on the
+// host that sent the webhook. Since the header saying which host sent it
+// isn't signed, this stops one host's secret being used to send webhooks for
+// repos on another host.
+func (e *VCSEventsController) repoOnWebhookHost(host string, cloneURL string) bool {
+	u, err := url.Parse(cloneURL)
+	if err != nil {
+		return false
+	}
+	repoHost := strings.ToLower(u.Hostname())
+	if _, ok := e.HostWebhookSecrets[repoHost]; !ok {
+		repoHost = ""
+	}
+	return repoHost == host
+}
+
 func (e *VCSEventsController) respond(w http.ResponseWriter, lvl logging.LogLevel, code int, format string, args ...interface{}) {
 	response := fmt.Sprintf(format, args...)
 	e.Logger.Log(lvl, response)
@@ -674,7 +674,7 @@ func setupE2E(t *testing.T, repoDir string) (events_controllers.VCSEventsControl
 		GithubUser: "github-user",
 		GitlabUser: "gitlab-user",
 	}
-	terraformClient, err := terraform.NewClient(logger, binDir, cacheDir, "", "", "", "default-tf-version", "https://releases.hashicorp.com", &NoopTFDownloader{}, false)
+	terraformClient, err := terraform.NewClient(logger, binDir, cacheDir, "", "", nil, nil, "", "default-tf-version", "terraform", "https://releases.hashicorp.com", "https://github.com/opentofu/opentofu/releases/download", "", &NoopTFDownloader{}, false, 0)
 	Ok(t, err)
 	boltdb, err := db.New(dataDir)
 	Ok(t, err)
@@ -716,10 +716,11 @@ func setupE2E(t *testing.T, repoDir string) (events_controllers.VCSEventsControl
 	parallelPoolSize := 1
 	silenceNoProjects := false
 
+	globalCfgStore := valid.NewGlobalCfgStore(globalCfg)
 	mockPreWorkflowHookRunner = runtimemocks.NewMockPreWorkflowHookRunner()
 	preWorkflowHooksCommandRunner := &events.DefaultPreWorkflowHooksCommandRunner{
 		VCSClient:             e2eVCSClient,
-		GlobalCfg:             globalCfg,
+		GlobalCfg:             globalCfgStore,
 		WorkingDirLocker:      locker,
 		WorkingDir:            workingDir,
 		PreWorkflowHookRunner: mockPreWorkflowHookRunner,
@@ -731,12 +732,14 @@ func setupE2E(t *testing.T, repoDir string) (events_controllers.VCSEventsControl
 		e2eVCSClient,
 		workingDir,
 		locker,
-		globalCfg,
+		globalCfgStore,
 		&events.DefaultPendingPlanFinder{},
 		commentParser,
 		false,
 		false,
 		"**/*.tf,**/*.tfvars,**/*.tfvars.json,**/terragrunt.hcl",
+		terraformClient,
+		false,
 	)
 
 	showStepRunner, err := runtime.NewShowStepRunner(terraformClient, defaultTFVersion)
@@ -848,6 +851,7 @@ func setupE2E(t *testing.T, repoDir string) (events_controllers.VCSEventsControl
 		projectCommandRunner,
 		pullUpdater,
 		dbUpdater,
+		e2eVCSClient,
 		silenceNoProjects,
 		false,
 	)
@@ -886,6 +890,7 @@ func setupE2E(t *testing.T, repoDir string) (events_controllers.VCSEventsControl
 		Drainer:                       drainer,
 		PreWorkflowHooksCommandRunner: preWorkflowHooksCommandRunner,
 		PullStatusFetcher:             boltdb,
+		GlobalCfg:                     globalCfgStore,
 	}
 
 	repoAllowlistChecker, err := events.NewRepoAllowlistChecker("*")
@@ -15,6 +15,7 @@ package events_test
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -102,6 +103,71 @@ func TestPost_InvalidGitlabSecret(t *testing.T) {
 	ResponseContains(t, w, http.StatusBadRequest, "err")
 }
 
+func TestPost_GithubHostWebhookSecret(t *testing.T) {
+	e, v, _, _, _, _, _, _ := setup(t)
+	hostSecret := []byte("ghe-secret")
+	e.HostWebhookSecrets = map[string][]byte{"ghe.example.com": hostSecret}
+	cases := []struct {
+		description string
+		host        string
+		cloneURL    string
+		expCode     int
+		expBody     string
+	}{
+		{
+			"webhooks from the host are validated with its secret",
+			"GHE.example.com",
+			"https://ghe.example.com/owner/repo.git",
+			http.StatusOK,
+			"Ignoring unsupported event",
+		},
+		{
+			"the host can't send webhooks for repos on other hosts",
+			"ghe.example.com",
+			"https://github.com/owner/repo.git",
+			http.StatusBadRequest,
+			"Repo https://github.com/owner/repo.git is not on the VCS host that sent this webhook",
+		},
+		{
+			"other hosts can't send webhooks for repos on the host",
+			"",
+			"https://ghe.example.com/owner/repo.git",
+			http.StatusBadRequest,
+			"Repo https://ghe.example.com/owner/repo.git is not on the VCS host that sent this webhook",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+			req.Header.Set(githubHeader, "value")
+			req.Header.Set("X-Github-Enterprise-Host", c.host)
+			expSecret := secret
+			if c.host != "" {
+				expSecret = hostSecret
+			}
+			When(v.Validate(req, expSecret)).ThenReturn([]byte(fmt.Sprintf(`{"repository": {"clone_url": %q}}`, c.cloneURL)), nil)
+			w := httptest.NewRecorder()
+			e.Post(w, req)
+			ResponseContains(t, w, c.expCode, c.expBody)
+		})
+	}
+}
+
+func TestPost_GitlabHostWebhookSecret(t *testing.T) {
+	e, _, gl, _, _, _, _, _ := setup(t)
+	hostSecret := []byte("gitlab-secret")
+	e.HostWebhookSecrets = map[string][]byte{"gitlab.example.com": hostSecret}
+	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+	req.Header.Set(gitlabHeader, "value")
+	req.Header.Set("X-Gitlab-Instance", "https://gitlab.example.com")
+	var event gitlab.PushEvent
+	event.Project.GitHTTPURL = "https://gitlab.com/owner/repo.git"
+	When(gl.ParseAndValidate(req, hostSecret)).ThenReturn(event, nil)
+	w := httptest.NewRecorder()
+	e.Post(w, req)
+	ResponseContains(t, w, http.StatusBadRequest, "Repo https://gitlab.com/owner/repo.git is not on the VCS host that sent this webhook")
+}
+
 func TestPost_UnsupportedGithubEvent(t *testing.T) {
 	t.Log("when the event type is an unsupported github event we ignore it")
 	e, v, _, _, _, _, _, _ := setup(t)
@@ -136,6 +202,17 @@ func TestPost_GitlabCommentOnCommit(t *testing.T) {
 	ResponseContains(t, w, http.StatusOK, "Ignoring comment on commit event")
 }
 
+func TestPost_GitlabGroupEvent(t *testing.T) {
+	t.Log("when the event is about a group, not one of its projects, we ignore it")
+	e, _, gl, _, _, _, _, _ := setup(t)
+	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+	w := httptest.NewRecorder()
+	req.Header.Set(gitlabHeader, "value")
+	When(gl.ParseAndValidate(req, secret)).ThenReturn(events_controllers.GitlabGroupEvent{EventName: "subgroup_create"}, nil)
+	e.Post(w, req)
+	ResponseContains(t, w, http.StatusOK, `Ignoring group event "subgroup_create"`)
+}
+
 func TestPost_GithubCommentNotCreated(t *testing.T) {
 	t.Log("when the event is a github comment but it's not a created event we ignore it")
 	e, v, _, _, _, _, _, _ := setup(t)
@@ -316,6 +393,45 @@ func TestPost_GitlabCommentResponse(t *testing.T) {
 	ResponseContains(t, w, http.StatusOK, "Commenting back on pull request")
 }
 
+func TestPost_GitlabDiscussionCommentResponse(t *testing.T) {
+	// When the comment is in a diff discussion we reply in the discussion.
+	e, _, gl, _, _, _, vcsClient, cp := setup(t)
+	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+	req.Header.Set(gitlabHeader, "value")
+	event := gitlab.MergeCommentEvent{}
+	event.ObjectAttributes.Type = "DiffNote"
+	event.ObjectAttributes.DiscussionID = "abc123"
+	When(gl.ParseAndValidate(req, secret)).ThenReturn(event, nil)
+	When(cp.Parse("", models.Gitlab)).ThenReturn(events.CommentParseResult{CommentResponse: "a comment"})
+	w := httptest.NewRecorder()
+	e.Post(w, req)
+	vcsClient.VerifyWasCalledOnce().CreateReply(models.Repo{}, 0, "abc123", "a comment", "")
+	vcsClient.VerifyWasCalled(Never()).CreateComment(matchers.AnyModelsRepo(), AnyInt(), AnyString(), AnyString())
+	ResponseContains(t, w, http.StatusOK, "Commenting back on pull request")
+}
+
+func TestPost_GitlabDiscussionCommentSuccess(t *testing.T) {
+	// The command should know the discussion so its results are replied in it.
+	e, _, gl, _, cr, _, _, cp := setup(t)
+	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+	req.Header.Set(gitlabHeader, "value")
+	event := gitlab.MergeCommentEvent{}
+	event.ObjectAttributes.ID = 5
+	event.ObjectAttributes.Type = "DiscussionNote"
+	event.ObjectAttributes.DiscussionID = "abc123"
+	When(gl.ParseAndValidate(req, secret)).ThenReturn(event, nil)
+	When(cp.Parse("", models.Gitlab)).ThenReturn(events.CommentParseResult{Command: &events.CommentCommand{Name: models.PlanCommand}})
+	w := httptest.NewRecorder()
+	e.Post(w, req)
+	ResponseContains(t, w, http.StatusOK, "Processing...")
+
+	cr.VerifyWasCalledOnce().RunCommentCommand(context.Background(), models.Repo{}, &models.Repo{}, nil, models.User{}, 0, &events.CommentCommand{
+		Name:         models.PlanCommand,
+		CommentID:    5,
+		DiscussionID: "abc123",
+	})
+}
+
 func TestPost_GithubCommentResponse(t *testing.T) {
 	t.Log("when the event is a github comment that warrants a comment response we comment back")
 	e, v, _, p, _, _, vcsClient, cp := setup(t)
@@ -344,7 +460,7 @@ func TestPost_GitlabCommentSuccess(t *testing.T) {
 	e.Post(w, req)
 	ResponseContains(t, w, http.StatusOK, "Processing...")
 
-	cr.VerifyWasCalledOnce().RunCommentCommand(models.Repo{}, &models.Repo{}, nil, models.User{}, 0, nil)
+	cr.VerifyWasCalledOnce().RunCommentCommand(context.Background(), models.Repo{}, &models.Repo{}, nil, models.User{}, 0, nil)
 }
 
 func TestPost_GithubCommentSuccess(t *testing.T) {
@@ -363,7 +479,278 @@ func TestPost_GithubCommentSuccess(t *testing.T) {
 	e.Post(w, req)
 	ResponseContains(t, w, http.StatusOK, "Processing...")
 
-	cr.VerifyWasCalledOnce().RunCommentCommand(baseRepo, nil, nil, user, 1, &cmd)
+	cr.VerifyWasCalledOnce().RunCommentCommand(context.Background(), baseRepo, nil, nil, user, 1, &cmd)
+}
+
+func TestPost_GithubCommentProgressReaction(t *testing.T) {
+	t.Log("when progress comments are enabled we react to the comment before running the command")
+	e, v, _, p, cr, _, vcsClient, cp := setup(t)
+	e.ProgressComments = true
+	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+	req.Header.Set(githubHeader, "issue_comment")
+	event := `{"action": "created", "comment": {"id": 123}}`
+	When(v.Validate(req, secret)).ThenReturn([]byte(event), nil)
+	baseRepo := models.Repo{}
+	user := models.User{}
+	cmd := events.CommentCommand{}
+	When(p.ParseGithubIssueCommentEvent(matchers.AnyPtrToGithubIssueCommentEvent())).ThenReturn(baseRepo, user, 1, nil)
+	When(cp.Parse("", models.Github)).ThenReturn(events.CommentParseResult{Command: &cmd})
+	When(vcsClient.SupportsCommentUpdates(baseRepo)).ThenReturn(true)
+	w := httptest.NewRecorder()
+	e.Post(w, req)
+	ResponseContains(t, w, http.StatusOK, "Processing...")
+
+	vcsClient.VerifyWasCalledOnce().ReactToComment(baseRepo, 1, int64(123), "eyes")
+	cr.VerifyWasCalledOnce().RunCommentCommand(context.Background(), baseRepo, nil, nil, user, 1, &events.CommentCommand{CommentID: 123})
+}
+
+func TestPost_GithubDuplicateDelivery(t *testing.T) {
+	t.Log("when a delivery was already claimed we don't handle it again")
+	e, v, _, p, cr, _, _, cp := setup(t)
+	e.DeliveryClaimer = &mapDeliveryClaimer{claimed: map[string]bool{}}
+	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+	req.Header.Set(githubHeader, "issue_comment")
+	req.Header.Set("X-Github-Delivery", "delivery-id")
+	When(v.Validate(req, secret)).ThenReturn([]byte(`{"action": "created"}`), nil)
+	baseRepo := models.Repo{}
+	user := models.User{}
+	cmd := events.CommentCommand{}
+	When(p.ParseGithubIssueCommentEvent(matchers.AnyPtrToGithubIssueCommentEvent())).ThenReturn(baseRepo, user, 1, nil)
+	When(cp.Parse("", models.Github)).ThenReturn(events.CommentParseResult{Command: &cmd})
+
+	w := httptest.NewRecorder()
+	e.Post(w, req)
+	ResponseContains(t, w, http.StatusOK, "Processing...")
+
+	w = httptest.NewRecorder()
+	e.Post(w, req)
+	ResponseContains(t, w, http.StatusOK, "Ignoring delivery delivery-id since it was already handled")
+	cr.VerifyWasCalledOnce().RunCommentCommand(context.Background(), baseRepo, nil, nil, user, 1, &cmd)
+}
+
+func TestPost_GithubFailedDeliveryIsReleased(t *testing.T) {
+	t.Log("when handling a delivery fails it's handled again when it's redelivered")
+	e, v, _, p, cr, _, _, cp := setup(t)
+	e.DeliveryClaimer = &mapDeliveryClaimer{claimed: map[string]bool{}}
+	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+	req.Header.Set(githubHeader, "issue_comment")
+	req.Header.Set("X-Github-Delivery", "delivery-id")
+	When(v.Validate(req, secret)).ThenReturn([]byte(`{"action": "created"}`), nil)
+	baseRepo := models.Repo{}
+	user := models.User{}
+	cmd := events.CommentCommand{}
+	When(p.ParseGithubIssueCommentEvent(matchers.AnyPtrToGithubIssueCommentEvent())).
+		ThenReturn(baseRepo, user, 1, errors.New("err")).
+		ThenReturn(baseRepo, user, 1, nil)
+	When(cp.Parse("", models.Github)).ThenReturn(events.CommentParseResult{Command: &cmd})
+
+	w := httptest.NewRecorder()
+	e.Post(w, req)
+	ResponseContains(t, w, http.StatusBadRequest, "Failed parsing event")
+
+	w = httptest.NewRecorder()
+	e.Post(w, req)
+	ResponseContains(t, w, http.StatusOK, "Processing...")
+	cr.VerifyWasCalledOnce().RunCommentCommand(context.Background(), baseRepo, nil, nil, user, 1, &cmd)
+}
+
+func TestPost_GithubDuplicateComment(t *testing.T) {
+	t.Log("when a comment was already handled in another delivery we don't run its command again")
+	e, v, _, p, cr, _, _, cp := setup(t)
+	e.DeliveryClaimer = &mapDeliveryClaimer{claimed: map[string]bool{}}
+	baseRepo := models.Repo{}
+	user := models.User{}
+	When(p.ParseGithubIssueCommentEvent(matchers.AnyPtrToGithubIssueCommentEvent())).ThenReturn(baseRepo, user, 1, nil)
+	When(cp.Parse("", models.Github)).ThenReturn(events.CommentParseResult{Command: &events.CommentCommand{}})
+
+	for i, expBody := range []string{"Processing...", "Ignoring delivery comment/123 since it was already handled"} {
+		req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+		req.Header.Set(githubHeader, "issue_comment")
+		req.Header.Set("X-Github-Delivery", fmt.Sprintf("delivery-%d", i))
+		When(v.Validate(req, secret)).ThenReturn([]byte(`{"action": "created", "comment": {"id": 123}}`), nil)
+		w := httptest.NewRecorder()
+		e.Post(w, req)
+		ResponseContains(t, w, http.StatusOK, expBody)
+	}
+	cr.VerifyWasCalledOnce().RunCommentCommand(context.Background(), baseRepo, nil, nil, user, 1, &events.CommentCommand{CommentID: 123})
+}
+
+func TestPost_GithubSameCommentIDOnTwoHosts(t *testing.T) {
+	t.Log("comments with the same id on different hosts are both handled")
+	e, v, _, p, cr, _, _, cp := setup(t)
+	e.DeliveryClaimer = &mapDeliveryClaimer{claimed: map[string]bool{}}
+	githubRepo := models.Repo{VCSHost: models.VCSHost{Hostname: "github.com", Type: models.Github}}
+	gheRepo := models.Repo{VCSHost: models.VCSHost{Hostname: "ghe.example.com", Type: models.Github}}
+	user := models.User{}
+	When(p.ParseGithubIssueCommentEvent(matchers.AnyPtrToGithubIssueCommentEvent())).
+		ThenReturn(githubRepo, user, 1, nil).
+		ThenReturn(gheRepo, user, 1, nil)
+	When(cp.Parse("", models.Github)).ThenReturn(events.CommentParseResult{Command: &events.CommentCommand{}})
+
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+		req.Header.Set(githubHeader, "issue_comment")
+		req.Header.Set("X-Github-Delivery", fmt.Sprintf("delivery-%d", i))
+		When(v.Validate(req, secret)).ThenReturn([]byte(`{"action": "created", "comment": {"id": 123}}`), nil)
+		w := httptest.NewRecorder()
+		e.Post(w, req)
+		ResponseContains(t, w, http.StatusOK, "Processing...")
+	}
+	cr.VerifyWasCalledOnce().RunCommentCommand(context.Background(), githubRepo, nil, nil, user, 1, &events.CommentCommand{CommentID: 123})
+	cr.VerifyWasCalledOnce().RunCommentCommand(context.Background(), gheRepo, nil, nil, user, 1, &events.CommentCommand{CommentID: 123})
+}
+
+func TestPost_GithubClaimDeliveryErr(t *testing.T) {
+	t.Log("when we can't claim a delivery we respond with an error so it's retried")
+	e, v, _, _, _, _, _, _ := setup(t)
+	e.DeliveryClaimer = &mapDeliveryClaimer{err: errors.New("connection refused")}
+	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+	req.Header.Set(githubHeader, "issue_comment")
+	req.Header.Set("X-Github-Delivery", "delivery-id")
+	When(v.Validate(req, secret)).ThenReturn([]byte(`{"action": "created"}`), nil)
+	w := httptest.NewRecorder()
+	e.Post(w, req)
+	ResponseContains(t, w, http.StatusServiceUnavailable, "Unable to claim delivery delivery-id: connection refused")
+}
+
+func TestPost_GithubCheckRunIgnoredAction(t *testing.T) {
+	t.Log("when the event is a github check run that wasn't re-requested we ignore it")
+	e, v, _, _, cr, _, _, _ := setup(t)
+	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+	req.Header.Set(githubHeader, "check_run")
+	event := `{"action": "completed"}`
+	When(v.Validate(req, secret)).ThenReturn([]byte(event), nil)
+	w := httptest.NewRecorder()
+	e.Post(w, req)
+	ResponseContains(t, w, http.StatusOK, "Ignoring check run event")
+
+	cr.VerifyWasCalled(Never()).RunCommentCommand(matchers.AnyContextContext(), matchers.AnyModelsRepo(), matchers.AnyPtrToModelsRepo(), matchers.AnyPtrToModelsPullRequest(), matchers.AnyModelsUser(), AnyInt(), matchers.AnyPtrToEventsCommentCommand())
+}
+
+func TestPost_GithubCheckRunRerequested(t *testing.T) {
+	t.Log("when a github check run created by atlantis is re-requested we re-plan the project")
+	e, v, _, p, cr, _, _, _ := setup(t)
+	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+	req.Header.Set(githubHeader, "check_run")
+	event := `{"action": "rerequested", "check_run": {"external_id": "project=myproject"}}`
+	When(v.Validate(req, secret)).ThenReturn([]byte(event), nil)
+	baseRepo := models.Repo{}
+	user := models.User{}
+	When(p.ParseGithubCheckRunEvent(matchers.AnyPtrToGithubCheckRunEvent())).ThenReturn(baseRepo, user, 1, nil)
+	w := httptest.NewRecorder()
+	e.Post(w, req)
+	ResponseContains(t, w, http.StatusOK, "Processing...")
+
+	cr.VerifyWasCalledOnce().RunCommentCommand(context.Background(), baseRepo, nil, nil, user, 1, &events.CommentCommand{Name: models.PlanCommand, ProjectName: "myproject"})
+}
+
+func TestPost_GithubPushEvent(t *testing.T) {
+	t.Log("when a branch is pushed to we update the pull requests into it")
+	e, v, _, p, _, _, _, _ := setup(t)
+	updater := emocks.NewMockBaseBranchUpdater()
+	e.BaseBranchUpdater = updater
+	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+	req.Header.Set(githubHeader, "push")
+	When(v.Validate(req, secret)).ThenReturn([]byte(`{"ref": "refs/heads/main"}`), nil)
+	repo := models.Repo{FullName: "owner/repo"}
+	user := models.User{Username: "pusher"}
+	When(p.ParseGithubPushEvent(matchers.AnyPtrToGithubPushEvent())).ThenReturn(repo, "main", user, nil)
+	w := httptest.NewRecorder()
+	e.Post(w, req)
+	ResponseContains(t, w, http.StatusOK, "Processing...")
+	updater.VerifyWasCalledOnce().UpdateBaseBranch(repo, "main", user)
+}
+
+func TestPost_GitlabPushEvent(t *testing.T) {
+	t.Log("when a branch is pushed to we update the merge requests into it")
+	e, _, gl, p, _, _, _, _ := setup(t)
+	updater := emocks.NewMockBaseBranchUpdater()
+	e.BaseBranchUpdater = updater
+	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+	req.Header.Set(gitlabHeader, "value")
+	When(gl.ParseAndValidate(req, secret)).ThenReturn(gitlab.PushEvent{}, nil)
+	repo := models.Repo{FullName: "owner/repo"}
+	user := models.User{Username: "pusher"}
+	When(p.ParseGitlabPushEvent(gitlab.PushEvent{})).ThenReturn(repo, "main", user, nil)
+	w := httptest.NewRecorder()
+	e.Post(w, req)
+	ResponseContains(t, w, http.StatusOK, "Processing...")
+	updater.VerifyWasCalledOnce().UpdateBaseBranch(repo, "main", user)
+}
+
+func TestPost_GithubPushEventIgnored(t *testing.T) {
+	cases := []struct {
+		description string
+		branch      string
+		allowlist   string
+		expCode     int
+		expBody     string
+	}{
+		{"not a branch", "", "*", http.StatusOK, "Ignoring push event since it wasn't a push to a branch"},
+		{"not allowlisted", "main", "github.com/nevermatch", http.StatusForbidden, "Ignoring push event from non-allowlisted repo"},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			e, v, _, p, _, _, _, _ := setup(t)
+			updater := emocks.NewMockBaseBranchUpdater()
+			e.BaseBranchUpdater = updater
+			var err error
+			e.RepoAllowlistChecker, err = events.NewRepoAllowlistChecker(c.allowlist)
+			Ok(t, err)
+			req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+			req.Header.Set(githubHeader, "push")
+			When(v.Validate(req, secret)).ThenReturn([]byte(`{"ref": "refs/tags/v1"}`), nil)
+			repo := models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Hostname: "github.com"}}
+			When(p.ParseGithubPushEvent(matchers.AnyPtrToGithubPushEvent())).ThenReturn(repo, c.branch, models.User{}, nil)
+			w := httptest.NewRecorder()
+			e.Post(w, req)
+			ResponseContains(t, w, c.expCode, c.expBody)
+			updater.VerifyWasCalled(Never()).UpdateBaseBranch(matchers.AnyModelsRepo(), AnyString(), matchers.AnyModelsUser())
+		})
+	}
+}
+
+func TestPost_GithubPullRequestReviewApproved(t *testing.T) {
+	t.Log("when a pull request is approved we try to auto-apply it")
+	e, v, _, p, _, _, _, _ := setup(t)
+	applier := emocks.NewMockAutoApplier()
+	e.AutoApplier = applier
+	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+	req.Header.Set(githubHeader, "pull_request_review")
+	When(v.Validate(req, secret)).ThenReturn([]byte(`{"action": "submitted", "review": {"state": "approved", "user": {"login": "approver"}}, "pull_request": {}}`), nil)
+	repo := models.Repo{FullName: "owner/repo"}
+	pull := models.PullRequest{Num: 1}
+	When(p.ParseGithubPull(matchers.AnyPtrToGithubPullRequest())).ThenReturn(pull, repo, repo, nil)
+	w := httptest.NewRecorder()
+	e.Post(w, req)
+	ResponseContains(t, w, http.StatusOK, "Processing...")
+	applier.VerifyWasCalledOnce().MaybeApply(repo, repo, pull, models.User{Username: "approver"})
+}
+
+func TestPost_GithubPullRequestReviewIgnored(t *testing.T) {
+	cases := []struct {
+		description string
+		action      string
+		state       string
+	}{
+		{"commented", "submitted", "commented"},
+		{"changes requested", "submitted", "changes_requested"},
+		{"dismissed", "dismissed", "approved"},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			e, v, _, _, _, _, _, _ := setup(t)
+			applier := emocks.NewMockAutoApplier()
+			e.AutoApplier = applier
+			req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+			req.Header.Set(githubHeader, "pull_request_review")
+			When(v.Validate(req, secret)).ThenReturn([]byte(fmt.Sprintf(`{"action": %q, "review": {"state": %q}}`, c.action, c.state)), nil)
+			w := httptest.NewRecorder()
+			e.Post(w, req)
+			ResponseContains(t, w, http.StatusOK, "Ignoring pull request review event since it wasn't an approval")
+			applier.VerifyWasCalled(Never()).MaybeApply(matchers.AnyModelsRepo(), matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), matchers.AnyModelsUser())
+		})
+	}
 }
 
 func TestPost_GithubPullRequestInvalid(t *testing.T) {
@@ -609,6 +996,50 @@ func TestPost_GitlabMergeRequestSuccess(t *testing.T) {
 	ResponseContains(t, w, http.StatusOK, "Pull request cleaned successfully")
 }
 
+// Bitbucket Cloud requests must be signed with the webhook secret if one is set.
+func TestPost_BBCloudInvalidSecret(t *testing.T) {
+	cases := []struct {
+		description string
+		sig         string
+	}{
+		{
+			"missing signature",
+			"",
+		},
+		{
+			"wrong signature",
+			"sha256=eec7f1700cc1636c63d5f3d21e9cce5f5114b789bdfb7ce53468d155d8f2f9ba",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			p := emocks.NewMockEventParsing()
+			ec := &events_controllers.VCSEventsController{
+				Parser:                 p,
+				BitbucketWebhookSecret: []byte("mysecret"),
+				SupportedVCSHosts:      []models.VCSHostType{models.BitbucketCloud},
+				Logger:                 logging.NewNoopLogger(t),
+			}
+			req, err := http.NewRequest("POST", "/events", bytes.NewBuffer([]byte(`{"key":"other"}`)))
+			Ok(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Event-Key", "pullrequest:created")
+			req.Header.Set("X-Request-UUID", "request-id")
+			if c.sig != "" {
+				req.Header.Set("X-Hub-Signature", c.sig)
+			}
+
+			w := httptest.NewRecorder()
+			ec.Post(w, req)
+
+			ResponseContains(t, w, http.StatusBadRequest, "request did not pass validation")
+			p.VerifyWasCalled(Never()).ParseBitbucketCloudPullEvent(matchers.AnySliceOfByte())
+		})
+	}
+}
+
 // Test Bitbucket server pull closed events.
 func TestPost_BBServerPullClosed(t *testing.T) {
 	cases := []struct {
@@ -743,7 +1174,7 @@ func TestPost_PullOpenedOrUpdated(t *testing.T) {
 			w := httptest.NewRecorder()
 			e.Post(w, req)
 			ResponseContains(t, w, http.StatusOK, "Processing...")
-			cr.VerifyWasCalledOnce().RunAutoplanCommand(models.Repo{}, models.Repo{}, models.PullRequest{State: models.ClosedPullState}, models.User{})
+			cr.VerifyWasCalledOnce().RunAutoplanCommand(context.Background(), models.Repo{}, models.Repo{}, models.PullRequest{State: models.ClosedPullState}, models.User{})
 		})
 	}
 }
@@ -776,3 +1207,25 @@ func setup(t *testing.T) (events_controllers.VCSEventsController, *mocks.MockGit
 	}
 	return e, v, gl, p, cr, c, vcsmock, cp
 }
+
+// mapDeliveryClaimer is a DeliveryClaimer that keeps claims in memory.
+type mapDeliveryClaimer struct {
+	claimed map[string]bool
+	err     error
+}
+
+func (m *mapDeliveryClaimer) ClaimDelivery(id string) (bool, error) {
+	if m.err != nil {
+		return false, m.err
+	}
+	if m.claimed[id] {
+		return false, nil
+	}
+	m.claimed[id] = true
+	return true, nil
+}
+
+func (m *mapDeliveryClaimer) ReleaseDelivery(id string) error {
+	delete(m.claimed, id)
+	return nil
+}
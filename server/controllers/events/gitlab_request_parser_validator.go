@@ -44,12 +44,24 @@ type GitlabRequestParserValidator interface {
 	//		// handle
 	//	case gitlab.MergeEvent:
 	//		// handle
+	//	case gitlab.PushEvent:
+	//		// handle
+	//	case GitlabGroupEvent:
+	//		// ignore
 	//	default:
 	//		// unsupported event
 	//	}
 	ParseAndValidate(r *http.Request, secret []byte) (interface{}, error)
 }
 
+// GitlabGroupEvent is an event that GitLab group webhooks send about the
+// group itself, ex. a subgroup or project was created, rather than about one
+// of its projects' merge requests. Atlantis has nothing to do for them.
+type GitlabGroupEvent struct {
+	// EventName is the kind of event, ex. subgroup_create.
+	EventName string `json:"event_name"`
+}
+
 // DefaultGitlabRequestParserValidator parses and validates GitLab requests.
 type DefaultGitlabRequestParserValidator struct{}
 
@@ -58,6 +70,12 @@ type DefaultGitlabRequestParserValidator struct{}
 func (d *DefaultGitlabRequestParserValidator) ParseAndValidate(r *http.Request, secret []byte) (interface{}, error) {
 	const mergeEventHeader = "Merge Request Hook"
 	const noteEventHeader = "Note Hook"
+	const pushEventHeader = "Push Hook"
+	// Group webhooks also send the same merge request, note and push events
+	// as project webhooks for every project in the group and its subgroups.
+	const subgroupEventHeader = "Subgroup Hook"
+	const memberEventHeader = "Member Hook"
+	const projectEventHeader = "Project Hook"
 
 	// Validate secret if specified.
 	headerSecret := r.Header.Get(secretHeader)
@@ -79,6 +97,18 @@ func (d *DefaultGitlabRequestParserValidator) ParseAndValidate(r *http.Request,
 			return nil, err
 		}
 		return m, nil
+	case pushEventHeader:
+		var p gitlab.PushEvent
+		if err := json.Unmarshal(bytes, &p); err != nil {
+			return nil, err
+		}
+		return p, nil
+	case subgroupEventHeader, memberEventHeader, projectEventHeader:
+		var g GitlabGroupEvent
+		if err := json.Unmarshal(bytes, &g); err != nil {
+			return nil, err
+		}
+		return g, nil
 	case noteEventHeader:
 		// First, parse a small part of the json to determine if this is a
 		// comment on a merge request or a commit.
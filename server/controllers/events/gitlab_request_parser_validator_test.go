@@ -138,6 +138,36 @@ func TestValidate_ValidMergeCommentEvent(t *testing.T) {
 	Equals(t, "Gitlab Test", b.(gitlab.MergeCommentEvent).Project.Name)
 }
 
+func TestValidate_ValidPushEvent(t *testing.T) {
+	t.Log("If the push event is valid it should be returned")
+	RegisterMockTestingT(t)
+	buf := bytes.NewBufferString(`{"object_kind": "push", "ref": "refs/heads/master", "user_username": "lkysow", "project": {"path_with_namespace": "lkysow/atlantis-example"}}`)
+	req, err := http.NewRequest("POST", "http://localhost/event", buf)
+	Ok(t, err)
+	req.Header.Set("X-Gitlab-Event", "Push Hook")
+	b, err := parser.ParseAndValidate(req, nil)
+	Ok(t, err)
+	Equals(t, "refs/heads/master", b.(gitlab.PushEvent).Ref)
+	Equals(t, "lkysow/atlantis-example", b.(gitlab.PushEvent).Project.PathWithNamespace)
+}
+
+func TestValidate_GroupEvents(t *testing.T) {
+	t.Log("Events group webhooks send about the group itself should be returned as group events")
+	RegisterMockTestingT(t)
+	for header, body := range map[string]string{
+		"Subgroup Hook": `{"event_name": "subgroup_create", "full_path": "group/subgroup", "group_path": "group"}`,
+		"Member Hook":   `{"event_name": "user_add_to_group", "group_path": "group", "user_username": "lkysow"}`,
+		"Project Hook":  `{"event_name": "project_create", "path_with_namespace": "group/repo"}`,
+	} {
+		req, err := http.NewRequest("POST", "http://localhost/event", bytes.NewBufferString(body))
+		Ok(t, err)
+		req.Header.Set("X-Gitlab-Event", header)
+		event, err := parser.ParseAndValidate(req, nil)
+		Ok(t, err)
+		Assert(t, event.(events.GitlabGroupEvent).EventName != "", "%s should have an event name", header)
+	}
+}
+
 var mergeEventJSON = `{
   "object_kind": "merge_request",
   "event_type": "merge_request",
@@ -55,7 +55,7 @@ func (g *GithubAppController) ExchangeCode(w http.ResponseWriter, r *http.Reques
 
 	g.Logger.Debug("Exchanging GitHub app code for app credentials")
 	creds := &vcs.GithubAnonymousCredentials{}
-	client, err := vcs.NewGithubClient(g.GithubHostname, creds, g.Logger)
+	client, err := vcs.NewGithubClient(g.GithubHostname, creds, g.Logger, nil)
 	if err != nil {
 		g.respond(w, logging.Error, http.StatusInternalServerError, "Failed to exchange code for github app: %s", err)
 		return
@@ -114,6 +114,7 @@ func (g *GithubAppController) New(w http.ResponseWriter, r *http.Request) {
 		Permissions: map[string]string{
 			"checks":           "write",
 			"contents":         "write",
+			"deployments":      "write",
 			"issues":           "write",
 			"pull_requests":    "write",
 			"repository_hooks": "write",
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/runatlantis/atlantis/server/controllers/templates"
+	"github.com/runatlantis/atlantis/server/core/locking"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// HistoryController renders the saved plan and apply outputs so they can be
+// viewed after a pull request is closed.
+type HistoryController struct {
+	AtlantisVersion string
+	AtlantisURL     *url.URL
+	DB              locking.Backend
+	Logger          logging.SimpleLogging
+	HistoryTemplate templates.TemplateWriter
+}
+
+// Get is the GET /history route. It renders the history for the pull request
+// given by the repo and pr query parameters. If pr isn't set, the history for
+// every pull request in the repo is rendered. The dir and workspace query
+// parameters optionally limit it to one project.
+func (h *HistoryController) Get(w http.ResponseWriter, r *http.Request) {
+	viewData := templates.HistoryData{
+		Repository:      r.URL.Query().Get("repo"),
+		Dir:             r.URL.Query().Get("dir"),
+		Workspace:       r.URL.Query().Get("workspace"),
+		AtlantisVersion: h.AtlantisVersion,
+		CleanedBasePath: h.AtlantisURL.Path,
+	}
+	if pr := r.URL.Query().Get("pr"); pr != "" {
+		pullNum, err := strconv.Atoi(pr)
+		if err != nil {
+			h.respond(w, logging.Warn, http.StatusBadRequest, "Invalid pr %q: %s", pr, err)
+			return
+		}
+		viewData.PullNum = pullNum
+	}
+
+	// Only look up history once a repo has been searched for since otherwise
+	// we'd render the history for every repo.
+	if viewData.Repository != "" {
+		history, err := h.DB.GetProjectHistory(viewData.Repository, viewData.PullNum)
+		if err != nil {
+			h.respond(w, logging.Error, http.StatusInternalServerError, "Failed getting history: %s", err)
+			return
+		}
+		// Show the most recent entries first.
+		for i := len(history) - 1; i >= 0; i-- {
+			e := history[i]
+			if (viewData.Dir != "" && e.RepoRelDir != viewData.Dir) || (viewData.Workspace != "" && e.Workspace != viewData.Workspace) {
+				continue
+			}
+			viewData.Entries = append(viewData.Entries, templates.HistoryEntry{
+				RepoFullName:  e.Pull.BaseRepo.FullName,
+				PullNum:       e.Pull.Num,
+				PullURL:       e.Pull.URL,
+				HeadCommit:    e.Pull.HeadCommit,
+				Path:          e.RepoRelDir,
+				Workspace:     e.Workspace,
+				ProjectName:   e.ProjectName,
+				Command:       e.Command.String(),
+				Status:        e.Status.String(),
+				Output:        e.Output,
+				TimeFormatted: e.Time.Format("02-01-2006 15:04:05"),
+			})
+		}
+	}
+
+	if err := h.HistoryTemplate.Execute(w, viewData); err != nil {
+		h.Logger.Err(err.Error())
+	}
+}
+
+// respond is a helper function to respond and log the response. lvl is the log
+// level to log at, code is the HTTP response code.
+func (h *HistoryController) respond(w http.ResponseWriter, lvl logging.LogLevel, responseCode int, format string, args ...interface{}) {
+	response := fmt.Sprintf(format, args...)
+	h.Logger.Log(lvl, response)
+	w.WriteHeader(responseCode)
+	fmt.Fprintln(w, response)
+}
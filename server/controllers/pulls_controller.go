@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/runatlantis/atlantis/server/controllers/templates"
+	"github.com/runatlantis/atlantis/server/core/locking"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/i18n"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// webUser is the user that commands started from the Atlantis UI are run as.
+const webUser = "atlantis-ui"
+
+// PullsController renders the open pull requests Atlantis knows about and
+// lets operators re-plan them or discard their plans.
+type PullsController struct {
+	AtlantisVersion   string
+	AtlantisURL       *url.URL
+	DB                locking.Backend
+	Logger            logging.SimpleLogging
+	PullsTemplate     templates.TemplateWriter
+	CommandRunner     events.CommandRunner
+	DeleteLockCommand events.DeleteLockCommand
+	VCSClient         vcs.Client
+	Localizer         *i18n.Localizer
+	// ActionsEnabled is true if pull requests can be re-planned and
+	// discarded. It's only set when web UI authentication is configured since
+	// otherwise anyone that can reach Atlantis could run them.
+	ActionsEnabled bool
+}
+
+// Get is the GET /pulls route. It renders every pull request Atlantis has run
+// commands on that hasn't been closed.
+func (p *PullsController) Get(w http.ResponseWriter, r *http.Request) {
+	statuses, err := p.DB.ListPullStatuses()
+	if err != nil {
+		p.respond(w, logging.Error, http.StatusInternalServerError, "Failed listing pull requests: %s", err)
+		return
+	}
+	viewData := templates.PullsData{
+		AtlantisVersion: p.AtlantisVersion,
+		CleanedBasePath: p.AtlantisURL.Path,
+		ActionsEnabled:  p.ActionsEnabled,
+	}
+	for _, s := range statuses {
+		pull := templates.PullData{
+			RepoFullName: s.Pull.BaseRepo.FullName,
+			PullNum:      s.Pull.Num,
+			PullURL:      s.Pull.URL,
+			Author:       s.Pull.Author,
+			HeadCommit:   s.Pull.HeadCommit,
+		}
+		for _, project := range s.Projects {
+			pull.Projects = append(pull.Projects, templates.PullProjectData{
+				Path:        project.RepoRelDir,
+				Workspace:   project.Workspace,
+				ProjectName: project.ProjectName,
+				Status:      project.Status.String(),
+			})
+		}
+		viewData.Pulls = append(viewData.Pulls, pull)
+	}
+
+	if err := p.PullsTemplate.Execute(w, viewData); err != nil {
+		p.Logger.Err(err.Error())
+	}
+}
+
+// Plan is the POST /pulls/plan route. It runs plan on the pull request given
+// by the repo and pr query parameters as if someone had commented
+// atlantis plan. It responds before the plan finishes; the results are
+// commented on the pull request.
+func (p *PullsController) Plan(w http.ResponseWriter, r *http.Request) {
+	if !p.checkActionsEnabled(w) {
+		return
+	}
+	status, ok := p.getPullStatus(w, r)
+	if !ok {
+		return
+	}
+	pull := status.Pull
+	// The pull request and the repo it's from are fetched again, the same as
+	// for comments, so we plan its latest commit and clone forks from the
+	// right repo. Bitbucket has no API to fetch them.
+	switch pull.BaseRepo.VCSHost.Type {
+	case models.BitbucketCloud, models.BitbucketServer:
+		p.respond(w, logging.Warn, http.StatusBadRequest, "Planning Bitbucket pull requests from the UI isn't supported, comment atlantis plan instead")
+		return
+	}
+	go p.CommandRunner.RunCommentCommand(context.Background(), pull.BaseRepo, nil, nil, models.User{Username: webUser}, pull.Num, &events.CommentCommand{Name: models.PlanCommand})
+	p.respond(w, logging.Info, http.StatusOK, "Planning %s#%d", pull.BaseRepo.FullName, pull.Num)
+}
+
+// Discard is the DELETE /pulls route. It discards the plans of the pull
+// request given by the repo and pr query parameters and deletes its locks, the
+// same as commenting atlantis unlock.
+func (p *PullsController) Discard(w http.ResponseWriter, r *http.Request) {
+	if !p.checkActionsEnabled(w) {
+		return
+	}
+	status, ok := p.getPullStatus(w, r)
+	if !ok {
+		return
+	}
+	pull := status.Pull
+	numLocks, err := p.DeleteLockCommand.DeleteLocksByPull(pull.BaseRepo.FullName, pull.Num)
+	if err != nil {
+		p.respond(w, logging.Error, http.StatusInternalServerError, "Deleting locks failed with: %s", err)
+		return
+	}
+	for _, project := range status.Projects {
+		if err := p.DB.UpdateProjectStatus(pull, project.Workspace, project.RepoRelDir, models.DiscardedPlanStatus); err != nil {
+			p.Logger.Err("unable to update project status: %s", err)
+		}
+	}
+
+	comment := p.Localizer.Sprintf(pull.BaseRepo, pull.BaseBranch, i18n.PullDiscardedViaUI)
+	if err := p.VCSClient.CreateComment(pull.BaseRepo, pull.Num, comment, models.UnlockCommand.String()); err != nil {
+		p.Logger.Warn("failed commenting on pull request: %s", err)
+	}
+	p.respond(w, logging.Info, http.StatusOK, "Discarded plans and deleted %d lock(s) for %s#%d", numLocks, pull.BaseRepo.FullName, pull.Num)
+}
+
+// checkActionsEnabled returns true if pull requests can be re-planned and
+// discarded. If it returns false, it's already responded with an error.
+func (p *PullsController) checkActionsEnabled(w http.ResponseWriter) bool {
+	if !p.ActionsEnabled {
+		p.respond(w, logging.Warn, http.StatusForbidden, "Pull requests can only be re-planned or discarded from the UI when web UI authentication is configured")
+	}
+	return p.ActionsEnabled
+}
+
+// getPullStatus returns the status of the pull request given by the repo and
+// pr query parameters. If it returns false, it's already responded with an
+// error.
+func (p *PullsController) getPullStatus(w http.ResponseWriter, r *http.Request) (models.PullStatus, bool) {
+	repo := r.URL.Query().Get("repo")
+	if repo == "" {
+		p.respond(w, logging.Warn, http.StatusBadRequest, "Missing required query parameter repo")
+		return models.PullStatus{}, false
+	}
+	pullNum, err := strconv.Atoi(r.URL.Query().Get("pr"))
+	if err != nil {
+		p.respond(w, logging.Warn, http.StatusBadRequest, "Invalid pr %q: %s", r.URL.Query().Get("pr"), err)
+		return models.PullStatus{}, false
+	}
+	statuses, err := p.DB.ListPullStatuses()
+	if err != nil {
+		p.respond(w, logging.Error, http.StatusInternalServerError, "Failed listing pull requests: %s", err)
+		return models.PullStatus{}, false
+	}
+	for _, s := range statuses {
+		if s.Pull.BaseRepo.FullName == repo && s.Pull.Num == pullNum {
+			return s, true
+		}
+	}
+	p.respond(w, logging.Info, http.StatusNotFound, "No pull request %s#%d found", repo, pullNum)
+	return models.PullStatus{}, false
+}
+
+// respond is a helper function to respond and log the response. lvl is the log
+// level to log at, code is the HTTP response code.
+func (p *PullsController) respond(w http.ResponseWriter, lvl logging.LogLevel, responseCode int, format string, args ...interface{}) {
+	response := fmt.Sprintf(format, args...)
+	p.Logger.Log(lvl, response)
+	w.WriteHeader(responseCode)
+	fmt.Fprintln(w, response)
+}
@@ -0,0 +1,193 @@
+package controllers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/controllers"
+	"github.com/runatlantis/atlantis/server/controllers/templates"
+	tMocks "github.com/runatlantis/atlantis/server/controllers/templates/mocks"
+	"github.com/runatlantis/atlantis/server/core/locking/mocks"
+	"github.com/runatlantis/atlantis/server/events"
+	mocks2 "github.com/runatlantis/atlantis/server/events/mocks"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/models"
+	vcsmocks "github.com/runatlantis/atlantis/server/events/vcs/mocks"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+var pullsFixture = []models.PullStatus{
+	{
+		Pull: models.PullRequest{
+			Num:        1,
+			HeadCommit: "sha",
+			URL:        "url",
+			Author:     "lkysow",
+			BaseRepo:   models.Repo{FullName: "owner/repo"},
+		},
+		Projects: []models.ProjectStatus{
+			{
+				Workspace:   "default",
+				RepoRelDir:  ".",
+				ProjectName: "proj",
+				Status:      models.PlannedPlanStatus,
+			},
+		},
+	},
+}
+
+func setupPulls(t *testing.T) (controllers.PullsController, *mocks.MockBackend, *mocks2.MockCommandRunner, *mocks2.MockDeleteLockCommand, *vcsmocks.MockClient) {
+	RegisterMockTestingT(t)
+	db := mocks.NewMockBackend()
+	When(db.ListPullStatuses()).ThenReturn(pullsFixture, nil)
+	runner := mocks2.NewMockCommandRunner()
+	dlc := mocks2.NewMockDeleteLockCommand()
+	vcsClient := vcsmocks.NewMockClient()
+	pc := controllers.PullsController{
+		AtlantisVersion:   "1300135",
+		AtlantisURL:       &url.URL{Path: "/path"},
+		DB:                db,
+		Logger:            logging.NewNoopLogger(t),
+		PullsTemplate:     tMocks.NewMockTemplateWriter(),
+		CommandRunner:     runner,
+		DeleteLockCommand: dlc,
+		VCSClient:         vcsClient,
+		ActionsEnabled:    true,
+	}
+	return pc, db, runner, dlc, vcsClient
+}
+
+func TestPullsController_Get(t *testing.T) {
+	pc, _, _, _, _ := setupPulls(t)
+	tmpl := tMocks.NewMockTemplateWriter()
+	pc.PullsTemplate = tmpl
+	req, _ := http.NewRequest("GET", "/pulls", nil)
+	w := httptest.NewRecorder()
+	pc.Get(w, req)
+	tmpl.VerifyWasCalledOnce().Execute(w, templates.PullsData{
+		Pulls: []templates.PullData{
+			{
+				RepoFullName: "owner/repo",
+				PullNum:      1,
+				PullURL:      "url",
+				Author:       "lkysow",
+				HeadCommit:   "sha",
+				Projects: []templates.PullProjectData{
+					{
+						Path:        ".",
+						Workspace:   "default",
+						ProjectName: "proj",
+						Status:      "planned",
+					},
+				},
+			},
+		},
+		AtlantisVersion: "1300135",
+		CleanedBasePath: "/path",
+		ActionsEnabled:  true,
+	})
+}
+
+func TestPullsController_Plan(t *testing.T) {
+	pc, _, runner, _, _ := setupPulls(t)
+	req, _ := http.NewRequest("POST", "/pulls/plan?repo=owner/repo&pr=1", nil)
+	w := httptest.NewRecorder()
+	pc.Plan(w, req)
+	ResponseContains(t, w, http.StatusOK, "Planning owner/repo#1")
+	pull := pullsFixture[0].Pull
+	// The head repo and pull request are fetched by the command runner so
+	// forks are planned from the right repo.
+	runner.VerifyWasCalledEventually(Once(), 2*time.Second).RunCommentCommand(
+		context.Background(),
+		pull.BaseRepo,
+		nil,
+		nil,
+		models.User{Username: "atlantis-ui"},
+		1,
+		&events.CommentCommand{Name: models.PlanCommand},
+	)
+}
+
+func TestPullsController_PlanBitbucket(t *testing.T) {
+	pc, db, runner, _, _ := setupPulls(t)
+	pull := pullsFixture[0].Pull
+	pull.BaseRepo.VCSHost.Type = models.BitbucketCloud
+	When(db.ListPullStatuses()).ThenReturn([]models.PullStatus{{Pull: pull}}, nil)
+	req, _ := http.NewRequest("POST", "/pulls/plan?repo=owner/repo&pr=1", nil)
+	w := httptest.NewRecorder()
+	pc.Plan(w, req)
+	ResponseContains(t, w, http.StatusBadRequest, "Planning Bitbucket pull requests from the UI isn't supported")
+	runner.VerifyWasCalled(Never()).RunCommentCommand(
+		matchers.AnyContextContext(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyPtrToModelsRepo(),
+		matchers.AnyPtrToModelsPullRequest(),
+		matchers.AnyModelsUser(),
+		AnyInt(),
+		matchers.AnyPtrToEventsCommentCommand(),
+	)
+}
+
+func TestPullsController_ActionsDisabled(t *testing.T) {
+	pc, _, _, dlc, _ := setupPulls(t)
+	pc.ActionsEnabled = false
+
+	req, _ := http.NewRequest("POST", "/pulls/plan?repo=owner/repo&pr=1", nil)
+	w := httptest.NewRecorder()
+	pc.Plan(w, req)
+	ResponseContains(t, w, http.StatusForbidden, "when web UI authentication is configured")
+
+	req, _ = http.NewRequest("DELETE", "/pulls?repo=owner/repo&pr=1", nil)
+	w = httptest.NewRecorder()
+	pc.Discard(w, req)
+	ResponseContains(t, w, http.StatusForbidden, "when web UI authentication is configured")
+	dlc.VerifyWasCalled(Never()).DeleteLocksByPull(AnyString(), AnyInt())
+}
+
+func TestPullsController_Discard(t *testing.T) {
+	pc, db, _, dlc, vcsClient := setupPulls(t)
+	When(dlc.DeleteLocksByPull("owner/repo", 1)).ThenReturn(2, nil)
+	req, _ := http.NewRequest("DELETE", "/pulls?repo=owner/repo&pr=1", nil)
+	w := httptest.NewRecorder()
+	pc.Discard(w, req)
+	ResponseContains(t, w, http.StatusOK, "Discarded plans and deleted 2 lock(s) for owner/repo#1")
+	pull := pullsFixture[0].Pull
+	db.VerifyWasCalledOnce().UpdateProjectStatus(pull, "default", ".", models.DiscardedPlanStatus)
+	vcsClient.VerifyWasCalledOnce().CreateComment(pull.BaseRepo, 1,
+		"**Warning**: All plans for this pull request were **discarded** and its locks deleted via the Atlantis UI.\n\n"+
+			"To `apply` you must run `plan` again.", "unlock")
+}
+
+func TestPullsController_Errors(t *testing.T) {
+	pc, _, _, dlc, _ := setupPulls(t)
+	cases := []struct {
+		url     string
+		expCode int
+		expBody string
+	}{
+		{"/pulls", http.StatusBadRequest, "Missing required query parameter repo"},
+		{"/pulls?repo=owner/repo&pr=abc", http.StatusBadRequest, "Invalid pr \"abc\""},
+		{"/pulls?repo=owner/repo&pr=2", http.StatusNotFound, "No pull request owner/repo#2 found"},
+		{"/pulls?repo=owner/other&pr=1", http.StatusNotFound, "No pull request owner/other#1 found"},
+	}
+	for _, c := range cases {
+		t.Run(c.url, func(t *testing.T) {
+			req, _ := http.NewRequest("DELETE", c.url, nil)
+			w := httptest.NewRecorder()
+			pc.Discard(w, req)
+			ResponseContains(t, w, c.expCode, c.expBody)
+
+			req, _ = http.NewRequest("POST", c.url, nil)
+			w = httptest.NewRecorder()
+			pc.Plan(w, req)
+			ResponseContains(t, w, c.expCode, c.expBody)
+		})
+	}
+	dlc.VerifyWasCalled(Never()).DeleteLocksByPull(AnyString(), AnyInt())
+}
@@ -4,8 +4,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/vcs"
 	"github.com/runatlantis/atlantis/server/logging"
 )
 
@@ -13,19 +16,156 @@ import (
 type StatusController struct {
 	Logger  logging.SimpleLogging
 	Drainer *events.Drainer
+	// WorkingDirGC is nil unless working dir garbage collection is enabled.
+	WorkingDirGC *events.WorkingDirGC
+	// APIRetrier is nil unless VCS API requests are retried.
+	APIRetrier *vcs.APIRetrier
+	// StateLocks counts the terraform state lock conflicts of plans and
+	// applies.
+	StateLocks *events.StateLockTracker
+	// DependencyChecks are run by /readyz and /status.
+	DependencyChecks []DependencyCheck
+}
+
+// dependencyCheckTimeout is how long a dependency check can take before it's
+// reported as failed, so that a hung VCS host doesn't hang probes.
+const dependencyCheckTimeout = 10 * time.Second
+
+// DependencyCheck checks a dependency Atlantis needs to run commands, ex. its
+// database or a VCS host.
+type DependencyCheck struct {
+	Name string
+	// Check returns an error if the dependency isn't usable.
+	Check func() error
 }
 
 type StatusResponse struct {
-	ShuttingDown  bool `json:"shutting_down"`
-	InProgressOps int  `json:"in_progress_operations"`
+	ShuttingDown  bool               `json:"shutting_down"`
+	InProgressOps int                `json:"in_progress_operations"`
+	Operations    []StatusOperation  `json:"operations"`
+	WorkspaceGC   *StatusWorkspaceGC `json:"workspace_gc,omitempty"`
+	// VCSAPI is keyed by VCS hostname.
+	VCSAPI map[string]StatusVCSAPI `json:"vcs_api,omitempty"`
+	// StateLocks is the contention for terraform state locks.
+	StateLocks *StatusStateLocks `json:"state_locks,omitempty"`
+	// Dependencies is keyed by DependencyCheck.Name.
+	Dependencies map[string]StatusDependency `json:"dependencies,omitempty"`
+}
+
+// ReadyResponse is the response of /readyz.
+type ReadyResponse struct {
+	Ready        bool                        `json:"ready"`
+	ShuttingDown bool                        `json:"shutting_down"`
+	Dependencies map[string]StatusDependency `json:"dependencies"`
+}
+
+// StatusDependency is the result of a DependencyCheck.
+type StatusDependency struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// StatusWorkspaceGC is the working dir garbage collector's totals in
+// StatusResponse.
+type StatusWorkspaceGC struct {
+	Runs           int       `json:"runs"`
+	LastRun        time.Time `json:"last_run"`
+	PullsCleaned   int       `json:"pulls_cleaned"`
+	ReclaimedBytes int64     `json:"reclaimed_bytes"`
+}
+
+// StatusStateLocks is the number of plans and applies that failed because
+// terraform couldn't acquire the state lock in StatusResponse.
+type StatusStateLocks struct {
+	Conflicts    int       `json:"conflicts"`
+	LastConflict time.Time `json:"last_conflict,omitempty"`
+	// Repos is keyed by repo full name.
+	Repos map[string]int `json:"repos"`
+}
+
+// StatusVCSAPI is the requests made to a VCS host and its latest rate limit
+// quota in StatusResponse.
+type StatusVCSAPI struct {
+	Requests    int              `json:"requests"`
+	Retries     int              `json:"retries"`
+	RateLimited int              `json:"rate_limited"`
+	RateLimit   *StatusRateLimit `json:"rate_limit,omitempty"`
+}
+
+// StatusRateLimit is the rate limit quota a VCS host last reported in
+// StatusVCSAPI.
+type StatusRateLimit struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+}
+
+// StatusOperation is an in-progress operation in StatusResponse.
+type StatusOperation struct {
+	Repo      string    `json:"repo"`
+	PullNum   int       `json:"pull"`
+	Command   string    `json:"command"`
+	StartedAt time.Time `json:"started_at"`
 }
 
 // Get is the GET /status route.
 func (d *StatusController) Get(w http.ResponseWriter, r *http.Request) {
 	status := d.Drainer.GetStatus()
+	ops := []StatusOperation{}
+	for _, op := range status.Operations {
+		ops = append(ops, StatusOperation{
+			Repo:      op.Repo,
+			PullNum:   op.PullNum,
+			Command:   op.Command,
+			StartedAt: op.StartedAt,
+		})
+	}
+	var gc *StatusWorkspaceGC
+	if d.WorkingDirGC != nil {
+		stats := d.WorkingDirGC.GetStats()
+		gc = &StatusWorkspaceGC{
+			Runs:           stats.Runs,
+			LastRun:        stats.LastRun,
+			PullsCleaned:   stats.PullsCleaned,
+			ReclaimedBytes: stats.ReclaimedBytes,
+		}
+	}
+	var vcsAPI map[string]StatusVCSAPI
+	if d.APIRetrier != nil {
+		vcsAPI = make(map[string]StatusVCSAPI)
+		for host, stats := range d.APIRetrier.GetStats() {
+			api := StatusVCSAPI{
+				Requests:    stats.Requests,
+				Retries:     stats.Retries,
+				RateLimited: stats.RateLimited,
+			}
+			if stats.Limit > 0 {
+				api.RateLimit = &StatusRateLimit{
+					Limit:     stats.Limit,
+					Remaining: stats.Remaining,
+					Reset:     stats.Reset,
+				}
+			}
+			vcsAPI[host] = api
+		}
+	}
+	var stateLocks *StatusStateLocks
+	if d.StateLocks != nil {
+		stats := d.StateLocks.GetStats()
+		stateLocks = &StatusStateLocks{
+			Conflicts:    stats.Conflicts,
+			LastConflict: stats.LastConflict,
+			Repos:        stats.Repos,
+		}
+	}
 	data, err := json.MarshalIndent(&StatusResponse{
 		ShuttingDown:  status.ShuttingDown,
 		InProgressOps: status.InProgressOps,
+		Operations:    ops,
+		WorkspaceGC:   gc,
+		VCSAPI:        vcsAPI,
+		StateLocks:    stateLocks,
+		Dependencies:  d.checkDependencies(),
 	}, "", "  ")
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -35,3 +175,75 @@ func (d *StatusController) Get(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(data) // nolint: errcheck
 }
+
+// Ready is the GET /readyz route. It responds with a 503 if Atlantis is
+// shutting down or any of its dependencies isn't usable so that load balancers
+// stop sending it requests.
+func (d *StatusController) Ready(w http.ResponseWriter, r *http.Request) {
+	resp := ReadyResponse{
+		Ready:        true,
+		ShuttingDown: d.Drainer.GetStatus().ShuttingDown,
+		Dependencies: d.checkDependencies(),
+	}
+	if resp.Dependencies == nil {
+		resp.Dependencies = map[string]StatusDependency{}
+	}
+	if resp.ShuttingDown {
+		resp.Ready = false
+	}
+	for name, dep := range resp.Dependencies {
+		if !dep.OK {
+			d.Logger.Warn("not ready because %s check failed: %s", name, dep.Error)
+			resp.Ready = false
+		}
+	}
+	data, err := json.MarshalIndent(&resp, "", "  ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "Error creating readiness json response: %s", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if !resp.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	w.Write(data) // nolint: errcheck
+}
+
+// checkDependencies runs the dependency checks in parallel and returns their
+// results, or nil if there aren't any.
+func (d *StatusController) checkDependencies() map[string]StatusDependency {
+	if len(d.DependencyChecks) == 0 {
+		return nil
+	}
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]StatusDependency)
+	for _, c := range d.DependencyChecks {
+		wg.Add(1)
+		go func(c DependencyCheck) {
+			defer wg.Done()
+			result := StatusDependency{OK: true}
+			if err := runDependencyCheck(c); err != nil {
+				result = StatusDependency{Error: err.Error()}
+			}
+			mutex.Lock()
+			results[c.Name] = result
+			mutex.Unlock()
+		}(c)
+	}
+	wg.Wait()
+	return results
+}
+
+// runDependencyCheck runs c, giving up after dependencyCheckTimeout.
+func runDependencyCheck(c DependencyCheck) error {
+	done := make(chan error, 1)
+	go func() { done <- c.Check() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(dependencyCheckTimeout):
+		return fmt.Errorf("timed out after %s", dependencyCheckTimeout)
+	}
+}
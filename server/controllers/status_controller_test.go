@@ -3,10 +3,12 @@ package controllers_test
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/runatlantis/atlantis/server/controllers"
 	"github.com/runatlantis/atlantis/server/events"
@@ -35,12 +37,41 @@ func TestStatusController_Startup(t *testing.T) {
 	Equals(t, 0, result.InProgressOps)
 }
 
+func TestStatusController_StateLocks(t *testing.T) {
+	tracker := &events.StateLockTracker{}
+	tracker.Record("owner/repo")
+	tracker.Record("owner/repo")
+	d := &controllers.StatusController{
+		Logger:     logging.NewNoopLogger(t),
+		Drainer:    &events.Drainer{},
+		StateLocks: tracker,
+	}
+	r, _ := http.NewRequest("GET", "/status", bytes.NewBuffer(nil))
+	w := httptest.NewRecorder()
+	d.Get(w, r)
+
+	var result controllers.StatusResponse
+	body, err := ioutil.ReadAll(w.Result().Body)
+	Ok(t, err)
+	Ok(t, json.Unmarshal(body, &result))
+	Equals(t, 2, result.StateLocks.Conflicts)
+	Equals(t, map[string]int{"owner/repo": 2}, result.StateLocks.Repos)
+	Assert(t, !result.StateLocks.LastConflict.IsZero(), "exp last conflict to be set")
+}
+
 func TestStatusController_InProgress(t *testing.T) {
 	logger := logging.NewNoopLogger(t)
 	r, _ := http.NewRequest("GET", "/status", bytes.NewBuffer(nil))
 	w := httptest.NewRecorder()
 	dr := &events.Drainer{}
 	dr.StartOp()
+	startedAt := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	dr.StartOperation(events.Operation{
+		Repo:      "owner/repo",
+		PullNum:   1,
+		Command:   "plan",
+		StartedAt: startedAt,
+	})
 
 	d := &controllers.StatusController{
 		Logger:  logger,
@@ -55,7 +86,10 @@ func TestStatusController_InProgress(t *testing.T) {
 	err = json.Unmarshal(body, &result)
 	Ok(t, err)
 	Equals(t, false, result.ShuttingDown)
-	Equals(t, 1, result.InProgressOps)
+	Equals(t, 2, result.InProgressOps)
+	Equals(t, []controllers.StatusOperation{
+		{Repo: "owner/repo", PullNum: 1, Command: "plan", StartedAt: startedAt},
+	}, result.Operations)
 }
 
 func TestStatusController_Shutdown(t *testing.T) {
@@ -80,3 +114,95 @@ func TestStatusController_Shutdown(t *testing.T) {
 	Equals(t, true, result.ShuttingDown)
 	Equals(t, 0, result.InProgressOps)
 }
+
+func TestStatusController_Ready(t *testing.T) {
+	logger := logging.NewNoopLogger(t)
+	ok := controllers.DependencyCheck{Name: "database", Check: func() error { return nil }}
+	failing := controllers.DependencyCheck{Name: "vcs:github.com", Check: func() error { return errors.New("401 Bad credentials") }}
+	cases := []struct {
+		description string
+		checks      []controllers.DependencyCheck
+		shutdown    bool
+		expCode     int
+		expResp     controllers.ReadyResponse
+	}{
+		{
+			description: "no checks",
+			expCode:     http.StatusOK,
+			expResp:     controllers.ReadyResponse{Ready: true, Dependencies: map[string]controllers.StatusDependency{}},
+		},
+		{
+			description: "checks pass",
+			checks:      []controllers.DependencyCheck{ok},
+			expCode:     http.StatusOK,
+			expResp: controllers.ReadyResponse{
+				Ready:        true,
+				Dependencies: map[string]controllers.StatusDependency{"database": {OK: true}},
+			},
+		},
+		{
+			description: "check fails",
+			checks:      []controllers.DependencyCheck{ok, failing},
+			expCode:     http.StatusServiceUnavailable,
+			expResp: controllers.ReadyResponse{
+				Dependencies: map[string]controllers.StatusDependency{
+					"database":       {OK: true},
+					"vcs:github.com": {Error: "401 Bad credentials"},
+				},
+			},
+		},
+		{
+			description: "shutting down",
+			checks:      []controllers.DependencyCheck{ok},
+			shutdown:    true,
+			expCode:     http.StatusServiceUnavailable,
+			expResp: controllers.ReadyResponse{
+				ShuttingDown: true,
+				Dependencies: map[string]controllers.StatusDependency{"database": {OK: true}},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			dr := &events.Drainer{}
+			if c.shutdown {
+				dr.ShutdownBlocking()
+			}
+			d := &controllers.StatusController{
+				Logger:           logger,
+				Drainer:          dr,
+				DependencyChecks: c.checks,
+			}
+			r, _ := http.NewRequest("GET", "/readyz", bytes.NewBuffer(nil))
+			w := httptest.NewRecorder()
+			d.Ready(w, r)
+
+			var result controllers.ReadyResponse
+			body, err := ioutil.ReadAll(w.Result().Body)
+			Ok(t, err)
+			Equals(t, c.expCode, w.Result().StatusCode)
+			Ok(t, json.Unmarshal(body, &result))
+			Equals(t, c.expResp, result)
+		})
+	}
+}
+
+func TestStatusController_Dependencies(t *testing.T) {
+	r, _ := http.NewRequest("GET", "/status", bytes.NewBuffer(nil))
+	w := httptest.NewRecorder()
+	d := &controllers.StatusController{
+		Logger:  logging.NewNoopLogger(t),
+		Drainer: &events.Drainer{},
+		DependencyChecks: []controllers.DependencyCheck{
+			{Name: "disk", Check: func() error { return errors.New("data dir is full") }},
+		},
+	}
+	d.Get(w, r)
+
+	var result controllers.StatusResponse
+	body, err := ioutil.ReadAll(w.Result().Body)
+	Ok(t, err)
+	Equals(t, 200, w.Result().StatusCode)
+	Ok(t, json.Unmarshal(body, &result))
+	Equals(t, map[string]controllers.StatusDependency{"disk": {Error: "data dir is full"}}, result.Dependencies)
+}
@@ -122,6 +122,9 @@ var IndexTemplate = template.Must(template.New("index.html.tmpl").Parse(`
     {{ else }}
     <p class="placeholder">No locks found.</p>
     {{ end }}
+    <p><a href="{{ .CleanedBasePath }}/pulls">View open pull requests</a></p>
+    <p><a href="{{ .CleanedBasePath }}/history">View plan and apply history</a></p>
+    <p><a href="{{ .CleanedBasePath }}/terraform-versions">Manage terraform versions</a></p>
   </section>
   <div id="applyLockMessageModal" class="modal">
     <!-- Modal content -->
@@ -352,6 +355,295 @@ v{{ .AtlantisVersion }}
 </html>
 `))
 
+// HistoryData holds the fields needed to display the history view.
+type HistoryData struct {
+	// Repository, PullNum, Dir and Workspace are the filters the history was
+	// looked up with. PullNum is 0, and Dir and Workspace are empty, if the
+	// history isn't filtered by them.
+	Repository      string
+	PullNum         int
+	Dir             string
+	Workspace       string
+	Entries         []HistoryEntry
+	AtlantisVersion string
+	// CleanedBasePath is the path Atlantis is accessible at externally. If
+	// not using a path-based proxy, this will be an empty string. Never ends
+	// in a '/' (hence "cleaned").
+	CleanedBasePath string
+}
+
+// HistoryEntry is the output of running a command on a project at a commit.
+type HistoryEntry struct {
+	RepoFullName  string
+	PullNum       int
+	PullURL       string
+	HeadCommit    string
+	Path          string
+	Workspace     string
+	ProjectName   string
+	Command       string
+	Status        string
+	Output        string
+	TimeFormatted string
+}
+
+var HistoryTemplate = template.Must(template.New("history.html.tmpl").Parse(`
+<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>atlantis</title>
+  <meta name="description" content="">
+  <meta name="author" content="">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/normalize.css">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/skeleton.css">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/custom.css">
+  <link rel="icon" type="image/png" href="{{ .CleanedBasePath }}/static/images/atlantis-icon.png">
+</head>
+<body>
+  <div class="container">
+    <section class="header">
+    <a title="atlantis" href="{{ .CleanedBasePath }}/"><img class="hero" src="{{ .CleanedBasePath }}/static/images/atlantis-icon_512.png"/></a>
+    <p class="title-heading">atlantis</p>
+    <p class="title-heading"><strong>History</strong></p>
+    </section>
+    <div class="navbar-spacer"></div>
+    <br>
+    <section>
+      <form action="{{ .CleanedBasePath }}/history" method="GET">
+        <input type="text" name="repo" placeholder="owner/repo" value="{{ .Repository }}">
+        <input type="number" name="pr" placeholder="pull request" value="{{ if .PullNum }}{{ .PullNum }}{{ end }}">
+        <input type="text" name="dir" placeholder="dir" value="{{ .Dir }}">
+        <input type="text" name="workspace" placeholder="workspace" value="{{ .Workspace }}">
+        <input class="button-primary" type="submit" value="Search">
+      </form>
+    </section>
+    <section>
+    {{ if .Entries }}
+    {{ range .Entries }}
+      <div class="twelve columns content lock-row">
+        <div class="list-title">{{ .RepoFullName }} <a href="{{ .PullURL }}" target="_blank"><span class="heading-font-size">#{{ .PullNum }}</span></a> <code>{{ .Command }}</code> <code>{{ .Path }}</code> <code>{{ .Workspace }}</code>{{ if .ProjectName }} <code>{{ .ProjectName }}</code>{{ end }}</div>
+        <div class="list-status"><code>{{ .Status }}</code></div>
+        <div class="list-timestamp"><span class="heading-font-size">{{ .TimeFormatted }}</span> <code>{{ .HeadCommit }}</code></div>
+      </div>
+      <details>
+        <summary>Show Output</summary>
+        <pre>{{ .Output }}</pre>
+      </details>
+    {{ end }}
+    {{ else if .Repository }}
+    <p class="placeholder">No history found.</p>
+    {{ else }}
+    <p class="placeholder">Search for a repository to see what was planned and applied.</p>
+    {{ end }}
+    </section>
+  </div>
+<footer>
+v{{ .AtlantisVersion }}
+</footer>
+</body>
+</html>
+`))
+
+// PullsData holds the fields needed to display the pull requests view.
+type PullsData struct {
+	Pulls           []PullData
+	AtlantisVersion string
+	// CleanedBasePath is the path Atlantis is accessible at externally. If
+	// not using a path-based proxy, this will be an empty string. Never ends
+	// in a '/' (hence "cleaned").
+	CleanedBasePath string
+	// ActionsEnabled is true if the re-plan and discard buttons are shown.
+	ActionsEnabled bool
+}
+
+// PullData is an open pull request and the status of its projects.
+type PullData struct {
+	RepoFullName string
+	PullNum      int
+	PullURL      string
+	Author       string
+	HeadCommit   string
+	Projects     []PullProjectData
+}
+
+// PullProjectData is the status of a project in a pull request.
+type PullProjectData struct {
+	Path        string
+	Workspace   string
+	ProjectName string
+	Status      string
+}
+
+var PullsTemplate = template.Must(template.New("pulls.html.tmpl").Parse(`
+<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>atlantis</title>
+  <meta name="description" content="">
+  <meta name="author" content="">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <script src="{{ .CleanedBasePath }}/static/js/jquery-3.5.1.min.js"></script>
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/normalize.css">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/skeleton.css">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/custom.css">
+  <link rel="icon" type="image/png" href="{{ .CleanedBasePath }}/static/images/atlantis-icon.png">
+</head>
+<body>
+  <div class="container">
+    <section class="header">
+    <a title="atlantis" href="{{ .CleanedBasePath }}/"><img class="hero" src="{{ .CleanedBasePath }}/static/images/atlantis-icon_512.png"/></a>
+    <p class="title-heading">atlantis</p>
+    <p class="title-heading"><strong>Pull Requests</strong></p>
+    <p class="js-pull-message"></p>
+    </section>
+    <div class="navbar-spacer"></div>
+    <br>
+    <section>
+    {{ if .Pulls }}
+    {{ range .Pulls }}
+      <div class="twelve columns content lock-row">
+        <div class="list-title">{{ .RepoFullName }} <a href="{{ .PullURL }}" target="_blank"><span class="heading-font-size">#{{ .PullNum }}</span></a> <span class="heading-font-size">{{ .Author }}</span></div>
+        {{ if $.ActionsEnabled }}
+        <div class="list-status">
+          <a class="button js-pull-action" data-method="POST" data-action="plan" data-repo="{{ .RepoFullName }}" data-pr="{{ .PullNum }}">Re-plan</a>
+          <a class="button js-pull-action" data-method="DELETE" data-action="" data-repo="{{ .RepoFullName }}" data-pr="{{ .PullNum }}">Discard</a>
+        </div>
+        {{ end }}
+        <div class="list-timestamp"><code>{{ .HeadCommit }}</code></div>
+      </div>
+      {{ range .Projects }}
+      <div class="twelve columns content">
+        <code>{{ .Path }}</code> <code>{{ .Workspace }}</code>{{ if .ProjectName }} <code>{{ .ProjectName }}</code>{{ end }} <strong>{{ .Status }}</strong>
+      </div>
+      {{ end }}
+    {{ end }}
+    {{ else }}
+    <p class="placeholder">No open pull requests found.</p>
+    {{ end }}
+    </section>
+  </div>
+<footer>
+v{{ .AtlantisVersion }}
+</footer>
+<script>
+  $(".js-pull-action").click(function() {
+    var btn = $(this);
+    var path = btn.data("action") ? "/pulls/" + btn.data("action") : "/pulls";
+    var params = $.param({repo: btn.data("repo"), pr: btn.data("pr")});
+    $.ajax({
+        url: '{{ .CleanedBasePath }}' + path + '?' + params,
+        type: btn.data("method"),
+        success: function(result) {
+          $("p.js-pull-message").text(result);
+        },
+        error: function(xhr) {
+          $("p.js-pull-message").text(xhr.responseText);
+        }
+    });
+  });
+</script>
+</body>
+</html>
+`))
+
+// TerraformVersionsData holds the fields needed to display the terraform
+// versions view.
+type TerraformVersionsData struct {
+	AtlantisVersion string
+	// CleanedBasePath is the path Atlantis is accessible at externally. If
+	// not using a path-based proxy, this will be an empty string. Never ends
+	// in a '/' (hence "cleaned").
+	CleanedBasePath string
+	Distribution    string
+	// Distributions are the distributions that can be viewed.
+	Distributions []string
+	// DefaultVersion is empty if Distribution isn't the default distribution.
+	DefaultVersion string
+	Installed      []string
+	Available      []string
+	// Error is set if the available versions couldn't be listed.
+	Error string
+}
+
+var TerraformVersionsTemplate = template.Must(template.New("terraform-versions.html.tmpl").Parse(`
+<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>atlantis</title>
+  <meta name="description" content="">
+  <meta name="author" content="">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <script src="{{ .CleanedBasePath }}/static/js/jquery-3.5.1.min.js"></script>
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/normalize.css">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/skeleton.css">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/custom.css">
+  <link rel="icon" type="image/png" href="{{ .CleanedBasePath }}/static/images/atlantis-icon.png">
+</head>
+<body>
+  <div class="container">
+    <section class="header">
+    <a title="atlantis" href="{{ .CleanedBasePath }}/"><img class="hero" src="{{ .CleanedBasePath }}/static/images/atlantis-icon_512.png"/></a>
+    <p class="title-heading">atlantis</p>
+    <p class="title-heading"><strong>Terraform Versions</strong></p>
+    <p class="js-install-message"></p>
+    </section>
+    <div class="navbar-spacer"></div>
+    <br>
+    <section>
+      {{ $basePath := .CleanedBasePath }}
+      {{ $distribution := .Distribution }}
+      <p>{{ range .Distributions }}{{ if eq . $distribution }}<strong>{{ . }}</strong>{{ else }}<a href="{{ $basePath }}/terraform-versions?distribution={{ . }}">{{ . }}</a>{{ end }} {{ end }}</p>
+      {{ if .Available }}
+      <select class="js-install-version">
+        {{ range .Available }}<option value="{{ . }}">{{ . }}</option>{{ end }}
+      </select>
+      <a class="button button-primary js-install" data-distribution="{{ .Distribution }}">Install</a>
+      {{ end }}
+      {{ if .Error }}
+      <p class="placeholder">Unable to list the versions that can be installed: {{ .Error }}</p>
+      {{ end }}
+    </section>
+    <section>
+    <p class="title-heading small"><strong>Installed</strong></p>
+    {{ if .Installed }}
+    {{ $default := .DefaultVersion }}
+    {{ range .Installed }}
+      <div class="twelve columns content">
+        <code>{{ . }}</code>{{ if eq . $default }} <strong>default</strong>{{ end }}
+      </div>
+    {{ end }}
+    {{ else }}
+    <p class="placeholder">No versions installed.</p>
+    {{ end }}
+    </section>
+  </div>
+<footer>
+v{{ .AtlantisVersion }}
+</footer>
+<script>
+  $(".js-install").click(function() {
+    var params = $.param({distribution: $(this).data("distribution"), version: $(".js-install-version").val()});
+    $("p.js-install-message").text("Installing...");
+    $.ajax({
+        url: '{{ .CleanedBasePath }}/terraform-versions?' + params,
+        type: "POST",
+        success: function() {
+          location.reload();
+        },
+        error: function(xhr) {
+          $("p.js-install-message").text(xhr.responseText);
+        }
+    });
+  });
+</script>
+</body>
+</html>
+`))
+
 // GithubSetupData holds the data for rendering the github app setup page
 type GithubSetupData struct {
 	Target        string
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	version "github.com/hashicorp/go-version"
+	"github.com/runatlantis/atlantis/server/controllers/templates"
+	"github.com/runatlantis/atlantis/server/core/terraform"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// TerraformVersionsController renders the terraform versions Atlantis has
+// installed in its data dir and lets operators install more.
+type TerraformVersionsController struct {
+	AtlantisVersion           string
+	AtlantisURL               *url.URL
+	Logger                    logging.SimpleLogging
+	TerraformClient           *terraform.DefaultClient
+	TerraformVersionsTemplate templates.TemplateWriter
+}
+
+// Get is the GET /terraform-versions route. It renders the versions of the
+// distribution given by the distribution query parameter, or the default
+// distribution.
+func (t *TerraformVersionsController) Get(w http.ResponseWriter, r *http.Request) {
+	d := r.URL.Query().Get("distribution")
+	if err := validateTFDistribution(d); err != nil {
+		t.respond(w, logging.Warn, http.StatusBadRequest, "%s", err)
+		return
+	}
+	versions, err := t.TerraformClient.ListVersions(d)
+	viewData := templates.TerraformVersionsData{
+		AtlantisVersion: t.AtlantisVersion,
+		CleanedBasePath: t.AtlantisURL.Path,
+		Distribution:    versions.Distribution,
+		Distributions:   []string{terraform.TerraformDistribution, terraform.OpenTofuDistribution},
+		Installed:       versionStrings(versions.Installed),
+		Available:       versionStrings(versions.Available),
+	}
+	if versions.Default != nil {
+		viewData.DefaultVersion = versions.Default.String()
+	}
+	// The installed versions are still shown if the released versions can't
+	// be listed, ex. because Atlantis can't reach the internet.
+	if err != nil {
+		t.Logger.Warn("%s", err)
+		viewData.Error = err.Error()
+	}
+	if err := t.TerraformVersionsTemplate.Execute(w, viewData); err != nil {
+		t.Logger.Err(err.Error())
+	}
+}
+
+// Install is the POST /terraform-versions route. It installs the version
+// given by the distribution and version query parameters.
+func (t *TerraformVersionsController) Install(w http.ResponseWriter, r *http.Request) {
+	d := r.URL.Query().Get("distribution")
+	v, err := parseTFInstallRequest(d, r.URL.Query().Get("version"))
+	if err != nil {
+		t.respond(w, logging.Warn, http.StatusBadRequest, "%s", err)
+		return
+	}
+	if err := t.TerraformClient.EnsureVersion(t.Logger, d, v); err != nil {
+		t.respond(w, logging.Error, http.StatusInternalServerError, "Installing %s failed with: %s", v, err)
+		return
+	}
+	t.respond(w, logging.Info, http.StatusOK, "Installed %s", v)
+}
+
+// respond is a helper function to respond and log the response. lvl is the log
+// level to log at, code is the HTTP response code.
+func (t *TerraformVersionsController) respond(w http.ResponseWriter, lvl logging.LogLevel, responseCode int, format string, args ...interface{}) {
+	response := fmt.Sprintf(format, args...)
+	t.Logger.Log(lvl, response)
+	w.WriteHeader(responseCode)
+	fmt.Fprintln(w, response)
+}
+
+// validateTFDistribution returns an error if d isn't empty, which means the
+// default distribution, or the name of a distribution.
+func validateTFDistribution(d string) error {
+	if d != "" && d != terraform.TerraformDistribution && d != terraform.OpenTofuDistribution {
+		return fmt.Errorf("invalid distribution %q: must be %s or %s", d, terraform.TerraformDistribution, terraform.OpenTofuDistribution)
+	}
+	return nil
+}
+
+// parseTFInstallRequest validates the distribution d and version v of a
+// request to install a version.
+func parseTFInstallRequest(d string, v string) (*version.Version, error) {
+	if err := validateTFDistribution(d); err != nil {
+		return nil, err
+	}
+	if v == "" {
+		return nil, fmt.Errorf("version is required")
+	}
+	parsed, err := version.NewVersion(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version %q: %s", v, err)
+	}
+	return parsed, nil
+}
+
+func versionStrings(versions []*version.Version) []string {
+	strs := []string{}
+	for _, v := range versions {
+		strs = append(strs, v.String())
+	}
+	return strs
+}
@@ -0,0 +1,129 @@
+package controllers_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/controllers"
+	"github.com/runatlantis/atlantis/server/controllers/templates"
+	tMocks "github.com/runatlantis/atlantis/server/controllers/templates/mocks"
+	"github.com/runatlantis/atlantis/server/core/terraform"
+	tfmocks "github.com/runatlantis/atlantis/server/core/terraform/mocks"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+const tfDownloadURL = "https://releases.example.com"
+
+// newTerraformClient returns a terraform client whose default version is
+// 1.5.7 and whose downloader lists 1.4.6, 1.5.7 and 1.6.0 as released.
+func newTerraformClient(t *testing.T) (*terraform.DefaultClient, *tfmocks.MockDownloader, func()) {
+	RegisterMockTestingT(t)
+	tmp, cleanup := TempDir(t)
+	binDir := filepath.Join(tmp, "bin")
+	Ok(t, os.Mkdir(binDir, 0700))
+	downloader := tfmocks.NewMockDownloader()
+	When(downloader.GetFile(AnyString(), AnyString())).Then(func(params []Param) ReturnValues {
+		contents := "#!/bin/sh\n"
+		if params[1].(string) == tfDownloadURL+"/terraform/index.json" {
+			contents = `{"name": "terraform", "versions": {"1.4.6": {}, "1.5.7": {}, "1.6.0": {}}}`
+		}
+		return []ReturnValue{ioutil.WriteFile(params[0].(string), []byte(contents), 0700)} // #nosec G306
+	})
+
+	// PATH is emptied so that terraform binaries on this machine aren't
+	// listed as installed.
+	path := os.Getenv("PATH")
+	Ok(t, os.Setenv("PATH", ""))
+	defer os.Setenv("PATH", path) // nolint: errcheck
+	client, err := terraform.NewTestClient(logging.NewNoopLogger(t), binDir, filepath.Join(tmp, "cache"), "", "", nil, nil, "1.5.7", "default-tf-version", terraform.TerraformDistribution, tfDownloadURL, "https://tofu.example.com", "", downloader, true, 0)
+	Ok(t, err)
+	return client, downloader, cleanup
+}
+
+func TestTerraformVersionsController_Get(t *testing.T) {
+	client, _, cleanup := newTerraformClient(t)
+	defer cleanup()
+	tmpl := tMocks.NewMockTemplateWriter()
+	tc := controllers.TerraformVersionsController{
+		AtlantisVersion:           "1300135",
+		AtlantisURL:               &url.URL{Path: "/path"},
+		Logger:                    logging.NewNoopLogger(t),
+		TerraformClient:           client,
+		TerraformVersionsTemplate: tmpl,
+	}
+	req, _ := http.NewRequest("GET", "/terraform-versions", nil)
+	w := httptest.NewRecorder()
+	tc.Get(w, req)
+	tmpl.VerifyWasCalledOnce().Execute(w, templates.TerraformVersionsData{
+		AtlantisVersion: "1300135",
+		CleanedBasePath: "/path",
+		Distribution:    "terraform",
+		Distributions:   []string{"terraform", "opentofu"},
+		DefaultVersion:  "1.5.7",
+		Installed:       []string{"1.5.7"},
+		Available:       []string{"1.6.0", "1.4.6"},
+	})
+
+	t.Log("installed versions are shown if the available versions can't be listed")
+	req, _ = http.NewRequest("GET", "/terraform-versions?distribution=opentofu", nil)
+	w = httptest.NewRecorder()
+	tc.Get(w, req)
+	tmpl.VerifyWasCalledOnce().Execute(w, templates.TerraformVersionsData{
+		AtlantisVersion: "1300135",
+		CleanedBasePath: "/path",
+		Distribution:    "opentofu",
+		Distributions:   []string{"terraform", "opentofu"},
+		Installed:       []string{},
+		Available:       []string{},
+		Error:           `listing opentofu versions: parsing "https://get.opentofu.org/tofu/api.json": invalid character '#' looking for beginning of value`,
+	})
+
+	req, _ = http.NewRequest("GET", "/terraform-versions?distribution=other", nil)
+	w = httptest.NewRecorder()
+	tc.Get(w, req)
+	ResponseContains(t, w, http.StatusBadRequest, `invalid distribution "other": must be terraform or opentofu`)
+}
+
+func TestTerraformVersionsController_Install(t *testing.T) {
+	client, downloader, cleanup := newTerraformClient(t)
+	defer cleanup()
+	tc := controllers.TerraformVersionsController{
+		AtlantisURL:     &url.URL{},
+		Logger:          logging.NewNoopLogger(t),
+		TerraformClient: client,
+	}
+	req, _ := http.NewRequest("POST", "/terraform-versions?version=1.6.0", nil)
+	w := httptest.NewRecorder()
+	tc.Install(w, req)
+	ResponseContains(t, w, http.StatusOK, "Installed 1.6.0")
+	baseURL := tfDownloadURL + "/terraform/1.6.0/terraform_1.6.0"
+	downloader.VerifyWasCalledOnce().GetFile(
+		filepath.Join(client.TerraformBinDir(), "terraform1.6.0"),
+		fmt.Sprintf("%s_%s_%s.zip?checksum=file:%s_SHA256SUMS", baseURL, runtime.GOOS, runtime.GOARCH, baseURL))
+
+	cases := []struct {
+		url     string
+		expBody string
+	}{
+		{"/terraform-versions", "version is required"},
+		{"/terraform-versions?version=latest", `invalid version "latest"`},
+		{"/terraform-versions?version=1.6.0&distribution=other", `invalid distribution "other"`},
+	}
+	for _, c := range cases {
+		t.Run(c.url, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", c.url, nil)
+			w := httptest.NewRecorder()
+			tc.Install(w, req)
+			ResponseContains(t, w, http.StatusBadRequest, c.expBody)
+		})
+	}
+}
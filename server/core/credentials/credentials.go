@@ -0,0 +1,98 @@
+// Package credentials obtains short-lived cloud credentials for projects so
+// that their steps run with the permissions of their own role instead of with
+// the credentials of the Atlantis server.
+package credentials
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// maxSessionNameLen is the longest role session name STS allows.
+const maxSessionNameLen = 64
+
+// invalidSessionNameChars matches the characters STS doesn't allow in role
+// session names.
+var invalidSessionNameChars = regexp.MustCompile(`[^\w+=,.@-]`)
+
+// Provider obtains the credentials configured for projects and returns them
+// as the env vars that terraform and the cloud CLIs read.
+type Provider struct {
+	// STS is used to assume AWS roles.
+	STS stsiface.STSAPI
+	// GCP is used to generate access tokens for GCP service accounts.
+	GCP GCPTokenGenerator
+}
+
+// NewProvider returns a Provider that assumes AWS roles and impersonates GCP
+// service accounts using the server's own credentials, ex. from its instance
+// profile or workload identity.
+func NewProvider() (*Provider, error) {
+	sess, err := session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "creating AWS session")
+	}
+	return &Provider{
+		STS: sts.New(sess),
+		GCP: &IAMCredentialsTokenGenerator{},
+	}, nil
+}
+
+// Env returns the env vars holding the credentials configured for ctx. It
+// returns nil if the project doesn't configure any.
+func (p *Provider) Env(ctx models.ProjectCommandContext) (map[string]string, error) {
+	if ctx.Credentials == nil {
+		return nil, nil
+	}
+	envs := make(map[string]string)
+	if awsCreds := ctx.Credentials.AWS; awsCreds != nil {
+		ctx.Log.Debug("assuming AWS role %s", awsCreds.RoleARN)
+		in := &sts.AssumeRoleInput{
+			RoleArn:         aws.String(awsCreds.RoleARN),
+			RoleSessionName: aws.String(sessionName(ctx)),
+		}
+		if awsCreds.SessionDuration != 0 {
+			in.DurationSeconds = aws.Int64(int64(awsCreds.SessionDuration.Seconds()))
+		}
+		out, err := p.STS.AssumeRole(in)
+		if err != nil {
+			return nil, errors.Wrapf(err, "assuming AWS role %s", awsCreds.RoleARN)
+		}
+		envs["AWS_ACCESS_KEY_ID"] = *out.Credentials.AccessKeyId
+		envs["AWS_SECRET_ACCESS_KEY"] = *out.Credentials.SecretAccessKey
+		envs["AWS_SESSION_TOKEN"] = *out.Credentials.SessionToken
+	}
+	if gcpCreds := ctx.Credentials.GCP; gcpCreds != nil {
+		ctx.Log.Debug("generating access token for GCP service account %s", gcpCreds.ServiceAccount)
+		token, err := p.GCP.GenerateAccessToken(gcpCreds.ServiceAccount, gcpCreds.Lifetime)
+		if err != nil {
+			return nil, errors.Wrapf(err, "generating access token for GCP service account %s", gcpCreds.ServiceAccount)
+		}
+		envs["GOOGLE_OAUTH_ACCESS_TOKEN"] = token
+	}
+	return envs, nil
+}
+
+// sessionName returns the AWS role session name for ctx. It identifies the
+// pull request in CloudTrail, ex. atlantis-owner.repo-12-project.
+func sessionName(ctx models.ProjectCommandContext) string {
+	name := fmt.Sprintf("atlantis-%s-%d", strings.Replace(ctx.Pull.BaseRepo.FullName, "/", ".", -1), ctx.Pull.Num)
+	if ctx.ProjectName != "" {
+		name += "-" + ctx.ProjectName
+	}
+	name = invalidSessionNameChars.ReplaceAllString(name, "_")
+	if len(name) > maxSessionNameLen {
+		name = name[:maxSessionNameLen]
+	}
+	return name
+}
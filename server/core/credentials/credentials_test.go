@@ -0,0 +1,127 @@
+package credentials_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	"github.com/runatlantis/atlantis/server/core/credentials"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// fakeSTS records the AssumeRole calls made by Provider.
+type fakeSTS struct {
+	stsiface.STSAPI
+	inputs []*sts.AssumeRoleInput
+	err    error
+}
+
+func (f *fakeSTS) AssumeRole(in *sts.AssumeRoleInput) (*sts.AssumeRoleOutput, error) {
+	f.inputs = append(f.inputs, in)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &sts.AssumeRoleOutput{
+		Credentials: &sts.Credentials{
+			AccessKeyId:     aws.String("key-id"),
+			SecretAccessKey: aws.String("secret"),
+			SessionToken:    aws.String("token"),
+		},
+	}, nil
+}
+
+type fakeGCP struct {
+	serviceAccount string
+	lifetime       time.Duration
+}
+
+func (f *fakeGCP) GenerateAccessToken(serviceAccount string, lifetime time.Duration) (string, error) {
+	f.serviceAccount = serviceAccount
+	f.lifetime = lifetime
+	return "gcp-token", nil
+}
+
+func projectCtx(t *testing.T, creds *valid.Credentials) models.ProjectCommandContext {
+	return models.ProjectCommandContext{
+		Log: logging.NewNoopLogger(t),
+		Pull: models.PullRequest{
+			Num:      12,
+			BaseRepo: models.Repo{FullName: "owner/repo"},
+		},
+		ProjectName: "prod",
+		Credentials: creds,
+	}
+}
+
+func TestProvider_Env_NoCredentials(t *testing.T) {
+	stsClient := &fakeSTS{}
+	p := &credentials.Provider{STS: stsClient, GCP: &fakeGCP{}}
+	envs, err := p.Env(projectCtx(t, nil))
+	Ok(t, err)
+	Equals(t, 0, len(envs))
+	Equals(t, 0, len(stsClient.inputs))
+}
+
+func TestProvider_Env_AWS(t *testing.T) {
+	stsClient := &fakeSTS{}
+	p := &credentials.Provider{STS: stsClient, GCP: &fakeGCP{}}
+	envs, err := p.Env(projectCtx(t, &valid.Credentials{
+		AWS: &valid.AWSCredentials{
+			RoleARN:         "arn:aws:iam::123456789012:role/prod",
+			SessionDuration: 30 * time.Minute,
+		},
+	}))
+	Ok(t, err)
+	Equals(t, map[string]string{
+		"AWS_ACCESS_KEY_ID":     "key-id",
+		"AWS_SECRET_ACCESS_KEY": "secret",
+		"AWS_SESSION_TOKEN":     "token",
+	}, envs)
+	Equals(t, 1, len(stsClient.inputs))
+	Equals(t, "arn:aws:iam::123456789012:role/prod", *stsClient.inputs[0].RoleArn)
+	Equals(t, "atlantis-owner.repo-12-prod", *stsClient.inputs[0].RoleSessionName)
+	Equals(t, int64(1800), *stsClient.inputs[0].DurationSeconds)
+}
+
+func TestProvider_Env_AWSSessionNameTruncated(t *testing.T) {
+	stsClient := &fakeSTS{}
+	p := &credentials.Provider{STS: stsClient, GCP: &fakeGCP{}}
+	ctx := projectCtx(t, &valid.Credentials{
+		AWS: &valid.AWSCredentials{RoleARN: "arn:aws:iam::123456789012:role/prod"},
+	})
+	ctx.ProjectName = strings.Repeat("a", 100) + " b"
+	_, err := p.Env(ctx)
+	Ok(t, err)
+	Equals(t, 64, len(*stsClient.inputs[0].RoleSessionName))
+	Assert(t, stsClient.inputs[0].DurationSeconds == nil, "exp STS default duration to be used")
+}
+
+func TestProvider_Env_AWSErr(t *testing.T) {
+	p := &credentials.Provider{STS: &fakeSTS{err: errors.New("access denied")}, GCP: &fakeGCP{}}
+	_, err := p.Env(projectCtx(t, &valid.Credentials{
+		AWS: &valid.AWSCredentials{RoleARN: "arn:aws:iam::123456789012:role/prod"},
+	}))
+	ErrEquals(t, "assuming AWS role arn:aws:iam::123456789012:role/prod: access denied", err)
+}
+
+func TestProvider_Env_GCP(t *testing.T) {
+	gcp := &fakeGCP{}
+	p := &credentials.Provider{STS: &fakeSTS{}, GCP: gcp}
+	envs, err := p.Env(projectCtx(t, &valid.Credentials{
+		GCP: &valid.GCPCredentials{
+			ServiceAccount: "prod@my-project.iam.gserviceaccount.com",
+			Lifetime:       time.Hour,
+		},
+	}))
+	Ok(t, err)
+	Equals(t, map[string]string{"GOOGLE_OAUTH_ACCESS_TOKEN": "gcp-token"}, envs)
+	Equals(t, "prod@my-project.iam.gserviceaccount.com", gcp.serviceAccount)
+	Equals(t, time.Hour, gcp.lifetime)
+}
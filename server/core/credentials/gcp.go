@@ -0,0 +1,65 @@
+package credentials
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	iamcredentials "google.golang.org/api/iamcredentials/v1"
+)
+
+// cloudPlatformScope is the OAuth scope of the access tokens generated for
+// service accounts. What they can do is limited by the accounts' IAM roles.
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// GCPTokenGenerator generates access tokens for GCP service accounts.
+type GCPTokenGenerator interface {
+	// GenerateAccessToken returns an access token for serviceAccount that
+	// expires after lifetime. If lifetime is zero, the API's default of one
+	// hour is used.
+	GenerateAccessToken(serviceAccount string, lifetime time.Duration) (string, error)
+}
+
+// IAMCredentialsTokenGenerator generates access tokens with the IAM
+// Credentials API using Application Default Credentials. The server's service
+// account needs the Service Account Token Creator role on the service
+// accounts it impersonates.
+type IAMCredentialsTokenGenerator struct {
+	mutex sync.Mutex
+	// svc is created on first use so that servers without GCP credentials
+	// can start.
+	svc *iamcredentials.Service
+}
+
+func (g *IAMCredentialsTokenGenerator) GenerateAccessToken(serviceAccount string, lifetime time.Duration) (string, error) {
+	svc, err := g.service()
+	if err != nil {
+		return "", err
+	}
+	req := &iamcredentials.GenerateAccessTokenRequest{
+		Scope: []string{cloudPlatformScope},
+	}
+	if lifetime != 0 {
+		req.Lifetime = fmt.Sprintf("%ds", int64(lifetime.Seconds()))
+	}
+	resp, err := svc.Projects.ServiceAccounts.GenerateAccessToken("projects/-/serviceAccounts/"+serviceAccount, req).Do()
+	if err != nil {
+		return "", err
+	}
+	return resp.AccessToken, nil
+}
+
+func (g *IAMCredentialsTokenGenerator) service() (*iamcredentials.Service, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if g.svc == nil {
+		svc, err := iamcredentials.NewService(context.Background())
+		if err != nil {
+			return nil, errors.Wrap(err, "creating IAM Credentials client")
+		}
+		g.svc = svc
+	}
+	return g.svc, nil
+}
@@ -3,10 +3,12 @@ package db
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path"
+	"sort"
 	"strings"
 	"time"
 
@@ -21,13 +23,29 @@ type BoltDB struct {
 	locksBucketName       []byte
 	pullsBucketName       []byte
 	globalLocksBucketName []byte
+	historyBucketName     []byte
+	auditBucketName       []byte
+	queueBucketName       []byte
+	deliveriesBucketName  []byte
+	reposBucketName       []byte
+	// deliveriesPrunedAt is when expired deliveries were last deleted. It's
+	// only used in write transactions, which BoltDB runs one at a time.
+	deliveriesPrunedAt time.Time
 }
 
 const (
 	locksBucketName       = "runLocks"
 	pullsBucketName       = "pulls"
 	globalLocksBucketName = "globalLocks"
+	historyBucketName     = "history"
+	auditBucketName       = "audit"
+	queueBucketName       = "commandQueue"
+	deliveriesBucketName  = "deliveries"
+	reposBucketName       = "registeredRepos"
 	pullKeySeparator      = "::"
+	// deliveryTTL is how long we remember webhook deliveries for. VCS hosts
+	// only retry failed deliveries within a few hours so this is plenty.
+	deliveryTTL = 72 * time.Hour
 )
 
 // New returns a valid locker. We need to be able to write to dataDir
@@ -55,6 +73,21 @@ func New(dataDir string) (*BoltDB, error) {
 		if _, err = tx.CreateBucketIfNotExists([]byte(globalLocksBucketName)); err != nil {
 			return errors.Wrapf(err, "creating bucket %q", globalLocksBucketName)
 		}
+		if _, err = tx.CreateBucketIfNotExists([]byte(historyBucketName)); err != nil {
+			return errors.Wrapf(err, "creating bucket %q", historyBucketName)
+		}
+		if _, err = tx.CreateBucketIfNotExists([]byte(auditBucketName)); err != nil {
+			return errors.Wrapf(err, "creating bucket %q", auditBucketName)
+		}
+		if _, err = tx.CreateBucketIfNotExists([]byte(queueBucketName)); err != nil {
+			return errors.Wrapf(err, "creating bucket %q", queueBucketName)
+		}
+		if _, err = tx.CreateBucketIfNotExists([]byte(deliveriesBucketName)); err != nil {
+			return errors.Wrapf(err, "creating bucket %q", deliveriesBucketName)
+		}
+		if _, err = tx.CreateBucketIfNotExists([]byte(reposBucketName)); err != nil {
+			return errors.Wrapf(err, "creating bucket %q", reposBucketName)
+		}
 		return nil
 	})
 	if err != nil {
@@ -66,6 +99,11 @@ func New(dataDir string) (*BoltDB, error) {
 		locksBucketName:       []byte(locksBucketName),
 		pullsBucketName:       []byte(pullsBucketName),
 		globalLocksBucketName: []byte(globalLocksBucketName),
+		historyBucketName:     []byte(historyBucketName),
+		auditBucketName:       []byte(auditBucketName),
+		queueBucketName:       []byte(queueBucketName),
+		deliveriesBucketName:  []byte(deliveriesBucketName),
+		reposBucketName:       []byte(reposBucketName),
 	}, nil
 }
 
@@ -76,6 +114,11 @@ func NewWithDB(db *bolt.DB, bucket string, globalBucket string) (*BoltDB, error)
 		locksBucketName:       []byte(bucket),
 		pullsBucketName:       []byte(pullsBucketName),
 		globalLocksBucketName: []byte(globalBucket),
+		historyBucketName:     []byte(historyBucketName),
+		auditBucketName:       []byte(auditBucketName),
+		queueBucketName:       []byte(queueBucketName),
+		deliveriesBucketName:  []byte(deliveriesBucketName),
+		reposBucketName:       []byte(reposBucketName),
 	}, nil
 }
 
@@ -86,7 +129,7 @@ func NewWithDB(db *bolt.DB, bucket string, globalBucket string) (*BoltDB, error)
 func (b *BoltDB) TryLock(newLock models.ProjectLock) (bool, models.ProjectLock, error) {
 	var lockAcquired bool
 	var currLock models.ProjectLock
-	key := b.lockKey(newLock.Project, newLock.Workspace)
+	key := b.lockKey(newLock.LockTarget())
 	newLockSerialized, _ := json.Marshal(newLock)
 	transactionErr := b.db.Update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket(b.locksBucketName)
@@ -356,6 +399,9 @@ func (b *BoltDB) UpdatePullWithResults(pull models.PullRequest, newResults []mod
 						res.ProjectName == proj.ProjectName {
 
 						proj.Status = res.PlanStatus()
+						if res.Command == models.PlanCommand {
+							proj.Targets = res.Targets
+						}
 						updatedExisting = true
 						break
 					}
@@ -392,6 +438,25 @@ func (b *BoltDB) GetPullStatus(pull models.PullRequest) (*models.PullStatus, err
 	return s, errors.Wrap(err, "DB transaction failed")
 }
 
+// ListPullStatuses returns the status of every pull request Atlantis has
+// run commands on that hasn't been closed, sorted by repo and pull number.
+func (b *BoltDB) ListPullStatuses() ([]models.PullStatus, error) {
+	var statuses []models.PullStatus
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(b.pullsBucketName)
+		return bucket.ForEach(func(k, v []byte) error {
+			var s models.PullStatus
+			if err := json.Unmarshal(v, &s); err != nil {
+				return errors.Wrapf(err, "deserializing pull at %q with contents %q", k, v)
+			}
+			statuses = append(statuses, s)
+			return nil
+		})
+	})
+	models.SortPullStatuses(statuses)
+	return statuses, errors.Wrap(err, "DB transaction failed")
+}
+
 // DeletePullStatus deletes the status for pull.
 func (b *BoltDB) DeletePullStatus(pull models.PullRequest) error {
 	key, err := b.pullKey(pull)
@@ -437,6 +502,286 @@ func (b *BoltDB) UpdateProjectStatus(pull models.PullRequest, workspace string,
 	return errors.Wrap(err, "DB transaction failed")
 }
 
+// AddProjectHistory saves the output of a command run on a project. There's
+// only one entry per project, command and commit so re-running a command
+// on the same commit overwrites the previous output.
+func (b *BoltDB) AddProjectHistory(history models.ProjectHistory) error {
+	key, err := b.historyKey(history)
+	if err != nil {
+		return err
+	}
+	serialized, err := json.Marshal(history)
+	if err != nil {
+		return errors.Wrap(err, "serializing")
+	}
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(b.historyBucketName)
+		return bucket.Put(key, serialized)
+	})
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// GetProjectHistory returns the saved command outputs for pull request
+// pullNum in repoFullName, oldest first. If pullNum is 0, the history for
+// every pull request in the repo is returned and if repoFullName is also
+// empty, the history for every repo is returned.
+func (b *BoltDB) GetProjectHistory(repoFullName string, pullNum int) ([]models.ProjectHistory, error) {
+	prefix := b.historyKeyPrefix(repoFullName, pullNum)
+	var history []models.ProjectHistory
+	err := b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(b.historyBucketName).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var h models.ProjectHistory
+			if err := json.Unmarshal(v, &h); err != nil {
+				return errors.Wrapf(err, "deserializing history at key %q", string(k))
+			}
+			// need to set it to Local after deserialization due to https://github.com/golang/go/issues/19486
+			h.Time = h.Time.Local()
+			history = append(history, h)
+		}
+		return nil
+	})
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Time.Before(history[j].Time)
+	})
+	return history, errors.Wrap(err, "DB transaction failed")
+}
+
+// AddAuditEvent appends event to the audit log. Events are keyed by an
+// increasing sequence number so they're never overwritten.
+func (b *BoltDB) AddAuditEvent(event models.AuditEvent) error {
+	serialized, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "serializing")
+	}
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(b.auditBucketName)
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, seq)
+		return bucket.Put(key, serialized)
+	})
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// ListAuditEvents returns every event in the audit log in the order they
+// were added.
+func (b *BoltDB) ListAuditEvents() ([]models.AuditEvent, error) {
+	var events []models.AuditEvent
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(b.auditBucketName)
+		return bucket.ForEach(func(k, v []byte) error {
+			var event models.AuditEvent
+			if err := json.Unmarshal(v, &event); err != nil {
+				return errors.Wrapf(err, "deserializing audit event at key %x", k)
+			}
+			// need to set it to Local after deserialization due to https://github.com/golang/go/issues/19486
+			event.Time = event.Time.Local()
+			events = append(events, event)
+			return nil
+		})
+	})
+	return events, errors.Wrap(err, "DB transaction failed")
+}
+
+// AddQueuedCommand saves cmd to the command queue, overwriting any command
+// with the same ID.
+func (b *BoltDB) AddQueuedCommand(cmd models.QueuedCommand) error {
+	serialized, err := json.Marshal(cmd)
+	if err != nil {
+		return errors.Wrap(err, "serializing")
+	}
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(b.queueBucketName)
+		return bucket.Put([]byte(cmd.ID), serialized)
+	})
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// DeleteQueuedCommand removes the command with id from the command queue.
+// It's not an error if it doesn't exist.
+func (b *BoltDB) DeleteQueuedCommand(id string) error {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(b.queueBucketName)
+		return bucket.Delete([]byte(id))
+	})
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// ListQueuedCommands returns the commands in the command queue, oldest
+// first.
+func (b *BoltDB) ListQueuedCommands() ([]models.QueuedCommand, error) {
+	var cmds []models.QueuedCommand
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(b.queueBucketName)
+		return bucket.ForEach(func(k, v []byte) error {
+			var cmd models.QueuedCommand
+			if err := json.Unmarshal(v, &cmd); err != nil {
+				return errors.Wrapf(err, "deserializing queued command at key %q", string(k))
+			}
+			cmds = append(cmds, cmd)
+			return nil
+		})
+	})
+	sort.SliceStable(cmds, func(i, j int) bool {
+		return cmds[i].Time.Before(cmds[j].Time)
+	})
+	return cmds, errors.Wrap(err, "DB transaction failed")
+}
+
+// ClaimDelivery records that the webhook delivery with id is being handled.
+// It returns false if the delivery was already claimed in the last
+// deliveryTTL.
+func (b *BoltDB) ClaimDelivery(id string) (bool, error) {
+	claimed := false
+	now := time.Now()
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(b.deliveriesBucketName)
+		if claimedAt := bucket.Get([]byte(id)); claimedAt != nil && now.Sub(deliveryTime(claimedAt)) < deliveryTTL {
+			return nil
+		}
+		claimed = true
+		value := make([]byte, 8)
+		binary.BigEndian.PutUint64(value, uint64(now.Unix()))
+		if err := bucket.Put([]byte(id), value); err != nil {
+			return err
+		}
+		// Expired deliveries are deleted at most hourly since it means
+		// reading every delivery.
+		if now.Sub(b.deliveriesPrunedAt) < time.Hour {
+			return nil
+		}
+		b.deliveriesPrunedAt = now
+		var expired [][]byte
+		if err := bucket.ForEach(func(k, v []byte) error {
+			if now.Sub(deliveryTime(v)) >= deliveryTTL {
+				expired = append(expired, k)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, k := range expired {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return claimed, errors.Wrap(err, "DB transaction failed")
+}
+
+// ReleaseDelivery deletes the claim on the webhook delivery with id.
+func (b *BoltDB) ReleaseDelivery(id string) error {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(b.deliveriesBucketName).Delete([]byte(id))
+	})
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// deliveryTime returns when the delivery whose value is v was claimed.
+func deliveryTime(v []byte) time.Time {
+	if len(v) != 8 {
+		return time.Time{}
+	}
+	return time.Unix(int64(binary.BigEndian.Uint64(v)), 0)
+}
+
+// AddRegisteredRepo saves repo, overwriting any repo with the same ID.
+func (b *BoltDB) AddRegisteredRepo(repo models.RegisteredRepo) error {
+	serialized, err := json.Marshal(repo)
+	if err != nil {
+		return errors.Wrap(err, "serializing")
+	}
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(b.reposBucketName)
+		return bucket.Put([]byte(repo.ID()), serialized)
+	})
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// AddRegisteredRepoIfAbsent saves repo unless a repo with the same ID is
+// already saved, in which case it returns false.
+func (b *BoltDB) AddRegisteredRepoIfAbsent(repo models.RegisteredRepo) (bool, error) {
+	serialized, err := json.Marshal(repo)
+	if err != nil {
+		return false, errors.Wrap(err, "serializing")
+	}
+	added := false
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(b.reposBucketName)
+		if bucket.Get([]byte(repo.ID())) != nil {
+			return nil
+		}
+		added = true
+		return bucket.Put([]byte(repo.ID()), serialized)
+	})
+	return added, errors.Wrap(err, "DB transaction failed")
+}
+
+// DeleteRegisteredRepo removes the registered repo with id. It's not an error
+// if it doesn't exist.
+func (b *BoltDB) DeleteRegisteredRepo(id string) error {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(b.reposBucketName)
+		return bucket.Delete([]byte(id))
+	})
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// ListRegisteredRepos returns the registered repos ordered by ID.
+func (b *BoltDB) ListRegisteredRepos() ([]models.RegisteredRepo, error) {
+	var repos []models.RegisteredRepo
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(b.reposBucketName)
+		return bucket.ForEach(func(k, v []byte) error {
+			var repo models.RegisteredRepo
+			if err := json.Unmarshal(v, &repo); err != nil {
+				return errors.Wrapf(err, "deserializing registered repo at key %q", string(k))
+			}
+			repos = append(repos, repo)
+			return nil
+		})
+	})
+	return repos, errors.Wrap(err, "DB transaction failed")
+}
+
+func (b *BoltDB) historyKeyPrefix(repoFullName string, pullNum int) []byte {
+	if repoFullName == "" {
+		return nil
+	}
+	if pullNum == 0 {
+		return []byte(fmt.Sprintf("%s%s", repoFullName, pullKeySeparator))
+	}
+	return []byte(fmt.Sprintf("%s%s%d%s", repoFullName, pullKeySeparator, pullNum, pullKeySeparator))
+}
+
+func (b *BoltDB) historyKey(h models.ProjectHistory) ([]byte, error) {
+	repo := h.Pull.BaseRepo.FullName
+	if strings.Contains(repo, pullKeySeparator) {
+		return nil, fmt.Errorf("repo name %q contains illegal string %q", repo, pullKeySeparator)
+	}
+	hostname := h.Pull.BaseRepo.VCSHost.Hostname
+	if strings.Contains(hostname, pullKeySeparator) {
+		return nil, fmt.Errorf("vcs hostname %q contains illegal string %q", hostname, pullKeySeparator)
+	}
+	return []byte(strings.Join([]string{
+		repo,
+		fmt.Sprintf("%d", h.Pull.Num),
+		// The hostname comes after the pull number so that history can
+		// still be looked up by repo and pull number prefix.
+		hostname,
+		h.Pull.HeadCommit,
+		h.Command.String(),
+		h.RepoRelDir,
+		h.Workspace,
+		h.ProjectName,
+	}, pullKeySeparator)), nil
+}
+
 func (b *BoltDB) pullKey(pull models.PullRequest) ([]byte, error) {
 	hostname := pull.BaseRepo.VCSHost.Hostname
 	if strings.Contains(hostname, pullKeySeparator) {
@@ -486,5 +831,6 @@ func (b *BoltDB) projectResultToProject(p models.ProjectResult) models.ProjectSt
 		RepoRelDir:  p.RepoRelDir,
 		ProjectName: p.ProjectName,
 		Status:      p.PlanStatus(),
+		Targets:     p.Targets,
 	}
 }
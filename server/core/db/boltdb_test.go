@@ -23,6 +23,7 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 	. "github.com/runatlantis/atlantis/testing"
 	bolt "go.etcd.io/bbolt"
 )
@@ -197,6 +198,30 @@ func TestLockingNoLocks(t *testing.T) {
 	Equals(t, lock, currLock)
 }
 
+func TestLockingGranularity(t *testing.T) {
+	t.Log("with dir granularity, a lock on a dir should block other workspaces")
+	db, b := newTestDB()
+	defer cleanupDB(db)
+	dirLock := lock
+	dirLock.Granularity = valid.DirLockGranularity
+	_, _, err := b.TryLock(dirLock)
+	Ok(t, err)
+
+	newLock := dirLock
+	newLock.Workspace = "different-workspace"
+	newLock.Pull.Num = pullNum + 1
+	acquired, currLock, err := b.TryLock(newLock)
+	Ok(t, err)
+	Equals(t, false, acquired)
+	Equals(t, dirLock, currLock)
+
+	t.Log("...and can be unlocked by its target")
+	target, targetWorkspace := dirLock.LockTarget()
+	l, err := b.Unlock(target, targetWorkspace)
+	Ok(t, err)
+	Equals(t, &dirLock, l)
+}
+
 func TestLockingExistingLock(t *testing.T) {
 	t.Log("if there is an existing lock, lock should...")
 	db, b := newTestDB()
@@ -480,6 +505,48 @@ func TestPullStatus_UpdateGet(t *testing.T) {
 	}, status.Projects)
 }
 
+func TestPullStatus_List(t *testing.T) {
+	b, cleanup := newTestDB2(t)
+	defer cleanup()
+
+	statuses, err := b.ListPullStatuses()
+	Ok(t, err)
+	Equals(t, 0, len(statuses))
+
+	repo := func(fullName string) models.Repo {
+		return models.Repo{
+			FullName: fullName,
+			VCSHost: models.VCSHost{
+				Hostname: "github.com",
+				Type:     models.Github,
+			},
+		}
+	}
+	results := []models.ProjectResult{
+		{
+			Command:    models.PlanCommand,
+			RepoRelDir: ".",
+			Workspace:  "default",
+			Failure:    "failure",
+		},
+	}
+	b2 := models.PullRequest{Num: 2, BaseRepo: repo("owner/b")}
+	a10 := models.PullRequest{Num: 10, BaseRepo: repo("owner/a")}
+	a9 := models.PullRequest{Num: 9, BaseRepo: repo("owner/a")}
+	for _, pull := range []models.PullRequest{b2, a10, a9} {
+		_, err = b.UpdatePullWithResults(pull, results)
+		Ok(t, err)
+	}
+	Ok(t, b.DeletePullStatus(b2))
+
+	statuses, err = b.ListPullStatuses()
+	Ok(t, err)
+	Equals(t, 2, len(statuses))
+	Equals(t, a9, statuses[0].Pull)
+	Equals(t, a10, statuses[1].Pull)
+	Equals(t, models.ErroredPlanStatus, statuses[0].Projects[0].Status)
+}
+
 // Test we can create a status, delete it, and then we shouldn't be able to getCommandLock
 // it.
 func TestPullStatus_UpdateDeleteGet(t *testing.T) {
@@ -767,6 +834,280 @@ func TestPullStatus_UpdateMerge(t *testing.T) {
 }
 
 // newTestDB returns a TestDB using a temporary path.
+// Test that the targets of a plan are kept until the project is planned again.
+func TestPullStatus_UpdateTargets(t *testing.T) {
+	b, cleanup := newTestDB2(t)
+	defer cleanup()
+
+	pull := models.PullRequest{
+		Num:        1,
+		HeadCommit: "sha",
+		BaseRepo: models.Repo{
+			FullName: "runatlantis/atlantis",
+			VCSHost: models.VCSHost{
+				Hostname: "github.com",
+				Type:     models.Github,
+			},
+		},
+	}
+	status, err := b.UpdatePullWithResults(pull, []models.ProjectResult{
+		{
+			Command:     models.PlanCommand,
+			RepoRelDir:  ".",
+			Workspace:   "default",
+			PlanSuccess: &models.PlanSuccess{},
+			Targets:     []string{"aws_instance.web"},
+		},
+	})
+	Ok(t, err)
+	Equals(t, []string{"aws_instance.web"}, status.Projects[0].Targets)
+
+	t.Log("policy checks don't change the targets")
+	status, err = b.UpdatePullWithResults(pull, []models.ProjectResult{
+		{
+			Command:            models.PolicyCheckCommand,
+			RepoRelDir:         ".",
+			Workspace:          "default",
+			PolicyCheckSuccess: &models.PolicyCheckSuccess{},
+		},
+	})
+	Ok(t, err)
+	Equals(t, []string{"aws_instance.web"}, status.Projects[0].Targets)
+
+	t.Log("planning again replaces the targets")
+	status, err = b.UpdatePullWithResults(pull, []models.ProjectResult{
+		{
+			Command:     models.PlanCommand,
+			RepoRelDir:  ".",
+			Workspace:   "default",
+			PlanSuccess: &models.PlanSuccess{},
+		},
+	})
+	Ok(t, err)
+	Equals(t, []string(nil), status.Projects[0].Targets)
+}
+
+func TestProjectHistory_AddGet(t *testing.T) {
+	r, cleanup := newTestDB2(t)
+	defer cleanup()
+
+	repo := models.Repo{FullName: "runatlantis/atlantis"}
+	plan := models.ProjectHistory{
+		Pull:       models.PullRequest{Num: 1, HeadCommit: "sha", BaseRepo: repo},
+		Workspace:  "default",
+		RepoRelDir: ".",
+		Command:    models.PlanCommand,
+		Status:     models.PlannedPlanStatus,
+		Output:     "Plan: 1 to add, 0 to change, 0 to destroy.",
+		Time:       time.Now().Add(-time.Minute),
+	}
+	apply := plan
+	apply.Command = models.ApplyCommand
+	apply.Status = models.AppliedPlanStatus
+	apply.Output = "Apply complete!"
+	apply.Time = time.Now()
+	otherPull := plan
+	otherPull.Pull = models.PullRequest{Num: 10, HeadCommit: "sha2", BaseRepo: repo}
+	otherRepo := plan
+	otherRepo.Pull = models.PullRequest{Num: 1, HeadCommit: "sha", BaseRepo: models.Repo{FullName: "runatlantis/atlantis-other"}}
+
+	t.Log("history is returned oldest first")
+	for _, h := range []models.ProjectHistory{apply, plan, otherPull, otherRepo} {
+		Ok(t, r.AddProjectHistory(h))
+	}
+	history, err := r.GetProjectHistory("runatlantis/atlantis", 1)
+	Ok(t, err)
+	Equals(t, 2, len(history))
+	Equals(t, plan.Output, history[0].Output)
+	Equals(t, apply.Output, history[1].Output)
+
+	t.Log("re-running a command on the same commit overwrites its output")
+	plan.Output = "No changes."
+	Ok(t, r.AddProjectHistory(plan))
+	history, err = r.GetProjectHistory("runatlantis/atlantis", 1)
+	Ok(t, err)
+	Equals(t, 2, len(history))
+	Equals(t, "No changes.", history[0].Output)
+
+	t.Log("pull number 0 returns the history for every pull in the repo")
+	history, err = r.GetProjectHistory("runatlantis/atlantis", 0)
+	Ok(t, err)
+	Equals(t, 3, len(history))
+
+	t.Log("an empty repo returns the history for every repo")
+	history, err = r.GetProjectHistory("", 0)
+	Ok(t, err)
+	Equals(t, 4, len(history))
+
+	t.Log("the same pull on another VCS host doesn't overwrite its history")
+	otherHost := plan
+	otherHost.Pull.BaseRepo = models.Repo{FullName: "runatlantis/atlantis", VCSHost: models.VCSHost{Hostname: "gitlab.com"}}
+	Ok(t, r.AddProjectHistory(otherHost))
+	history, err = r.GetProjectHistory("runatlantis/atlantis", 1)
+	Ok(t, err)
+	Equals(t, 3, len(history))
+}
+
+func TestAuditEvents_AddList(t *testing.T) {
+	r, cleanup := newTestDB2(t)
+	defer cleanup()
+
+	events, err := r.ListAuditEvents()
+	Ok(t, err)
+	Equals(t, 0, len(events))
+
+	plan := models.AuditEvent{
+		Time:     time.Now(),
+		Username: "lkysow",
+		Repo:     "runatlantis/atlantis",
+		PullNum:  1,
+		Command:  "plan",
+		Result:   models.AuditResultSuccess,
+	}
+	apply := plan
+	apply.Command = "apply"
+	apply.Result = models.AuditResultFailure
+	apply.Error = "exit status 1"
+
+	t.Log("events are appended in order, even if they're identical")
+	for _, e := range []models.AuditEvent{plan, apply, plan} {
+		Ok(t, r.AddAuditEvent(e))
+	}
+	events, err = r.ListAuditEvents()
+	Ok(t, err)
+	Equals(t, 3, len(events))
+	Equals(t, "plan", events[0].Command)
+	Equals(t, "apply", events[1].Command)
+	Equals(t, "exit status 1", events[1].Error)
+	Equals(t, "plan", events[2].Command)
+}
+
+func TestQueuedCommands_AddDeleteList(t *testing.T) {
+	r, cleanup := newTestDB2(t)
+	defer cleanup()
+	cmds, err := r.ListQueuedCommands()
+	Ok(t, err)
+	Equals(t, 0, len(cmds))
+
+	now := time.Now()
+	first := models.QueuedCommand{
+		ID:      "first",
+		Time:    now,
+		PullNum: 1,
+		Comment: []byte(`{"Name":1}`),
+	}
+	second := models.QueuedCommand{
+		ID:      "second",
+		Time:    now.Add(time.Second),
+		PullNum: 2,
+	}
+	Ok(t, r.AddQueuedCommand(second))
+	Ok(t, r.AddQueuedCommand(first))
+
+	t.Log("commands are listed oldest first")
+	cmds, err = r.ListQueuedCommands()
+	Ok(t, err)
+	Equals(t, 2, len(cmds))
+	Equals(t, "first", cmds[0].ID)
+	Equals(t, false, cmds[0].IsAutoplan())
+	Equals(t, "second", cmds[1].ID)
+	Equals(t, true, cmds[1].IsAutoplan())
+
+	t.Log("adding a command with the same ID overwrites it")
+	first.Attempts = 1
+	Ok(t, r.AddQueuedCommand(first))
+	cmds, err = r.ListQueuedCommands()
+	Ok(t, err)
+	Equals(t, 2, len(cmds))
+	Equals(t, 1, cmds[0].Attempts)
+
+	Ok(t, r.DeleteQueuedCommand("first"))
+	Ok(t, r.DeleteQueuedCommand("doesnotexist"))
+	cmds, err = r.ListQueuedCommands()
+	Ok(t, err)
+	Equals(t, 1, len(cmds))
+	Equals(t, "second", cmds[0].ID)
+}
+
+func TestRegisteredRepos_AddDeleteList(t *testing.T) {
+	r, cleanup := newTestDB2(t)
+	defer cleanup()
+	repos, err := r.ListRegisteredRepos()
+	Ok(t, err)
+	Equals(t, 0, len(repos))
+
+	first := models.RegisteredRepo{FullName: "owner/a", Hostname: "github.com", PlanOnly: true}
+	second := models.RegisteredRepo{FullName: "owner/b", Hostname: "github.com", WebhookID: 12}
+	Ok(t, r.AddRegisteredRepo(second))
+	Ok(t, r.AddRegisteredRepo(first))
+
+	t.Log("repos are listed by ID")
+	repos, err = r.ListRegisteredRepos()
+	Ok(t, err)
+	Equals(t, 2, len(repos))
+	Equals(t, "github.com/owner/a", repos[0].ID())
+	Equals(t, true, repos[0].PlanOnly)
+	Equals(t, int64(12), repos[1].WebhookID)
+
+	t.Log("adding a repo with the same ID overwrites it")
+	first.MaxParallel = 2
+	Ok(t, r.AddRegisteredRepo(first))
+	repos, err = r.ListRegisteredRepos()
+	Ok(t, err)
+	Equals(t, 2, len(repos))
+	Equals(t, 2, repos[0].MaxParallel)
+
+	Ok(t, r.DeleteRegisteredRepo("github.com/owner/a"))
+	Ok(t, r.DeleteRegisteredRepo("doesnotexist"))
+	repos, err = r.ListRegisteredRepos()
+	Ok(t, err)
+	Equals(t, 1, len(repos))
+	Equals(t, "github.com/owner/b", repos[0].ID())
+}
+
+func TestRegisteredRepos_AddIfAbsent(t *testing.T) {
+	r, cleanup := newTestDB2(t)
+	defer cleanup()
+	repo := models.RegisteredRepo{FullName: "owner/a", Hostname: "github.com", PlanOnly: true}
+	added, err := r.AddRegisteredRepoIfAbsent(repo)
+	Ok(t, err)
+	Equals(t, true, added)
+
+	t.Log("a repo with the same ID isn't overwritten")
+	repo.PlanOnly = false
+	added, err = r.AddRegisteredRepoIfAbsent(repo)
+	Ok(t, err)
+	Equals(t, false, added)
+	repos, err := r.ListRegisteredRepos()
+	Ok(t, err)
+	Equals(t, 1, len(repos))
+	Equals(t, true, repos[0].PlanOnly)
+}
+
+func TestClaimDelivery(t *testing.T) {
+	r, cleanup := newTestDB2(t)
+	defer cleanup()
+
+	claimed, err := r.ClaimDelivery("github/1")
+	Ok(t, err)
+	Equals(t, true, claimed)
+
+	t.Log("a delivery can only be claimed once")
+	claimed, err = r.ClaimDelivery("github/1")
+	Ok(t, err)
+	Equals(t, false, claimed)
+
+	claimed, err = r.ClaimDelivery("github/2")
+	Ok(t, err)
+	Equals(t, true, claimed)
+
+	t.Log("a released delivery can be claimed again")
+	Ok(t, r.ReleaseDelivery("github/1"))
+	claimed, err = r.ClaimDelivery("github/1")
+	Ok(t, err)
+	Equals(t, true, claimed)
+}
+
 func newTestDB() (*bolt.DB, *db.BoltDB) {
 	// Retrieve a temporary path.
 	f, err := ioutil.TempFile("", "")
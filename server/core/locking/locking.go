@@ -33,6 +33,27 @@ type Backend interface {
 	GetLock(project models.Project, workspace string) (*models.ProjectLock, error)
 	UnlockByPull(repoFullName string, pullNum int) ([]models.ProjectLock, error)
 
+	UpdateProjectStatus(pull models.PullRequest, workspace string, repoRelDir string, newStatus models.ProjectPlanStatus) error
+	GetPullStatus(pull models.PullRequest) (*models.PullStatus, error)
+	ListPullStatuses() ([]models.PullStatus, error)
+	DeletePullStatus(pull models.PullRequest) error
+	UpdatePullWithResults(pull models.PullRequest, newResults []models.ProjectResult) (models.PullStatus, error)
+
+	AddProjectHistory(history models.ProjectHistory) error
+	GetProjectHistory(repoFullName string, pullNum int) ([]models.ProjectHistory, error)
+
+	AddAuditEvent(event models.AuditEvent) error
+	ListAuditEvents() ([]models.AuditEvent, error)
+
+	AddQueuedCommand(cmd models.QueuedCommand) error
+	DeleteQueuedCommand(id string) error
+	ListQueuedCommands() ([]models.QueuedCommand, error)
+
+	AddRegisteredRepo(repo models.RegisteredRepo) error
+	AddRegisteredRepoIfAbsent(repo models.RegisteredRepo) (bool, error)
+	DeleteRegisteredRepo(id string) error
+	ListRegisteredRepos() ([]models.RegisteredRepo, error)
+
 	LockCommand(cmdName models.CommandName, lockTime time.Time) (*models.CommandLock, error)
 	UnlockCommand(cmdName models.CommandName) error
 	CheckCommandLock(cmdName models.CommandName) (*models.CommandLock, error)
@@ -56,7 +77,7 @@ type Client struct {
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_locker.go Locker
 
 type Locker interface {
-	TryLock(p models.Project, workspace string, pull models.PullRequest, user models.User) (TryLockResponse, error)
+	TryLock(p models.Project, workspace string, pull models.PullRequest, user models.User, granularity string) (TryLockResponse, error)
 	Unlock(key string) (*models.ProjectLock, error)
 	List() (map[string]models.ProjectLock, error)
 	UnlockByPull(repoFullName string, pullNum int) ([]models.ProjectLock, error)
@@ -73,20 +94,24 @@ func NewClient(backend Backend) *Client {
 // keyRegex matches and captures {repoFullName}/{path}/{workspace} where path can have multiple /'s in it.
 var keyRegex = regexp.MustCompile(`^(.*?\/.*?)\/(.*)\/(.*)$`)
 
-// TryLock attempts to acquire a lock to a project and workspace.
-func (c *Client) TryLock(p models.Project, workspace string, pull models.PullRequest, user models.User) (TryLockResponse, error) {
+// TryLock attempts to acquire a lock to a project and workspace. granularity
+// is what the lock is held on, one of the valid.*LockGranularity values, ex.
+// with valid.DirLockGranularity the project's dir is locked in every
+// workspace.
+func (c *Client) TryLock(p models.Project, workspace string, pull models.PullRequest, user models.User, granularity string) (TryLockResponse, error) {
 	lock := models.ProjectLock{
-		Workspace: workspace,
-		Time:      time.Now().Local(),
-		Project:   p,
-		User:      user,
-		Pull:      pull,
+		Workspace:   workspace,
+		Time:        time.Now().Local(),
+		Project:     p,
+		User:        user,
+		Pull:        pull,
+		Granularity: granularity,
 	}
 	lockAcquired, currLock, err := c.backend.TryLock(lock)
 	if err != nil {
 		return TryLockResponse{}, err
 	}
-	return TryLockResponse{lockAcquired, currLock, c.key(p, workspace)}, nil
+	return TryLockResponse{lockAcquired, currLock, c.key(lock.LockTarget())}, nil
 }
 
 // Unlock attempts to unlock a project and workspace. If successful,
@@ -110,7 +135,7 @@ func (c *Client) List() (map[string]models.ProjectLock, error) {
 		return m, err
 	}
 	for _, lock := range locks {
-		m[c.key(lock.Project, lock.Workspace)] = lock
+		m[c.key(lock.LockTarget())] = lock
 	}
 	return m, nil
 }
@@ -159,8 +184,9 @@ func NewNoOpLocker() *NoOpLocker {
 }
 
 // TryLock attempts to acquire a lock to a project and workspace.
-func (c *NoOpLocker) TryLock(p models.Project, workspace string, pull models.PullRequest, user models.User) (TryLockResponse, error) {
-	return TryLockResponse{true, models.ProjectLock{}, c.key(p, workspace)}, nil
+func (c *NoOpLocker) TryLock(p models.Project, workspace string, pull models.PullRequest, user models.User, granularity string) (TryLockResponse, error) {
+	lock := models.ProjectLock{Workspace: workspace, Project: p, Granularity: granularity}
+	return TryLockResponse{true, models.ProjectLock{}, c.key(lock.LockTarget())}, nil
 }
 
 // Unlock attempts to unlock a project and workspace. If successful,
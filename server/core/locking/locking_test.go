@@ -25,6 +25,7 @@ import (
 	"github.com/runatlantis/atlantis/server/core/locking/mocks"
 	"github.com/runatlantis/atlantis/server/core/locking/mocks/matchers"
 	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 	. "github.com/runatlantis/atlantis/testing"
 )
 
@@ -42,7 +43,7 @@ func TestTryLock_Err(t *testing.T) {
 	When(backend.TryLock(matchers.AnyModelsProjectLock())).ThenReturn(false, models.ProjectLock{}, errExpected)
 	t.Log("when the backend returns an error, TryLock should return that error")
 	l := locking.NewClient(backend)
-	_, err := l.TryLock(project, workspace, pull, user)
+	_, err := l.TryLock(project, workspace, pull, user, "")
 	Equals(t, err, err)
 }
 
@@ -52,11 +53,56 @@ func TestTryLock_Success(t *testing.T) {
 	backend := mocks.NewMockBackend()
 	When(backend.TryLock(matchers.AnyModelsProjectLock())).ThenReturn(true, currLock, nil)
 	l := locking.NewClient(backend)
-	r, err := l.TryLock(project, workspace, pull, user)
+	r, err := l.TryLock(project, workspace, pull, user, "")
 	Ok(t, err)
 	Equals(t, locking.TryLockResponse{LockAcquired: true, CurrLock: currLock, LockKey: "owner/repo/path/workspace"}, r)
 }
 
+func TestTryLock_NoOpLockerDirGranularity(t *testing.T) {
+	l := locking.NewNoOpLocker()
+	r, err := l.TryLock(project, workspace, pull, user, valid.DirLockGranularity)
+	Ok(t, err)
+	Equals(t, "owner/repo/path/*", r.LockKey)
+}
+
+func TestTryLock_Granularity(t *testing.T) {
+	namedProject := models.NewProject("owner/repo", "path")
+	namedProject.ProjectName = "staging"
+	cases := []struct {
+		granularity string
+		project     models.Project
+		expKey      string
+	}{
+		{valid.DirWorkspaceLockGranularity, namedProject, "owner/repo/path/workspace"},
+		{valid.DirLockGranularity, namedProject, "owner/repo/path/*"},
+		{valid.ProjectLockGranularity, namedProject, "owner/repo/project:staging/*"},
+		{valid.ProjectLockGranularity, project, "owner/repo/path/workspace"},
+	}
+	for _, c := range cases {
+		t.Run(c.granularity+" "+c.project.ProjectName, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			backend := mocks.NewMockBackend()
+			When(backend.TryLock(matchers.AnyModelsProjectLock())).ThenReturn(true, models.ProjectLock{}, nil)
+			l := locking.NewClient(backend)
+			r, err := l.TryLock(c.project, workspace, pull, user, c.granularity)
+			Ok(t, err)
+			Equals(t, c.expKey, r.LockKey)
+
+			t.Log("the lock keeps the project's dir and workspace")
+			lock := backend.VerifyWasCalledOnce().TryLock(matchers.AnyModelsProjectLock()).GetCapturedArguments()
+			Equals(t, c.project, lock.Project)
+			Equals(t, workspace, lock.Workspace)
+			Equals(t, c.granularity, lock.Granularity)
+
+			t.Log("the key can be used to unlock the lock")
+			_, err = l.Unlock(r.LockKey)
+			Ok(t, err)
+			target, targetWorkspace := lock.LockTarget()
+			backend.VerifyWasCalledOnce().Unlock(target, targetWorkspace)
+		})
+	}
+}
+
 func TestUnlock_InvalidKey(t *testing.T) {
 	RegisterMockTestingT(t)
 	backend := mocks.NewMockBackend()
@@ -149,7 +195,7 @@ func TestTryLock_NoOpLocker(t *testing.T) {
 	RegisterMockTestingT(t)
 	currLock := models.ProjectLock{}
 	l := locking.NewNoOpLocker()
-	r, err := l.TryLock(project, workspace, pull, user)
+	r, err := l.TryLock(project, workspace, pull, user, "")
 	Ok(t, err)
 	Equals(t, locking.TryLockResponse{LockAcquired: true, CurrLock: currLock, LockKey: "owner/repo/path/workspace"}, r)
 }
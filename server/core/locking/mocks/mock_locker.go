@@ -26,11 +26,11 @@ func NewMockLocker(options ...pegomock.Option) *MockLocker {
 func (mock *MockLocker) SetFailHandler(fh pegomock.FailHandler) { mock.fail = fh }
 func (mock *MockLocker) FailHandler() pegomock.FailHandler      { return mock.fail }
 
-func (mock *MockLocker) TryLock(p models.Project, workspace string, pull models.PullRequest, user models.User) (locking.TryLockResponse, error) {
+func (mock *MockLocker) TryLock(p models.Project, workspace string, pull models.PullRequest, user models.User, granularity string) (locking.TryLockResponse, error) {
 	if mock == nil {
 		panic("mock must not be nil. Use myMock := NewMockLocker().")
 	}
-	params := []pegomock.Param{p, workspace, pull, user}
+	params := []pegomock.Param{p, workspace, pull, user, granularity}
 	result := pegomock.GetGenericMockFrom(mock).Invoke("TryLock", params, []reflect.Type{reflect.TypeOf((*locking.TryLockResponse)(nil)).Elem(), reflect.TypeOf((*error)(nil)).Elem()})
 	var ret0 locking.TryLockResponse
 	var ret1 error
@@ -158,8 +158,8 @@ type VerifierMockLocker struct {
 	timeout                time.Duration
 }
 
-func (verifier *VerifierMockLocker) TryLock(p models.Project, workspace string, pull models.PullRequest, user models.User) *MockLocker_TryLock_OngoingVerification {
-	params := []pegomock.Param{p, workspace, pull, user}
+func (verifier *VerifierMockLocker) TryLock(p models.Project, workspace string, pull models.PullRequest, user models.User, granularity string) *MockLocker_TryLock_OngoingVerification {
+	params := []pegomock.Param{p, workspace, pull, user, granularity}
 	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "TryLock", params, verifier.timeout)
 	return &MockLocker_TryLock_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
 }
@@ -169,12 +169,12 @@ type MockLocker_TryLock_OngoingVerification struct {
 	methodInvocations []pegomock.MethodInvocation
 }
 
-func (c *MockLocker_TryLock_OngoingVerification) GetCapturedArguments() (models.Project, string, models.PullRequest, models.User) {
-	p, workspace, pull, user := c.GetAllCapturedArguments()
-	return p[len(p)-1], workspace[len(workspace)-1], pull[len(pull)-1], user[len(user)-1]
+func (c *MockLocker_TryLock_OngoingVerification) GetCapturedArguments() (models.Project, string, models.PullRequest, models.User, string) {
+	p, workspace, pull, user, granularity := c.GetAllCapturedArguments()
+	return p[len(p)-1], workspace[len(workspace)-1], pull[len(pull)-1], user[len(user)-1], granularity[len(granularity)-1]
 }
 
-func (c *MockLocker_TryLock_OngoingVerification) GetAllCapturedArguments() (_param0 []models.Project, _param1 []string, _param2 []models.PullRequest, _param3 []models.User) {
+func (c *MockLocker_TryLock_OngoingVerification) GetAllCapturedArguments() (_param0 []models.Project, _param1 []string, _param2 []models.PullRequest, _param3 []models.User, _param4 []string) {
 	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
 	if len(params) > 0 {
 		_param0 = make([]models.Project, len(c.methodInvocations))
@@ -193,6 +193,10 @@ func (c *MockLocker_TryLock_OngoingVerification) GetAllCapturedArguments() (_par
 		for u, param := range params[3] {
 			_param3[u] = param.(models.User)
 		}
+		_param4 = make([]string, len(c.methodInvocations))
+		for u, param := range params[4] {
+			_param4[u] = param.(string)
+		}
 	}
 	return
 }
@@ -0,0 +1,163 @@
+// Package modules resolves the local Terraform modules used by the
+// configurations in a repo so that changes to a module can be traced to the
+// projects that use it.
+package modules
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-config-inspect/tfconfig"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// DependencyGraph is the graph of local module calls in a repo. All dirs are
+// relative to the repo root and use forward slashes.
+type DependencyGraph struct {
+	// Calls maps the dir of each Terraform configuration to the dirs of the
+	// local modules it calls.
+	Calls map[string][]string `json:"calls"`
+	// Callers is the reverse of Calls. It maps the dir of each local module to
+	// the dirs that call it.
+	Callers map[string][]string `json:"callers"`
+}
+
+// BuildDependencyGraph parses the Terraform files in the repo at absRepoDir
+// and returns the graph of its local module calls. Only modules with a local
+// source, ex. "../modules/vpc", are included since those are the only ones
+// that can be modified in the same pull request.
+func BuildDependencyGraph(log logging.SimpleLogging, absRepoDir string) (*DependencyGraph, error) {
+	g := &DependencyGraph{
+		Calls:   make(map[string][]string),
+		Callers: make(map[string][]string),
+	}
+	err := filepath.Walk(absRepoDir, func(absPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if info.Name() == ".git" || info.Name() == ".terraform" {
+			return filepath.SkipDir
+		}
+		if !tfconfig.IsModuleDir(absPath) {
+			return nil
+		}
+		relDir, err := filepath.Rel(absRepoDir, absPath)
+		if err != nil {
+			return err
+		}
+		relDir = filepath.ToSlash(relDir)
+		module, diags := tfconfig.LoadModule(absPath)
+		if diags.HasErrors() {
+			// We still use whatever module calls could be parsed.
+			log.Debug("parsing Terraform files in %q: %s", relDir, diags.Err())
+		}
+
+		// Sort so the graph is deterministic.
+		var names []string
+		for name := range module.ModuleCalls {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			source := module.ModuleCalls[name].Source
+			if !isLocalSource(source) {
+				continue
+			}
+			called := path.Join(relDir, source)
+			if strings.HasPrefix(called, "../") {
+				// Modules outside of the repo can't be modified by a pull
+				// request.
+				continue
+			}
+			g.add(relDir, called)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// ModuleDir returns the closest dir, starting at dir and walking up to the
+// repo root, that is called as a local module. It returns an empty string if
+// there isn't one. It's used to find the module that a modified file is part
+// of, even if the file isn't a Terraform file, ex. a template.
+func (g *DependencyGraph) ModuleDir(dir string) string {
+	dir = path.Clean(dir)
+	for {
+		if _, ok := g.Callers[dir]; ok {
+			return dir
+		}
+		if dir == "." || dir == "/" {
+			return ""
+		}
+		dir = path.Dir(dir)
+	}
+}
+
+// Dependents returns the dirs of the root modules that call moduleDir,
+// directly or through other local modules. A dir that is itself called as a
+// module is not considered a root module.
+func (g *DependencyGraph) Dependents(moduleDir string) []string {
+	var roots []string
+	g.walk(g.Callers, moduleDir, func(dir string) {
+		if _, isModule := g.Callers[dir]; !isModule {
+			roots = append(roots, dir)
+		}
+	})
+	sort.Strings(roots)
+	return roots
+}
+
+// Dependencies returns the dirs of all local modules that dir calls, directly
+// or through other local modules.
+func (g *DependencyGraph) Dependencies(dir string) []string {
+	var deps []string
+	g.walk(g.Calls, path.Clean(dir), func(d string) {
+		deps = append(deps, d)
+	})
+	sort.Strings(deps)
+	return deps
+}
+
+// walk calls fn for each dir reachable from start by following edges. start
+// itself is not included.
+func (g *DependencyGraph) walk(edges map[string][]string, start string, fn func(dir string)) {
+	seen := map[string]bool{start: true}
+	queue := []string{start}
+	for len(queue) > 0 {
+		dir := queue[0]
+		queue = queue[1:]
+		for _, next := range edges[dir] {
+			if seen[next] {
+				continue
+			}
+			seen[next] = true
+			fn(next)
+			queue = append(queue, next)
+		}
+	}
+}
+
+func (g *DependencyGraph) add(caller string, called string) {
+	for _, c := range g.Calls[caller] {
+		if c == called {
+			return
+		}
+	}
+	g.Calls[caller] = append(g.Calls[caller], called)
+	g.Callers[called] = append(g.Callers[called], caller)
+}
+
+// isLocalSource returns true if source refers to a module on the local
+// filesystem. Terraform only treats sources starting with ./ or ../ as local.
+func isLocalSource(source string) bool {
+	return strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../")
+}
@@ -0,0 +1,101 @@
+package modules_test
+
+import (
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/core/modules"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestBuildDependencyGraph(t *testing.T) {
+	// Create dir structure:
+	// project1/
+	//   main.tf # calls ./local and ../modules/vpc
+	//   local/
+	//     main.tf
+	// project2/
+	//   main.tf # calls ../modules/network, a registry module and ../../outside
+	// modules/
+	//   network/
+	//     main.tf # calls ../vpc twice
+	//   vpc/
+	//     main.tf
+	//     templates/
+	//       user_data.sh
+	// .terraform/
+	//   modules/
+	//     main.tf # calls ../../modules/vpc, should be ignored
+	repoDir, cleanup := DirStructure(t, map[string]interface{}{
+		"project1": map[string]interface{}{
+			"main.tf": `
+module "local" { source = "./local" }
+module "vpc" { source = "../modules/vpc" }
+`,
+			"local": map[string]interface{}{
+				"main.tf": nil,
+			},
+		},
+		"project2": map[string]interface{}{
+			"main.tf": `
+module "network" { source = "../modules/network" }
+module "registry" { source = "terraform-aws-modules/vpc/aws" }
+module "outside" { source = "../../outside" }
+`,
+		},
+		"modules": map[string]interface{}{
+			"network": map[string]interface{}{
+				"main.tf": `
+module "vpc1" { source = "../vpc" }
+module "vpc2" { source = "../vpc/" }
+`,
+			},
+			"vpc": map[string]interface{}{
+				"main.tf": nil,
+				"templates": map[string]interface{}{
+					"user_data.sh": nil,
+				},
+			},
+		},
+		".terraform": map[string]interface{}{
+			"modules": map[string]interface{}{
+				"main.tf": `module "vpc" { source = "../../modules/vpc" }`,
+			},
+		},
+	})
+	defer cleanup()
+
+	graph, err := modules.BuildDependencyGraph(logging.NewNoopLogger(t), repoDir)
+	Ok(t, err)
+	Equals(t, map[string][]string{
+		"modules/network": {"modules/vpc"},
+		"project1":        {"project1/local", "modules/vpc"},
+		"project2":        {"modules/network"},
+	}, graph.Calls)
+	Equals(t, map[string][]string{
+		"modules/network": {"project2"},
+		"modules/vpc":     {"modules/network", "project1"},
+		"project1/local":  {"project1"},
+	}, graph.Callers)
+
+	t.Run("ModuleDir", func(t *testing.T) {
+		Equals(t, "modules/vpc", graph.ModuleDir("modules/vpc"))
+		Equals(t, "modules/vpc", graph.ModuleDir("modules/vpc/templates"))
+		Equals(t, "", graph.ModuleDir("modules"))
+		Equals(t, "", graph.ModuleDir("project2"))
+		Equals(t, "", graph.ModuleDir("."))
+	})
+
+	t.Run("Dependents", func(t *testing.T) {
+		Equals(t, []string{"project1", "project2"}, graph.Dependents("modules/vpc"))
+		Equals(t, []string{"project2"}, graph.Dependents("modules/network"))
+		Equals(t, []string{"project1"}, graph.Dependents("project1/local"))
+		Equals(t, []string(nil), graph.Dependents("project1"))
+	})
+
+	t.Run("Dependencies", func(t *testing.T) {
+		Equals(t, []string{"modules/vpc", "project1/local"}, graph.Dependencies("project1"))
+		Equals(t, []string{"modules/network", "modules/vpc"}, graph.Dependencies("project2"))
+		Equals(t, []string(nil), graph.Dependencies("modules/vpc"))
+	})
+}
@@ -0,0 +1,91 @@
+package planstorage
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"cloud.google.com/go/storage"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+// GCSPlanStorage stores planfiles in a Google Cloud Storage bucket.
+type GCSPlanStorage struct {
+	Client *storage.Client
+	Bucket string
+	// Prefix is prepended to all object names.
+	Prefix string
+}
+
+// NewGCSPlanStorage returns a GCSPlanStorage using Application Default
+// Credentials. opts are passed to the storage client.
+func NewGCSPlanStorage(bucket string, prefix string, opts ...option.ClientOption) (*GCSPlanStorage, error) {
+	client, err := storage.NewClient(context.Background(), opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating GCS client")
+	}
+	return &GCSPlanStorage{
+		Client: client,
+		Bucket: bucket,
+		Prefix: prefix,
+	}, nil
+}
+
+func (g *GCSPlanStorage) Upload(ctx models.ProjectCommandContext, planPath string) error {
+	f, err := os.Open(planPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close() // nolint: errcheck
+
+	key := planKey(g.Prefix, ctx, planPath)
+	w := g.Client.Bucket(g.Bucket).Object(key).NewWriter(context.Background())
+	if _, err := io.Copy(w, f); err != nil { // nolint: vetshadow
+		w.Close() // nolint: errcheck
+		return errors.Wrapf(err, "uploading planfile to gs://%s/%s", g.Bucket, key)
+	}
+	return errors.Wrapf(w.Close(), "uploading planfile to gs://%s/%s", g.Bucket, key)
+}
+
+func (g *GCSPlanStorage) Download(ctx models.ProjectCommandContext, planPath string) error {
+	key := planKey(g.Prefix, ctx, planPath)
+	r, err := g.Client.Bucket(g.Bucket).Object(key).NewReader(context.Background())
+	if err == storage.ErrObjectNotExist {
+		return ErrNotFound
+	}
+	if err != nil {
+		return errors.Wrapf(err, "downloading planfile from gs://%s/%s", g.Bucket, key)
+	}
+	defer r.Close() // nolint: errcheck
+	return writePlanfile(planPath, r, r.Attrs.LastModified)
+}
+
+func (g *GCSPlanStorage) Delete(ctx models.ProjectCommandContext, planPath string) error {
+	key := planKey(g.Prefix, ctx, planPath)
+	err := g.Client.Bucket(g.Bucket).Object(key).Delete(context.Background())
+	if err == storage.ErrObjectNotExist {
+		return nil
+	}
+	return errors.Wrapf(err, "deleting planfile gs://%s/%s", g.Bucket, key)
+}
+
+func (g *GCSPlanStorage) DeletePlans(repoFullName string, pullNum int, workspace string) error {
+	prefix := pullPrefix(g.Prefix, repoFullName, pullNum, workspace)
+	bucket := g.Client.Bucket(g.Bucket)
+	it := bucket.Objects(context.Background(), &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrapf(err, "listing planfiles in gs://%s/%s", g.Bucket, prefix)
+		}
+		if err := bucket.Object(attrs.Name).Delete(context.Background()); err != nil && err != storage.ErrObjectNotExist { // nolint: vetshadow
+			return errors.Wrapf(err, "deleting planfile gs://%s/%s", g.Bucket, attrs.Name)
+		}
+	}
+}
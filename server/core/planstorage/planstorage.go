@@ -0,0 +1,107 @@
+// Package planstorage stores planfiles outside of the Atlantis data dir so they
+// survive restarts and can be shared between Atlantis instances.
+package planstorage
+
+import (
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// ErrNotFound is returned by Download when there is no stored planfile.
+var ErrNotFound = errors.New("planfile not found in plan storage")
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_plan_storage.go PlanStorage
+
+// PlanStorage stores planfiles generated by `atlantis plan` so that they can
+// be retrieved by `atlantis apply`.
+type PlanStorage interface {
+	// Upload stores the planfile at planPath for the project in ctx.
+	Upload(ctx models.ProjectCommandContext, planPath string) error
+	// Download writes the stored planfile for the project in ctx to planPath.
+	// The file's modification time is set to when the planfile was stored so
+	// the plan's age can still be checked. It returns ErrNotFound if there is
+	// no stored planfile.
+	Download(ctx models.ProjectCommandContext, planPath string) error
+	// Delete deletes the stored planfile for the project in ctx.
+	Delete(ctx models.ProjectCommandContext, planPath string) error
+	// DeletePlans deletes all stored planfiles for the pull request. If
+	// workspace is not empty, only planfiles for that workspace are deleted.
+	DeletePlans(repoFullName string, pullNum int, workspace string) error
+}
+
+// LocalPlanStorage keeps planfiles only in the data dir where they are
+// generated. This is the default.
+type LocalPlanStorage struct{}
+
+func (l *LocalPlanStorage) Upload(ctx models.ProjectCommandContext, planPath string) error {
+	return nil
+}
+
+func (l *LocalPlanStorage) Download(ctx models.ProjectCommandContext, planPath string) error {
+	return ErrNotFound
+}
+
+func (l *LocalPlanStorage) Delete(ctx models.ProjectCommandContext, planPath string) error {
+	return nil
+}
+
+func (l *LocalPlanStorage) DeletePlans(repoFullName string, pullNum int, workspace string) error {
+	return nil
+}
+
+// planKey returns the object key for a planfile. The head commit is part of
+// the key so a plan is never used for a different commit than it was
+// generated for.
+func planKey(prefix string, ctx models.ProjectCommandContext, planPath string) string {
+	return path.Join(pullPrefix(prefix, ctx.Pull.BaseRepo.FullName, ctx.Pull.Num, ctx.Workspace), ctx.Pull.HeadCommit, ctx.RepoRelDir, filepath.Base(planPath))
+}
+
+// pullPrefix returns the key prefix, ending in a slash, that all planfiles for
+// the pull request and optionally workspace are stored under.
+func pullPrefix(prefix string, repoFullName string, pullNum int, workspace string) string {
+	return path.Join(prefix, repoFullName, strconv.Itoa(pullNum), workspace) + "/"
+}
+
+// writePlanfile writes the contents of r to planPath.
+func writePlanfile(planPath string, r io.Reader, storedAt time.Time) error {
+	f, err := os.OpenFile(planPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil { // nolint: vetshadow
+		f.Close() // nolint: errcheck
+		return errors.Wrap(err, "writing planfile")
+	}
+	if err := f.Close(); err != nil { // nolint: vetshadow
+		return err
+	}
+	if storedAt.IsZero() {
+		return nil
+	}
+	return errors.Wrap(os.Chtimes(planPath, storedAt, storedAt), "setting planfile modification time")
+}
+
+// DownloadIfMissing downloads the planfile at planPath from storage if it
+// doesn't exist locally, ex. because Atlantis was restarted since the plan.
+// It's not an error if there's no stored planfile either.
+func DownloadIfMissing(storage PlanStorage, ctx models.ProjectCommandContext, planPath string) error {
+	if storage == nil {
+		return nil
+	}
+	if _, err := os.Stat(planPath); !os.IsNotExist(err) {
+		return nil
+	}
+	ctx.Log.Info("planfile not found locally, downloading it from plan storage")
+	err := storage.Download(ctx, planPath)
+	if err == ErrNotFound {
+		return nil
+	}
+	return errors.Wrap(err, "downloading planfile")
+}
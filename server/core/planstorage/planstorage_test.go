@@ -0,0 +1,30 @@
+package planstorage_test
+
+import (
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/core/planstorage"
+	"github.com/runatlantis/atlantis/server/events/models"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func projectCtx(workspace string, dir string) models.ProjectCommandContext {
+	return models.ProjectCommandContext{
+		Pull: models.PullRequest{
+			Num:        1,
+			HeadCommit: "abc123",
+			BaseRepo:   models.Repo{FullName: "owner/repo"},
+		},
+		Workspace:  workspace,
+		RepoRelDir: dir,
+	}
+}
+
+func TestLocalPlanStorage(t *testing.T) {
+	storage := &planstorage.LocalPlanStorage{}
+	ctx := projectCtx("default", ".")
+	Ok(t, storage.Upload(ctx, "/path/default.tfplan"))
+	Equals(t, planstorage.ErrNotFound, storage.Download(ctx, "/path/default.tfplan"))
+	Ok(t, storage.Delete(ctx, "/path/default.tfplan"))
+	Ok(t, storage.DeletePlans("owner/repo", 1, ""))
+}
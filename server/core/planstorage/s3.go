@@ -0,0 +1,117 @@
+package planstorage
+
+import (
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// S3PlanStorage stores planfiles in an S3 bucket.
+type S3PlanStorage struct {
+	Client s3iface.S3API
+	Bucket string
+	// Prefix is prepended to all object keys.
+	Prefix string
+}
+
+// NewS3PlanStorage returns an S3PlanStorage using the default AWS credential
+// chain and region configuration.
+func NewS3PlanStorage(bucket string, prefix string) (*S3PlanStorage, error) {
+	sess, err := session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "creating AWS session")
+	}
+	return &S3PlanStorage{
+		Client: s3.New(sess),
+		Bucket: bucket,
+		Prefix: prefix,
+	}, nil
+}
+
+func (s *S3PlanStorage) Upload(ctx models.ProjectCommandContext, planPath string) error {
+	f, err := os.Open(planPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close() // nolint: errcheck
+
+	key := planKey(s.Prefix, ctx, planPath)
+	_, err = s.Client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+		Body:   f,
+	})
+	return errors.Wrapf(err, "uploading planfile to s3://%s/%s", s.Bucket, key)
+}
+
+func (s *S3PlanStorage) Download(ctx models.ProjectCommandContext, planPath string) error {
+	key := planKey(s.Prefix, ctx, planPath)
+	out, err := s.Client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+		return ErrNotFound
+	}
+	if err != nil {
+		return errors.Wrapf(err, "downloading planfile from s3://%s/%s", s.Bucket, key)
+	}
+	defer out.Body.Close() // nolint: errcheck
+	var storedAt time.Time
+	if out.LastModified != nil {
+		storedAt = *out.LastModified
+	}
+	return writePlanfile(planPath, out.Body, storedAt)
+}
+
+func (s *S3PlanStorage) Delete(ctx models.ProjectCommandContext, planPath string) error {
+	key := planKey(s.Prefix, ctx, planPath)
+	_, err := s.Client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+	return errors.Wrapf(err, "deleting planfile s3://%s/%s", s.Bucket, key)
+}
+
+func (s *S3PlanStorage) DeletePlans(repoFullName string, pullNum int, workspace string) error {
+	prefix := pullPrefix(s.Prefix, repoFullName, pullNum, workspace)
+	var objects []*s3.ObjectIdentifier
+	err := s.Client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(s.Bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, obj := range page.Contents {
+			objects = append(objects, &s3.ObjectIdentifier{Key: obj.Key})
+		}
+		return true
+	})
+	if err != nil {
+		return errors.Wrapf(err, "listing planfiles in s3://%s/%s", s.Bucket, prefix)
+	}
+
+	// DeleteObjects accepts at most 1000 keys per request.
+	for len(objects) > 0 {
+		n := len(objects)
+		if n > 1000 {
+			n = 1000
+		}
+		_, err = s.Client.DeleteObjects(&s3.DeleteObjectsInput{
+			Bucket: aws.String(s.Bucket),
+			Delete: &s3.Delete{Objects: objects[:n], Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return errors.Wrapf(err, "deleting planfiles in s3://%s/%s", s.Bucket, prefix)
+		}
+		objects = objects[n:]
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package planstorage_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/runatlantis/atlantis/server/core/planstorage"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// fakeS3 is an in-memory implementation of the parts of the S3 API used by
+// S3PlanStorage.
+type fakeS3 struct {
+	s3iface.S3API
+	objects map[string][]byte
+}
+
+func (f *fakeS3) PutObject(in *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	body, err := ioutil.ReadAll(in.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.objects[*in.Bucket+"/"+*in.Key] = body
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (f *fakeS3) GetObject(in *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	body, ok := f.objects[*in.Bucket+"/"+*in.Key]
+	if !ok {
+		return nil, awserr.New(s3.ErrCodeNoSuchKey, "not found", nil)
+	}
+	return &s3.GetObjectOutput{Body: ioutil.NopCloser(bytes.NewReader(body))}, nil
+}
+
+func (f *fakeS3) DeleteObject(in *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error) {
+	delete(f.objects, *in.Bucket+"/"+*in.Key)
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func (f *fakeS3) ListObjectsV2Pages(in *s3.ListObjectsV2Input, fn func(*s3.ListObjectsV2Output, bool) bool) error {
+	page := &s3.ListObjectsV2Output{}
+	for k := range f.objects {
+		key := strings.TrimPrefix(k, *in.Bucket+"/")
+		if strings.HasPrefix(key, *in.Prefix) {
+			page.Contents = append(page.Contents, &s3.Object{Key: aws.String(key)})
+		}
+	}
+	fn(page, true)
+	return nil
+}
+
+func (f *fakeS3) DeleteObjects(in *s3.DeleteObjectsInput) (*s3.DeleteObjectsOutput, error) {
+	for _, obj := range in.Delete.Objects {
+		delete(f.objects, *in.Bucket+"/"+*obj.Key)
+	}
+	return &s3.DeleteObjectsOutput{}, nil
+}
+
+func (f *fakeS3) keys() []string {
+	var keys []string
+	for k := range f.objects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestS3PlanStorage_UploadDownload(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	client := &fakeS3{objects: map[string][]byte{}}
+	storage := &planstorage.S3PlanStorage{Client: client, Bucket: "bucket", Prefix: "plans"}
+	ctx := projectCtx("default", "dir")
+	planPath := filepath.Join(tmp, "default.tfplan")
+
+	err := storage.Download(ctx, planPath)
+	Equals(t, planstorage.ErrNotFound, err)
+
+	Ok(t, ioutil.WriteFile(planPath, []byte("plan"), 0600))
+	Ok(t, storage.Upload(ctx, planPath))
+	Equals(t, []string{"bucket/plans/owner/repo/1/default/abc123/dir/default.tfplan"}, client.keys())
+
+	Ok(t, ioutil.WriteFile(planPath, []byte("overwritten"), 0600))
+	Ok(t, storage.Download(ctx, planPath))
+	contents, err := ioutil.ReadFile(planPath)
+	Ok(t, err)
+	Equals(t, "plan", string(contents))
+
+	// Plans from other commits aren't used.
+	otherCommit := ctx
+	otherCommit.Pull.HeadCommit = "def456"
+	Equals(t, planstorage.ErrNotFound, storage.Download(otherCommit, planPath))
+
+	Ok(t, storage.Delete(ctx, planPath))
+	Equals(t, 0, len(client.keys()))
+}
+
+func TestS3PlanStorage_DeletePlans(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	client := &fakeS3{objects: map[string][]byte{}}
+	storage := &planstorage.S3PlanStorage{Client: client, Bucket: "bucket"}
+	planPath := filepath.Join(tmp, "default.tfplan")
+	Ok(t, ioutil.WriteFile(planPath, []byte("plan"), 0600))
+
+	Ok(t, storage.Upload(projectCtx("default", "."), planPath))
+	Ok(t, storage.Upload(projectCtx("default", "dir"), planPath))
+	Ok(t, storage.Upload(projectCtx("staging", "."), planPath))
+	pull10 := projectCtx("default", ".")
+	pull10.Pull.Num = 10
+	Ok(t, storage.Upload(pull10, planPath))
+
+	Ok(t, storage.DeletePlans("owner/repo", 1, "default"))
+	Equals(t, []string{
+		"bucket/owner/repo/1/staging/abc123/default.tfplan",
+		"bucket/owner/repo/10/default/abc123/default.tfplan",
+	}, client.keys())
+
+	Ok(t, storage.DeletePlans("owner/repo", 1, ""))
+	Equals(t, []string{"bucket/owner/repo/10/default/abc123/default.tfplan"}, client.keys())
+}
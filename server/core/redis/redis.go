@@ -0,0 +1,726 @@
+// Package redis handles our remote database layer.
+package redis
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// RedisDB is a database using Redis. Unlike BoltDB it can be shared by
+// multiple Atlantis instances.
+type RedisDB struct { // nolint: golint
+	client *redis.Client
+}
+
+const (
+	pullKeySeparator = "::"
+	// auditKey is the key of the list that holds the audit log.
+	auditKey = "audit"
+	// queueKey is the key of the hash that holds the command queue.
+	queueKey = "queue"
+	// reposKey is the key of the hash that holds the registered repos.
+	reposKey = "registeredRepos"
+	// maxTxRetries is how many times we retry an optimistic transaction
+	// that failed because a watched key was modified concurrently.
+	maxTxRetries = 10
+	// deliveryTTL is how long we remember webhook deliveries for. VCS hosts
+	// only retry failed deliveries within a few hours so this is plenty.
+	deliveryTTL = 72 * time.Hour
+)
+
+var ctx = context.Background()
+
+// New returns a new RedisDB connected to the Redis instance at hostname:port.
+// It returns an error if the instance can't be reached.
+func New(hostname string, port int, password string, tlsEnabled bool, insecureSkipVerify bool, db int) (*RedisDB, error) {
+	var tlsConfig *tls.Config
+	if tlsEnabled {
+		tlsConfig = &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: insecureSkipVerify, // nolint: gosec
+		}
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:      fmt.Sprintf("%s:%d", hostname, port),
+		Password:  password,
+		DB:        db,
+		TLSConfig: tlsConfig,
+	})
+
+	// Check that the connection is valid.
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to connect to redis instance")
+	}
+
+	return &RedisDB{
+		client: client,
+	}, nil
+}
+
+// TryLock attempts to create a new lock. If the lock is
+// acquired, it will return true and the lock returned will be newLock.
+// If the lock is not acquired, it will return false and the current
+// lock that is preventing this lock from being acquired.
+func (r *RedisDB) TryLock(newLock models.ProjectLock) (bool, models.ProjectLock, error) {
+	var currLock models.ProjectLock
+	key := r.lockKey(newLock.LockTarget())
+	newLockSerialized, _ := json.Marshal(newLock)
+
+	// SETNX is atomic so two Atlantis instances can't both acquire the lock.
+	acquired, err := r.client.SetNX(ctx, key, newLockSerialized, 0).Result()
+	if err != nil {
+		return false, currLock, errors.Wrap(err, "db transaction failed")
+	}
+	if acquired {
+		return true, newLock, nil
+	}
+
+	// Otherwise the lock fails, return to caller the run that's holding the lock.
+	currLockSerialized, err := r.client.Get(ctx, key).Result()
+	if err == redis.Nil {
+		// The lock was deleted in between our two calls so try again.
+		return r.TryLock(newLock)
+	}
+	if err != nil {
+		return false, currLock, errors.Wrap(err, "db transaction failed")
+	}
+	if err := json.Unmarshal([]byte(currLockSerialized), &currLock); err != nil {
+		return false, currLock, errors.Wrap(err, "failed to deserialize current lock")
+	}
+	return false, currLock, nil
+}
+
+// Unlock attempts to unlock the project and workspace.
+// If there is no lock, then it will return a nil pointer.
+// If there is a lock, then it will delete it, and then return a pointer
+// to the deleted lock.
+func (r *RedisDB) Unlock(p models.Project, workspace string) (*models.ProjectLock, error) {
+	key := r.lockKey(p, workspace)
+	// GET and DEL in a MULTI so we return exactly the lock we deleted.
+	var getCmd *redis.StringCmd
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		getCmd = pipe.Get(ctx, key)
+		pipe.Del(ctx, key)
+		return nil
+	})
+	serialized, getErr := getCmd.Result()
+	if getErr == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "db transaction failed")
+	}
+
+	var lock models.ProjectLock
+	if err := json.Unmarshal([]byte(serialized), &lock); err != nil {
+		return nil, errors.Wrap(err, "failed to deserialize lock")
+	}
+	return &lock, nil
+}
+
+// List lists all current locks.
+func (r *RedisDB) List() ([]models.ProjectLock, error) {
+	var locks []models.ProjectLock
+	iter := r.client.Scan(ctx, 0, fmt.Sprintf("%s*", r.lockKeyPrefix()), 0).Iterator()
+	for iter.Next(ctx) {
+		serialized, err := r.client.Get(ctx, iter.Val()).Result()
+		if err == redis.Nil {
+			// The lock was deleted since we scanned.
+			continue
+		}
+		if err != nil {
+			return locks, errors.Wrap(err, "db transaction failed")
+		}
+
+		var lock models.ProjectLock
+		if err := json.Unmarshal([]byte(serialized), &lock); err != nil {
+			return locks, errors.Wrapf(err, "failed to deserialize lock at key %q", iter.Val())
+		}
+		// need to set it to Local after deserialization due to https://github.com/golang/go/issues/19486
+		lock.Time = lock.Time.Local()
+		locks = append(locks, lock)
+	}
+	if err := iter.Err(); err != nil {
+		return locks, errors.Wrap(err, "db transaction failed")
+	}
+
+	return locks, nil
+}
+
+// LockCommand attempts to create a new lock for a CommandName.
+// If the lock doesn't exists, it will create a lock and return a pointer to it.
+// If the lock already exists, it will return an "lock already exists" error
+func (r *RedisDB) LockCommand(cmdName models.CommandName, lockTime time.Time) (*models.CommandLock, error) {
+	lock := models.CommandLock{
+		CommandName: cmdName,
+		LockMetadata: models.LockMetadata{
+			UnixTime: lockTime.Unix(),
+		},
+	}
+
+	newLockSerialized, _ := json.Marshal(lock)
+	acquired, err := r.client.SetNX(ctx, r.commandLockKey(cmdName), newLockSerialized, 0).Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "db transaction failed")
+	}
+	if !acquired {
+		return nil, errors.New("db transaction failed: lock already exists")
+	}
+
+	return &lock, nil
+}
+
+// UnlockCommand removes CommandName lock if present.
+// If there are no lock it returns an error.
+func (r *RedisDB) UnlockCommand(cmdName models.CommandName) error {
+	deleted, err := r.client.Del(ctx, r.commandLockKey(cmdName)).Result()
+	if err != nil {
+		return errors.Wrap(err, "db transaction failed")
+	}
+	if deleted == 0 {
+		return errors.New("db transaction failed: no lock exists")
+	}
+	return nil
+}
+
+// CheckCommandLock checks if CommandName lock was set.
+// If the lock exists return the pointer to the lock object, otherwise return nil
+func (r *RedisDB) CheckCommandLock(cmdName models.CommandName) (*models.CommandLock, error) {
+	serialized, err := r.client.Get(ctx, r.commandLockKey(cmdName)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "db transaction failed")
+	}
+
+	cmdLock := models.CommandLock{}
+	if err := json.Unmarshal([]byte(serialized), &cmdLock); err != nil {
+		return nil, errors.Wrap(err, "failed to deserialize UserConfig")
+	}
+	return &cmdLock, nil
+}
+
+// ClaimDelivery records that the webhook delivery with id is being handled.
+// It returns false if the delivery was already claimed, ex. by another
+// Atlantis instance sharing this database.
+func (r *RedisDB) ClaimDelivery(id string) (bool, error) {
+	claimed, err := r.client.SetNX(ctx, r.deliveryKey(id), time.Now().Unix(), deliveryTTL).Result()
+	if err != nil {
+		return false, errors.Wrap(err, "db transaction failed")
+	}
+	return claimed, nil
+}
+
+// ReleaseDelivery deletes the claim on the webhook delivery with id.
+func (r *RedisDB) ReleaseDelivery(id string) error {
+	if err := r.client.Del(ctx, r.deliveryKey(id)).Err(); err != nil {
+		return errors.Wrap(err, "db transaction failed")
+	}
+	return nil
+}
+
+// UnlockByPull deletes all locks associated with that pull request and returns them.
+func (r *RedisDB) UnlockByPull(repoFullName string, pullNum int) ([]models.ProjectLock, error) {
+	var locks []models.ProjectLock
+
+	// We can use the repoFullName as a prefix search since that's the first
+	// part of the key after the lock prefix.
+	iter := r.client.Scan(ctx, 0, fmt.Sprintf("%s%s/*", r.lockKeyPrefix(), escapeGlob(repoFullName)), 0).Iterator()
+	for iter.Next(ctx) {
+		serialized, err := r.client.Get(ctx, iter.Val()).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return locks, errors.Wrap(err, "db transaction failed")
+		}
+
+		var lock models.ProjectLock
+		if err := json.Unmarshal([]byte(serialized), &lock); err != nil {
+			return locks, errors.Wrapf(err, "deserializing lock at key %q", iter.Val())
+		}
+		if lock.Pull.Num == pullNum {
+			locks = append(locks, lock)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return locks, errors.Wrap(err, "db transaction failed")
+	}
+
+	// delete the locks
+	for _, lock := range locks {
+		if _, err := r.Unlock(lock.Project, lock.Workspace); err != nil {
+			return locks, errors.Wrapf(err, "unlocking repo %s, path %s, workspace %s", lock.Project.RepoFullName, lock.Project.Path, lock.Workspace)
+		}
+	}
+	return locks, nil
+}
+
+// GetLock returns a pointer to the lock for that project and workspace.
+// If there is no lock, it returns a nil pointer.
+func (r *RedisDB) GetLock(p models.Project, workspace string) (*models.ProjectLock, error) {
+	key := r.lockKey(p, workspace)
+	serialized, err := r.client.Get(ctx, key).Result()
+	// serialized will be empty if there was no data at that key
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "getting lock data")
+	}
+
+	var lock models.ProjectLock
+	if err := json.Unmarshal([]byte(serialized), &lock); err != nil {
+		return nil, errors.Wrapf(err, "deserializing lock at key %q", key)
+	}
+
+	// need to set it to Local after deserialization due to https://github.com/golang/go/issues/19486
+	lock.Time = lock.Time.Local()
+	return &lock, nil
+}
+
+// UpdatePullWithResults updates pull's status with the latest project results.
+// It returns the new PullStatus object.
+func (r *RedisDB) UpdatePullWithResults(pull models.PullRequest, newResults []models.ProjectResult) (models.PullStatus, error) {
+	key, err := r.pullKey(pull)
+	if err != nil {
+		return models.PullStatus{}, err
+	}
+
+	var newStatus models.PullStatus
+	err = r.update(key, func(tx *redis.Tx) error {
+		currStatus, err := r.getPull(tx, key)
+		if err != nil {
+			return err
+		}
+
+		// If there is no pull OR if the pull we have is out of date, we
+		// just write a new pull.
+		if currStatus == nil || currStatus.Pull.HeadCommit != pull.HeadCommit {
+			var statuses []models.ProjectStatus
+			for _, res := range newResults {
+				statuses = append(statuses, r.projectResultToProject(res))
+			}
+			newStatus = models.PullStatus{
+				Pull:     pull,
+				Projects: statuses,
+			}
+		} else {
+			// If there's an existing pull at the right commit then we have to
+			// merge our project results with the existing ones. We do a merge
+			// because it's possible a user is just applying a single project
+			// in this command and so we don't want to delete our data about
+			// other projects that aren't affected by this command.
+			newStatus = *currStatus
+			for _, res := range newResults {
+				// First, check if we should update any existing projects.
+				updatedExisting := false
+				for i := range newStatus.Projects {
+					// NOTE: We're using a reference here because we are
+					// in-place updating its Status field.
+					proj := &newStatus.Projects[i]
+					if res.Workspace == proj.Workspace &&
+						res.RepoRelDir == proj.RepoRelDir &&
+						res.ProjectName == proj.ProjectName {
+
+						proj.Status = res.PlanStatus()
+						if res.Command == models.PlanCommand {
+							proj.Targets = res.Targets
+						}
+						updatedExisting = true
+						break
+					}
+				}
+
+				if !updatedExisting {
+					// If we didn't update an existing project, then we need to
+					// add this because it's a new one.
+					newStatus.Projects = append(newStatus.Projects, r.projectResultToProject(res))
+				}
+			}
+		}
+
+		// Now, we overwrite the key with our new status.
+		return r.writePull(tx, key, newStatus)
+	})
+	return newStatus, errors.Wrap(err, "DB transaction failed")
+}
+
+// GetPullStatus returns the status for pull.
+// If there is no status, returns a nil pointer.
+func (r *RedisDB) GetPullStatus(pull models.PullRequest) (*models.PullStatus, error) {
+	key, err := r.pullKey(pull)
+	if err != nil {
+		return nil, err
+	}
+	s, err := r.getPull(r.client, key)
+	return s, errors.Wrap(err, "DB transaction failed")
+}
+
+// ListPullStatuses returns the status of every pull request Atlantis has
+// run commands on that hasn't been closed, sorted by repo and pull number.
+func (r *RedisDB) ListPullStatuses() ([]models.PullStatus, error) {
+	var statuses []models.PullStatus
+	// Pull keys are hostname::repo::num. History keys also contain the
+	// separator so we skip them.
+	iter := r.client.Scan(ctx, 0, fmt.Sprintf("*%s*%s*", pullKeySeparator, pullKeySeparator), 0).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		if strings.HasPrefix(key, r.historyKeyPrefix()) || len(strings.Split(key, pullKeySeparator)) != 3 {
+			continue
+		}
+		s, err := r.getPull(r.client, key)
+		if err != nil {
+			return nil, errors.Wrap(err, "DB transaction failed")
+		}
+		// The pull may have been deleted since we scanned it.
+		if s != nil {
+			statuses = append(statuses, *s)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, errors.Wrap(err, "DB transaction failed")
+	}
+	models.SortPullStatuses(statuses)
+	return statuses, nil
+}
+
+// DeletePullStatus deletes the status for pull.
+func (r *RedisDB) DeletePullStatus(pull models.PullRequest) error {
+	key, err := r.pullKey(pull)
+	if err != nil {
+		return err
+	}
+	err = r.client.Del(ctx, key).Err()
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// UpdateProjectStatus updates project status.
+func (r *RedisDB) UpdateProjectStatus(pull models.PullRequest, workspace string, repoRelDir string, newStatus models.ProjectPlanStatus) error {
+	key, err := r.pullKey(pull)
+	if err != nil {
+		return err
+	}
+	err = r.update(key, func(tx *redis.Tx) error {
+		currStatusPtr, err := r.getPull(tx, key)
+		if err != nil {
+			return err
+		}
+		if currStatusPtr == nil {
+			return nil
+		}
+		currStatus := *currStatusPtr
+
+		// Update the status.
+		for i := range currStatus.Projects {
+			// NOTE: We're using a reference here because we are
+			// in-place updating its Status field.
+			proj := &currStatus.Projects[i]
+			if proj.Workspace == workspace && proj.RepoRelDir == repoRelDir {
+				proj.Status = newStatus
+				break
+			}
+		}
+		return r.writePull(tx, key, currStatus)
+	})
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// AddProjectHistory saves the output of a command run on a project. There's
+// only one entry per project, command and commit so re-running a command
+// on the same commit overwrites the previous output.
+func (r *RedisDB) AddProjectHistory(history models.ProjectHistory) error {
+	key, err := r.historyKey(history)
+	if err != nil {
+		return err
+	}
+	serialized, err := json.Marshal(history)
+	if err != nil {
+		return errors.Wrap(err, "serializing")
+	}
+	err = r.client.Set(ctx, key, serialized, 0).Err()
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// GetProjectHistory returns the saved command outputs for pull request
+// pullNum in repoFullName, oldest first. If pullNum is 0, the history for
+// every pull request in the repo is returned and if repoFullName is also
+// empty, the history for every repo is returned.
+func (r *RedisDB) GetProjectHistory(repoFullName string, pullNum int) ([]models.ProjectHistory, error) {
+	var history []models.ProjectHistory
+	iter := r.client.Scan(ctx, 0, r.historyKeyPattern(repoFullName, pullNum), 0).Iterator()
+	for iter.Next(ctx) {
+		serialized, err := r.client.Get(ctx, iter.Val()).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return history, errors.Wrap(err, "DB transaction failed")
+		}
+
+		var h models.ProjectHistory
+		if err := json.Unmarshal([]byte(serialized), &h); err != nil {
+			return history, errors.Wrapf(err, "deserializing history at key %q", iter.Val())
+		}
+		// need to set it to Local after deserialization due to https://github.com/golang/go/issues/19486
+		h.Time = h.Time.Local()
+		history = append(history, h)
+	}
+	if err := iter.Err(); err != nil {
+		return history, errors.Wrap(err, "DB transaction failed")
+	}
+
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Time.Before(history[j].Time)
+	})
+	return history, nil
+}
+
+// AddAuditEvent appends event to the audit log.
+func (r *RedisDB) AddAuditEvent(event models.AuditEvent) error {
+	serialized, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "serializing")
+	}
+	err = r.client.RPush(ctx, auditKey, serialized).Err()
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// ListAuditEvents returns every event in the audit log in the order they
+// were added.
+func (r *RedisDB) ListAuditEvents() ([]models.AuditEvent, error) {
+	serialized, err := r.client.LRange(ctx, auditKey, 0, -1).Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "DB transaction failed")
+	}
+	var events []models.AuditEvent
+	for i, s := range serialized {
+		var event models.AuditEvent
+		if err := json.Unmarshal([]byte(s), &event); err != nil {
+			return events, errors.Wrapf(err, "deserializing audit event at index %d", i)
+		}
+		// need to set it to Local after deserialization due to https://github.com/golang/go/issues/19486
+		event.Time = event.Time.Local()
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// AddQueuedCommand saves cmd to the command queue, overwriting any command
+// with the same ID.
+func (r *RedisDB) AddQueuedCommand(cmd models.QueuedCommand) error {
+	serialized, err := json.Marshal(cmd)
+	if err != nil {
+		return errors.Wrap(err, "serializing")
+	}
+	err = r.client.HSet(ctx, queueKey, cmd.ID, serialized).Err()
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// DeleteQueuedCommand removes the command with id from the command queue.
+// It's not an error if it doesn't exist.
+func (r *RedisDB) DeleteQueuedCommand(id string) error {
+	err := r.client.HDel(ctx, queueKey, id).Err()
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// ListQueuedCommands returns the commands in the command queue, oldest
+// first.
+func (r *RedisDB) ListQueuedCommands() ([]models.QueuedCommand, error) {
+	serialized, err := r.client.HGetAll(ctx, queueKey).Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "DB transaction failed")
+	}
+	var cmds []models.QueuedCommand
+	for id, s := range serialized {
+		var cmd models.QueuedCommand
+		if err := json.Unmarshal([]byte(s), &cmd); err != nil {
+			return nil, errors.Wrapf(err, "deserializing queued command %q", id)
+		}
+		cmds = append(cmds, cmd)
+	}
+	sort.SliceStable(cmds, func(i, j int) bool {
+		return cmds[i].Time.Before(cmds[j].Time)
+	})
+	return cmds, nil
+}
+
+// AddRegisteredRepo saves repo, overwriting any repo with the same ID.
+func (r *RedisDB) AddRegisteredRepo(repo models.RegisteredRepo) error {
+	serialized, err := json.Marshal(repo)
+	if err != nil {
+		return errors.Wrap(err, "serializing")
+	}
+	err = r.client.HSet(ctx, reposKey, repo.ID(), serialized).Err()
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// AddRegisteredRepoIfAbsent saves repo unless a repo with the same ID is
+// already saved, in which case it returns false.
+func (r *RedisDB) AddRegisteredRepoIfAbsent(repo models.RegisteredRepo) (bool, error) {
+	serialized, err := json.Marshal(repo)
+	if err != nil {
+		return false, errors.Wrap(err, "serializing")
+	}
+	added, err := r.client.HSetNX(ctx, reposKey, repo.ID(), serialized).Result()
+	return added, errors.Wrap(err, "DB transaction failed")
+}
+
+// DeleteRegisteredRepo removes the registered repo with id. It's not an error
+// if it doesn't exist.
+func (r *RedisDB) DeleteRegisteredRepo(id string) error {
+	err := r.client.HDel(ctx, reposKey, id).Err()
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// ListRegisteredRepos returns the registered repos ordered by ID.
+func (r *RedisDB) ListRegisteredRepos() ([]models.RegisteredRepo, error) {
+	serialized, err := r.client.HGetAll(ctx, reposKey).Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "DB transaction failed")
+	}
+	var repos []models.RegisteredRepo
+	for id, s := range serialized {
+		var repo models.RegisteredRepo
+		if err := json.Unmarshal([]byte(s), &repo); err != nil {
+			return nil, errors.Wrapf(err, "deserializing registered repo %q", id)
+		}
+		repos = append(repos, repo)
+	}
+	sort.Slice(repos, func(i, j int) bool {
+		return repos[i].ID() < repos[j].ID()
+	})
+	return repos, nil
+}
+
+// update runs fn in an optimistic transaction that watches key. If key is
+// modified by another client before fn writes to it, fn is retried.
+func (r *RedisDB) update(key string, fn func(tx *redis.Tx) error) error {
+	for i := 0; i < maxTxRetries; i++ {
+		err := r.client.Watch(ctx, fn, key)
+		if err != redis.TxFailedErr {
+			return err
+		}
+	}
+	return fmt.Errorf("key %q was modified concurrently %d times in a row", key, maxTxRetries)
+}
+
+func (r *RedisDB) pullKey(pull models.PullRequest) (string, error) {
+	hostname := pull.BaseRepo.VCSHost.Hostname
+	if strings.Contains(hostname, pullKeySeparator) {
+		return "", fmt.Errorf("vcs hostname %q contains illegal string %q", hostname, pullKeySeparator)
+	}
+	repo := pull.BaseRepo.FullName
+	if strings.Contains(repo, pullKeySeparator) {
+		return "", fmt.Errorf("repo name %q contains illegal string %q", hostname, pullKeySeparator)
+	}
+
+	return fmt.Sprintf("%s::%s::%d", hostname, repo, pull.Num), nil
+}
+
+func (r *RedisDB) commandLockKey(cmdName models.CommandName) string {
+	return fmt.Sprintf("global/%s/lock", cmdName)
+}
+
+func (r *RedisDB) deliveryKey(id string) string {
+	return fmt.Sprintf("delivery/%s", id)
+}
+
+func (r *RedisDB) lockKeyPrefix() string {
+	return "pr/"
+}
+
+func (r *RedisDB) lockKey(p models.Project, workspace string) string {
+	return fmt.Sprintf("%s%s/%s/%s", r.lockKeyPrefix(), p.RepoFullName, p.Path, workspace)
+}
+
+func (r *RedisDB) historyKeyPrefix() string {
+	return "history/"
+}
+
+func (r *RedisDB) historyKeyPattern(repoFullName string, pullNum int) string {
+	if repoFullName == "" {
+		return fmt.Sprintf("%s*", r.historyKeyPrefix())
+	}
+	if pullNum == 0 {
+		return fmt.Sprintf("%s%s%s*", r.historyKeyPrefix(), escapeGlob(repoFullName), pullKeySeparator)
+	}
+	return fmt.Sprintf("%s%s%s%d%s*", r.historyKeyPrefix(), escapeGlob(repoFullName), pullKeySeparator, pullNum, pullKeySeparator)
+}
+
+func (r *RedisDB) historyKey(h models.ProjectHistory) (string, error) {
+	repo := h.Pull.BaseRepo.FullName
+	if strings.Contains(repo, pullKeySeparator) {
+		return "", fmt.Errorf("repo name %q contains illegal string %q", repo, pullKeySeparator)
+	}
+	hostname := h.Pull.BaseRepo.VCSHost.Hostname
+	if strings.Contains(hostname, pullKeySeparator) {
+		return "", fmt.Errorf("vcs hostname %q contains illegal string %q", hostname, pullKeySeparator)
+	}
+	return fmt.Sprintf("%s%s", r.historyKeyPrefix(), strings.Join([]string{
+		repo,
+		fmt.Sprintf("%d", h.Pull.Num),
+		// The hostname comes after the pull number so that history can
+		// still be looked up by repo and pull number prefix.
+		hostname,
+		h.Pull.HeadCommit,
+		h.Command.String(),
+		h.RepoRelDir,
+		h.Workspace,
+		h.ProjectName,
+	}, pullKeySeparator)), nil
+}
+
+func (r *RedisDB) getPull(c redis.Cmdable, key string) (*models.PullStatus, error) {
+	serialized, err := c.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var p models.PullStatus
+	if err := json.Unmarshal([]byte(serialized), &p); err != nil {
+		return nil, errors.Wrapf(err, "deserializing pull at %q with contents %q", key, serialized)
+	}
+	return &p, nil
+}
+
+func (r *RedisDB) writePull(tx *redis.Tx, key string, pull models.PullStatus) error {
+	serialized, err := json.Marshal(pull)
+	if err != nil {
+		return errors.Wrap(err, "serializing")
+	}
+	_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		return pipe.Set(ctx, key, serialized, 0).Err()
+	})
+	return err
+}
+
+func (r *RedisDB) projectResultToProject(p models.ProjectResult) models.ProjectStatus {
+	return models.ProjectStatus{
+		Workspace:   p.Workspace,
+		RepoRelDir:  p.RepoRelDir,
+		ProjectName: p.ProjectName,
+		Status:      p.PlanStatus(),
+		Targets:     p.Targets,
+	}
+}
+
+// escapeGlob escapes the characters that have a special meaning in a Redis
+// SCAN MATCH pattern.
+func escapeGlob(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`).Replace(s)
+}
@@ -0,0 +1,611 @@
+package redis_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/runatlantis/atlantis/server/core/redis"
+	"github.com/runatlantis/atlantis/server/events/models"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+var project = models.NewProject("owner/repo", "parent/child")
+var workspace = "default"
+var pullNum = 1
+var lock = models.ProjectLock{
+	Pull: models.PullRequest{
+		Num: pullNum,
+	},
+	User: models.User{
+		Username: "lkysow",
+	},
+	Workspace: workspace,
+	Project:   project,
+	Time:      time.Now(),
+}
+
+func TestLockCommandNotSet(t *testing.T) {
+	t.Log("retrieving apply lock when there are none should return empty LockCommand")
+	r := newTestRedis(t)
+	exists, err := r.CheckCommandLock(models.ApplyCommand)
+	Ok(t, err)
+	Assert(t, exists == nil, "exp nil")
+}
+
+func TestLockCommandEnabled(t *testing.T) {
+	t.Log("setting the apply lock")
+	r := newTestRedis(t)
+	timeNow := time.Now()
+	_, err := r.LockCommand(models.ApplyCommand, timeNow)
+	Ok(t, err)
+
+	config, err := r.CheckCommandLock(models.ApplyCommand)
+	Ok(t, err)
+	Equals(t, true, config.IsLocked())
+}
+
+func TestLockCommandFail(t *testing.T) {
+	t.Log("setting the apply lock")
+	r := newTestRedis(t)
+	timeNow := time.Now()
+	_, err := r.LockCommand(models.ApplyCommand, timeNow)
+	Ok(t, err)
+
+	_, err = r.LockCommand(models.ApplyCommand, timeNow)
+	ErrEquals(t, "db transaction failed: lock already exists", err)
+}
+
+func TestUnlockCommandDisabled(t *testing.T) {
+	t.Log("unsetting the apply lock")
+	r := newTestRedis(t)
+	timeNow := time.Now()
+	_, err := r.LockCommand(models.ApplyCommand, timeNow)
+	Ok(t, err)
+
+	err = r.UnlockCommand(models.ApplyCommand)
+	Ok(t, err)
+
+	config, err := r.CheckCommandLock(models.ApplyCommand)
+	Ok(t, err)
+	Assert(t, config == nil, "exp nil object")
+}
+
+func TestUnlockCommandFail(t *testing.T) {
+	t.Log("unsetting the apply lock when there is none")
+	r := newTestRedis(t)
+	err := r.UnlockCommand(models.ApplyCommand)
+	ErrEquals(t, "db transaction failed: no lock exists", err)
+}
+
+func TestMixedLocksPresent(t *testing.T) {
+	r := newTestRedis(t)
+	timeNow := time.Now()
+	_, err := r.LockCommand(models.ApplyCommand, timeNow)
+	Ok(t, err)
+
+	_, _, err = r.TryLock(lock)
+	Ok(t, err)
+	ls, err := r.List()
+	Ok(t, err)
+	Equals(t, 1, len(ls))
+}
+
+func TestListNoLocks(t *testing.T) {
+	t.Log("listing locks when there are none should return an empty list")
+	r := newTestRedis(t)
+	ls, err := r.List()
+	Ok(t, err)
+	Equals(t, 0, len(ls))
+}
+
+func TestListMultipleLocks(t *testing.T) {
+	t.Log("listing locks when there are multiple should return them")
+	r := newTestRedis(t)
+
+	repos := []string{
+		"owner/repo1",
+		"owner/repo2",
+		"owner/repo3",
+	}
+	for _, repo := range repos {
+		newLock := lock
+		newLock.Project = models.NewProject(repo, "path")
+		_, _, err := r.TryLock(newLock)
+		Ok(t, err)
+	}
+	ls, err := r.List()
+	Ok(t, err)
+	Equals(t, 3, len(ls))
+	for _, repo := range repos {
+		found := false
+		for _, l := range ls {
+			if l.Project.RepoFullName == repo {
+				found = true
+			}
+		}
+		Assert(t, found, "expected %s in %v", repo, ls)
+	}
+}
+
+func TestLockingExistingLock(t *testing.T) {
+	t.Log("if there is an existing lock, lock should...")
+	r := newTestRedis(t)
+	acquired, _, err := r.TryLock(lock)
+	Ok(t, err)
+	Equals(t, true, acquired)
+
+	t.Log("...succeed if the new project has a different workspace")
+	{
+		newLock := lock
+		newLock.Workspace = "different-workspace"
+		acquired, currLock, err := r.TryLock(newLock)
+		Ok(t, err)
+		Equals(t, true, acquired)
+		Equals(t, newLock, currLock)
+	}
+
+	t.Log("...not succeed if the new project only has a different pullNum")
+	{
+		newLock := lock
+		newLock.Pull.Num = lock.Pull.Num + 1
+		acquired, currLock, err := r.TryLock(newLock)
+		Ok(t, err)
+		Equals(t, false, acquired)
+		Equals(t, pullNum, currLock.Pull.Num)
+	}
+}
+
+func TestUnlocking(t *testing.T) {
+	t.Log("unlocking with an existing lock should succeed and return the lock")
+	r := newTestRedis(t)
+	_, _, err := r.TryLock(lock)
+	Ok(t, err)
+
+	l, err := r.Unlock(project, workspace)
+	Ok(t, err)
+	Assert(t, l != nil, "exp lock")
+	Equals(t, lock.User, l.User)
+
+	t.Log("unlocking again should return nil")
+	l, err = r.Unlock(project, workspace)
+	Ok(t, err)
+	Assert(t, l == nil, "exp nil")
+
+	ls, err := r.List()
+	Ok(t, err)
+	Equals(t, 0, len(ls))
+}
+
+func TestUnlockByPullMatching(t *testing.T) {
+	t.Log("UnlockByPull should delete only the locks for that pull request")
+	r := newTestRedis(t)
+	_, _, err := r.TryLock(lock)
+	Ok(t, err)
+
+	otherPull := lock
+	otherPull.Workspace = "other"
+	otherPull.Pull.Num = pullNum + 1
+	_, _, err = r.TryLock(otherPull)
+	Ok(t, err)
+
+	otherRepo := lock
+	otherRepo.Project = models.NewProject("owner/repo-other", "path")
+	_, _, err = r.TryLock(otherRepo)
+	Ok(t, err)
+
+	locks, err := r.UnlockByPull(project.RepoFullName, pullNum)
+	Ok(t, err)
+	Equals(t, 1, len(locks))
+
+	ls, err := r.List()
+	Ok(t, err)
+	Equals(t, 2, len(ls))
+}
+
+func TestGetLock(t *testing.T) {
+	r := newTestRedis(t)
+	l, err := r.GetLock(project, workspace)
+	Ok(t, err)
+	Assert(t, l == nil, "exp nil")
+
+	_, _, err = r.TryLock(lock)
+	Ok(t, err)
+	l, err = r.GetLock(project, workspace)
+	Ok(t, err)
+	Equals(t, lock.Project, l.Project)
+	Equals(t, lock.Workspace, l.Workspace)
+	Equals(t, lock.Pull, l.Pull)
+	Equals(t, lock.User, l.User)
+}
+
+func TestPullStatus_UpdateGetDelete(t *testing.T) {
+	r := newTestRedis(t)
+	pull := testPull()
+
+	status, err := r.UpdatePullWithResults(
+		pull,
+		[]models.ProjectResult{
+			{
+				Command:    models.PlanCommand,
+				RepoRelDir: ".",
+				Workspace:  "default",
+				Failure:    "failure",
+			},
+		})
+	Ok(t, err)
+	Equals(t, []models.ProjectStatus{
+		{
+			Workspace:  "default",
+			RepoRelDir: ".",
+			Status:     models.ErroredPlanStatus,
+		},
+	}, status.Projects)
+
+	maybeStatus, err := r.GetPullStatus(pull)
+	Ok(t, err)
+	Equals(t, pull, maybeStatus.Pull) // nolint: staticcheck
+	Equals(t, status.Projects, maybeStatus.Projects)
+
+	err = r.DeletePullStatus(pull)
+	Ok(t, err)
+	maybeStatus, err = r.GetPullStatus(pull)
+	Ok(t, err)
+	Assert(t, maybeStatus == nil, "exp nil")
+}
+
+func TestPullStatus_List(t *testing.T) {
+	r := newTestRedis(t)
+	statuses, err := r.ListPullStatuses()
+	Ok(t, err)
+	Equals(t, 0, len(statuses))
+
+	pull1 := testPull()
+	pull2 := testPull()
+	pull2.Num = 2
+	results := []models.ProjectResult{
+		{
+			Command:    models.PlanCommand,
+			RepoRelDir: ".",
+			Workspace:  "default",
+			Failure:    "failure",
+		},
+	}
+	_, err = r.UpdatePullWithResults(pull2, results)
+	Ok(t, err)
+	_, err = r.UpdatePullWithResults(pull1, results)
+	Ok(t, err)
+	// History is stored under similar keys but isn't a pull status.
+	Ok(t, r.AddProjectHistory(models.ProjectHistory{Pull: pull1, RepoRelDir: ".", Workspace: "default", Command: models.PlanCommand}))
+
+	t.Log("pulls are listed by number")
+	statuses, err = r.ListPullStatuses()
+	Ok(t, err)
+	Equals(t, 2, len(statuses))
+	Equals(t, pull1, statuses[0].Pull)
+	Equals(t, pull2, statuses[1].Pull)
+
+	t.Log("deleted pulls aren't listed")
+	Ok(t, r.DeletePullStatus(pull1))
+	statuses, err = r.ListPullStatuses()
+	Ok(t, err)
+	Equals(t, 1, len(statuses))
+	Equals(t, pull2, statuses[0].Pull)
+}
+
+func TestPullStatus_UpdateMergeAndProject(t *testing.T) {
+	r := newTestRedis(t)
+	pull := testPull()
+
+	_, err := r.UpdatePullWithResults(
+		pull,
+		[]models.ProjectResult{
+			{
+				Command:    models.PlanCommand,
+				RepoRelDir: "mergeme",
+				Workspace:  "default",
+				Failure:    "failure",
+			},
+			{
+				Command:    models.PlanCommand,
+				RepoRelDir: "staythesame",
+				Workspace:  "default",
+				PlanSuccess: &models.PlanSuccess{
+					TerraformOutput: "tf out",
+				},
+			},
+		})
+	Ok(t, err)
+
+	status, err := r.UpdatePullWithResults(
+		pull,
+		[]models.ProjectResult{
+			{
+				Command:      models.ApplyCommand,
+				RepoRelDir:   "mergeme",
+				Workspace:    "default",
+				ApplySuccess: "applied!",
+			},
+			{
+				Command:    models.ApplyCommand,
+				RepoRelDir: "newresult",
+				Workspace:  "default",
+				Failure:    "failure",
+			},
+		})
+	Ok(t, err)
+	Equals(t, []models.ProjectStatus{
+		{
+			RepoRelDir: "mergeme",
+			Workspace:  "default",
+			Status:     models.AppliedPlanStatus,
+		},
+		{
+			RepoRelDir: "staythesame",
+			Workspace:  "default",
+			Status:     models.PlannedPlanStatus,
+		},
+		{
+			RepoRelDir: "newresult",
+			Workspace:  "default",
+			Status:     models.ErroredApplyStatus,
+		},
+	}, status.Projects)
+
+	err = r.UpdateProjectStatus(pull, "default", "staythesame", models.DiscardedPlanStatus)
+	Ok(t, err)
+	maybeStatus, err := r.GetPullStatus(pull)
+	Ok(t, err)
+	Equals(t, models.DiscardedPlanStatus, maybeStatus.Projects[1].Status)
+}
+
+func TestProjectHistory_AddGet(t *testing.T) {
+	r := newTestRedis(t)
+
+	repo := models.Repo{FullName: "runatlantis/atlantis"}
+	plan := models.ProjectHistory{
+		Pull:       models.PullRequest{Num: 1, HeadCommit: "sha", BaseRepo: repo},
+		Workspace:  "default",
+		RepoRelDir: ".",
+		Command:    models.PlanCommand,
+		Status:     models.PlannedPlanStatus,
+		Output:     "Plan: 1 to add, 0 to change, 0 to destroy.",
+		Time:       time.Now().Add(-time.Minute),
+	}
+	apply := plan
+	apply.Command = models.ApplyCommand
+	apply.Status = models.AppliedPlanStatus
+	apply.Output = "Apply complete!"
+	apply.Time = time.Now()
+	otherPull := plan
+	otherPull.Pull = models.PullRequest{Num: 10, HeadCommit: "sha2", BaseRepo: repo}
+	otherRepo := plan
+	otherRepo.Pull = models.PullRequest{Num: 1, HeadCommit: "sha", BaseRepo: models.Repo{FullName: "runatlantis/atlantis-other"}}
+
+	t.Log("history is returned oldest first")
+	for _, h := range []models.ProjectHistory{apply, plan, otherPull, otherRepo} {
+		Ok(t, r.AddProjectHistory(h))
+	}
+	history, err := r.GetProjectHistory("runatlantis/atlantis", 1)
+	Ok(t, err)
+	Equals(t, 2, len(history))
+	Equals(t, plan.Output, history[0].Output)
+	Equals(t, apply.Output, history[1].Output)
+
+	t.Log("re-running a command on the same commit overwrites its output")
+	plan.Output = "No changes."
+	Ok(t, r.AddProjectHistory(plan))
+	history, err = r.GetProjectHistory("runatlantis/atlantis", 1)
+	Ok(t, err)
+	Equals(t, 2, len(history))
+	Equals(t, "No changes.", history[0].Output)
+
+	t.Log("pull number 0 returns the history for every pull in the repo")
+	history, err = r.GetProjectHistory("runatlantis/atlantis", 0)
+	Ok(t, err)
+	Equals(t, 3, len(history))
+
+	t.Log("an empty repo returns the history for every repo")
+	history, err = r.GetProjectHistory("", 0)
+	Ok(t, err)
+	Equals(t, 4, len(history))
+
+	t.Log("the same pull on another VCS host doesn't overwrite its history")
+	otherHost := plan
+	otherHost.Pull.BaseRepo = models.Repo{FullName: "runatlantis/atlantis", VCSHost: models.VCSHost{Hostname: "gitlab.com"}}
+	Ok(t, r.AddProjectHistory(otherHost))
+	history, err = r.GetProjectHistory("runatlantis/atlantis", 1)
+	Ok(t, err)
+	Equals(t, 3, len(history))
+}
+
+func TestAuditEvents_AddList(t *testing.T) {
+	r := newTestRedis(t)
+
+	events, err := r.ListAuditEvents()
+	Ok(t, err)
+	Equals(t, 0, len(events))
+
+	plan := models.AuditEvent{
+		Time:     time.Now(),
+		Username: "lkysow",
+		Repo:     "runatlantis/atlantis",
+		PullNum:  1,
+		Command:  "plan",
+		Result:   models.AuditResultSuccess,
+	}
+	apply := plan
+	apply.Command = "apply"
+	apply.Result = models.AuditResultFailure
+	apply.Error = "exit status 1"
+
+	t.Log("events are appended in order, even if they're identical")
+	for _, e := range []models.AuditEvent{plan, apply, plan} {
+		Ok(t, r.AddAuditEvent(e))
+	}
+	events, err = r.ListAuditEvents()
+	Ok(t, err)
+	Equals(t, 3, len(events))
+	Equals(t, "plan", events[0].Command)
+	Equals(t, "apply", events[1].Command)
+	Equals(t, "exit status 1", events[1].Error)
+	Equals(t, "plan", events[2].Command)
+}
+
+func TestQueuedCommands_AddDeleteList(t *testing.T) {
+	r := newTestRedis(t)
+	cmds, err := r.ListQueuedCommands()
+	Ok(t, err)
+	Equals(t, 0, len(cmds))
+
+	now := time.Now()
+	first := models.QueuedCommand{
+		ID:      "first",
+		Time:    now,
+		PullNum: 1,
+		Comment: []byte(`{"Name":1}`),
+	}
+	second := models.QueuedCommand{
+		ID:      "second",
+		Time:    now.Add(time.Second),
+		PullNum: 2,
+	}
+	Ok(t, r.AddQueuedCommand(second))
+	Ok(t, r.AddQueuedCommand(first))
+
+	t.Log("commands are listed oldest first")
+	cmds, err = r.ListQueuedCommands()
+	Ok(t, err)
+	Equals(t, 2, len(cmds))
+	Equals(t, "first", cmds[0].ID)
+	Equals(t, false, cmds[0].IsAutoplan())
+	Equals(t, "second", cmds[1].ID)
+	Equals(t, true, cmds[1].IsAutoplan())
+
+	t.Log("adding a command with the same ID overwrites it")
+	first.Attempts = 1
+	Ok(t, r.AddQueuedCommand(first))
+	cmds, err = r.ListQueuedCommands()
+	Ok(t, err)
+	Equals(t, 2, len(cmds))
+	Equals(t, 1, cmds[0].Attempts)
+
+	Ok(t, r.DeleteQueuedCommand("first"))
+	Ok(t, r.DeleteQueuedCommand("doesnotexist"))
+	cmds, err = r.ListQueuedCommands()
+	Ok(t, err)
+	Equals(t, 1, len(cmds))
+	Equals(t, "second", cmds[0].ID)
+}
+
+func testPull() models.PullRequest {
+	return models.PullRequest{
+		Num:        1,
+		HeadCommit: "sha",
+		URL:        "url",
+		HeadBranch: "head",
+		BaseBranch: "base",
+		Author:     "lkysow",
+		State:      models.OpenPullState,
+		BaseRepo: models.Repo{
+			FullName:          "runatlantis/atlantis",
+			Owner:             "runatlantis",
+			Name:              "atlantis",
+			CloneURL:          "clone-url",
+			SanitizedCloneURL: "clone-url",
+			VCSHost: models.VCSHost{
+				Hostname: "github.com",
+				Type:     models.Github,
+			},
+		},
+	}
+}
+
+// newTestRedis starts an in-memory Redis server and returns a RedisDB
+// connected to it. The server is stopped when the test finishes.
+func TestRegisteredRepos_AddDeleteList(t *testing.T) {
+	r := newTestRedis(t)
+	repos, err := r.ListRegisteredRepos()
+	Ok(t, err)
+	Equals(t, 0, len(repos))
+
+	first := models.RegisteredRepo{FullName: "owner/a", Hostname: "github.com", PlanOnly: true}
+	second := models.RegisteredRepo{FullName: "owner/b", Hostname: "github.com", WebhookID: 12}
+	Ok(t, r.AddRegisteredRepo(second))
+	Ok(t, r.AddRegisteredRepo(first))
+
+	t.Log("repos are listed by ID")
+	repos, err = r.ListRegisteredRepos()
+	Ok(t, err)
+	Equals(t, 2, len(repos))
+	Equals(t, "github.com/owner/a", repos[0].ID())
+	Equals(t, true, repos[0].PlanOnly)
+	Equals(t, int64(12), repos[1].WebhookID)
+
+	t.Log("adding a repo with the same ID overwrites it")
+	first.MaxParallel = 2
+	Ok(t, r.AddRegisteredRepo(first))
+	repos, err = r.ListRegisteredRepos()
+	Ok(t, err)
+	Equals(t, 2, len(repos))
+	Equals(t, 2, repos[0].MaxParallel)
+
+	Ok(t, r.DeleteRegisteredRepo("github.com/owner/a"))
+	Ok(t, r.DeleteRegisteredRepo("doesnotexist"))
+	repos, err = r.ListRegisteredRepos()
+	Ok(t, err)
+	Equals(t, 1, len(repos))
+	Equals(t, "github.com/owner/b", repos[0].ID())
+}
+
+func TestRegisteredRepos_AddIfAbsent(t *testing.T) {
+	r := newTestRedis(t)
+	repo := models.RegisteredRepo{FullName: "owner/a", Hostname: "github.com", PlanOnly: true}
+	added, err := r.AddRegisteredRepoIfAbsent(repo)
+	Ok(t, err)
+	Equals(t, true, added)
+
+	t.Log("a repo with the same ID isn't overwritten")
+	repo.PlanOnly = false
+	added, err = r.AddRegisteredRepoIfAbsent(repo)
+	Ok(t, err)
+	Equals(t, false, added)
+	repos, err := r.ListRegisteredRepos()
+	Ok(t, err)
+	Equals(t, 1, len(repos))
+	Equals(t, true, repos[0].PlanOnly)
+}
+
+func TestClaimDelivery(t *testing.T) {
+	r := newTestRedis(t)
+
+	claimed, err := r.ClaimDelivery("github/1")
+	Ok(t, err)
+	Equals(t, true, claimed)
+
+	claimed, err = r.ClaimDelivery("github/1")
+	Ok(t, err)
+	Equals(t, false, claimed)
+
+	claimed, err = r.ClaimDelivery("github/2")
+	Ok(t, err)
+	Equals(t, true, claimed)
+
+	Ok(t, r.ReleaseDelivery("github/1"))
+	claimed, err = r.ClaimDelivery("github/1")
+	Ok(t, err)
+	Equals(t, true, claimed)
+}
+
+func newTestRedis(t *testing.T) *redis.RedisDB {
+	s, err := miniredis.Run()
+	Ok(t, err)
+	t.Cleanup(s.Close)
+
+	port, err := strconv.Atoi(s.Port())
+	Ok(t, err)
+	r, err := redis.New(s.Host(), port, "", false, false, 0)
+	Ok(t, err)
+	return r
+}
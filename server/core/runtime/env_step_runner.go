@@ -18,7 +18,7 @@ func (r *EnvStepRunner) Run(ctx models.ProjectCommandContext, command string, va
 	if value != "" {
 		return value, nil
 	}
-	res, err := r.RunStepRunner.Run(ctx, command, path, envs)
+	res, err := r.RunStepRunner.RunUnredacted(ctx, command, path, envs)
 	// Trim newline from res to support running `echo env_value` which has
 	// a newline. We don't recommend users run echo -n env_value to remove the
 	// newline because -n doesn't work in the sh shell which is what we use
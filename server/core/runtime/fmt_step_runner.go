@@ -0,0 +1,27 @@
+package runtime
+
+import (
+	"path/filepath"
+
+	version "github.com/hashicorp/go-version"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// FmtStepRunner runs `terraform fmt -check` so that unformatted files fail
+// the step. It never rewrites files.
+type FmtStepRunner struct {
+	TerraformExecutor TerraformExec
+	DefaultTFVersion  *version.Version
+}
+
+// Run runs terraform fmt -check. The diff of each unformatted file is
+// included in the output.
+func (f *FmtStepRunner) Run(ctx models.ProjectCommandContext, extraArgs []string, path string, envs map[string]string) (string, error) {
+	tfVersion := f.DefaultTFVersion
+	if ctx.TerraformVersion != nil {
+		tfVersion = ctx.TerraformVersion
+	}
+
+	fmtCmd := append([]string{"fmt", "-check", "-diff", "-no-color"}, extraArgs...)
+	return f.TerraformExecutor.RunCommandWithVersion(ctx.Context(), ctx.Log, filepath.Clean(path), fmtCmd, envs, ctx.TerraformDistribution, tfVersion, ctx.Workspace)
+}
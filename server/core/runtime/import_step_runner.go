@@ -0,0 +1,31 @@
+package runtime
+
+import (
+	"path/filepath"
+
+	version "github.com/hashicorp/go-version"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// ImportStepRunner runs `terraform import`.
+type ImportStepRunner struct {
+	TerraformExecutor TerraformExec
+	DefaultTFVersion  *version.Version
+}
+
+// Run runs terraform import. The comment args end with the ADDRESS and ID to
+// import so they're appended after any extra args.
+func (i *ImportStepRunner) Run(ctx models.ProjectCommandContext, extraArgs []string, path string, envs map[string]string) (string, error) {
+	tfVersion := i.DefaultTFVersion
+	if ctx.TerraformVersion != nil {
+		tfVersion = ctx.TerraformVersion
+	}
+
+	importCmd := append(append([]string{"import", "-input=false", "-no-color"}, extraArgs...), ctx.EscapedCommentArgs...)
+	out, err := i.TerraformExecutor.RunCommandWithVersion(ctx.Context(), ctx.Log, filepath.Clean(path), importCmd, envs, ctx.TerraformDistribution, tfVersion, ctx.Workspace)
+
+	if err == nil {
+		removeStalePlanfile(ctx, path)
+	}
+	return out, err
+}
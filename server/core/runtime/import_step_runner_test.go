@@ -0,0 +1,50 @@
+package runtime
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-version"
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/core/terraform/mocks"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestImportStepRunner_Run(t *testing.T) {
+	RegisterMockTestingT(t)
+	logger := logging.NewNoopLogger(t)
+	workspace := "default"
+	tmpDir, cleanup := TempDir(t)
+	defer cleanup()
+	planPath := filepath.Join(tmpDir, "default.tfplan")
+	err := ioutil.WriteFile(planPath, nil, 0600)
+	Ok(t, err)
+
+	context := models.ProjectCommandContext{
+		Log:                logger,
+		EscapedCommentArgs: []string{"-var", "a=b", "aws_instance.example", "i-abcd1234"},
+		Workspace:          workspace,
+		RepoRelDir:         ".",
+	}
+
+	terraform := mocks.NewMockClient()
+	tfVersion, _ := version.NewVersion("0.15.0")
+	When(terraform.RunCommandWithVersion(context.Context(), logger, tmpDir, []string{"import", "-input=false", "-no-color", "-lock-timeout=5m", "-var", "a=b", "aws_instance.example", "i-abcd1234"}, map[string]string(nil), "", tfVersion, workspace)).
+		ThenReturn("Import successful!", nil)
+
+	s := &ImportStepRunner{
+		TerraformExecutor: terraform,
+		DefaultTFVersion:  tfVersion,
+	}
+	output, err := s.Run(context, []string{"-lock-timeout=5m"}, tmpDir, map[string]string(nil))
+	Ok(t, err)
+	Equals(t, "Import successful!", output)
+
+	t.Log("the stale planfile should have been deleted")
+	_, err = os.Stat(planPath)
+	Assert(t, os.IsNotExist(err), "exp planfile to be deleted")
+}
@@ -34,15 +34,28 @@ func (i *InitStepRunner) Run(ctx models.ProjectCommandContext, extraArgs []strin
 	terraformInitArgs = append(terraformInitArgs, "-no-color")
 
 	lockfilePath := filepath.Join(path, ".terraform.lock.hcl")
-	if MustConstraint("< 0.14.0").Check(tfVersion) || fileDoesNotExists(lockfilePath) {
+	if MustConstraint("< 0.14.0").Check(tfVersion) || fileDoesNotExists(lockfilePath) || ctx.InitOptions.Upgrade {
 		terraformInitArgs = append(terraformInitArgs, "-upgrade")
 	}
 
+	// The project's init options come before the step's extra args so the
+	// extra args can still add to or override them.
+	if terraformInitVerb[0] == "init" && len(ctx.InitOptions.BackendConfig) > 0 {
+		// Projects in the same dir share .terraform, so without -reconfigure
+		// init fails for each project whose backend config differs from the
+		// one that was initialized last.
+		optionArgs := []string{"-reconfigure"}
+		for _, c := range ctx.InitOptions.BackendConfig {
+			optionArgs = append(optionArgs, "-backend-config="+c)
+		}
+		extraArgs = append(optionArgs, extraArgs...)
+	}
+
 	finalArgs := common.DeDuplicateExtraArgs(terraformInitArgs, extraArgs)
 
 	terraformInitCmd := append(terraformInitVerb, finalArgs...)
 
-	out, err := i.TerraformExecutor.RunCommandWithVersion(ctx.Log, path, terraformInitCmd, envs, tfVersion, ctx.Workspace)
+	out, err := i.TerraformExecutor.RunCommandWithVersion(ctx.Context(), ctx.Log, path, terraformInitCmd, envs, ctx.TerraformDistribution, tfVersion, ctx.Workspace)
 	// Only include the init output if there was an error. Otherwise it's
 	// unnecessary and lengthens the comment.
 	if err != nil {
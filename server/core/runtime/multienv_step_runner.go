@@ -0,0 +1,40 @@
+package runtime
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// MultiEnvStepRunner sets multiple environment variables from the output of a
+// single command.
+type MultiEnvStepRunner struct {
+	RunStepRunner *RunStepRunner
+}
+
+// Run runs the multienv step command. The command must output one KEY=VALUE
+// pair per line. Empty lines are ignored. The pairs are returned so they can be
+// set as environment variables for subsequent steps.
+func (r *MultiEnvStepRunner) Run(ctx models.ProjectCommandContext, command string, path string, envs map[string]string) (map[string]string, error) {
+	res, err := r.RunStepRunner.RunUnredacted(ctx, command, path, envs)
+	if err != nil {
+		return nil, err
+	}
+	vars := make(map[string]string)
+	for i, line := range strings.Split(res, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		// Only split on the first = so values can contain =.
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			// The line isn't included since it may hold a secret that wasn't
+			// redacted.
+			return nil, fmt.Errorf("multienv command output must be KEY=VALUE lines, line %d isn't", i+1)
+		}
+		vars[strings.TrimSpace(kv[0])] = kv[1]
+	}
+	return vars, nil
+}
@@ -0,0 +1,94 @@
+package runtime_test
+
+import (
+	"testing"
+
+	"github.com/hashicorp/go-version"
+	"github.com/runatlantis/atlantis/server/core/runtime"
+	"github.com/runatlantis/atlantis/server/core/terraform/mocks"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+
+	. "github.com/petergtz/pegomock"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestMultiEnvStepRunner_Run(t *testing.T) {
+	cases := []struct {
+		Command string
+		ExpVars map[string]string
+		ExpErr  string
+	}{
+		{
+			Command: "echo",
+			ExpVars: map[string]string{},
+		},
+		{
+			Command: "echo KEY1=value1",
+			ExpVars: map[string]string{"KEY1": "value1"},
+		},
+		{
+			Command: `printf 'KEY1=value1\n\nKEY2=a=b\nKEY3=\n'`,
+			ExpVars: map[string]string{"KEY1": "value1", "KEY2": "a=b", "KEY3": ""},
+		},
+		{
+			Command: "echo KEY1=$REPO_REL_DIR",
+			ExpVars: map[string]string{"KEY1": "mydir"},
+		},
+		{
+			Command: "echo not-a-pair",
+			ExpErr:  "multienv command output must be KEY=VALUE lines, line 1 isn't",
+		},
+		{
+			Command: "exit 1",
+			ExpErr:  "exit status 1",
+		},
+	}
+	RegisterMockTestingT(t)
+	tfClient := mocks.NewMockClient()
+	tfVersion, err := version.NewVersion("0.12.0")
+	Ok(t, err)
+	runStepRunner := runtime.RunStepRunner{
+		TerraformExecutor: tfClient,
+		DefaultTFVersion:  tfVersion,
+	}
+	multiEnvRunner := runtime.MultiEnvStepRunner{
+		RunStepRunner: &runStepRunner,
+	}
+	for _, c := range cases {
+		t.Run(c.Command, func(t *testing.T) {
+			tmpDir, cleanup := TempDir(t)
+			defer cleanup()
+			ctx := models.ProjectCommandContext{
+				BaseRepo: models.Repo{
+					Name:  "basename",
+					Owner: "baseowner",
+				},
+				HeadRepo: models.Repo{
+					Name:  "headname",
+					Owner: "headowner",
+				},
+				Pull: models.PullRequest{
+					Num:        2,
+					HeadBranch: "add-feat",
+					BaseBranch: "master",
+					Author:     "acme",
+				},
+				User: models.User{
+					Username: "acme-user",
+				},
+				Log:              logging.NewNoopLogger(t),
+				Workspace:        "myworkspace",
+				RepoRelDir:       "mydir",
+				TerraformVersion: tfVersion,
+			}
+			vars, err := multiEnvRunner.Run(ctx, c.Command, tmpDir, map[string]string(nil))
+			if c.ExpErr != "" {
+				ErrContains(t, c.ExpErr, err)
+				return
+			}
+			Ok(t, err)
+			Equals(t, c.ExpVars, vars)
+		})
+	}
+}
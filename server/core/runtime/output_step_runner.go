@@ -0,0 +1,90 @@
+package runtime
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/go-version"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// OutputStepRunner runs terraform output and writes the outputs listed in the
+// project's config to a json file so they can be shown in the apply comment.
+type OutputStepRunner struct {
+	TerraformExecutor TerraformExec
+	DefaultTFVersion  *version.Version
+}
+
+// terraformOutput is an output in the result of terraform output -json.
+type terraformOutput struct {
+	Sensitive bool            `json:"sensitive"`
+	Value     json.RawMessage `json:"value"`
+}
+
+func (o *OutputStepRunner) Run(ctx models.ProjectCommandContext, extraArgs []string, path string, envs map[string]string) (string, error) {
+	if len(ctx.Outputs) == 0 {
+		ctx.Log.Warn("skipping output step since the project doesn't list any outputs to show")
+		return "", nil
+	}
+
+	tfVersion := o.DefaultTFVersion
+	if ctx.TerraformVersion != nil {
+		tfVersion = ctx.TerraformVersion
+	}
+
+	out, err := o.TerraformExecutor.RunCommandWithVersion(ctx.Context(), ctx.Log, filepath.Clean(path), append([]string{"output", "-no-color", "-json"}, extraArgs...), envs, ctx.TerraformDistribution, tfVersion, ctx.Workspace)
+	if err != nil {
+		return out, errors.Wrap(err, "running terraform output")
+	}
+
+	var tfOutputs map[string]terraformOutput
+	if err := json.Unmarshal([]byte(out), &tfOutputs); err != nil {
+		return "", errors.Wrap(err, "parsing terraform output")
+	}
+
+	// Only the outputs the project lists are shown since the others may not
+	// be meant for the pull request, ex. outputs that aren't marked sensitive
+	// but hold credentials.
+	var outputs []models.TerraformOutput
+	for _, name := range ctx.Outputs {
+		tfOutput, ok := tfOutputs[name]
+		if !ok {
+			ctx.Log.Warn("project doesn't have an output named %q", name)
+			continue
+		}
+		output := models.TerraformOutput{Name: name, Sensitive: tfOutput.Sensitive}
+		if !tfOutput.Sensitive {
+			output.Value = outputValue(tfOutput.Value)
+		}
+		outputs = append(outputs, output)
+	}
+
+	result, err := json.Marshal(outputs)
+	if err != nil {
+		return "", errors.Wrap(err, "encoding outputs")
+	}
+	if err := ioutil.WriteFile(filepath.Join(path, ctx.GetOutputsFileName()), result, os.ModePerm); err != nil {
+		return "", errors.Wrap(err, "writing outputs")
+	}
+	return "", nil
+}
+
+// outputValue returns strings as is and other values as compact JSON.
+func outputValue(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return string(raw)
+	}
+	compact, err := json.Marshal(v)
+	if err != nil {
+		return string(raw)
+	}
+	return string(compact)
+}
@@ -0,0 +1,72 @@
+package runtime
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-version"
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/core/terraform/mocks"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestOutputStepRunner_Run(t *testing.T) {
+	logger := logging.NewNoopLogger(t)
+	path, cleanup := TempDir(t)
+	defer cleanup()
+	tfVersion, _ := version.NewVersion("0.14.0")
+	ctx := models.ProjectCommandContext{
+		Workspace:   "default",
+		ProjectName: "test",
+		Log:         logger,
+		Outputs:     []string{"endpoint", "subnet_ids", "password", "missing"},
+	}
+
+	RegisterMockTestingT(t)
+	mockExecutor := mocks.NewMockClient()
+	When(mockExecutor.RunCommandWithVersion(
+		ctx.Context(),
+		logger, path, []string{"output", "-no-color", "-json"}, map[string]string(nil), "", tfVersion, "default",
+	)).ThenReturn(`{
+  "endpoint": {"sensitive": false, "type": "string", "value": "https://api.example.com"},
+  "subnet_ids": {"sensitive": false, "type": ["list", "string"], "value": ["subnet-a", "subnet-b"]},
+  "password": {"sensitive": true, "type": "string", "value": "hunter2"},
+  "internal": {"sensitive": false, "type": "string", "value": "not listed"}
+}`, nil)
+
+	subject := OutputStepRunner{
+		TerraformExecutor: mockExecutor,
+		DefaultTFVersion:  tfVersion,
+	}
+	out, err := subject.Run(ctx, nil, path, nil)
+	Ok(t, err)
+	Equals(t, "", out)
+
+	written, err := ioutil.ReadFile(filepath.Join(path, "test-default.outputs.json"))
+	Ok(t, err)
+	Equals(t, `[{"Name":"endpoint","Value":"https://api.example.com","Sensitive":false},{"Name":"subnet_ids","Value":"[\"subnet-a\",\"subnet-b\"]","Sensitive":false},{"Name":"password","Value":"","Sensitive":true}]`, string(written))
+}
+
+func TestOutputStepRunner_RunNoOutputs(t *testing.T) {
+	path, cleanup := TempDir(t)
+	defer cleanup()
+
+	RegisterMockTestingT(t)
+	mockExecutor := mocks.NewMockClient()
+	subject := OutputStepRunner{
+		TerraformExecutor: mockExecutor,
+	}
+	_, err := subject.Run(models.ProjectCommandContext{
+		Workspace: "default",
+		Log:       logging.NewNoopLogger(t),
+	}, nil, path, nil)
+	Ok(t, err)
+
+	// terraform isn't run if the project doesn't list outputs to show.
+	files, err := ioutil.ReadDir(path)
+	Ok(t, err)
+	Equals(t, 0, len(files))
+}
@@ -10,6 +10,7 @@ import (
 
 	version "github.com/hashicorp/go-version"
 	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/core/planstorage"
 	"github.com/runatlantis/atlantis/server/events/models"
 )
 
@@ -30,6 +31,12 @@ type PlanStepRunner struct {
 	DefaultTFVersion    *version.Version
 	CommitStatusUpdater StatusUpdater
 	AsyncTFExec         AsyncTFExec
+	// PlanStorage, if set, is where planfiles are uploaded after they're
+	// generated.
+	PlanStorage planstorage.PlanStorage
+	// StructuredPlanOutput is true if we should also run `terraform show -json`
+	// on the planfile so the plan can be rendered as a structured diff.
+	StructuredPlanOutput bool
 }
 
 func (p *PlanStepRunner) Run(ctx models.ProjectCommandContext, extraArgs []string, path string, envs map[string]string) (string, error) {
@@ -46,15 +53,52 @@ func (p *PlanStepRunner) Run(ctx models.ProjectCommandContext, extraArgs []strin
 
 	planFile := filepath.Join(path, GetPlanFilename(ctx.Workspace, ctx.ProjectName))
 	planCmd := p.buildPlanCmd(ctx, extraArgs, path, tfVersion, planFile)
-	output, err := p.TerraformExecutor.RunCommandWithVersion(ctx.Log, filepath.Clean(path), planCmd, envs, tfVersion, ctx.Workspace)
+	output, err := p.TerraformExecutor.RunCommandWithVersion(ctx.Context(), ctx.Log, filepath.Clean(path), planCmd, envs, ctx.TerraformDistribution, tfVersion, ctx.Workspace)
 	if p.isRemoteOpsErr(output, err) {
 		ctx.Log.Debug("detected that this project is using TFE remote ops")
-		return p.remotePlan(ctx, extraArgs, path, tfVersion, planFile, envs)
+		output, err = p.remotePlan(ctx, extraArgs, path, tfVersion, planFile, envs)
+		if err != nil {
+			return output, err
+		}
+		return output, p.uploadPlan(ctx, planFile)
 	}
 	if err != nil {
 		return output, err
 	}
-	return p.fmtPlanOutput(output, tfVersion), nil
+	if p.StructuredPlanOutput {
+		p.showPlan(ctx, path, tfVersion, planFile, envs)
+	}
+	return fmtPlanOutput(output, tfVersion), p.uploadPlan(ctx, planFile)
+}
+
+// showPlan writes the JSON representation of planFile to the show result file
+// so the plan can be rendered as a structured diff. Errors are only logged
+// because we can still fall back to rendering the raw plan output.
+func (p *PlanStepRunner) showPlan(ctx models.ProjectCommandContext, path string, tfVersion *version.Version, planFile string, envs map[string]string) {
+	if !MustConstraint(">=" + minimumShowTfVersion).Check(tfVersion) {
+		ctx.Log.Debug("not rendering structured plan because terraform show -json requires >= %s", minimumShowTfVersion)
+		return
+	}
+	output, err := p.TerraformExecutor.RunCommandWithVersion(ctx.Context(), ctx.Log, filepath.Clean(path), []string{"show", "-no-color", "-json", filepath.Clean(planFile)}, envs, ctx.TerraformDistribution, tfVersion, ctx.Workspace)
+	if err != nil {
+		ctx.Log.Warn("unable to run terraform show on planfile: %s", err)
+		return
+	}
+	if err := ioutil.WriteFile(filepath.Join(path, ctx.GetShowResultFileName()), []byte(output), 0600); err != nil {
+		ctx.Log.Warn("unable to write terraform show result: %s", err)
+	}
+}
+
+// uploadPlan stores planFile in the plan storage, if configured, so it can be
+// used to apply even if the local copy is lost.
+func (p *PlanStepRunner) uploadPlan(ctx models.ProjectCommandContext, planFile string) error {
+	if p.PlanStorage == nil {
+		return nil
+	}
+	if err := p.PlanStorage.Upload(ctx, planFile); err != nil {
+		return errors.Wrap(err, "storing planfile")
+	}
+	return nil
 }
 
 // isRemoteOpsErr returns true if there was an error caused due to this
@@ -71,6 +115,7 @@ func (p *PlanStepRunner) isRemoteOpsErr(output string, err error) bool {
 func (p *PlanStepRunner) remotePlan(ctx models.ProjectCommandContext, extraArgs []string, path string, tfVersion *version.Version, planFile string, envs map[string]string) (string, error) {
 	argList := [][]string{
 		{"plan", "-input=false", "-refresh", "-no-color"},
+		destroyArgs(ctx),
 		extraArgs,
 		ctx.EscapedCommentArgs,
 	}
@@ -97,7 +142,7 @@ func (p *PlanStepRunner) remotePlan(ctx models.ProjectCommandContext, extraArgs
 		return output, errors.Wrap(err, "unable to create planfile for remote ops")
 	}
 
-	return p.fmtPlanOutput(output, tfVersion), nil
+	return fmtPlanOutput(output, tfVersion), nil
 }
 
 // switchWorkspace changes the terraform workspace if necessary and will create
@@ -125,7 +170,7 @@ func (p *PlanStepRunner) switchWorkspace(ctx models.ProjectCommandContext, path
 	// already in the right workspace then no need to switch. This will save us
 	// about ten seconds. This command is only available in > 0.10.
 	if !runningZeroPointNine {
-		workspaceShowOutput, err := p.TerraformExecutor.RunCommandWithVersion(ctx.Log, path, []string{workspaceCmd, "show"}, envs, tfVersion, ctx.Workspace)
+		workspaceShowOutput, err := p.TerraformExecutor.RunCommandWithVersion(ctx.Context(), ctx.Log, path, []string{workspaceCmd, "show"}, envs, ctx.TerraformDistribution, tfVersion, ctx.Workspace)
 		if err != nil {
 			return err
 		}
@@ -135,16 +180,35 @@ func (p *PlanStepRunner) switchWorkspace(ctx models.ProjectCommandContext, path
 		}
 	}
 
+	// If workspaces can't be created automatically, check that it exists so
+	// a typo doesn't create a workspace with its own, empty, state.
+	if ctx.NoWorkspaceAutocreate {
+		listOutput, err := p.TerraformExecutor.RunCommandWithVersion(ctx.Context(), ctx.Log, path, []string{workspaceCmd, "list"}, envs, ctx.TerraformDistribution, tfVersion, ctx.Workspace)
+		if err != nil {
+			return err
+		}
+		workspaces := parseWorkspaceList(listOutput)
+		exists := false
+		for _, w := range workspaces {
+			exists = exists || w == ctx.Workspace
+		}
+		if !exists {
+			return fmt.Errorf("workspace '%s' doesn't exist; available: %s", ctx.Workspace, strings.Join(workspaces, ", "))
+		}
+		_, err = p.TerraformExecutor.RunCommandWithVersion(ctx.Context(), ctx.Log, path, []string{workspaceCmd, "select", "-no-color", ctx.Workspace}, envs, ctx.TerraformDistribution, tfVersion, ctx.Workspace)
+		return err
+	}
+
 	// Finally we'll have to select the workspace. We need to figure out if this
 	// workspace exists so we can create it if it doesn't.
 	// To do this we can either select and catch the error or use list and then
 	// look for the workspace. Both commands take the same amount of time so
 	// that's why we're running select here.
-	_, err := p.TerraformExecutor.RunCommandWithVersion(ctx.Log, path, []string{workspaceCmd, "select", "-no-color", ctx.Workspace}, envs, tfVersion, ctx.Workspace)
+	_, err := p.TerraformExecutor.RunCommandWithVersion(ctx.Context(), ctx.Log, path, []string{workspaceCmd, "select", "-no-color", ctx.Workspace}, envs, ctx.TerraformDistribution, tfVersion, ctx.Workspace)
 	if err != nil {
 		// If terraform workspace select fails we run terraform workspace
 		// new to create a new workspace automatically.
-		out, err := p.TerraformExecutor.RunCommandWithVersion(ctx.Log, path, []string{workspaceCmd, "new", "-no-color", ctx.Workspace}, envs, tfVersion, ctx.Workspace)
+		out, err := p.TerraformExecutor.RunCommandWithVersion(ctx.Context(), ctx.Log, path, []string{workspaceCmd, "new", "-no-color", ctx.Workspace}, envs, ctx.TerraformDistribution, tfVersion, ctx.Workspace)
 		if err != nil {
 			return fmt.Errorf("%s: %s", err, out)
 		}
@@ -152,6 +216,20 @@ func (p *PlanStepRunner) switchWorkspace(ctx models.ProjectCommandContext, path
 	return nil
 }
 
+// parseWorkspaceList returns the workspaces in the output of terraform
+// workspace list, which has one workspace per line and marks the current one
+// with a *, ex. "* staging".
+func parseWorkspaceList(output string) []string {
+	var workspaces []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "*"))
+		if line != "" {
+			workspaces = append(workspaces, line)
+		}
+	}
+	return workspaces
+}
+
 func (p *PlanStepRunner) buildPlanCmd(ctx models.ProjectCommandContext, extraArgs []string, path string, tfVersion *version.Version, planFile string) []string {
 	tfVars := p.tfVars(ctx, tfVersion)
 
@@ -169,6 +247,8 @@ func (p *PlanStepRunner) buildPlanCmd(ctx models.ProjectCommandContext, extraArg
 		// NOTE: we need to quote the plan filename because Bitbucket Server can
 		// have spaces in its repo owner names.
 		{"plan", "-input=false", "-refresh", "-no-color", "-out", fmt.Sprintf("%q", planFile)},
+		destroyArgs(ctx),
+		targetArgs(ctx),
 		tfVars,
 		extraArgs,
 		ctx.EscapedCommentArgs,
@@ -178,6 +258,30 @@ func (p *PlanStepRunner) buildPlanCmd(ctx models.ProjectCommandContext, extraArg
 	return p.flatten(argList)
 }
 
+// destroyArgs returns the arguments that make terraform plan destroy every
+// resource if that was requested with atlantis plan --destroy.
+func destroyArgs(ctx models.ProjectCommandContext) []string {
+	if ctx.Destroy {
+		return []string{"-destroy"}
+	}
+	return nil
+}
+
+// targetArgs returns a -target argument for each resource address passed with
+// atlantis plan --target. Like the comment args, each character is escaped
+// since addresses can contain quotes, ex. aws_instance.web["prod"].
+func targetArgs(ctx models.ProjectCommandContext) []string {
+	var args []string
+	for _, target := range ctx.Targets {
+		var escaped string
+		for i := range target {
+			escaped += "\\" + string(target[i])
+		}
+		args = append(args, "-target="+escaped)
+	}
+	return args
+}
+
 // tfVars returns a list of "-var", "key=value" pairs that identify who and which
 // repo this command is running for. This can be used for naming the
 // session name in AWS which will identify in CloudTrail the source of
@@ -223,7 +327,7 @@ func (p *PlanStepRunner) flatten(slices [][]string) []string {
 // "- aws_security_group_rule.allow_all"
 // We do it for +, ~ and -.
 // It also removes the "Refreshing..." preamble.
-func (p *PlanStepRunner) fmtPlanOutput(output string, tfVersion *version.Version) string {
+func fmtPlanOutput(output string, tfVersion *version.Version) string {
 	output = StripRefreshingFromPlanOutput(output, tfVersion)
 	output = plusDiffRegex.ReplaceAllString(output, "+")
 	output = tildeDiffRegex.ReplaceAllString(output, "~")
@@ -253,7 +357,7 @@ func (p *PlanStepRunner) runRemotePlan(
 
 	// Start the async command execution.
 	ctx.Log.Debug("starting async tf remote operation")
-	_, outCh := p.AsyncTFExec.RunCommandAsync(ctx.Log, filepath.Clean(path), cmdArgs, envs, tfVersion, ctx.Workspace)
+	_, outCh := p.AsyncTFExec.RunCommandAsync(ctx.Context(), ctx.Log, filepath.Clean(path), cmdArgs, envs, ctx.TerraformDistribution, tfVersion, ctx.Workspace)
 	var lines []string
 	nextLineIsRunURL := false
 	var runURL string
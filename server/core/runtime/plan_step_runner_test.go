@@ -1,6 +1,7 @@
 package runtime_test
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -14,6 +15,8 @@ import (
 
 	. "github.com/petergtz/pegomock"
 	"github.com/pkg/errors"
+	psmocks "github.com/runatlantis/atlantis/server/core/planstorage/mocks"
+	psmatchers "github.com/runatlantis/atlantis/server/core/planstorage/mocks/matchers"
 	"github.com/runatlantis/atlantis/server/core/runtime"
 	"github.com/runatlantis/atlantis/server/core/terraform/mocks"
 	matchers2 "github.com/runatlantis/atlantis/server/core/terraform/mocks/matchers"
@@ -37,7 +40,7 @@ func TestRun_NoWorkspaceIn08(t *testing.T) {
 		TerraformExecutor: terraform,
 	}
 
-	When(terraform.RunCommandWithVersion(logging_matchers.AnyLoggingSimpleLogging(), AnyString(), AnyStringSlice(), matchers2.AnyMapOfStringToString(), matchers2.AnyPtrToGoVersionVersion(), AnyString())).
+	When(terraform.RunCommandWithVersion(matchers2.AnyContextContext(), logging_matchers.AnyLoggingSimpleLogging(), AnyString(), AnyStringSlice(), matchers2.AnyMapOfStringToString(), AnyString(), matchers2.AnyPtrToGoVersionVersion(), AnyString())).
 		ThenReturn("output", nil)
 	output, err := s.Run(models.ProjectCommandContext{
 		Log:                logger,
@@ -58,6 +61,7 @@ func TestRun_NoWorkspaceIn08(t *testing.T) {
 
 	Equals(t, "output", output)
 	terraform.VerifyWasCalledOnce().RunCommandWithVersion(
+		context.Background(),
 		logger,
 		"/path",
 		[]string{"plan",
@@ -81,26 +85,29 @@ func TestRun_NoWorkspaceIn08(t *testing.T) {
 			"comment",
 			"args"},
 		map[string]string(nil),
+		"",
 		tfVersion,
 		workspace)
 
 	// Verify that no env or workspace commands were run
-	terraform.VerifyWasCalled(Never()).RunCommandWithVersion(logger,
+	terraform.VerifyWasCalled(Never()).RunCommandWithVersion(context.Background(), logger,
 		"/path",
 		[]string{"env",
 			"select",
 			"-no-color",
 			"workspace"},
 		map[string]string(nil),
+		"",
 		tfVersion,
 		workspace)
-	terraform.VerifyWasCalled(Never()).RunCommandWithVersion(logger,
+	terraform.VerifyWasCalled(Never()).RunCommandWithVersion(context.Background(), logger,
 		"/path",
 		[]string{"workspace",
 			"select",
 			"-no-color",
 			"workspace"},
 		map[string]string(nil),
+		"",
 		tfVersion,
 		workspace)
 }
@@ -119,7 +126,7 @@ func TestRun_ErrWorkspaceIn08(t *testing.T) {
 		DefaultTFVersion:  tfVersion,
 	}
 
-	When(terraform.RunCommandWithVersion(logging_matchers.AnyLoggingSimpleLogging(), AnyString(), AnyStringSlice(), matchers2.AnyMapOfStringToString(), matchers2.AnyPtrToGoVersionVersion(), AnyString())).
+	When(terraform.RunCommandWithVersion(matchers2.AnyContextContext(), logging_matchers.AnyLoggingSimpleLogging(), AnyString(), AnyStringSlice(), matchers2.AnyMapOfStringToString(), AnyString(), matchers2.AnyPtrToGoVersionVersion(), AnyString())).
 		ThenReturn("output", nil)
 	_, err := s.Run(models.ProjectCommandContext{
 		Log:        logger,
@@ -167,7 +174,7 @@ func TestRun_SwitchesWorkspace(t *testing.T) {
 				DefaultTFVersion:  tfVersion,
 			}
 
-			When(terraform.RunCommandWithVersion(logging_matchers.AnyLoggingSimpleLogging(), AnyString(), AnyStringSlice(), matchers2.AnyMapOfStringToString(), matchers2.AnyPtrToGoVersionVersion(), AnyString())).
+			When(terraform.RunCommandWithVersion(matchers2.AnyContextContext(), logging_matchers.AnyLoggingSimpleLogging(), AnyString(), AnyStringSlice(), matchers2.AnyMapOfStringToString(), AnyString(), matchers2.AnyPtrToGoVersionVersion(), AnyString())).
 				ThenReturn("output", nil)
 			output, err := s.Run(models.ProjectCommandContext{
 				Log:                logger,
@@ -188,16 +195,17 @@ func TestRun_SwitchesWorkspace(t *testing.T) {
 
 			Equals(t, "output", output)
 			// Verify that env select was called as well as plan.
-			terraform.VerifyWasCalledOnce().RunCommandWithVersion(logger,
+			terraform.VerifyWasCalledOnce().RunCommandWithVersion(context.Background(), logger,
 				"/path",
 				[]string{c.expWorkspaceCmd,
 					"select",
 					"-no-color",
 					"workspace"},
 				map[string]string(nil),
+				"",
 				tfVersion,
 				"workspace")
-			terraform.VerifyWasCalledOnce().RunCommandWithVersion(logger,
+			terraform.VerifyWasCalledOnce().RunCommandWithVersion(context.Background(), logger,
 				"/path",
 				[]string{"plan",
 					"-input=false",
@@ -220,6 +228,7 @@ func TestRun_SwitchesWorkspace(t *testing.T) {
 					"comment",
 					"args"},
 				map[string]string(nil),
+				"",
 				tfVersion,
 				"workspace")
 		})
@@ -264,10 +273,10 @@ func TestRun_CreatesWorkspace(t *testing.T) {
 
 			// Ensure that we actually try to switch workspaces by making the
 			// output of `workspace show` to be a different name.
-			When(terraform.RunCommandWithVersion(logger, "/path", []string{"workspace", "show"}, map[string]string(nil), tfVersion, "workspace")).ThenReturn("diffworkspace\n", nil)
+			When(terraform.RunCommandWithVersion(context.Background(), logger, "/path", []string{"workspace", "show"}, map[string]string(nil), "", tfVersion, "workspace")).ThenReturn("diffworkspace\n", nil)
 
 			expWorkspaceArgs := []string{c.expWorkspaceCommand, "select", "-no-color", "workspace"}
-			When(terraform.RunCommandWithVersion(logger, "/path", expWorkspaceArgs, map[string]string(nil), tfVersion, "workspace")).ThenReturn("", errors.New("workspace does not exist"))
+			When(terraform.RunCommandWithVersion(context.Background(), logger, "/path", expWorkspaceArgs, map[string]string(nil), "", tfVersion, "workspace")).ThenReturn("", errors.New("workspace does not exist"))
 
 			expPlanArgs := []string{"plan",
 				"-input=false",
@@ -289,7 +298,7 @@ func TestRun_CreatesWorkspace(t *testing.T) {
 				"args",
 				"comment",
 				"args"}
-			When(terraform.RunCommandWithVersion(logger, "/path", expPlanArgs, map[string]string(nil), tfVersion, "workspace")).ThenReturn("output", nil)
+			When(terraform.RunCommandWithVersion(context.Background(), logger, "/path", expPlanArgs, map[string]string(nil), "", tfVersion, "workspace")).ThenReturn("output", nil)
 
 			output, err := s.Run(models.ProjectCommandContext{
 				Log:                logger,
@@ -310,8 +319,8 @@ func TestRun_CreatesWorkspace(t *testing.T) {
 
 			Equals(t, "output", output)
 			// Verify that env select was called as well as plan.
-			terraform.VerifyWasCalledOnce().RunCommandWithVersion(logger, "/path", expWorkspaceArgs, map[string]string(nil), tfVersion, "workspace")
-			terraform.VerifyWasCalledOnce().RunCommandWithVersion(logger, "/path", expPlanArgs, map[string]string(nil), tfVersion, "workspace")
+			terraform.VerifyWasCalledOnce().RunCommandWithVersion(context.Background(), logger, "/path", expWorkspaceArgs, map[string]string(nil), "", tfVersion, "workspace")
+			terraform.VerifyWasCalledOnce().RunCommandWithVersion(context.Background(), logger, "/path", expPlanArgs, map[string]string(nil), "", tfVersion, "workspace")
 		})
 	}
 }
@@ -327,7 +336,7 @@ func TestRun_NoWorkspaceSwitchIfNotNecessary(t *testing.T) {
 		TerraformExecutor: terraform,
 		DefaultTFVersion:  tfVersion,
 	}
-	When(terraform.RunCommandWithVersion(logger, "/path", []string{"workspace", "show"}, map[string]string(nil), tfVersion, "workspace")).ThenReturn("workspace\n", nil)
+	When(terraform.RunCommandWithVersion(context.Background(), logger, "/path", []string{"workspace", "show"}, map[string]string(nil), "", tfVersion, "workspace")).ThenReturn("workspace\n", nil)
 
 	expPlanArgs := []string{"plan",
 		"-input=false",
@@ -349,7 +358,7 @@ func TestRun_NoWorkspaceSwitchIfNotNecessary(t *testing.T) {
 		"args",
 		"comment",
 		"args"}
-	When(terraform.RunCommandWithVersion(logger, "/path", expPlanArgs, map[string]string(nil), tfVersion, "workspace")).ThenReturn("output", nil)
+	When(terraform.RunCommandWithVersion(context.Background(), logger, "/path", expPlanArgs, map[string]string(nil), "", tfVersion, "workspace")).ThenReturn("output", nil)
 
 	output, err := s.Run(models.ProjectCommandContext{
 		Log:                logger,
@@ -369,10 +378,61 @@ func TestRun_NoWorkspaceSwitchIfNotNecessary(t *testing.T) {
 	Ok(t, err)
 
 	Equals(t, "output", output)
-	terraform.VerifyWasCalledOnce().RunCommandWithVersion(logger, "/path", expPlanArgs, map[string]string(nil), tfVersion, "workspace")
+	terraform.VerifyWasCalledOnce().RunCommandWithVersion(context.Background(), logger, "/path", expPlanArgs, map[string]string(nil), "", tfVersion, "workspace")
 
 	// Verify that workspace select was never called.
-	terraform.VerifyWasCalled(Never()).RunCommandWithVersion(logger, "/path", []string{"workspace", "select", "-no-color", "workspace"}, map[string]string(nil), tfVersion, "workspace")
+	terraform.VerifyWasCalled(Never()).RunCommandWithVersion(context.Background(), logger, "/path", []string{"workspace", "select", "-no-color", "workspace"}, map[string]string(nil), "", tfVersion, "workspace")
+}
+
+func TestRun_NoWorkspaceAutocreate(t *testing.T) {
+	cases := []struct {
+		workspace string
+		expErr    string
+	}{
+		{"staging", ""},
+		{"prod", "workspace 'prod' doesn't exist; available: default, staging"},
+	}
+	for _, c := range cases {
+		t.Run(c.workspace, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			terraform := mocks.NewMockClient()
+			tfVersion, _ := version.NewVersion("0.14.0")
+			s := runtime.PlanStepRunner{
+				TerraformExecutor: terraform,
+				DefaultTFVersion:  tfVersion,
+			}
+			var cmds []string
+			When(terraform.RunCommandWithVersion(
+				matchers2.AnyContextContext(),
+				matchers.AnyPtrToLoggingSimpleLogger(),
+				AnyString(),
+				AnyStringSlice(),
+				matchers2.AnyMapOfStringToString(),
+				AnyString(),
+				matchers2.AnyPtrToGoVersionVersion(),
+				AnyString())).
+				Then(func(params []Param) ReturnValues {
+					tfArgs := params[3].([]string)
+					cmds = append(cmds, strings.Join(tfArgs[:2], " "))
+					switch {
+					case stringSliceEquals(tfArgs, []string{"workspace", "show"}):
+						return []ReturnValue{"default\n", nil}
+					case stringSliceEquals(tfArgs, []string{"workspace", "list"}):
+						return []ReturnValue{"* default\n  staging\n\n", nil}
+					default:
+						return []ReturnValue{"", nil}
+					}
+				})
+			_, err := s.Run(models.ProjectCommandContext{Workspace: c.workspace, NoWorkspaceAutocreate: true}, nil, "", map[string]string(nil))
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				Equals(t, []string{"workspace show", "workspace list"}, cmds)
+				return
+			}
+			Ok(t, err)
+			Equals(t, []string{"workspace show", "workspace list", "workspace select", "plan -input=false"}, cmds)
+		})
+	}
 }
 
 func TestRun_AddsEnvVarFile(t *testing.T) {
@@ -420,7 +480,7 @@ func TestRun_AddsEnvVarFile(t *testing.T) {
 		"-var-file",
 		envVarsFile,
 	}
-	When(terraform.RunCommandWithVersion(logger, tmpDir, expPlanArgs, map[string]string(nil), tfVersion, "workspace")).ThenReturn("output", nil)
+	When(terraform.RunCommandWithVersion(context.Background(), logger, tmpDir, expPlanArgs, map[string]string(nil), "", tfVersion, "workspace")).ThenReturn("output", nil)
 
 	output, err := s.Run(models.ProjectCommandContext{
 		Log:                logger,
@@ -440,8 +500,8 @@ func TestRun_AddsEnvVarFile(t *testing.T) {
 	Ok(t, err)
 
 	// Verify that env select was never called since we're in version >= 0.10
-	terraform.VerifyWasCalled(Never()).RunCommandWithVersion(logger, tmpDir, []string{"env", "select", "-no-color", "workspace"}, map[string]string(nil), tfVersion, "workspace")
-	terraform.VerifyWasCalledOnce().RunCommandWithVersion(logger, tmpDir, expPlanArgs, map[string]string(nil), tfVersion, "workspace")
+	terraform.VerifyWasCalled(Never()).RunCommandWithVersion(context.Background(), logger, tmpDir, []string{"env", "select", "-no-color", "workspace"}, map[string]string(nil), "", tfVersion, "workspace")
+	terraform.VerifyWasCalledOnce().RunCommandWithVersion(context.Background(), logger, tmpDir, expPlanArgs, map[string]string(nil), "", tfVersion, "workspace")
 	Equals(t, "output", output)
 }
 
@@ -456,7 +516,7 @@ func TestRun_UsesDiffPathForProject(t *testing.T) {
 		TerraformExecutor: terraform,
 		DefaultTFVersion:  tfVersion,
 	}
-	When(terraform.RunCommandWithVersion(logger, "/path", []string{"workspace", "show"}, map[string]string(nil), tfVersion, "workspace")).ThenReturn("workspace\n", nil)
+	When(terraform.RunCommandWithVersion(context.Background(), logger, "/path", []string{"workspace", "show"}, map[string]string(nil), "", tfVersion, "workspace")).ThenReturn("workspace\n", nil)
 
 	expPlanArgs := []string{"plan",
 		"-input=false",
@@ -479,7 +539,7 @@ func TestRun_UsesDiffPathForProject(t *testing.T) {
 		"comment",
 		"args",
 	}
-	When(terraform.RunCommandWithVersion(logger, "/path", expPlanArgs, map[string]string(nil), tfVersion, "default")).ThenReturn("output", nil)
+	When(terraform.RunCommandWithVersion(context.Background(), logger, "/path", expPlanArgs, map[string]string(nil), "", tfVersion, "default")).ThenReturn("output", nil)
 
 	output, err := s.Run(models.ProjectCommandContext{
 		Log:                logger,
@@ -537,16 +597,18 @@ Terraform will perform the following actions:
 		DefaultTFVersion:  tfVersion,
 	}
 	When(terraform.RunCommandWithVersion(
+		matchers2.AnyContextContext(),
 		matchers.AnyPtrToLoggingSimpleLogger(),
 		AnyString(),
 		AnyStringSlice(),
 		matchers2.AnyMapOfStringToString(),
+		AnyString(),
 		matchers2.AnyPtrToGoVersionVersion(),
 		AnyString())).
 		Then(func(params []Param) ReturnValues {
 			// This code allows us to return different values depending on the
 			// tf command being run while still using the wildcard matchers above.
-			tfArgs := params[2].([]string)
+			tfArgs := params[3].([]string)
 			if stringSliceEquals(tfArgs, []string{"workspace", "show"}) {
 				return []ReturnValue{"default", nil}
 			} else if tfArgs[0] == "plan" {
@@ -591,16 +653,18 @@ func TestRun_OutputOnErr(t *testing.T) {
 	expOutput := "expected output"
 	expErrMsg := "error!"
 	When(terraform.RunCommandWithVersion(
+		matchers2.AnyContextContext(),
 		matchers.AnyPtrToLoggingSimpleLogger(),
 		AnyString(),
 		AnyStringSlice(),
 		matchers2.AnyMapOfStringToString(),
+		AnyString(),
 		matchers2.AnyPtrToGoVersionVersion(),
 		AnyString())).
 		Then(func(params []Param) ReturnValues {
 			// This code allows us to return different values depending on the
 			// tf command being run while still using the wildcard matchers above.
-			tfArgs := params[2].([]string)
+			tfArgs := params[3].([]string)
 			if stringSliceEquals(tfArgs, []string{"workspace", "show"}) {
 				return []ReturnValue{"default\n", nil}
 			} else if tfArgs[0] == "plan" {
@@ -651,10 +715,12 @@ func TestRun_NoOptionalVarsIn012(t *testing.T) {
 		t.Run(c.name, func(t *testing.T) {
 			terraform := mocks.NewMockClient()
 			When(terraform.RunCommandWithVersion(
+				matchers2.AnyContextContext(),
 				matchers.AnyPtrToLoggingSimpleLogger(),
 				AnyString(),
 				AnyStringSlice(),
 				matchers2.AnyMapOfStringToString(),
+				AnyString(),
 				matchers2.AnyPtrToGoVersionVersion(),
 				AnyString())).ThenReturn("output", nil)
 
@@ -681,12 +747,91 @@ func TestRun_NoOptionalVarsIn012(t *testing.T) {
 			Ok(t, err)
 			Equals(t, "output", output)
 
-			terraform.VerifyWasCalledOnce().RunCommandWithVersion(nil, "/path", expPlanArgs, map[string]string(nil), tfVersion, "default")
+			terraform.VerifyWasCalledOnce().RunCommandWithVersion(context.Background(), nil, "/path", expPlanArgs, map[string]string(nil), "", tfVersion, "default")
 		})
 	}
 
 }
 
+func TestRun_Destroy(t *testing.T) {
+	RegisterMockTestingT(t)
+	terraform := mocks.NewMockClient()
+	When(terraform.RunCommandWithVersion(
+		matchers2.AnyContextContext(),
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		AnyString(),
+		AnyStringSlice(),
+		matchers2.AnyMapOfStringToString(),
+		AnyString(),
+		matchers2.AnyPtrToGoVersionVersion(),
+		AnyString())).ThenReturn("output", nil)
+
+	tfVersion, _ := version.NewVersion("0.14.0")
+	s := runtime.PlanStepRunner{
+		TerraformExecutor: terraform,
+		DefaultTFVersion:  tfVersion,
+	}
+	_, err := s.Run(models.ProjectCommandContext{
+		Workspace:  "default",
+		RepoRelDir: ".",
+		Destroy:    true,
+	}, []string{"extra", "args"}, "/path", map[string]string(nil))
+	Ok(t, err)
+
+	expPlanArgs := []string{
+		"plan",
+		"-input=false",
+		"-refresh",
+		"-no-color",
+		"-out",
+		fmt.Sprintf("%q", "/path/default.tfplan"),
+		"-destroy",
+		"extra",
+		"args",
+	}
+	terraform.VerifyWasCalledOnce().RunCommandWithVersion(context.Background(), nil, "/path", expPlanArgs, map[string]string(nil), "", tfVersion, "default")
+}
+
+func TestRun_Target(t *testing.T) {
+	RegisterMockTestingT(t)
+	terraform := mocks.NewMockClient()
+	When(terraform.RunCommandWithVersion(
+		matchers2.AnyContextContext(),
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		AnyString(),
+		AnyStringSlice(),
+		matchers2.AnyMapOfStringToString(),
+		AnyString(),
+		matchers2.AnyPtrToGoVersionVersion(),
+		AnyString())).ThenReturn("output", nil)
+
+	tfVersion, _ := version.NewVersion("0.14.0")
+	s := runtime.PlanStepRunner{
+		TerraformExecutor: terraform,
+		DefaultTFVersion:  tfVersion,
+	}
+	_, err := s.Run(models.ProjectCommandContext{
+		Workspace:  "default",
+		RepoRelDir: ".",
+		Targets:    []string{"aws_instance.web", `module.app["a"]`},
+	}, []string{"extra", "args"}, "/path", map[string]string(nil))
+	Ok(t, err)
+
+	expPlanArgs := []string{
+		"plan",
+		"-input=false",
+		"-refresh",
+		"-no-color",
+		"-out",
+		fmt.Sprintf("%q", "/path/default.tfplan"),
+		`-target=\a\w\s\_\i\n\s\t\a\n\c\e\.\w\e\b`,
+		`-target=\m\o\d\u\l\e\.\a\p\p\[\"\a\"\]`,
+		"extra",
+		"args",
+	}
+	terraform.VerifyWasCalledOnce().RunCommandWithVersion(context.Background(), nil, "/path", expPlanArgs, map[string]string(nil), "", tfVersion, "default")
+}
+
 // Test plans if using remote ops.
 func TestRun_RemoteOps(t *testing.T) {
 	cases := map[string]string{
@@ -725,10 +870,12 @@ locally at this time.
 
 			// First, terraform workspace gets run.
 			When(terraform.RunCommandWithVersion(
+				context.Background(),
 				logger,
 				absProjectPath,
 				[]string{"workspace", "show"},
 				map[string]string(nil),
+				"",
 				tfVersion,
 				"default")).ThenReturn("default\n", nil)
 
@@ -758,7 +905,7 @@ locally at this time.
 			planErr := errors.New("exit status 1: err")
 			planOutput := "\n" + remoteOpsErr
 			asyncTf.LinesToSend = remotePlanOutput
-			When(terraform.RunCommandWithVersion(logger, absProjectPath, expPlanArgs, map[string]string(nil), tfVersion, "default")).
+			When(terraform.RunCommandWithVersion(context.Background(), logger, absProjectPath, expPlanArgs, map[string]string(nil), "", tfVersion, "default")).
 				ThenReturn(planOutput, planErr)
 
 			// Now that mocking is set up, we're ready to run the plan.
@@ -890,7 +1037,7 @@ type remotePlanMock struct {
 	CalledArgs []string
 }
 
-func (r *remotePlanMock) RunCommandAsync(log logging.SimpleLogging, path string, args []string, envs map[string]string, v *version.Version, workspace string) (chan<- string, <-chan terraform.Line) {
+func (r *remotePlanMock) RunCommandAsync(ctx context.Context, log logging.SimpleLogging, path string, args []string, envs map[string]string, d string, v *version.Version, workspace string) (chan<- string, <-chan terraform.Line) {
 	r.CalledArgs = args
 	in := make(chan string)
 	out := make(chan terraform.Line)
@@ -950,3 +1097,71 @@ Terraform will perform the following actions:
 
 
 Plan: 0 to add, 0 to change, 1 to destroy.`
+
+func TestRun_UploadsPlanToStorage(t *testing.T) {
+	RegisterMockTestingT(t)
+	terraform := mocks.NewMockClient()
+	storage := psmocks.NewMockPlanStorage()
+	tfVersion, _ := version.NewVersion("0.12.0")
+	logger := logging.NewNoopLogger(t)
+	s := runtime.PlanStepRunner{
+		TerraformExecutor: terraform,
+		DefaultTFVersion:  tfVersion,
+		PlanStorage:       storage,
+	}
+	When(terraform.RunCommandWithVersion(matchers2.AnyContextContext(), matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), matchers2.AnyMapOfStringToString(), AnyString(), matchers2.AnyPtrToGoVersionVersion(), AnyString())).
+		ThenReturn("default\n", nil)
+	ctx := models.ProjectCommandContext{
+		Log:        logger,
+		Workspace:  "default",
+		RepoRelDir: ".",
+	}
+
+	_, err := s.Run(ctx, nil, "/path", map[string]string(nil))
+	Ok(t, err)
+	storage.VerifyWasCalledOnce().Upload(psmatchers.AnyModelsProjectCommandContext(), EqString("/path/default.tfplan"))
+
+	When(storage.Upload(psmatchers.AnyModelsProjectCommandContext(), AnyString())).ThenReturn(errors.New("access denied"))
+	_, err = s.Run(ctx, nil, "/path", map[string]string(nil))
+	ErrEquals(t, "storing planfile: access denied", err)
+}
+
+// Test that the plan is shown as JSON if structured plan output is enabled.
+func TestRun_StructuredPlanOutput(t *testing.T) {
+	RegisterMockTestingT(t)
+	terraform := mocks.NewMockClient()
+	tfVersion, _ := version.NewVersion("0.12.0")
+	logger := logging.NewNoopLogger(t)
+	tmpDir, cleanup := TempDir(t)
+	defer cleanup()
+	s := runtime.PlanStepRunner{
+		TerraformExecutor:    terraform,
+		DefaultTFVersion:     tfVersion,
+		StructuredPlanOutput: true,
+	}
+	showArgs := []string{"show", "-no-color", "-json", filepath.Join(tmpDir, "default.tfplan")}
+	When(terraform.RunCommandWithVersion(matchers2.AnyContextContext(), matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), matchers2.AnyMapOfStringToString(), AnyString(), matchers2.AnyPtrToGoVersionVersion(), AnyString())).
+		ThenReturn("default\n", nil)
+	When(terraform.RunCommandWithVersion(matchers2.AnyContextContext(), matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), EqStringSlice(showArgs), matchers2.AnyMapOfStringToString(), AnyString(), matchers2.AnyPtrToGoVersionVersion(), AnyString())).
+		ThenReturn(`{"resource_changes":[]}`, nil)
+	ctx := models.ProjectCommandContext{
+		Log:        logger,
+		Workspace:  "default",
+		RepoRelDir: ".",
+	}
+
+	output, err := s.Run(ctx, nil, tmpDir, map[string]string(nil))
+	Ok(t, err)
+	Equals(t, "default\n", output)
+	show, err := ioutil.ReadFile(filepath.Join(tmpDir, "default.json"))
+	Ok(t, err)
+	Equals(t, `{"resource_changes":[]}`, string(show))
+
+	// terraform show -json isn't supported before 0.12.
+	s.DefaultTFVersion, _ = version.NewVersion("0.11.14")
+	Ok(t, os.Remove(filepath.Join(tmpDir, "default.json")))
+	_, err = s.Run(ctx, nil, tmpDir, map[string]string(nil))
+	Ok(t, err)
+	_, err = os.Stat(filepath.Join(tmpDir, "default.json"))
+	Assert(t, os.IsNotExist(err), "exp show result to not exist")
+}
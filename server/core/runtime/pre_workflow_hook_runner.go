@@ -1,10 +1,12 @@
 package runtime
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"os/exec"
 
+	"github.com/runatlantis/atlantis/server/core/terraform"
 	"github.com/runatlantis/atlantis/server/events/models"
 )
 
@@ -40,13 +42,20 @@ func (wh DefaultPreWorkflowHookRunner) Run(ctx models.PreWorkflowHookCommandCont
 	}
 
 	cmd.Env = finalEnvVars
-	out, err := cmd.CombinedOutput()
-
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	stop, err := terraform.StartInterruptible(ctx.Context(), ctx.Log, cmd)
+	if err == nil {
+		err = cmd.Wait()
+		stop()
+	}
 	if err != nil {
-		err = fmt.Errorf("%s: running %q in %q: \n%s", err, command, path, out)
+		err = fmt.Errorf("%s: running %q in %q: \n%s", err, command, path, out.String())
 		ctx.Log.Debug("error: %s", err)
 		return "", err
 	}
 	ctx.Log.Info("successfully ran %q in %q", command, path)
-	return string(out), nil
+	return out.String(), nil
 }
@@ -0,0 +1,266 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/core/tfe"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// remoteRunHeaderPrefix follows remoteOpsHeader in planfiles created by
+// RemotePlanStepRunner and is followed by the id of the run that has the plan.
+const remoteRunHeaderPrefix = "Atlantis: TFE run "
+
+// defaultRemoteRunPollInterval is how often the status of runs is checked.
+const defaultRemoteRunPollInterval = 5 * time.Second
+
+func NewRemoteRunStepRunnerDelegate(defaultRunner Runner, remoteRunner Runner) Runner {
+	return &RemoteRunStepRunnerDelegate{
+		defaultRunner: defaultRunner,
+		remoteRunner:  remoteRunner,
+	}
+}
+
+// RemoteRunStepRunnerDelegate delegates to remoteRunner for projects that run
+// in a Terraform Cloud/Enterprise workspace, ie. that set tfe_workspace, and to
+// defaultRunner otherwise.
+type RemoteRunStepRunnerDelegate struct {
+	defaultRunner Runner
+	remoteRunner  Runner
+}
+
+func (r *RemoteRunStepRunnerDelegate) Run(ctx models.ProjectCommandContext, extraArgs []string, path string, envs map[string]string) (string, error) {
+	if ctx.TFEWorkspace != "" {
+		return r.remoteRunner.Run(ctx, extraArgs, path, envs)
+	}
+	return r.defaultRunner.Run(ctx, extraArgs, path, envs)
+}
+
+// RemotePlanStepRunner plans projects by creating a run in their Terraform
+// Cloud/Enterprise workspace instead of running terraform plan locally.
+type RemotePlanStepRunner struct {
+	// TFEClient is nil if --tfe-token isn't set.
+	TFEClient           tfe.Client
+	CommitStatusUpdater StatusUpdater
+	// PollInterval is how often the run's status is checked. Defaults to five
+	// seconds.
+	PollInterval time.Duration
+	// Timeout is how long to wait for the run before it's discarded, ex.
+	// --command-timeout. Zero means there's no limit.
+	Timeout time.Duration
+}
+
+func (p *RemotePlanStepRunner) Run(ctx models.ProjectCommandContext, extraArgs []string, path string, envs map[string]string) (string, error) {
+	if err := checkRemoteRunSupported(p.TFEClient, ctx, extraArgs); err != nil {
+		return "", err
+	}
+
+	// Only one run at a time can wait to be applied in a workspace so the
+	// project's previous plan is discarded to not block the new one.
+	planFile := filepath.Join(path, GetPlanFilename(ctx.Workspace, ctx.ProjectName))
+	if prevRunID, err := readRemoteRunID(planFile); err == nil {
+		if err := p.TFEClient.DiscardRun(prevRunID, "Discarded by Atlantis because the project was planned again."); err != nil {
+			ctx.Log.Debug("unable to discard previous run %s: %s", prevRunID, err)
+		}
+	}
+
+	message := fmt.Sprintf("Planned by Atlantis for %s#%d by %s", ctx.Pull.BaseRepo.FullName, ctx.Pull.Num, ctx.User.Username)
+	run, err := p.TFEClient.CreateRun(ctx.TFEWorkspace, filepath.Clean(path), message, ctx.Destroy)
+	if err != nil {
+		return "", err
+	}
+	ctx.Log.Info("created run %s in %s", run.ID, ctx.TFEWorkspace)
+	updateRemoteRunStatus(ctx, p.CommitStatusUpdater, models.PlanCommand, models.PendingCommitStatus, run.URL)
+
+	run, err = waitForRun(ctx, p.TFEClient, run, pollInterval(p.PollInterval), p.Timeout, tfe.Run.PlanFinished)
+	if err != nil {
+		updateRemoteRunStatus(ctx, p.CommitStatusUpdater, models.PlanCommand, models.FailedCommitStatus, run.URL)
+		return "", err
+	}
+	output, err := p.TFEClient.GetPlanLog(run)
+	if err != nil {
+		updateRemoteRunStatus(ctx, p.CommitStatusUpdater, models.PlanCommand, models.FailedCommitStatus, run.URL)
+		return "", err
+	}
+	output = fmt.Sprintf("%s\nRun: %s", strings.TrimSpace(output), run.URL)
+
+	switch {
+	case run.Confirmable, run.Status == tfe.PlannedAndFinishedStatus:
+	case run.Status == tfe.PolicyOverrideStatus:
+		output += "\n\nSentinel policies failed. The run can't be applied until they're overridden in Terraform Cloud/Enterprise."
+	default:
+		updateRemoteRunStatus(ctx, p.CommitStatusUpdater, models.PlanCommand, models.FailedCommitStatus, run.URL)
+		return "", fmt.Errorf("run %s finished with status %s\n%s", run.ID, run.Status, output)
+	}
+	updateRemoteRunStatus(ctx, p.CommitStatusUpdater, models.PlanCommand, models.SuccessCommitStatus, run.URL)
+
+	// Like with remote ops, the planfile only records the run so Atlantis
+	// knows the project has a plan and what to apply.
+	contents := fmt.Sprintf("%s%s%s\n%s", remoteOpsHeader, remoteRunHeaderPrefix, run.ID, output)
+	if err := ioutil.WriteFile(planFile, []byte(contents), 0600); err != nil {
+		return "", errors.Wrap(err, "unable to create planfile for remote run")
+	}
+	return output, nil
+}
+
+// RemoteApplyStepRunner applies the run created by RemotePlanStepRunner.
+type RemoteApplyStepRunner struct {
+	// TFEClient is nil if --tfe-token isn't set.
+	TFEClient           tfe.Client
+	CommitStatusUpdater StatusUpdater
+	// PollInterval is how often the run's status is checked. Defaults to five
+	// seconds.
+	PollInterval time.Duration
+	// Timeout is how long to wait for the run before it's discarded, ex.
+	// --command-timeout. Zero means there's no limit.
+	Timeout time.Duration
+}
+
+func (a *RemoteApplyStepRunner) Run(ctx models.ProjectCommandContext, extraArgs []string, path string, envs map[string]string) (string, error) {
+	if err := checkRemoteRunSupported(a.TFEClient, ctx, extraArgs); err != nil {
+		return "", err
+	}
+	planFile := filepath.Join(path, GetPlanFilename(ctx.Workspace, ctx.ProjectName))
+	runID, err := readRemoteRunID(planFile)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("no plan found at path %q and workspace %q–did you run plan?", ctx.RepoRelDir, ctx.Workspace)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	run, err := a.TFEClient.GetRun(ctx.TFEWorkspace, runID)
+	if err != nil {
+		return "", err
+	}
+	if run.Status == tfe.PlannedAndFinishedStatus {
+		ctx.Log.Info("run %s has no changes to apply", run.ID)
+		a.removePlanfile(ctx, planFile)
+		return fmt.Sprintf("No changes to apply.\nRun: %s", run.URL), nil
+	}
+	if !run.Confirmable {
+		return "", fmt.Errorf("run %s can't be applied because its status is %s, run plan again\nRun: %s", run.ID, run.Status, run.URL)
+	}
+
+	comment := fmt.Sprintf("Applied by Atlantis for %s#%d by %s", ctx.Pull.BaseRepo.FullName, ctx.Pull.Num, ctx.User.Username)
+	if err := a.TFEClient.ApplyRun(run.ID, comment); err != nil {
+		return "", err
+	}
+	ctx.Log.Info("applying run %s in %s", run.ID, ctx.TFEWorkspace)
+	updateRemoteRunStatus(ctx, a.CommitStatusUpdater, models.ApplyCommand, models.PendingCommitStatus, run.URL)
+
+	run, err = waitForRun(ctx, a.TFEClient, run, pollInterval(a.PollInterval), a.Timeout, tfe.Run.Finished)
+	if err != nil {
+		updateRemoteRunStatus(ctx, a.CommitStatusUpdater, models.ApplyCommand, models.FailedCommitStatus, run.URL)
+		return "", err
+	}
+	output, err := a.TFEClient.GetApplyLog(run)
+	if err != nil {
+		updateRemoteRunStatus(ctx, a.CommitStatusUpdater, models.ApplyCommand, models.FailedCommitStatus, run.URL)
+		return "", err
+	}
+	output = fmt.Sprintf("%s\nRun: %s", strings.TrimSpace(output), run.URL)
+	if run.Status != tfe.AppliedStatus {
+		updateRemoteRunStatus(ctx, a.CommitStatusUpdater, models.ApplyCommand, models.FailedCommitStatus, run.URL)
+		return "", fmt.Errorf("run %s finished with status %s\n%s", run.ID, run.Status, output)
+	}
+	updateRemoteRunStatus(ctx, a.CommitStatusUpdater, models.ApplyCommand, models.SuccessCommitStatus, run.URL)
+	a.removePlanfile(ctx, planFile)
+	return output, nil
+}
+
+func (a *RemoteApplyStepRunner) removePlanfile(ctx models.ProjectCommandContext, planFile string) {
+	if err := os.Remove(planFile); err != nil {
+		ctx.Log.Warn("failed to delete planfile after successful apply: %s", err)
+	}
+}
+
+// checkRemoteRunSupported returns an error if the step can't be run as a
+// remote run.
+func checkRemoteRunSupported(client tfe.Client, ctx models.ProjectCommandContext, extraArgs []string) error {
+	if client == nil {
+		return fmt.Errorf("project sets tfe_workspace %q but --tfe-token isn't set", ctx.TFEWorkspace)
+	}
+	// Remote runs are configured through the API so there's nowhere to pass
+	// terraform flags.
+	if len(extraArgs) > 0 || len(ctx.EscapedCommentArgs) > 0 {
+		return errors.New("extra arguments aren't supported for projects that run in Terraform Cloud/Enterprise")
+	}
+	return nil
+}
+
+// waitForRun polls run until done returns true. Each change of the run's
+// status is logged so its progress can be followed. If the command is
+// cancelled or timeout passes first, the run is discarded.
+func waitForRun(ctx models.ProjectCommandContext, client tfe.Client, run tfe.Run, interval time.Duration, timeout time.Duration, done func(tfe.Run) bool) (tfe.Run, error) {
+	waitCtx := ctx.Context()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(waitCtx, timeout)
+		defer cancel()
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	status := run.Status
+	for !done(run) {
+		select {
+		case <-waitCtx.Done():
+			reason := "the command was cancelled"
+			switch {
+			case ctx.Context().Err() == context.DeadlineExceeded:
+				reason = "the step timed out"
+			case ctx.Context().Err() == nil && waitCtx.Err() == context.DeadlineExceeded:
+				reason = fmt.Sprintf("it timed out after %s", timeout)
+			}
+			if err := client.DiscardRun(run.ID, fmt.Sprintf("Discarded by Atlantis because %s.", reason)); err != nil {
+				ctx.Log.Warn("unable to discard run %s: %s", run.ID, err)
+			}
+			return run, fmt.Errorf("run %s was discarded because %s\nRun: %s", run.ID, reason, run.URL)
+		case <-ticker.C:
+		}
+		updated, err := client.GetRun(ctx.TFEWorkspace, run.ID)
+		if err != nil {
+			return run, err
+		}
+		run = updated
+		if run.Status != status {
+			ctx.Log.Info("run %s is %s", run.ID, run.Status)
+			status = run.Status
+		}
+	}
+	return run, nil
+}
+
+// readRemoteRunID returns the id of the run recorded in planFile.
+func readRemoteRunID(planFile string) (string, error) {
+	contents, err := ioutil.ReadFile(planFile)
+	if err != nil {
+		return "", err
+	}
+	lines := strings.SplitN(string(contents), "\n", 3)
+	if len(lines) < 2 || lines[0]+"\n" != remoteOpsHeader || !strings.HasPrefix(lines[1], remoteRunHeaderPrefix) {
+		return "", errors.New("the plan wasn't created by a Terraform Cloud/Enterprise run, run plan again")
+	}
+	return strings.TrimPrefix(lines[1], remoteRunHeaderPrefix), nil
+}
+
+func updateRemoteRunStatus(ctx models.ProjectCommandContext, updater StatusUpdater, cmdName models.CommandName, status models.CommitStatus, url string) {
+	if err := updater.UpdateProject(ctx, cmdName, status, url); err != nil {
+		ctx.Log.Err("unable to update status: %s", err)
+	}
+}
+
+func pollInterval(interval time.Duration) time.Duration {
+	if interval == 0 {
+		return defaultRemoteRunPollInterval
+	}
+	return interval
+}
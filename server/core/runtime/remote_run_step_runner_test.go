@@ -0,0 +1,241 @@
+package runtime_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/core/runtime"
+	runtimemocks "github.com/runatlantis/atlantis/server/core/runtime/mocks"
+	"github.com/runatlantis/atlantis/server/core/tfe"
+	tfemocks "github.com/runatlantis/atlantis/server/core/tfe/mocks"
+	mocks2 "github.com/runatlantis/atlantis/server/events/mocks"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+const remoteRunURL = "https://app.terraform.io/app/org/workspaces/ws/runs/run-1"
+
+func remoteRunCtx(t *testing.T) models.ProjectCommandContext {
+	return models.ProjectCommandContext{
+		Log:          logging.NewNoopLogger(t),
+		Workspace:    "default",
+		RepoRelDir:   ".",
+		TFEWorkspace: "org/ws",
+		User:         models.User{Username: "lkysow"},
+		Pull:         models.PullRequest{Num: 2, BaseRepo: models.Repo{FullName: "owner/repo"}},
+	}
+}
+
+func TestRemoteRunStepRunnerDelegate(t *testing.T) {
+	RegisterMockTestingT(t)
+	defaultRunner := runtimemocks.NewMockRunner()
+	remoteRunner := runtimemocks.NewMockRunner()
+	subject := runtime.NewRemoteRunStepRunnerDelegate(defaultRunner, remoteRunner)
+
+	ctx := remoteRunCtx(t)
+	When(remoteRunner.Run(ctx, nil, "path", nil)).ThenReturn("remote", nil)
+	out, err := subject.Run(ctx, nil, "path", nil)
+	Ok(t, err)
+	Equals(t, "remote", out)
+
+	ctx.TFEWorkspace = ""
+	When(defaultRunner.Run(ctx, nil, "path", nil)).ThenReturn("default", nil)
+	out, err = subject.Run(ctx, nil, "path", nil)
+	Ok(t, err)
+	Equals(t, "default", out)
+}
+
+func TestRemotePlanStepRunner_Run(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	planPath := filepath.Join(tmp, "default.tfplan")
+	Ok(t, ioutil.WriteFile(planPath, []byte("Atlantis: this plan was created by remote ops\nAtlantis: TFE run run-0\nold plan"), 0600))
+
+	client := tfemocks.NewMockClient()
+	updater := mocks2.NewMockCommitStatusUpdater()
+	ctx := remoteRunCtx(t)
+	run := tfe.Run{ID: "run-1", Status: "pending", URL: remoteRunURL, PlanID: "plan-1"}
+	plannedRun := tfe.Run{ID: "run-1", Status: "planned", URL: remoteRunURL, PlanID: "plan-1", Confirmable: true}
+	When(client.CreateRun("org/ws", tmp, "Planned by Atlantis for owner/repo#2 by lkysow", false)).ThenReturn(run, nil)
+	When(client.GetRun("org/ws", "run-1")).
+		ThenReturn(tfe.Run{ID: "run-1", Status: "planning", URL: remoteRunURL, PlanID: "plan-1"}, nil).
+		ThenReturn(plannedRun, nil)
+	When(client.GetPlanLog(plannedRun)).ThenReturn("Plan: 1 to add, 0 to change, 0 to destroy.\n", nil)
+
+	subject := runtime.RemotePlanStepRunner{
+		TFEClient:           client,
+		CommitStatusUpdater: updater,
+		PollInterval:        1,
+	}
+	out, err := subject.Run(ctx, nil, tmp, nil)
+	Ok(t, err)
+	Equals(t, "Plan: 1 to add, 0 to change, 0 to destroy.\nRun: "+remoteRunURL, out)
+
+	client.VerifyWasCalledOnce().DiscardRun("run-0", "Discarded by Atlantis because the project was planned again.")
+	updater.VerifyWasCalledOnce().UpdateProject(ctx, models.PlanCommand, models.PendingCommitStatus, remoteRunURL)
+	updater.VerifyWasCalledOnce().UpdateProject(ctx, models.PlanCommand, models.SuccessCommitStatus, remoteRunURL)
+	contents, err := ioutil.ReadFile(planPath)
+	Ok(t, err)
+	Equals(t, "Atlantis: this plan was created by remote ops\nAtlantis: TFE run run-1\n"+out, string(contents))
+	Assert(t, runtime.IsRemotePlan(contents), "exp planfile to be a remote plan so show and policy_check skip it")
+}
+
+func TestRemotePlanStepRunner_RunErrored(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+
+	client := tfemocks.NewMockClient()
+	updater := mocks2.NewMockCommitStatusUpdater()
+	ctx := remoteRunCtx(t)
+	erroredRun := tfe.Run{ID: "run-1", Status: tfe.ErroredStatus, URL: remoteRunURL, PlanID: "plan-1"}
+	When(client.CreateRun("org/ws", tmp, "Planned by Atlantis for owner/repo#2 by lkysow", false)).ThenReturn(erroredRun, nil)
+	When(client.GetPlanLog(erroredRun)).ThenReturn("Error: bad config", nil)
+
+	subject := runtime.RemotePlanStepRunner{
+		TFEClient:           client,
+		CommitStatusUpdater: updater,
+		PollInterval:        1,
+	}
+	_, err := subject.Run(ctx, nil, tmp, nil)
+	ErrEquals(t, "run run-1 finished with status errored\nError: bad config\nRun: "+remoteRunURL, err)
+	updater.VerifyWasCalledOnce().UpdateProject(ctx, models.PlanCommand, models.FailedCommitStatus, remoteRunURL)
+	_, err = os.Stat(filepath.Join(tmp, "default.tfplan"))
+	Assert(t, os.IsNotExist(err), "exp no planfile")
+}
+
+func TestRemotePlanStepRunner_TimedOut(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+
+	client := tfemocks.NewMockClient()
+	updater := mocks2.NewMockCommitStatusUpdater()
+	ctx := remoteRunCtx(t)
+	run := tfe.Run{ID: "run-1", Status: "planning", URL: remoteRunURL, PlanID: "plan-1"}
+	When(client.CreateRun("org/ws", tmp, "Planned by Atlantis for owner/repo#2 by lkysow", false)).ThenReturn(run, nil)
+	When(client.GetRun("org/ws", "run-1")).ThenReturn(run, nil)
+
+	subject := runtime.RemotePlanStepRunner{
+		TFEClient:           client,
+		CommitStatusUpdater: updater,
+		PollInterval:        time.Millisecond,
+		Timeout:             50 * time.Millisecond,
+	}
+	_, err := subject.Run(ctx, nil, tmp, nil)
+	ErrEquals(t, "run run-1 was discarded because it timed out after 50ms\nRun: "+remoteRunURL, err)
+	client.VerifyWasCalledOnce().DiscardRun("run-1", "Discarded by Atlantis because it timed out after 50ms.")
+	updater.VerifyWasCalledOnce().UpdateProject(ctx, models.PlanCommand, models.FailedCommitStatus, remoteRunURL)
+	_, err = os.Stat(filepath.Join(tmp, "default.tfplan"))
+	Assert(t, os.IsNotExist(err), "exp no planfile")
+}
+
+func TestRemotePlanStepRunner_Unsupported(t *testing.T) {
+	RegisterMockTestingT(t)
+	ctx := remoteRunCtx(t)
+
+	subject := runtime.RemotePlanStepRunner{}
+	_, err := subject.Run(ctx, nil, "path", nil)
+	ErrEquals(t, `project sets tfe_workspace "org/ws" but --tfe-token isn't set`, err)
+
+	subject.TFEClient = tfemocks.NewMockClient()
+	_, err = subject.Run(ctx, []string{"-var", "a=b"}, "path", nil)
+	ErrEquals(t, "extra arguments aren't supported for projects that run in Terraform Cloud/Enterprise", err)
+}
+
+func TestRemoteApplyStepRunner_Run(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	planPath := filepath.Join(tmp, "default.tfplan")
+	Ok(t, ioutil.WriteFile(planPath, []byte("Atlantis: this plan was created by remote ops\nAtlantis: TFE run run-1\nplan"), 0600))
+
+	client := tfemocks.NewMockClient()
+	updater := mocks2.NewMockCommitStatusUpdater()
+	ctx := remoteRunCtx(t)
+	appliedRun := tfe.Run{ID: "run-1", Status: tfe.AppliedStatus, URL: remoteRunURL, ApplyID: "apply-1"}
+	When(client.GetRun("org/ws", "run-1")).
+		ThenReturn(tfe.Run{ID: "run-1", Status: "planned", URL: remoteRunURL, ApplyID: "apply-1", Confirmable: true}, nil).
+		ThenReturn(tfe.Run{ID: "run-1", Status: "applying", URL: remoteRunURL, ApplyID: "apply-1"}, nil).
+		ThenReturn(appliedRun, nil)
+	When(client.GetApplyLog(appliedRun)).ThenReturn("Apply complete! Resources: 1 added, 0 changed, 0 destroyed.", nil)
+
+	subject := runtime.RemoteApplyStepRunner{
+		TFEClient:           client,
+		CommitStatusUpdater: updater,
+		PollInterval:        1,
+	}
+	out, err := subject.Run(ctx, nil, tmp, nil)
+	Ok(t, err)
+	Equals(t, "Apply complete! Resources: 1 added, 0 changed, 0 destroyed.\nRun: "+remoteRunURL, out)
+	client.VerifyWasCalledOnce().ApplyRun("run-1", "Applied by Atlantis for owner/repo#2 by lkysow")
+	updater.VerifyWasCalledOnce().UpdateProject(ctx, models.ApplyCommand, models.SuccessCommitStatus, remoteRunURL)
+	_, err = os.Stat(planPath)
+	Assert(t, os.IsNotExist(err), "exp planfile to be deleted")
+}
+
+func TestRemoteApplyStepRunner_NotApplyable(t *testing.T) {
+	cases := []struct {
+		description string
+		run         tfe.Run
+		expOut      string
+		expErr      string
+	}{
+		{
+			description: "no changes",
+			run:         tfe.Run{ID: "run-1", Status: tfe.PlannedAndFinishedStatus, URL: remoteRunURL},
+			expOut:      "No changes to apply.\nRun: " + remoteRunURL,
+		},
+		{
+			description: "discarded",
+			run:         tfe.Run{ID: "run-1", Status: "discarded", URL: remoteRunURL},
+			expErr:      "run run-1 can't be applied because its status is discarded, run plan again\nRun: " + remoteRunURL,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			tmp, cleanup := TempDir(t)
+			defer cleanup()
+			Ok(t, ioutil.WriteFile(filepath.Join(tmp, "default.tfplan"), []byte("Atlantis: this plan was created by remote ops\nAtlantis: TFE run run-1\nplan"), 0600))
+
+			client := tfemocks.NewMockClient()
+			When(client.GetRun("org/ws", "run-1")).ThenReturn(c.run, nil)
+			subject := runtime.RemoteApplyStepRunner{
+				TFEClient:           client,
+				CommitStatusUpdater: mocks2.NewMockCommitStatusUpdater(),
+			}
+			out, err := subject.Run(remoteRunCtx(t), nil, tmp, nil)
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+			} else {
+				Ok(t, err)
+			}
+			Equals(t, c.expOut, out)
+			client.VerifyWasCalled(Never()).ApplyRun(AnyString(), AnyString())
+		})
+	}
+}
+
+func TestRemoteApplyStepRunner_LocalPlan(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	Ok(t, ioutil.WriteFile(filepath.Join(tmp, "default.tfplan"), []byte("a local planfile"), 0600))
+
+	updater := mocks2.NewMockCommitStatusUpdater()
+	subject := runtime.RemoteApplyStepRunner{
+		TFEClient:           tfemocks.NewMockClient(),
+		CommitStatusUpdater: updater,
+	}
+	_, err := subject.Run(remoteRunCtx(t), nil, tmp, nil)
+	ErrEquals(t, "the plan wasn't created by a Terraform Cloud/Enterprise run, run plan again", err)
+	updater.VerifyWasCalled(Never()).UpdateProject(matchers.AnyModelsProjectCommandContext(), matchers.AnyModelsCommandName(), matchers.AnyModelsCommitStatus(), AnyString())
+}
@@ -1,6 +1,7 @@
 package runtime
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"os/exec"
@@ -8,54 +9,82 @@ import (
 	"strings"
 
 	"github.com/hashicorp/go-version"
+	"github.com/runatlantis/atlantis/server/core/terraform"
 	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 )
 
 // RunStepRunner runs custom commands.
 type RunStepRunner struct {
 	TerraformExecutor TerraformExec
 	DefaultTFVersion  *version.Version
+	// DefaultTFDistribution is the distribution of terraform, ex. opentofu,
+	// used by projects that don't set one.
+	DefaultTFDistribution string
 	// TerraformBinDir is the directory where Atlantis downloads Terraform binaries.
 	TerraformBinDir string
+	// Secrets are the secrets commands can reference as ${secrets.NAME}.
+	Secrets map[string]valid.Secret
 }
 
+// Run runs command in path. The values of secrets are redacted from its
+// output.
 func (r *RunStepRunner) Run(ctx models.ProjectCommandContext, command string, path string, envs map[string]string) (string, error) {
+	out, err := r.RunUnredacted(ctx, command, path, envs)
+	return redactSecrets(out, r.Secrets), err
+}
+
+// RunUnredacted is like Run except the values of secrets aren't redacted from
+// the output. It must only be used when the output isn't shown to users, ex.
+// to set environment variables. Errors are still redacted.
+func (r *RunStepRunner) RunUnredacted(ctx models.ProjectCommandContext, command string, path string, envs map[string]string) (string, error) {
 	tfVersion := r.DefaultTFVersion
 	if ctx.TerraformVersion != nil {
 		tfVersion = ctx.TerraformVersion
 	}
+	tfDistribution := r.DefaultTFDistribution
+	if ctx.TerraformDistribution != "" {
+		tfDistribution = ctx.TerraformDistribution
+	}
 
-	err := r.TerraformExecutor.EnsureVersion(ctx.Log, tfVersion)
+	err := r.TerraformExecutor.EnsureVersion(ctx.Log, tfDistribution, tfVersion)
 	if err != nil {
-		err = fmt.Errorf("%s: Downloading terraform Version %s", err, tfVersion.String())
+		err = fmt.Errorf("%s: Downloading %s Version %s", err, tfDistribution, tfVersion.String())
 		ctx.Log.Debug("error: %s", err)
 		return "", err
 	}
 
-	cmd := exec.Command("sh", "-c", command) // #nosec
+	substituted, secretEnvs, err := substituteSecrets(command, r.Secrets)
+	if err != nil {
+		ctx.Log.Debug("error: %s", err)
+		return "", err
+	}
+
+	cmd := exec.Command("sh", "-c", substituted) // #nosec
 	cmd.Dir = path
 
 	baseEnvVars := os.Environ()
 	customEnvVars := map[string]string{
-		"ATLANTIS_TERRAFORM_VERSION": tfVersion.String(),
-		"BASE_BRANCH_NAME":           ctx.Pull.BaseBranch,
-		"BASE_REPO_NAME":             ctx.BaseRepo.Name,
-		"BASE_REPO_OWNER":            ctx.BaseRepo.Owner,
-		"COMMENT_ARGS":               strings.Join(ctx.EscapedCommentArgs, ","),
-		"DIR":                        path,
-		"HEAD_BRANCH_NAME":           ctx.Pull.HeadBranch,
-		"HEAD_COMMIT":                ctx.Pull.HeadCommit,
-		"HEAD_REPO_NAME":             ctx.HeadRepo.Name,
-		"HEAD_REPO_OWNER":            ctx.HeadRepo.Owner,
-		"PATH":                       fmt.Sprintf("%s:%s", os.Getenv("PATH"), r.TerraformBinDir),
-		"PLANFILE":                   filepath.Join(path, GetPlanFilename(ctx.Workspace, ctx.ProjectName)),
-		"SHOWFILE":                   filepath.Join(path, ctx.GetShowResultFileName()),
-		"PROJECT_NAME":               ctx.ProjectName,
-		"PULL_AUTHOR":                ctx.Pull.Author,
-		"PULL_NUM":                   fmt.Sprintf("%d", ctx.Pull.Num),
-		"REPO_REL_DIR":               ctx.RepoRelDir,
-		"USER_NAME":                  ctx.User.Username,
-		"WORKSPACE":                  ctx.Workspace,
+		"ATLANTIS_TERRAFORM_VERSION":      tfVersion.String(),
+		"ATLANTIS_TERRAFORM_DISTRIBUTION": tfDistribution,
+		"BASE_BRANCH_NAME":                ctx.Pull.BaseBranch,
+		"BASE_REPO_NAME":                  ctx.BaseRepo.Name,
+		"BASE_REPO_OWNER":                 ctx.BaseRepo.Owner,
+		"COMMENT_ARGS":                    strings.Join(ctx.EscapedCommentArgs, ","),
+		"DIR":                             path,
+		"HEAD_BRANCH_NAME":                ctx.Pull.HeadBranch,
+		"HEAD_COMMIT":                     ctx.Pull.HeadCommit,
+		"HEAD_REPO_NAME":                  ctx.HeadRepo.Name,
+		"HEAD_REPO_OWNER":                 ctx.HeadRepo.Owner,
+		"PATH":                            fmt.Sprintf("%s:%s", os.Getenv("PATH"), r.TerraformBinDir),
+		"PLANFILE":                        filepath.Join(path, GetPlanFilename(ctx.Workspace, ctx.ProjectName)),
+		"SHOWFILE":                        filepath.Join(path, ctx.GetShowResultFileName()),
+		"PROJECT_NAME":                    ctx.ProjectName,
+		"PULL_AUTHOR":                     ctx.Pull.Author,
+		"PULL_NUM":                        fmt.Sprintf("%d", ctx.Pull.Num),
+		"REPO_REL_DIR":                    ctx.RepoRelDir,
+		"USER_NAME":                       ctx.User.Username,
+		"WORKSPACE":                       ctx.Workspace,
 	}
 
 	finalEnvVars := baseEnvVars
@@ -65,14 +94,27 @@ func (r *RunStepRunner) Run(ctx models.ProjectCommandContext, command string, pa
 	for key, val := range envs {
 		finalEnvVars = append(finalEnvVars, fmt.Sprintf("%s=%s", key, val))
 	}
+	for key, val := range secretEnvs {
+		finalEnvVars = append(finalEnvVars, fmt.Sprintf("%s=%s", key, val))
+	}
 	cmd.Env = finalEnvVars
-	out, err := cmd.CombinedOutput()
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
 
+	// The command is interrupted if the command it's part of is cancelled.
+	stop, err := terraform.StartInterruptible(ctx.Context(), ctx.Log, cmd)
+	if err == nil {
+		err = cmd.Wait()
+		stop()
+	}
 	if err != nil {
-		err = fmt.Errorf("%s: running %q in %q: \n%s", err, command, path, out)
+		// We log and return the command as written, not the substituted
+		// command, and redact the output since errors are commented back.
+		err = fmt.Errorf("%s: running %q in %q: \n%s", err, command, path, redactSecrets(out.String(), r.Secrets))
 		ctx.Log.Debug("error: %s", err)
 		return "", err
 	}
 	ctx.Log.Info("successfully ran %q in %q", command, path)
-	return string(out), nil
+	return out.String(), nil
 }
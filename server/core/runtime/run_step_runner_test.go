@@ -1,10 +1,14 @@
 package runtime_test
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	version "github.com/hashicorp/go-version"
 	. "github.com/petergtz/pegomock"
@@ -13,6 +17,7 @@ import (
 	matchers2 "github.com/runatlantis/atlantis/server/core/terraform/mocks/matchers"
 	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
 	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 	"github.com/runatlantis/atlantis/server/logging"
 	. "github.com/runatlantis/atlantis/testing"
 )
@@ -24,6 +29,8 @@ func TestRunStepRunner_Run(t *testing.T) {
 		ExpOut      string
 		ExpErr      string
 		Version     string
+		// Distribution is the project's terraform distribution.
+		Distribution string
 	}{
 		{
 			Command: "",
@@ -75,6 +82,15 @@ func TestRunStepRunner_Run(t *testing.T) {
 			Command: "echo $PATH",
 			ExpOut:  fmt.Sprintf("%s:%s\n", os.Getenv("PATH"), "/bin/dir"),
 		},
+		{
+			Command: "echo distribution=$ATLANTIS_TERRAFORM_DISTRIBUTION",
+			ExpOut:  "distribution=terraform\n",
+		},
+		{
+			Command:      "echo distribution=$ATLANTIS_TERRAFORM_DISTRIBUTION",
+			ExpOut:       "distribution=opentofu\n",
+			Distribution: "opentofu",
+		},
 		{
 			Command: "echo args=$COMMENT_ARGS",
 			ExpOut:  "args=-target=resource1,-target=resource2\n",
@@ -99,15 +115,20 @@ func TestRunStepRunner_Run(t *testing.T) {
 
 		RegisterMockTestingT(t)
 		terraform := mocks.NewMockClient()
-		When(terraform.EnsureVersion(matchers.AnyPtrToLoggingSimpleLogger(), matchers2.AnyPtrToGoVersionVersion())).
+		When(terraform.EnsureVersion(matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), matchers2.AnyPtrToGoVersionVersion())).
 			ThenReturn(nil)
 
 		logger := logging.NewNoopLogger(t)
 
 		r := runtime.RunStepRunner{
-			TerraformExecutor: terraform,
-			DefaultTFVersion:  defaultVersion,
-			TerraformBinDir:   "/bin/dir",
+			TerraformExecutor:     terraform,
+			DefaultTFVersion:      defaultVersion,
+			DefaultTFDistribution: "terraform",
+			TerraformBinDir:       "/bin/dir",
+		}
+		expDistribution := "terraform"
+		if c.Distribution != "" {
+			expDistribution = c.Distribution
 		}
 		t.Run(c.Command, func(t *testing.T) {
 			tmpDir, cleanup := TempDir(t)
@@ -131,12 +152,13 @@ func TestRunStepRunner_Run(t *testing.T) {
 				User: models.User{
 					Username: "acme-user",
 				},
-				Log:                logger,
-				Workspace:          "myworkspace",
-				RepoRelDir:         "mydir",
-				TerraformVersion:   projVersion,
-				ProjectName:        c.ProjectName,
-				EscapedCommentArgs: []string{"-target=resource1", "-target=resource2"},
+				Log:                   logger,
+				Workspace:             "myworkspace",
+				RepoRelDir:            "mydir",
+				TerraformVersion:      projVersion,
+				TerraformDistribution: c.Distribution,
+				ProjectName:           c.ProjectName,
+				EscapedCommentArgs:    []string{"-target=resource1", "-target=resource2"},
 			}
 			out, err := r.Run(ctx, c.Command, tmpDir, map[string]string{"test": "var"})
 			if c.ExpErr != "" {
@@ -150,9 +172,115 @@ func TestRunStepRunner_Run(t *testing.T) {
 			expOut := strings.Replace(c.ExpOut, "$DIR", tmpDir, -1)
 			Equals(t, expOut, out)
 
-			terraform.VerifyWasCalledOnce().EnsureVersion(logger, projVersion)
-			terraform.VerifyWasCalled(Never()).EnsureVersion(logger, defaultVersion)
+			terraform.VerifyWasCalledOnce().EnsureVersion(logger, expDistribution, projVersion)
+			terraform.VerifyWasCalled(Never()).EnsureVersion(logger, expDistribution, defaultVersion)
+
+		})
+	}
+}
+
+func TestRunStepRunner_Secrets(t *testing.T) {
+	RegisterMockTestingT(t)
+	terraform := mocks.NewMockClient()
+	When(terraform.EnsureVersion(matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), matchers2.AnyPtrToGoVersionVersion())).
+		ThenReturn(nil)
+	defaultVersion, _ := version.NewVersion("0.8")
 
+	tmpDir, cleanup := TempDir(t)
+	defer cleanup()
+	secretFile := filepath.Join(tmpDir, "token")
+	Ok(t, ioutil.WriteFile(secretFile, []byte("file-secret\n"), 0600))
+	Ok(t, os.Setenv("ATLANTIS_TEST_SECRET", "env-secret"))
+	defer os.Unsetenv("ATLANTIS_TEST_SECRET") // nolint: errcheck
+
+	r := runtime.RunStepRunner{
+		TerraformExecutor: terraform,
+		DefaultTFVersion:  defaultVersion,
+		Secrets: map[string]valid.Secret{
+			"ENV_SECRET":     {Env: "ATLANTIS_TEST_SECRET"},
+			"FILE_SECRET":    {File: secretFile},
+			"MISSING_SECRET": {File: filepath.Join(tmpDir, "missing")},
+		},
+	}
+	ctx := models.ProjectCommandContext{
+		Log:        logging.NewNoopLogger(t),
+		Workspace:  "default",
+		RepoRelDir: ".",
+	}
+
+	cases := []struct {
+		description string
+		command     string
+		expOut      string
+		expErr      string
+	}{
+		{
+			description: "secrets are passed to the command and redacted from the output",
+			command:     "echo ${secrets.ENV_SECRET} ${secrets.FILE_SECRET} | tr a-z A-Z && echo ${secrets.FILE_SECRET}",
+			expOut:      "ENV-SECRET FILE-SECRET\n[REDACTED]\n",
+		},
+		{
+			description: "secrets that aren't referenced are still redacted",
+			command:     "echo $ATLANTIS_TEST_SECRET",
+			expOut:      "[REDACTED]\n",
+		},
+		{
+			description: "secrets are passed as environment variables",
+			command:     "echo ${secrets.ENV_SECRET} >/dev/null && env | grep -c ^ATLANTIS_SECRET_ENV_SECRET=",
+			expOut:      "1\n",
+		},
+		{
+			description: "secrets are redacted from errors",
+			command:     "echo ${secrets.ENV_SECRET} && exit 1",
+			expErr:      "exit status 1: running \"echo ${secrets.ENV_SECRET} && exit 1\" in \".\": \n[REDACTED]\n",
+		},
+		{
+			description: "undefined secret",
+			command:     "echo ${secrets.UNDEFINED}",
+			expErr:      "secret \"UNDEFINED\" is not defined in the server-side repo config",
+		},
+		{
+			description: "unreadable secret",
+			command:     "echo ${secrets.MISSING_SECRET}",
+			expErr:      "reading secret \"MISSING_SECRET\": open " + filepath.Join(tmpDir, "missing") + ": no such file or directory",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			out, err := r.Run(ctx, c.command, ".", nil)
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			Equals(t, c.expOut, out)
 		})
 	}
 }
+
+// Cancelling the command a run step is part of should interrupt the step.
+func TestRunStepRunner_Cancelled(t *testing.T) {
+	RegisterMockTestingT(t)
+	terraform := mocks.NewMockClient()
+	When(terraform.EnsureVersion(matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), matchers2.AnyPtrToGoVersionVersion())).
+		ThenReturn(nil)
+	defaultVersion, _ := version.NewVersion("0.8")
+	r := runtime.RunStepRunner{
+		TerraformExecutor: terraform,
+		DefaultTFVersion:  defaultVersion,
+	}
+
+	reqCtx, cancel := context.WithCancel(context.Background())
+	ctx := models.ProjectCommandContext{
+		Log:        logging.NewNoopLogger(t),
+		Workspace:  "default",
+		RepoRelDir: ".",
+		RequestCtx: reqCtx,
+	}
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := r.Run(ctx, "sleep 30", ".", nil)
+	ErrContains(t, "signal: interrupt", err)
+	Assert(t, time.Since(start) < 10*time.Second, "expected the step to be interrupted, it ran for %s", time.Since(start))
+}
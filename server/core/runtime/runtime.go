@@ -4,7 +4,10 @@ package runtime
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 
@@ -25,8 +28,8 @@ const (
 // TerraformExec brings the interface from TerraformClient into this package
 // without causing circular imports.
 type TerraformExec interface {
-	RunCommandWithVersion(log logging.SimpleLogging, path string, args []string, envs map[string]string, v *version.Version, workspace string) (string, error)
-	EnsureVersion(log logging.SimpleLogging, v *version.Version) error
+	RunCommandWithVersion(ctx context.Context, log logging.SimpleLogging, path string, args []string, envs map[string]string, d string, v *version.Version, workspace string) (string, error)
+	EnsureVersion(log logging.SimpleLogging, d string, v *version.Version) error
 }
 
 // AsyncTFExec brings the interface from TerraformClient into this package
@@ -40,7 +43,8 @@ type AsyncTFExec interface {
 	// Callers can use the input channel to pass stdin input to the command.
 	// If any error is passed on the out channel, there will be no
 	// further output (so callers are free to exit).
-	RunCommandAsync(log logging.SimpleLogging, path string, args []string, envs map[string]string, v *version.Version, workspace string) (chan<- string, <-chan terraform.Line)
+	// If ctx is cancelled, terraform is interrupted.
+	RunCommandAsync(ctx context.Context, log logging.SimpleLogging, path string, args []string, envs map[string]string, d string, v *version.Version, workspace string) (chan<- string, <-chan terraform.Line)
 }
 
 // StatusUpdater brings the interface from CommitStatusUpdater into this package
@@ -74,6 +78,16 @@ func GetPlanFilename(workspace string, projName string) string {
 	return fmt.Sprintf("%s-%s.tfplan", projName, workspace)
 }
 
+// removeStalePlanfile deletes the planfile for ctx in path, if there is one.
+// It's used after the state has been modified since the plan was generated
+// against the old state.
+func removeStalePlanfile(ctx models.ProjectCommandContext, path string) {
+	planPath := filepath.Join(path, GetPlanFilename(ctx.Workspace, ctx.ProjectName))
+	if err := os.Remove(planPath); err != nil && !os.IsNotExist(err) {
+		ctx.Log.Warn("failed to delete stale planfile: %s", err)
+	}
+}
+
 // isRemotePlan returns true if planContents are from a plan that was generated
 // using TFE remote operations.
 func IsRemotePlan(planContents []byte) bool {
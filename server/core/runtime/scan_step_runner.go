@@ -0,0 +1,259 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	goruntime "runtime"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/core/runtime/cache"
+	runtime_models "github.com/runatlantis/atlantis/server/core/runtime/models"
+	"github.com/runatlantis/atlantis/server/core/terraform"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+const (
+	// DefaultTfsecVersion is the version of tfsec scan steps download if they
+	// don't set one.
+	DefaultTfsecVersion = "1.28.1"
+	// DefaultCheckovVersion is the version of checkov scan steps download if
+	// they don't set one.
+	DefaultCheckovVersion = "2.3.0"
+)
+
+// scanner is a security scanner scan steps can run.
+type scanner struct {
+	defaultVersion *version.Version
+	// binaryPath is the path of the binary in the scanner's release archive.
+	binaryPath  string
+	downloadURL func(v *version.Version) string
+	// args are the args that make the scanner output its findings for the
+	// current dir as JSON without failing because of them.
+	args  []string
+	parse func(output string, path string) ([]models.ScanFinding, error)
+}
+
+var scanners = map[string]scanner{
+	"tfsec": {
+		defaultVersion: version.Must(version.NewVersion(DefaultTfsecVersion)),
+		binaryPath:     "tfsec",
+		downloadURL: func(v *version.Version) string {
+			prefix := fmt.Sprintf("https://github.com/aquasecurity/tfsec/releases/download/v%s", v.Original())
+			return fmt.Sprintf("%s/tfsec_%s_%s_%s.tar.gz?checksum=file:%s/tfsec_%s_checksums.txt", prefix, v.Original(), goruntime.GOOS, goruntime.GOARCH, prefix, v.Original())
+		},
+		args:  []string{".", "--format", "json", "--no-color", "--soft-fail"},
+		parse: parseTfsecOutput,
+	},
+	"checkov": {
+		defaultVersion: version.Must(version.NewVersion(DefaultCheckovVersion)),
+		binaryPath:     filepath.Join("dist", "checkov"),
+		downloadURL: func(v *version.Version) string {
+			arch := "X86_64"
+			if goruntime.GOARCH == "arm64" {
+				arch = "arm64"
+			}
+			// Each release zip has a sha256sum file alongside it.
+			url := fmt.Sprintf("https://github.com/bridgecrewio/checkov/releases/download/%s/checkov_%s_%s.zip", v.Original(), goruntime.GOOS, arch)
+			return fmt.Sprintf("%s?checksum=file:%s.sha256", url, url)
+		},
+		args:  []string{"-d", ".", "--framework", "terraform", "-o", "json", "--quiet", "--soft-fail"},
+		parse: parseCheckovOutput,
+	},
+}
+
+// ScanStepRunner runs a security scanner on a project and writes its findings
+// to a json file so they can be shown in the plan comment.
+type ScanStepRunner struct {
+	Exec runtime_models.Exec
+	// VersionCaches map scanners to the cache of their downloaded versions.
+	VersionCaches map[string]cache.ExecutionVersionCache
+}
+
+// NewScanStepRunner returns a ScanStepRunner that downloads scanners into
+// binDir.
+func NewScanStepRunner(binDir string, downloader terraform.Downloader) *ScanStepRunner {
+	caches := make(map[string]cache.ExecutionVersionCache)
+	for tool, s := range scanners {
+		tool, s := tool, s
+		caches[tool] = cache.NewExecutionVersionLayeredLoadingCache(tool, binDir, func(v *version.Version, destPath string) (runtime_models.FilePath, error) {
+			url := s.downloadURL(v)
+			if err := downloader.GetAny(destPath, url); err != nil {
+				return runtime_models.LocalFilePath(""), errors.Wrapf(err, "downloading %s version %s at %q", tool, v.String(), url)
+			}
+			return runtime_models.LocalFilePath(filepath.Join(destPath, s.binaryPath)), nil
+		})
+	}
+	return &ScanStepRunner{
+		Exec:          runtime_models.LocalExec{},
+		VersionCaches: caches,
+	}
+}
+
+func (s *ScanStepRunner) Run(ctx models.ProjectCommandContext, opts valid.ScanOptions, extraArgs []string, path string, envs map[string]string) (string, error) {
+	scanner, ok := scanners[opts.Tool]
+	if !ok {
+		return "", fmt.Errorf("%q is not a supported scanner", opts.Tool)
+	}
+	v := opts.Version
+	if v == nil {
+		v = scanner.defaultVersion
+	}
+	binPath, err := s.VersionCaches[opts.Tool].Get(v)
+	if err != nil {
+		return "", errors.Wrapf(err, "getting %s version %s", opts.Tool, v.String())
+	}
+
+	args := append(append([]string{binPath}, scanner.args...), extraArgs...)
+	out, err := s.Exec.CombinedOutput(args, envs, path)
+	if err != nil {
+		return out, errors.Wrapf(err, "running %s", opts.Tool)
+	}
+	findings, err := scanner.parse(out, path)
+	if err != nil {
+		return out, errors.Wrapf(err, "parsing %s output", opts.Tool)
+	}
+	ctx.Log.Info("%s found %d issues", opts.Tool, len(findings))
+
+	// A workflow can have more than one scan step so their results are
+	// appended to the ones of the steps before.
+	resultsFile := filepath.Join(path, ctx.GetScanResultsFileName())
+	var results []models.ScanResult
+	if data, err := ioutil.ReadFile(resultsFile); err == nil {
+		if err := json.Unmarshal(data, &results); err != nil {
+			return "", errors.Wrap(err, "parsing previous scan results")
+		}
+	}
+	results = append(results, models.ScanResult{
+		Tool:     opts.Tool,
+		Findings: findings,
+		FailOn:   opts.FailOn,
+	})
+	data, err := json.Marshal(results)
+	if err != nil {
+		return "", errors.Wrap(err, "encoding scan results")
+	}
+	if err := ioutil.WriteFile(resultsFile, data, 0600); err != nil {
+		return "", errors.Wrap(err, "writing scan results")
+	}
+	return "", nil
+}
+
+// scannerJSON returns the JSON in the output of a scanner, skipping any
+// warnings it printed before it.
+func scannerJSON(output string) (string, error) {
+	start := strings.IndexAny(output, "{[")
+	if start == -1 {
+		return "", errors.New("no JSON in output")
+	}
+	return output[start:], nil
+}
+
+// scanSeverity returns the severity a scanner reported in the format of
+// models.ScanFinding.
+func scanSeverity(severity string) string {
+	if severity == "" {
+		return models.UnknownScanSeverity
+	}
+	return strings.ToUpper(severity)
+}
+
+// tfsecOutput is the result of tfsec --format json.
+type tfsecOutput struct {
+	Results []struct {
+		RuleID      string `json:"rule_id"`
+		LongID      string `json:"long_id"`
+		Description string `json:"description"`
+		Severity    string `json:"severity"`
+		Resource    string `json:"resource"`
+		Location    struct {
+			Filename  string `json:"filename"`
+			StartLine int    `json:"start_line"`
+		} `json:"location"`
+	} `json:"results"`
+}
+
+func parseTfsecOutput(output string, path string) ([]models.ScanFinding, error) {
+	data, err := scannerJSON(output)
+	if err != nil {
+		return nil, err
+	}
+	var parsed tfsecOutput
+	if err := json.NewDecoder(strings.NewReader(data)).Decode(&parsed); err != nil {
+		return nil, err
+	}
+	var findings []models.ScanFinding
+	for _, r := range parsed.Results {
+		ruleID := r.LongID
+		if ruleID == "" {
+			ruleID = r.RuleID
+		}
+		// tfsec reports absolute filenames.
+		filename := r.Location.Filename
+		if rel, err := filepath.Rel(path, filename); err == nil && filepath.IsAbs(filename) {
+			filename = rel
+		}
+		findings = append(findings, models.ScanFinding{
+			RuleID:      ruleID,
+			Description: r.Description,
+			Severity:    scanSeverity(r.Severity),
+			Resource:    r.Resource,
+			Location:    fmt.Sprintf("%s:%d", filename, r.Location.StartLine),
+		})
+	}
+	return findings, nil
+}
+
+// checkovReport is the result of checkov -o json for a single framework.
+type checkovReport struct {
+	Results struct {
+		FailedChecks []struct {
+			CheckID       string `json:"check_id"`
+			CheckName     string `json:"check_name"`
+			Severity      string `json:"severity"`
+			Resource      string `json:"resource"`
+			FilePath      string `json:"file_path"`
+			FileLineRange []int  `json:"file_line_range"`
+		} `json:"failed_checks"`
+	} `json:"results"`
+}
+
+func parseCheckovOutput(output string, _ string) ([]models.ScanFinding, error) {
+	data, err := scannerJSON(output)
+	if err != nil {
+		return nil, err
+	}
+	// checkov outputs a list of reports if it ran more than one framework.
+	var reports []checkovReport
+	if data[0] == '[' {
+		err = json.NewDecoder(strings.NewReader(data)).Decode(&reports)
+	} else {
+		var report checkovReport
+		err = json.NewDecoder(strings.NewReader(data)).Decode(&report)
+		reports = append(reports, report)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var findings []models.ScanFinding
+	for _, report := range reports {
+		for _, c := range report.Results.FailedChecks {
+			location := strings.TrimPrefix(c.FilePath, "/")
+			if len(c.FileLineRange) > 0 {
+				location = fmt.Sprintf("%s:%d", location, c.FileLineRange[0])
+			}
+			findings = append(findings, models.ScanFinding{
+				RuleID:      c.CheckID,
+				Description: c.CheckName,
+				Severity:    scanSeverity(c.Severity),
+				Resource:    c.Resource,
+				Location:    location,
+			})
+		}
+	}
+	return findings, nil
+}
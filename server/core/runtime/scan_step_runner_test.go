@@ -0,0 +1,110 @@
+package runtime_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-version"
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/core/runtime"
+	"github.com/runatlantis/atlantis/server/core/runtime/cache"
+	cachemocks "github.com/runatlantis/atlantis/server/core/runtime/cache/mocks"
+	modelmocks "github.com/runatlantis/atlantis/server/core/runtime/models/mocks"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func setupScanStepRunner(t *testing.T) (*runtime.ScanStepRunner, *modelmocks.MockExec, models.ProjectCommandContext, string, func()) {
+	RegisterMockTestingT(t)
+	tmpDir, cleanup := TempDir(t)
+
+	tfsecCache := cachemocks.NewMockExecutionVersionCache()
+	When(tfsecCache.Get(version.Must(version.NewVersion(runtime.DefaultTfsecVersion)))).ThenReturn("/bin/tfsec1.28.1", nil)
+	checkovCache := cachemocks.NewMockExecutionVersionCache()
+	When(checkovCache.Get(version.Must(version.NewVersion("2.3.100")))).ThenReturn("/bin/checkov2.3.100", nil)
+	exec := modelmocks.NewMockExec()
+
+	ctx := models.ProjectCommandContext{
+		Workspace: "default",
+		Log:       logging.NewNoopLogger(t),
+	}
+	return &runtime.ScanStepRunner{
+		Exec: exec,
+		VersionCaches: map[string]cache.ExecutionVersionCache{
+			"tfsec":   tfsecCache,
+			"checkov": checkovCache,
+		},
+	}, exec, ctx, tmpDir, cleanup
+}
+
+func TestScanStepRunner_Run(t *testing.T) {
+	subject, exec, ctx, tmpDir, cleanup := setupScanStepRunner(t)
+	defer cleanup()
+
+	tfsecArgs := []string{"/bin/tfsec1.28.1", ".", "--format", "json", "--no-color", "--soft-fail", "--exclude", "aws-s3-enable-versioning"}
+	When(exec.CombinedOutput(tfsecArgs, map[string]string(nil), tmpDir)).ThenReturn(`{
+  "results": [
+    {
+      "rule_id": "AVD-AWS-0086",
+      "long_id": "aws-s3-block-public-acls",
+      "description": "No public access block so not blocking public acls",
+      "severity": "HIGH",
+      "resource": "aws_s3_bucket.logs",
+      "location": {"filename": "`+filepath.Join(tmpDir, "s3.tf")+`", "start_line": 3, "end_line": 7}
+    }
+  ]
+}`, nil)
+	out, err := subject.Run(ctx, valid.ScanOptions{Tool: "tfsec", FailOn: "CRITICAL"}, []string{"--exclude", "aws-s3-enable-versioning"}, tmpDir, nil)
+	Ok(t, err)
+	Equals(t, "", out)
+
+	t.Log("the results of later scan steps are appended")
+	checkovArgs := []string{"/bin/checkov2.3.100", "-d", ".", "--framework", "terraform", "-o", "json", "--quiet", "--soft-fail"}
+	When(exec.CombinedOutput(checkovArgs, map[string]string(nil), tmpDir)).ThenReturn(`2023-01-01 WARNING unable to fetch severities
+{
+  "check_type": "terraform",
+  "results": {
+    "failed_checks": [
+      {
+        "check_id": "CKV_AWS_18",
+        "check_name": "Ensure the S3 bucket has access logging enabled",
+        "severity": null,
+        "resource": "aws_s3_bucket.logs",
+        "file_path": "/s3.tf",
+        "file_line_range": [3, 7]
+      }
+    ]
+  }
+}`, nil)
+	_, err = subject.Run(ctx, valid.ScanOptions{Tool: "checkov", Version: version.Must(version.NewVersion("2.3.100"))}, nil, tmpDir, nil)
+	Ok(t, err)
+
+	written, err := ioutil.ReadFile(filepath.Join(tmpDir, "default.scan.json"))
+	Ok(t, err)
+	Equals(t, `[{"Tool":"tfsec","Findings":[{"RuleID":"aws-s3-block-public-acls","Description":"No public access block so not blocking public acls","Severity":"HIGH","Resource":"aws_s3_bucket.logs","Location":"s3.tf:3"}],"FailOn":"CRITICAL"},`+
+		`{"Tool":"checkov","Findings":[{"RuleID":"CKV_AWS_18","Description":"Ensure the S3 bucket has access logging enabled","Severity":"UNKNOWN","Resource":"aws_s3_bucket.logs","Location":"s3.tf:3"}],"FailOn":""}]`, string(written))
+}
+
+func TestScanStepRunner_RunErrors(t *testing.T) {
+	subject, exec, ctx, tmpDir, cleanup := setupScanStepRunner(t)
+	defer cleanup()
+	tfsecArgs := []string{"/bin/tfsec1.28.1", ".", "--format", "json", "--no-color", "--soft-fail"}
+
+	t.Log("the scanner failing is an error")
+	When(exec.CombinedOutput(tfsecArgs, map[string]string(nil), tmpDir)).ThenReturn("Error: unknown flag", errors.New("exit status 1"))
+	out, err := subject.Run(ctx, valid.ScanOptions{Tool: "tfsec"}, nil, tmpDir, nil)
+	ErrEquals(t, "running tfsec: exit status 1", err)
+	Equals(t, "Error: unknown flag", out)
+
+	t.Log("output that isn't JSON is an error")
+	When(exec.CombinedOutput(tfsecArgs, map[string]string(nil), tmpDir)).ThenReturn("no problems detected", nil)
+	_, err = subject.Run(ctx, valid.ScanOptions{Tool: "tfsec"}, nil, tmpDir, nil)
+	ErrEquals(t, "parsing tfsec output: no JSON in output", err)
+
+	_, err = subject.Run(ctx, valid.ScanOptions{Tool: "trivy"}, nil, tmpDir, nil)
+	ErrEquals(t, `"trivy" is not a supported scanner`, err)
+}
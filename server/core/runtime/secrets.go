@@ -0,0 +1,75 @@
+package runtime
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// SecretEnvVarPrefix is prefixed to a secret's name to get the name of the
+// environment variable its value is passed to commands in.
+const SecretEnvVarPrefix = "ATLANTIS_SECRET_"
+
+// RedactedSecret replaces secret values in command output.
+const RedactedSecret = "[REDACTED]"
+
+// secretRefRegex matches references to secrets in commands, ex.
+// ${secrets.DB_PASSWORD}.
+var secretRefRegex = regexp.MustCompile(`\$\{secrets\.([^}]*)\}`)
+
+// substituteSecrets replaces each ${secrets.NAME} reference in command with a
+// reference to an environment variable holding the secret's value. This way
+// the value never appears in the command line, which we log and which can be
+// seen in the process list. It returns the new command and the environment
+// variables that must be set to run it.
+func substituteSecrets(command string, secrets map[string]valid.Secret) (string, map[string]string, error) {
+	envs := make(map[string]string)
+	var err error
+	substituted := secretRefRegex.ReplaceAllStringFunc(command, func(ref string) string {
+		name := secretRefRegex.FindStringSubmatch(ref)[1]
+		secret, ok := secrets[name]
+		if !ok {
+			if err == nil {
+				err = fmt.Errorf("secret %q is not defined in the server-side repo config", name)
+			}
+			return ref
+		}
+		val, valErr := secret.Value()
+		if valErr != nil {
+			if err == nil {
+				err = fmt.Errorf("reading secret %q: %s", name, valErr)
+			}
+			return ref
+		}
+		envVar := SecretEnvVarPrefix + name
+		envs[envVar] = val
+		return fmt.Sprintf("${%s}", envVar)
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return substituted, envs, nil
+}
+
+// redactSecrets replaces the values of all secrets in out with
+// RedactedSecret. We redact every secret, not just those the command
+// referenced, since env backed secrets are also available to commands through
+// Atlantis' own environment.
+func redactSecrets(out string, secrets map[string]valid.Secret) string {
+	var vals []string
+	for _, secret := range secrets {
+		// Secrets we can't read can't be in the output.
+		if val, err := secret.Value(); err == nil && val != "" {
+			vals = append(vals, val)
+		}
+	}
+	// Replace longer values first in case one secret contains another.
+	sort.Slice(vals, func(i, j int) bool { return len(vals[i]) > len(vals[j]) })
+	for _, val := range vals {
+		out = strings.ReplaceAll(out, val, RedactedSecret)
+	}
+	return out
+}
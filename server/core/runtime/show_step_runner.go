@@ -40,10 +40,12 @@ func (p *ShowStepRunner) Run(ctx models.ProjectCommandContext, extraArgs []strin
 	showResultFile := filepath.Join(path, ctx.GetShowResultFileName())
 
 	output, err := p.TerraformExecutor.RunCommandWithVersion(
+		ctx.Context(),
 		ctx.Log,
 		path,
 		[]string{"show", "-no-color", "-json", filepath.Clean(planFile)},
 		envs,
+		ctx.TerraformDistribution,
 		tfVersion,
 		ctx.Workspace,
 	)
@@ -0,0 +1,31 @@
+package runtime
+
+import (
+	"path/filepath"
+
+	version "github.com/hashicorp/go-version"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// StateStepRunner runs `terraform state rm` and `terraform state mv`.
+type StateStepRunner struct {
+	TerraformExecutor TerraformExec
+	DefaultTFVersion  *version.Version
+}
+
+// Run runs the terraform state subcommand in ctx.SubName. The comment args
+// end with the addresses to operate on so they're appended after any extra
+// args.
+func (s *StateStepRunner) Run(ctx models.ProjectCommandContext, extraArgs []string, path string, envs map[string]string) (string, error) {
+	tfVersion := s.DefaultTFVersion
+	if ctx.TerraformVersion != nil {
+		tfVersion = ctx.TerraformVersion
+	}
+
+	stateCmd := append(append([]string{"state", ctx.SubName}, extraArgs...), ctx.EscapedCommentArgs...)
+	out, err := s.TerraformExecutor.RunCommandWithVersion(ctx.Context(), ctx.Log, filepath.Clean(path), stateCmd, envs, ctx.TerraformDistribution, tfVersion, ctx.Workspace)
+	if err == nil {
+		removeStalePlanfile(ctx, path)
+	}
+	return out, err
+}
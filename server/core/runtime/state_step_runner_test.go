@@ -0,0 +1,71 @@
+package runtime
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-version"
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/core/terraform/mocks"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestStateStepRunner_Run(t *testing.T) {
+	cases := []struct {
+		subName    string
+		commentArg []string
+		expArgs    []string
+	}{
+		{
+			"rm",
+			[]string{"aws_instance.a", "aws_instance.b"},
+			[]string{"state", "rm", "-lock-timeout=5m", "aws_instance.a", "aws_instance.b"},
+		},
+		{
+			"mv",
+			[]string{"aws_instance.a", "aws_instance.b"},
+			[]string{"state", "mv", "-lock-timeout=5m", "aws_instance.a", "aws_instance.b"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.subName, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			logger := logging.NewNoopLogger(t)
+			workspace := "default"
+			tmpDir, cleanup := TempDir(t)
+			defer cleanup()
+			planPath := filepath.Join(tmpDir, "default.tfplan")
+			err := ioutil.WriteFile(planPath, nil, 0600)
+			Ok(t, err)
+
+			context := models.ProjectCommandContext{
+				Log:                logger,
+				EscapedCommentArgs: c.commentArg,
+				Workspace:          workspace,
+				RepoRelDir:         ".",
+				SubName:            c.subName,
+			}
+
+			terraform := mocks.NewMockClient()
+			tfVersion, _ := version.NewVersion("0.15.0")
+			When(terraform.RunCommandWithVersion(context.Context(), logger, tmpDir, c.expArgs, map[string]string(nil), "", tfVersion, workspace)).
+				ThenReturn("Successfully changed state!", nil)
+
+			s := &StateStepRunner{
+				TerraformExecutor: terraform,
+				DefaultTFVersion:  tfVersion,
+			}
+			output, err := s.Run(context, []string{"-lock-timeout=5m"}, tmpDir, map[string]string(nil))
+			Ok(t, err)
+			Equals(t, "Successfully changed state!", output)
+
+			t.Log("the stale planfile should have been deleted")
+			_, err = os.Stat(planPath)
+			Assert(t, os.IsNotExist(err), "exp planfile to be deleted")
+		})
+	}
+}
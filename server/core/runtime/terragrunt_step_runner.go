@@ -0,0 +1,199 @@
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	version "github.com/hashicorp/go-version"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/core/runtime/cache"
+	runtime_models "github.com/runatlantis/atlantis/server/core/runtime/models"
+	"github.com/runatlantis/atlantis/server/core/terraform"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+const (
+	terragruntBinaryName        = "terragrunt"
+	terragruntConfigFilename    = "terragrunt.hcl"
+	terragruntDownloadURLPrefix = "https://github.com/gruntwork-io/terragrunt/releases/download/v"
+)
+
+// TerragruntVersionDownloader downloads terragrunt release binaries.
+type TerragruntVersionDownloader struct {
+	Downloader terraform.Downloader
+}
+
+func (t TerragruntVersionDownloader) downloadTerragruntVersion(v *version.Version, destPath string) (runtime_models.FilePath, error) {
+	versionURLPrefix := fmt.Sprintf("%s%s", terragruntDownloadURLPrefix, v.String())
+	binURL := fmt.Sprintf("%s/terragrunt_%s_%s", versionURLPrefix, runtime.GOOS, runtime.GOARCH)
+	checksumURL := fmt.Sprintf("%s/SHA256SUMS", versionURLPrefix)
+	fullSrcURL := fmt.Sprintf("%s?checksum=file:%s", binURL, checksumURL)
+
+	binPath := filepath.Join(destPath, terragruntBinaryName)
+	if err := t.Downloader.GetFile(binPath, fullSrcURL); err != nil {
+		return runtime_models.LocalFilePath(""), errors.Wrapf(err, "downloading terragrunt version %s at %q", v.String(), fullSrcURL)
+	}
+	// Terragrunt is released as a bare binary rather than an archive so it
+	// isn't downloaded as an executable.
+	if err := os.Chmod(binPath, 0700); err != nil {
+		return runtime_models.LocalFilePath(""), errors.Wrapf(err, "making %s executable", binPath)
+	}
+	return runtime_models.LocalFilePath(binPath), nil
+}
+
+// TerragruntStepRunner runs terragrunt plan or apply, depending on the
+// command being run, for projects that contain a terragrunt.hcl file. The
+// plan is saved to and applied from the same planfile as the built-in plan
+// and apply steps so that pending plan detection and automerge keep working.
+// Projects without a terragrunt.hcl file are delegated to PlanStepRunner or
+// ApplyStepRunner.
+type TerragruntStepRunner struct {
+	TerraformExecutor TerraformExec
+	DefaultTFVersion  *version.Version
+	// DefaultTFDistribution is the distribution of terraform, ex. opentofu,
+	// used by projects that don't set one.
+	DefaultTFDistribution string
+	// TerraformBinDir is the directory where Atlantis downloads Terraform binaries.
+	TerraformBinDir string
+	// DefaultTerragruntVersion is the version of terragrunt to use. If nil,
+	// the terragrunt binary in our PATH is used.
+	DefaultTerragruntVersion *version.Version
+	VersionCache             cache.ExecutionVersionCache
+	Exec                     runtime_models.Exec
+	PlanStepRunner           Runner
+	ApplyStepRunner          Runner
+}
+
+// NewTerragruntStepRunner constructs a TerragruntStepRunner that downloads
+// terragrunt versions into binDir.
+func NewTerragruntStepRunner(
+	terraformExecutor TerraformExec,
+	defaultTFVersion *version.Version,
+	defaultTFDistribution string,
+	terraformBinDir string,
+	defaultTerragruntVersion *version.Version,
+	binDir string,
+	downloader terraform.Downloader,
+	planStepRunner Runner,
+	applyStepRunner Runner,
+) *TerragruntStepRunner {
+	versionDownloader := TerragruntVersionDownloader{
+		Downloader: downloader,
+	}
+	return &TerragruntStepRunner{
+		TerraformExecutor:        terraformExecutor,
+		DefaultTFVersion:         defaultTFVersion,
+		DefaultTFDistribution:    defaultTFDistribution,
+		TerraformBinDir:          terraformBinDir,
+		DefaultTerragruntVersion: defaultTerragruntVersion,
+		VersionCache: cache.NewExecutionVersionLayeredLoadingCache(
+			terragruntBinaryName,
+			binDir,
+			versionDownloader.downloadTerragruntVersion,
+		),
+		Exec:            runtime_models.LocalExec{},
+		PlanStepRunner:  planStepRunner,
+		ApplyStepRunner: applyStepRunner,
+	}
+}
+
+func (t *TerragruntStepRunner) Run(ctx models.ProjectCommandContext, extraArgs []string, path string, envs map[string]string) (string, error) {
+	if _, err := os.Stat(filepath.Join(path, terragruntConfigFilename)); err != nil {
+		ctx.Log.Debug("no %s found, running terraform %s", terragruntConfigFilename, ctx.CommandName)
+		if ctx.CommandName == models.ApplyCommand {
+			return t.ApplyStepRunner.Run(ctx, extraArgs, path, envs)
+		}
+		return t.PlanStepRunner.Run(ctx, extraArgs, path, envs)
+	}
+
+	tfVersion := t.DefaultTFVersion
+	if ctx.TerraformVersion != nil {
+		tfVersion = ctx.TerraformVersion
+	}
+	tfDistribution := t.DefaultTFDistribution
+	if ctx.TerraformDistribution != "" {
+		tfDistribution = ctx.TerraformDistribution
+	}
+	if err := t.TerraformExecutor.EnsureVersion(ctx.Log, tfDistribution, tfVersion); err != nil {
+		return "", errors.Wrapf(err, "downloading %s version %s", tfDistribution, tfVersion)
+	}
+	terragruntPath := terragruntBinaryName
+	if t.DefaultTerragruntVersion != nil {
+		var err error
+		terragruntPath, err = t.VersionCache.Get(t.DefaultTerragruntVersion)
+		if err != nil {
+			return "", errors.Wrapf(err, "getting terragrunt version %s", t.DefaultTerragruntVersion)
+		}
+	}
+
+	finalEnvs := map[string]string{
+		"TF_IN_AUTOMATION":           "true",
+		"TF_WORKSPACE":               ctx.Workspace,
+		"TERRAGRUNT_TFPATH":          t.terraformPath(tfDistribution, tfVersion),
+		"ATLANTIS_TERRAFORM_VERSION": tfVersion.String(),
+		"WORKSPACE":                  ctx.Workspace,
+		"DIR":                        path,
+	}
+	for key, val := range envs {
+		finalEnvs[key] = val
+	}
+
+	// NOTE: we need to quote the plan path because Bitbucket Server can
+	// have spaces in its repo owner names which is part of the path.
+	planFile := filepath.Join(path, GetPlanFilename(ctx.Workspace, ctx.ProjectName))
+	if ctx.CommandName == models.ApplyCommand {
+		if _, err := os.Stat(planFile); os.IsNotExist(err) {
+			return "", fmt.Errorf("no plan found at path %q and workspace %q–did you run plan?", ctx.RepoRelDir, ctx.Workspace)
+		}
+		args := append(append(append([]string{terragruntPath, "apply", "-input=false", "-no-color", "--terragrunt-non-interactive"}, extraArgs...), ctx.EscapedCommentArgs...), fmt.Sprintf("%q", planFile))
+		out, err := t.run(ctx, args, finalEnvs, path)
+		if err != nil {
+			return out, err
+		}
+		ctx.Log.Info("apply successful, deleting planfile")
+		if removeErr := os.Remove(planFile); removeErr != nil {
+			ctx.Log.Warn("failed to delete planfile after successful apply: %s", removeErr)
+		}
+		return out, nil
+	}
+
+	args := append(append([]string{terragruntPath, "plan", "-input=false", "-refresh", "-no-color", "--terragrunt-non-interactive", "-out", fmt.Sprintf("%q", planFile)}, extraArgs...), ctx.EscapedCommentArgs...)
+	out, err := t.run(ctx, args, finalEnvs, path)
+	if err != nil {
+		return out, err
+	}
+	return fmtPlanOutput(out, tfVersion), nil
+}
+
+func (t *TerragruntStepRunner) run(ctx models.ProjectCommandContext, args []string, envs map[string]string, path string) (string, error) {
+	cmd := strings.Join(args, " ")
+	out, err := t.Exec.CombinedOutput(args, envs, path)
+	if err != nil {
+		err = errors.Wrapf(err, "running %q in %q", cmd, path)
+		ctx.Log.Err(err.Error())
+		return out, err
+	}
+	ctx.Log.Info("successfully ran %q in %q", cmd, path)
+	return out, nil
+}
+
+// terraformPath returns the path to the binary of version v of distribution d
+// that terragrunt should run. It looks in the same places as the terraform
+// client so it must be called after EnsureVersion.
+func (t *TerragruntStepRunner) terraformPath(d string, v *version.Version) string {
+	binName := terraform.BinName(d)
+	binFile := binName + v.String()
+	binPath := filepath.Join(t.TerraformBinDir, binFile)
+	if _, err := os.Stat(binPath); err == nil {
+		return binPath
+	}
+	if binPath, err := exec.LookPath(binFile); err == nil {
+		return binPath
+	}
+	// Otherwise this version is the binary in our PATH.
+	return binName
+}
@@ -0,0 +1,177 @@
+package runtime_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-version"
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/core/runtime"
+	cachemocks "github.com/runatlantis/atlantis/server/core/runtime/cache/mocks"
+	"github.com/runatlantis/atlantis/server/core/runtime/mocks"
+	modelmocks "github.com/runatlantis/atlantis/server/core/runtime/models/mocks"
+	"github.com/runatlantis/atlantis/server/core/runtime/models/mocks/matchers"
+	tfmocks "github.com/runatlantis/atlantis/server/core/terraform/mocks"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestTerragruntStepRunner_NoTerragruntConfig(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmpDir, cleanup := TempDir(t)
+	defer cleanup()
+
+	planRunner := mocks.NewMockRunner()
+	applyRunner := mocks.NewMockRunner()
+	exec := modelmocks.NewMockExec()
+	s := &runtime.TerragruntStepRunner{
+		Exec:            exec,
+		PlanStepRunner:  planRunner,
+		ApplyStepRunner: applyRunner,
+	}
+	envs := map[string]string{}
+
+	t.Log("plan is delegated to the plan step runner")
+	ctx := models.ProjectCommandContext{
+		CommandName: models.PlanCommand,
+		Log:         logging.NewNoopLogger(t),
+		Workspace:   "default",
+	}
+	When(planRunner.Run(ctx, []string{"-var", "a=b"}, tmpDir, envs)).ThenReturn("plan output", nil)
+	output, err := s.Run(ctx, []string{"-var", "a=b"}, tmpDir, envs)
+	Ok(t, err)
+	Equals(t, "plan output", output)
+
+	t.Log("apply is delegated to the apply step runner")
+	ctx.CommandName = models.ApplyCommand
+	When(applyRunner.Run(ctx, nil, tmpDir, envs)).ThenReturn("apply output", nil)
+	output, err = s.Run(ctx, nil, tmpDir, envs)
+	Ok(t, err)
+	Equals(t, "apply output", output)
+
+	exec.VerifyWasCalled(Never()).CombinedOutput(AnyStringSlice(), matchers.AnyMapOfStringToString(), AnyString())
+}
+
+func TestTerragruntStepRunner_Plan(t *testing.T) {
+	s, exec, tmpDir, cleanup := setupTerragruntStepRunner(t)
+	defer cleanup()
+
+	ctx := models.ProjectCommandContext{
+		CommandName:        models.PlanCommand,
+		Log:                logging.NewNoopLogger(t),
+		Workspace:          "staging",
+		RepoRelDir:         ".",
+		EscapedCommentArgs: []string{"\\-var", "\\a\\=\\b"},
+	}
+	planFile := filepath.Join(tmpDir, "staging.tfplan")
+	expArgs := []string{"/bin/terragrunt0.35.0", "plan", "-input=false", "-refresh", "-no-color", "--terragrunt-non-interactive", "-out", `"` + planFile + `"`, "-lock-timeout=5m", "\\-var", "\\a\\=\\b"}
+	expEnvs := map[string]string{
+		"TF_IN_AUTOMATION":           "true",
+		"TF_WORKSPACE":               "staging",
+		"TERRAGRUNT_TFPATH":          filepath.Join(tmpDir, "bin", "terraform0.15.0"),
+		"ATLANTIS_TERRAFORM_VERSION": "0.15.0",
+		"WORKSPACE":                  "staging",
+		"DIR":                        tmpDir,
+		"CUSTOM":                     "value",
+	}
+	When(exec.CombinedOutput(expArgs, expEnvs, tmpDir)).ThenReturn("Initializing modules...\nnull_resource.test: Refreshing state... [id=1]\n  + null_resource.test\nPlan: 1 to add, 0 to change, 0 to destroy.", nil)
+
+	output, err := s.Run(ctx, []string{"-lock-timeout=5m"}, tmpDir, map[string]string{"CUSTOM": "value"})
+	Ok(t, err)
+	Equals(t, "+ null_resource.test\nPlan: 1 to add, 0 to change, 0 to destroy.", output)
+}
+
+func TestTerragruntStepRunner_OpenTofu(t *testing.T) {
+	s, exec, tmpDir, cleanup := setupTerragruntStepRunner(t)
+	defer cleanup()
+	Ok(t, ioutil.WriteFile(filepath.Join(tmpDir, "bin", "tofu1.6.0"), nil, 0700)) // #nosec G306
+
+	ctx := models.ProjectCommandContext{
+		CommandName:           models.PlanCommand,
+		Log:                   logging.NewNoopLogger(t),
+		Workspace:             "default",
+		RepoRelDir:            ".",
+		TerraformVersion:      version.Must(version.NewVersion("1.6.0")),
+		TerraformDistribution: "opentofu",
+	}
+	planFile := filepath.Join(tmpDir, "default.tfplan")
+	expArgs := []string{"/bin/terragrunt0.35.0", "plan", "-input=false", "-refresh", "-no-color", "--terragrunt-non-interactive", "-out", `"` + planFile + `"`}
+	expEnvs := map[string]string{
+		"TF_IN_AUTOMATION":           "true",
+		"TF_WORKSPACE":               "default",
+		"TERRAGRUNT_TFPATH":          filepath.Join(tmpDir, "bin", "tofu1.6.0"),
+		"ATLANTIS_TERRAFORM_VERSION": "1.6.0",
+		"WORKSPACE":                  "default",
+		"DIR":                        tmpDir,
+	}
+	When(exec.CombinedOutput(expArgs, expEnvs, tmpDir)).ThenReturn("No changes.", nil)
+
+	output, err := s.Run(ctx, nil, tmpDir, nil)
+	Ok(t, err)
+	Equals(t, "No changes.", output)
+}
+
+func TestTerragruntStepRunner_Apply(t *testing.T) {
+	s, exec, tmpDir, cleanup := setupTerragruntStepRunner(t)
+	defer cleanup()
+
+	ctx := models.ProjectCommandContext{
+		CommandName: models.ApplyCommand,
+		Log:         logging.NewNoopLogger(t),
+		Workspace:   "default",
+		RepoRelDir:  ".",
+		ProjectName: "myproject",
+	}
+
+	t.Log("apply errors if there's no plan")
+	_, err := s.Run(ctx, nil, tmpDir, nil)
+	ErrEquals(t, `no plan found at path "." and workspace "default"–did you run plan?`, err)
+
+	planFile := filepath.Join(tmpDir, "myproject-default.tfplan")
+	Ok(t, ioutil.WriteFile(planFile, nil, 0600))
+	expArgs := []string{"/bin/terragrunt0.35.0", "apply", "-input=false", "-no-color", "--terragrunt-non-interactive", `"` + planFile + `"`}
+
+	t.Log("the plan is kept if apply fails")
+	When(exec.CombinedOutput(EqStringSlice(expArgs), matchers.AnyMapOfStringToString(), EqString(tmpDir))).ThenReturn("Error: apply failed", errors.New("exit status 1"))
+	output, err := s.Run(ctx, nil, tmpDir, nil)
+	Assert(t, err != nil, "exp error")
+	Equals(t, "Error: apply failed", output)
+	_, err = os.Stat(planFile)
+	Ok(t, err)
+
+	t.Log("the plan is deleted after a successful apply")
+	When(exec.CombinedOutput(EqStringSlice(expArgs), matchers.AnyMapOfStringToString(), EqString(tmpDir))).ThenReturn("Apply complete!", nil)
+	output, err = s.Run(ctx, nil, tmpDir, nil)
+	Ok(t, err)
+	Equals(t, "Apply complete!", output)
+	_, err = os.Stat(planFile)
+	Assert(t, os.IsNotExist(err), "exp planfile to be deleted")
+}
+
+func setupTerragruntStepRunner(t *testing.T) (*runtime.TerragruntStepRunner, *modelmocks.MockExec, string, func()) {
+	RegisterMockTestingT(t)
+	tmpDir, cleanup := TempDir(t)
+	Ok(t, ioutil.WriteFile(filepath.Join(tmpDir, "terragrunt.hcl"), nil, 0600))
+	binDir := filepath.Join(tmpDir, "bin")
+	Ok(t, os.Mkdir(binDir, 0700))
+	Ok(t, ioutil.WriteFile(filepath.Join(binDir, "terraform0.15.0"), nil, 0700))
+
+	tfVersion, _ := version.NewVersion("0.15.0")
+	tgVersion, _ := version.NewVersion("0.35.0")
+	versionCache := cachemocks.NewMockExecutionVersionCache()
+	When(versionCache.Get(tgVersion)).ThenReturn("/bin/terragrunt0.35.0", nil)
+	exec := modelmocks.NewMockExec()
+
+	return &runtime.TerragruntStepRunner{
+		TerraformExecutor:        tfmocks.NewMockClient(),
+		DefaultTFVersion:         tfVersion,
+		TerraformBinDir:          binDir,
+		DefaultTerragruntVersion: tgVersion,
+		VersionCache:             versionCache,
+		Exec:                     exec,
+	}, exec, tmpDir, cleanup
+}
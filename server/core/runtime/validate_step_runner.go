@@ -0,0 +1,26 @@
+package runtime
+
+import (
+	"path/filepath"
+
+	version "github.com/hashicorp/go-version"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// ValidateStepRunner runs `terraform validate`.
+type ValidateStepRunner struct {
+	TerraformExecutor TerraformExec
+	DefaultTFVersion  *version.Version
+}
+
+// Run runs terraform validate. Extra args and comment args are passed
+// through to validate.
+func (v *ValidateStepRunner) Run(ctx models.ProjectCommandContext, extraArgs []string, path string, envs map[string]string) (string, error) {
+	tfVersion := v.DefaultTFVersion
+	if ctx.TerraformVersion != nil {
+		tfVersion = ctx.TerraformVersion
+	}
+
+	validateCmd := append(append([]string{"validate", "-no-color"}, extraArgs...), ctx.EscapedCommentArgs...)
+	return v.TerraformExecutor.RunCommandWithVersion(ctx.Context(), ctx.Log, filepath.Clean(path), validateCmd, envs, ctx.TerraformDistribution, tfVersion, ctx.Workspace)
+}
@@ -0,0 +1,62 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/hashicorp/go-version"
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/core/terraform/mocks"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestValidateStepRunner_Run(t *testing.T) {
+	RegisterMockTestingT(t)
+	logger := logging.NewNoopLogger(t)
+	workspace := "default"
+	context := models.ProjectCommandContext{
+		Log:                logger,
+		EscapedCommentArgs: []string{"-json"},
+		Workspace:          workspace,
+		RepoRelDir:         ".",
+	}
+
+	terraform := mocks.NewMockClient()
+	tfVersion, _ := version.NewVersion("0.15.0")
+	When(terraform.RunCommandWithVersion(context.Context(), logger, "/path", []string{"validate", "-no-color", "-compact-warnings", "-json"}, map[string]string(nil), "", tfVersion, workspace)).
+		ThenReturn("Success! The configuration is valid.", nil)
+
+	s := &ValidateStepRunner{
+		TerraformExecutor: terraform,
+		DefaultTFVersion:  tfVersion,
+	}
+	output, err := s.Run(context, []string{"-compact-warnings"}, "/path", map[string]string(nil))
+	Ok(t, err)
+	Equals(t, "Success! The configuration is valid.", output)
+}
+
+func TestFmtStepRunner_Run(t *testing.T) {
+	RegisterMockTestingT(t)
+	logger := logging.NewNoopLogger(t)
+	workspace := "default"
+	context := models.ProjectCommandContext{
+		Log:                logger,
+		EscapedCommentArgs: []string{"-json"},
+		Workspace:          workspace,
+		RepoRelDir:         ".",
+	}
+
+	terraform := mocks.NewMockClient()
+	tfVersion, _ := version.NewVersion("0.15.0")
+	When(terraform.RunCommandWithVersion(context.Context(), logger, "/path", []string{"fmt", "-check", "-diff", "-no-color"}, map[string]string(nil), "", tfVersion, workspace)).
+		ThenReturn("", nil)
+
+	s := &FmtStepRunner{
+		TerraformExecutor: terraform,
+		DefaultTFVersion:  tfVersion,
+	}
+	output, err := s.Run(context, nil, "/path", map[string]string(nil))
+	Ok(t, err)
+	Equals(t, "", output)
+}
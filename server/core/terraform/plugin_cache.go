@@ -0,0 +1,162 @@
+package terraform
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// pluginCacheRunDirName is the name of the dir, inside the .terraform dir of
+// the project being run, that's used as the run's TF_PLUGIN_CACHE_DIR. It
+// has to live as long as the project's .terraform dir because terraform
+// links the providers it installs to the cache.
+const pluginCacheRunDirName = "atlantis-plugin-cache"
+
+// pluginCacheTmpPrefix is the prefix of the names of the entries that are
+// being added to the shared cache. They're skipped when warming.
+const pluginCacheTmpPrefix = ".atlantis-tmp-"
+
+// pluginCache shares the providers downloaded by terraform init between
+// runs without letting concurrent runs write to the same files, which
+// terraform doesn't support and which corrupts the cache.
+//
+// Each run gets its own cache dir that's warmed with hard links to the
+// providers in the shared cache, so warming is fast and doesn't use disk
+// space. After init, the providers it downloaded are added to the shared
+// cache by renaming them into place so that other runs never see partially
+// written providers.
+type pluginCache struct {
+	// dir is the shared cache.
+	dir string
+}
+
+// runDir returns the cache dir for runs in the project at path.
+func (p pluginCache) runDir(path string) string {
+	return filepath.Join(path, ".terraform", pluginCacheRunDirName)
+}
+
+// warm links the providers in the shared cache into runDir.
+func (p pluginCache) warm(runDir string) error {
+	if err := os.MkdirAll(runDir, 0700); err != nil {
+		return errors.Wrap(err, "creating plugin cache dir")
+	}
+	return errors.Wrap(linkTree(p.dir, runDir), "warming plugin cache")
+}
+
+// promote adds the providers in runDir that aren't in the shared cache to it.
+func (p pluginCache) promote(runDir string) error {
+	return errors.Wrap(promoteTree(runDir, p.dir), "adding providers to the shared plugin cache")
+}
+
+// linkTree links the files in src into dst, creating dirs as needed. Files
+// that already exist in dst are left alone.
+func linkTree(src string, dst string) error {
+	entries, err := ioutil.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), pluginCacheTmpPrefix) {
+			continue
+		}
+		srcPath := filepath.Join(src, e.Name())
+		dstPath := filepath.Join(dst, e.Name())
+		switch {
+		case e.IsDir():
+			if err := os.MkdirAll(dstPath, 0700); err != nil {
+				return err
+			}
+			if err := linkTree(srcPath, dstPath); err != nil {
+				return err
+			}
+		case e.Mode().IsRegular():
+			if _, err := os.Lstat(dstPath); err == nil {
+				continue
+			}
+			if err := linkOrCopy(srcPath, dstPath, e.Mode()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// promoteTree adds the dirs and files in src that aren't in dst to dst. Each
+// one is built under a temporary name and renamed into place. If another run
+// adds the same one first, its copy is kept.
+func promoteTree(src string, dst string) error {
+	entries, err := ioutil.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		srcPath := filepath.Join(src, e.Name())
+		dstPath := filepath.Join(dst, e.Name())
+		if !e.IsDir() && !e.Mode().IsRegular() {
+			continue
+		}
+		if info, err := os.Lstat(dstPath); err == nil {
+			if e.IsDir() && info.IsDir() {
+				if err := promoteTree(srcPath, dstPath); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		tmpDir, err := ioutil.TempDir(dst, pluginCacheTmpPrefix)
+		if err != nil {
+			return err
+		}
+		tmpPath := filepath.Join(tmpDir, e.Name())
+		if e.IsDir() {
+			err = os.Mkdir(tmpPath, 0700)
+			if err == nil {
+				err = linkTree(srcPath, tmpPath)
+			}
+		} else {
+			err = linkOrCopy(srcPath, tmpPath, e.Mode())
+		}
+		if err == nil {
+			if err = os.Rename(tmpPath, dstPath); err != nil {
+				if _, statErr := os.Lstat(dstPath); statErr == nil {
+					// Another run added it first.
+					err = nil
+				}
+			}
+		}
+		if rmErr := os.RemoveAll(tmpDir); err == nil {
+			err = rmErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// linkOrCopy hard links src to dst. If that's not possible, ex. because
+// they're on different file systems, src is copied instead.
+func linkOrCopy(src string, dst string, mode os.FileMode) error {
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+	in, err := os.Open(src) // nolint: gosec
+	if err != nil {
+		return err
+	}
+	defer in.Close() // nolint: errcheck
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode.Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close() // nolint: errcheck
+		return err
+	}
+	return out.Close()
+}
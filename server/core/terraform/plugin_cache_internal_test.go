@@ -0,0 +1,104 @@
+package terraform
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	version "github.com/hashicorp/go-version"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+const nullProvider = "registry.terraform.io/hashicorp/null/3.1.0/linux_amd64/terraform-provider-null_v3.1.0_x5"
+const awsProvider = "registry.terraform.io/hashicorp/aws/3.60.0/linux_amd64/terraform-provider-aws_v3.60.0_x5"
+
+func TestPluginCache_Warm(t *testing.T) {
+	shared, cleanup := TempDir(t)
+	defer cleanup()
+	path, cleanup2 := TempDir(t)
+	defer cleanup2()
+	writeProvider(t, shared, nullProvider, "null")
+	writeProvider(t, shared, pluginCacheTmpPrefix+"123/aws/terraform-provider-aws", "partial")
+
+	cache := pluginCache{dir: shared}
+	runDir := cache.runDir(path)
+	Ok(t, cache.warm(runDir))
+
+	t.Log("providers are linked rather than copied")
+	assertSameFile(t, filepath.Join(shared, nullProvider), filepath.Join(runDir, nullProvider))
+	info, err := os.Stat(filepath.Join(runDir, nullProvider))
+	Ok(t, err)
+	Equals(t, os.FileMode(0755), info.Mode().Perm())
+
+	t.Log("providers that are still being added to the shared cache are skipped")
+	_, err = os.Stat(filepath.Join(runDir, pluginCacheTmpPrefix+"123"))
+	Assert(t, os.IsNotExist(err), "exp partially added provider to be skipped")
+}
+
+func TestPluginCache_Promote(t *testing.T) {
+	shared, cleanup := TempDir(t)
+	defer cleanup()
+	runDir, cleanup2 := TempDir(t)
+	defer cleanup2()
+	writeProvider(t, shared, nullProvider, "shared null")
+	writeProvider(t, runDir, nullProvider, "run null")
+	writeProvider(t, runDir, awsProvider, "aws")
+
+	Ok(t, pluginCache{dir: shared}.promote(runDir))
+
+	t.Log("new providers are added")
+	assertSameFile(t, filepath.Join(runDir, awsProvider), filepath.Join(shared, awsProvider))
+
+	t.Log("providers that are already cached are left alone")
+	contents, err := ioutil.ReadFile(filepath.Join(shared, nullProvider))
+	Ok(t, err)
+	Equals(t, "shared null", string(contents))
+
+	t.Log("no temporary dirs are left behind")
+	entries, err := ioutil.ReadDir(shared)
+	Ok(t, err)
+	Equals(t, 1, len(entries))
+}
+
+// Test that init is run with its own plugin cache that's synced with the
+// shared cache.
+func TestDefaultClient_RunCommandWithVersion_InitSyncsPluginCache(t *testing.T) {
+	v, err := version.NewVersion("0.14.0")
+	Ok(t, err)
+	shared, cleanup := TempDir(t)
+	defer cleanup()
+	path, cleanup2 := TempDir(t)
+	defer cleanup2()
+	client := &DefaultClient{
+		defaultVersion:          v,
+		terraformPluginCacheDir: shared,
+		overrideTF:              "echo",
+		usePluginCache:          true,
+	}
+	runDir := filepath.Join(path, ".terraform", "atlantis-plugin-cache")
+	writeProvider(t, shared, nullProvider, "null")
+	// Simulates a provider that init downloads.
+	writeProvider(t, runDir, awsProvider, "aws")
+
+	_, err = client.RunCommandWithVersion(context.Background(), logging.NewNoopLogger(t), path, []string{"init"}, map[string]string{}, "", nil, "default")
+	Ok(t, err)
+	assertSameFile(t, filepath.Join(shared, nullProvider), filepath.Join(runDir, nullProvider))
+	assertSameFile(t, filepath.Join(runDir, awsProvider), filepath.Join(shared, awsProvider))
+}
+
+func writeProvider(t *testing.T, cacheDir string, relPath string, contents string) {
+	path := filepath.Join(cacheDir, relPath)
+	Ok(t, os.MkdirAll(filepath.Dir(path), 0700))
+	Ok(t, ioutil.WriteFile(path, []byte(contents), 0755))
+}
+
+func assertSameFile(t *testing.T, exp string, act string) {
+	expInfo, err := os.Stat(exp)
+	Ok(t, err)
+	actInfo, err := os.Stat(act)
+	Ok(t, err)
+	Assert(t, os.SameFile(expInfo, actInfo), "exp %q to be a link to %q", act, exp)
+}
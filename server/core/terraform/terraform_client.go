@@ -16,6 +16,9 @@ package terraform
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -24,44 +27,103 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
+	"syscall"
+	"time"
 
 	"github.com/hashicorp/go-getter"
 	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/terraform-config-inspect/tfconfig"
 	"github.com/mitchellh/go-homedir"
 	"github.com/pkg/errors"
 	"github.com/runatlantis/atlantis/server/logging"
+	"github.com/runatlantis/atlantis/server/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_terraform_client.go Client
 
 type Client interface {
-	// RunCommandWithVersion executes terraform with args in path. If v is nil,
-	// it will use the default Terraform version. workspace is the Terraform
-	// workspace which should be set as an environment variable.
-	RunCommandWithVersion(log logging.SimpleLogging, path string, args []string, envs map[string]string, v *version.Version, workspace string) (string, error)
+	// RunCommandWithVersion executes terraform with args in path. d is the
+	// distribution of terraform to run, ex. OpenTofuDistribution. If d is
+	// empty, it will use the default distribution and if v is nil, it will
+	// use the default version. workspace is the Terraform workspace which
+	// should be set as an environment variable. If ctx is cancelled or
+	// terraform runs for longer than the command timeout, it's interrupted.
+	RunCommandWithVersion(ctx context.Context, log logging.SimpleLogging, path string, args []string, envs map[string]string, d string, v *version.Version, workspace string) (string, error)
 
-	// EnsureVersion makes sure that terraform version `v` is available to use
-	EnsureVersion(log logging.SimpleLogging, v *version.Version) error
+	// EnsureVersion makes sure that version `v` of distribution `d` is
+	// available to use
+	EnsureVersion(log logging.SimpleLogging, d string, v *version.Version) error
+
+	// DetectVersion returns the version of distribution d to use for the
+	// project in projectDirectory based on the required_version setting in its
+	// configuration. If there's no required_version, distributions other than
+	// the default one use their own default version. It returns nil if the
+	// version can't be determined.
+	DetectVersion(log logging.SimpleLogging, d string, projectDirectory string) *version.Version
+}
+
+// TerraformDistribution and OpenTofuDistribution are the distributions of
+// terraform that can be run.
+const (
+	TerraformDistribution = "terraform"
+	OpenTofuDistribution  = "opentofu"
+)
+
+// BinName returns the name of the binary of distribution d, ex. tofu. Binaries
+// of specific versions are named BinName followed by the version, ex.
+// tofu1.6.0.
+func BinName(d string) string {
+	if d == OpenTofuDistribution {
+		return "tofu"
+	}
+	return "terraform"
+}
+
+// opentofuIndexURL lists the released versions of OpenTofu.
+var opentofuIndexURL = "https://get.opentofu.org/tofu/api.json"
+
+// distribution is where the binaries of a distribution of terraform are
+// downloaded from.
+type distribution struct {
+	name string
+	// binName is the name of the distribution's binary, see BinName.
+	binName string
+	// downloadURL is the base URL that versions are downloaded from.
+	downloadURL string
+	// defaultVersion is the version to use for projects that don't specify
+	// one if this isn't the default distribution. It's nil if there's none,
+	// in which case the newest released version is used. The default
+	// distribution's default version is DefaultClient.defaultVersion.
+	defaultVersion *version.Version
 }
 
 type DefaultClient struct {
 	// defaultVersion is the default version of terraform to use if another
 	// version isn't specified.
 	defaultVersion *version.Version
-	// We will run terraform with the TF_PLUGIN_CACHE_DIR env var set to this
-	// directory inside our data dir.
+	// defaultDistribution is the distribution to use if another distribution
+	// isn't specified.
+	defaultDistribution string
+	// distributions maps from the name of a distribution to where its
+	// binaries are downloaded from.
+	distributions map[string]distribution
+	// terraformPluginCacheDir is the plugin cache inside our data dir that's
+	// shared by all runs. Each run's TF_PLUGIN_CACHE_DIR is its own cache
+	// that's synced with this one, see pluginCache.
 	terraformPluginCacheDir string
 	binDir                  string
 	// overrideTF can be used to override the terraform binary during testing
 	// with another binary, ex. echo.
 	overrideTF string
 	// downloader downloads terraform versions.
-	downloader      Downloader
-	downloadBaseURL string
-	// versions maps from the string representation of a tf version (ex. 0.11.10)
-	// to the absolute path of that binary on disk (if it exists).
+	downloader Downloader
+	// versions maps from the binary name of a version (ex. terraform0.11.10 or
+	// tofu1.6.0) to the absolute path of that binary on disk (if it exists).
 	// Use versionsLock to control access.
 	versions map[string]string
 
@@ -70,6 +132,20 @@ type DefaultClient struct {
 
 	// usePluginCache determines whether or not to set the TF_PLUGIN_CACHE_DIR env var
 	usePluginCache bool
+
+	// commandTimeout is how long terraform can run before it's interrupted.
+	// If zero, it can run forever.
+	commandTimeout time.Duration
+
+	// credentials maps from the hostnames of registries and TFC/E
+	// installations to their tokens. Each is also set as a TF_TOKEN_<host> env
+	// var when terraform runs.
+	credentials map[string]string
+
+	// netrcFile is the path of the .netrc file that holds the credentials for
+	// downloading modules over HTTPS, if any. It's set as the NETRC env var
+	// when terraform runs.
+	netrcFile string
 }
 
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_downloader.go Downloader
@@ -80,13 +156,21 @@ type Downloader interface {
 	GetAny(dst, src string, opts ...getter.ClientOption) error
 }
 
-// versionRegex extracts the version from `terraform version` output.
+// versionRegex extracts the version from `terraform version` or
+// `tofu version` output.
 //     Terraform v0.12.0-alpha4 (2c36829d3265661d8edbd5014de8090ea7e2a076)
 //	   => 0.12.0-alpha4
 //
 //     Terraform v0.11.10
 //	   => 0.11.10
-var versionRegex = regexp.MustCompile("Terraform v(.*?)(\\s.*)?\n")
+//
+//     OpenTofu v1.6.0
+//	   => 1.6.0
+var versionRegex = regexp.MustCompile("(?:Terraform|OpenTofu) v(.*?)(\\s.*)?\n")
+
+// exactVersionRegex matches a required_version setting that is a single
+// exact version. We allow `= x.y.z`, `=x.y.z` or `x.y.z`.
+var exactVersionRegex = regexp.MustCompile(`^=?\s*([^\s]+)\s*$`)
 
 // NewClientWithDefaultVersion creates a new terraform client and pre-fetches the default version
 func NewClientWithDefaultVersion(
@@ -95,20 +179,61 @@ func NewClientWithDefaultVersion(
 	cacheDir string,
 	tfeToken string,
 	tfeHostname string,
+	registryCredentials map[string]string,
+	netrcCredentials []NetrcCredential,
 	defaultVersionStr string,
 	defaultVersionFlagName string,
+	defaultDistribution string,
 	tfDownloadURL string,
+	tofuDownloadURL string,
+	defaultTofuVersionStr string,
 	tfDownloader Downloader,
 	usePluginCache bool,
 	fetchAsync bool,
+	commandTimeout time.Duration,
 ) (*DefaultClient, error) {
 	var finalDefaultVersion *version.Version
 	var localVersion *version.Version
 	versions := make(map[string]string)
 	var versionsLock sync.Mutex
 
-	localPath, err := exec.LookPath("terraform")
+	if defaultDistribution == "" {
+		defaultDistribution = TerraformDistribution
+	}
+	distributions := map[string]distribution{
+		TerraformDistribution: {name: TerraformDistribution, binName: BinName(TerraformDistribution), downloadURL: tfDownloadURL},
+		OpenTofuDistribution:  {name: OpenTofuDistribution, binName: BinName(OpenTofuDistribution), downloadURL: tofuDownloadURL},
+	}
+	defaultDist, ok := distributions[defaultDistribution]
+	if !ok {
+		return nil, fmt.Errorf("unknown terraform distribution %q", defaultDistribution)
+	}
+	// The other distributions default to the version they're set to, or the
+	// version of their binary in our $PATH.
+	for name, d := range distributions {
+		if name == defaultDistribution {
+			continue
+		}
+		if name == OpenTofuDistribution && defaultTofuVersionStr != "" {
+			v, err := version.NewVersion(defaultTofuVersionStr)
+			if err != nil {
+				return nil, errors.Wrapf(err, "parsing default %s version", name)
+			}
+			d.defaultVersion = v
+		} else if path, err := exec.LookPath(d.binName); err == nil {
+			if v, err := getVersion(path); err == nil {
+				versions[d.binName+v.String()] = path
+				d.defaultVersion = v
+			}
+		}
+		distributions[name] = d
+	}
+
+	localPath, err := exec.LookPath(defaultDist.binName)
 	if err != nil && defaultVersionStr == "" {
+		if defaultDist.name == OpenTofuDistribution {
+			return nil, fmt.Errorf("tofu not found in $PATH. Set --%s or download OpenTofu from https://opentofu.org/docs/intro/install/", defaultVersionFlagName)
+		}
 		return nil, fmt.Errorf("terraform not found in $PATH. Set --%s or download terraform from https://www.terraform.io/downloads.html", defaultVersionFlagName)
 	}
 	if err == nil {
@@ -116,7 +241,7 @@ func NewClientWithDefaultVersion(
 		if err != nil {
 			return nil, err
 		}
-		versions[localVersion.String()] = localPath
+		versions[defaultDist.binName+localVersion.String()] = localPath
 		if defaultVersionStr == "" {
 			// If they haven't set a default version, then whatever they had
 			// locally is now the default.
@@ -134,10 +259,10 @@ func NewClientWithDefaultVersion(
 			// Since ensureVersion might end up downloading terraform,
 			// we call it asynchronously so as to not delay server startup.
 			versionsLock.Lock()
-			_, err := ensureVersion(log, tfDownloader, versions, defaultVersion, binDir, tfDownloadURL)
+			_, err := ensureVersion(log, tfDownloader, versions, defaultDist, defaultVersion, binDir)
 			versionsLock.Unlock()
 			if err != nil {
-				log.Err("could not download terraform %s: %s", defaultVersion.String(), err)
+				log.Err("could not download %s %s: %s", defaultDist.name, defaultVersion.String(), err)
 			}
 		}
 
@@ -148,26 +273,48 @@ func NewClientWithDefaultVersion(
 		}
 	}
 
-	// If tfeToken is set, we try to create a ~/.terraformrc file.
+	credentials := make(map[string]string)
 	if tfeToken != "" {
+		credentials[tfeHostname] = tfeToken
+	}
+	for host, token := range registryCredentials {
+		credentials[host] = token
+	}
+	// If there are any credentials, we try to create a ~/.terraformrc file.
+	if len(credentials) > 0 {
 		home, err := homedir.Dir()
 		if err != nil {
 			return nil, errors.Wrap(err, "getting home dir to write ~/.terraformrc file")
 		}
-		if err := generateRCFile(tfeToken, tfeHostname, home); err != nil {
+		if err := generateRCFile(credentials, home); err != nil {
+			return nil, err
+		}
+	}
+	var netrcFile string
+	if len(netrcCredentials) > 0 {
+		home, err := homedir.Dir()
+		if err != nil {
+			return nil, errors.Wrap(err, "getting home dir to write ~/.netrc file")
+		}
+		netrcFile, err = writeNetrcFile(netrcCredentials, home)
+		if err != nil {
 			return nil, err
 		}
 	}
 
 	return &DefaultClient{
 		defaultVersion:          finalDefaultVersion,
+		defaultDistribution:     defaultDistribution,
+		distributions:           distributions,
 		terraformPluginCacheDir: cacheDir,
 		binDir:                  binDir,
 		downloader:              tfDownloader,
-		downloadBaseURL:         tfDownloadURL,
 		versionsLock:            &versionsLock,
 		versions:                versions,
 		usePluginCache:          usePluginCache,
+		commandTimeout:          commandTimeout,
+		credentials:             credentials,
+		netrcFile:               netrcFile,
 	}, nil
 
 }
@@ -178,33 +325,58 @@ func NewTestClient(
 	cacheDir string,
 	tfeToken string,
 	tfeHostname string,
+	registryCredentials map[string]string,
+	netrcCredentials []NetrcCredential,
 	defaultVersionStr string,
 	defaultVersionFlagName string,
+	defaultDistribution string,
 	tfDownloadURL string,
+	tofuDownloadURL string,
+	defaultTofuVersionStr string,
 	tfDownloader Downloader,
-	usePluginCache bool) (*DefaultClient, error) {
+	usePluginCache bool,
+	commandTimeout time.Duration) (*DefaultClient, error) {
 	return NewClientWithDefaultVersion(
 		log,
 		binDir,
 		cacheDir,
 		tfeToken,
 		tfeHostname,
+		registryCredentials,
+		netrcCredentials,
 		defaultVersionStr,
 		defaultVersionFlagName,
+		defaultDistribution,
 		tfDownloadURL,
+		tofuDownloadURL,
+		defaultTofuVersionStr,
 		tfDownloader,
 		usePluginCache,
 		false,
+		commandTimeout,
 	)
 }
 
 // NewClient constructs a terraform client.
 // tfeToken is an optional terraform enterprise token.
+// registryCredentials optionally maps the hostnames of private module
+// registries to their tokens.
+// netrcCredentials are optional credentials for hosts that modules are
+// downloaded from over HTTPS.
 // defaultVersionStr is an optional default terraform version to use unless
 // a specific version is set.
 // defaultVersionFlagName is the name of the flag that sets the default terraform
 // version.
+// defaultDistribution is the distribution of terraform, ex. opentofu, to use
+// unless a specific distribution is set. The default version is a version of
+// this distribution.
+// tfDownloadURL and tofuDownloadURL are where Terraform and OpenTofu versions
+// are downloaded from.
+// defaultTofuVersionStr is an optional default OpenTofu version to use if
+// OpenTofu isn't the default distribution.
 // tfDownloader is used to download terraform versions.
+// commandTimeout is how long terraform can run before it's interrupted. If
+// zero, it can run forever.
 // Will asynchronously download the required version if it doesn't exist already.
 func NewClient(
 	log logging.SimpleLogging,
@@ -212,23 +384,35 @@ func NewClient(
 	cacheDir string,
 	tfeToken string,
 	tfeHostname string,
+	registryCredentials map[string]string,
+	netrcCredentials []NetrcCredential,
 	defaultVersionStr string,
 	defaultVersionFlagName string,
+	defaultDistribution string,
 	tfDownloadURL string,
+	tofuDownloadURL string,
+	defaultTofuVersionStr string,
 	tfDownloader Downloader,
-	usePluginCache bool) (*DefaultClient, error) {
+	usePluginCache bool,
+	commandTimeout time.Duration) (*DefaultClient, error) {
 	return NewClientWithDefaultVersion(
 		log,
 		binDir,
 		cacheDir,
 		tfeToken,
 		tfeHostname,
+		registryCredentials,
+		netrcCredentials,
 		defaultVersionStr,
 		defaultVersionFlagName,
+		defaultDistribution,
 		tfDownloadURL,
+		tofuDownloadURL,
+		defaultTofuVersionStr,
 		tfDownloader,
 		usePluginCache,
 		true,
+		commandTimeout,
 	)
 }
 
@@ -243,15 +427,26 @@ func (c *DefaultClient) TerraformBinDir() string {
 	return c.binDir
 }
 
+// DefaultDistribution returns the distribution of terraform we use if no
+// other distribution is defined.
+func (c *DefaultClient) DefaultDistribution() string {
+	return c.defaultDistribution
+}
+
 // See Client.EnsureVersion.
-func (c *DefaultClient) EnsureVersion(log logging.SimpleLogging, v *version.Version) error {
+func (c *DefaultClient) EnsureVersion(log logging.SimpleLogging, d string, v *version.Version) error {
+	dist, err := c.distribution(d)
+	if err != nil {
+		return err
+	}
 	if v == nil {
-		v = c.defaultVersion
+		if v, err = c.defaultVersionOf(dist); err != nil {
+			return err
+		}
 	}
 
-	var err error
 	c.versionsLock.Lock()
-	_, err = ensureVersion(log, c.downloader, c.versions, v, c.binDir, c.downloadBaseURL)
+	_, err = ensureVersion(log, c.downloader, c.versions, dist, v, c.binDir)
 	c.versionsLock.Unlock()
 	if err != nil {
 		return err
@@ -260,9 +455,280 @@ func (c *DefaultClient) EnsureVersion(log logging.SimpleLogging, v *version.Vers
 	return nil
 }
 
+// See Client.DetectVersion.
+// Exact versions are used as is. If required_version is a constraint, ex.
+// ~> 0.14.0, we use the newest version we already have that satisfies it and
+// otherwise the newest released version that does.
+func (c *DefaultClient) DetectVersion(log logging.SimpleLogging, d string, projectDirectory string) *version.Version {
+	dist, err := c.distribution(d)
+	if err != nil {
+		log.Err("trying to detect required version: %s", err)
+		return nil
+	}
+	module, diags := tfconfig.LoadModule(projectDirectory)
+	if diags.HasErrors() {
+		log.Err("trying to detect required version: %s", diags.Error())
+		return nil
+	}
+	if len(module.RequiredCore) == 0 {
+		log.Info("no required_version setting found in terraform configuration")
+		return c.distributionDefaultVersion(log, dist)
+	}
+	// If required_version is set in multiple places then all the constraints
+	// must be satisfied.
+	requiredVersionSetting := strings.Join(module.RequiredCore, ",")
+
+	if matched := exactVersionRegex.FindStringSubmatch(requiredVersionSetting); len(matched) > 0 {
+		v, err := version.NewVersion(matched[1])
+		if err == nil {
+			log.Info("detected module requires version: %q", v.String())
+			return v
+		}
+	}
+	constraints, err := version.NewConstraint(requiredVersionSetting)
+	if err != nil {
+		log.Err("parsing required_version setting %q: %s", requiredVersionSetting, err)
+		return nil
+	}
+
+	c.versionsLock.Lock()
+	localVersions := c.localVersions(dist)
+	c.versionsLock.Unlock()
+	if v := newestMatchingVersion(localVersions, constraints); v != nil {
+		log.Info("detected module requires version %q, using local version %q", requiredVersionSetting, v.String())
+		return v
+	}
+
+	releasedVersions, err := c.listReleasedVersions(dist)
+	if err != nil {
+		log.Err("listing %s versions to satisfy required_version %q: %s", dist.name, requiredVersionSetting, err)
+		return nil
+	}
+	if v := newestMatchingVersion(releasedVersions, constraints); v != nil {
+		log.Info("detected module requires version %q, using version %q", requiredVersionSetting, v.String())
+		return v
+	}
+	log.Info("no %s version satisfies required_version %q", dist.name, requiredVersionSetting)
+	return nil
+}
+
+// distributionDefaultVersion returns the version of d to use for projects
+// that don't require one. It returns nil for the default distribution since
+// callers use the default version then. Other distributions use their own
+// default version if they have one and otherwise their newest release so
+// they're never run with a version of a different distribution.
+func (c *DefaultClient) distributionDefaultVersion(log logging.SimpleLogging, d distribution) *version.Version {
+	if d.name == c.defaultDistribution {
+		return nil
+	}
+	if d.defaultVersion != nil {
+		log.Info("using default %s version %q", d.name, d.defaultVersion.String())
+		return d.defaultVersion
+	}
+	releasedVersions, err := c.listReleasedVersions(d)
+	if err != nil {
+		log.Err("listing %s versions to find the newest release: %s", d.name, err)
+		return nil
+	}
+	newest := newestMatchingVersion(releasedVersions, version.Constraints{})
+	if newest == nil {
+		log.Err("no %s versions are released", d.name)
+		return nil
+	}
+	log.Info("no default %s version, using its newest release %q", d.name, newest.String())
+	return newest
+}
+
+// localVersions returns the versions of distribution d that we don't need
+// to download. Callers must hold versionsLock.
+func (c *DefaultClient) localVersions(d distribution) []*version.Version {
+	var versions []*version.Version
+	if c.defaultVersion != nil && d.name == c.defaultDistribution {
+		versions = append(versions, c.defaultVersion)
+	}
+	for binFile := range c.versions {
+		if !strings.HasPrefix(binFile, d.binName) {
+			continue
+		}
+		if v, err := version.NewVersion(strings.TrimPrefix(binFile, d.binName)); err == nil {
+			versions = append(versions, v)
+		}
+	}
+	// Versions in our bin dir won't be in the versions map if they were
+	// downloaded before Atlantis was restarted.
+	binFiles, _ := ioutil.ReadDir(c.binDir)
+	for _, f := range binFiles {
+		if !strings.HasPrefix(f.Name(), d.binName) {
+			continue
+		}
+		if v, err := version.NewVersion(strings.TrimPrefix(f.Name(), d.binName)); err == nil {
+			versions = append(versions, v)
+		}
+	}
+	return versions
+}
+
+// listReleasedVersions returns the versions of distribution d available to
+// download. Terraform versions are listed by the index at its download URL
+// and OpenTofu versions by opentofuIndexURL.
+func (c *DefaultClient) listReleasedVersions(d distribution) ([]*version.Version, error) {
+	tmpDir, err := ioutil.TempDir("", "terraform-index")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir) // nolint: errcheck
+
+	indexFile := filepath.Join(tmpDir, "index.json")
+	indexURL := fmt.Sprintf("%s/terraform/index.json", d.downloadURL)
+	if d.name == OpenTofuDistribution {
+		indexURL = opentofuIndexURL
+	}
+	if err := c.downloader.GetFile(indexFile, indexURL); err != nil {
+		return nil, errors.Wrapf(err, "downloading %q", indexURL)
+	}
+	contents, err := ioutil.ReadFile(indexFile) // nolint: gosec
+	if err != nil {
+		return nil, err
+	}
+
+	var vStrs []string
+	if d.name == OpenTofuDistribution {
+		var index struct {
+			Versions []struct {
+				ID string `json:"id"`
+			} `json:"versions"`
+		}
+		if err := json.Unmarshal(contents, &index); err != nil {
+			return nil, errors.Wrapf(err, "parsing %q", indexURL)
+		}
+		for _, v := range index.Versions {
+			vStrs = append(vStrs, v.ID)
+		}
+	} else {
+		var index struct {
+			Versions map[string]interface{} `json:"versions"`
+		}
+		if err := json.Unmarshal(contents, &index); err != nil {
+			return nil, errors.Wrapf(err, "parsing %q", indexURL)
+		}
+		for vStr := range index.Versions {
+			vStrs = append(vStrs, vStr)
+		}
+	}
+
+	var versions []*version.Version
+	for _, vStr := range vStrs {
+		if v, err := version.NewVersion(vStr); err == nil {
+			versions = append(versions, v)
+		}
+	}
+	return versions, nil
+}
+
+// Versions are the versions of a distribution of terraform that are
+// installed and that can be installed.
+type Versions struct {
+	// Distribution is the name of the distribution, ex. terraform.
+	Distribution string
+	// Default is the default version, or nil if Distribution isn't the
+	// default distribution.
+	Default *version.Version
+	// Installed are the versions that don't need to be downloaded, newest
+	// first.
+	Installed []*version.Version
+	// Available are the released versions that aren't installed, newest
+	// first.
+	Available []*version.Version
+}
+
+// ListVersions returns the versions of distribution d. If d is empty, the
+// default distribution is used. If the released versions can't be listed,
+// the installed versions are returned along with the error.
+func (c *DefaultClient) ListVersions(d string) (Versions, error) {
+	dist, err := c.distribution(d)
+	if err != nil {
+		return Versions{}, err
+	}
+	versions := Versions{Distribution: dist.name}
+	versions.Default, _ = c.defaultVersionOf(dist)
+
+	c.versionsLock.Lock()
+	localVersions := c.localVersions(dist)
+	c.versionsLock.Unlock()
+	installed := make(map[string]bool)
+	for _, v := range localVersions {
+		if !installed[v.String()] {
+			installed[v.String()] = true
+			versions.Installed = append(versions.Installed, v)
+		}
+	}
+	sort.Sort(sort.Reverse(version.Collection(versions.Installed)))
+
+	releasedVersions, err := c.listReleasedVersions(dist)
+	if err != nil {
+		return versions, errors.Wrapf(err, "listing %s versions", dist.name)
+	}
+	for _, v := range releasedVersions {
+		if !installed[v.String()] {
+			versions.Available = append(versions.Available, v)
+		}
+	}
+	sort.Sort(sort.Reverse(version.Collection(versions.Available)))
+	return versions, nil
+}
+
+// defaultVersionOf returns the default version of distribution d. It errors
+// if d isn't the default distribution and has no default version.
+func (c *DefaultClient) defaultVersionOf(d distribution) (*version.Version, error) {
+	if d.name == c.defaultDistribution {
+		return c.defaultVersion, nil
+	}
+	if d.defaultVersion == nil {
+		return nil, fmt.Errorf("there's no default %s version, set terraform_version on the project", d.name)
+	}
+	return d.defaultVersion, nil
+}
+
+// distribution returns the distribution called name, or the default
+// distribution if name is empty.
+func (c *DefaultClient) distribution(name string) (distribution, error) {
+	if name == "" {
+		name = c.defaultDistribution
+	}
+	d, ok := c.distributions[name]
+	if !ok {
+		return distribution{}, fmt.Errorf("unknown terraform distribution %q", name)
+	}
+	return d, nil
+}
+
+// newestMatchingVersion returns the newest version in versions that satisfies
+// constraints, or nil if none do. Pre-releases are never matched.
+func newestMatchingVersion(versions []*version.Version, constraints version.Constraints) *version.Version {
+	var newest *version.Version
+	for _, v := range versions {
+		if v.Prerelease() != "" || !constraints.Check(v) {
+			continue
+		}
+		if newest == nil || v.GreaterThan(newest) {
+			newest = v
+		}
+	}
+	return newest
+}
+
+// interruptGracePeriod is how long terraform has to exit after it's
+// interrupted before it's killed. Terraform waits for the operations in
+// progress to finish and writes its state when it's interrupted, so this is
+// generous.
+const interruptGracePeriod = 5 * time.Minute
+
 // See Client.RunCommandWithVersion.
-func (c *DefaultClient) RunCommandWithVersion(log logging.SimpleLogging, path string, args []string, customEnvVars map[string]string, v *version.Version, workspace string) (string, error) {
-	tfCmd, cmd, err := c.prepCmd(log, v, workspace, path, args)
+func (c *DefaultClient) RunCommandWithVersion(ctx context.Context, log logging.SimpleLogging, path string, args []string, customEnvVars map[string]string, d string, v *version.Version, workspace string) (_ string, err error) {
+	ctx, span := c.startSpan(ctx, args, v, workspace)
+	defer func() { tracing.End(span, err) }()
+
+	tfCmd, cmd, err := c.prepCmd(log, d, v, workspace, path, args)
 	if err != nil {
 		return "", err
 	}
@@ -271,32 +737,141 @@ func (c *DefaultClient) RunCommandWithVersion(log logging.SimpleLogging, path st
 		envVars = append(envVars, fmt.Sprintf("%s=%s", key, val))
 	}
 	cmd.Env = envVars
-	out, err := cmd.CombinedOutput()
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	// Only init installs providers so only it needs the providers that are
+	// already cached and can add to the cache.
+	syncPluginCache := c.usePluginCache && len(args) > 0 && args[0] == "init"
+	if syncPluginCache {
+		if err := c.pluginCache().warm(c.pluginCache().runDir(path)); err != nil {
+			log.Warn("running init without cached providers: %s", err)
+		}
+	}
+
+	runCtx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	stop, err := StartInterruptible(runCtx, log, cmd)
+	if err == nil {
+		err = cmd.Wait()
+		stop()
+	}
 	if err != nil {
-		err = errors.Wrapf(err, "running %q in %q", tfCmd, path)
+		err = c.runErr(ctx, runCtx, err, tfCmd, path)
 		log.Err(err.Error())
-		return string(out), err
+		return out.String(), err
 	}
 	log.Info("successfully ran %q in %q", tfCmd, path)
-	return string(out), nil
+	if syncPluginCache {
+		if err := c.pluginCache().promote(c.pluginCache().runDir(path)); err != nil {
+			log.Warn("unable to cache providers: %s", err)
+		}
+	}
+	return out.String(), nil
+}
+
+// pluginCache returns the manager of the plugin cache shared by all runs.
+func (c *DefaultClient) pluginCache() pluginCache {
+	return pluginCache{dir: c.terraformPluginCacheDir}
+}
+
+// StartInterruptible starts cmd in its own process group and interrupts the
+// group if ctx is cancelled. The whole group is signalled so that terraform,
+// or whatever else the command runs, is interrupted too and not just the
+// shell running it. If the group hasn't
+// exited after interruptGracePeriod, it's killed. The returned func must be
+// called once cmd has exited.
+func StartInterruptible(ctx context.Context, log logging.SimpleLogging, cmd *exec.Cmd) (func(), error) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	exited := make(chan struct{})
+	go func() {
+		select {
+		case <-exited:
+			return
+		case <-ctx.Done():
+		}
+		// The process group's id is the pid of its leader.
+		pgid := cmd.Process.Pid
+		log.Info("interrupting command in %q: %s", cmd.Dir, ctx.Err())
+		if err := syscall.Kill(-pgid, syscall.SIGINT); err != nil {
+			log.Warn("unable to interrupt process group %d: %s", pgid, err)
+		}
+		select {
+		case <-exited:
+		case <-time.After(interruptGracePeriod):
+			log.Warn("killing process group %d since it didn't exit %s after being interrupted", pgid, interruptGracePeriod)
+			if err := syscall.Kill(-pgid, syscall.SIGKILL); err != nil {
+				log.Warn("unable to kill process group %d: %s", pgid, err)
+			}
+		}
+	}()
+	return func() { close(exited) }, nil
 }
 
-// prepCmd builds a ready to execute command based on the version of terraform
-// v, and args. It returns a printable representation of the command that will
-// be run and the actual command.
-func (c *DefaultClient) prepCmd(log logging.SimpleLogging, v *version.Version, workspace string, path string, args []string) (string, *exec.Cmd, error) {
+// startSpan starts the span of the terraform command that runs args. It
+// covers downloading terraform if it isn't installed yet.
+func (c *DefaultClient) startSpan(ctx context.Context, args []string, v *version.Version, workspace string) (context.Context, trace.Span) {
+	name := "terraform"
+	if len(args) > 0 {
+		name += " " + args[0]
+	}
 	if v == nil {
 		v = c.defaultVersion
 	}
+	attrs := []attribute.KeyValue{tracing.WorkspaceKey.String(workspace)}
+	if v != nil {
+		attrs = append(attrs, tracing.TerraformVersionKey.String(v.String()))
+	}
+	return tracing.Start(ctx, name, attrs...)
+}
 
+// withTimeout returns a context that's also cancelled once commandTimeout
+// has passed, if it's set.
+func (c *DefaultClient) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.commandTimeout == 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.commandTimeout)
+}
+
+// runErr wraps err, the error tfCmd exited with. If it was interrupted
+// because it ran for longer than commandTimeout, the error says so instead
+// of just showing the signal. ctx is the caller's context and runCtx is the
+// one returned by withTimeout.
+func (c *DefaultClient) runErr(ctx context.Context, runCtx context.Context, err error, tfCmd string, path string) error {
+	if ctx.Err() == nil && runCtx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("running %q in %q: timed out after %s", tfCmd, path, c.commandTimeout)
+	}
+	return errors.Wrapf(err, "running %q in %q", tfCmd, path)
+}
+
+// prepCmd builds a ready to execute command based on the distribution d and
+// version v of terraform, and args. It returns a printable representation of
+// the command that will be run and the actual command.
+func (c *DefaultClient) prepCmd(log logging.SimpleLogging, d string, v *version.Version, workspace string, path string, args []string) (string, *exec.Cmd, error) {
 	var binPath string
 	if c.overrideTF != "" {
 		// This is only set during testing.
 		binPath = c.overrideTF
+		if v == nil {
+			v = c.defaultVersion
+		}
 	} else {
-		var err error
+		dist, err := c.distribution(d)
+		if err != nil {
+			return "", nil, err
+		}
+		if v == nil {
+			if v, err = c.defaultVersionOf(dist); err != nil {
+				return "", nil, err
+			}
+		}
 		c.versionsLock.Lock()
-		binPath, err = ensureVersion(log, c.downloader, c.versions, v, c.binDir, c.downloadBaseURL)
+		binPath, err = ensureVersion(log, c.downloader, c.versions, dist, v, c.binDir)
 		c.versionsLock.Unlock()
 		if err != nil {
 			return "", nil, err
@@ -315,7 +890,15 @@ func (c *DefaultClient) prepCmd(log logging.SimpleLogging, v *version.Version, w
 		fmt.Sprintf("DIR=%s", path),
 	}
 	if c.usePluginCache {
-		envVars = append(envVars, fmt.Sprintf("TF_PLUGIN_CACHE_DIR=%s", c.terraformPluginCacheDir))
+		envVars = append(envVars, fmt.Sprintf("TF_PLUGIN_CACHE_DIR=%s", c.pluginCache().runDir(path)))
+	}
+	// Terraform >= 1.2 reads credentials from TF_TOKEN_<host> env vars, which
+	// take precedence over ~/.terraformrc.
+	for _, host := range sortedHosts(c.credentials) {
+		envVars = append(envVars, fmt.Sprintf("%s=%s", TokenEnvVar(host), c.credentials[host]))
+	}
+	if c.netrcFile != "" {
+		envVars = append(envVars, fmt.Sprintf("NETRC=%s", c.netrcFile))
 	}
 	// Append current Atlantis process's environment variables, ex.
 	// AWS_ACCESS_KEY.
@@ -341,21 +924,26 @@ type Line struct {
 // Callers can use the input channel to pass stdin input to the command.
 // If any error is passed on the out channel, there will be no
 // further output (so callers are free to exit).
-func (c *DefaultClient) RunCommandAsync(log logging.SimpleLogging, path string, args []string, customEnvVars map[string]string, v *version.Version, workspace string) (chan<- string, <-chan Line) {
+// If ctx is cancelled or terraform runs for longer than the command timeout,
+// it's interrupted and the error is passed on the out channel once it exits.
+func (c *DefaultClient) RunCommandAsync(ctx context.Context, log logging.SimpleLogging, path string, args []string, customEnvVars map[string]string, d string, v *version.Version, workspace string) (chan<- string, <-chan Line) {
 	outCh := make(chan Line)
 	inCh := make(chan string)
 
 	// We start a goroutine to do our work asynchronously and then immediately
 	// return our channels.
 	go func() {
+		ctx, span := c.startSpan(ctx, args, v, workspace)
+		var err error
 
-		// Ensure we close our channels when we exit.
+		// Ensure we close our channels and end the span when we exit.
 		defer func() {
+			tracing.End(span, err)
 			close(outCh)
 			close(inCh)
 		}()
 
-		tfCmd, cmd, err := c.prepCmd(log, v, workspace, path, args)
+		tfCmd, cmd, err := c.prepCmd(log, d, v, workspace, path, args)
 		if err != nil {
 			log.Err(err.Error())
 			outCh <- Line{Err: err}
@@ -371,7 +959,9 @@ func (c *DefaultClient) RunCommandAsync(log logging.SimpleLogging, path string,
 		cmd.Env = envVars
 
 		log.Debug("starting %q in %q", tfCmd, path)
-		err = cmd.Start()
+		runCtx, cancel := c.withTimeout(ctx)
+		defer cancel()
+		stop, err := StartInterruptible(runCtx, log, cmd)
 		if err != nil {
 			err = errors.Wrapf(err, "running %q in %q", tfCmd, path)
 			log.Err(err.Error())
@@ -417,10 +1007,11 @@ func (c *DefaultClient) RunCommandAsync(log logging.SimpleLogging, path string,
 
 		// Wait for the command to complete.
 		err = cmd.Wait()
+		stop()
 
 		// We're done now. Send an error if there was one.
 		if err != nil {
-			err = errors.Wrapf(err, "running %q in %q", tfCmd, path)
+			err = c.runErr(ctx, runCtx, err, tfCmd, path)
 			log.Err(err.Error())
 			outCh <- Line{Err: err}
 		} else {
@@ -442,19 +1033,20 @@ func MustConstraint(v string) version.Constraints {
 	return c
 }
 
-// ensureVersion returns the path to a terraform binary of version v.
+// ensureVersion returns the path to a binary of version v of distribution d.
 // It will download this version if we don't have it.
-func ensureVersion(log logging.SimpleLogging, dl Downloader, versions map[string]string, v *version.Version, binDir string, downloadURL string) (string, error) {
-	if binPath, ok := versions[v.String()]; ok {
+func ensureVersion(log logging.SimpleLogging, dl Downloader, versions map[string]string, d distribution, v *version.Version, binDir string) (string, error) {
+	binFile := d.binName + v.String()
+	if binPath, ok := versions[binFile]; ok {
 		return binPath, nil
 	}
 
-	// This tf version might not yet be in the versions map even though it
+	// This version might not yet be in the versions map even though it
 	// exists on disk. This would happen if users have manually added
-	// terraform{version} binaries. In this case we don't want to re-download.
-	binFile := "terraform" + v.String()
+	// terraform{version} or tofu{version} binaries. In this case we don't
+	// want to re-download.
 	if binPath, err := exec.LookPath(binFile); err == nil {
-		versions[v.String()] = binPath
+		versions[binFile] = binPath
 		return binPath, nil
 	}
 
@@ -462,30 +1054,50 @@ func ensureVersion(log logging.SimpleLogging, dl Downloader, versions map[string
 	// This could happen if Atlantis was restarted without losing its disk.
 	dest := filepath.Join(binDir, binFile)
 	if _, err := os.Stat(dest); err == nil {
-		versions[v.String()] = dest
+		versions[binFile] = dest
 		return dest, nil
 	}
-	log.Info("could not find terraform version %s in PATH or %s, downloading from %s", v.String(), binDir, downloadURL)
-	urlPrefix := fmt.Sprintf("%s/terraform/%s/terraform_%s", downloadURL, v.String(), v.String())
+	log.Info("could not find %s version %s in PATH or %s, downloading from %s", d.name, v.String(), binDir, d.downloadURL)
+	// Terraform releases are at <url>/terraform/<version>/ and OpenTofu
+	// releases, like on GitHub, at <url>/v<version>/.
+	urlPrefix := fmt.Sprintf("%s/terraform/%s/terraform_%s", d.downloadURL, v.String(), v.String())
+	if d.name == OpenTofuDistribution {
+		urlPrefix = fmt.Sprintf("%s/v%s/tofu_%s", d.downloadURL, v.String(), v.String())
+	}
 	binURL := fmt.Sprintf("%s_%s_%s.zip", urlPrefix, runtime.GOOS, runtime.GOARCH)
 	checksumURL := fmt.Sprintf("%s_SHA256SUMS", urlPrefix)
 	fullSrcURL := fmt.Sprintf("%s?checksum=file:%s", binURL, checksumURL)
-	if err := dl.GetFile(dest, fullSrcURL); err != nil {
-		return "", errors.Wrapf(err, "downloading terraform version %s at %q", v.String(), fullSrcURL)
+	if d.name == OpenTofuDistribution {
+		// OpenTofu archives also contain its license and readme so we extract
+		// them to a temporary dir and only keep the binary.
+		tmpDir := dest + ".download"
+		defer os.RemoveAll(tmpDir) // nolint: errcheck
+		if err := dl.GetAny(tmpDir, fullSrcURL); err != nil {
+			return "", errors.Wrapf(err, "downloading %s version %s at %q", d.name, v.String(), fullSrcURL)
+		}
+		if err := os.Rename(filepath.Join(tmpDir, d.binName), dest); err != nil {
+			return "", errors.Wrapf(err, "moving downloaded %s version %s to %s", d.name, v.String(), dest)
+		}
+	} else if err := dl.GetFile(dest, fullSrcURL); err != nil {
+		return "", errors.Wrapf(err, "downloading %s version %s at %q", d.name, v.String(), fullSrcURL)
 	}
 
-	log.Info("downloaded terraform %s to %s", v.String(), dest)
-	versions[v.String()] = dest
+	log.Info("downloaded %s %s to %s", d.name, v.String(), dest)
+	versions[binFile] = dest
 	return dest, nil
 }
 
-// generateRCFile generates a .terraformrc file containing config for tfeToken
-// and hostname tfeHostname.
+// generateRCFile generates a .terraformrc file containing a credentials block
+// for each host in credentials, which maps hostnames to tokens.
 // It will create the file in home/.terraformrc.
-func generateRCFile(tfeToken string, tfeHostname string, home string) error {
+func generateRCFile(credentials map[string]string, home string) error {
 	const rcFilename = ".terraformrc"
 	rcFile := filepath.Join(home, rcFilename)
-	config := fmt.Sprintf(rcFileContents, tfeHostname, tfeToken)
+	var blocks []string
+	for _, host := range sortedHosts(credentials) {
+		blocks = append(blocks, fmt.Sprintf(rcFileContents, host, credentials[host]))
+	}
+	config := strings.Join(blocks, "\n\n")
 
 	// If there is already a .terraformrc file and its contents aren't exactly
 	// what we would have written to it, then we error out because we don't
@@ -496,7 +1108,7 @@ func generateRCFile(tfeToken string, tfeHostname string, home string) error {
 			return errors.Wrapf(err, "trying to read %s to ensure we're not overwriting it", rcFile)
 		}
 		if config != string(currContents) {
-			return fmt.Errorf("can't write TFE token to %s because that file has contents that would be overwritten", rcFile)
+			return fmt.Errorf("can't write credentials to %s because that file has contents that would be overwritten", rcFile)
 		}
 		// Otherwise we don't need to write the file because it already has
 		// what we need.
@@ -504,11 +1116,145 @@ func generateRCFile(tfeToken string, tfeHostname string, home string) error {
 	}
 
 	if err := ioutil.WriteFile(rcFile, []byte(config), 0600); err != nil {
-		return errors.Wrapf(err, "writing generated %s file with TFE token to %s", rcFilename, rcFile)
+		return errors.Wrapf(err, "writing generated %s file with credentials to %s", rcFilename, rcFile)
 	}
 	return nil
 }
 
+// NetrcCredential is the login for a host that modules are downloaded from
+// over HTTPS, ex. by git or terraform's http getter.
+type NetrcCredential struct {
+	Host  string
+	User  string
+	Token string
+}
+
+// line returns the entry for c in a .netrc file.
+func (c NetrcCredential) line() string {
+	return fmt.Sprintf("machine %s login %s password %s", c.Host, c.User, c.Token)
+}
+
+// writeNetrcFile adds a line for each of credentials to home/.netrc, creating
+// it if it doesn't exist, and returns its path. Entries the file already has
+// for other hosts are kept but it errors instead of replacing different
+// credentials for one of the same hosts.
+func writeNetrcFile(credentials []NetrcCredential, home string) (string, error) {
+	const netrcFilename = ".netrc"
+	netrcFile := filepath.Join(home, netrcFilename)
+
+	var currLines []string
+	if _, err := os.Stat(netrcFile); err == nil {
+		currContents, err := ioutil.ReadFile(netrcFile) // nolint: gosec
+		if err != nil {
+			return "", errors.Wrapf(err, "trying to read %s to ensure we're not overwriting it", netrcFile)
+		}
+		currLines = strings.Split(string(currContents), "\n")
+	}
+
+	contents := strings.Join(currLines, "\n")
+	changed := false
+	for _, c := range credentials {
+		exists := false
+		for _, l := range currLines {
+			fields := strings.Fields(l)
+			if len(fields) < 2 || fields[0] != "machine" || fields[1] != c.Host {
+				continue
+			}
+			if l != c.line() {
+				return "", fmt.Errorf("can't write credentials for %s to %s because that file has other credentials for it", c.Host, netrcFile)
+			}
+			exists = true
+		}
+		if exists {
+			continue
+		}
+		if contents != "" && !strings.HasSuffix(contents, "\n") {
+			contents += "\n"
+		}
+		contents += c.line()
+		changed = true
+	}
+	// If the file already has all the credentials we don't need to write it.
+	if !changed {
+		return netrcFile, nil
+	}
+
+	if err := ioutil.WriteFile(netrcFile, []byte(contents), 0600); err != nil {
+		return "", errors.Wrapf(err, "writing generated %s file with credentials to %s", netrcFilename, netrcFile)
+	}
+	return netrcFile, nil
+}
+
+// ParseNetrcCredentials parses credentials in the form
+// host1=user1:token1,host2=user2:token2.
+func ParseNetrcCredentials(s string) ([]NetrcCredential, error) {
+	var credentials []NetrcCredential
+	if s == "" {
+		return credentials, nil
+	}
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(s, ",") {
+		hostLogin := strings.SplitN(strings.TrimSpace(entry), "=", 2)
+		if len(hostLogin) != 2 {
+			// The entry isn't included since it may contain a token.
+			return nil, errors.New("credentials must be in the form host=user:token")
+		}
+		userToken := strings.SplitN(hostLogin[1], ":", 2)
+		if len(userToken) != 2 {
+			return nil, errors.New("credentials must be in the form host=user:token")
+		}
+		c := NetrcCredential{Host: hostLogin[0], User: userToken[0], Token: userToken[1]}
+		for _, field := range []string{c.Host, c.User, c.Token} {
+			if field == "" || strings.ContainsAny(field, " \t\n") {
+				return nil, errors.New("credentials must be in the form host=user:token and can't contain whitespace")
+			}
+		}
+		if seen[c.Host] {
+			return nil, fmt.Errorf("%s has more than one set of credentials", c.Host)
+		}
+		seen[c.Host] = true
+		credentials = append(credentials, c)
+	}
+	return credentials, nil
+}
+
+// TokenEnvVar returns the name of the env var terraform reads the token for
+// host from, ex. TF_TOKEN_app_terraform_io. Periods in host are encoded as
+// underscores and hyphens as double underscores.
+func TokenEnvVar(host string) string {
+	encoded := strings.NewReplacer("-", "__", ".", "_").Replace(host)
+	return "TF_TOKEN_" + encoded
+}
+
+// ParseRegistryCredentials parses credentials in the form
+// host1=token1,host2=token2 into a map from hostnames to tokens.
+func ParseRegistryCredentials(s string) (map[string]string, error) {
+	credentials := make(map[string]string)
+	if s == "" {
+		return credentials, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" || strings.TrimSpace(kv[1]) == "" {
+			// The pair isn't included since it may contain a token.
+			return nil, errors.New("credentials must be in the form host=token")
+		}
+		credentials[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return credentials, nil
+}
+
+// sortedHosts returns the hostnames in credentials in order so that the files
+// and env vars generated from them are stable.
+func sortedHosts(credentials map[string]string) []string {
+	var hosts []string
+	for host := range credentials {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
 func getVersion(tfBinary string) (*version.Version, error) {
 	versionOutBytes, err := exec.Command(tfBinary, "version").Output() // #nosec
 	versionOutput := string(versionOutBytes)
@@ -523,8 +1269,8 @@ func getVersion(tfBinary string) (*version.Version, error) {
 }
 
 // rcFileContents is a format string to be used with Sprintf that can be used
-// to generate the contents of a ~/.terraformrc file for authenticating with
-// Terraform Enterprise.
+// to generate a credentials block of a ~/.terraformrc file for authenticating
+// with Terraform Enterprise or a private module registry.
 var rcFileContents = `credentials "%s" {
   token = %q
 }`
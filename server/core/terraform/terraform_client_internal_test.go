@@ -1,13 +1,18 @@
 package terraform
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/hashicorp/go-getter"
 	version "github.com/hashicorp/go-version"
 	"github.com/runatlantis/atlantis/server/logging"
 	. "github.com/runatlantis/atlantis/testing"
@@ -18,7 +23,7 @@ func TestGenerateRCFile_WritesFile(t *testing.T) {
 	tmp, cleanup := TempDir(t)
 	defer cleanup()
 
-	err := generateRCFile("token", "hostname", tmp)
+	err := generateRCFile(map[string]string{"hostname": "token"}, tmp)
 	Ok(t, err)
 
 	expContents := `credentials "hostname" {
@@ -29,6 +34,29 @@ func TestGenerateRCFile_WritesFile(t *testing.T) {
 	Equals(t, expContents, string(actContents))
 }
 
+// Test that we write a block for each host, sorted by hostname.
+func TestGenerateRCFile_MultipleHosts(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+
+	err := generateRCFile(map[string]string{
+		"registry.company.com": "registry-token",
+		"app.terraform.io":     "tfe-token",
+	}, tmp)
+	Ok(t, err)
+
+	expContents := `credentials "app.terraform.io" {
+  token = "tfe-token"
+}
+
+credentials "registry.company.com" {
+  token = "registry-token"
+}`
+	actContents, err := ioutil.ReadFile(filepath.Join(tmp, ".terraformrc"))
+	Ok(t, err)
+	Equals(t, expContents, string(actContents))
+}
+
 // Test that if the file already exists and its contents will be modified if
 // we write our config that we error out.
 func TestGenerateRCFile_WillNotOverwrite(t *testing.T) {
@@ -39,8 +67,8 @@ func TestGenerateRCFile_WillNotOverwrite(t *testing.T) {
 	err := ioutil.WriteFile(rcFile, []byte("contents"), 0600)
 	Ok(t, err)
 
-	actErr := generateRCFile("token", "hostname", tmp)
-	expErr := fmt.Sprintf("can't write TFE token to %s because that file has contents that would be overwritten", tmp+"/.terraformrc")
+	actErr := generateRCFile(map[string]string{"hostname": "token"}, tmp)
+	expErr := fmt.Sprintf("can't write credentials to %s because that file has contents that would be overwritten", tmp+"/.terraformrc")
 	ErrEquals(t, expErr, actErr)
 }
 
@@ -57,7 +85,7 @@ func TestGenerateRCFile_NoErrIfContentsSame(t *testing.T) {
 	err := ioutil.WriteFile(rcFile, []byte(contents), 0600)
 	Ok(t, err)
 
-	err = generateRCFile("token", "app.terraform.io", tmp)
+	err = generateRCFile(map[string]string{"app.terraform.io": "token"}, tmp)
 	Ok(t, err)
 }
 
@@ -72,15 +100,15 @@ func TestGenerateRCFile_ErrIfCannotRead(t *testing.T) {
 	Ok(t, err)
 
 	expErr := fmt.Sprintf("trying to read %s to ensure we're not overwriting it: open %s: permission denied", rcFile, rcFile)
-	actErr := generateRCFile("token", "hostname", tmp)
+	actErr := generateRCFile(map[string]string{"hostname": "token"}, tmp)
 	ErrEquals(t, expErr, actErr)
 }
 
 // Test that if we can't write, we error out.
 func TestGenerateRCFile_ErrIfCannotWrite(t *testing.T) {
 	rcFile := "/this/dir/does/not/exist/.terraformrc"
-	expErr := fmt.Sprintf("writing generated .terraformrc file with TFE token to %s: open %s: no such file or directory", rcFile, rcFile)
-	actErr := generateRCFile("token", "hostname", "/this/dir/does/not/exist")
+	expErr := fmt.Sprintf("writing generated .terraformrc file with credentials to %s: open %s: no such file or directory", rcFile, rcFile)
+	actErr := generateRCFile(map[string]string{"hostname": "token"}, "/this/dir/does/not/exist")
 	ErrEquals(t, expErr, actErr)
 }
 
@@ -105,12 +133,136 @@ func TestDefaultClient_RunCommandWithVersion_EnvVars(t *testing.T) {
 		"DIR=$DIR",
 	}
 	log := logging.NewNoopLogger(t)
-	out, err := client.RunCommandWithVersion(log, tmp, args, map[string]string{}, nil, "workspace")
+	out, err := client.RunCommandWithVersion(context.Background(), log, tmp, args, map[string]string{}, "", nil, "workspace")
 	Ok(t, err)
-	exp := fmt.Sprintf("TF_IN_AUTOMATION=true TF_PLUGIN_CACHE_DIR=%s WORKSPACE=workspace ATLANTIS_TERRAFORM_VERSION=0.11.11 DIR=%s\n", tmp, tmp)
+	exp := fmt.Sprintf("TF_IN_AUTOMATION=true TF_PLUGIN_CACHE_DIR=%s WORKSPACE=workspace ATLANTIS_TERRAFORM_VERSION=0.11.11 DIR=%s\n", filepath.Join(tmp, ".terraform", "atlantis-plugin-cache"), tmp)
 	Equals(t, exp, out)
 }
 
+// Test that it sets a TF_TOKEN_<host> env var for each host it has
+// credentials for.
+func TestDefaultClient_RunCommandWithVersion_TokenEnvVars(t *testing.T) {
+	v, err := version.NewVersion("1.2.0")
+	Ok(t, err)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	client := &DefaultClient{
+		defaultVersion:          v,
+		terraformPluginCacheDir: tmp,
+		overrideTF:              "echo",
+		credentials: map[string]string{
+			"app.terraform.io":        "tfe-token",
+			"my-registry.company.com": "registry-token",
+		},
+	}
+
+	args := []string{
+		"$TF_TOKEN_app_terraform_io",
+		"$TF_TOKEN_my__registry_company_com",
+	}
+	log := logging.NewNoopLogger(t)
+	out, err := client.RunCommandWithVersion(context.Background(), log, tmp, args, map[string]string{}, "", nil, "workspace")
+	Ok(t, err)
+	Equals(t, "tfe-token registry-token\n", out)
+}
+
+func TestParseRegistryCredentials(t *testing.T) {
+	credentials, err := ParseRegistryCredentials("")
+	Ok(t, err)
+	Equals(t, map[string]string{}, credentials)
+
+	credentials, err = ParseRegistryCredentials("registry.company.com=token1, artifactory.company.com = token=2")
+	Ok(t, err)
+	Equals(t, map[string]string{
+		"registry.company.com":    "token1",
+		"artifactory.company.com": "token=2",
+	}, credentials)
+
+	for _, s := range []string{"registry.company.com", "=token", "registry.company.com=", "a=b,,c=d"} {
+		_, err = ParseRegistryCredentials(s)
+		ErrEquals(t, "credentials must be in the form host=token", err)
+	}
+}
+
+// Test that it points NETRC at the .netrc file it wrote.
+func TestDefaultClient_RunCommandWithVersion_NetrcEnvVar(t *testing.T) {
+	v, err := version.NewVersion("1.2.0")
+	Ok(t, err)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	client := &DefaultClient{
+		defaultVersion:          v,
+		terraformPluginCacheDir: tmp,
+		overrideTF:              "echo",
+		netrcFile:               filepath.Join(tmp, ".netrc"),
+	}
+
+	log := logging.NewNoopLogger(t)
+	out, err := client.RunCommandWithVersion(context.Background(), log, tmp, []string{"$NETRC"}, map[string]string{}, "", nil, "workspace")
+	Ok(t, err)
+	Equals(t, filepath.Join(tmp, ".netrc")+"\n", out)
+}
+
+func TestWriteNetrcFile(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	credentials := []NetrcCredential{
+		{Host: "git.company.com", User: "atlantis", Token: "token1"},
+		{Host: "modules.company.com", User: "bot", Token: "token2"},
+	}
+	netrcFile := filepath.Join(tmp, ".netrc")
+
+	t.Log("the file is created if it doesn't exist")
+	path, err := writeNetrcFile(credentials[:1], tmp)
+	Ok(t, err)
+	Equals(t, netrcFile, path)
+	contents, err := ioutil.ReadFile(netrcFile)
+	Ok(t, err)
+	Equals(t, "machine git.company.com login atlantis password token1", string(contents))
+
+	t.Log("entries for other hosts are kept and existing entries aren't duplicated")
+	Ok(t, ioutil.WriteFile(netrcFile, []byte("machine other.com login me password secret\nmachine git.company.com login atlantis password token1\n"), 0600))
+	_, err = writeNetrcFile(credentials, tmp)
+	Ok(t, err)
+	contents, err = ioutil.ReadFile(netrcFile)
+	Ok(t, err)
+	Equals(t, "machine other.com login me password secret\nmachine git.company.com login atlantis password token1\nmachine modules.company.com login bot password token2", string(contents))
+
+	t.Log("other credentials for the same host aren't replaced")
+	_, err = writeNetrcFile([]NetrcCredential{{Host: "other.com", User: "atlantis", Token: "token3"}}, tmp)
+	ErrEquals(t, fmt.Sprintf("can't write credentials for other.com to %s because that file has other credentials for it", netrcFile), err)
+}
+
+// Test that if we can't write, we error out.
+func TestWriteNetrcFile_ErrIfCannotWrite(t *testing.T) {
+	netrcFile := "/this/dir/does/not/exist/.netrc"
+	expErr := fmt.Sprintf("writing generated .netrc file with credentials to %s: open %s: no such file or directory", netrcFile, netrcFile)
+	_, actErr := writeNetrcFile([]NetrcCredential{{Host: "git.company.com", User: "atlantis", Token: "token"}}, "/this/dir/does/not/exist")
+	ErrEquals(t, expErr, actErr)
+}
+
+func TestParseNetrcCredentials(t *testing.T) {
+	credentials, err := ParseNetrcCredentials("")
+	Ok(t, err)
+	Equals(t, 0, len(credentials))
+
+	credentials, err = ParseNetrcCredentials("git.company.com=atlantis:token1, modules.company.com=bot:to:ken2")
+	Ok(t, err)
+	Equals(t, []NetrcCredential{
+		{Host: "git.company.com", User: "atlantis", Token: "token1"},
+		{Host: "modules.company.com", User: "bot", Token: "to:ken2"},
+	}, credentials)
+
+	for _, s := range []string{"git.company.com", "git.company.com=token", "a=b:c,,d=e:f"} {
+		_, err = ParseNetrcCredentials(s)
+		ErrEquals(t, "credentials must be in the form host=user:token", err)
+	}
+	_, err = ParseNetrcCredentials("git.company.com=atlantis:")
+	ErrEquals(t, "credentials must be in the form host=user:token and can't contain whitespace", err)
+	_, err = ParseNetrcCredentials("git.company.com=a:b,git.company.com=c:d")
+	ErrEquals(t, "git.company.com has more than one set of credentials", err)
+}
+
 // Test that it returns an error on error.
 func TestDefaultClient_RunCommandWithVersion_Error(t *testing.T) {
 	v, err := version.NewVersion("0.11.11")
@@ -130,12 +282,52 @@ func TestDefaultClient_RunCommandWithVersion_Error(t *testing.T) {
 		"1",
 	}
 	log := logging.NewNoopLogger(t)
-	out, err := client.RunCommandWithVersion(log, tmp, args, map[string]string{}, nil, "workspace")
+	out, err := client.RunCommandWithVersion(context.Background(), log, tmp, args, map[string]string{}, "", nil, "workspace")
 	ErrEquals(t, fmt.Sprintf(`running "echo dying && exit 1" in %q: exit status 1`, tmp), err)
 	// Test that we still get our output.
 	Equals(t, "dying\n", out)
 }
 
+// Test that the command is interrupted when its context is cancelled.
+func TestDefaultClient_RunCommandWithVersion_Cancelled(t *testing.T) {
+	v, err := version.NewVersion("0.11.11")
+	Ok(t, err)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	client := &DefaultClient{
+		defaultVersion:          v,
+		terraformPluginCacheDir: tmp,
+		overrideTF:              "sleep",
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	log := logging.NewNoopLogger(t)
+	start := time.Now()
+	_, err = client.RunCommandWithVersion(ctx, log, tmp, []string{"30", "&&", "echo", "done"}, map[string]string{}, "", nil, "workspace")
+	ErrEquals(t, fmt.Sprintf(`running "sleep 30 && echo done" in %q: signal: interrupt`, tmp), err)
+	Assert(t, time.Since(start) < 10*time.Second, "exp command to be interrupted")
+}
+
+func TestDefaultClient_RunCommandWithVersion_TimedOut(t *testing.T) {
+	v, err := version.NewVersion("0.11.11")
+	Ok(t, err)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	client := &DefaultClient{
+		defaultVersion:          v,
+		terraformPluginCacheDir: tmp,
+		overrideTF:              "sleep",
+		commandTimeout:          100 * time.Millisecond,
+	}
+
+	log := logging.NewNoopLogger(t)
+	start := time.Now()
+	_, err = client.RunCommandWithVersion(context.Background(), log, tmp, []string{"30"}, map[string]string{}, "", nil, "workspace")
+	ErrEquals(t, fmt.Sprintf(`running "sleep 30" in %q: timed out after 100ms`, tmp), err)
+	Assert(t, time.Since(start) < 10*time.Second, "exp command to be interrupted")
+}
+
 func TestDefaultClient_RunCommandAsync_Success(t *testing.T) {
 	v, err := version.NewVersion("0.11.11")
 	Ok(t, err)
@@ -156,11 +348,11 @@ func TestDefaultClient_RunCommandAsync_Success(t *testing.T) {
 		"DIR=$DIR",
 	}
 	log := logging.NewNoopLogger(t)
-	_, outCh := client.RunCommandAsync(log, tmp, args, map[string]string{}, nil, "workspace")
+	_, outCh := client.RunCommandAsync(context.Background(), log, tmp, args, map[string]string{}, "", nil, "workspace")
 
 	out, err := waitCh(outCh)
 	Ok(t, err)
-	exp := fmt.Sprintf("TF_IN_AUTOMATION=true TF_PLUGIN_CACHE_DIR=%s WORKSPACE=workspace ATLANTIS_TERRAFORM_VERSION=0.11.11 DIR=%s", tmp, tmp)
+	exp := fmt.Sprintf("TF_IN_AUTOMATION=true TF_PLUGIN_CACHE_DIR=%s WORKSPACE=workspace ATLANTIS_TERRAFORM_VERSION=0.11.11 DIR=%s", filepath.Join(tmp, ".terraform", "atlantis-plugin-cache"), tmp)
 	Equals(t, exp, out)
 }
 
@@ -186,7 +378,7 @@ func TestDefaultClient_RunCommandAsync_BigOutput(t *testing.T) {
 		Ok(t, err)
 	}
 	log := logging.NewNoopLogger(t)
-	_, outCh := client.RunCommandAsync(log, tmp, []string{filename}, map[string]string{}, nil, "workspace")
+	_, outCh := client.RunCommandAsync(context.Background(), log, tmp, []string{filename}, map[string]string{}, "", nil, "workspace")
 
 	out, err := waitCh(outCh)
 	Ok(t, err)
@@ -204,7 +396,7 @@ func TestDefaultClient_RunCommandAsync_StderrOutput(t *testing.T) {
 		overrideTF:              "echo",
 	}
 	log := logging.NewNoopLogger(t)
-	_, outCh := client.RunCommandAsync(log, tmp, []string{"stderr", ">&2"}, map[string]string{}, nil, "workspace")
+	_, outCh := client.RunCommandAsync(context.Background(), log, tmp, []string{"stderr", ">&2"}, map[string]string{}, "", nil, "workspace")
 
 	out, err := waitCh(outCh)
 	Ok(t, err)
@@ -222,7 +414,7 @@ func TestDefaultClient_RunCommandAsync_ExitOne(t *testing.T) {
 		overrideTF:              "echo",
 	}
 	log := logging.NewNoopLogger(t)
-	_, outCh := client.RunCommandAsync(log, tmp, []string{"dying", "&&", "exit", "1"}, map[string]string{}, nil, "workspace")
+	_, outCh := client.RunCommandAsync(context.Background(), log, tmp, []string{"dying", "&&", "exit", "1"}, map[string]string{}, "", nil, "workspace")
 
 	out, err := waitCh(outCh)
 	ErrEquals(t, fmt.Sprintf(`running "echo dying && exit 1" in %q: exit status 1`, tmp), err)
@@ -241,7 +433,7 @@ func TestDefaultClient_RunCommandAsync_Input(t *testing.T) {
 		overrideTF:              "read",
 	}
 	log := logging.NewNoopLogger(t)
-	inCh, outCh := client.RunCommandAsync(log, tmp, []string{"a", "&&", "echo", "$a"}, map[string]string{}, nil, "workspace")
+	inCh, outCh := client.RunCommandAsync(context.Background(), log, tmp, []string{"a", "&&", "echo", "$a"}, map[string]string{}, "", nil, "workspace")
 	inCh <- "echo me\n"
 
 	out, err := waitCh(outCh)
@@ -249,6 +441,45 @@ func TestDefaultClient_RunCommandAsync_Input(t *testing.T) {
 	Equals(t, "echo me", out)
 }
 
+func TestDefaultClient_RunCommandAsync_Cancelled(t *testing.T) {
+	v, err := version.NewVersion("0.11.11")
+	Ok(t, err)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	client := &DefaultClient{
+		defaultVersion:          v,
+		terraformPluginCacheDir: tmp,
+		overrideTF:              "echo",
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	log := logging.NewNoopLogger(t)
+	_, outCh := client.RunCommandAsync(ctx, log, tmp, []string{"started", "&&", "sleep", "30"}, map[string]string{}, "", nil, "workspace")
+
+	// Cancel once the command has started.
+	Equals(t, "started", (<-outCh).Line)
+	cancel()
+	out, err := waitCh(outCh)
+	ErrEquals(t, fmt.Sprintf(`running "echo started && sleep 30" in %q: signal: interrupt`, tmp), err)
+	Equals(t, "", out)
+}
+
+func TestDefaultClient_RunCommandAsync_TimedOut(t *testing.T) {
+	v, err := version.NewVersion("0.11.11")
+	Ok(t, err)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	client := &DefaultClient{
+		defaultVersion:          v,
+		terraformPluginCacheDir: tmp,
+		overrideTF:              "sleep",
+		commandTimeout:          100 * time.Millisecond,
+	}
+	log := logging.NewNoopLogger(t)
+	_, outCh := client.RunCommandAsync(context.Background(), log, tmp, []string{"30"}, map[string]string{}, "", nil, "workspace")
+	_, err = waitCh(outCh)
+	ErrEquals(t, fmt.Sprintf(`running "sleep 30" in %q: timed out after 100ms`, tmp), err)
+}
+
 func waitCh(ch <-chan Line) (string, error) {
 	var ls []string
 	for line := range ch {
@@ -259,3 +490,249 @@ func waitCh(ch <-chan Line) (string, error) {
 	}
 	return strings.Join(ls, "\n"), nil
 }
+
+func TestDefaultClient_DetectVersion(t *testing.T) {
+	cases := []struct {
+		description     string
+		requiredVersion string
+		exp             string
+	}{
+		{
+			description:     "exact version",
+			requiredVersion: "0.12.8",
+			exp:             "0.12.8",
+		},
+		{
+			description:     "exact version using =",
+			requiredVersion: "= 0.12.8",
+			exp:             "0.12.8",
+		},
+		{
+			description:     "local version satisfies constraint",
+			requiredVersion: "~> 0.11.0",
+			exp:             "0.11.14",
+		},
+		{
+			description:     "version in bin dir satisfies constraint",
+			requiredVersion: ">= 0.12.0, < 0.13.0",
+			exp:             "0.12.20",
+		},
+		{
+			description:     "newest released version that satisfies constraint",
+			requiredVersion: ">= 0.13.0",
+			exp:             "0.14.2",
+		},
+		{
+			description:     "no version satisfies constraint",
+			requiredVersion: "> 1.0.0",
+			exp:             "",
+		},
+		{
+			description:     "invalid constraint",
+			requiredVersion: "~= 0.12.8",
+			exp:             "",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			tmp, cleanup := TempDir(t)
+			defer cleanup()
+			binDir := filepath.Join(tmp, "bin")
+			Ok(t, os.Mkdir(binDir, 0700))
+			Ok(t, ioutil.WriteFile(filepath.Join(binDir, "terraform0.12.20"), nil, 0700)) // #nosec G306
+			projectDir := filepath.Join(tmp, "project")
+			Ok(t, os.Mkdir(projectDir, 0700))
+			mainTF := fmt.Sprintf("terraform {\n  required_version = %q\n}\n", c.requiredVersion)
+			Ok(t, ioutil.WriteFile(filepath.Join(projectDir, "main.tf"), []byte(mainTF), 0600))
+
+			client := &DefaultClient{
+				defaultVersion:      version.Must(version.NewVersion("0.11.14")),
+				defaultDistribution: TerraformDistribution,
+				distributions:       testDistributions,
+				binDir:              binDir,
+				downloader: &fakeIndexDownloader{
+					url:   "https://releases.hashicorp.com/terraform/index.json",
+					index: `{"name": "terraform", "versions": {"0.12.8": {}, "0.13.5": {}, "0.14.2": {}, "0.15.0-beta1": {}}}`,
+				},
+				versions:     map[string]string{},
+				versionsLock: &sync.Mutex{},
+			}
+			v := client.DetectVersion(logging.NewNoopLogger(t), "", projectDir)
+			if c.exp == "" {
+				Assert(t, v == nil, "exp nil version but got %s", v)
+				return
+			}
+			Assert(t, v != nil, "exp version %s but got nil", c.exp)
+			Equals(t, c.exp, v.String())
+		})
+	}
+}
+
+// Test that OpenTofu versions are detected from its own binaries and index
+// rather than Terraform's.
+func TestDefaultClient_DetectVersion_OpenTofu(t *testing.T) {
+	cases := []struct {
+		description     string
+		requiredVersion string
+		exp             string
+	}{
+		{
+			description:     "version in bin dir satisfies constraint",
+			requiredVersion: "~> 1.6.0",
+			exp:             "1.6.2",
+		},
+		{
+			description:     "newest released version that satisfies constraint",
+			requiredVersion: ">= 1.7.0",
+			exp:             "1.8.1",
+		},
+		{
+			description:     "default terraform version isn't used",
+			requiredVersion: "~> 1.5.0",
+			exp:             "",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			tmp, cleanup := TempDir(t)
+			defer cleanup()
+			binDir := filepath.Join(tmp, "bin")
+			Ok(t, os.Mkdir(binDir, 0700))
+			Ok(t, ioutil.WriteFile(filepath.Join(binDir, "tofu1.6.2"), nil, 0700))      // #nosec G306
+			Ok(t, ioutil.WriteFile(filepath.Join(binDir, "terraform1.7.5"), nil, 0700)) // #nosec G306
+			projectDir := filepath.Join(tmp, "project")
+			Ok(t, os.Mkdir(projectDir, 0700))
+			mainTF := fmt.Sprintf("terraform {\n  required_version = %q\n}\n", c.requiredVersion)
+			Ok(t, ioutil.WriteFile(filepath.Join(projectDir, "main.tf"), []byte(mainTF), 0600))
+
+			client := &DefaultClient{
+				defaultVersion:      version.Must(version.NewVersion("1.5.7")),
+				defaultDistribution: TerraformDistribution,
+				distributions:       testDistributions,
+				binDir:              binDir,
+				downloader: &fakeIndexDownloader{
+					url:   opentofuIndexURL,
+					index: `{"versions": [{"id": "1.6.2"}, {"id": "1.7.0"}, {"id": "1.8.1"}, {"id": "1.9.0-alpha1"}]}`,
+				},
+				versions:     map[string]string{},
+				versionsLock: &sync.Mutex{},
+			}
+			v := client.DetectVersion(logging.NewNoopLogger(t), OpenTofuDistribution, projectDir)
+			if c.exp == "" {
+				Assert(t, v == nil, "exp nil version but got %s", v)
+				return
+			}
+			Assert(t, v != nil, "exp version %s but got nil", c.exp)
+			Equals(t, c.exp, v.String())
+		})
+	}
+}
+
+// Test that projects that use a distribution other than the default one
+// without requiring a version use that distribution's default version rather
+// than the default distribution's.
+func TestDefaultClient_DetectVersion_DistributionDefault(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	projectDir := filepath.Join(tmp, "project")
+	Ok(t, os.Mkdir(projectDir, 0700))
+	Ok(t, ioutil.WriteFile(filepath.Join(projectDir, "main.tf"), []byte("resource \"null_resource\" \"n\" {}\n"), 0600))
+
+	tofu := testDistributions[OpenTofuDistribution]
+	tofu.defaultVersion = version.Must(version.NewVersion("1.6.0"))
+	client := &DefaultClient{
+		defaultVersion:      version.Must(version.NewVersion("1.5.7")),
+		defaultDistribution: TerraformDistribution,
+		distributions: map[string]distribution{
+			TerraformDistribution: testDistributions[TerraformDistribution],
+			OpenTofuDistribution:  tofu,
+		},
+		binDir: tmp,
+		downloader: &fakeIndexDownloader{
+			url:   opentofuIndexURL,
+			index: `{"versions": [{"id": "1.6.2"}, {"id": "1.8.1"}, {"id": "1.9.0-alpha1"}]}`,
+		},
+		versions:     map[string]string{},
+		versionsLock: &sync.Mutex{},
+	}
+
+	t.Log("the default distribution uses the default version")
+	v := client.DetectVersion(logging.NewNoopLogger(t), TerraformDistribution, projectDir)
+	Assert(t, v == nil, "exp nil version but got %s", v)
+
+	t.Log("other distributions use their own default version")
+	v = client.DetectVersion(logging.NewNoopLogger(t), OpenTofuDistribution, projectDir)
+	Equals(t, "1.6.0", v.String())
+
+	t.Log("other distributions without a default version use their newest release")
+	client.distributions[OpenTofuDistribution] = testDistributions[OpenTofuDistribution]
+	v = client.DetectVersion(logging.NewNoopLogger(t), OpenTofuDistribution, projectDir)
+	Equals(t, "1.8.1", v.String())
+}
+
+var testDistributions = map[string]distribution{
+	TerraformDistribution: {name: TerraformDistribution, binName: "terraform", downloadURL: "https://releases.hashicorp.com"},
+	OpenTofuDistribution:  {name: OpenTofuDistribution, binName: "tofu", downloadURL: "https://github.com/opentofu/opentofu/releases/download"},
+}
+
+// fakeIndexDownloader writes index to the destination if it's asked to
+// download url.
+func TestDefaultClient_ListVersions(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	Ok(t, ioutil.WriteFile(filepath.Join(tmp, "terraform0.12.20"), nil, 0700)) // #nosec G306
+	Ok(t, ioutil.WriteFile(filepath.Join(tmp, "tofu1.6.0"), nil, 0700))        // #nosec G306
+	downloader := &fakeIndexDownloader{
+		url:   "https://releases.hashicorp.com/terraform/index.json",
+		index: `{"name": "terraform", "versions": {"0.11.14": {}, "0.12.8": {}, "0.14.2": {}, "0.12.20": {}}}`,
+	}
+	client := &DefaultClient{
+		defaultVersion:      version.Must(version.NewVersion("0.11.14")),
+		defaultDistribution: TerraformDistribution,
+		distributions:       testDistributions,
+		binDir:              tmp,
+		downloader:          downloader,
+		versions:            map[string]string{"terraform0.11.14": "/usr/bin/terraform"},
+		versionsLock:        &sync.Mutex{},
+	}
+
+	versions, err := client.ListVersions("")
+	Ok(t, err)
+	Equals(t, TerraformDistribution, versions.Distribution)
+	Equals(t, "0.11.14", versions.Default.String())
+	Equals(t, []string{"0.12.20", "0.11.14"}, versionStrings(versions.Installed))
+	Equals(t, []string{"0.14.2", "0.12.8"}, versionStrings(versions.Available))
+
+	t.Log("installed versions are listed even if released versions can't be")
+	downloader.url = "https://unreachable"
+	versions, err = client.ListVersions(OpenTofuDistribution)
+	ErrContains(t, "listing opentofu versions", err)
+	Assert(t, versions.Default == nil, "exp no default version for a distribution that isn't the default")
+	Equals(t, []string{"1.6.0"}, versionStrings(versions.Installed))
+}
+
+func versionStrings(versions []*version.Version) []string {
+	var strs []string
+	for _, v := range versions {
+		strs = append(strs, v.String())
+	}
+	return strs
+}
+
+type fakeIndexDownloader struct {
+	url   string
+	index string
+}
+
+func (f *fakeIndexDownloader) GetFile(dst, src string, opts ...getter.ClientOption) error {
+	if src != f.url {
+		return fmt.Errorf("unexpected url %q", src)
+	}
+	return ioutil.WriteFile(dst, []byte(f.index), 0600)
+}
+
+func (f *fakeIndexDownloader) GetAny(dst, src string, opts ...getter.ClientOption) error {
+	return errors.New("not implemented")
+}
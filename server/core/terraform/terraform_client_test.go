@@ -14,6 +14,7 @@
 package terraform_test
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -70,13 +71,13 @@ is 0.11.13. You can update by downloading from www.terraform.io/downloads.html
 	Ok(t, err)
 	defer tempSetEnv(t, "PATH", fmt.Sprintf("%s:%s", tmp, os.Getenv("PATH")))()
 
-	c, err := terraform.NewClient(logger, binDir, cacheDir, "", "", "", cmd.DefaultTFVersionFlag, cmd.DefaultTFDownloadURL, nil, true)
+	c, err := terraform.NewClient(logger, binDir, cacheDir, "", "", nil, nil, "", cmd.DefaultTFVersionFlag, cmd.DefaultTFDistribution, cmd.DefaultTFDownloadURL, cmd.DefaultTofuDownloadURL, "", nil, true, 0)
 	Ok(t, err)
 
 	Ok(t, err)
 	Equals(t, "0.11.10", c.DefaultVersion().String())
 
-	output, err := c.RunCommandWithVersion(logger, tmp, nil, map[string]string{"test": "123"}, nil, "")
+	output, err := c.RunCommandWithVersion(context.Background(), logger, tmp, nil, map[string]string{"test": "123"}, "", nil, "")
 	Ok(t, err)
 	Equals(t, fakeBinOut+"\n", output)
 }
@@ -99,13 +100,13 @@ is 0.11.13. You can update by downloading from www.terraform.io/downloads.html
 	Ok(t, err)
 	defer tempSetEnv(t, "PATH", fmt.Sprintf("%s:%s", tmp, os.Getenv("PATH")))()
 
-	c, err := terraform.NewClient(logger, binDir, cacheDir, "", "", "0.11.10", cmd.DefaultTFVersionFlag, cmd.DefaultTFDownloadURL, nil, true)
+	c, err := terraform.NewClient(logger, binDir, cacheDir, "", "", nil, nil, "0.11.10", cmd.DefaultTFVersionFlag, cmd.DefaultTFDistribution, cmd.DefaultTFDownloadURL, cmd.DefaultTofuDownloadURL, "", nil, true, 0)
 	Ok(t, err)
 
 	Ok(t, err)
 	Equals(t, "0.11.10", c.DefaultVersion().String())
 
-	output, err := c.RunCommandWithVersion(logger, tmp, nil, map[string]string{}, nil, "")
+	output, err := c.RunCommandWithVersion(context.Background(), logger, tmp, nil, map[string]string{}, "", nil, "")
 	Ok(t, err)
 	Equals(t, fakeBinOut+"\n", output)
 }
@@ -120,7 +121,7 @@ func TestNewClient_NoTF(t *testing.T) {
 	// Set PATH to only include our empty directory.
 	defer tempSetEnv(t, "PATH", tmp)()
 
-	_, err := terraform.NewClient(logger, binDir, cacheDir, "", "", "", cmd.DefaultTFVersionFlag, cmd.DefaultTFDownloadURL, nil, true)
+	_, err := terraform.NewClient(logger, binDir, cacheDir, "", "", nil, nil, "", cmd.DefaultTFVersionFlag, cmd.DefaultTFDistribution, cmd.DefaultTFDownloadURL, cmd.DefaultTofuDownloadURL, "", nil, true, 0)
 	ErrEquals(t, "terraform not found in $PATH. Set --default-tf-version or download terraform from https://www.terraform.io/downloads.html", err)
 }
 
@@ -138,13 +139,13 @@ func TestNewClient_DefaultTFFlagInPath(t *testing.T) {
 	Ok(t, err)
 	defer tempSetEnv(t, "PATH", fmt.Sprintf("%s:%s", tmp, os.Getenv("PATH")))()
 
-	c, err := terraform.NewClient(logger, binDir, cacheDir, "", "", "0.11.10", cmd.DefaultTFVersionFlag, cmd.DefaultTFDownloadURL, nil, true)
+	c, err := terraform.NewClient(logger, binDir, cacheDir, "", "", nil, nil, "0.11.10", cmd.DefaultTFVersionFlag, cmd.DefaultTFDistribution, cmd.DefaultTFDownloadURL, cmd.DefaultTofuDownloadURL, "", nil, true, 0)
 	Ok(t, err)
 
 	Ok(t, err)
 	Equals(t, "0.11.10", c.DefaultVersion().String())
 
-	output, err := c.RunCommandWithVersion(logger, tmp, nil, map[string]string{}, nil, "")
+	output, err := c.RunCommandWithVersion(context.Background(), logger, tmp, nil, map[string]string{}, "", nil, "")
 	Ok(t, err)
 	Equals(t, fakeBinOut+"\n", output)
 }
@@ -162,13 +163,13 @@ func TestNewClient_DefaultTFFlagInBinDir(t *testing.T) {
 	Ok(t, err)
 	defer tempSetEnv(t, "PATH", fmt.Sprintf("%s:%s", tmp, os.Getenv("PATH")))()
 
-	c, err := terraform.NewClient(logging.NewNoopLogger(t), binDir, cacheDir, "", "", "0.11.10", cmd.DefaultTFVersionFlag, cmd.DefaultTFDownloadURL, nil, true)
+	c, err := terraform.NewClient(logging.NewNoopLogger(t), binDir, cacheDir, "", "", nil, nil, "0.11.10", cmd.DefaultTFVersionFlag, cmd.DefaultTFDistribution, cmd.DefaultTFDownloadURL, cmd.DefaultTofuDownloadURL, "", nil, true, 0)
 	Ok(t, err)
 
 	Ok(t, err)
 	Equals(t, "0.11.10", c.DefaultVersion().String())
 
-	output, err := c.RunCommandWithVersion(logger, tmp, nil, map[string]string{}, nil, "")
+	output, err := c.RunCommandWithVersion(context.Background(), logger, tmp, nil, map[string]string{}, "", nil, "")
 	Ok(t, err)
 	Equals(t, fakeBinOut+"\n", output)
 }
@@ -189,7 +190,7 @@ func TestNewClient_DefaultTFFlagDownload(t *testing.T) {
 		err := ioutil.WriteFile(params[0].(string), []byte("#!/bin/sh\necho '\nTerraform v0.11.10\n'"), 0700) // #nosec G306
 		return []pegomock.ReturnValue{err}
 	})
-	c, err := terraform.NewClient(logger, binDir, cacheDir, "", "", "0.11.10", cmd.DefaultTFVersionFlag, "https://my-mirror.releases.mycompany.com", mockDownloader, true)
+	c, err := terraform.NewClient(logger, binDir, cacheDir, "", "", nil, nil, "0.11.10", cmd.DefaultTFVersionFlag, cmd.DefaultTFDistribution, "https://my-mirror.releases.mycompany.com", cmd.DefaultTofuDownloadURL, "", mockDownloader, true, 0)
 	Ok(t, err)
 
 	Ok(t, err)
@@ -204,7 +205,7 @@ func TestNewClient_DefaultTFFlagDownload(t *testing.T) {
 
 	// Reset PATH so that it has sh.
 	Ok(t, os.Setenv("PATH", orig))
-	output, err := c.RunCommandWithVersion(logger, tmp, nil, map[string]string{}, nil, "")
+	output, err := c.RunCommandWithVersion(context.Background(), logger, tmp, nil, map[string]string{}, "", nil, "")
 	Ok(t, err)
 	Equals(t, "\nTerraform v0.11.10\n\n", output)
 }
@@ -214,7 +215,7 @@ func TestNewClient_BadVersion(t *testing.T) {
 	logger := logging.NewNoopLogger(t)
 	_, binDir, cacheDir, cleanup := mkSubDirs(t)
 	defer cleanup()
-	_, err := terraform.NewClient(logger, binDir, cacheDir, "", "", "malformed", cmd.DefaultTFVersionFlag, cmd.DefaultTFDownloadURL, nil, true)
+	_, err := terraform.NewClient(logger, binDir, cacheDir, "", "", nil, nil, "malformed", cmd.DefaultTFVersionFlag, cmd.DefaultTFDistribution, cmd.DefaultTFDownloadURL, cmd.DefaultTofuDownloadURL, "", nil, true, 0)
 	ErrEquals(t, "Malformed version: malformed", err)
 }
 
@@ -238,17 +239,52 @@ func TestRunCommandWithVersion_DLsTF(t *testing.T) {
 		return []pegomock.ReturnValue{err}
 	})
 
-	c, err := terraform.NewClient(logger, binDir, cacheDir, "", "", "0.11.10", cmd.DefaultTFVersionFlag, cmd.DefaultTFDownloadURL, mockDownloader, true)
+	c, err := terraform.NewClient(logger, binDir, cacheDir, "", "", nil, nil, "0.11.10", cmd.DefaultTFVersionFlag, cmd.DefaultTFDistribution, cmd.DefaultTFDownloadURL, cmd.DefaultTofuDownloadURL, "", mockDownloader, true, 0)
 	Ok(t, err)
 	Equals(t, "0.11.10", c.DefaultVersion().String())
 
 	v, err := version.NewVersion("99.99.99")
 	Ok(t, err)
-	output, err := c.RunCommandWithVersion(logger, tmp, nil, map[string]string{}, v, "")
+	output, err := c.RunCommandWithVersion(context.Background(), logger, tmp, nil, map[string]string{}, "", v, "")
 	Assert(t, err == nil, "err: %s: %s", err, output)
 	Equals(t, "\nTerraform v99.99.99\n\n", output)
 }
 
+// Test that OpenTofu versions are downloaded from its releases and only the
+// tofu binary is kept from the archive.
+func TestRunCommandWithVersion_DLsOpenTofu(t *testing.T) {
+	logger := logging.NewNoopLogger(t)
+	RegisterMockTestingT(t)
+	tmp, binDir, cacheDir, cleanup := mkSubDirs(t)
+	defer cleanup()
+
+	mockDownloader := mocks.NewMockDownloader()
+	baseURL := fmt.Sprintf("%s/v1.6.0", cmd.DefaultTofuDownloadURL)
+	expURL := fmt.Sprintf("%s/tofu_1.6.0_%s_%s.zip?checksum=file:%s/tofu_1.6.0_SHA256SUMS",
+		baseURL,
+		runtime.GOOS,
+		runtime.GOARCH,
+		baseURL)
+	When(mockDownloader.GetAny(filepath.Join(tmp, "bin", "tofu1.6.0.download"), expURL)).Then(func(params []pegomock.Param) pegomock.ReturnValues {
+		dir := params[0].(string)
+		Ok(t, os.MkdirAll(dir, 0700))
+		Ok(t, ioutil.WriteFile(filepath.Join(dir, "LICENSE"), nil, 0600))
+		err := ioutil.WriteFile(filepath.Join(dir, "tofu"), []byte("#!/bin/sh\necho '\nOpenTofu v1.6.0\n'"), 0700) // #nosec G306
+		return []pegomock.ReturnValue{err}
+	})
+
+	c, err := terraform.NewClient(logger, binDir, cacheDir, "", "", nil, nil, "0.11.10", cmd.DefaultTFVersionFlag, cmd.DefaultTFDistribution, cmd.DefaultTFDownloadURL, cmd.DefaultTofuDownloadURL, "", mockDownloader, true, 0)
+	Ok(t, err)
+
+	v, err := version.NewVersion("1.6.0")
+	Ok(t, err)
+	output, err := c.RunCommandWithVersion(context.Background(), logger, tmp, nil, map[string]string{}, terraform.OpenTofuDistribution, v, "")
+	Assert(t, err == nil, "err: %s: %s", err, output)
+	Equals(t, "\nOpenTofu v1.6.0\n\n", output)
+	_, err = os.Stat(filepath.Join(tmp, "bin", "tofu1.6.0.download"))
+	Assert(t, os.IsNotExist(err), "exp the download dir to be removed")
+}
+
 // Test the EnsureVersion downloads terraform.
 func TestEnsureVersion_downloaded(t *testing.T) {
 	logger := logging.NewNoopLogger(t)
@@ -258,7 +294,7 @@ func TestEnsureVersion_downloaded(t *testing.T) {
 
 	mockDownloader := mocks.NewMockDownloader()
 
-	c, err := terraform.NewTestClient(logger, binDir, cacheDir, "", "", "0.11.10", cmd.DefaultTFVersionFlag, cmd.DefaultTFDownloadURL, mockDownloader, true)
+	c, err := terraform.NewTestClient(logger, binDir, cacheDir, "", "", nil, nil, "0.11.10", cmd.DefaultTFVersionFlag, cmd.DefaultTFDistribution, cmd.DefaultTFDownloadURL, cmd.DefaultTofuDownloadURL, "", mockDownloader, true, 0)
 	Ok(t, err)
 
 	Equals(t, "0.11.10", c.DefaultVersion().String())
@@ -266,7 +302,7 @@ func TestEnsureVersion_downloaded(t *testing.T) {
 	v, err := version.NewVersion("99.99.99")
 	Ok(t, err)
 
-	err = c.EnsureVersion(logger, v)
+	err = c.EnsureVersion(logger, "", v)
 
 	Ok(t, err)
 
@@ -0,0 +1,140 @@
+package terraform
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	version "github.com/hashicorp/go-version"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// Provider is a provider that's installed into the plugin cache when
+// warming.
+type Provider struct {
+	// Source is the provider's source address, ex. hashicorp/aws.
+	Source string
+	// Constraint is the version constraint of the provider, ex. ~> 5.0. If
+	// it's empty, the newest version is installed.
+	Constraint string
+}
+
+// ParseProviders parses providers in the form
+// source1@constraint1,source2, ex. hashicorp/aws@~> 5.0,hashicorp/null.
+// Constraints can't contain commas so they must be a single constraint.
+func ParseProviders(s string) ([]Provider, error) {
+	var providers []Provider
+	if strings.TrimSpace(s) == "" {
+		return providers, nil
+	}
+	for _, p := range strings.Split(s, ",") {
+		sourceConstraint := strings.SplitN(p, "@", 2)
+		provider := Provider{Source: strings.TrimSpace(sourceConstraint[0])}
+		if provider.Source == "" || strings.ContainsAny(provider.Source, " \t\"") {
+			return nil, fmt.Errorf("invalid provider %q: must be in the form source or source@constraint, ex. hashicorp/aws@~> 5.0", p)
+		}
+		if len(sourceConstraint) == 2 {
+			provider.Constraint = strings.TrimSpace(sourceConstraint[1])
+			if _, err := version.NewConstraint(provider.Constraint); err != nil {
+				return nil, errors.Wrapf(err, "invalid provider %q", p)
+			}
+		}
+		providers = append(providers, provider)
+	}
+	return providers, nil
+}
+
+// Warmer downloads the terraform versions and providers that are commonly
+// used ahead of time so that the first runs after a restart don't have to
+// wait for them.
+type Warmer struct {
+	Client Client
+	// Versions are the versions of the default distribution to download in
+	// addition to the default version.
+	Versions []*version.Version
+	// Providers are installed into the shared plugin cache. Rather than
+	// terraform providers mirror, whose output can't be used as a plugin
+	// cache, init is run on a configuration that requires them.
+	Providers []Provider
+	// Dir is a scratch dir that's used to install Providers.
+	Dir    string
+	Logger logging.SimpleLogging
+
+	// mutex ensures only one warm is running at a time so that, ex. a warm
+	// triggered through the API while the startup warm is running doesn't
+	// write to Dir at the same time.
+	mutex sync.Mutex
+}
+
+// Warm downloads the default version, Versions and Providers. Failures don't
+// stop the rest from being warmed. They're all returned together.
+func (w *Warmer) Warm(ctx context.Context) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	var failures []string
+	// A nil version is the default version.
+	for _, v := range append([]*version.Version{nil}, w.Versions...) {
+		if err := w.Client.EnsureVersion(w.Logger, "", v); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	if len(w.Providers) > 0 {
+		if err := w.warmProviders(ctx); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("warming failed: %s", strings.Join(failures, "; "))
+	}
+	w.Logger.Info("warmed %d terraform versions and %d providers", len(w.Versions)+1, len(w.Providers))
+	return nil
+}
+
+// warmProviders runs init with the default version on a configuration that
+// requires Providers. init adds the providers it downloads to the shared
+// plugin cache, see pluginCache.
+func (w *Warmer) warmProviders(ctx context.Context) error {
+	// Anything left from a previous warm is removed so init doesn't use its
+	// lock file, which would stop newer versions from being installed.
+	if err := os.RemoveAll(w.Dir); err != nil {
+		return errors.Wrapf(err, "cleaning %s", w.Dir)
+	}
+	if err := os.MkdirAll(w.Dir, 0700); err != nil {
+		return errors.Wrapf(err, "creating %s", w.Dir)
+	}
+	// The providers are already in the shared cache so the dir isn't needed
+	// after init.
+	defer os.RemoveAll(w.Dir) // nolint: errcheck
+
+	if err := ioutil.WriteFile(filepath.Join(w.Dir, "main.tf"), []byte(providersConfig(w.Providers)), 0600); err != nil {
+		return errors.Wrap(err, "writing providers config")
+	}
+	out, err := w.Client.RunCommandWithVersion(ctx, w.Logger, w.Dir, []string{"init", "-input=false", "-no-color"}, map[string]string{}, "", nil, "default")
+	if err != nil {
+		return errors.Wrapf(err, "installing providers: %s", out)
+	}
+	return nil
+}
+
+// providersConfig returns a terraform configuration that requires providers.
+// They're given generated local names so that providers with the same type
+// from different sources don't clash.
+func providersConfig(providers []Provider) string {
+	var b strings.Builder
+	b.WriteString("terraform {\n  required_providers {\n")
+	for i, p := range providers {
+		fmt.Fprintf(&b, "    p%d = {\n      source = %q\n", i, p.Source)
+		if p.Constraint != "" {
+			fmt.Fprintf(&b, "      version = %q\n", p.Constraint)
+		}
+		b.WriteString("    }\n")
+	}
+	b.WriteString("  }\n}\n")
+	return b.String()
+}
@@ -0,0 +1,131 @@
+package terraform_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	version "github.com/hashicorp/go-version"
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/core/terraform"
+	"github.com/runatlantis/atlantis/server/core/terraform/mocks"
+	"github.com/runatlantis/atlantis/server/core/terraform/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestParseProviders(t *testing.T) {
+	cases := []struct {
+		in     string
+		exp    []terraform.Provider
+		expErr string
+	}{
+		{
+			in: "",
+		},
+		{
+			in: "hashicorp/aws@~> 5.0, hashicorp/null",
+			exp: []terraform.Provider{
+				{Source: "hashicorp/aws", Constraint: "~> 5.0"},
+				{Source: "hashicorp/null"},
+			},
+		},
+		{
+			in:     "hashicorp/aws,",
+			expErr: `invalid provider "": must be in the form source or source@constraint, ex. hashicorp/aws@~> 5.0`,
+		},
+		{
+			in:     "hashicorp/aws@latest",
+			expErr: `invalid provider "hashicorp/aws@latest": Malformed constraint: latest`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.in, func(t *testing.T) {
+			providers, err := terraform.ParseProviders(c.in)
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			Equals(t, c.exp, providers)
+		})
+	}
+}
+
+func TestWarmer_Warm(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	client := mocks.NewMockClient()
+	v, err := version.NewVersion("1.5.7")
+	Ok(t, err)
+	dir := filepath.Join(tmp, "warm")
+	w := &terraform.Warmer{
+		Client:   client,
+		Versions: []*version.Version{v},
+		Providers: []terraform.Provider{
+			{Source: "hashicorp/aws", Constraint: "~> 5.0"},
+			{Source: "hashicorp/null"},
+		},
+		Dir:    dir,
+		Logger: logging.NewNoopLogger(t),
+	}
+	var config string
+	When(client.RunCommandWithVersion(matchers.AnyContextContext(), matchers.AnyLoggingSimpleLogging(), EqString(dir), matchers.AnySliceOfString(), matchers.AnyMapOfStringToString(), AnyString(), matchers.AnyPtrToGoVersionVersion(), AnyString())).
+		Then(func(params []Param) ReturnValues {
+			contents, err := ioutil.ReadFile(filepath.Join(dir, "main.tf"))
+			Ok(t, err)
+			config = string(contents)
+			return []ReturnValue{"", nil}
+		})
+
+	Ok(t, w.Warm(context.Background()))
+	client.VerifyWasCalledOnce().EnsureVersion(matchers.AnyLoggingSimpleLogging(), EqString(""), matchers.EqPtrToGoVersionVersion(nil))
+	client.VerifyWasCalledOnce().EnsureVersion(matchers.AnyLoggingSimpleLogging(), EqString(""), matchers.EqPtrToGoVersionVersion(v))
+	_, _, _, args, _, _, _, _ := client.VerifyWasCalledOnce().RunCommandWithVersion(matchers.AnyContextContext(), matchers.AnyLoggingSimpleLogging(), EqString(dir), matchers.AnySliceOfString(), matchers.AnyMapOfStringToString(), AnyString(), matchers.AnyPtrToGoVersionVersion(), AnyString()).GetCapturedArguments()
+	Equals(t, []string{"init", "-input=false", "-no-color"}, args)
+	Equals(t, `terraform {
+  required_providers {
+    p0 = {
+      source = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+    p1 = {
+      source = "hashicorp/null"
+    }
+  }
+}
+`, config)
+
+	t.Log("the scratch dir is removed")
+	_, err = os.Stat(dir)
+	Assert(t, os.IsNotExist(err), "exp %s to be removed", dir)
+}
+
+// Test that a failure doesn't stop the rest from being warmed.
+func TestWarmer_WarmFailures(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	client := mocks.NewMockClient()
+	v, err := version.NewVersion("0.0.1")
+	Ok(t, err)
+	dir := filepath.Join(tmp, "warm")
+	w := &terraform.Warmer{
+		Client:    client,
+		Versions:  []*version.Version{v},
+		Providers: []terraform.Provider{{Source: "hashicorp/null"}},
+		Dir:       dir,
+		Logger:    logging.NewNoopLogger(t),
+	}
+	When(client.EnsureVersion(matchers.AnyLoggingSimpleLogging(), AnyString(), matchers.EqPtrToGoVersionVersion(v))).
+		ThenReturn(errors.New("not found"))
+	When(client.RunCommandWithVersion(matchers.AnyContextContext(), matchers.AnyLoggingSimpleLogging(), AnyString(), matchers.AnySliceOfString(), matchers.AnyMapOfStringToString(), AnyString(), matchers.AnyPtrToGoVersionVersion(), AnyString())).
+		ThenReturn("no provider", errors.New("exit status 1"))
+
+	ErrEquals(t, "warming failed: not found; installing providers: no provider: exit status 1", w.Warm(context.Background()))
+	client.VerifyWasCalledOnce().EnsureVersion(matchers.AnyLoggingSimpleLogging(), EqString(""), matchers.EqPtrToGoVersionVersion(nil))
+}
@@ -0,0 +1,412 @@
+// Package tfe runs plans and applies as Terraform Cloud/Enterprise remote runs
+// using its API instead of running terraform locally.
+package tfe
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_client.go Client
+
+// Client creates and applies runs in Terraform Cloud/Enterprise workspaces.
+// Workspaces are referenced as <organization>/<workspace>, ex.
+// my-org/my-workspace.
+type Client interface {
+	// CreateRun uploads the configuration in dir and queues a plan for it in
+	// workspace. The run isn't applied until ApplyRun is called.
+	CreateRun(workspace string, dir string, message string, destroy bool) (Run, error)
+	// GetRun returns the current state of the run with id runID in workspace.
+	GetRun(workspace string, runID string) (Run, error)
+	// ApplyRun applies a run whose plan is waiting for confirmation.
+	ApplyRun(runID string, comment string) error
+	// DiscardRun discards a run whose plan is waiting for confirmation so
+	// later runs in its workspace aren't blocked by it.
+	DiscardRun(runID string, comment string) error
+	// GetPlanLog returns the output of run's plan.
+	GetPlanLog(run Run) (string, error)
+	// GetApplyLog returns the output of run's apply.
+	GetApplyLog(run Run) (string, error)
+}
+
+// Run is a Terraform Cloud/Enterprise run.
+type Run struct {
+	ID string
+	// Status is the run's status, ex. planning or applied. See
+	// https://www.terraform.io/docs/cloud/api/run.html#run-states.
+	Status string
+	// URL links to the run in the Terraform Cloud/Enterprise UI.
+	URL string
+	// Confirmable is true if the run's plan can be applied.
+	Confirmable bool
+	PlanID      string
+	ApplyID     string
+}
+
+// Run statuses Atlantis acts on.
+const (
+	PlannedAndFinishedStatus = "planned_and_finished"
+	PolicyOverrideStatus     = "policy_override"
+	AppliedStatus            = "applied"
+	ErroredStatus            = "errored"
+)
+
+// PlanFinished returns true if the run's plan is done, either because it's
+// waiting to be applied or overridden or because the run is over.
+func (r Run) PlanFinished() bool {
+	return r.Confirmable || r.Status == PolicyOverrideStatus || r.Finished()
+}
+
+// Finished returns true if the run is over and nothing else will happen to
+// it.
+func (r Run) Finished() bool {
+	switch r.Status {
+	case PlannedAndFinishedStatus, AppliedStatus, ErroredStatus, "discarded", "canceled", "force_canceled", "policy_soft_failed":
+		return true
+	}
+	return false
+}
+
+// DefaultClient implements Client using the Terraform Cloud/Enterprise API.
+type DefaultClient struct {
+	hostname     string
+	token        string
+	httpClient   *http.Client
+	pollInterval time.Duration
+}
+
+// NewClient returns a client for the Terraform Cloud/Enterprise installation at
+// hostname, ex. app.terraform.io, that authenticates with token.
+func NewClient(hostname string, token string) *DefaultClient {
+	return &DefaultClient{
+		hostname:     hostname,
+		token:        token,
+		httpClient:   &http.Client{Timeout: 30 * time.Second},
+		pollInterval: time.Second,
+	}
+}
+
+// ansiRegex matches the color codes in run logs.
+var ansiRegex = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// apiResource is the JSON:API document the API sends and receives.
+type apiResource struct {
+	Data struct {
+		ID            string                     `json:"id,omitempty"`
+		Type          string                     `json:"type"`
+		Attributes    map[string]interface{}     `json:"attributes,omitempty"`
+		Relationships map[string]apiRelationship `json:"relationships,omitempty"`
+	} `json:"data"`
+}
+
+type apiRelationship struct {
+	Data struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+	} `json:"data"`
+}
+
+// CreateRun implements Client.CreateRun.
+func (c *DefaultClient) CreateRun(workspace string, dir string, message string, destroy bool) (Run, error) {
+	org, name, err := splitWorkspace(workspace)
+	if err != nil {
+		return Run{}, err
+	}
+	ws, err := c.do("GET", fmt.Sprintf("/organizations/%s/workspaces/%s", url.PathEscape(org), url.PathEscape(name)), nil)
+	if err != nil {
+		return Run{}, errors.Wrapf(err, "getting workspace %s", workspace)
+	}
+
+	// Like the terraform CLI, if the workspace has a working directory we
+	// upload the directory it's relative to so that modules outside of it
+	// can be used.
+	uploadDir := dir
+	if workingDir, _ := ws.Data.Attributes["working-directory"].(string); workingDir != "" {
+		workingDir = filepath.Clean(workingDir)
+		if !strings.HasSuffix(dir, string(filepath.Separator)+workingDir) {
+			return Run{}, fmt.Errorf("workspace %s has working directory %q but the project's directory isn't %q", workspace, workingDir, workingDir)
+		}
+		uploadDir = strings.TrimSuffix(dir, string(filepath.Separator)+workingDir)
+	}
+
+	cvReq := apiResource{}
+	cvReq.Data.Type = "configuration-versions"
+	cvReq.Data.Attributes = map[string]interface{}{"auto-queue-runs": false}
+	cv, err := c.do("POST", fmt.Sprintf("/workspaces/%s/configuration-versions", ws.Data.ID), &cvReq)
+	if err != nil {
+		return Run{}, errors.Wrap(err, "creating configuration version")
+	}
+	uploadURL, _ := cv.Data.Attributes["upload-url"].(string)
+	if err := c.uploadConfiguration(uploadURL, uploadDir); err != nil {
+		return Run{}, errors.Wrap(err, "uploading configuration")
+	}
+	if err := c.waitForUpload(cv.Data.ID); err != nil {
+		return Run{}, err
+	}
+
+	runReq := apiResource{}
+	runReq.Data.Type = "runs"
+	runReq.Data.Attributes = map[string]interface{}{
+		"message":    message,
+		"is-destroy": destroy,
+		"auto-apply": false,
+	}
+	runReq.Data.Relationships = map[string]apiRelationship{
+		"workspace":             newRelationship("workspaces", ws.Data.ID),
+		"configuration-version": newRelationship("configuration-versions", cv.Data.ID),
+	}
+	run, err := c.do("POST", "/runs", &runReq)
+	if err != nil {
+		return Run{}, errors.Wrap(err, "creating run")
+	}
+	return c.toRun(org, name, run), nil
+}
+
+// GetRun implements Client.GetRun.
+func (c *DefaultClient) GetRun(workspace string, runID string) (Run, error) {
+	org, name, err := splitWorkspace(workspace)
+	if err != nil {
+		return Run{}, err
+	}
+	run, err := c.do("GET", "/runs/"+url.PathEscape(runID), nil)
+	if err != nil {
+		return Run{}, errors.Wrapf(err, "getting run %s", runID)
+	}
+	return c.toRun(org, name, run), nil
+}
+
+// ApplyRun implements Client.ApplyRun.
+func (c *DefaultClient) ApplyRun(runID string, comment string) error {
+	return c.runAction(runID, "apply", comment)
+}
+
+// DiscardRun implements Client.DiscardRun.
+func (c *DefaultClient) DiscardRun(runID string, comment string) error {
+	return c.runAction(runID, "discard", comment)
+}
+
+// GetPlanLog implements Client.GetPlanLog.
+func (c *DefaultClient) GetPlanLog(run Run) (string, error) {
+	return c.getLog("/plans/" + url.PathEscape(run.PlanID))
+}
+
+// GetApplyLog implements Client.GetApplyLog.
+func (c *DefaultClient) GetApplyLog(run Run) (string, error) {
+	return c.getLog("/applies/" + url.PathEscape(run.ApplyID))
+}
+
+func (c *DefaultClient) runAction(runID string, action string, comment string) error {
+	body, err := json.Marshal(map[string]string{"comment": comment})
+	if err != nil {
+		return err
+	}
+	resp, err := c.request("POST", fmt.Sprintf("/runs/%s/actions/%s", url.PathEscape(runID), action), body)
+	if err != nil {
+		return errors.Wrapf(err, "running %s on run %s", action, runID)
+	}
+	resp.Body.Close() // nolint: errcheck
+	return nil
+}
+
+// getLog returns the log of the plan or apply at path with colors removed.
+func (c *DefaultClient) getLog(path string) (string, error) {
+	res, err := c.do("GET", path, nil)
+	if err != nil {
+		return "", errors.Wrapf(err, "getting %s", path)
+	}
+	logURL, _ := res.Data.Attributes["log-read-url"].(string)
+	if logURL == "" {
+		return "", fmt.Errorf("%s has no log", path)
+	}
+	// The log URL is pre-signed so the token isn't sent to it.
+	resp, err := c.httpClient.Get(logURL) // nolint: gosec
+	if err != nil {
+		return "", errors.Wrap(err, "downloading log")
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("downloading log: unexpected status %d", resp.StatusCode)
+	}
+	log, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", errors.Wrap(err, "reading log")
+	}
+	return ansiRegex.ReplaceAllString(string(log), ""), nil
+}
+
+// waitForUpload waits until the configuration version with id cvID has been
+// processed so a run can be created from it.
+func (c *DefaultClient) waitForUpload(cvID string) error {
+	for {
+		cv, err := c.do("GET", "/configuration-versions/"+cvID, nil)
+		if err != nil {
+			return errors.Wrap(err, "getting configuration version")
+		}
+		switch status, _ := cv.Data.Attributes["status"].(string); status {
+		case "uploaded":
+			return nil
+		case "errored":
+			return errors.New("configuration upload errored")
+		}
+		time.Sleep(c.pollInterval)
+	}
+}
+
+// uploadConfiguration uploads dir as a gzipped tarball to uploadURL.
+func (c *DefaultClient) uploadConfiguration(uploadURL string, dir string) error {
+	var buf bytes.Buffer
+	if err := archiveDir(&buf, dir); err != nil {
+		return err
+	}
+	req, err := http.NewRequest("PUT", uploadURL, &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// do sends the API request and decodes the response.
+func (c *DefaultClient) do(method string, path string, reqBody *apiResource) (apiResource, error) {
+	var res apiResource
+	var body []byte
+	if reqBody != nil {
+		var err error
+		if body, err = json.Marshal(reqBody); err != nil {
+			return res, err
+		}
+	}
+	resp, err := c.request(method, path, body)
+	if err != nil {
+		return res, err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	err = json.NewDecoder(resp.Body).Decode(&res)
+	return res, errors.Wrap(err, "decoding response")
+}
+
+// request sends an authenticated API request and returns its response if it
+// was successful.
+func (c *DefaultClient) request(method string, path string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, fmt.Sprintf("https://%s/api/v2%s", c.hostname, path), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", "application/vnd.api+json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close() // nolint: errcheck
+		return nil, fmt.Errorf("%s %s: unexpected status %d: %s", method, path, resp.StatusCode, respBody)
+	}
+	return resp, nil
+}
+
+func (c *DefaultClient) toRun(org string, workspace string, res apiResource) Run {
+	status, _ := res.Data.Attributes["status"].(string)
+	var confirmable bool
+	if actions, ok := res.Data.Attributes["actions"].(map[string]interface{}); ok {
+		confirmable, _ = actions["is-confirmable"].(bool)
+	}
+	return Run{
+		ID:          res.Data.ID,
+		Status:      status,
+		URL:         fmt.Sprintf("https://%s/app/%s/workspaces/%s/runs/%s", c.hostname, org, workspace, res.Data.ID),
+		Confirmable: confirmable,
+		PlanID:      res.Data.Relationships["plan"].Data.ID,
+		ApplyID:     res.Data.Relationships["apply"].Data.ID,
+	}
+}
+
+func newRelationship(typ string, id string) apiRelationship {
+	var r apiRelationship
+	r.Data.Type = typ
+	r.Data.ID = id
+	return r
+}
+
+// splitWorkspace splits workspace into its organization and name.
+func splitWorkspace(workspace string) (string, string, error) {
+	split := strings.Split(workspace, "/")
+	if len(split) != 2 || split[0] == "" || split[1] == "" {
+		return "", "", fmt.Errorf("invalid workspace %q: must be of the form <organization>/<workspace>", workspace)
+	}
+	return split[0], split[1], nil
+}
+
+// archiveDir writes dir as a gzipped tarball to w. The .git and .terraform
+// directories aren't needed by remote runs so they're skipped.
+func archiveDir(w io.Writer, dir string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		if info.IsDir() && (info.Name() == ".git" || info.Name() == ".terraform") {
+			return filepath.SkipDir
+		}
+
+		var link string
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(path) // nolint: gosec
+		if err != nil {
+			return err
+		}
+		defer f.Close() // nolint: errcheck
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return errors.Wrapf(err, "archiving %s", dir)
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
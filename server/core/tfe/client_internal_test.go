@@ -0,0 +1,174 @@
+package tfe
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// fakeTFE is a Terraform Cloud API with one workspace, org/ws, and one run.
+type fakeTFE struct {
+	t                *testing.T
+	server           *httptest.Server
+	workingDirectory string
+	uploaded         []string
+	runRequest       map[string]interface{}
+	actions          []string
+}
+
+func newFakeTFE(t *testing.T) *fakeTFE {
+	f := &fakeTFE{t: t}
+	f.server = httptest.NewTLSServer(http.HandlerFunc(f.handle))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeTFE) client() *DefaultClient {
+	c := NewClient(strings.TrimPrefix(f.server.URL, "https://"), "token")
+	c.httpClient = f.server.Client()
+	c.pollInterval = 0
+	return c
+}
+
+func (f *fakeTFE) handle(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, "/api/") {
+		f.handleUnauthenticated(w, r)
+		return
+	}
+	Equals(f.t, "Bearer token", r.Header.Get("Authorization"))
+	switch r.Method + " " + r.URL.Path {
+	case "GET /api/v2/organizations/org/workspaces/ws":
+		fmt.Fprintf(w, `{"data":{"id":"ws-1","type":"workspaces","attributes":{"working-directory":%q}}}`, f.workingDirectory) // nolint: errcheck
+	case "POST /api/v2/workspaces/ws-1/configuration-versions":
+		fmt.Fprintf(w, `{"data":{"id":"cv-1","type":"configuration-versions","attributes":{"status":"pending","upload-url":"%s/upload"}}}`, f.server.URL) // nolint: errcheck
+	case "GET /api/v2/configuration-versions/cv-1":
+		status := "pending"
+		if f.uploaded != nil {
+			status = "uploaded"
+		}
+		fmt.Fprintf(w, `{"data":{"id":"cv-1","type":"configuration-versions","attributes":{"status":%q}}}`, status) // nolint: errcheck
+	case "POST /api/v2/runs":
+		Ok(f.t, json.NewDecoder(r.Body).Decode(&f.runRequest))
+		fmt.Fprint(w, runJSON) // nolint: errcheck
+	case "GET /api/v2/runs/run-1":
+		fmt.Fprint(w, runJSON) // nolint: errcheck
+	case "POST /api/v2/runs/run-1/actions/apply", "POST /api/v2/runs/run-1/actions/discard":
+		body, _ := ioutil.ReadAll(r.Body)
+		f.actions = append(f.actions, r.URL.Path[len("/api/v2/runs/run-1/actions/"):]+" "+string(body))
+		w.WriteHeader(http.StatusAccepted)
+	case "GET /api/v2/plans/plan-1":
+		fmt.Fprintf(w, `{"data":{"id":"plan-1","type":"plans","attributes":{"log-read-url":"%s/log"}}}`, f.server.URL) // nolint: errcheck
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"errors":[{"status":"404","title":"not found"}]}`) // nolint: errcheck
+	}
+}
+
+// handleUnauthenticated serves the pre-signed upload and log URLs which
+// mustn't be sent the token.
+func (f *fakeTFE) handleUnauthenticated(w http.ResponseWriter, r *http.Request) {
+	Equals(f.t, "", r.Header.Get("Authorization"))
+	switch r.Method + " " + r.URL.Path {
+	case "PUT /upload":
+		gz, err := gzip.NewReader(r.Body)
+		Ok(f.t, err)
+		tr := tar.NewReader(gz)
+		f.uploaded = []string{}
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			Ok(f.t, err)
+			f.uploaded = append(f.uploaded, hdr.Name)
+		}
+	case "GET /log":
+		fmt.Fprint(w, "\x1b[1mPlan:\x1b[0m 1 to add, 0 to change, 0 to destroy.") // nolint: errcheck
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+var runJSON = `{"data":{"id":"run-1","type":"runs",
+"attributes":{"status":"planned","actions":{"is-confirmable":true}},
+"relationships":{"plan":{"data":{"id":"plan-1","type":"plans"}},"apply":{"data":{"id":"apply-1","type":"applies"}}}}}`
+
+func TestDefaultClient_CreateRun(t *testing.T) {
+	f := newFakeTFE(t)
+	repoDir, cleanup := TempDir(t)
+	defer cleanup()
+	for _, file := range []string{"main.tf", "modules/vpc/main.tf", ".git/HEAD", ".terraform/terraform.tfstate"} {
+		Ok(t, os.MkdirAll(filepath.Join(repoDir, filepath.Dir(file)), 0700))
+		Ok(t, ioutil.WriteFile(filepath.Join(repoDir, file), nil, 0600))
+	}
+
+	run, err := f.client().CreateRun("org/ws", repoDir, "message", true)
+	Ok(t, err)
+	Equals(t, Run{
+		ID:          "run-1",
+		Status:      "planned",
+		URL:         fmt.Sprintf("https://%s/app/org/workspaces/ws/runs/run-1", strings.TrimPrefix(f.server.URL, "https://")),
+		Confirmable: true,
+		PlanID:      "plan-1",
+		ApplyID:     "apply-1",
+	}, run)
+	Equals(t, []string{"main.tf", "modules", "modules/vpc", "modules/vpc/main.tf"}, f.uploaded)
+
+	data := f.runRequest["data"].(map[string]interface{})
+	Equals(t, map[string]interface{}{"message": "message", "is-destroy": true, "auto-apply": false}, data["attributes"])
+	relationships := data["relationships"].(map[string]interface{})
+	Equals(t, "cv-1", relationships["configuration-version"].(map[string]interface{})["data"].(map[string]interface{})["id"])
+	Equals(t, "ws-1", relationships["workspace"].(map[string]interface{})["data"].(map[string]interface{})["id"])
+}
+
+func TestDefaultClient_CreateRunWorkingDirectory(t *testing.T) {
+	f := newFakeTFE(t)
+	f.workingDirectory = "project"
+	repoDir, cleanup := TempDir(t)
+	defer cleanup()
+	Ok(t, os.MkdirAll(filepath.Join(repoDir, "project"), 0700))
+	Ok(t, ioutil.WriteFile(filepath.Join(repoDir, "project", "main.tf"), nil, 0600))
+
+	t.Log("the directory the working directory is relative to is uploaded")
+	_, err := f.client().CreateRun("org/ws", filepath.Join(repoDir, "project"), "message", false)
+	Ok(t, err)
+	Equals(t, []string{"project", "project/main.tf"}, f.uploaded)
+
+	_, err = f.client().CreateRun("org/ws", repoDir, "message", false)
+	ErrEquals(t, `workspace org/ws has working directory "project" but the project's directory isn't "project"`, err)
+}
+
+func TestDefaultClient_Errors(t *testing.T) {
+	f := newFakeTFE(t)
+	_, err := f.client().CreateRun("org/missing", "dir", "message", false)
+	ErrContains(t, "getting workspace org/missing: GET /organizations/org/workspaces/missing: unexpected status 404", err)
+
+	_, err = f.client().GetRun("invalid", "run-1")
+	ErrEquals(t, `invalid workspace "invalid": must be of the form <organization>/<workspace>`, err)
+}
+
+func TestDefaultClient_RunActions(t *testing.T) {
+	f := newFakeTFE(t)
+	c := f.client()
+	Ok(t, c.ApplyRun("run-1", "apply comment"))
+	Ok(t, c.DiscardRun("run-1", "discard comment"))
+	Equals(t, []string{`apply {"comment":"apply comment"}`, `discard {"comment":"discard comment"}`}, f.actions)
+}
+
+func TestDefaultClient_GetPlanLog(t *testing.T) {
+	f := newFakeTFE(t)
+	log, err := f.client().GetPlanLog(Run{PlanID: "plan-1"})
+	Ok(t, err)
+	Equals(t, "Plan: 1 to add, 0 to change, 0 to destroy.", log)
+}
@@ -1,10 +1,15 @@
 package events
 
 import (
-	"github.com/runatlantis/atlantis/server/core/db"
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/runatlantis/atlantis/server/core/locking"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/i18n"
 )
 
 func NewApplyCommandRunner(
@@ -17,7 +22,7 @@ func NewApplyCommandRunner(
 	autoMerger *AutoMerger,
 	pullUpdater *PullUpdater,
 	dbUpdater *DBUpdater,
-	db *db.BoltDB,
+	db locking.Backend,
 	parallelPoolSize int,
 	SilenceNoProjects bool,
 	silenceVCSStatusNoProjects bool,
@@ -36,12 +41,13 @@ func NewApplyCommandRunner(
 		parallelPoolSize:           parallelPoolSize,
 		SilenceNoProjects:          SilenceNoProjects,
 		silenceVCSStatusNoProjects: silenceVCSStatusNoProjects,
+		confirmations:              newApplyConfirmations(),
 	}
 }
 
 type ApplyCommandRunner struct {
 	DisableApplyAll     bool
-	DB                  *db.BoltDB
+	DB                  locking.Backend
 	locker              locking.ApplyLockChecker
 	vcsClient           vcs.Client
 	commitStatusUpdater CommitStatusUpdater
@@ -57,12 +63,24 @@ type ApplyCommandRunner struct {
 	// SilenceVCSStatusNoPlans is whether any plan should set commit status if no projects
 	// are found
 	silenceVCSStatusNoProjects bool
+	// ConfirmationWindow, if set, is how long users have to confirm applies
+	// with atlantis apply --confirm. Applies that aren't confirmed only
+	// comment which plans they would apply.
+	ConfirmationWindow time.Duration
+	confirmations      *applyConfirmations
+	// GlobalCfg, if set, is used to limit how many projects atlantis apply
+	// without flags can apply per repo.
+	GlobalCfg *valid.GlobalCfgStore
+	// Localizer, if set, picks the locale of the comments. Otherwise they're
+	// in English.
+	Localizer *i18n.Localizer
 }
 
 func (a *ApplyCommandRunner) Run(ctx *CommandContext, cmd *CommentCommand) {
 	var err error
 	baseRepo := ctx.Pull.BaseRepo
 	pull := ctx.Pull
+	locale := a.Localizer.For(baseRepo, pull.BaseBranch)
 
 	locked, err := a.IsLocked()
 	// CheckApplyLock falls back to DisableApply flag if fetching the lock
@@ -74,26 +92,24 @@ func (a *ApplyCommandRunner) Run(ctx *CommandContext, cmd *CommentCommand) {
 
 	if locked {
 		ctx.Log.Info("ignoring apply command since apply disabled globally")
-		if err := a.vcsClient.CreateComment(baseRepo, pull.Num, applyDisabledComment, models.ApplyCommand.String()); err != nil {
+		if err := commentOnPull(a.vcsClient, ctx, i18n.Sprintf(locale, i18n.ApplyDisabled), models.ApplyCommand.String()); err != nil {
 			ctx.Log.Err("unable to comment on pull request: %s", err)
 		}
 
 		return
 	}
 
-	if a.DisableApplyAll && !cmd.IsForSpecificProject() {
+	// Auto-applies are opted into per repo so they aren't affected by
+	// disabling apply all.
+	if a.DisableApplyAll && !cmd.IsForSpecificProject() && !cmd.AutoApply {
 		ctx.Log.Info("ignoring apply command without flags since apply all is disabled")
-		if err := a.vcsClient.CreateComment(baseRepo, pull.Num, applyAllDisabledComment, models.ApplyCommand.String()); err != nil {
+		if err := commentOnPull(a.vcsClient, ctx, i18n.Sprintf(locale, i18n.ApplyAllDisabled), models.ApplyCommand.String()); err != nil {
 			ctx.Log.Err("unable to comment on pull request: %s", err)
 		}
 
 		return
 	}
 
-	if err = a.commitStatusUpdater.UpdateCombined(baseRepo, pull, models.PendingCommitStatus, cmd.CommandName()); err != nil {
-		ctx.Log.Warn("unable to update commit status: %s", err)
-	}
-
 	// Get the mergeable status before we set any build statuses of our own.
 	// We do this here because when we set a "Pending" status, if users have
 	// required the Atlantis status checks to pass, then we've now changed
@@ -120,6 +136,22 @@ func (a *ApplyCommandRunner) Run(ctx *CommandContext, cmd *CommentCommand) {
 		return
 	}
 
+	if limit, ok := a.applyAllMaxProjects(ctx, cmd); ok && len(projectCmds) > limit {
+		ctx.Log.Info("ignoring apply command without flags since it would apply %d projects, more than the limit of %d", len(projectCmds), limit)
+		if err := commentOnPull(a.vcsClient, ctx, applyAllMaxProjectsComment(locale, limit, projectCmds), models.ApplyCommand.String()); err != nil {
+			ctx.Log.Err("unable to comment on pull request: %s", err)
+		}
+		return
+	}
+
+	if a.needsConfirmation(cmd) && len(projectCmds) > 0 && !a.confirm(ctx, cmd, projectCmds, locale) {
+		return
+	}
+
+	if err = a.commitStatusUpdater.UpdateCombined(baseRepo, pull, models.PendingCommitStatus, cmd.CommandName()); err != nil {
+		ctx.Log.Warn("unable to update commit status: %s", err)
+	}
+
 	// If there are no projects to apply, don't respond to the PR and ignore
 	if len(projectCmds) == 0 && a.SilenceNoProjects {
 		ctx.Log.Info("determined there was no project to run apply in.")
@@ -157,9 +189,77 @@ func (a *ApplyCommandRunner) Run(ctx *CommandContext, cmd *CommentCommand) {
 
 	a.updateCommitStatus(ctx, pullStatus)
 
-	if a.autoMerger.automergeEnabled(projectCmds) && !cmd.AutoMergeDisabled {
-		a.autoMerger.automerge(ctx, pullStatus, a.autoMerger.deleteSourceBranchOnMergeEnabled(projectCmds))
+	// Auto-applied pull requests are always merged, that's what makes
+	// auto_apply a deployment queue.
+	if (cmd.AutoApply || a.autoMerger.automergeEnabled(projectCmds)) && !cmd.AutoMergeDisabled {
+		a.autoMerger.automerge(ctx, pullStatus, projectCmds)
+	}
+}
+
+// applyAllMaxProjects returns the most projects cmd can apply and whether
+// there's a limit. Only applies without flags are limited.
+func (a *ApplyCommandRunner) applyAllMaxProjects(ctx *CommandContext, cmd *CommentCommand) (int, bool) {
+	if a.GlobalCfg == nil || cmd.IsForSpecificProject() || cmd.AutoApply {
+		return 0, false
 	}
+	return a.GlobalCfg.Get().ApplyAllMaxProjects(ctx.Pull.BaseRepo.ID(), ctx.Pull.BaseBranch)
+}
+
+// needsConfirmation returns true if cmd must be confirmed before it applies.
+// Auto-applies and applies that were already confirmed don't.
+func (a *ApplyCommandRunner) needsConfirmation(cmd *CommentCommand) bool {
+	return a.ConfirmationWindow > 0 && !cmd.AutoApply && !cmd.Confirmed
+}
+
+// confirm returns true if the apply of projectCmds was confirmed. Otherwise
+// it records the apply as waiting to be confirmed and comments which plans
+// it would apply, or comments why the confirmation failed.
+func (a *ApplyCommandRunner) confirm(ctx *CommandContext, cmd *CommentCommand, projectCmds []models.ProjectCommandContext, locale string) bool {
+	if cmd.Confirm {
+		canConfirm := func(requester string) bool { return a.canConfirm(ctx, requester) }
+		if err := a.confirmations.confirm(ctx.Pull, projectCmds, canConfirm, time.Now(), locale); err != nil {
+			a.pullUpdater.updatePull(ctx, cmd, CommandResult{Failure: err.Error()})
+			return false
+		}
+		return true
+	}
+
+	ctx.Log.Info("waiting for apply to be confirmed")
+	a.confirmations.request(ctx.Pull, ctx.User, projectCmds, time.Now().Add(a.ConfirmationWindow))
+	comment := applyConfirmationComment(locale, ctx.Pull, cmd, projectCmds, a.ConfirmationWindow)
+	if progressCommentID := ctx.ProgressCommentID; progressCommentID != 0 {
+		ctx.ProgressCommentID = 0
+		err := a.vcsClient.UpdateComment(ctx.Pull.BaseRepo, ctx.Pull.Num, progressCommentID, comment, models.ApplyCommand.String())
+		if err == nil {
+			return false
+		}
+		ctx.Log.Warn("unable to update progress comment, commenting instead: %s", err)
+	}
+	if err := commentOnPull(a.vcsClient, ctx, comment, models.ApplyCommand.String()); err != nil {
+		ctx.Log.Err("unable to comment on pull request: %s", err)
+	}
+	return false
+}
+
+// canConfirm returns true if ctx.User can confirm an apply that requester
+// requested. Only the requester and members of the teams allowed to apply on
+// the repo can.
+func (a *ApplyCommandRunner) canConfirm(ctx *CommandContext, requester string) bool {
+	if ctx.User.Username == requester {
+		return true
+	}
+	if a.GlobalCfg == nil {
+		return false
+	}
+	teams := a.GlobalCfg.Get().AllowedApplyTeams(ctx.Pull.BaseRepo.ID(), ctx.Pull.BaseBranch)
+	if len(teams) == 0 {
+		return false
+	}
+	isMember, err := a.vcsClient.UserIsTeamMember(ctx.Pull.BaseRepo, ctx.User, teams)
+	if err != nil {
+		ctx.Log.Err("unable to check team membership for %s: %s", ctx.User.Username, err)
+	}
+	return isMember
 }
 
 func (a *ApplyCommandRunner) IsLocked() (bool, error) {
@@ -200,10 +300,14 @@ func (a *ApplyCommandRunner) updateCommitStatus(ctx *CommandContext, pullStatus
 	}
 }
 
-// applyAllDisabledComment is posted when apply all commands (i.e. "atlantis apply")
-// are disabled and an apply all command is issued.
-var applyAllDisabledComment = "**Error:** Running `atlantis apply` without flags is disabled." +
-	" You must specify which project to apply via the `-d <dir>`, `-w <workspace>` or `-p <project name>` flags."
-
-// applyDisabledComment is posted when apply commands are disabled globally and an apply command is issued.
-var applyDisabledComment = "**Error:** Running `atlantis apply` is disabled."
+// applyAllMaxProjectsComment is posted when an apply all command would apply
+// more than limit projects. It lists the commands to apply each project.
+func applyAllMaxProjectsComment(locale string, limit int, projectCmds []models.ProjectCommandContext) string {
+	var b strings.Builder
+	b.WriteString(i18n.Sprintf(locale, i18n.ApplyAllMaxProjects, limit, len(projectCmds)))
+	b.WriteString("\n\n")
+	for _, prj := range projectCmds {
+		fmt.Fprintf(&b, "* `%s`\n", prj.ApplyCmd)
+	}
+	return b.String()
+}
@@ -2,15 +2,21 @@ package events_test
 
 import (
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-github/v31/github"
 	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/core/db"
 	"github.com/runatlantis/atlantis/server/core/locking"
 	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/models/fixtures"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
 )
 
 func TestApplyCommandRunner_IsLocked(t *testing.T) {
@@ -68,3 +74,162 @@ func TestApplyCommandRunner_IsLocked(t *testing.T) {
 		})
 	}
 }
+
+func TestApplyCommandRunner_Confirmation(t *testing.T) {
+	vcsClient := setup(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	dbUpdater.DB = boltDB
+	applyCommandRunner.DB = boltDB
+	applyCommandRunner.ConfirmationWindow = 10 * time.Minute
+
+	modelPull := models.PullRequest{BaseRepo: fixtures.GithubRepo, State: models.OpenPullState, Num: fixtures.Pull.Num, HeadCommit: "abc123"}
+	newCtx := func(pull models.PullRequest) *events.CommandContext {
+		return &events.CommandContext{
+			User:     fixtures.User,
+			Log:      logging.NewNoopLogger(t),
+			Pull:     pull,
+			HeadRepo: fixtures.GithubRepo,
+			Trigger:  events.Comment,
+		}
+	}
+	When(projectCommandBuilder.BuildApplyCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand())).ThenReturn([]models.ProjectCommandContext{
+		{
+			CommandName: models.ApplyCommand,
+			Workspace:   "default",
+			RepoRelDir:  "dir",
+			ProjectName: "project",
+		},
+	}, nil)
+	When(projectCommandRunner.Apply(matchers.AnyModelsProjectCommandContext())).ThenReturn(models.ProjectResult{
+		Command:      models.ApplyCommand,
+		Workspace:    "default",
+		RepoRelDir:   "dir",
+		ApplySuccess: "success",
+	})
+
+	t.Log("confirming without a pending apply fails")
+	applyCommandRunner.Run(newCtx(modelPull), &events.CommentCommand{Name: models.ApplyCommand, Confirm: true})
+	_, _, comment, _ := vcsClient.VerifyWasCalledOnce().CreateComment(matchers.AnyModelsRepo(), AnyInt(), AnyString(), AnyString()).GetCapturedArguments()
+	Assert(t, strings.Contains(comment, "there's no apply waiting to be confirmed"), "got %q", comment)
+
+	t.Log("applying comments which plans would be applied")
+	applyCommandRunner.Run(newCtx(modelPull), &events.CommentCommand{Name: models.ApplyCommand, RepoRelDir: "dir"})
+	_, _, comments, _ := vcsClient.VerifyWasCalled(Times(2)).CreateComment(matchers.AnyModelsRepo(), AnyInt(), AnyString(), AnyString()).GetAllCapturedArguments()
+	Equals(t, "**Apply Not Yet Confirmed**: these plans for commit `abc123` would be applied:\n\n"+
+		"| Project | Dir | Workspace |\n|---|---|---|\n"+
+		"| project | `dir` | `default` |\n\n"+
+		"To apply them, comment within 10m0s:\n* `atlantis apply -d dir --confirm`\n", comments[1])
+	projectCommandRunner.VerifyWasCalled(Never()).Apply(matchers.AnyModelsProjectCommandContext())
+
+	t.Log("confirming after the pull request was updated fails")
+	updatedPull := modelPull
+	updatedPull.HeadCommit = "def456"
+	applyCommandRunner.Run(newCtx(updatedPull), &events.CommentCommand{Name: models.ApplyCommand, RepoRelDir: "dir", Confirm: true})
+	projectCommandRunner.VerifyWasCalled(Never()).Apply(matchers.AnyModelsProjectCommandContext())
+
+	t.Log("confirming applies the plans")
+	applyCommandRunner.Run(newCtx(modelPull), &events.CommentCommand{Name: models.ApplyCommand, RepoRelDir: "dir"})
+	applyCommandRunner.Run(newCtx(modelPull), &events.CommentCommand{Name: models.ApplyCommand, RepoRelDir: "dir", Confirm: true})
+	projectCommandRunner.VerifyWasCalledOnce().Apply(matchers.AnyModelsProjectCommandContext())
+
+	t.Log("confirmed applies don't need to be confirmed again")
+	applyCommandRunner.Run(newCtx(modelPull), &events.CommentCommand{Name: models.ApplyCommand, RepoRelDir: "dir", Confirmed: true})
+	projectCommandRunner.VerifyWasCalled(Times(2)).Apply(matchers.AnyModelsProjectCommandContext())
+}
+
+func TestApplyCommandRunner_ConfirmationUser(t *testing.T) {
+	vcsClient := setup(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	dbUpdater.DB = boltDB
+	applyCommandRunner.DB = boltDB
+	applyCommandRunner.ConfirmationWindow = 10 * time.Minute
+
+	modelPull := models.PullRequest{BaseRepo: fixtures.GithubRepo, State: models.OpenPullState, Num: fixtures.Pull.Num, HeadCommit: "abc123"}
+	other := models.User{Username: "other"}
+	newCtx := func(user models.User) *events.CommandContext {
+		return &events.CommandContext{
+			User:     user,
+			Log:      logging.NewNoopLogger(t),
+			Pull:     modelPull,
+			HeadRepo: fixtures.GithubRepo,
+			Trigger:  events.Comment,
+		}
+	}
+	When(projectCommandBuilder.BuildApplyCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand())).ThenReturn([]models.ProjectCommandContext{
+		{CommandName: models.ApplyCommand, Workspace: "default", RepoRelDir: "dir"},
+	}, nil)
+	When(projectCommandRunner.Apply(matchers.AnyModelsProjectCommandContext())).ThenReturn(models.ProjectResult{
+		Command:      models.ApplyCommand,
+		ApplySuccess: "success",
+	})
+
+	t.Log("other users can't confirm an apply")
+	applyCommandRunner.Run(newCtx(fixtures.User), &events.CommentCommand{Name: models.ApplyCommand, RepoRelDir: "dir"})
+	applyCommandRunner.Run(newCtx(other), &events.CommentCommand{Name: models.ApplyCommand, RepoRelDir: "dir", Confirm: true})
+	_, _, comments, _ := vcsClient.VerifyWasCalled(Times(2)).CreateComment(matchers.AnyModelsRepo(), AnyInt(), AnyString(), AnyString()).GetAllCapturedArguments()
+	Assert(t, strings.Contains(comments[1], "was requested by "+fixtures.User.Username), "got %q", comments[1])
+	projectCommandRunner.VerifyWasCalled(Never()).Apply(matchers.AnyModelsProjectCommandContext())
+
+	t.Log("members of the allowed apply teams can confirm an apply")
+	globalCfg := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})
+	globalCfg.Repos = append(globalCfg.Repos, valid.Repo{
+		ID:                fixtures.GithubRepo.ID(),
+		AllowedApplyTeams: []string{"platform"},
+	})
+	applyCommandRunner.GlobalCfg = valid.NewGlobalCfgStore(globalCfg)
+	When(vcsClient.UserIsTeamMember(fixtures.GithubRepo, other, []string{"platform"})).ThenReturn(true, nil)
+	applyCommandRunner.Run(newCtx(other), &events.CommentCommand{Name: models.ApplyCommand, RepoRelDir: "dir", Confirm: true})
+	projectCommandRunner.VerifyWasCalledOnce().Apply(matchers.AnyModelsProjectCommandContext())
+}
+
+func TestApplyCommandRunner_ApplyAllMaxProjects(t *testing.T) {
+	vcsClient := setup(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	dbUpdater.DB = boltDB
+	applyCommandRunner.DB = boltDB
+
+	limit := 1
+	globalCfg := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})
+	globalCfg.Repos = append(globalCfg.Repos, valid.Repo{
+		ID:                  fixtures.GithubRepo.ID(),
+		ApplyAllMaxProjects: &limit,
+	})
+	applyCommandRunner.GlobalCfg = valid.NewGlobalCfgStore(globalCfg)
+
+	modelPull := models.PullRequest{BaseRepo: fixtures.GithubRepo, State: models.OpenPullState, Num: fixtures.Pull.Num}
+	ctx := &events.CommandContext{
+		User:     fixtures.User,
+		Log:      logging.NewNoopLogger(t),
+		Pull:     modelPull,
+		HeadRepo: fixtures.GithubRepo,
+		Trigger:  events.Comment,
+	}
+	When(projectCommandBuilder.BuildApplyCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand())).ThenReturn([]models.ProjectCommandContext{
+		{CommandName: models.ApplyCommand, RepoRelDir: "dir1", Workspace: "default", ApplyCmd: "atlantis apply -d dir1"},
+		{CommandName: models.ApplyCommand, RepoRelDir: "dir2", Workspace: "default", ProjectName: "project2", ApplyCmd: "atlantis apply -p project2"},
+	}, nil)
+	When(projectCommandRunner.Apply(matchers.AnyModelsProjectCommandContext())).ThenReturn(models.ProjectResult{
+		Command:      models.ApplyCommand,
+		ApplySuccess: "success",
+	})
+
+	applyCommandRunner.Run(ctx, &events.CommentCommand{Name: models.ApplyCommand})
+	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GithubRepo, modelPull.Num, "**Error:** Running `atlantis apply` without flags is limited to 1 projects but this pull request has 2 plans to apply."+
+		" You must specify which project to apply via the `-d <dir>`, `-w <workspace>` or `-p <project name>` flags:\n\n"+
+		"* `atlantis apply -d dir1`\n"+
+		"* `atlantis apply -p project2`\n", "apply")
+	projectCommandRunner.VerifyWasCalled(Never()).Apply(matchers.AnyModelsProjectCommandContext())
+
+	t.Log("applies with flags aren't limited")
+	applyCommandRunner.Run(ctx, &events.CommentCommand{Name: models.ApplyCommand, RepoRelDir: "dir*"})
+	projectCommandRunner.VerifyWasCalled(Times(2)).Apply(matchers.AnyModelsProjectCommandContext())
+}
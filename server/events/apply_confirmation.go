@@ -0,0 +1,154 @@
+package events
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/i18n"
+)
+
+// applyConfirmations stores the applies that are waiting to be confirmed with
+// atlantis apply --confirm. They're kept in memory so they're lost if Atlantis
+// restarts, in which case users just need to comment atlantis apply again.
+type applyConfirmations struct {
+	// mutex prevents against multiple threads calling functions on this struct
+	// concurrently.
+	mutex sync.Mutex
+	// pending maps a pull request key to the apply waiting to be confirmed on
+	// that pull request. There's at most one per pull request.
+	pending map[string]pendingApply
+}
+
+// pendingApply is an apply that's waiting to be confirmed.
+type pendingApply struct {
+	// HeadCommit is the pull request's head commit when the apply was
+	// requested.
+	HeadCommit string
+	// Requester is the username of the user who requested the apply.
+	Requester string
+	// Projects are the keys of the projects that would be applied, sorted.
+	Projects []string
+	// Expires is when the apply can no longer be confirmed.
+	Expires time.Time
+}
+
+func newApplyConfirmations() *applyConfirmations {
+	return &applyConfirmations{
+		pending: make(map[string]pendingApply),
+	}
+}
+
+// request records that projectCmds, requested by user, are waiting to be
+// confirmed until expires, replacing any earlier apply waiting on the same
+// pull request.
+func (a *applyConfirmations) request(pull models.PullRequest, user models.User, projectCmds []models.ProjectCommandContext, expires time.Time) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.pending[a.key(pull)] = pendingApply{
+		HeadCommit: pull.HeadCommit,
+		Requester:  user.Username,
+		Projects:   confirmationProjects(projectCmds),
+		Expires:    expires,
+	}
+}
+
+// confirm removes the apply waiting on pull and returns an error describing,
+// in locale, why it can't go ahead if it isn't the apply of projectCmds at
+// the pull request's current head commit, or if it expired before now.
+// canConfirm is called with the username of the user who requested the apply
+// and returns whether the user confirming it is allowed to. If they aren't,
+// the apply is left waiting for someone who is.
+func (a *applyConfirmations) confirm(pull models.PullRequest, projectCmds []models.ProjectCommandContext, canConfirm func(requester string) bool, now time.Time, locale string) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	key := a.key(pull)
+	pending, ok := a.pending[key]
+	if !ok {
+		return errors.New(i18n.Sprintf(locale, i18n.ApplyConfirmNotPending))
+	}
+	if !canConfirm(pending.Requester) {
+		return errors.New(i18n.Sprintf(locale, i18n.ApplyConfirmNotAllowed, pending.Requester))
+	}
+	delete(a.pending, key)
+
+	switch {
+	case now.After(pending.Expires):
+		return errors.New(i18n.Sprintf(locale, i18n.ApplyConfirmExpired))
+	case pending.HeadCommit != pull.HeadCommit:
+		return errors.New(i18n.Sprintf(locale, i18n.ApplyConfirmPullUpdated, pending.HeadCommit, pull.HeadCommit))
+	case strings.Join(pending.Projects, ",") != strings.Join(confirmationProjects(projectCmds), ","):
+		return errors.New(i18n.Sprintf(locale, i18n.ApplyConfirmPlansChanged))
+	}
+	return nil
+}
+
+func (a *applyConfirmations) key(pull models.PullRequest) string {
+	return fmt.Sprintf("%s/%d", pull.BaseRepo.FullName, pull.Num)
+}
+
+// confirmationProjects returns the sorted keys of the projects in
+// projectCmds.
+func confirmationProjects(projectCmds []models.ProjectCommandContext) []string {
+	var projects []string
+	for _, cmd := range projectCmds {
+		projects = append(projects, fmt.Sprintf("%s/%s/%s", cmd.ProjectName, cmd.RepoRelDir, cmd.Workspace))
+	}
+	sort.Strings(projects)
+	return projects
+}
+
+// applyConfirmationComment returns the comment, in locale, listing the plans
+// that the apply of projectCmds would apply and how to confirm it.
+func applyConfirmationComment(locale string, pull models.PullRequest, cmd *CommentCommand, projectCmds []models.ProjectCommandContext, window time.Duration) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n%s\n|---|---|---|\n", i18n.Sprintf(locale, i18n.ApplyNotConfirmed, pull.HeadCommit), i18n.Sprintf(locale, i18n.ApplyNotConfirmedTable))
+	for _, prj := range projectCmds {
+		project := prj.ProjectName
+		if prj.PlanFromEarlierCommit {
+			project += " " + i18n.Sprintf(locale, i18n.ApplyNotConfirmedEarlier)
+		}
+		fmt.Fprintf(&b, "| %s | `%s` | `%s` |\n", project, prj.RepoRelDir, prj.Workspace)
+	}
+	fmt.Fprintf(&b, "\n%s\n* `%s`\n", i18n.Sprintf(locale, i18n.ApplyNotConfirmedHowTo, window), applyConfirmCommand(cmd))
+	return b.String()
+}
+
+// applyConfirmCommand returns the comment that confirms cmd, ex.
+// atlantis apply -d dir --confirm.
+func applyConfirmCommand(cmd *CommentCommand) string {
+	quote := func(s string) string {
+		if strings.Contains(s, " ") {
+			return fmt.Sprintf("%q", s)
+		}
+		return s
+	}
+	comment := fmt.Sprintf("%s %s", atlantisExecutable, models.ApplyCommand)
+	if cmd.ProjectName != "" {
+		comment += fmt.Sprintf(" -%s %s", projectFlagShort, cmd.ProjectName)
+	}
+	if cmd.RepoRelDir != "" {
+		comment += fmt.Sprintf(" -%s %s", dirFlagShort, quote(cmd.RepoRelDir))
+	}
+	if cmd.Workspace != "" {
+		comment += fmt.Sprintf(" -%s %s", workspaceFlagShort, quote(cmd.Workspace))
+	}
+	if cmd.Force {
+		comment += fmt.Sprintf(" --%s", forceFlagLong)
+	}
+	if cmd.AutoMergeDisabled {
+		comment += fmt.Sprintf(" --%s", autoMergeDisabledFlagLong)
+	}
+	if cmd.Verbose {
+		comment += fmt.Sprintf(" --%s", verboseFlagLong)
+	}
+	comment += fmt.Sprintf(" --%s", confirmFlagLong)
+	if len(cmd.Flags) > 0 {
+		comment += fmt.Sprintf(" -- %s", strings.Join(cmd.Flags, " "))
+	}
+	return comment
+}
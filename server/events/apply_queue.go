@@ -0,0 +1,142 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// ApplyQueue is used to serialize applies for a single project across pull
+// requests. Without it, multiple pull requests that apply against the same
+// state can race each other.
+type ApplyQueue interface {
+	// Enqueue adds the apply for ctx to the queue for its project. If the
+	// apply can run now, position will be 0 and release must be called once
+	// the apply is complete. Otherwise position is the apply's 1-based
+	// position in the queue and it will be re-run automatically once the
+	// applies ahead of it are complete. If the pull request's apply of the
+	// project is already running, release is nil.
+	Enqueue(ctx models.ProjectCommandContext) (release func(), position int)
+}
+
+// DefaultApplyQueue implements ApplyQueue. Queued applies are re-run through
+// CommandRunner as if the user had commented atlantis apply for just that
+// project.
+type DefaultApplyQueue struct {
+	// CommandRunner is used to run the next apply in a queue. It must be set
+	// before any applies are queued.
+	CommandRunner CommandRunner
+	// mutex prevents against multiple threads calling functions on this struct
+	// concurrently.
+	mutex sync.Mutex
+	// queues maps a project key to the applies for that project. The first
+	// apply in each queue is the one that's currently running, or that's
+	// about to be re-run.
+	queues map[string][]*queuedApply
+}
+
+// queuedApply is an apply waiting in, or at the front of, a queue.
+type queuedApply struct {
+	ctx models.ProjectCommandContext
+	// running is true once the apply has started. Only the call to Enqueue
+	// that started it can release it.
+	running bool
+}
+
+// NewDefaultApplyQueue is a constructor.
+func NewDefaultApplyQueue() *DefaultApplyQueue {
+	return &DefaultApplyQueue{
+		queues: make(map[string][]*queuedApply),
+	}
+}
+
+// Enqueue implements ApplyQueue.Enqueue.
+func (d *DefaultApplyQueue) Enqueue(ctx models.ProjectCommandContext) (func(), int) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	key := d.key(ctx)
+	queue := d.queues[key]
+	for i, queued := range queue {
+		// If this pull is already in the queue we don't add it again. If it's
+		// at the front then it's this pull's turn to apply, unless the apply
+		// is already running, ex. because apply was commented twice.
+		if queued.ctx.Pull.Num != ctx.Pull.Num {
+			continue
+		}
+		if i > 0 {
+			return func() {}, i
+		}
+		if queued.running {
+			return nil, 0
+		}
+		queued.running = true
+		return d.releaseFunc(key, queued), 0
+	}
+	queued := &queuedApply{ctx: ctx, running: len(queue) == 0}
+	d.queues[key] = append(queue, queued)
+	if queued.running {
+		return d.releaseFunc(key, queued), 0
+	}
+	return func() {}, len(queue)
+}
+
+// releaseFunc returns a function that releases queued.
+func (d *DefaultApplyQueue) releaseFunc(key string, queued *queuedApply) func() {
+	return func() {
+		d.mutex.Lock()
+		defer d.mutex.Unlock()
+		d.release(key, queued)
+	}
+}
+
+// release removes queued from the front of the queue for key and starts the
+// next queued apply, if there is one. d.mutex must be held.
+func (d *DefaultApplyQueue) release(key string, queued *queuedApply) {
+	queue := d.queues[key]
+	if len(queue) == 0 || queue[0] != queued {
+		return
+	}
+	queue = queue[1:]
+	if len(queue) == 0 {
+		delete(d.queues, key)
+		return
+	}
+	d.queues[key] = queue
+	go d.runNext(key, queue[0])
+}
+
+// runNext re-runs the queued apply. If the apply never made it back to the
+// queue, ex. because the pull request was closed, we release it ourselves so
+// the rest of the queue isn't blocked.
+func (d *DefaultApplyQueue) runNext(key string, queued *queuedApply) {
+	ctx := queued.ctx
+	cmd := &CommentCommand{
+		Name:        models.ApplyCommand,
+		ProjectName: ctx.ProjectName,
+		AutoApply:   ctx.AutoApply,
+		// The apply was already confirmed, if needed, before it was queued.
+		Confirmed: true,
+	}
+	if ctx.ProjectName == "" {
+		cmd.RepoRelDir = ctx.RepoRelDir
+		cmd.Workspace = ctx.Workspace
+	}
+	headRepo := ctx.HeadRepo
+	pull := ctx.Pull
+	d.CommandRunner.RunCommentCommand(context.Background(), ctx.Pull.BaseRepo, &headRepo, &pull, ctx.User, ctx.Pull.Num, cmd)
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	// If the apply is running it was started by another comment, which will
+	// release it when it's complete.
+	if !queued.running {
+		d.release(key, queued)
+	}
+}
+
+func (d *DefaultApplyQueue) key(ctx models.ProjectCommandContext) string {
+	return fmt.Sprintf("%s/%s/%s", ctx.Pull.BaseRepo.FullName, ctx.RepoRelDir, ctx.Workspace)
+}
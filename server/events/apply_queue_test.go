@@ -0,0 +1,118 @@
+package events_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestApplyQueue_Enqueue(t *testing.T) {
+	queue := events.NewDefaultApplyQueue()
+
+	t.Log("the first apply can run")
+	release1, position := queue.Enqueue(applyQueueCtx(1, "."))
+	Equals(t, 0, position)
+
+	t.Log("other pulls are queued in order")
+	_, position = queue.Enqueue(applyQueueCtx(2, "."))
+	Equals(t, 1, position)
+	_, position = queue.Enqueue(applyQueueCtx(3, "."))
+	Equals(t, 2, position)
+
+	t.Log("re-applying doesn't change the position")
+	_, position = queue.Enqueue(applyQueueCtx(2, "."))
+	Equals(t, 1, position)
+	release, position := queue.Enqueue(applyQueueCtx(1, "."))
+	Equals(t, 0, position)
+	Assert(t, release == nil, "exp no release for an apply that's already running")
+
+	t.Log("other projects have their own queue")
+	_, position = queue.Enqueue(applyQueueCtx(2, "other"))
+	Equals(t, 0, position)
+
+	t.Log("releasing runs the next apply")
+	runner := &fakeCommandRunner{ran: make(chan int, 2)}
+	queue.CommandRunner = runner
+	release1()
+	Equals(t, 2, waitForRun(t, runner))
+	Equals(t, 3, waitForRun(t, runner))
+	Equals(t, []*events.CommentCommand{
+		{Name: models.ApplyCommand, RepoRelDir: ".", Workspace: "default", Confirmed: true},
+		{Name: models.ApplyCommand, RepoRelDir: ".", Workspace: "default", Confirmed: true},
+	}, runner.cmds)
+
+	t.Log("once the queue is empty the next apply can run")
+	_, position = queue.Enqueue(applyQueueCtx(4, "."))
+	Equals(t, 0, position)
+}
+
+// Test that applying again while the pull request at the front of the queue
+// is still applying doesn't release its place and start the next apply.
+func TestApplyQueue_EnqueueWhileRunning(t *testing.T) {
+	queue := events.NewDefaultApplyQueue()
+	runner := &fakeCommandRunner{ran: make(chan int, 1)}
+	queue.CommandRunner = runner
+
+	release1, position := queue.Enqueue(applyQueueCtx(1, "."))
+	Equals(t, 0, position)
+	_, position = queue.Enqueue(applyQueueCtx(2, "."))
+	Equals(t, 1, position)
+
+	t.Log("the repeated apply can't run or release the running one")
+	release, position := queue.Enqueue(applyQueueCtx(1, "."))
+	Equals(t, 0, position)
+	Assert(t, release == nil, "exp no release for an apply that's already running")
+	_, position = queue.Enqueue(applyQueueCtx(2, "."))
+	Equals(t, 1, position)
+	select {
+	case pullNum := <-runner.ran:
+		t.Fatalf("exp no apply to be started but pull %d was", pullNum)
+	default:
+	}
+
+	t.Log("releasing the running apply starts the next one")
+	release1()
+	Equals(t, 2, waitForRun(t, runner))
+}
+
+func applyQueueCtx(pullNum int, repoRelDir string) models.ProjectCommandContext {
+	return models.ProjectCommandContext{
+		Pull: models.PullRequest{
+			Num:      pullNum,
+			BaseRepo: models.Repo{FullName: "owner/repo"},
+		},
+		RepoRelDir: repoRelDir,
+		Workspace:  "default",
+	}
+}
+
+func waitForRun(t *testing.T, runner *fakeCommandRunner) int {
+	select {
+	case pullNum := <-runner.ran:
+		return pullNum
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for queued apply to run")
+	}
+	return 0
+}
+
+// fakeCommandRunner records the comment commands it's asked to run. Since
+// it never calls back into the queue, the queue releases each apply itself.
+// Autoplans are only recorded in ran.
+type fakeCommandRunner struct {
+	ran  chan int
+	cmds []*events.CommentCommand
+}
+
+func (f *fakeCommandRunner) RunCommentCommand(_ context.Context, _ models.Repo, _ *models.Repo, _ *models.PullRequest, _ models.User, pullNum int, cmd *events.CommentCommand) {
+	f.cmds = append(f.cmds, cmd)
+	f.ran <- pullNum
+}
+
+func (f *fakeCommandRunner) RunAutoplanCommand(_ context.Context, _ models.Repo, _ models.Repo, pull models.PullRequest, _ models.User) {
+	f.ran <- pull.Num
+}
@@ -2,8 +2,11 @@ package events
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 )
 
 func NewApprovePoliciesCommandRunner(
@@ -12,6 +15,7 @@ func NewApprovePoliciesCommandRunner(
 	prjCommandRunner ProjectApprovePoliciesCommandRunner,
 	pullUpdater *PullUpdater,
 	dbUpdater *DBUpdater,
+	vcsClient vcs.Client,
 	SilenceNoProjects bool,
 	silenceVCSStatusNoProjects bool,
 ) *ApprovePoliciesCommandRunner {
@@ -21,6 +25,7 @@ func NewApprovePoliciesCommandRunner(
 		prjCmdRunner:               prjCommandRunner,
 		pullUpdater:                pullUpdater,
 		dbUpdater:                  dbUpdater,
+		vcsClient:                  vcsClient,
 		SilenceNoProjects:          SilenceNoProjects,
 		silenceVCSStatusNoProjects: silenceVCSStatusNoProjects,
 	}
@@ -32,6 +37,9 @@ type ApprovePoliciesCommandRunner struct {
 	dbUpdater           *DBUpdater
 	prjCmdBuilder       ProjectApprovePoliciesCommandBuilder
 	prjCmdRunner        ProjectApprovePoliciesCommandRunner
+	// vcsClient is used to check if the user is a member of the policy owner
+	// teams.
+	vcsClient vcs.Client
 	// SilenceNoProjects is whether Atlantis should respond to PRs if no projects
 	// are found
 	SilenceNoProjects          bool
@@ -90,10 +98,11 @@ func (a *ApprovePoliciesCommandRunner) buildApprovePolicyCommandResults(ctx *Com
 	// Check if vcs user is in the owner list of the PolicySets. All projects
 	// share the same Owners list at this time so no reason to iterate over each
 	// project.
-	if len(prjCmds) > 0 && !prjCmds[0].PolicySets.IsOwner(ctx.User.Username) {
+	if len(prjCmds) > 0 && !a.isPolicyOwner(ctx, prjCmds[0].PolicySets) {
 		result.Error = fmt.Errorf("contact policy owners to approve failing policies")
 		return
 	}
+	ctx.Log.Info("failing policies approved by policy owner %s", ctx.User.Username)
 
 	var prjResults []models.ProjectResult
 
@@ -105,6 +114,27 @@ func (a *ApprovePoliciesCommandRunner) buildApprovePolicyCommandResults(ctx *Com
 	return
 }
 
+// isPolicyOwner returns true if the user that ran the command is one of the
+// policy owner users or a member of one of the policy owner teams.
+func (a *ApprovePoliciesCommandRunner) isPolicyOwner(ctx *CommandContext, policySets valid.PolicySets) bool {
+	if policySets.IsOwner(ctx.User.Username) {
+		return true
+	}
+	teams := policySets.Owners.Teams
+	if len(teams) == 0 {
+		return false
+	}
+	isMember, err := a.vcsClient.UserIsTeamMember(ctx.Pull.BaseRepo, ctx.User, teams)
+	if err != nil {
+		ctx.Log.Err("unable to check team membership for %s: %s", ctx.User.Username, err)
+		return false
+	}
+	if !isMember {
+		ctx.Log.Info("user %s is not a policy owner or a member of the policy owner teams: %s", ctx.User.Username, strings.Join(teams, ", "))
+	}
+	return isMember
+}
+
 func (a *ApprovePoliciesCommandRunner) updateCommitStatus(ctx *CommandContext, pullStatus models.PullStatus) {
 	var numSuccess int
 	var numErrored int
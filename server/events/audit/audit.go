@@ -0,0 +1,43 @@
+// Package audit records the comment commands that Atlantis runs so that they
+// can be reviewed later, ex. as evidence for a compliance audit.
+package audit
+
+import (
+	"github.com/runatlantis/atlantis/server/core/locking"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_exporter.go Exporter
+
+// Exporter writes audit events to a store or forwards them to an external
+// system. Implement this interface to send events somewhere new, ex. syslog
+// or S3.
+type Exporter interface {
+	Export(event models.AuditEvent) error
+}
+
+// MultiExporter exports each event to all of its Exporters.
+type MultiExporter struct {
+	Exporters []Exporter
+}
+
+// Record exports event to every exporter. Errors are logged rather than
+// returned since failing to audit a command shouldn't fail the command.
+func (m *MultiExporter) Record(log logging.SimpleLogging, event models.AuditEvent) {
+	for _, e := range m.Exporters {
+		if err := e.Export(event); err != nil {
+			log.Err("unable to export audit event: %s", err)
+		}
+	}
+}
+
+// DBExporter appends events to the audit log in our database.
+type DBExporter struct {
+	DB locking.Backend
+}
+
+// Export appends event to the audit log.
+func (d *DBExporter) Export(event models.AuditEvent) error {
+	return d.DB.AddAuditEvent(event)
+}
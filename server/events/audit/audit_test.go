@@ -0,0 +1,69 @@
+package audit_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events/audit"
+	"github.com/runatlantis/atlantis/server/events/audit/mocks"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+var event = models.AuditEvent{
+	Time:       time.Date(2021, 11, 1, 12, 0, 0, 0, time.UTC),
+	DurationMS: 1500,
+	Username:   "lkysow",
+	Repo:       "owner/repo",
+	PullNum:    1,
+	Command:    "apply",
+	RepoRelDir: "dir",
+	Result:     models.AuditResultSuccess,
+}
+
+func TestMultiExporter_Record(t *testing.T) {
+	t.Log("an error from one exporter shouldn't stop the others")
+	RegisterMockTestingT(t)
+	failing := mocks.NewMockExporter()
+	When(failing.Export(event)).ThenReturn(errors.New("err"))
+	succeeding := mocks.NewMockExporter()
+	m := audit.MultiExporter{Exporters: []audit.Exporter{failing, succeeding}}
+
+	m.Record(logging.NewNoopLogger(t), event)
+
+	failing.VerifyWasCalledOnce().Export(event)
+	succeeding.VerifyWasCalledOnce().Export(event)
+}
+
+func TestWebhookExporter_Export(t *testing.T) {
+	var body []byte
+	var contentType string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer s.Close()
+
+	Ok(t, audit.NewWebhookExporter(s.URL).Export(event))
+	Equals(t, "application/json", contentType)
+	var got models.AuditEvent
+	Ok(t, json.Unmarshal(body, &got))
+	Equals(t, event, got)
+}
+
+func TestWebhookExporter_ExportErrStatus(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer s.Close()
+
+	err := audit.NewWebhookExporter(s.URL).Export(event)
+	ErrEquals(t, "sending audit event: webhook responded with status 500", err)
+}
@@ -0,0 +1,43 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// WebhookExporter POSTs each event as JSON to URL.
+type WebhookExporter struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewWebhookExporter returns a WebhookExporter that sends events to url.
+func NewWebhookExporter(url string) *WebhookExporter {
+	return &WebhookExporter{
+		URL:    url,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Export sends event to the webhook. Any non-2xx response is an error.
+func (w *WebhookExporter) Export(event models.AuditEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "serializing")
+	}
+	resp, err := w.Client.Post(w.URL, "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		return errors.Wrap(err, "sending audit event")
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("sending audit event: webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
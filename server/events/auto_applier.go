@@ -0,0 +1,105 @@
+package events
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/core/locking"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_auto_applier.go AutoApplier
+
+// AutoApplier applies the pull requests of repos that set auto_apply once
+// they're ready, turning them into a deployment queue.
+type AutoApplier interface {
+	// MaybeApply applies pull, as user, if its repo has auto_apply set and
+	// it's approved, mergeable and every project has been planned
+	// successfully at its head commit. It's called when pull is approved and
+	// after it's planned.
+	MaybeApply(baseRepo models.Repo, headRepo models.Repo, pull models.PullRequest, user models.User) error
+}
+
+// DefaultAutoApplier implements AutoApplier. The apply is run through
+// CommandRunner like atlantis apply so it's ordered with other applies of the
+// same projects by the apply queue and the pull request is merged after.
+type DefaultAutoApplier struct {
+	DB            locking.Backend
+	GlobalCfg     *valid.GlobalCfgStore
+	VCSClient     vcs.Client
+	CommandRunner CommandRunner
+	Logger        logging.SimpleLogging
+}
+
+// MaybeApply implements AutoApplier.MaybeApply.
+func (a *DefaultAutoApplier) MaybeApply(baseRepo models.Repo, headRepo models.Repo, pull models.PullRequest, user models.User) error {
+	if !a.GlobalCfg.Get().AutoApply(baseRepo.ID(), pull.BaseBranch) {
+		return nil
+	}
+	if pull.State != models.OpenPullState {
+		a.Logger.Debug("not auto-applying %s#%d because it isn't open", baseRepo.FullName, pull.Num)
+		return nil
+	}
+
+	status, err := a.DB.GetPullStatus(pull)
+	if err != nil {
+		return errors.Wrap(err, "getting pull status")
+	}
+	if reason := a.notPlannedReason(status, pull); reason != "" {
+		a.Logger.Debug("not auto-applying %s#%d because %s", baseRepo.FullName, pull.Num, reason)
+		return nil
+	}
+
+	approved, err := a.VCSClient.PullIsApproved(baseRepo, pull)
+	if err != nil {
+		return errors.Wrap(err, "checking if pull request is approved")
+	}
+	if !approved {
+		a.Logger.Debug("not auto-applying %s#%d because it isn't approved", baseRepo.FullName, pull.Num)
+		return nil
+	}
+	mergeable, err := a.VCSClient.PullIsMergeable(baseRepo, pull)
+	if err != nil {
+		return errors.Wrap(err, "checking if pull request is mergeable")
+	}
+	if !mergeable {
+		a.Logger.Debug("not auto-applying %s#%d because it isn't mergeable", baseRepo.FullName, pull.Num)
+		return nil
+	}
+
+	a.Logger.Info("auto-applying %s#%d for %s", baseRepo.FullName, pull.Num, user.Username)
+	a.CommandRunner.RunCommentCommand(context.Background(), baseRepo, &headRepo, &pull, user, pull.Num, &CommentCommand{Name: models.ApplyCommand, AutoApply: true})
+	return nil
+}
+
+// notPlannedReason returns why status shows pull isn't ready to be applied, or
+// an empty string if every project was planned successfully at pull's head
+// commit and at least one hasn't been applied yet.
+func (a *DefaultAutoApplier) notPlannedReason(status *models.PullStatus, pull models.PullRequest) string {
+	if status == nil || len(status.Projects) == 0 {
+		return "it has no plans"
+	}
+	// The status is updated by every command so if it's for an earlier commit
+	// then the pull request wasn't planned after its latest push.
+	if status.Pull.HeadCommit != pull.HeadCommit {
+		return "it hasn't been planned at its latest commit"
+	}
+	unapplied := 0
+	for _, project := range status.Projects {
+		switch project.Status {
+		case models.PlannedPlanStatus, models.PassedPolicyCheckStatus:
+			unapplied++
+		case models.AppliedPlanStatus:
+		default:
+			return fmt.Sprintf("project at dir %q workspace %q has status %q", project.RepoRelDir, project.Workspace, project.Status.String())
+		}
+	}
+	if unapplied == 0 {
+		return "it has already been applied"
+	}
+	return ""
+}
@@ -0,0 +1,146 @@
+package events_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/core/db"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/mocks"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/models/fixtures"
+	vcsmocks "github.com/runatlantis/atlantis/server/events/vcs/mocks"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// setupAutoApplier returns an applier for a repo with auto_apply set to
+// autoApply whose pull request is approved and mergeable. The pull request's
+// projects are saved with results.
+func setupAutoApplier(t *testing.T, autoApply bool, results []models.ProjectResult) (*events.DefaultAutoApplier, models.PullRequest, *mocks.MockCommandRunner, *vcsmocks.MockClient) {
+	RegisterMockTestingT(t)
+	tmp, cleanup := TempDir(t)
+	t.Cleanup(cleanup)
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+
+	pull := fixtures.Pull
+	pull.BaseRepo = fixtures.GithubRepo
+	pull.BaseBranch = "main"
+	if len(results) > 0 {
+		_, err = boltDB.UpdatePullWithResults(pull, results)
+		Ok(t, err)
+	}
+
+	globalCfg := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})
+	globalCfg.Repos = append(globalCfg.Repos, valid.Repo{
+		IDRegex:   regexp.MustCompile(".*"),
+		AutoApply: &autoApply,
+	})
+	runner := mocks.NewMockCommandRunner()
+	vcsClient := vcsmocks.NewMockClient()
+	When(vcsClient.PullIsApproved(fixtures.GithubRepo, pull)).ThenReturn(true, nil)
+	When(vcsClient.PullIsMergeable(fixtures.GithubRepo, pull)).ThenReturn(true, nil)
+	return &events.DefaultAutoApplier{
+		DB:            boltDB,
+		GlobalCfg:     valid.NewGlobalCfgStore(globalCfg),
+		VCSClient:     vcsClient,
+		CommandRunner: runner,
+		Logger:        logging.NewNoopLogger(t),
+	}, pull, runner, vcsClient
+}
+
+var plannedAutoApplyResults = []models.ProjectResult{
+	{Command: models.PlanCommand, RepoRelDir: "planned", Workspace: "default", PlanSuccess: &models.PlanSuccess{}},
+	{Command: models.ApplyCommand, RepoRelDir: "applied", Workspace: "default", ApplySuccess: "success"},
+}
+
+func TestMaybeApply_Applies(t *testing.T) {
+	a, pull, runner, _ := setupAutoApplier(t, true, plannedAutoApplyResults)
+	user := models.User{Username: "approver"}
+	Ok(t, a.MaybeApply(fixtures.GithubRepo, fixtures.GithubRepo, pull, user))
+
+	headRepo := fixtures.GithubRepo
+	runner.VerifyWasCalledOnce().RunCommentCommand(context.Background(), fixtures.GithubRepo, &headRepo, &pull, user, pull.Num, &events.CommentCommand{Name: models.ApplyCommand, AutoApply: true})
+}
+
+func TestMaybeApply_DoesNotApply(t *testing.T) {
+	cases := []struct {
+		description string
+		autoApply   bool
+		results     []models.ProjectResult
+		modify      func(pull *models.PullRequest, vcsClient *vcsmocks.MockClient)
+	}{
+		{
+			description: "auto_apply isn't set",
+			results:     plannedAutoApplyResults,
+		},
+		{
+			description: "closed",
+			autoApply:   true,
+			results:     plannedAutoApplyResults,
+			modify: func(pull *models.PullRequest, _ *vcsmocks.MockClient) {
+				pull.State = models.ClosedPullState
+			},
+		},
+		{
+			description: "not planned",
+			autoApply:   true,
+		},
+		{
+			description: "planned at an earlier commit",
+			autoApply:   true,
+			results:     plannedAutoApplyResults,
+			modify: func(pull *models.PullRequest, vcsClient *vcsmocks.MockClient) {
+				pull.HeadCommit = "new-commit"
+				When(vcsClient.PullIsApproved(fixtures.GithubRepo, *pull)).ThenReturn(true, nil)
+				When(vcsClient.PullIsMergeable(fixtures.GithubRepo, *pull)).ThenReturn(true, nil)
+			},
+		},
+		{
+			description: "plan errored",
+			autoApply:   true,
+			results: []models.ProjectResult{
+				{Command: models.PlanCommand, RepoRelDir: "planned", Workspace: "default", PlanSuccess: &models.PlanSuccess{}},
+				{Command: models.PlanCommand, RepoRelDir: "errored", Workspace: "default", Failure: "failed"},
+			},
+		},
+		{
+			description: "already applied",
+			autoApply:   true,
+			results: []models.ProjectResult{
+				{Command: models.ApplyCommand, RepoRelDir: "applied", Workspace: "default", ApplySuccess: "success"},
+			},
+		},
+		{
+			description: "not approved",
+			autoApply:   true,
+			results:     plannedAutoApplyResults,
+			modify: func(pull *models.PullRequest, vcsClient *vcsmocks.MockClient) {
+				When(vcsClient.PullIsApproved(fixtures.GithubRepo, *pull)).ThenReturn(false, nil)
+			},
+		},
+		{
+			description: "not mergeable",
+			autoApply:   true,
+			results:     plannedAutoApplyResults,
+			modify: func(pull *models.PullRequest, vcsClient *vcsmocks.MockClient) {
+				When(vcsClient.PullIsMergeable(fixtures.GithubRepo, *pull)).ThenReturn(false, nil)
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			a, pull, runner, vcsClient := setupAutoApplier(t, c.autoApply, c.results)
+			if c.modify != nil {
+				c.modify(&pull, vcsClient)
+			}
+			Ok(t, a.MaybeApply(fixtures.GithubRepo, fixtures.GithubRepo, pull, models.User{Username: "approver"}))
+			runner.VerifyWasCalled(Never()).RunCommentCommand(matchers.AnyContextContext(), matchers.AnyModelsRepo(), matchers.AnyPtrToModelsRepo(), matchers.AnyPtrToModelsPullRequest(), matchers.AnyModelsUser(), AnyInt(), matchers.AnyPtrToEventsCommentCommand())
+		})
+	}
+}
@@ -1,54 +1,130 @@
 package events
 
 import (
-	"fmt"
+	"bytes"
+	"text/template"
 
+	"github.com/pkg/errors"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/i18n"
 )
 
 type AutoMerger struct {
 	VCSClient       vcs.Client
 	GlobalAutomerge bool
+	// Localizer, if set, picks the locale of the comments. Otherwise they're
+	// in English.
+	Localizer *i18n.Localizer
 }
 
-func (c *AutoMerger) automerge(ctx *CommandContext, pullStatus models.PullStatus, deleteSourceBranchOnMerge bool) {
+// AutomergeCommitMessageData is the data available to the
+// automerge_commit_message template.
+type AutomergeCommitMessageData struct {
+	// RepoFullName is the owner and repo name, ex. runatlantis/atlantis.
+	RepoFullName string
+	PullNum      int
+	PullURL      string
+	// PullAuthor is the username of the pull request's author.
+	PullAuthor string
+	HeadBranch string
+	BaseBranch string
+	// User is the username of the user that ran the apply.
+	User string
+}
+
+func (c *AutoMerger) automerge(ctx *CommandContext, pullStatus models.PullStatus, projectCmds []models.ProjectCommandContext) {
 	// We only automerge if all projects have been successfully applied.
-	for _, p := range pullStatus.Projects {
-		if p.Status != models.AppliedPlanStatus {
-			ctx.Log.Info("not automerging because project at dir %q, workspace %q has status %q", p.RepoRelDir, p.Workspace, p.Status.String())
-			return
-		}
+	completeness := pullStatus.ApplyCompleteness()
+	if !completeness.Complete {
+		ctx.Log.Info("not automerging because %d/%d projects have been applied: %s", completeness.Applied, completeness.Total, completeness.Reason)
+		return
 	}
+	ctx.Log.Info("automerging because all %d projects have been applied", completeness.Total)
 
 	// Comment that we're automerging the pull request.
-	if err := c.VCSClient.CreateComment(ctx.Pull.BaseRepo, ctx.Pull.Num, automergeComment, models.ApplyCommand.String()); err != nil {
+	locale := c.Localizer.For(ctx.Pull.BaseRepo, ctx.Pull.BaseBranch)
+	if err := c.VCSClient.CreateComment(ctx.Pull.BaseRepo, ctx.Pull.Num, i18n.Sprintf(locale, i18n.Automerging), models.ApplyCommand.String()); err != nil {
 		ctx.Log.Err("failed to comment about automerge: %s", err)
 		// Commenting isn't required so continue.
 	}
 
 	// Make the API call to perform the merge.
-	ctx.Log.Info("automerging pull request")
-	var pullOptions models.PullRequestOptions
-	pullOptions.DeleteSourceBranchOnMerge = deleteSourceBranchOnMerge
-	err := c.VCSClient.MergePull(ctx.Pull, pullOptions)
+	pullOptions, err := c.pullRequestOptions(ctx, projectCmds)
+	if err == nil {
+		err = c.VCSClient.MergePull(ctx.Pull, pullOptions)
+	}
 
 	if err != nil {
 		ctx.Log.Err("automerging failed: %s", err)
 
-		failureComment := fmt.Sprintf("Automerging failed:\n```\n%s\n```", err)
+		failureComment := i18n.Sprintf(locale, i18n.AutomergeFailed, err)
 		if commentErr := c.VCSClient.CreateComment(ctx.Pull.BaseRepo, ctx.Pull.Num, failureComment, models.ApplyCommand.String()); commentErr != nil {
 			ctx.Log.Err("failed to comment about automerge failing: %s", err)
 		}
 	}
 }
 
+// pullRequestOptions returns the options to merge the pull request with.
+func (c *AutoMerger) pullRequestOptions(ctx *CommandContext, projectCmds []models.ProjectCommandContext) (models.PullRequestOptions, error) {
+	opts := models.PullRequestOptions{
+		DeleteSourceBranchOnMerge: c.deleteSourceBranchOnMergeEnabled(projectCmds),
+	}
+	if len(projectCmds) == 0 {
+		return opts, nil
+	}
+	// The merge method and commit message are set per repo so every project
+	// has the same settings.
+	opts.MergeMethod = projectCmds[0].AutomergeMethod
+	if tmpl := projectCmds[0].AutomergeCommitMessage; tmpl != "" {
+		msg, err := renderAutomergeCommitMessage(tmpl, AutomergeCommitMessageData{
+			RepoFullName: ctx.Pull.BaseRepo.FullName,
+			PullNum:      ctx.Pull.Num,
+			PullURL:      ctx.Pull.URL,
+			PullAuthor:   ctx.Pull.Author,
+			HeadBranch:   ctx.Pull.HeadBranch,
+			BaseBranch:   ctx.Pull.BaseBranch,
+			User:         ctx.User.Username,
+		})
+		if err != nil {
+			return opts, err
+		}
+		opts.CommitMessage = msg
+	}
+	return opts, nil
+}
+
+// renderAutomergeCommitMessage executes the automerge_commit_message
+// template tmpl with data.
+func renderAutomergeCommitMessage(tmpl string, data AutomergeCommitMessageData) (string, error) {
+	t, err := template.New("automerge_commit_message").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", errors.Wrap(err, "parsing automerge_commit_message")
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", errors.Wrap(err, "rendering automerge_commit_message")
+	}
+	return buf.String(), nil
+}
+
 // automergeEnabled returns true if automerging is enabled in this context.
 func (c *AutoMerger) automergeEnabled(projectCmds []models.ProjectCommandContext) bool {
 	// If the global automerge is set, we always automerge.
-	return c.GlobalAutomerge ||
-		// Otherwise we check if this repo is configured for automerging.
-		(len(projectCmds) > 0 && projectCmds[0].AutomergeEnabled)
+	if c.GlobalAutomerge {
+		return true
+	}
+	// Otherwise we check if every project is configured for automerging.
+	// Projects can override their repo's setting.
+	if len(projectCmds) == 0 {
+		return false
+	}
+	for _, p := range projectCmds {
+		if !p.AutomergeEnabled {
+			return false
+		}
+	}
+	return true
 }
 
 // deleteSourceBranchOnMergeEnabled returns true if we should delete the source branch on merge in this context.
@@ -0,0 +1,107 @@
+package events
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/core/locking"
+	"github.com/runatlantis/atlantis/server/core/planstorage"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/i18n"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_base_branch_updater.go BaseBranchUpdater
+
+// BaseBranchUpdater handles updates to the branches that pull requests will be
+// merged into.
+type BaseBranchUpdater interface {
+	// UpdateBaseBranch is called when branch of repo is pushed to. Depending
+	// on the repo's on_base_branch_update config, the plans of open pull
+	// requests into branch are invalidated or re-planned since they no longer
+	// reflect what will be applied.
+	UpdateBaseBranch(repo models.Repo, branch string, user models.User) error
+}
+
+// DefaultBaseBranchUpdater implements BaseBranchUpdater.
+type DefaultBaseBranchUpdater struct {
+	DB               locking.Backend
+	GlobalCfg        *valid.GlobalCfgStore
+	CommandRunner    CommandRunner
+	VCSClient        vcs.Client
+	WorkingDir       WorkingDir
+	WorkingDirLocker WorkingDirLocker
+	// PlanStorage, if set, is where stored planfiles are deleted from when
+	// plans are invalidated.
+	PlanStorage planstorage.PlanStorage
+	Localizer   *i18n.Localizer
+	Logger      logging.SimpleLogging
+}
+
+// UpdateBaseBranch implements BaseBranchUpdater.UpdateBaseBranch.
+func (u *DefaultBaseBranchUpdater) UpdateBaseBranch(repo models.Repo, branch string, user models.User) error {
+	action := u.GlobalCfg.Get().OnBaseBranchUpdate(repo.ID(), branch)
+	if action == "" {
+		u.Logger.Debug("not checking pull requests into %s/%s because %s isn't set", repo.FullName, branch, valid.OnBaseBranchUpdateKey)
+		return nil
+	}
+
+	statuses, err := u.DB.ListPullStatuses()
+	if err != nil {
+		return errors.Wrap(err, "listing pull requests")
+	}
+	for _, status := range statuses {
+		pull := status.Pull
+		if pull.BaseRepo.FullName != repo.FullName || pull.BaseBranch != branch || !status.HasPlans() {
+			continue
+		}
+		switch action {
+		case valid.InvalidateOnBaseBranchUpdate:
+			u.Logger.Info("invalidating plans for %s#%d because %s was updated by %s", repo.FullName, pull.Num, branch, user.Username)
+			if err := u.invalidatePlans(status); err != nil {
+				u.Logger.Err("unable to invalidate plans for %s#%d: %s", repo.FullName, pull.Num, err)
+			}
+		case valid.ReplanOnBaseBranchUpdate:
+			u.Logger.Info("re-planning %s#%d because %s was updated by %s", repo.FullName, pull.Num, branch, user.Username)
+			// The plan is run as the pull request's author since they're the
+			// one who will apply it.
+			u.CommandRunner.RunCommentCommand(context.Background(), pull.BaseRepo, nil, &pull, models.User{Username: pull.Author}, pull.Num, &CommentCommand{Name: models.PlanCommand})
+		}
+	}
+	return nil
+}
+
+// invalidatePlans deletes the pull request's planfiles so they can't be
+// applied and marks its planned projects as stale. The locks are kept since
+// the pull request still intends to modify those projects.
+func (u *DefaultBaseBranchUpdater) invalidatePlans(status models.PullStatus) error {
+	pull := status.Pull
+	unlockFn, err := u.WorkingDirLocker.TryLockPull(pull.BaseRepo.FullName, pull.Num)
+	if err != nil {
+		return err
+	}
+	err = u.WorkingDir.Delete(pull.BaseRepo, pull)
+	unlockFn()
+	if err != nil {
+		return errors.Wrap(err, "deleting working dir")
+	}
+	if u.PlanStorage != nil {
+		if err := u.PlanStorage.DeletePlans(pull.BaseRepo.FullName, pull.Num, ""); err != nil {
+			return errors.Wrap(err, "deleting stored plans")
+		}
+	}
+
+	for _, project := range status.Projects {
+		switch project.Status {
+		case models.PlannedPlanStatus, models.ErroredPolicyCheckStatus, models.PassedPolicyCheckStatus:
+			if err := u.DB.UpdateProjectStatus(pull, project.Workspace, project.RepoRelDir, models.StalePlanStatus); err != nil {
+				u.Logger.Err("unable to update project status: %s", err)
+			}
+		}
+	}
+
+	comment := u.Localizer.Sprintf(pull.BaseRepo, pull.BaseBranch, i18n.BaseBranchUpdated, pull.BaseBranch)
+	return u.VCSClient.CreateComment(pull.BaseRepo, pull.Num, comment, models.PlanCommand.String())
+}
@@ -0,0 +1,125 @@
+package events_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/core/db"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/mocks"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/models/fixtures"
+	vcsmocks "github.com/runatlantis/atlantis/server/events/vcs/mocks"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// setupBaseBranchUpdater returns an updater whose DB has an open pull request
+// into main with a planned and an applied project, and one into develop.
+func setupBaseBranchUpdater(t *testing.T, action string) (*events.DefaultBaseBranchUpdater, models.PullRequest, *mocks.MockCommandRunner, *mocks.MockWorkingDir, *vcsmocks.MockClient) {
+	RegisterMockTestingT(t)
+	tmp, cleanup := TempDir(t)
+	t.Cleanup(cleanup)
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+
+	pull := fixtures.Pull
+	pull.BaseRepo = fixtures.GithubRepo
+	pull.BaseBranch = "main"
+	_, err = boltDB.UpdatePullWithResults(pull, []models.ProjectResult{
+		{Command: models.PlanCommand, RepoRelDir: "planned", Workspace: "default", PlanSuccess: &models.PlanSuccess{}},
+		{Command: models.ApplyCommand, RepoRelDir: "applied", Workspace: "default", ApplySuccess: "success"},
+	})
+	Ok(t, err)
+	otherPull := pull
+	otherPull.Num = 2
+	otherPull.BaseBranch = "develop"
+	_, err = boltDB.UpdatePullWithResults(otherPull, []models.ProjectResult{
+		{Command: models.PlanCommand, RepoRelDir: "planned", Workspace: "default", PlanSuccess: &models.PlanSuccess{}},
+	})
+	Ok(t, err)
+
+	globalCfg := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})
+	globalCfg.Repos = append(globalCfg.Repos, valid.Repo{
+		IDRegex:            regexp.MustCompile(".*"),
+		OnBaseBranchUpdate: action,
+	})
+	runner := mocks.NewMockCommandRunner()
+	workingDir := mocks.NewMockWorkingDir()
+	vcsClient := vcsmocks.NewMockClient()
+	return &events.DefaultBaseBranchUpdater{
+		DB:               boltDB,
+		GlobalCfg:        valid.NewGlobalCfgStore(globalCfg),
+		CommandRunner:    runner,
+		VCSClient:        vcsClient,
+		WorkingDir:       workingDir,
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+		Logger:           logging.NewNoopLogger(t),
+	}, pull, runner, workingDir, vcsClient
+}
+
+func TestUpdateBaseBranch_Invalidate(t *testing.T) {
+	u, pull, _, workingDir, vcsClient := setupBaseBranchUpdater(t, valid.InvalidateOnBaseBranchUpdate)
+	Ok(t, u.UpdateBaseBranch(fixtures.GithubRepo, "main", models.User{Username: "pusher"}))
+
+	workingDir.VerifyWasCalledOnce().Delete(fixtures.GithubRepo, pull)
+	workingDir.VerifyWasCalledOnce().Delete(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest())
+	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GithubRepo, pull.Num,
+		"**Warning**: `main` was updated so the plans for this pull request are stale and were **discarded**.\n\nTo `apply` you must run `plan` again.",
+		"plan")
+
+	Equals(t, models.StalePlanStatus, projectStatus(t, u, pull, "planned"))
+	Equals(t, models.AppliedPlanStatus, projectStatus(t, u, pull, "applied"))
+
+	t.Log("plans that are already stale aren't invalidated again")
+	Ok(t, u.UpdateBaseBranch(fixtures.GithubRepo, "main", models.User{Username: "pusher"}))
+	workingDir.VerifyWasCalledOnce().Delete(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest())
+}
+
+func TestUpdateBaseBranch_Replan(t *testing.T) {
+	u, pull, runner, workingDir, vcsClient := setupBaseBranchUpdater(t, valid.ReplanOnBaseBranchUpdate)
+	Ok(t, u.UpdateBaseBranch(fixtures.GithubRepo, "main", models.User{Username: "pusher"}))
+
+	runner.VerifyWasCalledOnce().RunCommentCommand(context.Background(), fixtures.GithubRepo, nil, &pull, models.User{Username: "lkysow"}, pull.Num, &events.CommentCommand{Name: models.PlanCommand})
+	workingDir.VerifyWasCalled(Never()).Delete(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest())
+	vcsClient.VerifyWasCalled(Never()).CreateComment(matchers.AnyModelsRepo(), AnyInt(), AnyString(), AnyString())
+}
+
+func TestUpdateBaseBranch_Ignored(t *testing.T) {
+	otherRepo := fixtures.GithubRepo
+	otherRepo.FullName = "runatlantis/other"
+	cases := []struct {
+		description string
+		action      string
+		repo        models.Repo
+		branch      string
+	}{
+		{"not configured", "", fixtures.GithubRepo, "main"},
+		{"other repo", valid.InvalidateOnBaseBranchUpdate, otherRepo, "main"},
+		{"branch without pull requests", valid.InvalidateOnBaseBranchUpdate, fixtures.GithubRepo, "feature"},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			u, pull, _, workingDir, _ := setupBaseBranchUpdater(t, c.action)
+			Ok(t, u.UpdateBaseBranch(c.repo, c.branch, models.User{Username: "pusher"}))
+			workingDir.VerifyWasCalled(Never()).Delete(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest())
+			Equals(t, models.PlannedPlanStatus, projectStatus(t, u, pull, "planned"))
+		})
+	}
+}
+
+func projectStatus(t *testing.T, u *events.DefaultBaseBranchUpdater, pull models.PullRequest, dir string) models.ProjectPlanStatus {
+	status, err := u.DB.GetPullStatus(pull)
+	Ok(t, err)
+	for _, p := range status.Projects {
+		if p.RepoRelDir == dir {
+			return p.Status
+		}
+	}
+	t.Fatalf("no project %q", dir)
+	return 0
+}
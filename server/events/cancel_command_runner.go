@@ -0,0 +1,38 @@
+package events
+
+import (
+	"fmt"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+)
+
+func NewCancelCommandRunner(
+	commandCanceller *CommandCanceller,
+	vcsClient vcs.Client,
+) *CancelCommandRunner {
+	return &CancelCommandRunner{
+		commandCanceller: commandCanceller,
+		vcsClient:        vcsClient,
+	}
+}
+
+// CancelCommandRunner cancels the plans and applies running for a pull
+// request.
+type CancelCommandRunner struct {
+	commandCanceller *CommandCanceller
+	vcsClient        vcs.Client
+}
+
+func (c *CancelCommandRunner) Run(ctx *CommandContext, cmd *CommentCommand) {
+	numCancelled := c.commandCanceller.Cancel(ctx.Pull.BaseRepo.FullName, ctx.Pull.Num)
+	ctx.Log.Info("cancelled %d running command(s)", numCancelled)
+
+	vcsMessage := "No plans or applies are running for this PR"
+	if numCancelled > 0 {
+		vcsMessage = fmt.Sprintf("Cancelled %d running command(s). Resources that were being applied may have only been partially changed so check the output of any interrupted applies.", numCancelled)
+	}
+	if err := commentOnPull(c.vcsClient, ctx, vcsMessage, models.CancelCommand.String()); err != nil {
+		ctx.Log.Err("unable to comment: %s", err)
+	}
+}
@@ -0,0 +1,95 @@
+package events
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+)
+
+// PlanCheckRunAction is the identifier of the button on plan check runs that
+// re-runs plan for the project.
+const PlanCheckRunAction = "plan"
+
+// ChecksUpdater creates a check run for each project with the result of
+// planning it. Check runs are created in addition to commit statuses.
+type ChecksUpdater struct {
+	Client vcs.ChecksClient
+	// StatusName is the name used to identify Atlantis when creating checks.
+	StatusName string
+}
+
+// UpdateProjects creates a check run for each project result in res.
+func (c *ChecksUpdater) UpdateProjects(ctx *CommandContext, res CommandResult) {
+	for _, result := range res.ProjectResults {
+		if err := c.Client.CreateCheckRun(ctx.Pull.BaseRepo, ctx.Pull, c.planCheckRun(result)); err != nil {
+			ctx.Log.Err("unable to create check run: %s", err)
+		}
+	}
+}
+
+func (c *ChecksUpdater) planCheckRun(result models.ProjectResult) vcs.CheckRun {
+	projectID := result.ProjectName
+	if projectID == "" {
+		projectID = fmt.Sprintf("%s/%s", result.RepoRelDir, result.Workspace)
+	}
+	checkRun := vcs.CheckRun{
+		Name:       fmt.Sprintf("%s/%s: %s", c.StatusName, models.PlanCommand.String(), projectID),
+		ExternalID: checkRunExternalID(result),
+		State:      models.FailedCommitStatus,
+		Title:      "Plan failed",
+		Actions: []vcs.CheckRunAction{
+			{
+				Label:       "Re-plan",
+				Description: "Run atlantis plan for this project.",
+				Identifier:  PlanCheckRunAction,
+			},
+		},
+	}
+
+	switch {
+	case result.Error != nil:
+		checkRun.Summary = "Plan errored."
+		checkRun.Text = fmt.Sprintf("```\n%s\n```", result.Error.Error())
+	case result.Failure != "":
+		checkRun.Summary = result.Failure
+	case result.PlanSuccess != nil:
+		checkRun.State = models.SuccessCommitStatus
+		checkRun.Title = "Plan succeeded"
+		checkRun.Summary = result.PlanSuccess.Summary()
+		checkRun.Text = fmt.Sprintf("```diff\n%s\n```\n\n* :arrow_forward: To **apply** this plan, comment:\n    * `%s`",
+			result.PlanSuccess.TerraformOutput, result.PlanSuccess.ApplyCmd)
+	}
+	return checkRun
+}
+
+// checkRunExternalID encodes the project that result is for so it can be
+// re-planned when a check run action is requested.
+func checkRunExternalID(result models.ProjectResult) string {
+	values := url.Values{}
+	if result.ProjectName != "" {
+		values.Set("project", result.ProjectName)
+	} else {
+		values.Set("dir", result.RepoRelDir)
+		values.Set("workspace", result.Workspace)
+	}
+	return values.Encode()
+}
+
+// NewCheckRunPlanCommand returns the command to re-plan the project that the
+// check run with externalID was created for.
+func NewCheckRunPlanCommand(externalID string) (*CommentCommand, error) {
+	values, err := url.ParseQuery(externalID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing check run external id %q", externalID)
+	}
+	if values.Get("project") == "" && values.Get("dir") == "" {
+		return nil, fmt.Errorf("check run external id %q is not for a project", externalID)
+	}
+	return NewCommentCommand(values.Get("dir"), nil, models.PlanCommand, CommentCommandOptions{
+		Workspace:   values.Get("workspace"),
+		ProjectName: values.Get("project"),
+	}), nil
+}
@@ -0,0 +1,111 @@
+package events_test
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/events/vcs/mocks"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestChecksUpdater_UpdateProjects(t *testing.T) {
+	replanAction := []vcs.CheckRunAction{
+		{
+			Label:       "Re-plan",
+			Description: "Run atlantis plan for this project.",
+			Identifier:  events.PlanCheckRunAction,
+		},
+	}
+	cases := []struct {
+		description string
+		result      models.ProjectResult
+		exp         vcs.CheckRun
+	}{
+		{
+			description: "successful plan",
+			result: models.ProjectResult{
+				RepoRelDir: "path",
+				Workspace:  "default",
+				PlanSuccess: &models.PlanSuccess{
+					TerraformOutput: "+ null_resource.test\nPlan: 1 to add, 0 to change, 0 to destroy.",
+					ApplyCmd:        "atlantis apply -d path",
+				},
+			},
+			exp: vcs.CheckRun{
+				Name:       "atlantis/plan: path/default",
+				ExternalID: "dir=path&workspace=default",
+				State:      models.SuccessCommitStatus,
+				Title:      "Plan succeeded",
+				Summary:    "Plan: 1 to add, 0 to change, 0 to destroy.",
+				Text:       "```diff\n+ null_resource.test\nPlan: 1 to add, 0 to change, 0 to destroy.\n```\n\n* :arrow_forward: To **apply** this plan, comment:\n    * `atlantis apply -d path`",
+				Actions:    replanAction,
+			},
+		},
+		{
+			description: "failed plan",
+			result: models.ProjectResult{
+				ProjectName: "myproject",
+				RepoRelDir:  "path",
+				Workspace:   "default",
+				Failure:     "Pull request must be mergeable before running plan.",
+			},
+			exp: vcs.CheckRun{
+				Name:       "atlantis/plan: myproject",
+				ExternalID: "project=myproject",
+				State:      models.FailedCommitStatus,
+				Title:      "Plan failed",
+				Summary:    "Pull request must be mergeable before running plan.",
+				Actions:    replanAction,
+			},
+		},
+		{
+			description: "errored plan",
+			result: models.ProjectResult{
+				RepoRelDir: "path",
+				Workspace:  "staging",
+				Error:      errors.New("exit status 1"),
+			},
+			exp: vcs.CheckRun{
+				Name:       "atlantis/plan: path/staging",
+				ExternalID: "dir=path&workspace=staging",
+				State:      models.FailedCommitStatus,
+				Title:      "Plan failed",
+				Summary:    "Plan errored.",
+				Text:       "```\nexit status 1\n```",
+				Actions:    replanAction,
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			client := mocks.NewMockChecksClient()
+			updater := events.ChecksUpdater{Client: client, StatusName: "atlantis"}
+			pull := models.PullRequest{Num: 1, BaseRepo: models.Repo{FullName: "owner/repo"}}
+			ctx := &events.CommandContext{Pull: pull, Log: logging.NewNoopLogger(t)}
+
+			updater.UpdateProjects(ctx, events.CommandResult{ProjectResults: []models.ProjectResult{c.result}})
+
+			client.VerifyWasCalledOnce().CreateCheckRun(pull.BaseRepo, pull, c.exp)
+		})
+	}
+}
+
+func TestNewCheckRunPlanCommand(t *testing.T) {
+	cmd, err := events.NewCheckRunPlanCommand("dir=path&workspace=staging")
+	Ok(t, err)
+	Equals(t, &events.CommentCommand{Name: models.PlanCommand, RepoRelDir: "path", Workspace: "staging"}, cmd)
+
+	cmd, err = events.NewCheckRunPlanCommand("project=myproject")
+	Ok(t, err)
+	Equals(t, &events.CommentCommand{Name: models.PlanCommand, ProjectName: "myproject"}, cmd)
+
+	_, err = events.NewCheckRunPlanCommand("")
+	ErrEquals(t, `check run external id "" is not for a project`, err)
+}
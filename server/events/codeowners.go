@@ -0,0 +1,226 @@
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_codeowners_checker.go CodeownersChecker
+
+// CodeownersChecker checks that the owners of the files modified by a pull
+// request, as listed in the repo's CODEOWNERS file, approved it.
+type CodeownersChecker interface {
+	// UnapprovedFiles returns the files modified by pull under repoRelDir
+	// that have owners but weren't approved by any of them.
+	UnapprovedFiles(log logging.SimpleLogging, pull models.PullRequest, repoRelDir string) ([]string, error)
+}
+
+// DefaultCodeownersChecker implements CodeownersChecker using the VCS host's
+// review API and the CODEOWNERS file on the pull request's base branch. The
+// base branch's file is used so a pull request can't change its own owners.
+type DefaultCodeownersChecker struct {
+	VCSClient vcs.Client
+}
+
+// codeownersRule is a line of a CODEOWNERS file.
+type codeownersRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// UnapprovedFiles implements CodeownersChecker.UnapprovedFiles.
+func (c *DefaultCodeownersChecker) UnapprovedFiles(log logging.SimpleLogging, pull models.PullRequest, repoRelDir string) ([]string, error) {
+	modifiedFiles, err := c.VCSClient.GetModifiedFiles(pull.BaseRepo, pull)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting modified files")
+	}
+	var projectFiles []string
+	for _, file := range modifiedFiles {
+		if fileInDir(file, repoRelDir) {
+			projectFiles = append(projectFiles, file)
+		}
+	}
+	if len(projectFiles) == 0 {
+		return nil, nil
+	}
+
+	found, contents, err := c.VCSClient.DownloadCodeowners(pull)
+	if err != nil {
+		return nil, errors.Wrap(err, "downloading CODEOWNERS")
+	}
+	if !found {
+		return nil, errors.Errorf("no CODEOWNERS file found on branch %q", pull.BaseBranch)
+	}
+	rules := parseCodeowners(contents)
+
+	approvers, err := c.VCSClient.GetPullApprovers(pull.BaseRepo, pull)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting approvers")
+	}
+
+	// Many files usually share the same owners so we only check each owner
+	// once.
+	approvedOwners := make(map[string]bool)
+	var unapproved []string
+	for _, file := range projectFiles {
+		owners := codeownersFor(rules, file)
+		if len(owners) == 0 {
+			log.Debug("%s has no code owners", file)
+			continue
+		}
+		approved := false
+		for _, owner := range owners {
+			ok, checked := approvedOwners[owner]
+			if !checked {
+				ok, err = c.ownerApproved(pull.BaseRepo, owner, approvers)
+				if err != nil {
+					return nil, err
+				}
+				approvedOwners[owner] = ok
+			}
+			if ok {
+				approved = true
+				break
+			}
+		}
+		if !approved {
+			unapproved = append(unapproved, file)
+		}
+	}
+	return unapproved, nil
+}
+
+// ownerApproved returns true if owner, ex. @user or @org/team, is one of
+// approvers or a team one of them is a member of. Owners given by email can't
+// be matched to VCS users so they never approve.
+func (c *DefaultCodeownersChecker) ownerApproved(repo models.Repo, owner string, approvers []string) (bool, error) {
+	if !strings.HasPrefix(owner, "@") {
+		return false, nil
+	}
+	name := strings.TrimPrefix(owner, "@")
+	for _, approver := range approvers {
+		if strings.EqualFold(approver, name) {
+			return true, nil
+		}
+	}
+
+	// GitHub teams are written as @org/team but are looked up by their slug
+	// in the repo's organization. GitLab groups are looked up by their full
+	// path and can't be told apart from users so we check both.
+	team := name
+	if repo.VCSHost.Type == models.Github {
+		if !strings.Contains(name, "/") {
+			return false, nil
+		}
+		team = name[strings.Index(name, "/")+1:]
+	}
+	for _, approver := range approvers {
+		isMember, err := c.VCSClient.UserIsTeamMember(repo, models.User{Username: approver}, []string{team})
+		if err != nil {
+			return false, errors.Wrapf(err, "checking if %s is a member of %s", approver, owner)
+		}
+		if isMember {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// fileInDir returns true if file, relative to the repo root, is under dir.
+func fileInDir(file string, dir string) bool {
+	rel, err := filepath.Rel(dir, file)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
+// parseCodeowners parses the rules of a CODEOWNERS file. Blank lines,
+// comments and GitLab section headers are skipped.
+func parseCodeowners(contents []byte) []codeownersRule {
+	var rules []codeownersRule
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") || strings.HasPrefix(line, "^[") {
+			continue
+		}
+		fields := strings.Fields(line)
+		var owners []string
+		for _, field := range fields[1:] {
+			if strings.HasPrefix(field, "#") {
+				break
+			}
+			owners = append(owners, field)
+		}
+		rules = append(rules, codeownersRule{
+			pattern: codeownersPatternToRegexp(fields[0]),
+			owners:  owners,
+		})
+	}
+	return rules
+}
+
+// codeownersFor returns the owners of file. Like in GitHub and GitLab, the
+// last matching rule wins.
+func codeownersFor(rules []codeownersRule, file string) []string {
+	var owners []string
+	for _, rule := range rules {
+		if rule.pattern.MatchString(file) {
+			owners = rule.owners
+		}
+	}
+	return owners
+}
+
+// codeownersPatternToRegexp converts a gitignore style CODEOWNERS pattern to a
+// regexp matching file paths relative to the repo root.
+func codeownersPatternToRegexp(pattern string) *regexp.Regexp {
+	// Patterns with a leading or middle slash are relative to the repo root,
+	// otherwise they match at any depth.
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	var re strings.Builder
+	if anchored {
+		re.WriteString("^")
+	} else {
+		re.WriteString("^(.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			re.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			re.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			re.WriteString("[^/]*")
+		case pattern[i] == '?':
+			re.WriteString("[^/]")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		}
+	}
+
+	// A pattern matching a directory also matches everything in it, except
+	// that a trailing wildcard, ex. docs/*, only matches direct children.
+	lastElem := pattern[strings.LastIndex(pattern, "/")+1:]
+	switch {
+	case dirOnly:
+		re.WriteString("/.*$")
+	case strings.Contains(lastElem, "*"):
+		re.WriteString("$")
+	default:
+		re.WriteString("(/.*)?$")
+	}
+	return regexp.MustCompile(re.String())
+}
@@ -0,0 +1,120 @@
+package events_test
+
+import (
+	"testing"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	vcsmocks "github.com/runatlantis/atlantis/server/events/vcs/mocks"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+var codeownersFixture = `# Default owners.
+*                 @runatlantis/platform
+*.md              @docs-writer
+
+/modules/         @runatlantis/modules
+staging/**/*.tf   @staging-owner
+prod/             @prod-owner prod@example.com # inline comment
+prod/README.md
+`
+
+var codeownersModifiedFiles = []string{
+	"prod/main.tf",
+	"prod/README.md",
+	"staging/app/main.tf",
+	"modules/vpc/main.tf",
+	"docs/guide.md",
+	"other/main.tf",
+}
+
+func TestDefaultCodeownersChecker_UnapprovedFiles(t *testing.T) {
+	cases := []struct {
+		description   string
+		repoRelDir    string
+		approvers     []string
+		expUnapproved []string
+	}{
+		{
+			description:   "owner approved",
+			repoRelDir:    "prod",
+			approvers:     []string{"prod-owner"},
+			expUnapproved: nil,
+		},
+		{
+			description:   "owner didn't approve",
+			repoRelDir:    "prod",
+			approvers:     []string{"someone"},
+			expUnapproved: []string{"prod/main.tf"},
+		},
+		{
+			description:   "usernames are case insensitive",
+			repoRelDir:    "staging",
+			approvers:     []string{"Staging-Owner"},
+			expUnapproved: nil,
+		},
+		{
+			description:   "approved by team member",
+			repoRelDir:    ".",
+			approvers:     []string{"platform-member"},
+			expUnapproved: []string{"prod/main.tf", "staging/app/main.tf", "modules/vpc/main.tf", "docs/guide.md"},
+		},
+		{
+			description:   "no modified files in dir",
+			repoRelDir:    "dev",
+			approvers:     nil,
+			expUnapproved: nil,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			vcsClient := vcsmocks.NewMockClient()
+			pull := models.PullRequest{
+				BaseRepo:   models.Repo{FullName: "runatlantis/atlantis", VCSHost: models.VCSHost{Type: models.Github}},
+				BaseBranch: "main",
+			}
+			When(vcsClient.GetModifiedFiles(pull.BaseRepo, pull)).ThenReturn(codeownersModifiedFiles, nil)
+			When(vcsClient.DownloadCodeowners(pull)).ThenReturn(true, []byte(codeownersFixture), nil)
+			When(vcsClient.GetPullApprovers(pull.BaseRepo, pull)).ThenReturn(c.approvers, nil)
+			When(vcsClient.UserIsTeamMember(pull.BaseRepo, models.User{Username: "platform-member"}, []string{"platform"})).ThenReturn(true, nil)
+
+			checker := events.DefaultCodeownersChecker{VCSClient: vcsClient}
+			unapproved, err := checker.UnapprovedFiles(logging.NewNoopLogger(t), pull, c.repoRelDir)
+			Ok(t, err)
+			Equals(t, c.expUnapproved, unapproved)
+		})
+	}
+}
+
+func TestDefaultCodeownersChecker_GitlabGroup(t *testing.T) {
+	RegisterMockTestingT(t)
+	vcsClient := vcsmocks.NewMockClient()
+	pull := models.PullRequest{
+		BaseRepo:   models.Repo{FullName: "runatlantis/atlantis", VCSHost: models.VCSHost{Type: models.Gitlab}},
+		BaseBranch: "main",
+	}
+	When(vcsClient.GetModifiedFiles(pull.BaseRepo, pull)).ThenReturn([]string{"main.tf"}, nil)
+	When(vcsClient.DownloadCodeowners(pull)).ThenReturn(true, []byte("* @infra\n"), nil)
+	When(vcsClient.GetPullApprovers(pull.BaseRepo, pull)).ThenReturn([]string{"bob"}, nil)
+	When(vcsClient.UserIsTeamMember(pull.BaseRepo, models.User{Username: "bob"}, []string{"infra"})).ThenReturn(true, nil)
+
+	checker := events.DefaultCodeownersChecker{VCSClient: vcsClient}
+	unapproved, err := checker.UnapprovedFiles(logging.NewNoopLogger(t), pull, ".")
+	Ok(t, err)
+	Equals(t, 0, len(unapproved))
+}
+
+func TestDefaultCodeownersChecker_NoCodeowners(t *testing.T) {
+	RegisterMockTestingT(t)
+	vcsClient := vcsmocks.NewMockClient()
+	pull := models.PullRequest{BaseBranch: "main"}
+	When(vcsClient.GetModifiedFiles(pull.BaseRepo, pull)).ThenReturn([]string{"main.tf"}, nil)
+	When(vcsClient.DownloadCodeowners(pull)).ThenReturn(false, []byte{}, nil)
+
+	checker := events.DefaultCodeownersChecker{VCSClient: vcsClient}
+	_, err := checker.UnapprovedFiles(logging.NewNoopLogger(t), pull, ".")
+	ErrEquals(t, `no CODEOWNERS file found on branch "main"`, err)
+}
@@ -0,0 +1,108 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// errCommandCancelled is the error of projects whose steps were interrupted
+// because their command was cancelled.
+var errCommandCancelled = errors.New("command was cancelled")
+
+// CommandCanceller keeps track of the commands running for each pull request
+// so that they can be cancelled, ex. by atlantis cancel or when a new commit
+// supersedes a running plan.
+type CommandCanceller struct {
+	mutex sync.Mutex
+	// running are the commands that have been started and aren't done yet,
+	// keyed by pull request.
+	running map[string][]*runningCommand
+}
+
+type runningCommand struct {
+	name   models.CommandName
+	cancel context.CancelFunc
+	// done is closed once the command has finished.
+	done chan struct{}
+}
+
+// NewCommandCanceller is a constructor.
+func NewCommandCanceller() *CommandCanceller {
+	return &CommandCanceller{
+		running: make(map[string][]*runningCommand),
+	}
+}
+
+// Start registers a cmdName command that's running for the pull request. The
+// returned context is derived from parent and is cancelled if the command is
+// cancelled. done must be called once the command has finished.
+func (c *CommandCanceller) Start(parent context.Context, repoFullName string, pullNum int, cmdName models.CommandName) (ctx context.Context, done func()) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	ctx, cancel := context.WithCancel(parent)
+	cmd := &runningCommand{
+		name:   cmdName,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	key := c.pullKey(repoFullName, pullNum)
+	c.running[key] = append(c.running[key], cmd)
+	return ctx, func() {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		cmds := c.running[key]
+		for i, running := range cmds {
+			if running == cmd {
+				cmds = append(cmds[:i], cmds[i+1:]...)
+				break
+			}
+		}
+		if len(cmds) == 0 {
+			delete(c.running, key)
+		} else {
+			c.running[key] = cmds
+		}
+		cancel()
+		close(cmd.done)
+	}
+}
+
+// Cancel cancels the commands running for the pull request and waits for
+// them to finish so that they've released their locks. If cmdNames are given,
+// only commands with those names are cancelled. It returns how many commands
+// were cancelled.
+func (c *CommandCanceller) Cancel(repoFullName string, pullNum int, cmdNames ...models.CommandName) int {
+	c.mutex.Lock()
+	var cancelled []*runningCommand
+	for _, cmd := range c.running[c.pullKey(repoFullName, pullNum)] {
+		if len(cmdNames) > 0 && !containsCommandName(cmdNames, cmd.name) {
+			continue
+		}
+		cmd.cancel()
+		cancelled = append(cancelled, cmd)
+	}
+	c.mutex.Unlock()
+
+	for _, cmd := range cancelled {
+		<-cmd.done
+	}
+	return len(cancelled)
+}
+
+func (c *CommandCanceller) pullKey(repoFullName string, pullNum int) string {
+	return fmt.Sprintf("%s/%d", repoFullName, pullNum)
+}
+
+func containsCommandName(names []models.CommandName, name models.CommandName) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package events_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestCommandCanceller_Cancel(t *testing.T) {
+	c := events.NewCommandCanceller()
+	planCtx, planDone := c.Start(context.Background(), "owner/repo", 1, models.PlanCommand)
+	applyCtx, applyDone := c.Start(context.Background(), "owner/repo", 1, models.ApplyCommand)
+	otherCtx, otherDone := c.Start(context.Background(), "owner/repo", 2, models.PlanCommand)
+	defer otherDone()
+	go finishWhenCancelled(planCtx, planDone)
+	go finishWhenCancelled(applyCtx, applyDone)
+
+	Equals(t, 2, c.Cancel("owner/repo", 1))
+	Assert(t, planCtx.Err() != nil, "exp plan to be cancelled")
+	Assert(t, applyCtx.Err() != nil, "exp apply to be cancelled")
+	Ok(t, otherCtx.Err())
+
+	t.Log("cancelled commands are no longer running")
+	Equals(t, 0, c.Cancel("owner/repo", 1))
+}
+
+func TestCommandCanceller_CancelByName(t *testing.T) {
+	c := events.NewCommandCanceller()
+	planCtx, planDone := c.Start(context.Background(), "owner/repo", 1, models.PlanCommand)
+	applyCtx, applyDone := c.Start(context.Background(), "owner/repo", 1, models.ApplyCommand)
+	defer applyDone()
+	go finishWhenCancelled(planCtx, planDone)
+
+	Equals(t, 1, c.Cancel("owner/repo", 1, models.PlanCommand))
+	Assert(t, planCtx.Err() != nil, "exp plan to be cancelled")
+	Ok(t, applyCtx.Err())
+}
+
+func TestCommandCanceller_Done(t *testing.T) {
+	c := events.NewCommandCanceller()
+	_, done := c.Start(context.Background(), "owner/repo", 1, models.PlanCommand)
+	done()
+	Equals(t, 0, c.Cancel("owner/repo", 1))
+}
+
+// finishWhenCancelled simulates a command that finishes once it's cancelled.
+func finishWhenCancelled(ctx context.Context, done func()) {
+	<-ctx.Done()
+	done()
+}
@@ -13,6 +13,8 @@
 package events
 
 import (
+	"context"
+
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/logging"
 )
@@ -49,4 +51,23 @@ type CommandContext struct {
 	PullStatus *models.PullStatus
 
 	Trigger CommandTrigger
+
+	// ProgressCommentID is the id of the comment showing that the command is
+	// running. If it's not 0, the comment is edited with the command's results
+	// instead of posting a new comment.
+	ProgressCommentID int64
+
+	// DiscussionID is the id of the thread the command's comment was made
+	// in. If it's set, Atlantis replies in that thread instead of commenting
+	// on the pull request.
+	DiscussionID string
+
+	// RequestCtx, if set, carries the command's trace span. It's cancelled
+	// when the command is cancelled, ex. by atlantis cancel or because a new
+	// commit was pushed.
+	RequestCtx context.Context
+
+	// Result is the result of the command. It's set once the result has been
+	// commented on the pull request and is nil until then.
+	Result *CommandResult
 }
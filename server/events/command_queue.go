@@ -0,0 +1,198 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	"github.com/runatlantis/atlantis/server/tracing"
+)
+
+// DefaultCommandQueueMaxAttempts is how many times a queued command is started
+// before we give up on it. A command that's been started this many times has
+// probably been crashing Atlantis.
+const DefaultCommandQueueMaxAttempts = 3
+
+// QueuedCommandStore persists the command queue.
+type QueuedCommandStore interface {
+	AddQueuedCommand(cmd models.QueuedCommand) error
+	DeleteQueuedCommand(id string) error
+	ListQueuedCommands() ([]models.QueuedCommand, error)
+}
+
+// CommandQueue implements CommandRunner. Instead of running commands
+// immediately it persists them and runs them on a pool of workers. Commands
+// are only removed from the queue once they've finished so that if Atlantis
+// is stopped mid-command, the command is run again when it starts back up.
+type CommandQueue struct {
+	// Runner is used to actually run the commands.
+	Runner CommandRunner
+	Store  QueuedCommandStore
+	// CommitStatusUpdater is used to mark commands we've given up on as
+	// failed so their pull requests aren't left with a pending status.
+	CommitStatusUpdater CommitStatusUpdater
+	// Drainer is used to stop starting new commands once Atlantis is shutting
+	// down. Commands that haven't started are left in the queue.
+	Drainer *Drainer
+	Logger  logging.SimpleLogging
+	// MaxAttempts is how many times a command is started before we give up
+	// on it.
+	MaxAttempts int
+	commands    chan models.QueuedCommand
+	// seq is used to generate unique IDs for commands queued in the same
+	// nanosecond.
+	seq uint64
+}
+
+// NewCommandQueue is a constructor.
+func NewCommandQueue(runner CommandRunner, store QueuedCommandStore, commitStatusUpdater CommitStatusUpdater, drainer *Drainer, logger logging.SimpleLogging) *CommandQueue {
+	return &CommandQueue{
+		Runner:              runner,
+		Store:               store,
+		CommitStatusUpdater: commitStatusUpdater,
+		Drainer:             drainer,
+		Logger:              logger,
+		MaxAttempts:         DefaultCommandQueueMaxAttempts,
+		// The buffer is only so webhooks don't block while all the workers
+		// are busy. Commands are persisted before they're sent so nothing is
+		// lost if it fills up.
+		commands: make(chan models.QueuedCommand, 100),
+	}
+}
+
+// Start replays the commands left in the queue by a previous run of Atlantis
+// and then starts numWorkers workers. Commands that have already been
+// started MaxAttempts times are marked as failed instead of being replayed.
+func (q *CommandQueue) Start(numWorkers int) error {
+	cmds, err := q.Store.ListQueuedCommands()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < numWorkers; i++ {
+		go q.work()
+	}
+	for _, cmd := range cmds {
+		if cmd.Attempts >= q.MaxAttempts {
+			q.giveUp(cmd)
+			continue
+		}
+		q.Logger.Info("replaying queued command %s for %s#%d", cmd.ID, cmd.BaseRepo.FullName, cmd.PullNum)
+		go q.send(cmd)
+	}
+	return nil
+}
+
+// RunCommentCommand queues the comment command. Cancel commands are run
+// immediately instead since they'd otherwise wait for a worker behind the
+// commands they're cancelling.
+func (q *CommandQueue) RunCommentCommand(parentCtx context.Context, baseRepo models.Repo, maybeHeadRepo *models.Repo, maybePull *models.PullRequest, user models.User, pullNum int, cmd *CommentCommand) {
+	if cmd != nil && cmd.Name == models.CancelCommand {
+		q.Runner.RunCommentCommand(parentCtx, baseRepo, maybeHeadRepo, maybePull, user, pullNum, cmd)
+		return
+	}
+	serialized, err := json.Marshal(cmd)
+	if err != nil {
+		q.Logger.Err("serializing comment command for %s#%d: %s", baseRepo.FullName, pullNum, err)
+		return
+	}
+	q.enqueue(models.QueuedCommand{
+		BaseRepo:     baseRepo,
+		HeadRepo:     maybeHeadRepo,
+		Pull:         maybePull,
+		User:         user,
+		PullNum:      pullNum,
+		Comment:      serialized,
+		TraceContext: tracing.Inject(parentCtx),
+	})
+}
+
+// RunAutoplanCommand queues the autoplan.
+func (q *CommandQueue) RunAutoplanCommand(parentCtx context.Context, baseRepo models.Repo, headRepo models.Repo, pull models.PullRequest, user models.User) {
+	q.enqueue(models.QueuedCommand{
+		BaseRepo:     baseRepo,
+		HeadRepo:     &headRepo,
+		Pull:         &pull,
+		User:         user,
+		PullNum:      pull.Num,
+		TraceContext: tracing.Inject(parentCtx),
+	})
+}
+
+// enqueue persists cmd and sends it to the workers. If it can't be persisted
+// it's still run, it just won't survive a restart.
+func (q *CommandQueue) enqueue(cmd models.QueuedCommand) {
+	cmd.Time = time.Now()
+	cmd.ID = fmt.Sprintf("%d-%d", cmd.Time.UnixNano(), atomic.AddUint64(&q.seq, 1))
+	if err := q.Store.AddQueuedCommand(cmd); err != nil {
+		q.Logger.Warn("unable to persist command for %s#%d: %s", cmd.BaseRepo.FullName, cmd.PullNum, err)
+	}
+	q.send(cmd)
+}
+
+func (q *CommandQueue) send(cmd models.QueuedCommand) {
+	q.commands <- cmd
+}
+
+func (q *CommandQueue) work() {
+	for cmd := range q.commands {
+		q.run(cmd)
+	}
+}
+
+// run runs cmd and removes it from the queue once it's done.
+func (q *CommandQueue) run(cmd models.QueuedCommand) {
+	if q.Drainer != nil && q.Drainer.GetStatus().ShuttingDown {
+		// Leave it in the queue so it's run when we start back up.
+		return
+	}
+
+	// We record the attempt before running the command so that if it
+	// crashes Atlantis we eventually stop replaying it.
+	cmd.Attempts++
+	if err := q.Store.AddQueuedCommand(cmd); err != nil {
+		q.Logger.Warn("unable to persist attempt for queued command %s: %s", cmd.ID, err)
+	}
+
+	// The command continues the trace of the webhook that queued it.
+	parentCtx := tracing.Extract(cmd.TraceContext)
+	if cmd.IsAutoplan() {
+		q.Runner.RunAutoplanCommand(parentCtx, cmd.BaseRepo, *cmd.HeadRepo, *cmd.Pull, cmd.User)
+	} else {
+		var comment CommentCommand
+		if err := json.Unmarshal(cmd.Comment, &comment); err != nil {
+			q.Logger.Err("deserializing queued command %s: %s", cmd.ID, err)
+		} else {
+			q.Runner.RunCommentCommand(parentCtx, cmd.BaseRepo, cmd.HeadRepo, cmd.Pull, cmd.User, cmd.PullNum, &comment)
+		}
+	}
+
+	if err := q.Store.DeleteQueuedCommand(cmd.ID); err != nil {
+		q.Logger.Warn("unable to remove queued command %s: %s", cmd.ID, err)
+	}
+}
+
+// giveUp removes cmd from the queue. If it's a plan or apply and we know its
+// pull request, we also set a failed commit status since the status it set
+// when it started will still be pending.
+func (q *CommandQueue) giveUp(cmd models.QueuedCommand) {
+	q.Logger.Err("giving up on queued command %s for %s#%d after %d attempts", cmd.ID, cmd.BaseRepo.FullName, cmd.PullNum, cmd.Attempts)
+	name := models.PlanCommand
+	if !cmd.IsAutoplan() {
+		var comment CommentCommand
+		if err := json.Unmarshal(cmd.Comment, &comment); err == nil {
+			name = comment.Name
+		}
+	}
+	if cmd.Pull != nil && (name == models.PlanCommand || name == models.ApplyCommand) {
+		if err := q.CommitStatusUpdater.UpdateCombined(cmd.BaseRepo, *cmd.Pull, models.FailedCommitStatus, name); err != nil {
+			q.Logger.Warn("unable to update commit status: %s", err)
+		}
+	}
+	if err := q.Store.DeleteQueuedCommand(cmd.ID); err != nil {
+		q.Logger.Warn("unable to remove queued command %s: %s", cmd.ID, err)
+	}
+}
@@ -0,0 +1,145 @@
+package events_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/mocks"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestCommandQueue_RunsAndRemovesCommands(t *testing.T) {
+	RegisterMockTestingT(t)
+	store := newFakeQueuedCommandStore()
+	runner := &fakeCommandRunner{ran: make(chan int, 2)}
+	queue := events.NewCommandQueue(runner, store, mocks.NewMockCommitStatusUpdater(), &events.Drainer{}, logging.NewNoopLogger(t))
+	Ok(t, queue.Start(1))
+
+	repo := models.Repo{FullName: "owner/repo"}
+	cmd := &events.CommentCommand{Name: models.PlanCommand, RepoRelDir: "dir", Flags: []string{"-target=foo"}}
+	queue.RunCommentCommand(context.Background(), repo, nil, nil, models.User{Username: "user"}, 1, cmd)
+	queue.RunAutoplanCommand(context.Background(), repo, repo, models.PullRequest{Num: 2, BaseRepo: repo}, models.User{})
+
+	Equals(t, 1, waitForRun(t, runner))
+	Equals(t, 2, waitForRun(t, runner))
+	Equals(t, []*events.CommentCommand{cmd}, runner.cmds)
+
+	t.Log("commands are removed once they've run")
+	store.waitForDeletes(t, 2)
+	cmds, err := store.ListQueuedCommands()
+	Ok(t, err)
+	Equals(t, 0, len(cmds))
+	Equals(t, 2, len(store.attempts))
+}
+
+func TestCommandQueue_RunsCancelImmediately(t *testing.T) {
+	RegisterMockTestingT(t)
+	store := newFakeQueuedCommandStore()
+	runner := &fakeCommandRunner{ran: make(chan int, 1)}
+	queue := events.NewCommandQueue(runner, store, mocks.NewMockCommitStatusUpdater(), &events.Drainer{}, logging.NewNoopLogger(t))
+	// No workers are started so the command can only run if it isn't queued.
+	cmd := &events.CommentCommand{Name: models.CancelCommand}
+	queue.RunCommentCommand(context.Background(), models.Repo{FullName: "owner/repo"}, nil, nil, models.User{}, 1, cmd)
+
+	Equals(t, 1, waitForRun(t, runner))
+	Equals(t, []*events.CommentCommand{cmd}, runner.cmds)
+	cmds, err := store.ListQueuedCommands()
+	Ok(t, err)
+	Equals(t, 0, len(cmds))
+}
+
+func TestCommandQueue_StartReplaysCommands(t *testing.T) {
+	RegisterMockTestingT(t)
+	store := newFakeQueuedCommandStore()
+	repo := models.Repo{FullName: "owner/repo"}
+	pull := models.PullRequest{Num: 2, BaseRepo: repo}
+	Ok(t, store.AddQueuedCommand(models.QueuedCommand{
+		ID:       "interrupted",
+		Attempts: 1,
+		BaseRepo: repo,
+		PullNum:  1,
+		Comment:  []byte(`{"Name":1}`),
+	}))
+	Ok(t, store.AddQueuedCommand(models.QueuedCommand{
+		ID:       "crashing",
+		Attempts: events.DefaultCommandQueueMaxAttempts,
+		BaseRepo: repo,
+		Pull:     &pull,
+		PullNum:  2,
+		Comment:  []byte(`{"Name":0}`),
+	}))
+	runner := &fakeCommandRunner{ran: make(chan int, 2)}
+	updater := mocks.NewMockCommitStatusUpdater()
+	queue := events.NewCommandQueue(runner, store, updater, &events.Drainer{}, logging.NewNoopLogger(t))
+	Ok(t, queue.Start(1))
+
+	t.Log("commands under the max attempts are run again")
+	Equals(t, 1, waitForRun(t, runner))
+	Equals(t, []*events.CommentCommand{{Name: models.PlanCommand}}, runner.cmds)
+
+	t.Log("commands at the max attempts are marked as failed")
+	updater.VerifyWasCalledOnce().UpdateCombined(repo, pull, models.FailedCommitStatus, models.ApplyCommand)
+	store.waitForDeletes(t, 2)
+	cmds, err := store.ListQueuedCommands()
+	Ok(t, err)
+	Equals(t, 0, len(cmds))
+}
+
+// fakeQueuedCommandStore keeps the queue in memory.
+type fakeQueuedCommandStore struct {
+	mutex    sync.Mutex
+	cmds     map[string]models.QueuedCommand
+	attempts []int
+	deleted  chan string
+}
+
+func newFakeQueuedCommandStore() *fakeQueuedCommandStore {
+	return &fakeQueuedCommandStore{
+		cmds:    make(map[string]models.QueuedCommand),
+		deleted: make(chan string, 10),
+	}
+}
+
+func (f *fakeQueuedCommandStore) AddQueuedCommand(cmd models.QueuedCommand) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if cmd.Attempts > 0 {
+		f.attempts = append(f.attempts, cmd.Attempts)
+	}
+	f.cmds[cmd.ID] = cmd
+	return nil
+}
+
+func (f *fakeQueuedCommandStore) DeleteQueuedCommand(id string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	delete(f.cmds, id)
+	f.deleted <- id
+	return nil
+}
+
+func (f *fakeQueuedCommandStore) ListQueuedCommands() ([]models.QueuedCommand, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	var cmds []models.QueuedCommand
+	for _, cmd := range f.cmds {
+		cmds = append(cmds, cmd)
+	}
+	return cmds, nil
+}
+
+func (f *fakeQueuedCommandStore) waitForDeletes(t *testing.T, n int) {
+	for i := 0; i < n; i++ {
+		select {
+		case <-f.deleted:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for queued command to be removed")
+		}
+	}
+}
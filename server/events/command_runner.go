@@ -14,16 +14,23 @@
 package events
 
 import (
+	"context"
 	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/go-github/v31/github"
 	"github.com/mcdafydd/go-azuredevops/azuredevops"
 	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/audit"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/i18n"
 	"github.com/runatlantis/atlantis/server/logging"
 	"github.com/runatlantis/atlantis/server/recovery"
+	"github.com/runatlantis/atlantis/server/tracing"
 	gitlab "github.com/xanzy/go-gitlab"
 )
 
@@ -38,8 +45,9 @@ type CommandRunner interface {
 	// RunCommentCommand is the first step after a command request has been parsed.
 	// It handles gathering additional information needed to execute the command
 	// and then calling the appropriate services to finish executing the command.
-	RunCommentCommand(baseRepo models.Repo, maybeHeadRepo *models.Repo, maybePull *models.PullRequest, user models.User, pullNum int, cmd *CommentCommand)
-	RunAutoplanCommand(baseRepo models.Repo, headRepo models.Repo, pull models.PullRequest, user models.User)
+	// The command's spans are children of parentCtx's span, if it has one.
+	RunCommentCommand(parentCtx context.Context, baseRepo models.Repo, maybeHeadRepo *models.Repo, maybePull *models.PullRequest, user models.User, pullNum int, cmd *CommentCommand)
+	RunAutoplanCommand(parentCtx context.Context, baseRepo models.Repo, headRepo models.Repo, pull models.PullRequest, user models.User)
 }
 
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_github_pull_getter.go GithubPullGetter
@@ -64,6 +72,9 @@ type AzureDevopsPullGetter interface {
 type GitlabMergeRequestGetter interface {
 	// GetMergeRequest gets the pull request with the id pullNum for the repo.
 	GetMergeRequest(repoFullName string, pullNum int) (*gitlab.MergeRequest, error)
+	// GetProject gets the project with id projectID, ex. the fork a merge
+	// request is from.
+	GetProject(projectID int) (*gitlab.Project, error)
 }
 
 // CommentCommandRunner runs individual command workflows.
@@ -92,9 +103,15 @@ type DefaultCommandRunner struct {
 	GithubPullGetter         GithubPullGetter
 	AzureDevopsPullGetter    AzureDevopsPullGetter
 	GitlabMergeRequestGetter GitlabMergeRequestGetter
-	DisableAutoplan          bool
-	EventParser              EventParsing
-	Logger                   logging.SimpleLogging
+	// HostGithubPullGetters and HostGitlabMergeRequestGetters map the
+	// hostnames of additional VCS hosts to the getters for their pull
+	// requests. They're used instead of GithubPullGetter and
+	// GitlabMergeRequestGetter for repos on those hosts.
+	HostGithubPullGetters         map[string]GithubPullGetter
+	HostGitlabMergeRequestGetters map[string]GitlabMergeRequestGetter
+	DisableAutoplan               bool
+	EventParser                   EventParsing
+	Logger                        logging.SimpleLogging
 	// AllowForkPRs controls whether we operate on pull requests from forks.
 	AllowForkPRs bool
 	// ParallelPoolSize controls the size of the wait group used to run
@@ -114,17 +131,62 @@ type DefaultCommandRunner struct {
 	Drainer                       *Drainer
 	PreWorkflowHooksCommandRunner PreWorkflowHooksCommandRunner
 	PullStatusFetcher             PullStatusFetcher
+	// Auditor, if set, records every comment command that's run.
+	Auditor *audit.MultiExporter
+	// GlobalCfg is the server-side repo config. It's used to look up which
+	// teams are allowed to run commands that change infrastructure.
+	GlobalCfg *valid.GlobalCfgStore
+	// RepoAllowlistChecker, if set, is used to look up the restrictions the
+	// repo allowlist places on each repo.
+	RepoAllowlistChecker *RepoAllowlistChecker
+	// RepoOpLimiter, if set, enforces the max-parallel repo restriction.
+	RepoOpLimiter *RepoOpLimiter
+	// DiskUsageLimiter, if set, rejects plans once the data dir is using too
+	// much disk.
+	DiskUsageLimiter *DiskUsageLimiter
+	// ProgressComments is true if comment commands should post a comment
+	// showing they're running that's then edited with their results, on VCS
+	// hosts that support it.
+	ProgressComments bool
+	// HistoryURLGenerator, if set, is used to link to the pull request's
+	// command history from progress comments.
+	HistoryURLGenerator HistoryURLGenerator
+	// RepoCfgValidator, if set, validates the atlantis.yaml file of pull
+	// requests that modify it when they're opened or updated.
+	RepoCfgValidator *RepoCfgValidator
+	// CommandCanceller, if set, keeps track of running plans and applies so
+	// they can be cancelled. Autoplans cancel the plans that are still
+	// running for the pull request's previous commit.
+	CommandCanceller *CommandCanceller
+	// AutoApplier, if set, applies pull requests of repos that set auto_apply
+	// once they've been planned, in case they were approved first.
+	AutoApplier AutoApplier
+	// Localizer, if set, picks the locale of the comments. Otherwise they're
+	// in English.
+	Localizer *i18n.Localizer
 }
 
 // RunAutoplanCommand runs plan and policy_checks when a pull request is opened or updated.
-func (c *DefaultCommandRunner) RunAutoplanCommand(baseRepo models.Repo, headRepo models.Repo, pull models.PullRequest, user models.User) {
-	if opStarted := c.Drainer.StartOp(); !opStarted {
+func (c *DefaultCommandRunner) RunAutoplanCommand(parentCtx context.Context, baseRepo models.Repo, headRepo models.Repo, pull models.PullRequest, user models.User) {
+	requestCtx, span := tracing.Start(parentCtx, "autoplan",
+		tracing.RepoKey.String(baseRepo.FullName),
+		tracing.PullNumKey.Int(pull.Num),
+		tracing.CommandKey.String(models.PlanCommand.String()),
+	)
+	defer span.End()
+
+	opDone, opStarted := c.Drainer.StartOperation(Operation{
+		Repo:    baseRepo.FullName,
+		PullNum: pull.Num,
+		Command: models.PlanCommand.String(),
+	})
+	if !opStarted {
 		if commentErr := c.VCSClient.CreateComment(baseRepo, pull.Num, ShutdownComment, models.PlanCommand.String()); commentErr != nil {
 			c.Logger.Log(logging.Error, "unable to comment that Atlantis is shutting down: %s", commentErr)
 		}
 		return
 	}
-	defer c.Drainer.OpDone()
+	defer opDone()
 
 	log := c.buildLogger(baseRepo.FullName, pull.Num)
 	defer c.logPanics(baseRepo, pull.Num, log)
@@ -141,13 +203,40 @@ func (c *DefaultCommandRunner) RunAutoplanCommand(baseRepo models.Repo, headRepo
 		HeadRepo:   headRepo,
 		PullStatus: status,
 		Trigger:    Auto,
+		RequestCtx: requestCtx,
 	}
 	if !c.validateCtxAndComment(ctx) {
 		return
 	}
+	if c.RepoCfgValidator != nil {
+		c.RepoCfgValidator.Validate(ctx)
+	}
 	if c.DisableAutoplan {
 		return
 	}
+	restrictions := c.repoRestrictions(baseRepo)
+	if restrictions.NoAutoplan {
+		log.Info("not autoplanning because the repo allowlist restricts this repo to %s", NoAutoplanRestriction)
+		return
+	}
+	// Deferred before the repo operation is started so it's done by the time
+	// the apply is run.
+	defer c.autoApply(ctx)
+	// The plans still running for the previous commit are superseded so we
+	// cancel them before they use up a repo operation.
+	if c.CommandCanceller != nil {
+		if n := c.CommandCanceller.Cancel(baseRepo.FullName, pull.Num, models.PlanCommand); n > 0 {
+			log.Info("cancelled %d running plan(s) superseded by commit %s", n, pull.HeadCommit)
+		}
+	}
+	if !c.startRepoOp(ctx, restrictions, models.PlanCommand) {
+		return
+	}
+	defer c.repoOpDone(baseRepo)
+	if !c.checkDiskUsage(ctx, models.PlanCommand) {
+		return
+	}
+	defer c.startCancellable(ctx, models.PlanCommand)()
 
 	err = c.PreWorkflowHooksCommandRunner.RunPreHooks(ctx)
 
@@ -165,58 +254,238 @@ func (c *DefaultCommandRunner) RunAutoplanCommand(baseRepo models.Repo, headRepo
 // enough data to construct the Repo model and callers might want to wait until
 // the event is further validated before making an additional (potentially
 // wasteful) call to get the necessary data.
-func (c *DefaultCommandRunner) RunCommentCommand(baseRepo models.Repo, maybeHeadRepo *models.Repo, maybePull *models.PullRequest, user models.User, pullNum int, cmd *CommentCommand) {
-	if opStarted := c.Drainer.StartOp(); !opStarted {
+func (c *DefaultCommandRunner) RunCommentCommand(parentCtx context.Context, baseRepo models.Repo, maybeHeadRepo *models.Repo, maybePull *models.PullRequest, user models.User, pullNum int, cmd *CommentCommand) {
+	op := Operation{Repo: baseRepo.FullName, PullNum: pullNum}
+	spanName := "comment command"
+	if cmd != nil {
+		op.Command = cmd.DisplayName()
+		spanName = "atlantis " + op.Command
+	}
+	requestCtx, span := tracing.Start(parentCtx, spanName,
+		tracing.RepoKey.String(baseRepo.FullName),
+		tracing.PullNumKey.Int(pullNum),
+		tracing.CommandKey.String(op.Command),
+	)
+	defer span.End()
+
+	start := time.Now()
+	log := c.buildLogger(baseRepo.FullName, pullNum)
+	defer c.logPanics(baseRepo, pullNum, log)
+
+	// ctx is filled in once the pull request has been fetched. It's created
+	// first so that commands which fail before then are still audited.
+	ctx := &CommandContext{
+		User:       user,
+		Log:        log,
+		Pull:       models.PullRequest{Num: pullNum, BaseRepo: baseRepo},
+		Trigger:    Comment,
+		RequestCtx: requestCtx,
+	}
+	if cmd != nil {
+		ctx.DiscussionID = cmd.DiscussionID
+	}
+	rejected := false
+	defer func() { c.recordAudit(ctx, cmd, start, rejected) }()
+
+	opDone, opStarted := c.Drainer.StartOperation(op)
+	if !opStarted {
+		rejected = true
 		if commentErr := c.VCSClient.CreateComment(baseRepo, pullNum, ShutdownComment, ""); commentErr != nil {
 			c.Logger.Log(logging.Error, "unable to comment that Atlantis is shutting down: %s", commentErr)
 		}
 		return
 	}
-	defer c.Drainer.OpDone()
-
-	log := c.buildLogger(baseRepo.FullName, pullNum)
-	defer c.logPanics(baseRepo, pullNum, log)
+	defer opDone()
 
+	_, fetchSpan := tracing.Start(requestCtx, "fetch pull request")
 	headRepo, pull, err := c.ensureValidRepoMetadata(baseRepo, maybeHeadRepo, maybePull, user, pullNum, log)
+	tracing.End(fetchSpan, err)
 	if err != nil {
+		ctx.Result = &CommandResult{Error: err}
 		return
 	}
+	ctx.Pull = pull
+	ctx.HeadRepo = headRepo
 
 	status, err := c.PullStatusFetcher.GetPullStatus(pull)
 
 	if err != nil {
 		log.Err("Unable to fetch pull status, this is likely a bug.", err)
 	}
+	ctx.PullStatus = status
 
-	ctx := &CommandContext{
-		User:       user,
-		Log:        log,
-		Pull:       pull,
-		PullStatus: status,
-		HeadRepo:   headRepo,
-		Trigger:    Comment,
+	if !c.validateCtxAndComment(ctx) {
+		rejected = true
+		return
 	}
 
-	if !c.validateCtxAndComment(ctx) {
+	if !c.validateTeamAllowed(ctx, cmd) {
+		rejected = true
 		return
 	}
 
+	restrictions := c.repoRestrictions(baseRepo)
+	if !c.validateRepoRestrictions(ctx, cmd, restrictions) {
+		rejected = true
+		return
+	}
+	if cmd.Name == models.PlanCommand {
+		defer c.autoApply(ctx)
+	}
+	// Unlocking and cancelling are always allowed so that locks can be
+	// released and commands stopped even when the repo is at its limit.
+	if cmd.Name != models.UnlockCommand && cmd.Name != models.CancelCommand {
+		if !c.startRepoOp(ctx, restrictions, cmd.Name) {
+			rejected = true
+			return
+		}
+		defer c.repoOpDone(baseRepo)
+	}
+	if cmd.Name == models.PlanCommand && !c.checkDiskUsage(ctx, cmd.Name) {
+		rejected = true
+		return
+	}
+	if cmd.Name == models.PlanCommand || cmd.Name == models.ApplyCommand {
+		defer c.startCancellable(ctx, cmd.Name)()
+	}
+
+	c.createProgressComment(ctx, cmd)
+
 	err = c.PreWorkflowHooksCommandRunner.RunPreHooks(ctx)
 
 	if err != nil {
-		ctx.Log.Err("Error running pre-workflow hooks %s. Proceeding with %s command.", err, cmd.Name.String())
+		ctx.Log.Err("Error running pre-workflow hooks %s. Proceeding with %s command.", err, cmd.DisplayName())
 	}
 
 	cmdRunner := buildCommentCommandRunner(c, cmd.CommandName())
 
 	cmdRunner.Run(ctx, cmd)
+
+	// Some commands, ex. unlock, don't comment their results so the progress
+	// comment is updated here instead.
+	if ctx.ProgressCommentID != 0 {
+		comment := c.Localizer.Sprintf(baseRepo, ctx.Pull.BaseBranch, i18n.ProgressFinished, cmd.DisplayName(), ctx.User.Username)
+		if err := c.VCSClient.UpdateComment(baseRepo, pullNum, ctx.ProgressCommentID, comment, ""); err != nil {
+			ctx.Log.Warn("unable to update progress comment: %s", err)
+		}
+		ctx.ProgressCommentID = 0
+	}
+}
+
+// StartAPICommand checks that cmd, which was started through the API rather
+// than a comment, is allowed to run. It applies the same restrictions as
+// comment commands but returns an error instead of commenting since API runs
+// don't need a pull request. Rejected commands are audited here. Otherwise
+// the returned func must be called once the command is complete, after
+// ctx.Result is set, to release its repo operation and audit it.
+func (c *DefaultCommandRunner) StartAPICommand(ctx *CommandContext, cmd *CommentCommand) (func(), error) {
+	start := time.Now()
+	reject := func(err error) (func(), error) {
+		ctx.Log.Info("not running %s through the api: %s", cmd.DisplayName(), err)
+		c.recordAudit(ctx, cmd, start, true)
+		return nil, err
+	}
+
+	restrictions := c.repoRestrictions(ctx.Pull.BaseRepo)
+	switch cmd.Name {
+	case models.ApplyCommand, models.ImportCommand, models.StateCommand, models.CustomCommand:
+		// API requests aren't made by a VCS user so there's no one whose team
+		// membership could be checked.
+		if teams := c.GlobalCfg.Get().AllowedApplyTeams(ctx.Pull.BaseRepo.ID(), ctx.Pull.BaseBranch); len(teams) > 0 {
+			return reject(fmt.Errorf("%s is restricted to members of these teams on this repo so it can't be run through the api: %s", cmd.DisplayName(), strings.Join(teams, ", ")))
+		}
+		if restrictions.PlanOnly {
+			return reject(fmt.Errorf("%s is disabled for this repo, it is restricted to %s", cmd.DisplayName(), PlanOnlyRestriction))
+		}
+	}
+	// API applies plan first so they're held to the disk limit too.
+	if cmd.Name == models.PlanCommand || cmd.Name == models.ApplyCommand {
+		if c.DiskUsageLimiter != nil {
+			if exceeded, used := c.DiskUsageLimiter.Exceeded(); exceeded {
+				return reject(fmt.Errorf("atlantis is low on disk space, its data dir is using %s which is over its limit of %s", formatBytes(used), formatBytes(c.DiskUsageLimiter.MaxBytes)))
+			}
+		}
+	}
+	if c.RepoOpLimiter != nil && !c.RepoOpLimiter.StartOp(ctx.Pull.BaseRepo.FullName, restrictions.MaxParallel) {
+		return reject(fmt.Errorf("%d operations are already in progress for this repo", restrictions.MaxParallel))
+	}
+
+	return func() {
+		c.repoOpDone(ctx.Pull.BaseRepo)
+		c.recordAudit(ctx, cmd, start, false)
+	}, nil
+}
+
+// autoApply applies the pull request once it's been planned if its repo sets
+// auto_apply and it's ready.
+func (c *DefaultCommandRunner) autoApply(ctx *CommandContext) {
+	if c.AutoApplier == nil {
+		return
+	}
+	if err := c.AutoApplier.MaybeApply(ctx.Pull.BaseRepo, ctx.HeadRepo, ctx.Pull, ctx.User); err != nil {
+		ctx.Log.Err("unable to auto-apply: %s", err)
+	}
+}
+
+// startCancellable registers the command so it can be cancelled and replaces
+// ctx.RequestCtx with a context that's cancelled when it is. The returned func
+// must be called once the command is done.
+func (c *DefaultCommandRunner) startCancellable(ctx *CommandContext, cmdName models.CommandName) func() {
+	if c.CommandCanceller == nil {
+		return func() {}
+	}
+	var done func()
+	ctx.RequestCtx, done = c.CommandCanceller.Start(ctx.RequestCtx, ctx.Pull.BaseRepo.FullName, ctx.Pull.Num, cmdName)
+	return done
+}
+
+// createProgressComment posts a comment showing that cmd is running and sets
+// ctx.ProgressCommentID so its results are edited into it.
+func (c *DefaultCommandRunner) createProgressComment(ctx *CommandContext, cmd *CommentCommand) {
+	baseRepo := ctx.Pull.BaseRepo
+	if !c.ProgressComments || !c.VCSClient.SupportsCommentUpdates(baseRepo) {
+		return
+	}
+	// The first line mustn't contain the command's name so the comment isn't
+	// hidden by --hide-prev-plan-comments before it's updated.
+	locale := c.Localizer.For(baseRepo, ctx.Pull.BaseBranch)
+	comment := i18n.Sprintf(locale, i18n.ProgressRunning, cmd.DisplayName(), ctx.User.Username)
+	if c.HistoryURLGenerator != nil {
+		comment += "\n\n" + i18n.Sprintf(locale, i18n.ProgressViewLogs, c.HistoryURLGenerator.GenerateHistoryURL(baseRepo.FullName, ctx.Pull.Num))
+	}
+	var id int64
+	var err error
+	if ctx.DiscussionID != "" {
+		id, err = c.VCSClient.CreateReply(baseRepo, ctx.Pull.Num, ctx.DiscussionID, comment, "")
+	} else {
+		id, err = c.VCSClient.CreateUpdatableComment(baseRepo, ctx.Pull.Num, comment)
+	}
+	if err != nil {
+		ctx.Log.Warn("unable to create progress comment: %s", err)
+		return
+	}
+	ctx.ProgressCommentID = id
+}
+
+// commentOnPull comments on ctx's pull request, replying in the thread the
+// command came from if there is one.
+func commentOnPull(client vcs.Client, ctx *CommandContext, comment string, command string) error {
+	if ctx.DiscussionID != "" {
+		_, err := client.CreateReply(ctx.Pull.BaseRepo, ctx.Pull.Num, ctx.DiscussionID, comment, command)
+		return err
+	}
+	return client.CreateComment(ctx.Pull.BaseRepo, ctx.Pull.Num, comment, command)
 }
 
 func (c *DefaultCommandRunner) getGithubData(baseRepo models.Repo, pullNum int) (models.PullRequest, models.Repo, error) {
-	if c.GithubPullGetter == nil {
+	getter := c.GithubPullGetter
+	if hostGetter, ok := c.HostGithubPullGetters[strings.ToLower(baseRepo.VCSHost.Hostname)]; ok {
+		getter = hostGetter
+	}
+	if getter == nil {
 		return models.PullRequest{}, models.Repo{}, errors.New("Atlantis not configured to support GitHub")
 	}
-	ghPull, err := c.GithubPullGetter.GetPullRequest(baseRepo, pullNum)
+	ghPull, err := getter.GetPullRequest(baseRepo, pullNum)
 	if err != nil {
 		return models.PullRequest{}, models.Repo{}, errors.Wrap(err, "making pull request API call to GitHub")
 	}
@@ -227,16 +496,37 @@ func (c *DefaultCommandRunner) getGithubData(baseRepo models.Repo, pullNum int)
 	return pull, headRepo, nil
 }
 
-func (c *DefaultCommandRunner) getGitlabData(baseRepo models.Repo, pullNum int) (models.PullRequest, error) {
-	if c.GitlabMergeRequestGetter == nil {
-		return models.PullRequest{}, errors.New("Atlantis not configured to support GitLab")
+// getGitlabData fetches the merge request. If maybeHeadRepo is nil, ex. when
+// the command was started from the UI, the repo the merge request is from is
+// fetched too.
+func (c *DefaultCommandRunner) getGitlabData(baseRepo models.Repo, maybeHeadRepo *models.Repo, pullNum int) (models.PullRequest, models.Repo, error) {
+	getter := c.GitlabMergeRequestGetter
+	if hostGetter, ok := c.HostGitlabMergeRequestGetters[strings.ToLower(baseRepo.VCSHost.Hostname)]; ok {
+		getter = hostGetter
 	}
-	mr, err := c.GitlabMergeRequestGetter.GetMergeRequest(baseRepo.FullName, pullNum)
+	if getter == nil {
+		return models.PullRequest{}, models.Repo{}, errors.New("Atlantis not configured to support GitLab")
+	}
+	mr, err := getter.GetMergeRequest(baseRepo.FullName, pullNum)
 	if err != nil {
-		return models.PullRequest{}, errors.Wrap(err, "making merge request API call to GitLab")
+		return models.PullRequest{}, models.Repo{}, errors.Wrap(err, "making merge request API call to GitLab")
 	}
 	pull := c.EventParser.ParseGitlabMergeRequest(mr, baseRepo)
-	return pull, nil
+	if maybeHeadRepo != nil {
+		return pull, *maybeHeadRepo, nil
+	}
+	if mr.SourceProjectID == mr.TargetProjectID {
+		return pull, baseRepo, nil
+	}
+	project, err := getter.GetProject(mr.SourceProjectID)
+	if err != nil {
+		return pull, models.Repo{}, errors.Wrap(err, "making project API call to GitLab")
+	}
+	headRepo, err := c.EventParser.ParseGitlabProject(project)
+	if err != nil {
+		return pull, headRepo, errors.Wrap(err, "extracting required fields from project data")
+	}
+	return pull, headRepo, nil
 }
 
 func (c *DefaultCommandRunner) getAzureDevopsData(baseRepo models.Repo, pullNum int) (models.PullRequest, models.Repo, error) {
@@ -278,7 +568,7 @@ func (c *DefaultCommandRunner) ensureValidRepoMetadata(
 	case models.Github:
 		pull, headRepo, err = c.getGithubData(baseRepo, pullNum)
 	case models.Gitlab:
-		pull, err = c.getGitlabData(baseRepo, pullNum)
+		pull, headRepo, err = c.getGitlabData(baseRepo, maybeHeadRepo, pullNum)
 	case models.BitbucketCloud, models.BitbucketServer:
 		if maybePull == nil {
 			err = errors.New("pull request should not be nil–this is a bug")
@@ -307,7 +597,7 @@ func (c *DefaultCommandRunner) validateCtxAndComment(ctx *CommandContext) bool {
 			return false
 		}
 		ctx.Log.Info("command was run on a fork pull request which is disallowed")
-		if err := c.VCSClient.CreateComment(ctx.Pull.BaseRepo, ctx.Pull.Num, fmt.Sprintf("Atlantis commands can't be run on fork pull requests. To enable, set --%s  or, to disable this message, set --%s", c.AllowForkPRsFlag, c.SilenceForkPRErrorsFlag), ""); err != nil {
+		if err := commentOnPull(c.VCSClient, ctx, fmt.Sprintf("Atlantis commands can't be run on fork pull requests. To enable, set --%s  or, to disable this message, set --%s", c.AllowForkPRsFlag, c.SilenceForkPRErrorsFlag), ""); err != nil {
 			ctx.Log.Err("unable to comment: %s", err)
 		}
 		return false
@@ -315,7 +605,7 @@ func (c *DefaultCommandRunner) validateCtxAndComment(ctx *CommandContext) bool {
 
 	if ctx.Pull.State != models.OpenPullState {
 		ctx.Log.Info("command was run on closed pull request")
-		if err := c.VCSClient.CreateComment(ctx.Pull.BaseRepo, ctx.Pull.Num, "Atlantis commands can't be run on closed pull requests", ""); err != nil {
+		if err := commentOnPull(c.VCSClient, ctx, "Atlantis commands can't be run on closed pull requests", ""); err != nil {
 			ctx.Log.Err("unable to comment: %s", err)
 		}
 		return false
@@ -323,6 +613,168 @@ func (c *DefaultCommandRunner) validateCtxAndComment(ctx *CommandContext) bool {
 	return true
 }
 
+// validateTeamAllowed returns false and comments on the pull request if cmd
+// changes infrastructure, or can interrupt changes, and the user isn't a
+// member of any of the teams allowed to apply on this repo.
+func (c *DefaultCommandRunner) validateTeamAllowed(ctx *CommandContext, cmd *CommentCommand) bool {
+	// Cancel is restricted too since it can interrupt applies.
+	switch cmd.Name {
+	case models.ApplyCommand, models.ImportCommand, models.StateCommand, models.CancelCommand, models.CustomCommand:
+	default:
+		return true
+	}
+	teams := c.GlobalCfg.Get().AllowedApplyTeams(ctx.Pull.BaseRepo.ID(), ctx.Pull.BaseBranch)
+	if len(teams) == 0 {
+		return true
+	}
+
+	isMember, err := c.VCSClient.UserIsTeamMember(ctx.Pull.BaseRepo, ctx.User, teams)
+	if err != nil {
+		ctx.Log.Err("unable to check team membership for %s: %s", ctx.User.Username, err)
+	}
+	if isMember {
+		return true
+	}
+
+	ctx.Log.Info("user %s is not a member of any of the teams allowed to run %s: %s", ctx.User.Username, cmd.DisplayName(), strings.Join(teams, ", "))
+	comment := c.Localizer.Sprintf(ctx.Pull.BaseRepo, ctx.Pull.BaseBranch, i18n.UserNotInApplyTeams, ctx.User.Username, cmd.DisplayName(), strings.Join(teams, "`, `"))
+	if err := commentOnPull(c.VCSClient, ctx, comment, cmd.Name.String()); err != nil {
+		ctx.Log.Err("unable to comment: %s", err)
+	}
+	return false
+}
+
+// repoRestrictions returns the restrictions the repo allowlist places on repo.
+func (c *DefaultCommandRunner) repoRestrictions(repo models.Repo) RepoRestrictions {
+	if c.RepoAllowlistChecker == nil {
+		return RepoRestrictions{}
+	}
+	return c.RepoAllowlistChecker.Restrictions(repo.FullName, repo.VCSHost.Hostname)
+}
+
+// validateRepoRestrictions returns false and comments on the pull request if
+// cmd changes infrastructure and the repo is restricted to planning.
+func (c *DefaultCommandRunner) validateRepoRestrictions(ctx *CommandContext, cmd *CommentCommand, restrictions RepoRestrictions) bool {
+	if !restrictions.PlanOnly {
+		return true
+	}
+	switch cmd.Name {
+	case models.ApplyCommand, models.ImportCommand, models.StateCommand, models.CustomCommand:
+	default:
+		return true
+	}
+
+	ctx.Log.Info("not running %s because the repo allowlist restricts this repo to %s", cmd.DisplayName(), PlanOnlyRestriction)
+	comment := c.Localizer.Sprintf(ctx.Pull.BaseRepo, ctx.Pull.BaseBranch, i18n.CommandPlanOnly, cmd.DisplayName())
+	if err := commentOnPull(c.VCSClient, ctx, comment, cmd.Name.String()); err != nil {
+		ctx.Log.Err("unable to comment: %s", err)
+	}
+	return false
+}
+
+// startRepoOp starts an operation for the pull request's repo. It returns
+// false and comments on the pull request if the repo already has as many
+// operations in progress as its max-parallel restriction allows. If it
+// returns true, repoOpDone must be called once the operation is complete.
+func (c *DefaultCommandRunner) startRepoOp(ctx *CommandContext, restrictions RepoRestrictions, cmdName models.CommandName) bool {
+	if c.RepoOpLimiter == nil {
+		return true
+	}
+	if c.RepoOpLimiter.StartOp(ctx.Pull.BaseRepo.FullName, restrictions.MaxParallel) {
+		return true
+	}
+
+	ctx.Log.Info("not running %s because %d operations are already in progress for this repo", cmdName.String(), restrictions.MaxParallel)
+	comment := c.Localizer.Sprintf(ctx.Pull.BaseRepo, ctx.Pull.BaseBranch, i18n.RepoMaxParallel, restrictions.MaxParallel)
+	if err := commentOnPull(c.VCSClient, ctx, comment, cmdName.String()); err != nil {
+		ctx.Log.Err("unable to comment: %s", err)
+	}
+	return false
+}
+
+// repoOpDone marks an operation started with startRepoOp as complete.
+func (c *DefaultCommandRunner) repoOpDone(repo models.Repo) {
+	if c.RepoOpLimiter != nil {
+		c.RepoOpLimiter.OpDone(repo.FullName)
+	}
+}
+
+// checkDiskUsage returns false and comments on the pull request if the data
+// dir is using more disk than allowed.
+func (c *DefaultCommandRunner) checkDiskUsage(ctx *CommandContext, cmdName models.CommandName) bool {
+	if c.DiskUsageLimiter == nil {
+		return true
+	}
+	exceeded, used := c.DiskUsageLimiter.Exceeded()
+	if !exceeded {
+		return true
+	}
+
+	ctx.Log.Warn("not running %s because the data dir is using %d bytes which is over the limit of %d", cmdName.String(), used, c.DiskUsageLimiter.MaxBytes)
+	comment := c.Localizer.Sprintf(ctx.Pull.BaseRepo, ctx.Pull.BaseBranch, i18n.LowDiskSpace, formatBytes(used), formatBytes(c.DiskUsageLimiter.MaxBytes))
+	if err := commentOnPull(c.VCSClient, ctx, comment, cmdName.String()); err != nil {
+		ctx.Log.Err("unable to comment: %s", err)
+	}
+	return false
+}
+
+// recordAudit records that cmd was run if auditing is enabled. rejected is
+// true if the command wasn't allowed to run.
+func (c *DefaultCommandRunner) recordAudit(ctx *CommandContext, cmd *CommentCommand, start time.Time, rejected bool) {
+	if c.Auditor == nil {
+		return
+	}
+	event := models.AuditEvent{
+		Time:        start,
+		DurationMS:  time.Since(start).Milliseconds(),
+		Username:    ctx.User.Username,
+		Repo:        ctx.Pull.BaseRepo.FullName,
+		PullNum:     ctx.Pull.Num,
+		Command:     cmd.DisplayName(),
+		RepoRelDir:  cmd.RepoRelDir,
+		Workspace:   cmd.Workspace,
+		ProjectName: cmd.ProjectName,
+		Flags:       cmd.Flags,
+		Targets:     cmd.Targets,
+		Targeted:    len(cmd.Targets) > 0,
+		Result:      models.AuditResultUnknown,
+	}
+	if rejected {
+		event.Result = models.AuditResultRejected
+	} else if res := ctx.Result; res != nil {
+		event.Result = models.AuditResultSuccess
+		if res.HasErrors() {
+			event.Result = models.AuditResultFailure
+		}
+		if res.Error != nil {
+			event.Error = res.Error.Error()
+		} else if res.Failure != "" {
+			event.Error = res.Failure
+		}
+		for _, r := range res.ProjectResults {
+			p := models.AuditProjectEvent{
+				RepoRelDir:  r.RepoRelDir,
+				Workspace:   r.Workspace,
+				ProjectName: r.ProjectName,
+				Targets:     r.Targets,
+				Result:      models.AuditResultSuccess,
+			}
+			if len(r.Targets) > 0 {
+				event.Targeted = true
+			}
+			if r.Error != nil {
+				p.Result = models.AuditResultFailure
+				p.Error = r.Error.Error()
+			} else if r.Failure != "" {
+				p.Result = models.AuditResultFailure
+				p.Error = r.Failure
+			}
+			event.Projects = append(event.Projects, p)
+		}
+	}
+	c.Auditor.Record(ctx.Log, event)
+}
+
 // logPanics logs and creates a comment on the pull request for panics.
 func (c *DefaultCommandRunner) logPanics(baseRepo models.Repo, pullNum int, logger logging.SimpleLogging) {
 	if err := recover(); err != nil {
@@ -338,5 +790,3 @@ func (c *DefaultCommandRunner) logPanics(baseRepo models.Repo, pullNum int, logg
 		}
 	}
 }
-
-var automergeComment = `Automatically merging because all plans have been successfully applied.`
@@ -171,3 +171,9 @@ func (m *MockCSU) UpdateCombined(repo models.Repo, pull models.PullRequest, stat
 func (m *MockCSU) UpdateProject(ctx models.ProjectCommandContext, cmdName models.CommandName, status models.CommitStatus, url string) error {
 	return nil
 }
+func (m *MockCSU) UpdateProjectResult(ctx models.ProjectCommandContext, result models.ProjectResult, url string) error {
+	return nil
+}
+func (m *MockCSU) UpdateMergeConflict(repo models.Repo, pull models.PullRequest, command models.CommandName) error {
+	return nil
+}
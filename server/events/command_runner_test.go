@@ -14,10 +14,15 @@
 package events_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/runatlantis/atlantis/server/core/db"
 	"github.com/runatlantis/atlantis/server/events/yaml/valid"
@@ -27,6 +32,9 @@ import (
 	. "github.com/petergtz/pegomock"
 	lockingmocks "github.com/runatlantis/atlantis/server/core/locking/mocks"
 	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/audit"
+	auditmocks "github.com/runatlantis/atlantis/server/events/audit/mocks"
+	auditmatchers "github.com/runatlantis/atlantis/server/events/audit/mocks/matchers"
 	"github.com/runatlantis/atlantis/server/events/mocks"
 	eventmocks "github.com/runatlantis/atlantis/server/events/mocks"
 	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
@@ -34,6 +42,7 @@ import (
 	"github.com/runatlantis/atlantis/server/events/models/fixtures"
 	vcsmocks "github.com/runatlantis/atlantis/server/events/vcs/mocks"
 	. "github.com/runatlantis/atlantis/testing"
+	gitlab "github.com/xanzy/go-gitlab"
 )
 
 var projectCommandBuilder *mocks.MockProjectCommandBuilder
@@ -61,6 +70,7 @@ var planCommandRunner *events.PlanCommandRunner
 var applyLockChecker *lockingmocks.MockApplyLockChecker
 var applyCommandRunner *events.ApplyCommandRunner
 var unlockCommandRunner *events.UnlockCommandRunner
+var commandCanceller *events.CommandCanceller
 var preWorkflowHooksCommandRunner events.PreWorkflowHooksCommandRunner
 
 func setup(t *testing.T) *vcsmocks.MockClient {
@@ -152,6 +162,7 @@ func setup(t *testing.T) *vcsmocks.MockClient {
 		projectCommandRunner,
 		pullUpdater,
 		dbUpdater,
+		vcsClient,
 		SilenceNoProjects,
 		false,
 	)
@@ -162,6 +173,8 @@ func setup(t *testing.T) *vcsmocks.MockClient {
 		SilenceNoProjects,
 	)
 
+	commandCanceller = events.NewCommandCanceller()
+
 	versionCommandRunner := events.NewVersionCommandRunner(
 		pullUpdater,
 		projectCommandBuilder,
@@ -176,6 +189,7 @@ func setup(t *testing.T) *vcsmocks.MockClient {
 		models.ApprovePoliciesCommand: approvePoliciesCommandRunner,
 		models.UnlockCommand:          unlockCommandRunner,
 		models.VersionCommand:         versionCommandRunner,
+		models.CancelCommand:          events.NewCancelCommandRunner(commandCanceller, vcsClient),
 	}
 
 	preWorkflowHooksCommandRunner = mocks.NewMockPreWorkflowHooksCommandRunner()
@@ -195,6 +209,8 @@ func setup(t *testing.T) *vcsmocks.MockClient {
 		Drainer:                       drainer,
 		PreWorkflowHooksCommandRunner: preWorkflowHooksCommandRunner,
 		PullStatusFetcher:             defaultBoltDB,
+		GlobalCfg:                     valid.NewGlobalCfgStore(valid.GlobalCfg{}),
+		CommandCanceller:              commandCanceller,
 	}
 	return vcsClient
 }
@@ -203,7 +219,7 @@ func TestRunCommentCommand_LogPanics(t *testing.T) {
 	t.Log("if there is a panic it is commented back on the pull request")
 	vcsClient := setup(t)
 	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenPanic("panic test - if you're seeing this in a test failure this isn't the failing test")
-	ch.RunCommentCommand(fixtures.GithubRepo, &fixtures.GithubRepo, nil, fixtures.User, 1, &events.CommentCommand{Name: models.PlanCommand})
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, &fixtures.GithubRepo, nil, fixtures.User, 1, &events.CommentCommand{Name: models.PlanCommand})
 	_, _, comment, _ := vcsClient.VerifyWasCalledOnce().CreateComment(matchers.AnyModelsRepo(), AnyInt(), AnyString(), AnyString()).GetCapturedArguments()
 	Assert(t, strings.Contains(comment, "Error: goroutine panic"), fmt.Sprintf("comment should be about a goroutine panic but was %q", comment))
 }
@@ -212,7 +228,7 @@ func TestRunCommentCommand_GithubPullErr(t *testing.T) {
 	t.Log("if getting the github pull request fails an error should be logged")
 	vcsClient := setup(t)
 	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(nil, errors.New("err"))
-	ch.RunCommentCommand(fixtures.GithubRepo, &fixtures.GithubRepo, nil, fixtures.User, fixtures.Pull.Num, nil)
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, &fixtures.GithubRepo, nil, fixtures.User, fixtures.Pull.Num, nil)
 	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GithubRepo, fixtures.Pull.Num, "`Error: making pull request API call to GitHub: err`", "")
 }
 
@@ -220,10 +236,20 @@ func TestRunCommentCommand_GitlabMergeRequestErr(t *testing.T) {
 	t.Log("if getting the gitlab merge request fails an error should be logged")
 	vcsClient := setup(t)
 	When(gitlabGetter.GetMergeRequest(fixtures.GitlabRepo.FullName, fixtures.Pull.Num)).ThenReturn(nil, errors.New("err"))
-	ch.RunCommentCommand(fixtures.GitlabRepo, &fixtures.GitlabRepo, nil, fixtures.User, fixtures.Pull.Num, nil)
+	ch.RunCommentCommand(context.Background(), fixtures.GitlabRepo, &fixtures.GitlabRepo, nil, fixtures.User, fixtures.Pull.Num, nil)
 	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GitlabRepo, fixtures.Pull.Num, "`Error: making merge request API call to GitLab: err`", "")
 }
 
+func TestRunCommentCommand_GitlabForkErr(t *testing.T) {
+	t.Log("if the head repo isn't known, the fork a merge request is from should be fetched")
+	vcsClient := setup(t)
+	When(gitlabGetter.GetMergeRequest(fixtures.GitlabRepo.FullName, fixtures.Pull.Num)).
+		ThenReturn(&gitlab.MergeRequest{SourceProjectID: 2, TargetProjectID: 1}, nil)
+	When(gitlabGetter.GetProject(2)).ThenReturn(nil, errors.New("err"))
+	ch.RunCommentCommand(context.Background(), fixtures.GitlabRepo, nil, nil, fixtures.User, fixtures.Pull.Num, nil)
+	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GitlabRepo, fixtures.Pull.Num, "`Error: making project API call to GitLab: err`", "")
+}
+
 func TestRunCommentCommand_GithubPullParseErr(t *testing.T) {
 	t.Log("if parsing the returned github pull request fails an error should be logged")
 	vcsClient := setup(t)
@@ -231,7 +257,7 @@ func TestRunCommentCommand_GithubPullParseErr(t *testing.T) {
 	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(&pull, nil)
 	When(eventParsing.ParseGithubPull(&pull)).ThenReturn(fixtures.Pull, fixtures.GithubRepo, fixtures.GitlabRepo, errors.New("err"))
 
-	ch.RunCommentCommand(fixtures.GithubRepo, &fixtures.GithubRepo, nil, fixtures.User, fixtures.Pull.Num, nil)
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, &fixtures.GithubRepo, nil, fixtures.User, fixtures.Pull.Num, nil)
 	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GithubRepo, fixtures.Pull.Num, "`Error: extracting required fields from comment data: err`", "")
 }
 
@@ -254,7 +280,7 @@ func TestRunCommentCommand_ForkPRDisabled(t *testing.T) {
 	headRepo.Owner = "forkrepo"
 	When(eventParsing.ParseGithubPull(&pull)).ThenReturn(modelPull, modelPull.BaseRepo, headRepo, nil)
 
-	ch.RunCommentCommand(fixtures.GithubRepo, nil, nil, fixtures.User, fixtures.Pull.Num, nil)
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, nil, nil, fixtures.User, fixtures.Pull.Num, nil)
 	commentMessage := fmt.Sprintf("Atlantis commands can't be run on fork pull requests. To enable, set --%s  or, to disable this message, set --%s", ch.AllowForkPRsFlag, ch.SilenceForkPRErrorsFlag)
 	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GithubRepo, modelPull.Num, commentMessage, "")
 }
@@ -273,7 +299,7 @@ func TestRunCommentCommand_ForkPRDisabled_SilenceEnabled(t *testing.T) {
 	headRepo.Owner = "forkrepo"
 	When(eventParsing.ParseGithubPull(&pull)).ThenReturn(modelPull, modelPull.BaseRepo, headRepo, nil)
 
-	ch.RunCommentCommand(fixtures.GithubRepo, nil, nil, fixtures.User, fixtures.Pull.Num, nil)
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, nil, nil, fixtures.User, fixtures.Pull.Num, nil)
 	vcsClient.VerifyWasCalled(Never()).CreateComment(matchers.AnyModelsRepo(), AnyInt(), AnyString(), AnyString())
 }
 
@@ -286,7 +312,7 @@ func TestRunCommentCommandPlan_NoProjects_SilenceEnabled(t *testing.T) {
 	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(&pull, nil)
 	When(eventParsing.ParseGithubPull(&pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
 
-	ch.RunCommentCommand(fixtures.GithubRepo, nil, nil, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.PlanCommand})
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, nil, nil, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.PlanCommand})
 	vcsClient.VerifyWasCalled(Never()).CreateComment(matchers.AnyModelsRepo(), AnyInt(), AnyString(), AnyString())
 	commitUpdater.VerifyWasCalledOnce().UpdateCombinedCount(
 		matchers.AnyModelsRepo(),
@@ -307,7 +333,7 @@ func TestRunCommentCommandApply_NoProjects_SilenceEnabled(t *testing.T) {
 	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(&pull, nil)
 	When(eventParsing.ParseGithubPull(&pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
 
-	ch.RunCommentCommand(fixtures.GithubRepo, nil, nil, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.ApplyCommand})
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, nil, nil, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.ApplyCommand})
 	vcsClient.VerifyWasCalled(Never()).CreateComment(matchers.AnyModelsRepo(), AnyInt(), AnyString(), AnyString())
 	commitUpdater.VerifyWasCalledOnce().UpdateCombinedCount(
 		matchers.AnyModelsRepo(),
@@ -328,7 +354,7 @@ func TestRunCommentCommandApprovePolicy_NoProjects_SilenceEnabled(t *testing.T)
 	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(&pull, nil)
 	When(eventParsing.ParseGithubPull(&pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
 
-	ch.RunCommentCommand(fixtures.GithubRepo, nil, nil, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.ApprovePoliciesCommand})
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, nil, nil, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.ApprovePoliciesCommand})
 	vcsClient.VerifyWasCalled(Never()).CreateComment(matchers.AnyModelsRepo(), AnyInt(), AnyString(), AnyString())
 	commitUpdater.VerifyWasCalledOnce().UpdateCombinedCount(
 		matchers.AnyModelsRepo(),
@@ -349,7 +375,7 @@ func TestRunCommentCommandUnlock_NoProjects_SilenceEnabled(t *testing.T) {
 	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(&pull, nil)
 	When(eventParsing.ParseGithubPull(&pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
 
-	ch.RunCommentCommand(fixtures.GithubRepo, nil, nil, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.UnlockCommand})
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, nil, nil, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.UnlockCommand})
 	vcsClient.VerifyWasCalled(Never()).CreateComment(matchers.AnyModelsRepo(), AnyInt(), AnyString(), AnyString())
 }
 
@@ -365,7 +391,57 @@ func TestRunCommentCommand_DisableApplyAllDisabled(t *testing.T) {
 	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(pull, nil)
 	When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
 
-	ch.RunCommentCommand(fixtures.GithubRepo, nil, nil, fixtures.User, modelPull.Num, &events.CommentCommand{Name: models.ApplyCommand})
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, nil, nil, fixtures.User, modelPull.Num, &events.CommentCommand{Name: models.ApplyCommand})
+	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GithubRepo, modelPull.Num, "**Error:** Running `atlantis apply` without flags is disabled. You must specify which project to apply via the `-d <dir>`, `-w <workspace>` or `-p <project name>` flags.", "apply")
+}
+
+func TestRunCommentCommand_ApplyTeamNotAllowed(t *testing.T) {
+	t.Log("if \"atlantis apply\" is run by a user who isn't in an allowed team" +
+		" atlantis should comment saying that this is not allowed")
+	vcsClient := setup(t)
+	ch.GlobalCfg.Set(valid.GlobalCfg{
+		Repos: []valid.Repo{
+			{
+				IDRegex:           regexp.MustCompile(".*"),
+				AllowedApplyTeams: []string{"platform", "sre"},
+			},
+		},
+	})
+	pull := &github.PullRequest{
+		State: github.String("open"),
+	}
+	modelPull := models.PullRequest{BaseRepo: fixtures.GithubRepo, State: models.OpenPullState, Num: fixtures.Pull.Num}
+	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(pull, nil)
+	When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
+	When(vcsClient.UserIsTeamMember(fixtures.GithubRepo, fixtures.User, []string{"platform", "sre"})).ThenReturn(false, nil)
+
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, nil, nil, fixtures.User, modelPull.Num, &events.CommentCommand{Name: models.ApplyCommand})
+	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GithubRepo, modelPull.Num, "**Error:** User `lkysow` is not allowed to run `atlantis apply` on this repo. Only members of these teams can: `platform`, `sre`.", "apply")
+}
+
+func TestRunCommentCommand_ApplyTeamAllowed(t *testing.T) {
+	t.Log("if \"atlantis apply\" is run by a user in an allowed team" +
+		" atlantis should run the apply")
+	vcsClient := setup(t)
+	applyCommandRunner.DisableApplyAll = true
+	ch.GlobalCfg.Set(valid.GlobalCfg{
+		Repos: []valid.Repo{
+			{
+				IDRegex:           regexp.MustCompile(".*"),
+				AllowedApplyTeams: []string{"platform"},
+			},
+		},
+	})
+	pull := &github.PullRequest{
+		State: github.String("open"),
+	}
+	modelPull := models.PullRequest{BaseRepo: fixtures.GithubRepo, State: models.OpenPullState, Num: fixtures.Pull.Num}
+	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(pull, nil)
+	When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
+	When(vcsClient.UserIsTeamMember(fixtures.GithubRepo, fixtures.User, []string{"platform"})).ThenReturn(true, nil)
+
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, nil, nil, fixtures.User, modelPull.Num, &events.CommentCommand{Name: models.ApplyCommand})
+	// The apply command runner was reached so its own disabled comment is made.
 	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GithubRepo, modelPull.Num, "**Error:** Running `atlantis apply` without flags is disabled. You must specify which project to apply via the `-d <dir>`, `-w <workspace>` or `-p <project name>` flags.", "apply")
 }
 
@@ -385,7 +461,7 @@ func TestRunCommentCommand_DisableDisableAutoplan(t *testing.T) {
 			},
 		}, nil)
 
-	ch.RunAutoplanCommand(fixtures.GithubRepo, fixtures.GithubRepo, fixtures.Pull, fixtures.User)
+	ch.RunAutoplanCommand(context.Background(), fixtures.GithubRepo, fixtures.GithubRepo, fixtures.Pull, fixtures.User)
 	projectCommandBuilder.VerifyWasCalled(Never()).BuildAutoplanCommands(matchers.AnyPtrToEventsCommandContext())
 }
 
@@ -400,10 +476,89 @@ func TestRunCommentCommand_ClosedPull(t *testing.T) {
 	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(pull, nil)
 	When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
 
-	ch.RunCommentCommand(fixtures.GithubRepo, &fixtures.GithubRepo, nil, fixtures.User, fixtures.Pull.Num, nil)
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, &fixtures.GithubRepo, nil, fixtures.User, fixtures.Pull.Num, nil)
 	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GithubRepo, modelPull.Num, "Atlantis commands can't be run on closed pull requests", "")
 }
 
+func TestRunCommentCommand_Audit(t *testing.T) {
+	t.Log("the command, who ran it and its result should be audited")
+	setup(t)
+	exporter := auditmocks.NewMockExporter()
+	ch.Auditor = &audit.MultiExporter{Exporters: []audit.Exporter{exporter}}
+	var pull github.PullRequest
+	modelPull := models.PullRequest{BaseRepo: fixtures.GithubRepo, State: models.OpenPullState, Num: fixtures.Pull.Num}
+	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(&pull, nil)
+	When(eventParsing.ParseGithubPull(&pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
+
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, nil, nil, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.PlanCommand, RepoRelDir: "dir", Workspace: "default"})
+
+	event := exporter.VerifyWasCalledOnce().Export(auditmatchers.AnyModelsAuditEvent()).GetCapturedArguments()
+	Assert(t, !event.Time.IsZero(), "exp time to be set")
+	event.Time = time.Time{}
+	event.DurationMS = 0
+	Equals(t, models.AuditEvent{
+		Username:   fixtures.User.Username,
+		Repo:       fixtures.GithubRepo.FullName,
+		PullNum:    fixtures.Pull.Num,
+		Command:    "plan",
+		RepoRelDir: "dir",
+		Workspace:  "default",
+		Result:     models.AuditResultSuccess,
+	}, event)
+}
+
+func TestRunCommentCommand_AuditTargeted(t *testing.T) {
+	t.Log("commands with --target should be audited as targeted")
+	setup(t)
+	exporter := auditmocks.NewMockExporter()
+	ch.Auditor = &audit.MultiExporter{Exporters: []audit.Exporter{exporter}}
+	var pull github.PullRequest
+	modelPull := models.PullRequest{BaseRepo: fixtures.GithubRepo, State: models.OpenPullState, Num: fixtures.Pull.Num}
+	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(&pull, nil)
+	When(eventParsing.ParseGithubPull(&pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
+
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, nil, nil, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.PlanCommand, RepoRelDir: "dir", Workspace: "default", Targets: []string{"aws_instance.web"}})
+
+	event := exporter.VerifyWasCalledOnce().Export(auditmatchers.AnyModelsAuditEvent()).GetCapturedArguments()
+	Equals(t, []string{"aws_instance.web"}, event.Targets)
+	Equals(t, true, event.Targeted)
+}
+
+func TestRunCommentCommand_AuditRejected(t *testing.T) {
+	t.Log("commands that aren't allowed to run should be audited as rejected")
+	setup(t)
+	exporter := auditmocks.NewMockExporter()
+	ch.Auditor = &audit.MultiExporter{Exporters: []audit.Exporter{exporter}}
+	pull := &github.PullRequest{
+		State: github.String("closed"),
+	}
+	modelPull := models.PullRequest{BaseRepo: fixtures.GithubRepo, State: models.ClosedPullState, Num: fixtures.Pull.Num}
+	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(pull, nil)
+	When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
+
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, &fixtures.GithubRepo, nil, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.ApplyCommand})
+
+	event := exporter.VerifyWasCalledOnce().Export(auditmatchers.AnyModelsAuditEvent()).GetCapturedArguments()
+	Equals(t, "apply", event.Command)
+	Equals(t, models.AuditResultRejected, event.Result)
+}
+
+func TestRunCommentCommand_AuditPullErr(t *testing.T) {
+	t.Log("commands that fail before the pull request is fetched should still be audited")
+	setup(t)
+	exporter := auditmocks.NewMockExporter()
+	ch.Auditor = &audit.MultiExporter{Exporters: []audit.Exporter{exporter}}
+	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(nil, errors.New("err"))
+
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, &fixtures.GithubRepo, nil, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.PlanCommand})
+
+	event := exporter.VerifyWasCalledOnce().Export(auditmatchers.AnyModelsAuditEvent()).GetCapturedArguments()
+	Equals(t, fixtures.GithubRepo.FullName, event.Repo)
+	Equals(t, fixtures.Pull.Num, event.PullNum)
+	Equals(t, models.AuditResultFailure, event.Result)
+	Equals(t, "making pull request API call to GitHub: err", event.Error)
+}
+
 func TestRunUnlockCommand_VCSComment(t *testing.T) {
 	t.Log("if unlock PR command is run, atlantis should" +
 		" invoke the delete command and comment on PR accordingly")
@@ -416,7 +571,7 @@ func TestRunUnlockCommand_VCSComment(t *testing.T) {
 	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(pull, nil)
 	When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
 
-	ch.RunCommentCommand(fixtures.GithubRepo, &fixtures.GithubRepo, nil, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.UnlockCommand})
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, &fixtures.GithubRepo, nil, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.UnlockCommand})
 
 	deleteLockCommand.VerifyWasCalledOnce().DeleteLocksByPull(fixtures.GithubRepo.FullName, fixtures.Pull.Num)
 	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GithubRepo, fixtures.Pull.Num, "All Atlantis locks for this PR have been unlocked and plans discarded", "unlock")
@@ -435,11 +590,48 @@ func TestRunUnlockCommandFail_VCSComment(t *testing.T) {
 	When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
 	When(deleteLockCommand.DeleteLocksByPull(fixtures.GithubRepo.FullName, fixtures.Pull.Num)).ThenReturn(0, errors.New("err"))
 
-	ch.RunCommentCommand(fixtures.GithubRepo, &fixtures.GithubRepo, nil, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.UnlockCommand})
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, &fixtures.GithubRepo, nil, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.UnlockCommand})
 
 	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GithubRepo, fixtures.Pull.Num, "Failed to delete PR locks", "unlock")
 }
 
+func TestRunUnlockCommand_Project(t *testing.T) {
+	t.Log("if unlock is run with -d and -w, atlantis should only delete the" +
+		" locks for that project and comment on PR accordingly")
+
+	vcsClient := setup(t)
+	pull := &github.PullRequest{
+		State: github.String("open"),
+	}
+	modelPull := models.PullRequest{BaseRepo: fixtures.GithubRepo, State: models.OpenPullState, Num: fixtures.Pull.Num}
+	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(pull, nil)
+	When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
+	When(deleteLockCommand.DeleteProjectLocksByPull(fixtures.GithubRepo.FullName, fixtures.Pull.Num, "dir", "staging")).ThenReturn(1, nil)
+
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, &fixtures.GithubRepo, nil, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.UnlockCommand, RepoRelDir: "dir", Workspace: "staging"})
+
+	deleteLockCommand.VerifyWasCalled(Never()).DeleteLocksByPull(AnyString(), AnyInt())
+	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GithubRepo, fixtures.Pull.Num, "Atlantis locks for dir `dir` and workspace `staging` have been unlocked and plans discarded", "unlock")
+}
+
+func TestRunUnlockCommand_ProjectNoLocks(t *testing.T) {
+	t.Log("if unlock is run with -d and there are no matching locks, atlantis" +
+		" should say so")
+
+	vcsClient := setup(t)
+	pull := &github.PullRequest{
+		State: github.String("open"),
+	}
+	modelPull := models.PullRequest{BaseRepo: fixtures.GithubRepo, State: models.OpenPullState, Num: fixtures.Pull.Num}
+	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(pull, nil)
+	When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
+	When(deleteLockCommand.DeleteProjectLocksByPull(fixtures.GithubRepo.FullName, fixtures.Pull.Num, "dir", "")).ThenReturn(0, nil)
+
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, &fixtures.GithubRepo, nil, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.UnlockCommand, RepoRelDir: "dir"})
+
+	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GithubRepo, fixtures.Pull.Num, "No Atlantis locks found for dir `dir` in this PR", "unlock")
+}
+
 // Test that if one plan fails and we are using automerge, that
 // we delete the plans.
 func TestRunAutoplanCommand_DeletePlans(t *testing.T) {
@@ -484,10 +676,77 @@ func TestRunAutoplanCommand_DeletePlans(t *testing.T) {
 	When(workingDir.GetPullDir(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest())).
 		ThenReturn(tmp, nil)
 	fixtures.Pull.BaseRepo = fixtures.GithubRepo
-	ch.RunAutoplanCommand(fixtures.GithubRepo, fixtures.GithubRepo, fixtures.Pull, fixtures.User)
+	ch.RunAutoplanCommand(context.Background(), fixtures.GithubRepo, fixtures.GithubRepo, fixtures.Pull, fixtures.User)
 	pendingPlanFinder.VerifyWasCalledOnce().DeletePlans(tmp)
 }
 
+func TestRunAutoplanCommand_MergeConflict(t *testing.T) {
+	vcsClient := setup(t)
+	When(projectCommandBuilder.BuildAutoplanCommands(matchers.AnyPtrToEventsCommandContext())).
+		ThenReturn(nil, &events.MergeConflictError{BaseBranch: "main", HeadBranch: "branch", Files: []string{"main.tf"}})
+	fixtures.Pull.BaseRepo = fixtures.GithubRepo
+	ch.RunAutoplanCommand(context.Background(), fixtures.GithubRepo, fixtures.GithubRepo, fixtures.Pull, fixtures.User)
+
+	t.Log("terraform isn't run and the conflict is reported")
+	projectCommandRunner.VerifyWasCalled(Never()).Plan(matchers.AnyModelsProjectCommandContext())
+	commitUpdater.VerifyWasCalledOnce().UpdateMergeConflict(fixtures.GithubRepo, fixtures.Pull, models.PlanCommand)
+	commitUpdater.VerifyWasCalled(Never()).UpdateCombined(fixtures.GithubRepo, fixtures.Pull, models.FailedCommitStatus, models.PlanCommand)
+	_, _, comment, _ := vcsClient.VerifyWasCalledOnce().CreateComment(matchers.AnyModelsRepo(), AnyInt(), AnyString(), AnyString()).GetCapturedArguments()
+	Assert(t, strings.Contains(comment, "**Plan Failed**: cannot merge main into branch because of conflicts in main.tf."), "unexpected comment: %s", comment)
+}
+
+func TestRunAutoplanCommand_SilenceNoChangePlans(t *testing.T) {
+	noChanges := models.ProjectResult{
+		RepoRelDir:  "unchanged",
+		Workspace:   "default",
+		PlanSuccess: &models.PlanSuccess{TerraformOutput: "No changes. Your infrastructure matches the configuration."},
+	}
+	changes := models.ProjectResult{
+		RepoRelDir:  "changed",
+		Workspace:   "default",
+		PlanSuccess: &models.PlanSuccess{TerraformOutput: "Plan: 1 to add, 0 to change, 0 to destroy."},
+	}
+	cases := []struct {
+		description string
+		results     []models.ProjectResult
+		expComment  bool
+	}{
+		{"some plans have changes", []models.ProjectResult{noChanges, changes}, true},
+		{"no plans have changes", []models.ProjectResult{noChanges, noChanges}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			vcsClient := setup(t)
+			pullUpdater.SilenceNoChangePlans = true
+			tmp, cleanup := TempDir(t)
+			defer cleanup()
+			boltDB, err := db.New(tmp)
+			Ok(t, err)
+			dbUpdater.DB = boltDB
+
+			When(projectCommandBuilder.BuildAutoplanCommands(matchers.AnyPtrToEventsCommandContext())).
+				ThenReturn([]models.ProjectCommandContext{{CommandName: models.PlanCommand}, {CommandName: models.PlanCommand}}, nil)
+			callCount := 0
+			When(projectCommandRunner.Plan(matchers.AnyModelsProjectCommandContext())).Then(func(_ []Param) ReturnValues {
+				callCount++
+				return ReturnValues{c.results[callCount-1]}
+			})
+			fixtures.Pull.BaseRepo = fixtures.GithubRepo
+			ch.RunAutoplanCommand(context.Background(), fixtures.GithubRepo, fixtures.GithubRepo, fixtures.Pull, fixtures.User)
+
+			t.Log("the plans are still counted as successful")
+			commitUpdater.VerifyWasCalledOnce().UpdateCombinedCount(fixtures.GithubRepo, fixtures.Pull, models.SuccessCommitStatus, models.PlanCommand, 2, 2)
+			if !c.expComment {
+				vcsClient.VerifyWasCalled(Never()).CreateComment(matchers.AnyModelsRepo(), AnyInt(), AnyString(), AnyString())
+				return
+			}
+			_, _, comment, _ := vcsClient.VerifyWasCalledOnce().CreateComment(matchers.AnyModelsRepo(), AnyInt(), AnyString(), AnyString()).GetCapturedArguments()
+			Assert(t, strings.Contains(comment, "dir: `changed`"), "unexpected comment: %s", comment)
+			Assert(t, !strings.Contains(comment, "unchanged"), "unexpected comment: %s", comment)
+		})
+	}
+}
+
 func TestFailedApprovalCreatesFailedStatusUpdate(t *testing.T) {
 	t.Log("if \"atlantis approve_policies\" is run by non policy owner policy check status fails.")
 	setup(t)
@@ -523,7 +782,7 @@ func TestFailedApprovalCreatesFailedStatusUpdate(t *testing.T) {
 
 	When(workingDir.GetPullDir(fixtures.GithubRepo, fixtures.Pull)).ThenReturn(tmp, nil)
 
-	ch.RunCommentCommand(fixtures.GithubRepo, &fixtures.GithubRepo, &fixtures.Pull, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.ApprovePoliciesCommand})
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, &fixtures.GithubRepo, &fixtures.Pull, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.ApprovePoliciesCommand})
 	commitUpdater.VerifyWasCalledOnce().UpdateCombinedCount(
 		matchers.AnyModelsRepo(),
 		matchers.AnyModelsPullRequest(),
@@ -579,7 +838,57 @@ func TestApprovedPoliciesUpdateFailedPolicyStatus(t *testing.T) {
 		}
 	})
 
-	ch.RunCommentCommand(fixtures.GithubRepo, &fixtures.GithubRepo, &fixtures.Pull, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.ApprovePoliciesCommand})
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, &fixtures.GithubRepo, &fixtures.Pull, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.ApprovePoliciesCommand})
+	commitUpdater.VerifyWasCalledOnce().UpdateCombinedCount(
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsPullRequest(),
+		matchers.EqModelsCommitStatus(models.SuccessCommitStatus),
+		matchers.EqModelsCommandName(models.PolicyCheckCommand),
+		EqInt(1),
+		EqInt(1),
+	)
+}
+
+func TestApprovedPoliciesByPolicyOwnerTeam(t *testing.T) {
+	t.Log("if \"atlantis approve_policies\" is run by a member of a policy owner team all policy checks are approved.")
+	vcsClient := setup(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	dbUpdater.DB = boltDB
+	applyCommandRunner.DB = boltDB
+
+	pull := &github.PullRequest{
+		State: github.String("open"),
+	}
+	modelPull := models.PullRequest{
+		BaseRepo: fixtures.GithubRepo,
+		State:    models.OpenPullState,
+		Num:      fixtures.Pull.Num,
+	}
+	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(pull, nil)
+	When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
+	When(vcsClient.UserIsTeamMember(fixtures.GithubRepo, fixtures.User, []string{"policy-owners"})).ThenReturn(true, nil)
+
+	When(projectCommandBuilder.BuildApprovePoliciesCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand())).ThenReturn([]models.ProjectCommandContext{
+		{
+			CommandName: models.ApprovePoliciesCommand,
+			PolicySets: valid.PolicySets{
+				Owners: valid.PolicyOwners{
+					Teams: []string{"policy-owners"},
+				},
+			},
+		},
+	}, nil)
+	When(workingDir.GetPullDir(fixtures.GithubRepo, fixtures.Pull)).ThenReturn(tmp, nil)
+	When(projectCommandRunner.ApprovePolicies(matchers.AnyModelsProjectCommandContext())).ThenReturn(models.ProjectResult{
+		Command:            models.PolicyCheckCommand,
+		PolicyCheckSuccess: &models.PolicyCheckSuccess{},
+	})
+
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, &fixtures.GithubRepo, &fixtures.Pull, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.ApprovePoliciesCommand})
+	projectCommandRunner.VerifyWasCalledOnce().ApprovePolicies(matchers.AnyModelsProjectCommandContext())
 	commitUpdater.VerifyWasCalledOnce().UpdateCombinedCount(
 		matchers.AnyModelsRepo(),
 		matchers.AnyModelsPullRequest(),
@@ -642,7 +951,7 @@ func TestApplyMergeablityWhenPolicyCheckFails(t *testing.T) {
 	})
 
 	When(workingDir.GetPullDir(fixtures.GithubRepo, modelPull)).ThenReturn(tmp, nil)
-	ch.RunCommentCommand(fixtures.GithubRepo, &fixtures.GithubRepo, &modelPull, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.ApplyCommand})
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, &fixtures.GithubRepo, &modelPull, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.ApplyCommand})
 }
 
 func TestApplyWithAutoMerge_VSCMerge(t *testing.T) {
@@ -662,10 +971,93 @@ func TestApplyWithAutoMerge_VSCMerge(t *testing.T) {
 		DeleteSourceBranchOnMerge: false,
 	}
 
-	ch.RunCommentCommand(fixtures.GithubRepo, &fixtures.GithubRepo, nil, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.ApplyCommand})
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, &fixtures.GithubRepo, nil, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.ApplyCommand})
 	vcsClient.VerifyWasCalledOnce().MergePull(modelPull, pullOptions)
 }
 
+func TestApplyWithAutoMerge_MergeMethodAndCommitMessage(t *testing.T) {
+	t.Log("if the repo sets automerge_method and automerge_commit_message they're used to merge")
+	vcsClient := setup(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	dbUpdater.DB = boltDB
+	applyCommandRunner.DB = boltDB
+
+	pull := &github.PullRequest{
+		State: github.String("open"),
+	}
+	modelPull := models.PullRequest{BaseRepo: fixtures.GithubRepo, State: models.OpenPullState, Num: fixtures.Pull.Num}
+	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(pull, nil)
+	When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
+	When(workingDir.GetPullDir(fixtures.GithubRepo, modelPull)).ThenReturn(tmp, nil)
+	When(projectCommandBuilder.BuildApplyCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand())).ThenReturn([]models.ProjectCommandContext{
+		{
+			CommandName:            models.ApplyCommand,
+			Workspace:              "default",
+			RepoRelDir:             ".",
+			AutomergeEnabled:       true,
+			AutomergeMethod:        models.SquashMergeMethod,
+			AutomergeCommitMessage: "Merge {{ .RepoFullName }}#{{ .PullNum }} applied by {{ .User }}",
+		},
+	}, nil)
+	When(projectCommandRunner.Apply(matchers.AnyModelsProjectCommandContext())).ThenReturn(models.ProjectResult{
+		Command:      models.ApplyCommand,
+		Workspace:    "default",
+		RepoRelDir:   ".",
+		ApplySuccess: "success",
+	})
+
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, &fixtures.GithubRepo, &modelPull, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.ApplyCommand})
+	vcsClient.VerifyWasCalledOnce().MergePull(modelPull, models.PullRequestOptions{
+		MergeMethod:   models.SquashMergeMethod,
+		CommitMessage: "Merge runatlantis/atlantis#1 applied by lkysow",
+	})
+}
+
+func TestApplyWithAutoMerge_ProjectDisabled(t *testing.T) {
+	t.Log("if any project disables automerge then the pull request isn't merged")
+	vcsClient := setup(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	dbUpdater.DB = boltDB
+	applyCommandRunner.DB = boltDB
+
+	pull := &github.PullRequest{
+		State: github.String("open"),
+	}
+	modelPull := models.PullRequest{BaseRepo: fixtures.GithubRepo, State: models.OpenPullState, Num: fixtures.Pull.Num}
+	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(pull, nil)
+	When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
+	When(workingDir.GetPullDir(fixtures.GithubRepo, modelPull)).ThenReturn(tmp, nil)
+	When(projectCommandBuilder.BuildApplyCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand())).ThenReturn([]models.ProjectCommandContext{
+		{
+			CommandName:      models.ApplyCommand,
+			Workspace:        "default",
+			RepoRelDir:       ".",
+			AutomergeEnabled: true,
+		},
+		{
+			CommandName:      models.ApplyCommand,
+			Workspace:        "default",
+			RepoRelDir:       "noautomerge",
+			AutomergeEnabled: false,
+		},
+	}, nil)
+	When(projectCommandRunner.Apply(matchers.AnyModelsProjectCommandContext())).ThenReturn(models.ProjectResult{
+		Command:      models.ApplyCommand,
+		Workspace:    "default",
+		RepoRelDir:   ".",
+		ApplySuccess: "success",
+	})
+
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, &fixtures.GithubRepo, &modelPull, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.ApplyCommand})
+	vcsClient.VerifyWasCalled(Never()).MergePull(matchers.AnyModelsPullRequest(), matchers.AnyModelsPullRequestOptions())
+}
+
 func TestRunApply_DiscardedProjects(t *testing.T) {
 	t.Log("if \"atlantis apply\" is run with automerge and at least one project" +
 		" has a discarded plan, automerge should not take place")
@@ -700,7 +1092,7 @@ func TestRunApply_DiscardedProjects(t *testing.T) {
 	When(eventParsing.ParseGithubPull(ghPull)).ThenReturn(pull, pull.BaseRepo, fixtures.GithubRepo, nil)
 	When(workingDir.GetPullDir(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest())).
 		ThenReturn(tmp, nil)
-	ch.RunCommentCommand(fixtures.GithubRepo, &fixtures.GithubRepo, &pull, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.ApplyCommand})
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, &fixtures.GithubRepo, &pull, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.ApplyCommand})
 
 	vcsClient.VerifyWasCalled(Never()).MergePull(matchers.AnyModelsPullRequest(), matchers.AnyModelsPullRequestOptions())
 }
@@ -709,7 +1101,7 @@ func TestRunCommentCommand_DrainOngoing(t *testing.T) {
 	t.Log("if drain is ongoing then a message should be displayed")
 	vcsClient := setup(t)
 	drainer.ShutdownBlocking()
-	ch.RunCommentCommand(fixtures.GithubRepo, &fixtures.GithubRepo, nil, fixtures.User, fixtures.Pull.Num, nil)
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, &fixtures.GithubRepo, nil, fixtures.User, fixtures.Pull.Num, nil)
 	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GithubRepo, fixtures.Pull.Num, "Atlantis server is shutting down, please try again later.", "")
 }
 
@@ -717,16 +1109,95 @@ func TestRunCommentCommand_DrainNotOngoing(t *testing.T) {
 	t.Log("if drain is not ongoing then remove ongoing operation must be called even if panic occurred")
 	setup(t)
 	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenPanic("panic test - if you're seeing this in a test failure this isn't the failing test")
-	ch.RunCommentCommand(fixtures.GithubRepo, &fixtures.GithubRepo, nil, fixtures.User, fixtures.Pull.Num, nil)
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, &fixtures.GithubRepo, nil, fixtures.User, fixtures.Pull.Num, nil)
 	githubGetter.VerifyWasCalledOnce().GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)
 	Equals(t, 0, drainer.GetStatus().InProgressOps)
 }
 
+func TestRunCommentCommand_PlanOnlyRepo(t *testing.T) {
+	t.Log("if \"atlantis apply\" is run on a repo restricted to plan-only" +
+		" atlantis should comment saying that apply is disabled")
+	vcsClient := setup(t)
+	var err error
+	ch.RepoAllowlistChecker, err = events.NewRepoAllowlistChecker("github.com/runatlantis/*|plan-only")
+	Ok(t, err)
+	pull := &github.PullRequest{
+		State: github.String("open"),
+	}
+	modelPull := models.PullRequest{BaseRepo: fixtures.GithubRepo, State: models.OpenPullState, Num: fixtures.Pull.Num}
+	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(pull, nil)
+	When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
+
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, nil, nil, fixtures.User, modelPull.Num, &events.CommentCommand{Name: models.ApplyCommand})
+	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GithubRepo, modelPull.Num, "**Error:** `atlantis apply` is disabled for this repo. Atlantis is only allowed to plan it.", "apply")
+	projectCommandBuilder.VerifyWasCalled(Never()).BuildApplyCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand())
+}
+
+func TestRunCommentCommand_MaxParallelRepo(t *testing.T) {
+	t.Log("if a repo already has max-parallel commands running atlantis" +
+		" should comment saying to try again later")
+	vcsClient := setup(t)
+	var err error
+	ch.RepoAllowlistChecker, err = events.NewRepoAllowlistChecker("github.com/runatlantis/*|max-parallel=1")
+	Ok(t, err)
+	ch.RepoOpLimiter = &events.RepoOpLimiter{}
+	Assert(t, ch.RepoOpLimiter.StartOp(fixtures.GithubRepo.FullName, 1), "exp op to start")
+	pull := &github.PullRequest{
+		State: github.String("open"),
+	}
+	modelPull := models.PullRequest{BaseRepo: fixtures.GithubRepo, State: models.OpenPullState, Num: fixtures.Pull.Num}
+	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(pull, nil)
+	When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
+
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, nil, nil, fixtures.User, modelPull.Num, &events.CommentCommand{Name: models.PlanCommand})
+	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GithubRepo, modelPull.Num, "**Error:** This repo can only run 1 Atlantis command(s) at once and that many are already running. Try again once they've finished.", "plan")
+	projectCommandBuilder.VerifyWasCalled(Never()).BuildPlanCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand())
+
+	t.Log("once the running command finishes, the slot is free again")
+	ch.RepoOpLimiter.OpDone(fixtures.GithubRepo.FullName)
+	Assert(t, ch.RepoOpLimiter.StartOp(fixtures.GithubRepo.FullName, 1), "exp op to start")
+}
+
+func TestRunCommentCommand_DataDirOverLimit(t *testing.T) {
+	t.Log("if the data dir is using more disk than allowed, plans should be" +
+		" rejected with a comment")
+	vcsClient := setup(t)
+	dataDir, cleanup := TempDir(t)
+	defer cleanup()
+	Ok(t, ioutil.WriteFile(filepath.Join(dataDir, "planfile"), make([]byte, 2048), 0600))
+	ch.DiskUsageLimiter = &events.DiskUsageLimiter{
+		DataDir:  dataDir,
+		MaxBytes: 1024,
+	}
+	pull := &github.PullRequest{
+		State: github.String("open"),
+	}
+	modelPull := models.PullRequest{BaseRepo: fixtures.GithubRepo, State: models.OpenPullState, Num: fixtures.Pull.Num}
+	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(pull, nil)
+	When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
+
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, nil, nil, fixtures.User, modelPull.Num, &events.CommentCommand{Name: models.PlanCommand})
+	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GithubRepo, modelPull.Num, "**Error:** Atlantis is low on disk space: its data dir is using 2.0 KiB which is over its limit of 1.0 KiB. Try again once the working dirs of closed pull requests have been cleaned up.", "plan")
+	projectCommandBuilder.VerifyWasCalled(Never()).BuildPlanCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand())
+}
+
+func TestRunAutoplanCommand_NoAutoplanRepo(t *testing.T) {
+	t.Log("if a repo is restricted to no-autoplan then autoplan should not run")
+	setup(t)
+	var err error
+	ch.RepoAllowlistChecker, err = events.NewRepoAllowlistChecker("github.com/runatlantis/*|no-autoplan")
+	Ok(t, err)
+	modelPull := models.PullRequest{BaseRepo: fixtures.GithubRepo, State: models.OpenPullState, Num: fixtures.Pull.Num}
+
+	ch.RunAutoplanCommand(context.Background(), fixtures.GithubRepo, fixtures.GithubRepo, modelPull, fixtures.User)
+	projectCommandBuilder.VerifyWasCalled(Never()).BuildAutoplanCommands(matchers.AnyPtrToEventsCommandContext())
+}
+
 func TestRunAutoplanCommand_DrainOngoing(t *testing.T) {
 	t.Log("if drain is ongoing then a message should be displayed")
 	vcsClient := setup(t)
 	drainer.ShutdownBlocking()
-	ch.RunAutoplanCommand(fixtures.GithubRepo, fixtures.GithubRepo, fixtures.Pull, fixtures.User)
+	ch.RunAutoplanCommand(context.Background(), fixtures.GithubRepo, fixtures.GithubRepo, fixtures.Pull, fixtures.User)
 	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GithubRepo, fixtures.Pull.Num, "Atlantis server is shutting down, please try again later.", "plan")
 }
 
@@ -735,7 +1206,109 @@ func TestRunAutoplanCommand_DrainNotOngoing(t *testing.T) {
 	setup(t)
 	fixtures.Pull.BaseRepo = fixtures.GithubRepo
 	When(projectCommandBuilder.BuildAutoplanCommands(matchers.AnyPtrToEventsCommandContext())).ThenPanic("panic test - if you're seeing this in a test failure this isn't the failing test")
-	ch.RunAutoplanCommand(fixtures.GithubRepo, fixtures.GithubRepo, fixtures.Pull, fixtures.User)
+	ch.RunAutoplanCommand(context.Background(), fixtures.GithubRepo, fixtures.GithubRepo, fixtures.Pull, fixtures.User)
 	projectCommandBuilder.VerifyWasCalledOnce().BuildAutoplanCommands(matchers.AnyPtrToEventsCommandContext())
 	Equals(t, 0, drainer.GetStatus().InProgressOps)
 }
+
+func TestRunCommentCommand_Cancel(t *testing.T) {
+	t.Log("if \"atlantis cancel\" is run the commands running for the pull request should be cancelled")
+	vcsClient := setup(t)
+	var pull github.PullRequest
+	modelPull := models.PullRequest{BaseRepo: fixtures.GithubRepo, State: models.OpenPullState, Num: fixtures.Pull.Num}
+	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(&pull, nil)
+	When(eventParsing.ParseGithubPull(&pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
+	cmdCtx, done := commandCanceller.Start(context.Background(), fixtures.GithubRepo.FullName, fixtures.Pull.Num, models.ApplyCommand)
+	go finishWhenCancelled(cmdCtx, done)
+
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, nil, nil, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.CancelCommand})
+	Assert(t, cmdCtx.Err() != nil, "exp running apply to be cancelled")
+	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GithubRepo, fixtures.Pull.Num, "Cancelled 1 running command(s). Resources that were being applied may have only been partially changed so check the output of any interrupted applies.", "cancel")
+
+	t.Log("nothing is left to cancel")
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, nil, nil, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.CancelCommand})
+	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GithubRepo, fixtures.Pull.Num, "No plans or applies are running for this PR", "cancel")
+}
+
+func TestRunAutoplanCommand_CancelsRunningPlans(t *testing.T) {
+	t.Log("autoplans should cancel the plans still running for the pull request but not its applies")
+	setup(t)
+	fixtures.Pull.BaseRepo = fixtures.GithubRepo
+	planCtx, planDone := commandCanceller.Start(context.Background(), fixtures.GithubRepo.FullName, fixtures.Pull.Num, models.PlanCommand)
+	go finishWhenCancelled(planCtx, planDone)
+	applyCtx, applyDone := commandCanceller.Start(context.Background(), fixtures.GithubRepo.FullName, fixtures.Pull.Num, models.ApplyCommand)
+	defer applyDone()
+
+	ch.RunAutoplanCommand(context.Background(), fixtures.GithubRepo, fixtures.GithubRepo, fixtures.Pull, fixtures.User)
+	Assert(t, planCtx.Err() != nil, "exp running plan to be cancelled")
+	Ok(t, applyCtx.Err())
+	cmdCtx := projectCommandBuilder.VerifyWasCalledOnce().BuildAutoplanCommands(matchers.AnyPtrToEventsCommandContext()).GetCapturedArguments()
+	Assert(t, cmdCtx.RequestCtx != nil, "exp autoplan to be cancellable")
+}
+
+func TestRunCommentCommand_ProgressComment(t *testing.T) {
+	t.Log("if progress comments are enabled, the command's results should be edited into the progress comment")
+	vcsClient := setup(t)
+	ch.ProgressComments = true
+	var pull github.PullRequest
+	modelPull := models.PullRequest{BaseRepo: fixtures.GithubRepo, State: models.OpenPullState, Num: fixtures.Pull.Num}
+	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(&pull, nil)
+	When(eventParsing.ParseGithubPull(&pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
+	When(vcsClient.SupportsCommentUpdates(fixtures.GithubRepo)).ThenReturn(true)
+	When(vcsClient.CreateUpdatableComment(matchers.AnyModelsRepo(), AnyInt(), AnyString())).ThenReturn(int64(123), nil)
+
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, nil, nil, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.PlanCommand})
+	_, _, comment := vcsClient.VerifyWasCalledOnce().CreateUpdatableComment(matchers.AnyModelsRepo(), AnyInt(), AnyString()).GetCapturedArguments()
+	Assert(t, strings.HasPrefix(comment, ":hourglass_flowing_sand: Running...\n\n`atlantis plan` was requested by @"+fixtures.User.Username), "unexpected progress comment %q", comment)
+	vcsClient.VerifyWasCalledOnce().UpdateComment(matchers.EqModelsRepo(fixtures.GithubRepo), EqInt(fixtures.Pull.Num), EqInt64(123), AnyString(), EqString("plan"))
+	vcsClient.VerifyWasCalled(Never()).CreateComment(matchers.AnyModelsRepo(), AnyInt(), AnyString(), AnyString())
+}
+
+func TestRunCommentCommand_ProgressCommentNotSupported(t *testing.T) {
+	t.Log("if the VCS host doesn't support editing comments, results should be commented as usual")
+	vcsClient := setup(t)
+	ch.ProgressComments = true
+	var pull github.PullRequest
+	modelPull := models.PullRequest{BaseRepo: fixtures.GithubRepo, State: models.OpenPullState, Num: fixtures.Pull.Num}
+	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(&pull, nil)
+	When(eventParsing.ParseGithubPull(&pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
+
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, nil, nil, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.PlanCommand})
+	vcsClient.VerifyWasCalled(Never()).CreateUpdatableComment(matchers.AnyModelsRepo(), AnyInt(), AnyString())
+	vcsClient.VerifyWasCalledOnce().CreateComment(matchers.AnyModelsRepo(), AnyInt(), AnyString(), EqString("plan"))
+}
+
+func TestRunUnlockCommand_DiscussionReply(t *testing.T) {
+	t.Log("if the command came from a discussion, its results should be replied in the discussion")
+	vcsClient := setup(t)
+	pull := &github.PullRequest{
+		State: github.String("open"),
+	}
+	modelPull := models.PullRequest{BaseRepo: fixtures.GithubRepo, State: models.OpenPullState, Num: fixtures.Pull.Num}
+	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(pull, nil)
+	When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
+
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, &fixtures.GithubRepo, nil, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.UnlockCommand, DiscussionID: "abc123"})
+
+	vcsClient.VerifyWasCalledOnce().CreateReply(fixtures.GithubRepo, fixtures.Pull.Num, "abc123", "All Atlantis locks for this PR have been unlocked and plans discarded", "unlock")
+	vcsClient.VerifyWasCalled(Never()).CreateComment(matchers.AnyModelsRepo(), AnyInt(), AnyString(), AnyString())
+}
+
+func TestRunUnlockCommand_ProgressComment(t *testing.T) {
+	t.Log("commands that comment their results themselves should mark the progress comment as finished")
+	vcsClient := setup(t)
+	ch.ProgressComments = true
+	pull := &github.PullRequest{
+		State: github.String("open"),
+	}
+	modelPull := models.PullRequest{BaseRepo: fixtures.GithubRepo, State: models.OpenPullState, Num: fixtures.Pull.Num}
+	When(githubGetter.GetPullRequest(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(pull, nil)
+	When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, fixtures.GithubRepo, nil)
+	When(vcsClient.SupportsCommentUpdates(fixtures.GithubRepo)).ThenReturn(true)
+	When(vcsClient.CreateUpdatableComment(matchers.AnyModelsRepo(), AnyInt(), AnyString())).ThenReturn(int64(123), nil)
+
+	ch.RunCommentCommand(context.Background(), fixtures.GithubRepo, &fixtures.GithubRepo, nil, fixtures.User, fixtures.Pull.Num, &events.CommentCommand{Name: models.UnlockCommand})
+
+	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GithubRepo, fixtures.Pull.Num, "All Atlantis locks for this PR have been unlocked and plans discarded", "unlock")
+	vcsClient.VerifyWasCalledOnce().UpdateComment(fixtures.GithubRepo, fixtures.Pull.Num, int64(123), ":heavy_check_mark: Finished\n\n`atlantis unlock` requested by @"+fixtures.User.Username+" has finished.", "")
+}
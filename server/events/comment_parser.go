@@ -18,14 +18,18 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/url"
+	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"text/template"
 
 	"github.com/flynn-archive/go-shlex"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/yaml"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/i18n"
 	"github.com/spf13/pflag"
 )
 
@@ -40,7 +44,19 @@ const (
 	autoMergeDisabledFlagShort = ""
 	verboseFlagLong            = "verbose"
 	verboseFlagShort           = ""
+	destroyFlagLong            = "destroy"
+	destroyFlagShort           = ""
+	forceFlagLong              = "force"
+	forceFlagShort             = ""
+	confirmFlagLong            = "confirm"
+	confirmFlagShort           = ""
+	fmtFlagLong                = "fmt"
+	fmtFlagShort               = ""
+	targetFlagLong             = "target"
+	targetFlagShort            = ""
 	atlantisExecutable         = "atlantis"
+	stateRmSubcommand          = "rm"
+	stateMvSubcommand          = "mv"
 )
 
 // multiLineRegex is used to ignore multi-line comments since those aren't valid
@@ -50,6 +66,16 @@ const (
 // and pasting GitHub comments.
 var multiLineRegex = regexp.MustCompile(`.*\r?\n[^\r\n]+`)
 
+// targetAddressRegex matches the resource addresses that can be passed to
+// --target, ex. aws_instance.web, module.app["prod"].aws_instance.web[0] or
+// module.app.
+var targetAddressRegex = regexp.MustCompile(`^(module\.` + targetNamePattern + targetIndexPattern + `(\.module\.` + targetNamePattern + targetIndexPattern + `)*(\.(data\.)?` + targetNamePattern + `\.` + targetNamePattern + targetIndexPattern + `)?|(data\.)?` + targetNamePattern + `\.` + targetNamePattern + targetIndexPattern + `)$`)
+
+const (
+	targetNamePattern  = `[A-Za-z_][A-Za-z0-9_-]*`
+	targetIndexPattern = `(\[([0-9]+|"[^"]*")\])?`
+)
+
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_comment_parsing.go CommentParsing
 
 // CommentParsing handles parsing pull request comments.
@@ -78,6 +104,14 @@ type CommentParser struct {
 	BitbucketUser   string
 	AzureDevopsUser string
 	ApplyDisabled   bool
+	// GlobalCfg is where the custom commands defined in the server-side repo
+	// config are read from so they're updated when it's reloaded. If it's nil
+	// only the built-in commands are parsed.
+	GlobalCfg *valid.GlobalCfgStore
+	// Locale is the locale of the errors returned for comments that can't be
+	// parsed. Comments are parsed before the repo's config is known so this
+	// is always the server's --locale, never a repo's locale.
+	Locale string
 }
 
 // CommentParseResult describes the result of parsing a comment as a command.
@@ -97,7 +131,10 @@ type CommentParseResult struct {
 // Valid commands contain:
 // - The initial "executable" name, 'run' or 'atlantis' or '@GithubUser'
 //   where GithubUser is the API user Atlantis is running as.
-// - Then a command, either 'plan', 'apply', 'approve_policies', or 'help'.
+// - Then a command, either 'plan', 'apply', 'approve_policies', 'import',
+//   'state', 'validate', 'cancel', 'help' or a custom command defined in the
+//   server-side repo config.
+// - If the command is 'state', then a subcommand, either 'rm' or 'mv'.
 // - Then optional flags, then an optional separator '--' followed by optional
 //   extra flags to be appended to the terraform plan/apply command.
 //
@@ -107,7 +144,15 @@ type CommentParseResult struct {
 // - @GithubUser plan -w staging
 // - atlantis plan -w staging -d dir --verbose
 // - atlantis plan --verbose -- -key=value -key2 value2
+// - atlantis plan -d dir --destroy
+// - atlantis plan -d dir --target aws_instance.web --target module.db
+// - atlantis plan -d 'stacks/prod/*' -w staging,production
 // - atlantis approve_policies
+// - atlantis import -d dir aws_instance.example i-abcd1234
+// - atlantis state rm -p project aws_instance.example
+// - atlantis validate -d dir --fmt
+// - atlantis cancel
+// - atlantis deploy -p project
 //
 func (e *CommentParser) Parse(comment string, vcsHost models.VCSHostType) CommentParseResult {
 	if multiLineRegex.MatchString(comment) {
@@ -148,7 +193,7 @@ func (e *CommentParser) Parse(comment string, vcsHost models.VCSHostType) Commen
 	// parser.
 	args, err := shlex.Split(comment)
 	if err != nil {
-		return CommentParseResult{CommentResponse: fmt.Sprintf("```\nError parsing command: %s\n```", err)}
+		return CommentParseResult{CommentResponse: i18n.Sprintf(e.Locale, i18n.ParseCommandError, err)}
 	}
 	if len(args) < 1 {
 		return CommentParseResult{Ignore: true}
@@ -166,17 +211,23 @@ func (e *CommentParser) Parse(comment string, vcsHost models.VCSHostType) Commen
 		return CommentParseResult{CommentResponse: e.HelpComment(e.ApplyDisabled)}
 	}
 
-	// Need to have a plan, apply, approve_policy or unlock at this point.
-	if !e.stringInSlice(command, []string{models.PlanCommand.String(), models.ApplyCommand.String(), models.UnlockCommand.String(), models.ApprovePoliciesCommand.String(), models.VersionCommand.String()}) {
-		return CommentParseResult{CommentResponse: fmt.Sprintf("```\nError: unknown command %q.\nRun 'atlantis --help' for usage.\n```", command)}
+	// Need to have a plan, apply, approve_policy, unlock, version, import,
+	// state, validate, cancel or custom command at this point.
+	customCmd, isCustom := e.customCommands()[command]
+	if !isCustom && !e.stringInSlice(command, []string{models.PlanCommand.String(), models.ApplyCommand.String(), models.UnlockCommand.String(), models.ApprovePoliciesCommand.String(), models.VersionCommand.String(), models.ImportCommand.String(), models.StateCommand.String(), models.ValidateCommand.String(), models.CancelCommand.String()}) {
+		return CommentParseResult{CommentResponse: i18n.Sprintf(e.Locale, i18n.UnknownCommand, command)}
 	}
 
 	var workspace string
 	var dir string
 	var project string
-	var verbose, autoMergeDisabled bool
+	var verbose, autoMergeDisabled, destroy, force, confirm, checkFmt bool
+	var targets []string
 	var flagSet *pflag.FlagSet
 	var name models.CommandName
+	var subName string
+	// It's safe to use [2:] because we know there's at least 2 elements in args.
+	flagArgs := args[2:]
 
 	// Set up the flag parsing depending on the command.
 	switch command {
@@ -188,6 +239,8 @@ func (e *CommentParser) Parse(comment string, vcsHost models.VCSHostType) Commen
 		flagSet.StringVarP(&dir, dirFlagLong, dirFlagShort, "", "Which directory to run plan in relative to root of repo, ex. 'child/dir'.")
 		flagSet.StringVarP(&project, projectFlagLong, projectFlagShort, "", fmt.Sprintf("Which project to run plan for. Refers to the name of the project configured in %s. Cannot be used at same time as workspace or dir flags.", yaml.AtlantisYAMLFilename))
 		flagSet.BoolVarP(&verbose, verboseFlagLong, verboseFlagShort, false, "Append Atlantis log to comment.")
+		flagSet.BoolVarP(&destroy, destroyFlagLong, destroyFlagShort, false, "Plan to destroy every resource. Must be allowed by the server-side repo config.")
+		flagSet.StringArrayVarP(&targets, targetFlagLong, targetFlagShort, nil, "Limit the plan to this resource `address`, ex. 'aws_instance.web'. Can be repeated.")
 	case models.ApplyCommand.String():
 		name = models.ApplyCommand
 		flagSet = pflag.NewFlagSet(models.ApplyCommand.String(), pflag.ContinueOnError)
@@ -196,6 +249,9 @@ func (e *CommentParser) Parse(comment string, vcsHost models.VCSHostType) Commen
 		flagSet.StringVarP(&dir, dirFlagLong, dirFlagShort, "", "Apply the plan for this directory, relative to root of repo, ex. 'child/dir'.")
 		flagSet.StringVarP(&project, projectFlagLong, projectFlagShort, "", fmt.Sprintf("Apply the plan for this project. Refers to the name of the project configured in %s. Cannot be used at same time as workspace or dir flags.", yaml.AtlantisYAMLFilename))
 		flagSet.BoolVarP(&autoMergeDisabled, autoMergeDisabledFlagLong, autoMergeDisabledFlagShort, false, "Disable automerge after apply.")
+		flagSet.BoolVarP(&force, forceFlagLong, forceFlagShort, false, "Apply plans even if they were generated from an earlier commit of the pull request.")
+		flagSet.BoolVarP(&confirm, confirmFlagLong, confirmFlagShort, false, "Confirm an apply that's waiting for confirmation.")
+		flagSet.StringArrayVarP(&targets, targetFlagLong, targetFlagShort, nil, "Only apply plans that were targeted at exactly these resource `address`es. Can be repeated.")
 		flagSet.BoolVarP(&verbose, verboseFlagLong, verboseFlagShort, false, "Append Atlantis log to comment.")
 	case models.ApprovePoliciesCommand.String():
 		name = models.ApprovePoliciesCommand
@@ -206,6 +262,8 @@ func (e *CommentParser) Parse(comment string, vcsHost models.VCSHostType) Commen
 		name = models.UnlockCommand
 		flagSet = pflag.NewFlagSet(models.UnlockCommand.String(), pflag.ContinueOnError)
 		flagSet.SetOutput(ioutil.Discard)
+		flagSet.StringVarP(&workspace, workspaceFlagLong, workspaceFlagShort, "", "Only unlock projects in this Terraform workspace.")
+		flagSet.StringVarP(&dir, dirFlagLong, dirFlagShort, "", "Only unlock the project in this directory, relative to root of repo, ex. 'child/dir'.")
 	case models.VersionCommand.String():
 		name = models.VersionCommand
 		flagSet = pflag.NewFlagSet(models.VersionCommand.String(), pflag.ContinueOnError)
@@ -213,14 +271,61 @@ func (e *CommentParser) Parse(comment string, vcsHost models.VCSHostType) Commen
 		flagSet.StringVarP(&dir, dirFlagLong, dirFlagShort, "", "Which directory to run version in relative to root of repo, ex. 'child/dir'.")
 		flagSet.StringVarP(&project, projectFlagLong, projectFlagShort, "", fmt.Sprintf("Print the version for this project. Refers to the name of the project configured in %s.", yaml.AtlantisYAMLFilename))
 		flagSet.BoolVarP(&verbose, verboseFlagLong, verboseFlagShort, false, "Append Atlantis log to comment.")
+	case models.ImportCommand.String():
+		name = models.ImportCommand
+		flagSet = pflag.NewFlagSet(models.ImportCommand.String(), pflag.ContinueOnError)
+		flagSet.SetOutput(ioutil.Discard)
+		flagSet.StringVarP(&workspace, workspaceFlagLong, workspaceFlagShort, "", "Switch to this Terraform workspace before importing.")
+		flagSet.StringVarP(&dir, dirFlagLong, dirFlagShort, "", "Which directory to run import in relative to root of repo, ex. 'child/dir'.")
+		flagSet.StringVarP(&project, projectFlagLong, projectFlagShort, "", fmt.Sprintf("Which project to run import for. Refers to the name of the project configured in %s. Cannot be used at same time as workspace or dir flags.", yaml.AtlantisYAMLFilename))
+		flagSet.BoolVarP(&verbose, verboseFlagLong, verboseFlagShort, false, "Append Atlantis log to comment.")
+	case models.StateCommand.String():
+		name = models.StateCommand
+		flagSet = pflag.NewFlagSet(models.StateCommand.String(), pflag.ContinueOnError)
+		flagSet.SetOutput(ioutil.Discard)
+		flagSet.StringVarP(&workspace, workspaceFlagLong, workspaceFlagShort, "", "Switch to this Terraform workspace before modifying state.")
+		flagSet.StringVarP(&dir, dirFlagLong, dirFlagShort, "", "Which directory to modify state in relative to root of repo, ex. 'child/dir'.")
+		flagSet.StringVarP(&project, projectFlagLong, projectFlagShort, "", fmt.Sprintf("Which project to modify state for. Refers to the name of the project configured in %s. Cannot be used at same time as workspace or dir flags.", yaml.AtlantisYAMLFilename))
+		flagSet.BoolVarP(&verbose, verboseFlagLong, verboseFlagShort, false, "Append Atlantis log to comment.")
+
+		// State requires a subcommand before any flags.
+		if len(args) < 3 || !e.stringInSlice(args[2], []string{stateRmSubcommand, stateMvSubcommand}) {
+			return CommentParseResult{CommentResponse: e.errMarkdown(i18n.Sprintf(e.Locale, i18n.StateRequiresSubcommand, stateRmSubcommand, stateMvSubcommand), command, flagSet)}
+		}
+		subName = args[2]
+		flagArgs = args[3:]
+	case models.ValidateCommand.String():
+		name = models.ValidateCommand
+		flagSet = pflag.NewFlagSet(models.ValidateCommand.String(), pflag.ContinueOnError)
+		flagSet.SetOutput(ioutil.Discard)
+		flagSet.StringVarP(&workspace, workspaceFlagLong, workspaceFlagShort, "", "Switch to this Terraform workspace before validating.")
+		flagSet.StringVarP(&dir, dirFlagLong, dirFlagShort, "", "Which directory to run validate in relative to root of repo, ex. 'child/dir'.")
+		flagSet.StringVarP(&project, projectFlagLong, projectFlagShort, "", fmt.Sprintf("Which project to run validate for. Refers to the name of the project configured in %s. Cannot be used at same time as workspace or dir flags.", yaml.AtlantisYAMLFilename))
+		flagSet.BoolVarP(&checkFmt, fmtFlagLong, fmtFlagShort, false, "Also check that files are formatted with terraform fmt.")
+		flagSet.BoolVarP(&verbose, verboseFlagLong, verboseFlagShort, false, "Append Atlantis log to comment.")
+	case models.CancelCommand.String():
+		name = models.CancelCommand
+		flagSet = pflag.NewFlagSet(models.CancelCommand.String(), pflag.ContinueOnError)
+		flagSet.SetOutput(ioutil.Discard)
+	case customCmd.Name:
+		name = models.CustomCommand
+		subName = customCmd.Name
+		flagSet = pflag.NewFlagSet(command, pflag.ContinueOnError)
+		flagSet.SetOutput(ioutil.Discard)
+		flagSet.StringVarP(&workspace, workspaceFlagLong, workspaceFlagShort, "", fmt.Sprintf("Switch to this Terraform workspace before running %s.", command))
+		flagSet.StringVarP(&dir, dirFlagLong, dirFlagShort, "", fmt.Sprintf("Which directory to run %s in relative to root of repo, ex. 'child/dir'.", command))
+		flagSet.StringVarP(&project, projectFlagLong, projectFlagShort, "", fmt.Sprintf("Which project to run %s for. Refers to the name of the project configured in %s. Cannot be used at same time as workspace or dir flags.", command, yaml.AtlantisYAMLFilename))
+		flagSet.BoolVarP(&verbose, verboseFlagLong, verboseFlagShort, false, "Append Atlantis log to comment.")
 	default:
 		return CommentParseResult{CommentResponse: fmt.Sprintf("Error: unknown command %q – this is a bug", command)}
 	}
 
 	// Now parse the flags.
-	// It's safe to use [2:] because we know there's at least 2 elements in args.
-	err = flagSet.Parse(args[2:])
+	err = flagSet.Parse(flagArgs)
 	if err == pflag.ErrHelp {
+		if isCustom && customCmd.Description != "" {
+			return CommentParseResult{CommentResponse: fmt.Sprintf("```\n%s\n\nUsage of %s:\n%s\n```", customCmd.Description, command, flagSet.FlagUsagesWrapped(usagesCols))}
+		}
 		return CommentParseResult{CommentResponse: fmt.Sprintf("```\nUsage of %s:\n%s\n```", command, flagSet.FlagUsagesWrapped(usagesCols))}
 	}
 	if err != nil {
@@ -236,25 +341,51 @@ func (e *CommentParser) Parse(comment string, vcsHost models.VCSHostType) Commen
 	} else {
 		unusedArgs = flagSet.Args()[0:flagSet.ArgsLenAtDash()]
 	}
+
+	// Import and state are the only commands that take positional arguments,
+	// ex. the resource ADDRESS and ID to import.
+	var positionalArgs []string
+	switch {
+	case name == models.ImportCommand && len(unusedArgs) != 2:
+		return CommentParseResult{CommentResponse: e.errMarkdown(i18n.Sprintf(e.Locale, i18n.ImportRequiresArgs), command, flagSet)}
+	case subName == stateRmSubcommand && len(unusedArgs) == 0:
+		return CommentParseResult{CommentResponse: e.errMarkdown(i18n.Sprintf(e.Locale, i18n.StateRmRequiresArgs), command, flagSet)}
+	case subName == stateMvSubcommand && len(unusedArgs) != 2:
+		return CommentParseResult{CommentResponse: e.errMarkdown(i18n.Sprintf(e.Locale, i18n.StateMvRequiresArgs), command, flagSet)}
+	}
+	if name == models.ImportCommand || name == models.StateCommand {
+		positionalArgs = unusedArgs
+		unusedArgs = nil
+	}
 	if len(unusedArgs) > 0 {
-		return CommentParseResult{CommentResponse: e.errMarkdown(fmt.Sprintf("unknown argument(s) – %s", strings.Join(unusedArgs, " ")), command, flagSet)}
+		return CommentParseResult{CommentResponse: e.errMarkdown(i18n.Sprintf(e.Locale, i18n.UnknownArgs, strings.Join(unusedArgs, " ")), command, flagSet)}
 	}
 
 	var extraArgs []string
 	if flagSet.ArgsLenAtDash() != -1 {
 		extraArgs = flagSet.Args()[flagSet.ArgsLenAtDash():]
 	}
+	// terraform import and state expect their options before any positional
+	// arguments so they're appended after any extra args.
+	extraArgs = append(extraArgs, positionalArgs...)
 
-	dir, err = e.validateDir(dir)
+	dir, err = e.validateDirs(dir)
 	if err != nil {
 		return CommentParseResult{CommentResponse: e.errMarkdown(err.Error(), command, flagSet)}
 	}
+	if err := e.validateWorkspaces(workspace); err != nil {
+		return CommentParseResult{CommentResponse: e.errMarkdown(err.Error(), command, flagSet)}
+	}
+	if err := e.validateTargets(targets); err != nil {
+		return CommentParseResult{CommentResponse: e.errMarkdown(err.Error(), command, flagSet)}
+	}
 
-	// Use the same validation that Terraform uses: https://git.io/vxGhU. Plus
-	// we also don't allow '..'. We don't want the workspace to contain a path
-	// since we create files based on the name.
-	if workspace != url.PathEscape(workspace) || strings.Contains(workspace, "..") {
-		return CommentParseResult{CommentResponse: e.errMarkdown(fmt.Sprintf("invalid workspace: %q", workspace), command, flagSet)}
+	// Lists and patterns are only expanded for commands that can run in more
+	// than one project.
+	if e.stringInSlice(command, []string{models.UnlockCommand.String(), models.ImportCommand.String(), models.StateCommand.String()}) &&
+		(strings.ContainsAny(dir, multiTargetChars) || strings.ContainsAny(workspace, multiTargetChars)) {
+		err := fmt.Sprintf("-%s/--%s and -%s/--%s can't be lists or patterns with %s", dirFlagShort, dirFlagLong, workspaceFlagShort, workspaceFlagLong, command)
+		return CommentParseResult{CommentResponse: e.errMarkdown(err, command, flagSet)}
 	}
 
 	// If project is specified, dir or workspace should not be set. Since we
@@ -268,7 +399,18 @@ func (e *CommentParser) Parse(comment string, vcsHost models.VCSHostType) Commen
 	}
 
 	return CommentParseResult{
-		Command: NewCommentCommand(dir, extraArgs, name, verbose, autoMergeDisabled, workspace, project),
+		Command: NewCommentCommand(dir, extraArgs, name, CommentCommandOptions{
+			SubName:           subName,
+			Verbose:           verbose,
+			AutoMergeDisabled: autoMergeDisabled,
+			Destroy:           destroy,
+			Force:             force,
+			Confirm:           confirm,
+			Fmt:               checkFmt,
+			Workspace:         workspace,
+			ProjectName:       project,
+			Targets:           targets,
+		}),
 	}
 }
 
@@ -331,6 +473,65 @@ func (e *CommentParser) buildFlags(repoRelDir string, workspace string, project
 	return flags
 }
 
+// validateDirs validates each dir in the comma-separated list dirs and
+// returns the cleaned list. Dirs can be glob patterns, ex. stacks/prod/*.
+func (e *CommentParser) validateDirs(dirs string) (string, error) {
+	if dirs == "" {
+		return dirs, nil
+	}
+	var validated []string
+	for _, dir := range strings.Split(dirs, ",") {
+		if strings.TrimSpace(dir) == "" {
+			return "", fmt.Errorf("invalid list %q: dirs can't be empty", dirs)
+		}
+		validatedDir, err := e.validateDir(dir)
+		if err != nil {
+			return "", err
+		}
+		if _, err := path.Match(validatedDir, ""); err != nil {
+			return "", fmt.Errorf("invalid pattern %q", dir)
+		}
+		validated = append(validated, validatedDir)
+	}
+	return strings.Join(validated, ","), nil
+}
+
+// validateWorkspaces validates each workspace in the comma-separated list
+// workspaces. Workspaces can be glob patterns, ex. prod-*.
+func (e *CommentParser) validateWorkspaces(workspaces string) error {
+	if workspaces == "" {
+		return nil
+	}
+	for _, workspace := range strings.Split(workspaces, ",") {
+		// Use the same validation that Terraform uses: https://git.io/vxGhU.
+		// Plus we also don't allow '..'. We don't want the workspace to
+		// contain a path since we create files based on the name. Glob
+		// characters are removed first since they'd be escaped.
+		name := strings.NewReplacer("*", "", "?", "", "[", "", "]", "").Replace(workspace)
+		if workspace == "" || name != url.PathEscape(name) || strings.Contains(name, "..") {
+			return fmt.Errorf("invalid workspace: %q", workspace)
+		}
+		if workspace == pullCloneDirName {
+			return fmt.Errorf("invalid workspace: %q is reserved by Atlantis", workspace)
+		}
+		if _, err := path.Match(workspace, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q", workspace)
+		}
+	}
+	return nil
+}
+
+// validateTargets validates that each of targets is a resource address that
+// terraform's -target flag accepts.
+func (e *CommentParser) validateTargets(targets []string) error {
+	for _, target := range targets {
+		if !targetAddressRegex.MatchString(target) {
+			return fmt.Errorf("invalid resource address %q for --%s", target, targetFlagLong)
+		}
+	}
+	return nil
+}
+
 func (e *CommentParser) validateDir(dir string) (string, error) {
 	if dir == "" {
 		return dir, nil
@@ -349,6 +550,15 @@ func (e *CommentParser) validateDir(dir string) (string, error) {
 	return validatedDir, nil
 }
 
+// customCommands returns the custom commands defined in the server-side repo
+// config, keyed by name.
+func (e *CommentParser) customCommands() map[string]valid.CustomCommand {
+	if e.GlobalCfg == nil {
+		return nil
+	}
+	return e.GlobalCfg.Get().CustomCommands
+}
+
 func (e *CommentParser) stringInSlice(a string, list []string) bool {
 	for _, b := range list {
 		if b == a {
@@ -359,16 +569,23 @@ func (e *CommentParser) stringInSlice(a string, list []string) bool {
 }
 
 func (e *CommentParser) errMarkdown(errMsg string, command string, flagSet *pflag.FlagSet) string {
-	return fmt.Sprintf("```\nError: %s.\nUsage of %s:\n%s```", errMsg, command, flagSet.FlagUsagesWrapped(usagesCols))
+	return i18n.Sprintf(e.Locale, i18n.CommandUsageError, errMsg, command, flagSet.FlagUsagesWrapped(usagesCols))
 }
 
 func (e *CommentParser) HelpComment(applyDisabled bool) string {
 	buf := &bytes.Buffer{}
 	var tmpl = template.Must(template.New("").Parse(helpCommentTemplate))
+	var customCmds []valid.CustomCommand
+	for _, cmd := range e.customCommands() {
+		customCmds = append(customCmds, cmd)
+	}
+	sort.Slice(customCmds, func(i, j int) bool { return customCmds[i].Name < customCmds[j].Name })
 	if err := tmpl.Execute(buf, struct {
-		ApplyDisabled bool
+		ApplyDisabled  bool
+		CustomCommands []valid.CustomCommand
 	}{
-		ApplyDisabled: applyDisabled,
+		ApplyDisabled:  applyDisabled,
+		CustomCommands: customCmds,
 	}); err != nil {
 		return fmt.Sprintf("Failed to render template, this is a bug: %v", err)
 	}
@@ -386,6 +603,9 @@ Usage:
 Examples:
   # run plan in the root directory passing the -target flag to terraform
   atlantis plan -d . -- -target=resource
+
+  # plan every project in a subdirectory of stacks/prod
+  atlantis plan -d 'stacks/prod/*'
   {{- if not .ApplyDisabled }}
 
   # apply all unapplied plans from this pull request
@@ -393,6 +613,12 @@ Examples:
 
   # apply the plan for the root directory and staging workspace
   atlantis apply -d . -w staging
+
+  # import an existing resource into the state of the root directory
+  atlantis import -d . aws_instance.example i-abcd1234
+
+  # remove a resource from the state of the root directory
+  atlantis state rm -d . aws_instance.example
 {{- end }}
 
 Commands:
@@ -401,10 +627,21 @@ Commands:
 {{- if not .ApplyDisabled }}
   apply    Runs 'terraform apply' on all unapplied plans from this pull request.
            To only apply a specific plan, use the -d, -w and -p flags.
+  import   Runs 'terraform import ADDRESS ID' for a single project.
+           Any existing plan for the project is discarded.
+  state    Runs 'terraform state rm' or 'terraform state mv' for a single
+           project if enabled on the server. Any existing plan for the
+           project is discarded.
 {{- end }}
   unlock   Removes all atlantis locks and discards all plans for this PR.
-           To unlock a specific plan you can use the Atlantis UI.
+           To unlock a specific project, use the -d and -w flags.
   version  Print the output of 'terraform version'
+  validate Runs 'terraform validate' for the changes in this pull request
+           without locking them. To also check formatting, use --fmt.
+  cancel   Cancels the plans and applies running for this pull request.
+{{- range .CustomCommands }}
+  {{ printf "%-8s" .Name }} {{ if .Description }}{{ .Description }}{{ else }}Runs the {{ .Workflow.Name }} workflow.{{ end }}
+{{- end }}
   help     View help.
 
 Flags:
@@ -421,9 +658,10 @@ var DidYouMeanAtlantisComment = "Did you mean to use `atlantis` instead of `terr
 // `atlantis unlock` with flags.
 
 var UnlockUsage = "`Usage of unlock:`\n\n ```cmake\n" +
-	`atlantis unlock	
+	`atlantis unlock [-d dir] [-w workspace]
 
   Unlocks the entire PR and discards all plans in this PR.
-  Arguments or flags are not supported at the moment.
-  If you need to unlock a specific project please use the atlantis UI.` +
+  To only unlock a specific project, use -d and/or -w:
+  -d, --dir string         Only unlock the project in this directory, relative to root of repo, ex. 'child/dir'.
+  -w, --workspace string   Only unlock projects in this Terraform workspace.` +
 	"\n```"
@@ -20,6 +20,7 @@ import (
 
 	"github.com/runatlantis/atlantis/server/events"
 	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 	. "github.com/runatlantis/atlantis/testing"
 )
 
@@ -155,12 +156,34 @@ func TestParse_UnusedArguments(t *testing.T) {
 }
 
 func TestParse_UnknownShorthandFlag(t *testing.T) {
-	comment := "atlantis unlock -d ."
+	comment := "atlantis unlock -p project"
 	r := commentParser.Parse(comment, models.Github)
 
 	Equals(t, UnlockUsage, r.CommentResponse)
 }
 
+func TestParse_UnlockProject(t *testing.T) {
+	cases := []struct {
+		comment      string
+		expDir       string
+		expWorkspace string
+	}{
+		{"atlantis unlock", "", ""},
+		{"atlantis unlock -d dir", "dir", ""},
+		{"atlantis unlock -d ./dir/ -w staging", "dir", "staging"},
+		{"atlantis unlock --workspace staging", "", "staging"},
+	}
+	for _, c := range cases {
+		t.Run(c.comment, func(t *testing.T) {
+			r := commentParser.Parse(c.comment, models.Github)
+			Equals(t, "", r.CommentResponse)
+			Equals(t, models.UnlockCommand, r.Command.Name)
+			Equals(t, c.expDir, r.Command.RepoRelDir)
+			Equals(t, c.expWorkspace, r.Command.Workspace)
+		})
+	}
+}
+
 func TestParse_DidYouMeanAtlantis(t *testing.T) {
 	t.Log("given a comment that should result in a 'did you mean atlantis'" +
 		"response, should set CommentParseResult.CommentResult")
@@ -716,20 +739,37 @@ Examples:
   # run plan in the root directory passing the -target flag to terraform
   atlantis plan -d . -- -target=resource
 
+  # plan every project in a subdirectory of stacks/prod
+  atlantis plan -d 'stacks/prod/*'
+
   # apply all unapplied plans from this pull request
   atlantis apply
 
   # apply the plan for the root directory and staging workspace
   atlantis apply -d . -w staging
 
+  # import an existing resource into the state of the root directory
+  atlantis import -d . aws_instance.example i-abcd1234
+
+  # remove a resource from the state of the root directory
+  atlantis state rm -d . aws_instance.example
+
 Commands:
   plan     Runs 'terraform plan' for the changes in this pull request.
            To plan a specific project, use the -d, -w and -p flags.
   apply    Runs 'terraform apply' on all unapplied plans from this pull request.
            To only apply a specific plan, use the -d, -w and -p flags.
+  import   Runs 'terraform import ADDRESS ID' for a single project.
+           Any existing plan for the project is discarded.
+  state    Runs 'terraform state rm' or 'terraform state mv' for a single
+           project if enabled on the server. Any existing plan for the
+           project is discarded.
   unlock   Removes all atlantis locks and discards all plans for this PR.
-           To unlock a specific plan you can use the Atlantis UI.
+           To unlock a specific project, use the -d and -w flags.
   version  Print the output of 'terraform version'
+  validate Runs 'terraform validate' for the changes in this pull request
+           without locking them. To also check formatting, use --fmt.
+  cancel   Cancels the plans and applies running for this pull request.
   help     View help.
 
 Flags:
@@ -751,12 +791,18 @@ Examples:
   # run plan in the root directory passing the -target flag to terraform
   atlantis plan -d . -- -target=resource
 
+  # plan every project in a subdirectory of stacks/prod
+  atlantis plan -d 'stacks/prod/*'
+
 Commands:
   plan     Runs 'terraform plan' for the changes in this pull request.
            To plan a specific project, use the -d, -w and -p flags.
   unlock   Removes all atlantis locks and discards all plans for this PR.
-           To unlock a specific plan you can use the Atlantis UI.
+           To unlock a specific project, use the -d and -w flags.
   version  Print the output of 'terraform version'
+  validate Runs 'terraform validate' for the changes in this pull request
+           without locking them. To also check formatting, use --fmt.
+  cancel   Cancels the plans and applies running for this pull request.
   help     View help.
 
 Flags:
@@ -815,34 +861,383 @@ func TestParse_VCSUsername(t *testing.T) {
 	}
 }
 
+func TestParse_Import(t *testing.T) {
+	cases := []struct {
+		comment      string
+		expDir       string
+		expWorkspace string
+		expProject   string
+		expFlags     []string
+	}{
+		{
+			"atlantis import aws_instance.example i-abcd1234",
+			"",
+			"",
+			"",
+			[]string{"aws_instance.example", "i-abcd1234"},
+		},
+		{
+			"atlantis import -d dir -w staging aws_instance.example i-abcd1234",
+			"dir",
+			"staging",
+			"",
+			[]string{"aws_instance.example", "i-abcd1234"},
+		},
+		{
+			"atlantis import -p project 'aws_instance.example[\"a\"]' i-abcd1234",
+			"",
+			"",
+			"project",
+			[]string{"aws_instance.example[\"a\"]", "i-abcd1234"},
+		},
+		{
+			"atlantis import aws_instance.example i-abcd1234 -- -var foo=bar",
+			"",
+			"",
+			"",
+			[]string{"-var", "foo=bar", "aws_instance.example", "i-abcd1234"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.comment, func(t *testing.T) {
+			r := commentParser.Parse(c.comment, models.Github)
+			Equals(t, "", r.CommentResponse)
+			Equals(t, models.ImportCommand, r.Command.Name)
+			Equals(t, c.expDir, r.Command.RepoRelDir)
+			Equals(t, c.expWorkspace, r.Command.Workspace)
+			Equals(t, c.expProject, r.Command.ProjectName)
+			Equals(t, c.expFlags, r.Command.Flags)
+		})
+	}
+}
+
+func TestParse_Destroy(t *testing.T) {
+	r := commentParser.Parse("atlantis plan -d dir --destroy", models.Github)
+	Equals(t, "", r.CommentResponse)
+	Equals(t, models.PlanCommand, r.Command.Name)
+	Equals(t, "dir", r.Command.RepoRelDir)
+	Equals(t, true, r.Command.Destroy)
+
+	r = commentParser.Parse("atlantis plan -d dir", models.Github)
+	Equals(t, false, r.Command.Destroy)
+
+	t.Log("only plan accepts --destroy")
+	r = commentParser.Parse("atlantis apply --destroy", models.Github)
+	Assert(t, strings.Contains(r.CommentResponse, "unknown flag: --destroy"), "exp unknown flag error but got %q", r.CommentResponse)
+}
+
+func TestParse_Target(t *testing.T) {
+	r := commentParser.Parse(`atlantis plan -d dir --target aws_instance.web --target 'module.app["prod"].aws_instance.web[0]'`, models.Github)
+	Equals(t, "", r.CommentResponse)
+	Equals(t, []string{"aws_instance.web", `module.app["prod"].aws_instance.web[0]`}, r.Command.Targets)
+
+	r = commentParser.Parse("atlantis apply -p project --target module.db --target data.aws_ami.ubuntu", models.Github)
+	Equals(t, "", r.CommentResponse)
+	Equals(t, models.ApplyCommand, r.Command.Name)
+	Equals(t, []string{"module.db", "data.aws_ami.ubuntu"}, r.Command.Targets)
+
+	r = commentParser.Parse("atlantis plan -d dir", models.Github)
+	Equals(t, []string(nil), r.Command.Targets)
+
+	for _, target := range []string{"aws_instance", "aws_instance.", "module", "aws_instance.web[prod]", "aws_instance.web;rm", "-destroy", "module.app.aws_instance"} {
+		t.Run(target, func(t *testing.T) {
+			r := commentParser.Parse(fmt.Sprintf("atlantis plan --target '%s'", target), models.Github)
+			Assert(t, strings.Contains(r.CommentResponse, fmt.Sprintf("invalid resource address %q for --target", target)), "exp invalid address error but got %q", r.CommentResponse)
+		})
+	}
+}
+
+func TestParse_Force(t *testing.T) {
+	r := commentParser.Parse("atlantis apply -p project --force", models.Github)
+	Equals(t, "", r.CommentResponse)
+	Equals(t, models.ApplyCommand, r.Command.Name)
+	Equals(t, "project", r.Command.ProjectName)
+	Equals(t, true, r.Command.Force)
+
+	r = commentParser.Parse("atlantis apply", models.Github)
+	Equals(t, false, r.Command.Force)
+
+	t.Log("only apply accepts --force")
+	r = commentParser.Parse("atlantis plan --force", models.Github)
+	Assert(t, strings.Contains(r.CommentResponse, "unknown flag: --force"), "exp unknown flag error but got %q", r.CommentResponse)
+}
+
+func TestParse_Confirm(t *testing.T) {
+	r := commentParser.Parse("atlantis apply -p project --confirm", models.Github)
+	Equals(t, "", r.CommentResponse)
+	Equals(t, models.ApplyCommand, r.Command.Name)
+	Equals(t, "project", r.Command.ProjectName)
+	Equals(t, true, r.Command.Confirm)
+
+	r = commentParser.Parse("atlantis apply", models.Github)
+	Equals(t, false, r.Command.Confirm)
+
+	t.Log("only apply accepts --confirm")
+	r = commentParser.Parse("atlantis plan --confirm", models.Github)
+	Assert(t, strings.Contains(r.CommentResponse, "unknown flag: --confirm"), "exp unknown flag error but got %q", r.CommentResponse)
+}
+
+func TestParse_Validate(t *testing.T) {
+	r := commentParser.Parse("atlantis validate", models.Github)
+	Equals(t, "", r.CommentResponse)
+	Equals(t, models.ValidateCommand, r.Command.Name)
+	Equals(t, false, r.Command.IsForSpecificProject())
+	Equals(t, false, r.Command.Fmt)
+
+	r = commentParser.Parse("atlantis validate -d dir -w staging --fmt -- -json", models.Github)
+	Equals(t, "", r.CommentResponse)
+	Equals(t, "dir", r.Command.RepoRelDir)
+	Equals(t, "staging", r.Command.Workspace)
+	Equals(t, true, r.Command.Fmt)
+	Equals(t, []string{"-json"}, r.Command.Flags)
+
+	t.Log("only validate accepts --fmt")
+	r = commentParser.Parse("atlantis plan --fmt", models.Github)
+	Assert(t, strings.Contains(r.CommentResponse, "unknown flag: --fmt"), "exp unknown flag error but got %q", r.CommentResponse)
+}
+
+func TestParse_MultiTarget(t *testing.T) {
+	r := commentParser.Parse("atlantis plan -d 'stacks/prod/*,./shared/' -w staging,prod-*", models.Github)
+	Equals(t, "", r.CommentResponse)
+	Equals(t, "stacks/prod/*,shared", r.Command.RepoRelDir)
+	Equals(t, "staging,prod-*", r.Command.Workspace)
+	Equals(t, true, r.Command.IsMultiTarget())
+
+	r = commentParser.Parse("atlantis apply -d dir -w staging", models.Github)
+	Equals(t, "", r.CommentResponse)
+	Equals(t, false, r.Command.IsMultiTarget())
+
+	cases := []struct {
+		comment string
+		expErr  string
+	}{
+		{"atlantis plan -d dir,,other", `Error: invalid list "dir,,other": dirs can't be empty`},
+		{"atlantis plan -d dir,../other", `Error: using a relative path "../other"`},
+		{"atlantis plan -d 'stacks/[prod'", `Error: invalid pattern "stacks/[prod"`},
+		{"atlantis plan -w staging,../prod", `Error: invalid workspace: "../prod"`},
+		{"atlantis plan -w staging,", `Error: invalid workspace: ""`},
+		{"atlantis plan -w .atlantis-clone", `Error: invalid workspace: ".atlantis-clone" is reserved by Atlantis`},
+		{"atlantis unlock -d 'stacks/*'", "Error: -d/--dir and -w/--workspace can't be lists or patterns with unlock"},
+		{"atlantis import -d dir -w a,b ADDR ID", "Error: -d/--dir and -w/--workspace can't be lists or patterns with import"},
+		{"atlantis state rm -d 'stacks/*' ADDR", "Error: -d/--dir and -w/--workspace can't be lists or patterns with state"},
+	}
+	for _, c := range cases {
+		t.Run(c.comment, func(t *testing.T) {
+			r := commentParser.Parse(c.comment, models.Github)
+			Assert(t, strings.Contains(r.CommentResponse, c.expErr),
+				"expected CommentResponse %q to contain %q", r.CommentResponse, c.expErr)
+		})
+	}
+}
+
+func TestParse_Cancel(t *testing.T) {
+	r := commentParser.Parse("atlantis cancel", models.Github)
+	Equals(t, "", r.CommentResponse)
+	Equals(t, models.CancelCommand, r.Command.Name)
+
+	t.Log("cancel doesn't accept any flags")
+	r = commentParser.Parse("atlantis cancel -d dir", models.Github)
+	Assert(t, strings.Contains(r.CommentResponse, "unknown shorthand flag: 'd'"), "exp unknown flag error but got %q", r.CommentResponse)
+}
+
+func TestParse_CustomCommand(t *testing.T) {
+	globalCfg := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})
+	globalCfg.CustomCommands = map[string]valid.CustomCommand{
+		"deploy": {Name: "deploy", Description: "Deploys the pull request.", Workflow: globalCfg.Workflows["default"]},
+	}
+	parser := events.CommentParser{GithubUser: "github-user", GlobalCfg: valid.NewGlobalCfgStore(globalCfg)}
+
+	r := parser.Parse("atlantis deploy -p project --verbose -- -arg", models.Github)
+	Equals(t, "", r.CommentResponse)
+	Equals(t, models.CustomCommand, r.Command.Name)
+	Equals(t, "deploy", r.Command.SubName)
+	Equals(t, "deploy", r.Command.DisplayName())
+	Equals(t, "project", r.Command.ProjectName)
+	Equals(t, true, r.Command.Verbose)
+	Equals(t, []string{"-arg"}, r.Command.Flags)
+
+	r = parser.Parse("atlantis deploy -d 'stacks/*'", models.Github)
+	Equals(t, "", r.CommentResponse)
+	Equals(t, true, r.Command.IsMultiTarget())
+
+	r = parser.Parse("atlantis deploy --help", models.Github)
+	Assert(t, strings.HasPrefix(r.CommentResponse, "```\nDeploys the pull request.\n\nUsage of deploy:\n"), "exp usage but got %q", r.CommentResponse)
+	Assert(t, strings.Contains(r.CommentResponse, "Which directory to run deploy in"), "exp usage but got %q", r.CommentResponse)
+
+	r = parser.Parse("atlantis help", models.Github)
+	Assert(t, strings.Contains(r.CommentResponse, "  deploy   Deploys the pull request.\n  help "), "exp deploy in help but got %q", r.CommentResponse)
+
+	t.Log("custom commands are unknown if they aren't configured")
+	r = commentParser.Parse("atlantis deploy", models.Github)
+	Assert(t, strings.Contains(r.CommentResponse, `Error: unknown command "deploy"`), "exp unknown command but got %q", r.CommentResponse)
+}
+
+func TestParse_ImportWrongNumberOfArgs(t *testing.T) {
+	for _, comment := range []string{
+		"atlantis import",
+		"atlantis import aws_instance.example",
+		"atlantis import aws_instance.example i-abcd1234 extra",
+		"atlantis import -- aws_instance.example i-abcd1234",
+	} {
+		t.Run(comment, func(t *testing.T) {
+			r := commentParser.Parse(comment, models.Github)
+			Equals(t, fmt.Sprintf("```\nError: import requires exactly two arguments: ADDRESS and ID.\n%s```", ImportUsage), r.CommentResponse)
+		})
+	}
+}
+
+func TestParse_State(t *testing.T) {
+	cases := []struct {
+		comment      string
+		expSubName   string
+		expDir       string
+		expWorkspace string
+		expProject   string
+		expFlags     []string
+	}{
+		{
+			"atlantis state rm aws_instance.example",
+			"rm",
+			"",
+			"",
+			"",
+			[]string{"aws_instance.example"},
+		},
+		{
+			"atlantis state rm -d dir -w staging aws_instance.a aws_instance.b",
+			"rm",
+			"dir",
+			"staging",
+			"",
+			[]string{"aws_instance.a", "aws_instance.b"},
+		},
+		{
+			"atlantis state mv -p project aws_instance.a 'aws_instance.b[\"key\"]'",
+			"mv",
+			"",
+			"",
+			"project",
+			[]string{"aws_instance.a", "aws_instance.b[\"key\"]"},
+		},
+		{
+			"atlantis state rm aws_instance.example -- -lock=false",
+			"rm",
+			"",
+			"",
+			"",
+			[]string{"-lock=false", "aws_instance.example"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.comment, func(t *testing.T) {
+			r := commentParser.Parse(c.comment, models.Github)
+			Equals(t, "", r.CommentResponse)
+			Equals(t, models.StateCommand, r.Command.Name)
+			Equals(t, c.expSubName, r.Command.SubName)
+			Equals(t, c.expDir, r.Command.RepoRelDir)
+			Equals(t, c.expWorkspace, r.Command.Workspace)
+			Equals(t, c.expProject, r.Command.ProjectName)
+			Equals(t, c.expFlags, r.Command.Flags)
+		})
+	}
+}
+
+func TestParse_StateErrors(t *testing.T) {
+	cases := []struct {
+		comment string
+		expErr  string
+	}{
+		{
+			"atlantis state",
+			"state requires a subcommand: rm or mv",
+		},
+		{
+			"atlantis state list",
+			"state requires a subcommand: rm or mv",
+		},
+		{
+			"atlantis state -d . rm aws_instance.example",
+			"state requires a subcommand: rm or mv",
+		},
+		{
+			"atlantis state rm",
+			"state rm requires at least one argument: ADDRESS",
+		},
+		{
+			"atlantis state mv aws_instance.a",
+			"state mv requires exactly two arguments: SOURCE and DESTINATION",
+		},
+		{
+			"atlantis state mv aws_instance.a aws_instance.b aws_instance.c",
+			"state mv requires exactly two arguments: SOURCE and DESTINATION",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.comment, func(t *testing.T) {
+			r := commentParser.Parse(c.comment, models.Github)
+			Equals(t, fmt.Sprintf("```\nError: %s.\n%s```", c.expErr, StateUsage), r.CommentResponse)
+		})
+	}
+}
+
 var PlanUsage = `Usage of plan:
+      --destroy            Plan to destroy every resource. Must be allowed by the
+                           server-side repo config.
   -d, --dir string         Which directory to run plan in relative to root of repo,
                            ex. 'child/dir'.
   -p, --project string     Which project to run plan for. Refers to the name of the
                            project configured in atlantis.yaml. Cannot be used at
                            same time as workspace or dir flags.
+      --target address     Limit the plan to this resource address, ex.
+                           'aws_instance.web'. Can be repeated.
       --verbose            Append Atlantis log to comment.
   -w, --workspace string   Switch to this Terraform workspace before planning.
 `
 
 var ApplyUsage = `Usage of apply:
       --auto-merge-disabled   Disable automerge after apply.
+      --confirm               Confirm an apply that's waiting for confirmation.
   -d, --dir string            Apply the plan for this directory, relative to root of
                               repo, ex. 'child/dir'.
+      --force                 Apply plans even if they were generated from an
+                              earlier commit of the pull request.
   -p, --project string        Apply the plan for this project. Refers to the name of
                               the project configured in atlantis.yaml. Cannot be
                               used at same time as workspace or dir flags.
+      --target address        Only apply plans that were targeted at exactly these
+                              resource addresses. Can be repeated.
       --verbose               Append Atlantis log to comment.
   -w, --workspace string      Apply the plan for this Terraform workspace.
 `
 
+var ImportUsage = `Usage of import:
+  -d, --dir string         Which directory to run import in relative to root of
+                           repo, ex. 'child/dir'.
+  -p, --project string     Which project to run import for. Refers to the name of
+                           the project configured in atlantis.yaml. Cannot be used
+                           at same time as workspace or dir flags.
+      --verbose            Append Atlantis log to comment.
+  -w, --workspace string   Switch to this Terraform workspace before importing.
+`
+
 var ApprovePolicyUsage = `Usage of approve_policies:
       --verbose   Append Atlantis log to comment.
 `
 var UnlockUsage = "`Usage of unlock:`\n\n ```cmake\n" +
-	`atlantis unlock	
+	`atlantis unlock [-d dir] [-w workspace]
 
   Unlocks the entire PR and discards all plans in this PR.
-  Arguments or flags are not supported at the moment.
-  If you need to unlock a specific project please use the atlantis UI.` +
+  To only unlock a specific project, use -d and/or -w:
+  -d, --dir string         Only unlock the project in this directory, relative to root of repo, ex. 'child/dir'.
+  -w, --workspace string   Only unlock projects in this Terraform workspace.` +
 	"\n```"
+
+var StateUsage = `Usage of state:
+  -d, --dir string         Which directory to modify state in relative to root of
+                           repo, ex. 'child/dir'.
+  -p, --project string     Which project to modify state for. Refers to the name of
+                           the project configured in atlantis.yaml. Cannot be used
+                           at same time as workspace or dir flags.
+      --verbose            Append Atlantis log to comment.
+  -w, --workspace string   Switch to this Terraform workspace before modifying state.
+`
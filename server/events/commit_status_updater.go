@@ -19,6 +19,7 @@ import (
 
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/i18n"
 )
 
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_commit_status_updater.go CommitStatusUpdater
@@ -35,6 +36,13 @@ type CommitStatusUpdater interface {
 	// UpdateProject sets the commit status for the project represented by
 	// ctx.
 	UpdateProject(ctx models.ProjectCommandContext, cmdName models.CommandName, status models.CommitStatus, url string) error
+	// UpdateProjectResult sets the commit status for the project represented
+	// by ctx from the result of running a command on it. The description
+	// summarizes the resources a plan or apply changed.
+	UpdateProjectResult(ctx models.ProjectCommandContext, result models.ProjectResult, url string) error
+	// UpdateMergeConflict fails the combined status of the head commit of
+	// pull because pull can't be merged into its base branch.
+	UpdateMergeConflict(repo models.Repo, pull models.PullRequest, command models.CommandName) error
 }
 
 // DefaultCommitStatusUpdater implements CommitStatusUpdater.
@@ -42,54 +50,109 @@ type DefaultCommitStatusUpdater struct {
 	Client vcs.Client
 	// StatusName is the name used to identify Atlantis when creating PR statuses.
 	StatusName string
+	// HistoryURLGenerator, if set, is used to link the statuses of whole
+	// commands to the pull request's history page.
+	HistoryURLGenerator HistoryURLGenerator
+	// Localizer picks the locale of the status descriptions.
+	Localizer *i18n.Localizer
 }
 
 func (d *DefaultCommitStatusUpdater) UpdateCombined(repo models.Repo, pull models.PullRequest, status models.CommitStatus, command models.CommandName) error {
 	src := fmt.Sprintf("%s/%s", d.StatusName, command.String())
-	var descripWords string
-	switch status {
-	case models.PendingCommitStatus:
-		descripWords = "in progress..."
-	case models.FailedCommitStatus:
-		descripWords = "failed."
-	case models.SuccessCommitStatus:
-		descripWords = "succeeded."
-	}
-	descrip := fmt.Sprintf("%s %s", strings.Title(command.String()), descripWords)
-	return d.Client.UpdateStatus(repo, pull, status, src, descrip, "")
+	descrip := statusDescription(d.Localizer.For(repo, pull.BaseBranch), status, command)
+	return d.Client.UpdateStatus(repo, pull, status, src, descrip, d.historyURL(repo, pull))
 }
 
 func (d *DefaultCommitStatusUpdater) UpdateCombinedCount(repo models.Repo, pull models.PullRequest, status models.CommitStatus, command models.CommandName, numSuccess int, numTotal int) error {
 	src := fmt.Sprintf("%s/%s", d.StatusName, command.String())
-	cmdVerb := "unknown"
+	msg := i18n.StatusProjectsUnknown
 
 	switch command {
 	case models.PlanCommand:
-		cmdVerb = "planned"
+		msg = i18n.StatusProjectsPlanned
 	case models.PolicyCheckCommand:
-		cmdVerb = "policies checked"
+		msg = i18n.StatusProjectsPolicyChecked
 	case models.ApplyCommand:
-		cmdVerb = "applied"
+		msg = i18n.StatusProjectsApplied
 	}
 
-	return d.Client.UpdateStatus(repo, pull, status, src, fmt.Sprintf("%d/%d projects %s successfully.", numSuccess, numTotal, cmdVerb), "")
+	return d.Client.UpdateStatus(repo, pull, status, src, d.Localizer.Sprintf(repo, pull.BaseBranch, msg, numSuccess, numTotal), d.historyURL(repo, pull))
 }
 
 func (d *DefaultCommitStatusUpdater) UpdateProject(ctx models.ProjectCommandContext, cmdName models.CommandName, status models.CommitStatus, url string) error {
-	projectID := ctx.ProjectName
-	if projectID == "" {
-		projectID = fmt.Sprintf("%s/%s", ctx.RepoRelDir, ctx.Workspace)
+	descrip := statusDescription(d.Localizer.For(ctx.BaseRepo, ctx.Pull.BaseBranch), status, cmdName)
+	return d.Client.UpdateStatus(ctx.BaseRepo, ctx.Pull, status, d.projectSrc(ctx, cmdName), descrip, url)
+}
+
+func (d *DefaultCommitStatusUpdater) UpdateProjectResult(ctx models.ProjectCommandContext, result models.ProjectResult, url string) error {
+	status := models.SuccessCommitStatus
+	if !result.IsSuccessful() {
+		status = models.FailedCommitStatus
 	}
-	src := fmt.Sprintf("%s/%s: %s", d.StatusName, cmdName.String(), projectID)
-	var descripWords string
+	descrip := projectResultDescription(d.Localizer.For(ctx.BaseRepo, ctx.Pull.BaseBranch), result)
+	return d.Client.UpdateStatus(ctx.BaseRepo, ctx.Pull, status, d.projectSrc(ctx, result.Command), descrip, url)
+}
+
+func (d *DefaultCommitStatusUpdater) UpdateMergeConflict(repo models.Repo, pull models.PullRequest, command models.CommandName) error {
+	src := fmt.Sprintf("%s/%s", d.StatusName, command.String())
+	descrip := d.Localizer.Sprintf(repo, pull.BaseBranch, i18n.StatusMergeConflicts, strings.Title(command.String()), pull.BaseBranch)
+	return d.Client.UpdateStatus(repo, pull, models.FailedCommitStatus, src, descrip, d.historyURL(repo, pull))
+}
+
+// statusDescription returns the description, in locale, of a status for
+// command, ex. "Plan in progress...".
+func statusDescription(locale string, status models.CommitStatus, command models.CommandName) string {
+	var msg i18n.Message
 	switch status {
 	case models.PendingCommitStatus:
-		descripWords = "in progress..."
+		msg = i18n.StatusInProgress
 	case models.FailedCommitStatus:
-		descripWords = "failed."
+		msg = i18n.StatusFailed
 	case models.SuccessCommitStatus:
-		descripWords = "succeeded."
+		msg = i18n.StatusSucceeded
+	}
+	return i18n.Sprintf(locale, msg, strings.Title(command.String()))
+}
+
+// projectSrc returns the name of the status for running cmdName on ctx's
+// project, ex. atlantis/plan: dir/default.
+func (d *DefaultCommitStatusUpdater) projectSrc(ctx models.ProjectCommandContext, cmdName models.CommandName) string {
+	projectID := ctx.ProjectName
+	if projectID == "" {
+		projectID = fmt.Sprintf("%s/%s", ctx.RepoRelDir, ctx.Workspace)
+	}
+	return fmt.Sprintf("%s/%s: %s", d.StatusName, cmdName.String(), projectID)
+}
+
+// historyURL returns the URL that the statuses of whole commands on pull link
+// to, or an empty string if there's no history page.
+func (d *DefaultCommitStatusUpdater) historyURL(repo models.Repo, pull models.PullRequest) string {
+	if d.HistoryURLGenerator == nil {
+		return ""
+	}
+	return d.HistoryURLGenerator.GenerateHistoryURL(repo.FullName, pull.Num)
+}
+
+// projectResultDescription returns the description, in locale, of the status
+// for result, ex. "Plan: 3 to add, 1 to change, 0 to destroy.". Statuses only
+// show a single line so the counts are used instead of the output.
+func projectResultDescription(locale string, result models.ProjectResult) string {
+	cmdTitle := strings.Title(result.Command.String())
+	if !result.IsSuccessful() {
+		return i18n.Sprintf(locale, i18n.StatusFailed, cmdTitle)
+	}
+	if result.PlanSuccess != nil {
+		if changes, ok := result.PlanSuccess.Changes(); ok {
+			if changes == (models.PlanChanges{}) {
+				return i18n.Sprintf(locale, i18n.StatusPlanNoChanges)
+			}
+			return i18n.Sprintf(locale, i18n.StatusPlanChanges, changes.Add, changes.Change, changes.Destroy)
+		}
+	}
+	if result.ApplySuccess != "" {
+		if changes, ok := applyChanges(result.ApplySuccess); ok {
+			return i18n.Sprintf(locale, i18n.StatusApplyChanges, changes.Add, changes.Change, changes.Destroy)
+		}
 	}
-	descrip := fmt.Sprintf("%s %s", strings.Title(cmdName.String()), descripWords)
-	return d.Client.UpdateStatus(ctx.BaseRepo, ctx.Pull, status, src, descrip, url)
+	return i18n.Sprintf(locale, i18n.StatusSucceeded, cmdTitle)
 }
@@ -14,6 +14,7 @@
 package events_test
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
@@ -21,6 +22,7 @@ import (
 	"github.com/runatlantis/atlantis/server/events"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/vcs/mocks"
+	"github.com/runatlantis/atlantis/server/i18n"
 	. "github.com/runatlantis/atlantis/testing"
 )
 
@@ -257,3 +259,85 @@ func TestDefaultCommitStatusUpdater_UpdateProjectCustomStatusName(t *testing.T)
 	client.VerifyWasCalledOnce().UpdateStatus(models.Repo{}, models.PullRequest{},
 		models.SuccessCommitStatus, "custom/apply: ./default", "Apply succeeded.", "url")
 }
+
+// Test that descriptions are written in the server's locale.
+func TestDefaultCommitStatusUpdater_UpdateProjectLocale(t *testing.T) {
+	RegisterMockTestingT(t)
+	client := mocks.NewMockClient()
+	s := events.DefaultCommitStatusUpdater{Client: client, StatusName: "atlantis", Localizer: &i18n.Localizer{Locale: i18n.Japanese}}
+	err := s.UpdateProject(models.ProjectCommandContext{
+		RepoRelDir: ".",
+		Workspace:  "default",
+	},
+		models.PlanCommand,
+		models.PendingCommitStatus,
+		"url")
+	Ok(t, err)
+	client.VerifyWasCalledOnce().UpdateStatus(models.Repo{}, models.PullRequest{},
+		models.PendingCommitStatus, "atlantis/plan: ./default", "Plan 実行中...", "url")
+}
+
+// Test that the description summarizes the resources the result changed.
+func TestDefaultCommitStatusUpdater_UpdateProjectResult(t *testing.T) {
+	RegisterMockTestingT(t)
+	cases := []struct {
+		result     models.ProjectResult
+		expStatus  models.CommitStatus
+		expDescrip string
+	}{
+		{
+			models.ProjectResult{Command: models.PlanCommand, PlanSuccess: &models.PlanSuccess{TerraformOutput: "Plan: 3 to add, 1 to change, 0 to destroy."}},
+			models.SuccessCommitStatus,
+			"Plan: 3 to add, 1 to change, 0 to destroy.",
+		},
+		{
+			models.ProjectResult{Command: models.PlanCommand, PlanSuccess: &models.PlanSuccess{TerraformOutput: "No changes. Your infrastructure matches the configuration."}},
+			models.SuccessCommitStatus,
+			"Plan: no changes.",
+		},
+		{
+			models.ProjectResult{Command: models.PlanCommand, PlanSuccess: &models.PlanSuccess{TerraformOutput: "custom output"}},
+			models.SuccessCommitStatus,
+			"Plan succeeded.",
+		},
+		{
+			models.ProjectResult{Command: models.PlanCommand, Error: errors.New("err")},
+			models.FailedCommitStatus,
+			"Plan failed.",
+		},
+		{
+			models.ProjectResult{Command: models.ApplyCommand, ApplySuccess: "Apply complete! Resources: 1 added, 0 changed, 2 destroyed."},
+			models.SuccessCommitStatus,
+			"Apply: 1 added, 0 changed, 2 destroyed.",
+		},
+		{
+			models.ProjectResult{Command: models.ApplyCommand, Failure: "locked"},
+			models.FailedCommitStatus,
+			"Apply failed.",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.expDescrip, func(t *testing.T) {
+			client := mocks.NewMockClient()
+			s := events.DefaultCommitStatusUpdater{Client: client, StatusName: "atlantis"}
+			err := s.UpdateProjectResult(models.ProjectCommandContext{
+				RepoRelDir: ".",
+				Workspace:  "default",
+			}, c.result, "url")
+			Ok(t, err)
+			client.VerifyWasCalledOnce().UpdateStatus(models.Repo{}, models.PullRequest{}, c.expStatus, fmt.Sprintf("atlantis/%s: ./default", c.result.Command.String()), c.expDescrip, "url")
+		})
+	}
+}
+
+// Test that the statuses of whole commands link to the pull request's history.
+func TestDefaultCommitStatusUpdater_HistoryURL(t *testing.T) {
+	RegisterMockTestingT(t)
+	client := mocks.NewMockClient()
+	s := events.DefaultCommitStatusUpdater{Client: client, StatusName: "atlantis", HistoryURLGenerator: mockURLGenerator{}}
+	repo := models.Repo{FullName: "owner/repo"}
+	pull := models.PullRequest{Num: 1}
+	Ok(t, s.UpdateCombinedCount(repo, pull, models.SuccessCommitStatus, models.PlanCommand, 1, 1))
+	client.VerifyWasCalledOnce().UpdateStatus(repo, pull, models.SuccessCommitStatus, "atlantis/plan", "1/1 projects planned successfully.", "https://history/owner/repo/1")
+}
@@ -0,0 +1,70 @@
+package events
+
+import (
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+)
+
+func NewCustomCommandRunner(
+	vcsClient vcs.Client,
+	pullUpdater *PullUpdater,
+	prjCmdBuilder ProjectCustomCommandBuilder,
+	prjCmdRunner ProjectCustomCommandRunner,
+	parallelPoolSize int,
+) *CustomCommandRunner {
+	return &CustomCommandRunner{
+		vcsClient:        vcsClient,
+		pullUpdater:      pullUpdater,
+		prjCmdBuilder:    prjCmdBuilder,
+		prjCmdRunner:     prjCmdRunner,
+		parallelPoolSize: parallelPoolSize,
+	}
+}
+
+// CustomCommandRunner runs the custom commands defined in the server-side repo
+// config, ex. atlantis deploy, in the projects affected by a pull request.
+type CustomCommandRunner struct {
+	vcsClient        vcs.Client
+	pullUpdater      *PullUpdater
+	prjCmdBuilder    ProjectCustomCommandBuilder
+	prjCmdRunner     ProjectCustomCommandRunner
+	parallelPoolSize int
+}
+
+func (c *CustomCommandRunner) Run(ctx *CommandContext, cmd *CommentCommand) {
+	// Custom commands are held to the apply requirements, which can include
+	// the pull request being mergeable.
+	var err error
+	ctx.PullMergeable, err = c.vcsClient.PullIsMergeable(ctx.Pull.BaseRepo, ctx.Pull)
+	if err != nil {
+		ctx.PullMergeable = false
+		ctx.Log.Warn("unable to get mergeable status: %s. Continuing with mergeable assumed false", err)
+	}
+
+	projectCmds, err := c.prjCmdBuilder.BuildCustomCommands(ctx, cmd)
+	if err != nil {
+		c.pullUpdater.updatePull(ctx, cmd, CommandResult{Error: err})
+		return
+	}
+
+	if len(projectCmds) == 0 {
+		ctx.Log.Info("no projects to run %s in", cmd.SubName)
+		return
+	}
+
+	// Custom commands run wherever plan would so they're parallel when plan
+	// is.
+	var result CommandResult
+	if c.isParallelEnabled(projectCmds) {
+		ctx.Log.Info("Running %s in parallel", cmd.SubName)
+		result = runProjectCmdsParallel(projectCmds, c.prjCmdRunner.Custom, c.parallelPoolSize)
+	} else {
+		result = runProjectCmds(projectCmds, c.prjCmdRunner.Custom)
+	}
+
+	c.pullUpdater.updatePull(ctx, cmd, result)
+}
+
+func (c *CustomCommandRunner) isParallelEnabled(cmds []models.ProjectCommandContext) bool {
+	return len(cmds) > 0 && cmds[0].ParallelPlanEnabled
+}
@@ -1,12 +1,14 @@
 package events
 
 import (
-	"github.com/runatlantis/atlantis/server/core/db"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/core/locking"
 	"github.com/runatlantis/atlantis/server/events/models"
 )
 
 type DBUpdater struct {
-	DB *db.BoltDB
+	DB locking.Backend
 }
 
 func (c *DBUpdater) updateDB(ctx *CommandContext, pull models.PullRequest, results []models.ProjectResult) (models.PullStatus, error) {
@@ -22,5 +24,50 @@ func (c *DBUpdater) updateDB(ctx *CommandContext, pull models.PullRequest, resul
 		filtered = append(filtered, r)
 	}
 	ctx.Log.Debug("updating DB with pull results")
-	return c.DB.UpdatePullWithResults(pull, filtered)
+	pullStatus, err := c.DB.UpdatePullWithResults(pull, filtered)
+	if err != nil {
+		return pullStatus, err
+	}
+	c.addHistory(ctx, pull, filtered)
+	return pullStatus, nil
+}
+
+// addHistory saves the output of plan, policy check and apply results so they
+// can still be viewed after the pull request's status is deleted. Failing to save history
+// is only logged because it shouldn't fail the command.
+func (c *DBUpdater) addHistory(ctx *CommandContext, pull models.PullRequest, results []models.ProjectResult) {
+	now := time.Now()
+	for _, r := range results {
+		if r.Command != models.PlanCommand && r.Command != models.PolicyCheckCommand && r.Command != models.ApplyCommand {
+			continue
+		}
+		history := models.ProjectHistory{
+			Pull:        pull,
+			Workspace:   r.Workspace,
+			RepoRelDir:  r.RepoRelDir,
+			ProjectName: r.ProjectName,
+			Command:     r.Command,
+			Status:      r.PlanStatus(),
+			Output:      historyOutput(r),
+			Time:        now,
+		}
+		if err := c.DB.AddProjectHistory(history); err != nil {
+			ctx.Log.Warn("unable to save history for project at dir %q workspace %q: %s", r.RepoRelDir, r.Workspace, err)
+		}
+	}
+}
+
+func historyOutput(r models.ProjectResult) string {
+	switch {
+	case r.Error != nil:
+		return r.Error.Error()
+	case r.Failure != "":
+		return r.Failure
+	case r.PlanSuccess != nil:
+		return r.PlanSuccess.TerraformOutput
+	case r.PolicyCheckSuccess != nil:
+		return r.PolicyCheckSuccess.PolicyCheckOutput
+	default:
+		return r.ApplySuccess
+	}
 }
@@ -1,8 +1,8 @@
 package events
 
 import (
-	"github.com/runatlantis/atlantis/server/core/db"
 	"github.com/runatlantis/atlantis/server/core/locking"
+	"github.com/runatlantis/atlantis/server/core/planstorage"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/logging"
 )
@@ -13,6 +13,7 @@ import (
 type DeleteLockCommand interface {
 	DeleteLock(id string) (*models.ProjectLock, error)
 	DeleteLocksByPull(repoFullName string, pullNum int) (int, error)
+	DeleteProjectLocksByPull(repoFullName string, pullNum int, path string, workspace string) (int, error)
 }
 
 // DefaultDeleteLockCommand deletes a specific lock after a request from the LocksController.
@@ -21,7 +22,10 @@ type DefaultDeleteLockCommand struct {
 	Logger           logging.SimpleLogging
 	WorkingDir       WorkingDir
 	WorkingDirLocker WorkingDirLocker
-	DB               *db.BoltDB
+	DB               locking.Backend
+	// PlanStorage, if set, is where stored planfiles for deleted locks are
+	// deleted from.
+	PlanStorage planstorage.PlanStorage
 }
 
 // DeleteLock handles deleting the lock at id
@@ -58,6 +62,37 @@ func (l *DefaultDeleteLockCommand) DeleteLocksByPull(repoFullName string, pullNu
 	return numLocks, nil
 }
 
+// DeleteProjectLocksByPull deletes the locks held by the pull request for the
+// project at path and workspace. If path or workspace is empty, locks for any
+// path or workspace are deleted.
+func (l *DefaultDeleteLockCommand) DeleteProjectLocksByPull(repoFullName string, pullNum int, path string, workspace string) (int, error) {
+	locks, err := l.Locker.List()
+	if err != nil {
+		return 0, err
+	}
+
+	numLocks := 0
+	for key, lock := range locks {
+		if lock.Project.RepoFullName != repoFullName || lock.Pull.Num != pullNum {
+			continue
+		}
+		if (path != "" && lock.Project.Path != path) || (workspace != "" && lock.Workspace != workspace) {
+			continue
+		}
+		deleted, err := l.DeleteLock(key) // nolint: vetshadow
+		if err != nil {
+			return numLocks, err
+		}
+		if deleted != nil {
+			numLocks++
+		}
+	}
+	if numLocks == 0 {
+		l.Logger.Debug("No matching locks found for pull")
+	}
+	return numLocks, nil
+}
+
 func (l *DefaultDeleteLockCommand) deleteWorkingDir(lock models.ProjectLock) {
 	// NOTE: Because BaseRepo was added to the PullRequest model later, previous
 	// installations of Atlantis will have locks in their DB that do not have
@@ -76,6 +111,11 @@ func (l *DefaultDeleteLockCommand) deleteWorkingDir(lock models.ProjectLock) {
 			l.Logger.Err("unable to delete workspace: %s", err)
 		}
 	}
+	if l.PlanStorage != nil {
+		if err := l.PlanStorage.DeletePlans(lock.Pull.BaseRepo.FullName, lock.Pull.Num, lock.Workspace); err != nil {
+			l.Logger.Err("unable to delete stored plans: %s", err)
+		}
+	}
 	if err := l.DB.UpdateProjectStatus(lock.Pull, lock.Workspace, lock.Project.Path, models.DiscardedPlanStatus); err != nil {
 		l.Logger.Err("unable to delete project status: %s", err)
 	}
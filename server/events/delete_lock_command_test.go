@@ -133,3 +133,44 @@ func TestDeleteLocksByPull_OldFormat(t *testing.T) {
 	_, err := dlc.DeleteLocksByPull(repoName, pullNum)
 	Ok(t, err)
 }
+
+func TestDeleteProjectLocksByPull(t *testing.T) {
+	t.Log("Only locks held by the pull for the matching dir and workspace are deleted")
+	RegisterMockTestingT(t)
+	l := lockmocks.NewMockLocker()
+	lockFor := func(pullNum int, path string, workspace string) models.ProjectLock {
+		return models.ProjectLock{
+			Pull:      models.PullRequest{Num: pullNum},
+			Workspace: workspace,
+			Project:   models.Project{Path: path, RepoFullName: "owner/repo"},
+		}
+	}
+	locks := map[string]models.ProjectLock{
+		"owner/repo/dir/default":   lockFor(2, "dir", "default"),
+		"owner/repo/dir/staging":   lockFor(2, "dir", "staging"),
+		"owner/repo/other/default": lockFor(2, "other", "default"),
+		"owner/repo/./default":     lockFor(3, ".", "default"),
+	}
+	When(l.List()).ThenReturn(locks, nil)
+	for key, lock := range locks {
+		lock := lock
+		When(l.Unlock(key)).ThenReturn(&lock, nil)
+	}
+	dlc := events.DefaultDeleteLockCommand{
+		Locker: l,
+		Logger: logging.NewNoopLogger(t),
+	}
+
+	numLocks, err := dlc.DeleteProjectLocksByPull("owner/repo", 2, "dir", "")
+	Ok(t, err)
+	Equals(t, 2, numLocks)
+	l.VerifyWasCalledOnce().Unlock("owner/repo/dir/default")
+	l.VerifyWasCalledOnce().Unlock("owner/repo/dir/staging")
+	l.VerifyWasCalled(Never()).Unlock("owner/repo/other/default")
+
+	numLocks, err = dlc.DeleteProjectLocksByPull("owner/repo", 2, "", "default")
+	Ok(t, err)
+	Equals(t, 2, numLocks)
+	l.VerifyWasCalledOnce().Unlock("owner/repo/other/default")
+	l.VerifyWasCalled(Never()).Unlock("owner/repo/./default")
+}
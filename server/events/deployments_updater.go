@@ -0,0 +1,53 @@
+package events
+
+import (
+	"fmt"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+)
+
+// DeploymentsUpdater records each project apply as a deployment to an
+// environment named after the project, or its workspace if it isn't named,
+// so that the repo's environments show what was applied where.
+type DeploymentsUpdater struct {
+	Client vcs.DeploymentsClient
+}
+
+// Start creates an in progress deployment for the apply of ctx's project and
+// returns its id.
+func (d *DeploymentsUpdater) Start(ctx models.ProjectCommandContext, logURL string) (int64, error) {
+	id, err := d.Client.CreateDeployment(ctx.Pull.BaseRepo, ctx.Pull, deploymentEnvironment(ctx), d.description(ctx, "Applying"))
+	if err != nil {
+		return 0, err
+	}
+	return id, d.Client.UpdateDeploymentStatus(ctx.Pull.BaseRepo, id, models.PendingCommitStatus, logURL, d.description(ctx, "Applying"))
+}
+
+// Finish sets the state of the deployment with id to status.
+func (d *DeploymentsUpdater) Finish(ctx models.ProjectCommandContext, id int64, status models.CommitStatus, logURL string) error {
+	verb := "Applied"
+	if status == models.FailedCommitStatus {
+		verb = "Failed to apply"
+	}
+	return d.Client.UpdateDeploymentStatus(ctx.Pull.BaseRepo, id, status, logURL, d.description(ctx, verb))
+}
+
+// description describes the apply of ctx's project, ex. "Applied dir/default
+// from pull request #1". GitHub truncates descriptions longer than 140 chars.
+func (d *DeploymentsUpdater) description(ctx models.ProjectCommandContext, verb string) string {
+	projectID := ctx.ProjectName
+	if projectID == "" {
+		projectID = fmt.Sprintf("%s/%s", ctx.RepoRelDir, ctx.Workspace)
+	}
+	return fmt.Sprintf("%s %s from pull request #%d", verb, projectID, ctx.Pull.Num)
+}
+
+// deploymentEnvironment returns the environment that applying ctx's project
+// deploys to.
+func deploymentEnvironment(ctx models.ProjectCommandContext) string {
+	if ctx.ProjectName != "" {
+		return ctx.ProjectName
+	}
+	return ctx.Workspace
+}
@@ -0,0 +1,83 @@
+package events
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// diskUsageCacheDuration is how long the data dir's measured size is reused
+// for. Measuring it walks the whole data dir so it isn't done for every plan.
+const diskUsageCacheDuration = time.Minute
+
+// DiskUsageLimiter rejects new plans once the data dir is using more than
+// MaxBytes so that they don't fail part way through with "no space left on
+// device" errors.
+type DiskUsageLimiter struct {
+	DataDir  string
+	MaxBytes int64
+	// GC, if set, is run in the background when the limit is exceeded to free
+	// up space.
+	GC *WorkingDirGC
+
+	mutex      sync.Mutex
+	usedBytes  int64
+	measuredAt time.Time
+	gcRunning  bool
+}
+
+// Exceeded returns true if the data dir is using more than MaxBytes, along
+// with how many bytes it's using. If it returns true, GC is started if it
+// isn't already running.
+func (l *DiskUsageLimiter) Exceeded() (bool, int64) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if time.Since(l.measuredAt) > diskUsageCacheDuration {
+		l.usedBytes = dirSize(l.DataDir)
+		l.measuredAt = time.Now()
+	}
+	if l.usedBytes <= l.MaxBytes {
+		return false, l.usedBytes
+	}
+	if l.GC != nil && !l.gcRunning {
+		l.gcRunning = true
+		go l.runGC()
+	}
+	return true, l.usedBytes
+}
+
+// Check returns an error if the data dir is using more than MaxBytes. It's used
+// by readiness checks.
+func (l *DiskUsageLimiter) Check() error {
+	if exceeded, used := l.Exceeded(); exceeded {
+		return fmt.Errorf("data dir is using %s, more than the limit of %s", formatBytes(used), formatBytes(l.MaxBytes))
+	}
+	return nil
+}
+
+// runGC runs a collection and then forgets the measured size so the space it
+// freed up is noticed by the next call to Exceeded.
+func (l *DiskUsageLimiter) runGC() {
+	l.GC.Collect()
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.gcRunning = false
+	l.measuredAt = time.Time{}
+}
+
+// formatBytes formats b in the largest unit that keeps it at least 1, ex.
+// 1.5 GiB.
+func formatBytes(b int64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
@@ -1,7 +1,9 @@
 package events
 
 import (
+	"sort"
 	"sync"
+	"time"
 )
 
 // Drainer is used to gracefully shut down atlantis by waiting for in-progress
@@ -10,6 +12,10 @@ type Drainer struct {
 	status DrainStatus
 	mutex  sync.Mutex
 	wg     sync.WaitGroup
+	// ops are the in-progress operations that were started with
+	// StartOperation, keyed by an ID.
+	ops    map[uint64]Operation
+	nextOp uint64
 }
 
 type DrainStatus struct {
@@ -17,6 +23,18 @@ type DrainStatus struct {
 	ShuttingDown bool
 	// InProgressOps is the number of operations currently in progress.
 	InProgressOps int
+	// Operations are the in-progress operations that were started with
+	// StartOperation, oldest first. Operations started with StartOp are only
+	// counted in InProgressOps.
+	Operations []Operation
+}
+
+// Operation describes an in-progress operation.
+type Operation struct {
+	Repo      string
+	PullNum   int
+	Command   string
+	StartedAt time.Time
 }
 
 // StartOp tries to start a new operation. It returns false if Atlantis is
@@ -25,6 +43,38 @@ func (d *Drainer) StartOp() bool {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
 
+	return d.startOp()
+}
+
+// StartOperation is like StartOp but op is also reported by GetStatus until
+// it's complete. If the operation was started, done must be called once it's
+// complete instead of OpDone.
+func (d *Drainer) StartOperation(op Operation) (done func(), started bool) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if !d.startOp() {
+		return nil, false
+	}
+	if op.StartedAt.IsZero() {
+		op.StartedAt = time.Now()
+	}
+	if d.ops == nil {
+		d.ops = make(map[uint64]Operation)
+	}
+	id := d.nextOp
+	d.nextOp++
+	d.ops[id] = op
+	return func() {
+		d.mutex.Lock()
+		delete(d.ops, id)
+		d.mutex.Unlock()
+		d.OpDone()
+	}, true
+}
+
+// startOp must be called with the mutex held.
+func (d *Drainer) startOp() bool {
 	if d.status.ShuttingDown {
 		return false
 	}
@@ -59,5 +109,15 @@ func (d *Drainer) ShutdownBlocking() {
 }
 
 func (d *Drainer) GetStatus() DrainStatus {
-	return d.status
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	status := d.status
+	for _, op := range d.ops {
+		status.Operations = append(status.Operations, op)
+	}
+	sort.SliceStable(status.Operations, func(i, j int) bool {
+		return status.Operations[i].StartedAt.Before(status.Operations[j].StartedAt)
+	})
+	return status
 }
@@ -34,6 +34,40 @@ func TestDrainer(t *testing.T) {
 	Equals(t, 1, d.GetStatus().InProgressOps)
 }
 
+func TestDrainer_StartOperation(t *testing.T) {
+	d := events.Drainer{}
+	first := events.Operation{Repo: "owner/repo", PullNum: 1, Command: "plan", StartedAt: time.Now()}
+	second := events.Operation{Repo: "owner/repo", PullNum: 2, Command: "apply", StartedAt: first.StartedAt.Add(time.Second)}
+
+	doneSecond, ok := d.StartOperation(second)
+	Equals(t, true, ok)
+	doneFirst, ok := d.StartOperation(first)
+	Equals(t, true, ok)
+	d.StartOp()
+
+	// Operations are listed oldest first and ops without details are only
+	// counted.
+	Equals(t, events.DrainStatus{
+		InProgressOps: 3,
+		Operations:    []events.Operation{first, second},
+	}, d.GetStatus())
+
+	doneFirst()
+	Equals(t, events.DrainStatus{
+		InProgressOps: 2,
+		Operations:    []events.Operation{second},
+	}, d.GetStatus())
+
+	d.OpDone()
+	doneSecond()
+	Equals(t, events.DrainStatus{}, d.GetStatus())
+
+	// Once shutting down, operations aren't started.
+	d.ShutdownBlocking()
+	_, ok = d.StartOperation(first)
+	Equals(t, false, ok)
+}
+
 func TestDrainer_Shutdown(t *testing.T) {
 	d := events.Drainer{}
 	d.StartOp()
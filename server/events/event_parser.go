@@ -24,6 +24,7 @@ import (
 	"github.com/mcdafydd/go-azuredevops/azuredevops"
 	"github.com/pkg/errors"
 	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
 	"github.com/runatlantis/atlantis/server/events/vcs/bitbucketcloud"
 	"github.com/runatlantis/atlantis/server/events/vcs/bitbucketserver"
 	"github.com/xanzy/go-gitlab"
@@ -31,6 +32,12 @@ import (
 )
 
 const gitlabPullOpened = "opened"
+
+// gitlabDraftTitlePrefixes are the title prefixes GitLab uses to mark a merge
+// request as a draft. See
+// https://docs.gitlab.com/ee/user/project/merge_requests/drafts.html.
+var gitlabDraftTitlePrefixes = []string{"draft:", "[draft]", "(draft)", "wip:", "[wip]"}
+
 const usagesCols = 90
 
 // PullCommand is a command to run on a pull request.
@@ -85,23 +92,61 @@ func (c AutoplanCommand) IsAutoplan() bool {
 type CommentCommand struct {
 	// RepoRelDir is the path relative to the repo root to run the command in.
 	// Will never end in "/". If empty then the comment specified no directory.
+	// It can be a comma-separated list of dirs that can contain glob
+	// patterns, see IsMultiTarget.
 	RepoRelDir string
 	// Flags are the extra arguments appended to the comment,
 	// ex. atlantis plan -- -target=resource
 	Flags []string
 	// Name is the name of the command the comment specified.
 	Name models.CommandName
+	// SubName is the name of the subcommand the comment specified, ex. rm for
+	// atlantis state rm, or the name of the custom command, ex. deploy for
+	// atlantis deploy. It's empty for other commands.
+	SubName string
 	// AutoMergeDisabled is true if the command should not automerge after apply.
 	AutoMergeDisabled bool
 	// Verbose is true if the command should output verbosely.
 	Verbose bool
+	// Destroy is true if the plan should destroy every resource, ex.
+	// atlantis plan --destroy.
+	Destroy bool
+	// Force is true if the apply should use plans that were generated from an
+	// earlier commit of the pull request, ex. atlantis apply --force.
+	Force bool
+	// Confirm is true if the apply confirms an earlier apply that's waiting
+	// for confirmation, ex. atlantis apply --confirm.
+	Confirm bool
+	// Confirmed is true if the apply doesn't need to be confirmed, ex.
+	// because it was queued after it was confirmed. It can't be set from a
+	// comment.
+	Confirmed bool
+	// AutoApply is true if the command is an apply that was started because
+	// the pull request was approved, mergeable and planned successfully and
+	// its repo has auto_apply set. It can't be set from a comment.
+	AutoApply bool
+	// Fmt is true if validate should also check that files are formatted,
+	// ex. atlantis validate --fmt.
+	Fmt bool
+	// Targets are the resource addresses passed with --target, ex.
+	// atlantis plan --target aws_instance.web. For apply they must match the
+	// addresses the plan was targeted at.
+	Targets []string
 	// Workspace is the name of the Terraform workspace to run the command in.
-	// If empty then the comment specified no workspace.
+	// If empty then the comment specified no workspace. Like RepoRelDir, it
+	// can be a comma-separated list of workspaces that can contain glob
+	// patterns.
 	Workspace string
 	// ProjectName is the name of a project to run the command on. It refers to a
 	// project specified in an atlantis.yaml file.
 	// If empty then the comment specified no project.
 	ProjectName string
+	// CommentID is the id of the comment the command came from. It's 0 if
+	// the VCS host doesn't support editing or reacting to comments.
+	CommentID int64
+	// DiscussionID is the id of the thread the comment was made in, ex. a
+	// GitLab merge request diff discussion. It's empty for top-level comments.
+	DiscussionID string
 }
 
 // IsForSpecificProject returns true if the command is for a specific dir, workspace
@@ -111,6 +156,39 @@ func (c CommentCommand) IsForSpecificProject() bool {
 	return c.RepoRelDir != "" || c.Workspace != "" || c.ProjectName != ""
 }
 
+// IsMultiTarget returns true if the command's dir or workspace is a list or a
+// glob pattern, ex. atlantis plan -d 'stacks/prod/*', so it can target more
+// than one project.
+func (c CommentCommand) IsMultiTarget() bool {
+	return strings.ContainsAny(c.RepoRelDir, multiTargetChars) || strings.ContainsAny(c.Workspace, multiTargetChars)
+}
+
+// MatchesTarget returns true if the project at repoRelDir and workspace is
+// targeted by the command's dir and workspace. An empty dir or workspace
+// matches every dir or workspace. Patterns are matched with path.Match so *
+// doesn't match across dirs.
+func (c CommentCommand) MatchesTarget(repoRelDir string, workspace string) bool {
+	return matchesTargetList(c.RepoRelDir, repoRelDir) && matchesTargetList(c.Workspace, workspace)
+}
+
+// multiTargetChars are the characters that make a dir or workspace a list or
+// a glob pattern.
+const multiTargetChars = ",*?["
+
+// matchesTargetList returns true if s matches any of the comma-separated
+// patterns in list, or list is empty.
+func matchesTargetList(list string, s string) bool {
+	if list == "" {
+		return true
+	}
+	for _, pattern := range strings.Split(list, ",") {
+		if matched, _ := path.Match(pattern, s); matched {
+			return true
+		}
+	}
+	return false
+}
+
 // CommandName returns the name of this command.
 func (c CommentCommand) CommandName() models.CommandName {
 	return c.Name
@@ -126,13 +204,37 @@ func (c CommentCommand) IsAutoplan() bool {
 	return false
 }
 
+// DisplayName returns the name users type to run the command, ex. plan, or
+// deploy for the custom command atlantis deploy.
+func (c CommentCommand) DisplayName() string {
+	if c.Name == models.CustomCommand {
+		return c.SubName
+	}
+	return c.Name.String()
+}
+
 // String returns a string representation of the command.
 func (c CommentCommand) String() string {
-	return fmt.Sprintf("command=%q verbose=%t dir=%q workspace=%q project=%q flags=%q", c.Name.String(), c.Verbose, c.RepoRelDir, c.Workspace, c.ProjectName, strings.Join(c.Flags, ","))
+	return fmt.Sprintf("command=%q verbose=%t dir=%q workspace=%q project=%q flags=%q", c.DisplayName(), c.Verbose, c.RepoRelDir, c.Workspace, c.ProjectName, strings.Join(c.Flags, ","))
+}
+
+// CommentCommandOptions are the options of a comment command, ex. its flags.
+// See CommentCommand for what each one means.
+type CommentCommandOptions struct {
+	SubName           string
+	Verbose           bool
+	AutoMergeDisabled bool
+	Destroy           bool
+	Force             bool
+	Confirm           bool
+	Fmt               bool
+	Workspace         string
+	ProjectName       string
+	Targets           []string
 }
 
 // NewCommentCommand constructs a CommentCommand, setting all missing fields to defaults.
-func NewCommentCommand(repoRelDir string, flags []string, name models.CommandName, verbose, autoMergeDisabled bool, workspace string, project string) *CommentCommand {
+func NewCommentCommand(repoRelDir string, flags []string, name models.CommandName, opts CommentCommandOptions) *CommentCommand {
 	// If repoRelDir was empty we want to keep it that way to indicate that it
 	// wasn't specified in the comment.
 	if repoRelDir != "" {
@@ -145,10 +247,16 @@ func NewCommentCommand(repoRelDir string, flags []string, name models.CommandNam
 		RepoRelDir:        repoRelDir,
 		Flags:             flags,
 		Name:              name,
-		Verbose:           verbose,
-		Workspace:         workspace,
-		AutoMergeDisabled: autoMergeDisabled,
-		ProjectName:       project,
+		SubName:           opts.SubName,
+		Verbose:           opts.Verbose,
+		Workspace:         opts.Workspace,
+		AutoMergeDisabled: opts.AutoMergeDisabled,
+		Destroy:           opts.Destroy,
+		Force:             opts.Force,
+		Confirm:           opts.Confirm,
+		Fmt:               opts.Fmt,
+		ProjectName:       opts.ProjectName,
+		Targets:           opts.Targets,
 	}
 }
 
@@ -165,6 +273,14 @@ type EventParsing interface {
 	ParseGithubIssueCommentEvent(comment *github.IssueCommentEvent) (
 		baseRepo models.Repo, user models.User, pullNum int, err error)
 
+	// ParseGithubCheckRunEvent parses GitHub check run events, ex. when a
+	// user clicks a button on a check run created by Atlantis.
+	// baseRepo is the repo that the pull request will be merged into.
+	// user is the user that triggered the event.
+	// pullNum is the number of the pull request the check run is for.
+	ParseGithubCheckRunEvent(event *github.CheckRunEvent) (
+		baseRepo models.Repo, user models.User, pullNum int, err error)
+
 	// ParseGithubPull parses the response from the GitHub API endpoint (not
 	// from a webhook) that returns a pull request.
 	// pull is the parsed pull request.
@@ -187,6 +303,14 @@ type EventParsing interface {
 	// returns a repo into the Atlantis model.
 	ParseGithubRepo(ghRepo *github.Repository) (models.Repo, error)
 
+	// ParseGithubPushEvent parses GitHub push events.
+	// baseRepo is the repo that was pushed to.
+	// branch is the branch that was pushed to. It's empty if a tag was
+	// pushed or the branch was deleted.
+	// user is the user that pushed.
+	ParseGithubPushEvent(event *github.PushEvent) (
+		baseRepo models.Repo, branch string, user models.User, err error)
+
 	// ParseGitlabMergeRequestEvent parses GitLab merge request events.
 	// pull is the parsed merge request.
 	// pullEventType is the type of event, for example opened/closed.
@@ -209,6 +333,18 @@ type EventParsing interface {
 	// that returns a merge request.
 	ParseGitlabMergeRequest(mr *gitlab.MergeRequest, baseRepo models.Repo) models.PullRequest
 
+	// ParseGitlabProject parses the response from the GitLab API endpoint
+	// that returns a project, ex. the fork a merge request is from.
+	ParseGitlabProject(project *gitlab.Project) (models.Repo, error)
+
+	// ParseGitlabPushEvent parses GitLab push events.
+	// baseRepo is the repo that was pushed to.
+	// branch is the branch that was pushed to. It's empty if the branch was
+	// deleted.
+	// user is the user that pushed.
+	ParseGitlabPushEvent(event gitlab.PushEvent) (
+		baseRepo models.Repo, branch string, user models.User, err error)
+
 	// ParseBitbucketCloudPullEvent parses a pull request event from Bitbucket
 	// Cloud (bitbucket.org).
 	// pull is the parsed pull request.
@@ -280,20 +416,64 @@ type EventParsing interface {
 	// ParseAzureDevopsRepo parses the response from the Azure DevOps API endpoint that
 	// returns a repo into the Atlantis model.
 	ParseAzureDevopsRepo(adRepo *azuredevops.GitRepository) (models.Repo, error)
+
+	// ParseAPIPlanRequest parses a repo referenced by an API request (not a
+	// webhook) into the Atlantis model.
+	// vcsHostType is the VCS host the repo is on.
+	// path is the repo's full name, ex. runatlantis/atlantis.
+	// cloneURL is the repo's clone URL without credentials.
+	ParseAPIPlanRequest(vcsHostType models.VCSHostType, path string, cloneURL string) (models.Repo, error)
 }
 
 // EventParser parses VCS events.
 type EventParser struct {
-	GithubUser         string
-	GithubToken        string
-	GitlabUser         string
-	GitlabToken        string
+	GithubUser  string
+	GithubToken string
+	GitlabUser  string
+	GitlabToken string
+	// AllowDraftPRs is true if draft pull requests (GitHub and Azure DevOps)
+	// and draft merge requests (GitLab) should be autoplanned.
 	AllowDraftPRs      bool
 	BitbucketUser      string
 	BitbucketToken     string
 	BitbucketServerURL string
 	AzureDevopsToken   string
 	AzureDevopsUser    string
+	// AzureDevopsHostname is the hostname of the Azure DevOps Server install,
+	// optionally followed by its virtual directory, ex. ado.example.com/tfs.
+	// If empty or dev.azure.com, Azure DevOps Services is used.
+	AzureDevopsHostname string
+	// Hosts are additional GitHub and GitLab hosts. Repos on them are given
+	// their host's credentials instead of the GitHub or GitLab ones above.
+	Hosts []vcs.HostConfig
+}
+
+// newRepo constructs a GitHub or GitLab repo with the credentials for the
+// host it's cloned from.
+func (e *EventParser) newRepo(vcsHostType models.VCSHostType, repoFullName string, cloneURL string) (models.Repo, error) {
+	user, token := e.GithubUser, e.GithubToken
+	if vcsHostType == models.Gitlab {
+		user, token = e.GitlabUser, e.GitlabToken
+	}
+	if u, err := url.Parse(cloneURL); err == nil {
+		for _, h := range e.Hosts {
+			if h.VCSHostType() == vcsHostType && strings.EqualFold(h.Hostname, u.Hostname()) {
+				user, token = h.User, h.Token
+				break
+			}
+		}
+	}
+	return models.NewRepo(vcsHostType, repoFullName, cloneURL, user, token)
+}
+
+// ParseAPIPlanRequest parses a repo referenced by an API request into the
+// Atlantis model, adding the credentials for its VCS host to the clone URL.
+func (e *EventParser) ParseAPIPlanRequest(vcsHostType models.VCSHostType, path string, cloneURL string) (models.Repo, error) {
+	switch vcsHostType {
+	case models.Github, models.Gitlab:
+		return e.newRepo(vcsHostType, path, cloneURL)
+	}
+	return models.Repo{}, fmt.Errorf("API requests are not supported for %s", vcsHostType)
 }
 
 // GetBitbucketCloudPullEventType returns the type of the pull request
@@ -467,6 +647,32 @@ func (e *EventParser) ParseGithubPullEvent(pullEvent *github.PullRequestEvent) (
 	return
 }
 
+// ParseGithubCheckRunEvent parses GitHub check run events.
+// See EventParsing for return value docs.
+func (e *EventParser) ParseGithubCheckRunEvent(event *github.CheckRunEvent) (baseRepo models.Repo, user models.User, pullNum int, err error) {
+	baseRepo, err = e.ParseGithubRepo(event.Repo)
+	if err != nil {
+		return
+	}
+	if event.Sender.GetLogin() == "" {
+		err = errors.New("sender.login is null")
+		return
+	}
+	user = models.User{
+		Username: event.Sender.GetLogin(),
+	}
+	if event.CheckRun == nil || len(event.CheckRun.PullRequests) == 0 {
+		err = errors.New("check_run.pull_requests is empty")
+		return
+	}
+	pullNum = event.CheckRun.PullRequests[0].GetNumber()
+	if pullNum == 0 {
+		err = errors.New("check_run.pull_requests[0].number is null")
+		return
+	}
+	return
+}
+
 // ParseGithubPull parses the response from the GitHub API endpoint (not
 // from a webhook) that returns a pull request.
 // See EventParsing for return value docs.
@@ -534,7 +740,38 @@ func (e *EventParser) ParseGithubPull(pull *github.PullRequest) (pullModel model
 // returns a repo into the Atlantis model.
 // See EventParsing for return value docs.
 func (e *EventParser) ParseGithubRepo(ghRepo *github.Repository) (models.Repo, error) {
-	return models.NewRepo(models.Github, ghRepo.GetFullName(), ghRepo.GetCloneURL(), e.GithubUser, e.GithubToken)
+	return e.newRepo(models.Github, ghRepo.GetFullName(), ghRepo.GetCloneURL())
+}
+
+// ParseGithubPushEvent parses GitHub push events.
+// See EventParsing for return value docs.
+func (e *EventParser) ParseGithubPushEvent(event *github.PushEvent) (baseRepo models.Repo, branch string, user models.User, err error) {
+	baseRepo, err = e.newRepo(models.Github, event.GetRepo().GetFullName(), event.GetRepo().GetCloneURL())
+	if err != nil {
+		return
+	}
+	if event.Sender.GetLogin() == "" {
+		err = errors.New("sender.login is null")
+		return
+	}
+	user = models.User{
+		Username: event.Sender.GetLogin(),
+	}
+	if !event.GetDeleted() {
+		branch = branchFromRef(event.GetRef())
+	}
+	return
+}
+
+// branchFromRef returns the branch name of a git ref, ex. main for
+// refs/heads/main. It returns an empty string if ref isn't a branch, ex. a
+// tag.
+func branchFromRef(ref string) string {
+	const branchPrefix = "refs/heads/"
+	if !strings.HasPrefix(ref, branchPrefix) {
+		return ""
+	}
+	return strings.TrimPrefix(ref, branchPrefix)
 }
 
 // ParseGitlabMergeRequestEvent parses GitLab merge request events.
@@ -548,11 +785,11 @@ func (e *EventParser) ParseGitlabMergeRequestEvent(event gitlab.MergeEvent) (pul
 	// GitLab also has a "merged" state, but we map that to Closed so we don't
 	// need to check for it.
 
-	baseRepo, err = models.NewRepo(models.Gitlab, event.Project.PathWithNamespace, event.Project.GitHTTPURL, e.GitlabUser, e.GitlabToken)
+	baseRepo, err = e.newRepo(models.Gitlab, event.Project.PathWithNamespace, event.Project.GitHTTPURL)
 	if err != nil {
 		return
 	}
-	headRepo, err = models.NewRepo(models.Gitlab, event.ObjectAttributes.Source.PathWithNamespace, event.ObjectAttributes.Source.GitHTTPURL, e.GitlabUser, e.GitlabToken)
+	headRepo, err = e.newRepo(models.Gitlab, event.ObjectAttributes.Source.PathWithNamespace, event.ObjectAttributes.Source.GitHTTPURL)
 	if err != nil {
 		return
 	}
@@ -568,11 +805,24 @@ func (e *EventParser) ParseGitlabMergeRequestEvent(event gitlab.MergeEvent) (pul
 		BaseRepo:   baseRepo,
 	}
 
-	switch event.ObjectAttributes.Action {
+	action := event.ObjectAttributes.Action
+	// If it's a draft MR we ignore it for auto-planning if configured to do so.
+	// Closed draft MRs still need their locks deleted.
+	if event.ObjectAttributes.WorkInProgress && action != "merge" && action != "close" && !e.AllowDraftPRs {
+		action = "other"
+	}
+
+	switch action {
 	case "open":
 		eventType = models.OpenedPullEvent
 	case "update":
 		eventType = models.UpdatedPullEvent
+		// When an author marks an MR as ready GitLab sends an update event
+		// that removes the draft prefix from the title. We want atlantis to
+		// treat this as a freshly opened MR.
+		if !e.AllowDraftPRs && isGitlabDraftTitle(event.Changes.Title.Previous) && !isGitlabDraftTitle(event.Changes.Title.Current) {
+			eventType = models.OpenedPullEvent
+		}
 	case "merge", "close":
 		eventType = models.ClosedPullEvent
 	default:
@@ -593,7 +843,7 @@ func (e *EventParser) ParseGitlabMergeRequestCommentEvent(event gitlab.MergeComm
 	// Parse the base repo first.
 	repoFullName := event.Project.PathWithNamespace
 	cloneURL := event.Project.GitHTTPURL
-	baseRepo, err = models.NewRepo(models.Gitlab, repoFullName, cloneURL, e.GitlabUser, e.GitlabToken)
+	baseRepo, err = e.newRepo(models.Gitlab, repoFullName, cloneURL)
 	if err != nil {
 		return
 	}
@@ -604,7 +854,7 @@ func (e *EventParser) ParseGitlabMergeRequestCommentEvent(event gitlab.MergeComm
 	// Now parse the head repo.
 	headRepoFullName := event.MergeRequest.Source.PathWithNamespace
 	headCloneURL := event.MergeRequest.Source.GitHTTPURL
-	headRepo, err = models.NewRepo(models.Gitlab, headRepoFullName, headCloneURL, e.GitlabUser, e.GitlabToken)
+	headRepo, err = e.newRepo(models.Gitlab, headRepoFullName, headCloneURL)
 	return
 }
 
@@ -632,6 +882,29 @@ func (e *EventParser) ParseGitlabMergeRequest(mr *gitlab.MergeRequest, baseRepo
 	}
 }
 
+// ParseGitlabProject parses a GitLab project into a repo.
+// See EventParsing for return value docs.
+func (e *EventParser) ParseGitlabProject(project *gitlab.Project) (models.Repo, error) {
+	return e.newRepo(models.Gitlab, project.PathWithNamespace, project.HTTPURLToRepo)
+}
+
+// ParseGitlabPushEvent parses GitLab push events.
+// See EventParsing for return value docs.
+func (e *EventParser) ParseGitlabPushEvent(event gitlab.PushEvent) (baseRepo models.Repo, branch string, user models.User, err error) {
+	baseRepo, err = e.newRepo(models.Gitlab, event.Project.PathWithNamespace, event.Project.GitHTTPURL)
+	if err != nil {
+		return
+	}
+	user = models.User{
+		Username: event.UserUsername,
+	}
+	// GitLab sets after to all zeros when the branch was deleted.
+	if strings.Trim(event.After, "0") != "" {
+		branch = branchFromRef(event.Ref)
+	}
+	return
+}
+
 // GetBitbucketServerPullEventType returns the type of the pull request
 // event given the Bitbucket Server header.
 func (e *EventParser) GetBitbucketServerPullEventType(eventTypeHeader string) models.PullRequestEventType {
@@ -757,7 +1030,14 @@ func (e *EventParser) ParseAzureDevopsPullEvent(event azuredevops.Event) (pull m
 		err = errors.New("CreatedBy.UniqueName is null")
 		return
 	}
-	switch event.EventType {
+	eventType := event.EventType
+	// If it's a draft PR we ignore it for auto-planning if configured to do so.
+	// Once the PR is published, Azure DevOps sends an updated event which
+	// autoplans it. Closed draft PRs still need their locks deleted.
+	if pullResource.GetIsDraft() && pull.State != models.ClosedPullState && !e.AllowDraftPRs {
+		eventType = "other"
+	}
+	switch eventType {
 	case "git.pullrequest.created":
 		pullEventType = models.OpenedPullEvent
 	case "git.pullrequest.updated":
@@ -846,6 +1126,10 @@ func (e *EventParser) ParseAzureDevopsPull(pull *azuredevops.GitPullRequest) (pu
 // https://runatlantis.visualstudio.com/project/_git/repo
 // https://dev.azure.com/runatlantis/project/_git/repo
 //
+// For Azure DevOps Server installs, the owner is the project collection:
+//
+// https://ado.example.com/tfs/collection/project/_git/repo
+//
 // See EventParsing for return value docs.
 func (e *EventParser) ParseAzureDevopsRepo(adRepo *azuredevops.GitRepository) (models.Repo, error) {
 	teamProject := adRepo.GetProject()
@@ -862,17 +1146,60 @@ func (e *EventParser) ParseAzureDevopsRepo(adRepo *azuredevops.GitRepository) (m
 			owner = strings.Split(uri.Host, ".")[0]
 		} else if strings.Contains(uri.Host, "dev.azure.com") {
 			owner = strings.Split(uri.Path, "/")[1]
+		} else if e.isAzureDevopsServer() {
+			owner = e.azureDevopsServerOwner(uri)
 		} else {
 			owner = ""
 		}
 	}
 
+	project := teamProject.GetName()
+	repo := adRepo.GetName()
 	// Construct our own clone URL so we always get the new dev.azure.com
 	// hostname for now.
 	// https://docs.microsoft.com/en-us/azure/devops/release-notes/2018/sep-10-azure-devops-launch#switch-existing-organizations-to-use-the-new-domain-name-url
-	project := teamProject.GetName()
-	repo := adRepo.GetName()
-	cloneURL := fmt.Sprintf("https://dev.azure.com/%s/%s/_git/%s", owner, project, repo)
+	cloneHostname := "dev.azure.com"
+	if e.isAzureDevopsServer() {
+		cloneHostname = strings.TrimSuffix(e.AzureDevopsHostname, "/")
+	}
+	cloneURL := fmt.Sprintf("https://%s/%s/%s/_git/%s", cloneHostname, owner, project, repo)
 	fullName := fmt.Sprintf("%s/%s/%s", owner, project, repo)
 	return models.NewRepo(models.AzureDevops, fullName, cloneURL, e.AzureDevopsUser, e.AzureDevopsToken)
 }
+
+// isAzureDevopsServer returns true if Atlantis is configured to use an Azure
+// DevOps Server install instead of Azure DevOps Services.
+func (e *EventParser) isAzureDevopsServer() bool {
+	return e.AzureDevopsHostname != "" && e.AzureDevopsHostname != "dev.azure.com"
+}
+
+// azureDevopsServerOwner returns the project collection from the web URL of
+// a repo on the configured Azure DevOps Server, or an empty string if the URL
+// is for a different server.
+func (e *EventParser) azureDevopsServerOwner(uri *url.URL) string {
+	hostname := strings.TrimSuffix(e.AzureDevopsHostname, "/")
+	virtualDir := ""
+	if i := strings.Index(hostname, "/"); i != -1 {
+		hostname, virtualDir = hostname[:i], hostname[i:]
+	}
+	if !strings.EqualFold(uri.Host, hostname) {
+		return ""
+	}
+	// Paths are case insensitive on Azure DevOps Server.
+	if !strings.HasPrefix(strings.ToLower(uri.Path), strings.ToLower(virtualDir)+"/") {
+		return ""
+	}
+	return strings.Split(uri.Path[len(virtualDir)+1:], "/")[0]
+}
+
+// isGitlabDraftTitle returns true if title marks a GitLab merge request as a
+// draft.
+func isGitlabDraftTitle(title string) bool {
+	lower := strings.ToLower(strings.TrimSpace(title))
+	for _, prefix := range gitlabDraftTitlePrefixes {
+		if strings.HasPrefix(lower, prefix) {
+			return true
+		}
+	}
+	return false
+}
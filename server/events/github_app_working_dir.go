@@ -25,7 +25,9 @@ func (g *GithubAppWorkingDir) Clone(log logging.SimpleLogging, headRepo models.R
 
 	log.Info("Refreshing git tokens for Github App")
 
-	token, err := g.Credentials.GetToken()
+	// The app may be installed on multiple owners so we get a token for the
+	// installation on the owner of the base repo.
+	token, err := g.Credentials.GetToken(p.BaseRepo.Owner)
 	if err != nil {
 		return "", false, errors.Wrap(err, "getting github token")
 	}
@@ -44,7 +46,7 @@ func (g *GithubAppWorkingDir) Clone(log logging.SimpleLogging, headRepo models.R
 
 	// Realistically, this is a super brittle way of supporting clones using gh app installation tokens
 	// This URL should be built during Repo creation and the struct should be immutable going forward.
-	// Doing this requires a larger refactor however, and can probably be coupled with supporting > 1 installation
+	// Doing this requires a larger refactor however.
 	authURL := fmt.Sprintf("://x-access-token:%s", token)
 	baseRepo.CloneURL = strings.Replace(baseRepo.CloneURL, "://:", authURL, 1)
 	baseRepo.SanitizedCloneURL = strings.Replace(baseRepo.SanitizedCloneURL, "://:", "://x-access-token:", 1)
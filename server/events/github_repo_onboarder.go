@@ -0,0 +1,62 @@
+package events
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// GithubStatusCheckRequirer makes status contexts required checks of GitHub
+// branches. It's implemented by vcs.GithubClient.
+type GithubStatusCheckRequirer interface {
+	// RequireStatusChecks makes contexts required status checks of repo's
+	// branch, keeping the checks it already requires.
+	RequireStatusChecks(repo models.Repo, branch string, contexts []string) error
+}
+
+// GithubRepoOnboarder sets up the branch protection of GitHub repos the first
+// time they trigger Atlantis, so repos that send their webhooks through an
+// organization webhook don't need any setup of their own.
+type GithubRepoOnboarder struct {
+	// Clients maps the hostnames of GitHub hosts to the client used to
+	// protect the branches of their repos.
+	Clients map[string]GithubStatusCheckRequirer
+	// Contexts are the status contexts that are required on each repo's
+	// default branch, ex. atlantis/apply.
+	Contexts []string
+	Logger   logging.SimpleLogging
+
+	// mutex protects onboarded.
+	mutex sync.Mutex
+	// onboarded is the set of repo IDs whose branch protection has been set
+	// up. It's kept in memory so each repo is checked again once after
+	// Atlantis restarts, which doesn't change repos that were set up.
+	onboarded map[string]bool
+}
+
+// Onboard requires Contexts on repo's defaultBranch unless it was already
+// done. Errors are logged rather than returned since they shouldn't stop the
+// event that triggered Atlantis from being handled.
+func (o *GithubRepoOnboarder) Onboard(repo models.Repo, defaultBranch string) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.onboarded[repo.ID()] || defaultBranch == "" {
+		return
+	}
+	client, ok := o.Clients[strings.ToLower(repo.VCSHost.Hostname)]
+	if !ok {
+		return
+	}
+
+	o.Logger.Info("requiring %s on %s/%s", strings.Join(o.Contexts, ", "), repo.FullName, defaultBranch)
+	if err := client.RequireStatusChecks(repo, defaultBranch, o.Contexts); err != nil {
+		o.Logger.Warn("unable to require status checks on %s/%s: %s", repo.FullName, defaultBranch, err)
+		return
+	}
+	if o.onboarded == nil {
+		o.onboarded = make(map[string]bool)
+	}
+	o.onboarded[repo.ID()] = true
+}
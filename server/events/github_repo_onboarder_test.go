@@ -0,0 +1,60 @@
+package events_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// fakeStatusCheckRequirer records the branches it's asked to protect.
+type fakeStatusCheckRequirer struct {
+	err      error
+	branches []string
+}
+
+func (f *fakeStatusCheckRequirer) RequireStatusChecks(repo models.Repo, branch string, contexts []string) error {
+	f.branches = append(f.branches, repo.FullName+"/"+branch)
+	return f.err
+}
+
+func TestGithubRepoOnboarder_Onboard(t *testing.T) {
+	client := &fakeStatusCheckRequirer{}
+	o := &events.GithubRepoOnboarder{
+		Clients:  map[string]events.GithubStatusCheckRequirer{"github.com": client},
+		Contexts: []string{"atlantis/apply"},
+		Logger:   logging.NewNoopLogger(t),
+	}
+	repo := models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Hostname: "github.com"}}
+	other := models.Repo{FullName: "owner/other", VCSHost: models.VCSHost{Hostname: "github.com"}}
+	enterprise := models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Hostname: "github.example.com"}}
+
+	t.Log("each repo is onboarded once")
+	o.Onboard(repo, "main")
+	o.Onboard(repo, "main")
+	o.Onboard(other, "master")
+	Equals(t, []string{"owner/repo/main", "owner/other/master"}, client.branches)
+
+	t.Log("repos on hosts without a client aren't onboarded")
+	o.Onboard(enterprise, "main")
+	Equals(t, 2, len(client.branches))
+}
+
+func TestGithubRepoOnboarder_OnboardRetriesErrors(t *testing.T) {
+	client := &fakeStatusCheckRequirer{err: errors.New("forbidden")}
+	o := &events.GithubRepoOnboarder{
+		Clients:  map[string]events.GithubStatusCheckRequirer{"github.com": client},
+		Contexts: []string{"atlantis/apply"},
+		Logger:   logging.NewNoopLogger(t),
+	}
+	repo := models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Hostname: "github.com"}}
+
+	o.Onboard(repo, "main")
+	client.err = nil
+	o.Onboard(repo, "main")
+	o.Onboard(repo, "main")
+	Equals(t, []string{"owner/repo/main", "owner/repo/main"}, client.branches)
+}
@@ -0,0 +1,42 @@
+package events
+
+import (
+	"reflect"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/yaml"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// GlobalCfgReloader re-parses the server-side repo config file and swaps the
+// result into Store so config changes don't require a restart.
+type GlobalCfgReloader struct {
+	// Path is the server-side repo config file, ex. --repo-config.
+	Path string
+	// Args are used to build the default config that the file is parsed on
+	// top of.
+	Args            valid.GlobalCfgArgs
+	ParserValidator *yaml.ParserValidator
+	Store           *valid.GlobalCfgStore
+	Logger          logging.SimpleLogging
+}
+
+// Reload parses the config file and, if it's valid, replaces the config in
+// Store. If it isn't valid, the current config is kept and the error is
+// returned. Operations that are already running keep using the config they
+// started with.
+func (r *GlobalCfgReloader) Reload() error {
+	// The default config is built fresh each time because parsing modifies
+	// it.
+	cfg, err := r.ParserValidator.ParseGlobalCfg(r.Path, valid.NewGlobalCfgFromArgs(r.Args))
+	if err != nil {
+		return errors.Wrapf(err, "parsing %s file", r.Path)
+	}
+	if !reflect.DeepEqual(r.Store.Get().Secrets, cfg.Secrets) {
+		r.Logger.Warn("secrets in %s have changed but secrets are only loaded on startup, restart Atlantis to use them", r.Path)
+	}
+	r.Store.Set(cfg)
+	r.Logger.Info("reloaded server-side repo config from %s", r.Path)
+	return nil
+}
@@ -0,0 +1,46 @@
+package events_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/yaml"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestGlobalCfgReloader_Reload(t *testing.T) {
+	tmpDir, cleanup := TempDir(t)
+	defer cleanup()
+	path := filepath.Join(tmpDir, "repos.yaml")
+	Ok(t, ioutil.WriteFile(path, []byte(`
+repos:
+- id: /.*/
+  allowed_apply_teams: [platform]
+`), 0600))
+
+	store := valid.NewGlobalCfgStore(valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{}))
+	reloader := &events.GlobalCfgReloader{
+		Path:            path,
+		ParserValidator: &yaml.ParserValidator{},
+		Store:           store,
+		Logger:          logging.NewNoopLogger(t),
+	}
+	Ok(t, reloader.Reload())
+	Equals(t, []string{"platform"}, store.Get().AllowedApplyTeams("github.com/owner/repo", "main"))
+
+	t.Log("an invalid file keeps the current config")
+	Ok(t, ioutil.WriteFile(path, []byte("repos:\n- id: /.*/\n  workflow: missing\n"), 0600))
+	err := reloader.Reload()
+	Assert(t, err != nil, "expected error")
+	ErrContains(t, "parsing "+path+" file", err)
+	Equals(t, []string{"platform"}, store.Get().AllowedApplyTeams("github.com/owner/repo", "main"))
+
+	t.Log("removing a repo restores the defaults")
+	Ok(t, ioutil.WriteFile(path, []byte("repos: []\n"), 0600))
+	Ok(t, reloader.Reload())
+	Equals(t, 0, len(store.Get().AllowedApplyTeams("github.com/owner/repo", "main")))
+}
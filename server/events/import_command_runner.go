@@ -0,0 +1,78 @@
+package events
+
+import (
+	"github.com/runatlantis/atlantis/server/core/locking"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+)
+
+func NewImportCommandRunner(
+	vcsClient vcs.Client,
+	applyCommandLocker locking.ApplyLockChecker,
+	pullUpdater *PullUpdater,
+	prjCmdBuilder ProjectImportCommandBuilder,
+	prjCmdRunner ProjectImportCommandRunner,
+	db locking.Backend,
+) *ImportCommandRunner {
+	return &ImportCommandRunner{
+		vcsClient:     vcsClient,
+		locker:        applyCommandLocker,
+		pullUpdater:   pullUpdater,
+		prjCmdBuilder: prjCmdBuilder,
+		prjCmdRunner:  prjCmdRunner,
+		DB:            db,
+	}
+}
+
+type ImportCommandRunner struct {
+	DB            locking.Backend
+	locker        locking.ApplyLockChecker
+	vcsClient     vcs.Client
+	pullUpdater   *PullUpdater
+	prjCmdBuilder ProjectImportCommandBuilder
+	prjCmdRunner  ProjectImportCommandRunner
+}
+
+func (i *ImportCommandRunner) Run(ctx *CommandContext, cmd *CommentCommand) {
+	pull := ctx.Pull
+
+	// Import modifies state so we treat it like apply when apply is disabled
+	// globally.
+	lock, err := i.locker.CheckApplyLock()
+	if err != nil {
+		ctx.Log.Warn("checking global apply lock: %s", err)
+	}
+	if lock.Locked {
+		ctx.Log.Info("ignoring import command since apply disabled globally")
+		if err := commentOnPull(i.vcsClient, ctx, importDisabledComment, models.ImportCommand.String()); err != nil {
+			ctx.Log.Err("unable to comment on pull request: %s", err)
+		}
+		return
+	}
+
+	projectCmds, err := i.prjCmdBuilder.BuildImportCommands(ctx, cmd)
+	if err != nil {
+		i.pullUpdater.updatePull(ctx, cmd, CommandResult{Error: err})
+		return
+	}
+
+	if len(projectCmds) == 0 {
+		ctx.Log.Info("no projects to run import in")
+		return
+	}
+
+	result := runProjectCmds(projectCmds, i.prjCmdRunner.Import)
+	i.pullUpdater.updatePull(ctx, cmd, result)
+
+	// A successful import deletes the project's plan so we mark it discarded.
+	for _, projResult := range result.ProjectResults {
+		if projResult.ImportSuccess == nil {
+			continue
+		}
+		if err := i.DB.UpdateProjectStatus(pull, projResult.Workspace, projResult.RepoRelDir, models.DiscardedPlanStatus); err != nil {
+			ctx.Log.Err("updating project status: %s", err)
+		}
+	}
+}
+
+var importDisabledComment = "**Error:** Running `atlantis import` is disabled since `atlantis apply` is disabled."
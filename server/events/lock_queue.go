@@ -0,0 +1,152 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/runatlantis/atlantis/server/core/locking"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// LockQueue queues plans that couldn't run because another pull request holds
+// their project's lock so that they're run automatically once it's released.
+type LockQueue interface {
+	// Wait queues the plan for ctx behind lock, which is held by another pull
+	// request. It returns the 1-based position of ctx's pull request in the
+	// queue.
+	Wait(lock models.ProjectLock, ctx models.ProjectCommandContext) int
+	// Released re-runs the plans queued behind locks, which were deleted.
+	Released(locks []models.ProjectLock)
+	// RemovePull removes the plans of a pull request from every queue, ex.
+	// because it was closed.
+	RemovePull(repoFullName string, pullNum int)
+}
+
+// DefaultLockQueue implements LockQueue. Queued plans are re-run through
+// CommandRunner as if the user had commented atlantis plan for just that
+// project.
+type DefaultLockQueue struct {
+	// CommandRunner is used to re-run queued plans. It must be set before any
+	// plans are queued.
+	CommandRunner CommandRunner
+	// mutex prevents against multiple threads calling functions on this struct
+	// concurrently.
+	mutex sync.Mutex
+	// queues maps a lock key to the plans waiting for that lock, in the order
+	// they were queued.
+	queues map[string][]models.ProjectCommandContext
+}
+
+// NewDefaultLockQueue is a constructor.
+func NewDefaultLockQueue() *DefaultLockQueue {
+	return &DefaultLockQueue{
+		queues: make(map[string][]models.ProjectCommandContext),
+	}
+}
+
+// Wait implements LockQueue.Wait.
+func (d *DefaultLockQueue) Wait(lock models.ProjectLock, ctx models.ProjectCommandContext) int {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	key := d.key(lock)
+	queue := d.queues[key]
+	for i, queued := range queue {
+		// If this pull is already in the queue we don't add it again.
+		if queued.Pull.BaseRepo.FullName == ctx.Pull.BaseRepo.FullName && queued.Pull.Num == ctx.Pull.Num && queued.RepoRelDir == ctx.RepoRelDir && queued.Workspace == ctx.Workspace {
+			return i + 1
+		}
+	}
+	d.queues[key] = append(queue, ctx)
+	return len(queue) + 1
+}
+
+// Released implements LockQueue.Released. Every plan queued behind a lock is
+// re-run in order. The first to run acquires the lock and the rest are
+// queued again behind it. This way no plan is stuck in the queue if the plan
+// ahead of it doesn't take the lock, ex. because it failed.
+func (d *DefaultLockQueue) Released(locks []models.ProjectLock) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for _, lock := range locks {
+		key := d.key(lock)
+		queue := d.queues[key]
+		if len(queue) == 0 {
+			continue
+		}
+		delete(d.queues, key)
+		go d.run(queue)
+	}
+}
+
+// RemovePull implements LockQueue.RemovePull.
+func (d *DefaultLockQueue) RemovePull(repoFullName string, pullNum int) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for key, queue := range d.queues {
+		var kept []models.ProjectCommandContext
+		for _, queued := range queue {
+			if queued.Pull.BaseRepo.FullName != repoFullName || queued.Pull.Num != pullNum {
+				kept = append(kept, queued)
+			}
+		}
+		if len(kept) == 0 {
+			delete(d.queues, key)
+		} else {
+			d.queues[key] = kept
+		}
+	}
+}
+
+// run re-runs the plans in queue one after another.
+func (d *DefaultLockQueue) run(queue []models.ProjectCommandContext) {
+	for _, ctx := range queue {
+		cmd := &CommentCommand{
+			Name:        models.PlanCommand,
+			ProjectName: ctx.ProjectName,
+		}
+		if ctx.ProjectName == "" {
+			cmd.RepoRelDir = ctx.RepoRelDir
+			cmd.Workspace = ctx.Workspace
+		}
+		headRepo := ctx.HeadRepo
+		pull := ctx.Pull
+		d.CommandRunner.RunCommentCommand(context.Background(), ctx.Pull.BaseRepo, &headRepo, &pull, ctx.User, ctx.Pull.Num, cmd)
+	}
+}
+
+func (d *DefaultLockQueue) key(lock models.ProjectLock) string {
+	project, workspace := lock.LockTarget()
+	return fmt.Sprintf("%s/%s/%s", project.RepoFullName, project.Path, workspace)
+}
+
+// LockQueueLocker wraps a locking.Locker to tell Queue when locks are
+// released so the plans waiting for them can run.
+type LockQueueLocker struct {
+	locking.Locker
+	Queue LockQueue
+}
+
+// Unlock implements locking.Locker.Unlock.
+func (l *LockQueueLocker) Unlock(key string) (*models.ProjectLock, error) {
+	lock, err := l.Locker.Unlock(key)
+	if lock != nil {
+		l.Queue.Released([]models.ProjectLock{*lock})
+	}
+	return lock, err
+}
+
+// UnlockByPull implements locking.Locker.UnlockByPull. The pull request's own
+// queued plans are removed since its locks are only all deleted when it's
+// closed or unlocked by the user.
+func (l *LockQueueLocker) UnlockByPull(repoFullName string, pullNum int) ([]models.ProjectLock, error) {
+	l.Queue.RemovePull(repoFullName, pullNum)
+	locks, err := l.Locker.UnlockByPull(repoFullName, pullNum)
+	if len(locks) > 0 {
+		l.Queue.Released(locks)
+	}
+	return locks, err
+}
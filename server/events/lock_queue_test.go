@@ -0,0 +1,79 @@
+package events_test
+
+import (
+	"testing"
+
+	. "github.com/petergtz/pegomock"
+	lockingmocks "github.com/runatlantis/atlantis/server/core/locking/mocks"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestLockQueue_Wait(t *testing.T) {
+	queue := events.NewDefaultLockQueue()
+	lock := lockQueueLock(1, ".")
+
+	t.Log("plans are queued in order")
+	Equals(t, 1, queue.Wait(lock, applyQueueCtx(2, ".")))
+	Equals(t, 2, queue.Wait(lock, applyQueueCtx(3, ".")))
+
+	t.Log("re-planning doesn't change the position")
+	Equals(t, 1, queue.Wait(lock, applyQueueCtx(2, ".")))
+
+	t.Log("other locks have their own queue")
+	Equals(t, 1, queue.Wait(lockQueueLock(1, "other"), applyQueueCtx(3, "other")))
+
+	t.Log("removed pulls leave the queue")
+	queue.RemovePull("owner/repo", 2)
+	Equals(t, 1, queue.Wait(lock, applyQueueCtx(3, ".")))
+
+	t.Log("releasing the lock re-runs the queued plans")
+	runner := &fakeCommandRunner{ran: make(chan int, 2)}
+	queue.CommandRunner = runner
+	queue.Released([]models.ProjectLock{lock})
+	Equals(t, 3, waitForRun(t, runner))
+	Equals(t, []*events.CommentCommand{
+		{Name: models.PlanCommand, RepoRelDir: ".", Workspace: "default"},
+	}, runner.cmds)
+
+	t.Log("once released the queue is empty")
+	Equals(t, 1, queue.Wait(lock, applyQueueCtx(4, ".")))
+}
+
+func TestLockQueueLocker_Unlock(t *testing.T) {
+	RegisterMockTestingT(t)
+	lock := lockQueueLock(1, ".")
+	backend := lockingmocks.NewMockLocker()
+	When(backend.Unlock("owner/repo/./default")).ThenReturn(&lock, nil)
+	When(backend.UnlockByPull("owner/repo", 1)).ThenReturn([]models.ProjectLock{lock}, nil)
+	queue := events.NewDefaultLockQueue()
+	runner := &fakeCommandRunner{ran: make(chan int, 2)}
+	queue.CommandRunner = runner
+	locker := &events.LockQueueLocker{Locker: backend, Queue: queue}
+
+	t.Log("unlocking re-runs the queued plans")
+	queue.Wait(lock, applyQueueCtx(2, "."))
+	_, err := locker.Unlock("owner/repo/./default")
+	Ok(t, err)
+	Equals(t, 2, waitForRun(t, runner))
+
+	t.Log("unlocking a pull re-runs the plans queued behind it and drops its own")
+	queue.Wait(lock, applyQueueCtx(3, "."))
+	queue.Wait(lockQueueLock(3, "other"), applyQueueCtx(1, "other"))
+	_, err = locker.UnlockByPull("owner/repo", 1)
+	Ok(t, err)
+	Equals(t, 3, waitForRun(t, runner))
+	Equals(t, 1, queue.Wait(lockQueueLock(3, "other"), applyQueueCtx(4, "other")))
+}
+
+func lockQueueLock(pullNum int, path string) models.ProjectLock {
+	return models.ProjectLock{
+		Project:   models.NewProject("owner/repo", path),
+		Workspace: "default",
+		Pull: models.PullRequest{
+			Num:      pullNum,
+			BaseRepo: models.Repo{FullName: "owner/repo"},
+		},
+	}
+}
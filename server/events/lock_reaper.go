@@ -0,0 +1,82 @@
+package events
+
+import (
+	"time"
+
+	"github.com/runatlantis/atlantis/server/core/locking"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/i18n"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// LockReaper periodically deletes project locks that are older than MaxAge
+// and comments on their pull requests that they expired. It releases locks
+// held by pull requests that were abandoned without being closed, which
+// would otherwise have to be deleted from the UI.
+type LockReaper struct {
+	// MaxAge is how long a lock can be held before it's deleted.
+	MaxAge time.Duration
+	Locker locking.Locker
+	// DeleteLockCommand deletes expired locks along with their plans, the
+	// same as deleting them from the UI.
+	DeleteLockCommand DeleteLockCommand
+	VCSClient         vcs.Client
+	Localizer         *i18n.Localizer
+	Logger            logging.SimpleLogging
+}
+
+// Start reaps expired locks every interval until stop is closed.
+func (r *LockReaper) Start(interval time.Duration, stop <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				r.Reap()
+			}
+		}
+	}()
+}
+
+// Reap deletes every lock older than MaxAge.
+func (r *LockReaper) Reap() {
+	locks, err := r.Locker.List()
+	if err != nil {
+		r.Logger.Err("listing locks to expire: %s", err)
+		return
+	}
+	for key, l := range locks {
+		if time.Since(l.Time) <= r.MaxAge {
+			continue
+		}
+		lock, err := r.DeleteLockCommand.DeleteLock(key)
+		if err != nil {
+			r.Logger.Err("deleting expired lock %q: %s", key, err)
+			continue
+		}
+		// The lock was already deleted, ex. by another Atlantis instance.
+		if lock == nil {
+			continue
+		}
+		r.Logger.Info("deleted lock %q held by %s#%d since %s", key, lock.Pull.BaseRepo.FullName, lock.Pull.Num, lock.Time.Format(time.RFC3339))
+		r.commentExpired(*lock)
+	}
+}
+
+// commentExpired comments on the pull request that held lock that its lock
+// expired.
+func (r *LockReaper) commentExpired(lock models.ProjectLock) {
+	// Locks from older versions of Atlantis don't have the BaseRepo so
+	// there's nowhere to comment.
+	if lock.Pull.BaseRepo == (models.Repo{}) {
+		return
+	}
+	comment := r.Localizer.Sprintf(lock.Pull.BaseRepo, lock.Pull.BaseBranch, i18n.LockExpired, lock.Project.Path, lock.Workspace, r.MaxAge)
+	if err := r.VCSClient.CreateComment(lock.Pull.BaseRepo, lock.Pull.Num, comment, ""); err != nil {
+		r.Logger.Warn("commenting on %s#%d that its lock expired: %s", lock.Pull.BaseRepo.FullName, lock.Pull.Num, err)
+	}
+}
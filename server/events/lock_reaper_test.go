@@ -0,0 +1,63 @@
+package events_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/petergtz/pegomock"
+	lockingmocks "github.com/runatlantis/atlantis/server/core/locking/mocks"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/mocks"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/models/fixtures"
+	vcsmocks "github.com/runatlantis/atlantis/server/events/vcs/mocks"
+	vcsmatchers "github.com/runatlantis/atlantis/server/events/vcs/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+func TestLockReaper_Reap(t *testing.T) {
+	RegisterMockTestingT(t)
+	pull := fixtures.Pull
+	pull.BaseRepo = fixtures.GithubRepo
+	expiredLock := models.ProjectLock{
+		Project:   models.NewProject(fixtures.GithubRepo.FullName, "expired"),
+		Workspace: "default",
+		Pull:      pull,
+		Time:      time.Now().Add(-48 * time.Hour),
+	}
+	goneLock := expiredLock
+	goneLock.Project.Path = "gone"
+	erroredLock := expiredLock
+	erroredLock.Project.Path = "errored"
+	freshLock := expiredLock
+	freshLock.Project.Path = "fresh"
+	freshLock.Time = time.Now()
+
+	locker := lockingmocks.NewMockLocker()
+	When(locker.List()).ThenReturn(map[string]models.ProjectLock{
+		"runatlantis/atlantis/expired/default": expiredLock,
+		"runatlantis/atlantis/gone/default":    goneLock,
+		"runatlantis/atlantis/errored/default": erroredLock,
+		"runatlantis/atlantis/fresh/default":   freshLock,
+	}, nil)
+	deleteLockCommand := mocks.NewMockDeleteLockCommand()
+	When(deleteLockCommand.DeleteLock("runatlantis/atlantis/expired/default")).ThenReturn(&expiredLock, nil)
+	When(deleteLockCommand.DeleteLock("runatlantis/atlantis/gone/default")).ThenReturn(nil, nil)
+	When(deleteLockCommand.DeleteLock("runatlantis/atlantis/errored/default")).ThenReturn(nil, errors.New("err"))
+	vcsClient := vcsmocks.NewMockClient()
+	reaper := &events.LockReaper{
+		MaxAge:            24 * time.Hour,
+		Locker:            locker,
+		DeleteLockCommand: deleteLockCommand,
+		VCSClient:         vcsClient,
+		Logger:            logging.NewNoopLogger(t),
+	}
+	reaper.Reap()
+
+	deleteLockCommand.VerifyWasCalled(Never()).DeleteLock("runatlantis/atlantis/fresh/default")
+	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GithubRepo, pull.Num,
+		"**Warning**: The lock for dir: `expired` workspace: `default` expired after being held for more than 24h0m0s, so its plan was **discarded**.\n\n"+
+			"To `apply` this plan you must run `plan` again.", "")
+	vcsClient.VerifyWasCalledOnce().CreateComment(vcsmatchers.AnyModelsRepo(), AnyInt(), AnyString(), AnyString())
+}
@@ -16,11 +16,14 @@ package events
 import (
 	"bytes"
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 	"text/template"
 
 	"github.com/Masterminds/sprig/v3"
 	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/i18n"
 )
 
 var (
@@ -29,6 +32,9 @@ var (
 	policyCheckCommandTitle     = models.PolicyCheckCommand.TitleString()
 	approvePoliciesCommandTitle = models.ApprovePoliciesCommand.TitleString()
 	versionCommandTitle         = models.VersionCommand.TitleString()
+	importCommandTitle          = models.ImportCommand.TitleString()
+	stateCommandTitle           = models.StateCommand.TitleString()
+	validateCommandTitle        = models.ValidateCommand.TitleString()
 	// maxUnwrappedLines is the maximum number of lines the Terraform output
 	// can be before we wrap it in an expandable template.
 	maxUnwrappedLines = 12
@@ -55,6 +61,9 @@ type commonData struct {
 	DisableApplyAll    bool
 	DisableApply       bool
 	DisableRepoLocking bool
+	// Custom is true if Command is a custom command defined in the
+	// server-side repo config.
+	Custom bool
 }
 
 // errData is data about an error response.
@@ -72,6 +81,9 @@ type failureData struct {
 // resultData is data about a successful response.
 type resultData struct {
 	Results []projectResultTmplData
+	// PlanChangesTotal is the summary of resource changes across all
+	// successful plans, ex. "+3 ~1 -0". It's empty if no plan had a summary.
+	PlanChangesTotal string
 	commonData
 }
 
@@ -81,6 +93,24 @@ type planSuccessData struct {
 	PlanWasDeleted     bool
 	DisableApply       bool
 	DisableRepoLocking bool
+	ResourceGroups     []resourceGroupData
+}
+
+// resourceGroupData is the resources in a structured plan that have the same
+// action.
+type resourceGroupData struct {
+	// Action is the title of the group, ex. "Create".
+	Action string
+	// Symbol is prepended to each address so the diff is highlighted.
+	Symbol    string
+	Addresses []string
+}
+
+// applySuccessData is the output of a successful apply and the outputs of the
+// project after it.
+type applySuccessData struct {
+	Output  string
+	Outputs []models.TerraformOutput
 }
 
 type policyCheckSuccessData struct {
@@ -92,12 +122,48 @@ type projectResultTmplData struct {
 	RepoRelDir  string
 	ProjectName string
 	Rendered    string
+	// PlanChanges is the summary of resource changes for this project's
+	// plan, ex. "+3 ~1 -0". It's empty if there was no successful plan.
+	PlanChanges string
 }
 
-// Render formats the data into a markdown string.
+// rollupData is the data for a rollup comment.
+type rollupData struct {
+	Rows []rollupRowData
+	// HistoryURL links to the output of every project. It's empty if
+	// there's no history page to link to.
+	HistoryURL       string
+	PlanChangesTotal string
+	commonData
+}
+
+// rollupRowData is one project's row in a rollup comment.
+type rollupRowData struct {
+	Workspace   string
+	RepoRelDir  string
+	ProjectName string
+	Success     bool
+	Status      string
+	// Changes is the number of resources the project's plan or apply
+	// changed. It's nil if they couldn't be parsed from its output.
+	Changes *models.PlanChanges
+	URL     string
+}
+
+// Render formats the data into a markdown string in locale.
 // nolint: interfacer
-func (m *MarkdownRenderer) Render(res CommandResult, cmdName models.CommandName, log string, verbose bool, vcsHost models.VCSHostType) string {
-	commandStr := strings.Title(strings.Replace(cmdName.String(), "_", " ", -1))
+func (m *MarkdownRenderer) Render(res CommandResult, cmdName models.CommandName, log string, verbose bool, vcsHost models.VCSHostType, locale string) string {
+	return m.render(res, cmdName.String(), false, log, verbose, vcsHost, locale)
+}
+
+// RenderCustom formats the results of the custom command name, ex. deploy,
+// into a markdown string in locale.
+func (m *MarkdownRenderer) RenderCustom(res CommandResult, name string, log string, verbose bool, vcsHost models.VCSHostType, locale string) string {
+	return m.render(res, name, true, log, verbose, vcsHost, locale)
+}
+
+func (m *MarkdownRenderer) render(res CommandResult, name string, custom bool, log string, verbose bool, vcsHost models.VCSHostType, locale string) string {
+	commandStr := strings.Title(strings.Replace(name, "_", " ", -1))
 	common := commonData{
 		Command:            commandStr,
 		Verbose:            verbose,
@@ -106,21 +172,26 @@ func (m *MarkdownRenderer) Render(res CommandResult, cmdName models.CommandName,
 		DisableApplyAll:    m.DisableApplyAll || m.DisableApply,
 		DisableApply:       m.DisableApply,
 		DisableRepoLocking: m.DisableRepoLocking,
+		Custom:             custom,
 	}
 	if res.Error != nil {
-		return m.renderTemplate(unwrappedErrWithLogTmpl, errData{res.Error.Error(), common})
+		return m.renderTemplate(locale, unwrappedErrWithLogTmpl, errData{res.Error.Error(), common})
 	}
 	if res.Failure != "" {
-		return m.renderTemplate(failureWithLogTmpl, failureData{res.Failure, common})
+		return m.renderTemplate(locale, failureWithLogTmpl, failureData{res.Failure, common})
 	}
-	return m.renderProjectResults(res.ProjectResults, common, vcsHost)
+	return m.renderProjectResults(res.ProjectResults, common, vcsHost, locale)
 }
 
-func (m *MarkdownRenderer) renderProjectResults(results []models.ProjectResult, common commonData, vcsHost models.VCSHostType) string {
+func (m *MarkdownRenderer) renderProjectResults(results []models.ProjectResult, common commonData, vcsHost models.VCSHostType, locale string) string {
 	var resultsTmplData []projectResultTmplData
 	numPlanSuccesses := 0
 	numPolicyCheckSuccesses := 0
 	numVersionSuccesses := 0
+	numValidateSuccesses := 0
+	numCustomSuccesses := 0
+	numPlanChanges := 0
+	var totalChanges models.PlanChanges
 
 	for _, result := range results {
 		resultData := projectResultTmplData{
@@ -133,7 +204,7 @@ func (m *MarkdownRenderer) renderProjectResults(results []models.ProjectResult,
 			if m.shouldUseWrappedTmpl(vcsHost, result.Error.Error()) {
 				tmpl = wrappedErrTmpl
 			}
-			resultData.Rendered = m.renderTemplate(tmpl, struct {
+			resultData.Rendered = m.renderTemplate(locale, tmpl, struct {
 				Command string
 				Error   string
 			}{
@@ -141,7 +212,7 @@ func (m *MarkdownRenderer) renderProjectResults(results []models.ProjectResult,
 				Error:   result.Error.Error(),
 			})
 		} else if result.Failure != "" {
-			resultData.Rendered = m.renderTemplate(failureTmpl, struct {
+			resultData.Rendered = m.renderTemplate(locale, failureTmpl, struct {
 				Command string
 				Failure string
 			}{
@@ -149,32 +220,72 @@ func (m *MarkdownRenderer) renderProjectResults(results []models.ProjectResult,
 				Failure: result.Failure,
 			})
 		} else if result.PlanSuccess != nil {
-			if m.shouldUseWrappedTmpl(vcsHost, result.PlanSuccess.TerraformOutput) {
-				resultData.Rendered = m.renderTemplate(planSuccessWrappedTmpl, planSuccessData{PlanSuccess: *result.PlanSuccess, PlanSummary: result.PlanSuccess.Summary(), PlanWasDeleted: common.PlansDeleted, DisableApply: common.DisableApply, DisableRepoLocking: common.DisableRepoLocking})
+			if result.PlanSuccess.StructuredPlan != nil {
+				data := planSuccessData{PlanSuccess: *result.PlanSuccess, PlanSummary: result.PlanSuccess.Summary(), PlanWasDeleted: common.PlansDeleted, DisableApply: common.DisableApply, DisableRepoLocking: common.DisableRepoLocking, ResourceGroups: resourceGroups(locale, *result.PlanSuccess.StructuredPlan)}
+				if m.supportsFolding(vcsHost) {
+					resultData.Rendered = m.renderTemplate(locale, structuredPlanWrappedTmpl, data)
+				} else {
+					resultData.Rendered = m.renderTemplate(locale, structuredPlanUnwrappedTmpl, data)
+				}
+			} else if m.shouldUseWrappedTmpl(vcsHost, result.PlanSuccess.TerraformOutput) {
+				resultData.Rendered = m.renderTemplate(locale, planSuccessWrappedTmpl, planSuccessData{PlanSuccess: *result.PlanSuccess, PlanSummary: result.PlanSuccess.Summary(), PlanWasDeleted: common.PlansDeleted, DisableApply: common.DisableApply, DisableRepoLocking: common.DisableRepoLocking})
 			} else {
-				resultData.Rendered = m.renderTemplate(planSuccessUnwrappedTmpl, planSuccessData{PlanSuccess: *result.PlanSuccess, PlanWasDeleted: common.PlansDeleted, DisableApply: common.DisableApply, DisableRepoLocking: common.DisableRepoLocking})
+				resultData.Rendered = m.renderTemplate(locale, planSuccessUnwrappedTmpl, planSuccessData{PlanSuccess: *result.PlanSuccess, PlanWasDeleted: common.PlansDeleted, DisableApply: common.DisableApply, DisableRepoLocking: common.DisableRepoLocking})
+			}
+			if changes, ok := result.PlanSuccess.Changes(); ok {
+				resultData.PlanChanges = changes.String()
+				totalChanges.Add += changes.Add
+				totalChanges.Change += changes.Change
+				totalChanges.Destroy += changes.Destroy
+				numPlanChanges++
 			}
 			numPlanSuccesses++
 		} else if result.PolicyCheckSuccess != nil {
 			if m.shouldUseWrappedTmpl(vcsHost, result.PolicyCheckSuccess.PolicyCheckOutput) {
-				resultData.Rendered = m.renderTemplate(policyCheckSuccessWrappedTmpl, policyCheckSuccessData{PolicyCheckSuccess: *result.PolicyCheckSuccess})
+				resultData.Rendered = m.renderTemplate(locale, policyCheckSuccessWrappedTmpl, policyCheckSuccessData{PolicyCheckSuccess: *result.PolicyCheckSuccess})
 			} else {
-				resultData.Rendered = m.renderTemplate(policyCheckSuccessUnwrappedTmpl, policyCheckSuccessData{PolicyCheckSuccess: *result.PolicyCheckSuccess})
+				resultData.Rendered = m.renderTemplate(locale, policyCheckSuccessUnwrappedTmpl, policyCheckSuccessData{PolicyCheckSuccess: *result.PolicyCheckSuccess})
 			}
 			numPolicyCheckSuccesses++
 		} else if result.ApplySuccess != "" {
 			if m.shouldUseWrappedTmpl(vcsHost, result.ApplySuccess) {
-				resultData.Rendered = m.renderTemplate(applyWrappedSuccessTmpl, struct{ Output string }{result.ApplySuccess})
+				resultData.Rendered = m.renderTemplate(locale, applyWrappedSuccessTmpl, applySuccessData{Output: result.ApplySuccess, Outputs: result.ApplyOutputs})
 			} else {
-				resultData.Rendered = m.renderTemplate(applyUnwrappedSuccessTmpl, struct{ Output string }{result.ApplySuccess})
+				resultData.Rendered = m.renderTemplate(locale, applyUnwrappedSuccessTmpl, applySuccessData{Output: result.ApplySuccess, Outputs: result.ApplyOutputs})
 			}
 		} else if result.VersionSuccess != "" {
 			if m.shouldUseWrappedTmpl(vcsHost, result.VersionSuccess) {
-				resultData.Rendered = m.renderTemplate(versionWrappedSuccessTmpl, struct{ Output string }{result.VersionSuccess})
+				resultData.Rendered = m.renderTemplate(locale, versionWrappedSuccessTmpl, struct{ Output string }{result.VersionSuccess})
 			} else {
-				resultData.Rendered = m.renderTemplate(versionUnwrappedSuccessTmpl, struct{ Output string }{result.VersionSuccess})
+				resultData.Rendered = m.renderTemplate(locale, versionUnwrappedSuccessTmpl, struct{ Output string }{result.VersionSuccess})
 			}
 			numVersionSuccesses++
+		} else if result.ValidateSuccess != "" {
+			if m.shouldUseWrappedTmpl(vcsHost, result.ValidateSuccess) {
+				resultData.Rendered = m.renderTemplate(locale, validateWrappedSuccessTmpl, struct{ Output string }{result.ValidateSuccess})
+			} else {
+				resultData.Rendered = m.renderTemplate(locale, validateUnwrappedSuccessTmpl, struct{ Output string }{result.ValidateSuccess})
+			}
+			numValidateSuccesses++
+		} else if result.ImportSuccess != nil {
+			if m.shouldUseWrappedTmpl(vcsHost, result.ImportSuccess.Output) {
+				resultData.Rendered = m.renderTemplate(locale, stateChangeWrappedSuccessTmpl, *result.ImportSuccess)
+			} else {
+				resultData.Rendered = m.renderTemplate(locale, stateChangeUnwrappedSuccessTmpl, *result.ImportSuccess)
+			}
+		} else if result.StateSuccess != nil {
+			if m.shouldUseWrappedTmpl(vcsHost, result.StateSuccess.Output) {
+				resultData.Rendered = m.renderTemplate(locale, stateChangeWrappedSuccessTmpl, *result.StateSuccess)
+			} else {
+				resultData.Rendered = m.renderTemplate(locale, stateChangeUnwrappedSuccessTmpl, *result.StateSuccess)
+			}
+		} else if result.CustomSuccess != nil {
+			if m.shouldUseWrappedTmpl(vcsHost, result.CustomSuccess.Output) {
+				resultData.Rendered = m.renderTemplate(locale, validateWrappedSuccessTmpl, *result.CustomSuccess)
+			} else {
+				resultData.Rendered = m.renderTemplate(locale, validateUnwrappedSuccessTmpl, *result.CustomSuccess)
+			}
+			numCustomSuccesses++
 		} else {
 			resultData.Rendered = "Found no template. This is a bug!"
 		}
@@ -183,6 +294,12 @@ func (m *MarkdownRenderer) renderProjectResults(results []models.ProjectResult,
 
 	var tmpl *template.Template
 	switch {
+	case common.Custom && len(resultsTmplData) == 1 && numCustomSuccesses > 0:
+		tmpl = singleProjectVersionSuccessTmpl
+	case common.Custom && len(resultsTmplData) == 1:
+		tmpl = singleProjectVersionUnsuccessfulTmpl
+	case common.Custom:
+		tmpl = multiProjectVersionTmpl
 	case len(resultsTmplData) == 1 && common.Command == planCommandTitle && numPlanSuccesses > 0:
 		tmpl = singleProjectPlanSuccessTmpl
 	case len(resultsTmplData) == 1 && common.Command == planCommandTitle && numPlanSuccesses == 0:
@@ -195,6 +312,10 @@ func (m *MarkdownRenderer) renderProjectResults(results []models.ProjectResult,
 		tmpl = singleProjectVersionSuccessTmpl
 	case len(resultsTmplData) == 1 && common.Command == versionCommandTitle && numVersionSuccesses == 0:
 		tmpl = singleProjectVersionUnsuccessfulTmpl
+	case len(resultsTmplData) == 1 && common.Command == validateCommandTitle && numValidateSuccesses > 0:
+		tmpl = singleProjectVersionSuccessTmpl
+	case len(resultsTmplData) == 1 && common.Command == validateCommandTitle && numValidateSuccesses == 0:
+		tmpl = singleProjectVersionUnsuccessfulTmpl
 	case len(resultsTmplData) == 1 && common.Command == applyCommandTitle:
 		tmpl = singleProjectApplyTmpl
 	case common.Command == planCommandTitle,
@@ -204,12 +325,95 @@ func (m *MarkdownRenderer) renderProjectResults(results []models.ProjectResult,
 		tmpl = approveAllProjectsTmpl
 	case common.Command == applyCommandTitle:
 		tmpl = multiProjectApplyTmpl
-	case common.Command == versionCommandTitle:
+	case len(resultsTmplData) == 1 && (common.Command == importCommandTitle || common.Command == stateCommandTitle):
+		tmpl = singleProjectStateChangeTmpl
+	case common.Command == versionCommandTitle,
+		common.Command == validateCommandTitle:
 		tmpl = multiProjectVersionTmpl
 	default:
 		return "no template matched–this is a bug"
 	}
-	return m.renderTemplate(tmpl, resultData{resultsTmplData, common})
+	data := resultData{Results: resultsTmplData, commonData: common}
+	if numPlanChanges > 0 {
+		data.PlanChangesTotal = totalChanges.String()
+	}
+	return m.renderTemplate(locale, tmpl, data)
+}
+
+// RenderRollup formats the results of plan or apply as a table with a row
+// per project instead of including each project's output. The rows link to
+// the projects' output on the history page of pull if urls is set. It's
+// rendered in locale.
+func (m *MarkdownRenderer) RenderRollup(res CommandResult, cmdName models.CommandName, pull models.PullRequest, urls HistoryURLGenerator, log string, verbose bool, locale string) string {
+	common := commonData{
+		Command:            cmdName.TitleString(),
+		Verbose:            verbose,
+		Log:                log,
+		PlansDeleted:       res.PlansDeleted,
+		DisableApplyAll:    m.DisableApplyAll || m.DisableApply || cmdName != models.PlanCommand,
+		DisableApply:       m.DisableApply,
+		DisableRepoLocking: m.DisableRepoLocking,
+	}
+	if res.Error != nil {
+		return m.renderTemplate(locale, unwrappedErrWithLogTmpl, errData{res.Error.Error(), common})
+	}
+	if res.Failure != "" {
+		return m.renderTemplate(locale, failureWithLogTmpl, failureData{res.Failure, common})
+	}
+
+	data := rollupData{commonData: common}
+	if urls != nil {
+		data.HistoryURL = urls.GenerateHistoryURL(pull.BaseRepo.FullName, pull.Num)
+	}
+	var totalChanges models.PlanChanges
+	numChanges := 0
+	for _, result := range res.ProjectResults {
+		row := rollupRowData{
+			Workspace:   result.Workspace,
+			RepoRelDir:  result.RepoRelDir,
+			ProjectName: result.ProjectName,
+			Success:     result.IsSuccessful(),
+			Status:      result.PlanStatus().String(),
+		}
+		var changes models.PlanChanges
+		var ok bool
+		if result.PlanSuccess != nil {
+			changes, ok = result.PlanSuccess.Changes()
+		} else if result.ApplySuccess != "" {
+			changes, ok = applyChanges(result.ApplySuccess)
+		}
+		if ok {
+			row.Changes = &changes
+			totalChanges.Add += changes.Add
+			totalChanges.Change += changes.Change
+			totalChanges.Destroy += changes.Destroy
+			numChanges++
+		}
+		if urls != nil {
+			row.URL = urls.GenerateProjectHistoryURL(pull.BaseRepo.FullName, pull.Num, result.RepoRelDir, result.Workspace)
+		}
+		data.Rows = append(data.Rows, row)
+	}
+	if numChanges > 0 {
+		data.PlanChangesTotal = totalChanges.String()
+	}
+	return m.renderTemplate(locale, rollupTmpl, data)
+}
+
+// applyChanges parses the number of resources added, changed and destroyed
+// from the output of apply. It returns false if the output doesn't contain
+// Terraform's summary line.
+func applyChanges(output string) (models.PlanChanges, bool) {
+	r := regexp.MustCompile(`Apply complete! Resources: (\d+) added, (\d+) changed, (\d+) destroyed.`)
+	match := r.FindStringSubmatch(output)
+	if match == nil {
+		return models.PlanChanges{}, false
+	}
+	// The regex only matches digits so we can ignore the errors.
+	add, _ := strconv.Atoi(match[1])
+	change, _ := strconv.Atoi(match[2])
+	destroy, _ := strconv.Atoi(match[3])
+	return models.PlanChanges{Add: add, Change: change, Destroy: destroy}, true
 }
 
 // shouldUseWrappedTmpl returns true if we should use the wrapped markdown
@@ -217,6 +421,16 @@ func (m *MarkdownRenderer) renderProjectResults(results []models.ProjectResult,
 // load. Some VCS providers or versions of VCS providers don't support this
 // syntax.
 func (m *MarkdownRenderer) shouldUseWrappedTmpl(vcsHost models.VCSHostType, output string) bool {
+	if !m.supportsFolding(vcsHost) {
+		return false
+	}
+
+	return strings.Count(output, "\n") > maxUnwrappedLines
+}
+
+// supportsFolding returns true if we can use the folding markdown syntax
+// when commenting on vcsHost.
+func (m *MarkdownRenderer) supportsFolding(vcsHost models.VCSHostType) bool {
 	if m.DisableMarkdownFolding {
 		return false
 	}
@@ -230,154 +444,298 @@ func (m *MarkdownRenderer) shouldUseWrappedTmpl(vcsHost models.VCSHostType, outp
 		return false
 	}
 
-	return strings.Count(output, "\n") > maxUnwrappedLines
+	return true
 }
 
-func (m *MarkdownRenderer) renderTemplate(tmpl *template.Template, data interface{}) string {
+// resourceGroups returns the non-empty groups of resources in plan in the
+// order Terraform lists actions in its own plan output. The groups' titles
+// are in locale.
+func resourceGroups(locale string, plan models.StructuredPlan) []resourceGroupData {
+	var groups []resourceGroupData
+	for _, g := range []resourceGroupData{
+		{Action: i18n.Sprintf(locale, i18n.ResourcesCreate), Symbol: "+", Addresses: plan.Create},
+		{Action: i18n.Sprintf(locale, i18n.ResourcesDestroy), Symbol: "-", Addresses: plan.Delete},
+		{Action: i18n.Sprintf(locale, i18n.ResourcesUpdate), Symbol: "!", Addresses: plan.Update},
+		{Action: i18n.Sprintf(locale, i18n.ResourcesReplace), Symbol: "-/+", Addresses: plan.Replace},
+	} {
+		if len(g.Addresses) > 0 {
+			groups = append(groups, g)
+		}
+	}
+	return groups
+}
+
+func (m *MarkdownRenderer) renderTemplate(locale string, tmpl *template.Template, data interface{}) string {
 	buf := &bytes.Buffer{}
-	if err := tmpl.Execute(buf, data); err != nil {
+	if err := localize(tmpl, locale).Execute(buf, data); err != nil {
 		return fmt.Sprintf("Failed to render template, this is a bug: %v", err)
 	}
 	return buf.String()
 }
 
+// localeFuncs returns the template funcs that render messages in locale. The
+// templates use t to render messages from the i18n catalog, ex.
+// {{ t "show_output" }}.
+func localeFuncs(locale string) template.FuncMap {
+	return template.FuncMap{
+		"t": func(msg string, args ...interface{}) string {
+			return i18n.Sprintf(locale, i18n.Message(msg), args...)
+		},
+	}
+}
+
+// localize returns a copy of tmpl that renders its messages in locale.
+func localize(tmpl *template.Template, locale string) *template.Template {
+	return template.Must(tmpl.Clone()).Funcs(localeFuncs(locale))
+}
+
+// parseTmpl parses text as a template. Its messages are rendered in English
+// unless it's localized.
+func parseTmpl(text string) *template.Template {
+	return template.Must(template.New("").Funcs(sprig.TxtFuncMap()).Funcs(localeFuncs(i18n.English)).Parse(text))
+}
+
+// ranForProjectTmpl is the first line of single project comments.
+var ranForProjectTmpl = "{{ if $result.ProjectName }}{{ t \"ran_for_project\" .Command $result.ProjectName $result.RepoRelDir $result.Workspace }}" +
+	"{{ else }}{{ t \"ran_for_dir\" .Command $result.RepoRelDir $result.Workspace }}{{ end }}"
+
+// projectTitleTmpl identifies $result's project in multi project comments.
+var projectTitleTmpl = "{{ if $result.ProjectName }}{{ t \"project_dir_workspace\" $result.ProjectName $result.RepoRelDir $result.Workspace }}" +
+	"{{ else }}{{ t \"dir_workspace\" $result.RepoRelDir $result.Workspace }}{{ end }}"
+
 // todo: refactor to remove duplication #refactor
-var singleProjectApplyTmpl = template.Must(template.New("").Parse(
-	"{{$result := index .Results 0}}Ran {{.Command}} for {{ if $result.ProjectName }}project: `{{$result.ProjectName}}` {{ end }}dir: `{{$result.RepoRelDir}}` workspace: `{{$result.Workspace}}`\n\n{{$result.Rendered}}\n" + logTmpl))
-var singleProjectPlanSuccessTmpl = template.Must(template.New("").Parse(
-	"{{$result := index .Results 0}}Ran {{.Command}} for {{ if $result.ProjectName }}project: `{{$result.ProjectName}}` {{ end }}dir: `{{$result.RepoRelDir}}` workspace: `{{$result.Workspace}}`\n\n{{$result.Rendered}}\n" +
+var singleProjectApplyTmpl = parseTmpl(
+	"{{$result := index .Results 0}}" + ranForProjectTmpl + "\n\n{{$result.Rendered}}\n" + logTmpl)
+var singleProjectPlanSuccessTmpl = parseTmpl(
+	"{{$result := index .Results 0}}" + ranForProjectTmpl + "\n\n{{$result.Rendered}}\n" +
 		"\n" +
 		"{{ if ne .DisableApplyAll true  }}---\n" +
-		"* :fast_forward: To **apply** all unapplied plans from this pull request, comment:\n" +
-		"    * `atlantis apply`\n" +
-		"* :put_litter_in_its_place: To delete all plans and locks for the PR, comment:\n" +
-		"    * `atlantis unlock`{{ end }}" + logTmpl))
-var singleProjectPlanUnsuccessfulTmpl = template.Must(template.New("").Parse(
-	"{{$result := index .Results 0}}Ran {{.Command}} for dir: `{{$result.RepoRelDir}}` workspace: `{{$result.Workspace}}`\n\n" +
-		"{{$result.Rendered}}\n" + logTmpl))
-var singleProjectVersionSuccessTmpl = template.Must(template.New("").Parse(
-	"{{$result := index .Results 0}}Ran {{.Command}} for {{ if $result.ProjectName }}project: `{{$result.ProjectName}}` {{ end }}dir: `{{$result.RepoRelDir}}` workspace: `{{$result.Workspace}}`\n\n{{$result.Rendered}}\n" + logTmpl))
-var singleProjectVersionUnsuccessfulTmpl = template.Must(template.New("").Parse(
-	"{{$result := index .Results 0}}Ran {{.Command}} for dir: `{{$result.RepoRelDir}}` workspace: `{{$result.Workspace}}`\n\n{{$result.Rendered}}\n" + logTmpl))
-var approveAllProjectsTmpl = template.Must(template.New("").Funcs(sprig.TxtFuncMap()).Parse(
-	"Approved Policies for {{ len .Results }} projects:\n\n" +
+		"{{ t \"apply_all_next_steps\" }}{{ end }}" + logTmpl)
+var singleProjectPlanUnsuccessfulTmpl = parseTmpl(
+	"{{$result := index .Results 0}}{{ t \"ran_for_dir\" .Command $result.RepoRelDir $result.Workspace }}\n\n" +
+		"{{$result.Rendered}}\n" + logTmpl)
+var singleProjectVersionSuccessTmpl = parseTmpl(
+	"{{$result := index .Results 0}}" + ranForProjectTmpl + "\n\n{{$result.Rendered}}\n" + logTmpl)
+var singleProjectVersionUnsuccessfulTmpl = parseTmpl(
+	"{{$result := index .Results 0}}{{ t \"ran_for_dir\" .Command $result.RepoRelDir $result.Workspace }}\n\n{{$result.Rendered}}\n" + logTmpl)
+var singleProjectStateChangeTmpl = parseTmpl(
+	"{{$result := index .Results 0}}" + ranForProjectTmpl + "\n\n{{$result.Rendered}}\n" + logTmpl)
+var approveAllProjectsTmpl = parseTmpl(
+	"{{ t \"approved_policies\" (len .Results) }}\n\n" +
 		"{{ range $result := .Results }}" +
-		"1. {{ if $result.ProjectName }}project: `{{$result.ProjectName}}` {{ end }}dir: `{{$result.RepoRelDir}}` workspace: `{{$result.Workspace}}`\n" +
-		"{{end}}\n" + logTmpl))
-var multiProjectPlanTmpl = template.Must(template.New("").Funcs(sprig.TxtFuncMap()).Parse(
-	"Ran {{.Command}} for {{ len .Results }} projects:\n\n" +
+		"1. " + projectTitleTmpl + "\n" +
+		"{{end}}\n" + logTmpl)
+var multiProjectPlanTmpl = parseTmpl(
+	"{{ t \"ran_for_projects\" .Command (len .Results) }}\n\n" +
+		"{{ if .PlanChangesTotal }}{{ t \"total_changes\" .PlanChangesTotal }}\n\n{{ end }}" +
 		"{{ range $result := .Results }}" +
-		"1. {{ if $result.ProjectName }}project: `{{$result.ProjectName}}` {{ end }}dir: `{{$result.RepoRelDir}}` workspace: `{{$result.Workspace}}`\n" +
+		"1. " + projectTitleTmpl + "{{ if $result.PlanChanges }} (`{{$result.PlanChanges}}`){{ end }}\n" +
 		"{{end}}\n" +
 		"{{ $disableApplyAll := .DisableApplyAll }}{{ range $i, $result := .Results }}" +
-		"### {{add $i 1}}. {{ if $result.ProjectName }}project: `{{$result.ProjectName}}` {{ end }}dir: `{{$result.RepoRelDir}}` workspace: `{{$result.Workspace}}`\n" +
+		"### {{add $i 1}}. " + projectTitleTmpl + "\n" +
 		"{{$result.Rendered}}\n\n" +
-		"{{ if ne $disableApplyAll true }}---\n{{end}}{{end}}{{ if ne .DisableApplyAll true }}{{ if and (gt (len .Results) 0) (not .PlansDeleted) }}* :fast_forward: To **apply** all unapplied plans from this pull request, comment:\n" +
-		"    * `atlantis apply`\n" +
-		"* :put_litter_in_its_place: To delete all plans and locks for the PR, comment:\n" +
-		"    * `atlantis unlock`" +
+		"{{ if ne $disableApplyAll true }}---\n{{end}}{{end}}{{ if ne .DisableApplyAll true }}{{ if and (gt (len .Results) 0) (not .PlansDeleted) }}" +
+		"{{ t \"apply_all_next_steps\" }}" +
 		"{{end}}{{end}}" +
-		logTmpl))
-var multiProjectApplyTmpl = template.Must(template.New("").Funcs(sprig.TxtFuncMap()).Parse(
-	"Ran {{.Command}} for {{ len .Results }} projects:\n\n" +
+		logTmpl)
+var multiProjectApplyTmpl = parseTmpl(
+	"{{ t \"ran_for_projects\" .Command (len .Results) }}\n\n" +
 		"{{ range $result := .Results }}" +
-		"1. {{ if $result.ProjectName }}project: `{{$result.ProjectName}}` {{ end }}dir: `{{$result.RepoRelDir}}` workspace: `{{$result.Workspace}}`\n" +
+		"1. " + projectTitleTmpl + "\n" +
 		"{{end}}\n" +
 		"{{ range $i, $result := .Results }}" +
-		"### {{add $i 1}}. {{ if $result.ProjectName }}project: `{{$result.ProjectName}}` {{ end }}dir: `{{$result.RepoRelDir}}` workspace: `{{$result.Workspace}}`\n" +
+		"### {{add $i 1}}. " + projectTitleTmpl + "\n" +
 		"{{$result.Rendered}}\n\n" +
 		"---\n{{end}}" +
-		logTmpl))
-var multiProjectVersionTmpl = template.Must(template.New("").Funcs(sprig.TxtFuncMap()).Parse(
-	"Ran {{.Command}} for {{ len .Results }} projects:\n\n" +
+		logTmpl)
+var multiProjectVersionTmpl = parseTmpl(
+	"{{ t \"ran_for_projects\" .Command (len .Results) }}\n\n" +
 		"{{ range $result := .Results }}" +
-		"1. {{ if $result.ProjectName }}project: `{{$result.ProjectName}}` {{ end }}dir: `{{$result.RepoRelDir}}` workspace: `{{$result.Workspace}}`\n" +
+		"1. " + projectTitleTmpl + "\n" +
 		"{{end}}\n" +
 		"{{ range $i, $result := .Results }}" +
-		"### {{add $i 1}}. {{ if $result.ProjectName }}project: `{{$result.ProjectName}}` {{ end }}dir: `{{$result.RepoRelDir}}` workspace: `{{$result.Workspace}}`\n" +
+		"### {{add $i 1}}. " + projectTitleTmpl + "\n" +
 		"{{$result.Rendered}}\n\n" +
 		"---\n{{end}}" +
-		logTmpl))
-var planSuccessUnwrappedTmpl = template.Must(template.New("").Parse(
-	"```diff\n" +
+		logTmpl)
+var rollupTmpl = parseTmpl(
+	"{{ if .HistoryURL }}{{ t \"ran_for_projects_with_history\" .Command (len .Rows) .HistoryURL }}{{ else }}{{ t \"ran_for_projects\" .Command (len .Rows) }}{{ end }}\n\n" +
+		"{{ if .PlanChangesTotal }}{{ t \"total_changes\" .PlanChangesTotal }}\n\n{{ end }}" +
+		"{{ t \"rollup_table_header\" }}{{ if .HistoryURL }}{{ t \"rollup_output_header\" }}{{ end }}\n" +
+		"|---------|-----|-----------|--------|-----|--------|---------|{{ if .HistoryURL }}--------|{{ end }}\n" +
+		"{{ range .Rows }}" +
+		"|{{ if .ProjectName }} `{{.ProjectName}}`{{ end }} | `{{.RepoRelDir}}` | `{{.Workspace}}` | {{ if .Success }}:white_check_mark:{{ else }}:x:{{ end }} `{{.Status}}` |" +
+		"{{ with .Changes }} {{.Add}} | {{.Change}} | {{.Destroy}} |{{ else }} | | |{{ end }}{{ if .URL }} {{ t \"rollup_view\" .URL }} |{{ end }}\n" +
+		"{{ end }}" +
+		"{{ if ne .DisableApplyAll true }}{{ if and (gt (len .Rows) 0) (not .PlansDeleted) }}\n---\n" +
+		"{{ t \"apply_all_next_steps\" }}" +
+		"{{end}}{{end}}" +
+		logTmpl)
+
+// destroyPlanWarning is shown above plans that destroy every resource so
+// they're not applied by accident.
+var destroyPlanWarning = "{{ if .Destroy }}{{ t \"destroy_plan_warning\" }}\n\n{{ end }}"
+
+// planRetriesNote lists the steps that were retried after transient errors so
+// it's clear why the plan took longer than usual.
+var planRetriesNote = "{{ if .Retries }}{{ t \"steps_retried\" }}\n" +
+	"{{ range .Retries }}{{ t \"step_retry\" .StepName .Attempt .Error }}\n{{ end }}\n{{ end }}"
+
+// scanFindings lists the findings of the plan's scan steps grouped by
+// severity.
+var scanFindings = "{{ range .ScanResults }}{{ if .Findings }}" +
+	"{{ if .FailOn }}{{ t \"scan_found_issues_fail_on\" .Tool (len .Findings) .FailOn }}{{ else }}{{ t \"scan_found_issues\" .Tool (len .Findings) }}{{ end }}\n" +
+	"{{ range .Groups }}\n**{{.Severity}} ({{ len .Findings }})**\n" +
+	"{{ range .Findings }}{{ if .Resource }}{{ t \"scan_finding_resource\" .RuleID .Resource .Location .Description }}{{ else }}{{ t \"scan_finding\" .RuleID .Location .Description }}{{ end }}\n{{ end }}{{ end }}\n" +
+	"{{ else }}{{ t \"scan_no_issues\" .Tool }}\n\n{{ end }}{{ end }}"
+
+var scanFindingsTmpl = parseTmpl(scanFindings)
+
+// renderScanFindings renders the findings of results in locale for the
+// failure of a plan that failed its security scan.
+func renderScanFindings(locale string, results []models.ScanResult) string {
+	buf := &bytes.Buffer{}
+	if err := localize(scanFindingsTmpl, locale).Execute(buf, struct{ ScanResults []models.ScanResult }{results}); err != nil {
+		return fmt.Sprintf("Failed to render template, this is a bug: %v", err)
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+// divergedWarning is shown after plans if the base branch is ahead of the
+// pull request.
+var divergedWarning = "{{ if .HasDiverged }}\n\n{{ t \"branch_diverged\" }}{{end}}"
+
+var planSuccessUnwrappedTmpl = parseTmpl(
+	destroyPlanWarning + planRetriesNote + scanFindings +
+		"```diff\n" +
 		"{{.TerraformOutput}}\n" +
 		"```\n\n" + planNextSteps +
-		"{{ if .HasDiverged }}\n\n:warning: The branch we're merging into is ahead, it is recommended to pull new commits first.{{end}}"))
+		divergedWarning)
 
-var planSuccessWrappedTmpl = template.Must(template.New("").Parse(
-	"<details><summary>Show Output</summary>\n\n" +
+var planSuccessWrappedTmpl = parseTmpl(
+	destroyPlanWarning + planRetriesNote + scanFindings +
+		"<details><summary>{{ t \"show_output\" }}</summary>\n\n" +
 		"```diff\n" +
 		"{{.TerraformOutput}}\n" +
 		"```\n\n" +
 		planNextSteps + "\n" +
 		"</details>" + "\n" +
 		"{{.PlanSummary}}" +
-		"{{ if .HasDiverged }}\n\n:warning: The branch we're merging into is ahead, it is recommended to pull new commits first.{{end}}"))
+		divergedWarning)
+
+var structuredPlanWrappedTmpl = parseTmpl(
+	destroyPlanWarning + planRetriesNote + scanFindings +
+		"{{ range .ResourceGroups }}<details><summary>{{.Action}} ({{ len .Addresses }})</summary>\n\n" +
+		"```diff\n" +
+		"{{ $symbol := .Symbol }}{{ range .Addresses }}{{ $symbol }} {{ . }}\n{{ end }}" +
+		"```\n" +
+		"</details>\n" +
+		"{{ end }}\n" +
+		planNextSteps + "\n" +
+		"{{.PlanSummary}}" +
+		divergedWarning)
+
+var structuredPlanUnwrappedTmpl = parseTmpl(
+	destroyPlanWarning + planRetriesNote + scanFindings +
+		"{{ range .ResourceGroups }}**{{.Action}} ({{ len .Addresses }})**\n" +
+		"```diff\n" +
+		"{{ $symbol := .Symbol }}{{ range .Addresses }}{{ $symbol }} {{ . }}\n{{ end }}" +
+		"```\n" +
+		"{{ end }}\n" +
+		planNextSteps + "\n" +
+		"{{.PlanSummary}}" +
+		divergedWarning)
 
-var policyCheckSuccessUnwrappedTmpl = template.Must(template.New("").Parse(
+var policyCheckSuccessUnwrappedTmpl = parseTmpl(
 	"```diff\n" +
 		"{{.PolicyCheckOutput}}\n" +
 		"```\n\n" + policyCheckNextSteps +
-		"{{ if .HasDiverged }}\n\n:warning: The branch we're merging into is ahead, it is recommended to pull new commits first.{{end}}"))
+		divergedWarning)
 
-var policyCheckSuccessWrappedTmpl = template.Must(template.New("").Parse(
-	"<details><summary>Show Output</summary>\n\n" +
+var policyCheckSuccessWrappedTmpl = parseTmpl(
+	"<details><summary>{{ t \"show_output\" }}</summary>\n\n" +
 		"```diff\n" +
 		"{{.PolicyCheckOutput}}\n" +
 		"```\n\n" +
 		policyCheckNextSteps + "\n" +
 		"</details>" +
-		"{{ if .HasDiverged }}\n\n:warning: The branch we're merging into is ahead, it is recommended to pull new commits first.{{end}}"))
+		divergedWarning)
 
 // policyCheckNextSteps are instructions appended after successful plans as to what
 // to do next.
-var policyCheckNextSteps = "* :arrow_forward: To **apply** this plan, comment:\n" +
-	"    * `{{.ApplyCmd}}`\n" +
-	"* :put_litter_in_its_place: To **delete** this plan click [here]({{.LockURL}})\n" +
-	"* :repeat: To re-run policies **plan** this project again by commenting:\n" +
-	"    * `{{.RePlanCmd}}`"
+var policyCheckNextSteps = "{{ t \"apply_plan_step\" .ApplyCmd }}\n" +
+	"{{ t \"delete_plan_step\" .LockURL }}\n" +
+	"{{ t \"policy_replan_step\" .RePlanCmd }}"
 
 // planNextSteps are instructions appended after successful plans as to what
 // to do next.
-var planNextSteps = "{{ if .PlanWasDeleted }}This plan was not saved because one or more projects failed and automerge requires all plans pass.{{ else }}" +
-	"{{ if not .DisableApply }}* :arrow_forward: To **apply** this plan, comment:\n" +
-	"    * `{{.ApplyCmd}}`\n{{end}}" +
-	"{{ if not .DisableRepoLocking }}* :put_litter_in_its_place: To **delete** this plan click [here]({{.LockURL}})\n{{end}}" +
-	"* :repeat: To **plan** this project again, comment:\n" +
-	"    * `{{.RePlanCmd}}`{{end}}"
-var applyUnwrappedSuccessTmpl = template.Must(template.New("").Parse(
+var planNextSteps = "{{ if .PlanWasDeleted }}{{ t \"plan_not_saved\" }}{{ else }}" +
+	"{{ if not .DisableApply }}{{ t \"apply_plan_step\" .ApplyCmd }}\n{{end}}" +
+	"{{ if not .DisableRepoLocking }}{{ t \"delete_plan_step\" .LockURL }}\n{{end}}" +
+	"{{ t \"replan_step\" .RePlanCmd }}{{end}}"
+var applyUnwrappedSuccessTmpl = parseTmpl(
 	"```diff\n" +
 		"{{.Output}}\n" +
-		"```"))
-var applyWrappedSuccessTmpl = template.Must(template.New("").Parse(
-	"<details><summary>Show Output</summary>\n\n" +
+		"```" + applyOutputs)
+var applyWrappedSuccessTmpl = parseTmpl(
+	"<details><summary>{{ t \"show_output\" }}</summary>\n\n" +
 		"```diff\n" +
 		"{{.Output}}\n" +
 		"```\n" +
-		"</details>"))
-var versionUnwrappedSuccessTmpl = template.Must(template.New("").Parse("```\n{{.Output}}```"))
-var versionWrappedSuccessTmpl = template.Must(template.New("").Parse(
-	"<details><summary>Show Output</summary>\n\n" +
+		"</details>" + applyOutputs)
+
+// applyOutputs lists the project's outputs after the apply output so they're
+// visible even if the apply output is folded.
+var applyOutputs = "{{ if .Outputs }}\n\n{{ t \"apply_outputs\" }}\n" +
+	"{{ range .Outputs }}\n* `{{.Name}}`: {{ if .Sensitive }}{{ t \"sensitive_output\" }}{{ else }}`{{.Value}}`{{ end }}{{ end }}{{ end }}"
+var versionUnwrappedSuccessTmpl = parseTmpl("```\n{{.Output}}```")
+var versionWrappedSuccessTmpl = parseTmpl(
+	"<details><summary>{{ t \"show_output\" }}</summary>\n\n" +
 		"```\n" +
 		"{{.Output}}" +
 		"```\n" +
-		"</details>"))
-var unwrappedErrTmplText = "**{{.Command}} Error**\n" +
+		"</details>")
+var validateUnwrappedSuccessTmpl = parseTmpl(
+	"```\n" +
+		"{{.Output}}\n" +
+		"```")
+var validateWrappedSuccessTmpl = parseTmpl(
+	"<details><summary>{{ t \"show_output\" }}</summary>\n\n" +
+		"```\n" +
+		"{{.Output}}\n" +
+		"```\n" +
+		"</details>")
+var stateChangeUnwrappedSuccessTmpl = parseTmpl(
+	"```diff\n" +
+		"{{.Output}}\n" +
+		"```\n\n" + stateChangeNextSteps)
+var stateChangeWrappedSuccessTmpl = parseTmpl(
+	"<details><summary>{{ t \"show_output\" }}</summary>\n\n" +
+		"```diff\n" +
+		"{{.Output}}\n" +
+		"```\n" +
+		"</details>\n\n" + stateChangeNextSteps)
+
+// stateChangeNextSteps are instructions appended after successful imports and
+// state commands as to what to do next.
+var stateChangeNextSteps = "{{ t \"state_change_plan_deleted\" }}\n\n" +
+	"{{ t \"replan_step\" .RePlanCmd }}"
+var unwrappedErrTmplText = "{{ t \"command_error\" .Command }}\n" +
 	"```\n" +
 	"{{.Error}}\n" +
 	"```" +
 	"{{ if eq .Command \"Policy Check\" }}" +
-	"\n* :heavy_check_mark: To **approve** failing policies either request an approval from approvers or address the failure by modifying the codebase.\n" +
+	"\n{{ t \"policy_approve_hint\" }}\n" +
 	"{{ end }}"
-var wrappedErrTmplText = "**{{.Command}} Error**\n" +
-	"<details><summary>Show Output</summary>\n\n" +
+var wrappedErrTmplText = "{{ t \"command_error\" .Command }}\n" +
+	"<details><summary>{{ t \"show_output\" }}</summary>\n\n" +
 	"```\n" +
 	"{{.Error}}\n" +
 	"```\n</details>"
-var unwrappedErrTmpl = template.Must(template.New("").Parse(unwrappedErrTmplText))
-var unwrappedErrWithLogTmpl = template.Must(template.New("").Parse(unwrappedErrTmplText + logTmpl))
-var wrappedErrTmpl = template.Must(template.New("").Parse(wrappedErrTmplText))
-var failureTmplText = "**{{.Command}} Failed**: {{.Failure}}"
-var failureTmpl = template.Must(template.New("").Parse(failureTmplText))
-var failureWithLogTmpl = template.Must(template.New("").Parse(failureTmplText + logTmpl))
-var logTmpl = "{{if .Verbose}}\n<details><summary>Log</summary>\n  <p>\n\n```\n{{.Log}}```\n</p></details>{{end}}\n"
+var unwrappedErrTmpl = parseTmpl(unwrappedErrTmplText)
+var unwrappedErrWithLogTmpl = parseTmpl(unwrappedErrTmplText + logTmpl)
+var wrappedErrTmpl = parseTmpl(wrappedErrTmplText)
+var failureTmplText = "{{ t \"command_failed\" .Command .Failure }}"
+var failureTmpl = parseTmpl(failureTmplText)
+var failureWithLogTmpl = parseTmpl(failureTmplText + logTmpl)
+var logTmpl = "{{if .Verbose}}\n<details><summary>{{ t \"log_summary\" }}</summary>\n  <p>\n\n```\n{{.Log}}```\n</p></details>{{end}}\n"
@@ -21,6 +21,7 @@ import (
 
 	"github.com/runatlantis/atlantis/server/events"
 	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/i18n"
 	. "github.com/runatlantis/atlantis/testing"
 )
 
@@ -60,7 +61,7 @@ func TestRenderErr(t *testing.T) {
 		}
 		for _, verbose := range []bool{true, false} {
 			t.Run(fmt.Sprintf("%s_%t", c.Description, verbose), func(t *testing.T) {
-				s := r.Render(res, c.Command, "log", verbose, models.Github)
+				s := r.Render(res, c.Command, "log", verbose, models.Github, i18n.English)
 				if !verbose {
 					Equals(t, c.Expected, s)
 				} else {
@@ -105,7 +106,7 @@ func TestRenderFailure(t *testing.T) {
 		}
 		for _, verbose := range []bool{true, false} {
 			t.Run(fmt.Sprintf("%s_%t", c.Description, verbose), func(t *testing.T) {
-				s := r.Render(res, c.Command, "log", verbose, models.Github)
+				s := r.Render(res, c.Command, "log", verbose, models.Github, i18n.English)
 				if !verbose {
 					Equals(t, c.Expected, s)
 				} else {
@@ -122,7 +123,7 @@ func TestRenderErrAndFailure(t *testing.T) {
 		Error:   errors.New("error"),
 		Failure: "failure",
 	}
-	s := r.Render(res, models.PlanCommand, "", false, models.Github)
+	s := r.Render(res, models.PlanCommand, "", false, models.Github, i18n.English)
 	Equals(t, "**Plan Error**\n```\nerror\n```\n", s)
 }
 
@@ -174,6 +175,119 @@ $$$
     * $atlantis apply$
 * :put_litter_in_its_place: To delete all plans and locks for the PR, comment:
     * $atlantis unlock$
+`,
+		},
+		{
+			"single successful import",
+			models.ImportCommand,
+			[]models.ProjectResult{
+				{
+					ImportSuccess: &models.ImportSuccess{
+						Output:    "import-output",
+						RePlanCmd: "atlantis plan -d path -w workspace",
+					},
+					Workspace:  "workspace",
+					RepoRelDir: "path",
+				},
+			},
+			models.Github,
+			`Ran Import for dir: $path$ workspace: $workspace$
+
+$$$diff
+import-output
+$$$
+
+:put_litter_in_its_place: Any existing plan for this project was deleted since it's now stale.
+
+* :repeat: To **plan** this project again, comment:
+    * $atlantis plan -d path -w workspace$
+
+`,
+		},
+		{
+			"single successful state command",
+			models.StateCommand,
+			[]models.ProjectResult{
+				{
+					StateSuccess: &models.StateSuccess{
+						Output:    "state-output",
+						RePlanCmd: "atlantis plan -p projectname",
+					},
+					Workspace:   "workspace",
+					RepoRelDir:  "path",
+					ProjectName: "projectname",
+				},
+			},
+			models.Github,
+			`Ran State for project: $projectname$ dir: $path$ workspace: $workspace$
+
+$$$diff
+state-output
+$$$
+
+:put_litter_in_its_place: Any existing plan for this project was deleted since it's now stale.
+
+* :repeat: To **plan** this project again, comment:
+    * $atlantis plan -p projectname$
+
+`,
+		},
+		{
+			"single successful validate",
+			models.ValidateCommand,
+			[]models.ProjectResult{
+				{
+					ValidateSuccess: "Success! The configuration is valid.",
+					Workspace:       "workspace",
+					RepoRelDir:      "path",
+				},
+			},
+			models.Github,
+			`Ran Validate for dir: $path$ workspace: $workspace$
+
+$$$
+Success! The configuration is valid.
+$$$
+
+`,
+		},
+		{
+			"multiple validate with a failure",
+			models.ValidateCommand,
+			[]models.ProjectResult{
+				{
+					ValidateSuccess: "Success! The configuration is valid.",
+					Workspace:       "workspace",
+					RepoRelDir:      "path",
+				},
+				{
+					Error:       errors.New("exit status 1\nError: Unsupported argument"),
+					Workspace:   "workspace",
+					RepoRelDir:  "path2",
+					ProjectName: "projectname",
+				},
+			},
+			models.Github,
+			`Ran Validate for 2 projects:
+
+1. dir: $path$ workspace: $workspace$
+1. project: $projectname$ dir: $path2$ workspace: $workspace$
+
+### 1. dir: $path$ workspace: $workspace$
+$$$
+Success! The configuration is valid.
+$$$
+
+---
+### 2. project: $projectname$ dir: $path2$ workspace: $workspace$
+**Validate Error**
+$$$
+exit status 1
+Error: Unsupported argument
+$$$
+
+---
+
 `,
 		},
 		{
@@ -753,7 +867,7 @@ $$$
 			}
 			for _, verbose := range []bool{true, false} {
 				t.Run(c.Description, func(t *testing.T) {
-					s := r.Render(res, c.Command, "log", verbose, c.VCSHost)
+					s := r.Render(res, c.Command, "log", verbose, c.VCSHost, i18n.English)
 					expWithBackticks := strings.Replace(c.Expected, "$", "`", -1)
 					if !verbose {
 						Equals(t, expWithBackticks, s)
@@ -906,7 +1020,7 @@ $$$
 			}
 			for _, verbose := range []bool{true, false} {
 				t.Run(c.Description, func(t *testing.T) {
-					s := r.Render(res, c.Command, "log", verbose, c.VCSHost)
+					s := r.Render(res, c.Command, "log", verbose, c.VCSHost, i18n.English)
 					expWithBackticks := strings.Replace(c.Expected, "$", "`", -1)
 					if !verbose {
 						Equals(t, expWithBackticks, s)
@@ -1052,7 +1166,7 @@ $$$
 			}
 			for _, verbose := range []bool{true, false} {
 				t.Run(c.Description, func(t *testing.T) {
-					s := r.Render(res, c.Command, "log", verbose, c.VCSHost)
+					s := r.Render(res, c.Command, "log", verbose, c.VCSHost, i18n.English)
 					expWithBackticks := strings.Replace(c.Expected, "$", "`", -1)
 					if !verbose {
 						Equals(t, expWithBackticks, s)
@@ -1079,7 +1193,7 @@ func TestRenderProjectResults_DisableFolding(t *testing.T) {
 				Error:      errors.New(strings.Repeat("line\n", 13)),
 			},
 		},
-	}, models.PlanCommand, "log", false, models.Github)
+	}, models.PlanCommand, "log", false, models.Github, i18n.English)
 	Equals(t, false, strings.Contains(rendered, "<details>"))
 }
 
@@ -1163,7 +1277,7 @@ func TestRenderProjectResults_WrappedErr(t *testing.T) {
 							Error:      errors.New(c.Output),
 						},
 					},
-				}, models.PlanCommand, "log", false, c.VCSHost)
+				}, models.PlanCommand, "log", false, c.VCSHost, i18n.English)
 				var exp string
 				if c.ShouldWrap {
 					exp = `Ran Plan for dir: $.$ workspace: $default$
@@ -1383,7 +1497,7 @@ func TestRenderProjectResults_MultiProjectApplyWrapped(t *testing.T) {
 				ApplySuccess: tfOut,
 			},
 		},
-	}, models.ApplyCommand, "log", false, models.Github)
+	}, models.ApplyCommand, "log", false, models.Github, i18n.English)
 	exp := `Ran Apply for 2 projects:
 
 1. dir: $.$ workspace: $staging$
@@ -1439,11 +1553,13 @@ func TestRenderProjectResults_MultiProjectPlanWrapped(t *testing.T) {
 				},
 			},
 		},
-	}, models.PlanCommand, "log", false, models.Github)
+	}, models.PlanCommand, "log", false, models.Github, i18n.English)
 	exp := `Ran Plan for 2 projects:
 
-1. dir: $.$ workspace: $staging$
-1. dir: $.$ workspace: $production$
+**Total:** $+2 ~0 -0$
+
+1. dir: $.$ workspace: $staging$ ($+1 ~0 -0$)
+1. dir: $.$ workspace: $production$ ($+1 ~0 -0$)
 
 ### 1. dir: $.$ workspace: $staging$
 <details><summary>Show Output</summary>
@@ -1486,6 +1602,457 @@ Plan: 1 to add, 0 to change, 0 to destroy.
 	Equals(t, expWithBackticks, rendered)
 }
 
+// Test that the resource change summary of each project and the total across
+// projects is rendered at the top of multi-project plans.
+func TestRenderProjectResults_MultiProjectPlanChanges(t *testing.T) {
+	mr := events.MarkdownRenderer{}
+	rendered := mr.Render(events.CommandResult{
+		ProjectResults: []models.ProjectResult{
+			{
+				RepoRelDir: "dir1",
+				Workspace:  "default",
+				PlanSuccess: &models.PlanSuccess{
+					TerraformOutput: "Plan: 3 to add, 1 to change, 2 to destroy.",
+					LockURL:         "lock-url",
+					ApplyCmd:        "apply-cmd",
+					RePlanCmd:       "replan-cmd",
+				},
+			},
+			{
+				RepoRelDir: "dir2",
+				Workspace:  "default",
+				PlanSuccess: &models.PlanSuccess{
+					TerraformOutput: "No changes. Your infrastructure matches the configuration.",
+					LockURL:         "lock-url2",
+					ApplyCmd:        "apply-cmd2",
+					RePlanCmd:       "replan-cmd2",
+				},
+			},
+			{
+				RepoRelDir: "dir3",
+				Workspace:  "default",
+				Error:      errors.New("error"),
+			},
+		},
+	}, models.PlanCommand, "log", false, models.Github, i18n.English)
+	exp := `Ran Plan for 3 projects:
+
+**Total:** $+3 ~1 -2$
+
+1. dir: $dir1$ workspace: $default$ ($+3 ~1 -2$)
+1. dir: $dir2$ workspace: $default$ ($+0 ~0 -0$)
+1. dir: $dir3$ workspace: $default$
+
+`
+	expWithBackticks := strings.Replace(exp, "$", "`", -1)
+	Assert(t, strings.HasPrefix(rendered, expWithBackticks), "exp prefix %q, got %q", expWithBackticks, rendered)
+}
+
+// Test that structured plans are rendered as resources grouped by action
+// instead of the raw plan output.
+func TestRenderProjectResults_StructuredPlan(t *testing.T) {
+	result := events.CommandResult{
+		ProjectResults: []models.ProjectResult{
+			{
+				RepoRelDir: ".",
+				Workspace:  "default",
+				PlanSuccess: &models.PlanSuccess{
+					TerraformOutput: "raw-output\nPlan: 1 to add, 1 to change, 2 to destroy.",
+					LockURL:         "lock-url",
+					ApplyCmd:        "apply-cmd",
+					RePlanCmd:       "replan-cmd",
+					StructuredPlan: &models.StructuredPlan{
+						Create:  []string{"null_resource.a"},
+						Update:  []string{"null_resource.b"},
+						Replace: []string{"null_resource.c"},
+						Delete:  []string{"null_resource.d"},
+					},
+				},
+			},
+		},
+	}
+
+	cases := []struct {
+		vcsHost models.VCSHostType
+		exp     string
+	}{
+		{
+			models.Github,
+			`Ran Plan for dir: $.$ workspace: $default$
+
+<details><summary>Create (1)</summary>
+
+$$$diff
++ null_resource.a
+$$$
+</details>
+<details><summary>Destroy (1)</summary>
+
+$$$diff
+- null_resource.d
+$$$
+</details>
+<details><summary>Update in-place (1)</summary>
+
+$$$diff
+! null_resource.b
+$$$
+</details>
+<details><summary>Replace (1)</summary>
+
+$$$diff
+-/+ null_resource.c
+$$$
+</details>
+
+* :arrow_forward: To **apply** this plan, comment:
+    * $apply-cmd$
+* :put_litter_in_its_place: To **delete** this plan click [here](lock-url)
+* :repeat: To **plan** this project again, comment:
+    * $replan-cmd$
+Plan: 1 to add, 1 to change, 2 to destroy.
+`,
+		},
+		{
+			models.BitbucketCloud,
+			`Ran Plan for dir: $.$ workspace: $default$
+
+**Create (1)**
+$$$diff
++ null_resource.a
+$$$
+**Destroy (1)**
+$$$diff
+- null_resource.d
+$$$
+**Update in-place (1)**
+$$$diff
+! null_resource.b
+$$$
+**Replace (1)**
+$$$diff
+-/+ null_resource.c
+$$$
+
+* :arrow_forward: To **apply** this plan, comment:
+    * $apply-cmd$
+* :put_litter_in_its_place: To **delete** this plan click [here](lock-url)
+* :repeat: To **plan** this project again, comment:
+    * $replan-cmd$
+Plan: 1 to add, 1 to change, 2 to destroy.
+`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.vcsHost.String(), func(t *testing.T) {
+			mr := events.MarkdownRenderer{}
+			rendered := mr.Render(result, models.PlanCommand, "log", false, c.vcsHost, i18n.English)
+			exp := strings.Replace(c.exp, "$", "`", -1)
+			Assert(t, strings.HasPrefix(rendered, exp), "exp prefix %q, got %q", exp, rendered)
+		})
+	}
+}
+
+func TestRenderProjectResults_DestroyPlan(t *testing.T) {
+	result := events.CommandResult{
+		ProjectResults: []models.ProjectResult{
+			{
+				RepoRelDir: ".",
+				Workspace:  "default",
+				PlanSuccess: &models.PlanSuccess{
+					TerraformOutput: "terraform-output",
+					LockURL:         "lock-url",
+					ApplyCmd:        "apply-cmd",
+					RePlanCmd:       "replan-cmd",
+					Destroy:         true,
+				},
+			},
+		},
+	}
+	mr := events.MarkdownRenderer{}
+	rendered := mr.Render(result, models.PlanCommand, "log", false, models.Github, i18n.English)
+	exp := strings.Replace(`Ran Plan for dir: $.$ workspace: $default$
+
+:warning: **This is a destroy plan.** Applying it will destroy every resource in this project.
+
+$$$diff
+terraform-output
+$$$
+`, "$", "`", -1)
+	Assert(t, strings.HasPrefix(rendered, exp), "exp prefix %q, got %q", exp, rendered)
+
+	result.ProjectResults[0].PlanSuccess.Destroy = false
+	rendered = mr.Render(result, models.PlanCommand, "log", false, models.Github, i18n.English)
+	Assert(t, !strings.Contains(rendered, "destroy plan"), "exp no destroy warning, got %q", rendered)
+}
+
+func TestRenderProjectResults_PlanRetries(t *testing.T) {
+	result := events.CommandResult{
+		ProjectResults: []models.ProjectResult{
+			{
+				RepoRelDir: ".",
+				Workspace:  "default",
+				PlanSuccess: &models.PlanSuccess{
+					TerraformOutput: "terraform-output",
+					LockURL:         "lock-url",
+					ApplyCmd:        "apply-cmd",
+					RePlanCmd:       "replan-cmd",
+					Retries: []models.StepRetry{
+						{StepName: "init", Attempt: 1, Error: "Error: connection reset by peer"},
+						{StepName: "plan", Attempt: 1, Error: "Error: Rate exceeded"},
+					},
+				},
+			},
+		},
+	}
+	mr := events.MarkdownRenderer{}
+	rendered := mr.Render(result, models.PlanCommand, "log", false, models.Github, i18n.English)
+	exp := strings.Replace(`Ran Plan for dir: $.$ workspace: $default$
+
+:recycle: Some steps failed with transient errors and were retried:
+* $init$ attempt 1: $Error: connection reset by peer$
+* $plan$ attempt 1: $Error: Rate exceeded$
+
+$$$diff
+terraform-output
+$$$
+`, "$", "`", -1)
+	Assert(t, strings.HasPrefix(rendered, exp), "exp prefix %q, got %q", exp, rendered)
+}
+
+func TestRenderProjectResults_PlanScanResults(t *testing.T) {
+	result := events.CommandResult{
+		ProjectResults: []models.ProjectResult{
+			{
+				RepoRelDir: ".",
+				Workspace:  "default",
+				PlanSuccess: &models.PlanSuccess{
+					TerraformOutput: "terraform-output",
+					LockURL:         "lock-url",
+					ApplyCmd:        "apply-cmd",
+					RePlanCmd:       "replan-cmd",
+					ScanResults: []models.ScanResult{
+						{
+							Tool: "checkov",
+							Findings: []models.ScanFinding{
+								{RuleID: "CKV_AWS_18", Description: "Ensure access logging is enabled", Severity: "UNKNOWN", Resource: "aws_s3_bucket.logs", Location: "s3.tf:3"},
+								{RuleID: "CKV_AWS_19", Description: "Ensure encryption is enabled", Severity: "LOW", Location: "s3.tf:3"},
+								{RuleID: "CKV_AWS_20", Description: "Ensure the bucket isn't public", Severity: "CRITICAL", Resource: "aws_s3_bucket.logs", Location: "s3.tf:3"},
+							},
+						},
+						{Tool: "tfsec", FailOn: "HIGH"},
+					},
+				},
+			},
+		},
+	}
+	mr := events.MarkdownRenderer{}
+	rendered := mr.Render(result, models.PlanCommand, "log", false, models.Github, i18n.English)
+	exp := strings.Replace(`Ran Plan for dir: $.$ workspace: $default$
+
+:mag: **checkov** found 3 issue(s):
+
+**CRITICAL (1)**
+* $CKV_AWS_20$ $aws_s3_bucket.logs$ at $s3.tf:3$: Ensure the bucket isn't public
+
+**LOW (1)**
+* $CKV_AWS_19$ at $s3.tf:3$: Ensure encryption is enabled
+
+**UNKNOWN (1)**
+* $CKV_AWS_18$ $aws_s3_bucket.logs$ at $s3.tf:3$: Ensure access logging is enabled
+
+:white_check_mark: **tfsec** found no issues.
+
+$$$diff
+terraform-output
+$$$
+`, "$", "`", -1)
+	Assert(t, strings.HasPrefix(rendered, exp), "exp prefix %q, got %q", exp, rendered)
+}
+
+func TestRenderProjectResults_ApplyOutputs(t *testing.T) {
+	result := events.CommandResult{
+		ProjectResults: []models.ProjectResult{
+			{
+				RepoRelDir:   ".",
+				Workspace:    "default",
+				ApplySuccess: "success",
+				ApplyOutputs: []models.TerraformOutput{
+					{Name: "endpoint", Value: "https://api.example.com"},
+					{Name: "password", Sensitive: true},
+				},
+			},
+		},
+	}
+	mr := events.MarkdownRenderer{}
+	rendered := mr.Render(result, models.ApplyCommand, "log", false, models.Github, i18n.English)
+	exp := strings.Replace(`Ran Apply for dir: $.$ workspace: $default$
+
+$$$diff
+success
+$$$
+
+**Outputs**
+
+* $endpoint$: $https://api.example.com$
+* $password$: *(sensitive)*
+
+`, "$", "`", -1)
+	Equals(t, exp, rendered)
+}
+
+func TestRenderCustom(t *testing.T) {
+	mr := events.MarkdownRenderer{}
+	result := events.CommandResult{
+		ProjectResults: []models.ProjectResult{
+			{
+				RepoRelDir:    "path",
+				Workspace:     "workspace",
+				CustomSuccess: &models.CustomSuccess{Output: "deployed"},
+			},
+		},
+	}
+	rendered := mr.RenderCustom(result, "deploy", "log", false, models.Github, i18n.English)
+	exp := strings.Replace(`Ran Deploy for dir: $path$ workspace: $workspace$
+
+$$$
+deployed
+$$$
+
+`, "$", "`", -1)
+	Equals(t, exp, rendered)
+
+	result.ProjectResults = append(result.ProjectResults, models.ProjectResult{
+		RepoRelDir: "path2",
+		Workspace:  "workspace",
+		Failure:    "This project is currently locked",
+	})
+	rendered = mr.RenderCustom(result, "deploy", "log", false, models.Github, i18n.English)
+	exp = strings.Replace(`Ran Deploy for 2 projects:
+
+1. dir: $path$ workspace: $workspace$
+1. dir: $path2$ workspace: $workspace$
+
+### 1. dir: $path$ workspace: $workspace$
+$$$
+deployed
+$$$
+
+---
+### 2. dir: $path2$ workspace: $workspace$
+**Deploy Failed**: This project is currently locked
+
+---
+
+`, "$", "`", -1)
+	Equals(t, exp, rendered)
+}
+
+// Test that comments are rendered in the locale they're asked for and that
+// terraform's output isn't translated.
+func TestRender_Japanese(t *testing.T) {
+	mr := events.MarkdownRenderer{}
+	result := events.CommandResult{
+		ProjectResults: []models.ProjectResult{
+			{
+				RepoRelDir: "path",
+				Workspace:  "workspace",
+				PlanSuccess: &models.PlanSuccess{
+					TerraformOutput: "terraform-output",
+					LockURL:         "lock-url",
+					RePlanCmd:       "atlantis plan -d path -w workspace",
+					ApplyCmd:        "atlantis apply -d path -w workspace",
+				},
+			},
+		},
+	}
+	rendered := mr.Render(result, models.PlanCommand, "log", false, models.Github, i18n.Japanese)
+	exp := strings.Replace(`ディレクトリ $path$、ワークスペース $workspace$ で Plan を実行しました
+
+$$$diff
+terraform-output
+$$$
+
+* :arrow_forward: このプランを **apply** するには、次のコメントをしてください:
+    * $atlantis apply -d path -w workspace$
+* :put_litter_in_its_place: このプランを**削除**するには[ここ](lock-url)をクリックしてください
+* :repeat: このプロジェクトをもう一度 **plan** するには、次のコメントをしてください:
+    * $atlantis plan -d path -w workspace$
+
+---
+* :fast_forward: このプルリクエストの apply されていないプランをすべて **apply** するには、次のコメントをしてください:
+    * $atlantis apply$
+* :put_litter_in_its_place: このプルリクエストのすべてのプランとロックを削除するには、次のコメントをしてください:
+    * $atlantis unlock$
+`, "$", "`", -1)
+	Equals(t, exp, rendered)
+
+	rendered = mr.Render(events.CommandResult{Failure: "failure"}, models.PlanCommand, "log", false, models.Github, i18n.Japanese)
+	Equals(t, "**Plan 失敗**: failure\n", rendered)
+}
+
+func TestRenderRollup(t *testing.T) {
+	mr := events.MarkdownRenderer{}
+	pull := models.PullRequest{Num: 1, BaseRepo: models.Repo{FullName: "owner/repo"}}
+	result := events.CommandResult{
+		ProjectResults: []models.ProjectResult{
+			{
+				Command:     models.PlanCommand,
+				RepoRelDir:  "path",
+				Workspace:   "default",
+				ProjectName: "project",
+				PlanSuccess: &models.PlanSuccess{TerraformOutput: "Plan: 1 to add, 2 to change, 0 to destroy."},
+			},
+			{
+				Command:    models.PlanCommand,
+				RepoRelDir: "path2",
+				Workspace:  "staging",
+				Error:      errors.New("error"),
+			},
+		},
+	}
+	rendered := mr.RenderRollup(result, models.PlanCommand, pull, mockURLGenerator{}, "log", false, i18n.English)
+	exp := strings.Replace(`Ran Plan for 2 projects. See the [full output](https://history/owner/repo/1):
+
+**Total:** $+1 ~2 -0$
+
+| Project | Dir | Workspace | Status | Add | Change | Destroy | Output |
+|---------|-----|-----------|--------|-----|--------|---------|--------|
+| $project$ | $path$ | $default$ | :white_check_mark: $planned$ | 1 | 2 | 0 | [view](https://history/owner/repo/1/path/default) |
+| | $path2$ | $staging$ | :x: $plan_errored$ | | | | [view](https://history/owner/repo/1/path2/staging) |
+
+---
+* :fast_forward: To **apply** all unapplied plans from this pull request, comment:
+    * $atlantis apply$
+* :put_litter_in_its_place: To delete all plans and locks for the PR, comment:
+    * $atlantis unlock$
+`, "$", "`", -1)
+	Equals(t, exp, rendered)
+
+	t.Log("applies are rolled up without links if there's no history page")
+	result = events.CommandResult{
+		ProjectResults: []models.ProjectResult{
+			{
+				Command:      models.ApplyCommand,
+				RepoRelDir:   "path",
+				Workspace:    "default",
+				ApplySuccess: "Apply complete! Resources: 1 added, 0 changed, 3 destroyed.",
+			},
+		},
+	}
+	rendered = mr.RenderRollup(result, models.ApplyCommand, pull, nil, "log", false, i18n.English)
+	exp = strings.Replace(`Ran Apply for 1 projects:
+
+**Total:** $+1 ~0 -3$
+
+| Project | Dir | Workspace | Status | Add | Change | Destroy |
+|---------|-----|-----------|--------|-----|--------|---------|
+| | $path$ | $default$ | :white_check_mark: $applied$ | 1 | 0 | 3 |
+
+`, "$", "`", -1)
+	Equals(t, exp, rendered)
+}
+
 // Test rendering when there was an error in one of the plans and we deleted
 // all the plans as a result.
 func TestRenderProjectResults_PlansDeleted(t *testing.T) {
@@ -1588,7 +2155,7 @@ This plan was not saved because one or more projects failed and automerge requir
 	for name, c := range cases {
 		t.Run(name, func(t *testing.T) {
 			mr := events.MarkdownRenderer{}
-			rendered := mr.Render(c.cr, models.PlanCommand, "log", false, models.Github)
+			rendered := mr.Render(c.cr, models.PlanCommand, "log", false, models.Github, i18n.English)
 			expWithBackticks := strings.Replace(c.exp, "$", "`", -1)
 			Equals(t, expWithBackticks, rendered)
 		})
@@ -2056,7 +2623,7 @@ $$$
 			}
 			for _, verbose := range []bool{true, false} {
 				t.Run(c.Description, func(t *testing.T) {
-					s := r.Render(res, c.Command, "log", verbose, c.VCSHost)
+					s := r.Render(res, c.Command, "log", verbose, c.VCSHost, i18n.English)
 					expWithBackticks := strings.Replace(c.Expected, "$", "`", -1)
 					if !verbose {
 						Equals(t, expWithBackticks, s)
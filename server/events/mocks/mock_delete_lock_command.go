@@ -63,6 +63,25 @@ func (mock *MockDeleteLockCommand) DeleteLocksByPull(repoFullName string, pullNu
 	return ret0, ret1
 }
 
+func (mock *MockDeleteLockCommand) DeleteProjectLocksByPull(repoFullName string, pullNum int, path string, workspace string) (int, error) {
+	if mock == nil {
+		panic("mock must not be nil. Use myMock := NewMockDeleteLockCommand().")
+	}
+	params := []pegomock.Param{repoFullName, pullNum, path, workspace}
+	result := pegomock.GetGenericMockFrom(mock).Invoke("DeleteProjectLocksByPull", params, []reflect.Type{reflect.TypeOf((*int)(nil)).Elem(), reflect.TypeOf((*error)(nil)).Elem()})
+	var ret0 int
+	var ret1 error
+	if len(result) != 0 {
+		if result[0] != nil {
+			ret0 = result[0].(int)
+		}
+		if result[1] != nil {
+			ret1 = result[1].(error)
+		}
+	}
+	return ret0, ret1
+}
+
 func (mock *MockDeleteLockCommand) VerifyWasCalledOnce() *VerifierMockDeleteLockCommand {
 	return &VerifierMockDeleteLockCommand{
 		mock:                   mock,
@@ -157,3 +176,42 @@ func (c *MockDeleteLockCommand_DeleteLocksByPull_OngoingVerification) GetAllCapt
 	}
 	return
 }
+
+func (verifier *VerifierMockDeleteLockCommand) DeleteProjectLocksByPull(repoFullName string, pullNum int, path string, workspace string) *MockDeleteLockCommand_DeleteProjectLocksByPull_OngoingVerification {
+	params := []pegomock.Param{repoFullName, pullNum, path, workspace}
+	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "DeleteProjectLocksByPull", params, verifier.timeout)
+	return &MockDeleteLockCommand_DeleteProjectLocksByPull_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
+}
+
+type MockDeleteLockCommand_DeleteProjectLocksByPull_OngoingVerification struct {
+	mock              *MockDeleteLockCommand
+	methodInvocations []pegomock.MethodInvocation
+}
+
+func (c *MockDeleteLockCommand_DeleteProjectLocksByPull_OngoingVerification) GetCapturedArguments() (string, int, string, string) {
+	repoFullName, pullNum, path, workspace := c.GetAllCapturedArguments()
+	return repoFullName[len(repoFullName)-1], pullNum[len(pullNum)-1], path[len(path)-1], workspace[len(workspace)-1]
+}
+
+func (c *MockDeleteLockCommand_DeleteProjectLocksByPull_OngoingVerification) GetAllCapturedArguments() (_param0 []string, _param1 []int, _param2 []string, _param3 []string) {
+	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
+	if len(params) > 0 {
+		_param0 = make([]string, len(c.methodInvocations))
+		for u, param := range params[0] {
+			_param0[u] = param.(string)
+		}
+		_param1 = make([]int, len(c.methodInvocations))
+		for u, param := range params[1] {
+			_param1[u] = param.(int)
+		}
+		_param2 = make([]string, len(c.methodInvocations))
+		for u, param := range params[2] {
+			_param2[u] = param.(string)
+		}
+		_param3 = make([]string, len(c.methodInvocations))
+		for u, param := range params[3] {
+			_param3[u] = param.(string)
+		}
+	}
+	return
+}
@@ -17,10 +17,14 @@
 package models
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"net/url"
 	paths "path"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -115,11 +119,11 @@ func NewRepo(vcsHostType VCSHostType, repoFullName string, cloneURL string, vcsU
 	sanitizedCloneURL := strings.Replace(cloneURL, "https://", "https://"+redactedAuth, -1)
 	sanitizedCloneURL = strings.Replace(sanitizedCloneURL, "http://", "http://"+redactedAuth, -1)
 
+	if err := ValidateRepoFullName(repoFullName); err != nil {
+		return Repo{}, err
+	}
 	// Get the owner and repo names from the full name.
 	owner, repo := SplitRepoFullName(repoFullName)
-	if owner == "" || repo == "" {
-		return Repo{}, fmt.Errorf("invalid repo format %q, owner %q or repo %q was empty", repoFullName, owner, repo)
-	}
 	// Only GitLab and AzureDevops repos can have /'s in their owners.
 	// This is for GitLab subgroups and Azure DevOps Team Projects.
 	if strings.Contains(owner, "/") && vcsHostType != Gitlab && vcsHostType != AzureDevops {
@@ -176,8 +180,21 @@ type PullRequestOptions struct {
 	// When DeleteSourceBranchOnMerge flag is set to true VCS deletes the source branch after the PR is merged
 	// Applied by GitLab & AzureDevops
 	DeleteSourceBranchOnMerge bool
+	// MergeMethod is how to merge the pull request, ex. SquashMergeMethod.
+	// If empty, the VCS host's default is used.
+	MergeMethod string
+	// CommitMessage is the message of the merge (or squash) commit. If empty,
+	// a default message is used.
+	CommitMessage string
 }
 
+// Merge methods that can be set in PullRequestOptions.MergeMethod.
+const (
+	MergeCommitMergeMethod = "merge"
+	SquashMergeMethod      = "squash"
+	RebaseMergeMethod      = "rebase"
+)
+
 type PullRequestState int
 
 const (
@@ -250,8 +267,31 @@ type ProjectLock struct {
 	Workspace string
 	// Time is the time at which the lock was first created.
 	Time time.Time
+	// Granularity is what the lock is held on, one of the
+	// valid.*LockGranularity values. Empty means the project's dir and
+	// workspace, which is how locks were held before it was configurable.
+	Granularity string
+}
+
+// LockTarget returns the project and workspace that identify what l is held
+// on. Locks with the same target can't be held by different pull requests.
+func (l ProjectLock) LockTarget() (Project, string) {
+	switch l.Granularity {
+	case valid.DirLockGranularity:
+		return Project{RepoFullName: l.Project.RepoFullName, Path: l.Project.Path}, AllWorkspacesLockTarget
+	case valid.ProjectLockGranularity:
+		// Projects without names can only be told apart by dir and workspace.
+		if l.Project.ProjectName != "" {
+			return Project{RepoFullName: l.Project.RepoFullName, Path: "project:" + l.Project.ProjectName}, AllWorkspacesLockTarget
+		}
+	}
+	return Project{RepoFullName: l.Project.RepoFullName, Path: l.Project.Path}, l.Workspace
 }
 
+// AllWorkspacesLockTarget is the workspace of the LockTarget of locks held
+// on every workspace.
+const AllWorkspacesLockTarget = "*"
+
 // Project represents a Terraform project. Since there may be multiple
 // Terraform projects in a single repo we also include Path to the project
 // root relative to the repo root.
@@ -265,6 +305,8 @@ type Project struct {
 	// out how this is saved in boltdb vs. its usage everywhere else so we don't
 	// break existing dbs.
 	Path string
+	// ProjectName is the name of the project in atlantis.yaml, if it has one.
+	ProjectName string
 }
 
 func (p Project) String() string {
@@ -330,6 +372,23 @@ func (h VCSHostType) String() string {
 	return "<missing String() implementation>"
 }
 
+// NewVCSHostType returns the VCSHostType whose String() is t, ex. "Github".
+func NewVCSHostType(t string) (VCSHostType, error) {
+	switch t {
+	case "Github":
+		return Github, nil
+	case "Gitlab":
+		return Gitlab, nil
+	case "BitbucketCloud":
+		return BitbucketCloud, nil
+	case "BitbucketServer":
+		return BitbucketServer, nil
+	case "AzureDevops":
+		return AzureDevops, nil
+	}
+	return -1, fmt.Errorf("%q is not a valid type", t)
+}
+
 // ProjectCommandContext defines the context for a plan or apply stage that will
 // be executed for a project.
 type ProjectCommandContext struct {
@@ -340,9 +399,18 @@ type ProjectCommandContext struct {
 	// ApplyRequirements is the list of requirements that must be satisfied
 	// before we will run the apply stage.
 	ApplyRequirements []string
-	// AutomergeEnabled is true if automerge is enabled for the repo that this
-	// project is in.
+	// CustomApplyRequirements maps the names of custom apply requirements to
+	// the command that must succeed for the requirement to pass.
+	CustomApplyRequirements map[string]string
+	// AutomergeEnabled is true if automerge is enabled for this project,
+	// either by its repo or by the project itself.
 	AutomergeEnabled bool
+	// AutomergeMethod is the merge method to use when automerging, ex.
+	// squash. If empty, the VCS host's default is used.
+	AutomergeMethod string
+	// AutomergeCommitMessage is the text/template for the automerge commit
+	// message. If empty, a default message is used.
+	AutomergeCommitMessage string
 	// ParallelApplyEnabled is true if parallel apply is enabled for this project.
 	ParallelApplyEnabled bool
 	// ParallelPlanEnabled is true if parallel plan is enabled for this project.
@@ -388,6 +456,14 @@ type ProjectCommandContext struct {
 	// commands for this project. This can be set to nil in which case we will
 	// use the default Atlantis terraform version.
 	TerraformVersion *version.Version
+	// TerraformDistribution is the distribution of terraform, ex. opentofu,
+	// we should use when executing commands for this project. If empty we
+	// will use the default Atlantis distribution.
+	TerraformDistribution string
+	// TFEWorkspace, if set, is the Terraform Cloud/Enterprise workspace, ex.
+	// my-org/my-workspace, that plan and apply are run in via the API
+	// instead of running terraform locally.
+	TFEWorkspace string
 	// User is the user that triggered this command.
 	User User
 	// Verbose is true when the user would like verbose output.
@@ -398,8 +474,106 @@ type ProjectCommandContext struct {
 	// PolicySets represent the policies that are run on the plan as part of the
 	// policy check stage
 	PolicySets valid.PolicySets
+	// SubName is the name of the subcommand, ex. rm for atlantis state rm, or
+	// the name of a custom command, ex. deploy. It is empty for other
+	// commands.
+	SubName string
 	// DeleteSourceBranchOnMerge will attempt to allow a branch to be deleted when merged (AzureDevOps & GitLab Support Only)
 	DeleteSourceBranchOnMerge bool
+	// Destroy is true if this is a plan that destroys every resource, ex.
+	// atlantis plan --destroy.
+	Destroy bool
+	// AllowDestroyPlans is true if the server-side config allows destroy
+	// plans for this project.
+	AllowDestroyPlans bool
+	// NoWorkspaceAutocreate is true if planning in a workspace that doesn't
+	// exist should fail rather than create it.
+	NoWorkspaceAutocreate bool
+	// Targets are the resource addresses passed with --target, ex.
+	// atlantis plan --target aws_instance.web.
+	Targets []string
+	// PlannedTargets are the resource addresses the project's current plan
+	// was targeted at. It's only set for apply.
+	PlannedTargets []string
+	// DisableTargeting is true if the server-side config doesn't allow the
+	// project to be planned or applied with -target.
+	DisableTargeting bool
+	// LockGranularity is what the project's lock is held on, one of the
+	// valid.*LockGranularity values.
+	LockGranularity string
+	// PlanFromEarlierCommit is true if this project was planned at an earlier
+	// commit of the pull request than Pull.HeadCommit, so its planfile may not
+	// reflect the pull request's current changes.
+	PlanFromEarlierCommit bool
+	// Force is true if the apply should use the project's plan even if it's
+	// from an earlier commit, ex. atlantis apply --force.
+	Force bool
+	// AutoApply is true if the apply was started by the repo's auto_apply
+	// config rather than a comment.
+	AutoApply bool
+	// Credentials, if set, are the cloud credentials to obtain before running
+	// the project's steps.
+	Credentials *valid.Credentials
+	// InitOptions are the options terraform init is run with, ex.
+	// -backend-config files.
+	InitOptions valid.InitOptions
+	// InitSteps are the init steps of the project's plan stage. They're run
+	// before apply if the project's working dir had to be restored.
+	InitSteps []valid.Step
+	// Outputs are the names of the project's outputs that the output step
+	// shows in the apply comment.
+	Outputs []string
+	// RequestCtx, if set, carries the command's trace span and is cancelled
+	// when the command is cancelled, ex. by atlantis cancel. Use Context() to
+	// get the context to run steps with.
+	RequestCtx context.Context
+}
+
+// Context returns the context that the project's steps should be run with.
+// It's cancelled when the command is cancelled.
+func (p ProjectCommandContext) Context() context.Context {
+	if p.RequestCtx == nil {
+		return context.Background()
+	}
+	return p.RequestCtx
+}
+
+// IsDestroyPlan returns true if this plan will destroy every resource, either
+// because --destroy was commented or because -destroy was passed as an extra
+// argument, ex. atlantis plan -- -destroy.
+func (p ProjectCommandContext) IsDestroyPlan() bool {
+	if p.Destroy {
+		return true
+	}
+	for _, arg := range p.EscapedCommentArgs {
+		switch strings.Replace(arg, "\\", "", -1) {
+		case "-destroy", "--destroy", "-destroy=true", "--destroy=true":
+			return true
+		}
+	}
+	return false
+}
+
+// TargetAddresses returns the resource addresses this command is targeted at,
+// either with --target or with -target passed as an extra argument, ex.
+// atlantis plan -- -target=aws_instance.web.
+func (p ProjectCommandContext) TargetAddresses() []string {
+	targets := append([]string(nil), p.Targets...)
+	for i := 0; i < len(p.EscapedCommentArgs); i++ {
+		arg := strings.Replace(p.EscapedCommentArgs[i], "\\", "", -1)
+		switch {
+		case arg == "-target" || arg == "--target":
+			if i+1 < len(p.EscapedCommentArgs) {
+				i++
+				targets = append(targets, strings.Replace(p.EscapedCommentArgs[i], "\\", "", -1))
+			}
+		case strings.HasPrefix(arg, "-target="):
+			targets = append(targets, strings.TrimPrefix(arg, "-target="))
+		case strings.HasPrefix(arg, "--target="):
+			targets = append(targets, strings.TrimPrefix(arg, "--target="))
+		}
+	}
+	return targets
 }
 
 // GetShowResultFileName returns the filename (not the path) to store the tf show result
@@ -411,6 +585,116 @@ func (p ProjectCommandContext) GetShowResultFileName() string {
 	return fmt.Sprintf("%s-%s.json", projName, p.Workspace)
 }
 
+// GetOutputsFileName returns the name of the file, relative to the project's
+// dir, that the output step writes the project's outputs to.
+func (p ProjectCommandContext) GetOutputsFileName() string {
+	if p.ProjectName == "" {
+		return fmt.Sprintf("%s.outputs.json", p.Workspace)
+	}
+	projName := strings.Replace(p.ProjectName, "/", planfileSlashReplace, -1)
+	return fmt.Sprintf("%s-%s.outputs.json", projName, p.Workspace)
+}
+
+// TerraformOutput is an output of a project's root module.
+type TerraformOutput struct {
+	Name string
+	// Value is the output's value. Strings are as is and other types are
+	// JSON, ex. ["a","b"]. It's empty if the output is sensitive.
+	Value     string
+	Sensitive bool
+}
+
+// GetScanResultsFileName returns the name of the file, relative to the
+// project's dir, that scan steps write their findings to.
+func (p ProjectCommandContext) GetScanResultsFileName() string {
+	if p.ProjectName == "" {
+		return fmt.Sprintf("%s.scan.json", p.Workspace)
+	}
+	projName := strings.Replace(p.ProjectName, "/", planfileSlashReplace, -1)
+	return fmt.Sprintf("%s-%s.scan.json", projName, p.Workspace)
+}
+
+// ScanResult is the result of a scan step running a security scanner, ex.
+// tfsec, on a project.
+type ScanResult struct {
+	Tool     string
+	Findings []ScanFinding
+	// FailOn is the severity at or above which findings fail the plan. It's
+	// empty if findings don't fail it.
+	FailOn string
+}
+
+// ScanFinding is an issue a security scanner found in a project.
+type ScanFinding struct {
+	RuleID      string
+	Description string
+	// Severity is one of valid.ScanSeverities, or UNKNOWN if the scanner
+	// didn't report it, ex. checkov without a platform API key.
+	Severity string
+	// Resource is the address of the resource with the issue, if any.
+	Resource string
+	// Location is the file, relative to the project's dir, and line of the
+	// issue, ex. main.tf:12.
+	Location string
+}
+
+// ScanSeverityGroup is the findings of a scan with the same severity.
+type ScanSeverityGroup struct {
+	Severity string
+	Findings []ScanFinding
+}
+
+// UnknownScanSeverity is the severity of findings the scanner didn't report
+// a severity for.
+const UnknownScanSeverity = "UNKNOWN"
+
+// scanSeverityRank returns how severe severity is, 0 being the least severe.
+// Unknown severities rank the same as the lowest so they fail plans that fail
+// on any finding.
+func scanSeverityRank(severity string) int {
+	for i, s := range valid.ScanSeverities {
+		if s == severity {
+			return i
+		}
+	}
+	return 0
+}
+
+// Groups returns the findings grouped by severity, most severe first.
+func (r ScanResult) Groups() []ScanSeverityGroup {
+	var groups []ScanSeverityGroup
+	for i := len(valid.ScanSeverities) - 1; i >= 0; i-- {
+		groups = appendScanGroup(groups, valid.ScanSeverities[i], r.Findings)
+	}
+	return appendScanGroup(groups, UnknownScanSeverity, r.Findings)
+}
+
+func appendScanGroup(groups []ScanSeverityGroup, severity string, findings []ScanFinding) []ScanSeverityGroup {
+	group := ScanSeverityGroup{Severity: severity}
+	for _, f := range findings {
+		if f.Severity == severity {
+			group.Findings = append(group.Findings, f)
+		}
+	}
+	if len(group.Findings) == 0 {
+		return groups
+	}
+	return append(groups, group)
+}
+
+// Failed returns true if any finding is at or above FailOn.
+func (r ScanResult) Failed() bool {
+	if r.FailOn == "" {
+		return false
+	}
+	for _, f := range r.Findings {
+		if scanSeverityRank(f.Severity) >= scanSeverityRank(r.FailOn) {
+			return true
+		}
+	}
+	return false
+}
+
 // SplitRepoFullName splits a repo full name up into its owner and repo
 // name segments. If the repoFullName is malformed, may return empty
 // strings for owner or repo.
@@ -426,6 +710,22 @@ func SplitRepoFullName(repoFullName string) (owner string, repo string) {
 	return repoFullName[:lastSlashIdx], repoFullName[lastSlashIdx+1:]
 }
 
+// ValidateRepoFullName returns an error if repoFullName isn't of the form
+// owner/repo. Since repos are cloned into a dir named after their full name,
+// none of its parts can be . or .. either.
+func ValidateRepoFullName(repoFullName string) error {
+	owner, repo := SplitRepoFullName(repoFullName)
+	if owner == "" || repo == "" {
+		return fmt.Errorf("invalid repo format %q, owner %q or repo %q was empty", repoFullName, owner, repo)
+	}
+	for _, part := range strings.Split(repoFullName, "/") {
+		if part == "" || part == "." || part == ".." || strings.Contains(part, "\\") {
+			return fmt.Errorf("invalid repo format %q, %q isn't a valid owner or repo", repoFullName, part)
+		}
+	}
+	return nil
+}
+
 // ProjectResult is the result of executing a plan/policy_check/apply for a specific project.
 type ProjectResult struct {
 	Command            CommandName
@@ -437,7 +737,20 @@ type ProjectResult struct {
 	PolicyCheckSuccess *PolicyCheckSuccess
 	ApplySuccess       string
 	VersionSuccess     string
+	ValidateSuccess    string
+	ImportSuccess      *ImportSuccess
+	StateSuccess       *StateSuccess
+	CustomSuccess      *CustomSuccess
 	ProjectName        string
+	// ApplyOutputs are the project's outputs after a successful apply, if
+	// its apply steps included an output step.
+	ApplyOutputs []TerraformOutput
+	// Targets are the resource addresses the project's plan was targeted at
+	// with -target, if any. They're set for plan and apply.
+	Targets []string
+	// CreatedLock is the key of the project lock the plan created, if it
+	// created one rather than the pull request already holding it.
+	CreatedLock string
 }
 
 // CommitStatus returns the vcs commit status of this project result.
@@ -500,6 +813,27 @@ type PlanSuccess struct {
 	// branch we're merging into has been updated since we cloned and merged
 	// it.
 	HasDiverged bool
+	// StructuredPlan is the plan's resource changes grouped by action. It's
+	// only set if structured plan output is enabled.
+	StructuredPlan *StructuredPlan
+	// Destroy is true if this plan destroys every resource.
+	Destroy bool
+	// Retries are the attempts of the plan's steps that failed with a
+	// transient error and were run again.
+	Retries []StepRetry
+	// ScanResults are the results of the plan's scan steps.
+	ScanResults []ScanResult
+}
+
+// StepRetry is an attempt of a step that failed with a transient error, ex.
+// provider throttling, and was run again.
+type StepRetry struct {
+	// StepName is the name of the step, ex. init.
+	StepName string
+	// Attempt is the number of the attempt that failed, starting at 1.
+	Attempt int
+	// Error is the line of terraform's output that shows the error.
+	Error string
 }
 
 // Summary extracts one line summary of plan changes from TerraformOutput.
@@ -518,6 +852,37 @@ func (p *PlanSuccess) Summary() string {
 	return note + r.FindString(p.TerraformOutput)
 }
 
+// PlanChanges is the number of resources a plan will add, change and destroy.
+type PlanChanges struct {
+	Add     int
+	Change  int
+	Destroy int
+}
+
+// String returns a short summary of the changes, ex. "+3 ~1 -0".
+func (p PlanChanges) String() string {
+	return fmt.Sprintf("+%d ~%d -%d", p.Add, p.Change, p.Destroy)
+}
+
+// Changes parses the number of resources to add, change and destroy from
+// TerraformOutput. It returns false if the output doesn't contain a plan
+// summary line.
+func (p *PlanSuccess) Changes() (PlanChanges, bool) {
+	r := regexp.MustCompile(`Plan: (\d+) to add, (\d+) to change, (\d+) to destroy.`)
+	if match := r.FindStringSubmatch(p.TerraformOutput); match != nil {
+		// The regex only matches digits so we can ignore the errors.
+		add, _ := strconv.Atoi(match[1])
+		change, _ := strconv.Atoi(match[2])
+		destroy, _ := strconv.Atoi(match[3])
+		return PlanChanges{Add: add, Change: change, Destroy: destroy}, true
+	}
+	r = regexp.MustCompile(`No changes. (Infrastructure is up-to-date|Your infrastructure matches the configuration).`)
+	if r.MatchString(p.TerraformOutput) {
+		return PlanChanges{}, true
+	}
+	return PlanChanges{}, false
+}
+
 // PolicyCheckSuccess is the result of a successful policy check run.
 type PolicyCheckSuccess struct {
 	// PolicyCheckOutput is the output from policy check binary(conftest|opa)
@@ -538,6 +903,30 @@ type VersionSuccess struct {
 	VersionOutput string
 }
 
+// ImportSuccess is the result of a successful import run.
+type ImportSuccess struct {
+	// Output is the output from Terraform of running import.
+	Output string
+	// RePlanCmd is the command that users should run to re-plan this project
+	// since any existing plan is discarded after an import.
+	RePlanCmd string
+}
+
+// StateSuccess is the result of a successful state command run.
+type StateSuccess struct {
+	// Output is the output from Terraform of running the state command.
+	Output string
+	// RePlanCmd is the command that users should run to re-plan this project
+	// since any existing plan is discarded after the state is modified.
+	RePlanCmd string
+}
+
+// CustomSuccess is the result of a successful custom command.
+type CustomSuccess struct {
+	// Output is the output of the steps of the command's workflow.
+	Output string
+}
+
 // PullStatus is the current status of a pull request that is in progress.
 type PullStatus struct {
 	// Projects are the projects that have been modified in this pull request.
@@ -557,6 +946,62 @@ func (p PullStatus) StatusCount(status ProjectPlanStatus) int {
 	return c
 }
 
+// HasPlans returns true if any of the projects has a plan that hasn't been
+// applied or discarded yet.
+func (p PullStatus) HasPlans() bool {
+	for _, pr := range p.Projects {
+		switch pr.Status {
+		case PlannedPlanStatus, ErroredPolicyCheckStatus, PassedPolicyCheckStatus:
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyCompleteness is how much of a pull request has been applied.
+type ApplyCompleteness struct {
+	// Complete is true if every project has been applied. A pull request with
+	// no projects is complete.
+	Complete bool
+	// Applied is the number of projects that have been applied.
+	Applied int
+	// Total is the number of projects, including those whose plans errored,
+	// were discarded or are stale.
+	Total int
+	// Reason explains why the pull request isn't complete. It's empty if it
+	// is.
+	Reason string
+}
+
+// ApplyCompleteness returns how much of the pull request has been applied.
+// Every project must be applied for it to be complete so projects whose plan
+// errored, was discarded or is stale keep it incomplete until they're planned
+// and applied again.
+func (p PullStatus) ApplyCompleteness() ApplyCompleteness {
+	c := ApplyCompleteness{
+		Applied: p.StatusCount(AppliedPlanStatus),
+		Total:   len(p.Projects),
+	}
+	for _, pr := range p.Projects {
+		if pr.Status != AppliedPlanStatus {
+			c.Reason = fmt.Sprintf("project at dir %q, workspace %q has status %q", pr.RepoRelDir, pr.Workspace, pr.Status.String())
+			break
+		}
+	}
+	c.Complete = c.Reason == ""
+	return c
+}
+
+// SortPullStatuses sorts statuses by repo and then pull request number.
+func SortPullStatuses(statuses []PullStatus) {
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Pull.BaseRepo.FullName != statuses[j].Pull.BaseRepo.FullName {
+			return statuses[i].Pull.BaseRepo.FullName < statuses[j].Pull.BaseRepo.FullName
+		}
+		return statuses[i].Pull.Num < statuses[j].Pull.Num
+	})
+}
+
 // ProjectStatus is the status of a specific project.
 type ProjectStatus struct {
 	Workspace   string
@@ -564,6 +1009,81 @@ type ProjectStatus struct {
 	ProjectName string
 	// Status is the status of where this project is at in the planning cycle.
 	Status ProjectPlanStatus
+	// Targets are the resource addresses the project was last planned with
+	// -target at, if any.
+	Targets []string
+}
+
+// ProjectHistory is the output of running plan or apply for a project at a
+// specific commit. Unlike PullStatus it's kept after the pull request is
+// closed so that what was planned and applied can be audited later.
+type ProjectHistory struct {
+	// Pull is the pull request the command was run on.
+	Pull        PullRequest
+	Workspace   string
+	RepoRelDir  string
+	ProjectName string
+	Command     CommandName
+	// Status is the status the project was in after the command ran.
+	Status ProjectPlanStatus
+	// Output is the Terraform output, or the failure or error message if the
+	// command didn't succeed.
+	Output string
+	// Time is when the command finished.
+	Time time.Time
+}
+
+const (
+	// AuditResultSuccess means the command ran and every project succeeded.
+	AuditResultSuccess = "success"
+	// AuditResultFailure means the command or at least one project failed
+	// or errored.
+	AuditResultFailure = "failure"
+	// AuditResultRejected means the command wasn't run, ex. because it was
+	// commented on a closed pull request.
+	AuditResultRejected = "rejected"
+	// AuditResultUnknown means the command ran but didn't report a result,
+	// ex. unlock.
+	AuditResultUnknown = "unknown"
+)
+
+// AuditEvent is a record of a comment command being run. It's serialized as
+// JSON when it's exported so the field names are part of our API.
+type AuditEvent struct {
+	// Time is when the command was received.
+	Time time.Time `json:"time"`
+	// DurationMS is how long the command took to run in milliseconds.
+	DurationMS  int64    `json:"duration_ms"`
+	Username    string   `json:"user"`
+	Repo        string   `json:"repository"`
+	PullNum     int      `json:"pr"`
+	Command     string   `json:"command"`
+	RepoRelDir  string   `json:"directory,omitempty"`
+	Workspace   string   `json:"workspace,omitempty"`
+	ProjectName string   `json:"project_name,omitempty"`
+	Flags       []string `json:"flags,omitempty"`
+	// Targets are the resource addresses passed with --target.
+	Targets []string `json:"targets,omitempty"`
+	// Targeted is true if any of the command's projects were planned or
+	// applied with -target. Targeted applies can leave the state out of sync
+	// with the configuration so they're recorded separately.
+	Targeted bool `json:"targeted,omitempty"`
+	// Result is one of the AuditResult* constants.
+	Result   string              `json:"result"`
+	Error    string              `json:"error,omitempty"`
+	Projects []AuditProjectEvent `json:"projects,omitempty"`
+}
+
+// AuditProjectEvent is the result of a command for a single project.
+type AuditProjectEvent struct {
+	RepoRelDir  string `json:"directory"`
+	Workspace   string `json:"workspace"`
+	ProjectName string `json:"project_name,omitempty"`
+	// Targets are the resource addresses the project was planned or applied
+	// with -target at, if any.
+	Targets []string `json:"targets,omitempty"`
+	Result  string   `json:"result"`
+	Error   string   `json:"error,omitempty"`
 }
 
 // ProjectPlanStatus is the status of where this project is at in the planning
@@ -592,6 +1112,10 @@ const (
 	// PassedPolicyCheckStatus means that there was an unapplied plan that was
 	// discarded due to a project being unlocked
 	PassedPolicyCheckStatus
+	// StalePlanStatus means that there was an unapplied plan that was
+	// discarded because the branch the pull request will be merged into was
+	// updated.
+	StalePlanStatus
 )
 
 // String returns a string representation of the status.
@@ -611,6 +1135,8 @@ func (p ProjectPlanStatus) String() string {
 		return "policy_check_errored"
 	case PassedPolicyCheckStatus:
 		return "policy_check_passed"
+	case StalePlanStatus:
+		return "plan_stale"
 	default:
 		panic("missing String() impl for ProjectPlanStatus")
 	}
@@ -634,6 +1160,18 @@ const (
 	AutoplanCommand
 	// VersionCommand is a command to run terraform version.
 	VersionCommand
+	// ImportCommand is a command to run terraform import.
+	ImportCommand
+	// StateCommand is a command to run terraform state rm or mv.
+	StateCommand
+	// ValidateCommand is a command to run terraform validate.
+	ValidateCommand
+	// CancelCommand is a command to cancel the plans and applies running for
+	// a pull request.
+	CancelCommand
+	// CustomCommand is a command defined in the server-side repo config, ex.
+	// atlantis deploy. Its name is the command's SubName.
+	CustomCommand
 	// Adding more? Don't forget to update String() below
 )
 
@@ -658,6 +1196,16 @@ func (c CommandName) String() string {
 		return "approve_policies"
 	case VersionCommand:
 		return "version"
+	case ImportCommand:
+		return "import"
+	case StateCommand:
+		return "state"
+	case ValidateCommand:
+		return "validate"
+	case CancelCommand:
+		return "cancel"
+	case CustomCommand:
+		return "custom"
 	}
 	return ""
 }
@@ -679,4 +1227,73 @@ type PreWorkflowHookCommandContext struct {
 	User User
 	// Verbose is true when the user would like verbose output.
 	Verbose bool
+	// RequestCtx, if set, is cancelled when the command that's running the
+	// hooks is cancelled. Use Context() to get the context to run hooks with.
+	RequestCtx context.Context
+}
+
+// Context returns the context that the hooks should be run with.
+func (p PreWorkflowHookCommandContext) Context() context.Context {
+	if p.RequestCtx == nil {
+		return context.Background()
+	}
+	return p.RequestCtx
+}
+
+// QueuedCommand is a command that's been received but hasn't finished
+// running. It's persisted so it can be run again if Atlantis restarts.
+type QueuedCommand struct {
+	// ID uniquely identifies the command in the queue.
+	ID string
+	// Time is when the command was received.
+	Time time.Time
+	// Attempts is the number of times the command has been started.
+	Attempts int
+	BaseRepo Repo
+	// HeadRepo is set for autoplans and may be set for comment commands.
+	HeadRepo *Repo
+	// Pull is set for autoplans. For comment commands it's only set if the
+	// VCS host includes it in the comment webhook.
+	Pull    *PullRequest
+	User    User
+	PullNum int
+	// Comment is the serialized comment command. It's empty for autoplans.
+	Comment json.RawMessage `json:",omitempty"`
+	// TraceContext is the trace context of the webhook that queued the
+	// command so that the command's spans are part of the same trace. It's
+	// empty if tracing isn't enabled.
+	TraceContext map[string]string `json:",omitempty"`
+}
+
+// IsAutoplan returns true if the command is an autoplan rather than a
+// comment command.
+func (q QueuedCommand) IsAutoplan() bool {
+	return len(q.Comment) == 0
+}
+
+// RegisteredRepo is a repo that was onboarded through the API rather than
+// --repo-allowlist. It's persisted so it stays allowlisted when Atlantis
+// restarts.
+type RegisteredRepo struct {
+	// FullName is the owner and repo name separated by a "/", ex.
+	// runatlantis/atlantis.
+	FullName string
+	// Hostname is the hostname of the repo's VCS host, ex. github.com.
+	Hostname string
+	// PlanOnly, NoAutoplan and MaxParallel are the same restrictions that
+	// can be set on --repo-allowlist entries.
+	PlanOnly    bool
+	NoAutoplan  bool
+	MaxParallel int
+	// WebhookID is the ID of the webhook Atlantis created on the repo, or 0
+	// if it didn't create one.
+	WebhookID int64
+	// Time is when the repo was registered.
+	Time time.Time
+}
+
+// ID returns the repo's {hostname}/{owner}/{repo}, ex.
+// github.com/runatlantis/atlantis.
+func (r RegisteredRepo) ID() string {
+	return fmt.Sprintf("%s/%s", r.Hostname, r.FullName)
 }
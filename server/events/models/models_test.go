@@ -108,6 +108,14 @@ func TestNewRepo_FullNameWrongFormat(t *testing.T) {
 			"/b",
 			`invalid repo format "/b", owner "" or repo "b" was empty`,
 		},
+		{
+			"../repo",
+			`invalid repo format "../repo", ".." isn't a valid owner or repo`,
+		},
+		{
+			"owner/.",
+			`invalid repo format "owner/.", "." isn't a valid owner or repo`,
+		},
 	}
 	for _, c := range cases {
 		t.Run(c.repoFullName, func(t *testing.T) {
@@ -228,6 +236,19 @@ func TestVCSHostType_ToString(t *testing.T) {
 	}
 }
 
+func TestNewVCSHostType(t *testing.T) {
+	for _, exp := range []models.VCSHostType{models.Github, models.Gitlab, models.BitbucketCloud, models.BitbucketServer, models.AzureDevops} {
+		t.Run(exp.String(), func(t *testing.T) {
+			act, err := models.NewVCSHostType(exp.String())
+			Ok(t, err)
+			Equals(t, exp, act)
+		})
+	}
+
+	_, err := models.NewVCSHostType("Gitea")
+	ErrEquals(t, `"Gitea" is not a valid type`, err)
+}
+
 func TestSplitRepoFullName(t *testing.T) {
 	cases := []struct {
 		input    string
@@ -573,6 +594,47 @@ func TestPlanSuccess_Summary(t *testing.T) {
 	}
 }
 
+func TestPlanSuccess_Changes(t *testing.T) {
+	cases := []struct {
+		output     string
+		expChanges models.PlanChanges
+		expOk      bool
+	}{
+		{
+			output:     "Terraform will perform the following actions:\n\nPlan: 3 to add, 1 to change, 12 to destroy.",
+			expChanges: models.PlanChanges{Add: 3, Change: 1, Destroy: 12},
+			expOk:      true,
+		},
+		{
+			output:     "No changes. Infrastructure is up-to-date.",
+			expChanges: models.PlanChanges{},
+			expOk:      true,
+		},
+		{
+			output:     "No changes. Your infrastructure matches the configuration.",
+			expChanges: models.PlanChanges{},
+			expOk:      true,
+		},
+		{
+			output: "No match",
+			expOk:  false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.output, func(t *testing.T) {
+			p := models.PlanSuccess{TerraformOutput: c.output}
+			changes, ok := p.Changes()
+			Equals(t, c.expOk, ok)
+			Equals(t, c.expChanges, changes)
+		})
+	}
+}
+
+func TestPlanChanges_String(t *testing.T) {
+	Equals(t, "+3 ~1 -0", models.PlanChanges{Add: 3, Change: 1}.String())
+}
+
 func TestPullStatus_StatusCount(t *testing.T) {
 	ps := models.PullStatus{
 		Projects: []models.ProjectStatus{
@@ -609,6 +671,57 @@ func TestPullStatus_StatusCount(t *testing.T) {
 	Equals(t, 1, ps.StatusCount(models.PassedPolicyCheckStatus))
 }
 
+func TestPullStatus_HasPlans(t *testing.T) {
+	for status, exp := range map[models.ProjectPlanStatus]bool{
+		models.PlannedPlanStatus:        true,
+		models.PassedPolicyCheckStatus:  true,
+		models.ErroredPolicyCheckStatus: true,
+		models.ErroredPlanStatus:        false,
+		models.AppliedPlanStatus:        false,
+		models.DiscardedPlanStatus:      false,
+		models.StalePlanStatus:          false,
+	} {
+		ps := models.PullStatus{
+			Projects: []models.ProjectStatus{
+				{Status: models.AppliedPlanStatus},
+				{Status: status},
+			},
+		}
+		Equals(t, exp, ps.HasPlans())
+	}
+	Equals(t, false, models.PullStatus{}.HasPlans())
+}
+
+func TestPullStatus_ApplyCompleteness(t *testing.T) {
+	t.Log("pull requests with no projects are complete")
+	Equals(t, models.ApplyCompleteness{Complete: true}, models.PullStatus{}.ApplyCompleteness())
+
+	ps := models.PullStatus{
+		Projects: []models.ProjectStatus{
+			{RepoRelDir: "dir1", Workspace: "default", Status: models.AppliedPlanStatus},
+			{RepoRelDir: "dir2", Workspace: "default", Status: models.AppliedPlanStatus},
+		},
+	}
+	Equals(t, models.ApplyCompleteness{Complete: true, Applied: 2, Total: 2}, ps.ApplyCompleteness())
+
+	for _, status := range []models.ProjectPlanStatus{
+		models.ErroredPlanStatus,
+		models.PlannedPlanStatus,
+		models.ErroredApplyStatus,
+		models.DiscardedPlanStatus,
+		models.ErroredPolicyCheckStatus,
+		models.PassedPolicyCheckStatus,
+		models.StalePlanStatus,
+	} {
+		ps.Projects[1].Status = status
+		Equals(t, models.ApplyCompleteness{
+			Applied: 1,
+			Total:   2,
+			Reason:  fmt.Sprintf("project at dir \"dir2\", workspace \"default\" has status %q", status.String()),
+		}, ps.ApplyCompleteness())
+	}
+}
+
 func TestApplyCommand_String(t *testing.T) {
 	uc := models.ApplyCommand
 
@@ -632,3 +745,60 @@ func TestUnlockCommand_String(t *testing.T) {
 
 	Equals(t, "unlock", uc.String())
 }
+
+func TestScanResult_Failed(t *testing.T) {
+	cases := []struct {
+		failOn   string
+		severity string
+		exp      bool
+	}{
+		{"", "CRITICAL", false},
+		{"HIGH", "MEDIUM", false},
+		{"HIGH", "HIGH", true},
+		{"HIGH", "CRITICAL", true},
+		{"MEDIUM", models.UnknownScanSeverity, false},
+		{"LOW", models.UnknownScanSeverity, true},
+	}
+	for _, c := range cases {
+		t.Run(c.failOn+" "+c.severity, func(t *testing.T) {
+			r := models.ScanResult{
+				Tool:     "tfsec",
+				FailOn:   c.failOn,
+				Findings: []models.ScanFinding{{RuleID: "rule", Severity: c.severity}},
+			}
+			Equals(t, c.exp, r.Failed())
+		})
+	}
+}
+
+func TestProjectCommandContext_TargetAddresses(t *testing.T) {
+	cases := map[string]struct {
+		ctx models.ProjectCommandContext
+		exp []string
+	}{
+		"no targets": {
+			ctx: models.ProjectCommandContext{EscapedCommentArgs: []string{`\-\v\a\r`, `\a\=\b`}},
+			exp: nil,
+		},
+		"--target": {
+			ctx: models.ProjectCommandContext{Targets: []string{"aws_instance.web"}},
+			exp: []string{"aws_instance.web"},
+		},
+		"-target=address extra arg": {
+			ctx: models.ProjectCommandContext{EscapedCommentArgs: []string{`\-\t\a\r\g\e\t\=\m\o\d\u\l\e\.\a`}},
+			exp: []string{"module.a"},
+		},
+		"-target address extra args": {
+			ctx: models.ProjectCommandContext{
+				Targets:            []string{"aws_instance.web"},
+				EscapedCommentArgs: []string{`\-\-\t\a\r\g\e\t`, `\m\o\d\u\l\e\.\a`},
+			},
+			exp: []string{"aws_instance.web", "module.a"},
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			Equals(t, c.exp, c.ctx.TargetAddresses())
+		})
+	}
+}
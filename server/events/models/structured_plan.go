@@ -0,0 +1,58 @@
+package models
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// StructuredPlan is the set of resource changes in a plan grouped by action.
+// It's parsed from the output of `terraform show -json` on the planfile.
+type StructuredPlan struct {
+	// Create, Update, Replace and Delete are the addresses of the resources
+	// that the plan will create, update in-place, replace and delete.
+	Create  []string
+	Update  []string
+	Replace []string
+	Delete  []string
+}
+
+// showJSON is the subset of the `terraform show -json` output we use.
+// See https://www.terraform.io/docs/internals/json-format.html.
+type showJSON struct {
+	ResourceChanges []struct {
+		Address string `json:"address"`
+		Change  struct {
+			Actions []string `json:"actions"`
+		} `json:"change"`
+	} `json:"resource_changes"`
+}
+
+// NewStructuredPlan parses the output of `terraform show -json` on a planfile.
+// Resources that are only read or have no changes are ignored.
+func NewStructuredPlan(show []byte) (*StructuredPlan, error) {
+	var parsed showJSON
+	if err := json.Unmarshal(show, &parsed); err != nil {
+		return nil, errors.Wrap(err, "parsing terraform show output")
+	}
+
+	plan := &StructuredPlan{}
+	for _, rc := range parsed.ResourceChanges {
+		actions := rc.Change.Actions
+		switch {
+		case len(actions) == 2:
+			// Replacements are either ["delete", "create"] or
+			// ["create", "delete"] when create_before_destroy is set.
+			plan.Replace = append(plan.Replace, rc.Address)
+		case len(actions) != 1:
+			continue
+		case actions[0] == "create":
+			plan.Create = append(plan.Create, rc.Address)
+		case actions[0] == "update":
+			plan.Update = append(plan.Update, rc.Address)
+		case actions[0] == "delete":
+			plan.Delete = append(plan.Delete, rc.Address)
+		}
+	}
+	return plan, nil
+}
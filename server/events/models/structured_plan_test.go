@@ -0,0 +1,36 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestNewStructuredPlan(t *testing.T) {
+	show := `{
+  "format_version": "0.1",
+  "resource_changes": [
+    {"address": "null_resource.create", "change": {"actions": ["create"]}},
+    {"address": "null_resource.update", "change": {"actions": ["update"]}},
+    {"address": "null_resource.delete", "change": {"actions": ["delete"]}},
+    {"address": "null_resource.replace", "change": {"actions": ["delete", "create"]}},
+    {"address": "null_resource.replace_cbd", "change": {"actions": ["create", "delete"]}},
+    {"address": "null_resource.noop", "change": {"actions": ["no-op"]}},
+    {"address": "data.null_data_source.read", "change": {"actions": ["read"]}}
+  ]
+}`
+	plan, err := models.NewStructuredPlan([]byte(show))
+	Ok(t, err)
+	Equals(t, &models.StructuredPlan{
+		Create:  []string{"null_resource.create"},
+		Update:  []string{"null_resource.update"},
+		Replace: []string{"null_resource.replace", "null_resource.replace_cbd"},
+		Delete:  []string{"null_resource.delete"},
+	}, plan)
+}
+
+func TestNewStructuredPlan_InvalidJSON(t *testing.T) {
+	_, err := models.NewStructuredPlan([]byte("Error: not json"))
+	ErrContains(t, "parsing terraform show output", err)
+}
@@ -51,6 +51,10 @@ func (p *DefaultPendingPlanFinder) findWithAbsPaths(pullDir string) ([]PendingPl
 	var absPaths []string
 	for _, workspaceDir := range workspaceDirs {
 		workspace := workspaceDir.Name()
+		// The pull's clone isn't a workspace, its worktrees are.
+		if workspace == pullCloneDirName {
+			continue
+		}
 		repoDir := filepath.Join(pullDir, workspace)
 
 		// Any generated plans should be untracked by git since Atlantis created
@@ -1,6 +1,7 @@
 package events
 
 import (
+	"github.com/pkg/errors"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/vcs"
 )
@@ -71,6 +72,10 @@ func (p *PlanCommandRunner) runAutoplan(ctx *CommandContext) {
 
 	projectCmds, err := p.prjCmdBuilder.BuildAutoplanCommands(ctx)
 	if err != nil {
+		if conflictErr, ok := errors.Cause(err).(*MergeConflictError); ok {
+			p.reportMergeConflict(ctx, AutoplanCommand{}, conflictErr)
+			return
+		}
 		if statusErr := p.commitStatusUpdater.UpdateCombined(baseRepo, pull, models.FailedCommitStatus, models.PlanCommand); statusErr != nil {
 			ctx.Log.Warn("unable to update commit status: %s", statusErr)
 		}
@@ -156,6 +161,10 @@ func (p *PlanCommandRunner) run(ctx *CommandContext, cmd *CommentCommand) {
 
 	projectCmds, err := p.prjCmdBuilder.BuildPlanCommands(ctx, cmd)
 	if err != nil {
+		if conflictErr, ok := errors.Cause(err).(*MergeConflictError); ok {
+			p.reportMergeConflict(ctx, cmd, conflictErr)
+			return
+		}
 		if statusErr := p.commitStatusUpdater.UpdateCombined(ctx.Pull.BaseRepo, ctx.Pull, models.FailedCommitStatus, models.PlanCommand); statusErr != nil {
 			ctx.Log.Warn("unable to update commit status: %s", statusErr)
 		}
@@ -285,3 +294,13 @@ func (p *PlanCommandRunner) partitionProjectCmds(
 func (p *PlanCommandRunner) isParallelEnabled(projectCmds []models.ProjectCommandContext) bool {
 	return len(projectCmds) > 0 && projectCmds[0].ParallelPlanEnabled
 }
+
+// reportMergeConflict fails the plan commit status and comments that the
+// pull's conflicts with its base branch must be resolved before it can be
+// planned.
+func (p *PlanCommandRunner) reportMergeConflict(ctx *CommandContext, command PullCommand, conflictErr *MergeConflictError) {
+	if err := p.commitStatusUpdater.UpdateMergeConflict(ctx.Pull.BaseRepo, ctx.Pull, models.PlanCommand); err != nil {
+		ctx.Log.Warn("unable to update commit status: %s", err)
+	}
+	p.pullUpdater.updatePull(ctx, command, CommandResult{Failure: conflictErr.Error()})
+}
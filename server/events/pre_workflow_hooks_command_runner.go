@@ -18,7 +18,7 @@ type DefaultPreWorkflowHooksCommandRunner struct {
 	VCSClient             vcs.Client
 	WorkingDirLocker      WorkingDirLocker
 	WorkingDir            WorkingDir
-	GlobalCfg             valid.GlobalCfg
+	GlobalCfg             *valid.GlobalCfgStore
 	PreWorkflowHookRunner runtime.PreWorkflowHookRunner
 }
 
@@ -33,7 +33,7 @@ func (w *DefaultPreWorkflowHooksCommandRunner) RunPreHooks(
 	log := ctx.Log
 
 	preWorkflowHooks := make([]*valid.PreWorkflowHook, 0)
-	for _, repo := range w.GlobalCfg.Repos {
+	for _, repo := range w.GlobalCfg.Get().Repos {
 		if repo.IDMatches(baseRepo.ID()) && repo.BranchMatches(pull.BaseBranch) && len(repo.PreWorkflowHooks) > 0 {
 			preWorkflowHooks = append(preWorkflowHooks, repo.PreWorkflowHooks...)
 		}
@@ -60,12 +60,13 @@ func (w *DefaultPreWorkflowHooksCommandRunner) RunPreHooks(
 
 	err = w.runHooks(
 		models.PreWorkflowHookCommandContext{
-			BaseRepo: baseRepo,
-			HeadRepo: headRepo,
-			Log:      log,
-			Pull:     pull,
-			User:     user,
-			Verbose:  false,
+			BaseRepo:   baseRepo,
+			HeadRepo:   headRepo,
+			Log:        log,
+			Pull:       pull,
+			User:       user,
+			Verbose:    false,
+			RequestCtx: ctx.RequestCtx,
 		},
 		preWorkflowHooks, repoDir)
 
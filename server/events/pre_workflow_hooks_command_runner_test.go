@@ -90,7 +90,7 @@ func TestRunPreHooks_Clone(t *testing.T) {
 			},
 		}
 
-		wh.GlobalCfg = globalCfg
+		wh.GlobalCfg = valid.NewGlobalCfgStore(globalCfg)
 
 		When(whWorkingDirLocker.TryLock(fixtures.GithubRepo.FullName, newPull.Num, events.DefaultWorkspace)).ThenReturn(unlockFn, nil)
 		When(whWorkingDir.Clone(log, fixtures.GithubRepo, newPull, events.DefaultWorkspace)).ThenReturn(repoDir, false, nil)
@@ -121,7 +121,7 @@ func TestRunPreHooks_Clone(t *testing.T) {
 			},
 		}
 
-		wh.GlobalCfg = globalCfg
+		wh.GlobalCfg = valid.NewGlobalCfgStore(globalCfg)
 
 		err := wh.RunPreHooks(ctx)
 
@@ -145,7 +145,7 @@ func TestRunPreHooks_Clone(t *testing.T) {
 			},
 		}
 
-		wh.GlobalCfg = globalCfg
+		wh.GlobalCfg = valid.NewGlobalCfgStore(globalCfg)
 
 		When(whWorkingDirLocker.TryLock(fixtures.GithubRepo.FullName, newPull.Num, events.DefaultWorkspace)).ThenReturn(func() {}, errors.New("some error"))
 
@@ -175,7 +175,7 @@ func TestRunPreHooks_Clone(t *testing.T) {
 			},
 		}
 
-		wh.GlobalCfg = globalCfg
+		wh.GlobalCfg = valid.NewGlobalCfgStore(globalCfg)
 
 		When(whWorkingDirLocker.TryLock(fixtures.GithubRepo.FullName, newPull.Num, events.DefaultWorkspace)).ThenReturn(unlockFn, nil)
 		When(whWorkingDir.Clone(log, fixtures.GithubRepo, newPull, events.DefaultWorkspace)).ThenReturn(repoDir, false, errors.New("some error"))
@@ -207,7 +207,7 @@ func TestRunPreHooks_Clone(t *testing.T) {
 			},
 		}
 
-		wh.GlobalCfg = globalCfg
+		wh.GlobalCfg = valid.NewGlobalCfgStore(globalCfg)
 
 		When(whWorkingDirLocker.TryLock(fixtures.GithubRepo.FullName, newPull.Num, events.DefaultWorkspace)).ThenReturn(unlockFn, nil)
 		When(whWorkingDir.Clone(log, fixtures.GithubRepo, newPull, events.DefaultWorkspace)).ThenReturn(repoDir, false, nil)
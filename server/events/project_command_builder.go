@@ -3,10 +3,14 @@ package events
 import (
 	"fmt"
 	"os"
+	"path"
+	"path/filepath"
+	"strings"
 
 	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 
 	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/core/terraform"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/vcs"
 	"github.com/runatlantis/atlantis/server/events/yaml"
@@ -36,27 +40,31 @@ func NewProjectCommandBuilder(
 	vcsClient vcs.Client,
 	workingDir WorkingDir,
 	workingDirLocker WorkingDirLocker,
-	globalCfg valid.GlobalCfg,
+	globalCfg *valid.GlobalCfgStore,
 	pendingPlanFinder *DefaultPendingPlanFinder,
 	commentBuilder CommentBuilder,
 	skipCloneNoChanges bool,
 	EnableRegExpCmd bool,
 	AutoplanFileList string,
+	terraformClient terraform.Client,
+	restoreWorkingDirOnApply bool,
 ) *DefaultProjectCommandBuilder {
 	projectCommandBuilder := &DefaultProjectCommandBuilder{
-		ParserValidator:    parserValidator,
-		ProjectFinder:      projectFinder,
-		VCSClient:          vcsClient,
-		WorkingDir:         workingDir,
-		WorkingDirLocker:   workingDirLocker,
-		GlobalCfg:          globalCfg,
-		PendingPlanFinder:  pendingPlanFinder,
-		SkipCloneNoChanges: skipCloneNoChanges,
-		EnableRegExpCmd:    EnableRegExpCmd,
-		AutoplanFileList:   AutoplanFileList,
+		ParserValidator:          parserValidator,
+		ProjectFinder:            projectFinder,
+		VCSClient:                vcsClient,
+		WorkingDir:               workingDir,
+		WorkingDirLocker:         workingDirLocker,
+		GlobalCfg:                globalCfg,
+		PendingPlanFinder:        pendingPlanFinder,
+		SkipCloneNoChanges:       skipCloneNoChanges,
+		EnableRegExpCmd:          EnableRegExpCmd,
+		AutoplanFileList:         AutoplanFileList,
+		RestoreWorkingDirOnApply: restoreWorkingDirOnApply,
 		ProjectCommandContextBuilder: NewProjectCommandContextBulder(
 			policyChecksSupported,
 			commentBuilder,
+			terraformClient,
 		),
 	}
 
@@ -92,6 +100,32 @@ type ProjectVersionCommandBuilder interface {
 	BuildVersionCommands(ctx *CommandContext, comment *CommentCommand) ([]models.ProjectCommandContext, error)
 }
 
+type ProjectImportCommandBuilder interface {
+	// BuildImportCommands builds the project Import command for this ctx and
+	// comment. Import always runs in a single project.
+	BuildImportCommands(ctx *CommandContext, comment *CommentCommand) ([]models.ProjectCommandContext, error)
+}
+
+type ProjectStateCommandBuilder interface {
+	// BuildStateCommands builds the project State command for this ctx and
+	// comment. State commands always run in a single project.
+	BuildStateCommands(ctx *CommandContext, comment *CommentCommand) ([]models.ProjectCommandContext, error)
+}
+
+type ProjectValidateCommandBuilder interface {
+	// BuildValidateCommands builds project Validate commands for this ctx and
+	// comment. If comment doesn't specify one project then the projects
+	// modified in the pull request are validated.
+	BuildValidateCommands(ctx *CommandContext, comment *CommentCommand) ([]models.ProjectCommandContext, error)
+}
+
+type ProjectCustomCommandBuilder interface {
+	// BuildCustomCommands builds project commands for the custom command in
+	// comment. Like validate, if comment doesn't specify one project then the
+	// command runs in the projects modified in the pull request.
+	BuildCustomCommands(ctx *CommandContext, comment *CommentCommand) ([]models.ProjectCommandContext, error)
+}
+
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_project_command_builder.go ProjectCommandBuilder
 
 // ProjectCommandBuilder builds commands that run on individual projects.
@@ -100,6 +134,10 @@ type ProjectCommandBuilder interface {
 	ProjectApplyCommandBuilder
 	ProjectApprovePoliciesCommandBuilder
 	ProjectVersionCommandBuilder
+	ProjectImportCommandBuilder
+	ProjectStateCommandBuilder
+	ProjectValidateCommandBuilder
+	ProjectCustomCommandBuilder
 }
 
 // DefaultProjectCommandBuilder implements ProjectCommandBuilder.
@@ -111,17 +149,21 @@ type DefaultProjectCommandBuilder struct {
 	VCSClient                    vcs.Client
 	WorkingDir                   WorkingDir
 	WorkingDirLocker             WorkingDirLocker
-	GlobalCfg                    valid.GlobalCfg
+	GlobalCfg                    *valid.GlobalCfgStore
 	PendingPlanFinder            *DefaultPendingPlanFinder
 	ProjectCommandContextBuilder ProjectCommandContextBuilder
 	SkipCloneNoChanges           bool
 	EnableRegExpCmd              bool
 	AutoplanFileList             string
+	// RestoreWorkingDirOnApply causes applies to re-clone the pull request if
+	// its working dir is missing, ex. after a restart with ephemeral storage.
+	// It's set when planfiles are kept in remote plan storage.
+	RestoreWorkingDirOnApply bool
 }
 
 // See ProjectCommandBuilder.BuildAutoplanCommands.
 func (p *DefaultProjectCommandBuilder) BuildAutoplanCommands(ctx *CommandContext) ([]models.ProjectCommandContext, error) {
-	projCtxs, err := p.buildPlanAllCommands(ctx, nil, false)
+	projCtxs, err := p.buildModifiedProjectsCommands(ctx, models.PlanCommand, nil, false)
 	if err != nil {
 		return nil, err
 	}
@@ -138,19 +180,37 @@ func (p *DefaultProjectCommandBuilder) BuildAutoplanCommands(ctx *CommandContext
 
 // See ProjectCommandBuilder.BuildPlanCommands.
 func (p *DefaultProjectCommandBuilder) BuildPlanCommands(ctx *CommandContext, cmd *CommentCommand) ([]models.ProjectCommandContext, error) {
-	if !cmd.IsForSpecificProject() {
-		return p.buildPlanAllCommands(ctx, cmd.Flags, cmd.Verbose)
+	var pcc []models.ProjectCommandContext
+	var err error
+	switch {
+	case cmd.IsMultiTarget():
+		pcc, err = p.buildMultiTargetPlanCommands(ctx, models.PlanCommand, cmd)
+	case !cmd.IsForSpecificProject():
+		pcc, err = p.buildModifiedProjectsCommands(ctx, models.PlanCommand, cmd.Flags, cmd.Verbose)
+	default:
+		pcc, err = p.buildProjectPlanCommand(ctx, models.PlanCommand, cmd)
+	}
+	for i := range pcc {
+		pcc[i].Destroy = cmd.Destroy
+		pcc[i].Targets = cmd.Targets
 	}
-	pcc, err := p.buildProjectPlanCommand(ctx, cmd)
 	return pcc, err
 }
 
 // See ProjectCommandBuilder.BuildApplyCommands.
 func (p *DefaultProjectCommandBuilder) BuildApplyCommands(ctx *CommandContext, cmd *CommentCommand) ([]models.ProjectCommandContext, error) {
-	if !cmd.IsForSpecificProject() {
-		return p.buildAllProjectCommands(ctx, cmd)
+	var pac []models.ProjectCommandContext
+	var err error
+	if !cmd.IsForSpecificProject() || cmd.IsMultiTarget() {
+		pac, err = p.buildAllProjectCommands(ctx, cmd)
+	} else {
+		pac, err = p.buildProjectApplyCommand(ctx, cmd)
+	}
+	for i := range pac {
+		pac[i].Force = cmd.Force
+		pac[i].AutoApply = cmd.AutoApply
+		pac[i].Targets = cmd.Targets
 	}
-	pac, err := p.buildProjectApplyCommand(ctx, cmd)
 	return pac, err
 }
 
@@ -159,16 +219,81 @@ func (p *DefaultProjectCommandBuilder) BuildApprovePoliciesCommands(ctx *Command
 }
 
 func (p *DefaultProjectCommandBuilder) BuildVersionCommands(ctx *CommandContext, cmd *CommentCommand) ([]models.ProjectCommandContext, error) {
-	if !cmd.IsForSpecificProject() {
+	if !cmd.IsForSpecificProject() || cmd.IsMultiTarget() {
 		return p.buildAllProjectCommands(ctx, cmd)
 	}
 	pac, err := p.buildProjectVersionCommand(ctx, cmd)
 	return pac, err
 }
 
-// buildPlanAllCommands builds plan contexts for all projects we determine were
-// modified in this ctx.
-func (p *DefaultProjectCommandBuilder) buildPlanAllCommands(ctx *CommandContext, commentFlags []string, verbose bool) ([]models.ProjectCommandContext, error) {
+// See ProjectCommandBuilder.BuildImportCommands.
+func (p *DefaultProjectCommandBuilder) BuildImportCommands(ctx *CommandContext, cmd *CommentCommand) ([]models.ProjectCommandContext, error) {
+	return p.buildSingleProjectStateCommand(ctx, cmd)
+}
+
+// See ProjectCommandBuilder.BuildStateCommands.
+func (p *DefaultProjectCommandBuilder) BuildStateCommands(ctx *CommandContext, cmd *CommentCommand) ([]models.ProjectCommandContext, error) {
+	pcc, err := p.buildSingleProjectStateCommand(ctx, cmd)
+	for i := range pcc {
+		pcc[i].SubName = cmd.SubName
+	}
+	return pcc, err
+}
+
+// See ProjectCommandBuilder.BuildValidateCommands.
+func (p *DefaultProjectCommandBuilder) BuildValidateCommands(ctx *CommandContext, cmd *CommentCommand) ([]models.ProjectCommandContext, error) {
+	var pcc []models.ProjectCommandContext
+	var err error
+	switch {
+	case cmd.IsMultiTarget():
+		pcc, err = p.buildMultiTargetPlanCommands(ctx, models.ValidateCommand, cmd)
+	case !cmd.IsForSpecificProject():
+		pcc, err = p.buildModifiedProjectsCommands(ctx, models.ValidateCommand, cmd.Flags, cmd.Verbose)
+	default:
+		pcc, err = p.buildProjectPlanCommand(ctx, models.ValidateCommand, cmd)
+	}
+	if cmd.Fmt {
+		for i := range pcc {
+			pcc[i].Steps = append(pcc[i].Steps, valid.Step{StepName: "fmt"})
+		}
+	}
+	return pcc, err
+}
+
+// See ProjectCommandBuilder.BuildCustomCommands.
+func (p *DefaultProjectCommandBuilder) BuildCustomCommands(ctx *CommandContext, cmd *CommentCommand) ([]models.ProjectCommandContext, error) {
+	// The command was parsed from the same config but it may have been
+	// reloaded since.
+	customCmd, ok := p.GlobalCfg.Get().CustomCommands[cmd.SubName]
+	if !ok {
+		return nil, fmt.Errorf("command %q is not defined in the server-side repo config", cmd.SubName)
+	}
+	var pcc []models.ProjectCommandContext
+	var err error
+	switch {
+	case cmd.IsMultiTarget():
+		pcc, err = p.buildMultiTargetPlanCommands(ctx, models.CustomCommand, cmd)
+	case !cmd.IsForSpecificProject():
+		pcc, err = p.buildModifiedProjectsCommands(ctx, models.CustomCommand, cmd.Flags, cmd.Verbose)
+	default:
+		pcc, err = p.buildProjectPlanCommand(ctx, models.CustomCommand, cmd)
+	}
+	for i := range pcc {
+		pcc[i].SubName = cmd.SubName
+		pcc[i].Steps = customCmd.Workflow.Plan.Steps
+		// Custom commands can change state, ex. with terraform import, so
+		// they're held to the apply requirements unless they set their own.
+		if customCmd.Requirements != nil {
+			pcc[i].ApplyRequirements = customCmd.Requirements
+		}
+	}
+	return pcc, err
+}
+
+// buildModifiedProjectsCommands builds contexts for cmdName, ex. plan, for all
+// projects we determine were modified in this ctx.
+func (p *DefaultProjectCommandBuilder) buildModifiedProjectsCommands(ctx *CommandContext, cmdName models.CommandName, commentFlags []string, verbose bool) ([]models.ProjectCommandContext, error) {
+	globalCfg := p.GlobalCfg.Get()
 	// We'll need the list of modified files.
 	modifiedFiles, err := p.VCSClient.GetModifiedFiles(ctx.Pull.BaseRepo, ctx.Pull)
 	if err != nil {
@@ -183,7 +308,7 @@ func (p *DefaultProjectCommandBuilder) buildPlanAllCommands(ctx *CommandContext,
 		}
 
 		if hasRepoCfg {
-			repoCfg, err := p.ParserValidator.ParseRepoCfgData(repoCfgData, p.GlobalCfg, ctx.Pull.BaseRepo.ID())
+			repoCfg, err := p.ParserValidator.ParseRepoCfgData(repoCfgData, globalCfg, ctx.Pull.BaseRepo.ID())
 			if err != nil {
 				return nil, errors.Wrapf(err, "parsing %s", yaml.AtlantisYAMLFilename)
 			}
@@ -230,7 +355,7 @@ func (p *DefaultProjectCommandBuilder) buildPlanAllCommands(ctx *CommandContext,
 	if hasRepoCfg {
 		// If there's a repo cfg then we'll use it to figure out which projects
 		// should be planed.
-		repoCfg, err := p.ParserValidator.ParseRepoCfg(repoDir, p.GlobalCfg, ctx.Pull.BaseRepo.ID())
+		repoCfg, err := p.ParserValidator.ParseRepoCfg(repoDir, globalCfg, ctx.Pull.BaseRepo.ID())
 		if err != nil {
 			return nil, errors.Wrapf(err, "parsing %s", yaml.AtlantisYAMLFilename)
 		}
@@ -241,14 +366,24 @@ func (p *DefaultProjectCommandBuilder) buildPlanAllCommands(ctx *CommandContext,
 		}
 		ctx.Log.Info("%d projects are to be planned based on their when_modified config", len(matchingProjects))
 
+		triggeredDirs, err := p.ProjectFinder.DetermineTriggeredDirs(ctx.Log, modifiedFiles, globalCfg.AutoplanTriggers(ctx.Pull.BaseRepo.ID(), ctx.Pull.BaseBranch), repoDir)
+		if err != nil {
+			return nil, errors.Wrap(err, "evaluating autoplan triggers")
+		}
+		matchingProjects = p.addTriggeredProjects(matchingProjects, repoCfg.Projects, triggeredDirs)
+
 		for _, mp := range matchingProjects {
 			ctx.Log.Debug("determining config for project at dir: %q workspace: %q", mp.Dir, mp.Workspace)
-			mergedCfg := p.GlobalCfg.MergeProjectCfg(ctx.Log, ctx.Pull.BaseRepo.ID(), mp, repoCfg)
+			mergedCfg := globalCfg.MergeProjectCfg(ctx.Log, ctx.Pull.BaseRepo.ID(), mp, repoCfg)
+			if !mergedCfg.CommandAllowed(cmdName.String()) {
+				ctx.Log.Info("not running %s for project at dir %q, workspace %q because %s doesn't include it", cmdName, mp.Dir, mp.Workspace, valid.AllowedCommandsKey)
+				continue
+			}
 
 			projCtxs = append(projCtxs,
 				p.ProjectCommandContextBuilder.BuildProjectContext(
 					ctx,
-					models.PlanCommand,
+					cmdName,
 					mergedCfg,
 					commentFlags,
 					repoDir,
@@ -267,15 +402,32 @@ func (p *DefaultProjectCommandBuilder) buildPlanAllCommands(ctx *CommandContext,
 		if err != nil {
 			return nil, errors.Wrapf(err, "finding modified projects: %s", modifiedFiles)
 		}
+		triggeredDirs, err := p.ProjectFinder.DetermineTriggeredDirs(ctx.Log, modifiedFiles, globalCfg.AutoplanTriggers(ctx.Pull.BaseRepo.ID(), ctx.Pull.BaseBranch), repoDir)
+		if err != nil {
+			return nil, errors.Wrap(err, "evaluating autoplan triggers")
+		}
+	TRIGGERED:
+		for _, dir := range triggeredDirs {
+			for _, mp := range modifiedProjects {
+				if mp.Path == dir {
+					continue TRIGGERED
+				}
+			}
+			modifiedProjects = append(modifiedProjects, models.NewProject(ctx.Pull.BaseRepo.FullName, dir))
+		}
 		ctx.Log.Info("automatically determined that there were %d projects modified in this pull request: %s", len(modifiedProjects), modifiedProjects)
 		for _, mp := range modifiedProjects {
 			ctx.Log.Debug("determining config for project at dir: %q", mp.Path)
-			pCfg := p.GlobalCfg.DefaultProjCfg(ctx.Log, ctx.Pull.BaseRepo.ID(), mp.Path, DefaultWorkspace)
+			pCfg := globalCfg.DefaultProjCfg(ctx.Log, ctx.Pull.BaseRepo.ID(), mp.Path, DefaultWorkspace)
+			if !pCfg.CommandAllowed(cmdName.String()) {
+				ctx.Log.Info("not running %s for project at dir %q because %s doesn't include it", cmdName, mp.Path, valid.AllowedCommandsKey)
+				continue
+			}
 
 			projCtxs = append(projCtxs,
 				p.ProjectCommandContextBuilder.BuildProjectContext(
 					ctx,
-					models.PlanCommand,
+					cmdName,
 					pCfg,
 					commentFlags,
 					repoDir,
@@ -291,16 +443,42 @@ func (p *DefaultProjectCommandBuilder) buildPlanAllCommands(ctx *CommandContext,
 	return projCtxs, nil
 }
 
-// buildProjectPlanCommand builds a plan context for a single project.
-// cmd must be for only one project.
-func (p *DefaultProjectCommandBuilder) buildProjectPlanCommand(ctx *CommandContext, cmd *CommentCommand) ([]models.ProjectCommandContext, error) {
+// addTriggeredProjects returns matching plus the projects from allProjects
+// whose dir is in triggeredDirs and that aren't already in matching.
+func (p *DefaultProjectCommandBuilder) addTriggeredProjects(matching []valid.Project, allProjects []valid.Project, triggeredDirs []string) []valid.Project {
+	if len(triggeredDirs) == 0 {
+		return matching
+	}
+	included := make(map[string]bool)
+	for _, mp := range matching {
+		included[filepath.Clean(mp.Dir)+"/"+mp.Workspace] = true
+	}
+	for _, proj := range allProjects {
+		key := filepath.Clean(proj.Dir) + "/" + proj.Workspace
+		if included[key] {
+			continue
+		}
+		for _, dir := range triggeredDirs {
+			if filepath.Clean(proj.Dir) == dir {
+				matching = append(matching, proj)
+				included[key] = true
+				break
+			}
+		}
+	}
+	return matching
+}
+
+// buildProjectPlanCommand builds a cmdName context, ex. plan, for a single
+// project. cmd must be for only one project.
+func (p *DefaultProjectCommandBuilder) buildProjectPlanCommand(ctx *CommandContext, cmdName models.CommandName, cmd *CommentCommand) ([]models.ProjectCommandContext, error) {
 	workspace := DefaultWorkspace
 	if cmd.Workspace != "" {
 		workspace = cmd.Workspace
 	}
 
 	var pcc []models.ProjectCommandContext
-	ctx.Log.Debug("building plan command")
+	ctx.Log.Debug("building %s command", cmdName)
 	unlockFn, err := p.WorkingDirLocker.TryLock(ctx.Pull.BaseRepo.FullName, ctx.Pull.Num, workspace)
 	if err != nil {
 		return pcc, err
@@ -327,7 +505,7 @@ func (p *DefaultProjectCommandBuilder) buildProjectPlanCommand(ctx *CommandConte
 
 	return p.buildProjectCommandCtx(
 		ctx,
-		models.PlanCommand,
+		cmdName,
 		cmd.ProjectName,
 		cmd.Flags,
 		defaultRepoDir,
@@ -339,7 +517,7 @@ func (p *DefaultProjectCommandBuilder) buildProjectPlanCommand(ctx *CommandConte
 
 // getCfg returns the atlantis.yaml config (if it exists) for this project. If
 // there is no config, then projectCfg and repoCfg will be nil.
-func (p *DefaultProjectCommandBuilder) getCfg(ctx *CommandContext, projectName string, dir string, workspace string, repoDir string) (projectsCfg []valid.Project, repoCfg *valid.RepoCfg, err error) {
+func (p *DefaultProjectCommandBuilder) getCfg(ctx *CommandContext, globalCfg valid.GlobalCfg, projectName string, dir string, workspace string, repoDir string) (projectsCfg []valid.Project, repoCfg *valid.RepoCfg, err error) {
 	hasConfigFile, err := p.ParserValidator.HasRepoCfg(repoDir)
 	if err != nil {
 		err = errors.Wrapf(err, "looking for %s file in %q", yaml.AtlantisYAMLFilename, repoDir)
@@ -354,7 +532,7 @@ func (p *DefaultProjectCommandBuilder) getCfg(ctx *CommandContext, projectName s
 	}
 
 	var repoConfig valid.RepoCfg
-	repoConfig, err = p.ParserValidator.ParseRepoCfg(repoDir, p.GlobalCfg, ctx.Pull.BaseRepo.ID())
+	repoConfig, err = p.ParserValidator.ParseRepoCfg(repoDir, globalCfg, ctx.Pull.BaseRepo.ID())
 	if err != nil {
 		return
 	}
@@ -371,7 +549,7 @@ func (p *DefaultProjectCommandBuilder) getCfg(ctx *CommandContext, projectName s
 			}
 		}
 		if len(projectsCfg) == 0 {
-			err = fmt.Errorf("no project with name %q is defined in %s", projectName, yaml.AtlantisYAMLFilename)
+			err = fmt.Errorf("no project with name %q is defined in %s%s", projectName, yaml.AtlantisYAMLFilename, definedProjectNames(repoCfg))
 			return
 		}
 		return
@@ -390,7 +568,8 @@ func (p *DefaultProjectCommandBuilder) getCfg(ctx *CommandContext, projectName s
 }
 
 // buildAllProjectCommands builds contexts for a command for every project that has
-// pending plans in this ctx.
+// pending plans in this ctx. If commentCmd is multi-target, only the plans of
+// the projects it targets are used.
 func (p *DefaultProjectCommandBuilder) buildAllProjectCommands(ctx *CommandContext, commentCmd *CommentCommand) ([]models.ProjectCommandContext, error) {
 	// Lock all dirs in this pull request (instead of a single dir) because we
 	// don't know how many dirs we'll need to run the command in.
@@ -418,16 +597,193 @@ func (p *DefaultProjectCommandBuilder) buildAllProjectCommands(ctx *CommandConte
 	}
 
 	var cmds []models.ProjectCommandContext
+	matched := 0
 	for _, plan := range plans {
+		if !commentCmd.MatchesTarget(plan.RepoRelDir, plan.Workspace) {
+			continue
+		}
+		matched++
 		commentCmds, err := p.buildProjectCommandCtx(ctx, commentCmd.CommandName(), plan.ProjectName, commentCmd.Flags, defaultRepoDir, plan.RepoRelDir, plan.Workspace, commentCmd.Verbose)
+		if _, ok := errors.Cause(err).(*CommandNotAllowedError); ok {
+			// Projects that don't allow this command are skipped rather than
+			// failing the whole command.
+			ctx.Log.Info("skipping: %s", err)
+			continue
+		}
 		if err != nil {
 			return nil, errors.Wrapf(err, "building command for dir %q", plan.RepoRelDir)
 		}
 		cmds = append(cmds, commentCmds...)
 	}
+	if commentCmd.IsMultiTarget() && matched == 0 {
+		return nil, fmt.Errorf("no plans matched dir: %q workspace: %q–did you run plan?", commentCmd.RepoRelDir, commentCmd.Workspace)
+	}
 	return cmds, nil
 }
 
+// buildMultiTargetPlanCommands builds cmdName contexts, ex. plan, for every
+// project targeted by cmd's lists or patterns, ex. atlantis plan -d
+// 'stacks/prod/*'.
+func (p *DefaultProjectCommandBuilder) buildMultiTargetPlanCommands(ctx *CommandContext, cmdName models.CommandName, cmd *CommentCommand) ([]models.ProjectCommandContext, error) {
+	targets, err := p.findTargets(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no projects matched dir: %q workspace: %q", cmd.RepoRelDir, cmd.Workspace)
+	}
+
+	var pcc []models.ProjectCommandContext
+	for i := range targets {
+		targetCtxs, err := p.buildProjectPlanCommand(ctx, cmdName, &targets[i])
+		if err != nil {
+			return nil, errors.Wrapf(err, "building command for dir %q workspace %q", targets[i].RepoRelDir, targets[i].Workspace)
+		}
+		pcc = append(pcc, targetCtxs...)
+	}
+	return pcc, nil
+}
+
+// findTargets expands cmd's lists and patterns into a command for each
+// project it targets. If there's an atlantis.yaml, they're matched against its
+// projects. Otherwise dir patterns are matched against the dirs that contain
+// Terraform files and workspaces can only be lists since there's nothing to
+// match patterns against.
+func (p *DefaultProjectCommandBuilder) findTargets(ctx *CommandContext, cmd *CommentCommand) ([]CommentCommand, error) {
+	unlockFn, err := p.WorkingDirLocker.TryLock(ctx.Pull.BaseRepo.FullName, ctx.Pull.Num, DefaultWorkspace)
+	if err != nil {
+		return nil, err
+	}
+	defer unlockFn()
+
+	ctx.Log.Debug("cloning repository")
+	repoDir, _, err := p.WorkingDir.Clone(ctx.Log, ctx.HeadRepo, ctx.Pull, DefaultWorkspace)
+	if err != nil {
+		return nil, err
+	}
+	hasRepoCfg, err := p.ParserValidator.HasRepoCfg(repoDir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "looking for %s file in %q", yaml.AtlantisYAMLFilename, repoDir)
+	}
+
+	var targets []CommentCommand
+	if hasRepoCfg {
+		repoCfg, err := p.ParserValidator.ParseRepoCfg(repoDir, p.GlobalCfg.Get(), ctx.Pull.BaseRepo.ID())
+		if err != nil {
+			return nil, err
+		}
+		for _, project := range repoCfg.Projects {
+			if !cmd.MatchesTarget(project.Dir, project.Workspace) {
+				continue
+			}
+			target := *cmd
+			target.RepoRelDir = project.Dir
+			target.Workspace = project.Workspace
+			// Projects that share a dir and workspace can only be told apart
+			// by their names.
+			if len(repoCfg.FindProjectsByDirWorkspace(project.Dir, project.Workspace)) > 1 && project.Name != nil {
+				target.RepoRelDir = ""
+				target.Workspace = ""
+				target.ProjectName = *project.Name
+			}
+			targets = append(targets, target)
+		}
+		return targets, nil
+	}
+
+	dirs, err := expandDirs(repoDir, cmd.RepoRelDir)
+	if err != nil {
+		return nil, err
+	}
+	workspaces := []string{DefaultWorkspace}
+	if cmd.Workspace != "" {
+		workspaces = strings.Split(cmd.Workspace, ",")
+	}
+	for _, workspace := range workspaces {
+		if strings.ContainsAny(workspace, multiTargetChars) {
+			return nil, fmt.Errorf("workspace pattern %q can only be used with projects configured in %s", workspace, yaml.AtlantisYAMLFilename)
+		}
+	}
+	for _, dir := range dirs {
+		for _, workspace := range workspaces {
+			target := *cmd
+			target.RepoRelDir = dir
+			target.Workspace = workspace
+			targets = append(targets, target)
+		}
+	}
+	return targets, nil
+}
+
+// expandDirs expands the comma-separated dirs into the dirs they refer to.
+// Patterns are matched against the dirs in repoDir that contain Terraform
+// files. Dirs that aren't patterns are used as is, like they are in commands
+// for a single project.
+func expandDirs(repoDir string, dirs string) ([]string, error) {
+	if dirs == "" {
+		return []string{DefaultRepoRelDir}, nil
+	}
+	var tfDirs []string
+	var expanded []string
+	seen := make(map[string]bool)
+	add := func(dir string) {
+		if !seen[dir] {
+			seen[dir] = true
+			expanded = append(expanded, dir)
+		}
+	}
+	for _, pattern := range strings.Split(dirs, ",") {
+		if !strings.ContainsAny(pattern, multiTargetChars) {
+			add(pattern)
+			continue
+		}
+		if tfDirs == nil {
+			var err error
+			if tfDirs, err = terraformDirs(repoDir); err != nil {
+				return nil, err
+			}
+		}
+		for _, dir := range tfDirs {
+			if matched, _ := path.Match(pattern, dir); matched {
+				add(dir)
+			}
+		}
+	}
+	return expanded, nil
+}
+
+// terraformDirs returns the dirs in repoDir, relative to it, that contain
+// Terraform files.
+func terraformDirs(repoDir string) ([]string, error) {
+	dirs := []string{}
+	seen := make(map[string]bool)
+	err := filepath.Walk(repoDir, func(absPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" || info.Name() == ".terraform" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(absPath) != ".tf" {
+			return nil
+		}
+		relDir, err := filepath.Rel(repoDir, filepath.Dir(absPath))
+		if err != nil {
+			return err
+		}
+		relDir = filepath.ToSlash(relDir)
+		if !seen[relDir] {
+			seen[relDir] = true
+			dirs = append(dirs, relDir)
+		}
+		return nil
+	})
+	return dirs, errors.Wrapf(err, "finding dirs with Terraform files in %q", repoDir)
+}
+
 // buildProjectApplyCommand builds an apply command for the single project
 // identified by cmd.
 func (p *DefaultProjectCommandBuilder) buildProjectApplyCommand(ctx *CommandContext, cmd *CommentCommand) ([]models.ProjectCommandContext, error) {
@@ -446,6 +802,10 @@ func (p *DefaultProjectCommandBuilder) buildProjectApplyCommand(ctx *CommandCont
 	// use the default repository workspace because it is the only one guaranteed to have an atlantis.yaml,
 	// other workspaces will not have the file if they are using pre_workflow_hooks to generate it dynamically
 	repoDir, err := p.WorkingDir.GetWorkingDir(ctx.Pull.BaseRepo, ctx.Pull, DefaultWorkspace)
+	if os.IsNotExist(errors.Cause(err)) && p.RestoreWorkingDirOnApply {
+		ctx.Log.Info("working dir not found, cloning it again to apply stored plans")
+		repoDir, _, err = p.WorkingDir.Clone(ctx.Log, ctx.HeadRepo, ctx.Pull, DefaultWorkspace)
+	}
 	if os.IsNotExist(errors.Cause(err)) {
 		return projCtx, errors.New("no working directory found–did you run plan?")
 	} else if err != nil {
@@ -510,6 +870,78 @@ func (p *DefaultProjectCommandBuilder) buildProjectVersionCommand(ctx *CommandCo
 	)
 }
 
+// buildSingleProjectStateCommand builds a command that modifies state, ex.
+// import, for the single project identified by cmd. These commands don't
+// depend on a prior plan so, like plan, they clone the repo if needed.
+func (p *DefaultProjectCommandBuilder) buildSingleProjectStateCommand(ctx *CommandContext, cmd *CommentCommand) ([]models.ProjectCommandContext, error) {
+	workspace := DefaultWorkspace
+	if cmd.Workspace != "" {
+		workspace = cmd.Workspace
+	}
+
+	var projCtx []models.ProjectCommandContext
+	unlockFn, err := p.WorkingDirLocker.TryLock(ctx.Pull.BaseRepo.FullName, ctx.Pull.Num, workspace)
+	if err != nil {
+		return projCtx, err
+	}
+	defer unlockFn()
+
+	_, _, err = p.WorkingDir.Clone(ctx.Log, ctx.HeadRepo, ctx.Pull, workspace)
+	if err != nil {
+		return projCtx, err
+	}
+
+	// use the default repository workspace because it is the only one guaranteed to have an atlantis.yaml,
+	// other workspaces will not have the file if they are using pre_workflow_hooks to generate it dynamically
+	defaultRepoDir, err := p.WorkingDir.GetWorkingDir(ctx.Pull.BaseRepo, ctx.Pull, DefaultWorkspace)
+	if err != nil {
+		return projCtx, err
+	}
+
+	repoRelDir := DefaultRepoRelDir
+	if cmd.RepoRelDir != "" {
+		repoRelDir = cmd.RepoRelDir
+	}
+
+	projCtx, err = p.buildProjectCommandCtx(
+		ctx,
+		cmd.Name,
+		cmd.ProjectName,
+		cmd.Flags,
+		defaultRepoDir,
+		repoRelDir,
+		workspace,
+		cmd.Verbose,
+	)
+	if err != nil {
+		return projCtx, err
+	}
+	if len(projCtx) > 1 {
+		var names []string
+		for _, c := range projCtx {
+			names = append(names, c.ProjectName)
+		}
+		return nil, fmt.Errorf("%s must run in a single project but %d projects matched: %s", cmd.Name.String(), len(projCtx), strings.Join(names, ", "))
+	}
+	return projCtx, nil
+}
+
+// definedProjectNames returns the names of the projects in repoCfg, for
+// error messages about project names that don't match, or an empty string if
+// none of them are named.
+func definedProjectNames(repoCfg *valid.RepoCfg) string {
+	var names []string
+	for _, p := range repoCfg.Projects {
+		if p.Name != nil {
+			names = append(names, *p.Name)
+		}
+	}
+	if len(names) == 0 {
+		return "; no projects are named, use -d and -w instead"
+	}
+	return fmt.Sprintf("; the projects are: %s", strings.Join(names, ", "))
+}
+
 // buildProjectCommandCtx builds a context for a single or several projects identified
 // by the parameters.
 func (p *DefaultProjectCommandBuilder) buildProjectCommandCtx(ctx *CommandContext,
@@ -521,7 +953,8 @@ func (p *DefaultProjectCommandBuilder) buildProjectCommandCtx(ctx *CommandContex
 	workspace string,
 	verbose bool) ([]models.ProjectCommandContext, error) {
 
-	matchingProjects, repoCfgPtr, err := p.getCfg(ctx, projectName, repoRelDir, workspace, repoDir)
+	globalCfg := p.GlobalCfg.Get()
+	matchingProjects, repoCfgPtr, err := p.getCfg(ctx, globalCfg, projectName, repoRelDir, workspace, repoDir)
 	if err != nil {
 		return []models.ProjectCommandContext{}, err
 	}
@@ -544,7 +977,10 @@ func (p *DefaultProjectCommandBuilder) buildProjectCommandCtx(ctx *CommandContex
 		workspace = projCfg.Workspace
 		for _, mp := range matchingProjects {
 			ctx.Log.Debug("Merging config for project at dir: %q workspace: %q", mp.Dir, mp.Workspace)
-			projCfg = p.GlobalCfg.MergeProjectCfg(ctx.Log, ctx.Pull.BaseRepo.ID(), mp, *repoCfgPtr)
+			projCfg = globalCfg.MergeProjectCfg(ctx.Log, ctx.Pull.BaseRepo.ID(), mp, *repoCfgPtr)
+			if !projCfg.CommandAllowed(cmd.String()) {
+				return []models.ProjectCommandContext{}, &CommandNotAllowedError{Command: cmd, Cfg: projCfg}
+			}
 
 			projCtxs = append(projCtxs,
 				p.ProjectCommandContextBuilder.BuildProjectContext(
@@ -561,7 +997,10 @@ func (p *DefaultProjectCommandBuilder) buildProjectCommandCtx(ctx *CommandContex
 				)...)
 		}
 	} else {
-		projCfg = p.GlobalCfg.DefaultProjCfg(ctx.Log, ctx.Pull.BaseRepo.ID(), repoRelDir, workspace)
+		projCfg = globalCfg.DefaultProjCfg(ctx.Log, ctx.Pull.BaseRepo.ID(), repoRelDir, workspace)
+		if !projCfg.CommandAllowed(cmd.String()) {
+			return []models.ProjectCommandContext{}, &CommandNotAllowedError{Command: cmd, Cfg: projCfg}
+		}
 		projCtxs = append(projCtxs,
 			p.ProjectCommandContextBuilder.BuildProjectContext(
 				ctx,
@@ -596,3 +1035,14 @@ func (p *DefaultProjectCommandBuilder) validateWorkspaceAllowed(repoCfg *valid.R
 
 	return repoCfg.ValidateWorkspaceAllowed(repoRelDir, workspace)
 }
+
+// CommandNotAllowedError is returned when a command is run on a project
+// whose allowed_commands doesn't include it.
+type CommandNotAllowedError struct {
+	Command models.CommandName
+	Cfg     valid.MergedProjectCfg
+}
+
+func (e *CommandNotAllowedError) Error() string {
+	return fmt.Sprintf("%s is not allowed for project at dir %q, workspace %q: %s is [%s]", e.Command, e.Cfg.RepoRelDir, e.Cfg.Workspace, valid.AllowedCommandsKey, strings.Join(e.Cfg.AllowedCommands, ", "))
+}
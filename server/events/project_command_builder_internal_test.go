@@ -7,6 +7,7 @@ import (
 
 	version "github.com/hashicorp/go-version"
 	. "github.com/petergtz/pegomock"
+	tmocks "github.com/runatlantis/atlantis/server/core/terraform/mocks"
 	"github.com/runatlantis/atlantis/server/events/matchers"
 	"github.com/runatlantis/atlantis/server/events/models"
 	vcsmocks "github.com/runatlantis/atlantis/server/events/vcs/mocks"
@@ -593,12 +594,14 @@ projects:
 				vcsClient,
 				workingDir,
 				NewDefaultWorkingDirLocker(),
-				globalCfg,
+				valid.NewGlobalCfgStore(globalCfg),
 				&DefaultPendingPlanFinder{},
 				&CommentParser{},
 				false,
 				false,
 				"**/*.tf,**/*.tfvars,**/*.tfvars.json,**/terragrunt.hcl",
+				tmocks.NewMockClient(),
+				false,
 			)
 
 			// We run a test for each type of command.
@@ -780,12 +783,14 @@ projects:
 				vcsClient,
 				workingDir,
 				NewDefaultWorkingDirLocker(),
-				globalCfg,
+				valid.NewGlobalCfgStore(globalCfg),
 				&DefaultPendingPlanFinder{},
 				&CommentParser{},
 				false,
 				true,
 				"**/*.tf,**/*.tfvars,**/*.tfvars.json,**/terragrunt.hcl",
+				tmocks.NewMockClient(),
+				false,
 			)
 
 			// We run a test for each type of command, again specific projects
@@ -993,12 +998,14 @@ workflows:
 				vcsClient,
 				workingDir,
 				NewDefaultWorkingDirLocker(),
-				globalCfg,
+				valid.NewGlobalCfgStore(globalCfg),
 				&DefaultPendingPlanFinder{},
 				&CommentParser{},
 				false,
 				false,
 				"**/*.tf,**/*.tfvars,**/*.tfvars.json,**/terragrunt.hcl",
+				tmocks.NewMockClient(),
+				false,
 			)
 
 			cmd := models.PolicyCheckCommand
@@ -1,13 +1,15 @@
 package events_test
 
 import (
-	"fmt"
 	"io/ioutil"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/hashicorp/go-version"
 	. "github.com/petergtz/pegomock"
+	tmocks "github.com/runatlantis/atlantis/server/core/terraform/mocks"
+	tmatchers "github.com/runatlantis/atlantis/server/core/terraform/mocks/matchers"
 	"github.com/runatlantis/atlantis/server/events"
 	"github.com/runatlantis/atlantis/server/events/matchers"
 	"github.com/runatlantis/atlantis/server/events/mocks"
@@ -150,12 +152,14 @@ projects:
 				vcsClient,
 				workingDir,
 				events.NewDefaultWorkingDirLocker(),
-				valid.NewGlobalCfgFromArgs(globalCfgArgs),
+				valid.NewGlobalCfgStore(valid.NewGlobalCfgFromArgs(globalCfgArgs)),
 				&events.DefaultPendingPlanFinder{},
 				&events.CommentParser{},
 				false,
 				false,
 				"**/*.tf,**/*.tfvars,**/*.tfvars.json,**/terragrunt.hcl",
+				tmocks.NewMockClient(),
+				false,
 			)
 
 			ctxs, err := builder.BuildAutoplanCommands(&events.CommandContext{
@@ -175,6 +179,95 @@ projects:
 }
 
 // Test building a plan and apply command for one project.
+// Test that projects are autoplanned when a local module they call is modified
+// and the server-side config has an autoplan trigger for it.
+func TestDefaultProjectCommandBuilder_BuildAutoplanCommands_Triggers(t *testing.T) {
+	cases := []struct {
+		Description  string
+		AtlantisYAML string
+		expDirs      []string
+	}{
+		{
+			Description: "no atlantis.yaml",
+			expDirs:     []string{"project1"},
+		},
+		{
+			Description: "atlantis.yaml",
+			AtlantisYAML: `
+version: 3
+projects:
+- dir: project1
+- dir: project2
+`,
+			expDirs: []string{"project1"},
+		},
+	}
+
+	logger := logging.NewNoopLogger(t)
+
+	for _, c := range cases {
+		t.Run(c.Description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			tmpDir, cleanup := DirStructure(t, map[string]interface{}{
+				"project1": map[string]interface{}{
+					"main.tf": `module "vpc" { source = "../modules/vpc" }`,
+				},
+				"project2": map[string]interface{}{
+					"main.tf": nil,
+				},
+				"modules": map[string]interface{}{
+					"vpc": map[string]interface{}{
+						"main.tf": nil,
+					},
+				},
+			})
+			defer cleanup()
+
+			workingDir := mocks.NewMockWorkingDir()
+			When(workingDir.Clone(matchers.AnyPtrToLoggingSimpleLogger(), matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString())).ThenReturn(tmpDir, false, nil)
+			vcsClient := vcsmocks.NewMockClient()
+			When(vcsClient.GetModifiedFiles(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest())).ThenReturn([]string{"modules/vpc/main.tf"}, nil)
+			if c.AtlantisYAML != "" {
+				err := ioutil.WriteFile(filepath.Join(tmpDir, yaml.AtlantisYAMLFilename), []byte(c.AtlantisYAML), 0600)
+				Ok(t, err)
+			}
+
+			globalCfg := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})
+			globalCfg.Repos[0].AutoplanTriggers = []valid.AutoplanTrigger{
+				{WhenModified: []string{"modules/**"}},
+			}
+
+			builder := events.NewProjectCommandBuilder(
+				false,
+				&yaml.ParserValidator{},
+				&events.DefaultProjectFinder{},
+				vcsClient,
+				workingDir,
+				events.NewDefaultWorkingDirLocker(),
+				valid.NewGlobalCfgStore(globalCfg),
+				&events.DefaultPendingPlanFinder{},
+				&events.CommentParser{},
+				false,
+				false,
+				"**/*.tf,**/*.tfvars,**/*.tfvars.json,**/terragrunt.hcl",
+				tmocks.NewMockClient(),
+				false,
+			)
+
+			ctxs, err := builder.BuildAutoplanCommands(&events.CommandContext{
+				PullMergeable: true,
+				Log:           logger,
+			})
+			Ok(t, err)
+			var dirs []string
+			for _, ctx := range ctxs {
+				dirs = append(dirs, ctx.RepoRelDir)
+			}
+			Equals(t, c.expDirs, dirs)
+		})
+	}
+}
+
 func TestDefaultProjectCommandBuilder_BuildSinglePlanApplyCommand(t *testing.T) {
 	cases := []struct {
 		Description      string
@@ -349,7 +442,24 @@ version: 3
 projects:
 - dir: .
 `,
-			ExpErr: "no project with name \"notconfigured\" is defined in atlantis.yaml",
+			ExpErr: "no project with name \"notconfigured\" is defined in atlantis.yaml; no projects are named, use -d and -w instead",
+		},
+		{
+			Description: "atlantis.yaml with project flag not matching named projects",
+			Cmd: events.CommentCommand{
+				Name:        models.PlanCommand,
+				ProjectName: "notconfigured",
+			},
+			AtlantisYAML: `
+version: 3
+projects:
+- name: staging
+  dir: staging
+- name: production
+  dir: production
+- dir: .
+`,
+			ExpErr: "no project with name \"notconfigured\" is defined in atlantis.yaml; the projects are: staging, production",
 		},
 		{
 			Description: "atlantis.yaml with ParallelPlan Set to true",
@@ -412,12 +522,14 @@ projects:
 					vcsClient,
 					workingDir,
 					events.NewDefaultWorkingDirLocker(),
-					valid.NewGlobalCfgFromArgs(globalCfgArgs),
+					valid.NewGlobalCfgStore(valid.NewGlobalCfgFromArgs(globalCfgArgs)),
 					&events.DefaultPendingPlanFinder{},
 					&events.CommentParser{},
 					false,
 					true,
 					"**/*.tf,**/*.tfvars,**/*.tfvars.json,**/terragrunt.hcl",
+					tmocks.NewMockClient(),
+					false,
 				)
 
 				var actCtxs []models.ProjectCommandContext
@@ -563,12 +675,14 @@ projects:
 				vcsClient,
 				workingDir,
 				events.NewDefaultWorkingDirLocker(),
-				valid.NewGlobalCfgFromArgs(globalCfgArgs),
+				valid.NewGlobalCfgStore(valid.NewGlobalCfgFromArgs(globalCfgArgs)),
 				&events.DefaultPendingPlanFinder{},
 				&events.CommentParser{},
 				false,
 				false,
 				"**/*.tf,**/*.tfvars,**/*.tfvars.json,**/terragrunt.hcl",
+				tmocks.NewMockClient(),
+				false,
 			)
 
 			ctxs, err := builder.BuildPlanCommands(
@@ -650,12 +764,14 @@ func TestDefaultProjectCommandBuilder_BuildMultiApply(t *testing.T) {
 		nil,
 		workingDir,
 		events.NewDefaultWorkingDirLocker(),
-		valid.NewGlobalCfgFromArgs(globalCfgArgs),
+		valid.NewGlobalCfgStore(valid.NewGlobalCfgFromArgs(globalCfgArgs)),
 		&events.DefaultPendingPlanFinder{},
 		&events.CommentParser{},
 		false,
 		false,
 		"**/*.tf,**/*.tfvars,**/*.tfvars.json,**/terragrunt.hcl",
+		tmocks.NewMockClient(),
+		false,
 	)
 
 	ctxs, err := builder.BuildApplyCommands(
@@ -682,6 +798,72 @@ func TestDefaultProjectCommandBuilder_BuildMultiApply(t *testing.T) {
 	Equals(t, "workspace2", ctxs[3].Workspace)
 }
 
+func TestDefaultProjectCommandBuilder_AllowedCommands(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmpDir, cleanup := DirStructure(t, map[string]interface{}{
+		"default": map[string]interface{}{
+			"atlantis.yaml": `
+version: 3
+projects:
+- dir: project1
+  allowed_commands: [plan]
+- dir: project2
+`,
+			"project1": map[string]interface{}{
+				"main.tf":        nil,
+				"default.tfplan": nil,
+			},
+			"project2": map[string]interface{}{
+				"main.tf":        nil,
+				"default.tfplan": nil,
+			},
+		},
+	})
+	defer cleanup()
+	repoDir := filepath.Join(tmpDir, "default")
+	runCmd(t, repoDir, "git", "init")
+
+	workingDir := mocks.NewMockWorkingDir()
+	When(workingDir.Clone(matchers.AnyPtrToLoggingSimpleLogger(), matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString())).ThenReturn(repoDir, false, nil)
+	When(workingDir.GetWorkingDir(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString())).ThenReturn(repoDir, nil)
+	When(workingDir.GetPullDir(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest())).ThenReturn(tmpDir, nil)
+	vcsClient := vcsmocks.NewMockClient()
+	When(vcsClient.GetModifiedFiles(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest())).ThenReturn([]string{"project1/main.tf", "project2/main.tf"}, nil)
+
+	builder := events.NewProjectCommandBuilder(
+		false,
+		&yaml.ParserValidator{},
+		&events.DefaultProjectFinder{},
+		vcsClient,
+		workingDir,
+		events.NewDefaultWorkingDirLocker(),
+		valid.NewGlobalCfgStore(valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})),
+		&events.DefaultPendingPlanFinder{},
+		&events.CommentParser{},
+		false,
+		false,
+		"**/*.tf,**/*.tfvars,**/*.tfvars.json,**/terragrunt.hcl",
+		tmocks.NewMockClient(),
+		false,
+	)
+	cmdCtx := &events.CommandContext{Log: logging.NewNoopLogger(t)}
+
+	t.Log("projects that allow plan are autoplanned")
+	ctxs, err := builder.BuildAutoplanCommands(cmdCtx)
+	Ok(t, err)
+	Equals(t, 2, len(ctxs))
+
+	t.Log("apply all skips projects that don't allow apply")
+	ctxs, err = builder.BuildApplyCommands(cmdCtx, &events.CommentCommand{Name: models.ApplyCommand})
+	Ok(t, err)
+	Equals(t, 1, len(ctxs))
+	Equals(t, "project2", ctxs[0].RepoRelDir)
+
+	t.Log("applying a project that doesn't allow apply is an error")
+	_, err = builder.BuildApplyCommands(cmdCtx, &events.CommentCommand{Name: models.ApplyCommand, RepoRelDir: "project1"})
+	ErrEquals(t, `apply is not allowed for project at dir "project1", workspace "default": allowed_commands is [plan]`, err)
+}
+
 // Test that if a directory has a list of workspaces configured then we don't
 // allow plans for other workspace names.
 func TestDefaultProjectCommandBuilder_WrongWorkspaceName(t *testing.T) {
@@ -730,12 +912,14 @@ projects:
 		nil,
 		workingDir,
 		events.NewDefaultWorkingDirLocker(),
-		valid.NewGlobalCfgFromArgs(globalCfgArgs),
+		valid.NewGlobalCfgStore(valid.NewGlobalCfgFromArgs(globalCfgArgs)),
 		&events.DefaultPendingPlanFinder{},
 		&events.CommentParser{},
 		false,
 		false,
 		"**/*.tf,**/*.tfvars,**/*.tfvars.json,**/terragrunt.hcl",
+		tmocks.NewMockClient(),
+		false,
 	)
 
 	ctx := &events.CommandContext{
@@ -805,12 +989,14 @@ func TestDefaultProjectCommandBuilder_EscapeArgs(t *testing.T) {
 				vcsClient,
 				workingDir,
 				events.NewDefaultWorkingDirLocker(),
-				valid.NewGlobalCfgFromArgs(globalCfgArgs),
+				valid.NewGlobalCfgStore(valid.NewGlobalCfgFromArgs(globalCfgArgs)),
 				&events.DefaultPendingPlanFinder{},
 				&events.CommentParser{},
 				false,
 				false,
 				"**/*.tf,**/*.tfvars,**/*.tfvars.json,**/terragrunt.hcl",
+				tmocks.NewMockClient(),
+				false,
 			)
 
 			var actCtxs []models.ProjectCommandContext
@@ -832,18 +1018,9 @@ func TestDefaultProjectCommandBuilder_EscapeArgs(t *testing.T) {
 	}
 }
 
-// Test that terraform version is used when specified in terraform configuration
+// Test that the terraform version detected from the terraform configuration
+// is used unless the project config specifies one.
 func TestDefaultProjectCommandBuilder_TerraformVersion(t *testing.T) {
-	// For the following tests:
-	// If terraform configuration is used, result should be `0.12.8`.
-	// If project configuration is used, result should be `0.12.6`.
-	// If default is to be used, result should be `nil`.
-	baseVersionConfig := `
-terraform {
-  required_version = "%s0.12.8"
-}
-`
-
 	atlantisYamlContent := `
 version: 3
 projects:
@@ -851,98 +1028,81 @@ projects:
   terraform_version: v0.12.6
 `
 
-	exactSymbols := []string{"", "="}
-	nonExactSymbols := []string{">", ">=", "<", "<=", "~="}
-
 	type testCase struct {
 		DirStructure  map[string]interface{}
 		AtlantisYAML  string
 		ModifiedFiles []string
-		Exp           map[string][]int
+		// DetectedVersions maps from project dir to the version detected from
+		// its terraform configuration.
+		DetectedVersions map[string]string
+		Exp              map[string][]int
 	}
 
-	testCases := make(map[string]testCase)
-
-	for _, exactSymbol := range exactSymbols {
-		testCases[fmt.Sprintf("exact version in terraform config using \"%s\"", exactSymbol)] = testCase{
+	testCases := map[string]testCase{
+		"version detected from terraform config": {
 			DirStructure: map[string]interface{}{
 				"project1": map[string]interface{}{
-					"main.tf": fmt.Sprintf(baseVersionConfig, exactSymbol),
+					"main.tf": nil,
 				},
 			},
-			ModifiedFiles: []string{"project1/main.tf"},
+			ModifiedFiles:    []string{"project1/main.tf"},
+			DetectedVersions: map[string]string{"project1": "0.12.8"},
 			Exp: map[string][]int{
 				"project1": {0, 12, 8},
 			},
-		}
-	}
-
-	for _, nonExactSymbol := range nonExactSymbols {
-		testCases[fmt.Sprintf("non-exact version in terraform config using \"%s\"", nonExactSymbol)] = testCase{
+		},
+		// atlantis.yaml should take precedence over terraform config
+		"with project config and terraform config": {
 			DirStructure: map[string]interface{}{
 				"project1": map[string]interface{}{
-					"main.tf": fmt.Sprintf(baseVersionConfig, nonExactSymbol),
+					"main.tf": nil,
 				},
+				yaml.AtlantisYAMLFilename: atlantisYamlContent,
 			},
-			ModifiedFiles: []string{"project1/main.tf"},
+			ModifiedFiles:    []string{"project1/main.tf", "project2/main.tf"},
+			DetectedVersions: map[string]string{"project1": "0.12.8"},
 			Exp: map[string][]int{
-				"project1": nil,
+				"project1": {0, 12, 6},
 			},
-		}
-	}
-
-	// atlantis.yaml should take precedence over terraform config
-	testCases["with project config and terraform config"] = testCase{
-		DirStructure: map[string]interface{}{
-			"project1": map[string]interface{}{
-				"main.tf": fmt.Sprintf(baseVersionConfig, exactSymbols[0]),
-			},
-			yaml.AtlantisYAMLFilename: atlantisYamlContent,
-		},
-		ModifiedFiles: []string{"project1/main.tf", "project2/main.tf"},
-		Exp: map[string][]int{
-			"project1": {0, 12, 6},
 		},
-	}
-
-	testCases["with project config only"] = testCase{
-		DirStructure: map[string]interface{}{
-			"project1": map[string]interface{}{
-				"main.tf": nil,
+		"with project config only": {
+			DirStructure: map[string]interface{}{
+				"project1": map[string]interface{}{
+					"main.tf": nil,
+				},
+				yaml.AtlantisYAMLFilename: atlantisYamlContent,
 			},
-			yaml.AtlantisYAMLFilename: atlantisYamlContent,
-		},
-		ModifiedFiles: []string{"project1/main.tf"},
-		Exp: map[string][]int{
-			"project1": {0, 12, 6},
-		},
-	}
-
-	testCases["neither project config or terraform config"] = testCase{
-		DirStructure: map[string]interface{}{
-			"project1": map[string]interface{}{
-				"main.tf": nil,
+			ModifiedFiles: []string{"project1/main.tf"},
+			Exp: map[string][]int{
+				"project1": {0, 12, 6},
 			},
 		},
-		ModifiedFiles: []string{"project1/main.tf", "project2/main.tf"},
-		Exp: map[string][]int{
-			"project1": nil,
-		},
-	}
-
-	testCases["project with different terraform config"] = testCase{
-		DirStructure: map[string]interface{}{
-			"project1": map[string]interface{}{
-				"main.tf": fmt.Sprintf(baseVersionConfig, exactSymbols[0]),
+		"neither project config or terraform config": {
+			DirStructure: map[string]interface{}{
+				"project1": map[string]interface{}{
+					"main.tf": nil,
+				},
 			},
-			"project2": map[string]interface{}{
-				"main.tf": strings.Replace(fmt.Sprintf(baseVersionConfig, exactSymbols[0]), "0.12.8", "0.12.9", -1),
+			ModifiedFiles: []string{"project1/main.tf", "project2/main.tf"},
+			Exp: map[string][]int{
+				"project1": nil,
 			},
 		},
-		ModifiedFiles: []string{"project1/main.tf", "project2/main.tf"},
-		Exp: map[string][]int{
-			"project1": {0, 12, 8},
-			"project2": {0, 12, 9},
+		"project with different terraform config": {
+			DirStructure: map[string]interface{}{
+				"project1": map[string]interface{}{
+					"main.tf": nil,
+				},
+				"project2": map[string]interface{}{
+					"main.tf": nil,
+				},
+			},
+			ModifiedFiles:    []string{"project1/main.tf", "project2/main.tf"},
+			DetectedVersions: map[string]string{"project1": "0.12.8", "project2": "0.12.9"},
+			Exp: map[string][]int{
+				"project1": {0, 12, 8},
+				"project2": {0, 12, 9},
+			},
 		},
 	}
 
@@ -970,6 +1130,11 @@ projects:
 				matchers.AnyModelsPullRequest(),
 				AnyString())).ThenReturn(tmpDir, nil)
 
+			terraformClient := tmocks.NewMockClient()
+			for dir, v := range testCase.DetectedVersions {
+				When(terraformClient.DetectVersion(tmatchers.AnyLoggingSimpleLogging(), AnyString(), EqString(filepath.Join(tmpDir, dir)))).ThenReturn(version.Must(version.NewVersion(v)))
+			}
+
 			globalCfgArgs := valid.GlobalCfgArgs{
 				AllowRepoCfg:  true,
 				MergeableReq:  false,
@@ -984,12 +1149,14 @@ projects:
 				vcsClient,
 				workingDir,
 				events.NewDefaultWorkingDirLocker(),
-				valid.NewGlobalCfgFromArgs(globalCfgArgs),
+				valid.NewGlobalCfgStore(valid.NewGlobalCfgFromArgs(globalCfgArgs)),
 				&events.DefaultPendingPlanFinder{},
 				&events.CommentParser{},
 				false,
 				false,
 				"**/*.tf,**/*.tfvars,**/*.tfvars.json,**/terragrunt.hcl",
+				terraformClient,
+				false,
 			)
 
 			actCtxs, err := builder.BuildPlanCommands(
@@ -1047,12 +1214,14 @@ projects:
 		vcsClient,
 		workingDir,
 		events.NewDefaultWorkingDirLocker(),
-		valid.NewGlobalCfgFromArgs(globalCfgArgs),
+		valid.NewGlobalCfgStore(valid.NewGlobalCfgFromArgs(globalCfgArgs)),
 		&events.DefaultPendingPlanFinder{},
 		&events.CommentParser{},
 		true,
 		false,
 		"**/*.tf,**/*.tfvars,**/*.tfvars.json,**/terragrunt.hcl",
+		tmocks.NewMockClient(),
+		false,
 	)
 
 	var actCtxs []models.ProjectCommandContext
@@ -1099,12 +1268,14 @@ func TestDefaultProjectCommandBuilder_WithPolicyCheckEnabled_BuildAutoplanComman
 		vcsClient,
 		workingDir,
 		events.NewDefaultWorkingDirLocker(),
-		globalCfg,
+		valid.NewGlobalCfgStore(globalCfg),
 		&events.DefaultPendingPlanFinder{},
 		&events.CommentParser{},
 		false,
 		false,
 		"**/*.tf,**/*.tfvars,**/*.tfvars.json,**/terragrunt.hcl",
+		tmocks.NewMockClient(),
+		false,
 	)
 
 	ctxs, err := builder.BuildAutoplanCommands(&events.CommandContext{
@@ -1175,12 +1346,14 @@ func TestDefaultProjectCommandBuilder_BuildVersionCommand(t *testing.T) {
 		nil,
 		workingDir,
 		events.NewDefaultWorkingDirLocker(),
-		valid.NewGlobalCfgFromArgs(globalCfgArgs),
+		valid.NewGlobalCfgStore(valid.NewGlobalCfgFromArgs(globalCfgArgs)),
 		&events.DefaultPendingPlanFinder{},
 		&events.CommentParser{},
 		false,
 		false,
 		"**/*.tf,**/*.tfvars,**/*.tfvars.json,**/terragrunt.hcl",
+		tmocks.NewMockClient(),
+		false,
 	)
 
 	ctxs, err := builder.BuildVersionCommands(
@@ -1206,3 +1379,342 @@ func TestDefaultProjectCommandBuilder_BuildVersionCommand(t *testing.T) {
 	Equals(t, "project2", ctxs[3].RepoRelDir)
 	Equals(t, "workspace2", ctxs[3].Workspace)
 }
+
+func TestDefaultProjectCommandBuilder_BuildValidateCommands(t *testing.T) {
+	cases := map[string]struct {
+		Cmd      events.CommentCommand
+		ExpDirs  []string
+		ExpSteps []string
+	}{
+		"all modified projects": {
+			Cmd:      events.CommentCommand{Name: models.ValidateCommand},
+			ExpDirs:  []string{"project1", "project2"},
+			ExpSteps: []string{"init", "validate"},
+		},
+		"specific project with fmt": {
+			Cmd:      events.CommentCommand{Name: models.ValidateCommand, RepoRelDir: "project1", Fmt: true},
+			ExpDirs:  []string{"project1"},
+			ExpSteps: []string{"init", "validate", "fmt"},
+		},
+	}
+
+	logger := logging.NewNoopLogger(t)
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			tmpDir, cleanup := DirStructure(t, map[string]interface{}{
+				"project1": map[string]interface{}{
+					"main.tf": nil,
+				},
+				"project2": map[string]interface{}{
+					"main.tf": nil,
+				},
+			})
+			defer cleanup()
+
+			workingDir := mocks.NewMockWorkingDir()
+			When(workingDir.Clone(matchers.AnyPtrToLoggingSimpleLogger(), matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString())).ThenReturn(tmpDir, false, nil)
+			When(workingDir.GetWorkingDir(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString())).ThenReturn(tmpDir, nil)
+			vcsClient := vcsmocks.NewMockClient()
+			When(vcsClient.GetModifiedFiles(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest())).ThenReturn([]string{"project1/main.tf", "project2/main.tf"}, nil)
+
+			builder := events.NewProjectCommandBuilder(
+				false,
+				&yaml.ParserValidator{},
+				&events.DefaultProjectFinder{},
+				vcsClient,
+				workingDir,
+				events.NewDefaultWorkingDirLocker(),
+				valid.NewGlobalCfgStore(valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})),
+				&events.DefaultPendingPlanFinder{},
+				&events.CommentParser{},
+				false,
+				false,
+				"**/*.tf,**/*.tfvars,**/*.tfvars.json,**/terragrunt.hcl",
+				tmocks.NewMockClient(),
+				false,
+			)
+
+			ctxs, err := builder.BuildValidateCommands(&events.CommandContext{Log: logger}, &c.Cmd)
+			Ok(t, err)
+			Equals(t, len(c.ExpDirs), len(ctxs))
+			for i, actCtx := range ctxs {
+				Equals(t, models.ValidateCommand, actCtx.CommandName)
+				Equals(t, c.ExpDirs[i], actCtx.RepoRelDir)
+				var stepNames []string
+				for _, step := range actCtx.Steps {
+					stepNames = append(stepNames, step.StepName)
+				}
+				Equals(t, c.ExpSteps, stepNames)
+			}
+		})
+	}
+}
+
+func TestDefaultProjectCommandBuilder_BuildCustomCommands(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmpDir, cleanup := DirStructure(t, map[string]interface{}{
+		"project1": map[string]interface{}{
+			"main.tf": nil,
+		},
+		"project2": map[string]interface{}{
+			"main.tf": nil,
+		},
+	})
+	defer cleanup()
+
+	workingDir := mocks.NewMockWorkingDir()
+	When(workingDir.Clone(matchers.AnyPtrToLoggingSimpleLogger(), matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString())).ThenReturn(tmpDir, false, nil)
+	When(workingDir.GetWorkingDir(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString())).ThenReturn(tmpDir, nil)
+	vcsClient := vcsmocks.NewMockClient()
+	When(vcsClient.GetModifiedFiles(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest())).ThenReturn([]string{"project1/main.tf", "project2/main.tf"}, nil)
+
+	deploySteps := []valid.Step{{StepName: "init"}, {StepName: "run", RunCommand: "./deploy.sh"}}
+	globalCfg := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})
+	globalCfg.CustomCommands = map[string]valid.CustomCommand{
+		"deploy": {Name: "deploy", Workflow: valid.Workflow{Name: "deploy", Plan: valid.Stage{Steps: deploySteps}}},
+	}
+	builder := events.NewProjectCommandBuilder(
+		false,
+		&yaml.ParserValidator{},
+		&events.DefaultProjectFinder{},
+		vcsClient,
+		workingDir,
+		events.NewDefaultWorkingDirLocker(),
+		valid.NewGlobalCfgStore(globalCfg),
+		&events.DefaultPendingPlanFinder{},
+		&events.CommentParser{},
+		false,
+		false,
+		"**/*.tf,**/*.tfvars,**/*.tfvars.json,**/terragrunt.hcl",
+		tmocks.NewMockClient(),
+		false,
+	)
+
+	logger := logging.NewNoopLogger(t)
+	ctxs, err := builder.BuildCustomCommands(&events.CommandContext{Log: logger}, &events.CommentCommand{Name: models.CustomCommand, SubName: "deploy"})
+	Ok(t, err)
+	Equals(t, 2, len(ctxs))
+	for i, actCtx := range ctxs {
+		Equals(t, models.CustomCommand, actCtx.CommandName)
+		Equals(t, "deploy", actCtx.SubName)
+		Equals(t, []string{"project1", "project2"}[i], actCtx.RepoRelDir)
+		Equals(t, deploySteps, actCtx.Steps)
+	}
+
+	_, err = builder.BuildCustomCommands(&events.CommandContext{Log: logger}, &events.CommentCommand{Name: models.CustomCommand, SubName: "removed"})
+	ErrEquals(t, `command "removed" is not defined in the server-side repo config`, err)
+}
+
+// Test that commands that must run in a single project name the projects
+// that a -p regex matched.
+func TestDefaultProjectCommandBuilder_BuildImportCommands_AmbiguousProject(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmpDir, cleanup := DirStructure(t, map[string]interface{}{
+		"staging": map[string]interface{}{
+			"main.tf": nil,
+		},
+		"production": map[string]interface{}{
+			"main.tf": nil,
+		},
+		"atlantis.yaml": `
+version: 3
+projects:
+- name: app-staging
+  dir: staging
+- name: app-production
+  dir: production
+`,
+	})
+	defer cleanup()
+
+	workingDir := mocks.NewMockWorkingDir()
+	When(workingDir.Clone(matchers.AnyPtrToLoggingSimpleLogger(), matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString())).ThenReturn(tmpDir, false, nil)
+	When(workingDir.GetWorkingDir(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString())).ThenReturn(tmpDir, nil)
+	builder := events.NewProjectCommandBuilder(
+		false,
+		&yaml.ParserValidator{},
+		&events.DefaultProjectFinder{},
+		vcsmocks.NewMockClient(),
+		workingDir,
+		events.NewDefaultWorkingDirLocker(),
+		valid.NewGlobalCfgStore(valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})),
+		&events.DefaultPendingPlanFinder{},
+		&events.CommentParser{},
+		false,
+		true,
+		"**/*.tf,**/*.tfvars,**/*.tfvars.json,**/terragrunt.hcl",
+		tmocks.NewMockClient(),
+		false,
+	)
+
+	_, err := builder.BuildImportCommands(&events.CommandContext{Log: logging.NewNoopLogger(t)}, &events.CommentCommand{
+		Name:        models.ImportCommand,
+		ProjectName: "app-.*",
+	})
+	ErrEquals(t, "import must run in a single project but 2 projects matched: app-staging, app-production", err)
+}
+
+func TestDefaultProjectCommandBuilder_BuildMultiTargetPlanCommands(t *testing.T) {
+	cases := map[string]struct {
+		atlantisYAML string
+		dir          string
+		workspace    string
+		exp          []string
+		expErr       string
+	}{
+		"dir pattern without config": {
+			dir: "stacks/prod/*",
+			exp: []string{"stacks/prod/app default", "stacks/prod/db default"},
+		},
+		"dir list and workspace list without config": {
+			dir:       "stacks/staging/app,stacks/prod/app",
+			workspace: "a,b",
+			exp:       []string{"stacks/staging/app a", "stacks/staging/app b", "stacks/prod/app a", "stacks/prod/app b"},
+		},
+		"workspace pattern without config": {
+			workspace: "prod-*",
+			expErr:    `workspace pattern "prod-*" can only be used with projects configured in atlantis.yaml`,
+		},
+		"dir pattern with config": {
+			atlantisYAML: `
+version: 3
+projects:
+- dir: stacks/prod/app
+- dir: stacks/prod/app
+  workspace: eu
+- dir: stacks/staging/app
+`,
+			dir: "stacks/prod/*",
+			exp: []string{"stacks/prod/app default", "stacks/prod/app eu"},
+		},
+		"workspace pattern with config": {
+			atlantisYAML: `
+version: 3
+projects:
+- dir: stacks/prod/app
+- dir: stacks/prod/app
+  workspace: eu
+- dir: stacks/staging/app
+  workspace: eu-staging
+`,
+			workspace: "eu*",
+			exp:       []string{"stacks/prod/app eu", "stacks/staging/app eu-staging"},
+		},
+		"no matches": {
+			dir:    "other/*",
+			expErr: `no projects matched dir: "other/*" workspace: ""`,
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			dirStructure := map[string]interface{}{
+				"stacks": map[string]interface{}{
+					"prod": map[string]interface{}{
+						"app":       map[string]interface{}{"main.tf": nil},
+						"db":        map[string]interface{}{"main.tf": nil},
+						"README.md": nil,
+					},
+					"staging": map[string]interface{}{
+						"app": map[string]interface{}{"main.tf": nil},
+					},
+				},
+			}
+			if c.atlantisYAML != "" {
+				dirStructure["atlantis.yaml"] = c.atlantisYAML
+			}
+			tmpDir, cleanup := DirStructure(t, dirStructure)
+			defer cleanup()
+
+			workingDir := mocks.NewMockWorkingDir()
+			When(workingDir.Clone(matchers.AnyPtrToLoggingSimpleLogger(), matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString())).ThenReturn(tmpDir, false, nil)
+			When(workingDir.GetWorkingDir(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString())).ThenReturn(tmpDir, nil)
+			builder := events.NewProjectCommandBuilder(
+				false,
+				&yaml.ParserValidator{},
+				&events.DefaultProjectFinder{},
+				vcsmocks.NewMockClient(),
+				workingDir,
+				events.NewDefaultWorkingDirLocker(),
+				valid.NewGlobalCfgStore(valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{AllowRepoCfg: true})),
+				&events.DefaultPendingPlanFinder{},
+				&events.CommentParser{},
+				false,
+				false,
+				"**/*.tf,**/*.tfvars,**/*.tfvars.json,**/terragrunt.hcl",
+				tmocks.NewMockClient(),
+				false,
+			)
+
+			ctxs, err := builder.BuildPlanCommands(&events.CommandContext{Log: logging.NewNoopLogger(t)}, &events.CommentCommand{
+				Name:       models.PlanCommand,
+				RepoRelDir: c.dir,
+				Workspace:  c.workspace,
+			})
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			var targets []string
+			for _, ctx := range ctxs {
+				targets = append(targets, ctx.RepoRelDir+" "+ctx.Workspace)
+			}
+			Equals(t, c.exp, targets)
+		})
+	}
+}
+
+func TestDefaultProjectCommandBuilder_BuildMultiTargetApplyCommands(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmpDir, cleanup := DirStructure(t, map[string]interface{}{
+		"default": map[string]interface{}{
+			"stacks": map[string]interface{}{
+				"prod": map[string]interface{}{
+					"main.tf":        nil,
+					"default.tfplan": nil,
+				},
+				"staging": map[string]interface{}{
+					"main.tf":        nil,
+					"default.tfplan": nil,
+				},
+			},
+		},
+	})
+	defer cleanup()
+	runCmd(t, filepath.Join(tmpDir, "default"), "git", "init")
+
+	workingDir := mocks.NewMockWorkingDir()
+	When(workingDir.GetPullDir(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest())).ThenReturn(tmpDir, nil)
+	builder := events.NewProjectCommandBuilder(
+		false,
+		&yaml.ParserValidator{},
+		&events.DefaultProjectFinder{},
+		nil,
+		workingDir,
+		events.NewDefaultWorkingDirLocker(),
+		valid.NewGlobalCfgStore(valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})),
+		&events.DefaultPendingPlanFinder{},
+		&events.CommentParser{},
+		false,
+		false,
+		"**/*.tf,**/*.tfvars,**/*.tfvars.json,**/terragrunt.hcl",
+		tmocks.NewMockClient(),
+		false,
+	)
+
+	ctxs, err := builder.BuildApplyCommands(&events.CommandContext{Log: logging.NewNoopLogger(t)}, &events.CommentCommand{
+		Name:       models.ApplyCommand,
+		RepoRelDir: "stacks/p*",
+	})
+	Ok(t, err)
+	Equals(t, 1, len(ctxs))
+	Equals(t, "stacks/prod", ctxs[0].RepoRelDir)
+
+	_, err = builder.BuildApplyCommands(&events.CommandContext{Log: logging.NewNoopLogger(t)}, &events.CommentCommand{
+		Name:       models.ApplyCommand,
+		RepoRelDir: "other/*",
+	})
+	ErrEquals(t, `no plans matched dir: "other/*" workspace: ""–did you run plan?`, err)
+}
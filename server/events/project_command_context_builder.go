@@ -2,23 +2,23 @@ package events
 
 import (
 	"path/filepath"
-	"regexp"
 
-	"github.com/hashicorp/go-version"
-	"github.com/hashicorp/terraform-config-inspect/tfconfig"
+	"github.com/runatlantis/atlantis/server/core/terraform"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 )
 
-func NewProjectCommandContextBulder(policyCheckEnabled bool, commentBuilder CommentBuilder) ProjectCommandContextBuilder {
+func NewProjectCommandContextBulder(policyCheckEnabled bool, commentBuilder CommentBuilder, terraformClient terraform.Client) ProjectCommandContextBuilder {
 	projectCommandContextBuilder := &DefaultProjectCommandContextBuilder{
-		CommentBuilder: commentBuilder,
+		CommentBuilder:    commentBuilder,
+		TerraformExecutor: terraformClient,
 	}
 
 	if policyCheckEnabled {
 		return &PolicyCheckProjectCommandContextBuilder{
 			CommentBuilder:               commentBuilder,
 			ProjectCommandContextBuilder: projectCommandContextBuilder,
+			TerraformExecutor:            terraformClient,
 		}
 	}
 
@@ -39,6 +39,9 @@ type ProjectCommandContextBuilder interface {
 
 type DefaultProjectCommandContextBuilder struct {
 	CommentBuilder CommentBuilder
+	// TerraformExecutor is used to detect the terraform version of projects
+	// that don't set terraform_version.
+	TerraformExecutor terraform.Client
 }
 
 func (cb *DefaultProjectCommandContextBuilder) BuildProjectContext(
@@ -52,29 +55,47 @@ func (cb *DefaultProjectCommandContextBuilder) BuildProjectContext(
 	ctx.Log.Debug("Building project command context for %s", cmdName)
 
 	var steps []valid.Step
+	// planCommentFlags are the comment flags used to build the re-plan
+	// command.
+	planCommentFlags := commentFlags
 	switch cmdName {
 	case models.PlanCommand:
 		steps = prjCfg.Workflow.Plan.Steps
 	case models.ApplyCommand:
-		steps = prjCfg.Workflow.Apply.Steps
+		steps = withOutputStep(prjCfg.Workflow.Apply.Steps, prjCfg.Outputs)
 	case models.VersionCommand:
 		// Setting statically since there will only be one step
 		steps = []valid.Step{{
 			StepName: "version",
 		}}
+	case models.ImportCommand:
+		steps = stateSteps(prjCfg.Workflow.Plan, "import")
+		// The import comment flags end with the ADDRESS and ID which aren't
+		// valid for plan.
+		planCommentFlags = nil
+	case models.StateCommand:
+		steps = stateSteps(prjCfg.Workflow.Plan, "state")
+		planCommentFlags = nil
+	case models.ValidateCommand:
+		steps = stateSteps(prjCfg.Workflow.Plan, "validate")
+		planCommentFlags = nil
+	case models.CustomCommand:
+		// The steps are the custom command's workflow's, which are set by
+		// ProjectCommandBuilder.BuildCustomCommands.
+		planCommentFlags = nil
 	}
 
 	// If TerraformVersion not defined in config file look for a
 	// terraform.require_version block.
 	if prjCfg.TerraformVersion == nil {
-		prjCfg.TerraformVersion = getTfVersion(ctx, filepath.Join(repoDir, prjCfg.RepoRelDir))
+		prjCfg.TerraformVersion = cb.TerraformExecutor.DetectVersion(ctx.Log, prjCfg.TerraformDistribution, filepath.Join(repoDir, prjCfg.RepoRelDir))
 	}
 
 	projectCmds = append(projectCmds, newProjectCommandContext(
 		ctx,
 		cmdName,
 		cb.CommentBuilder.BuildApplyComment(prjCfg.RepoRelDir, prjCfg.Workspace, prjCfg.Name, prjCfg.AutoMergeDisabled),
-		cb.CommentBuilder.BuildPlanComment(prjCfg.RepoRelDir, prjCfg.Workspace, prjCfg.Name, commentFlags),
+		cb.CommentBuilder.BuildPlanComment(prjCfg.RepoRelDir, prjCfg.Workspace, prjCfg.Name, planCommentFlags),
 		cb.CommentBuilder.BuildVersionComment(prjCfg.RepoRelDir, prjCfg.Workspace, prjCfg.Name),
 		prjCfg,
 		steps,
@@ -93,6 +114,7 @@ func (cb *DefaultProjectCommandContextBuilder) BuildProjectContext(
 type PolicyCheckProjectCommandContextBuilder struct {
 	ProjectCommandContextBuilder *DefaultProjectCommandContextBuilder
 	CommentBuilder               CommentBuilder
+	TerraformExecutor            terraform.Client
 }
 
 func (cb *PolicyCheckProjectCommandContextBuilder) BuildProjectContext(
@@ -108,7 +130,7 @@ func (cb *PolicyCheckProjectCommandContextBuilder) BuildProjectContext(
 	// If TerraformVersion not defined in config file look for a
 	// terraform.require_version block.
 	if prjCfg.TerraformVersion == nil {
-		prjCfg.TerraformVersion = getTfVersion(ctx, filepath.Join(repoDir, prjCfg.RepoRelDir))
+		prjCfg.TerraformVersion = cb.TerraformExecutor.DetectVersion(ctx.Log, prjCfg.TerraformDistribution, filepath.Join(repoDir, prjCfg.RepoRelDir))
 	}
 
 	projectCmds = cb.ProjectCommandContextBuilder.BuildProjectContext(
@@ -168,21 +190,36 @@ func newProjectCommandContext(ctx *CommandContext,
 ) models.ProjectCommandContext {
 
 	var projectPlanStatus models.ProjectPlanStatus
+	var planFromEarlierCommit bool
+	var plannedTargets []string
 
 	if ctx.PullStatus != nil {
+		// The pull status is reset when the pull request's head commit
+		// changes so if this project isn't in it, it hasn't been planned at
+		// the current commit.
+		found := false
 		for _, project := range ctx.PullStatus.Projects {
 
 			// if name is not used, let's match the directory
 			if projCfg.Name == "" && project.RepoRelDir == projCfg.RepoRelDir {
 				projectPlanStatus = project.Status
+				plannedTargets = project.Targets
+				found = true
 				break
 			}
 
 			if projCfg.Name != "" && project.ProjectName == projCfg.Name {
 				projectPlanStatus = project.Status
+				plannedTargets = project.Targets
+				found = true
 				break
 			}
 		}
+		planFromEarlierCommit = !found || ctx.PullStatus.Pull.HeadCommit != ctx.Pull.HeadCommit
+	}
+
+	if projCfg.Automerge != nil {
+		automergeEnabled = *projCfg.Automerge
 	}
 
 	return models.ProjectCommandContext{
@@ -191,6 +228,8 @@ func newProjectCommandContext(ctx *CommandContext,
 		BaseRepo:                  ctx.Pull.BaseRepo,
 		EscapedCommentArgs:        escapedCommentArgs,
 		AutomergeEnabled:          automergeEnabled,
+		AutomergeMethod:           projCfg.AutomergeMethod,
+		AutomergeCommitMessage:    projCfg.AutomergeCommitMessage,
 		DeleteSourceBranchOnMerge: deleteSourceBranchOnMerge,
 		ParallelApplyEnabled:      parallelApplyEnabled,
 		ParallelPlanEnabled:       parallelPlanEnabled,
@@ -203,17 +242,74 @@ func newProjectCommandContext(ctx *CommandContext,
 		Pull:                      ctx.Pull,
 		ProjectName:               projCfg.Name,
 		ApplyRequirements:         projCfg.ApplyRequirements,
+		CustomApplyRequirements:   projCfg.CustomApplyReqs,
 		RePlanCmd:                 planCmd,
 		RepoRelDir:                projCfg.RepoRelDir,
 		RepoConfigVersion:         projCfg.RepoCfgVersion,
 		TerraformVersion:          projCfg.TerraformVersion,
+		TerraformDistribution:     projCfg.TerraformDistribution,
+		TFEWorkspace:              projCfg.TFEWorkspace,
 		User:                      ctx.User,
 		Verbose:                   verbose,
 		Workspace:                 projCfg.Workspace,
 		PolicySets:                policySets,
+		AllowDestroyPlans:         projCfg.AllowDestroyPlans,
+		NoWorkspaceAutocreate:     projCfg.NoWorkspaceAutocreate,
+		DisableTargeting:          projCfg.DisableTargeting,
+		LockGranularity:           projCfg.LockGranularity,
+		PlanFromEarlierCommit:     planFromEarlierCommit,
+		PlannedTargets:            plannedTargets,
+		Credentials:               projCfg.Credentials,
+		InitOptions:               projCfg.Init,
+		InitSteps:                 initSteps(projCfg.Workflow.Plan),
+		Outputs:                   projCfg.Outputs,
+		RequestCtx:                ctx.RequestCtx,
 	}
 }
 
+// stateSteps returns the steps to run for a command that modifies state, ex.
+// import. These aren't configurable workflow stages so we run init the same
+// way the plan stage does, ex. with the same -backend-config args, followed by
+// the step named stepName.
+func stateSteps(planStage valid.Stage, stepName string) []valid.Step {
+	initStep := valid.Step{StepName: "init"}
+	for _, step := range planStage.Steps {
+		if step.StepName == "init" {
+			initStep = step
+			break
+		}
+	}
+	return []valid.Step{initStep, {StepName: stepName}}
+}
+
+// initSteps returns the init steps of the plan stage. Custom workflows that
+// init with run steps have none.
+func initSteps(planStage valid.Stage) []valid.Step {
+	var steps []valid.Step
+	for _, step := range planStage.Steps {
+		if step.StepName == "init" {
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+// withOutputStep returns the apply steps with an output step at the end if
+// the project lists outputs to show and the steps don't already have one, so
+// outputs can be shown without a custom workflow.
+func withOutputStep(applySteps []valid.Step, outputs []string) []valid.Step {
+	if len(outputs) == 0 {
+		return applySteps
+	}
+	for _, step := range applySteps {
+		if step.StepName == "output" {
+			return applySteps
+		}
+	}
+	steps := append([]valid.Step{}, applySteps...)
+	return append(steps, valid.Step{StepName: "output"})
+}
+
 func escapeArgs(args []string) []string {
 	var escaped []string
 	for _, arg := range args {
@@ -225,36 +321,3 @@ func escapeArgs(args []string) []string {
 	}
 	return escaped
 }
-
-// Extracts required_version from Terraform configuration.
-// Returns nil if unable to determine version from configuration.
-func getTfVersion(ctx *CommandContext, absProjDir string) *version.Version {
-	module, diags := tfconfig.LoadModule(absProjDir)
-	if diags.HasErrors() {
-		ctx.Log.Err("trying to detect required version: %s", diags.Error())
-		return nil
-	}
-
-	if len(module.RequiredCore) != 1 {
-		ctx.Log.Info("cannot determine which version to use from terraform configuration, detected %d possibilities.", len(module.RequiredCore))
-		return nil
-	}
-	requiredVersionSetting := module.RequiredCore[0]
-
-	// We allow `= x.y.z`, `=x.y.z` or `x.y.z` where `x`, `y` and `z` are integers.
-	re := regexp.MustCompile(`^=?\s*([^\s]+)\s*$`)
-	matched := re.FindStringSubmatch(requiredVersionSetting)
-	if len(matched) == 0 {
-		ctx.Log.Debug("did not specify exact version in terraform configuration, found %q", requiredVersionSetting)
-		return nil
-	}
-	ctx.Log.Debug("found required_version setting of %q", requiredVersionSetting)
-	version, err := version.NewVersion(matched[1])
-	if err != nil {
-		ctx.Log.Debug(err.Error())
-		return nil
-	}
-
-	ctx.Log.Info("detected module requires version: %q", version.String())
-	return version
-}
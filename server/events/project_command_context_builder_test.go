@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	. "github.com/petergtz/pegomock"
+	tmocks "github.com/runatlantis/atlantis/server/core/terraform/mocks"
 	"github.com/runatlantis/atlantis/server/events"
 	"github.com/runatlantis/atlantis/server/events/mocks"
 	"github.com/runatlantis/atlantis/server/events/models"
@@ -16,7 +17,8 @@ func TestProjectCommandContextBuilder_PullStatus(t *testing.T) {
 
 	mockCommentBuilder := mocks.NewMockCommentBuilder()
 	subject := events.DefaultProjectCommandContextBuilder{
-		CommentBuilder: mockCommentBuilder,
+		CommentBuilder:    mockCommentBuilder,
+		TerraformExecutor: tmocks.NewMockClient(),
 	}
 
 	projRepoRelDir := "dir1"
@@ -102,4 +104,41 @@ func TestProjectCommandContextBuilder_PullStatus(t *testing.T) {
 		assert.True(t, result[0].ParallelApplyEnabled)
 		assert.False(t, result[0].ParallelPlanEnabled)
 	})
+
+	t.Run("when the project was planned at an earlier commit", func(t *testing.T) {
+		projCfg.Name = ""
+		commandCtx.Pull = models.PullRequest{HeadCommit: "new"}
+		pullStatus.Pull = models.PullRequest{HeadCommit: "new"}
+		pullStatus.Projects = []models.ProjectStatus{
+			{
+				Status:     models.PlannedPlanStatus,
+				RepoRelDir: "dir1",
+			},
+		}
+
+		result := subject.BuildProjectContext(commandCtx, models.ApplyCommand, projCfg, []string{}, "some/dir", false, false, false, false, false)
+		assert.False(t, result[0].PlanFromEarlierCommit)
+
+		pullStatus.Projects[0].RepoRelDir = "dir2"
+		result = subject.BuildProjectContext(commandCtx, models.ApplyCommand, projCfg, []string{}, "some/dir", false, false, false, false, false)
+		assert.True(t, result[0].PlanFromEarlierCommit)
+
+		pullStatus.Projects[0].RepoRelDir = "dir1"
+		pullStatus.Pull.HeadCommit = "old"
+		result = subject.BuildProjectContext(commandCtx, models.ApplyCommand, projCfg, []string{}, "some/dir", false, false, false, false, false)
+		assert.True(t, result[0].PlanFromEarlierCommit)
+	})
+	t.Run("when the project lists outputs", func(t *testing.T) {
+		pullStatus.Projects = nil
+		result := subject.BuildProjectContext(commandCtx, models.ApplyCommand, projCfg, []string{}, "some/dir", false, false, false, false, false)
+		assert.Equal(t, valid.DefaultApplyStage.Steps, result[0].Steps)
+
+		projCfg.Outputs = []string{"endpoint"}
+		result = subject.BuildProjectContext(commandCtx, models.ApplyCommand, projCfg, []string{}, "some/dir", false, false, false, false, false)
+		assert.Equal(t, []valid.Step{{StepName: "apply"}, {StepName: "output"}}, result[0].Steps)
+		assert.Equal(t, []string{"endpoint"}, result[0].Outputs)
+
+		// The default workflow isn't changed.
+		assert.Equal(t, []valid.Step{{StepName: "apply"}}, valid.DefaultApplyStage.Steps)
+	})
 }
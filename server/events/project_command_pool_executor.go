@@ -1,10 +1,12 @@
 package events
 
 import (
+	"errors"
 	"sync"
 
 	"github.com/remeh/sizedwaitgroup"
 	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/tracing"
 )
 
 type prjCmdRunnerFunc func(ctx models.ProjectCommandContext) models.ProjectResult
@@ -25,7 +27,7 @@ func runProjectCmdsParallel(
 
 		execute = func() {
 			defer wg.Done()
-			res := runnerFunc(pCmd)
+			res := runProjectCmd(pCmd, runnerFunc)
 			mux.Lock()
 			results = append(results, res)
 			mux.Unlock()
@@ -44,9 +46,27 @@ func runProjectCmds(
 ) CommandResult {
 	var results []models.ProjectResult
 	for _, pCmd := range cmds {
-		res := runnerFunc(pCmd)
+		res := runProjectCmd(pCmd, runnerFunc)
 
 		results = append(results, res)
 	}
 	return CommandResult{ProjectResults: results}
 }
+
+// runProjectCmd runs ctx's command in a span of its own so that each
+// project's clone and steps are grouped together in traces.
+func runProjectCmd(ctx models.ProjectCommandContext, runnerFunc prjCmdRunnerFunc) models.ProjectResult {
+	ctx, span := startProjectSpan(ctx, "project",
+		tracing.CommandKey.String(ctx.CommandName.String()),
+		tracing.ProjectKey.String(ctx.ProjectName),
+		tracing.DirKey.String(ctx.RepoRelDir),
+		tracing.WorkspaceKey.String(ctx.Workspace),
+	)
+	res := runnerFunc(ctx)
+	err := res.Error
+	if err == nil && res.Failure != "" {
+		err = errors.New(res.Failure)
+	}
+	tracing.End(span, err)
+	return res
+}
@@ -14,18 +14,28 @@
 package events
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/core/planstorage"
 	"github.com/runatlantis/atlantis/server/core/runtime"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/webhooks"
 	"github.com/runatlantis/atlantis/server/events/yaml/raw"
 	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/i18n"
 	"github.com/runatlantis/atlantis/server/logging"
+	"github.com/runatlantis/atlantis/server/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // DirNotExistErr is an error caused by the directory not existing.
@@ -46,6 +56,16 @@ type LockURLGenerator interface {
 	GenerateLockURL(lockID string) string
 }
 
+// HistoryURLGenerator generates urls to the command history page.
+type HistoryURLGenerator interface {
+	// GenerateHistoryURL returns the full URL to the history of pullNum in
+	// repoFullName.
+	GenerateHistoryURL(repoFullName string, pullNum int) string
+	// GenerateProjectHistoryURL returns the full URL to the history of the
+	// project at repoRelDir and workspace in pullNum.
+	GenerateProjectHistoryURL(repoFullName string, pullNum int, repoRelDir string, workspace string) string
+}
+
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_step_runner.go StepRunner
 
 // StepRunner runs steps. Steps are individual pieces of execution like
@@ -70,6 +90,23 @@ type EnvStepRunner interface {
 	Run(ctx models.ProjectCommandContext, cmd string, value string, path string, envs map[string]string) (string, error)
 }
 
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_multienv_step_runner.go MultiEnvStepRunner
+
+// MultiEnvStepRunner runs multienv steps.
+type MultiEnvStepRunner interface {
+	// Run cmd in path and return the environment variables it output.
+	Run(ctx models.ProjectCommandContext, cmd string, path string, envs map[string]string) (map[string]string, error)
+}
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_scan_step_runner.go ScanStepRunner
+
+// ScanStepRunner runs scan steps.
+type ScanStepRunner interface {
+	// Run runs the scanner in opts on path and saves its findings to the
+	// project's scan results file.
+	Run(ctx models.ProjectCommandContext, opts valid.ScanOptions, extraArgs []string, path string, envs map[string]string) (string, error)
+}
+
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_webhooks_sender.go WebhooksSender
 
 // WebhooksSender sends webhook.
@@ -78,6 +115,14 @@ type WebhooksSender interface {
 	Send(log logging.SimpleLogging, res webhooks.ApplyResult) error
 }
 
+// CredentialsProvider obtains the cloud credentials that projects configure,
+// ex. by assuming an AWS role.
+type CredentialsProvider interface {
+	// Env returns the env vars holding the credentials configured for ctx,
+	// or nil if it doesn't configure any.
+	Env(ctx models.ProjectCommandContext) (map[string]string, error)
+}
+
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_project_command_runner.go ProjectCommandRunner
 
 type ProjectPlanCommandRunner interface {
@@ -105,6 +150,26 @@ type ProjectVersionCommandRunner interface {
 	Version(ctx models.ProjectCommandContext) models.ProjectResult
 }
 
+type ProjectImportCommandRunner interface {
+	// Import runs terraform import for the project described by ctx.
+	Import(ctx models.ProjectCommandContext) models.ProjectResult
+}
+
+type ProjectStateCommandRunner interface {
+	// State runs terraform state rm or mv for the project described by ctx.
+	State(ctx models.ProjectCommandContext) models.ProjectResult
+}
+
+type ProjectValidateCommandRunner interface {
+	// Validate runs terraform validate for the project described by ctx.
+	Validate(ctx models.ProjectCommandContext) models.ProjectResult
+}
+
+type ProjectCustomCommandRunner interface {
+	// Custom runs the custom command described by ctx.
+	Custom(ctx models.ProjectCommandContext) models.ProjectResult
+}
+
 // ProjectCommandRunner runs project commands. A project command is a command
 // for a specific TF project.
 type ProjectCommandRunner interface {
@@ -113,30 +178,91 @@ type ProjectCommandRunner interface {
 	ProjectPolicyCheckCommandRunner
 	ProjectApprovePoliciesCommandRunner
 	ProjectVersionCommandRunner
+	ProjectImportCommandRunner
+	ProjectStateCommandRunner
+	ProjectValidateCommandRunner
+	ProjectCustomCommandRunner
 }
 
 // DefaultProjectCommandRunner implements ProjectCommandRunner.
 type DefaultProjectCommandRunner struct {
-	Locker                ProjectLocker
-	LockURLGenerator      LockURLGenerator
+	Locker           ProjectLocker
+	LockURLGenerator LockURLGenerator
+	// HistoryURLGenerator is used to link webhooks to the command output.
+	// It's optional.
+	HistoryURLGenerator   HistoryURLGenerator
 	InitStepRunner        StepRunner
 	PlanStepRunner        StepRunner
 	ShowStepRunner        StepRunner
 	ApplyStepRunner       StepRunner
 	PolicyCheckStepRunner StepRunner
 	VersionStepRunner     StepRunner
+	ImportStepRunner      StepRunner
+	StateStepRunner       StepRunner
+	ValidateStepRunner    StepRunner
+	FmtStepRunner         StepRunner
+	TerragruntStepRunner  StepRunner
+	OutputStepRunner      StepRunner
 	RunStepRunner         CustomStepRunner
 	EnvStepRunner         EnvStepRunner
+	MultiEnvStepRunner    MultiEnvStepRunner
+	ScanStepRunner        ScanStepRunner
 	PullApprovedChecker   runtime.PullApprovedChecker
+	CodeownersChecker     CodeownersChecker
 	WorkingDir            WorkingDir
 	Webhooks              WebhooksSender
 	WorkingDirLocker      WorkingDirLocker
+	// ApplyQueue serializes applies for the same project across pull
+	// requests. If it's nil, applies aren't queued.
+	ApplyQueue ApplyQueue
+	// LockQueue queues plans that are blocked by another pull request's lock
+	// so they run once it's released. If it's nil, plans aren't queued.
+	LockQueue LockQueue
+	// RestoreWorkingDirOnApply causes applies to re-clone and init the
+	// project if its working dir was lost, so that planfiles from remote plan
+	// storage can be applied.
+	RestoreWorkingDirOnApply bool
+	// PlanStorage, if set, is where planfiles are downloaded from before
+	// apply if they're not found locally.
+	PlanStorage planstorage.PlanStorage
+	// StructuredPlanOutput causes plans to be rendered from the terraform show
+	// result written by the plan step instead of from the raw plan output.
+	StructuredPlanOutput bool
+	// CommitStatusUpdater, if set, is used to give each project its own commit
+	// status for plan, policy check and apply, in addition to the combined
+	// statuses set for the whole command.
+	CommitStatusUpdater CommitStatusUpdater
+	// Deployments, if set, is used to record each apply as a deployment.
+	Deployments *DeploymentsUpdater
+	// CredentialsProvider obtains the credentials of projects that configure
+	// them before their steps are run.
+	CredentialsProvider CredentialsProvider
+	// StateLocks, if set, counts the plans and applies that failed because
+	// terraform couldn't acquire the state lock.
+	StateLocks *StateLockTracker
+	// MaxStepRetries is how many times init and plan steps are run again when
+	// they fail with a transient error. If it's 0, they aren't retried.
+	MaxStepRetries int
+	// StepRetryBackoff is how long to wait before the first retry of a step.
+	// The wait doubles after each retry.
+	StepRetryBackoff time.Duration
+	// Localizer picks the locale of failures. If it's nil they're in
+	// English.
+	Localizer *i18n.Localizer
+}
+
+// targetingDisabledFailure is returned when a project is planned or applied
+// with -target but its repo sets disable_targeting.
+func (p *DefaultProjectCommandRunner) targetingDisabledFailure(ctx models.ProjectCommandContext) string {
+	return p.Localizer.Sprintf(ctx.Pull.BaseRepo, ctx.Pull.BaseBranch, i18n.TargetingDisabled, valid.DisableTargetingKey)
 }
 
 // Plan runs terraform plan for the project described by ctx.
 func (p *DefaultProjectCommandRunner) Plan(ctx models.ProjectCommandContext) models.ProjectResult {
-	planSuccess, failure, err := p.doPlan(ctx)
-	return models.ProjectResult{
+	start := time.Now()
+	p.updateProjectStatus(ctx, models.PlanCommand, models.PendingCommitStatus)
+	planSuccess, failure, queued, createdLock, err := p.doPlan(ctx)
+	result := models.ProjectResult{
 		Command:     models.PlanCommand,
 		PlanSuccess: planSuccess,
 		Error:       err,
@@ -144,13 +270,26 @@ func (p *DefaultProjectCommandRunner) Plan(ctx models.ProjectCommandContext) mod
 		RepoRelDir:  ctx.RepoRelDir,
 		Workspace:   ctx.Workspace,
 		ProjectName: ctx.ProjectName,
+		Targets:     ctx.TargetAddresses(),
+		CreatedLock: createdLock,
+	}
+	// Queued plans stay pending until the queue re-runs them.
+	if !queued {
+		p.updateProjectResult(ctx, result)
 	}
+	// Only send webhooks for plans that ran, not ones that were blocked by
+	// a lock.
+	if p.Webhooks != nil && (planSuccess != nil || err != nil) {
+		p.Webhooks.Send(ctx.Log, p.webhookResult(ctx, webhooks.PlanEvent, err == nil, time.Since(start))) // nolint: errcheck
+	}
+	return result
 }
 
 // PolicyCheck evaluates policies defined with Rego for the project described by ctx.
 func (p *DefaultProjectCommandRunner) PolicyCheck(ctx models.ProjectCommandContext) models.ProjectResult {
+	p.updateProjectStatus(ctx, models.PolicyCheckCommand, models.PendingCommitStatus)
 	policySuccess, failure, err := p.doPolicyCheck(ctx)
-	return models.ProjectResult{
+	result := models.ProjectResult{
 		Command:            models.PolicyCheckCommand,
 		PolicyCheckSuccess: policySuccess,
 		Error:              err,
@@ -159,12 +298,15 @@ func (p *DefaultProjectCommandRunner) PolicyCheck(ctx models.ProjectCommandConte
 		Workspace:          ctx.Workspace,
 		ProjectName:        ctx.ProjectName,
 	}
+	p.updateProjectResult(ctx, result)
+	return result
 }
 
 // Apply runs terraform apply for the project described by ctx.
 func (p *DefaultProjectCommandRunner) Apply(ctx models.ProjectCommandContext) models.ProjectResult {
-	applyOut, failure, err := p.doApply(ctx)
-	return models.ProjectResult{
+	p.updateProjectStatus(ctx, models.ApplyCommand, models.PendingCommitStatus)
+	applyOut, tfOutputs, failure, queued, err := p.doApply(ctx)
+	result := models.ProjectResult{
 		Command:      models.ApplyCommand,
 		Failure:      failure,
 		Error:        err,
@@ -172,11 +314,21 @@ func (p *DefaultProjectCommandRunner) Apply(ctx models.ProjectCommandContext) mo
 		RepoRelDir:   ctx.RepoRelDir,
 		Workspace:    ctx.Workspace,
 		ProjectName:  ctx.ProjectName,
+		ApplyOutputs: tfOutputs,
+		Targets:      ctx.PlannedTargets,
+	}
+	// Queued applies stay pending until the queue re-runs them.
+	if !queued {
+		p.updateProjectResult(ctx, result)
 	}
+	return result
 }
 
 func (p *DefaultProjectCommandRunner) ApprovePolicies(ctx models.ProjectCommandContext) models.ProjectResult {
 	approvedOut, failure, err := p.doApprovePolicies(ctx)
+	if err == nil && failure == "" {
+		p.updateProjectStatus(ctx, models.PolicyCheckCommand, models.SuccessCommitStatus)
+	}
 	return models.ProjectResult{
 		Command:            models.PolicyCheckCommand,
 		Failure:            failure,
@@ -201,13 +353,89 @@ func (p *DefaultProjectCommandRunner) Version(ctx models.ProjectCommandContext)
 	}
 }
 
+// Import runs terraform import for the project described by ctx.
+func (p *DefaultProjectCommandRunner) Import(ctx models.ProjectCommandContext) models.ProjectResult {
+	var importSuccess *models.ImportSuccess
+	output, failure, err := p.doStateChange(ctx)
+	if failure == "" && err == nil {
+		importSuccess = &models.ImportSuccess{
+			Output:    output,
+			RePlanCmd: ctx.RePlanCmd,
+		}
+	}
+	return models.ProjectResult{
+		Command:       models.ImportCommand,
+		Failure:       failure,
+		Error:         err,
+		ImportSuccess: importSuccess,
+		RepoRelDir:    ctx.RepoRelDir,
+		Workspace:     ctx.Workspace,
+		ProjectName:   ctx.ProjectName,
+	}
+}
+
+// State runs terraform state rm or mv for the project described by ctx.
+func (p *DefaultProjectCommandRunner) State(ctx models.ProjectCommandContext) models.ProjectResult {
+	var stateSuccess *models.StateSuccess
+	output, failure, err := p.doStateChange(ctx)
+	if failure == "" && err == nil {
+		stateSuccess = &models.StateSuccess{
+			Output:    output,
+			RePlanCmd: ctx.RePlanCmd,
+		}
+	}
+	return models.ProjectResult{
+		Command:      models.StateCommand,
+		Failure:      failure,
+		Error:        err,
+		StateSuccess: stateSuccess,
+		RepoRelDir:   ctx.RepoRelDir,
+		Workspace:    ctx.Workspace,
+		ProjectName:  ctx.ProjectName,
+	}
+}
+
+// Validate runs terraform validate, and terraform fmt -check if requested, for
+// the project described by ctx.
+func (p *DefaultProjectCommandRunner) Validate(ctx models.ProjectCommandContext) models.ProjectResult {
+	validateOut, failure, err := p.doValidate(ctx)
+	return models.ProjectResult{
+		Command:         models.ValidateCommand,
+		Failure:         failure,
+		Error:           err,
+		ValidateSuccess: validateOut,
+		RepoRelDir:      ctx.RepoRelDir,
+		Workspace:       ctx.Workspace,
+		ProjectName:     ctx.ProjectName,
+	}
+}
+
+// Custom runs the steps of the custom command described by ctx. The steps
+// can do anything, ex. deploy, so like import it holds the project lock.
+func (p *DefaultProjectCommandRunner) Custom(ctx models.ProjectCommandContext) models.ProjectResult {
+	var customSuccess *models.CustomSuccess
+	output, failure, err := p.doStateChange(ctx)
+	if failure == "" && err == nil {
+		customSuccess = &models.CustomSuccess{Output: output}
+	}
+	return models.ProjectResult{
+		Command:       models.CustomCommand,
+		Failure:       failure,
+		Error:         err,
+		CustomSuccess: customSuccess,
+		RepoRelDir:    ctx.RepoRelDir,
+		Workspace:     ctx.Workspace,
+		ProjectName:   ctx.ProjectName,
+	}
+}
+
 func (p *DefaultProjectCommandRunner) doApprovePolicies(ctx models.ProjectCommandContext) (*models.PolicyCheckSuccess, string, error) {
 
 	// TODO: Make this a bit smarter
 	// without checking some sort of state that the policy check has indeed passed this is likely to cause issues
 
 	return &models.PolicyCheckSuccess{
-		PolicyCheckOutput: "Policies approved",
+		PolicyCheckOutput: p.Localizer.Sprintf(ctx.Pull.BaseRepo, ctx.Pull.BaseBranch, i18n.PoliciesApproved),
 	}, "", nil
 }
 
@@ -218,7 +446,7 @@ func (p *DefaultProjectCommandRunner) doPolicyCheck(ctx models.ProjectCommandCon
 	// we will attempt to capture the lock here but fail to get the working directory
 	// at which point we will unlock again to preserve functionality
 	// If we fail to capture the lock here (super unlikely) then we error out and the user is forced to replan
-	lockAttempt, err := p.Locker.TryLock(ctx.Log, ctx.Pull, ctx.User, ctx.Workspace, models.NewProject(ctx.Pull.BaseRepo.FullName, ctx.RepoRelDir))
+	lockAttempt, err := p.lockProject(ctx)
 
 	if err != nil {
 		return nil, "", errors.Wrap(err, "acquiring lock")
@@ -282,43 +510,109 @@ func (p *DefaultProjectCommandRunner) doPolicyCheck(ctx models.ProjectCommandCon
 	}, "", nil
 }
 
-func (p *DefaultProjectCommandRunner) doPlan(ctx models.ProjectCommandContext) (*models.PlanSuccess, string, error) {
+// lockProject tries to lock ctx's project for its pull request with the
+// project's lock granularity.
+func (p *DefaultProjectCommandRunner) lockProject(ctx models.ProjectCommandContext) (*TryLockResponse, error) {
+	project := models.NewProject(ctx.Pull.BaseRepo.FullName, ctx.RepoRelDir)
+	project.ProjectName = ctx.ProjectName
+	return p.Locker.TryLock(ctx.Log, ctx.Pull, ctx.User, ctx.Workspace, project, ctx.LockGranularity)
+}
+
+// doPlan plans ctx's project. Along with the result, it returns the key of
+// the project lock it created, if it created one and still holds it.
+func (p *DefaultProjectCommandRunner) doPlan(ctx models.ProjectCommandContext) (*models.PlanSuccess, string, bool, string, error) {
+	// Check this before the extra args are passed to terraform so -destroy
+	// can't be used to get around the server-side config.
+	if ctx.IsDestroyPlan() && !ctx.AllowDestroyPlans {
+		return nil, p.Localizer.Sprintf(ctx.Pull.BaseRepo, ctx.Pull.BaseBranch, i18n.DestroyPlansNotAllowed, valid.AllowDestroyPlansKey), false, "", nil
+	}
+	if ctx.DisableTargeting && len(ctx.TargetAddresses()) > 0 {
+		return nil, p.targetingDisabledFailure(ctx), false, "", nil
+	}
+
 	// Acquire Atlantis lock for this repo/dir/workspace.
-	lockAttempt, err := p.Locker.TryLock(ctx.Log, ctx.Pull, ctx.User, ctx.Workspace, models.NewProject(ctx.Pull.BaseRepo.FullName, ctx.RepoRelDir))
+	lockAttempt, err := p.lockProject(ctx)
 	if err != nil {
-		return nil, "", errors.Wrap(err, "acquiring lock")
+		return nil, "", false, "", errors.Wrap(err, "acquiring lock")
 	}
 	if !lockAttempt.LockAcquired {
-		return nil, lockAttempt.LockFailureReason, nil
+		if p.LockQueue != nil {
+			position := p.LockQueue.Wait(lockAttempt.CurrLock, ctx)
+			return nil, p.Localizer.Sprintf(ctx.Pull.BaseRepo, ctx.Pull.BaseBranch, i18n.LockQueued, lockAttempt.CurrLock.Pull.Num, position), true, "", nil
+		}
+		return nil, lockAttempt.LockFailureReason, false, "", nil
 	}
 	ctx.Log.Debug("acquired lock for project")
+	var createdLock string
+	if lockAttempt.LockCreated {
+		createdLock = lockAttempt.LockKey
+	}
 
 	// Acquire internal lock for the directory we're going to operate in.
 	unlockFn, err := p.WorkingDirLocker.TryLock(ctx.Pull.BaseRepo.FullName, ctx.Pull.Num, ctx.Workspace)
 	if err != nil {
-		return nil, "", err
+		return nil, "", false, createdLock, err
 	}
 	defer unlockFn()
 
 	// Clone is idempotent so okay to run even if the repo was already cloned.
-	repoDir, hasDiverged, cloneErr := p.WorkingDir.Clone(ctx.Log, ctx.HeadRepo, ctx.Pull, ctx.Workspace)
+	repoDir, hasDiverged, cloneErr := p.clone(ctx)
 	if cloneErr != nil {
 		if unlockErr := lockAttempt.UnlockFn(); unlockErr != nil {
 			ctx.Log.Err("error unlocking state after plan error: %v", unlockErr)
 		}
-		return nil, "", cloneErr
+		return nil, "", false, "", cloneErr
 	}
 	projAbsPath := filepath.Join(repoDir, ctx.RepoRelDir)
 	if _, err = os.Stat(projAbsPath); os.IsNotExist(err) {
-		return nil, "", DirNotExistErr{RepoRelDir: ctx.RepoRelDir}
+		return nil, "", false, createdLock, DirNotExistErr{RepoRelDir: ctx.RepoRelDir}
 	}
 
-	outputs, err := p.runSteps(ctx.Steps, ctx, projAbsPath)
+	showFile := filepath.Join(projAbsPath, ctx.GetShowResultFileName())
+	if p.StructuredPlanOutput {
+		// Remove the result of a previous plan so we never render a stale
+		// structured plan.
+		if err := os.Remove(showFile); err != nil && !os.IsNotExist(err) {
+			return nil, "", false, createdLock, errors.Wrap(err, "removing previous terraform show result")
+		}
+	}
+
+	scanFile := filepath.Join(projAbsPath, ctx.GetScanResultsFileName())
+	if err := os.Remove(scanFile); err != nil && !os.IsNotExist(err) {
+		return nil, "", false, createdLock, errors.Wrap(err, "removing previous scan results")
+	}
+
+	outputs, retries, err := p.runStepsWithRetries(ctx.Steps, ctx, projAbsPath)
 	if err != nil {
 		if unlockErr := lockAttempt.UnlockFn(); unlockErr != nil {
 			ctx.Log.Err("error unlocking state after plan error: %v", unlockErr)
 		}
-		return nil, "", fmt.Errorf("%s\n%s", err, strings.Join(outputs, "\n"))
+		if failure, ok := p.stateLockFailure(ctx, models.PlanCommand, err, outputs); ok {
+			return nil, failure, false, "", nil
+		}
+		return nil, "", false, "", fmt.Errorf("%s\n%s", err, strings.Join(outputs, "\n"))
+	}
+
+	scanResults, err := readScanResults(scanFile)
+	if err != nil {
+		return nil, "", false, createdLock, err
+	}
+	if failure, ok := p.scanFailure(ctx, scanResults); ok {
+		// The plan is deleted so it can't be applied until the findings are
+		// fixed.
+		planFile := filepath.Join(projAbsPath, runtime.GetPlanFilename(ctx.Workspace, ctx.ProjectName))
+		if err := os.Remove(planFile); err != nil && !os.IsNotExist(err) {
+			return nil, "", false, createdLock, errors.Wrap(err, "deleting plan that failed the security scan")
+		}
+		if unlockErr := lockAttempt.UnlockFn(); unlockErr != nil {
+			ctx.Log.Err("error unlocking state after security scan failure: %v", unlockErr)
+		}
+		return nil, failure, false, "", nil
+	}
+
+	var structuredPlan *models.StructuredPlan
+	if p.StructuredPlanOutput {
+		structuredPlan = p.readStructuredPlan(ctx, showFile)
 	}
 
 	return &models.PlanSuccess{
@@ -327,67 +621,378 @@ func (p *DefaultProjectCommandRunner) doPlan(ctx models.ProjectCommandContext) (
 		RePlanCmd:       ctx.RePlanCmd,
 		ApplyCmd:        ctx.ApplyCmd,
 		HasDiverged:     hasDiverged,
-	}, "", nil
+		StructuredPlan:  structuredPlan,
+		Destroy:         ctx.IsDestroyPlan(),
+		Retries:         retries,
+		ScanResults:     scanResults,
+	}, "", false, createdLock, nil
 }
 
-func (p *DefaultProjectCommandRunner) doApply(ctx models.ProjectCommandContext) (applyOut string, failure string, err error) {
+// readStructuredPlan parses the terraform show result written by the plan
+// step. It returns nil if there is no result, ex. because the workflow
+// doesn't use the plan step, so that the raw plan output is rendered instead.
+func (p *DefaultProjectCommandRunner) readStructuredPlan(ctx models.ProjectCommandContext, showFile string) *models.StructuredPlan {
+	show, err := ioutil.ReadFile(showFile) // nolint: gosec
+	if err != nil {
+		if !os.IsNotExist(err) {
+			ctx.Log.Warn("unable to read terraform show result: %s", err)
+		}
+		return nil
+	}
+	structuredPlan, err := models.NewStructuredPlan(show)
+	if err != nil {
+		ctx.Log.Warn("unable to render structured plan: %s", err)
+		return nil
+	}
+	return structuredPlan
+}
+
+func (p *DefaultProjectCommandRunner) doApply(ctx models.ProjectCommandContext) (applyOut string, tfOutputs []models.TerraformOutput, failure string, queued bool, err error) {
 	repoDir, err := p.WorkingDir.GetWorkingDir(ctx.Pull.BaseRepo, ctx.Pull, ctx.Workspace)
+	restored := false
+	if os.IsNotExist(err) && p.RestoreWorkingDirOnApply {
+		repoDir, err = p.restoreWorkingDir(ctx)
+		restored = err == nil
+	}
 	if err != nil {
 		if os.IsNotExist(err) {
-			return "", "", errors.New("project has not been cloned–did you run plan?")
+			return "", nil, "", false, errors.New("project has not been cloned–did you run plan?")
 		}
-		return "", "", err
+		return "", nil, "", false, err
 	}
 	absPath := filepath.Join(repoDir, ctx.RepoRelDir)
 	if _, err = os.Stat(absPath); os.IsNotExist(err) {
-		return "", "", DirNotExistErr{RepoRelDir: ctx.RepoRelDir}
+		return "", nil, "", false, DirNotExistErr{RepoRelDir: ctx.RepoRelDir}
 	}
 
+	// Acquire internal lock for the directory we're going to operate in. It's
+	// held through the checks below so that a concurrent plan can't change
+	// the planfile they look at.
+	unlockFn, err := p.WorkingDirLocker.TryLock(ctx.Pull.BaseRepo.FullName, ctx.Pull.Num, ctx.Workspace)
+	if err != nil {
+		return "", nil, "", false, err
+	}
+	defer unlockFn()
+
+	// The checks below look at the planfile so it has to be downloaded first
+	// if it's only in plan storage, ex. after a restart.
+	planFile := filepath.Join(absPath, runtime.GetPlanFilename(ctx.Workspace, ctx.ProjectName))
+	if err = planstorage.DownloadIfMissing(p.PlanStorage, ctx, planFile); err != nil {
+		return "", nil, "", false, err
+	}
+
+	// Targeted plans can be applied without --target but if it's given it
+	// must match what the plan was targeted at so it's clear what's being
+	// applied.
+	if ctx.DisableTargeting && (len(ctx.Targets) > 0 || len(ctx.PlannedTargets) > 0) {
+		return "", nil, p.targetingDisabledFailure(ctx), false, nil
+	}
+	if len(ctx.Targets) > 0 && !sameTargets(ctx.Targets, ctx.PlannedTargets) {
+		if len(ctx.PlannedTargets) == 0 {
+			return "", nil, p.Localizer.Sprintf(ctx.Pull.BaseRepo, ctx.Pull.BaseBranch, i18n.PlanNotTargeted), false, nil
+		}
+		return "", nil, p.Localizer.Sprintf(ctx.Pull.BaseRepo, ctx.Pull.BaseBranch, i18n.PlanTargetsDiffer, strings.Join(ctx.PlannedTargets, ", "), strings.Join(ctx.Targets, ", ")), false, nil
+	}
+
+	// A planfile left over from an earlier commit doesn't include the changes
+	// pushed since so applying it could undo them. If there's no planfile the
+	// apply step fails with its usual error telling users to run plan.
+	if ctx.PlanFromEarlierCommit && !ctx.Force {
+		if _, err = os.Stat(planFile); err == nil {
+			return "", nil, p.Localizer.Sprintf(ctx.Pull.BaseRepo, ctx.Pull.BaseBranch, i18n.PlanFromEarlierCommit, ctx.Pull.HeadCommit), false, nil
+		}
+	}
+
+	if failure, err = p.applyRequirementsFailure(ctx, repoDir, absPath, planFile); failure != "" || err != nil {
+		return "", nil, failure, false, err
+	}
+
+	// Wait our turn if another pull request is applying this project. The
+	// queue will re-run this apply once it reaches the front.
+	if p.ApplyQueue != nil {
+		release, position := p.ApplyQueue.Enqueue(ctx)
+		if release == nil {
+			return "", nil, p.Localizer.Sprintf(ctx.Pull.BaseRepo, ctx.Pull.BaseBranch, i18n.ApplyInProgress), false, nil
+		}
+		if position > 0 {
+			return "", nil, p.Localizer.Sprintf(ctx.Pull.BaseRepo, ctx.Pull.BaseBranch, i18n.ApplyQueued, position), true, nil
+		}
+		defer release()
+	}
+
+	if restored {
+		if err = p.initRestoredWorkingDir(ctx, absPath); err != nil {
+			return "", nil, "", false, err
+		}
+	}
+
+	outputsFile := filepath.Join(absPath, ctx.GetOutputsFileName())
+	// Remove the outputs of a previous apply so we never show stale ones.
+	if err := os.Remove(outputsFile); err != nil && !os.IsNotExist(err) {
+		return "", nil, "", false, errors.Wrap(err, "removing previous outputs")
+	}
+
+	deploymentID := p.startDeployment(ctx)
+	start := time.Now()
+	outputs, err := p.runSteps(ctx.Steps, ctx, absPath)
+	p.finishDeployment(ctx, deploymentID, projectCommitStatus("", err))
+	p.Webhooks.Send(ctx.Log, p.webhookResult(ctx, webhooks.ApplyEvent, err == nil, time.Since(start))) // nolint: errcheck
+	if err != nil {
+		if failure, ok := p.stateLockFailure(ctx, models.ApplyCommand, err, outputs); ok {
+			return "", nil, failure, false, nil
+		}
+		return "", nil, "", false, fmt.Errorf("%s\n%s", err, strings.Join(outputs, "\n"))
+	}
+	return strings.Join(outputs, "\n"), p.readOutputs(ctx, outputsFile), "", false, nil
+}
+
+// applyRequirementsFailure returns why the project's apply requirements
+// don't pass, or "" if they all do. They're checked before apply and before
+// custom commands, which can also change state.
+func (p *DefaultProjectCommandRunner) applyRequirementsFailure(ctx models.ProjectCommandContext, repoDir string, absPath string, planFile string) (string, error) {
 	for _, req := range ctx.ApplyRequirements {
 		switch req {
 		case raw.ApprovedApplyRequirement:
-			approved, err := p.PullApprovedChecker.PullIsApproved(ctx.Pull.BaseRepo, ctx.Pull) // nolint: vetshadow
+			approved, err := p.PullApprovedChecker.PullIsApproved(ctx.Pull.BaseRepo, ctx.Pull)
 			if err != nil {
-				return "", "", errors.Wrap(err, "checking if pull request was approved")
+				return "", errors.Wrap(err, "checking if pull request was approved")
 			}
 			if !approved {
-				return "", "Pull request must be approved by at least one person other than the author before running apply.", nil
+				return p.Localizer.Sprintf(ctx.Pull.BaseRepo, ctx.Pull.BaseBranch, i18n.ApplyReqApproved), nil
 			}
 		// this should come before mergeability check since mergeability is a superset of this check.
 		case valid.PoliciesPassedApplyReq:
 			if ctx.ProjectPlanStatus == models.ErroredPolicyCheckStatus {
-				return "", "All policies must pass for project before running apply", nil
+				return p.Localizer.Sprintf(ctx.Pull.BaseRepo, ctx.Pull.BaseBranch, i18n.ApplyReqPoliciesPassed), nil
 			}
 		case raw.MergeableApplyRequirement:
 			if !ctx.PullMergeable {
-				return "", "Pull request must be mergeable before running apply.", nil
+				return p.Localizer.Sprintf(ctx.Pull.BaseRepo, ctx.Pull.BaseBranch, i18n.ApplyReqMergeable), nil
+			}
+		case raw.CodeownersApprovedApplyRequirement:
+			unapproved, err := p.CodeownersChecker.UnapprovedFiles(ctx.Log, ctx.Pull, ctx.RepoRelDir)
+			if err != nil {
+				return "", errors.Wrap(err, "checking if code owners approved")
+			}
+			if len(unapproved) > 0 {
+				return p.Localizer.Sprintf(ctx.Pull.BaseRepo, ctx.Pull.BaseBranch, i18n.ApplyReqCodeowners, strings.Join(unapproved, ", ")), nil
 			}
 		case raw.UnDivergedApplyRequirement:
 			if p.WorkingDir.HasDiverged(ctx.Log, repoDir) {
-				return "", "Default branch must be rebased onto pull request before running apply.", nil
+				return p.Localizer.Sprintf(ctx.Pull.BaseRepo, ctx.Pull.BaseBranch, i18n.ApplyReqUndiverged), nil
+			}
+		default:
+			if maxAge, ok, _ := valid.ParsePlanNewerThanApplyReq(req); ok {
+				// If there's no plan we let the apply step fail with its usual
+				// error telling users to run plan.
+				if info, err := os.Stat(planFile); err == nil && time.Since(info.ModTime()) > maxAge {
+					return p.Localizer.Sprintf(ctx.Pull.BaseRepo, ctx.Pull.BaseBranch, i18n.ApplyReqPlanNewerThan, maxAge), nil
+				}
+				continue
+			}
+			if command, ok := ctx.CustomApplyRequirements[req]; ok {
+				if _, err := p.RunStepRunner.Run(ctx, command, absPath, nil); err != nil {
+					return p.Localizer.Sprintf(ctx.Pull.BaseRepo, ctx.Pull.BaseBranch, i18n.ApplyReqCustom, req, err), nil
+				}
 			}
 		}
 	}
-	// Acquire internal lock for the directory we're going to operate in.
+	return "", nil
+}
+
+// readOutputs returns the outputs written by the output step, or nil if
+// there are none, ex. because the workflow doesn't use the output step.
+func (p *DefaultProjectCommandRunner) readOutputs(ctx models.ProjectCommandContext, outputsFile string) []models.TerraformOutput {
+	contents, err := ioutil.ReadFile(outputsFile) // nolint: gosec
+	if err != nil {
+		if !os.IsNotExist(err) {
+			ctx.Log.Warn("unable to read outputs: %s", err)
+		}
+		return nil
+	}
+	var tfOutputs []models.TerraformOutput
+	if err := json.Unmarshal(contents, &tfOutputs); err != nil {
+		ctx.Log.Warn("unable to parse outputs: %s", err)
+		return nil
+	}
+	return tfOutputs
+}
+
+// readScanResults returns the results written by the scan steps, or nil if
+// there are none, ex. because the workflow doesn't use the scan step. Unlike
+// the other files written by steps, results that can't be read are an error
+// since they could fail the plan.
+func readScanResults(scanFile string) ([]models.ScanResult, error) {
+	contents, err := ioutil.ReadFile(scanFile) // nolint: gosec
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "reading scan results")
+	}
+	var results []models.ScanResult
+	if err := json.Unmarshal(contents, &results); err != nil {
+		return nil, errors.Wrap(err, "parsing scan results")
+	}
+	return results, nil
+}
+
+// scanFailure returns the failure to comment if any scan step found issues
+// at or above the severity it fails on, and false if none did.
+func (p *DefaultProjectCommandRunner) scanFailure(ctx models.ProjectCommandContext, results []models.ScanResult) (string, bool) {
+	var failed []models.ScanResult
+	for _, r := range results {
+		if r.Failed() {
+			failed = append(failed, r)
+		}
+	}
+	if len(failed) == 0 {
+		return "", false
+	}
+	locale := p.Localizer.For(ctx.Pull.BaseRepo, ctx.Pull.BaseBranch)
+	return i18n.Sprintf(locale, i18n.ScanFailed, ctx.RePlanCmd) + "\n\n" + renderScanFindings(locale, failed), true
+}
+
+// stateLockFailure returns the failure to comment if cmdName failed with err
+// because terraform couldn't acquire the project's state lock, and records
+// the conflict. It returns false if the command failed for another reason.
+func (p *DefaultProjectCommandRunner) stateLockFailure(ctx models.ProjectCommandContext, cmdName models.CommandName, err error, outputs []string) (string, bool) {
+	_, timedOut := err.(stepTimeoutErr)
+	conflict, ok := ParseStateLockConflict(fmt.Sprintf("%s\n%s", err, strings.Join(outputs, "\n")), timedOut)
+	if !ok {
+		return "", false
+	}
+	ctx.Log.Warn("%s couldn't acquire the state lock: %s", cmdName.String(), err)
+	if p.StateLocks != nil {
+		p.StateLocks.Record(ctx.Pull.BaseRepo.FullName)
+	}
+	return conflict.Failure(p.Localizer.For(ctx.Pull.BaseRepo, ctx.Pull.BaseBranch), ctx, cmdName), true
+}
+
+// webhookResult builds the result sent to webhooks for event.
+func (p *DefaultProjectCommandRunner) webhookResult(ctx models.ProjectCommandContext, event string, success bool, duration time.Duration) webhooks.ApplyResult {
+	result := webhooks.ApplyResult{
+		Event:       event,
+		Workspace:   ctx.Workspace,
+		User:        ctx.User,
+		Repo:        ctx.Pull.BaseRepo,
+		Pull:        ctx.Pull,
+		Success:     success,
+		Directory:   ctx.RepoRelDir,
+		ProjectName: ctx.ProjectName,
+		Duration:    duration,
+		AutoApply:   ctx.AutoApply,
+	}
+	if p.HistoryURLGenerator != nil {
+		result.LogURL = p.HistoryURLGenerator.GenerateHistoryURL(ctx.Pull.BaseRepo.FullName, ctx.Pull.Num)
+	}
+	return result
+}
+
+// updateProjectStatus sets the commit status of ctx's project if per-project
+// statuses are enabled. Errors are logged since the command's result matters
+// more than its status.
+func (p *DefaultProjectCommandRunner) updateProjectStatus(ctx models.ProjectCommandContext, cmdName models.CommandName, status models.CommitStatus) {
+	// Projects that run in Terraform Cloud/Enterprise set their own statuses
+	// that link to the run.
+	if p.CommitStatusUpdater == nil || ctx.TFEWorkspace != "" {
+		return
+	}
+	if err := p.CommitStatusUpdater.UpdateProject(ctx, cmdName, status, p.projectHistoryURL(ctx)); err != nil {
+		ctx.Log.Warn("unable to update project commit status: %s", err)
+	}
+}
+
+// updateProjectResult sets the commit status of ctx's project from result if
+// per-project statuses are enabled, like updateProjectStatus.
+func (p *DefaultProjectCommandRunner) updateProjectResult(ctx models.ProjectCommandContext, result models.ProjectResult) {
+	if p.CommitStatusUpdater == nil || ctx.TFEWorkspace != "" {
+		return
+	}
+	if err := p.CommitStatusUpdater.UpdateProjectResult(ctx, result, p.projectHistoryURL(ctx)); err != nil {
+		ctx.Log.Warn("unable to update project commit status: %s", err)
+	}
+}
+
+// projectHistoryURL returns the URL to the history of ctx's project that its
+// statuses link to, or an empty string if there's no history page.
+func (p *DefaultProjectCommandRunner) projectHistoryURL(ctx models.ProjectCommandContext) string {
+	if p.HistoryURLGenerator == nil {
+		return ""
+	}
+	return p.HistoryURLGenerator.GenerateProjectHistoryURL(ctx.Pull.BaseRepo.FullName, ctx.Pull.Num, ctx.RepoRelDir, ctx.Workspace)
+}
+
+// startDeployment creates a deployment for the apply of ctx's project if
+// deployments are enabled and returns its id, or 0 if it wasn't created.
+// Errors are logged since failing to record the deployment shouldn't stop the
+// apply.
+func (p *DefaultProjectCommandRunner) startDeployment(ctx models.ProjectCommandContext) int64 {
+	if p.Deployments == nil {
+		return 0
+	}
+	id, err := p.Deployments.Start(ctx, p.deploymentLogURL(ctx))
+	if err != nil {
+		ctx.Log.Warn("unable to create deployment: %s", err)
+	}
+	return id
+}
+
+// finishDeployment sets the state of the deployment created by
+// startDeployment.
+func (p *DefaultProjectCommandRunner) finishDeployment(ctx models.ProjectCommandContext, id int64, status models.CommitStatus) {
+	if p.Deployments == nil || id == 0 {
+		return
+	}
+	if err := p.Deployments.Finish(ctx, id, status, p.deploymentLogURL(ctx)); err != nil {
+		ctx.Log.Warn("unable to update deployment status: %s", err)
+	}
+}
+
+// deploymentLogURL returns the URL that deployments link to for the apply's
+// output.
+func (p *DefaultProjectCommandRunner) deploymentLogURL(ctx models.ProjectCommandContext) string {
+	if p.HistoryURLGenerator != nil {
+		return p.HistoryURLGenerator.GenerateHistoryURL(ctx.Pull.BaseRepo.FullName, ctx.Pull.Num)
+	}
+	return ctx.Pull.URL
+}
+
+// projectCommitStatus returns the commit status for a project command that
+// returned failure and err.
+func projectCommitStatus(failure string, err error) models.CommitStatus {
+	if err != nil || failure != "" {
+		return models.FailedCommitStatus
+	}
+	return models.SuccessCommitStatus
+}
+
+// restoreWorkingDir clones the pull request again for ctx's workspace. It's
+// used when the working dir was lost, ex. after a restart.
+func (p *DefaultProjectCommandRunner) restoreWorkingDir(ctx models.ProjectCommandContext) (string, error) {
 	unlockFn, err := p.WorkingDirLocker.TryLock(ctx.Pull.BaseRepo.FullName, ctx.Pull.Num, ctx.Workspace)
 	if err != nil {
-		return "", "", err
+		return "", err
 	}
 	defer unlockFn()
 
-	outputs, err := p.runSteps(ctx.Steps, ctx, absPath)
-	p.Webhooks.Send(ctx.Log, webhooks.ApplyResult{ // nolint: errcheck
-		Workspace: ctx.Workspace,
-		User:      ctx.User,
-		Repo:      ctx.Pull.BaseRepo,
-		Pull:      ctx.Pull,
-		Success:   err == nil,
-		Directory: ctx.RepoRelDir,
-	})
-	if err != nil {
-		return "", "", fmt.Errorf("%s\n%s", err, strings.Join(outputs, "\n"))
+	ctx.Log.Info("working dir not found, cloning it again to apply stored plan")
+	repoDir, _, err := p.clone(ctx)
+	return repoDir, err
+}
+
+// initRestoredWorkingDir runs the init steps of the project's workflow in
+// absPath after restoreWorkingDir, because applying a planfile requires the
+// providers and modules to be installed.
+func (p *DefaultProjectCommandRunner) initRestoredWorkingDir(ctx models.ProjectCommandContext, absPath string) error {
+	if len(ctx.InitSteps) == 0 {
+		return nil
 	}
-	return strings.Join(outputs, "\n"), "", nil
+	ctx.Log.Info("running init since working dir was restored")
+	if out, err := p.runSteps(ctx.InitSteps, ctx, absPath); err != nil {
+		return fmt.Errorf("running init: %s\n%s", err, strings.Join(out, "\n"))
+	}
+	return nil
 }
 
 func (p *DefaultProjectCommandRunner) doVersion(ctx models.ProjectCommandContext) (versionOut string, failure string, err error) {
@@ -418,41 +1023,282 @@ func (p *DefaultProjectCommandRunner) doVersion(ctx models.ProjectCommandContext
 	return strings.Join(outputs, "\n"), "", nil
 }
 
+// doStateChange runs the steps of a command that modifies state directly, ex.
+// import or state rm, or that may, ex. a custom command, and returns their
+// output.
+func (p *DefaultProjectCommandRunner) doStateChange(ctx models.ProjectCommandContext) (output string, failure string, err error) {
+	// Acquire Atlantis lock for this repo/dir/workspace. These commands modify
+	// state so they must hold the same lock as plan and apply.
+	lockAttempt, err := p.lockProject(ctx)
+	if err != nil {
+		return "", "", errors.Wrap(err, "acquiring lock")
+	}
+	if !lockAttempt.LockAcquired {
+		return "", lockAttempt.LockFailureReason, nil
+	}
+	ctx.Log.Debug("acquired lock for project")
+
+	// Acquire internal lock for the directory we're going to operate in.
+	unlockFn, err := p.WorkingDirLocker.TryLock(ctx.Pull.BaseRepo.FullName, ctx.Pull.Num, ctx.Workspace)
+	if err != nil {
+		return "", "", err
+	}
+	defer unlockFn()
+
+	// Clone is idempotent so okay to run even if the repo was already cloned.
+	repoDir, _, err := p.clone(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	projAbsPath := filepath.Join(repoDir, ctx.RepoRelDir)
+	if _, err = os.Stat(projAbsPath); os.IsNotExist(err) {
+		return "", "", DirNotExistErr{RepoRelDir: ctx.RepoRelDir}
+	}
+
+	// Custom commands can do anything apply can so they must pass the same
+	// requirements, or the command's own.
+	if ctx.CommandName == models.CustomCommand {
+		planFile := filepath.Join(projAbsPath, runtime.GetPlanFilename(ctx.Workspace, ctx.ProjectName))
+		if failure, err = p.applyRequirementsFailure(ctx, repoDir, projAbsPath, planFile); failure != "" || err != nil {
+			return "", failure, err
+		}
+	}
+
+	outputs, err := p.runSteps(ctx.Steps, ctx, projAbsPath)
+	if err != nil {
+		return "", "", fmt.Errorf("%s\n%s", err, strings.Join(outputs, "\n"))
+	}
+
+	return strings.Join(outputs, "\n"), "", nil
+}
+
+// doValidate runs the validate steps. Validate doesn't modify state so unlike
+// plan it doesn't acquire the project lock.
+func (p *DefaultProjectCommandRunner) doValidate(ctx models.ProjectCommandContext) (validateOut string, failure string, err error) {
+	// Acquire internal lock for the directory we're going to operate in.
+	unlockFn, err := p.WorkingDirLocker.TryLock(ctx.Pull.BaseRepo.FullName, ctx.Pull.Num, ctx.Workspace)
+	if err != nil {
+		return "", "", err
+	}
+	defer unlockFn()
+
+	// Clone is idempotent so okay to run even if the repo was already cloned.
+	repoDir, _, err := p.clone(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	projAbsPath := filepath.Join(repoDir, ctx.RepoRelDir)
+	if _, err = os.Stat(projAbsPath); os.IsNotExist(err) {
+		return "", "", DirNotExistErr{RepoRelDir: ctx.RepoRelDir}
+	}
+
+	outputs, err := p.runSteps(ctx.Steps, ctx, projAbsPath)
+	if err != nil {
+		return "", "", fmt.Errorf("%s\n%s", err, strings.Join(outputs, "\n"))
+	}
+
+	return strings.Join(outputs, "\n"), "", nil
+}
+
+// sameTargets returns true if a and b contain the same resource addresses,
+// in any order.
+func sameTargets(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sortedA := append([]string(nil), a...)
+	sortedB := append([]string(nil), b...)
+	sort.Strings(sortedA)
+	sort.Strings(sortedB)
+	for i := range sortedA {
+		if sortedA[i] != sortedB[i] {
+			return false
+		}
+	}
+	return true
+}
+
 func (p *DefaultProjectCommandRunner) runSteps(steps []valid.Step, ctx models.ProjectCommandContext, absPath string) ([]string, error) {
+	outputs, _, err := p.runStepsWithRetries(steps, ctx, absPath)
+	return outputs, err
+}
+
+// runStepsWithRetries runs steps like runSteps and also returns the attempts
+// of init and plan steps that failed with a transient error and were run
+// again.
+func (p *DefaultProjectCommandRunner) runStepsWithRetries(steps []valid.Step, ctx models.ProjectCommandContext, absPath string) ([]string, []models.StepRetry, error) {
 	var outputs []string
-	envs := make(map[string]string)
+	var retries []models.StepRetry
+	envs, err := p.credentialEnvs(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
 	for _, step := range steps {
 		var out string
 		var err error
-		switch step.StepName {
-		case "init":
-			out, err = p.InitStepRunner.Run(ctx, step.ExtraArgs, absPath, envs)
-		case "plan":
-			out, err = p.PlanStepRunner.Run(ctx, step.ExtraArgs, absPath, envs)
-		case "show":
-			_, err = p.ShowStepRunner.Run(ctx, step.ExtraArgs, absPath, envs)
-		case "policy_check":
-			out, err = p.PolicyCheckStepRunner.Run(ctx, step.ExtraArgs, absPath, envs)
-		case "apply":
-			out, err = p.ApplyStepRunner.Run(ctx, step.ExtraArgs, absPath, envs)
-		case "version":
-			out, err = p.VersionStepRunner.Run(ctx, step.ExtraArgs, absPath, envs)
-		case "run":
-			out, err = p.RunStepRunner.Run(ctx, step.RunCommand, absPath, envs)
-		case "env":
-			out, err = p.EnvStepRunner.Run(ctx, step.RunCommand, step.EnvVarValue, absPath, envs)
-			envs[step.EnvVarName] = out
-			// We reset out to the empty string because we don't want it to
-			// be printed to the PR, it's solely to set the environment variable.
-			out = ""
+		for attempt := 1; ; attempt++ {
+			if ctx.Context().Err() != nil {
+				return outputs, retries, errCommandCancelled
+			}
+			out, err = p.runStep(ctx, step, absPath, envs)
+			if err == nil || attempt > p.MaxStepRetries || !retriableSteps[step.StepName] {
+				break
+			}
+			if _, ok := err.(stepTimeoutErr); ok || err == errCommandCancelled {
+				break
+			}
+			transientErr, ok := ParseTransientError(out, err)
+			if !ok {
+				break
+			}
+			retries = append(retries, models.StepRetry{StepName: step.StepName, Attempt: attempt, Error: transientErr})
+			backoff := retryBackoff(p.StepRetryBackoff, attempt)
+			ctx.Log.Warn("%s step failed with transient error %q, retrying in %s", step.StepName, transientErr, backoff)
+			select {
+			case <-ctx.Context().Done():
+				return outputs, retries, errCommandCancelled
+			case <-time.After(backoff):
+			}
 		}
 
 		if out != "" {
 			outputs = append(outputs, out)
 		}
 		if err != nil {
-			return outputs, err
+			return outputs, retries, err
+		}
+	}
+	return outputs, retries, nil
+}
+
+// runStep runs step once in its own span, with its timeout if it has one.
+// envs is updated with the variables set by env and multienv steps.
+func (p *DefaultProjectCommandRunner) runStep(ctx models.ProjectCommandContext, step valid.Step, absPath string, envs map[string]string) (string, error) {
+	stepCtx, cancelStep := stepContext(ctx, step)
+	stepCtx, span := startProjectSpan(stepCtx, step.StepName)
+	var out string
+	var err error
+	switch step.StepName {
+	case "init":
+		out, err = p.InitStepRunner.Run(stepCtx, step.ExtraArgs, absPath, envs)
+	case "plan":
+		out, err = p.PlanStepRunner.Run(stepCtx, step.ExtraArgs, absPath, envs)
+	case "show":
+		_, err = p.ShowStepRunner.Run(stepCtx, step.ExtraArgs, absPath, envs)
+	case "policy_check":
+		out, err = p.PolicyCheckStepRunner.Run(stepCtx, step.ExtraArgs, absPath, envs)
+	case "apply":
+		out, err = p.ApplyStepRunner.Run(stepCtx, step.ExtraArgs, absPath, envs)
+	case "version":
+		out, err = p.VersionStepRunner.Run(stepCtx, step.ExtraArgs, absPath, envs)
+	case "import":
+		out, err = p.ImportStepRunner.Run(stepCtx, step.ExtraArgs, absPath, envs)
+	case "state":
+		out, err = p.StateStepRunner.Run(stepCtx, step.ExtraArgs, absPath, envs)
+	case "validate":
+		out, err = p.ValidateStepRunner.Run(stepCtx, step.ExtraArgs, absPath, envs)
+	case "fmt":
+		out, err = p.FmtStepRunner.Run(stepCtx, step.ExtraArgs, absPath, envs)
+	case "terragrunt":
+		out, err = p.TerragruntStepRunner.Run(stepCtx, step.ExtraArgs, absPath, envs)
+	case "output":
+		// Like the show step, the outputs are written to a file that's read
+		// once the steps are done.
+		_, err = p.OutputStepRunner.Run(stepCtx, step.ExtraArgs, absPath, envs)
+	case "scan":
+		opts := valid.ScanOptions{Tool: valid.DefaultScanTool}
+		if step.Scan != nil {
+			opts = *step.Scan
 		}
+		// The findings are also written to a file that's read once the steps
+		// are done.
+		_, err = p.ScanStepRunner.Run(stepCtx, opts, step.ExtraArgs, absPath, envs)
+	case "run":
+		out, err = p.RunStepRunner.Run(stepCtx, step.RunCommand, absPath, envs)
+	case "env":
+		out, err = p.EnvStepRunner.Run(stepCtx, step.RunCommand, step.EnvVarValue, absPath, envs)
+		envs[step.EnvVarName] = out
+		// We reset out to the empty string because we don't want it to
+		// be printed to the PR, it's solely to set the environment variable.
+		out = ""
+	case "multienv":
+		var vars map[string]string
+		vars, err = p.MultiEnvStepRunner.Run(stepCtx, step.RunCommand, absPath, envs)
+		// Like the env step, we don't print the output to the PR since it
+		// likely contains credentials.
+		for k, v := range vars {
+			envs[k] = v
+		}
+	}
+	cancelStep()
+
+	if err != nil {
+		// Steps that are interrupted fail with terraform's own error
+		// which doesn't say why.
+		if ctx.Context().Err() != nil {
+			err = errCommandCancelled
+		} else if stepCtx.Context().Err() == context.DeadlineExceeded {
+			err = stepTimeoutErr{StepName: step.StepName, Timeout: step.Timeout}
+		}
+	}
+	tracing.End(span, err)
+	return out, err
+}
+
+// stepTimeoutErr is the error of a step that took longer than its timeout.
+type stepTimeoutErr struct {
+	StepName string
+	Timeout  time.Duration
+}
+
+func (e stepTimeoutErr) Error() string {
+	return fmt.Sprintf("%s step timed out after %s", e.StepName, e.Timeout)
+}
+
+// startProjectSpan starts a span that's a child of ctx's span and returns
+// ctx with the span in it. If tracing isn't enabled, ctx is returned as is.
+func startProjectSpan(ctx models.ProjectCommandContext, name string, attrs ...attribute.KeyValue) (models.ProjectCommandContext, trace.Span) {
+	spanCtx, span := tracing.Start(ctx.Context(), name, attrs...)
+	if span.SpanContext().IsValid() {
+		ctx.RequestCtx = spanCtx
+	}
+	return ctx, span
+}
+
+// clone clones the project's repo in a span of its own so that time spent
+// cloning can be told apart from time spent running steps.
+func (p *DefaultProjectCommandRunner) clone(ctx models.ProjectCommandContext) (string, bool, error) {
+	_, span := tracing.Start(ctx.Context(), "clone")
+	repoDir, hasDiverged, err := p.WorkingDir.Clone(ctx.Log, ctx.HeadRepo, ctx.Pull, ctx.Workspace)
+	tracing.End(span, err)
+	return repoDir, hasDiverged, err
+}
+
+// stepContext returns the context to run step with. If the step has a
+// timeout, its RequestCtx is also cancelled once the timeout has passed. The
+// returned func must be called once the step is done.
+func stepContext(ctx models.ProjectCommandContext, step valid.Step) (models.ProjectCommandContext, context.CancelFunc) {
+	if step.Timeout == 0 {
+		return ctx, func() {}
+	}
+	var cancel context.CancelFunc
+	ctx.RequestCtx, cancel = context.WithTimeout(ctx.Context(), step.Timeout)
+	return ctx, cancel
+}
+
+// credentialEnvs returns the env vars to run ctx's steps with. They start out
+// holding the credentials ctx configures, if any.
+func (p *DefaultProjectCommandRunner) credentialEnvs(ctx models.ProjectCommandContext) (map[string]string, error) {
+	envs := make(map[string]string)
+	if ctx.Credentials == nil || p.CredentialsProvider == nil {
+		return envs, nil
+	}
+	credEnvs, err := p.CredentialsProvider.Env(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "obtaining credentials")
+	}
+	for k, v := range credEnvs {
+		envs[k] = v
 	}
-	return outputs, nil
+	return envs, nil
 }
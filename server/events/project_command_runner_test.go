@@ -14,11 +14,18 @@
 package events_test
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/go-version"
 	. "github.com/petergtz/pegomock"
+	psmocks "github.com/runatlantis/atlantis/server/core/planstorage/mocks"
 	"github.com/runatlantis/atlantis/server/core/runtime"
 	mocks2 "github.com/runatlantis/atlantis/server/core/runtime/mocks"
 	tmocks "github.com/runatlantis/atlantis/server/core/terraform/mocks"
@@ -26,11 +33,135 @@ import (
 	"github.com/runatlantis/atlantis/server/events/mocks"
 	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
 	"github.com/runatlantis/atlantis/server/events/models"
+	vcsmocks "github.com/runatlantis/atlantis/server/events/vcs/mocks"
+	"github.com/runatlantis/atlantis/server/events/webhooks"
 	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 	"github.com/runatlantis/atlantis/server/logging"
 	. "github.com/runatlantis/atlantis/testing"
 )
 
+// Test that a plan sends a plan webhook.
+func TestDefaultProjectCommandRunner_PlanWebhook(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockInit := mocks.NewMockStepRunner()
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	mockLocker := mocks.NewMockProjectLocker()
+	sender := &recordingWebhooksSender{}
+
+	runner := events.DefaultProjectCommandRunner{
+		Locker:              mockLocker,
+		LockURLGenerator:    mockURLGenerator{},
+		HistoryURLGenerator: mockURLGenerator{},
+		InitStepRunner:      mockInit,
+		WorkingDir:          mockWorkingDir,
+		Webhooks:            sender,
+		WorkingDirLocker:    events.NewDefaultWorkingDirLocker(),
+	}
+
+	repoDir, cleanup := TempDir(t)
+	defer cleanup()
+	When(mockWorkingDir.Clone(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsPullRequest(),
+		AnyString(),
+	)).ThenReturn(repoDir, false, nil)
+	When(mockLocker.TryLock(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsPullRequest(),
+		matchers.AnyModelsUser(),
+		AnyString(),
+		matchers.AnyModelsProject(),
+		AnyString(),
+	)).ThenReturn(&events.TryLockResponse{
+		LockAcquired: true,
+		LockKey:      "lock-key",
+	}, nil)
+
+	ctx := models.ProjectCommandContext{
+		Log:         logging.NewNoopLogger(t),
+		Steps:       []valid.Step{{StepName: "init"}},
+		Workspace:   "default",
+		RepoRelDir:  ".",
+		ProjectName: "project",
+		Pull: models.PullRequest{
+			Num:      2,
+			BaseRepo: models.Repo{FullName: "owner/repo"},
+		},
+	}
+	When(mockInit.Run(ctx, nil, repoDir, map[string]string(nil))).ThenReturn("init", nil)
+	res := runner.Plan(ctx)
+	Assert(t, res.PlanSuccess != nil, "exp plan success")
+
+	Equals(t, 1, len(sender.results))
+	result := sender.results[0]
+	Equals(t, webhooks.PlanEvent, result.Event)
+	Equals(t, true, result.Success)
+	Equals(t, "project", result.ProjectName)
+	Equals(t, "https://history/owner/repo/2", result.LogURL)
+}
+
+// Test that the plan step's terraform show result is parsed into a structured
+// plan and that a result left over from a previous plan is never used.
+func TestDefaultProjectCommandRunner_PlanStructured(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockPlan := mocks.NewMockStepRunner()
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	mockLocker := mocks.NewMockProjectLocker()
+
+	runner := events.DefaultProjectCommandRunner{
+		Locker:               mockLocker,
+		LockURLGenerator:     mockURLGenerator{},
+		PlanStepRunner:       mockPlan,
+		WorkingDir:           mockWorkingDir,
+		WorkingDirLocker:     events.NewDefaultWorkingDirLocker(),
+		StructuredPlanOutput: true,
+	}
+
+	repoDir, cleanup := TempDir(t)
+	defer cleanup()
+	When(mockWorkingDir.Clone(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsPullRequest(),
+		AnyString(),
+	)).ThenReturn(repoDir, false, nil)
+	When(mockLocker.TryLock(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsPullRequest(),
+		matchers.AnyModelsUser(),
+		AnyString(),
+		matchers.AnyModelsProject(),
+		AnyString(),
+	)).ThenReturn(&events.TryLockResponse{
+		LockAcquired: true,
+		LockKey:      "lock-key",
+	}, nil)
+
+	ctx := models.ProjectCommandContext{
+		Log:        logging.NewNoopLogger(t),
+		Steps:      []valid.Step{{StepName: "plan"}},
+		Workspace:  "default",
+		RepoRelDir: ".",
+	}
+	showFile := filepath.Join(repoDir, ctx.GetShowResultFileName())
+	When(mockPlan.Run(matchers.AnyModelsProjectCommandContext(), matchers.AnySliceOfString(), AnyString(), matchers.AnyMapOfStringToString())).Then(func(params []Param) ReturnValues {
+		err := ioutil.WriteFile(showFile, []byte(`{"resource_changes":[{"address":"null_resource.a","change":{"actions":["create"]}}]}`), 0600)
+		Ok(t, err)
+		return ReturnValues{"plan", nil}
+	})
+	res := runner.Plan(ctx)
+	Assert(t, res.PlanSuccess != nil, "exp plan success")
+	Equals(t, &models.StructuredPlan{Create: []string{"null_resource.a"}}, res.PlanSuccess.StructuredPlan)
+
+	// If the plan step doesn't write a new result, ex. because the project
+	// uses TFE remote operations, we render the raw output.
+	When(mockPlan.Run(matchers.AnyModelsProjectCommandContext(), matchers.AnySliceOfString(), AnyString(), matchers.AnyMapOfStringToString())).ThenReturn("plan", nil)
+	res = runner.Plan(ctx)
+	Assert(t, res.PlanSuccess != nil, "exp plan success")
+	Assert(t, res.PlanSuccess.StructuredPlan == nil, "exp no structured plan")
+}
+
 // Test that it runs the expected plan steps.
 func TestDefaultProjectCommandRunner_Plan(t *testing.T) {
 	RegisterMockTestingT(t)
@@ -70,6 +201,7 @@ func TestDefaultProjectCommandRunner_Plan(t *testing.T) {
 		matchers.AnyModelsUser(),
 		AnyString(),
 		matchers.AnyModelsProject(),
+		AnyString(),
 	)).ThenReturn(&events.TryLockResponse{
 		LockAcquired: true,
 		LockKey:      "lock-key",
@@ -130,6 +262,36 @@ func TestDefaultProjectCommandRunner_Plan(t *testing.T) {
 
 // Test what happens if there's no working dir. This signals that the project
 // was never planned.
+// Test that destroy plans are rejected unless the server-side config allows
+// them, including when -destroy is passed as an extra argument.
+func TestDefaultProjectCommandRunner_PlanDestroyNotAllowed(t *testing.T) {
+	runner := &events.DefaultProjectCommandRunner{}
+	cases := []models.ProjectCommandContext{
+		{Destroy: true},
+		{EscapedCommentArgs: []string{"\\-\\d\\e\\s\\t\\r\\o\\y"}},
+	}
+	for _, ctx := range cases {
+		res := runner.Plan(ctx)
+		Assert(t, res.PlanSuccess == nil, "exp plan to not run")
+		Equals(t, "Destroy plans are not allowed for this project. To allow them, set `allow_destroy_plans: true` in the server-side repo config.", res.Failure)
+	}
+}
+
+// Test that targeted plans are rejected if the server-side config disables
+// targeting, including when -target is passed as an extra argument.
+func TestDefaultProjectCommandRunner_PlanTargetingDisabled(t *testing.T) {
+	runner := &events.DefaultProjectCommandRunner{}
+	cases := []models.ProjectCommandContext{
+		{DisableTargeting: true, Targets: []string{"aws_instance.web"}},
+		{DisableTargeting: true, EscapedCommentArgs: []string{"\\-\\t\\a\\r\\g\\e\\t\\=\\a\\.\\b"}},
+	}
+	for _, ctx := range cases {
+		res := runner.Plan(ctx)
+		Assert(t, res.PlanSuccess == nil, "exp plan to not run")
+		Equals(t, "Targeting resources with `-target` is not allowed for this project because `disable_targeting: true` is set in the server-side repo config.", res.Failure)
+	}
+}
+
 func TestDefaultProjectCommandRunner_ApplyNotCloned(t *testing.T) {
 	mockWorkingDir := mocks.NewMockWorkingDir()
 	runner := &events.DefaultProjectCommandRunner{
@@ -164,6 +326,31 @@ func TestDefaultProjectCommandRunner_ApplyNotApproved(t *testing.T) {
 	Equals(t, "Pull request must be approved by at least one person other than the author before running apply.", res.Failure)
 }
 
+// Test that if code owner approval is required and an owner didn't approve we
+// give an error.
+func TestDefaultProjectCommandRunner_ApplyCodeownersNotApproved(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	mockCodeowners := mocks.NewMockCodeownersChecker()
+	runner := &events.DefaultProjectCommandRunner{
+		WorkingDir:        mockWorkingDir,
+		CodeownersChecker: mockCodeowners,
+		WorkingDirLocker:  events.NewDefaultWorkingDirLocker(),
+	}
+	ctx := models.ProjectCommandContext{
+		ApplyRequirements: []string{"codeowners_approved"},
+		RepoRelDir:        "prod",
+	}
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	Ok(t, os.Mkdir(filepath.Join(tmp, "prod"), 0700))
+	When(mockWorkingDir.GetWorkingDir(ctx.BaseRepo, ctx.Pull, ctx.Workspace)).ThenReturn(tmp, nil)
+	When(mockCodeowners.UnapprovedFiles(ctx.Log, ctx.Pull, "prod")).ThenReturn([]string{"prod/main.tf", "prod/vars.tf"}, nil)
+
+	res := runner.Apply(ctx)
+	Equals(t, "Each modified file must be approved by one of its code owners before running apply. Not approved: prod/main.tf, prod/vars.tf.", res.Failure)
+}
+
 // Test that if mergeable is required and the PR isn't mergeable we give an error.
 func TestDefaultProjectCommandRunner_ApplyNotMergeable(t *testing.T) {
 	RegisterMockTestingT(t)
@@ -203,161 +390,1262 @@ func TestDefaultProjectCommandRunner_ApplyDiverged(t *testing.T) {
 	Equals(t, "Default branch must be rebased onto pull request before running apply.", res.Failure)
 }
 
-// Test that it runs the expected apply steps.
-func TestDefaultProjectCommandRunner_Apply(t *testing.T) {
-	cases := []struct {
-		description string
-		steps       []valid.Step
-		applyReqs   []string
+// Test that it returns an error on apply if the plan is older than allowed.
+func TestDefaultProjectCommandRunner_ApplyPlanTooOld(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	runner := &events.DefaultProjectCommandRunner{
+		WorkingDir:       mockWorkingDir,
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+	}
+	ctx := models.ProjectCommandContext{
+		ApplyRequirements: []string{"plan_newer_than:1h"},
+		RepoRelDir:        ".",
+		Workspace:         "default",
+	}
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	planFile := filepath.Join(tmp, "default.tfplan")
+	Ok(t, os.WriteFile(planFile, nil, 0600))
+	old := time.Now().Add(-2 * time.Hour)
+	Ok(t, os.Chtimes(planFile, old, old))
+	When(mockWorkingDir.GetWorkingDir(ctx.BaseRepo, ctx.Pull, ctx.Workspace)).ThenReturn(tmp, nil)
 
-		expSteps      []string
-		expOut        string
-		expFailure    string
-		pullMergeable bool
+	res := runner.Apply(ctx)
+	Equals(t, "Plan must have been generated in the last 1h0m0s before running apply. Run plan again to generate a new plan.", res.Failure)
+}
+
+// Test that a plan from an earlier commit isn't applied unless --force is
+// used.
+func TestDefaultProjectCommandRunner_ApplyPlanFromEarlierCommit(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	runner := &events.DefaultProjectCommandRunner{
+		WorkingDir:       mockWorkingDir,
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+		Webhooks:         mocks.NewMockWebhooksSender(),
+	}
+	ctx := models.ProjectCommandContext{
+		Log:                   logging.NewNoopLogger(t),
+		Pull:                  models.PullRequest{HeadCommit: "abc123"},
+		RepoRelDir:            ".",
+		Workspace:             "default",
+		PlanFromEarlierCommit: true,
+	}
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	Ok(t, os.WriteFile(filepath.Join(tmp, "default.tfplan"), nil, 0600))
+	When(mockWorkingDir.GetWorkingDir(ctx.BaseRepo, ctx.Pull, ctx.Workspace)).ThenReturn(tmp, nil)
+
+	res := runner.Apply(ctx)
+	Equals(t, "This plan was generated from an earlier commit than abc123. Run plan again, or to apply it anyway, comment `atlantis apply --force`.", res.Failure)
+
+	ctx.Force = true
+	res = runner.Apply(ctx)
+	Equals(t, "", res.Failure)
+	Ok(t, res.Error)
+}
+
+// Test that apply --target only applies plans that were targeted at the same
+// addresses.
+func TestDefaultProjectCommandRunner_ApplyTargets(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	runner := &events.DefaultProjectCommandRunner{
+		WorkingDir:       mockWorkingDir,
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+		Webhooks:         mocks.NewMockWebhooksSender(),
+	}
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	When(mockWorkingDir.GetWorkingDir(models.Repo{}, models.PullRequest{}, "default")).ThenReturn(tmp, nil)
+
+	cases := []struct {
+		description    string
+		targets        []string
+		plannedTargets []string
+		disable        bool
+		expFailure     string
 	}{
 		{
-			description: "normal workflow",
-			steps:       valid.DefaultApplyStage.Steps,
-			expSteps:    []string{"apply"},
-			expOut:      "apply",
-		},
-		{
-			description: "approval required",
-			steps:       valid.DefaultApplyStage.Steps,
-			applyReqs:   []string{"approved"},
-			expSteps:    []string{"approve", "apply"},
-			expOut:      "apply",
+			description:    "same targets in a different order",
+			targets:        []string{"module.b", "aws_instance.a"},
+			plannedTargets: []string{"aws_instance.a", "module.b"},
 		},
 		{
-			description:   "mergeable required",
-			steps:         valid.DefaultApplyStage.Steps,
-			pullMergeable: true,
-			applyReqs:     []string{"mergeable"},
-			expSteps:      []string{"apply"},
-			expOut:        "apply",
+			description:    "targeted plan without --target",
+			plannedTargets: []string{"aws_instance.a"},
 		},
 		{
-			description:   "mergeable required, pull not mergeable",
-			steps:         valid.DefaultApplyStage.Steps,
-			pullMergeable: false,
-			applyReqs:     []string{"mergeable"},
-			expSteps:      []string{""},
-			expOut:        "",
-			expFailure:    "Pull request must be mergeable before running apply.",
+			description:    "different targets",
+			targets:        []string{"aws_instance.a"},
+			plannedTargets: []string{"aws_instance.a", "module.b"},
+			expFailure:     "This project's plan was targeted at aws_instance.a, module.b, not aws_instance.a. Run plan again with these targets, or to apply the plan, comment `atlantis apply` without `--target`.",
 		},
 		{
-			description:   "mergeable and approved required",
-			steps:         valid.DefaultApplyStage.Steps,
-			pullMergeable: true,
-			applyReqs:     []string{"mergeable", "approved"},
-			expSteps:      []string{"approved", "apply"},
-			expOut:        "apply",
+			description: "plan wasn't targeted",
+			targets:     []string{"aws_instance.a"},
+			expFailure:  "This project's plan isn't targeted. To apply it, comment `atlantis apply` without `--target`.",
 		},
 		{
-			description: "workflow with custom apply stage",
-			steps: []valid.Step{
-				{
-					StepName:    "env",
-					EnvVarName:  "key",
-					EnvVarValue: "value",
-				},
-				{
-					StepName: "run",
-				},
-				{
-					StepName: "apply",
-				},
-				{
-					StepName: "plan",
-				},
-				{
-					StepName: "init",
-				},
-			},
-			expSteps: []string{"env", "run", "apply", "plan", "init"},
-			expOut:   "run\napply\nplan\ninit",
+			description:    "targeting disabled",
+			plannedTargets: []string{"aws_instance.a"},
+			disable:        true,
+			expFailure:     "Targeting resources with `-target` is not allowed for this project because `disable_targeting: true` is set in the server-side repo config.",
 		},
 	}
-
 	for _, c := range cases {
-		if c.description != "workflow with custom apply stage" {
-			continue
-		}
 		t.Run(c.description, func(t *testing.T) {
-			RegisterMockTestingT(t)
-			mockInit := mocks.NewMockStepRunner()
-			mockPlan := mocks.NewMockStepRunner()
-			mockApply := mocks.NewMockStepRunner()
-			mockRun := mocks.NewMockCustomStepRunner()
-			mockEnv := mocks.NewMockEnvStepRunner()
-			mockApproved := mocks2.NewMockPullApprovedChecker()
-			mockWorkingDir := mocks.NewMockWorkingDir()
-			mockLocker := mocks.NewMockProjectLocker()
-			mockSender := mocks.NewMockWebhooksSender()
+			res := runner.Apply(models.ProjectCommandContext{
+				Log:              logging.NewNoopLogger(t),
+				RepoRelDir:       ".",
+				Workspace:        "default",
+				Targets:          c.targets,
+				PlannedTargets:   c.plannedTargets,
+				DisableTargeting: c.disable,
+			})
+			Equals(t, c.expFailure, res.Failure)
+			Equals(t, c.plannedTargets, res.Targets)
+		})
+	}
+}
 
-			runner := events.DefaultProjectCommandRunner{
-				Locker:              mockLocker,
-				LockURLGenerator:    mockURLGenerator{},
-				InitStepRunner:      mockInit,
-				PlanStepRunner:      mockPlan,
-				ApplyStepRunner:     mockApply,
-				RunStepRunner:       mockRun,
-				EnvStepRunner:       mockEnv,
-				PullApprovedChecker: mockApproved,
-				WorkingDir:          mockWorkingDir,
-				Webhooks:            mockSender,
-				WorkingDirLocker:    events.NewDefaultWorkingDirLocker(),
-			}
-			repoDir, cleanup := TempDir(t)
-			defer cleanup()
-			When(mockWorkingDir.GetWorkingDir(
-				matchers.AnyModelsRepo(),
-				matchers.AnyModelsPullRequest(),
-				AnyString(),
-			)).ThenReturn(repoDir, nil)
+// Test that it returns an error on apply if a custom requirement fails.
+func TestDefaultProjectCommandRunner_ApplyCustomRequirementFailed(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	mockRun := mocks.NewMockCustomStepRunner()
+	runner := &events.DefaultProjectCommandRunner{
+		WorkingDir:       mockWorkingDir,
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+		RunStepRunner:    mockRun,
+	}
+	ctx := models.ProjectCommandContext{
+		ApplyRequirements:       []string{"change_window"},
+		CustomApplyRequirements: map[string]string{"change_window": "./check-window.sh"},
+		RepoRelDir:              ".",
+	}
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	When(mockWorkingDir.GetWorkingDir(ctx.BaseRepo, ctx.Pull, ctx.Workspace)).ThenReturn(tmp, nil)
+	When(mockRun.Run(matchers.AnyModelsProjectCommandContext(), EqString("./check-window.sh"), EqString(tmp), matchers.AnyMapOfStringToString())).
+		ThenReturn("", errors.New("outside change window"))
 
-			ctx := models.ProjectCommandContext{
-				Log:               logging.NewNoopLogger(t),
-				Steps:             c.steps,
+	res := runner.Apply(ctx)
+	Equals(t, `Apply requirement "change_window" must pass before running apply: outside change window`, res.Failure)
+}
+
+// Test that custom commands must pass the apply requirements before they run.
+func TestDefaultProjectCommandRunner_CustomApplyRequirements(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	mockLocker := mocks.NewMockProjectLocker()
+	mockRun := mocks.NewMockCustomStepRunner()
+	runner := &events.DefaultProjectCommandRunner{
+		Locker:           mockLocker,
+		WorkingDir:       mockWorkingDir,
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+		RunStepRunner:    mockRun,
+		Webhooks:         mocks.NewMockWebhooksSender(),
+	}
+	ctx := models.ProjectCommandContext{
+		CommandName:       models.CustomCommand,
+		Log:               logging.NewNoopLogger(t),
+		Steps:             []valid.Step{{StepName: "run", RunCommand: "terraform import a.b c"}},
+		ApplyRequirements: []string{"mergeable"},
+		Workspace:         "default",
+		RepoRelDir:        ".",
+	}
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	When(mockWorkingDir.Clone(matchers.AnyPtrToLoggingSimpleLogger(), matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString())).ThenReturn(tmp, false, nil)
+	When(mockLocker.TryLock(matchers.AnyPtrToLoggingSimpleLogger(), matchers.AnyModelsPullRequest(), matchers.AnyModelsUser(), AnyString(), matchers.AnyModelsProject(), AnyString())).
+		ThenReturn(&events.TryLockResponse{LockAcquired: true, LockKey: "lock-key"}, nil)
+
+	res := runner.Custom(ctx)
+	Equals(t, "Pull request must be mergeable before running apply.", res.Failure)
+	mockRun.VerifyWasCalled(Never()).Run(matchers.AnyModelsProjectCommandContext(), AnyString(), AnyString(), matchers.AnyMapOfStringToString())
+}
+
+// Test that if the working dir is missing and restoring is enabled, the pull
+// request is cloned again and initialized before applying.
+func TestDefaultProjectCommandRunner_ApplyRestoresWorkingDir(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	mockInit := mocks.NewMockStepRunner()
+	mockApply := mocks.NewMockStepRunner()
+	runner := &events.DefaultProjectCommandRunner{
+		WorkingDir:               mockWorkingDir,
+		WorkingDirLocker:         events.NewDefaultWorkingDirLocker(),
+		InitStepRunner:           mockInit,
+		ApplyStepRunner:          mockApply,
+		Webhooks:                 mocks.NewMockWebhooksSender(),
+		RestoreWorkingDirOnApply: true,
+	}
+	ctx := models.ProjectCommandContext{
+		Log:        logging.NewNoopLogger(t),
+		Steps:      []valid.Step{{StepName: "apply"}},
+		InitSteps:  []valid.Step{{StepName: "init"}},
+		Workspace:  "default",
+		RepoRelDir: ".",
+	}
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	When(mockWorkingDir.GetWorkingDir(ctx.BaseRepo, ctx.Pull, ctx.Workspace)).ThenReturn("", os.ErrNotExist)
+	When(mockWorkingDir.Clone(matchers.AnyPtrToLoggingSimpleLogger(), matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString())).ThenReturn(tmp, false, nil)
+	When(mockInit.Run(ctx, nil, tmp, map[string]string{})).ThenReturn("init", nil)
+	When(mockApply.Run(ctx, nil, tmp, map[string]string{})).ThenReturn("apply", nil)
+
+	res := runner.Apply(ctx)
+	Equals(t, "", res.Failure)
+	Equals(t, "apply", res.ApplySuccess)
+	mockWorkingDir.VerifyWasCalledOnce().Clone(matchers.AnyPtrToLoggingSimpleLogger(), matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), EqString("default"))
+	mockInit.VerifyWasCalledOnce().Run(ctx, nil, tmp, map[string]string{})
+}
+
+// Test that the planfile is downloaded from plan storage while the working
+// dir is locked so that a concurrent plan can't change it.
+func TestDefaultProjectCommandRunner_ApplyDownloadsPlanWhileLocked(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	mockApply := mocks.NewMockStepRunner()
+	mockStorage := psmocks.NewMockPlanStorage()
+	workingDirLocker := events.NewDefaultWorkingDirLocker()
+	runner := &events.DefaultProjectCommandRunner{
+		WorkingDir:       mockWorkingDir,
+		WorkingDirLocker: workingDirLocker,
+		ApplyStepRunner:  mockApply,
+		PlanStorage:      mockStorage,
+		Webhooks:         mocks.NewMockWebhooksSender(),
+	}
+	ctx := models.ProjectCommandContext{
+		Log:        logging.NewNoopLogger(t),
+		Steps:      []valid.Step{{StepName: "apply"}},
+		Workspace:  "default",
+		RepoRelDir: ".",
+	}
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	When(mockWorkingDir.GetWorkingDir(ctx.BaseRepo, ctx.Pull, ctx.Workspace)).ThenReturn(tmp, nil)
+	When(mockApply.Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())).ThenReturn("apply", nil)
+	var lockErr error
+	When(mockStorage.Download(matchers.AnyModelsProjectCommandContext(), AnyString())).Then(func(params []Param) ReturnValues {
+		_, lockErr = workingDirLocker.TryLock(ctx.Pull.BaseRepo.FullName, ctx.Pull.Num, ctx.Workspace)
+		return []ReturnValue{nil}
+	})
+
+	res := runner.Apply(ctx)
+	Ok(t, res.Error)
+	Assert(t, lockErr != nil, "exp working dir to be locked during the download")
+}
+
+// Test that the outputs written by the output step are returned with the
+// apply and that outputs from a previous apply aren't.
+func TestDefaultProjectCommandRunner_ApplyOutputs(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	mockApply := mocks.NewMockStepRunner()
+	mockOutput := mocks.NewMockStepRunner()
+	runner := &events.DefaultProjectCommandRunner{
+		WorkingDir:       mockWorkingDir,
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+		ApplyStepRunner:  mockApply,
+		OutputStepRunner: mockOutput,
+		Webhooks:         mocks.NewMockWebhooksSender(),
+	}
+	ctx := models.ProjectCommandContext{
+		Log:        logging.NewNoopLogger(t),
+		Steps:      []valid.Step{{StepName: "apply"}, {StepName: "output"}},
+		Workspace:  "default",
+		RepoRelDir: ".",
+		Outputs:    []string{"endpoint"},
+	}
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	outputsFile := filepath.Join(tmp, "default.outputs.json")
+	When(mockWorkingDir.GetWorkingDir(ctx.BaseRepo, ctx.Pull, ctx.Workspace)).ThenReturn(tmp, nil)
+	When(mockApply.Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())).ThenReturn("apply", nil)
+	When(mockOutput.Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())).Then(func(params []Param) ReturnValues {
+		err := ioutil.WriteFile(outputsFile, []byte(`[{"Name":"endpoint","Value":"https://api.example.com"}]`), 0600)
+		return []ReturnValue{"", err}
+	})
+
+	res := runner.Apply(ctx)
+	Ok(t, res.Error)
+	Equals(t, "apply", res.ApplySuccess)
+	Equals(t, []models.TerraformOutput{{Name: "endpoint", Value: "https://api.example.com"}}, res.ApplyOutputs)
+
+	t.Log("outputs from a previous apply aren't returned")
+	ctx.Steps = []valid.Step{{StepName: "apply"}}
+	res = runner.Apply(ctx)
+	Ok(t, res.Error)
+	Equals(t, []models.TerraformOutput(nil), res.ApplyOutputs)
+}
+
+// Test that if another pull is applying the same project, the apply is queued.
+func TestDefaultProjectCommandRunner_ApplyQueued(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	mockApply := mocks.NewMockStepRunner()
+	applyQueue := events.NewDefaultApplyQueue()
+	runner := &events.DefaultProjectCommandRunner{
+		WorkingDir:       mockWorkingDir,
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+		ApplyStepRunner:  mockApply,
+		ApplyQueue:       applyQueue,
+	}
+	ctx := models.ProjectCommandContext{
+		Pull:       models.PullRequest{Num: 2},
+		RepoRelDir: ".",
+		Workspace:  "default",
+		Steps:      []valid.Step{{StepName: "apply"}},
+	}
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	When(mockWorkingDir.GetWorkingDir(ctx.BaseRepo, ctx.Pull, ctx.Workspace)).ThenReturn(tmp, nil)
+
+	otherCtx := ctx
+	otherCtx.Pull = models.PullRequest{Num: 1}
+	_, position := applyQueue.Enqueue(otherCtx)
+	Equals(t, 0, position)
+
+	res := runner.Apply(ctx)
+	Equals(t, "Another pull request is currently applying this project. This apply is number 1 in the queue and will run automatically once the applies ahead of it are complete.", res.Failure)
+	mockApply.VerifyWasCalled(Never()).Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())
+}
+
+// Test that if another pull holds the project's lock, the plan is queued.
+func TestDefaultProjectCommandRunner_PlanQueued(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockLocker := mocks.NewMockProjectLocker()
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	lockQueue := events.NewDefaultLockQueue()
+	runner := &events.DefaultProjectCommandRunner{
+		Locker:           mockLocker,
+		WorkingDir:       mockWorkingDir,
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+		LockQueue:        lockQueue,
+	}
+	currLock := models.ProjectLock{
+		Project:   models.NewProject("owner/repo", "."),
+		Workspace: "default",
+		Pull:      models.PullRequest{Num: 1},
+	}
+	When(mockLocker.TryLock(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsPullRequest(),
+		matchers.AnyModelsUser(),
+		AnyString(),
+		matchers.AnyModelsProject(),
+		AnyString(),
+	)).ThenReturn(&events.TryLockResponse{
+		LockAcquired: false,
+		CurrLock:     currLock,
+	}, nil)
+	ctx := models.ProjectCommandContext{
+		Log:        logging.NewNoopLogger(t),
+		Pull:       models.PullRequest{Num: 2},
+		RepoRelDir: ".",
+		Workspace:  "default",
+	}
+
+	res := runner.Plan(ctx)
+	Equals(t, "This project is currently locked by an unapplied plan from pull #1. This plan is queued behind it as number 1 in the queue and will run automatically once the lock is released.", res.Failure)
+	mockWorkingDir.VerifyWasCalled(Never()).Clone(matchers.AnyPtrToLoggingSimpleLogger(), matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString())
+	Equals(t, 2, lockQueue.Wait(currLock, applyQueueCtx(3, ".")))
+}
+
+// Test that each project gets its own commit status if enabled.
+func TestDefaultProjectCommandRunner_ProjectStatuses(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	mockApply := mocks.NewMockStepRunner()
+	updater := mocks.NewMockCommitStatusUpdater()
+	applyQueue := events.NewDefaultApplyQueue()
+	runner := &events.DefaultProjectCommandRunner{
+		WorkingDir:          mockWorkingDir,
+		WorkingDirLocker:    events.NewDefaultWorkingDirLocker(),
+		ApplyStepRunner:     mockApply,
+		ApplyQueue:          applyQueue,
+		Webhooks:            mocks.NewMockWebhooksSender(),
+		CommitStatusUpdater: updater,
+	}
+	ctx := models.ProjectCommandContext{
+		Log:        logging.NewNoopLogger(t),
+		Pull:       models.PullRequest{Num: 2},
+		RepoRelDir: ".",
+		Workspace:  "default",
+		Steps:      []valid.Step{{StepName: "apply"}},
+	}
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	When(mockWorkingDir.GetWorkingDir(ctx.BaseRepo, ctx.Pull, ctx.Workspace)).ThenReturn(tmp, nil)
+	When(mockApply.Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())).ThenReturn("applied", nil)
+
+	t.Log("a successful apply sets the project's status to pending then its result")
+	res := runner.Apply(ctx)
+	Equals(t, "applied", res.ApplySuccess)
+	updater.VerifyWasCalledOnce().UpdateProject(ctx, models.ApplyCommand, models.PendingCommitStatus, "")
+	updater.VerifyWasCalledOnce().UpdateProjectResult(ctx, res, "")
+
+	t.Log("a queued apply stays pending")
+	otherCtx := ctx
+	otherCtx.Pull = models.PullRequest{Num: 1}
+	applyQueue.Enqueue(otherCtx)
+	res = runner.Apply(ctx)
+	Assert(t, res.Failure != "", "exp apply to be queued")
+	updater.VerifyWasCalled(Times(2)).UpdateProject(ctx, models.ApplyCommand, models.PendingCommitStatus, "")
+	updater.VerifyWasCalled(Never()).UpdateProjectResult(ctx, res, "")
+
+	t.Log("a failed apply sets the project's status from its result")
+	When(mockWorkingDir.GetWorkingDir(ctx.BaseRepo, ctx.Pull, ctx.Workspace)).ThenReturn("", os.ErrNotExist)
+	res = runner.Apply(ctx)
+	Assert(t, res.Error != nil, "exp apply to fail")
+	updater.VerifyWasCalledOnce().UpdateProjectResult(ctx, res, "")
+
+	t.Log("statuses link to the project's history if there's a history page")
+	runner.HistoryURLGenerator = mockURLGenerator{}
+	res = runner.Apply(ctx)
+	updater.VerifyWasCalledOnce().UpdateProjectResult(ctx, res, "https://history//2/./default")
+}
+
+// Test that applies are recorded as deployments if enabled.
+func TestDefaultProjectCommandRunner_Deployments(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	mockApply := mocks.NewMockStepRunner()
+	client := vcsmocks.NewMockDeploymentsClient()
+	runner := &events.DefaultProjectCommandRunner{
+		WorkingDir:       mockWorkingDir,
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+		ApplyStepRunner:  mockApply,
+		Webhooks:         mocks.NewMockWebhooksSender(),
+		Deployments:      &events.DeploymentsUpdater{Client: client},
+	}
+	ctx := models.ProjectCommandContext{
+		Log:        logging.NewNoopLogger(t),
+		Pull:       models.PullRequest{Num: 2, URL: "https://github.com/owner/repo/pull/2"},
+		RepoRelDir: "dir",
+		Workspace:  "staging",
+		Steps:      []valid.Step{{StepName: "apply"}},
+	}
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	Ok(t, os.Mkdir(filepath.Join(tmp, "dir"), 0700))
+	When(mockWorkingDir.GetWorkingDir(ctx.BaseRepo, ctx.Pull, ctx.Workspace)).ThenReturn(tmp, nil)
+	When(client.CreateDeployment(ctx.Pull.BaseRepo, ctx.Pull, "staging", "Applying dir/staging from pull request #2")).ThenReturn(int64(10), nil)
+
+	t.Log("a successful apply is deployed to the workspace's environment")
+	When(mockApply.Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())).ThenReturn("applied", nil)
+	res := runner.Apply(ctx)
+	Equals(t, "applied", res.ApplySuccess)
+	client.VerifyWasCalledOnce().UpdateDeploymentStatus(ctx.Pull.BaseRepo, int64(10), models.PendingCommitStatus, ctx.Pull.URL, "Applying dir/staging from pull request #2")
+	client.VerifyWasCalledOnce().UpdateDeploymentStatus(ctx.Pull.BaseRepo, int64(10), models.SuccessCommitStatus, ctx.Pull.URL, "Applied dir/staging from pull request #2")
+
+	t.Log("a failed apply fails the deployment")
+	When(mockApply.Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())).ThenReturn("", errors.New("apply failed"))
+	res = runner.Apply(ctx)
+	Assert(t, res.Error != nil, "exp apply to fail")
+	client.VerifyWasCalledOnce().UpdateDeploymentStatus(ctx.Pull.BaseRepo, int64(10), models.FailedCommitStatus, ctx.Pull.URL, "Failed to apply dir/staging from pull request #2")
+
+	t.Log("applies that don't run aren't deployed")
+	When(mockWorkingDir.GetWorkingDir(ctx.BaseRepo, ctx.Pull, ctx.Workspace)).ThenReturn("", os.ErrNotExist)
+	runner.Apply(ctx)
+	client.VerifyWasCalled(Times(2)).CreateDeployment(ctx.Pull.BaseRepo, ctx.Pull, "staging", "Applying dir/staging from pull request #2")
+}
+
+// Test that it runs the expected apply steps.
+func TestDefaultProjectCommandRunner_Apply(t *testing.T) {
+	cases := []struct {
+		description string
+		steps       []valid.Step
+		applyReqs   []string
+
+		expSteps      []string
+		expOut        string
+		expFailure    string
+		pullMergeable bool
+	}{
+		{
+			description: "normal workflow",
+			steps:       valid.DefaultApplyStage.Steps,
+			expSteps:    []string{"apply"},
+			expOut:      "apply",
+		},
+		{
+			description: "approval required",
+			steps:       valid.DefaultApplyStage.Steps,
+			applyReqs:   []string{"approved"},
+			expSteps:    []string{"approve", "apply"},
+			expOut:      "apply",
+		},
+		{
+			description:   "mergeable required",
+			steps:         valid.DefaultApplyStage.Steps,
+			pullMergeable: true,
+			applyReqs:     []string{"mergeable"},
+			expSteps:      []string{"apply"},
+			expOut:        "apply",
+		},
+		{
+			description:   "mergeable required, pull not mergeable",
+			steps:         valid.DefaultApplyStage.Steps,
+			pullMergeable: false,
+			applyReqs:     []string{"mergeable"},
+			expSteps:      []string{""},
+			expOut:        "",
+			expFailure:    "Pull request must be mergeable before running apply.",
+		},
+		{
+			description:   "mergeable and approved required",
+			steps:         valid.DefaultApplyStage.Steps,
+			pullMergeable: true,
+			applyReqs:     []string{"mergeable", "approved"},
+			expSteps:      []string{"approved", "apply"},
+			expOut:        "apply",
+		},
+		{
+			description: "workflow with custom apply stage",
+			steps: []valid.Step{
+				{
+					StepName:    "env",
+					EnvVarName:  "key",
+					EnvVarValue: "value",
+				},
+				{
+					StepName: "run",
+				},
+				{
+					StepName: "apply",
+				},
+				{
+					StepName: "plan",
+				},
+				{
+					StepName: "init",
+				},
+			},
+			expSteps: []string{"env", "run", "apply", "plan", "init"},
+			expOut:   "run\napply\nplan\ninit",
+		},
+	}
+
+	for _, c := range cases {
+		if c.description != "workflow with custom apply stage" {
+			continue
+		}
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			mockInit := mocks.NewMockStepRunner()
+			mockPlan := mocks.NewMockStepRunner()
+			mockApply := mocks.NewMockStepRunner()
+			mockRun := mocks.NewMockCustomStepRunner()
+			mockEnv := mocks.NewMockEnvStepRunner()
+			mockApproved := mocks2.NewMockPullApprovedChecker()
+			mockWorkingDir := mocks.NewMockWorkingDir()
+			mockLocker := mocks.NewMockProjectLocker()
+			mockSender := mocks.NewMockWebhooksSender()
+
+			runner := events.DefaultProjectCommandRunner{
+				Locker:              mockLocker,
+				LockURLGenerator:    mockURLGenerator{},
+				InitStepRunner:      mockInit,
+				PlanStepRunner:      mockPlan,
+				ApplyStepRunner:     mockApply,
+				RunStepRunner:       mockRun,
+				EnvStepRunner:       mockEnv,
+				PullApprovedChecker: mockApproved,
+				WorkingDir:          mockWorkingDir,
+				Webhooks:            mockSender,
+				WorkingDirLocker:    events.NewDefaultWorkingDirLocker(),
+			}
+			repoDir, cleanup := TempDir(t)
+			defer cleanup()
+			When(mockWorkingDir.GetWorkingDir(
+				matchers.AnyModelsRepo(),
+				matchers.AnyModelsPullRequest(),
+				AnyString(),
+			)).ThenReturn(repoDir, nil)
+
+			ctx := models.ProjectCommandContext{
+				Log:               logging.NewNoopLogger(t),
+				Steps:             c.steps,
 				Workspace:         "default",
 				ApplyRequirements: c.applyReqs,
 				RepoRelDir:        ".",
 				PullMergeable:     c.pullMergeable,
 			}
-			expEnvs := map[string]string{
-				"key": "value",
+			expEnvs := map[string]string{
+				"key": "value",
+			}
+			When(mockInit.Run(ctx, nil, repoDir, expEnvs)).ThenReturn("init", nil)
+			When(mockPlan.Run(ctx, nil, repoDir, expEnvs)).ThenReturn("plan", nil)
+			When(mockApply.Run(ctx, nil, repoDir, expEnvs)).ThenReturn("apply", nil)
+			When(mockRun.Run(ctx, "", repoDir, expEnvs)).ThenReturn("run", nil)
+			When(mockEnv.Run(ctx, "", "value", repoDir, make(map[string]string))).ThenReturn("value", nil)
+			When(mockApproved.PullIsApproved(ctx.BaseRepo, ctx.Pull)).ThenReturn(true, nil)
+
+			res := runner.Apply(ctx)
+			Equals(t, c.expOut, res.ApplySuccess)
+			Equals(t, c.expFailure, res.Failure)
+
+			for _, step := range c.expSteps {
+				switch step {
+				case "approved":
+					mockApproved.VerifyWasCalledOnce().PullIsApproved(ctx.BaseRepo, ctx.Pull)
+				case "init":
+					mockInit.VerifyWasCalledOnce().Run(ctx, nil, repoDir, expEnvs)
+				case "plan":
+					mockPlan.VerifyWasCalledOnce().Run(ctx, nil, repoDir, expEnvs)
+				case "apply":
+					mockApply.VerifyWasCalledOnce().Run(ctx, nil, repoDir, expEnvs)
+				case "run":
+					mockRun.VerifyWasCalledOnce().Run(ctx, "", repoDir, expEnvs)
+				case "env":
+					mockEnv.VerifyWasCalledOnce().Run(ctx, "", "value", repoDir, expEnvs)
+				}
+			}
+		})
+	}
+}
+
+// Test that import runs its steps while holding the project lock.
+func TestDefaultProjectCommandRunner_Import(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockInit := mocks.NewMockStepRunner()
+	mockImport := mocks.NewMockStepRunner()
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	mockLocker := mocks.NewMockProjectLocker()
+
+	runner := events.DefaultProjectCommandRunner{
+		Locker:           mockLocker,
+		LockURLGenerator: mockURLGenerator{},
+		InitStepRunner:   mockInit,
+		ImportStepRunner: mockImport,
+		WorkingDir:       mockWorkingDir,
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+	}
+
+	repoDir, cleanup := TempDir(t)
+	defer cleanup()
+	When(mockWorkingDir.Clone(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsPullRequest(),
+		AnyString(),
+	)).ThenReturn(repoDir, false, nil)
+
+	ctx := models.ProjectCommandContext{
+		Log: logging.NewNoopLogger(t),
+		Steps: []valid.Step{
+			{
+				StepName: "init",
+			},
+			{
+				StepName: "import",
+			},
+		},
+		Workspace:  "default",
+		RepoRelDir: ".",
+		RePlanCmd:  "atlantis plan -d .",
+	}
+	expEnvs := map[string]string{}
+	When(mockInit.Run(ctx, nil, repoDir, expEnvs)).ThenReturn("", nil)
+	When(mockImport.Run(ctx, nil, repoDir, expEnvs)).ThenReturn("Import successful!", nil)
+
+	t.Run("lock held by another pull", func(t *testing.T) {
+		When(mockLocker.TryLock(
+			matchers.AnyPtrToLoggingSimpleLogger(),
+			matchers.AnyModelsPullRequest(),
+			matchers.AnyModelsUser(),
+			AnyString(),
+			matchers.AnyModelsProject(),
+			AnyString(),
+		)).ThenReturn(&events.TryLockResponse{
+			LockAcquired:      false,
+			LockFailureReason: "locked",
+		}, nil)
+
+		res := runner.Import(ctx)
+		Equals(t, "locked", res.Failure)
+		Assert(t, res.ImportSuccess == nil, "exp no import success")
+		mockImport.VerifyWasCalled(Never()).Run(ctx, nil, repoDir, expEnvs)
+	})
+
+	t.Run("lock acquired", func(t *testing.T) {
+		When(mockLocker.TryLock(
+			matchers.AnyPtrToLoggingSimpleLogger(),
+			matchers.AnyModelsPullRequest(),
+			matchers.AnyModelsUser(),
+			AnyString(),
+			matchers.AnyModelsProject(),
+			AnyString(),
+		)).ThenReturn(&events.TryLockResponse{
+			LockAcquired: true,
+			LockKey:      "lock-key",
+		}, nil)
+
+		res := runner.Import(ctx)
+		Equals(t, models.ImportCommand, res.Command)
+		Equals(t, "", res.Failure)
+		Ok(t, res.Error)
+		Equals(t, &models.ImportSuccess{
+			Output:    "Import successful!",
+			RePlanCmd: "atlantis plan -d .",
+		}, res.ImportSuccess)
+		mockInit.VerifyWasCalledOnce().Run(ctx, nil, repoDir, expEnvs)
+		mockImport.VerifyWasCalledOnce().Run(ctx, nil, repoDir, expEnvs)
+	})
+}
+
+func TestDefaultProjectCommandRunner_State(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockInit := mocks.NewMockStepRunner()
+	mockState := mocks.NewMockStepRunner()
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	mockLocker := mocks.NewMockProjectLocker()
+
+	runner := events.DefaultProjectCommandRunner{
+		Locker:           mockLocker,
+		LockURLGenerator: mockURLGenerator{},
+		InitStepRunner:   mockInit,
+		StateStepRunner:  mockState,
+		WorkingDir:       mockWorkingDir,
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+	}
+
+	repoDir, cleanup := TempDir(t)
+	defer cleanup()
+	When(mockWorkingDir.Clone(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsPullRequest(),
+		AnyString(),
+	)).ThenReturn(repoDir, false, nil)
+	When(mockLocker.TryLock(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsPullRequest(),
+		matchers.AnyModelsUser(),
+		AnyString(),
+		matchers.AnyModelsProject(),
+		AnyString(),
+	)).ThenReturn(&events.TryLockResponse{
+		LockAcquired: true,
+		LockKey:      "lock-key",
+	}, nil)
+
+	ctx := models.ProjectCommandContext{
+		Log: logging.NewNoopLogger(t),
+		Steps: []valid.Step{
+			{
+				StepName: "init",
+			},
+			{
+				StepName: "state",
+			},
+		},
+		Workspace:  "default",
+		RepoRelDir: ".",
+		RePlanCmd:  "atlantis plan -d .",
+		SubName:    "rm",
+	}
+	expEnvs := map[string]string{}
+	When(mockInit.Run(ctx, nil, repoDir, expEnvs)).ThenReturn("", nil)
+	When(mockState.Run(ctx, nil, repoDir, expEnvs)).ThenReturn("Removed aws_instance.example", nil)
+
+	res := runner.State(ctx)
+	Equals(t, models.StateCommand, res.Command)
+	Equals(t, "", res.Failure)
+	Ok(t, res.Error)
+	Equals(t, &models.StateSuccess{
+		Output:    "Removed aws_instance.example",
+		RePlanCmd: "atlantis plan -d .",
+	}, res.StateSuccess)
+	mockLocker.VerifyWasCalledOnce().TryLock(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsPullRequest(),
+		matchers.AnyModelsUser(),
+		AnyString(),
+		matchers.AnyModelsProject(),
+		AnyString(),
+	)
+	mockState.VerifyWasCalledOnce().Run(ctx, nil, repoDir, expEnvs)
+}
+
+func TestDefaultProjectCommandRunner_Validate(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockInit := mocks.NewMockStepRunner()
+	mockValidate := mocks.NewMockStepRunner()
+	mockFmt := mocks.NewMockStepRunner()
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	mockLocker := mocks.NewMockProjectLocker()
+
+	runner := events.DefaultProjectCommandRunner{
+		Locker:             mockLocker,
+		LockURLGenerator:   mockURLGenerator{},
+		InitStepRunner:     mockInit,
+		ValidateStepRunner: mockValidate,
+		FmtStepRunner:      mockFmt,
+		WorkingDir:         mockWorkingDir,
+		WorkingDirLocker:   events.NewDefaultWorkingDirLocker(),
+	}
+
+	repoDir, cleanup := TempDir(t)
+	defer cleanup()
+	When(mockWorkingDir.Clone(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsPullRequest(),
+		AnyString(),
+	)).ThenReturn(repoDir, false, nil)
+
+	ctx := models.ProjectCommandContext{
+		Log: logging.NewNoopLogger(t),
+		Steps: []valid.Step{
+			{
+				StepName: "init",
+			},
+			{
+				StepName: "validate",
+			},
+			{
+				StepName: "fmt",
+			},
+		},
+		Workspace:  "default",
+		RepoRelDir: ".",
+	}
+	expEnvs := map[string]string{}
+	When(mockInit.Run(ctx, nil, repoDir, expEnvs)).ThenReturn("", nil)
+	When(mockValidate.Run(ctx, nil, repoDir, expEnvs)).ThenReturn("Success! The configuration is valid.", nil)
+	When(mockFmt.Run(ctx, nil, repoDir, expEnvs)).ThenReturn("main.tf", errors.New("exit status 3"))
+
+	res := runner.Validate(ctx)
+	Equals(t, models.ValidateCommand, res.Command)
+	ErrEquals(t, "exit status 3\nSuccess! The configuration is valid.\nmain.tf", res.Error)
+	Equals(t, "", res.ValidateSuccess)
+	mockValidate.VerifyWasCalledOnce().Run(ctx, nil, repoDir, expEnvs)
+	// Validate doesn't modify state so it shouldn't lock the project.
+	mockLocker.VerifyWasCalled(Never()).TryLock(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsPullRequest(),
+		matchers.AnyModelsUser(),
+		AnyString(),
+		matchers.AnyModelsProject(),
+		AnyString(),
+	)
+}
+
+// fakeCredentialsProvider returns fixed env vars for every project.
+type fakeCredentialsProvider struct {
+	envs map[string]string
+	err  error
+}
+
+func (f fakeCredentialsProvider) Env(ctx models.ProjectCommandContext) (map[string]string, error) {
+	return f.envs, f.err
+}
+
+func TestDefaultProjectCommandRunner_Credentials(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockInit := mocks.NewMockStepRunner()
+	mockValidate := mocks.NewMockStepRunner()
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	repoDir, cleanup := TempDir(t)
+	defer cleanup()
+	When(mockWorkingDir.Clone(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsPullRequest(),
+		AnyString(),
+	)).ThenReturn(repoDir, false, nil)
+
+	ctx := models.ProjectCommandContext{
+		Log: logging.NewNoopLogger(t),
+		Steps: []valid.Step{
+			{
+				StepName: "init",
+			},
+			{
+				StepName: "validate",
+			},
+		},
+		Workspace:  "default",
+		RepoRelDir: ".",
+		Credentials: &valid.Credentials{
+			AWS: &valid.AWSCredentials{RoleARN: "arn:aws:iam::123456789012:role/prod"},
+		},
+	}
+	credEnvs := map[string]string{
+		"AWS_ACCESS_KEY_ID":     "key-id",
+		"AWS_SECRET_ACCESS_KEY": "secret",
+		"AWS_SESSION_TOKEN":     "token",
+	}
+
+	t.Run("credentials are passed to every step", func(t *testing.T) {
+		runner := events.DefaultProjectCommandRunner{
+			InitStepRunner:      mockInit,
+			ValidateStepRunner:  mockValidate,
+			WorkingDir:          mockWorkingDir,
+			WorkingDirLocker:    events.NewDefaultWorkingDirLocker(),
+			CredentialsProvider: fakeCredentialsProvider{envs: credEnvs},
+		}
+		When(mockInit.Run(ctx, nil, repoDir, credEnvs)).ThenReturn("", nil)
+		When(mockValidate.Run(ctx, nil, repoDir, credEnvs)).ThenReturn("Success! The configuration is valid.", nil)
+
+		res := runner.Validate(ctx)
+		Ok(t, res.Error)
+		Equals(t, "Success! The configuration is valid.", res.ValidateSuccess)
+		mockInit.VerifyWasCalledOnce().Run(ctx, nil, repoDir, credEnvs)
+	})
+
+	t.Run("steps aren't run if credentials can't be obtained", func(t *testing.T) {
+		runner := events.DefaultProjectCommandRunner{
+			InitStepRunner:      mockInit,
+			ValidateStepRunner:  mockValidate,
+			WorkingDir:          mockWorkingDir,
+			WorkingDirLocker:    events.NewDefaultWorkingDirLocker(),
+			CredentialsProvider: fakeCredentialsProvider{err: errors.New("access denied")},
+		}
+		res := runner.Validate(ctx)
+		ErrEquals(t, "obtaining credentials: access denied\n", res.Error)
+		mockValidate.VerifyWasCalledOnce().Run(ctx, nil, repoDir, credEnvs)
+	})
+}
+
+func TestDefaultProjectCommandRunner_Cancelled(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockInit := mocks.NewMockStepRunner()
+	mockValidate := mocks.NewMockStepRunner()
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	repoDir, cleanup := TempDir(t)
+	defer cleanup()
+	When(mockWorkingDir.Clone(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsPullRequest(),
+		AnyString(),
+	)).ThenReturn(repoDir, false, nil)
+	runner := events.DefaultProjectCommandRunner{
+		InitStepRunner:     mockInit,
+		ValidateStepRunner: mockValidate,
+		WorkingDir:         mockWorkingDir,
+		WorkingDirLocker:   events.NewDefaultWorkingDirLocker(),
+	}
+	cancelCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx := models.ProjectCommandContext{
+		Log: logging.NewNoopLogger(t),
+		Steps: []valid.Step{
+			{
+				StepName: "init",
+			},
+			{
+				StepName: "validate",
+			},
+		},
+		Workspace:  "default",
+		RepoRelDir: ".",
+		RequestCtx: cancelCtx,
+	}
+
+	t.Log("the step that's interrupted fails with the cancellation instead of its own error")
+	When(mockInit.Run(ctx, nil, repoDir, map[string]string{})).Then(func(params []Param) ReturnValues {
+		cancel()
+		return []ReturnValue{"", errors.New("signal: interrupt")}
+	})
+	res := runner.Validate(ctx)
+	ErrContains(t, "command was cancelled", res.Error)
+	mockValidate.VerifyWasCalled(Never()).Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())
+
+	t.Log("no steps are run once the command is cancelled")
+	res = runner.Validate(ctx)
+	ErrContains(t, "command was cancelled", res.Error)
+	mockInit.VerifyWasCalledOnce().Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())
+}
+
+func TestDefaultProjectCommandRunner_StepTimeout(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockInit := mocks.NewMockStepRunner()
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	repoDir, cleanup := TempDir(t)
+	defer cleanup()
+	When(mockWorkingDir.Clone(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsPullRequest(),
+		AnyString(),
+	)).ThenReturn(repoDir, false, nil)
+	runner := events.DefaultProjectCommandRunner{
+		InitStepRunner:   mockInit,
+		WorkingDir:       mockWorkingDir,
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+	}
+	ctx := models.ProjectCommandContext{
+		Log: logging.NewNoopLogger(t),
+		Steps: []valid.Step{
+			{
+				StepName: "init",
+				Timeout:  10 * time.Millisecond,
+			},
+		},
+		Workspace:  "default",
+		RepoRelDir: ".",
+	}
+	// The step runs until it's interrupted.
+	When(mockInit.Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())).Then(func(params []Param) ReturnValues {
+		<-params[0].(models.ProjectCommandContext).Context().Done()
+		return []ReturnValue{"", errors.New("signal: interrupt")}
+	})
+
+	res := runner.Validate(ctx)
+	ErrContains(t, "init step timed out after 10ms", res.Error)
+}
+
+func TestDefaultProjectCommandRunner_PlanStateLockConflict(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockPlan := mocks.NewMockStepRunner()
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	mockLocker := mocks.NewMockProjectLocker()
+	tracker := &events.StateLockTracker{}
+	runner := events.DefaultProjectCommandRunner{
+		Locker:           mockLocker,
+		LockURLGenerator: mockURLGenerator{},
+		PlanStepRunner:   mockPlan,
+		WorkingDir:       mockWorkingDir,
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+		StateLocks:       tracker,
+	}
+	repoDir, cleanup := TempDir(t)
+	defer cleanup()
+	When(mockWorkingDir.Clone(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsPullRequest(),
+		AnyString(),
+	)).ThenReturn(repoDir, false, nil)
+	When(mockLocker.TryLock(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsPullRequest(),
+		matchers.AnyModelsUser(),
+		AnyString(),
+		matchers.AnyModelsProject(),
+		AnyString(),
+	)).ThenReturn(&events.TryLockResponse{
+		LockAcquired: true,
+		LockKey:      "lock-key",
+		UnlockFn:     func() error { return nil },
+	}, nil)
+	When(mockPlan.Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())).ThenReturn(`Acquiring state lock. This may take a few moments...
+╷
+│ Error: Error acquiring the state lock
+│
+│ Error message: ConditionalCheckFailedException: The conditional request failed
+│ Lock Info:
+│   ID:        2b8a4d2c-1f0e-4c3b-9a7e-5d6f7a8b9c0d
+│   Path:      tf-state/prod/terraform.tfstate
+│   Operation: OperationTypeApply
+│   Who:       runner@ci-42
+│   Version:   1.5.7
+│   Created:   2023-10-02 09:15:43.123456 +0000 UTC
+│   Info:
+╵`, errors.New("exit status 1"))
+
+	res := runner.Plan(models.ProjectCommandContext{
+		Log:        logging.NewNoopLogger(t),
+		Steps:      []valid.Step{{StepName: "plan"}},
+		Workspace:  "default",
+		RepoRelDir: ".",
+		RePlanCmd:  "atlantis plan -d .",
+		Pull:       models.PullRequest{Num: 1, BaseRepo: models.Repo{FullName: "owner/repo"}},
+	})
+	Ok(t, res.Error)
+	Equals(t, "Terraform couldn't acquire the state lock because it's held by `runner@ci-42` for `OperationTypeApply` since 2023-10-02 09:15:43.123456 +0000 UTC. Another run is likely using this project's state, ex. a different pipeline or a local terraform command. Wait for it to finish and then comment `atlantis plan -d .` to try again. If nothing is using the state, its lock can be released with `terraform force-unlock 2b8a4d2c-1f0e-4c3b-9a7e-5d6f7a8b9c0d`.", res.Failure)
+	stats := tracker.GetStats()
+	Equals(t, 1, stats.Conflicts)
+	Equals(t, map[string]int{"owner/repo": 1}, stats.Repos)
+}
+
+func TestDefaultProjectCommandRunner_PlanRetriesTransientErrors(t *testing.T) {
+	cases := []struct {
+		description string
+		maxRetries  int
+		initErrs    []string
+		expRetries  []models.StepRetry
+		expErr      bool
+	}{
+		{
+			description: "retried until it succeeds",
+			maxRetries:  2,
+			initErrs:    []string{"Error: connection reset by peer", "Error: TLS handshake timeout"},
+			expRetries: []models.StepRetry{
+				{StepName: "init", Attempt: 1, Error: "Error: connection reset by peer"},
+				{StepName: "init", Attempt: 2, Error: "Error: TLS handshake timeout"},
+			},
+		},
+		{
+			description: "retries disabled",
+			maxRetries:  0,
+			initErrs:    []string{"Error: connection reset by peer"},
+			expErr:      true,
+		},
+		{
+			description: "out of retries",
+			maxRetries:  1,
+			initErrs:    []string{"Error: connection reset by peer", "Error: connection reset by peer"},
+			expErr:      true,
+		},
+		{
+			description: "not transient",
+			maxRetries:  2,
+			initErrs:    []string{"Error: Unsupported argument"},
+			expErr:      true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			mockInit := mocks.NewMockStepRunner()
+			mockPlan := mocks.NewMockStepRunner()
+			mockWorkingDir := mocks.NewMockWorkingDir()
+			mockLocker := mocks.NewMockProjectLocker()
+			runner := events.DefaultProjectCommandRunner{
+				Locker:           mockLocker,
+				LockURLGenerator: mockURLGenerator{},
+				InitStepRunner:   mockInit,
+				PlanStepRunner:   mockPlan,
+				WorkingDir:       mockWorkingDir,
+				WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+				MaxStepRetries:   c.maxRetries,
+				StepRetryBackoff: time.Millisecond,
+			}
+			repoDir, cleanup := TempDir(t)
+			defer cleanup()
+			When(mockWorkingDir.Clone(
+				matchers.AnyPtrToLoggingSimpleLogger(),
+				matchers.AnyModelsRepo(),
+				matchers.AnyModelsPullRequest(),
+				AnyString(),
+			)).ThenReturn(repoDir, false, nil)
+			When(mockLocker.TryLock(
+				matchers.AnyPtrToLoggingSimpleLogger(),
+				matchers.AnyModelsPullRequest(),
+				matchers.AnyModelsUser(),
+				AnyString(),
+				matchers.AnyModelsProject(),
+				AnyString(),
+			)).ThenReturn(&events.TryLockResponse{
+				LockAcquired: true,
+				LockKey:      "lock-key",
+				UnlockFn:     func() error { return nil },
+			}, nil)
+			stubbing := When(mockInit.Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString()))
+			for _, initErr := range c.initErrs {
+				stubbing = stubbing.ThenReturn(initErr, errors.New("exit status 1"))
 			}
-			When(mockInit.Run(ctx, nil, repoDir, expEnvs)).ThenReturn("init", nil)
-			When(mockPlan.Run(ctx, nil, repoDir, expEnvs)).ThenReturn("plan", nil)
-			When(mockApply.Run(ctx, nil, repoDir, expEnvs)).ThenReturn("apply", nil)
-			When(mockRun.Run(ctx, "", repoDir, expEnvs)).ThenReturn("run", nil)
-			When(mockEnv.Run(ctx, "", "value", repoDir, make(map[string]string))).ThenReturn("value", nil)
-			When(mockApproved.PullIsApproved(ctx.BaseRepo, ctx.Pull)).ThenReturn(true, nil)
-
-			res := runner.Apply(ctx)
-			Equals(t, c.expOut, res.ApplySuccess)
-			Equals(t, c.expFailure, res.Failure)
+			stubbing.ThenReturn("init", nil)
+			When(mockPlan.Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())).ThenReturn("plan", nil)
 
-			for _, step := range c.expSteps {
-				switch step {
-				case "approved":
-					mockApproved.VerifyWasCalledOnce().PullIsApproved(ctx.BaseRepo, ctx.Pull)
-				case "init":
-					mockInit.VerifyWasCalledOnce().Run(ctx, nil, repoDir, expEnvs)
-				case "plan":
-					mockPlan.VerifyWasCalledOnce().Run(ctx, nil, repoDir, expEnvs)
-				case "apply":
-					mockApply.VerifyWasCalledOnce().Run(ctx, nil, repoDir, expEnvs)
-				case "run":
-					mockRun.VerifyWasCalledOnce().Run(ctx, "", repoDir, expEnvs)
-				case "env":
-					mockEnv.VerifyWasCalledOnce().Run(ctx, "", "value", repoDir, expEnvs)
-				}
+			res := runner.Plan(models.ProjectCommandContext{
+				Log:        logging.NewNoopLogger(t),
+				Steps:      []valid.Step{{StepName: "init"}, {StepName: "plan"}},
+				Workspace:  "default",
+				RepoRelDir: ".",
+			})
+			if c.expErr {
+				Assert(t, res.Error != nil, "expected an error")
+				mockPlan.VerifyWasCalled(Never()).Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())
+				return
 			}
+			Ok(t, res.Error)
+			Equals(t, "init\nplan", res.PlanSuccess.TerraformOutput)
+			Equals(t, c.expRetries, res.PlanSuccess.Retries)
 		})
 	}
 }
 
+func TestDefaultProjectCommandRunner_PlanScan(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockPlan := mocks.NewMockStepRunner()
+	mockScan := mocks.NewMockScanStepRunner()
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	mockLocker := mocks.NewMockProjectLocker()
+	runner := events.DefaultProjectCommandRunner{
+		Locker:           mockLocker,
+		LockURLGenerator: mockURLGenerator{},
+		PlanStepRunner:   mockPlan,
+		ScanStepRunner:   mockScan,
+		WorkingDir:       mockWorkingDir,
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+	}
+	repoDir, cleanup := TempDir(t)
+	defer cleanup()
+	When(mockWorkingDir.Clone(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsPullRequest(),
+		AnyString(),
+	)).ThenReturn(repoDir, false, nil)
+	When(mockLocker.TryLock(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsPullRequest(),
+		matchers.AnyModelsUser(),
+		AnyString(),
+		matchers.AnyModelsProject(),
+		AnyString(),
+	)).ThenReturn(&events.TryLockResponse{
+		LockAcquired: true,
+		LockKey:      "lock-key",
+		UnlockFn:     func() error { return nil },
+	}, nil)
+	planFile := filepath.Join(repoDir, "default.tfplan")
+	When(mockPlan.Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())).Then(func(params []Param) ReturnValues {
+		return []ReturnValue{"plan", ioutil.WriteFile(planFile, nil, 0600)}
+	})
+	var failOn string
+	When(mockScan.Run(matchers.AnyModelsProjectCommandContext(), matchers.AnyValidScanOptions(), AnyStringSlice(), AnyString(), matchers.AnyMapOfStringToString())).Then(func(params []Param) ReturnValues {
+		err := ioutil.WriteFile(filepath.Join(repoDir, "default.scan.json"), []byte(`[{"Tool":"tfsec","FailOn":"`+failOn+`","Findings":[{"RuleID":"aws-s3-block-public-acls","Description":"No public access block","Severity":"HIGH","Resource":"aws_s3_bucket.logs","Location":"s3.tf:3"}]}]`), 0600)
+		return []ReturnValue{"", err}
+	})
+	ctx := models.ProjectCommandContext{
+		Log:        logging.NewNoopLogger(t),
+		Steps:      []valid.Step{{StepName: "plan"}, {StepName: "scan", Scan: &valid.ScanOptions{Tool: "tfsec"}}},
+		Workspace:  "default",
+		RepoRelDir: ".",
+		RePlanCmd:  "atlantis plan -d .",
+	}
+
+	t.Log("findings below the threshold are shown with the plan")
+	failOn = "CRITICAL"
+	res := runner.Plan(ctx)
+	Ok(t, res.Error)
+	Equals(t, "", res.Failure)
+	Equals(t, "plan", res.PlanSuccess.TerraformOutput)
+	Equals(t, []models.ScanResult{{
+		Tool:     "tfsec",
+		FailOn:   "CRITICAL",
+		Findings: []models.ScanFinding{{RuleID: "aws-s3-block-public-acls", Description: "No public access block", Severity: "HIGH", Resource: "aws_s3_bucket.logs", Location: "s3.tf:3"}},
+	}}, res.PlanSuccess.ScanResults)
+	_, err := os.Stat(planFile)
+	Ok(t, err)
+
+	t.Log("findings at the threshold fail the plan and delete it")
+	failOn = "HIGH"
+	res = runner.Plan(ctx)
+	Ok(t, res.Error)
+	Assert(t, res.PlanSuccess == nil, "expected no plan")
+	Equals(t, "The security scan found issues at or above the severity it fails on so the plan was discarded. Fix them and comment `atlantis plan -d .` to plan again.\n\n"+
+		":mag: **tfsec** found 1 issue(s), plans fail on `HIGH` or higher:\n\n**HIGH (1)**\n* `aws-s3-block-public-acls` `aws_s3_bucket.logs` at `s3.tf:3`: No public access block", res.Failure)
+	_, err = os.Stat(planFile)
+	Assert(t, os.IsNotExist(err), "expected plan to be deleted")
+
+	t.Log("results from a previous plan aren't used")
+	ctx.Steps = []valid.Step{{StepName: "plan"}}
+	res = runner.Plan(ctx)
+	Ok(t, res.Error)
+	Equals(t, []models.ScanResult(nil), res.PlanSuccess.ScanResults)
+}
+
 // Test run and env steps. We don't use mocks for this test since we're
 // not running any Terraform.
 func TestDefaultProjectCommandRunner_RunEnvSteps(t *testing.T) {
@@ -372,6 +1660,9 @@ func TestDefaultProjectCommandRunner_RunEnvSteps(t *testing.T) {
 	env := runtime.EnvStepRunner{
 		RunStepRunner: &run,
 	}
+	multiEnv := runtime.MultiEnvStepRunner{
+		RunStepRunner: &run,
+	}
 	mockWorkingDir := mocks.NewMockWorkingDir()
 	mockLocker := mocks.NewMockProjectLocker()
 
@@ -380,6 +1671,7 @@ func TestDefaultProjectCommandRunner_RunEnvSteps(t *testing.T) {
 		LockURLGenerator:    mockURLGenerator{},
 		RunStepRunner:       &run,
 		EnvStepRunner:       &env,
+		MultiEnvStepRunner:  &multiEnv,
 		PullApprovedChecker: nil,
 		WorkingDir:          mockWorkingDir,
 		Webhooks:            nil,
@@ -400,6 +1692,7 @@ func TestDefaultProjectCommandRunner_RunEnvSteps(t *testing.T) {
 		matchers.AnyModelsUser(),
 		AnyString(),
 		matchers.AnyModelsProject(),
+		AnyString(),
 	)).ThenReturn(&events.TryLockResponse{
 		LockAcquired: true,
 		LockKey:      "lock-key",
@@ -440,6 +1733,15 @@ func TestDefaultProjectCommandRunner_RunEnvSteps(t *testing.T) {
 				StepName:   "run",
 				RunCommand: "echo dynamic_var=$dynamic_var",
 			},
+			// Test setting multiple variables at once
+			{
+				StepName:   "multienv",
+				RunCommand: "echo multi1=one && echo multi2=$var",
+			},
+			{
+				StepName:   "run",
+				RunCommand: "echo multi1=$multi1 multi2=$multi2",
+			},
 		},
 		Workspace:  "default",
 		RepoRelDir: ".",
@@ -447,7 +1749,7 @@ func TestDefaultProjectCommandRunner_RunEnvSteps(t *testing.T) {
 	res := runner.Plan(ctx)
 	Assert(t, res.PlanSuccess != nil, "exp plan success")
 	Equals(t, "https://lock-key", res.PlanSuccess.LockURL)
-	Equals(t, "var=\n\nvar=value\n\ndynamic_var=dynamic_value\n\ndynamic_var=overridden\n", res.PlanSuccess.TerraformOutput)
+	Equals(t, "var=\n\nvar=value\n\ndynamic_var=dynamic_value\n\ndynamic_var=overridden\n\nmulti1=one multi2=value\n", res.PlanSuccess.TerraformOutput)
 }
 
 type mockURLGenerator struct{}
@@ -455,3 +1757,21 @@ type mockURLGenerator struct{}
 func (m mockURLGenerator) GenerateLockURL(lockID string) string {
 	return "https://" + lockID
 }
+
+func (m mockURLGenerator) GenerateHistoryURL(repoFullName string, pullNum int) string {
+	return fmt.Sprintf("https://history/%s/%d", repoFullName, pullNum)
+}
+
+func (m mockURLGenerator) GenerateProjectHistoryURL(repoFullName string, pullNum int, repoRelDir string, workspace string) string {
+	return fmt.Sprintf("https://history/%s/%d/%s/%s", repoFullName, pullNum, repoRelDir, workspace)
+}
+
+// recordingWebhooksSender records every result it's sent.
+type recordingWebhooksSender struct {
+	results []webhooks.ApplyResult
+}
+
+func (r *recordingWebhooksSender) Send(_ logging.SimpleLogging, res webhooks.ApplyResult) error {
+	r.results = append(r.results, res)
+	return nil
+}
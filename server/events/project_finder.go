@@ -17,12 +17,14 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 
 	"github.com/docker/docker/pkg/fileutils"
 	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/core/modules"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/logging"
 )
@@ -38,18 +40,28 @@ type ProjectFinder interface {
 	// based on modifiedFiles and the repo's config.
 	// absRepoDir is the path to the cloned repo on disk.
 	DetermineProjectsViaConfig(log logging.SimpleLogging, modifiedFiles []string, config valid.RepoCfg, absRepoDir string) ([]valid.Project, error)
+	// DetermineTriggeredDirs returns the dirs, relative to the repo root, of
+	// the projects that should be planned because modifiedFiles matched one of
+	// the autoplan triggers from the server-side repo config.
+	// absRepoDir is the path to the cloned repo on disk.
+	DetermineTriggeredDirs(log logging.SimpleLogging, modifiedFiles []string, triggers []valid.AutoplanTrigger, absRepoDir string) ([]string, error)
 }
 
 // ignoredFilenameFragments contains filename fragments to ignore while looking at changes
 var ignoredFilenameFragments = []string{"terraform.tfstate", "terraform.tfstate.backup", "tflint.hcl"}
 
 // DefaultProjectFinder implements ProjectFinder.
-type DefaultProjectFinder struct{}
+type DefaultProjectFinder struct {
+	// AutoplanModules is true if modifying a local module should cause the
+	// projects that call it to be planned.
+	AutoplanModules bool
+}
 
 // See ProjectFinder.DetermineProjects.
 func (p *DefaultProjectFinder) DetermineProjects(log logging.SimpleLogging, modifiedFiles []string, repoFullName string, absRepoDir string, autoplanFileList string) []models.Project {
 	var projects []models.Project
 
+	modifiedFiles = p.addSymlinkedPaths(log, modifiedFiles, absRepoDir)
 	modifiedTerraformFiles := p.filterToFileList(log, modifiedFiles, autoplanFileList)
 	if len(modifiedTerraformFiles) == 0 {
 		return projects
@@ -64,6 +76,14 @@ func (p *DefaultProjectFinder) DetermineProjects(log logging.SimpleLogging, modi
 			dirs = append(dirs, projectDir)
 		}
 	}
+	if p.AutoplanModules {
+		graph, err := modules.BuildDependencyGraph(log, absRepoDir)
+		if err != nil {
+			log.Warn("unable to build module dependency graph, projects that call modified modules won't be planned: %s", err)
+		} else {
+			dirs = append(dirs, p.moduleDependents(log, modifiedTerraformFiles, graph)...)
+		}
+	}
 	uniqueDirs := p.unique(dirs)
 
 	// The list of modified files will include files that were deleted. We still
@@ -83,27 +103,30 @@ func (p *DefaultProjectFinder) DetermineProjects(log logging.SimpleLogging, modi
 // See ProjectFinder.DetermineProjectsViaConfig.
 func (p *DefaultProjectFinder) DetermineProjectsViaConfig(log logging.SimpleLogging, modifiedFiles []string, config valid.RepoCfg, absRepoDir string) ([]valid.Project, error) {
 	var projects []valid.Project
+
+	// If we haven't cloned the repo yet (see below), we can't find which
+	// modules the projects use or resolve symlinks.
+	var graph *modules.DependencyGraph
+	if absRepoDir != "" {
+		modifiedFiles = p.addSymlinkedPaths(log, modifiedFiles, absRepoDir)
+	}
+	if p.AutoplanModules && absRepoDir != "" {
+		var err error
+		graph, err = modules.BuildDependencyGraph(log, absRepoDir)
+		if err != nil {
+			log.Warn("unable to build module dependency graph, projects that call modified modules won't be planned: %s", err)
+		}
+	}
+
 	for _, project := range config.Projects {
 		log.Debug("checking if project at dir %q workspace %q was modified", project.Dir, project.Workspace)
-		var whenModifiedRelToRepoRoot []string
-		for _, wm := range project.Autoplan.WhenModified {
-			wm = strings.TrimSpace(wm)
-			// An exclusion uses a '!' at the beginning. If it's there, we need
-			// to remove it, then add in the project path, then add it back.
-			exclusion := false
-			if wm != "" && wm[0] == '!' {
-				wm = wm[1:]
-				exclusion = true
-			}
-
-			// Prepend project dir to when modified patterns because the patterns
-			// are relative to the project dirs but our list of modified files is
-			// relative to the repo root.
-			wmRelPath := filepath.Join(project.Dir, wm)
-			if exclusion {
-				wmRelPath = "!" + wmRelPath
+		whenModifiedRelToRepoRoot := p.whenModifiedRelToRepoRoot(project.Dir, project.Autoplan.WhenModified)
+		if graph != nil {
+			// The project's when_modified patterns also apply to the local
+			// modules it uses, relative to each module's dir.
+			for _, moduleDir := range graph.Dependencies(project.Dir) {
+				whenModifiedRelToRepoRoot = append(whenModifiedRelToRepoRoot, p.whenModifiedRelToRepoRoot(moduleDir, project.Autoplan.WhenModified)...)
 			}
-			whenModifiedRelToRepoRoot = append(whenModifiedRelToRepoRoot, wmRelPath)
 		}
 		pm, err := fileutils.NewPatternMatcher(whenModifiedRelToRepoRoot)
 		if err != nil {
@@ -144,6 +167,186 @@ func (p *DefaultProjectFinder) DetermineProjectsViaConfig(log logging.SimpleLogg
 	return projects, nil
 }
 
+// whenModifiedRelToRepoRoot returns the whenModified patterns, which are
+// relative to dir, as patterns relative to the repo root.
+func (p *DefaultProjectFinder) whenModifiedRelToRepoRoot(dir string, whenModified []string) []string {
+	var patterns []string
+	for _, wm := range whenModified {
+		wm = strings.TrimSpace(wm)
+		// An exclusion uses a '!' at the beginning. If it's there, we need
+		// to remove it, then add in the dir, then add it back.
+		exclusion := false
+		if wm != "" && wm[0] == '!' {
+			wm = wm[1:]
+			exclusion = true
+		}
+
+		// Prepend dir to when modified patterns because the patterns are
+		// relative to dir but our list of modified files is relative to the
+		// repo root.
+		wmRelPath := filepath.Join(dir, wm)
+		if exclusion {
+			wmRelPath = "!" + wmRelPath
+		}
+		patterns = append(patterns, wmRelPath)
+	}
+	return patterns
+}
+
+// See ProjectFinder.DetermineTriggeredDirs.
+func (p *DefaultProjectFinder) DetermineTriggeredDirs(log logging.SimpleLogging, modifiedFiles []string, triggers []valid.AutoplanTrigger, absRepoDir string) ([]string, error) {
+	if len(triggers) == 0 {
+		return nil, nil
+	}
+
+	var dirs []string
+	// graph is only built if a trigger needs it since it requires parsing
+	// every Terraform file in the repo.
+	var graph *modules.DependencyGraph
+	for _, trigger := range triggers {
+		pm, err := fileutils.NewPatternMatcher(trigger.WhenModified)
+		if err != nil {
+			return nil, errors.Wrapf(err, "matching modified files with patterns: %v", trigger.WhenModified)
+		}
+		var matched []string
+		for _, file := range modifiedFiles {
+			match, err := pm.Matches(file)
+			if err != nil {
+				log.Debug("match err for file %q: %s", file, err)
+				continue
+			}
+			if match {
+				matched = append(matched, file)
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+		log.Debug("files %v matched autoplan trigger %v", matched, trigger.WhenModified)
+
+		if len(trigger.Dirs) > 0 {
+			for _, d := range trigger.Dirs {
+				dirs = append(dirs, path.Clean(d))
+			}
+			continue
+		}
+
+		if graph == nil {
+			graph, err = modules.BuildDependencyGraph(log, absRepoDir)
+			if err != nil {
+				return nil, errors.Wrap(err, "building module dependency graph")
+			}
+		}
+		dirs = append(dirs, p.moduleDependents(log, matched, graph)...)
+	}
+
+	exists := p.removeNonExistingDirs(p.unique(dirs), absRepoDir)
+	log.Info("autoplan triggers matched %d project(s) at path(s): %v",
+		len(exists), strings.Join(exists, ", "))
+	return exists, nil
+}
+
+// addSymlinkedPaths returns modifiedFiles plus the paths through which
+// symlinks in the repo reference them. For example if project1/common.tfvars
+// links to shared/common.tfvars and shared/common.tfvars was modified then
+// project1/common.tfvars is added, so project1 is considered modified. Links to
+// dirs are followed too so a link to a shared module dir adds the paths of the
+// modified files inside it.
+func (p *DefaultProjectFinder) addSymlinkedPaths(log logging.SimpleLogging, modifiedFiles []string, absRepoDir string) []string {
+	links, err := p.findSymlinks(absRepoDir)
+	if err != nil {
+		log.Warn("unable to find symlinks, projects that reference modified files through them won't be planned: %s", err)
+		return modifiedFiles
+	}
+	if len(links) == 0 {
+		return modifiedFiles
+	}
+
+	// Links are sorted so that the paths, and so the projects, are always in
+	// the same order.
+	var sortedLinks []string
+	for link := range links {
+		sortedLinks = append(sortedLinks, link)
+	}
+	sort.Strings(sortedLinks)
+
+	paths := append([]string{}, modifiedFiles...)
+	for _, file := range modifiedFiles {
+		for _, link := range sortedLinks {
+			target := links[link]
+			var linkedPath string
+			switch {
+			case file == target:
+				linkedPath = link
+			case strings.HasPrefix(file, target+"/"):
+				linkedPath = path.Join(link, strings.TrimPrefix(file, target+"/"))
+			default:
+				continue
+			}
+			log.Debug("file %q is referenced through symlink %q", file, link)
+			paths = append(paths, linkedPath)
+		}
+	}
+	return p.unique(paths)
+}
+
+// findSymlinks returns the symlinks in absRepoDir that resolve to a path
+// inside it, mapped to the path they resolve to. Both are relative to
+// absRepoDir. Links that can't be resolved or that resolve outside of the
+// repo are ignored.
+func (p *DefaultProjectFinder) findSymlinks(absRepoDir string) (map[string]string, error) {
+	// The repo dir is resolved too so that, ex. on macOS where the temp dir is
+	// itself a symlink, resolved links are still inside it.
+	resolvedRepoDir, err := filepath.EvalSymlinks(absRepoDir)
+	if err != nil {
+		return nil, err
+	}
+	links := make(map[string]string)
+	err = filepath.Walk(absRepoDir, func(absPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && (info.Name() == ".git" || info.Name() == ".terraform") {
+			return filepath.SkipDir
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			return nil
+		}
+		resolved, err := filepath.EvalSymlinks(absPath)
+		if err != nil {
+			return nil
+		}
+		target, err := filepath.Rel(resolvedRepoDir, resolved)
+		if err != nil || target == ".." || strings.HasPrefix(target, ".."+string(filepath.Separator)) {
+			return nil
+		}
+		link, err := filepath.Rel(absRepoDir, absPath)
+		if err != nil {
+			return err
+		}
+		links[filepath.ToSlash(link)] = filepath.ToSlash(target)
+		return nil
+	})
+	return links, err
+}
+
+// moduleDependents returns the dirs of the root modules that call the local
+// modules that files are part of.
+func (p *DefaultProjectFinder) moduleDependents(log logging.SimpleLogging, files []string, graph *modules.DependencyGraph) []string {
+	var dirs []string
+	for _, file := range files {
+		moduleDir := graph.ModuleDir(path.Dir(file))
+		if moduleDir == "" {
+			log.Debug("file %q is not part of a module called from this repo", file)
+			continue
+		}
+		dependents := graph.Dependents(moduleDir)
+		log.Debug("module at dir %q is used by %v", moduleDir, dependents)
+		dirs = append(dirs, dependents...)
+	}
+	return dirs
+}
+
 // filterToFileList filters out files not included in the file list
 func (p *DefaultProjectFinder) filterToFileList(log logging.SimpleLogging, files []string, fileList string) []string {
 	var filtered []string
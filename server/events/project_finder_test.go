@@ -17,6 +17,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"testing"
 
 	"github.com/runatlantis/atlantis/server/events"
@@ -508,3 +509,330 @@ func TestDefaultProjectFinder_DetermineProjectsViaConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestDefaultProjectFinder_DetermineTriggeredDirs(t *testing.T) {
+	// Create dir structure:
+	// project1/
+	//   main.tf # calls ../modules/vpc
+	// project2/
+	//   main.tf # calls ../modules/network
+	// project3/
+	//   main.tf # calls a registry module
+	// shared/
+	//   common.tfvars
+	// modules/
+	//   network/
+	//     main.tf # calls ../vpc
+	//   vpc/
+	//     main.tf
+	//     templates/
+	//       user_data.sh
+	//   unused/
+	//     main.tf
+	tmpDir, cleanup := DirStructure(t, map[string]interface{}{
+		"project1": map[string]interface{}{
+			"main.tf": `module "vpc" { source = "../modules/vpc" }`,
+		},
+		"project2": map[string]interface{}{
+			"main.tf": `module "network" { source = "../modules/network" }`,
+		},
+		"project3": map[string]interface{}{
+			"main.tf": `module "vpc" { source = "terraform-aws-modules/vpc/aws" }`,
+		},
+		"shared": map[string]interface{}{
+			"common.tfvars": nil,
+		},
+		"modules": map[string]interface{}{
+			"network": map[string]interface{}{
+				"main.tf": `module "vpc" { source = "../vpc" }`,
+			},
+			"vpc": map[string]interface{}{
+				"main.tf": nil,
+				"templates": map[string]interface{}{
+					"user_data.sh": nil,
+				},
+			},
+			"unused": map[string]interface{}{
+				"main.tf": nil,
+			},
+		},
+	})
+	defer cleanup()
+
+	cases := []struct {
+		description string
+		triggers    []valid.AutoplanTrigger
+		modified    []string
+		expDirs     []string
+	}{
+		{
+			description: "no triggers",
+			triggers:    nil,
+			modified:    []string{"modules/vpc/main.tf"},
+			expDirs:     nil,
+		},
+		{
+			description: "module change plans direct and indirect callers",
+			triggers: []valid.AutoplanTrigger{
+				{WhenModified: []string{"modules/**"}},
+			},
+			modified: []string{"modules/vpc/main.tf"},
+			expDirs:  []string{"project1", "project2"},
+		},
+		{
+			description: "non-terraform file in module",
+			triggers: []valid.AutoplanTrigger{
+				{WhenModified: []string{"modules/**"}},
+			},
+			modified: []string{"modules/vpc/templates/user_data.sh"},
+			expDirs:  []string{"project1", "project2"},
+		},
+		{
+			description: "change to intermediate module",
+			triggers: []valid.AutoplanTrigger{
+				{WhenModified: []string{"modules/**"}},
+			},
+			modified: []string{"modules/network/main.tf"},
+			expDirs:  []string{"project2"},
+		},
+		{
+			description: "module that isn't called",
+			triggers: []valid.AutoplanTrigger{
+				{WhenModified: []string{"modules/**"}},
+			},
+			modified: []string{"modules/unused/main.tf"},
+			expDirs:  nil,
+		},
+		{
+			description: "no match",
+			triggers: []valid.AutoplanTrigger{
+				{WhenModified: []string{"modules/**"}},
+			},
+			modified: []string{"project1/main.tf"},
+			expDirs:  nil,
+		},
+		{
+			description: "explicit dirs",
+			triggers: []valid.AutoplanTrigger{
+				{WhenModified: []string{"shared/*.tfvars"}, Dirs: []string{"project1", "project3/", "deleted"}},
+			},
+			modified: []string{"shared/common.tfvars"},
+			expDirs:  []string{"project1", "project3"},
+		},
+		{
+			description: "multiple triggers are de-duplicated",
+			triggers: []valid.AutoplanTrigger{
+				{WhenModified: []string{"shared/*.tfvars"}, Dirs: []string{"project1"}},
+				{WhenModified: []string{"modules/**"}},
+			},
+			modified: []string{"shared/common.tfvars", "modules/vpc/main.tf"},
+			expDirs:  []string{"project1", "project2"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			pf := events.DefaultProjectFinder{}
+			dirs, err := pf.DetermineTriggeredDirs(logging.NewNoopLogger(t), c.modified, c.triggers, tmpDir)
+			Ok(t, err)
+			Equals(t, c.expDirs, dirs)
+		})
+	}
+}
+
+func TestDefaultProjectFinder_AutoplanModules(t *testing.T) {
+	// Create dir structure:
+	// project1/
+	//   main.tf # calls ../modules/vpc
+	// project2/
+	//   main.tf
+	// modules/
+	//   vpc/
+	//     main.tf
+	//     README.md
+	tmpDir, cleanup := DirStructure(t, map[string]interface{}{
+		"project1": map[string]interface{}{
+			"main.tf": `module "vpc" { source = "../modules/vpc" }`,
+		},
+		"project2": map[string]interface{}{
+			"main.tf": nil,
+		},
+		"modules": map[string]interface{}{
+			"vpc": map[string]interface{}{
+				"main.tf":   nil,
+				"README.md": nil,
+			},
+		},
+	})
+	defer cleanup()
+	logger := logging.NewNoopLogger(t)
+	config := valid.RepoCfg{
+		Projects: []valid.Project{
+			{
+				Dir:      "project1",
+				Autoplan: valid.Autoplan{Enabled: true, WhenModified: []string{"**/*.tf"}},
+			},
+			{
+				Dir:      "project2",
+				Autoplan: valid.Autoplan{Enabled: true, WhenModified: []string{"**/*.tf"}},
+			},
+		},
+	}
+
+	cases := []struct {
+		description     string
+		autoplanModules bool
+		modified        []string
+		expProjPaths    []string
+	}{
+		{
+			description:     "disabled",
+			autoplanModules: false,
+			modified:        []string{"modules/vpc/main.tf"},
+			expProjPaths:    nil,
+		},
+		{
+			description:     "module modified",
+			autoplanModules: true,
+			modified:        []string{"modules/vpc/main.tf"},
+			expProjPaths:    []string{"project1"},
+		},
+		{
+			description:     "module file not matching patterns",
+			autoplanModules: true,
+			modified:        []string{"modules/vpc/README.md"},
+			expProjPaths:    nil,
+		},
+		{
+			description:     "module and project modified",
+			autoplanModules: true,
+			modified:        []string{"modules/vpc/main.tf", "project2/main.tf"},
+			expProjPaths:    []string{"project1", "project2"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			pf := events.DefaultProjectFinder{AutoplanModules: c.autoplanModules}
+
+			projects := pf.DetermineProjects(logger, c.modified, "owner/repo", tmpDir, "**/*.tf")
+			var paths []string
+			for _, p := range projects {
+				paths = append(paths, p.Path)
+			}
+			sort.Strings(paths)
+			Equals(t, c.expProjPaths, paths)
+
+			cfgProjects, err := pf.DetermineProjectsViaConfig(logger, c.modified, config, tmpDir)
+			Ok(t, err)
+			paths = nil
+			for _, p := range cfgProjects {
+				paths = append(paths, p.Dir)
+			}
+			Equals(t, c.expProjPaths, paths)
+		})
+	}
+}
+
+func TestDefaultProjectFinder_Symlinks(t *testing.T) {
+	// Create dir structure:
+	// project1/
+	//   main.tf
+	//   common.tfvars -> ../shared/common.tfvars
+	//   modules/
+	//     vpc -> ../../modules/vpc
+	// project2/
+	//   main.tf
+	// shared/
+	//   common.tfvars
+	// modules/
+	//   vpc/
+	//     main.tf
+	tmpDir, cleanup := DirStructure(t, map[string]interface{}{
+		"project1": map[string]interface{}{
+			"main.tf": nil,
+			"modules": map[string]interface{}{},
+		},
+		"project2": map[string]interface{}{
+			"main.tf": nil,
+		},
+		"shared": map[string]interface{}{
+			"common.tfvars": nil,
+		},
+		"modules": map[string]interface{}{
+			"vpc": map[string]interface{}{
+				"main.tf": nil,
+			},
+		},
+	})
+	defer cleanup()
+	Ok(t, os.Symlink("../shared/common.tfvars", filepath.Join(tmpDir, "project1", "common.tfvars")))
+	Ok(t, os.Symlink("../../modules/vpc", filepath.Join(tmpDir, "project1", "modules", "vpc")))
+	logger := logging.NewNoopLogger(t)
+	config := valid.RepoCfg{
+		Projects: []valid.Project{
+			{
+				Dir:      "project1",
+				Autoplan: valid.Autoplan{Enabled: true, WhenModified: []string{"**/*.tf*"}},
+			},
+			{
+				Dir:      "project2",
+				Autoplan: valid.Autoplan{Enabled: true, WhenModified: []string{"**/*.tf*"}},
+			},
+		},
+	}
+
+	// Without a config, shared/ is still planned too since it contains a
+	// modified file.
+	cases := []struct {
+		description  string
+		modified     []string
+		expProjPaths []string
+		expCfgPaths  []string
+	}{
+		{
+			description:  "linked file modified",
+			modified:     []string{"shared/common.tfvars"},
+			expProjPaths: []string{"project1", "shared"},
+			expCfgPaths:  []string{"project1"},
+		},
+		{
+			description:  "file in linked dir modified",
+			modified:     []string{"modules/vpc/main.tf"},
+			expProjPaths: []string{"project1"},
+			expCfgPaths:  []string{"project1"},
+		},
+		{
+			description:  "linked file and project modified",
+			modified:     []string{"shared/common.tfvars", "project2/main.tf"},
+			expProjPaths: []string{"project1", "project2", "shared"},
+			expCfgPaths:  []string{"project1", "project2"},
+		},
+		{
+			description:  "unlinked file modified",
+			modified:     []string{"project2/main.tf"},
+			expProjPaths: []string{"project2"},
+			expCfgPaths:  []string{"project2"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			pf := events.DefaultProjectFinder{}
+
+			projects := pf.DetermineProjects(logger, c.modified, "owner/repo", tmpDir, "**/*.tf,**/*.tfvars")
+			var paths []string
+			for _, p := range projects {
+				paths = append(paths, p.Path)
+			}
+			sort.Strings(paths)
+			Equals(t, c.expProjPaths, paths)
+
+			cfgProjects, err := pf.DetermineProjectsViaConfig(logger, c.modified, config, tmpDir)
+			Ok(t, err)
+			paths = nil
+			for _, p := range cfgProjects {
+				paths = append(paths, p.Dir)
+			}
+			Equals(t, c.expCfgPaths, paths)
+		})
+	}
+}
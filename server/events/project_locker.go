@@ -14,11 +14,10 @@
 package events
 
 import (
-	"fmt"
-
 	"github.com/runatlantis/atlantis/server/core/locking"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/i18n"
 	"github.com/runatlantis/atlantis/server/logging"
 )
 
@@ -32,14 +31,18 @@ type ProjectLocker interface {
 	// return value will be a string describing why the lock was not acquired.
 	// The third return value is a function that can be called to unlock the
 	// lock. It will only be set if the lock was acquired. Any errors will set
-	// error.
-	TryLock(log logging.SimpleLogging, pull models.PullRequest, user models.User, workspace string, project models.Project) (*TryLockResponse, error)
+	// error. granularity is what the lock is held on, one of the
+	// valid.*LockGranularity values.
+	TryLock(log logging.SimpleLogging, pull models.PullRequest, user models.User, workspace string, project models.Project, granularity string) (*TryLockResponse, error)
 }
 
 // DefaultProjectLocker implements ProjectLocker.
 type DefaultProjectLocker struct {
 	Locker    locking.Locker
 	VCSClient vcs.Client
+	// Localizer picks the locale of lock failures. If it's nil they're in
+	// English.
+	Localizer *i18n.Localizer
 }
 
 // TryLockResponse is the result of trying to lock a project.
@@ -55,11 +58,17 @@ type TryLockResponse struct {
 	UnlockFn func() error
 	// LockKey is the key for the lock if the lock was acquired.
 	LockKey string
+	// LockCreated is true if this attempt created the lock, as opposed to the
+	// pull request already holding it.
+	LockCreated bool
+	// CurrLock is the lock held by another pull request. It will only be set
+	// if LockAcquired is false.
+	CurrLock models.ProjectLock
 }
 
 // TryLock implements ProjectLocker.TryLock.
-func (p *DefaultProjectLocker) TryLock(log logging.SimpleLogging, pull models.PullRequest, user models.User, workspace string, project models.Project) (*TryLockResponse, error) {
-	lockAttempt, err := p.Locker.TryLock(project, workspace, pull, user)
+func (p *DefaultProjectLocker) TryLock(log logging.SimpleLogging, pull models.PullRequest, user models.User, workspace string, project models.Project, granularity string) (*TryLockResponse, error) {
+	lockAttempt, err := p.Locker.TryLock(project, workspace, pull, user, granularity)
 	if err != nil {
 		return nil, err
 	}
@@ -68,13 +77,11 @@ func (p *DefaultProjectLocker) TryLock(log logging.SimpleLogging, pull models.Pu
 		if err != nil {
 			return nil, err
 		}
-		failureMsg := fmt.Sprintf(
-			"This project is currently locked by an unapplied plan from pull %s. To continue, delete the lock from %s or apply that plan and merge the pull request.\n\nOnce the lock is released, comment `atlantis plan` here to re-plan.",
-			link,
-			link)
+		failureMsg := p.Localizer.Sprintf(pull.BaseRepo, pull.BaseBranch, i18n.LockedByPull, link, link)
 		return &TryLockResponse{
 			LockAcquired:      false,
 			LockFailureReason: failureMsg,
+			CurrLock:          lockAttempt.CurrLock,
 		}, nil
 	}
 	log.Info("acquired lock with id %q", lockAttempt.LockKey)
@@ -84,6 +91,7 @@ func (p *DefaultProjectLocker) TryLock(log logging.SimpleLogging, pull models.Pu
 			_, err := p.Locker.Unlock(lockAttempt.LockKey)
 			return err
 		},
-		LockKey: lockAttempt.LockKey,
+		LockKey:     lockAttempt.LockKey,
+		LockCreated: lockAttempt.LockAcquired,
 	}, nil
 }
@@ -23,6 +23,7 @@ import (
 	"github.com/runatlantis/atlantis/server/events"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 	"github.com/runatlantis/atlantis/server/logging"
 	. "github.com/runatlantis/atlantis/testing"
 )
@@ -43,7 +44,7 @@ func TestDefaultProjectLocker_TryLockWhenLocked(t *testing.T) {
 	lockingPull := models.PullRequest{
 		Num: 2,
 	}
-	When(mockLocker.TryLock(expProject, expWorkspace, expPull, expUser)).ThenReturn(
+	When(mockLocker.TryLock(expProject, expWorkspace, expPull, expUser, valid.DirWorkspaceLockGranularity)).ThenReturn(
 		locking.TryLockResponse{
 			LockAcquired: false,
 			CurrLock: models.ProjectLock{
@@ -53,12 +54,15 @@ func TestDefaultProjectLocker_TryLockWhenLocked(t *testing.T) {
 		},
 		nil,
 	)
-	res, err := locker.TryLock(logging.NewNoopLogger(t), expPull, expUser, expWorkspace, expProject)
+	res, err := locker.TryLock(logging.NewNoopLogger(t), expPull, expUser, expWorkspace, expProject, valid.DirWorkspaceLockGranularity)
 	link, _ := mockClient.MarkdownPullLink(lockingPull)
 	Ok(t, err)
 	Equals(t, &events.TryLockResponse{
 		LockAcquired:      false,
 		LockFailureReason: fmt.Sprintf("This project is currently locked by an unapplied plan from pull %s. To continue, delete the lock from %s or apply that plan and merge the pull request.\n\nOnce the lock is released, comment `atlantis plan` here to re-plan.", link, link),
+		CurrLock: models.ProjectLock{
+			Pull: lockingPull,
+		},
 	}, res)
 }
 
@@ -80,7 +84,7 @@ func TestDefaultProjectLocker_TryLockWhenLockedSamePull(t *testing.T) {
 		Num: 2,
 	}
 	lockKey := "key"
-	When(mockLocker.TryLock(expProject, expWorkspace, expPull, expUser)).ThenReturn(
+	When(mockLocker.TryLock(expProject, expWorkspace, expPull, expUser, valid.DirWorkspaceLockGranularity)).ThenReturn(
 		locking.TryLockResponse{
 			LockAcquired: false,
 			CurrLock: models.ProjectLock{
@@ -90,9 +94,10 @@ func TestDefaultProjectLocker_TryLockWhenLockedSamePull(t *testing.T) {
 		},
 		nil,
 	)
-	res, err := locker.TryLock(logging.NewNoopLogger(t), expPull, expUser, expWorkspace, expProject)
+	res, err := locker.TryLock(logging.NewNoopLogger(t), expPull, expUser, expWorkspace, expProject, valid.DirWorkspaceLockGranularity)
 	Ok(t, err)
 	Equals(t, true, res.LockAcquired)
+	Equals(t, false, res.LockCreated)
 
 	// UnlockFn should work.
 	mockLocker.VerifyWasCalled(Never()).Unlock(lockKey)
@@ -119,7 +124,7 @@ func TestDefaultProjectLocker_TryLockUnlocked(t *testing.T) {
 		Num: 2,
 	}
 	lockKey := "key"
-	When(mockLocker.TryLock(expProject, expWorkspace, expPull, expUser)).ThenReturn(
+	When(mockLocker.TryLock(expProject, expWorkspace, expPull, expUser, valid.DirWorkspaceLockGranularity)).ThenReturn(
 		locking.TryLockResponse{
 			LockAcquired: true,
 			CurrLock: models.ProjectLock{
@@ -129,9 +134,10 @@ func TestDefaultProjectLocker_TryLockUnlocked(t *testing.T) {
 		},
 		nil,
 	)
-	res, err := locker.TryLock(logging.NewNoopLogger(t), expPull, expUser, expWorkspace, expProject)
+	res, err := locker.TryLock(logging.NewNoopLogger(t), expPull, expUser, expWorkspace, expProject, valid.DirWorkspaceLockGranularity)
 	Ok(t, err)
 	Equals(t, true, res.LockAcquired)
+	Equals(t, true, res.LockCreated)
 
 	// UnlockFn should work.
 	mockLocker.VerifyWasCalled(Never()).Unlock(lockKey)
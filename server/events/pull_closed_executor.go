@@ -20,12 +20,11 @@ import (
 	"strings"
 	"text/template"
 
-	"github.com/runatlantis/atlantis/server/core/db"
-
 	"github.com/runatlantis/atlantis/server/logging"
 
 	"github.com/pkg/errors"
 	"github.com/runatlantis/atlantis/server/core/locking"
+	"github.com/runatlantis/atlantis/server/core/planstorage"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/vcs"
 )
@@ -46,7 +45,13 @@ type PullClosedExecutor struct {
 	VCSClient  vcs.Client
 	WorkingDir WorkingDir
 	Logger     logging.SimpleLogging
-	DB         *db.BoltDB
+	DB         locking.Backend
+	// PlanStorage, if set, is where stored planfiles for the pull are deleted
+	// from.
+	PlanStorage planstorage.PlanStorage
+	// WorkingDirLocker, if set, is used to cancel the commands still waiting
+	// for the pull's working dirs.
+	WorkingDirLocker WorkingDirLocker
 }
 
 type templatedProject struct {
@@ -61,9 +66,17 @@ var pullClosedTemplate = template.Must(template.New("").Parse(
 
 // CleanUpPull cleans up after a closed pull request.
 func (p *PullClosedExecutor) CleanUpPull(repo models.Repo, pull models.PullRequest) error {
+	if p.WorkingDirLocker != nil {
+		p.WorkingDirLocker.CancelWaiting(repo.FullName, pull.Num)
+	}
 	if err := p.WorkingDir.Delete(repo, pull); err != nil {
 		return errors.Wrap(err, "cleaning workspace")
 	}
+	if p.PlanStorage != nil {
+		if err := p.PlanStorage.DeletePlans(repo.FullName, pull.Num, ""); err != nil {
+			return errors.Wrap(err, "cleaning stored plans")
+		}
+	}
 
 	// Finally, delete locks. We do this last because when someone
 	// unlocks a project, right now we don't actually delete the plan
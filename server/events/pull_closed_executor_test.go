@@ -43,6 +43,20 @@ func TestCleanUpPullWorkspaceErr(t *testing.T) {
 	Equals(t, "cleaning workspace: err", actualErr.Error())
 }
 
+func TestCleanUpPullCancelsWaitingCommands(t *testing.T) {
+	t.Log("commands waiting for the pull's working dirs are cancelled")
+	RegisterMockTestingT(t)
+	w := mocks.NewMockWorkingDir()
+	wl := mocks.NewMockWorkingDirLocker()
+	pce := events.PullClosedExecutor{
+		WorkingDir:       w,
+		WorkingDirLocker: wl,
+	}
+	When(w.Delete(fixtures.GithubRepo, fixtures.Pull)).ThenReturn(errors.New("err"))
+	_ = pce.CleanUpPull(fixtures.GithubRepo, fixtures.Pull)
+	wl.VerifyWasCalledOnce().CancelWaiting(fixtures.GithubRepo.FullName, fixtures.Pull.Num)
+}
+
 func TestCleanUpPullUnlockErr(t *testing.T) {
 	t.Log("when locker.UnlockByPull returns an error, we return it")
 	RegisterMockTestingT(t)
@@ -1,14 +1,33 @@
 package events
 
-import "github.com/runatlantis/atlantis/server/events/vcs"
+import (
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/i18n"
+)
 
 type PullUpdater struct {
 	HidePrevPlanComments bool
 	VCSClient            vcs.Client
 	MarkdownRenderer     *MarkdownRenderer
+	// ChecksUpdater, if set, also reports plan results as GitHub check runs.
+	ChecksUpdater *ChecksUpdater
+	// SilenceNoChangePlans is whether projects whose plans have no changes
+	// are left out of plan comments. Repos can override it in GlobalCfg.
+	SilenceNoChangePlans bool
+	GlobalCfg            *valid.GlobalCfgStore
+	// HistoryURLGenerator, if set, is used by rollup comments to link to the
+	// projects' output.
+	HistoryURLGenerator HistoryURLGenerator
+	// Localizer picks the locale comments are rendered in. If it's nil
+	// they're rendered in English.
+	Localizer *i18n.Localizer
 }
 
 func (c *PullUpdater) updatePull(ctx *CommandContext, command PullCommand, res CommandResult) {
+	ctx.Result = &res
+
 	// Log if we got any errors or failures.
 	if res.Error != nil {
 		ctx.Log.Err(res.Error.Error())
@@ -16,6 +35,18 @@ func (c *PullUpdater) updatePull(ctx *CommandContext, command PullCommand, res C
 		ctx.Log.Warn(res.Failure)
 	}
 
+	// A progress comment has already been posted so it's always updated with
+	// every result.
+	commentRes := res
+	if ctx.ProgressCommentID == 0 && c.silenceNoChangePlans(ctx, command) {
+		commentRes = withoutNoChangePlans(res)
+		if len(res.ProjectResults) > 0 && len(commentRes.ProjectResults) == 0 {
+			ctx.Log.Info("not commenting because no plan has changes")
+			c.updateChecks(ctx, command, res)
+			return
+		}
+	}
+
 	// HidePrevCommandComments will hide old comments left from previous runs to reduce
 	// clutter in a pull/merge request. This will not delete the comment, since the
 	// comment trail may be useful in auditing or backtracing problems.
@@ -25,8 +56,79 @@ func (c *PullUpdater) updatePull(ctx *CommandContext, command PullCommand, res C
 		}
 	}
 
-	comment := c.MarkdownRenderer.Render(res, command.CommandName(), ctx.Log.GetHistory(), command.IsVerbose(), ctx.Pull.BaseRepo.VCSHost.Type)
-	if err := c.VCSClient.CreateComment(ctx.Pull.BaseRepo, ctx.Pull.Num, comment, command.CommandName().String()); err != nil {
+	comment := c.render(ctx, command, commentRes)
+	if progressCommentID := ctx.ProgressCommentID; progressCommentID != 0 {
+		// The progress comment is only edited once so later results, ex. from
+		// an automatic policy check, are posted as new comments.
+		ctx.ProgressCommentID = 0
+		err := c.VCSClient.UpdateComment(ctx.Pull.BaseRepo, ctx.Pull.Num, progressCommentID, comment, command.CommandName().String())
+		if err == nil {
+			c.updateChecks(ctx, command, res)
+			return
+		}
+		ctx.Log.Warn("unable to update progress comment, commenting instead: %s", err)
+	}
+	if err := commentOnPull(c.VCSClient, ctx, comment, command.CommandName().String()); err != nil {
 		ctx.Log.Err("unable to comment: %s", err)
 	}
+	c.updateChecks(ctx, command, res)
+}
+
+// render renders res as the comment for command. Custom commands are titled
+// with their own name, ex. Deploy. Plans and applies are rolled up into a
+// table if the repo's comment_format is rollup.
+func (c *PullUpdater) render(ctx *CommandContext, command PullCommand, res CommandResult) string {
+	locale := c.Localizer.For(ctx.Pull.BaseRepo, ctx.Pull.BaseBranch)
+	if cmd, ok := command.(*CommentCommand); ok && cmd.Name == models.CustomCommand {
+		return c.MarkdownRenderer.RenderCustom(res, cmd.SubName, ctx.Log.GetHistory(), cmd.IsVerbose(), ctx.Pull.BaseRepo.VCSHost.Type, locale)
+	}
+	if c.rollup(ctx, command) {
+		return c.MarkdownRenderer.RenderRollup(res, command.CommandName(), ctx.Pull, c.HistoryURLGenerator, ctx.Log.GetHistory(), command.IsVerbose(), locale)
+	}
+	return c.MarkdownRenderer.Render(res, command.CommandName(), ctx.Log.GetHistory(), command.IsVerbose(), ctx.Pull.BaseRepo.VCSHost.Type, locale)
+}
+
+// rollup returns true if command's results should be rolled up into a table.
+func (c *PullUpdater) rollup(ctx *CommandContext, command PullCommand) bool {
+	if c.GlobalCfg == nil || (command.CommandName() != models.PlanCommand && command.CommandName() != models.ApplyCommand) {
+		return false
+	}
+	return c.GlobalCfg.Get().CommentFormat(ctx.Pull.BaseRepo.ID(), ctx.Pull.BaseBranch) == valid.RollupCommentFormat
+}
+
+// silenceNoChangePlans returns true if plans with no changes should be left
+// out of command's comment.
+func (c *PullUpdater) silenceNoChangePlans(ctx *CommandContext, command PullCommand) bool {
+	if command.CommandName() != models.PlanCommand {
+		return false
+	}
+	if c.GlobalCfg == nil {
+		return c.SilenceNoChangePlans
+	}
+	return c.GlobalCfg.Get().SilenceNoChangePlans(ctx.Pull.BaseRepo.ID(), ctx.Pull.BaseBranch, c.SilenceNoChangePlans)
+}
+
+// withoutNoChangePlans returns res without the successful plans that have no
+// changes. Errors and failures are kept.
+func withoutNoChangePlans(res CommandResult) CommandResult {
+	if res.Error != nil || res.Failure != "" {
+		return res
+	}
+	var projectResults []models.ProjectResult
+	for _, pr := range res.ProjectResults {
+		if pr.PlanSuccess != nil {
+			if changes, ok := pr.PlanSuccess.Changes(); ok && changes == (models.PlanChanges{}) {
+				continue
+			}
+		}
+		projectResults = append(projectResults, pr)
+	}
+	res.ProjectResults = projectResults
+	return res
+}
+
+func (c *PullUpdater) updateChecks(ctx *CommandContext, command PullCommand, res CommandResult) {
+	if c.ChecksUpdater != nil && command.CommandName() == models.PlanCommand && ctx.Pull.BaseRepo.VCSHost.Type == models.Github {
+		c.ChecksUpdater.UpdateProjects(ctx, res)
+	}
 }
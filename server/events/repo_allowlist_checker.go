@@ -15,42 +15,198 @@ package events
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+	"sync"
 )
 
-// Wildcard matches 0-n of all characters except commas.
+// Wildcard matches 0-n of all characters except commas. Rules can have more
+// than one, ex. gitlab.com/group/*/infra-*.
 const Wildcard = "*"
 
+// RestrictionSeparator separates a rule in the allowlist from its
+// restrictions, ex. github.com/org/*|plan-only|max-parallel=2.
+const RestrictionSeparator = "|"
+
+// Restrictions that can be set on allowlist rules.
+const (
+	PlanOnlyRestriction    = "plan-only"
+	NoAutoplanRestriction  = "no-autoplan"
+	MaxParallelRestriction = "max-parallel"
+)
+
+// RepoRestrictions limit what Atlantis will do for an allowlisted repo. The
+// zero value is unrestricted.
+type RepoRestrictions struct {
+	// PlanOnly is true if commands that change infrastructure, ex. apply,
+	// can't be run.
+	PlanOnly bool
+	// NoAutoplan is true if plans aren't run automatically when pull
+	// requests are opened or updated.
+	NoAutoplan bool
+	// MaxParallel is the maximum number of commands that can run at once
+	// for the repo. 0 means unlimited.
+	MaxParallel int
+}
+
 // RepoAllowlistChecker implements checking if repos are allowlisted to be used with
 // this Atlantis.
 type RepoAllowlistChecker struct {
-	rules []string
+	rules []repoAllowlistRule
+	// hostRules maps the hostnames of VCS hosts with their own allowlist to
+	// its rules. They're used instead of rules for repos on those hosts.
+	hostRules map[string][]repoAllowlistRule
+
+	// registeredMutex protects registered.
+	registeredMutex sync.RWMutex
+	// registered maps the lowercase {hostname}/{owner}/{repo} of repos
+	// registered at runtime, ex. through the API, to their restrictions.
+	// They're checked before the rules since they're more specific.
+	registered map[string]RepoRestrictions
+}
+
+type repoAllowlistRule struct {
+	pattern      string
+	restrictions RepoRestrictions
 }
 
 // NewRepoAllowlistChecker constructs a new checker and validates that the
 // allowlist isn't malformed.
 func NewRepoAllowlistChecker(allowlist string) (*RepoAllowlistChecker, error) {
-	rules := strings.Split(allowlist, ",")
-	for _, rule := range rules {
-		if strings.Contains(rule, "://") {
-			return nil, fmt.Errorf("allowlist %q contained ://", rule)
-		}
+	rules, err := parseAllowlistRules(allowlist)
+	if err != nil {
+		return nil, err
 	}
 	return &RepoAllowlistChecker{
 		rules: rules,
 	}, nil
 }
 
+// SetHostAllowlist sets the allowlist for repos on vcsHostname, which is
+// used instead of the allowlist the checker was constructed with. Its rules
+// are {owner}/{repo} without the hostname, ex. runatlantis/*.
+func (r *RepoAllowlistChecker) SetHostAllowlist(vcsHostname string, allowlist string) error {
+	rules, err := parseAllowlistRules(allowlist)
+	if err != nil {
+		return err
+	}
+	if r.hostRules == nil {
+		r.hostRules = make(map[string][]repoAllowlistRule)
+	}
+	r.hostRules[strings.ToLower(vcsHostname)] = rules
+	return nil
+}
+
+// AddRepo allowlists the repo with ID {hostname}/{owner}/{repo} in addition
+// to the rules the checker was constructed with. If the repo was already
+// added, its restrictions are replaced.
+func (r *RepoAllowlistChecker) AddRepo(id string, restrictions RepoRestrictions) {
+	r.registeredMutex.Lock()
+	defer r.registeredMutex.Unlock()
+	if r.registered == nil {
+		r.registered = make(map[string]RepoRestrictions)
+	}
+	r.registered[strings.ToLower(id)] = restrictions
+}
+
+// SetRepos replaces the repos added with AddRepo with repos, which maps
+// their IDs to their restrictions.
+func (r *RepoAllowlistChecker) SetRepos(repos map[string]RepoRestrictions) {
+	registered := make(map[string]RepoRestrictions, len(repos))
+	for id, restrictions := range repos {
+		registered[strings.ToLower(id)] = restrictions
+	}
+	r.registeredMutex.Lock()
+	defer r.registeredMutex.Unlock()
+	r.registered = registered
+}
+
+// RemoveRepo removes a repo added with AddRepo. It's still allowlisted if it
+// matches one of the other rules.
+func (r *RepoAllowlistChecker) RemoveRepo(id string) {
+	r.registeredMutex.Lock()
+	defer r.registeredMutex.Unlock()
+	delete(r.registered, strings.ToLower(id))
+}
+
+func parseAllowlistRules(allowlist string) ([]repoAllowlistRule, error) {
+	var rules []repoAllowlistRule
+	for _, rawRule := range strings.Split(allowlist, ",") {
+		parts := strings.Split(rawRule, RestrictionSeparator)
+		rule := repoAllowlistRule{pattern: parts[0]}
+		if strings.Contains(rule.pattern, "://") {
+			return nil, fmt.Errorf("allowlist %q contained ://", rule.pattern)
+		}
+		for _, restriction := range parts[1:] {
+			if err := rule.restrictions.parse(restriction); err != nil {
+				return nil, fmt.Errorf("allowlist %q: %s", rawRule, err)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
 // IsAllowlisted returns true if this repo is in our allowlist and false
 // otherwise.
 func (r *RepoAllowlistChecker) IsAllowlisted(repoFullName string, vcsHostname string) bool {
+	_, ok := r.matchingRule(repoFullName, vcsHostname)
+	return ok
+}
+
+// Restrictions returns the restrictions for this repo. If the repo matches
+// more than one rule in the allowlist, the first rule's restrictions apply.
+// Repos added with AddRepo use the restrictions they were added with.
+func (r *RepoAllowlistChecker) Restrictions(repoFullName string, vcsHostname string) RepoRestrictions {
+	rule, _ := r.matchingRule(repoFullName, vcsHostname)
+	return rule.restrictions
+}
+
+func (r *RepoAllowlistChecker) matchingRule(repoFullName string, vcsHostname string) (repoAllowlistRule, bool) {
+	rules := r.rules
 	candidate := fmt.Sprintf("%s/%s", vcsHostname, repoFullName)
-	for _, rule := range r.rules {
-		if r.matchesRule(rule, candidate) {
-			return true
+	r.registeredMutex.RLock()
+	restrictions, ok := r.registered[strings.ToLower(candidate)]
+	r.registeredMutex.RUnlock()
+	if ok {
+		return repoAllowlistRule{pattern: candidate, restrictions: restrictions}, true
+	}
+	if hostRules, ok := r.hostRules[strings.ToLower(vcsHostname)]; ok {
+		rules = hostRules
+		candidate = repoFullName
+	}
+	for _, rule := range rules {
+		if r.matchesRule(rule.pattern, candidate) {
+			return rule, true
+		}
+	}
+	return repoAllowlistRule{}, false
+}
+
+func (r *RepoRestrictions) parse(restriction string) error {
+	name, value := restriction, ""
+	if i := strings.Index(restriction, "="); i != -1 {
+		name, value = restriction[:i], restriction[i+1:]
+	}
+	switch name {
+	case PlanOnlyRestriction:
+		r.PlanOnly = true
+	case NoAutoplanRestriction:
+		r.NoAutoplan = true
+	case MaxParallelRestriction:
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			return fmt.Errorf("%s must be set to a positive number, ex. %s=2", MaxParallelRestriction, MaxParallelRestriction)
 		}
+		r.MaxParallel = limit
+		return nil
+	default:
+		return fmt.Errorf("unknown restriction %q, must be one of %s, %s or %s=<n>", restriction, PlanOnlyRestriction, NoAutoplanRestriction, MaxParallelRestriction)
 	}
-	return false
+	if value != "" {
+		return fmt.Errorf("restriction %q does not take a value", name)
+	}
+	return nil
 }
 
 func (r *RepoAllowlistChecker) matchesRule(rule string, candidate string) bool {
@@ -58,38 +214,32 @@ func (r *RepoAllowlistChecker) matchesRule(rule string, candidate string) bool {
 	rule = strings.ToLower(rule)
 	candidate = strings.ToLower(candidate)
 
-	wildcardIdx := strings.Index(rule, Wildcard)
-	if wildcardIdx == -1 {
+	parts := strings.Split(rule, Wildcard)
+	if len(parts) == 1 {
 		// No wildcard so can do a straight up match.
 		return candidate == rule
 	}
 
-	// If the candidate length is less than where we found the wildcard
-	// then it can't be equal. For example:
-	//   rule: abc*
-	//   candidate: ab
-	if len(candidate) < wildcardIdx {
+	// The candidate must start with what's before the first wildcard and end
+	// with what's after the last one. Example:
+	//   rule: gitlab.com/group/*-abc
+	//   candidate: gitlab.com/group/repo-abc
+	prefix, suffix := parts[0], parts[len(parts)-1]
+	if !strings.HasPrefix(candidate, prefix) {
 		return false
 	}
+	candidate = candidate[len(prefix):]
 
-	// If wildcard is not the last character, substring both to compare what is after the wildcard.  Example:
-	// candidate: repo-abc
-	// rule: *-abc
-	// substr(candidate): -abc
-	// substr(rule): -abc
-	if wildcardIdx != len(rule)-1 {
-		// If the rule substring after wildcard does not exist in the candidate, then it is not a match.
-		idx := strings.LastIndex(candidate, rule[wildcardIdx+1:])
+	// What's between wildcards must appear in order. Matching each part as
+	// early as possible leaves the most room for the rest. Example:
+	//   rule: gitlab.com/group/*/infra-*
+	//   candidate: gitlab.com/group/subgroup/infra-aws
+	for _, part := range parts[1 : len(parts)-1] {
+		idx := strings.Index(candidate, part)
 		if idx == -1 {
 			return false
 		}
-		return candidate[idx:] == rule[wildcardIdx+1:]
+		candidate = candidate[idx+len(part):]
 	}
-
-	// If wildcard is last character, substring both so they're comparing before the wildcard. Example:
-	// candidate: abcd
-	// rule: abc*
-	// substr(candidate): abc
-	// substr(rule): abc
-	return candidate[:wildcardIdx] == rule[:wildcardIdx]
+	return strings.HasSuffix(candidate, suffix)
 }
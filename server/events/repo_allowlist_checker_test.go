@@ -175,6 +175,34 @@ func TestRepoAllowlistChecker_IsAllowlisted(t *testing.T) {
 			"github.com",
 			true,
 		},
+		{
+			"group wildcard should match repos in subgroups",
+			"gitlab.com/group/*",
+			"group/subgroup/repo",
+			"gitlab.com",
+			true,
+		},
+		{
+			"wildcard in the middle should match what's before it",
+			"gitlab.com/group/*-infra",
+			"othergroup/repo-infra",
+			"gitlab.com",
+			false,
+		},
+		{
+			"should match multiple wildcards",
+			"gitlab.com/group/*/infra-*",
+			"group/subgroup/infra-aws",
+			"gitlab.com",
+			true,
+		},
+		{
+			"should match multiple wildcards in order",
+			"gitlab.com/group/*/infra-*",
+			"group/infra-team/repo",
+			"gitlab.com",
+			false,
+		},
 	}
 
 	for _, c := range cases {
@@ -209,3 +237,86 @@ func TestRepoAllowlistChecker_ContainsSchema(t *testing.T) {
 		})
 	}
 }
+
+func TestRepoAllowlistChecker_Restrictions(t *testing.T) {
+	w, err := events.NewRepoAllowlistChecker("github.com/owner/trusted,github.com/owner/*|plan-only|no-autoplan|max-parallel=2,github.com/other/*|max-parallel=1")
+	Ok(t, err)
+
+	Equals(t, events.RepoRestrictions{}, w.Restrictions("owner/trusted", "github.com"))
+	Equals(t, events.RepoRestrictions{PlanOnly: true, NoAutoplan: true, MaxParallel: 2}, w.Restrictions("owner/untrusted", "github.com"))
+	Equals(t, events.RepoRestrictions{MaxParallel: 1}, w.Restrictions("other/repo", "github.com"))
+	Equals(t, events.RepoRestrictions{}, w.Restrictions("nope/repo", "github.com"))
+
+	t.Log("restrictions don't change whether a repo is allowlisted")
+	Equals(t, true, w.IsAllowlisted("owner/untrusted", "github.com"))
+	Equals(t, false, w.IsAllowlisted("nope/repo", "github.com"))
+}
+
+func TestRepoAllowlistChecker_InvalidRestrictions(t *testing.T) {
+	cases := []struct {
+		allowlist string
+		expErr    string
+	}{
+		{
+			"github.com/owner/*|read-only",
+			`allowlist "github.com/owner/*|read-only": unknown restriction "read-only", must be one of plan-only, no-autoplan or max-parallel=<n>`,
+		},
+		{
+			"github.com/owner/*|max-parallel",
+			`allowlist "github.com/owner/*|max-parallel": max-parallel must be set to a positive number, ex. max-parallel=2`,
+		},
+		{
+			"github.com/owner/*|max-parallel=0",
+			`allowlist "github.com/owner/*|max-parallel=0": max-parallel must be set to a positive number, ex. max-parallel=2`,
+		},
+		{
+			"github.com/owner/*|plan-only=true",
+			`allowlist "github.com/owner/*|plan-only=true": restriction "plan-only" does not take a value`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.allowlist, func(t *testing.T) {
+			_, err := events.NewRepoAllowlistChecker(c.allowlist)
+			ErrEquals(t, c.expErr, err)
+		})
+	}
+}
+
+func TestRepoAllowlistChecker_HostAllowlist(t *testing.T) {
+	w, err := events.NewRepoAllowlistChecker("github.com/owner/*,ghe.example.com/*")
+	Ok(t, err)
+	Ok(t, w.SetHostAllowlist("GHE.example.com", "team/*|plan-only"))
+
+	t.Log("the host's allowlist is used instead of the default one")
+	Equals(t, true, w.IsAllowlisted("team/repo", "ghe.example.com"))
+	Equals(t, false, w.IsAllowlisted("other/repo", "ghe.example.com"))
+	Equals(t, events.RepoRestrictions{PlanOnly: true}, w.Restrictions("team/repo", "ghe.example.com"))
+
+	t.Log("other hosts use the default allowlist")
+	Equals(t, true, w.IsAllowlisted("owner/repo", "github.com"))
+	Equals(t, false, w.IsAllowlisted("team/repo", "github.com"))
+
+	ErrEquals(t, `allowlist "https://team/*" contained ://`, w.SetHostAllowlist("ghe.example.com", "https://team/*"))
+}
+
+func TestRepoAllowlistChecker_AddRepo(t *testing.T) {
+	w, err := events.NewRepoAllowlistChecker("github.com/owner/*|plan-only")
+	Ok(t, err)
+	Ok(t, w.SetHostAllowlist("ghe.example.com", "team/*"))
+
+	t.Log("added repos are allowlisted with their own restrictions")
+	w.AddRepo("github.com/Other/Repo", events.RepoRestrictions{MaxParallel: 2})
+	w.AddRepo("ghe.example.com/other/repo", events.RepoRestrictions{})
+	w.AddRepo("github.com/owner/repo", events.RepoRestrictions{NoAutoplan: true})
+	Equals(t, true, w.IsAllowlisted("other/repo", "github.com"))
+	Equals(t, events.RepoRestrictions{MaxParallel: 2}, w.Restrictions("other/repo", "github.com"))
+	Equals(t, true, w.IsAllowlisted("other/repo", "ghe.example.com"))
+	Equals(t, events.RepoRestrictions{NoAutoplan: true}, w.Restrictions("owner/repo", "github.com"))
+
+	t.Log("removed repos fall back to the rules")
+	w.RemoveRepo("github.com/other/repo")
+	w.RemoveRepo("github.com/owner/repo")
+	Equals(t, false, w.IsAllowlisted("other/repo", "github.com"))
+	Equals(t, events.RepoRestrictions{PlanOnly: true}, w.Restrictions("owner/repo", "github.com"))
+}
@@ -0,0 +1,87 @@
+package events
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/events/yaml"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// RepoCfgValidator validates the atlantis.yaml file of pull requests that
+// modify it so that mistakes are found when the pull request is opened or
+// updated rather than when someone next runs a command.
+type RepoCfgValidator struct {
+	VCSClient       vcs.Client
+	ParserValidator *yaml.ParserValidator
+	GlobalCfg       *valid.GlobalCfgStore
+	// StatusName is the name used to identify Atlantis when creating PR
+	// statuses. The validator's status is named <StatusName>/config.
+	StatusName string
+}
+
+// Validate checks the atlantis.yaml file in ctx.Pull if the pull request
+// modifies it. If the file is invalid it comments with each problem and sets a
+// failed commit status, otherwise it sets a successful commit status.
+func (v *RepoCfgValidator) Validate(ctx *CommandContext) {
+	repo := ctx.Pull.BaseRepo
+	if !v.VCSClient.SupportsSingleFileDownload(repo) {
+		ctx.Log.Debug("not validating %s because %s doesn't support downloading single files", yaml.AtlantisYAMLFilename, repo.VCSHost.Type.String())
+		return
+	}
+	modifiedFiles, err := v.VCSClient.GetModifiedFiles(repo, ctx.Pull)
+	if err != nil {
+		ctx.Log.Warn("unable to check whether %s was modified: %s", yaml.AtlantisYAMLFilename, err)
+		return
+	}
+	modified := false
+	for _, f := range modifiedFiles {
+		if f == yaml.AtlantisYAMLFilename {
+			modified = true
+			break
+		}
+	}
+	if !modified {
+		return
+	}
+
+	hasCfg, cfgData, err := v.VCSClient.DownloadRepoConfigFile(ctx.Pull)
+	if err != nil {
+		ctx.Log.Warn("unable to download %s: %s", yaml.AtlantisYAMLFilename, err)
+		return
+	}
+	// If the file was deleted there's nothing to validate.
+	if !hasCfg {
+		return
+	}
+
+	src := fmt.Sprintf("%s/config", v.StatusName)
+	cfgErrs := v.ParserValidator.ValidateRepoCfgData(cfgData, v.GlobalCfg.Get(), repo.ID())
+	if len(cfgErrs) == 0 {
+		if err := v.VCSClient.UpdateStatus(repo, ctx.Pull, models.SuccessCommitStatus, src, fmt.Sprintf("%s is valid.", yaml.AtlantisYAMLFilename), ""); err != nil {
+			ctx.Log.Warn("unable to update commit status: %s", err)
+		}
+		return
+	}
+
+	ctx.Log.Info("%s is invalid", yaml.AtlantisYAMLFilename)
+	if err := v.VCSClient.UpdateStatus(repo, ctx.Pull, models.FailedCommitStatus, src, fmt.Sprintf("%s is invalid.", yaml.AtlantisYAMLFilename), ""); err != nil {
+		ctx.Log.Warn("unable to update commit status: %s", err)
+	}
+	if err := v.VCSClient.CreateComment(repo, ctx.Pull.Num, repoCfgErrorsComment(cfgErrs), ""); err != nil {
+		ctx.Log.Err("unable to comment: %s", err)
+	}
+}
+
+// repoCfgErrorsComment renders the comment listing the problems in an
+// atlantis.yaml file.
+func repoCfgErrorsComment(cfgErrs []yaml.RepoCfgError) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**Error:** `%s` is invalid. Atlantis won't be able to run commands in this pull request until it's fixed:\n", yaml.AtlantisYAMLFilename)
+	for _, e := range cfgErrs {
+		fmt.Fprintf(&b, "\n* %s", e.String())
+	}
+	return b.String()
+}
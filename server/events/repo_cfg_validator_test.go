@@ -0,0 +1,57 @@
+package events_test
+
+import (
+	"testing"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/models/fixtures"
+	vcsmocks "github.com/runatlantis/atlantis/server/events/vcs/mocks"
+	vcsmatchers "github.com/runatlantis/atlantis/server/events/vcs/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/yaml"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+func setupRepoCfgValidator(t *testing.T, modifiedFiles []string, cfg string) (*events.RepoCfgValidator, *events.CommandContext, *vcsmocks.MockClient) {
+	RegisterMockTestingT(t)
+	vcsClient := vcsmocks.NewMockClient()
+	pull := fixtures.Pull
+	pull.BaseRepo = fixtures.GithubRepo
+	When(vcsClient.SupportsSingleFileDownload(fixtures.GithubRepo)).ThenReturn(true)
+	When(vcsClient.GetModifiedFiles(fixtures.GithubRepo, pull)).ThenReturn(modifiedFiles, nil)
+	When(vcsClient.DownloadRepoConfigFile(pull)).ThenReturn(true, []byte(cfg), nil)
+	validator := &events.RepoCfgValidator{
+		VCSClient:       vcsClient,
+		ParserValidator: &yaml.ParserValidator{},
+		GlobalCfg:       valid.NewGlobalCfgStore(valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})),
+		StatusName:      "atlantis",
+	}
+	ctx := &events.CommandContext{
+		Log:  logging.NewNoopLogger(t),
+		Pull: pull,
+	}
+	return validator, ctx, vcsClient
+}
+
+func TestRepoCfgValidator_Invalid(t *testing.T) {
+	validator, ctx, vcsClient := setupRepoCfgValidator(t, []string{"main.tf", "atlantis.yaml"}, "version: 3\nprojects:\n- dir: ../other\n")
+	validator.Validate(ctx)
+	vcsClient.VerifyWasCalledOnce().UpdateStatus(fixtures.GithubRepo, ctx.Pull, models.FailedCommitStatus, "atlantis/config", "atlantis.yaml is invalid.", "")
+	vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GithubRepo, ctx.Pull.Num, "**Error:** `atlantis.yaml` is invalid. Atlantis won't be able to run commands in this pull request until it's fixed:\n\n* line 3: projects[0].dir: cannot contain '..'", "")
+}
+
+func TestRepoCfgValidator_Valid(t *testing.T) {
+	validator, ctx, vcsClient := setupRepoCfgValidator(t, []string{"atlantis.yaml"}, "version: 3\nprojects:\n- dir: .\n")
+	validator.Validate(ctx)
+	vcsClient.VerifyWasCalledOnce().UpdateStatus(fixtures.GithubRepo, ctx.Pull, models.SuccessCommitStatus, "atlantis/config", "atlantis.yaml is valid.", "")
+	vcsClient.VerifyWasCalled(Never()).CreateComment(vcsmatchers.AnyModelsRepo(), AnyInt(), AnyString(), AnyString())
+}
+
+func TestRepoCfgValidator_NotModified(t *testing.T) {
+	validator, ctx, vcsClient := setupRepoCfgValidator(t, []string{"main.tf", "modules/atlantis.yaml"}, "invalid")
+	validator.Validate(ctx)
+	vcsClient.VerifyWasCalled(Never()).DownloadRepoConfigFile(ctx.Pull)
+	vcsClient.VerifyWasCalled(Never()).CreateComment(vcsmatchers.AnyModelsRepo(), AnyInt(), AnyString(), AnyString())
+}
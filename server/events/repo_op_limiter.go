@@ -0,0 +1,41 @@
+package events
+
+import (
+	"sync"
+)
+
+// RepoOpLimiter limits the number of operations that can be in progress at
+// once for each repo. The zero value is ready to use.
+type RepoOpLimiter struct {
+	mutex sync.Mutex
+	ops   map[string]int
+}
+
+// StartOp tries to start a new operation for repoFullName. It returns false if
+// limit operations are already in progress for the repo. A limit of 0 means
+// any number can run at once. If StartOp returns true, OpDone must be called
+// when the operation is complete.
+func (l *RepoOpLimiter) StartOp(repoFullName string, limit int) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.ops == nil {
+		l.ops = make(map[string]int)
+	}
+	if limit > 0 && l.ops[repoFullName] >= limit {
+		return false
+	}
+	l.ops[repoFullName]++
+	return true
+}
+
+// OpDone marks an operation for repoFullName as complete.
+func (l *RepoOpLimiter) OpDone(repoFullName string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.ops[repoFullName]--
+	if l.ops[repoFullName] <= 0 {
+		delete(l.ops, repoFullName)
+	}
+}
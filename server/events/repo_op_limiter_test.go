@@ -0,0 +1,28 @@
+package events_test
+
+import (
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestRepoOpLimiter(t *testing.T) {
+	l := events.RepoOpLimiter{}
+
+	Equals(t, true, l.StartOp("owner/repo", 2))
+	Equals(t, true, l.StartOp("owner/repo", 2))
+	Equals(t, false, l.StartOp("owner/repo", 2))
+
+	t.Log("other repos aren't affected")
+	Equals(t, true, l.StartOp("owner/other", 1))
+
+	t.Log("completing an op frees up a slot")
+	l.OpDone("owner/repo")
+	Equals(t, true, l.StartOp("owner/repo", 2))
+
+	t.Log("0 is unlimited")
+	for i := 0; i < 10; i++ {
+		Equals(t, true, l.StartOp("owner/unlimited", 0))
+	}
+}
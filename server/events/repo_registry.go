@@ -0,0 +1,206 @@
+package events
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/core/locking"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// WebhookCreator creates and deletes the webhooks that send a repo's events to
+// Atlantis. It's implemented by vcs.GithubClient and vcs.GitlabClient.
+type WebhookCreator interface {
+	// CreateWebhook creates a webhook on repo that sends events to url,
+	// signed with secret, and returns its ID.
+	CreateWebhook(repo models.Repo, url string, secret string) (int64, error)
+	// DeleteWebhook deletes the webhook with id from repo.
+	DeleteWebhook(repo models.Repo, id int64) error
+}
+
+// RepoRegistry onboards repos at runtime, ex. through the API, so they don't
+// need to be added to --repo-allowlist. Registered repos are saved to DB so
+// they're still allowlisted after Atlantis restarts.
+type RepoRegistry struct {
+	DB               locking.Backend
+	AllowlistChecker *RepoAllowlistChecker
+	// WebhookCreators maps the hostnames of VCS hosts to the client used to
+	// create webhooks on their repos.
+	WebhookCreators map[string]WebhookCreator
+	// WebhookSecrets maps the hostnames of VCS hosts to the secret that
+	// webhooks created on their repos are signed with.
+	WebhookSecrets map[string]string
+	// WebhookURL is the URL created webhooks send events to, ex.
+	// https://atlantis.example.com/events.
+	WebhookURL string
+	Logger     logging.SimpleLogging
+}
+
+// Load allowlists the registered repos, replacing the repos it allowlisted
+// before. Other Atlantis instances that share DB can register and unregister
+// repos so it's run every interval by Start to pick up their changes.
+func (r *RepoRegistry) Load() error {
+	repos, err := r.DB.ListRegisteredRepos()
+	if err != nil {
+		return errors.Wrap(err, "listing registered repos")
+	}
+	registered := make(map[string]RepoRestrictions, len(repos))
+	for _, repo := range repos {
+		registered[repo.ID()] = restrictionsOf(repo)
+	}
+	r.AllowlistChecker.SetRepos(registered)
+	return nil
+}
+
+// Start loads the registered repos every interval until stop is closed.
+func (r *RepoRegistry) Start(interval time.Duration, stop <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if err := r.Load(); err != nil {
+					r.Logger.Err("loading registered repos: %s", err)
+				}
+			}
+		}
+	}()
+}
+
+// Register allowlists repo with restrictions and saves it. If createWebhook
+// is true, a webhook that sends the repo's events to Atlantis is created
+// once the repo is saved. Registering a repo that's already registered
+// replaces its restrictions and keeps its webhook.
+func (r *RepoRegistry) Register(repo models.Repo, restrictions RepoRestrictions, createWebhook bool) (models.RegisteredRepo, error) {
+	registered := models.RegisteredRepo{
+		FullName:    repo.FullName,
+		Hostname:    repo.VCSHost.Hostname,
+		PlanOnly:    restrictions.PlanOnly,
+		NoAutoplan:  restrictions.NoAutoplan,
+		MaxParallel: restrictions.MaxParallel,
+		Time:        time.Now(),
+	}
+	creator, hasCreator := r.WebhookCreators[strings.ToLower(repo.VCSHost.Hostname)]
+	if createWebhook && !hasCreator {
+		return models.RegisteredRepo{}, fmt.Errorf("webhooks can't be created on %s repos", repo.VCSHost.Hostname)
+	}
+
+	existing, err := r.find(registered.ID())
+	if err != nil {
+		return models.RegisteredRepo{}, err
+	}
+	added := false
+	if existing == nil {
+		// The repo is saved before its webhook is created so that if it's
+		// registered by two requests at once, ex. on different instances,
+		// only the one that saved it creates a webhook.
+		added, err = r.DB.AddRegisteredRepoIfAbsent(registered)
+		if err != nil {
+			return models.RegisteredRepo{}, errors.Wrap(err, "saving registered repo")
+		}
+		if !added {
+			if existing, err = r.find(registered.ID()); err != nil {
+				return models.RegisteredRepo{}, err
+			}
+		}
+	}
+	if existing != nil {
+		registered.FullName = existing.FullName
+		registered.Hostname = existing.Hostname
+		registered.WebhookID = existing.WebhookID
+	}
+
+	createdWebhook := false
+	if createWebhook && registered.WebhookID == 0 {
+		registered.WebhookID, err = creator.CreateWebhook(repo, r.WebhookURL, r.WebhookSecrets[strings.ToLower(repo.VCSHost.Hostname)])
+		if err != nil {
+			r.undoAdd(registered, added)
+			return models.RegisteredRepo{}, err
+		}
+		createdWebhook = true
+	}
+
+	if !added || createdWebhook {
+		if err := r.DB.AddRegisteredRepo(registered); err != nil {
+			if createdWebhook {
+				// Don't leave a webhook for a repo Atlantis doesn't know about.
+				if delErr := creator.DeleteWebhook(repo, registered.WebhookID); delErr != nil {
+					r.Logger.Warn("unable to delete webhook %d from %s: %s", registered.WebhookID, repo.FullName, delErr)
+				}
+			}
+			r.undoAdd(registered, added)
+			return models.RegisteredRepo{}, errors.Wrap(err, "saving registered repo")
+		}
+	}
+	r.AllowlistChecker.AddRepo(registered.ID(), restrictions)
+	r.Logger.Info("registered repo %s", registered.ID())
+	return registered, nil
+}
+
+// Unregister removes repo from the allowlist and deletes the webhook that was
+// created when it was registered. It returns false if repo wasn't
+// registered.
+func (r *RepoRegistry) Unregister(repo models.Repo) (bool, error) {
+	id := fmt.Sprintf("%s/%s", repo.VCSHost.Hostname, repo.FullName)
+	existing, err := r.find(id)
+	if err != nil || existing == nil {
+		return false, err
+	}
+	if existing.WebhookID != 0 {
+		if creator, ok := r.WebhookCreators[strings.ToLower(repo.VCSHost.Hostname)]; ok {
+			if err := creator.DeleteWebhook(repo, existing.WebhookID); err != nil {
+				r.Logger.Warn("unable to delete webhook %d from %s: %s", existing.WebhookID, repo.FullName, err)
+			}
+		}
+	}
+	if err := r.DB.DeleteRegisteredRepo(existing.ID()); err != nil {
+		return false, errors.Wrap(err, "deleting registered repo")
+	}
+	r.AllowlistChecker.RemoveRepo(existing.ID())
+	r.Logger.Info("unregistered repo %s", existing.ID())
+	return true, nil
+}
+
+// undoAdd deletes registered if it was added by the failed registration.
+func (r *RepoRegistry) undoAdd(registered models.RegisteredRepo, added bool) {
+	if !added {
+		return
+	}
+	if err := r.DB.DeleteRegisteredRepo(registered.ID()); err != nil {
+		r.Logger.Warn("unable to delete registered repo %s: %s", registered.ID(), err)
+	}
+}
+
+// List returns the registered repos ordered by ID.
+func (r *RepoRegistry) List() ([]models.RegisteredRepo, error) {
+	return r.DB.ListRegisteredRepos()
+}
+
+// find returns the registered repo with id, or nil if there isn't one. IDs
+// are compared case-insensitively like the allowlist.
+func (r *RepoRegistry) find(id string) (*models.RegisteredRepo, error) {
+	repos, err := r.DB.ListRegisteredRepos()
+	if err != nil {
+		return nil, errors.Wrap(err, "listing registered repos")
+	}
+	for _, repo := range repos {
+		if strings.EqualFold(repo.ID(), id) {
+			return &repo, nil
+		}
+	}
+	return nil, nil
+}
+
+func restrictionsOf(repo models.RegisteredRepo) RepoRestrictions {
+	return RepoRestrictions{
+		PlanOnly:    repo.PlanOnly,
+		NoAutoplan:  repo.NoAutoplan,
+		MaxParallel: repo.MaxParallel,
+	}
+}
@@ -0,0 +1,146 @@
+package events_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/core/db"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// fakeWebhookCreator records the webhooks it's asked to create and delete.
+type fakeWebhookCreator struct {
+	createErr error
+	created   []string
+	deleted   []int64
+}
+
+func (f *fakeWebhookCreator) CreateWebhook(repo models.Repo, url string, secret string) (int64, error) {
+	if f.createErr != nil {
+		return 0, f.createErr
+	}
+	f.created = append(f.created, repo.FullName+" "+url+" "+secret)
+	return int64(len(f.created)), nil
+}
+
+func (f *fakeWebhookCreator) DeleteWebhook(repo models.Repo, id int64) error {
+	f.deleted = append(f.deleted, id)
+	return nil
+}
+
+func setupRepoRegistry(t *testing.T) (*events.RepoRegistry, *fakeWebhookCreator) {
+	tmp, cleanup := TempDir(t)
+	t.Cleanup(cleanup)
+	boltDB, err := db.New(tmp)
+	Ok(t, err)
+	checker, err := events.NewRepoAllowlistChecker("github.com/owner/*")
+	Ok(t, err)
+	creator := &fakeWebhookCreator{}
+	return &events.RepoRegistry{
+		DB:               boltDB,
+		AllowlistChecker: checker,
+		WebhookCreators:  map[string]events.WebhookCreator{"github.com": creator},
+		WebhookSecrets:   map[string]string{"github.com": "secret"},
+		WebhookURL:       "https://atlantis.example.com/events",
+		Logger:           logging.NewNoopLogger(t),
+	}, creator
+}
+
+func TestRepoRegistry_RegisterAndUnregister(t *testing.T) {
+	r, creator := setupRepoRegistry(t)
+	repo := models.Repo{FullName: "other/repo", VCSHost: models.VCSHost{Hostname: "github.com"}}
+
+	registered, err := r.Register(repo, events.RepoRestrictions{PlanOnly: true}, true)
+	Ok(t, err)
+	Equals(t, "github.com/other/repo", registered.ID())
+	Equals(t, int64(1), registered.WebhookID)
+	Equals(t, []string{"other/repo https://atlantis.example.com/events secret"}, creator.created)
+	Equals(t, true, r.AllowlistChecker.IsAllowlisted("other/repo", "github.com"))
+	Equals(t, events.RepoRestrictions{PlanOnly: true}, r.AllowlistChecker.Restrictions("other/repo", "github.com"))
+
+	t.Log("registering again updates the restrictions and keeps the webhook")
+	registered, err = r.Register(repo, events.RepoRestrictions{}, true)
+	Ok(t, err)
+	Equals(t, int64(1), registered.WebhookID)
+	Equals(t, 1, len(creator.created))
+	Equals(t, events.RepoRestrictions{}, r.AllowlistChecker.Restrictions("other/repo", "github.com"))
+	repos, err := r.List()
+	Ok(t, err)
+	Equals(t, 1, len(repos))
+
+	t.Log("unregistering deletes the webhook")
+	ok, err := r.Unregister(repo)
+	Ok(t, err)
+	Equals(t, true, ok)
+	Equals(t, []int64{1}, creator.deleted)
+	Equals(t, false, r.AllowlistChecker.IsAllowlisted("other/repo", "github.com"))
+	ok, err = r.Unregister(repo)
+	Ok(t, err)
+	Equals(t, false, ok)
+}
+
+func TestRepoRegistry_Load(t *testing.T) {
+	r, _ := setupRepoRegistry(t)
+	Ok(t, r.DB.AddRegisteredRepo(models.RegisteredRepo{FullName: "other/repo", Hostname: "github.com", MaxParallel: 2}))
+
+	Ok(t, r.Load())
+	Equals(t, true, r.AllowlistChecker.IsAllowlisted("other/repo", "github.com"))
+	Equals(t, events.RepoRestrictions{MaxParallel: 2}, r.AllowlistChecker.Restrictions("other/repo", "github.com"))
+}
+
+func TestRepoRegistry_LoadChangesFromOtherInstances(t *testing.T) {
+	r, _ := setupRepoRegistry(t)
+	checker, err := events.NewRepoAllowlistChecker("github.com/owner/*")
+	Ok(t, err)
+	other := *r
+	other.AllowlistChecker = checker
+	repo := models.Repo{FullName: "other/repo", VCSHost: models.VCSHost{Hostname: "github.com"}}
+
+	t.Log("repos registered on another instance are allowlisted once loaded")
+	_, err = other.Register(repo, events.RepoRestrictions{}, false)
+	Ok(t, err)
+	Equals(t, false, r.AllowlistChecker.IsAllowlisted("other/repo", "github.com"))
+	Ok(t, r.Load())
+	Equals(t, true, r.AllowlistChecker.IsAllowlisted("other/repo", "github.com"))
+
+	t.Log("repos unregistered on another instance aren't allowlisted once loaded")
+	_, err = other.Unregister(repo)
+	Ok(t, err)
+	Ok(t, r.Load())
+	Equals(t, false, r.AllowlistChecker.IsAllowlisted("other/repo", "github.com"))
+}
+
+func TestRepoRegistry_RegisterOnlyCreatesOneWebhook(t *testing.T) {
+	t.Log("a repo saved by another registration isn't given a second webhook")
+	r, creator := setupRepoRegistry(t)
+	Ok(t, r.DB.AddRegisteredRepo(models.RegisteredRepo{FullName: "other/repo", Hostname: "github.com", WebhookID: 7}))
+	repo := models.Repo{FullName: "other/repo", VCSHost: models.VCSHost{Hostname: "github.com"}}
+	added, err := r.DB.AddRegisteredRepoIfAbsent(models.RegisteredRepo{FullName: "other/repo", Hostname: "github.com"})
+	Ok(t, err)
+	Equals(t, false, added)
+
+	registered, err := r.Register(repo, events.RepoRestrictions{}, true)
+	Ok(t, err)
+	Equals(t, int64(7), registered.WebhookID)
+	Equals(t, 0, len(creator.created))
+}
+
+func TestRepoRegistry_RegisterErrors(t *testing.T) {
+	r, creator := setupRepoRegistry(t)
+
+	t.Log("webhooks can't be created on hosts without a client")
+	_, err := r.Register(models.Repo{FullName: "other/repo", VCSHost: models.VCSHost{Hostname: "gitlab.com"}}, events.RepoRestrictions{}, true)
+	ErrEquals(t, "webhooks can't be created on gitlab.com repos", err)
+
+	t.Log("repos aren't registered if their webhook can't be created")
+	creator.createErr = errors.New("forbidden")
+	_, err = r.Register(models.Repo{FullName: "other/repo", VCSHost: models.VCSHost{Hostname: "github.com"}}, events.RepoRestrictions{}, true)
+	ErrEquals(t, "forbidden", err)
+	Equals(t, false, r.AllowlistChecker.IsAllowlisted("other/repo", "github.com"))
+	repos, err := r.List()
+	Ok(t, err)
+	Equals(t, 0, len(repos))
+}
@@ -0,0 +1,230 @@
+package events
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+const (
+	// SARIFExportFormat exports results as SARIF 2.1.0, which code scanning
+	// dashboards such as GitHub's ingest.
+	SARIFExportFormat = "sarif"
+	// JUnitExportFormat exports results as JUnit XML, which CI quality gates
+	// such as GitLab's test reports ingest.
+	JUnitExportFormat = "junit"
+)
+
+// exportedCommands are the commands whose results are exported, in the
+// order they're exported in.
+var exportedCommands = []models.CommandName{models.PlanCommand, models.PolicyCheckCommand}
+
+// LatestResults returns the latest plan and policy check history of each
+// project in history, which must be ordered oldest first. Results are
+// ordered by command and then by when the project was first run.
+func LatestResults(history []models.ProjectHistory) []models.ProjectHistory {
+	type key struct {
+		command     models.CommandName
+		repoRelDir  string
+		workspace   string
+		projectName string
+	}
+	latest := make(map[key]models.ProjectHistory)
+	var order []key
+	for _, h := range history {
+		k := key{h.Command, h.RepoRelDir, h.Workspace, h.ProjectName}
+		if _, ok := latest[k]; !ok {
+			order = append(order, k)
+		}
+		latest[k] = h
+	}
+
+	var results []models.ProjectHistory
+	for _, cmd := range exportedCommands {
+		for _, k := range order {
+			if k.command == cmd {
+				results = append(results, latest[k])
+			}
+		}
+	}
+	return results
+}
+
+// resultFailed returns true if the command in h didn't succeed.
+func resultFailed(h models.ProjectHistory) bool {
+	return h.Status == models.ErroredPlanStatus || h.Status == models.ErroredPolicyCheckStatus
+}
+
+// resultName identifies the project in h in exported results.
+func resultName(h models.ProjectHistory) string {
+	if h.ProjectName != "" {
+		return fmt.Sprintf("project %q (dir %q, workspace %q)", h.ProjectName, h.RepoRelDir, h.Workspace)
+	}
+	return fmt.Sprintf("dir %q, workspace %q", h.RepoRelDir, h.Workspace)
+}
+
+// sarifLog is the subset of the SARIF 2.1.0 schema Atlantis exports.
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID     string            `json:"ruleId"`
+	Level      string            `json:"level"`
+	Message    sarifMessage      `json:"message"`
+	Locations  []sarifLocation   `json:"locations"`
+	Properties map[string]string `json:"properties"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// sarifRuleID returns the id of the SARIF rule for failures of cmd.
+func sarifRuleID(cmd models.CommandName) string {
+	return "atlantis/" + cmd.String()
+}
+
+// ExportSARIF returns the latest plan and policy check results in history as
+// a SARIF log. Only failures are results since code scanning treats every
+// result as an alert. Each is located at the project's dir.
+func ExportSARIF(history []models.ProjectHistory) ([]byte, error) {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "Atlantis",
+			InformationURI: "https://www.runatlantis.io",
+			Rules: []sarifRule{
+				{ID: sarifRuleID(models.PlanCommand), ShortDescription: sarifMessage{Text: "Terraform plan failed"}},
+				{ID: sarifRuleID(models.PolicyCheckCommand), ShortDescription: sarifMessage{Text: "Policy check failed"}},
+			},
+		}},
+		Results: []sarifResult{},
+	}
+	for _, h := range LatestResults(history) {
+		if !resultFailed(h) {
+			continue
+		}
+		run.Results = append(run.Results, sarifResult{
+			RuleID:  sarifRuleID(h.Command),
+			Level:   "error",
+			Message: sarifMessage{Text: fmt.Sprintf("%s failed for %s at commit %s:\n\n%s", h.Command.TitleString(), resultName(h), h.Pull.HeadCommit, h.Output)},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: h.RepoRelDir},
+			}}},
+			Properties: map[string]string{
+				"project":   h.ProjectName,
+				"workspace": h.Workspace,
+			},
+		})
+	}
+	return json.MarshalIndent(sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{run},
+	}, "", "  ")
+}
+
+// junitTestSuites is the subset of the JUnit XML format Atlantis exports.
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Output  string `xml:",chardata"`
+}
+
+// ExportJUnit returns the latest plan and policy check results in history as
+// JUnit XML with a test suite per command and a test case per project.
+func ExportJUnit(history []models.ProjectHistory) ([]byte, error) {
+	suites := junitTestSuites{Name: "atlantis"}
+	results := LatestResults(history)
+	for _, cmd := range exportedCommands {
+		suite := junitTestSuite{Name: cmd.String()}
+		for _, h := range results {
+			if h.Command != cmd {
+				continue
+			}
+			testCase := junitTestCase{
+				Name:      resultName(h),
+				ClassName: h.Pull.BaseRepo.FullName,
+			}
+			if resultFailed(h) {
+				testCase.Failure = &junitFailure{
+					Message: fmt.Sprintf("%s failed at commit %s", cmd.TitleString(), h.Pull.HeadCommit),
+					Output:  h.Output,
+				}
+				suite.Failures++
+			} else {
+				testCase.SystemOut = h.Output
+			}
+			suite.TestCases = append(suite.TestCases, testCase)
+			suite.Tests++
+		}
+		if suite.Tests == 0 {
+			continue
+		}
+		suites.Suites = append(suites.Suites, suite)
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+	}
+	out, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
@@ -0,0 +1,106 @@
+package events_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func exportHistory() []models.ProjectHistory {
+	pull := models.PullRequest{Num: 1, HeadCommit: "sha", BaseRepo: models.Repo{FullName: "owner/repo"}}
+	start := time.Date(2021, 11, 1, 12, 0, 0, 0, time.UTC)
+	return []models.ProjectHistory{
+		{Pull: pull, RepoRelDir: "a", Workspace: "default", Command: models.PlanCommand, Status: models.ErroredPlanStatus, Output: "old error", Time: start},
+		{Pull: pull, RepoRelDir: "b", Workspace: "default", ProjectName: "b", Command: models.PlanCommand, Status: models.ErroredPlanStatus, Output: "Error: bad config", Time: start},
+		{Pull: pull, RepoRelDir: "a", Workspace: "default", Command: models.PolicyCheckCommand, Status: models.ErroredPolicyCheckStatus, Output: "FAIL - deny s3 public", Time: start.Add(time.Minute)},
+		{Pull: pull, RepoRelDir: "a", Workspace: "default", Command: models.PlanCommand, Status: models.PlannedPlanStatus, Output: "No changes.", Time: start.Add(2 * time.Minute)},
+		{Pull: pull, RepoRelDir: "a", Workspace: "default", Command: models.ApplyCommand, Status: models.AppliedPlanStatus, Output: "Apply complete!", Time: start.Add(3 * time.Minute)},
+	}
+}
+
+func TestLatestResults(t *testing.T) {
+	history := exportHistory()
+	Equals(t, []models.ProjectHistory{history[3], history[1], history[2]}, events.LatestResults(history))
+	Equals(t, 0, len(events.LatestResults(nil)))
+}
+
+func TestExportSARIF(t *testing.T) {
+	out, err := events.ExportSARIF(exportHistory())
+	Ok(t, err)
+
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string `json:"name"`
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID  string `json:"ruleId"`
+				Level   string `json:"level"`
+				Message struct {
+					Text string `json:"text"`
+				} `json:"message"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	Ok(t, json.Unmarshal(out, &log))
+	Equals(t, "2.1.0", log.Version)
+	Equals(t, 1, len(log.Runs))
+	run := log.Runs[0]
+	Equals(t, "Atlantis", run.Tool.Driver.Name)
+	Equals(t, 2, len(run.Tool.Driver.Rules))
+
+	t.Log("only the latest failures are results")
+	Equals(t, 2, len(run.Results))
+	Equals(t, "atlantis/plan", run.Results[0].RuleID)
+	Equals(t, "error", run.Results[0].Level)
+	Equals(t, "Plan failed for project \"b\" (dir \"b\", workspace \"default\") at commit sha:\n\nError: bad config", run.Results[0].Message.Text)
+	Equals(t, "b", run.Results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	Equals(t, "atlantis/policy_check", run.Results[1].RuleID)
+	Equals(t, "Policy Check failed for dir \"a\", workspace \"default\" at commit sha:\n\nFAIL - deny s3 public", run.Results[1].Message.Text)
+	Equals(t, "a", run.Results[1].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+}
+
+func TestExportSARIF_NoFailures(t *testing.T) {
+	out, err := events.ExportSARIF(nil)
+	Ok(t, err)
+	Assert(t, strings.Contains(string(out), `"results": []`), "expected empty results, got %s", out)
+}
+
+func TestExportJUnit(t *testing.T) {
+	out, err := events.ExportJUnit(exportHistory())
+	Ok(t, err)
+	Equals(t, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="atlantis" tests="3" failures="2">
+  <testsuite name="plan" tests="2" failures="1">
+    <testcase name="dir &#34;a&#34;, workspace &#34;default&#34;" classname="owner/repo">
+      <system-out>No changes.</system-out>
+    </testcase>
+    <testcase name="project &#34;b&#34; (dir &#34;b&#34;, workspace &#34;default&#34;)" classname="owner/repo">
+      <failure message="Plan failed at commit sha">Error: bad config</failure>
+    </testcase>
+  </testsuite>
+  <testsuite name="policy_check" tests="1" failures="1">
+    <testcase name="dir &#34;a&#34;, workspace &#34;default&#34;" classname="owner/repo">
+      <failure message="Policy Check failed at commit sha">FAIL - deny s3 public</failure>
+    </testcase>
+  </testsuite>
+</testsuites>`, string(out))
+}
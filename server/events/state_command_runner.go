@@ -0,0 +1,93 @@
+package events
+
+import (
+	"github.com/runatlantis/atlantis/server/core/locking"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+)
+
+func NewStateCommandRunner(
+	vcsClient vcs.Client,
+	applyCommandLocker locking.ApplyLockChecker,
+	pullUpdater *PullUpdater,
+	prjCmdBuilder ProjectStateCommandBuilder,
+	prjCmdRunner ProjectStateCommandRunner,
+	db locking.Backend,
+	enabled bool,
+) *StateCommandRunner {
+	return &StateCommandRunner{
+		vcsClient:     vcsClient,
+		locker:        applyCommandLocker,
+		pullUpdater:   pullUpdater,
+		prjCmdBuilder: prjCmdBuilder,
+		prjCmdRunner:  prjCmdRunner,
+		DB:            db,
+		enabled:       enabled,
+	}
+}
+
+type StateCommandRunner struct {
+	DB            locking.Backend
+	locker        locking.ApplyLockChecker
+	vcsClient     vcs.Client
+	pullUpdater   *PullUpdater
+	prjCmdBuilder ProjectStateCommandBuilder
+	prjCmdRunner  ProjectStateCommandRunner
+	// enabled is true if state commands were enabled on the server. They're
+	// disabled by default since they're destructive.
+	enabled bool
+}
+
+func (s *StateCommandRunner) Run(ctx *CommandContext, cmd *CommentCommand) {
+	pull := ctx.Pull
+
+	if !s.enabled {
+		ctx.Log.Info("ignoring state command since state commands are disabled")
+		if err := commentOnPull(s.vcsClient, ctx, stateDisabledComment, models.StateCommand.String()); err != nil {
+			ctx.Log.Err("unable to comment on pull request: %s", err)
+		}
+		return
+	}
+
+	// State commands modify state so we treat them like apply when apply is
+	// disabled globally.
+	lock, err := s.locker.CheckApplyLock()
+	if err != nil {
+		ctx.Log.Warn("checking global apply lock: %s", err)
+	}
+	if lock.Locked {
+		ctx.Log.Info("ignoring state command since apply disabled globally")
+		if err := commentOnPull(s.vcsClient, ctx, stateApplyDisabledComment, models.StateCommand.String()); err != nil {
+			ctx.Log.Err("unable to comment on pull request: %s", err)
+		}
+		return
+	}
+
+	projectCmds, err := s.prjCmdBuilder.BuildStateCommands(ctx, cmd)
+	if err != nil {
+		s.pullUpdater.updatePull(ctx, cmd, CommandResult{Error: err})
+		return
+	}
+
+	if len(projectCmds) == 0 {
+		ctx.Log.Info("no projects to run state command in")
+		return
+	}
+
+	result := runProjectCmds(projectCmds, s.prjCmdRunner.State)
+	s.pullUpdater.updatePull(ctx, cmd, result)
+
+	// A successful state command deletes the project's plan so we mark it
+	// discarded.
+	for _, projResult := range result.ProjectResults {
+		if projResult.StateSuccess == nil {
+			continue
+		}
+		if err := s.DB.UpdateProjectStatus(pull, projResult.Workspace, projResult.RepoRelDir, models.DiscardedPlanStatus); err != nil {
+			ctx.Log.Err("updating project status: %s", err)
+		}
+	}
+}
+
+var stateDisabledComment = "**Error:** Running `atlantis state` is disabled. It can be enabled with the `--enable-state-cmd` server flag."
+var stateApplyDisabledComment = "**Error:** Running `atlantis state` is disabled since `atlantis apply` is disabled."
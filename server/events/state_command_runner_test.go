@@ -0,0 +1,64 @@
+package events_test
+
+import (
+	"testing"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/core/locking"
+	lockingmocks "github.com/runatlantis/atlantis/server/core/locking/mocks"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/models/fixtures"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+func TestStateCommandRunner_Disabled(t *testing.T) {
+	cases := []struct {
+		Description string
+		Enabled     bool
+		ApplyLocked bool
+		ExpComment  string
+	}{
+		{
+			Description: "When state commands aren't enabled it comments an error",
+			Enabled:     false,
+			ExpComment:  "**Error:** Running `atlantis state` is disabled. It can be enabled with the `--enable-state-cmd` server flag.",
+		},
+		{
+			Description: "When global apply lock is present it comments an error",
+			Enabled:     true,
+			ApplyLocked: true,
+			ExpComment:  "**Error:** Running `atlantis state` is disabled since `atlantis apply` is disabled.",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Description, func(t *testing.T) {
+			vcsClient := setup(t)
+			modelPull := models.PullRequest{BaseRepo: fixtures.GithubRepo, State: models.OpenPullState, Num: fixtures.Pull.Num}
+			ctx := &events.CommandContext{
+				User:     fixtures.User,
+				Log:      logging.NewNoopLogger(t),
+				Pull:     modelPull,
+				HeadRepo: fixtures.GithubRepo,
+				Trigger:  events.Comment,
+			}
+
+			When(applyLockChecker.CheckApplyLock()).ThenReturn(locking.ApplyCommandLock{Locked: c.ApplyLocked}, nil)
+			stateCommandRunner := events.NewStateCommandRunner(
+				vcsClient,
+				applyLockChecker,
+				pullUpdater,
+				projectCommandBuilder,
+				projectCommandRunner,
+				lockingmocks.NewMockBackend(),
+				c.Enabled,
+			)
+			stateCommandRunner.Run(ctx, &events.CommentCommand{Name: models.StateCommand, SubName: "rm"})
+
+			vcsClient.VerifyWasCalledOnce().CreateComment(fixtures.GithubRepo, modelPull.Num, c.ExpComment, "state")
+			projectCommandBuilder.VerifyWasCalled(Never()).BuildStateCommands(matchers.AnyPtrToEventsCommandContext(), matchers.AnyPtrToEventsCommentCommand())
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package events
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/i18n"
+)
+
+// StateLockConflict is a terraform state lock that was held by someone else
+// when a command tried to acquire it, ex. another pipeline's DynamoDB or GCS
+// lock. Its fields are empty if terraform didn't report them.
+type StateLockConflict struct {
+	ID        string
+	Path      string
+	Operation string
+	Who       string
+	Created   string
+	// TimedOut is true if the command was stopped while waiting for the lock,
+	// ex. because of -lock-timeout and a step timeout, rather than terraform
+	// reporting that it couldn't acquire it.
+	TimedOut bool
+}
+
+// ParseStateLockConflict returns the state lock conflict reported in the
+// output of a failed terraform command, and false if it didn't fail because
+// of the state lock. timedOut is true if the command was stopped because it
+// took too long.
+func ParseStateLockConflict(output string, timedOut bool) (StateLockConflict, bool) {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		// Terraform >= 0.15 draws a box around diagnostics.
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "│╷╵"))
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	if !strings.Contains(output, "Error acquiring the state lock") && !strings.Contains(output, "Error locking state") {
+		// A command that's stopped while waiting for the lock hasn't printed
+		// anything since it started acquiring it.
+		if timedOut && len(lines) > 0 && strings.HasPrefix(lines[len(lines)-1], "Acquiring state lock") {
+			return StateLockConflict{TimedOut: true}, true
+		}
+		return StateLockConflict{}, false
+	}
+
+	var conflict StateLockConflict
+	fields := map[string]*string{
+		"ID:":        &conflict.ID,
+		"Path:":      &conflict.Path,
+		"Operation:": &conflict.Operation,
+		"Who:":       &conflict.Who,
+		"Created:":   &conflict.Created,
+	}
+	for _, line := range lines {
+		for prefix, field := range fields {
+			if strings.HasPrefix(line, prefix) && *field == "" {
+				*field = strings.TrimSpace(strings.TrimPrefix(line, prefix))
+			}
+		}
+	}
+	return conflict, true
+}
+
+// Failure returns the failure commented on the pull request, in locale, when
+// cmdName couldn't run on the project in ctx because of the conflict.
+func (c StateLockConflict) Failure(locale string, ctx models.ProjectCommandContext, cmdName models.CommandName) string {
+	var msg string
+	switch {
+	case c.TimedOut:
+		msg = i18n.Sprintf(locale, i18n.StateLockTimedOut)
+	case c.Who == "":
+		msg = i18n.Sprintf(locale, i18n.StateLockNotAcquired)
+	default:
+		var operation, since string
+		if c.Operation != "" {
+			operation = i18n.Sprintf(locale, i18n.StateLockOperation, c.Operation)
+		}
+		if c.Created != "" {
+			since = i18n.Sprintf(locale, i18n.StateLockSince, c.Created)
+		}
+		msg = i18n.Sprintf(locale, i18n.StateLockHeldBy, c.Who, operation, since)
+	}
+	retryCmd := ctx.RePlanCmd
+	if cmdName == models.ApplyCommand {
+		retryCmd = ctx.ApplyCmd
+	}
+	msg += " " + i18n.Sprintf(locale, i18n.StateLockRetry, retryCmd)
+	if c.ID != "" {
+		msg += " " + i18n.Sprintf(locale, i18n.StateLockForceUnlock, c.ID)
+	}
+	return msg
+}
+
+// StateLockTracker counts the state lock conflicts of plans and applies so
+// contention between Atlantis and other users of the same state is visible
+// in /status.
+type StateLockTracker struct {
+	mutex sync.Mutex
+	stats StateLockStats
+}
+
+// StateLockStats are the state lock conflicts since Atlantis started.
+type StateLockStats struct {
+	Conflicts    int
+	LastConflict time.Time
+	// Repos maps the full names of repos to their number of conflicts.
+	Repos map[string]int
+}
+
+// Record counts a conflict in repoFullName.
+func (t *StateLockTracker) Record(repoFullName string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.stats.Repos == nil {
+		t.stats.Repos = make(map[string]int)
+	}
+	t.stats.Conflicts++
+	t.stats.LastConflict = time.Now()
+	t.stats.Repos[repoFullName]++
+}
+
+// GetStats returns a copy of the tracker's stats.
+func (t *StateLockTracker) GetStats() StateLockStats {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	stats := t.stats
+	stats.Repos = make(map[string]int)
+	for repo, n := range t.stats.Repos {
+		stats.Repos[repo] = n
+	}
+	return stats
+}
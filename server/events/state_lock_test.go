@@ -0,0 +1,85 @@
+package events_test
+
+import (
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/i18n"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestParseStateLockConflict(t *testing.T) {
+	cases := []struct {
+		description string
+		output      string
+		timedOut    bool
+		exp         events.StateLockConflict
+		expOK       bool
+	}{
+		{
+			"terraform >= 0.15",
+			`Acquiring state lock. This may take a few moments...
+╷
+│ Error: Error acquiring the state lock
+│
+│ Lock Info:
+│   ID:        abc
+│   Path:      bucket/terraform.tfstate
+│   Operation: OperationTypePlan
+│   Who:       user@host
+│   Created:   2023-10-02 09:15:43 +0000 UTC
+╵`,
+			false,
+			events.StateLockConflict{ID: "abc", Path: "bucket/terraform.tfstate", Operation: "OperationTypePlan", Who: "user@host", Created: "2023-10-02 09:15:43 +0000 UTC"},
+			true,
+		},
+		{
+			"terraform < 0.15",
+			`Error locking state: Error acquiring the state lock: writing "gs://bucket/default.tflock" failed
+Lock Info:
+  ID:        1634567890123456
+  Who:       user@host`,
+			false,
+			events.StateLockConflict{ID: "1634567890123456", Who: "user@host"},
+			true,
+		},
+		{
+			"timed out waiting for the lock",
+			"Acquiring state lock. This may take a few moments...\n",
+			true,
+			events.StateLockConflict{TimedOut: true},
+			true,
+		},
+		{
+			"timed out after acquiring the lock",
+			"Acquiring state lock. This may take a few moments...\nRefreshing state...\n",
+			true,
+			events.StateLockConflict{},
+			false,
+		},
+		{
+			"other errors",
+			"Error: Invalid reference",
+			false,
+			events.StateLockConflict{},
+			false,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			conflict, ok := events.ParseStateLockConflict(c.output, c.timedOut)
+			Equals(t, c.expOK, ok)
+			Equals(t, c.exp, conflict)
+		})
+	}
+}
+
+func TestStateLockConflict_Failure(t *testing.T) {
+	ctx := models.ProjectCommandContext{RePlanCmd: "atlantis plan -d dir", ApplyCmd: "atlantis apply -d dir"}
+
+	Equals(t, "Terraform timed out waiting for the state lock. Another run is likely using this project's state, ex. a different pipeline or a local terraform command. Wait for it to finish and then comment `atlantis apply -d dir` to try again.",
+		events.StateLockConflict{TimedOut: true}.Failure(i18n.English, ctx, models.ApplyCommand))
+	Equals(t, "Terraform couldn't acquire the state lock. Another run is likely using this project's state, ex. a different pipeline or a local terraform command. Wait for it to finish and then comment `atlantis plan -d dir` to try again. If nothing is using the state, its lock can be released with `terraform force-unlock abc`.",
+		events.StateLockConflict{ID: "abc"}.Failure(i18n.English, ctx, models.PlanCommand))
+}
@@ -0,0 +1,59 @@
+package events
+
+import (
+	"strings"
+	"time"
+)
+
+// transientErrors are lowercase substrings of terraform output that mean a
+// step failed because of a flaky network or cloud API rather than because of
+// the project, so running it again is likely to succeed.
+var transientErrors = []string{
+	"connection reset by peer",
+	"connection refused",
+	"tls handshake timeout",
+	"i/o timeout",
+	"client.timeout exceeded",
+	"unexpected eof",
+	"throttling",
+	"rate exceeded",
+	"requestlimitexceeded",
+	"too many requests",
+	"service unavailable",
+	"bad gateway",
+	"could not connect to registry",
+	"failed to request discovery document",
+}
+
+// retriableSteps are the steps that are retried when they fail with a
+// transient error. They don't change any infrastructure so running them
+// again is safe.
+var retriableSteps = map[string]bool{
+	"init": true,
+	"plan": true,
+}
+
+// ParseTransientError returns the line of a failed step's output and err that
+// shows it failed with a transient error, and false if it didn't.
+func ParseTransientError(output string, err error) (string, bool) {
+	if err != nil {
+		output += "\n" + err.Error()
+	}
+	for _, line := range strings.Split(output, "\n") {
+		// Terraform >= 0.15 draws a box around diagnostics.
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "│╷╵"))
+		lower := strings.ToLower(line)
+		for _, transient := range transientErrors {
+			if strings.Contains(lower, transient) {
+				return line, true
+			}
+		}
+	}
+	return "", false
+}
+
+// retryBackoff returns how long to wait before the retry after attempt
+// failed. The wait doubles after each retry.
+func retryBackoff(backoff time.Duration, attempt int) time.Duration {
+	return backoff * time.Duration(1<<uint(attempt-1))
+}
@@ -0,0 +1,68 @@
+package events_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestParseTransientError(t *testing.T) {
+	cases := []struct {
+		description string
+		output      string
+		err         error
+		exp         string
+		expOK       bool
+	}{
+		{
+			"provider throttling",
+			`╷
+│ Error: reading EC2 Instances: operation error EC2: DescribeInstances, https response error StatusCode: 400, api error RequestLimitExceeded: Request limit exceeded.
+│
+╵`,
+			errors.New("exit status 1"),
+			"Error: reading EC2 Instances: operation error EC2: DescribeInstances, https response error StatusCode: 400, api error RequestLimitExceeded: Request limit exceeded.",
+			true,
+		},
+		{
+			"registry timeout",
+			`Initializing provider plugins...
+- Finding hashicorp/aws versions matching "~> 5.0"...
+╷
+│ Error: Failed to query available provider packages
+│
+│ Could not retrieve the list of available versions for provider hashicorp/aws: could not connect to registry.terraform.io: Failed to request discovery document: Get "https://registry.terraform.io/.well-known/terraform.json": net/http: TLS handshake timeout
+╵`,
+			errors.New("exit status 1"),
+			`Could not retrieve the list of available versions for provider hashicorp/aws: could not connect to registry.terraform.io: Failed to request discovery document: Get "https://registry.terraform.io/.well-known/terraform.json": net/http: TLS handshake timeout`,
+			true,
+		},
+		{
+			"error",
+			"",
+			errors.New("read tcp 10.0.0.1:443: read: connection reset by peer"),
+			"read tcp 10.0.0.1:443: read: connection reset by peer",
+			true,
+		},
+		{
+			"not transient",
+			`╷
+│ Error: Unsupported argument
+│
+│ An argument named "foo" is not expected here.
+╵`,
+			errors.New("exit status 1"),
+			"",
+			false,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			transientErr, ok := events.ParseTransientError(c.output, c.err)
+			Equals(t, c.expOK, ok)
+			Equals(t, c.exp, transientErr)
+		})
+	}
+}
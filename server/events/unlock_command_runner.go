@@ -1,6 +1,9 @@
 package events
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/vcs"
 )
@@ -32,8 +35,16 @@ func (u *UnlockCommandRunner) Run(
 	baseRepo := ctx.Pull.BaseRepo
 	pullNum := ctx.Pull.Num
 
-	vcsMessage := "All Atlantis locks for this PR have been unlocked and plans discarded"
-	numLocks, err := u.deleteLockCommand.DeleteLocksByPull(baseRepo.FullName, pullNum)
+	var vcsMessage string
+	var numLocks int
+	var err error
+	if cmd.RepoRelDir != "" || cmd.Workspace != "" {
+		numLocks, err = u.deleteLockCommand.DeleteProjectLocksByPull(baseRepo.FullName, pullNum, cmd.RepoRelDir, cmd.Workspace)
+		vcsMessage = u.projectUnlockedMessage(cmd, numLocks)
+	} else {
+		numLocks, err = u.deleteLockCommand.DeleteLocksByPull(baseRepo.FullName, pullNum)
+		vcsMessage = "All Atlantis locks for this PR have been unlocked and plans discarded"
+	}
 	if err != nil {
 		vcsMessage = "Failed to delete PR locks"
 		ctx.Log.Err("failed to delete locks by pull %s", err.Error())
@@ -44,7 +55,23 @@ func (u *UnlockCommandRunner) Run(
 		return
 	}
 
-	if commentErr := u.vcsClient.CreateComment(baseRepo, pullNum, vcsMessage, models.UnlockCommand.String()); commentErr != nil {
+	if commentErr := commentOnPull(u.vcsClient, ctx, vcsMessage, models.UnlockCommand.String()); commentErr != nil {
 		ctx.Log.Err("unable to comment: %s", commentErr)
 	}
 }
+
+// projectUnlockedMessage returns the comment for unlocking the projects
+// matching cmd's dir and workspace.
+func (u *UnlockCommandRunner) projectUnlockedMessage(cmd *CommentCommand, numLocks int) string {
+	var filters []string
+	if cmd.RepoRelDir != "" {
+		filters = append(filters, fmt.Sprintf("dir `%s`", cmd.RepoRelDir))
+	}
+	if cmd.Workspace != "" {
+		filters = append(filters, fmt.Sprintf("workspace `%s`", cmd.Workspace))
+	}
+	if numLocks == 0 {
+		return fmt.Sprintf("No Atlantis locks found for %s in this PR", strings.Join(filters, " and "))
+	}
+	return fmt.Sprintf("Atlantis locks for %s have been unlocked and plans discarded", strings.Join(filters, " and "))
+}
@@ -0,0 +1,54 @@
+package events
+
+import "github.com/runatlantis/atlantis/server/events/models"
+
+func NewValidateCommandRunner(
+	pullUpdater *PullUpdater,
+	prjCmdBuilder ProjectValidateCommandBuilder,
+	prjCmdRunner ProjectValidateCommandRunner,
+	parallelPoolSize int,
+) *ValidateCommandRunner {
+	return &ValidateCommandRunner{
+		pullUpdater:      pullUpdater,
+		prjCmdBuilder:    prjCmdBuilder,
+		prjCmdRunner:     prjCmdRunner,
+		parallelPoolSize: parallelPoolSize,
+	}
+}
+
+// ValidateCommandRunner runs terraform validate on the projects affected by a
+// pull request. It doesn't lock the projects since it doesn't touch state.
+type ValidateCommandRunner struct {
+	pullUpdater      *PullUpdater
+	prjCmdBuilder    ProjectValidateCommandBuilder
+	prjCmdRunner     ProjectValidateCommandRunner
+	parallelPoolSize int
+}
+
+func (v *ValidateCommandRunner) Run(ctx *CommandContext, cmd *CommentCommand) {
+	projectCmds, err := v.prjCmdBuilder.BuildValidateCommands(ctx, cmd)
+	if err != nil {
+		v.pullUpdater.updatePull(ctx, cmd, CommandResult{Error: err})
+		return
+	}
+
+	if len(projectCmds) == 0 {
+		ctx.Log.Info("no projects to run validate in")
+		return
+	}
+
+	// Validate runs wherever plan would so it's parallel when plan is.
+	var result CommandResult
+	if v.isParallelEnabled(projectCmds) {
+		ctx.Log.Info("Running validate in parallel")
+		result = runProjectCmdsParallel(projectCmds, v.prjCmdRunner.Validate, v.parallelPoolSize)
+	} else {
+		result = runProjectCmds(projectCmds, v.prjCmdRunner.Validate)
+	}
+
+	v.pullUpdater.updatePull(ctx, cmd, result)
+}
+
+func (v *ValidateCommandRunner) isParallelEnabled(cmds []models.ProjectCommandContext) bool {
+	return len(cmds) > 0 && cmds[0].ParallelPlanEnabled
+}
@@ -0,0 +1,288 @@
+package vcs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+const (
+	defaultAPIMinBackoff = time.Second
+	defaultAPIMaxBackoff = time.Minute
+)
+
+// APIRetrier retries VCS API requests that fail because of rate limits or
+// server errors, backing off exponentially with jitter between attempts. It
+// also records the rate limit quota each host reports. One APIRetrier is
+// shared by all the VCS clients so its stats cover every host.
+type APIRetrier struct {
+	// MaxRetries is how many times a request is retried before its last
+	// response is returned.
+	MaxRetries int
+	// MinBackoff is the wait before the first retry. It doubles for each
+	// retry after that up to MaxBackoff.
+	MinBackoff time.Duration
+	// MaxBackoff is the longest wait between retries. Requests are not
+	// retried if the host asks to wait longer than this, ex. until an hourly
+	// rate limit resets.
+	MaxBackoff time.Duration
+	Logger     logging.SimpleLogging
+
+	mutex sync.Mutex
+	hosts map[string]*APIHostStats
+	// sleep waits for d or until ctx is done. It's overridden in tests.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// APIHostStats are the totals and latest rate limit quota for one VCS host.
+type APIHostStats struct {
+	Requests int
+	Retries  int
+	// RateLimited is how many responses said the rate limit was exceeded.
+	RateLimited int
+	// Limit, Remaining and Reset are from the rate limit headers of the
+	// latest response that had them. Limit is 0 if the host has never sent
+	// them.
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// NewAPIRetrier returns an APIRetrier that retries requests up to maxRetries
+// times.
+func NewAPIRetrier(maxRetries int, logger logging.SimpleLogging) *APIRetrier {
+	return &APIRetrier{
+		MaxRetries: maxRetries,
+		MinBackoff: defaultAPIMinBackoff,
+		MaxBackoff: defaultAPIMaxBackoff,
+		Logger:     logger,
+	}
+}
+
+// Transport returns a transport that sends requests with base and retries
+// them. If r is nil, base is returned as is. If base is nil,
+// http.DefaultTransport is used.
+func (r *APIRetrier) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if r == nil {
+		return base
+	}
+	return &apiRetryTransport{retrier: r, base: base}
+}
+
+// GetStats returns the stats of each host, keyed by hostname.
+func (r *APIRetrier) GetStats() map[string]APIHostStats {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	stats := make(map[string]APIHostStats, len(r.hosts))
+	for host, s := range r.hosts {
+		stats[host] = *s
+	}
+	return stats
+}
+
+// apiRetryTransport is the transport returned by APIRetrier.Transport.
+type apiRetryTransport struct {
+	retrier *APIRetrier
+	base    http.RoundTripper
+}
+
+func (t *apiRetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := t.retrier
+	// Requests with bodies can only be retried if the body can be read again.
+	maxRetries := r.MaxRetries
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		maxRetries = 0
+	}
+
+	for attempt := 0; ; attempt++ {
+		attemptReq := req
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(attemptReq)
+		if err != nil {
+			return nil, err
+		}
+		wait, retry := r.checkRetry(req, resp, attempt)
+		if !retry || attempt >= maxRetries {
+			return resp, nil
+		}
+
+		io.Copy(ioutil.Discard, resp.Body) // nolint: errcheck
+		resp.Body.Close()                  // nolint: errcheck
+		r.Logger.Warn("%s %s failed with status %d, retrying in %s (retry %d of %d)", req.Method, req.URL.Path, resp.StatusCode, wait.Round(time.Millisecond), attempt+1, maxRetries)
+		r.mutex.Lock()
+		r.hostStats(req.URL.Host).Retries++
+		r.mutex.Unlock()
+		if err := r.wait(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// checkRetry records resp's stats and returns whether req should be retried
+// and how long to wait first. Rate limited requests are always retried since
+// the host didn't act on them. Server errors are only retried for idempotent
+// methods so that ex. comments aren't posted twice.
+func (r *APIRetrier) checkRetry(req *http.Request, resp *http.Response, attempt int) (time.Duration, bool) {
+	rateLimited := isRateLimited(resp)
+
+	r.mutex.Lock()
+	stats := r.hostStats(req.URL.Host)
+	stats.Requests++
+	if rateLimited {
+		stats.RateLimited++
+	}
+	if limit, ok := headerInt(resp.Header, "X-RateLimit-Limit", "RateLimit-Limit"); ok {
+		stats.Limit = limit
+		stats.Remaining, _ = headerInt(resp.Header, "X-RateLimit-Remaining", "RateLimit-Remaining")
+		if reset, ok := headerInt(resp.Header, "X-RateLimit-Reset", "RateLimit-Reset"); ok {
+			stats.Reset = time.Unix(int64(reset), 0)
+		}
+	}
+	r.mutex.Unlock()
+
+	switch {
+	case rateLimited:
+		wait, ok := rateLimitWait(resp)
+		if !ok {
+			return r.backoff(attempt), true
+		}
+		if wait > r.MaxBackoff {
+			r.Logger.Warn("rate limit for %s resets in %s which is longer than the max backoff of %s, not retrying", req.URL.Host, wait.Round(time.Second), r.MaxBackoff)
+			return 0, false
+		}
+		return wait, true
+	case resp.StatusCode >= 500 && isIdempotent(req.Method):
+		return r.backoff(attempt), true
+	default:
+		return 0, false
+	}
+}
+
+// hostStats returns the stats for host, creating them if needed. The caller
+// must hold r.mutex.
+func (r *APIRetrier) hostStats(host string) *APIHostStats {
+	if r.hosts == nil {
+		r.hosts = make(map[string]*APIHostStats)
+	}
+	stats, ok := r.hosts[host]
+	if !ok {
+		stats = &APIHostStats{}
+		r.hosts[host] = stats
+	}
+	return stats
+}
+
+// backoff returns how long to wait before retry number attempt+1. It's
+// between half and all of MinBackoff doubled attempt times, capped at
+// MaxBackoff.
+func (r *APIRetrier) backoff(attempt int) time.Duration {
+	backoff := r.MinBackoff
+	for i := 0; i < attempt && backoff < r.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > r.MaxBackoff {
+		backoff = r.MaxBackoff
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	// nolint: gosec
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+func (r *APIRetrier) wait(ctx context.Context, d time.Duration) error {
+	if r.sleep != nil {
+		return r.sleep(ctx, d)
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// isRateLimited returns true if resp says the rate limit was exceeded. GitLab
+// and others respond with 429. GitHub responds with 403 and either no
+// remaining quota or, for its secondary rate limits, a message saying so.
+func isRateLimited(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	if resp.StatusCode != http.StatusForbidden {
+		return false
+	}
+	if resp.Header.Get("Retry-After") != "" || resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		return true
+	}
+	// The body is read so it can be checked and then replaced so callers can
+	// still read it.
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close() // nolint: errcheck
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	msg := strings.ToLower(string(body))
+	return strings.Contains(msg, "secondary rate limit") || strings.Contains(msg, "abuse detection")
+}
+
+// rateLimitWait returns how long resp says to wait before retrying, from
+// either its Retry-After header or when its rate limit resets.
+func rateLimitWait(resp *http.Response) (time.Duration, bool) {
+	if secs, ok := headerInt(resp.Header, "Retry-After"); ok {
+		return time.Duration(secs) * time.Second, true
+	}
+	if remaining, ok := headerInt(resp.Header, "X-RateLimit-Remaining", "RateLimit-Remaining"); !ok || remaining > 0 {
+		return 0, false
+	}
+	if reset, ok := headerInt(resp.Header, "X-RateLimit-Reset", "RateLimit-Reset"); ok {
+		wait := time.Until(time.Unix(int64(reset), 0))
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
+
+// headerInt returns the value of the first of names that's set in h as an int.
+func headerInt(h http.Header, names ...string) (int, bool) {
+	for _, name := range names {
+		if v := h.Get(name); v != "" {
+			i, err := strconv.Atoi(v)
+			return i, err == nil
+		}
+	}
+	return 0, false
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,237 @@
+package vcs
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// newTestAPIRetrier returns an APIRetrier that records its waits instead of
+// sleeping.
+func newTestAPIRetrier(t *testing.T, waits *[]time.Duration) *APIRetrier {
+	r := NewAPIRetrier(3, logging.NewNoopLogger(t))
+	r.sleep = func(_ context.Context, d time.Duration) error {
+		*waits = append(*waits, d)
+		return nil
+	}
+	return r
+}
+
+// newTestAPIServer returns a server that responds with the next of handlers
+// for each request.
+func newTestAPIServer(t *testing.T, handlers ...http.HandlerFunc) (*httptest.Server, *int) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls >= len(handlers) {
+			t.Errorf("unexpected request %d", calls+1)
+			return
+		}
+		handlers[calls](w, r)
+		calls++
+	}))
+	return server, &calls
+}
+
+func TestAPIRetrier_RetriesServerErrors(t *testing.T) {
+	var waits []time.Duration
+	retrier := newTestAPIRetrier(t, &waits)
+	server, calls := newTestAPIServer(t,
+		func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusBadGateway) },
+		func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusServiceUnavailable) },
+		func(w http.ResponseWriter, _ *http.Request) { fmt.Fprint(w, "ok") },
+	)
+	defer server.Close()
+
+	client := &http.Client{Transport: retrier.Transport(nil)}
+	resp, err := client.Get(server.URL)
+	Ok(t, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	Ok(t, err)
+	Equals(t, "ok", string(body))
+	Equals(t, 3, *calls)
+
+	// The waits are jittered between half and all of the backoff.
+	Equals(t, 2, len(waits))
+	Assert(t, waits[0] >= 500*time.Millisecond && waits[0] <= time.Second, "first wait was %s", waits[0])
+	Assert(t, waits[1] >= time.Second && waits[1] <= 2*time.Second, "second wait was %s", waits[1])
+
+	u, _ := url.Parse(server.URL)
+	stats := retrier.GetStats()[u.Host]
+	Equals(t, 3, stats.Requests)
+	Equals(t, 2, stats.Retries)
+	Equals(t, 0, stats.RateLimited)
+}
+
+func TestAPIRetrier_GivesUpAfterMaxRetries(t *testing.T) {
+	var waits []time.Duration
+	retrier := newTestAPIRetrier(t, &waits)
+	fail := func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusInternalServerError) }
+	server, calls := newTestAPIServer(t, fail, fail, fail, fail)
+	defer server.Close()
+
+	client := &http.Client{Transport: retrier.Transport(nil)}
+	resp, err := client.Get(server.URL)
+	Ok(t, err)
+	Equals(t, http.StatusInternalServerError, resp.StatusCode)
+	Equals(t, 4, *calls)
+}
+
+// Server errors for requests that aren't idempotent shouldn't be retried in
+// case the request was acted on.
+func TestAPIRetrier_DoesNotRetryPostServerErrors(t *testing.T) {
+	var waits []time.Duration
+	retrier := newTestAPIRetrier(t, &waits)
+	server, calls := newTestAPIServer(t,
+		func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusBadGateway) },
+	)
+	defer server.Close()
+
+	client := &http.Client{Transport: retrier.Transport(nil)}
+	resp, err := client.Post(server.URL, "application/json", strings.NewReader("{}"))
+	Ok(t, err)
+	Equals(t, http.StatusBadGateway, resp.StatusCode)
+	Equals(t, 1, *calls)
+}
+
+// GitHub's secondary rate limits respond with 403 and a Retry-After header.
+// Rate limited requests weren't acted on so even POSTs are retried.
+func TestAPIRetrier_RetriesSecondaryRateLimit(t *testing.T) {
+	var waits []time.Duration
+	retrier := newTestAPIRetrier(t, &waits)
+	var bodies []string
+	readBody := func(r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		Ok(t, err)
+		bodies = append(bodies, string(body))
+	}
+	server, calls := newTestAPIServer(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			readBody(r)
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message": "You have exceeded a secondary rate limit."}`)
+		},
+		func(w http.ResponseWriter, r *http.Request) {
+			readBody(r)
+			w.WriteHeader(http.StatusCreated)
+		},
+	)
+	defer server.Close()
+
+	client := &http.Client{Transport: retrier.Transport(nil)}
+	resp, err := client.Post(server.URL, "application/json", strings.NewReader(`{"body": "comment"}`))
+	Ok(t, err)
+	Equals(t, http.StatusCreated, resp.StatusCode)
+	Equals(t, 2, *calls)
+	Equals(t, []time.Duration{7 * time.Second}, waits)
+	Equals(t, []string{`{"body": "comment"}`, `{"body": "comment"}`}, bodies)
+
+	u, _ := url.Parse(server.URL)
+	Equals(t, 1, retrier.GetStats()[u.Host].RateLimited)
+}
+
+// A 403 that isn't for a rate limit shouldn't be retried and its body should
+// still be readable.
+func TestAPIRetrier_DoesNotRetryForbidden(t *testing.T) {
+	var waits []time.Duration
+	retrier := newTestAPIRetrier(t, &waits)
+	server, calls := newTestAPIServer(t,
+		func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message": "Resource not accessible by integration"}`)
+		},
+	)
+	defer server.Close()
+
+	client := &http.Client{Transport: retrier.Transport(nil)}
+	resp, err := client.Get(server.URL)
+	Ok(t, err)
+	Equals(t, 1, *calls)
+	body, err := ioutil.ReadAll(resp.Body)
+	Ok(t, err)
+	Equals(t, `{"message": "Resource not accessible by integration"}`, string(body))
+}
+
+// When the rate limit is used up, the request should be retried once it
+// resets unless that's longer than the max backoff.
+func TestAPIRetrier_PrimaryRateLimit(t *testing.T) {
+	cases := []struct {
+		description string
+		resetIn     time.Duration
+		expCalls    int
+		expRemain   int
+	}{
+		{"resets soon", 10 * time.Second, 2, 4999},
+		{"resets after max backoff", time.Hour, 1, 0},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			var waits []time.Duration
+			retrier := newTestAPIRetrier(t, &waits)
+			reset := time.Now().Add(c.resetIn).Unix()
+			server, calls := newTestAPIServer(t,
+				func(w http.ResponseWriter, _ *http.Request) {
+					w.Header().Set("X-RateLimit-Limit", "5000")
+					w.Header().Set("X-RateLimit-Remaining", "0")
+					w.Header().Set("X-RateLimit-Reset", fmt.Sprint(reset))
+					w.WriteHeader(http.StatusForbidden)
+				},
+				func(w http.ResponseWriter, _ *http.Request) {
+					w.Header().Set("X-RateLimit-Limit", "5000")
+					w.Header().Set("X-RateLimit-Remaining", "4999")
+					w.Header().Set("X-RateLimit-Reset", fmt.Sprint(reset))
+				},
+			)
+			defer server.Close()
+
+			client := &http.Client{Transport: retrier.Transport(nil)}
+			_, err := client.Get(server.URL)
+			Ok(t, err)
+			Equals(t, c.expCalls, *calls)
+
+			u, _ := url.Parse(server.URL)
+			stats := retrier.GetStats()[u.Host]
+			Equals(t, 5000, stats.Limit)
+			Equals(t, c.expRemain, stats.Remaining)
+			Equals(t, time.Unix(reset, 0), stats.Reset)
+		})
+	}
+}
+
+// GitLab responds with 429 and RateLimit-* headers.
+func TestAPIRetrier_GitlabRateLimit(t *testing.T) {
+	var waits []time.Duration
+	retrier := newTestAPIRetrier(t, &waits)
+	server, calls := newTestAPIServer(t,
+		func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("RateLimit-Limit", "600")
+			w.Header().Set("RateLimit-Remaining", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		},
+		func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("RateLimit-Limit", "600")
+			w.Header().Set("RateLimit-Remaining", "599")
+		},
+	)
+	defer server.Close()
+
+	client := &http.Client{Transport: retrier.Transport(nil)}
+	resp, err := client.Get(server.URL)
+	Ok(t, err)
+	Equals(t, http.StatusOK, resp.StatusCode)
+	Equals(t, 2, *calls)
+
+	u, _ := url.Parse(server.URL)
+	stats := retrier.GetStats()[u.Host]
+	Equals(t, 1, stats.RateLimited)
+	Equals(t, 599, stats.Remaining)
+}
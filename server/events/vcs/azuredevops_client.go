@@ -22,21 +22,23 @@ type AzureDevopsClient struct {
 	UserName string
 }
 
-// NewAzureDevopsClient returns a valid Azure DevOps client.
-func NewAzureDevopsClient(hostname string, userName string, token string) (*AzureDevopsClient, error) {
+// NewAzureDevopsClient returns a valid Azure DevOps client. If retrier is set,
+// its requests are retried when they're rate limited or fail.
+func NewAzureDevopsClient(hostname string, userName string, token string, retrier *APIRetrier) (*AzureDevopsClient, error) {
 	tp := azuredevops.BasicAuthTransport{
 		Username: "",
 		Password: strings.TrimSpace(token),
 	}
 	httpClient := tp.Client()
 	httpClient.Timeout = time.Second * 10
+	httpClient.Transport = retrier.Transport(httpClient.Transport)
 	var adClient, err = azuredevops.NewClient(httpClient)
 	if err != nil {
 		return nil, err
 	}
 
-	if hostname != "dev.azure.com" {
-		baseURL := fmt.Sprintf("https://%s/", hostname)
+	if hostname != "" && hostname != "dev.azure.com" {
+		baseURL := fmt.Sprintf("https://%s/", strings.TrimSuffix(hostname, "/"))
 		base, err := url.Parse(baseURL)
 		if err != nil {
 			return nil, errors.Wrapf(err, "invalid azure devops hostname trying to parse %s", baseURL)
@@ -100,6 +102,10 @@ func (g *AzureDevopsClient) CreateComment(repo models.Repo, pullNum int, comment
 		"\n<br>\n\n**Warning**: Output length greater than max comment size. Continued in next comment."
 	sepStart := "Continued from previous comment.\n<details><summary>Show Output</summary>\n\n" +
 		"```diff\n"
+	if command != "" {
+		sepStart = fmt.Sprintf("Continued %s output from previous comment.\n<details><summary>Show Output</summary>\n\n", command) +
+			"```diff\n"
+	}
 
 	// maxCommentLength is the maximum number of chars allowed in a single comment
 	// This length was copied from the Github client - haven't found documentation
@@ -130,7 +136,53 @@ func (g *AzureDevopsClient) CreateComment(repo models.Repo, pullNum int, comment
 	return nil
 }
 
+// azureDevopsThreadList is the response to listing a pull request's threads.
+type azureDevopsThreadList struct {
+	Value []*azuredevops.GitPullRequestCommentThread `json:"value"`
+}
+
+// CreateReply creates the comment with CreateComment since only GitLab
+// discussions are replied to.
+func (g *AzureDevopsClient) CreateReply(repo models.Repo, pullNum int, discussionID string, comment string, command string) (int64, error) {
+	return 0, g.CreateComment(repo, pullNum, comment, command)
+}
+
+// HidePrevCommandComments closes previous comment threads from command which
+// collapses them in the pull request's overview. Each comment is posted in its
+// own thread by CreateComment.
 func (g *AzureDevopsClient) HidePrevCommandComments(repo models.Repo, pullNum int, command string) error {
+	owner, project, repoName := SplitAzureDevopsRepoFullName(repo.FullName)
+	threadsURL := fmt.Sprintf("%s/%s/_apis/git/repositories/%s/pullrequests/%d/threads",
+		owner, project, repoName, pullNum)
+	req, err := g.Client.NewRequest("GET", threadsURL+"?api-version=5.1", nil)
+	if err != nil {
+		return err
+	}
+	var threads azureDevopsThreadList
+	if _, err := g.Client.Execute(g.ctx, req, &threads); err != nil {
+		return errors.Wrap(err, "listing threads")
+	}
+
+	for _, thread := range threads.Value {
+		if thread.GetIsDeleted() || thread.GetStatus() == "closed" || len(thread.Comments) == 0 {
+			continue
+		}
+		comment := thread.Comments[0]
+		if comment.Author != nil && !strings.EqualFold(comment.Author.GetUniqueName(), g.UserName) {
+			continue
+		}
+		if !common.IsCommandComment(comment.GetContent(), command) {
+			continue
+		}
+		update := azuredevops.GitPullRequestCommentThread{Status: azuredevops.String("closed")}
+		req, err := g.Client.NewRequest("PATCH", fmt.Sprintf("%s/%d?api-version=5.1", threadsURL, thread.GetID()), &update)
+		if err != nil {
+			return err
+		}
+		if _, err := g.Client.Execute(g.ctx, req, nil); err != nil {
+			return errors.Wrapf(err, "closing thread %d", thread.GetID())
+		}
+	}
 	return nil
 }
 
@@ -215,6 +267,15 @@ func (g *AzureDevopsClient) PullIsMergeable(repo models.Repo, pull models.PullRe
 	return true, nil
 }
 
+// PullIsClosed returns true if the pull request was completed or abandoned.
+func (g *AzureDevopsClient) PullIsClosed(repo models.Repo, pull models.PullRequest) (bool, error) {
+	adPull, err := g.GetPullRequest(repo, pull.Num)
+	if err != nil {
+		return false, errors.Wrap(err, "getting pull request")
+	}
+	return adPull.GetStatus() != azuredevops.PullActive.String(), nil
+}
+
 // GetPullRequest returns the pull request.
 func (g *AzureDevopsClient) GetPullRequest(repo models.Repo, num int) (*azuredevops.GitPullRequest, error) {
 	opts := azuredevops.PullRequestGetOptions{
@@ -288,8 +349,9 @@ func (g *AzureDevopsClient) UpdateStatus(repo models.Repo, pull models.PullReque
 	return err
 }
 
-// MergePull merges the merge request using the default no fast-forward strategy
-// If the user has set a branch policy that disallows no fast-forward, the merge will fail
+// MergePull merges the merge request using the merge method from pullOptions,
+// defaulting to the no fast-forward strategy.
+// If the user has set a branch policy that disallows the strategy, the merge will fail
 // until we handle branch policies
 // https://docs.microsoft.com/en-us/azure/devops/repos/git/branch-policies?view=azure-devops
 func (g *AzureDevopsClient) MergePull(pull models.PullRequest, pullOptions models.PullRequestOptions) error {
@@ -312,13 +374,26 @@ func (g *AzureDevopsClient) MergePull(pull models.PullRequest, pullOptions model
 	}
 	// Set default pull request completion options
 	mcm := azuredevops.NoFastForward.String()
+	switch pullOptions.MergeMethod {
+	case "", models.MergeCommitMergeMethod:
+	case models.SquashMergeMethod:
+		mcm = azuredevops.Squash.String()
+	case models.RebaseMergeMethod:
+		mcm = azuredevops.Rebase.String()
+	default:
+		return fmt.Errorf("merge method %q is not supported by Azure DevOps", pullOptions.MergeMethod)
+	}
+	commitMsg := common.AutomergeCommitMsg
+	if pullOptions.CommitMessage != "" {
+		commitMsg = pullOptions.CommitMessage
+	}
 	twi := new(bool)
 	*twi = true
 	completionOpts := azuredevops.GitPullRequestCompletionOptions{
 		BypassPolicy:            new(bool),
 		BypassReason:            azuredevops.String(""),
 		DeleteSourceBranch:      &pullOptions.DeleteSourceBranchOnMerge,
-		MergeCommitMessage:      azuredevops.String(common.AutomergeCommitMsg),
+		MergeCommitMessage:      azuredevops.String(commitMsg),
 		MergeStrategy:           &mcm,
 		SquashMerge:             new(bool),
 		TransitionWorkItems:     twi,
@@ -385,6 +460,38 @@ func (g *AzureDevopsClient) DownloadRepoConfigFile(pull models.PullRequest) (boo
 	return false, []byte{}, fmt.Errorf("Not Implemented")
 }
 
+func (g *AzureDevopsClient) GetCloneURL(VCSHostType models.VCSHostType, repo string) (string, error) {
+	return "", fmt.Errorf("Not Implemented")
+}
+
+func (g *AzureDevopsClient) UserIsTeamMember(repo models.Repo, user models.User, teams []string) (bool, error) {
+	return false, fmt.Errorf("Not Implemented")
+}
+
+func (g *AzureDevopsClient) GetPullApprovers(repo models.Repo, pull models.PullRequest) ([]string, error) {
+	return nil, fmt.Errorf("Not Implemented")
+}
+
+func (g *AzureDevopsClient) DownloadCodeowners(pull models.PullRequest) (bool, []byte, error) {
+	return false, []byte{}, fmt.Errorf("Not Implemented")
+}
+
+func (g *AzureDevopsClient) SupportsCommentUpdates(repo models.Repo) bool {
+	return false
+}
+
+func (g *AzureDevopsClient) ReactToComment(repo models.Repo, pullNum int, commentID int64, reaction string) error {
+	return fmt.Errorf("Not Implemented")
+}
+
+func (g *AzureDevopsClient) CreateUpdatableComment(repo models.Repo, pullNum int, comment string) (int64, error) {
+	return 0, fmt.Errorf("Not Implemented")
+}
+
+func (g *AzureDevopsClient) UpdateComment(repo models.Repo, pullNum int, commentID int64, comment string, command string) error {
+	return fmt.Errorf("Not Implemented")
+}
+
 // GitStatusContextFromSrc parses an Atlantis formatted src string into a context suitable
 // for the status update API. In the AzureDevops branch policy UI there is a single string
 // field used to drive these contexts where all text preceding the final '/' character is
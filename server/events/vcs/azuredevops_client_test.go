@@ -99,7 +99,7 @@ func TestAzureDevopsClient_MergePull(t *testing.T) {
 
 			testServerURL, err := url.Parse(testServer.URL)
 			Ok(t, err)
-			client, err := vcs.NewAzureDevopsClient(testServerURL.Host, "user", "token")
+			client, err := vcs.NewAzureDevopsClient(testServerURL.Host, "user", "token", nil)
 			client.Client.VsaexBaseURL = *testServerURL
 			Ok(t, err)
 			defer disableSSLVerification()()
@@ -213,7 +213,7 @@ func TestAzureDevopsClient_UpdateStatus(t *testing.T) {
 
 			testServerURL, err := url.Parse(testServer.URL)
 			Ok(t, err)
-			client, err := vcs.NewAzureDevopsClient(testServerURL.Host, "user", "token")
+			client, err := vcs.NewAzureDevopsClient(testServerURL.Host, "user", "token", nil)
 			Ok(t, err)
 			defer disableSSLVerification()()
 
@@ -276,7 +276,7 @@ func TestAzureDevopsClient_GetModifiedFiles(t *testing.T) {
 
 	testServerURL, err := url.Parse(testServer.URL)
 	Ok(t, err)
-	client, err := vcs.NewAzureDevopsClient(testServerURL.Host, "user", "token")
+	client, err := vcs.NewAzureDevopsClient(testServerURL.Host, "user", "token", nil)
 	Ok(t, err)
 	defer disableSSLVerification()()
 
@@ -297,6 +297,47 @@ func TestAzureDevopsClient_GetModifiedFiles(t *testing.T) {
 	Equals(t, []string{"file1.txt", "file2.txt"}, files)
 }
 
+// Only open threads started by Atlantis from the command should be closed.
+func TestAzureDevopsClient_HidePrevCommandComments(t *testing.T) {
+	threadsResp := `{"value": [
+	{"id": 1, "status": "active", "comments": [{"content": "Ran Plan for dir: .", "author": {"uniqueName": "user"}}]},
+	{"id": 2, "status": "active", "comments": [{"content": "Ran Plan for dir: .", "author": {"uniqueName": "someone-else"}}]},
+	{"id": 3, "status": "closed", "comments": [{"content": "Ran Plan for dir: .", "author": {"uniqueName": "user"}}]},
+	{"id": 4, "status": "active", "comments": [{"content": "Ran Apply for dir: .", "author": {"uniqueName": "user"}}]},
+	{"id": 5, "status": "active", "comments": [{"content": "Continued plan output from previous comment.", "author": {"uniqueName": "USER"}}]}
+], "count": 5}`
+	var closed []string
+	testServer := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method + " " + r.RequestURI {
+			case "GET /owner/project/_apis/git/repositories/repo/pullrequests/1/threads?api-version=5.1":
+				w.Write([]byte(threadsResp)) // nolint: errcheck
+			case "PATCH /owner/project/_apis/git/repositories/repo/pullrequests/1/threads/1?api-version=5.1",
+				"PATCH /owner/project/_apis/git/repositories/repo/pullrequests/1/threads/5?api-version=5.1":
+				body, err := ioutil.ReadAll(r.Body)
+				Ok(t, err)
+				Equals(t, `{"status":"closed"}`, strings.TrimSpace(string(body)))
+				closed = append(closed, r.RequestURI)
+				w.Write([]byte(`{}`)) // nolint: errcheck
+			default:
+				t.Errorf("got unexpected request at %q", r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+
+	testServerURL, err := url.Parse(testServer.URL)
+	Ok(t, err)
+	client, err := vcs.NewAzureDevopsClient(testServerURL.Host, "user", "token", nil)
+	Ok(t, err)
+	defer disableSSLVerification()()
+
+	Ok(t, client.HidePrevCommandComments(models.Repo{FullName: "owner/project/repo"}, 1, "Plan"))
+	Equals(t, []string{
+		"/owner/project/_apis/git/repositories/repo/pullrequests/1/threads/1?api-version=5.1",
+		"/owner/project/_apis/git/repositories/repo/pullrequests/1/threads/5?api-version=5.1",
+	}, closed)
+}
+
 func TestAzureDevopsClient_PullIsMergeable(t *testing.T) {
 	type Policy struct {
 		genre  string
@@ -396,7 +437,7 @@ func TestAzureDevopsClient_PullIsMergeable(t *testing.T) {
 			testServerURL, err := url.Parse(testServer.URL)
 			Ok(t, err)
 
-			client, err := vcs.NewAzureDevopsClient(testServerURL.Host, "user", "token")
+			client, err := vcs.NewAzureDevopsClient(testServerURL.Host, "user", "token", nil)
 			Ok(t, err)
 
 			defer disableSSLVerification()()
@@ -490,7 +531,7 @@ func TestAzureDevopsClient_PullIsApproved(t *testing.T) {
 			testServerURL, err := url.Parse(testServer.URL)
 			Ok(t, err)
 
-			client, err := vcs.NewAzureDevopsClient(testServerURL.Host, "user", "token")
+			client, err := vcs.NewAzureDevopsClient(testServerURL.Host, "user", "token", nil)
 			Ok(t, err)
 
 			defer disableSSLVerification()()
@@ -535,7 +576,7 @@ func TestAzureDevopsClient_GetPullRequest(t *testing.T) {
 			}))
 		testServerURL, err := url.Parse(testServer.URL)
 		Ok(t, err)
-		client, err := vcs.NewAzureDevopsClient(testServerURL.Host, "user", "token")
+		client, err := vcs.NewAzureDevopsClient(testServerURL.Host, "user", "token", nil)
 		Ok(t, err)
 		defer disableSSLVerification()()
 
@@ -555,7 +596,7 @@ func TestAzureDevopsClient_GetPullRequest(t *testing.T) {
 }
 
 func TestAzureDevopsClient_MarkdownPullLink(t *testing.T) {
-	client, err := vcs.NewAzureDevopsClient("hostname", "user", "token")
+	client, err := vcs.NewAzureDevopsClient("hostname", "user", "token", nil)
 	Ok(t, err)
 	pull := models.PullRequest{Num: 1}
 	s, _ := client.MarkdownPullLink(pull)
@@ -621,3 +662,19 @@ func TestAzureDevopsClient_GitStatusContextFromSrc(t *testing.T) {
 		Equals(t, &c.expGenre, result.Genre)
 	}
 }
+
+func TestNewAzureDevopsClient_BaseURL(t *testing.T) {
+	cases := map[string]string{
+		"dev.azure.com":        "https://dev.azure.com/",
+		"ado.example.com":      "https://ado.example.com/",
+		"ado.example.com/tfs":  "https://ado.example.com/tfs/",
+		"ado.example.com/tfs/": "https://ado.example.com/tfs/",
+	}
+	for hostname, exp := range cases {
+		t.Run(hostname, func(t *testing.T) {
+			client, err := vcs.NewAzureDevopsClient(hostname, "user", "token", nil)
+			Ok(t, err)
+			Equals(t, exp, client.Client.BaseURL.String())
+		})
+	}
+}
@@ -10,9 +10,15 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs/common"
 	validator "gopkg.in/go-playground/validator.v9"
 )
 
+// maxCommentLength is the maximum number of chars we post in a single comment.
+// Bitbucket Cloud accepts longer comments but they become unusably slow to
+// render so we use the same limit as Bitbucket Server.
+const maxCommentLength = 32768
+
 type Client struct {
 	HTTPClient  *http.Client
 	Username    string
@@ -85,10 +91,25 @@ func (b *Client) GetModifiedFiles(repo models.Repo, pull models.PullRequest) ([]
 }
 
 // CreateComment creates a comment on the merge request.
+// If comment length is greater than the max comment length we split into
+// multiple comments.
 func (b *Client) CreateComment(repo models.Repo, pullNum int, comment string, command string) error {
-	// NOTE: I tried to find the maximum size of a comment for bitbucket.org but
-	// I got up to 200k chars without issue so for now I'm not going to bother
-	// to detect this.
+	sepEnd := "\n```\n**Warning**: Output length greater than max comment size. Continued in next comment."
+	sepStart := "Continued from previous comment.\n```diff\n"
+	if command != "" {
+		sepStart = fmt.Sprintf("Continued %s output from previous comment.\n```diff\n", command)
+	}
+	comments := common.SplitComment(comment, maxCommentLength, sepEnd, sepStart)
+	for _, c := range comments {
+		if err := b.postComment(repo, pullNum, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// postComment actually posts the comment. It's a helper for CreateComment().
+func (b *Client) postComment(repo models.Repo, pullNum int, comment string) error {
 	bodyBytes, err := json.Marshal(map[string]map[string]string{"content": {
 		"raw": comment,
 	}})
@@ -100,7 +121,60 @@ func (b *Client) CreateComment(repo models.Repo, pullNum int, comment string, co
 	return err
 }
 
+// CreateReply creates the comment with CreateComment since only GitLab
+// discussions are replied to.
+func (b *Client) CreateReply(repo models.Repo, pullNum int, discussionID string, comment string, command string) (int64, error) {
+	return 0, b.CreateComment(repo, pullNum, comment, command)
+}
+
+// HidePrevCommandComments deletes previous comments from command since
+// Bitbucket Cloud can't hide or collapse comments.
 func (b *Client) HidePrevCommandComments(repo models.Repo, pullNum int, command string) error {
+	resp, err := b.makeRequest("GET", fmt.Sprintf("%s/2.0/user", b.BaseURL), nil)
+	if err != nil {
+		return errors.Wrap(err, "getting current user")
+	}
+	var user User
+	if err := json.Unmarshal(resp, &user); err != nil {
+		return errors.Wrapf(err, "Could not parse response %q", string(resp))
+	}
+	if err := validator.New().Struct(user); err != nil {
+		return errors.Wrapf(err, "API response %q was missing fields", string(resp))
+	}
+
+	var toDelete []int
+	nextPageURL := fmt.Sprintf("%s/2.0/repositories/%s/pullrequests/%d/comments", b.BaseURL, repo.FullName, pullNum)
+	// We'll only loop 1000 times as a safety measure.
+	maxLoops := 1000
+	for i := 0; i < maxLoops; i++ {
+		resp, err := b.makeRequest("GET", nextPageURL, nil)
+		if err != nil {
+			return err
+		}
+		var comments PullRequestComments
+		if err := json.Unmarshal(resp, &comments); err != nil {
+			return errors.Wrapf(err, "Could not parse response %q", string(resp))
+		}
+		for _, c := range comments.Values {
+			if c.Deleted || c.User == nil || c.User.UUID == nil || *c.User.UUID != *user.UUID {
+				continue
+			}
+			if c.Content != nil && c.Content.Raw != nil && common.IsCommandComment(*c.Content.Raw, command) {
+				toDelete = append(toDelete, c.ID)
+			}
+		}
+		if comments.Next == nil || *comments.Next == "" {
+			break
+		}
+		nextPageURL = *comments.Next
+	}
+
+	for _, id := range toDelete {
+		path := fmt.Sprintf("%s/2.0/repositories/%s/pullrequests/%d/comments/%d", b.BaseURL, repo.FullName, pullNum, id)
+		if _, err := b.makeRequest("DELETE", path, nil); err != nil {
+			return errors.Wrapf(err, "deleting comment %d", id)
+		}
+	}
 	return nil
 }
 
@@ -129,6 +203,30 @@ func (b *Client) PullIsApproved(repo models.Repo, pull models.PullRequest) (bool
 	return false, nil
 }
 
+// PullIsClosed returns true if the pull request was merged, declined or
+// superseded.
+func (b *Client) PullIsClosed(repo models.Repo, pull models.PullRequest) (bool, error) {
+	path := fmt.Sprintf("%s/2.0/repositories/%s/pullrequests/%d", b.BaseURL, repo.FullName, pull.Num)
+	resp, err := b.makeRequest("GET", path, nil)
+	if err != nil {
+		return false, err
+	}
+	var pullResp PullRequest
+	if err := json.Unmarshal(resp, &pullResp); err != nil {
+		return false, errors.Wrapf(err, "Could not parse response %q", string(resp))
+	}
+	if pullResp.State == nil {
+		return false, fmt.Errorf("API response %q was missing the pull request state", string(resp))
+	}
+	return *pullResp.State != "OPEN", nil
+}
+
+// Ping implements vcs.Pinger.Ping by getting the authenticated user.
+func (b *Client) Ping() error {
+	_, err := b.makeRequest("GET", fmt.Sprintf("%s/2.0/user", b.BaseURL), nil)
+	return err
+}
+
 // PullIsMergeable returns true if the merge request has no conflicts and can be merged.
 func (b *Client) PullIsMergeable(repo models.Repo, pull models.PullRequest) (bool, error) {
 	nextPageURL := fmt.Sprintf("%s/2.0/repositories/%s/pullrequests/%d/diffstat", b.BaseURL, repo.FullName, pull.Num)
@@ -193,10 +291,32 @@ func (b *Client) UpdateStatus(repo models.Repo, pull models.PullRequest, status
 	return err
 }
 
-// MergePull merges the pull request.
+// MergePull merges the pull request. Bitbucket Cloud doesn't support the
+// rebase merge method.
 func (b *Client) MergePull(pull models.PullRequest, pullOptions models.PullRequestOptions) error {
 	path := fmt.Sprintf("%s/2.0/repositories/%s/pullrequests/%d/merge", b.BaseURL, pull.BaseRepo.FullName, pull.Num)
-	_, err := b.makeRequest("POST", path, nil)
+	var strategy string
+	switch pullOptions.MergeMethod {
+	case "":
+	case models.MergeCommitMergeMethod:
+		strategy = "merge_commit"
+	case models.SquashMergeMethod:
+		strategy = "squash"
+	default:
+		return fmt.Errorf("merge method %q is not supported by Bitbucket Cloud", pullOptions.MergeMethod)
+	}
+	if strategy == "" && pullOptions.CommitMessage == "" {
+		_, err := b.makeRequest("POST", path, nil)
+		return err
+	}
+	bodyBytes, err := json.Marshal(MergeRequest{
+		MergeStrategy: strategy,
+		Message:       pullOptions.CommitMessage,
+	})
+	if err != nil {
+		return errors.Wrap(err, "json encoding")
+	}
+	_, err = b.makeRequest("POST", path, bytes.NewBuffer(bodyBytes))
 	return err
 }
 
@@ -233,7 +353,7 @@ func (b *Client) makeRequest(method string, path string, reqBody io.Reader) ([]b
 	defer resp.Body.Close() // nolint: errcheck
 	requestStr := fmt.Sprintf("%s %s", method, path)
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent {
 		respBody, _ := ioutil.ReadAll(resp.Body)
 		return nil, fmt.Errorf("making request %q unexpected status code: %d, body: %s", requestStr, resp.StatusCode, string(respBody))
 	}
@@ -254,3 +374,35 @@ func (b *Client) SupportsSingleFileDownload(models.Repo) bool {
 func (b *Client) DownloadRepoConfigFile(pull models.PullRequest) (bool, []byte, error) {
 	return false, []byte{}, fmt.Errorf("Not Implemented")
 }
+
+func (b *Client) GetCloneURL(VCSHostType models.VCSHostType, repo string) (string, error) {
+	return "", fmt.Errorf("Not Implemented")
+}
+
+func (b *Client) UserIsTeamMember(repo models.Repo, user models.User, teams []string) (bool, error) {
+	return false, fmt.Errorf("Not Implemented")
+}
+
+func (b *Client) GetPullApprovers(repo models.Repo, pull models.PullRequest) ([]string, error) {
+	return nil, fmt.Errorf("Not Implemented")
+}
+
+func (b *Client) DownloadCodeowners(pull models.PullRequest) (bool, []byte, error) {
+	return false, []byte{}, fmt.Errorf("Not Implemented")
+}
+
+func (b *Client) SupportsCommentUpdates(repo models.Repo) bool {
+	return false
+}
+
+func (b *Client) ReactToComment(repo models.Repo, pullNum int, commentID int64, reaction string) error {
+	return fmt.Errorf("Not Implemented")
+}
+
+func (b *Client) CreateUpdatableComment(repo models.Repo, pullNum int, comment string) (int64, error) {
+	return 0, fmt.Errorf("Not Implemented")
+}
+
+func (b *Client) UpdateComment(repo models.Repo, pullNum int, commentID int64, comment string, command string) error {
+	return fmt.Errorf("Not Implemented")
+}
@@ -1,11 +1,13 @@
 package bitbucketcloud_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/runatlantis/atlantis/server/events/models"
@@ -345,6 +347,74 @@ func TestClient_PullIsMergeable(t *testing.T) {
 
 }
 
+// Test that comments that are too long are split into multiple comments.
+func TestClient_CreateCommentSplits(t *testing.T) {
+	var comments []string
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.RequestURI {
+		case "/2.0/repositories/owner/repo/pullrequests/1/comments":
+			body, err := ioutil.ReadAll(r.Body)
+			Ok(t, err)
+			var comment map[string]map[string]string
+			Ok(t, json.Unmarshal(body, &comment))
+			comments = append(comments, comment["content"]["raw"])
+			w.Write([]byte("{}")) // nolint: errcheck
+		default:
+			t.Errorf("got unexpected request at %q", r.RequestURI)
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+	}))
+	defer testServer.Close()
+
+	client := bitbucketcloud.NewClient(http.DefaultClient, "user", "pass", "runatlantis.io")
+	client.BaseURL = testServer.URL
+
+	err := client.CreateComment(models.Repo{FullName: "owner/repo"}, 1, strings.Repeat("a", 32769), "")
+	Ok(t, err)
+	Equals(t, 2, len(comments))
+	Assert(t, strings.HasPrefix(comments[1], "Continued from previous comment."), "exp continued comment, got %q", comments[1][:50])
+}
+
+// Only Atlantis's comments from the command should be deleted.
+func TestClient_HidePrevCommandComments(t *testing.T) {
+	var deleted []string
+	var testServer *httptest.Server
+	testServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.RequestURI {
+		case "GET /2.0/user":
+			w.Write([]byte(`{"uuid": "{atlantis}"}`)) // nolint: errcheck
+		case "GET /2.0/repositories/owner/repo/pullrequests/1/comments":
+			fmt.Fprintf(w, `{"values": [
+	{"id": 1, "content": {"raw": "Ran Plan for dir: ."}, "user": {"uuid": "{atlantis}"}},
+	{"id": 2, "content": {"raw": "Ran Plan for dir: ."}, "user": {"uuid": "{someone-else}"}}
+], "next": "%s/2.0/repositories/owner/repo/pullrequests/1/comments?page=2"}`, testServer.URL) // nolint: errcheck
+		case "GET /2.0/repositories/owner/repo/pullrequests/1/comments?page=2":
+			w.Write([]byte(`{"values": [
+	{"id": 3, "content": {"raw": "Continued plan output from previous comment."}, "user": {"uuid": "{atlantis}"}},
+	{"id": 4, "content": {"raw": "Ran Apply for dir: ."}, "user": {"uuid": "{atlantis}"}},
+	{"id": 5, "content": {"raw": ""}, "deleted": true, "user": {"uuid": "{atlantis}"}}
+]}`)) // nolint: errcheck
+		case "DELETE /2.0/repositories/owner/repo/pullrequests/1/comments/1", "DELETE /2.0/repositories/owner/repo/pullrequests/1/comments/3":
+			deleted = append(deleted, r.RequestURI)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("got unexpected request at %q", r.RequestURI)
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer testServer.Close()
+
+	client := bitbucketcloud.NewClient(http.DefaultClient, "user", "pass", "runatlantis.io")
+	client.BaseURL = testServer.URL
+
+	Ok(t, client.HidePrevCommandComments(models.Repo{FullName: "owner/repo"}, 1, "Plan"))
+	Equals(t, []string{
+		"/2.0/repositories/owner/repo/pullrequests/1/comments/1",
+		"/2.0/repositories/owner/repo/pullrequests/1/comments/3",
+	}, deleted)
+}
+
 func TestClient_MarkdownPullLink(t *testing.T) {
 	client := bitbucketcloud.NewClient(http.DefaultClient, "user", "pass", "runatlantis.io")
 	pull := models.PullRequest{Num: 1}
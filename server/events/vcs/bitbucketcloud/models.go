@@ -86,3 +86,26 @@ type CommentContent struct {
 type Author struct {
 	UUID *string `json:"uuid,omitempty" validate:"required"`
 }
+
+// User is the response to getting the current user.
+type User struct {
+	UUID *string `json:"uuid,omitempty" validate:"required"`
+}
+
+// PullRequestComments is a page of a pull request's comments.
+type PullRequestComments struct {
+	Values []PullRequestComment `json:"values,omitempty"`
+	Next   *string              `json:"next,omitempty"`
+}
+type PullRequestComment struct {
+	ID      int             `json:"id"`
+	Deleted bool            `json:"deleted"`
+	Content *CommentContent `json:"content,omitempty"`
+	User    *Author         `json:"user,omitempty"`
+}
+
+// MergeRequest is the body of a request to merge a pull request.
+type MergeRequest struct {
+	MergeStrategy string `json:"merge_strategy,omitempty"`
+	Message       string `json:"message,omitempty"`
+}
@@ -0,0 +1,37 @@
+package bitbucketcloud
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// signaturePrefix is prepended to the hex-encoded HMAC that Bitbucket Cloud
+// sends in the X-Hub-Signature header. Bitbucket Cloud only signs with SHA256.
+const signaturePrefix = "sha256="
+
+// ValidateSignature returns nil if the signature is a valid HMAC-SHA256 of
+// payload using secretKey. Otherwise it returns an error.
+func ValidateSignature(payload []byte, signature string, secretKey []byte) error {
+	if signature == "" {
+		return errors.New("missing signature")
+	}
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return fmt.Errorf("error parsing signature %q: must start with %q", signature, signaturePrefix)
+	}
+	messageMAC, err := hex.DecodeString(strings.TrimPrefix(signature, signaturePrefix))
+	if err != nil {
+		return fmt.Errorf("error decoding signature %q: %v", signature, err)
+	}
+	mac := hmac.New(sha256.New, secretKey)
+	// nolint: errcheck
+	mac.Write(payload)
+	if !hmac.Equal(messageMAC, mac.Sum(nil)) {
+		return errors.New("payload signature check failed")
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package bitbucketcloud_test
+
+import (
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/vcs/bitbucketcloud"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestValidateSignature(t *testing.T) {
+	body := `{"key":"value"}`
+	sig := `sha256=eec7f1700cc1636c63d5f3d21e9cce5f5114b789bdfb7ce53468d155d8f2f9ba`
+	err := bitbucketcloud.ValidateSignature([]byte(body), sig, []byte("mysecret"))
+	Ok(t, err)
+}
+
+func TestValidateSignature_Invalid(t *testing.T) {
+	body := `{"key":"other"}`
+	sig := `sha256=eec7f1700cc1636c63d5f3d21e9cce5f5114b789bdfb7ce53468d155d8f2f9ba`
+	err := bitbucketcloud.ValidateSignature([]byte(body), sig, []byte("mysecret"))
+	ErrEquals(t, "payload signature check failed", err)
+}
+
+func TestValidateSignature_Missing(t *testing.T) {
+	err := bitbucketcloud.ValidateSignature([]byte(`{}`), "", []byte("mysecret"))
+	ErrEquals(t, "missing signature", err)
+}
+
+func TestValidateSignature_WrongHash(t *testing.T) {
+	err := bitbucketcloud.ValidateSignature([]byte(`{}`), "sha1=abcd", []byte("mysecret"))
+	ErrEquals(t, `error parsing signature "sha1=abcd": must start with "sha256="`, err)
+}
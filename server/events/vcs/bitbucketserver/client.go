@@ -132,6 +132,9 @@ func (b *Client) GetProjectKey(repoName string, cloneURL string) (string, error)
 func (b *Client) CreateComment(repo models.Repo, pullNum int, comment string, command string) error {
 	sepEnd := "\n```\n**Warning**: Output length greater than max comment size. Continued in next comment."
 	sepStart := "Continued from previous comment.\n```diff\n"
+	if command != "" {
+		sepStart = fmt.Sprintf("Continued %s output from previous comment.\n```diff\n", command)
+	}
 	comments := common.SplitComment(comment, maxCommentLength, sepEnd, sepStart)
 	for _, c := range comments {
 		if err := b.postComment(repo, pullNum, c); err != nil {
@@ -141,7 +144,69 @@ func (b *Client) CreateComment(repo models.Repo, pullNum int, comment string, co
 	return nil
 }
 
+// CreateReply creates the comment with CreateComment since only GitLab
+// discussions are replied to.
+func (b *Client) CreateReply(repo models.Repo, pullNum int, discussionID string, comment string, command string) (int64, error) {
+	return 0, b.CreateComment(repo, pullNum, comment, command)
+}
+
+// HidePrevCommandComments deletes previous comments from command since
+// Bitbucket Server can't hide or collapse comments.
 func (b *Client) HidePrevCommandComments(repo models.Repo, pullNum int, command string) error {
+	projectKey, err := b.GetProjectKey(repo.Name, repo.SanitizedCloneURL)
+	if err != nil {
+		return err
+	}
+	pullURL := fmt.Sprintf("%s/rest/api/1.0/projects/%s/repos/%s/pull-requests/%d", b.BaseURL, projectKey, repo.Name, pullNum)
+
+	// Comments are listed through the pull request's activities.
+	var toDelete []ActivityComment
+	deleted := make(map[int]bool)
+	nextPageStart := 0
+	// We'll only loop 1000 times as a safety measure.
+	maxLoops := 1000
+	for i := 0; i < maxLoops; i++ {
+		resp, err := b.makeRequest("GET", fmt.Sprintf("%s/activities?start=%d", pullURL, nextPageStart), nil)
+		if err != nil {
+			return err
+		}
+		var activities Activities
+		if err := json.Unmarshal(resp, &activities); err != nil {
+			return errors.Wrapf(err, "Could not parse response %q", string(resp))
+		}
+		if err := validator.New().Struct(activities); err != nil {
+			return errors.Wrapf(err, "API response %q was missing fields", string(resp))
+		}
+		for _, a := range activities.Values {
+			if a.Action != "COMMENTED" || a.Comment == nil {
+				continue
+			}
+			if a.CommentAction == "DELETED" {
+				deleted[a.Comment.ID] = true
+				continue
+			}
+			if a.CommentAction != "ADDED" || !strings.EqualFold(a.Comment.Author.Name, b.Username) {
+				continue
+			}
+			if common.IsCommandComment(a.Comment.Text, command) {
+				toDelete = append(toDelete, *a.Comment)
+			}
+		}
+		if *activities.IsLastPage {
+			break
+		}
+		nextPageStart = *activities.NextPageStart
+	}
+
+	for _, c := range toDelete {
+		if deleted[c.ID] {
+			continue
+		}
+		path := fmt.Sprintf("%s/comments/%d?version=%d", pullURL, c.ID, c.Version)
+		if _, err := b.makeRequest("DELETE", path, nil); err != nil {
+			return errors.Wrapf(err, "deleting comment %d", c.ID)
+		}
+	}
 	return nil
 }
 
@@ -186,6 +251,33 @@ func (b *Client) PullIsApproved(repo models.Repo, pull models.PullRequest) (bool
 	return false, nil
 }
 
+// PullIsClosed returns true if the pull request was merged or declined.
+func (b *Client) PullIsClosed(repo models.Repo, pull models.PullRequest) (bool, error) {
+	projectKey, err := b.GetProjectKey(repo.Name, repo.SanitizedCloneURL)
+	if err != nil {
+		return false, err
+	}
+	path := fmt.Sprintf("%s/rest/api/1.0/projects/%s/repos/%s/pull-requests/%d", b.BaseURL, projectKey, repo.Name, pull.Num)
+	resp, err := b.makeRequest("GET", path, nil)
+	if err != nil {
+		return false, err
+	}
+	var pullResp PullRequest
+	if err := json.Unmarshal(resp, &pullResp); err != nil {
+		return false, errors.Wrapf(err, "Could not parse response %q", string(resp))
+	}
+	if pullResp.State == nil {
+		return false, fmt.Errorf("API response %q was missing the pull request state", string(resp))
+	}
+	return *pullResp.State != "OPEN", nil
+}
+
+// Ping implements vcs.Pinger.Ping by getting the authenticated user.
+func (b *Client) Ping() error {
+	_, err := b.makeRequest("GET", fmt.Sprintf("%s/rest/api/1.0/users/%s", b.BaseURL, url.PathEscape(b.Username)), nil)
+	return err
+}
+
 // PullIsMergeable returns true if the merge request has no conflicts and can be merged.
 func (b *Client) PullIsMergeable(repo models.Repo, pull models.PullRequest) (bool, error) {
 	projectKey, err := b.GetProjectKey(repo.Name, repo.SanitizedCloneURL)
@@ -243,28 +335,147 @@ func (b *Client) UpdateStatus(repo models.Repo, pull models.PullRequest, status
 	return err
 }
 
-// MergePull merges the pull request.
+// MergePull merges the pull request. Bitbucket Server uses the pull request's
+// version for optimistic locking so if the pull request is updated between
+// getting its version and merging, ex. by a reviewer approving it, the merge
+// is retried with the new version. If the merge is vetoed by the repo's merge
+// checks, ex. required builds or approvals, the error says why.
 func (b *Client) MergePull(pull models.PullRequest, pullOptions models.PullRequestOptions) error {
 	projectKey, err := b.GetProjectKey(pull.BaseRepo.Name, pull.BaseRepo.SanitizedCloneURL)
 	if err != nil {
 		return err
 	}
 
-	// We need to make a get pull request API call to get the correct "version".
-	path := fmt.Sprintf("%s/rest/api/1.0/projects/%s/repos/%s/pull-requests/%d", b.BaseURL, projectKey, pull.BaseRepo.Name, pull.Num)
-	resp, err := b.makeRequest("GET", path, nil)
-	if err != nil {
-		return err
+	var strategyID string
+	switch pullOptions.MergeMethod {
+	case "":
+	case models.MergeCommitMergeMethod:
+		strategyID = "no-ff"
+	case models.SquashMergeMethod:
+		strategyID = "squash"
+	case models.RebaseMergeMethod:
+		strategyID = "rebase-no-ff"
+	default:
+		return fmt.Errorf("merge method %q is not supported by Bitbucket Server", pullOptions.MergeMethod)
+	}
+	var bodyBytes []byte
+	if strategyID != "" || pullOptions.CommitMessage != "" {
+		bodyBytes, err = json.Marshal(MergeRequest{
+			Message:    pullOptions.CommitMessage,
+			StrategyID: strategyID,
+		})
+		if err != nil {
+			return errors.Wrap(err, "json encoding")
+		}
 	}
+
+	pullURL := fmt.Sprintf("%s/rest/api/1.0/projects/%s/repos/%s/pull-requests/%d", b.BaseURL, projectKey, pull.BaseRepo.Name, pull.Num)
 	var pullResp PullRequest
-	if err := json.Unmarshal(resp, &pullResp); err != nil {
-		return errors.Wrapf(err, "Could not parse response %q", string(resp))
+	for attempt := 1; ; attempt++ {
+		// We need to make a get pull request API call to get the correct "version".
+		resp, err := b.makeRequest("GET", pullURL, nil)
+		if err != nil {
+			return err
+		}
+		pullResp = PullRequest{}
+		if err := json.Unmarshal(resp, &pullResp); err != nil {
+			return errors.Wrapf(err, "Could not parse response %q", string(resp))
+		}
+		if err := validator.New().Struct(pullResp); err != nil {
+			return errors.Wrapf(err, "API response %q was missing fields", string(resp))
+		}
+
+		var reqBody io.Reader
+		if bodyBytes != nil {
+			reqBody = bytes.NewReader(bodyBytes)
+		}
+		_, err = b.makeRequest("POST", fmt.Sprintf("%s/merge?version=%d", pullURL, *pullResp.Version), reqBody)
+		if err == nil {
+			break
+		}
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusConflict {
+			return err
+		}
+		mergeErr := parseMergeError(apiErr.Body)
+		if mergeErr.outOfDate && attempt < maxMergeAttempts {
+			continue
+		}
+		if mergeErr.reason != "" {
+			return errors.New(mergeErr.reason)
+		}
+		return err
 	}
-	if err := validator.New().Struct(pullResp); err != nil {
-		return errors.Wrapf(err, "API response %q was missing fields", string(resp))
+
+	if pullOptions.DeleteSourceBranchOnMerge {
+		if err := b.deleteSourceBranch(pullResp); err != nil {
+			return errors.Wrap(err, "pull request was merged but deleting its source branch failed")
+		}
+	}
+	return nil
+}
+
+// maxMergeAttempts is how many times MergePull tries to merge a pull request
+// that keeps being updated before giving up.
+const maxMergeAttempts = 3
+
+// mergeError is what a 409 response to a merge request means.
+type mergeError struct {
+	// outOfDate is true if the pull request was updated since its version was
+	// fetched.
+	outOfDate bool
+	// reason is why the merge can't be done, ex. the vetoes from merge checks.
+	reason string
+}
+
+// parseMergeError parses the body of a 409 response to a merge request.
+func parseMergeError(body []byte) mergeError {
+	var errResp MergeErrorResponse
+	if err := json.Unmarshal(body, &errResp); err != nil {
+		return mergeError{}
+	}
+	var mergeErr mergeError
+	var reasons []string
+	for _, e := range errResp.Errors {
+		switch {
+		case strings.HasSuffix(e.ExceptionName, ".PullRequestOutOfDateException"):
+			mergeErr.outOfDate = true
+		case len(e.Vetoes) > 0:
+			for _, v := range e.Vetoes {
+				reason := v.SummaryMessage
+				if v.DetailedMessage != "" {
+					reason = fmt.Sprintf("%s: %s", v.SummaryMessage, v.DetailedMessage)
+				}
+				reasons = append(reasons, reason)
+			}
+		case e.Message != "":
+			reasons = append(reasons, e.Message)
+		}
+	}
+	if len(reasons) > 0 {
+		mergeErr.reason = "pull request can't be merged: " + strings.Join(reasons, "; ")
 	}
-	path = fmt.Sprintf("%s/rest/api/1.0/projects/%s/repos/%s/pull-requests/%d/merge?version=%d", b.BaseURL, projectKey, pull.BaseRepo.Name, pull.Num, *pullResp.Version)
-	_, err = b.makeRequest("POST", path, nil)
+	return mergeErr
+}
+
+// deleteSourceBranch deletes the merged pull request's source branch unless
+// it's in a fork or has had commits pushed to it since the pull request was
+// fetched.
+func (b *Client) deleteSourceBranch(pullResp PullRequest) error {
+	from, to := pullResp.FromRef.Repository, pullResp.ToRef.Repository
+	if *from.Slug != *to.Slug || *from.Project.Key != *to.Project.Key {
+		return nil
+	}
+	bodyBytes, err := json.Marshal(map[string]interface{}{
+		"name":     "refs/heads/" + *pullResp.FromRef.DisplayID,
+		"endPoint": *pullResp.FromRef.LatestCommit,
+		"dryRun":   false,
+	})
+	if err != nil {
+		return errors.Wrap(err, "json encoding")
+	}
+	path := fmt.Sprintf("%s/rest/branch-utils/1.0/projects/%s/repos/%s/branches", b.BaseURL, *from.Project.Key, *from.Slug)
+	_, err = b.makeRequest("DELETE", path, bytes.NewReader(bodyBytes))
 	return err
 }
 
@@ -303,7 +514,7 @@ func (b *Client) makeRequest(method string, path string, reqBody io.Reader) ([]b
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated && resp.StatusCode != 204 {
 		respBody, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("making request %q unexpected status code: %d, body: %s", requestStr, resp.StatusCode, string(respBody))
+		return nil, &APIError{Request: requestStr, StatusCode: resp.StatusCode, Body: respBody}
 	}
 	respBody, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
@@ -312,6 +523,17 @@ func (b *Client) makeRequest(method string, path string, reqBody io.Reader) ([]b
 	return respBody, nil
 }
 
+// APIError is returned when a request gets an unexpected status code.
+type APIError struct {
+	Request    string
+	StatusCode int
+	Body       []byte
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("making request %q unexpected status code: %d, body: %s", e.Request, e.StatusCode, string(e.Body))
+}
+
 func (b *Client) SupportsSingleFileDownload(repo models.Repo) bool {
 	return false
 }
@@ -322,3 +544,35 @@ func (b *Client) SupportsSingleFileDownload(repo models.Repo) bool {
 func (b *Client) DownloadRepoConfigFile(pull models.PullRequest) (bool, []byte, error) {
 	return false, []byte{}, fmt.Errorf("not implemented")
 }
+
+func (b *Client) GetCloneURL(VCSHostType models.VCSHostType, repo string) (string, error) {
+	return "", fmt.Errorf("not implemented")
+}
+
+func (b *Client) UserIsTeamMember(repo models.Repo, user models.User, teams []string) (bool, error) {
+	return false, fmt.Errorf("not implemented")
+}
+
+func (b *Client) GetPullApprovers(repo models.Repo, pull models.PullRequest) ([]string, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (b *Client) DownloadCodeowners(pull models.PullRequest) (bool, []byte, error) {
+	return false, []byte{}, fmt.Errorf("not implemented")
+}
+
+func (b *Client) SupportsCommentUpdates(repo models.Repo) bool {
+	return false
+}
+
+func (b *Client) ReactToComment(repo models.Repo, pullNum int, commentID int64, reaction string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (b *Client) CreateUpdatableComment(repo models.Repo, pullNum int, comment string) (int64, error) {
+	return 0, fmt.Errorf("not implemented")
+}
+
+func (b *Client) UpdateComment(repo models.Repo, pullNum int, commentID int64, comment string, command string) error {
+	return fmt.Errorf("not implemented")
+}
@@ -183,6 +183,218 @@ func TestClient_MergePull(t *testing.T) {
 	Ok(t, err)
 }
 
+// Test that the merge method and commit message are sent in the body.
+func TestClient_MergePullStrategy(t *testing.T) {
+	pullRequest, err := ioutil.ReadFile(filepath.Join("testdata", "pull-request.json"))
+	Ok(t, err)
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.RequestURI {
+		case "/rest/api/1.0/projects/ow/repos/repo/pull-requests/1":
+			w.Write(pullRequest) // nolint: errcheck
+			return
+		case "/rest/api/1.0/projects/ow/repos/repo/pull-requests/1/merge?version=3":
+			body, err := ioutil.ReadAll(r.Body)
+			Ok(t, err)
+			Equals(t, `{"message":"Merge #1","strategyId":"squash"}`, string(body))
+			w.Write(pullRequest) // nolint: errcheck
+		default:
+			t.Errorf("got unexpected request at %q", r.RequestURI)
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+	}))
+	defer testServer.Close()
+
+	client, err := bitbucketserver.NewClient(http.DefaultClient, "user", "pass", testServer.URL, "runatlantis.io")
+	Ok(t, err)
+
+	err = client.MergePull(models.PullRequest{
+		Num: 1,
+		BaseRepo: models.Repo{
+			FullName:          "owner/repo",
+			Owner:             "owner",
+			Name:              "repo",
+			SanitizedCloneURL: fmt.Sprintf("%s/scm/ow/repo.git", testServer.URL),
+		},
+	}, models.PullRequestOptions{
+		MergeMethod:   models.SquashMergeMethod,
+		CommitMessage: "Merge #1",
+	})
+	Ok(t, err)
+}
+
+// Test that the merge is retried with the new version if the pull request was
+// updated since its version was fetched.
+func TestClient_MergePullOutOfDate(t *testing.T) {
+	pullRequest, err := ioutil.ReadFile(filepath.Join("testdata", "pull-request.json"))
+	Ok(t, err)
+	version := 3
+	var merges []string
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.RequestURI {
+		case "/rest/api/1.0/projects/ow/repos/repo/pull-requests/1":
+			w.Write([]byte(strings.Replace(string(pullRequest), `"version": 3`, fmt.Sprintf(`"version": %d`, version), 1))) // nolint: errcheck
+		case "/rest/api/1.0/projects/ow/repos/repo/pull-requests/1/merge?version=3":
+			merges = append(merges, r.RequestURI)
+			// A reviewer approved the pull request before it was merged.
+			version = 4
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"errors": [{"message": "You are attempting to modify a pull request based on out-of-date information.", "exceptionName": "com.atlassian.bitbucket.pull.PullRequestOutOfDateException"}]}`)) // nolint: errcheck
+		case "/rest/api/1.0/projects/ow/repos/repo/pull-requests/1/merge?version=4":
+			merges = append(merges, r.RequestURI)
+			w.Write(pullRequest) // nolint: errcheck
+		default:
+			t.Errorf("got unexpected request at %q", r.RequestURI)
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer testServer.Close()
+
+	client, err := bitbucketserver.NewClient(http.DefaultClient, "user", "pass", testServer.URL, "runatlantis.io")
+	Ok(t, err)
+	err = client.MergePull(models.PullRequest{
+		Num: 1,
+		BaseRepo: models.Repo{
+			FullName:          "owner/repo",
+			Owner:             "owner",
+			Name:              "repo",
+			SanitizedCloneURL: fmt.Sprintf("%s/scm/ow/repo.git", testServer.URL),
+		},
+	}, models.PullRequestOptions{})
+	Ok(t, err)
+	Equals(t, 2, len(merges))
+}
+
+// Test that the vetoes from merge checks, ex. required builds or approvals,
+// are in the error.
+func TestClient_MergePullVetoed(t *testing.T) {
+	vetoedResp := `{"errors": [{
+  "message": "Merging the pull request has been vetoed.",
+  "exceptionName": "com.atlassian.bitbucket.pull.PullRequestMergeVetoedException",
+  "vetoes": [
+    {"summaryMessage": "Not all required builds are successful yet", "detailedMessage": "You need 2 successful builds before this pull request can be merged."},
+    {"summaryMessage": "Not all required reviewers have approved yet", "detailedMessage": ""}
+  ]
+}]}`
+	pullRequest, err := ioutil.ReadFile(filepath.Join("testdata", "pull-request.json"))
+	Ok(t, err)
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.RequestURI {
+		case "/rest/api/1.0/projects/ow/repos/repo/pull-requests/1":
+			w.Write(pullRequest) // nolint: errcheck
+		case "/rest/api/1.0/projects/ow/repos/repo/pull-requests/1/merge?version=3":
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(vetoedResp)) // nolint: errcheck
+		default:
+			t.Errorf("got unexpected request at %q", r.RequestURI)
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer testServer.Close()
+
+	client, err := bitbucketserver.NewClient(http.DefaultClient, "user", "pass", testServer.URL, "runatlantis.io")
+	Ok(t, err)
+	err = client.MergePull(models.PullRequest{
+		Num: 1,
+		BaseRepo: models.Repo{
+			FullName:          "owner/repo",
+			Owner:             "owner",
+			Name:              "repo",
+			SanitizedCloneURL: fmt.Sprintf("%s/scm/ow/repo.git", testServer.URL),
+		},
+	}, models.PullRequestOptions{})
+	ErrEquals(t, "pull request can't be merged: Not all required builds are successful yet: You need 2 successful builds before this pull request can be merged.; Not all required reviewers have approved yet", err)
+}
+
+// Test that the source branch is deleted after merging if enabled.
+func TestClient_MergePullDeleteSourceBranch(t *testing.T) {
+	pullRequest, err := ioutil.ReadFile(filepath.Join("testdata", "pull-request.json"))
+	Ok(t, err)
+	deleted := false
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.RequestURI {
+		case "/rest/api/1.0/projects/ow/repos/repo/pull-requests/1":
+			w.Write(pullRequest) // nolint: errcheck
+		case "/rest/api/1.0/projects/ow/repos/repo/pull-requests/1/merge?version=3":
+			w.Write(pullRequest) // nolint: errcheck
+		case "/rest/branch-utils/1.0/projects/AT/repos/example/branches":
+			Equals(t, "DELETE", r.Method)
+			body, err := ioutil.ReadAll(r.Body)
+			Ok(t, err)
+			Equals(t, `{"dryRun":false,"endPoint":"bdcaa224f4b65edb853a689404ef79cf47d8cdda","name":"refs/heads/hi"}`, string(body))
+			deleted = true
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("got unexpected request at %q", r.RequestURI)
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer testServer.Close()
+
+	client, err := bitbucketserver.NewClient(http.DefaultClient, "user", "pass", testServer.URL, "runatlantis.io")
+	Ok(t, err)
+	err = client.MergePull(models.PullRequest{
+		Num: 1,
+		BaseRepo: models.Repo{
+			FullName:          "owner/repo",
+			Owner:             "owner",
+			Name:              "repo",
+			SanitizedCloneURL: fmt.Sprintf("%s/scm/ow/repo.git", testServer.URL),
+		},
+	}, models.PullRequestOptions{
+		DeleteSourceBranchOnMerge: true,
+	})
+	Ok(t, err)
+	Assert(t, deleted, "expected source branch to be deleted")
+}
+
+// Only Atlantis's comments from the command that haven't been deleted should
+// be deleted.
+func TestClient_HidePrevCommandComments(t *testing.T) {
+	activities := []string{
+		`{"values": [
+	{"action": "COMMENTED", "commentAction": "ADDED", "comment": {"id": 1, "version": 0, "text": "Ran Plan for dir: .", "author": {"name": "user"}}},
+	{"action": "COMMENTED", "commentAction": "ADDED", "comment": {"id": 2, "version": 0, "text": "Ran Plan for dir: .", "author": {"name": "someone-else"}}},
+	{"action": "APPROVED"}
+], "nextPageStart": 3, "isLastPage": false}`,
+		`{"values": [
+	{"action": "COMMENTED", "commentAction": "ADDED", "comment": {"id": 3, "version": 2, "text": "Continued plan output from previous comment.", "author": {"name": "user"}}},
+	{"action": "COMMENTED", "commentAction": "ADDED", "comment": {"id": 4, "version": 0, "text": "Ran Apply for dir: .", "author": {"name": "user"}}},
+	{"action": "COMMENTED", "commentAction": "ADDED", "comment": {"id": 5, "version": 0, "text": "Ran Plan for dir: .", "author": {"name": "user"}}},
+	{"action": "COMMENTED", "commentAction": "DELETED", "comment": {"id": 5, "version": 0, "text": "Ran Plan for dir: .", "author": {"name": "user"}}}
+], "isLastPage": true}`,
+	}
+	var deleted []string
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.RequestURI {
+		case "GET /rest/api/1.0/projects/ow/repos/repo/pull-requests/1/activities?start=0":
+			w.Write([]byte(activities[0])) // nolint: errcheck
+		case "GET /rest/api/1.0/projects/ow/repos/repo/pull-requests/1/activities?start=3":
+			w.Write([]byte(activities[1])) // nolint: errcheck
+		default:
+			if r.Method != "DELETE" {
+				t.Errorf("got unexpected request at %q", r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+				return
+			}
+			deleted = append(deleted, strings.TrimPrefix(r.RequestURI, "/rest/api/1.0/projects/ow/repos/repo/pull-requests/1/comments/"))
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer testServer.Close()
+
+	client, err := bitbucketserver.NewClient(http.DefaultClient, "user", "pass", testServer.URL, "runatlantis.io")
+	Ok(t, err)
+	repo := models.Repo{
+		FullName:          "owner/repo",
+		Owner:             "owner",
+		Name:              "repo",
+		SanitizedCloneURL: fmt.Sprintf("%s/scm/ow/repo.git", testServer.URL),
+	}
+	Ok(t, client.HidePrevCommandComments(repo, 1, "Plan"))
+	Equals(t, []string{"1?version=0", "3?version=2"}, deleted)
+}
+
 func TestClient_MarkdownPullLink(t *testing.T) {
 	client, err := bitbucketserver.NewClient(nil, "u", "p", "https://base-url", "atlantis-url")
 	Ok(t, err)
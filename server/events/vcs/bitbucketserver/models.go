@@ -58,6 +58,26 @@ type Comment struct {
 	Text *string `json:"text,omitempty" validate:"required"`
 }
 
+// Activities is a page of a pull request's activities.
+type Activities struct {
+	Values []struct {
+		Action        string           `json:"action"`
+		CommentAction string           `json:"commentAction"`
+		Comment       *ActivityComment `json:"comment,omitempty"`
+	} `json:"values,omitempty"`
+	NextPageStart *int  `json:"nextPageStart,omitempty"`
+	IsLastPage    *bool `json:"isLastPage,omitempty" validate:"required"`
+}
+
+type ActivityComment struct {
+	ID      int    `json:"id"`
+	Version int    `json:"version"`
+	Text    string `json:"text"`
+	Author  struct {
+		Name string `json:"name"`
+	} `json:"author"`
+}
+
 type Changes struct {
 	Values []struct {
 		Path struct {
@@ -75,3 +95,21 @@ type MergeStatus struct {
 	CanMerge   *bool `json:"canMerge,omitempty" validate:"required"`
 	Conflicted *bool `json:"conflicted,omitempty" validate:"required"`
 }
+
+// MergeRequest is the body of a request to merge a pull request.
+type MergeRequest struct {
+	Message    string `json:"message,omitempty"`
+	StrategyID string `json:"strategyId,omitempty"`
+}
+
+// MergeErrorResponse is the body of a 409 response to a merge request.
+type MergeErrorResponse struct {
+	Errors []struct {
+		Message       string `json:"message"`
+		ExceptionName string `json:"exceptionName"`
+		Vetoes        []struct {
+			SummaryMessage  string `json:"summaryMessage"`
+			DetailedMessage string `json:"detailedMessage"`
+		} `json:"vetoes"`
+	} `json:"errors"`
+}
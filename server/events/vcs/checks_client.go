@@ -0,0 +1,47 @@
+package vcs
+
+import (
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_checks_client.go ChecksClient
+
+// ChecksClient is used to create check runs, which are richer than commit
+// statuses since they can contain output and buttons for follow-up actions.
+// Only GitHub supports checks and only when authenticated as a GitHub App.
+type ChecksClient interface {
+	// CreateCheckRun creates a completed check run on the head commit of pull.
+	// Check runs with the same name replace each other in the UI.
+	CreateCheckRun(repo models.Repo, pull models.PullRequest, checkRun CheckRun) error
+}
+
+// CheckRun is a single check run, ex. the plan for a single project.
+type CheckRun struct {
+	// Name identifies the check run, ex. atlantis/plan: dir/default.
+	Name string
+	// ExternalID is echoed back in events for this check run so we can
+	// identify what it was for.
+	ExternalID string
+	// State is the conclusion of the check run.
+	State models.CommitStatus
+	// Title and Summary are shown at the top of the check run's output.
+	Title   string
+	Summary string
+	// Text is the markdown body of the check run's output.
+	Text string
+	// Actions are the buttons shown on the check run.
+	Actions []CheckRunAction
+}
+
+// CheckRunAction is a button on a check run that a user can click to trigger
+// a follow-up action.
+type CheckRunAction struct {
+	// Label is the text on the button. GitHub limits it to 20 characters.
+	Label string
+	// Description is a short explanation of what the action does. GitHub
+	// limits it to 40 characters.
+	Description string
+	// Identifier is echoed back in the requested_action event when the button
+	// is clicked. GitHub limits it to 20 characters.
+	Identifier string
+}
@@ -25,9 +25,17 @@ type Client interface {
 	// relative to the repo root, e.g. parent/child/file.txt.
 	GetModifiedFiles(repo models.Repo, pull models.PullRequest) ([]string, error)
 	CreateComment(repo models.Repo, pullNum int, comment string, command string) error
+	// CreateReply posts comment as a reply in the discussion with id
+	// discussionID, ex. a GitLab merge request thread, and returns the id of
+	// its first comment. Hosts without threaded discussions post it with
+	// CreateComment and return 0.
+	CreateReply(repo models.Repo, pullNum int, discussionID string, comment string, command string) (int64, error)
 	HidePrevCommandComments(repo models.Repo, pullNum int, command string) error
 	PullIsApproved(repo models.Repo, pull models.PullRequest) (bool, error)
 	PullIsMergeable(repo models.Repo, pull models.PullRequest) (bool, error)
+	// PullIsClosed returns true if the pull request has been closed or
+	// merged.
+	PullIsClosed(repo models.Repo, pull models.PullRequest) (bool, error)
 	// UpdateStatus updates the commit status to state for pull. src is the
 	// source of this status. This should be relatively static across runs,
 	// ex. atlantis/plan or atlantis/apply.
@@ -44,4 +52,40 @@ type Client interface {
 	// if BaseRepo had one repo config file, its content will placed on the second return value
 	DownloadRepoConfigFile(pull models.PullRequest) (bool, []byte, error)
 	SupportsSingleFileDownload(repo models.Repo) bool
+	// GetCloneURL returns the clone URL of the repo with full name repo,
+	// ex. runatlantis/atlantis. It's used when a command is triggered without a
+	// pull request event that would otherwise contain the URL.
+	GetCloneURL(VCSHostType models.VCSHostType, repo string) (string, error)
+	// UserIsTeamMember returns true if user is an active member of at least
+	// one of teams. Teams are GitHub team slugs in the repo's organization or
+	// GitLab group paths.
+	UserIsTeamMember(repo models.Repo, user models.User, teams []string) (bool, error)
+	// GetPullApprovers returns the usernames of the users who currently
+	// approve the pull request.
+	GetPullApprovers(repo models.Repo, pull models.PullRequest) ([]string, error)
+	// DownloadCodeowners returns the contents of the repo's CODEOWNERS file
+	// on the pull request's base branch. The first return value is false if
+	// the repo doesn't have one.
+	DownloadCodeowners(pull models.PullRequest) (bool, []byte, error)
+	CommentUpdater
+}
+
+// CommentUpdater reacts to and edits pull request comments so a command's
+// progress and results can be shown in a single comment. Only hosts for which
+// SupportsCommentUpdates returns true implement the other methods.
+type CommentUpdater interface {
+	// SupportsCommentUpdates returns true if repo's VCS host supports
+	// reacting to and editing comments.
+	SupportsCommentUpdates(repo models.Repo) bool
+	// ReactToComment adds reaction, ex. eyes, to the comment with id
+	// commentID on pull request pullNum.
+	ReactToComment(repo models.Repo, pullNum int, commentID int64, reaction string) error
+	// CreateUpdatableComment creates a comment like CreateComment and returns
+	// its id so it can be edited with UpdateComment. comment must fit in a
+	// single comment.
+	CreateUpdatableComment(repo models.Repo, pullNum int, comment string) (int64, error)
+	// UpdateComment replaces the body of the comment with id commentID. If
+	// comment is too long for one comment, the rest of it is posted in new
+	// comments like CreateComment does.
+	UpdateComment(repo models.Repo, pullNum int, commentID int64, comment string, command string) error
 }
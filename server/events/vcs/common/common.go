@@ -4,6 +4,8 @@ package common
 
 import (
 	"math"
+	"strings"
+	"unicode/utf8"
 )
 
 // AutomergeCommitMsg is the commit message Atlantis will use when automatically
@@ -35,6 +37,29 @@ func SplitComment(comment string, maxSize int, sepEnd string, sepStart string) [
 	return comments
 }
 
+// TruncateComment truncates comment so that it's under maxSize once sepEnd is
+// appended. If comment is already under maxSize it's returned unchanged. It's
+// never truncated in the middle of a multi-byte character.
+func TruncateComment(comment string, maxSize int, sepEnd string) string {
+	if len(comment) <= maxSize {
+		return comment
+	}
+	end := maxSize - len(sepEnd)
+	for end > 0 && !utf8.RuneStart(comment[end]) {
+		end--
+	}
+	return comment[:end] + sepEnd
+}
+
+// IsCommandComment returns true if comment looks like it's the output of
+// command. This is crude filtering: the comment templates typically include
+// the command name somewhere in the first line so callers should also check
+// that the comment was made by the Atlantis user.
+func IsCommandComment(comment string, command string) bool {
+	firstLine := strings.SplitN(comment, "\n", 2)[0]
+	return strings.Contains(strings.ToLower(firstLine), strings.ToLower(command))
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
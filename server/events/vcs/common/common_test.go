@@ -61,3 +61,28 @@ func TestSplitComment_FourComments(t *testing.T) {
 		sepStart + comment[expMax*2:expMax*3] + sepEnd,
 		sepStart + comment[expMax*3:]}, split)
 }
+
+func TestIsCommandComment(t *testing.T) {
+	Equals(t, true, common.IsCommandComment("Ran Plan for dir: `.`\nplan output", "plan"))
+	Equals(t, true, common.IsCommandComment("Continued Plan from previous comment.", "plan"))
+	Equals(t, false, common.IsCommandComment("Ran Apply for dir: `.`\nplan output", "plan"))
+	Equals(t, false, common.IsCommandComment("", "plan"))
+}
+
+func TestTruncateComment_UnderMax(t *testing.T) {
+	comment := "comment under max size"
+	Equals(t, comment, common.TruncateComment(comment, len(comment), "sepEnd"))
+}
+
+func TestTruncateComment_OverMax(t *testing.T) {
+	comment := strings.Repeat("a", 100)
+	truncated := common.TruncateComment(comment, 50, "sepEnd")
+	Equals(t, 50, len(truncated))
+	Equals(t, strings.Repeat("a", 44)+"sepEnd", truncated)
+}
+
+func TestTruncateComment_MultiByte(t *testing.T) {
+	comment := strings.Repeat("é", 50)
+	truncated := common.TruncateComment(comment, 51, "sepEnd")
+	Equals(t, strings.Repeat("é", 22)+"sepEnd", truncated)
+}
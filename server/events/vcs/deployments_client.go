@@ -0,0 +1,19 @@
+package vcs
+
+import (
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_deployments_client.go DeploymentsClient
+
+// DeploymentsClient is used to record applies as deployments so that the
+// repo's environments show what was applied where. Only GitHub supports
+// deployments.
+type DeploymentsClient interface {
+	// CreateDeployment creates a deployment of the head commit of pull to
+	// environment and returns its id.
+	CreateDeployment(repo models.Repo, pull models.PullRequest, environment string, description string) (int64, error)
+	// UpdateDeploymentStatus sets the state of the deployment with id
+	// deploymentID. A pending state means the deployment is in progress.
+	UpdateDeploymentStatus(repo models.Repo, deploymentID int64, state models.CommitStatus, logURL string, description string) error
+}
@@ -525,7 +525,7 @@ func GithubAppTestServer(t *testing.T) (string, error) {
 	counter := 0
 	testServer := httptest.NewTLSServer(
 		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			switch r.RequestURI {
+			switch r.URL.Path {
 			case "/api/v3/app-manifests/good-code/conversions":
 				encodedKey := strings.Join(strings.Split(GithubPrivateKey, "\n"), "\\n")
 				appInfo := fmt.Sprintf(githubConversionJSON, encodedKey)
@@ -542,12 +542,10 @@ func GithubAppTestServer(t *testing.T) (string, error) {
 				w.Write([]byte(githubAppInstallationJSON)) // nolint: errcheck
 				return
 			case "/api/v3/apps/some-app":
-				token := strings.Replace(r.Header.Get("Authorization"), "token ", "", 1)
-
-				// token is taken from githubAppTokenJSON
-				if token != "some-token" {
+				token := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", 1)
+				if err := validateGithubToken(token); err != nil {
 					w.WriteHeader(403)
-					w.Write([]byte("Invalid installation token")) // nolint: errcheck
+					w.Write([]byte("Invalid token")) // nolint: errcheck
 					return
 				}
 				w.Write([]byte(githubAppJSON)) // nolint: errcheck
@@ -575,3 +573,55 @@ func GithubAppTestServer(t *testing.T) (string, error) {
 
 	return testServerURL.Host, err
 }
+
+// GithubAppMultipleInstallationsTestServer is like GithubAppTestServer except
+// the app is installed on two owners, org1 and org2. The token for each
+// installation is token-<installation id> and it's only accepted for
+// GET /repos/<owner>/repo/pulls/1 of the owner it was issued for.
+func GithubAppMultipleInstallationsTestServer(t *testing.T) (string, error) {
+	installationTokens := map[string]string{
+		"org1": "token-1",
+		"org2": "token-2",
+	}
+	testServer := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api/v3/app/installations":
+				token := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", 1)
+				if err := validateGithubToken(token); err != nil {
+					w.WriteHeader(403)
+					w.Write([]byte("Invalid token")) // nolint: errcheck
+					return
+				}
+				w.Write([]byte(`[{"id": 1, "account": {"login": "org1"}}, {"id": 2, "account": {"login": "Org2"}}]`)) // nolint: errcheck
+				return
+			case "/api/v3/app/installations/1/access_tokens", "/api/v3/app/installations/2/access_tokens":
+				token := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", 1)
+				if err := validateGithubToken(token); err != nil {
+					w.WriteHeader(403)
+					w.Write([]byte("Invalid token")) // nolint: errcheck
+					return
+				}
+				id := strings.Split(r.URL.Path, "/")[5]
+				fmt.Fprintf(w, `{"token": "token-%s", "expires_at": "2050-01-01T00:00:00Z"}`, id)
+				return
+			case "/api/v3/repos/org1/repo/pulls/1", "/api/v3/repos/org2/repo/pulls/1":
+				owner := strings.Split(r.URL.Path, "/")[4]
+				if r.Header.Get("Authorization") != "token "+installationTokens[owner] {
+					w.WriteHeader(403)
+					w.Write([]byte("Invalid installation token")) // nolint: errcheck
+					return
+				}
+				w.Write([]byte(`{"number": 1}`)) // nolint: errcheck
+				return
+			default:
+				t.Errorf("got unexpected request at %q", r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+				return
+			}
+		}))
+
+	testServerURL, err := url.Parse(testServer.URL)
+
+	return testServerURL.Host, err
+}
@@ -18,8 +18,10 @@ import (
 	"encoding/base64"
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/Laisky/graphql"
 	"github.com/google/go-github/v31/github"
@@ -42,6 +44,16 @@ type GithubClient struct {
 	v4MutateClient *graphql.Client
 	ctx            context.Context
 	logger         logging.SimpleLogging
+	// UploadLargeComments causes comments longer than maxCommentLength to
+	// be uploaded as a secret gist and linked from a truncated comment instead
+	// of being split into multiple comments.
+	UploadLargeComments bool
+	// MergeableIgnoreContexts are the status contexts, usually Atlantis's
+	// own, that don't stop a pull request blocked by branch protection from
+	// being mergeable. A context ignores the per-project statuses that start
+	// with it followed by a colon too, ex. atlantis/apply ignores
+	// atlantis/apply: project1.
+	MergeableIgnoreContexts []string
 }
 
 // GithubAppTemporarySecrets holds app credentials obtained from github after creation.
@@ -58,12 +70,14 @@ type GithubAppTemporarySecrets struct {
 	URL string
 }
 
-// NewGithubClient returns a valid GitHub client.
-func NewGithubClient(hostname string, credentials GithubCredentials, logger logging.SimpleLogging) (*GithubClient, error) {
+// NewGithubClient returns a valid GitHub client. If retrier is set, its
+// requests are retried when they're rate limited or fail.
+func NewGithubClient(hostname string, credentials GithubCredentials, logger logging.SimpleLogging, retrier *APIRetrier) (*GithubClient, error) {
 	transport, err := credentials.Client()
 	if err != nil {
 		return nil, errors.Wrap(err, "error initializing github authentication transport")
 	}
+	transport.Transport = retrier.Transport(transport.Transport)
 
 	var graphqlURL string
 	var client *github.Client
@@ -143,8 +157,31 @@ func (g *GithubClient) GetModifiedFiles(repo models.Repo, pull models.PullReques
 
 // CreateComment creates a comment on the pull request.
 // If comment length is greater than the max comment length we split into
-// multiple comments.
+// multiple comments, or, if UploadLargeComments is set, upload the full
+// comment as a gist and link to it.
 func (g *GithubClient) CreateComment(repo models.Repo, pullNum int, comment string, command string) error {
+	for _, c := range g.splitComment(repo, pullNum, comment, command) {
+		if err := g.postComment(repo, pullNum, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitComment returns the comments comment needs to be posted as. If it's
+// too long for one comment it's either truncated with a link to the full
+// output in a gist or split into multiple comments.
+func (g *GithubClient) splitComment(repo models.Repo, pullNum int, comment string, command string) []string {
+	if len(comment) > maxCommentLength && g.UploadLargeComments {
+		gistURL, err := g.uploadGist(repo, pullNum, comment, command)
+		if err == nil {
+			sepEnd := "\n```\n</details>" +
+				fmt.Sprintf("\n<br>\n\n**Warning**: Output length greater than max comment size. See the full output [here](%s).", gistURL)
+			return []string{common.TruncateComment(comment, maxCommentLength, sepEnd)}
+		}
+		g.logger.Warn("unable to upload comment as a gist, splitting it into multiple comments instead: %s", err)
+	}
+
 	var sepStart string
 
 	sepEnd := "\n```\n</details>" +
@@ -158,17 +195,82 @@ func (g *GithubClient) CreateComment(repo models.Repo, pullNum int, comment stri
 			"```diff\n"
 	}
 
-	comments := common.SplitComment(comment, maxCommentLength, sepEnd, sepStart)
-	for i := range comments {
-		g.logger.Debug("POST /repos/%v/%v/issues/%d/comments", repo.Owner, repo.Name, pullNum)
-		_, _, err := g.client.Issues.CreateComment(g.ctx, repo.Owner, repo.Name, pullNum, &github.IssueComment{Body: &comments[i]})
-		if err != nil {
+	return common.SplitComment(comment, maxCommentLength, sepEnd, sepStart)
+}
+
+// SupportsCommentUpdates returns true since GitHub supports reacting to and
+// editing comments.
+func (g *GithubClient) SupportsCommentUpdates(repo models.Repo) bool {
+	return true
+}
+
+// ReactToComment adds reaction, ex. eyes, to the comment with id commentID.
+func (g *GithubClient) ReactToComment(repo models.Repo, pullNum int, commentID int64, reaction string) error {
+	g.logger.Debug("POST /repos/%v/%v/issues/comments/%d/reactions", repo.Owner, repo.Name, commentID)
+	_, _, err := g.client.Reactions.CreateIssueCommentReaction(g.ctx, repo.Owner, repo.Name, commentID, reaction)
+	return err
+}
+
+// CreateUpdatableComment posts comment and returns its id.
+func (g *GithubClient) CreateUpdatableComment(repo models.Repo, pullNum int, comment string) (int64, error) {
+	g.logger.Debug("POST /repos/%v/%v/issues/%d/comments", repo.Owner, repo.Name, pullNum)
+	c, _, err := g.client.Issues.CreateComment(g.ctx, repo.Owner, repo.Name, pullNum, &github.IssueComment{Body: &comment})
+	if err != nil {
+		return 0, err
+	}
+	return c.GetID(), nil
+}
+
+// UpdateComment replaces the body of the comment with id commentID. Output
+// that doesn't fit is posted in new comments.
+func (g *GithubClient) UpdateComment(repo models.Repo, pullNum int, commentID int64, comment string, command string) error {
+	comments := g.splitComment(repo, pullNum, comment, command)
+	g.logger.Debug("PATCH /repos/%v/%v/issues/comments/%d", repo.Owner, repo.Name, commentID)
+	if _, _, err := g.client.Issues.EditComment(g.ctx, repo.Owner, repo.Name, commentID, &github.IssueComment{Body: &comments[0]}); err != nil {
+		return err
+	}
+	for _, c := range comments[1:] {
+		if err := g.postComment(repo, pullNum, c); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// postComment actually posts the comment. It's a helper for CreateComment().
+func (g *GithubClient) postComment(repo models.Repo, pullNum int, comment string) error {
+	g.logger.Debug("POST /repos/%v/%v/issues/%d/comments", repo.Owner, repo.Name, pullNum)
+	_, _, err := g.client.Issues.CreateComment(g.ctx, repo.Owner, repo.Name, pullNum, &github.IssueComment{Body: &comment})
+	return err
+}
+
+// uploadGist uploads comment as a secret gist and returns its URL.
+// Gists can only be created with a user's token, not by GitHub apps.
+func (g *GithubClient) uploadGist(repo models.Repo, pullNum int, comment string, command string) (string, error) {
+	description := fmt.Sprintf("Atlantis output for %s#%d", repo.FullName, pullNum)
+	if command != "" {
+		description = fmt.Sprintf("Atlantis %s output for %s#%d", command, repo.FullName, pullNum)
+	}
+	g.logger.Debug("POST /gists")
+	gist, _, err := g.client.Gists.Create(g.ctx, &github.Gist{
+		Description: github.String(description),
+		Public:      github.Bool(false),
+		Files: map[github.GistFilename]github.GistFile{
+			"atlantis-output.md": {Content: github.String(comment)},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	return gist.GetHTMLURL(), nil
+}
+
+// CreateReply creates the comment with CreateComment since only GitLab
+// discussions are replied to.
+func (g *GithubClient) CreateReply(repo models.Repo, pullNum int, discussionID string, comment string, command string) (int64, error) {
+	return 0, g.CreateComment(repo, pullNum, comment, command)
+}
+
 func (g *GithubClient) HidePrevCommandComments(repo models.Repo, pullNum int, command string) error {
 	var allComments []*github.IssueComment
 	nextPage := 0
@@ -196,16 +298,7 @@ func (g *GithubClient) HidePrevCommandComments(repo models.Repo, pullNum int, co
 		if comment.User != nil && !strings.EqualFold(comment.User.GetLogin(), g.user) {
 			continue
 		}
-		// Crude filtering: The comment templates typically include the command name
-		// somewhere in the first line. It's a bit of an assumption, but seems like
-		// a reasonable one, given we've already filtered the comments by the
-		// configured Atlantis user.
-		body := strings.Split(comment.GetBody(), "\n")
-		if len(body) == 0 {
-			continue
-		}
-		firstLine := strings.ToLower(body[0])
-		if !strings.Contains(firstLine, strings.ToLower(command)) {
+		if !common.IsCommandComment(comment.GetBody(), command) {
 			continue
 		}
 		var m struct {
@@ -223,7 +316,9 @@ func (g *GithubClient) HidePrevCommandComments(repo models.Repo, pullNum int, co
 				SubjectID:  comment.GetNodeID(),
 			},
 		}
-		if err := g.v4MutateClient.Mutate(g.ctx, &m, input); err != nil {
+		// GraphQL requests don't include the repo in their URL so we add the
+		// owner to the context for GitHub apps installed on multiple owners.
+		if err := g.v4MutateClient.Mutate(withGithubOwner(g.ctx, repo.Owner), &m, input); err != nil {
 			return errors.Wrapf(err, "minimize comment %s", comment.GetNodeID())
 		}
 	}
@@ -275,12 +370,198 @@ func (g *GithubClient) PullIsMergeable(repo models.Repo, pull models.PullRequest
 	// has_hooks: GitHub Enterprise only, if a repo has custom pre-receive
 	//            hooks. Merging is allowed (green box).
 	// See: https://github.com/octokit/octokit.net/issues/1763
+	// blocked: Branch protection isn't satisfied. If that's only because of
+	//          ignored statuses then Atlantis can't be what's stopping them,
+	//          ex. atlantis/apply is pending until the pull request is applied.
+	if state == "blocked" && len(g.MergeableIgnoreContexts) > 0 {
+		return g.isMergeableIgnoringContexts(repo, githubPR)
+	}
 	if state != "clean" && state != "unstable" && state != "has_hooks" {
 		return false, nil
 	}
 	return true, nil
 }
 
+// isMergeableIgnoringContexts returns true if the blocked pull request has the
+// approvals its base branch's protection requires and every required status
+// check that isn't in MergeableIgnoreContexts has passed.
+func (g *GithubClient) isMergeableIgnoringContexts(repo models.Repo, pull *github.PullRequest) (bool, error) {
+	branch := pull.GetBase().GetRef()
+	g.logger.Debug("GET /repos/%v/%v/branches/%s/protection", repo.Owner, repo.Name, branch)
+	protection, _, err := g.client.Repositories.GetBranchProtection(g.ctx, repo.Owner, repo.Name, branch)
+	if err != nil {
+		return false, errors.Wrap(err, "getting branch protection")
+	}
+	if reviews := protection.RequiredPullRequestReviews; reviews != nil && reviews.RequiredApprovingReviewCount > 0 {
+		approvals, err := g.countApprovals(repo, pull.GetNumber())
+		if err != nil {
+			return false, err
+		}
+		if approvals < reviews.RequiredApprovingReviewCount {
+			return false, nil
+		}
+	}
+	if protection.RequiredStatusChecks == nil {
+		return false, nil
+	}
+
+	passed, err := g.passedContexts(repo, pull.GetHead().GetSHA())
+	if err != nil {
+		return false, err
+	}
+	for _, required := range protection.RequiredStatusChecks.Contexts {
+		if !g.isIgnoredContext(required) && !passed[required] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// RequireStatusChecks makes contexts required status checks of repo's branch.
+// If branch isn't protected, it's protected with only those checks required.
+// If it already requires status checks, contexts are added to them. Branches
+// that are protected without requiring status checks are left alone since
+// their protection would have to be replaced.
+func (g *GithubClient) RequireStatusChecks(repo models.Repo, branch string, contexts []string) error {
+	g.logger.Debug("GET /repos/%v/%v/branches/%s/protection", repo.Owner, repo.Name, branch)
+	protection, resp, err := g.client.Repositories.GetBranchProtection(g.ctx, repo.Owner, repo.Name, branch)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		g.logger.Debug("PUT /repos/%v/%v/branches/%s/protection", repo.Owner, repo.Name, branch)
+		_, _, err = g.client.Repositories.UpdateBranchProtection(g.ctx, repo.Owner, repo.Name, branch, &github.ProtectionRequest{
+			RequiredStatusChecks: &github.RequiredStatusChecks{Contexts: contexts},
+		})
+		return errors.Wrap(err, "protecting branch")
+	}
+	if err != nil {
+		return errors.Wrap(err, "getting branch protection")
+	}
+	if protection.RequiredStatusChecks == nil {
+		return fmt.Errorf("branch %s is protected without required status checks so they weren't added", branch)
+	}
+
+	required := protection.RequiredStatusChecks.Contexts
+	isRequired := make(map[string]bool)
+	for _, c := range required {
+		isRequired[c] = true
+	}
+	added := false
+	for _, c := range contexts {
+		if !isRequired[c] {
+			required = append(required, c)
+			isRequired[c] = true
+			added = true
+		}
+	}
+	if !added {
+		return nil
+	}
+	g.logger.Debug("PATCH /repos/%v/%v/branches/%s/protection/required_status_checks", repo.Owner, repo.Name, branch)
+	_, _, err = g.client.Repositories.UpdateRequiredStatusChecks(g.ctx, repo.Owner, repo.Name, branch, &github.RequiredStatusChecksRequest{
+		Contexts: required,
+	})
+	return errors.Wrap(err, "updating required status checks")
+}
+
+// countApprovals returns how many users' latest review of the pull request
+// approves it.
+func (g *GithubClient) countApprovals(repo models.Repo, pullNum int) (int, error) {
+	latest := make(map[string]string)
+	opts := github.ListOptions{PerPage: 100}
+	for {
+		g.logger.Debug("GET /repos/%v/%v/pulls/%d/reviews", repo.Owner, repo.Name, pullNum)
+		reviews, resp, err := g.client.PullRequests.ListReviews(g.ctx, repo.Owner, repo.Name, pullNum, &opts)
+		if err != nil {
+			return 0, errors.Wrap(err, "getting reviews")
+		}
+		// Reviews are listed oldest first and comments don't change whether
+		// a user approves.
+		for _, review := range reviews {
+			if review.GetState() != "COMMENTED" {
+				latest[review.GetUser().GetLogin()] = review.GetState()
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	approvals := 0
+	for _, state := range latest {
+		if state == "APPROVED" {
+			approvals++
+		}
+	}
+	return approvals, nil
+}
+
+// passedContexts returns the statuses and check runs of ref that passed.
+func (g *GithubClient) passedContexts(repo models.Repo, ref string) (map[string]bool, error) {
+	passed := make(map[string]bool)
+	statusOpts := github.ListOptions{PerPage: 100}
+	for {
+		g.logger.Debug("GET /repos/%v/%v/commits/%s/status", repo.Owner, repo.Name, ref)
+		combined, resp, err := g.client.Repositories.GetCombinedStatus(g.ctx, repo.Owner, repo.Name, ref, &statusOpts)
+		if err != nil {
+			return nil, errors.Wrap(err, "getting commit statuses")
+		}
+		for _, status := range combined.Statuses {
+			passed[status.GetContext()] = status.GetState() == "success"
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		statusOpts.Page = resp.NextPage
+	}
+
+	checkOpts := github.ListCheckRunsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		g.logger.Debug("GET /repos/%v/%v/commits/%s/check-runs", repo.Owner, repo.Name, ref)
+		checks, resp, err := g.client.Checks.ListCheckRunsForRef(g.ctx, repo.Owner, repo.Name, ref, &checkOpts)
+		if err != nil {
+			return nil, errors.Wrap(err, "getting check runs")
+		}
+		for _, run := range checks.CheckRuns {
+			switch run.GetConclusion() {
+			case "success", "neutral", "skipped":
+				passed[run.GetName()] = true
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		checkOpts.Page = resp.NextPage
+	}
+	return passed, nil
+}
+
+// isIgnoredContext returns true if statusContext is in MergeableIgnoreContexts
+// or is a per-project status of one of them.
+func (g *GithubClient) isIgnoredContext(statusContext string) bool {
+	for _, ignored := range g.MergeableIgnoreContexts {
+		if statusContext == ignored || strings.HasPrefix(statusContext, ignored+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// PullIsClosed returns true if the pull request is closed or merged.
+func (g *GithubClient) PullIsClosed(repo models.Repo, pull models.PullRequest) (bool, error) {
+	githubPR, err := g.GetPullRequest(repo, pull.Num)
+	if err != nil {
+		return false, errors.Wrap(err, "getting pull request")
+	}
+	return githubPR.GetState() == "closed", nil
+}
+
+// Ping implements Pinger.Ping. It gets the rate limits because that works
+// with every type of credential and doesn't count against them.
+func (g *GithubClient) Ping() error {
+	g.logger.Debug("GET /rate_limit")
+	_, _, err := g.client.RateLimits(g.ctx)
+	return errors.Wrap(err, "getting rate limits")
+}
+
 // GetPullRequest returns the pull request.
 func (g *GithubClient) GetPullRequest(repo models.Repo, num int) (*github.PullRequest, error) {
 	var err error
@@ -328,26 +609,154 @@ func (g *GithubClient) UpdateStatus(repo models.Repo, pull models.PullRequest, s
 	return err
 }
 
-// MergePull merges the pull request.
-func (g *GithubClient) MergePull(pull models.PullRequest, pullOptions models.PullRequestOptions) error {
-	// Users can set their repo to disallow certain types of merging.
-	// We detect which types aren't allowed and use the type that is.
-	g.logger.Debug("GET /repos/%v/%v", pull.BaseRepo.Owner, pull.BaseRepo.Name)
-	repo, _, err := g.client.Repositories.Get(g.ctx, pull.BaseRepo.Owner, pull.BaseRepo.Name)
+// maxCheckRunTextLen is the maximum length of a check run's output text
+// accepted by GitHub.
+const maxCheckRunTextLen = 65535
+
+// CreateCheckRun creates a completed check run on the head commit of pull.
+// See https://docs.github.com/en/rest/reference/checks#create-a-check-run.
+func (g *GithubClient) CreateCheckRun(repo models.Repo, pull models.PullRequest, checkRun CheckRun) error {
+	conclusion := "neutral"
+	switch checkRun.State {
+	case models.SuccessCommitStatus:
+		conclusion = "success"
+	case models.FailedCommitStatus:
+		conclusion = "failure"
+	}
+
+	text := checkRun.Text
+	if len(text) > maxCheckRunTextLen {
+		truncatedMsg := "\n\n...output truncated"
+		end := maxCheckRunTextLen - len(truncatedMsg)
+		// Don't cut a multi-byte character in half.
+		for end > 0 && !utf8.RuneStart(text[end]) {
+			end--
+		}
+		text = text[:end] + truncatedMsg
+	}
+
+	var actions []*github.CheckRunAction
+	for _, action := range checkRun.Actions {
+		actions = append(actions, &github.CheckRunAction{
+			Label:       action.Label,
+			Description: action.Description,
+			Identifier:  action.Identifier,
+		})
+	}
+
+	opts := github.CreateCheckRunOptions{
+		Name:        checkRun.Name,
+		HeadSHA:     pull.HeadCommit,
+		ExternalID:  github.String(checkRun.ExternalID),
+		Status:      github.String("completed"),
+		Conclusion:  github.String(conclusion),
+		CompletedAt: &github.Timestamp{Time: time.Now()},
+		Output: &github.CheckRunOutput{
+			Title:   github.String(checkRun.Title),
+			Summary: github.String(checkRun.Summary),
+			Text:    github.String(text),
+		},
+		Actions: actions,
+	}
+	g.logger.Debug("POST /repos/%v/%v/check-runs", repo.Owner, repo.Name)
+	_, _, err := g.client.Checks.CreateCheckRun(g.ctx, repo.Owner, repo.Name, opts)
+	return err
+}
+
+// CreateDeployment creates a deployment of the head commit of pull to
+// environment.
+// See https://docs.github.com/en/rest/reference/repos#create-a-deployment.
+func (g *GithubClient) CreateDeployment(repo models.Repo, pull models.PullRequest, environment string, description string) (int64, error) {
+	req := &github.DeploymentRequest{
+		Ref:         github.String(pull.HeadCommit),
+		Environment: github.String(environment),
+		Description: github.String(description),
+		// The apply has already happened by the time the deployment is
+		// created so don't let GitHub merge the default branch into the
+		// pull request or wait for commit statuses, ex. atlantis/apply
+		// which will be pending.
+		AutoMerge:        github.Bool(false),
+		RequiredContexts: &[]string{},
+	}
+	g.logger.Debug("POST /repos/%v/%v/deployments", repo.Owner, repo.Name)
+	deployment, _, err := g.client.Repositories.CreateDeployment(g.ctx, repo.Owner, repo.Name, req)
 	if err != nil {
-		return errors.Wrap(err, "fetching repo info")
+		return 0, err
 	}
-	const (
-		defaultMergeMethod = "merge"
-		rebaseMergeMethod  = "rebase"
-		squashMergeMethod  = "squash"
-	)
-	method := defaultMergeMethod
-	if !repo.GetAllowMergeCommit() {
-		if repo.GetAllowRebaseMerge() {
-			method = rebaseMergeMethod
-		} else if repo.GetAllowSquashMerge() {
-			method = squashMergeMethod
+	return deployment.GetID(), nil
+}
+
+// UpdateDeploymentStatus creates a new status for the deployment with id
+// deploymentID.
+// See https://docs.github.com/en/rest/reference/repos#create-a-deployment-status.
+func (g *GithubClient) UpdateDeploymentStatus(repo models.Repo, deploymentID int64, state models.CommitStatus, logURL string, description string) error {
+	ghState := "error"
+	switch state {
+	case models.PendingCommitStatus:
+		ghState = "in_progress"
+	case models.SuccessCommitStatus:
+		ghState = "success"
+	case models.FailedCommitStatus:
+		ghState = "failure"
+	}
+	req := &github.DeploymentStatusRequest{
+		State:       github.String(ghState),
+		Description: github.String(description),
+	}
+	if logURL != "" {
+		req.LogURL = github.String(logURL)
+	}
+	g.logger.Debug("POST /repos/%v/%v/deployments/%d/statuses", repo.Owner, repo.Name, deploymentID)
+	_, _, err := g.client.Repositories.CreateDeploymentStatus(g.ctx, repo.Owner, repo.Name, deploymentID, req)
+	return err
+}
+
+// CreateWebhook creates a webhook on repo that sends the events Atlantis
+// uses to url, signed with secret. It returns the webhook's ID.
+// See https://docs.github.com/en/rest/reference/repos#create-a-repository-webhook.
+func (g *GithubClient) CreateWebhook(repo models.Repo, url string, secret string) (int64, error) {
+	hook := &github.Hook{
+		Events: []string{"issue_comment", "pull_request", "pull_request_review", "push"},
+		Config: map[string]interface{}{
+			"url":          url,
+			"content_type": "json",
+			"secret":       secret,
+		},
+		Active: github.Bool(true),
+	}
+	g.logger.Debug("POST /repos/%v/%v/hooks", repo.Owner, repo.Name)
+	hook, _, err := g.client.Repositories.CreateHook(g.ctx, repo.Owner, repo.Name, hook)
+	if err != nil {
+		return 0, errors.Wrap(err, "creating webhook")
+	}
+	return hook.GetID(), nil
+}
+
+// DeleteWebhook deletes the webhook with id from repo.
+func (g *GithubClient) DeleteWebhook(repo models.Repo, id int64) error {
+	g.logger.Debug("DELETE /repos/%v/%v/hooks/%d", repo.Owner, repo.Name, id)
+	_, err := g.client.Repositories.DeleteHook(g.ctx, repo.Owner, repo.Name, id)
+	return errors.Wrap(err, "deleting webhook")
+}
+
+// MergePull merges the pull request.
+func (g *GithubClient) MergePull(pull models.PullRequest, pullOptions models.PullRequestOptions) error {
+	method := pullOptions.MergeMethod
+	if method == "" {
+		// Users can set their repo to disallow certain types of merging.
+		// We detect which types aren't allowed and use the type that is.
+		g.logger.Debug("GET /repos/%v/%v", pull.BaseRepo.Owner, pull.BaseRepo.Name)
+		repo, _, err := g.client.Repositories.Get(g.ctx, pull.BaseRepo.Owner, pull.BaseRepo.Name)
+		if err != nil {
+			return errors.Wrap(err, "fetching repo info")
+		}
+		method = models.MergeCommitMergeMethod
+		if !repo.GetAllowMergeCommit() {
+			if repo.GetAllowRebaseMerge() {
+				method = models.RebaseMergeMethod
+			} else if repo.GetAllowSquashMerge() {
+				method = models.SquashMergeMethod
+			}
 		}
 	}
 
@@ -355,15 +764,15 @@ func (g *GithubClient) MergePull(pull models.PullRequest, pullOptions models.Pul
 	options := &github.PullRequestOptions{
 		MergeMethod: method,
 	}
-	g.logger.Debug("PUT /repos/%v/%v/pulls/%d/merge", repo.Owner, repo.Name, pull.Num)
+	g.logger.Debug("PUT /repos/%v/%v/pulls/%d/merge", pull.BaseRepo.Owner, pull.BaseRepo.Name, pull.Num)
 	mergeResult, _, err := g.client.PullRequests.Merge(
 		g.ctx,
 		pull.BaseRepo.Owner,
 		pull.BaseRepo.Name,
 		pull.Num,
-		// NOTE: Using the empty string here causes GitHub to autogenerate
-		// the commit message as it normally would.
-		"",
+		// NOTE: If the commit message is empty GitHub autogenerates it as it
+		// normally would.
+		pullOptions.CommitMessage,
 		options)
 	if err != nil {
 		return errors.Wrap(err, "merging pull request")
@@ -419,3 +828,96 @@ func (g *GithubClient) DownloadRepoConfigFile(pull models.PullRequest) (bool, []
 func (g *GithubClient) SupportsSingleFileDownload(repo models.Repo) bool {
 	return true
 }
+
+// GetCloneURL returns the HTTPS clone URL of the repo.
+func (g *GithubClient) GetCloneURL(VCSHostType models.VCSHostType, repo string) (string, error) {
+	owner, name := models.SplitRepoFullName(repo)
+	repository, _, err := g.client.Repositories.Get(g.ctx, owner, name)
+	if err != nil {
+		return "", err
+	}
+	return repository.GetCloneURL(), nil
+}
+
+// githubCodeownersPaths are where GitHub looks for the CODEOWNERS file, in
+// order.
+var githubCodeownersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// GetPullApprovers returns the users whose latest review of the pull request
+// is an approval. Reviews that only comment don't change a user's approval.
+func (g *GithubClient) GetPullApprovers(repo models.Repo, pull models.PullRequest) ([]string, error) {
+	// Reviews are listed oldest first so later states overwrite earlier ones.
+	latestStates := make(map[string]string)
+	nextPage := 0
+	for {
+		opts := github.ListOptions{
+			PerPage: 300,
+		}
+		if nextPage != 0 {
+			opts.Page = nextPage
+		}
+		g.logger.Debug("GET /repos/%v/%v/pulls/%d/reviews", repo.Owner, repo.Name, pull.Num)
+		pageReviews, resp, err := g.client.PullRequests.ListReviews(g.ctx, repo.Owner, repo.Name, pull.Num, &opts)
+		if err != nil {
+			return nil, errors.Wrap(err, "getting reviews")
+		}
+		for _, review := range pageReviews {
+			if review == nil || review.GetState() == "COMMENTED" {
+				continue
+			}
+			latestStates[review.GetUser().GetLogin()] = review.GetState()
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		nextPage = resp.NextPage
+	}
+
+	var approvers []string
+	for user, state := range latestStates {
+		if state == "APPROVED" {
+			approvers = append(approvers, user)
+		}
+	}
+	sort.Strings(approvers)
+	return approvers, nil
+}
+
+// DownloadCodeowners returns the contents of the first CODEOWNERS file found
+// on the pull request's base branch.
+func (g *GithubClient) DownloadCodeowners(pull models.PullRequest) (bool, []byte, error) {
+	opt := github.RepositoryContentGetOptions{Ref: pull.BaseBranch}
+	for _, path := range githubCodeownersPaths {
+		fileContent, _, resp, err := g.client.Repositories.GetContents(g.ctx, pull.BaseRepo.Owner, pull.BaseRepo.Name, path, &opt)
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			continue
+		}
+		if err != nil {
+			return true, []byte{}, errors.Wrapf(err, "getting %s", path)
+		}
+		content, err := fileContent.GetContent()
+		if err != nil {
+			return true, []byte{}, errors.Wrapf(err, "decoding %s", path)
+		}
+		return true, []byte(content), nil
+	}
+	return false, []byte{}, nil
+}
+
+// UserIsTeamMember returns true if user is an active member of any of the
+// teams, given as slugs, in the organization that owns repo.
+func (g *GithubClient) UserIsTeamMember(repo models.Repo, user models.User, teams []string) (bool, error) {
+	for _, team := range teams {
+		membership, resp, err := g.client.Teams.GetTeamMembershipBySlug(g.ctx, repo.Owner, team, user.Username)
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			continue
+		}
+		if err != nil {
+			return false, errors.Wrapf(err, "getting membership of team %q", team)
+		}
+		if membership.GetState() == "active" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
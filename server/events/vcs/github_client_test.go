@@ -11,6 +11,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/google/go-github/v31/github"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/vcs"
 	"github.com/runatlantis/atlantis/server/logging"
@@ -61,7 +62,7 @@ func TestGithubClient_GetModifiedFiles(t *testing.T) {
 
 	testServerURL, err := url.Parse(testServer.URL)
 	Ok(t, err)
-	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, logger)
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, logger, nil)
 	Ok(t, err)
 	defer disableSSLVerification()()
 
@@ -116,7 +117,7 @@ func TestGithubClient_GetModifiedFilesMovedFile(t *testing.T) {
 
 	testServerURL, err := url.Parse(testServer.URL)
 	Ok(t, err)
-	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, logging.NewNoopLogger(t))
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, logging.NewNoopLogger(t), nil)
 	Ok(t, err)
 	defer disableSSLVerification()()
 
@@ -210,7 +211,7 @@ func TestGithubClient_PaginatesComments(t *testing.T) {
 	testServerURL, err := url.Parse(testServer.URL)
 	Ok(t, err)
 
-	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, logging.NewNoopLogger(t))
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, logging.NewNoopLogger(t), nil)
 	Ok(t, err)
 	defer disableSSLVerification()()
 
@@ -299,7 +300,7 @@ func TestGithubClient_HideOldComments(t *testing.T) {
 	testServerURL, err := url.Parse(testServer.URL)
 	Ok(t, err)
 
-	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, logging.NewNoopLogger(t))
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, logging.NewNoopLogger(t), nil)
 	Ok(t, err)
 	defer disableSSLVerification()()
 
@@ -365,7 +366,7 @@ func TestGithubClient_UpdateStatus(t *testing.T) {
 
 			testServerURL, err := url.Parse(testServer.URL)
 			Ok(t, err)
-			client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, logging.NewNoopLogger(t))
+			client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, logging.NewNoopLogger(t), nil)
 			Ok(t, err)
 			defer disableSSLVerification()()
 
@@ -387,6 +388,168 @@ func TestGithubClient_UpdateStatus(t *testing.T) {
 	}
 }
 
+func TestGithubClient_CreateCheckRun(t *testing.T) {
+	testServer := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.RequestURI {
+			case "/api/v3/repos/owner/repo/check-runs":
+				var body map[string]interface{}
+				err := json.NewDecoder(r.Body).Decode(&body)
+				Ok(t, err)
+				defer r.Body.Close() // nolint: errcheck
+				Equals(t, "atlantis/plan: dir/default", body["name"])
+				Equals(t, "sha", body["head_sha"])
+				Equals(t, "dir=dir&workspace=default", body["external_id"])
+				Equals(t, "completed", body["status"])
+				Equals(t, "failure", body["conclusion"])
+				Equals(t, map[string]interface{}{
+					"title":   "Plan failed",
+					"summary": "summary",
+					"text":    "text",
+				}, body["output"])
+				Equals(t, []interface{}{
+					map[string]interface{}{
+						"label":       "Re-plan",
+						"description": "description",
+						"identifier":  "plan",
+					},
+				}, body["actions"])
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte("{}")) // nolint: errcheck
+			default:
+				t.Errorf("got unexpected request at %q", r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+				return
+			}
+		}))
+
+	testServerURL, err := url.Parse(testServer.URL)
+	Ok(t, err)
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, logging.NewNoopLogger(t), nil)
+	Ok(t, err)
+	defer disableSSLVerification()()
+
+	err = client.CreateCheckRun(models.Repo{
+		FullName: "owner/repo",
+		Owner:    "owner",
+		Name:     "repo",
+		VCSHost: models.VCSHost{
+			Type:     models.Github,
+			Hostname: "github.com",
+		},
+	}, models.PullRequest{
+		Num:        1,
+		HeadCommit: "sha",
+	}, vcs.CheckRun{
+		Name:       "atlantis/plan: dir/default",
+		ExternalID: "dir=dir&workspace=default",
+		State:      models.FailedCommitStatus,
+		Title:      "Plan failed",
+		Summary:    "summary",
+		Text:       "text",
+		Actions: []vcs.CheckRunAction{
+			{
+				Label:       "Re-plan",
+				Description: "description",
+				Identifier:  "plan",
+			},
+		},
+	})
+	Ok(t, err)
+}
+
+// Check run text over GitHub's limit should be truncated without splitting a
+// multi-byte character.
+func TestGithubClient_CreateCheckRunTruncatesText(t *testing.T) {
+	testServer := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body struct {
+				Output struct {
+					Text string `json:"text"`
+				} `json:"output"`
+			}
+			err := json.NewDecoder(r.Body).Decode(&body)
+			Ok(t, err)
+			defer r.Body.Close() // nolint: errcheck
+			Assert(t, len(body.Output.Text) <= 65535, "expected text to be truncated, got %d bytes", len(body.Output.Text))
+			Assert(t, strings.HasSuffix(body.Output.Text, "\n\n...output truncated"), "expected truncated message")
+			Assert(t, !strings.ContainsRune(body.Output.Text, '\uFFFD'), "expected no split characters")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte("{}")) // nolint: errcheck
+		}))
+
+	testServerURL, err := url.Parse(testServer.URL)
+	Ok(t, err)
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, logging.NewNoopLogger(t), nil)
+	Ok(t, err)
+	defer disableSSLVerification()()
+
+	err = client.CreateCheckRun(models.Repo{
+		FullName: "owner/repo",
+		Owner:    "owner",
+		Name:     "repo",
+	}, models.PullRequest{
+		Num:        1,
+		HeadCommit: "sha",
+	}, vcs.CheckRun{
+		Name:  "atlantis/plan: dir/default",
+		State: models.SuccessCommitStatus,
+		Text:  strings.Repeat("é", 40000),
+	})
+	Ok(t, err)
+}
+
+func TestGithubClient_Deployments(t *testing.T) {
+	testServer := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body map[string]interface{}
+			err := json.NewDecoder(r.Body).Decode(&body)
+			Ok(t, err)
+			defer r.Body.Close() // nolint: errcheck
+			switch r.RequestURI {
+			case "/api/v3/repos/owner/repo/deployments":
+				Equals(t, map[string]interface{}{
+					"ref":               "sha",
+					"environment":       "staging",
+					"description":       "Applying",
+					"auto_merge":        false,
+					"required_contexts": []interface{}{},
+				}, body)
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(`{"id": 10}`)) // nolint: errcheck
+			case "/api/v3/repos/owner/repo/deployments/10/statuses":
+				Equals(t, map[string]interface{}{
+					"state":       "in_progress",
+					"description": "Applying",
+					"log_url":     "https://atlantis/pull/1",
+				}, body)
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte("{}")) // nolint: errcheck
+			default:
+				t.Errorf("got unexpected request at %q", r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+				return
+			}
+		}))
+
+	testServerURL, err := url.Parse(testServer.URL)
+	Ok(t, err)
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, logging.NewNoopLogger(t), nil)
+	Ok(t, err)
+	defer disableSSLVerification()()
+
+	repo := models.Repo{
+		FullName: "owner/repo",
+		Owner:    "owner",
+		Name:     "repo",
+	}
+	id, err := client.CreateDeployment(repo, models.PullRequest{Num: 1, HeadCommit: "sha"}, "staging", "Applying")
+	Ok(t, err)
+	Equals(t, int64(10), id)
+	err = client.UpdateDeploymentStatus(repo, id, models.PendingCommitStatus, "https://atlantis/pull/1", "Applying")
+	Ok(t, err)
+}
+
 func TestGithubClient_PullIsApproved(t *testing.T) {
 	respTemplate := `[
 		{
@@ -451,7 +614,7 @@ func TestGithubClient_PullIsApproved(t *testing.T) {
 
 	testServerURL, err := url.Parse(testServer.URL)
 	Ok(t, err)
-	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, logging.NewNoopLogger(t))
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, logging.NewNoopLogger(t), nil)
 	Ok(t, err)
 	defer disableSSLVerification()()
 
@@ -542,7 +705,7 @@ func TestGithubClient_PullIsMergeable(t *testing.T) {
 				}))
 			testServerURL, err := url.Parse(testServer.URL)
 			Ok(t, err)
-			client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, logging.NewNoopLogger(t))
+			client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, logging.NewNoopLogger(t), nil)
 			Ok(t, err)
 			defer disableSSLVerification()()
 
@@ -565,6 +728,207 @@ func TestGithubClient_PullIsMergeable(t *testing.T) {
 	}
 }
 
+func TestGithubClient_PullIsMergeableIgnoringContexts(t *testing.T) {
+	cases := []struct {
+		description       string
+		requiredContexts  string
+		requiredApprovals int
+		expMergeable      bool
+	}{
+		{
+			"only ignored checks are pending",
+			`["atlantis/apply", "ci/build", "lint"]`,
+			1,
+			true,
+		},
+		{
+			"required check is pending",
+			`["atlantis/apply", "ci/test"]`,
+			0,
+			false,
+		},
+		{
+			"atlantis/plan isn't ignored",
+			`["atlantis/apply", "atlantis/plan"]`,
+			0,
+			false,
+		},
+		{
+			"not enough approvals",
+			`["atlantis/apply"]`,
+			2,
+			false,
+		},
+	}
+
+	jsBytes, err := ioutil.ReadFile("fixtures/github-pull-request.json")
+	Ok(t, err)
+	pullJSON := strings.Replace(string(jsBytes), `"mergeable_state": "clean"`, `"mergeable_state": "blocked"`, 1)
+	sha := "6dcb09b5b57875f334f61aebed695e2e4193db5e"
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			testServer := httptest.NewTLSServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					switch r.URL.Path {
+					case "/api/v3/repos/owner/repo/pulls/1":
+						w.Write([]byte(pullJSON)) // nolint: errcheck
+					case "/api/v3/repos/owner/repo/branches/master/protection":
+						fmt.Fprintf(w, `{"required_status_checks": {"contexts": %s}, "required_pull_request_reviews": {"required_approving_review_count": %d}}`, c.requiredContexts, c.requiredApprovals)
+					case "/api/v3/repos/owner/repo/pulls/1347/reviews":
+						// approver2 approved then requested changes so only
+						// approver1 approves.
+						w.Write([]byte(`[
+							{"state": "APPROVED", "user": {"login": "approver1"}},
+							{"state": "APPROVED", "user": {"login": "approver2"}},
+							{"state": "CHANGES_REQUESTED", "user": {"login": "approver2"}},
+							{"state": "COMMENTED", "user": {"login": "approver1"}}
+						]`)) // nolint: errcheck
+					case "/api/v3/repos/owner/repo/commits/" + sha + "/status":
+						w.Write([]byte(`{"statuses": [
+							{"context": "atlantis/plan", "state": "pending"},
+							{"context": "atlantis/apply", "state": "pending"},
+							{"context": "atlantis/apply: project1", "state": "pending"},
+							{"context": "ci/build", "state": "success"},
+							{"context": "ci/test", "state": "pending"}
+						]}`)) // nolint: errcheck
+					case "/api/v3/repos/owner/repo/commits/" + sha + "/check-runs":
+						w.Write([]byte(`{"total_count": 1, "check_runs": [{"name": "lint", "status": "completed", "conclusion": "success"}]}`)) // nolint: errcheck
+					default:
+						t.Errorf("got unexpected request at %q", r.RequestURI)
+						http.Error(w, "not found", http.StatusNotFound)
+					}
+				}))
+			testServerURL, err := url.Parse(testServer.URL)
+			Ok(t, err)
+			client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, logging.NewNoopLogger(t), nil)
+			Ok(t, err)
+			client.MergeableIgnoreContexts = []string{"atlantis/apply"}
+			defer disableSSLVerification()()
+
+			actMergeable, err := client.PullIsMergeable(models.Repo{
+				FullName: "owner/repo",
+				Owner:    "owner",
+				Name:     "repo",
+			}, models.PullRequest{
+				Num: 1,
+			})
+			Ok(t, err)
+			Equals(t, c.expMergeable, actMergeable)
+		})
+	}
+}
+
+func TestGithubClient_RequireStatusChecks(t *testing.T) {
+	cases := []struct {
+		description string
+		protection  string
+		// expUpdate is the body of the request that updates the branch's
+		// protection, or empty if it shouldn't be updated.
+		expUpdate string
+		expErr    string
+	}{
+		{
+			"unprotected branch is protected",
+			"",
+			`{"required_status_checks":{"strict":false,"contexts":["atlantis/plan","atlantis/apply"]},"required_pull_request_reviews":null,"enforce_admins":false,"restrictions":null}`,
+			"",
+		},
+		{
+			"missing contexts are added to required checks",
+			`{"required_status_checks": {"strict": true, "contexts": ["ci/build", "atlantis/plan"]}}`,
+			`{"contexts":["ci/build","atlantis/plan","atlantis/apply"]}`,
+			"",
+		},
+		{
+			"contexts are already required",
+			`{"required_status_checks": {"contexts": ["atlantis/apply", "atlantis/plan"]}}`,
+			"",
+			"",
+		},
+		{
+			"protected branch without required checks is left alone",
+			`{"required_pull_request_reviews": {"required_approving_review_count": 1}}`,
+			"",
+			"branch main is protected without required status checks so they weren't added",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			var update string
+			testServer := httptest.NewTLSServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					switch r.Method + " " + r.URL.Path {
+					case "GET /api/v3/repos/owner/repo/branches/main/protection":
+						if c.protection == "" {
+							http.Error(w, `{"message": "Branch not protected"}`, http.StatusNotFound)
+							return
+						}
+						w.Write([]byte(c.protection)) // nolint: errcheck
+					case "PUT /api/v3/repos/owner/repo/branches/main/protection",
+						"PATCH /api/v3/repos/owner/repo/branches/main/protection/required_status_checks":
+						body, err := ioutil.ReadAll(r.Body)
+						Ok(t, err)
+						update = strings.TrimSpace(string(body))
+						w.Write([]byte("{}")) // nolint: errcheck
+					default:
+						t.Errorf("got unexpected request %s %q", r.Method, r.RequestURI)
+						http.Error(w, "not found", http.StatusNotFound)
+					}
+				}))
+			testServerURL, err := url.Parse(testServer.URL)
+			Ok(t, err)
+			client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, logging.NewNoopLogger(t), nil)
+			Ok(t, err)
+			defer disableSSLVerification()()
+
+			err = client.RequireStatusChecks(models.Repo{
+				FullName: "owner/repo",
+				Owner:    "owner",
+				Name:     "repo",
+			}, "main", []string{"atlantis/plan", "atlantis/apply"})
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+			} else {
+				Ok(t, err)
+			}
+			Equals(t, c.expUpdate, update)
+		})
+	}
+}
+
+func TestGithubClient_CreateWebhook(t *testing.T) {
+	var body string
+	testServer := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method + " " + r.URL.Path {
+			case "POST /api/v3/repos/owner/repo/hooks":
+				b, err := ioutil.ReadAll(r.Body)
+				Ok(t, err)
+				body = strings.TrimSpace(string(b))
+				w.Write([]byte(`{"id": 12}`)) // nolint: errcheck
+			default:
+				t.Errorf("got unexpected request %s %q", r.Method, r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+	testServerURL, err := url.Parse(testServer.URL)
+	Ok(t, err)
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, logging.NewNoopLogger(t), nil)
+	Ok(t, err)
+	defer disableSSLVerification()()
+
+	id, err := client.CreateWebhook(models.Repo{
+		FullName: "owner/repo",
+		Owner:    "owner",
+		Name:     "repo",
+	}, "https://atlantis.example.com/events", "secret")
+	Ok(t, err)
+	Equals(t, int64(12), id)
+	Equals(t, `{"name":"web","config":{"content_type":"json","secret":"secret","url":"https://atlantis.example.com/events"},"events":["issue_comment","pull_request","pull_request_review","push"],"active":true}`, body)
+}
+
 func TestGithubClient_MergePullHandlesError(t *testing.T) {
 	cases := []struct {
 		code    int
@@ -624,7 +988,7 @@ func TestGithubClient_MergePullHandlesError(t *testing.T) {
 
 			testServerURL, err := url.Parse(testServer.URL)
 			Ok(t, err)
-			client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, logging.NewNoopLogger(t))
+			client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, logging.NewNoopLogger(t), nil)
 			Ok(t, err)
 			defer disableSSLVerification()()
 
@@ -747,7 +1111,7 @@ func TestGithubClient_MergePullCorrectMethod(t *testing.T) {
 
 			testServerURL, err := url.Parse(testServer.URL)
 			Ok(t, err)
-			client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, logging.NewNoopLogger(t))
+			client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, logging.NewNoopLogger(t), nil)
 			Ok(t, err)
 			defer disableSSLVerification()()
 
@@ -774,8 +1138,91 @@ func TestGithubClient_MergePullCorrectMethod(t *testing.T) {
 	}
 }
 
+// If the merge method is configured we use it without looking up the repo's
+// allowed merge methods.
+func TestGithubClient_MergePullConfiguredMethod(t *testing.T) {
+	testServer := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.RequestURI {
+			case "/api/v3/repos/runatlantis/atlantis/pulls/1/merge":
+				body, err := ioutil.ReadAll(r.Body)
+				Ok(t, err)
+				defer r.Body.Close() // nolint: errcheck
+				Equals(t, `{"commit_message":"Merge #1","merge_method":"squash"}`+"\n", string(body))
+
+				resp := `{"sha":"6dcb09b5b57875f334f61aebed695e2e4193db5e","merged":true,"message":"Pull Request successfully merged"}`
+				w.Write([]byte(resp)) // nolint: errcheck
+			default:
+				t.Errorf("got unexpected request at %q", r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+				return
+			}
+		}))
+
+	testServerURL, err := url.Parse(testServer.URL)
+	Ok(t, err)
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, logging.NewNoopLogger(t), nil)
+	Ok(t, err)
+	defer disableSSLVerification()()
+
+	err = client.MergePull(
+		models.PullRequest{
+			BaseRepo: models.Repo{
+				FullName: "runatlantis/atlantis",
+				Owner:    "runatlantis",
+				Name:     "atlantis",
+			},
+			Num: 1,
+		}, models.PullRequestOptions{
+			MergeMethod:   models.SquashMergeMethod,
+			CommitMessage: "Merge #1",
+		})
+	Ok(t, err)
+}
+
+func TestGithubClient_Ping(t *testing.T) {
+	cases := []struct {
+		code   int
+		expErr string
+	}{
+		{http.StatusOK, ""},
+		{http.StatusUnauthorized, "401 Bad credentials"},
+	}
+	for _, c := range cases {
+		t.Run(fmt.Sprint(c.code), func(t *testing.T) {
+			testServer := httptest.NewTLSServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					switch r.RequestURI {
+					case "/api/v3/rate_limit":
+						w.WriteHeader(c.code)
+						if c.code == http.StatusOK {
+							w.Write([]byte(`{"resources": {"core": {"limit": 5000, "remaining": 4999}}}`)) // nolint: errcheck
+							return
+						}
+						w.Write([]byte(`{"message": "Bad credentials"}`)) // nolint: errcheck
+					default:
+						t.Errorf("got unexpected request at %q", r.RequestURI)
+						http.Error(w, "not found", http.StatusNotFound)
+					}
+				}))
+			testServerURL, err := url.Parse(testServer.URL)
+			Ok(t, err)
+			client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, logging.NewNoopLogger(t), nil)
+			Ok(t, err)
+			defer disableSSLVerification()()
+
+			err = client.Ping()
+			if c.expErr == "" {
+				Ok(t, err)
+				return
+			}
+			Assert(t, err != nil && strings.Contains(err.Error(), c.expErr), "exp error containing %q, got %v", c.expErr, err)
+		})
+	}
+}
+
 func TestGithubClient_MarkdownPullLink(t *testing.T) {
-	client, err := vcs.NewGithubClient("hostname", &vcs.GithubUserCredentials{"user", "pass"}, logging.NewNoopLogger(t))
+	client, err := vcs.NewGithubClient("hostname", &vcs.GithubUserCredentials{"user", "pass"}, logging.NewNoopLogger(t), nil)
 	Ok(t, err)
 	pull := models.PullRequest{Num: 1}
 	s, _ := client.MarkdownPullLink(pull)
@@ -830,7 +1277,7 @@ func TestGithubClient_SplitComments(t *testing.T) {
 
 	testServerURL, err := url.Parse(testServer.URL)
 	Ok(t, err)
-	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, logging.NewNoopLogger(t))
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, logging.NewNoopLogger(t), nil)
 	Ok(t, err)
 	defer disableSSLVerification()()
 	pull := models.PullRequest{Num: 1}
@@ -862,6 +1309,103 @@ func TestGithubClient_SplitComments(t *testing.T) {
 	Assert(t, strings.Contains(secondSplit, "continued from previous comment"), fmt.Sprintf("comment should contain no reference to the command name but was %q", secondSplit))
 }
 
+// Test that comments that are too long are uploaded as a gist and linked to
+// from a truncated comment if UploadLargeComments is set.
+func TestGithubClient_UploadLargeComments(t *testing.T) {
+	var gist github.Gist
+	var comments []string
+	testServer := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := ioutil.ReadAll(r.Body)
+			Ok(t, err)
+			defer r.Body.Close() // nolint: errcheck
+			switch r.Method + " " + r.RequestURI {
+			case "POST /api/v3/gists":
+				Ok(t, json.Unmarshal(body, &gist))
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(`{"html_url": "https://gist.github.com/lkysow/1"}`)) // nolint: errcheck
+			case "POST /api/v3/repos/runatlantis/atlantis/issues/1/comments":
+				var comment github.IssueComment
+				Ok(t, json.Unmarshal(body, &comment))
+				comments = append(comments, comment.GetBody())
+			default:
+				t.Errorf("got unexpected request at %q", r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+
+	testServerURL, err := url.Parse(testServer.URL)
+	Ok(t, err)
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, logging.NewNoopLogger(t), nil)
+	Ok(t, err)
+	client.UploadLargeComments = true
+	defer disableSSLVerification()()
+	repo := models.Repo{
+		FullName: "runatlantis/atlantis",
+		Owner:    "runatlantis",
+		Name:     "atlantis",
+	}
+
+	comment := strings.Repeat("a", 65537)
+	err = client.CreateComment(repo, 1, comment, models.PlanCommand.String())
+	Ok(t, err)
+
+	Equals(t, "Atlantis plan output for runatlantis/atlantis#1", gist.GetDescription())
+	Equals(t, false, gist.GetPublic())
+	file := gist.Files["atlantis-output.md"]
+	Equals(t, comment, file.GetContent())
+	Equals(t, 1, len(comments))
+	Equals(t, 65536, len(comments[0]))
+	Assert(t, strings.HasSuffix(comments[0], "See the full output [here](https://gist.github.com/lkysow/1)."), "exp link to gist, got %q", comments[0])
+}
+
+func TestGithubClient_UpdatableComments(t *testing.T) {
+	var requests []string
+	testServer := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := ioutil.ReadAll(r.Body)
+			Ok(t, err)
+			defer r.Body.Close() // nolint: errcheck
+			requests = append(requests, r.Method+" "+r.RequestURI+" "+strings.TrimSpace(string(body)))
+			switch r.Method + " " + r.RequestURI {
+			case "POST /api/v3/repos/runatlantis/atlantis/issues/1/comments":
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(`{"id": 5}`)) // nolint: errcheck
+			case "PATCH /api/v3/repos/runatlantis/atlantis/issues/comments/5":
+				w.Write([]byte(`{"id": 5}`)) // nolint: errcheck
+			case "POST /api/v3/repos/runatlantis/atlantis/issues/comments/4/reactions":
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(`{"id": 1}`)) // nolint: errcheck
+			default:
+				t.Errorf("got unexpected request at %q", r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+
+	testServerURL, err := url.Parse(testServer.URL)
+	Ok(t, err)
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, logging.NewNoopLogger(t), nil)
+	Ok(t, err)
+	defer disableSSLVerification()()
+	repo := models.Repo{
+		FullName: "runatlantis/atlantis",
+		Owner:    "runatlantis",
+		Name:     "atlantis",
+	}
+
+	Equals(t, true, client.SupportsCommentUpdates(repo))
+	Ok(t, client.ReactToComment(repo, 1, 4, "eyes"))
+	id, err := client.CreateUpdatableComment(repo, 1, "running")
+	Ok(t, err)
+	Equals(t, int64(5), id)
+	Ok(t, client.UpdateComment(repo, 1, id, "done", models.PlanCommand.String()))
+	Equals(t, []string{
+		`POST /api/v3/repos/runatlantis/atlantis/issues/comments/4/reactions {"content":"eyes"}`,
+		`POST /api/v3/repos/runatlantis/atlantis/issues/1/comments {"body":"running"}`,
+		`PATCH /api/v3/repos/runatlantis/atlantis/issues/comments/5 {"body":"done"}`,
+	}, requests)
+}
+
 // Test that we retry the get pull request call if it 404s.
 func TestGithubClient_Retry404(t *testing.T) {
 	var numCalls = 0
@@ -888,7 +1432,7 @@ func TestGithubClient_Retry404(t *testing.T) {
 
 	testServerURL, err := url.Parse(testServer.URL)
 	Ok(t, err)
-	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, logging.NewNoopLogger(t))
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, logging.NewNoopLogger(t), nil)
 	Ok(t, err)
 	defer disableSSLVerification()()
 	repo := models.Repo{
@@ -906,3 +1450,120 @@ func TestGithubClient_Retry404(t *testing.T) {
 	Ok(t, err)
 	Equals(t, 3, numCalls)
 }
+
+func TestGithubClient_UserIsTeamMember(t *testing.T) {
+	testServer := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method + " " + r.RequestURI {
+			case "GET /api/v3/orgs/runatlantis/teams/sre/memberships/lkysow":
+				http.Error(w, "not found", http.StatusNotFound)
+			case "GET /api/v3/orgs/runatlantis/teams/pending/memberships/lkysow":
+				w.Write([]byte(`{"state":"pending","role":"member"}`)) // nolint: errcheck
+			case "GET /api/v3/orgs/runatlantis/teams/platform/memberships/lkysow":
+				w.Write([]byte(`{"state":"active","role":"member"}`)) // nolint: errcheck
+			default:
+				t.Errorf("got unexpected request at %q", r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+
+	testServerURL, err := url.Parse(testServer.URL)
+	Ok(t, err)
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, logging.NewNoopLogger(t), nil)
+	Ok(t, err)
+	defer disableSSLVerification()()
+	repo := models.Repo{
+		FullName: "runatlantis/atlantis",
+		Owner:    "runatlantis",
+		Name:     "atlantis",
+	}
+	user := models.User{Username: "lkysow"}
+
+	isMember, err := client.UserIsTeamMember(repo, user, []string{"sre", "pending"})
+	Ok(t, err)
+	Equals(t, false, isMember)
+
+	isMember, err = client.UserIsTeamMember(repo, user, []string{"sre", "platform"})
+	Ok(t, err)
+	Equals(t, true, isMember)
+}
+
+func TestGithubClient_GetPullApprovers(t *testing.T) {
+	testServer := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.RequestURI {
+			case "/api/v3/repos/runatlantis/atlantis/pulls/1/reviews?per_page=300":
+				w.Write([]byte(`[
+					{"user": {"login": "approver"}, "state": "APPROVED"},
+					{"user": {"login": "changed-mind"}, "state": "APPROVED"},
+					{"user": {"login": "changed-mind"}, "state": "CHANGES_REQUESTED"},
+					{"user": {"login": "commenter"}, "state": "APPROVED"},
+					{"user": {"login": "commenter"}, "state": "COMMENTED"},
+					{"user": {"login": "dismissed"}, "state": "APPROVED"},
+					{"user": {"login": "dismissed"}, "state": "DISMISSED"}
+				]`)) // nolint: errcheck
+			default:
+				t.Errorf("got unexpected request at %q", r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+
+	testServerURL, err := url.Parse(testServer.URL)
+	Ok(t, err)
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, logging.NewNoopLogger(t), nil)
+	Ok(t, err)
+	defer disableSSLVerification()()
+	repo := models.Repo{
+		FullName: "runatlantis/atlantis",
+		Owner:    "runatlantis",
+		Name:     "atlantis",
+	}
+
+	approvers, err := client.GetPullApprovers(repo, models.PullRequest{Num: 1})
+	Ok(t, err)
+	Equals(t, []string{"approver", "commenter"}, approvers)
+}
+
+func TestGithubClient_DownloadCodeowners(t *testing.T) {
+	// The content is base64 encoded "* @runatlantis/sre\n".
+	testServer := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.RequestURI {
+			case "/api/v3/repos/runatlantis/atlantis/contents/.github/CODEOWNERS?ref=main":
+				http.Error(w, "not found", http.StatusNotFound)
+			case "/api/v3/repos/runatlantis/atlantis/contents/CODEOWNERS?ref=main":
+				w.Write([]byte(`{"type": "file", "encoding": "base64", "content": "KiBAcnVuYXRsYW50aXMvc3JlCg=="}`)) // nolint: errcheck
+			case "/api/v3/repos/runatlantis/atlantis/contents/.github/CODEOWNERS?ref=none",
+				"/api/v3/repos/runatlantis/atlantis/contents/CODEOWNERS?ref=none",
+				"/api/v3/repos/runatlantis/atlantis/contents/docs/CODEOWNERS?ref=none":
+				http.Error(w, "not found", http.StatusNotFound)
+			default:
+				t.Errorf("got unexpected request at %q", r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+
+	testServerURL, err := url.Parse(testServer.URL)
+	Ok(t, err)
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, logging.NewNoopLogger(t), nil)
+	Ok(t, err)
+	defer disableSSLVerification()()
+	pull := models.PullRequest{
+		BaseRepo: models.Repo{
+			FullName: "runatlantis/atlantis",
+			Owner:    "runatlantis",
+			Name:     "atlantis",
+		},
+		BaseBranch: "main",
+	}
+
+	found, contents, err := client.DownloadCodeowners(pull)
+	Ok(t, err)
+	Equals(t, true, found)
+	Equals(t, "* @runatlantis/sre\n", string(contents))
+
+	pull.BaseBranch = "none"
+	found, _, err = client.DownloadCodeowners(pull)
+	Ok(t, err)
+	Equals(t, false, found)
+}
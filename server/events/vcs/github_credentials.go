@@ -3,9 +3,11 @@ package vcs
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 
 	"github.com/bradleyfalzon/ghinstallation"
 	"github.com/google/go-github/v31/github"
@@ -17,7 +19,8 @@ import (
 // GithubCredentials handles creating http.Clients that authenticate.
 type GithubCredentials interface {
 	Client() (*http.Client, error)
-	GetToken() (string, error)
+	// GetToken returns a token for accessing repos owned by owner.
+	GetToken(owner string) (string, error)
 	GetUser() (string, error)
 }
 
@@ -36,7 +39,7 @@ func (c *GithubAnonymousCredentials) GetUser() (string, error) {
 }
 
 // GetToken returns an empty token.
-func (c *GithubAnonymousCredentials) GetToken() (string, error) {
+func (c *GithubAnonymousCredentials) GetToken(_ string) (string, error) {
 	return "", nil
 }
 
@@ -61,28 +64,52 @@ func (c *GithubUserCredentials) GetUser() (string, error) {
 }
 
 // GetToken returns the user token.
-func (c *GithubUserCredentials) GetToken() (string, error) {
+func (c *GithubUserCredentials) GetToken(_ string) (string, error) {
 	return c.Token, nil
 }
 
 // GithubAppCredentials implements GithubCredentials for github app installation token flow.
+// The app can be installed on multiple accounts (users or organizations).
+// Requests are authenticated as the installation on the account that owns the
+// repo they're for.
 type GithubAppCredentials struct {
-	AppID          int64
-	KeyPath        string
-	Hostname       string
-	apiURL         *url.URL
-	installationID int64
-	tr             *ghinstallation.Transport
-	AppSlug        string
+	AppID int64
+	// Key is the app's PEM encoded private key. If it's not set, the key is
+	// read from KeyPath.
+	Key      []byte
+	KeyPath  string
+	Hostname string
+	apiURL   *url.URL
+	AppSlug  string
+
+	mutex sync.Mutex
+	appTr *ghinstallation.AppsTransport
+	// installations maps the lowercase logins of the accounts the app is
+	// installed on to the installation IDs.
+	installations map[string]int64
+	// transports caches a transport per installation ID. Each transport
+	// caches its installation's token and refreshes it before it expires.
+	transports map[int64]*ghinstallation.Transport
 }
 
-// Client returns a github app installation client.
+// githubOwnerCtxKey is the context key for the owner of the repo a request is
+// for. It's used when the owner can't be determined from the request's URL,
+// ex. for GraphQL requests.
+type githubOwnerCtxKey struct{}
+
+// withGithubOwner returns a context for requests about repos owned by owner.
+func withGithubOwner(ctx context.Context, owner string) context.Context {
+	return context.WithValue(ctx, githubOwnerCtxKey{}, owner)
+}
+
+// Client returns a github app installation client. Each request is
+// authenticated as the installation on the owner of the repo it's for.
 func (c *GithubAppCredentials) Client() (*http.Client, error) {
-	itr, err := c.transport()
-	if err != nil {
+	// Get the apps transport now so we error early if the key is invalid.
+	if _, err := c.appsTransport(); err != nil {
 		return nil, err
 	}
-	return &http.Client{Transport: itr}, nil
+	return &http.Client{Transport: &githubAppTransport{creds: c}}, nil
 }
 
 // GetUser returns the username for these credentials.
@@ -91,13 +118,13 @@ func (c *GithubAppCredentials) GetUser() (string, error) {
 	if c.AppSlug == "" {
 		return "", nil
 	}
-	client, err := c.Client()
-
+	// We use the app's JWT since this request isn't for any installation.
+	appTr, err := c.appsTransport()
 	if err != nil {
 		return "", errors.Wrap(err, "initializing client")
 	}
 
-	ghClient := github.NewClient(client)
+	ghClient := github.NewClient(&http.Client{Transport: appTr})
 	ghClient.BaseURL = c.getAPIURL()
 	ctx := context.Background()
 
@@ -111,9 +138,10 @@ func (c *GithubAppCredentials) GetUser() (string, error) {
 	return fmt.Sprintf("%s[bot]", app.GetName()), nil
 }
 
-// GetToken returns a fresh installation token.
-func (c *GithubAppCredentials) GetToken() (string, error) {
-	tr, err := c.transport()
+// GetToken returns a token for the installation on owner. Tokens are cached
+// until shortly before they expire.
+func (c *GithubAppCredentials) GetToken(owner string) (string, error) {
+	tr, err := c.transport(owner)
 	if err != nil {
 		return "", errors.Wrap(err, "transport failed")
 	}
@@ -121,55 +149,158 @@ func (c *GithubAppCredentials) GetToken() (string, error) {
 	return tr.Token(context.Background())
 }
 
-func (c *GithubAppCredentials) getInstallationID() (int64, error) {
-	if c.installationID != 0 {
-		return c.installationID, nil
+// appsTransport returns a transport that authenticates as the app itself
+// rather than as one of its installations.
+func (c *GithubAppCredentials) appsTransport() (*ghinstallation.AppsTransport, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.appTr != nil {
+		return c.appTr, nil
 	}
 
-	tr := http.DefaultTransport
-	// A non-installation transport
-	t, err := ghinstallation.NewAppsTransportKeyFromFile(tr, c.AppID, c.KeyPath)
+	key := c.Key
+	if len(key) == 0 {
+		var err error
+		key, err = ioutil.ReadFile(c.KeyPath) // nolint: gosec
+		if err != nil {
+			return nil, fmt.Errorf("could not read private key: %s", err)
+		}
+	}
+	appTr, err := ghinstallation.NewAppsTransport(http.DefaultTransport, c.AppID, key)
+	if err != nil {
+		return nil, err
+	}
+	appTr.BaseURL = strings.TrimSuffix(c.getAPIURL().String(), "/")
+	c.appTr = appTr
+	return c.appTr, nil
+}
+
+// getInstallationID returns the ID of the installation on owner. If owner is
+// empty, the app must only have a single installation. The installations are
+// listed again if owner isn't found in case the app was installed since we
+// last listed them.
+func (c *GithubAppCredentials) getInstallationID(owner string) (int64, error) {
+	owner = strings.ToLower(owner)
+	c.mutex.Lock()
+	id, ok := c.lookupInstallation(owner)
+	c.mutex.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	installations, err := c.listInstallations()
 	if err != nil {
 		return 0, err
 	}
-	t.BaseURL = c.getAPIURL().String()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.installations = installations
+	if id, ok := c.lookupInstallation(owner); ok {
+		return id, nil
+	}
+	if owner == "" {
+		return 0, fmt.Errorf("unable to determine which installation to use, expected 1 installation, found %d", len(installations))
+	}
+	return 0, fmt.Errorf("app is not installed on %q", owner)
+}
+
+// lookupInstallation must be called with the mutex held.
+func (c *GithubAppCredentials) lookupInstallation(owner string) (int64, bool) {
+	if owner == "" {
+		if len(c.installations) == 1 {
+			for _, id := range c.installations {
+				return id, true
+			}
+		}
+		return 0, false
+	}
+	id, ok := c.installations[owner]
+	return id, ok
+}
+
+func (c *GithubAppCredentials) listInstallations() (map[string]int64, error) {
+	appTr, err := c.appsTransport()
+	if err != nil {
+		return nil, err
+	}
 
 	// Query github with the app's JWT
-	client := github.NewClient(&http.Client{Transport: t})
+	client := github.NewClient(&http.Client{Transport: appTr})
 	client.BaseURL = c.getAPIURL()
 	ctx := context.Background()
 
-	installations, _, err := client.Apps.ListInstallations(ctx, nil)
+	installations := make(map[string]int64)
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		page, resp, err := client.Apps.ListInstallations(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, installation := range page {
+			installations[strings.ToLower(installation.GetAccount().GetLogin())] = installation.GetID()
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return installations, nil
+}
+
+// transport returns the transport for the installation on owner.
+func (c *GithubAppCredentials) transport(owner string) (*ghinstallation.Transport, error) {
+	installationID, err := c.getInstallationID(owner)
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+	appTr, err := c.appsTransport()
+	if err != nil {
+		return nil, err
 	}
 
-	if len(installations) != 1 {
-		return 0, fmt.Errorf("wrong number of installations, expected 1, found %d", len(installations))
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if tr, ok := c.transports[installationID]; ok {
+		return tr, nil
 	}
+	if c.transports == nil {
+		c.transports = make(map[int64]*ghinstallation.Transport)
+	}
+	tr := ghinstallation.NewFromAppsTransport(appTr, installationID)
+	c.transports[installationID] = tr
+	return tr, nil
+}
 
-	c.installationID = installations[0].GetID()
-	return c.installationID, nil
+// githubAppTransport authenticates each request as the installation on the
+// owner of the repo the request is for.
+type githubAppTransport struct {
+	creds *GithubAppCredentials
 }
 
-func (c *GithubAppCredentials) transport() (*ghinstallation.Transport, error) {
-	if c.tr != nil {
-		return c.tr, nil
+func (t *githubAppTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	owner, _ := req.Context().Value(githubOwnerCtxKey{}).(string)
+	if owner == "" {
+		owner = githubOwnerFromPath(req.URL.Path)
 	}
-
-	installationID, err := c.getInstallationID()
+	tr, err := t.creds.transport(owner)
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrapf(err, "getting github app installation for %s", req.URL.Path)
 	}
+	return tr.RoundTrip(req)
+}
 
-	tr := http.DefaultTransport
-	itr, err := ghinstallation.NewKeyFromFile(tr, c.AppID, installationID, c.KeyPath)
-	if err == nil {
-		apiURL := c.getAPIURL()
-		itr.BaseURL = strings.TrimSuffix(apiURL.String(), "/")
-		c.tr = itr
+// githubOwnerFromPath returns the owner from API paths like
+// /repos/{owner}/{repo}/... or /orgs/{org}/.... It returns an empty string for
+// other paths.
+func githubOwnerFromPath(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i := 0; i < len(parts)-1; i++ {
+		if parts[i] == "repos" || parts[i] == "orgs" {
+			return parts[i+1]
+		}
 	}
-	return itr, err
+	return ""
 }
 
 func (c *GithubAppCredentials) getAPIURL() *url.URL {
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/vcs"
 	"github.com/runatlantis/atlantis/server/events/vcs/fixtures"
 	"github.com/runatlantis/atlantis/server/logging"
@@ -16,7 +17,7 @@ func TestGithubClient_GetUser_AppSlug(t *testing.T) {
 	Ok(t, err)
 
 	anonCreds := &vcs.GithubAnonymousCredentials{}
-	anonClient, err := vcs.NewGithubClient(testServer, anonCreds, logging.NewNoopLogger(t))
+	anonClient, err := vcs.NewGithubClient(testServer, anonCreds, logging.NewNoopLogger(t), nil)
 	Ok(t, err)
 	tempSecrets, err := anonClient.ExchangeCode("good-code")
 	Ok(t, err)
@@ -46,7 +47,7 @@ func TestGithubClient_AppAuthentication(t *testing.T) {
 	Ok(t, err)
 
 	anonCreds := &vcs.GithubAnonymousCredentials{}
-	anonClient, err := vcs.NewGithubClient(testServer, anonCreds, logging.NewNoopLogger(t))
+	anonClient, err := vcs.NewGithubClient(testServer, anonCreds, logging.NewNoopLogger(t), nil)
 	Ok(t, err)
 	tempSecrets, err := anonClient.ExchangeCode("good-code")
 	Ok(t, err)
@@ -62,13 +63,13 @@ func TestGithubClient_AppAuthentication(t *testing.T) {
 		KeyPath:  keyPath,
 		Hostname: testServer,
 	}
-	_, err = vcs.NewGithubClient(testServer, appCreds, logging.NewNoopLogger(t))
+	_, err = vcs.NewGithubClient(testServer, appCreds, logging.NewNoopLogger(t), nil)
 	Ok(t, err)
 
-	token, err := appCreds.GetToken()
+	token, err := appCreds.GetToken("github")
 	Ok(t, err)
 
-	newToken, err := appCreds.GetToken()
+	newToken, err := appCreds.GetToken("github")
 	Ok(t, err)
 
 	user, err := appCreds.GetUser()
@@ -80,3 +81,50 @@ func TestGithubClient_AppAuthentication(t *testing.T) {
 		t.Errorf("app token was not cached: %q != %q", token, newToken)
 	}
 }
+
+func TestGithubClient_AppMultipleInstallations(t *testing.T) {
+	defer disableSSLVerification()()
+	testServer, err := fixtures.GithubAppMultipleInstallationsTestServer(t)
+	Ok(t, err)
+
+	appCreds := &vcs.GithubAppCredentials{
+		AppID:    1,
+		Key:      []byte(fixtures.GithubPrivateKey),
+		Hostname: testServer,
+	}
+	client, err := vcs.NewGithubClient(testServer, appCreds, logging.NewNoopLogger(t), nil)
+	Ok(t, err)
+
+	t.Log("each owner gets its own installation's token")
+	token, err := appCreds.GetToken("org1")
+	Ok(t, err)
+	Equals(t, "token-1", token)
+	token, err = appCreds.GetToken("ORG2")
+	Ok(t, err)
+	Equals(t, "token-2", token)
+
+	t.Log("API requests use the token for the repo's owner")
+	for _, owner := range []string{"org1", "org2"} {
+		repo := models.Repo{Owner: owner, Name: "repo", FullName: owner + "/repo"}
+		pull, err := client.GetPullRequest(repo, 1)
+		Ok(t, err)
+		Equals(t, 1, pull.GetNumber())
+	}
+
+	t.Log("owners the app isn't installed on error")
+	_, err = appCreds.GetToken("org3")
+	ErrEquals(t, "transport failed: app is not installed on \"org3\"", err)
+
+	t.Log("requests without an owner error since there are multiple installations")
+	_, err = appCreds.GetToken("")
+	ErrEquals(t, "transport failed: unable to determine which installation to use, expected 1 installation, found 2", err)
+}
+
+func TestGithubAppCredentials_InvalidKey(t *testing.T) {
+	appCreds := &vcs.GithubAppCredentials{
+		AppID: 1,
+		Key:   []byte("not a key"),
+	}
+	_, err := appCreds.Client()
+	ErrContains(t, "could not parse private key", err)
+}
@@ -36,8 +36,18 @@ type GitlabClient struct {
 	Client *gitlab.Client
 	// Version is set to the server version.
 	Version *version.Version
+	// UploadLargeComments causes comments longer than
+	// gitlabMaxCommentLength to be uploaded as a private project snippet and
+	// linked from a truncated comment instead of being split into multiple
+	// comments.
+	UploadLargeComments bool
+	logger              logging.SimpleLogging
 }
 
+// gitlabMaxCommentLength is the maximum number of chars allowed in a single
+// note by GitLab.
+const gitlabMaxCommentLength = 1000000
+
 // commonMarkSupported is a version constraint that is true when this version of
 // GitLab supports CommonMark, a markdown specification.
 // See https://about.gitlab.com/2018/07/22/gitlab-11-1-released/
@@ -46,13 +56,21 @@ var commonMarkSupported = MustConstraint(">=11.1")
 // gitlabClientUnderTest is true if we're running under go test.
 var gitlabClientUnderTest = false
 
-// NewGitlabClient returns a valid GitLab client.
-func NewGitlabClient(hostname string, token string, logger logging.SimpleLogging) (*GitlabClient, error) {
-	client := &GitlabClient{}
+// NewGitlabClient returns a valid GitLab client. If retrier is set, its
+// requests are retried when they're rate limited or fail instead of using the
+// GitLab library's own retries.
+func NewGitlabClient(hostname string, token string, logger logging.SimpleLogging, retrier *APIRetrier) (*GitlabClient, error) {
+	client := &GitlabClient{logger: logger}
+	var opts []gitlab.ClientOptionFunc
+	if retrier != nil {
+		opts = append(opts,
+			gitlab.WithHTTPClient(&http.Client{Transport: retrier.Transport(nil)}),
+			gitlab.WithoutRetries())
+	}
 
 	// Create the client differently depending on the base URL.
 	if hostname == "gitlab.com" {
-		glClient, err := gitlab.NewClient(token)
+		glClient, err := gitlab.NewClient(token, opts...)
 		if err != nil {
 			return nil, err
 		}
@@ -81,7 +99,7 @@ func NewGitlabClient(hostname string, token string, logger logging.SimpleLogging
 		// Now we're ready to construct the client.
 		absoluteURL = strings.TrimSuffix(absoluteURL, "/")
 		apiURL := fmt.Sprintf("%s/api/v4/", absoluteURL)
-		glClient, err := gitlab.NewClient(token, gitlab.WithBaseURL(apiURL))
+		glClient, err := gitlab.NewClient(token, append(opts, gitlab.WithBaseURL(apiURL))...)
 		if err != nil {
 			return nil, err
 		}
@@ -143,12 +161,175 @@ func (g *GitlabClient) GetModifiedFiles(repo models.Repo, pull models.PullReques
 }
 
 // CreateComment creates a comment on the merge request.
+// If comment length is greater than the max comment length we split into
+// multiple comments, or, if UploadLargeComments is set, upload the full
+// comment as a snippet and link to it.
 func (g *GitlabClient) CreateComment(repo models.Repo, pullNum int, comment string, command string) error {
+	for _, c := range g.splitComment(repo, pullNum, comment, command) {
+		if err := g.postComment(repo, pullNum, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateReply posts comment as notes in the merge request discussion with id
+// discussionID and returns the id of the first note. Long comments are split
+// like with CreateComment.
+func (g *GitlabClient) CreateReply(repo models.Repo, pullNum int, discussionID string, comment string, command string) (int64, error) {
+	var firstID int64
+	for i, c := range g.splitComment(repo, pullNum, comment, command) {
+		note, _, err := g.Client.Discussions.AddMergeRequestDiscussionNote(repo.FullName, pullNum, discussionID, &gitlab.AddMergeRequestDiscussionNoteOptions{Body: gitlab.String(c)})
+		if err != nil {
+			return 0, err
+		}
+		if i == 0 {
+			firstID = int64(note.ID)
+		}
+	}
+	return firstID, nil
+}
+
+// splitComment returns the comments comment needs to be posted as. If it's
+// too long for one comment it's either truncated with a link to the full
+// output in a snippet or split into multiple comments.
+func (g *GitlabClient) splitComment(repo models.Repo, pullNum int, comment string, command string) []string {
+	if len(comment) <= gitlabMaxCommentLength {
+		return []string{comment}
+	}
+
+	// The command is included in the first line of continued notes so that
+	// they're collapsed by HidePrevCommandComments too.
+	continued := "Continued from previous comment."
+	if command != "" {
+		continued = fmt.Sprintf("Continued %s output from previous comment.", command)
+	}
+	// Only close the folding markdown if this version of GitLab supports it.
+	sepEnd := "\n```\n**Warning**: Output length greater than max comment size."
+	sepStart := continued + "\n```diff\n"
+	if g.SupportsCommonMark() {
+		sepEnd = "\n```\n</details>\n<br>\n\n**Warning**: Output length greater than max comment size."
+		sepStart = continued + "\n<details><summary>Show Output</summary>\n\n```diff\n"
+	}
+
+	if g.UploadLargeComments {
+		snippetURL, err := g.uploadSnippet(repo, pullNum, comment, command)
+		if err == nil {
+			sepEnd += fmt.Sprintf(" See the full output [here](%s).", snippetURL)
+			return []string{common.TruncateComment(comment, gitlabMaxCommentLength, sepEnd)}
+		}
+		g.logger.Warn("unable to upload comment as a snippet, splitting it into multiple comments instead: %s", err)
+	}
+
+	return common.SplitComment(comment, gitlabMaxCommentLength, sepEnd+" Continued in next comment.", sepStart)
+}
+
+// SupportsCommentUpdates returns true since GitLab supports reacting to and
+// editing notes.
+func (g *GitlabClient) SupportsCommentUpdates(repo models.Repo) bool {
+	return true
+}
+
+// ReactToComment awards the emoji reaction, ex. eyes, to the note with id
+// commentID.
+func (g *GitlabClient) ReactToComment(repo models.Repo, pullNum int, commentID int64, reaction string) error {
+	_, _, err := g.Client.AwardEmoji.CreateMergeRequestAwardEmojiOnNote(repo.FullName, pullNum, int(commentID), &gitlab.CreateAwardEmojiOptions{Name: reaction})
+	return err
+}
+
+// CreateUpdatableComment posts comment as a note and returns its id.
+func (g *GitlabClient) CreateUpdatableComment(repo models.Repo, pullNum int, comment string) (int64, error) {
+	note, _, err := g.Client.Notes.CreateMergeRequestNote(repo.FullName, pullNum, &gitlab.CreateMergeRequestNoteOptions{Body: gitlab.String(comment)})
+	if err != nil {
+		return 0, err
+	}
+	return int64(note.ID), nil
+}
+
+// UpdateComment replaces the body of the note with id commentID. Output that
+// doesn't fit is posted in new notes.
+func (g *GitlabClient) UpdateComment(repo models.Repo, pullNum int, commentID int64, comment string, command string) error {
+	comments := g.splitComment(repo, pullNum, comment, command)
+	if _, _, err := g.Client.Notes.UpdateMergeRequestNote(repo.FullName, pullNum, int(commentID), &gitlab.UpdateMergeRequestNoteOptions{Body: gitlab.String(comments[0])}); err != nil {
+		return err
+	}
+	for _, c := range comments[1:] {
+		if err := g.postComment(repo, pullNum, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// postComment actually posts the comment. It's a helper for CreateComment().
+func (g *GitlabClient) postComment(repo models.Repo, pullNum int, comment string) error {
 	_, _, err := g.Client.Notes.CreateMergeRequestNote(repo.FullName, pullNum, &gitlab.CreateMergeRequestNoteOptions{Body: gitlab.String(comment)})
 	return err
 }
 
+// uploadSnippet uploads comment as a private project snippet and returns its
+// URL.
+func (g *GitlabClient) uploadSnippet(repo models.Repo, pullNum int, comment string, command string) (string, error) {
+	title := fmt.Sprintf("Atlantis output for !%d", pullNum)
+	if command != "" {
+		title = fmt.Sprintf("Atlantis %s output for !%d", command, pullNum)
+	}
+	snippet, _, err := g.Client.ProjectSnippets.CreateSnippet(repo.FullName, &gitlab.CreateProjectSnippetOptions{
+		Title:      gitlab.String(title),
+		FileName:   gitlab.String("atlantis-output.md"),
+		Content:    gitlab.String(comment),
+		Visibility: gitlab.Visibility(gitlab.PrivateVisibility),
+	})
+	if err != nil {
+		return "", err
+	}
+	return snippet.WebURL, nil
+}
+
+// gitlabHiddenCommentPrefix starts notes that were collapsed by
+// HidePrevCommandComments.
+const gitlabHiddenCommentPrefix = "<details><summary>Outdated, click to expand</summary>\n\n"
+
+// HidePrevCommandComments collapses previous notes from command. GitLab can't
+// hide notes so they're edited to wrap their body in a collapsed section.
 func (g *GitlabClient) HidePrevCommandComments(repo models.Repo, pullNum int, command string) error {
+	user, _, err := g.Client.Users.CurrentUser()
+	if err != nil {
+		return errors.Wrap(err, "getting current user")
+	}
+
+	var allNotes []*gitlab.Note
+	nextPage := 0
+	for {
+		g.logger.Debug("GET /projects/%s/merge_requests/%d/notes", repo.FullName, pullNum)
+		notes, resp, err := g.Client.Notes.ListMergeRequestNotes(repo.FullName, pullNum, &gitlab.ListMergeRequestNotesOptions{
+			ListOptions: gitlab.ListOptions{Page: nextPage, PerPage: 100},
+			OrderBy:     gitlab.String("created_at"),
+			Sort:        gitlab.String("asc"),
+		})
+		if err != nil {
+			return errors.Wrap(err, "listing notes")
+		}
+		allNotes = append(allNotes, notes...)
+		if resp.NextPage == 0 {
+			break
+		}
+		nextPage = resp.NextPage
+	}
+
+	for _, note := range allNotes {
+		if note.System || !strings.EqualFold(note.Author.Username, user.Username) {
+			continue
+		}
+		if strings.HasPrefix(note.Body, gitlabHiddenCommentPrefix) || !common.IsCommandComment(note.Body, command) {
+			continue
+		}
+		body := gitlabHiddenCommentPrefix + note.Body + "\n</details>"
+		g.logger.Debug("PUT /projects/%s/merge_requests/%d/notes/%d", repo.FullName, pullNum, note.ID)
+		if _, _, err := g.Client.Notes.UpdateMergeRequestNote(repo.FullName, pullNum, note.ID, &gitlab.UpdateMergeRequestNoteOptions{Body: gitlab.String(body)}); err != nil {
+			return errors.Wrapf(err, "collapsing note %d", note.ID)
+		}
+	}
 	return nil
 }
 
@@ -188,6 +369,44 @@ func (g *GitlabClient) PullIsMergeable(repo models.Repo, pull models.PullRequest
 	return false, nil
 }
 
+// PullIsClosed returns true if the merge request is closed or merged.
+func (g *GitlabClient) PullIsClosed(repo models.Repo, pull models.PullRequest) (bool, error) {
+	mr, err := g.GetMergeRequest(repo.FullName, pull.Num)
+	if err != nil {
+		return false, err
+	}
+	return mr.State == "closed" || mr.State == "merged", nil
+}
+
+// Ping implements vcs.Pinger.Ping by getting the token's user.
+func (g *GitlabClient) Ping() error {
+	_, _, err := g.Client.Users.CurrentUser()
+	return errors.Wrap(err, "getting current user")
+}
+
+// CreateWebhook creates a project hook on repo that sends the events Atlantis
+// uses to url with secret as its token. It returns the hook's ID.
+func (g *GitlabClient) CreateWebhook(repo models.Repo, url string, secret string) (int64, error) {
+	hook, _, err := g.Client.Projects.AddProjectHook(repo.FullName, &gitlab.AddProjectHookOptions{
+		URL:                   gitlab.String(url),
+		Token:                 gitlab.String(secret),
+		PushEvents:            gitlab.Bool(true),
+		MergeRequestsEvents:   gitlab.Bool(true),
+		NoteEvents:            gitlab.Bool(true),
+		EnableSSLVerification: gitlab.Bool(true),
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "creating project hook")
+	}
+	return int64(hook.ID), nil
+}
+
+// DeleteWebhook deletes the project hook with id from repo.
+func (g *GitlabClient) DeleteWebhook(repo models.Repo, id int64) error {
+	_, err := g.Client.Projects.DeleteProjectHook(repo.FullName, int(id))
+	return errors.Wrap(err, "deleting project hook")
+}
+
 // UpdateStatus updates the build status of a commit.
 func (g *GitlabClient) UpdateStatus(repo models.Repo, pull models.PullRequest, state models.CommitStatus, src string, description string, url string) error {
 	gitlabState := gitlab.Failed
@@ -213,16 +432,36 @@ func (g *GitlabClient) GetMergeRequest(repoFullName string, pullNum int) (*gitla
 	return mr, err
 }
 
+// GetProject returns the project with id projectID.
+func (g *GitlabClient) GetProject(projectID int) (*gitlab.Project, error) {
+	project, _, err := g.Client.Projects.GetProject(projectID, nil)
+	return project, err
+}
+
 // MergePull merges the merge request.
+// GitLab sets whether merges rebase at the project level so the rebase merge
+// method isn't supported.
 func (g *GitlabClient) MergePull(pull models.PullRequest, pullOptions models.PullRequestOptions) error {
 	commitMsg := common.AutomergeCommitMsg
+	if pullOptions.CommitMessage != "" {
+		commitMsg = pullOptions.CommitMessage
+	}
+	opts := &gitlab.AcceptMergeRequestOptions{
+		MergeCommitMessage:       &commitMsg,
+		ShouldRemoveSourceBranch: &pullOptions.DeleteSourceBranchOnMerge,
+	}
+	switch pullOptions.MergeMethod {
+	case "", models.MergeCommitMergeMethod:
+	case models.SquashMergeMethod:
+		opts.Squash = gitlab.Bool(true)
+		opts.SquashCommitMessage = &commitMsg
+	default:
+		return fmt.Errorf("merge method %q is not supported by GitLab, set the merge method in your project's settings instead", pullOptions.MergeMethod)
+	}
 	_, _, err := g.Client.MergeRequests.AcceptMergeRequest(
 		pull.BaseRepo.FullName,
 		pull.Num,
-		&gitlab.AcceptMergeRequestOptions{
-			MergeCommitMessage:       &commitMsg,
-			ShouldRemoveSourceBranch: &pullOptions.DeleteSourceBranchOnMerge,
-		})
+		opts)
 	return errors.Wrap(err, "unable to merge merge request, it may not be in a mergeable state")
 }
 
@@ -295,3 +534,73 @@ func (g *GitlabClient) DownloadRepoConfigFile(pull models.PullRequest) (bool, []
 func (g *GitlabClient) SupportsSingleFileDownload(repo models.Repo) bool {
 	return true
 }
+
+// GetCloneURL returns the HTTPS clone URL of the project.
+func (g *GitlabClient) GetCloneURL(VCSHostType models.VCSHostType, repo string) (string, error) {
+	project, _, err := g.Client.Projects.GetProject(repo, nil)
+	if err != nil {
+		return "", err
+	}
+	return project.HTTPURLToRepo, nil
+}
+
+// gitlabCodeownersPaths are where GitLab looks for the CODEOWNERS file, in
+// order.
+var gitlabCodeownersPaths = []string{"CODEOWNERS", "docs/CODEOWNERS", ".gitlab/CODEOWNERS"}
+
+// GetPullApprovers returns the users who approved the merge request.
+func (g *GitlabClient) GetPullApprovers(repo models.Repo, pull models.PullRequest) ([]string, error) {
+	approvals, _, err := g.Client.MergeRequests.GetMergeRequestApprovals(repo.FullName, pull.Num)
+	if err != nil {
+		return nil, err
+	}
+	var approvers []string
+	for _, approver := range approvals.ApprovedBy {
+		if approver != nil && approver.User != nil {
+			approvers = append(approvers, approver.User.Username)
+		}
+	}
+	return approvers, nil
+}
+
+// DownloadCodeowners returns the contents of the first CODEOWNERS file found
+// on the merge request's target branch.
+func (g *GitlabClient) DownloadCodeowners(pull models.PullRequest) (bool, []byte, error) {
+	opt := gitlab.GetRawFileOptions{Ref: gitlab.String(pull.BaseBranch)}
+	for _, path := range gitlabCodeownersPaths {
+		bytes, resp, err := g.Client.RepositoryFiles.GetRawFile(pull.BaseRepo.FullName, path, &opt)
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			continue
+		}
+		if err != nil {
+			return true, []byte{}, errors.Wrapf(err, "getting %s", path)
+		}
+		return true, bytes, nil
+	}
+	return false, []byte{}, nil
+}
+
+// UserIsTeamMember returns true if user is an active member of any of the
+// groups. Inherited membership from parent groups isn't considered.
+func (g *GitlabClient) UserIsTeamMember(repo models.Repo, user models.User, teams []string) (bool, error) {
+	users, _, err := g.Client.Users.ListUsers(&gitlab.ListUsersOptions{Username: gitlab.String(user.Username)})
+	if err != nil {
+		return false, errors.Wrapf(err, "looking up user %q", user.Username)
+	}
+	if len(users) == 0 {
+		return false, nil
+	}
+	for _, team := range teams {
+		member, resp, err := g.Client.GroupMembers.GetGroupMember(team, users[0].ID)
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			continue
+		}
+		if err != nil {
+			return false, errors.Wrapf(err, "getting membership of group %q", team)
+		}
+		if member.State == "active" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
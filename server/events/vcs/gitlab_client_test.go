@@ -1,10 +1,12 @@
 package vcs
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	version "github.com/hashicorp/go-version"
@@ -56,7 +58,7 @@ func TestNewGitlabClient_BaseURL(t *testing.T) {
 	for _, c := range cases {
 		t.Run(c.Hostname, func(t *testing.T) {
 			log := logging.NewNoopLogger(t)
-			client, err := NewGitlabClient(c.Hostname, "token", log)
+			client, err := NewGitlabClient(c.Hostname, "token", log, nil)
 			Ok(t, err)
 			Equals(t, c.ExpBaseURL, client.Client.BaseURL().String())
 		})
@@ -177,6 +179,232 @@ func TestGitlabClient_MergePull(t *testing.T) {
 	}
 }
 
+func TestGitlabClient_MergePullSquash(t *testing.T) {
+	testServer := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.RequestURI {
+			case "/api/v4/projects/runatlantis%2Fatlantis/merge_requests/1/merge":
+				body, err := ioutil.ReadAll(r.Body)
+				Ok(t, err)
+				Equals(t, `{"merge_commit_message":"Merge !1","squash_commit_message":"Merge !1","squash":true,"should_remove_source_branch":false}`, string(body))
+				w.Write([]byte(mergeSuccess)) // nolint: errcheck
+			case "/api/v4/":
+				// Rate limiter requests.
+				w.WriteHeader(http.StatusOK)
+			default:
+				t.Errorf("got unexpected request at %q", r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+
+	internalClient, err := gitlab.NewClient("token", gitlab.WithBaseURL(testServer.URL))
+	Ok(t, err)
+	client := &GitlabClient{
+		Client:  internalClient,
+		Version: nil,
+	}
+
+	pull := models.PullRequest{
+		Num: 1,
+		BaseRepo: models.Repo{
+			FullName: "runatlantis/atlantis",
+			Owner:    "runatlantis",
+			Name:     "atlantis",
+		},
+	}
+	err = client.MergePull(pull, models.PullRequestOptions{
+		MergeMethod:   models.SquashMergeMethod,
+		CommitMessage: "Merge !1",
+	})
+	Ok(t, err)
+
+	err = client.MergePull(pull, models.PullRequestOptions{
+		MergeMethod: models.RebaseMergeMethod,
+	})
+	ErrEquals(t, `merge method "rebase" is not supported by GitLab, set the merge method in your project's settings instead`, err)
+}
+
+// Test that comments that are too long are split into multiple notes or, if
+// UploadLargeComments is set, uploaded as a snippet.
+func TestGitlabClient_CreateCommentLarge(t *testing.T) {
+	var notes []string
+	var snippet gitlab.CreateProjectSnippetOptions
+	testServer := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := ioutil.ReadAll(r.Body)
+			Ok(t, err)
+			switch r.Method + " " + r.RequestURI {
+			case "POST /api/v4/projects/runatlantis%2Fatlantis/merge_requests/1/notes":
+				var note gitlab.CreateMergeRequestNoteOptions
+				Ok(t, json.Unmarshal(body, &note))
+				notes = append(notes, *note.Body)
+				w.Write([]byte(`{"id": 1}`)) // nolint: errcheck
+			case "POST /api/v4/projects/runatlantis%2Fatlantis/snippets":
+				Ok(t, json.Unmarshal(body, &snippet))
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(`{"id": 1, "web_url": "https://gitlab.com/runatlantis/atlantis/-/snippets/1"}`)) // nolint: errcheck
+			case "GET /api/v4/":
+				// Rate limiter requests.
+				w.WriteHeader(http.StatusOK)
+			default:
+				t.Errorf("got unexpected request at %q", r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+
+	internalClient, err := gitlab.NewClient("token", gitlab.WithBaseURL(testServer.URL))
+	Ok(t, err)
+	client := &GitlabClient{
+		Client:  internalClient,
+		Version: version.Must(version.NewVersion("13.0.0")),
+		logger:  logging.NewNoopLogger(t),
+	}
+	repo := models.Repo{FullName: "runatlantis/atlantis"}
+	comment := strings.Repeat("a", gitlabMaxCommentLength+1)
+
+	Ok(t, client.CreateComment(repo, 1, comment, models.PlanCommand.String()))
+	Equals(t, 2, len(notes))
+	Assert(t, strings.HasPrefix(notes[1], "Continued plan output from previous comment.\n<details>"), "exp continued note, got %q", notes[1][:50])
+
+	notes = nil
+	client.UploadLargeComments = true
+	Ok(t, client.CreateComment(repo, 1, comment, models.PlanCommand.String()))
+	Equals(t, "Atlantis plan output for !1", *snippet.Title)
+	Equals(t, gitlab.PrivateVisibility, *snippet.Visibility)
+	Equals(t, comment, *snippet.Content)
+	Equals(t, 1, len(notes))
+	Equals(t, gitlabMaxCommentLength, len(notes[0]))
+	Assert(t, strings.HasSuffix(notes[0], "See the full output [here](https://gitlab.com/runatlantis/atlantis/-/snippets/1)."), "exp link to snippet")
+}
+
+func TestGitlabClient_UpdatableComments(t *testing.T) {
+	var requests []string
+	testServer := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := ioutil.ReadAll(r.Body)
+			Ok(t, err)
+			switch r.Method + " " + r.RequestURI {
+			case "POST /api/v4/projects/runatlantis%2Fatlantis/merge_requests/1/notes",
+				"PUT /api/v4/projects/runatlantis%2Fatlantis/merge_requests/1/notes/5":
+				requests = append(requests, r.Method+" "+string(body))
+				w.Write([]byte(`{"id": 5}`)) // nolint: errcheck
+			case "POST /api/v4/projects/runatlantis%2Fatlantis/merge_requests/1/notes/4/award_emoji":
+				requests = append(requests, r.Method+" "+string(body))
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(`{"id": 1}`)) // nolint: errcheck
+			case "GET /api/v4/":
+				// Rate limiter requests.
+				w.WriteHeader(http.StatusOK)
+			default:
+				t.Errorf("got unexpected request at %q", r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+
+	internalClient, err := gitlab.NewClient("token", gitlab.WithBaseURL(testServer.URL))
+	Ok(t, err)
+	client := &GitlabClient{
+		Client:  internalClient,
+		Version: version.Must(version.NewVersion("13.0.0")),
+		logger:  logging.NewNoopLogger(t),
+	}
+	repo := models.Repo{FullName: "runatlantis/atlantis"}
+
+	Equals(t, true, client.SupportsCommentUpdates(repo))
+	Ok(t, client.ReactToComment(repo, 1, 4, "eyes"))
+	id, err := client.CreateUpdatableComment(repo, 1, "running")
+	Ok(t, err)
+	Equals(t, int64(5), id)
+	Ok(t, client.UpdateComment(repo, 1, id, "done", models.PlanCommand.String()))
+	Equals(t, []string{
+		`POST {"name":"eyes"}`,
+		`POST {"body":"running"}`,
+		`PUT {"body":"done"}`,
+	}, requests)
+}
+
+// Replies should be posted as notes in the discussion.
+func TestGitlabClient_CreateReply(t *testing.T) {
+	var requests []string
+	testServer := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := ioutil.ReadAll(r.Body)
+			Ok(t, err)
+			switch r.Method + " " + r.RequestURI {
+			case "POST /api/v4/projects/runatlantis%2Fatlantis/merge_requests/1/discussions/abc123/notes":
+				requests = append(requests, string(body))
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(`{"id": 7}`)) // nolint: errcheck
+			case "GET /api/v4/":
+				// Rate limiter requests.
+				w.WriteHeader(http.StatusOK)
+			default:
+				t.Errorf("got unexpected request at %q", r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+
+	internalClient, err := gitlab.NewClient("token", gitlab.WithBaseURL(testServer.URL))
+	Ok(t, err)
+	client := &GitlabClient{
+		Client:  internalClient,
+		Version: version.Must(version.NewVersion("13.0.0")),
+		logger:  logging.NewNoopLogger(t),
+	}
+	repo := models.Repo{FullName: "runatlantis/atlantis"}
+
+	id, err := client.CreateReply(repo, 1, "abc123", "plan output", models.PlanCommand.String())
+	Ok(t, err)
+	Equals(t, int64(7), id)
+	Equals(t, []string{`{"body":"plan output"}`}, requests)
+}
+
+// Only Atlantis's notes from the command that aren't already collapsed should
+// be collapsed.
+func TestGitlabClient_HidePrevCommandComments(t *testing.T) {
+	notesResp := `[
+	{"id": 1, "body": "Ran Plan for dir: .", "author": {"username": "atlantis"}},
+	{"id": 2, "body": "Ran Plan for dir: .", "author": {"username": "someone-else"}},
+	{"id": 3, "body": "Ran Apply for dir: .", "author": {"username": "atlantis"}},
+	{"id": 4, "body": "<details><summary>Outdated, click to expand</summary>\n\nRan Plan for dir: .\n</details>", "author": {"username": "atlantis"}},
+	{"id": 5, "body": "added 1 commit to plan", "system": true, "author": {"username": "atlantis"}}
+]`
+	var updated []string
+	testServer := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := ioutil.ReadAll(r.Body)
+			Ok(t, err)
+			switch r.Method + " " + r.RequestURI {
+			case "GET /api/v4/user":
+				w.Write([]byte(`{"id": 1, "username": "atlantis"}`)) // nolint: errcheck
+			case "GET /api/v4/projects/runatlantis%2Fatlantis/merge_requests/1/notes?order_by=created_at&per_page=100&sort=asc":
+				w.Write([]byte(notesResp)) // nolint: errcheck
+			case "PUT /api/v4/projects/runatlantis%2Fatlantis/merge_requests/1/notes/1":
+				var note gitlab.UpdateMergeRequestNoteOptions
+				Ok(t, json.Unmarshal(body, &note))
+				updated = append(updated, *note.Body)
+				w.Write([]byte(`{"id": 1}`)) // nolint: errcheck
+			case "GET /api/v4/":
+				// Rate limiter requests.
+				w.WriteHeader(http.StatusOK)
+			default:
+				t.Errorf("got unexpected request at %q", r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+
+	internalClient, err := gitlab.NewClient("token", gitlab.WithBaseURL(testServer.URL))
+	Ok(t, err)
+	client := &GitlabClient{
+		Client:  internalClient,
+		Version: version.Must(version.NewVersion("13.0.0")),
+		logger:  logging.NewNoopLogger(t),
+	}
+
+	Ok(t, client.HidePrevCommandComments(models.Repo{FullName: "runatlantis/atlantis"}, 1, "Plan"))
+	Equals(t, []string{"<details><summary>Outdated, click to expand</summary>\n\nRan Plan for dir: .\n</details>"}, updated)
+}
+
 func TestGitlabClient_UpdateStatus(t *testing.T) {
 	cases := []struct {
 		status   models.CommitStatus
@@ -245,7 +473,7 @@ func TestGitlabClient_UpdateStatus(t *testing.T) {
 func TestGitlabClient_MarkdownPullLink(t *testing.T) {
 	gitlabClientUnderTest = true
 	defer func() { gitlabClientUnderTest = false }()
-	client, err := NewGitlabClient("gitlab.com", "token", nil)
+	client, err := NewGitlabClient("gitlab.com", "token", nil, nil)
 	Ok(t, err)
 	pull := models.PullRequest{Num: 1}
 	s, _ := client.MarkdownPullLink(pull)
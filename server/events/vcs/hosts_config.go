@@ -0,0 +1,97 @@
+package vcs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// HostConfig is an additional VCS host that Atlantis serves alongside the
+// one configured for each VCS type with flags, ex. a second GitHub
+// Enterprise instance.
+type HostConfig struct {
+	// Name identifies the host in logs.
+	Name string `yaml:"name"`
+	// Type is the kind of host, either github or gitlab.
+	Type string `yaml:"type"`
+	// Hostname is the hostname of the host, ex. ghe.example.com. Repos are
+	// matched to the host by the hostname of their clone URL.
+	Hostname string `yaml:"hostname"`
+	// User and Token are the credentials used for the host's API and for
+	// cloning.
+	User  string `yaml:"user"`
+	Token string `yaml:"token"`
+	// WebhookSecret, if set, is used to validate webhooks from the host.
+	WebhookSecret string `yaml:"webhook_secret"`
+	// RepoAllowlist, if set, replaces --repo-allowlist for repos on the
+	// host. Its rules don't include the hostname, ex. org/*.
+	RepoAllowlist string `yaml:"repo_allowlist"`
+}
+
+// HostTypes maps the types of hosts supported in HostConfig to their
+// VCSHostType.
+var HostTypes = map[string]models.VCSHostType{
+	"github": models.Github,
+	"gitlab": models.Gitlab,
+}
+
+// VCSHostType returns the type of the host.
+func (h HostConfig) VCSHostType() models.VCSHostType {
+	return HostTypes[h.Type]
+}
+
+// ReadHostsConfig reads and validates the VCS hosts config file at path.
+func ReadHostsConfig(path string) ([]HostConfig, error) {
+	data, err := ioutil.ReadFile(path) // nolint: gosec
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading %s", path)
+	}
+	hosts, err := ParseHostsConfig(data)
+	return hosts, errors.Wrapf(err, "parsing %s", path)
+}
+
+// ParseHostsConfig parses and validates a VCS hosts config, ex.
+//   hosts:
+//   - name: ghe-east
+//     type: github
+//     hostname: ghe-east.example.com
+//     user: atlantis
+//     token: ...
+func ParseHostsConfig(data []byte) ([]HostConfig, error) {
+	var cfg struct {
+		Hosts []HostConfig `yaml:"hosts"`
+	}
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool)
+	hostnames := make(map[string]bool)
+	for i, h := range cfg.Hosts {
+		if h.Name == "" {
+			return nil, fmt.Errorf("hosts[%d]: name must be set", i)
+		}
+		if names[h.Name] {
+			return nil, fmt.Errorf("host %q: name must be unique", h.Name)
+		}
+		names[h.Name] = true
+		if _, ok := HostTypes[h.Type]; !ok {
+			return nil, fmt.Errorf("host %q: type must be github or gitlab, got %q", h.Name, h.Type)
+		}
+		if h.Hostname == "" || strings.Contains(h.Hostname, "://") {
+			return nil, fmt.Errorf("host %q: hostname must be set to a hostname only, ex. ghe.example.com", h.Name)
+		}
+		hostname := strings.ToLower(h.Hostname)
+		if hostnames[hostname] {
+			return nil, fmt.Errorf("host %q: hostname %q is used by another host", h.Name, h.Hostname)
+		}
+		hostnames[hostname] = true
+		if h.User == "" || h.Token == "" {
+			return nil, fmt.Errorf("host %q: user and token must be set", h.Name)
+		}
+	}
+	return cfg.Hosts, nil
+}
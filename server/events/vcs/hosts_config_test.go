@@ -0,0 +1,111 @@
+package vcs_test
+
+import (
+	"testing"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/events/vcs/mocks"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestParseHostsConfig(t *testing.T) {
+	hosts, err := vcs.ParseHostsConfig([]byte(`
+hosts:
+- name: ghe-east
+  type: github
+  hostname: ghe-east.example.com
+  user: atlantis
+  token: token
+  webhook_secret: secret
+  repo_allowlist: team/*
+- name: gitlab
+  type: gitlab
+  hostname: gitlab.example.com
+  user: atlantis
+  token: token
+`))
+	Ok(t, err)
+	Equals(t, []vcs.HostConfig{
+		{
+			Name:          "ghe-east",
+			Type:          "github",
+			Hostname:      "ghe-east.example.com",
+			User:          "atlantis",
+			Token:         "token",
+			WebhookSecret: "secret",
+			RepoAllowlist: "team/*",
+		},
+		{
+			Name:     "gitlab",
+			Type:     "gitlab",
+			Hostname: "gitlab.example.com",
+			User:     "atlantis",
+			Token:    "token",
+		},
+	}, hosts)
+	Equals(t, models.Github, hosts[0].VCSHostType())
+	Equals(t, models.Gitlab, hosts[1].VCSHostType())
+}
+
+func TestParseHostsConfig_Invalid(t *testing.T) {
+	cases := []struct {
+		description string
+		config      string
+		expErr      string
+	}{
+		{
+			"missing name",
+			"hosts:\n- type: github\n  hostname: ghe.example.com\n  user: u\n  token: t",
+			"hosts[0]: name must be set",
+		},
+		{
+			"duplicate name",
+			"hosts:\n- name: a\n  type: github\n  hostname: a.example.com\n  user: u\n  token: t\n- name: a\n  type: github\n  hostname: b.example.com\n  user: u\n  token: t",
+			`host "a": name must be unique`,
+		},
+		{
+			"unsupported type",
+			"hosts:\n- name: a\n  type: bitbucket\n  hostname: a.example.com\n  user: u\n  token: t",
+			`host "a": type must be github or gitlab, got "bitbucket"`,
+		},
+		{
+			"hostname with scheme",
+			"hosts:\n- name: a\n  type: github\n  hostname: https://a.example.com\n  user: u\n  token: t",
+			`host "a": hostname must be set to a hostname only, ex. ghe.example.com`,
+		},
+		{
+			"duplicate hostname",
+			"hosts:\n- name: a\n  type: github\n  hostname: a.example.com\n  user: u\n  token: t\n- name: b\n  type: gitlab\n  hostname: A.example.com\n  user: u\n  token: t",
+			`host "b": hostname "A.example.com" is used by another host`,
+		},
+		{
+			"missing token",
+			"hosts:\n- name: a\n  type: github\n  hostname: a.example.com\n  user: u",
+			`host "a": user and token must be set`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			_, err := vcs.ParseHostsConfig([]byte(c.config))
+			ErrEquals(t, c.expErr, err)
+		})
+	}
+}
+
+func TestClientProxy_AddHost(t *testing.T) {
+	RegisterMockTestingT(t)
+	defaultClient := mocks.NewMockClient()
+	hostClient := mocks.NewMockClient()
+	proxy := vcs.NewClientProxy(defaultClient, nil, nil, nil, nil)
+	proxy.AddHost("GHE.example.com", hostClient)
+
+	hostRepo := models.Repo{VCSHost: models.VCSHost{Type: models.Github, Hostname: "ghe.example.com"}}
+	Ok(t, proxy.CreateComment(hostRepo, 1, "comment", ""))
+	hostClient.VerifyWasCalledOnce().CreateComment(hostRepo, 1, "comment", "")
+
+	defaultRepo := models.Repo{VCSHost: models.VCSHost{Type: models.Github, Hostname: "github.com"}}
+	Ok(t, proxy.CreateComment(defaultRepo, 1, "comment", ""))
+	defaultClient.VerifyWasCalledOnce().CreateComment(defaultRepo, 1, "comment", "")
+}
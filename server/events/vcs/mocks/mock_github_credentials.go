@@ -44,11 +44,11 @@ func (mock *MockGithubCredentials) Client() (*http.Client, error) {
 	return ret0, ret1
 }
 
-func (mock *MockGithubCredentials) GetToken() (string, error) {
+func (mock *MockGithubCredentials) GetToken(owner string) (string, error) {
 	if mock == nil {
 		panic("mock must not be nil. Use myMock := NewMockGithubCredentials().")
 	}
-	params := []pegomock.Param{}
+	params := []pegomock.Param{owner}
 	result := pegomock.GetGenericMockFrom(mock).Invoke("GetToken", params, []reflect.Type{reflect.TypeOf((*string)(nil)).Elem(), reflect.TypeOf((*error)(nil)).Elem()})
 	var ret0 string
 	var ret1 error
@@ -136,8 +136,8 @@ func (c *MockGithubCredentials_Client_OngoingVerification) GetCapturedArguments(
 func (c *MockGithubCredentials_Client_OngoingVerification) GetAllCapturedArguments() {
 }
 
-func (verifier *VerifierMockGithubCredentials) GetToken() *MockGithubCredentials_GetToken_OngoingVerification {
-	params := []pegomock.Param{}
+func (verifier *VerifierMockGithubCredentials) GetToken(owner string) *MockGithubCredentials_GetToken_OngoingVerification {
+	params := []pegomock.Param{owner}
 	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "GetToken", params, verifier.timeout)
 	return &MockGithubCredentials_GetToken_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
 }
@@ -147,10 +147,20 @@ type MockGithubCredentials_GetToken_OngoingVerification struct {
 	methodInvocations []pegomock.MethodInvocation
 }
 
-func (c *MockGithubCredentials_GetToken_OngoingVerification) GetCapturedArguments() {
+func (c *MockGithubCredentials_GetToken_OngoingVerification) GetCapturedArguments() string {
+	owner := c.GetAllCapturedArguments()
+	return owner[len(owner)-1]
 }
 
-func (c *MockGithubCredentials_GetToken_OngoingVerification) GetAllCapturedArguments() {
+func (c *MockGithubCredentials_GetToken_OngoingVerification) GetAllCapturedArguments() (_param0 []string) {
+	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
+	if len(params) > 0 {
+		_param0 = make([]string, len(c.methodInvocations))
+		for u, param := range params[0] {
+			_param0[u] = param.(string)
+		}
+	}
+	return
 }
 
 func (verifier *VerifierMockGithubCredentials) GetUser() *MockGithubCredentials_GetUser_OngoingVerification {
@@ -32,6 +32,9 @@ func (a *NotConfiguredVCSClient) GetModifiedFiles(repo models.Repo, pull models.
 func (a *NotConfiguredVCSClient) CreateComment(repo models.Repo, pullNum int, comment string, command string) error {
 	return a.err()
 }
+func (a *NotConfiguredVCSClient) CreateReply(repo models.Repo, pullNum int, discussionID string, comment string, command string) (int64, error) {
+	return 0, a.err()
+}
 func (a *NotConfiguredVCSClient) HidePrevCommandComments(repo models.Repo, pullNum int, command string) error {
 	return nil
 }
@@ -41,6 +44,9 @@ func (a *NotConfiguredVCSClient) PullIsApproved(repo models.Repo, pull models.Pu
 func (a *NotConfiguredVCSClient) PullIsMergeable(repo models.Repo, pull models.PullRequest) (bool, error) {
 	return false, a.err()
 }
+func (a *NotConfiguredVCSClient) PullIsClosed(repo models.Repo, pull models.PullRequest) (bool, error) {
+	return false, a.err()
+}
 func (a *NotConfiguredVCSClient) UpdateStatus(repo models.Repo, pull models.PullRequest, state models.CommitStatus, src string, description string, url string) error {
 	return a.err()
 }
@@ -61,3 +67,29 @@ func (a *NotConfiguredVCSClient) SupportsSingleFileDownload(repo models.Repo) bo
 func (a *NotConfiguredVCSClient) DownloadRepoConfigFile(pull models.PullRequest) (bool, []byte, error) {
 	return true, []byte{}, a.err()
 }
+
+func (a *NotConfiguredVCSClient) GetCloneURL(VCSHostType models.VCSHostType, repo string) (string, error) {
+	return "", a.err()
+}
+
+func (a *NotConfiguredVCSClient) UserIsTeamMember(repo models.Repo, user models.User, teams []string) (bool, error) {
+	return false, a.err()
+}
+func (a *NotConfiguredVCSClient) GetPullApprovers(repo models.Repo, pull models.PullRequest) ([]string, error) {
+	return nil, a.err()
+}
+func (a *NotConfiguredVCSClient) DownloadCodeowners(pull models.PullRequest) (bool, []byte, error) {
+	return false, []byte{}, a.err()
+}
+func (a *NotConfiguredVCSClient) SupportsCommentUpdates(repo models.Repo) bool {
+	return false
+}
+func (a *NotConfiguredVCSClient) ReactToComment(repo models.Repo, pullNum int, commentID int64, reaction string) error {
+	return a.err()
+}
+func (a *NotConfiguredVCSClient) CreateUpdatableComment(repo models.Repo, pullNum int, comment string) (int64, error) {
+	return 0, a.err()
+}
+func (a *NotConfiguredVCSClient) UpdateComment(repo models.Repo, pullNum int, commentID int64, comment string, command string) error {
+	return a.err()
+}
@@ -0,0 +1,9 @@
+package vcs
+
+// Pinger is implemented by the VCS clients that can check they're usable. It's
+// used by readiness checks. Azure DevOps doesn't support it.
+type Pinger interface {
+	// Ping makes an authenticated request to the VCS host and returns an error
+	// if it can't be reached or the client's credentials aren't valid.
+	Ping() error
+}
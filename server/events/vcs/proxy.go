@@ -14,6 +14,8 @@
 package vcs
 
 import (
+	"strings"
+
 	"github.com/runatlantis/atlantis/server/events/models"
 )
 
@@ -23,6 +25,9 @@ type ClientProxy struct {
 	// clients maps from the vcs host type to the client that implements the
 	// api for that host type, ex. github -> github client.
 	clients map[models.VCSHostType]Client
+	// hostClients maps from the hostname of additional VCS hosts to their
+	// clients. They take precedence over clients.
+	hostClients map[string]Client
 }
 
 func NewClientProxy(githubClient Client, gitlabClient Client, bitbucketCloudClient Client, bitbucketServerClient Client, azuredevopsClient Client) *ClientProxy {
@@ -52,42 +57,99 @@ func NewClientProxy(githubClient Client, gitlabClient Client, bitbucketCloudClie
 	}
 }
 
+// AddHost routes calls for repos on hostname to client instead of the
+// client for the host's type.
+func (d *ClientProxy) AddHost(hostname string, client Client) {
+	if d.hostClients == nil {
+		d.hostClients = make(map[string]Client)
+	}
+	d.hostClients[strings.ToLower(hostname)] = client
+}
+
+// client returns the client for the host repo is on.
+func (d *ClientProxy) client(repo models.Repo) Client {
+	if client, ok := d.hostClients[strings.ToLower(repo.VCSHost.Hostname)]; ok {
+		return client
+	}
+	return d.clients[repo.VCSHost.Type]
+}
+
 func (d *ClientProxy) GetModifiedFiles(repo models.Repo, pull models.PullRequest) ([]string, error) {
-	return d.clients[repo.VCSHost.Type].GetModifiedFiles(repo, pull)
+	return d.client(repo).GetModifiedFiles(repo, pull)
 }
 
 func (d *ClientProxy) CreateComment(repo models.Repo, pullNum int, comment string, command string) error {
-	return d.clients[repo.VCSHost.Type].CreateComment(repo, pullNum, comment, command)
+	return d.client(repo).CreateComment(repo, pullNum, comment, command)
+}
+
+func (d *ClientProxy) CreateReply(repo models.Repo, pullNum int, discussionID string, comment string, command string) (int64, error) {
+	return d.client(repo).CreateReply(repo, pullNum, discussionID, comment, command)
 }
 
 func (d *ClientProxy) HidePrevCommandComments(repo models.Repo, pullNum int, command string) error {
-	return d.clients[repo.VCSHost.Type].HidePrevCommandComments(repo, pullNum, command)
+	return d.client(repo).HidePrevCommandComments(repo, pullNum, command)
 }
 
 func (d *ClientProxy) PullIsApproved(repo models.Repo, pull models.PullRequest) (bool, error) {
-	return d.clients[repo.VCSHost.Type].PullIsApproved(repo, pull)
+	return d.client(repo).PullIsApproved(repo, pull)
 }
 
 func (d *ClientProxy) PullIsMergeable(repo models.Repo, pull models.PullRequest) (bool, error) {
-	return d.clients[repo.VCSHost.Type].PullIsMergeable(repo, pull)
+	return d.client(repo).PullIsMergeable(repo, pull)
+}
+
+func (d *ClientProxy) PullIsClosed(repo models.Repo, pull models.PullRequest) (bool, error) {
+	return d.client(repo).PullIsClosed(repo, pull)
 }
 
 func (d *ClientProxy) UpdateStatus(repo models.Repo, pull models.PullRequest, state models.CommitStatus, src string, description string, url string) error {
-	return d.clients[repo.VCSHost.Type].UpdateStatus(repo, pull, state, src, description, url)
+	return d.client(repo).UpdateStatus(repo, pull, state, src, description, url)
 }
 
 func (d *ClientProxy) MergePull(pull models.PullRequest, pullOptions models.PullRequestOptions) error {
-	return d.clients[pull.BaseRepo.VCSHost.Type].MergePull(pull, pullOptions)
+	return d.client(pull.BaseRepo).MergePull(pull, pullOptions)
 }
 
 func (d *ClientProxy) MarkdownPullLink(pull models.PullRequest) (string, error) {
-	return d.clients[pull.BaseRepo.VCSHost.Type].MarkdownPullLink(pull)
+	return d.client(pull.BaseRepo).MarkdownPullLink(pull)
 }
 
 func (d *ClientProxy) DownloadRepoConfigFile(pull models.PullRequest) (bool, []byte, error) {
-	return d.clients[pull.BaseRepo.VCSHost.Type].DownloadRepoConfigFile(pull)
+	return d.client(pull.BaseRepo).DownloadRepoConfigFile(pull)
 }
 
 func (d *ClientProxy) SupportsSingleFileDownload(repo models.Repo) bool {
-	return d.clients[repo.VCSHost.Type].SupportsSingleFileDownload(repo)
+	return d.client(repo).SupportsSingleFileDownload(repo)
+}
+
+func (d *ClientProxy) GetCloneURL(VCSHostType models.VCSHostType, repo string) (string, error) {
+	return d.clients[VCSHostType].GetCloneURL(VCSHostType, repo)
+}
+
+func (d *ClientProxy) UserIsTeamMember(repo models.Repo, user models.User, teams []string) (bool, error) {
+	return d.client(repo).UserIsTeamMember(repo, user, teams)
+}
+
+func (d *ClientProxy) GetPullApprovers(repo models.Repo, pull models.PullRequest) ([]string, error) {
+	return d.client(repo).GetPullApprovers(repo, pull)
+}
+
+func (d *ClientProxy) DownloadCodeowners(pull models.PullRequest) (bool, []byte, error) {
+	return d.client(pull.BaseRepo).DownloadCodeowners(pull)
+}
+
+func (d *ClientProxy) SupportsCommentUpdates(repo models.Repo) bool {
+	return d.client(repo).SupportsCommentUpdates(repo)
+}
+
+func (d *ClientProxy) ReactToComment(repo models.Repo, pullNum int, commentID int64, reaction string) error {
+	return d.client(repo).ReactToComment(repo, pullNum, commentID, reaction)
+}
+
+func (d *ClientProxy) CreateUpdatableComment(repo models.Repo, pullNum int, comment string) (int64, error) {
+	return d.client(repo).CreateUpdatableComment(repo, pullNum, comment)
+}
+
+func (d *ClientProxy) UpdateComment(repo models.Repo, pullNum int, commentID int64, comment string, command string) error {
+	return d.client(repo).UpdateComment(repo, pullNum, commentID, comment, command)
 }
@@ -0,0 +1,111 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// HTTPSignatureHeader is the header containing the HMAC-SHA256 of the payload
+// when the webhook has a secret, ex. "sha256=<hex digest>".
+const HTTPSignatureHeader = "X-Atlantis-Signature"
+
+// HTTPWebhook POSTs a JSON payload to an arbitrary URL.
+type HTTPWebhook struct {
+	Client         *http.Client
+	WorkspaceRegex *regexp.Regexp
+	URL            string
+	// Secret, if set, is used to sign the payload. The signature is sent in
+	// the HTTPSignatureHeader header.
+	Secret string
+}
+
+// HTTPPayload is the JSON body sent by HTTPWebhook.
+type HTTPPayload struct {
+	Event       string  `json:"event"`
+	Repo        string  `json:"repo"`
+	Pull        int     `json:"pull"`
+	PullURL     string  `json:"pull_url"`
+	User        string  `json:"user"`
+	Directory   string  `json:"directory"`
+	Workspace   string  `json:"workspace"`
+	ProjectName string  `json:"project_name"`
+	Success     bool    `json:"success"`
+	Duration    float64 `json:"duration_seconds"`
+	LogURL      string  `json:"log_url"`
+	AutoApply   bool    `json:"auto_apply"`
+}
+
+// NewHTTP returns an HTTPWebhook that posts to url for workspaces matching r.
+func NewHTTP(r *regexp.Regexp, url string, secret string) *HTTPWebhook {
+	return &HTTPWebhook{
+		Client:         &http.Client{Timeout: 10 * time.Second},
+		WorkspaceRegex: r,
+		URL:            url,
+		Secret:         secret,
+	}
+}
+
+// Send POSTs the result to h.URL if the workspace matches the regex. Any
+// non-2xx response is an error.
+func (h *HTTPWebhook) Send(log logging.SimpleLogging, applyResult ApplyResult) error {
+	if !h.WorkspaceRegex.MatchString(applyResult.Workspace) {
+		return nil
+	}
+	event := applyResult.Event
+	if event == "" {
+		event = ApplyEvent
+	}
+	body, err := json.Marshal(HTTPPayload{
+		Event:       event,
+		Repo:        applyResult.Repo.FullName,
+		Pull:        applyResult.Pull.Num,
+		PullURL:     applyResult.Pull.URL,
+		User:        applyResult.User.Username,
+		Directory:   applyResult.Directory,
+		Workspace:   applyResult.Workspace,
+		ProjectName: applyResult.ProjectName,
+		Success:     applyResult.Success,
+		Duration:    applyResult.Duration.Seconds(),
+		LogURL:      applyResult.LogURL,
+		AutoApply:   applyResult.AutoApply,
+	})
+	if err != nil {
+		return errors.Wrap(err, "serializing")
+	}
+	req, err := http.NewRequest("POST", h.URL, bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.Secret != "" {
+		req.Header.Set(HTTPSignatureHeader, Sign(body, h.Secret))
+	}
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "sending webhook to %s", h.URL)
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("sending webhook to %s: responded with status %d", h.URL, resp.StatusCode)
+	}
+	log.Debug("sent %s webhook to %s", event, h.URL)
+	return nil
+}
+
+// Sign returns the value of the HTTPSignatureHeader header for payload.
+func Sign(payload []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	// nolint: errcheck
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
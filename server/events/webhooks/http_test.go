@@ -0,0 +1,100 @@
+package webhooks_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/webhooks"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestHTTPWebhook_Send(t *testing.T) {
+	var body []byte
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		body, err = ioutil.ReadAll(r.Body)
+		Ok(t, err)
+		header = r.Header
+	}))
+	defer srv.Close()
+
+	hook := webhooks.NewHTTP(regexp.MustCompile(".*"), srv.URL, "mysecret")
+	err := hook.Send(logging.NewNoopLogger(t), webhooks.ApplyResult{
+		Event:       webhooks.PlanEvent,
+		Workspace:   "production",
+		Repo:        models.Repo{FullName: "runatlantis/atlantis"},
+		Pull:        models.PullRequest{Num: 1, URL: "https://github.com/runatlantis/atlantis/pull/1"},
+		User:        models.User{Username: "lkysow"},
+		Success:     true,
+		Directory:   "dir",
+		ProjectName: "project",
+		Duration:    1500 * time.Millisecond,
+		LogURL:      "https://atlantis.example.com/history?pr=1&repo=runatlantis%2Fatlantis",
+	})
+	Ok(t, err)
+
+	Equals(t, "application/json", header.Get("Content-Type"))
+	Equals(t, webhooks.Sign(body, "mysecret"), header.Get(webhooks.HTTPSignatureHeader))
+	var payload webhooks.HTTPPayload
+	Ok(t, json.Unmarshal(body, &payload))
+	Equals(t, webhooks.HTTPPayload{
+		Event:       "plan",
+		Repo:        "runatlantis/atlantis",
+		Pull:        1,
+		PullURL:     "https://github.com/runatlantis/atlantis/pull/1",
+		User:        "lkysow",
+		Directory:   "dir",
+		Workspace:   "production",
+		ProjectName: "project",
+		Success:     true,
+		Duration:    1.5,
+		LogURL:      "https://atlantis.example.com/history?pr=1&repo=runatlantis%2Fatlantis",
+	}, payload)
+}
+
+func TestHTTPWebhook_SendNoSecret(t *testing.T) {
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+	}))
+	defer srv.Close()
+
+	hook := webhooks.NewHTTP(regexp.MustCompile(".*"), srv.URL, "")
+	Ok(t, hook.Send(logging.NewNoopLogger(t), webhooks.ApplyResult{}))
+	Equals(t, "", header.Get(webhooks.HTTPSignatureHeader))
+}
+
+func TestHTTPWebhook_SendNoRegexMatch(t *testing.T) {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+
+	hook := webhooks.NewHTTP(regexp.MustCompile("production"), srv.URL, "")
+	Ok(t, hook.Send(logging.NewNoopLogger(t), webhooks.ApplyResult{Workspace: "staging"}))
+	Assert(t, !called, "expected webhook not to be sent")
+}
+
+func TestHTTPWebhook_SendErrStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	hook := webhooks.NewHTTP(regexp.MustCompile(".*"), srv.URL, "")
+	err := hook.Send(logging.NewNoopLogger(t), webhooks.ApplyResult{})
+	ErrEquals(t, "sending webhook to "+srv.URL+": responded with status 500", err)
+}
+
+func TestSign(t *testing.T) {
+	Equals(t, "sha256=eec7f1700cc1636c63d5f3d21e9cce5f5114b789bdfb7ce53468d155d8f2f9ba", webhooks.Sign([]byte(`{"key":"value"}`), "mysecret"))
+}
@@ -110,7 +110,12 @@ func (d *DefaultSlackClient) createAttachments(applyResult ApplyResult) []slack.
 		successWord = "failed"
 	}
 
-	text := fmt.Sprintf("Apply %s for <%s|%s>", successWord, applyResult.Pull.URL, applyResult.Repo.FullName)
+	// Auto-applies are called out since no one commented to start them.
+	command := "Apply"
+	if applyResult.AutoApply {
+		command = "Auto-apply"
+	}
+	text := fmt.Sprintf("%s %s for <%s|%s>", command, successWord, applyResult.Pull.URL, applyResult.Repo.FullName)
 	directory := applyResult.Directory
 	// Since "." looks weird, replace it with "/" to make it clear this is the root.
 	if directory == "." {
@@ -135,6 +135,14 @@ func TestPostMessage_Success(t *testing.T) {
 	err = client.PostMessage(channel, result)
 	Ok(t, err)
 	underlying.VerifyWasCalledOnce().PostMessage(channel, "", expParams)
+
+	t.Log("When the apply was an auto-apply, the message should say so")
+	result.AutoApply = true
+	expParams.Attachments[0].Text = "Auto-apply failed for <url|runatlantis/atlantis>"
+
+	err = client.PostMessage(channel, result)
+	Ok(t, err)
+	underlying.VerifyWasCalledOnce().PostMessage(channel, "", expParams)
 }
 
 func TestPostMessage_Error(t *testing.T) {
@@ -15,7 +15,9 @@ package webhooks
 
 import (
 	"fmt"
+	"net/url"
 	"regexp"
+	"time"
 
 	"errors"
 
@@ -24,7 +26,9 @@ import (
 )
 
 const SlackKind = "slack"
+const HTTPKind = "http"
 const ApplyEvent = "apply"
+const PlanEvent = "plan"
 
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_sender.go Sender
 
@@ -34,14 +38,27 @@ type Sender interface {
 	Send(log logging.SimpleLogging, applyResult ApplyResult) error
 }
 
-// ApplyResult is the result of a terraform apply.
+// ApplyResult is the result of a terraform apply or, for webhooks configured
+// with "event: plan", a terraform plan.
 type ApplyResult struct {
-	Workspace string
-	Repo      models.Repo
-	Pull      models.PullRequest
-	User      models.User
-	Success   bool
-	Directory string
+	// Event is the command that was run, ex. ApplyEvent or PlanEvent. If
+	// empty, the result is of an apply.
+	Event       string
+	Workspace   string
+	Repo        models.Repo
+	Pull        models.PullRequest
+	User        models.User
+	Success     bool
+	Directory   string
+	ProjectName string
+	// Duration is how long the command took to run.
+	Duration time.Duration
+	// LogURL is a link to the Atlantis page showing the command's output.
+	// It may be empty.
+	LogURL string
+	// AutoApply is true if the apply was started by the repo's auto_apply
+	// config rather than a comment.
+	AutoApply bool
 }
 
 // MultiWebhookSender sends multiple webhooks for each one it's configured for.
@@ -54,6 +71,28 @@ type Config struct {
 	WorkspaceRegex string
 	Kind           string
 	Channel        string
+	// URL is the URL to POST to. It only applies to http webhooks.
+	URL string
+	// Secret, if set, is used to sign http webhook payloads.
+	Secret string
+}
+
+// eventSender wraps a Sender so it's only sent results for Event.
+type eventSender struct {
+	Event  string
+	Sender Sender
+}
+
+// Send sends the webhook if applyResult is for s.Event.
+func (s *eventSender) Send(log logging.SimpleLogging, applyResult ApplyResult) error {
+	event := applyResult.Event
+	if event == "" {
+		event = ApplyEvent
+	}
+	if event != s.Event {
+		return nil
+	}
+	return s.Sender.Send(log, applyResult)
 }
 
 func NewMultiWebhookSender(configs []Config, client SlackClient) (*MultiWebhookSender, error) {
@@ -66,11 +105,14 @@ func NewMultiWebhookSender(configs []Config, client SlackClient) (*MultiWebhookS
 		if c.Kind == "" || c.Event == "" {
 			return nil, errors.New("must specify \"kind\" and \"event\" keys for webhooks")
 		}
-		if c.Event != ApplyEvent {
-			return nil, fmt.Errorf("\"event: %s\" not supported. Only \"event: %s\" is supported right now", c.Event, ApplyEvent)
+		if c.Event != ApplyEvent && c.Event != PlanEvent {
+			return nil, fmt.Errorf("\"event: %s\" not supported. Only \"event: %s\" and \"event: %s\" are supported right now", c.Event, ApplyEvent, PlanEvent)
 		}
 		switch c.Kind {
 		case SlackKind:
+			if c.Event != ApplyEvent {
+				return nil, fmt.Errorf("\"event: %s\" not supported for webhooks of \"kind: slack\". Only \"event: %s\" is supported", c.Event, ApplyEvent)
+			}
 			if !client.TokenIsSet() {
 				return nil, errors.New("must specify top-level \"slack-token\" if using a webhook of \"kind: slack\"")
 			}
@@ -81,9 +123,18 @@ func NewMultiWebhookSender(configs []Config, client SlackClient) (*MultiWebhookS
 			if err != nil {
 				return nil, err
 			}
-			webhooks = append(webhooks, slack)
+			webhooks = append(webhooks, &eventSender{Event: c.Event, Sender: slack})
+		case HTTPKind:
+			if c.URL == "" {
+				return nil, errors.New("must specify \"url\" if using a webhook of \"kind: http\"")
+			}
+			u, err := url.Parse(c.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				return nil, fmt.Errorf("\"url: %s\" must be an absolute http:// or https:// URL", c.URL)
+			}
+			webhooks = append(webhooks, &eventSender{Event: c.Event, Sender: NewHTTP(r, c.URL, c.Secret)})
 		default:
-			return nil, fmt.Errorf("\"kind: %s\" not supported. Only \"kind: %s\" is supported right now", c.Kind, SlackKind)
+			return nil, fmt.Errorf("\"kind: %s\" not supported. Only \"kind: %s\" and \"kind: %s\" are supported right now", c.Kind, SlackKind, HTTPKind)
 		}
 	}
 
@@ -96,7 +147,7 @@ func NewMultiWebhookSender(configs []Config, client SlackClient) (*MultiWebhookS
 func (w *MultiWebhookSender) Send(log logging.SimpleLogging, result ApplyResult) error {
 	for _, w := range w.Webhooks {
 		if err := w.Send(log, result); err != nil {
-			log.Warn("error sending slack webhook: %s", err)
+			log.Warn("error sending webhook: %s", err)
 		}
 	}
 	return nil
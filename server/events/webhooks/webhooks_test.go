@@ -14,6 +14,9 @@
 package webhooks_test
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
@@ -78,7 +81,7 @@ func TestNewWebhooksManager_UnsupportedEvent(t *testing.T) {
 	configs[0].Event = unsupportedEvent
 	_, err := webhooks.NewMultiWebhookSender(configs, client)
 	Assert(t, err != nil, "expected error")
-	Equals(t, "\"event: badevent\" not supported. Only \"event: apply\" is supported right now", err.Error())
+	Equals(t, "\"event: badevent\" not supported. Only \"event: apply\" and \"event: plan\" are supported right now", err.Error())
 }
 
 func TestNewWebhooksManager_NoKind(t *testing.T) {
@@ -103,7 +106,68 @@ func TestNewWebhooksManager_UnsupportedKind(t *testing.T) {
 	configs[0].Kind = unsupportedKind
 	_, err := webhooks.NewMultiWebhookSender(configs, client)
 	Assert(t, err != nil, "expected error")
-	Equals(t, "\"kind: badkind\" not supported. Only \"kind: slack\" is supported right now", err.Error())
+	Equals(t, "\"kind: badkind\" not supported. Only \"kind: slack\" and \"kind: http\" are supported right now", err.Error())
+}
+
+func TestNewWebhooksManager_SlackPlanEvent(t *testing.T) {
+	t.Log("Slack webhooks only support apply events")
+	RegisterMockTestingT(t)
+	client := mocks.NewMockSlackClient()
+	When(client.TokenIsSet()).ThenReturn(true)
+	When(client.ChannelExists(validChannel)).ThenReturn(true, nil)
+
+	configs := validConfigs()
+	configs[0].Event = webhooks.PlanEvent
+	_, err := webhooks.NewMultiWebhookSender(configs, client)
+	ErrEquals(t, "\"event: plan\" not supported for webhooks of \"kind: slack\". Only \"event: apply\" is supported", err)
+}
+
+func TestNewWebhooksManager_HTTPConfig(t *testing.T) {
+	cases := []struct {
+		description string
+		url         string
+		expErr      string
+	}{
+		{
+			"no url",
+			"",
+			"must specify \"url\" if using a webhook of \"kind: http\"",
+		},
+		{
+			"relative url",
+			"/events",
+			"\"url: /events\" must be an absolute http:// or https:// URL",
+		},
+		{
+			"bad scheme",
+			"ftp://example.com",
+			"\"url: ftp://example.com\" must be an absolute http:// or https:// URL",
+		},
+		{
+			"valid",
+			"https://example.com/events",
+			"",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			configs := []webhooks.Config{
+				{
+					Event:          webhooks.PlanEvent,
+					WorkspaceRegex: validRegex,
+					Kind:           webhooks.HTTPKind,
+					URL:            c.url,
+				},
+			}
+			m, err := webhooks.NewMultiWebhookSender(configs, nil)
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			Equals(t, 1, len(m.Webhooks))
+		})
+	}
 }
 
 func TestNewWebhooksManager_NoConfigSuccess(t *testing.T) {
@@ -163,6 +227,33 @@ func TestSend_SingleSuccess(t *testing.T) {
 	sender.VerifyWasCalledOnce().Send(logger, result)
 }
 
+func TestSend_FiltersByEvent(t *testing.T) {
+	t.Log("Webhooks should only be sent results for their configured event")
+	var received []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload webhooks.HTTPPayload
+		Ok(t, json.NewDecoder(r.Body).Decode(&payload))
+		received = append(received, payload.Event)
+	}))
+	defer srv.Close()
+
+	configs := []webhooks.Config{
+		{
+			Event:          webhooks.PlanEvent,
+			WorkspaceRegex: validRegex,
+			Kind:           webhooks.HTTPKind,
+			URL:            srv.URL,
+		},
+	}
+	m, err := webhooks.NewMultiWebhookSender(configs, nil)
+	Ok(t, err)
+	logger := logging.NewNoopLogger(t)
+	Ok(t, m.Send(logger, webhooks.ApplyResult{Event: webhooks.ApplyEvent}))
+	Ok(t, m.Send(logger, webhooks.ApplyResult{}))
+	Ok(t, m.Send(logger, webhooks.ApplyResult{Event: webhooks.PlanEvent}))
+	Equals(t, []string{"plan"}, received)
+}
+
 func TestSend_MultipleSuccess(t *testing.T) {
 	t.Log("Sending multiple webhooks should succeed")
 	RegisterMockTestingT(t)
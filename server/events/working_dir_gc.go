@@ -0,0 +1,246 @@
+package events
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/core/locking"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// WorkingDirGC periodically deletes the working dirs, locks and plans of pull
+// requests that are closed or haven't been used in a long time. It catches
+// pulls whose close event Atlantis never received, ex. because the webhook
+// was missed or the repo was removed.
+type WorkingDirGC struct {
+	DataDir string
+	// MaxAge is how long a pull's working dir can go unmodified before it's
+	// deleted even if the pull is still open. If it's 0, only the working
+	// dirs of closed pulls are deleted.
+	MaxAge time.Duration
+	// VCSClient is used to check whether pulls are closed. Pulls that Atlantis
+	// has no locks or status for can't be looked up and are only deleted
+	// once they're older than MaxAge.
+	VCSClient        vcs.Client
+	PullCleaner      PullCleaner
+	WorkingDirLocker WorkingDirLocker
+	DB               locking.Backend
+	Logger           logging.SimpleLogging
+
+	mutex sync.Mutex
+	stats WorkingDirGCStats
+}
+
+// WorkingDirGCStats are the totals across all the runs of a WorkingDirGC.
+type WorkingDirGCStats struct {
+	Runs           int
+	LastRun        time.Time
+	PullsCleaned   int
+	ReclaimedBytes int64
+}
+
+// pullDir is a pull request's directory under the data dir.
+type pullDir struct {
+	repoFullName string
+	pullNum      int
+	path         string
+}
+
+// Start runs a collection every interval until stop is closed.
+func (g *WorkingDirGC) Start(interval time.Duration, stop <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				g.Collect()
+			}
+		}
+	}()
+}
+
+// Collect scans the data dir once and cleans up every pull that is closed or
+// older than MaxAge.
+func (g *WorkingDirGC) Collect() {
+	dirs, err := g.findPullDirs()
+	if err != nil {
+		g.Logger.Err("finding working dirs to garbage collect: %s", err)
+		return
+	}
+	pulls, err := g.knownPulls()
+	if err != nil {
+		g.Logger.Err("listing pulls to garbage collect: %s", err)
+		return
+	}
+
+	cleaned := 0
+	var reclaimed int64
+	for _, dir := range dirs {
+		pull, known := pulls[pullKey(dir.repoFullName, dir.pullNum)]
+		if !known {
+			pull = models.PullRequest{
+				Num:      dir.pullNum,
+				BaseRepo: models.Repo{FullName: dir.repoFullName},
+			}
+		}
+		reason := g.collectReason(dir, pull, known)
+		if reason == "" {
+			continue
+		}
+
+		unlockFn, err := g.WorkingDirLocker.TryLockPull(dir.repoFullName, dir.pullNum)
+		if err != nil {
+			g.Logger.Debug("skipping garbage collection of %s#%d: working dir is in use", dir.repoFullName, dir.pullNum)
+			continue
+		}
+		size := dirSize(dir.path)
+		err = g.PullCleaner.CleanUpPull(pull.BaseRepo, pull)
+		unlockFn()
+		if err != nil {
+			g.Logger.Err("garbage collecting %s#%d: %s", dir.repoFullName, dir.pullNum, err)
+			continue
+		}
+		g.Logger.Info("garbage collected working dir of %s pull %s#%d, reclaimed %d bytes", reason, dir.repoFullName, dir.pullNum, size)
+		cleaned++
+		reclaimed += size
+	}
+
+	g.mutex.Lock()
+	g.stats.Runs++
+	g.stats.LastRun = time.Now()
+	g.stats.PullsCleaned += cleaned
+	g.stats.ReclaimedBytes += reclaimed
+	g.mutex.Unlock()
+}
+
+// GetStats returns the totals across all runs so far.
+func (g *WorkingDirGC) GetStats() WorkingDirGCStats {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.stats
+}
+
+// collectReason returns why dir should be cleaned up, ex. closed, or an empty
+// string if it should be kept.
+func (g *WorkingDirGC) collectReason(dir pullDir, pull models.PullRequest, known bool) string {
+	if known {
+		closed, err := g.VCSClient.PullIsClosed(pull.BaseRepo, pull)
+		if err != nil {
+			g.Logger.Warn("checking if %s#%d is closed: %s", dir.repoFullName, dir.pullNum, err)
+		} else if closed {
+			return "closed"
+		}
+	}
+	if g.MaxAge > 0 && time.Since(lastModified(dir.path)) > g.MaxAge {
+		return "inactive"
+	}
+	return ""
+}
+
+// knownPulls returns the pulls Atlantis has locks or a status for, keyed by
+// pullKey.
+func (g *WorkingDirGC) knownPulls() (map[string]models.PullRequest, error) {
+	pulls := make(map[string]models.PullRequest)
+	statuses, err := g.DB.ListPullStatuses()
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range statuses {
+		pulls[pullKey(s.Pull.BaseRepo.FullName, s.Pull.Num)] = s.Pull
+	}
+	locks, err := g.DB.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range locks {
+		pulls[pullKey(l.Pull.BaseRepo.FullName, l.Pull.Num)] = l.Pull
+	}
+	return pulls, nil
+}
+
+// findPullDirs returns the pull dirs under the data dir. Pull dirs are found
+// by their workspaces, which are git clones, because repo full names can
+// contain any number of slashes, ex. GitLab subgroups.
+func (g *WorkingDirGC) findPullDirs() ([]pullDir, error) {
+	reposDir := filepath.Join(g.DataDir, workingDirPrefix)
+	seen := make(map[string]bool)
+	var dirs []pullDir
+	err := filepath.Walk(reposDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == reposDir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if _, err := os.Stat(filepath.Join(path, ".git")); err != nil {
+			return nil
+		}
+		// path is a workspace so its parent is the pull dir.
+		pullPath := filepath.Dir(path)
+		if seen[pullPath] {
+			return filepath.SkipDir
+		}
+		seen[pullPath] = true
+		rel, err := filepath.Rel(reposDir, pullPath)
+		if err != nil {
+			return err
+		}
+		num, err := strconv.Atoi(filepath.Base(rel))
+		if err != nil || filepath.Dir(rel) == "." {
+			return filepath.SkipDir
+		}
+		dirs = append(dirs, pullDir{
+			repoFullName: filepath.ToSlash(filepath.Dir(rel)),
+			pullNum:      num,
+			path:         pullPath,
+		})
+		return filepath.SkipDir
+	})
+	return dirs, err
+}
+
+func pullKey(repoFullName string, pullNum int) string {
+	return repoFullName + "/" + strconv.Itoa(pullNum)
+}
+
+// lastModified returns when dir or any of its workspaces were last modified.
+func lastModified(dir string) time.Time {
+	var latest time.Time
+	if info, err := os.Stat(dir); err == nil {
+		latest = info.ModTime()
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return latest
+	}
+	for _, info := range infos {
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
+
+// dirSize returns the total size of the files under dir.
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error { // nolint: errcheck
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
@@ -0,0 +1,130 @@
+package events_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/core/db"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/mocks"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/models/fixtures"
+	vcsmocks "github.com/runatlantis/atlantis/server/events/vcs/mocks"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// mkWorkspace creates a fake clone for the pull's default workspace and sets
+// its modification time to modTime.
+func mkWorkspace(t *testing.T, dataDir string, repoFullName string, pullNum string, modTime time.Time) string {
+	pullDir := filepath.Join(dataDir, "repos", repoFullName, pullNum)
+	workspaceDir := filepath.Join(pullDir, "default")
+	Ok(t, os.MkdirAll(filepath.Join(workspaceDir, ".git"), 0700))
+	Ok(t, ioutil.WriteFile(filepath.Join(workspaceDir, "main.tf"), []byte("resource"), 0600))
+	Ok(t, os.Chtimes(filepath.Join(workspaceDir, ".git"), modTime, modTime))
+	Ok(t, os.Chtimes(filepath.Join(workspaceDir, "main.tf"), modTime, modTime))
+	Ok(t, os.Chtimes(workspaceDir, modTime, modTime))
+	Ok(t, os.Chtimes(pullDir, modTime, modTime))
+	return pullDir
+}
+
+func TestWorkingDirGC_Collect(t *testing.T) {
+	RegisterMockTestingT(t)
+	dataDir, cleanup := TempDir(t)
+	defer cleanup()
+	backend, err := db.New(dataDir)
+	Ok(t, err)
+
+	openPull := fixtures.Pull
+	openPull.BaseRepo = fixtures.GithubRepo
+	closedPull := openPull
+	closedPull.Num = 2
+	for _, p := range []models.PullRequest{openPull, closedPull} {
+		_, err = backend.UpdatePullWithResults(p, []models.ProjectResult{{RepoRelDir: ".", Workspace: "default"}})
+		Ok(t, err)
+	}
+
+	old := time.Now().Add(-48 * time.Hour)
+	mkWorkspace(t, dataDir, "runatlantis/atlantis", "1", time.Now())
+	mkWorkspace(t, dataDir, "runatlantis/atlantis", "2", time.Now())
+	mkWorkspace(t, dataDir, "group/subgroup/repo", "3", old)
+	mkWorkspace(t, dataDir, "owner/unknown", "4", time.Now())
+
+	vcsClient := vcsmocks.NewMockClient()
+	When(vcsClient.PullIsClosed(fixtures.GithubRepo, openPull)).ThenReturn(false, nil)
+	When(vcsClient.PullIsClosed(fixtures.GithubRepo, closedPull)).ThenReturn(true, nil)
+	cleaner := mocks.NewMockPullCleaner()
+	gc := &events.WorkingDirGC{
+		DataDir:          dataDir,
+		MaxAge:           24 * time.Hour,
+		VCSClient:        vcsClient,
+		PullCleaner:      cleaner,
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+		DB:               backend,
+		Logger:           logging.NewNoopLogger(t),
+	}
+	gc.Collect()
+
+	cleaner.VerifyWasCalledOnce().CleanUpPull(fixtures.GithubRepo, closedPull)
+	cleaner.VerifyWasCalledOnce().CleanUpPull(models.Repo{FullName: "group/subgroup/repo"}, models.PullRequest{
+		Num:      3,
+		BaseRepo: models.Repo{FullName: "group/subgroup/repo"},
+	})
+	cleaner.VerifyWasCalled(Times(2)).CleanUpPull(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest())
+
+	stats := gc.GetStats()
+	Equals(t, 1, stats.Runs)
+	Equals(t, 2, stats.PullsCleaned)
+	Equals(t, int64(2*len("resource")), stats.ReclaimedBytes)
+}
+
+func TestWorkingDirGC_CollectSkipsLockedPulls(t *testing.T) {
+	RegisterMockTestingT(t)
+	dataDir, cleanup := TempDir(t)
+	defer cleanup()
+	backend, err := db.New(dataDir)
+	Ok(t, err)
+	mkWorkspace(t, dataDir, "owner/repo", "1", time.Now().Add(-48*time.Hour))
+
+	locker := events.NewDefaultWorkingDirLocker()
+	unlockFn, err := locker.TryLockPull("owner/repo", 1)
+	Ok(t, err)
+	defer unlockFn()
+	cleaner := mocks.NewMockPullCleaner()
+	gc := &events.WorkingDirGC{
+		DataDir:          dataDir,
+		MaxAge:           24 * time.Hour,
+		VCSClient:        vcsmocks.NewMockClient(),
+		PullCleaner:      cleaner,
+		WorkingDirLocker: locker,
+		DB:               backend,
+		Logger:           logging.NewNoopLogger(t),
+	}
+	gc.Collect()
+
+	cleaner.VerifyWasCalled(Never()).CleanUpPull(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest())
+	Equals(t, 0, gc.GetStats().PullsCleaned)
+}
+
+func TestWorkingDirGC_CollectNoReposDir(t *testing.T) {
+	RegisterMockTestingT(t)
+	dataDir, cleanup := TempDir(t)
+	defer cleanup()
+	backend, err := db.New(dataDir)
+	Ok(t, err)
+	gc := &events.WorkingDirGC{
+		DataDir:          dataDir,
+		VCSClient:        vcsmocks.NewMockClient(),
+		PullCleaner:      mocks.NewMockPullCleaner(),
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+		DB:               backend,
+		Logger:           logging.NewNoopLogger(t),
+	}
+	gc.Collect()
+	Equals(t, 1, gc.GetStats().Runs)
+}
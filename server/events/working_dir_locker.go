@@ -14,9 +14,13 @@
 package events
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"sync"
+	"time"
+
+	"github.com/pkg/errors"
 )
 
 //go:generate pegomock generate --use-experimental-model-gen --package mocks -o mocks/mock_working_dir_locker.go WorkingDirLocker
@@ -38,10 +42,19 @@ type WorkingDirLocker interface {
 	// an error if the workspace is already locked. The error is expected to
 	// be printed to the pull request.
 	TryLockPull(repoFullName string, pullNum int) (func(), error)
+	// CancelWaiting makes the commands waiting for a lock in this repo and
+	// pull give up. It's called when the pull request is closed.
+	CancelWaiting(repoFullName string, pullNum int)
 }
 
 // DefaultWorkingDirLocker implements WorkingDirLocker.
 type DefaultWorkingDirLocker struct {
+	// Timeout is how long TryLock and TryLockPull wait for a locked working
+	// dir to be unlocked before failing. Commands waiting for the same working
+	// dir get it in the order they started waiting. If 0, they fail
+	// immediately.
+	Timeout time.Duration
+
 	// mutex prevents against multiple threads calling functions on this struct
 	// concurrently. It's only used for entry/exit to each function.
 	mutex sync.Mutex
@@ -49,6 +62,21 @@ type DefaultWorkingDirLocker struct {
 	// matching to determine if something is locked. It's naive but that's okay
 	// because there won't be many locks at one time.
 	locks []string
+	// waiters are the lock requests waiting for their keys to be unlocked, in
+	// the order they started waiting.
+	waiters []*workingDirWaiter
+}
+
+// workingDirWaiter is a lock request waiting for its key to be unlocked.
+type workingDirWaiter struct {
+	key     string
+	pullKey string
+	// acquired is closed once key has been locked for the waiter.
+	acquired chan struct{}
+	cancel   context.CancelFunc
+	// closed is true if the waiter was cancelled because its pull request
+	// was closed.
+	closed bool
 }
 
 // NewDefaultWorkingDirLocker is a constructor.
@@ -57,40 +85,150 @@ func NewDefaultWorkingDirLocker() *DefaultWorkingDirLocker {
 }
 
 func (d *DefaultWorkingDirLocker) TryLockPull(repoFullName string, pullNum int) (func(), error) {
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
-
 	pullKey := d.pullKey(repoFullName, pullNum)
-	for _, l := range d.locks {
-		if l == pullKey || strings.HasPrefix(l, pullKey+"/") {
-			return func() {}, fmt.Errorf("The Atlantis working dir is currently locked by another" +
-				" command that is running for this pull request.\n" +
-				"Wait until the previous command is complete and try again.")
-		}
+	err := d.lock(pullKey, pullKey)
+	if err == errPullClosed {
+		return func() {}, err
+	}
+	if err != nil {
+		return func() {}, fmt.Errorf("The Atlantis working dir is currently locked by another" +
+			" command that is running for this pull request.\n" +
+			"Wait until the previous command is complete and try again.")
 	}
-	d.locks = append(d.locks, pullKey)
 	return func() {
 		d.UnlockPull(repoFullName, pullNum)
 	}, nil
 }
 
 func (d *DefaultWorkingDirLocker) TryLock(repoFullName string, pullNum int, workspace string) (func(), error) {
+	pullKey := d.pullKey(repoFullName, pullNum)
+	workspaceKey := d.workspaceKey(repoFullName, pullNum, workspace)
+	err := d.lock(workspaceKey, pullKey)
+	if err == errPullClosed {
+		return func() {}, err
+	}
+	if err != nil {
+		return func() {}, fmt.Errorf("The %s workspace is currently locked by another"+
+			" command that is running for this pull request.\n"+
+			"Wait until the previous command is complete and try again.", workspace)
+	}
+	return func() {
+		d.unlock(repoFullName, pullNum, workspace)
+	}, nil
+}
+
+// CancelWaiting cancels the lock requests waiting in this repo and pull.
+func (d *DefaultWorkingDirLocker) CancelWaiting(repoFullName string, pullNum int) {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
 
 	pullKey := d.pullKey(repoFullName, pullNum)
-	workspaceKey := d.workspaceKey(repoFullName, pullNum, workspace)
+	for _, w := range d.waiters {
+		if w.pullKey == pullKey {
+			w.closed = true
+			w.cancel()
+		}
+	}
+}
+
+// errPullClosed is returned to lock requests that were waiting when their
+// pull request was closed.
+var errPullClosed = errors.New("The pull request was closed while this command was waiting for the Atlantis working dir.")
+
+// errLocked is returned by lock when key is locked.
+var errLocked = errors.New("locked")
+
+// lock locks key, waiting up to d.Timeout for it to be unlocked if it's
+// locked. It returns errLocked if key is still locked after that or
+// errPullClosed if CancelWaiting is called for its pull while it's waiting.
+func (d *DefaultWorkingDirLocker) lock(key string, pullKey string) error {
+	d.mutex.Lock()
+	if !d.isLocked(key, len(d.waiters)) {
+		d.locks = append(d.locks, key)
+		d.mutex.Unlock()
+		return nil
+	}
+	if d.Timeout == 0 {
+		d.mutex.Unlock()
+		return errLocked
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), d.Timeout)
+	defer cancel()
+	w := &workingDirWaiter{
+		key:      key,
+		pullKey:  pullKey,
+		acquired: make(chan struct{}),
+		cancel:   cancel,
+	}
+	d.waiters = append(d.waiters, w)
+	d.mutex.Unlock()
+
+	select {
+	case <-w.acquired:
+		return nil
+	case <-ctx.Done():
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	// The key may have been locked for us just as we timed out.
+	select {
+	case <-w.acquired:
+		return nil
+	default:
+	}
+	for i, other := range d.waiters {
+		if other == w {
+			d.waiters = append(d.waiters[:i], d.waiters[i+1:]...)
+			break
+		}
+	}
+	// The waiters behind us may not have to wait anymore.
+	d.lockForWaiters()
+	if w.closed {
+		return errPullClosed
+	}
+	return errLocked
+}
+
+// isLocked returns true if key conflicts with a held lock or with one of the
+// first numWaiters waiters. Checking the waiters stops new and later requests
+// from jumping the queue. Callers must hold d.mutex.
+func (d *DefaultWorkingDirLocker) isLocked(key string, numWaiters int) bool {
 	for _, l := range d.locks {
-		if l == pullKey || l == workspaceKey {
-			return func() {}, fmt.Errorf("The %s workspace is currently locked by another"+
-				" command that is running for this pull request.\n"+
-				"Wait until the previous command is complete and try again.", workspace)
+		if d.keysConflict(key, l) {
+			return true
 		}
 	}
-	d.locks = append(d.locks, workspaceKey)
-	return func() {
-		d.unlock(repoFullName, pullNum, workspace)
-	}, nil
+	for _, w := range d.waiters[:numWaiters] {
+		if d.keysConflict(key, w.key) {
+			return true
+		}
+	}
+	return false
+}
+
+// keysConflict returns true if a and b can't be locked at the same time,
+// either because they're the same key or because one is the pull key of the
+// other.
+func (d *DefaultWorkingDirLocker) keysConflict(a string, b string) bool {
+	return a == b || strings.HasPrefix(a, b+"/") || strings.HasPrefix(b, a+"/")
+}
+
+// lockForWaiters locks the keys of the waiters that no longer conflict with
+// the held locks or with the waiters ahead of them. Callers must hold
+// d.mutex.
+func (d *DefaultWorkingDirLocker) lockForWaiters() {
+	var stillWaiting []*workingDirWaiter
+	for i, w := range d.waiters {
+		if d.isLocked(w.key, i) {
+			stillWaiting = append(stillWaiting, w)
+			continue
+		}
+		d.locks = append(d.locks, w.key)
+		close(w.acquired)
+	}
+	d.waiters = stillWaiting
 }
 
 // Unlock unlocks the workspace for this pull.
@@ -119,6 +257,7 @@ func (d *DefaultWorkingDirLocker) removeLock(key string) {
 		}
 	}
 	d.locks = newLocks
+	d.lockForWaiters()
 }
 
 func (d *DefaultWorkingDirLocker) workspaceKey(repo string, pull int, workspace string) string {
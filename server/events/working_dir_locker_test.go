@@ -15,6 +15,7 @@ package events_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/runatlantis/atlantis/server/events"
 	. "github.com/runatlantis/atlantis/testing"
@@ -196,3 +197,101 @@ func TestLockPull_WorkspaceFirst(t *testing.T) {
 	_, err = locker.TryLockPull("owner/repo", 1)
 	Ok(t, err)
 }
+
+// waitForQueue gives the goroutines started by a test time to start waiting
+// for their locks.
+func waitForQueue() {
+	time.Sleep(50 * time.Millisecond)
+}
+
+func TestTryLock_WaitsForUnlock(t *testing.T) {
+	locker := events.NewDefaultWorkingDirLocker()
+	locker.Timeout = 5 * time.Second
+	unlock, err := locker.TryLock(repo, 1, workspace)
+	Ok(t, err)
+
+	errs := make(chan error)
+	go func() {
+		_, err := locker.TryLock(repo, 1, workspace)
+		errs <- err
+	}()
+	waitForQueue()
+	unlock()
+	Ok(t, <-errs)
+}
+
+func TestTryLock_Timeout(t *testing.T) {
+	locker := events.NewDefaultWorkingDirLocker()
+	locker.Timeout = 10 * time.Millisecond
+	_, err := locker.TryLock(repo, 1, workspace)
+	Ok(t, err)
+
+	_, err = locker.TryLock(repo, 1, workspace)
+	ErrEquals(t, "The default workspace is currently locked by another"+
+		" command that is running for this pull request.\n"+
+		"Wait until the previous command is complete and try again.", err)
+
+	// A request that timed out shouldn't block later requests.
+	_, err = locker.TryLock(repo, 1, "other")
+	Ok(t, err)
+}
+
+// Requests should get their locks in the order they started waiting, even if
+// a later request's workspace is unlocked first.
+func TestTryLock_WaitersAreFair(t *testing.T) {
+	locker := events.NewDefaultWorkingDirLocker()
+	locker.Timeout = 5 * time.Second
+	unlockWorkspace, err := locker.TryLock(repo, 1, workspace)
+	Ok(t, err)
+
+	acquired := make(chan string)
+	unlockPull := make(chan func())
+	go func() {
+		unlock, err := locker.TryLockPull(repo, 1)
+		if err != nil {
+			acquired <- err.Error()
+			return
+		}
+		acquired <- "pull"
+		unlockPull <- unlock
+	}()
+	waitForQueue()
+	go func() {
+		if _, err := locker.TryLock(repo, 1, "other"); err != nil {
+			acquired <- err.Error()
+			return
+		}
+		acquired <- "other"
+	}()
+	waitForQueue()
+
+	unlockWorkspace()
+	Equals(t, "pull", <-acquired)
+	(<-unlockPull)()
+	Equals(t, "other", <-acquired)
+}
+
+func TestTryLock_CancelWaiting(t *testing.T) {
+	locker := events.NewDefaultWorkingDirLocker()
+	locker.Timeout = 5 * time.Second
+	_, err := locker.TryLock(repo, 1, workspace)
+	Ok(t, err)
+
+	errs := make(chan error, 2)
+	go func() {
+		_, err := locker.TryLock(repo, 1, workspace)
+		errs <- err
+	}()
+	go func() {
+		_, err := locker.TryLockPull(repo, 1)
+		errs <- err
+	}()
+	waitForQueue()
+
+	// Cancelling a different pull shouldn't affect the waiters.
+	locker.CancelWaiting(repo, 2)
+	locker.CancelWaiting(repo, 1)
+	for i := 0; i < 2; i++ {
+		ErrEquals(t, "The pull request was closed while this command was waiting for the Atlantis working dir.", <-errs)
+	}
+}
@@ -6,10 +6,13 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/runatlantis/atlantis/server/events"
 	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 	"github.com/runatlantis/atlantis/server/logging"
 	. "github.com/runatlantis/atlantis/testing"
 )
@@ -52,6 +55,19 @@ func TestClone_NoneExisting(t *testing.T) {
 	Equals(t, expCommit, actCommit)
 }
 
+// The dir the pull request is cloned into can't also be a workspace.
+func TestClone_ReservedWorkspace(t *testing.T) {
+	dataDir, cleanup := TempDir(t)
+	defer cleanup()
+
+	wd := &events.FileWorkspace{DataDir: dataDir}
+	_, _, err := wd.Clone(logging.NewNoopLogger(t), models.Repo{}, models.PullRequest{
+		BaseRepo:   models.Repo{},
+		HeadBranch: "branch",
+	}, ".atlantis-clone")
+	ErrEquals(t, `workspace ".atlantis-clone" is reserved by Atlantis`, err)
+}
+
 // Test that if we don't have any existing files, we check out the repo
 // successfully when we're using the merge method.
 func TestClone_CheckoutMergeNoneExisting(t *testing.T) {
@@ -110,6 +126,272 @@ func TestClone_CheckoutMergeNoneExisting(t *testing.T) {
 	Equals(t, expLsOutput, actLsOutput)
 }
 
+// Test that with a checkout depth, the merge method fetches more history until
+// it finds where the branch branched off.
+func TestClone_CheckoutMergeShallow(t *testing.T) {
+	repoDir, cleanup := initRepo(t)
+	defer cleanup()
+
+	// Add history before the branch point that shouldn't need to be fetched.
+	for i := 0; i < 5; i++ {
+		runCmd(t, repoDir, "git", "commit", "--allow-empty", "-m", fmt.Sprintf("old-commit%d", i))
+	}
+	runCmd(t, repoDir, "git", "branch", "-f", "branch")
+
+	// Advance both branches by more commits than the checkout depth.
+	runCmd(t, repoDir, "git", "checkout", "branch")
+	for i := 0; i < 3; i++ {
+		runCmd(t, repoDir, "touch", fmt.Sprintf("branch-file%d", i))
+		runCmd(t, repoDir, "git", "add", fmt.Sprintf("branch-file%d", i))
+		runCmd(t, repoDir, "git", "commit", "-m", fmt.Sprintf("branch-commit%d", i))
+	}
+	branchCommit := runCmd(t, repoDir, "git", "rev-parse", "HEAD")
+	runCmd(t, repoDir, "git", "checkout", "master")
+	for i := 0; i < 3; i++ {
+		runCmd(t, repoDir, "touch", fmt.Sprintf("master-file%d", i))
+		runCmd(t, repoDir, "git", "add", fmt.Sprintf("master-file%d", i))
+		runCmd(t, repoDir, "git", "commit", "-m", fmt.Sprintf("master-commit%d", i))
+	}
+	masterCommit := runCmd(t, repoDir, "git", "rev-parse", "HEAD")
+
+	dataDir, cleanup2 := TempDir(t)
+	defer cleanup2()
+
+	overrideURL := fmt.Sprintf("file://%s", repoDir)
+	wd := &events.FileWorkspace{
+		DataDir:                     dataDir,
+		CheckoutMerge:               true,
+		CheckoutDepth:               1,
+		TestingOverrideHeadCloneURL: overrideURL,
+		TestingOverrideBaseCloneURL: overrideURL,
+	}
+
+	cloneDir, _, err := wd.Clone(logging.NewNoopLogger(t), models.Repo{}, models.PullRequest{
+		BaseRepo:   models.Repo{},
+		HeadBranch: "branch",
+		BaseBranch: "master",
+	}, "default")
+	Ok(t, err)
+
+	Equals(t, masterCommit, runCmd(t, cloneDir, "git", "rev-parse", "HEAD~1"))
+	Equals(t, branchCommit, runCmd(t, cloneDir, "git", "rev-parse", "HEAD^2"))
+	Equals(t, "true\n", runCmd(t, cloneDir, "git", "rev-parse", "--is-shallow-repository"))
+}
+
+// Test that with a clone cache, the cache is created and then used as a
+// reference for the clone.
+func TestClone_CloneCache(t *testing.T) {
+	repoDir, cleanup := initRepo(t)
+	defer cleanup()
+	expCommit := runCmd(t, repoDir, "git", "rev-parse", "HEAD")
+
+	dataDir, cleanup2 := TempDir(t)
+	defer cleanup2()
+
+	overrideURL := fmt.Sprintf("file://%s", repoDir)
+	wd := &events.FileWorkspace{
+		DataDir:                     dataDir,
+		CloneCacheDir:               filepath.Join(dataDir, "clone-cache"),
+		TestingOverrideHeadCloneURL: overrideURL,
+		TestingOverrideBaseCloneURL: overrideURL,
+	}
+	baseRepo := models.Repo{
+		FullName: "owner/repo",
+		VCSHost:  models.VCSHost{Hostname: "github.com"},
+	}
+
+	cloneDir, _, err := wd.Clone(logging.NewNoopLogger(t), baseRepo, models.PullRequest{
+		Num:        1,
+		BaseRepo:   baseRepo,
+		HeadBranch: "branch",
+	}, "default")
+	Ok(t, err)
+	Equals(t, expCommit, runCmd(t, cloneDir, "git", "rev-parse", "HEAD"))
+
+	cacheDir := filepath.Join(dataDir, "clone-cache", "github.com", "owner", "repo.git")
+	Equals(t, expCommit, runCmd(t, cacheDir, "git", "rev-parse", "refs/heads/branch"))
+	alternates := runCmd(t, dataDir, "cat", "repos/owner/repo/1/.atlantis-clone/.git/objects/info/alternates")
+	Equals(t, filepath.Join(cacheDir, "objects")+"\n", alternates)
+
+	// A new commit should be fetched into the existing cache.
+	runCmd(t, repoDir, "git", "checkout", "branch")
+	runCmd(t, repoDir, "touch", "branch-file")
+	runCmd(t, repoDir, "git", "add", "branch-file")
+	runCmd(t, repoDir, "git", "commit", "-m", "branch-commit")
+	newCommit := runCmd(t, repoDir, "git", "rev-parse", "HEAD")
+	_, _, err = wd.Clone(logging.NewNoopLogger(t), baseRepo, models.PullRequest{
+		Num:        1,
+		BaseRepo:   baseRepo,
+		HeadBranch: "branch",
+		HeadCommit: strings.TrimSpace(newCommit),
+	}, "default")
+	Ok(t, err)
+	Equals(t, newCommit, runCmd(t, cacheDir, "git", "rev-parse", "refs/heads/branch"))
+}
+
+// Test that the workspaces of a pull are worktrees of one clone so the repo
+// is only fetched once.
+func TestClone_WorktreePerWorkspace(t *testing.T) {
+	repoDir, cleanup := initRepo(t)
+	defer cleanup()
+	runCmd(t, repoDir, "git", "checkout", "branch")
+	runCmd(t, repoDir, "touch", "branch-file")
+	runCmd(t, repoDir, "git", "add", "branch-file")
+	runCmd(t, repoDir, "git", "commit", "-m", "branch-commit")
+	branchCommit := runCmd(t, repoDir, "git", "rev-parse", "HEAD")
+
+	dataDir, cleanup2 := TempDir(t)
+	defer cleanup2()
+
+	overrideURL := fmt.Sprintf("file://%s", repoDir)
+	wd := &events.FileWorkspace{
+		DataDir:                     dataDir,
+		CheckoutMerge:               true,
+		TestingOverrideHeadCloneURL: overrideURL,
+		TestingOverrideBaseCloneURL: overrideURL,
+	}
+	pull := models.PullRequest{
+		BaseRepo:   models.Repo{},
+		HeadBranch: "branch",
+		BaseBranch: "master",
+		HeadCommit: strings.TrimSpace(branchCommit),
+	}
+	defaultDir, _, err := wd.Clone(logging.NewNoopLogger(t), models.Repo{}, pull, "default")
+	Ok(t, err)
+
+	// Move the repo so that it can't be fetched from again.
+	runCmd(t, repoDir, "mv", ".git", ".git-moved")
+	stagingDir, _, err := wd.Clone(logging.NewNoopLogger(t), models.Repo{}, pull, "staging")
+	Ok(t, err)
+
+	pullCloneGitDir := filepath.Join(dataDir, "repos", "0", ".atlantis-clone", ".git")
+	for _, cloneDir := range []string{defaultDir, stagingDir} {
+		Equals(t, branchCommit, runCmd(t, cloneDir, "git", "rev-parse", "HEAD^2"))
+		Equals(t, pullCloneGitDir+"\n", runCmd(t, cloneDir, "git", "rev-parse", "--path-format=absolute", "--git-common-dir"))
+		info, err := os.Stat(filepath.Join(cloneDir, ".git"))
+		Ok(t, err)
+		Equals(t, false, info.IsDir())
+	}
+
+	// Deleting a workspace and cloning it again should reuse the clone too.
+	Ok(t, wd.DeleteForWorkspace(models.Repo{}, pull, "staging"))
+	stagingDir, _, err = wd.Clone(logging.NewNoopLogger(t), models.Repo{}, pull, "staging")
+	Ok(t, err)
+	Equals(t, branchCommit, runCmd(t, stagingDir, "git", "rev-parse", "HEAD^2"))
+}
+
+// Test that when the pull's head commit changes, recloning one workspace
+// updates the shared clone in place so the other workspaces' worktrees still
+// work.
+func TestClone_WorktreeNewCommitKeepsOtherWorkspaces(t *testing.T) {
+	repoDir, cleanup := initRepo(t)
+	defer cleanup()
+	runCmd(t, repoDir, "git", "checkout", "branch")
+	runCmd(t, repoDir, "touch", "branch-file")
+	runCmd(t, repoDir, "git", "add", "branch-file")
+	runCmd(t, repoDir, "git", "commit", "-m", "branch-commit")
+	branchCommit := runCmd(t, repoDir, "git", "rev-parse", "HEAD")
+
+	dataDir, cleanup2 := TempDir(t)
+	defer cleanup2()
+
+	overrideURL := fmt.Sprintf("file://%s", repoDir)
+	wd := &events.FileWorkspace{
+		DataDir:                     dataDir,
+		CheckoutMerge:               true,
+		TestingOverrideHeadCloneURL: overrideURL,
+		TestingOverrideBaseCloneURL: overrideURL,
+	}
+	pull := models.PullRequest{
+		BaseRepo:   models.Repo{},
+		HeadBranch: "branch",
+		BaseBranch: "master",
+		HeadCommit: strings.TrimSpace(branchCommit),
+	}
+	_, _, err := wd.Clone(logging.NewNoopLogger(t), models.Repo{}, pull, "default")
+	Ok(t, err)
+	stagingDir, _, err := wd.Clone(logging.NewNoopLogger(t), models.Repo{}, pull, "staging")
+	Ok(t, err)
+
+	runCmd(t, repoDir, "touch", "branch-file2")
+	runCmd(t, repoDir, "git", "add", "branch-file2")
+	runCmd(t, repoDir, "git", "commit", "-m", "branch-commit2")
+	pull.HeadCommit = strings.TrimSpace(runCmd(t, repoDir, "git", "rev-parse", "HEAD"))
+	defaultDir, _, err := wd.Clone(logging.NewNoopLogger(t), models.Repo{}, pull, "default")
+	Ok(t, err)
+	Equals(t, pull.HeadCommit+"\n", runCmd(t, defaultDir, "git", "rev-parse", "HEAD^2"))
+
+	// The staging worktree is still at the old commit but git still works in
+	// it, ex. for finding pending plans.
+	Equals(t, branchCommit, runCmd(t, stagingDir, "git", "rev-parse", "HEAD^2"))
+	runCmd(t, stagingDir, "git", "ls-files", ".", "--others")
+}
+
+// Test that workspaces whose names only differ in characters that aren't
+// allowed in branch names get their own worktree branches.
+func TestClone_WorktreeBranchesDontCollide(t *testing.T) {
+	repoDir, cleanup := initRepo(t)
+	defer cleanup()
+	dataDir, cleanup2 := TempDir(t)
+	defer cleanup2()
+
+	overrideURL := fmt.Sprintf("file://%s", repoDir)
+	wd := &events.FileWorkspace{
+		DataDir:                     dataDir,
+		CheckoutMerge:               true,
+		TestingOverrideHeadCloneURL: overrideURL,
+		TestingOverrideBaseCloneURL: overrideURL,
+	}
+	pull := models.PullRequest{
+		BaseRepo:   models.Repo{},
+		HeadBranch: "branch",
+		BaseBranch: "master",
+	}
+	dotDir, _, err := wd.Clone(logging.NewNoopLogger(t), models.Repo{}, pull, "a.b")
+	Ok(t, err)
+	dashDir, _, err := wd.Clone(logging.NewNoopLogger(t), models.Repo{}, pull, "a-b")
+	Ok(t, err)
+	Assert(t, runCmd(t, dotDir, "git", "branch", "--show-current") != runCmd(t, dashDir, "git", "branch", "--show-current"), "expected different worktree branches")
+}
+
+// Test that a worktree reports when the branch it merged into has been updated
+// since it was cloned.
+func TestClone_WorktreeMasterHasDiverged(t *testing.T) {
+	repoDir, cleanup := initRepo(t)
+	defer cleanup()
+	runCmd(t, repoDir, "git", "checkout", "branch")
+	runCmd(t, repoDir, "touch", "branch-file")
+	runCmd(t, repoDir, "git", "add", "branch-file")
+	runCmd(t, repoDir, "git", "commit", "-m", "branch-commit")
+	runCmd(t, repoDir, "git", "checkout", "master")
+
+	dataDir, cleanup2 := TempDir(t)
+	defer cleanup2()
+
+	wd := &events.FileWorkspace{
+		DataDir:       dataDir,
+		CheckoutMerge: true,
+	}
+	repo := models.Repo{CloneURL: repoDir}
+	pull := models.PullRequest{
+		BaseRepo:   repo,
+		HeadBranch: "branch",
+		BaseBranch: "master",
+	}
+	_, hasDiverged, err := wd.Clone(logging.NewNoopLogger(t), repo, pull, "default")
+	Ok(t, err)
+	Equals(t, false, hasDiverged)
+
+	// Advance master. The existing worktree is at the right commit so it's
+	// reused and should now have diverged.
+	runCmd(t, repoDir, "touch", "master-file")
+	runCmd(t, repoDir, "git", "add", "master-file")
+	runCmd(t, repoDir, "git", "commit", "-m", "master-commit")
+	_, hasDiverged, err = wd.Clone(logging.NewNoopLogger(t), repo, pull, "default")
+	Ok(t, err)
+	Equals(t, true, hasDiverged)
+}
+
 // Test that if we're using the merge method and the repo is already cloned at
 // the right commit, then we don't reclone.
 func TestClone_CheckoutMergeNoReclone(t *testing.T) {
@@ -245,18 +527,20 @@ func TestClone_CheckoutMergeConflict(t *testing.T) {
 		TestingOverrideBaseCloneURL: overrideURL,
 	}
 
-	_, _, err := wd.Clone(logging.NewNoopLogger(t), models.Repo{}, models.PullRequest{
+	cloneDir, _, err := wd.Clone(logging.NewNoopLogger(t), models.Repo{}, models.PullRequest{
 		BaseRepo:   models.Repo{},
 		HeadBranch: "branch",
 		BaseBranch: "master",
 	}, "default")
 
-	ErrContains(t, "running git merge -q --no-ff -m atlantis-merge FETCH_HEAD", err)
-	ErrContains(t, "Auto-merging file", err)
-	ErrContains(t, "CONFLICT (add/add)", err)
-	ErrContains(t, "Merge conflict in file", err)
-	ErrContains(t, "Automatic merge failed; fix conflicts and then commit the result.", err)
-	ErrContains(t, "exit status 1", err)
+	conflictErr, ok := err.(*events.MergeConflictError)
+	Assert(t, ok, "exp MergeConflictError, got %v", err)
+	Equals(t, []string{"file"}, conflictErr.Files)
+	ErrEquals(t, "cannot merge master into branch because of conflicts in file. Resolve the conflicts, ex. by merging or rebasing on master, and push again", err)
+
+	// Nothing should be run on the half-merged files.
+	_, err = os.Stat(cloneDir)
+	Assert(t, os.IsNotExist(err), "exp clone dir to be deleted")
 }
 
 // Test that if the repo is already cloned and is at the right commit, we
@@ -288,6 +572,60 @@ func TestClone_NoReclone(t *testing.T) {
 	Ok(t, err)
 }
 
+// Test that submodules are only checked out if the server-side repo config
+// enables them.
+func TestClone_Submodules(t *testing.T) {
+	// Git only allows submodules to be cloned over file:// if configured to.
+	os.Setenv("GIT_CONFIG_COUNT", "1")                   // nolint: errcheck
+	os.Setenv("GIT_CONFIG_KEY_0", "protocol.file.allow") // nolint: errcheck
+	os.Setenv("GIT_CONFIG_VALUE_0", "always")            // nolint: errcheck
+	defer os.Unsetenv("GIT_CONFIG_COUNT")                // nolint: errcheck
+	defer os.Unsetenv("GIT_CONFIG_KEY_0")                // nolint: errcheck
+	defer os.Unsetenv("GIT_CONFIG_VALUE_0")              // nolint: errcheck
+
+	submoduleDir, cleanup := initRepo(t)
+	defer cleanup()
+	runCmd(t, submoduleDir, "touch", "main.tf")
+	runCmd(t, submoduleDir, "git", "add", "main.tf")
+	runCmd(t, submoduleDir, "git", "commit", "-m", "module")
+
+	repoDir, cleanup2 := initRepo(t)
+	defer cleanup2()
+	runCmd(t, repoDir, "git", "checkout", "branch")
+	runCmd(t, repoDir, "git", "submodule", "add", fmt.Sprintf("file://%s", submoduleDir), "modules/shared")
+	runCmd(t, repoDir, "git", "commit", "-m", "add submodule")
+
+	for _, checkoutMerge := range []bool{false, true} {
+		for _, checkoutSubmodules := range []bool{false, true} {
+			t.Run(fmt.Sprintf("merge %t submodules %t", checkoutMerge, checkoutSubmodules), func(t *testing.T) {
+				dataDir, cleanup3 := TempDir(t)
+				defer cleanup3()
+				globalCfg := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})
+				globalCfg.Repos = append(globalCfg.Repos, valid.Repo{
+					IDRegex:            regexp.MustCompile(".*"),
+					CheckoutSubmodules: &checkoutSubmodules,
+				})
+				wd := &events.FileWorkspace{
+					DataDir:                     dataDir,
+					CheckoutMerge:               checkoutMerge,
+					GlobalCfg:                   valid.NewGlobalCfgStore(globalCfg),
+					TestingOverrideHeadCloneURL: fmt.Sprintf("file://%s", repoDir),
+					TestingOverrideBaseCloneURL: fmt.Sprintf("file://%s", repoDir),
+				}
+				cloneDir, _, err := wd.Clone(logging.NewNoopLogger(t), models.Repo{}, models.PullRequest{
+					BaseRepo:   models.Repo{},
+					HeadBranch: "branch",
+					BaseBranch: "master",
+				}, "default")
+				Ok(t, err)
+
+				_, err = os.Stat(filepath.Join(cloneDir, "modules", "shared", "main.tf"))
+				Equals(t, checkoutSubmodules, err == nil)
+			})
+		}
+	}
+}
+
 // Test that if the repo is already cloned but is at the wrong commit, we
 // reclone.
 func TestClone_RecloneWrongCommit(t *testing.T) {
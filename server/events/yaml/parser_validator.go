@@ -94,20 +94,62 @@ func (p *ParserValidator) ParseRepoCfgData(repoCfgData []byte, globalCfg valid.G
 // configFile. defaultCfg will be merged into the parsed config.
 // If there is no file at configFile it will return an error.
 func (p *ParserValidator) ParseGlobalCfg(configFile string, defaultCfg valid.GlobalCfg) (valid.GlobalCfg, error) {
+	rawCfg, err := p.readGlobalCfg(filepath.Clean(configFile), nil, make(map[string]bool))
+	if err != nil {
+		return valid.GlobalCfg{}, err
+	}
+	return p.validateRawGlobalCfg(rawCfg, defaultCfg, "yaml")
+}
+
+// readGlobalCfg reads the global repo config file at configFile and merges
+// in the files it includes. includedBy is the chain of files that included
+// configFile, used to detect include cycles, and read is every file read so
+// far so that a file included more than once is only merged once.
+func (p *ParserValidator) readGlobalCfg(configFile string, includedBy []string, read map[string]bool) (raw.GlobalCfg, error) {
+	read[configFile] = true
+
 	configData, err := ioutil.ReadFile(configFile) // nolint: gosec
 	if err != nil {
-		return valid.GlobalCfg{}, errors.Wrapf(err, "unable to read %s file", configFile)
+		return raw.GlobalCfg{}, errors.Wrapf(err, "unable to read %s file", configFile)
 	}
 	if len(configData) == 0 {
-		return valid.GlobalCfg{}, fmt.Errorf("file %s was empty", configFile)
+		return raw.GlobalCfg{}, fmt.Errorf("file %s was empty", configFile)
 	}
 
 	var rawCfg raw.GlobalCfg
 	if err := yaml.UnmarshalStrict(configData, &rawCfg); err != nil {
-		return valid.GlobalCfg{}, err
+		if len(includedBy) > 0 {
+			return raw.GlobalCfg{}, errors.Wrapf(err, "parsing %s", configFile)
+		}
+		return raw.GlobalCfg{}, err
 	}
 
-	return p.validateRawGlobalCfg(rawCfg, defaultCfg, "yaml")
+	chain := append(append([]string{}, includedBy...), configFile)
+	includes := rawCfg.Include
+	rawCfg.Include = nil
+	for _, include := range includes {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(configFile), include)
+		}
+		include = filepath.Clean(include)
+		for _, f := range chain {
+			if f == include {
+				return raw.GlobalCfg{}, fmt.Errorf("include cycle: %s", strings.Join(append(chain, include), " -> "))
+			}
+		}
+		if read[include] {
+			continue
+		}
+		includedCfg, err := p.readGlobalCfg(include, chain, read)
+		if err != nil {
+			return raw.GlobalCfg{}, err
+		}
+		rawCfg, err = rawCfg.Merge(includedCfg)
+		if err != nil {
+			return raw.GlobalCfg{}, errors.Wrapf(err, "including %s in %s", include, configFile)
+		}
+	}
+	return rawCfg, nil
 }
 
 // ParseGlobalCfgJSON parses a json string cfgJSON into global config.
@@ -124,6 +166,13 @@ func (p *ParserValidator) validateRawGlobalCfg(rawCfg raw.GlobalCfg, defaultCfg
 	// Setting ErrorTag means our errors will use the field names defined in
 	// the struct tags for yaml/json.
 	validation.ErrorTag = errTag
+	if len(rawCfg.Include) > 0 {
+		return valid.GlobalCfg{}, errors.New("include is only supported in server-side repo config files")
+	}
+	rawCfg, err := rawCfg.ApplyTemplates()
+	if err != nil {
+		return valid.GlobalCfg{}, err
+	}
 	if err := rawCfg.Validate(); err != nil {
 		return valid.GlobalCfg{}, err
 	}
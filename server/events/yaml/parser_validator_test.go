@@ -1214,6 +1214,42 @@ func TestParseGlobalCfg(t *testing.T) {
   branch: /?/`,
 			expErr: "repos: (0: (branch: parsing: /?/: error parsing regexp: missing argument to repetition operator: `?`.).).",
 		},
+		"invalid on_base_branch_update": {
+			input: `repos:
+- id: /.*/
+  on_base_branch_update: delete`,
+			expErr: "repos: (0: (on_base_branch_update: must be one of invalidate or replan.).).",
+		},
+		"invalid terraform_distribution": {
+			input: `repos:
+- id: /.*/
+  terraform_distribution: pulumi`,
+			expErr: "repos: (0: (terraform_distribution: must be one of terraform or opentofu.).).",
+		},
+		"invalid lock_granularity": {
+			input: `repos:
+- id: /.*/
+  lock_granularity: repo`,
+			expErr: "repos: (0: (lock_granularity: must be one of dir_workspace, dir or project.).).",
+		},
+		"invalid comment_format": {
+			input: `repos:
+- id: /.*/
+  comment_format: compact`,
+			expErr: "repos: (0: (comment_format: must be one of full or rollup.).).",
+		},
+		"negative apply_all_max_projects": {
+			input: `repos:
+- id: /.*/
+  apply_all_max_projects: -1`,
+			expErr: "repos: (0: (apply_all_max_projects: must not be negative.).).",
+		},
+		"unsupported locale": {
+			input: `repos:
+- id: /.*/
+  locale: fr`,
+			expErr: "repos: (0: (locale: must be one of en or ja.).).",
+		},
 		"workflow doesn't exist": {
 			input: `repos:
 - id: /.*/
@@ -1224,13 +1260,158 @@ func TestParseGlobalCfg(t *testing.T) {
 			input: `repos:
 - id: /.*/
   allowed_overrides: [invalid]`,
-			expErr: "repos: (0: (allowed_overrides: \"invalid\" is not a valid override, only \"apply_requirements\", \"workflow\" and \"delete_source_branch_on_merge\" are supported.).).",
+			expErr: "repos: (0: (allowed_overrides: \"invalid\" is not a valid override, only \"apply_requirements\", \"workflow\", \"delete_source_branch_on_merge\" and \"credentials\" are supported.).).",
 		},
 		"invalid apply_requirement": {
 			input: `repos:
 - id: /.*/
   apply_requirements: [invalid]`,
-			expErr: "repos: (0: (apply_requirements: \"invalid\" is not a valid apply_requirement, only \"approved\", \"mergeable\" and \"undiverged\" are supported.).).",
+			expErr: "\"invalid\" is not a valid apply_requirement, only \"approved\", \"mergeable\", \"undiverged\", \"codeowners_approved\", \"plan_newer_than:<duration>\" and custom_apply_requirements are supported",
+		},
+		"invalid plan_newer_than apply_requirement": {
+			input: `repos:
+- id: /.*/
+  apply_requirements: [plan_newer_than:-1h]`,
+			expErr: "repos: (0: (apply_requirements: \"plan_newer_than:-1h\" is not a valid apply_requirement: duration must be positive.).).",
+		},
+		"custom apply_requirement without run": {
+			input: `custom_apply_requirements:
+  change_window: {}`,
+			expErr: "custom apply requirement \"change_window\" must set \"run\"",
+		},
+		"custom apply_requirement shadowing built-in": {
+			input: `custom_apply_requirements:
+  approved:
+    run: exit 0`,
+			expErr: "custom apply requirement \"approved\" conflicts with the built-in apply requirement of the same name",
+		},
+		"custom apply_requirement": {
+			input: `
+repos:
+- id: github.com/owner/repo
+  apply_requirements: [change_window, plan_newer_than:24h]
+custom_apply_requirements:
+  change_window:
+    run: ./in-change-window.sh`,
+			exp: valid.GlobalCfg{
+				Repos: append(defaultCfg.Repos, valid.Repo{
+					ID:                "github.com/owner/repo",
+					ApplyRequirements: []string{"change_window", "plan_newer_than:24h"},
+				}),
+				Workflows: defaultCfg.Workflows,
+				CustomApplyReqs: map[string]string{
+					"change_window": "./in-change-window.sh",
+				},
+			},
+		},
+		"secret without a source": {
+			input: `secrets:
+  DB_PASSWORD: {}`,
+			expErr: "secret \"DB_PASSWORD\" must set one of \"env\" or \"file\"",
+		},
+		"secret with env and file": {
+			input: `secrets:
+  DB_PASSWORD:
+    env: DB_PASSWORD
+    file: /etc/db-password`,
+			expErr: "secret \"DB_PASSWORD\" can only set one of \"env\" or \"file\", found both",
+		},
+		"secret with invalid name": {
+			input: `secrets:
+  db-password:
+    env: DB_PASSWORD`,
+			expErr: "secret \"db-password\" must only contain letters, numbers and underscores and cannot start with a number",
+		},
+		"secrets": {
+			input: `
+secrets:
+  DB_PASSWORD:
+    env: PROD_DB_PASSWORD
+  API_TOKEN:
+    file: /etc/atlantis/api-token`,
+			exp: valid.GlobalCfg{
+				Repos:     defaultCfg.Repos,
+				Workflows: defaultCfg.Workflows,
+				Secrets: map[string]valid.Secret{
+					"DB_PASSWORD": {Env: "PROD_DB_PASSWORD"},
+					"API_TOKEN":   {File: "/etc/atlantis/api-token"},
+				},
+			},
+		},
+		"command with invalid name": {
+			input: `commands:
+  Deploy:
+    workflow: default`,
+			expErr: "command \"Deploy\" must only contain lowercase letters, numbers, '-' and '_' and must start with a letter",
+		},
+		"command shadowing built-in": {
+			input: `commands:
+  plan:
+    workflow: default`,
+			expErr: "command \"plan\" conflicts with the built-in command of the same name",
+		},
+		"command without workflow": {
+			input: `commands:
+  deploy: {}`,
+			expErr: "command \"deploy\" must set \"workflow\"",
+		},
+		"command workflow doesn't exist": {
+			input: `commands:
+  deploy:
+    workflow: notdefined`,
+			expErr: "command \"deploy\": workflow \"notdefined\" is not defined",
+		},
+		"commands": {
+			input: `
+commands:
+  deploy:
+    workflow: default
+    description: Deploys the pull request.`,
+			exp: valid.GlobalCfg{
+				Repos:     defaultCfg.Repos,
+				Workflows: defaultCfg.Workflows,
+				CustomCommands: map[string]valid.CustomCommand{
+					"deploy": {
+						Name:        "deploy",
+						Description: "Deploys the pull request.",
+						Workflow:    defaultCfg.Workflows["default"],
+					},
+				},
+			},
+		},
+		"commands with requirements": {
+			input: `
+commands:
+  import-all:
+    workflow: default
+    requirements: [approved]
+  show:
+    workflow: default
+    requirements: []`,
+			exp: valid.GlobalCfg{
+				Repos:     defaultCfg.Repos,
+				Workflows: defaultCfg.Workflows,
+				CustomCommands: map[string]valid.CustomCommand{
+					"import-all": {
+						Name:         "import-all",
+						Workflow:     defaultCfg.Workflows["default"],
+						Requirements: []string{"approved"},
+					},
+					"show": {
+						Name:         "show",
+						Workflow:     defaultCfg.Workflows["default"],
+						Requirements: []string{},
+					},
+				},
+			},
+		},
+		"command with invalid requirement": {
+			input: `
+commands:
+  deploy:
+    workflow: default
+    requirements: [notreal]`,
+			expErr: "command \"deploy\": \"notreal\" is not a valid apply_requirement, only \"approved\", \"mergeable\", \"undiverged\", \"codeowners_approved\", \"plan_newer_than:<duration>\" and custom_apply_requirements are supported",
 		},
 		"no workflows key": {
 			input: `repos: []`,
@@ -1309,6 +1490,24 @@ repos:
   workflow: custom1
   allowed_overrides: [apply_requirements, workflow, delete_source_branch_on_merge]
   allow_custom_workflows: true
+  allowed_apply_teams: [platform]
+  allow_destroy_plans: true
+  on_base_branch_update: replan
+  terraform_distribution: opentofu
+  lock_granularity: dir
+  checkout_submodules: true
+  checkout_lfs: false
+  auto_apply: true
+  silence_no_change_plans: true
+  comment_format: rollup
+  apply_all_max_projects: 3
+  locale: ja
+  disable_workspace_autocreate: true
+  disable_targeting: true
+  autoplan_triggers:
+  - when_modified: ["modules/**"]
+  - when_modified: ["shared/*.tfvars"]
+    dirs: [project1]
 - id: /.*/
   branch: /(master|main)/
   pre_workflow_hooks:
@@ -1342,12 +1541,30 @@ policies:
 				Repos: []valid.Repo{
 					defaultCfg.Repos[0],
 					{
-						ID:                   "github.com/owner/repo",
-						ApplyRequirements:    []string{"approved", "mergeable"},
-						PreWorkflowHooks:     preWorkflowHooks,
-						Workflow:             &customWorkflow1,
-						AllowedOverrides:     []string{"apply_requirements", "workflow", "delete_source_branch_on_merge"},
-						AllowCustomWorkflows: Bool(true),
+						ID:                    "github.com/owner/repo",
+						ApplyRequirements:     []string{"approved", "mergeable"},
+						PreWorkflowHooks:      preWorkflowHooks,
+						Workflow:              &customWorkflow1,
+						AllowedOverrides:      []string{"apply_requirements", "workflow", "delete_source_branch_on_merge"},
+						AllowCustomWorkflows:  Bool(true),
+						AllowedApplyTeams:     []string{"platform"},
+						AllowDestroyPlans:     Bool(true),
+						OnBaseBranchUpdate:    "replan",
+						TerraformDistribution: "opentofu",
+						LockGranularity:       "dir",
+						CheckoutSubmodules:    Bool(true),
+						CheckoutLFS:           Bool(false),
+						AutoApply:             Bool(true),
+						SilenceNoChangePlans:  Bool(true),
+						CommentFormat:         "rollup",
+						ApplyAllMaxProjects:   Int(3),
+						Locale:                "ja",
+						NoWorkspaceAutocreate: Bool(true),
+						DisableTargeting:      Bool(true),
+						AutoplanTriggers: []valid.AutoplanTrigger{
+							{WhenModified: []string{"modules/**"}},
+							{WhenModified: []string{"shared/*.tfvars"}, Dirs: []string{"project1"}},
+						},
 					},
 					{
 						IDRegex:          regexp.MustCompile(".*"),
@@ -1513,6 +1730,181 @@ workflows:
 	}
 }
 
+func TestParseGlobalCfg_Include(t *testing.T) {
+	tmp, cleanup := DirStructure(t, map[string]interface{}{
+		"repos.yaml": `
+include: [teams/a.yaml, common.yaml]
+repos:
+- id: github.com/org/a
+  apply_requirements: [mergeable]
+`,
+		"teams": map[string]interface{}{
+			"a.yaml": `
+include: [../common.yaml]
+repos:
+- id: github.com/org/a
+  apply_requirements: [approved]
+  workflow: team-a
+workflows:
+  team-a: {}
+`,
+		},
+		"common.yaml": `
+workflows:
+  shared: {}
+`,
+	})
+	defer cleanup()
+	r := yaml.ParserValidator{}
+	defaultCfg := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})
+
+	act, err := r.ParseGlobalCfg(filepath.Join(tmp, "repos.yaml"), defaultCfg)
+	Ok(t, err)
+
+	t.Log("included repos come before the including file's so they're overridden")
+	Equals(t, 3, len(act.Repos))
+	Equals(t, "github.com/org/a", act.Repos[1].ID)
+	Equals(t, []string{"approved"}, act.Repos[1].ApplyRequirements)
+	Equals(t, "team-a", act.Repos[1].Workflow.Name)
+	Equals(t, []string{"mergeable"}, act.Repos[2].ApplyRequirements)
+
+	t.Log("a file included more than once is only merged once")
+	_, ok := act.Workflows["shared"]
+	Assert(t, ok, "expected shared workflow")
+	_, ok = act.Workflows["team-a"]
+	Assert(t, ok, "expected team-a workflow")
+}
+
+func TestParseGlobalCfg_IncludeErrors(t *testing.T) {
+	cases := map[string]struct {
+		files  map[string]interface{}
+		expErr string
+	}{
+		"cycle": {
+			files: map[string]interface{}{
+				"a.yaml": "include: [b.yaml]",
+				"b.yaml": "include: [./a.yaml]",
+			},
+			expErr: "include cycle: <tmp>/a.yaml -> <tmp>/b.yaml -> <tmp>/a.yaml",
+		},
+		"missing file": {
+			files: map[string]interface{}{
+				"a.yaml": "include: [missing.yaml]",
+			},
+			expErr: "unable to read <tmp>/missing.yaml file: open <tmp>/missing.yaml: no such file or directory",
+		},
+		"invalid included file": {
+			files: map[string]interface{}{
+				"a.yaml": "include: [b.yaml]",
+				"b.yaml": "unknown: key",
+			},
+			expErr: "parsing <tmp>/b.yaml: yaml: unmarshal errors:\n  line 1: field unknown not found in type raw.GlobalCfg",
+		},
+		"workflow defined twice": {
+			files: map[string]interface{}{
+				"a.yaml": "include: [b.yaml]\nworkflows:\n  w: {}",
+				"b.yaml": "workflows:\n  w: {}",
+			},
+			expErr: `including <tmp>/b.yaml in <tmp>/a.yaml: workflow "w" is defined more than once`,
+		},
+		"policies defined twice": {
+			files: map[string]interface{}{
+				"a.yaml": "include: [b.yaml]\npolicies:\n  policy_sets:\n  - name: a\n    path: a\n    source: local",
+				"b.yaml": "policies:\n  policy_sets:\n  - name: b\n    path: b\n    source: local",
+			},
+			expErr: "including <tmp>/b.yaml in <tmp>/a.yaml: policies can only be defined in one file",
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			tmp, cleanup := DirStructure(t, c.files)
+			defer cleanup()
+			r := yaml.ParserValidator{}
+			_, err := r.ParseGlobalCfg(filepath.Join(tmp, "a.yaml"), valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{}))
+			ErrEquals(t, strings.Replace(c.expErr, "<tmp>", tmp, -1), err)
+		})
+	}
+}
+
+func TestParseGlobalCfg_Templates(t *testing.T) {
+	tmp, cleanup := DirStructure(t, map[string]interface{}{
+		"repos.yaml": `
+templates:
+- id: /github.com/org/.*/
+  apply_requirements: [approved]
+  allow_custom_workflows: true
+- id: /github.com/org/(?P<team>[^-]+)-infra/
+  workflow: ${team}
+  allowed_apply_teams: ["${team}-admins"]
+repos:
+- id: github.com/org/payments-infra
+- id: github.com/org/search-infra
+  apply_requirements: [mergeable]
+- id: /github.com/org/.*-infra/
+- id: github.com/other/repo
+workflows:
+  payments: {}
+  search: {}
+`,
+	})
+	defer cleanup()
+	r := yaml.ParserValidator{}
+	act, err := r.ParseGlobalCfg(filepath.Join(tmp, "repos.yaml"), valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{}))
+	Ok(t, err)
+	Equals(t, 5, len(act.Repos))
+
+	t.Log("matching templates are merged with captures expanded")
+	payments := act.Repos[1]
+	Equals(t, []string{"approved"}, payments.ApplyRequirements)
+	Equals(t, "payments", payments.Workflow.Name)
+	Equals(t, []string{"payments-admins"}, payments.AllowedApplyTeams)
+	Equals(t, true, *payments.AllowCustomWorkflows)
+
+	t.Log("fields set in the repo take precedence")
+	search := act.Repos[2]
+	Equals(t, []string{"mergeable"}, search.ApplyRequirements)
+	Equals(t, "search", search.Workflow.Name)
+
+	t.Log("templates don't apply to regex repos or repos they don't match")
+	Assert(t, act.Repos[3].Workflow == nil, "expected no workflow for regex repo")
+	Assert(t, act.Repos[4].AllowCustomWorkflows == nil, "expected no template for other repo")
+}
+
+func TestParseGlobalCfg_TemplateErrors(t *testing.T) {
+	cases := map[string]struct {
+		input  string
+		expErr string
+	}{
+		"exact id": {
+			input:  "templates:\n- id: github.com/org/repo",
+			expErr: `template id "github.com/org/repo" must be a regex, ex. /github.com/org/.*/`,
+		},
+		"invalid field": {
+			input:  "templates:\n- id: /.*/\n  lock_granularity: repo",
+			expErr: "template /.*/: lock_granularity: must be one of dir_workspace, dir or project.",
+		},
+		"expanded workflow not defined": {
+			input:  "templates:\n- id: /github.com/org/(.*)/\n  workflow: ${1}\nrepos:\n- id: github.com/org/missing",
+			expErr: `workflow "missing" is not defined`,
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			tmp, cleanup := DirStructure(t, map[string]interface{}{"repos.yaml": c.input})
+			defer cleanup()
+			r := yaml.ParserValidator{}
+			_, err := r.ParseGlobalCfg(filepath.Join(tmp, "repos.yaml"), valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{}))
+			ErrEquals(t, c.expErr, err)
+		})
+	}
+}
+
+func TestParserValidator_ParseGlobalCfgJSON_Include(t *testing.T) {
+	r := yaml.ParserValidator{}
+	_, err := r.ParseGlobalCfgJSON(`{"include": ["other.yaml"]}`, valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{}))
+	ErrEquals(t, "include is only supported in server-side repo config files", err)
+}
+
 // Test that if we pass in JSON strings everything should parse fine.
 func TestParserValidator_ParseGlobalCfgJSON(t *testing.T) {
 	customWorkflow := valid.Workflow{
@@ -1774,3 +2166,7 @@ func String(v string) *string { return &v }
 // Bool is a helper routine that allocates a new bool value
 // to store v and returns a pointer to it.
 func Bool(v bool) *bool { return &v }
+
+// Int is a helper routine that allocates a new int value
+// to store v and returns a pointer to it.
+func Int(v int) *int { return &v }
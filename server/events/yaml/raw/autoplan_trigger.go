@@ -0,0 +1,38 @@
+package raw
+
+import (
+	"errors"
+
+	"github.com/docker/docker/pkg/fileutils"
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// AutoplanTrigger is the raw schema for an autoplan trigger in the
+// server-side repo config. It maps files matching WhenModified to the
+// projects that should be planned when they change.
+type AutoplanTrigger struct {
+	WhenModified []string `yaml:"when_modified" json:"when_modified"`
+	Dirs         []string `yaml:"dirs,omitempty" json:"dirs,omitempty"`
+}
+
+func (a AutoplanTrigger) Validate() error {
+	patternsValid := func(value interface{}) error {
+		patterns := value.([]string)
+		if len(patterns) == 0 {
+			return errors.New("cannot be empty")
+		}
+		_, err := fileutils.NewPatternMatcher(patterns)
+		return err
+	}
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.WhenModified, validation.By(patternsValid)),
+	)
+}
+
+func (a AutoplanTrigger) ToValid() valid.AutoplanTrigger {
+	return valid.AutoplanTrigger{
+		WhenModified: a.WhenModified,
+		Dirs:         a.Dirs,
+	}
+}
@@ -0,0 +1,67 @@
+package raw_test
+
+import (
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/yaml/raw"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestAutoplanTrigger_UnmarshalYAML(t *testing.T) {
+	var a raw.AutoplanTrigger
+	err := yaml.UnmarshalStrict([]byte(`
+when_modified: ["shared/*.tfvars"]
+dirs: [project1, project2]
+`), &a)
+	Ok(t, err)
+	Equals(t, raw.AutoplanTrigger{
+		WhenModified: []string{"shared/*.tfvars"},
+		Dirs:         []string{"project1", "project2"},
+	}, a)
+}
+
+func TestAutoplanTrigger_Validate(t *testing.T) {
+	cases := []struct {
+		description string
+		input       raw.AutoplanTrigger
+		expErr      string
+	}{
+		{
+			description: "valid",
+			input:       raw.AutoplanTrigger{WhenModified: []string{"modules/**", "!modules/README.md"}},
+			expErr:      "",
+		},
+		{
+			description: "when_modified not set",
+			input:       raw.AutoplanTrigger{Dirs: []string{"project1"}},
+			expErr:      "when_modified: cannot be empty.",
+		},
+		{
+			description: "invalid pattern",
+			input:       raw.AutoplanTrigger{WhenModified: []string{"modules/["}},
+			expErr:      "when_modified: syntax error in pattern.",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			err := c.input.Validate()
+			if c.expErr == "" {
+				Ok(t, err)
+			} else {
+				ErrEquals(t, c.expErr, err)
+			}
+		})
+	}
+}
+
+func TestAutoplanTrigger_ToValid(t *testing.T) {
+	Equals(t, valid.AutoplanTrigger{
+		WhenModified: []string{"modules/**"},
+		Dirs:         []string{"project1"},
+	}, raw.AutoplanTrigger{
+		WhenModified: []string{"modules/**"},
+		Dirs:         []string{"project1"},
+	}.ToValid())
+}
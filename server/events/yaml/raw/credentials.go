@@ -0,0 +1,115 @@
+package raw
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// roleARNRegex matches IAM role ARNs in any AWS partition, ex.
+// arn:aws:iam::123456789012:role/atlantis.
+var roleARNRegex = regexp.MustCompile(`^arn:aws[a-z-]*:iam::\d{12}:role/.+$`)
+
+// Credentials is the raw schema for the cloud credentials of a project.
+type Credentials struct {
+	AWS *AWSCredentials `yaml:"aws,omitempty" json:"aws,omitempty"`
+	GCP *GCPCredentials `yaml:"gcp,omitempty" json:"gcp,omitempty"`
+}
+
+// AWSCredentials is the raw schema for the AWS IAM role to assume.
+type AWSCredentials struct {
+	RoleARN         string `yaml:"role_arn" json:"role_arn"`
+	SessionDuration string `yaml:"session_duration,omitempty" json:"session_duration,omitempty"`
+}
+
+// GCPCredentials is the raw schema for the GCP service account to
+// impersonate.
+type GCPCredentials struct {
+	ServiceAccount string `yaml:"service_account" json:"service_account"`
+	Lifetime       string `yaml:"lifetime,omitempty" json:"lifetime,omitempty"`
+}
+
+func (c Credentials) Validate() error {
+	if c.AWS == nil && c.GCP == nil {
+		return errors.New("must set one of aws or gcp")
+	}
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.AWS),
+		validation.Field(&c.GCP),
+	)
+}
+
+func (a AWSCredentials) Validate() error {
+	validRoleARN := func(value interface{}) error {
+		arn := value.(string)
+		if !roleARNRegex.MatchString(arn) {
+			return fmt.Errorf("%q is not an IAM role ARN, ex. arn:aws:iam::123456789012:role/atlantis", arn)
+		}
+		return nil
+	}
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.RoleARN, validation.Required, validation.By(validRoleARN)),
+		// STS only allows sessions between 15 minutes and 12 hours.
+		validation.Field(&a.SessionDuration, validation.By(durationBetween(15*time.Minute, 12*time.Hour))),
+	)
+}
+
+func (g GCPCredentials) Validate() error {
+	validServiceAccount := func(value interface{}) error {
+		if !strings.Contains(value.(string), "@") {
+			return fmt.Errorf("%q is not a service account email", value)
+		}
+		return nil
+	}
+	return validation.ValidateStruct(&g,
+		validation.Field(&g.ServiceAccount, validation.Required, validation.By(validServiceAccount)),
+		validation.Field(&g.Lifetime, validation.By(durationBetween(time.Second, 12*time.Hour))),
+	)
+}
+
+func (c Credentials) ToValid() *valid.Credentials {
+	v := &valid.Credentials{}
+	if c.AWS != nil {
+		// The duration is checked in Validate() so the error can be ignored.
+		d, _ := parseOptionalDuration(c.AWS.SessionDuration)
+		v.AWS = &valid.AWSCredentials{
+			RoleARN:         c.AWS.RoleARN,
+			SessionDuration: d,
+		}
+	}
+	if c.GCP != nil {
+		d, _ := parseOptionalDuration(c.GCP.Lifetime)
+		v.GCP = &valid.GCPCredentials{
+			ServiceAccount: c.GCP.ServiceAccount,
+			Lifetime:       d,
+		}
+	}
+	return v
+}
+
+// durationBetween returns a validation rule that checks that an optional
+// duration string is between min and max.
+func durationBetween(min time.Duration, max time.Duration) validation.RuleFunc {
+	return func(value interface{}) error {
+		d, err := parseOptionalDuration(value.(string))
+		if err != nil {
+			return err
+		}
+		if d != 0 && (d < min || d > max) {
+			return fmt.Errorf("must be between %s and %s", min, max)
+		}
+		return nil
+	}
+}
+
+func parseOptionalDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(s)
+}
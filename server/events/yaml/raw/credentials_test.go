@@ -0,0 +1,95 @@
+package raw_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/yaml/raw"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestCredentials_UnmarshalYAML(t *testing.T) {
+	var c raw.Credentials
+	err := yaml.UnmarshalStrict([]byte(`
+aws:
+  role_arn: arn:aws:iam::123456789012:role/prod
+  session_duration: 30m
+gcp:
+  service_account: prod@my-project.iam.gserviceaccount.com
+`), &c)
+	Ok(t, err)
+	Equals(t, raw.Credentials{
+		AWS: &raw.AWSCredentials{
+			RoleARN:         "arn:aws:iam::123456789012:role/prod",
+			SessionDuration: "30m",
+		},
+		GCP: &raw.GCPCredentials{
+			ServiceAccount: "prod@my-project.iam.gserviceaccount.com",
+		},
+	}, c)
+}
+
+func TestCredentials_Validate(t *testing.T) {
+	cases := []struct {
+		description string
+		input       raw.Credentials
+		expErr      string
+	}{
+		{
+			description: "valid",
+			input: raw.Credentials{
+				AWS: &raw.AWSCredentials{RoleARN: "arn:aws-us-gov:iam::123456789012:role/path/prod", SessionDuration: "1h"},
+				GCP: &raw.GCPCredentials{ServiceAccount: "prod@my-project.iam.gserviceaccount.com", Lifetime: "30m"},
+			},
+			expErr: "",
+		},
+		{
+			description: "empty",
+			input:       raw.Credentials{},
+			expErr:      "must set one of aws or gcp",
+		},
+		{
+			description: "not a role arn",
+			input:       raw.Credentials{AWS: &raw.AWSCredentials{RoleARN: "arn:aws:iam::123456789012:user/prod"}},
+			expErr:      `aws: (role_arn: "arn:aws:iam::123456789012:user/prod" is not an IAM role ARN, ex. arn:aws:iam::123456789012:role/atlantis.).`,
+		},
+		{
+			description: "session too long",
+			input:       raw.Credentials{AWS: &raw.AWSCredentials{RoleARN: "arn:aws:iam::123456789012:role/prod", SessionDuration: "24h"}},
+			expErr:      "aws: (session_duration: must be between 15m0s and 12h0m0s.).",
+		},
+		{
+			description: "invalid lifetime",
+			input:       raw.Credentials{GCP: &raw.GCPCredentials{ServiceAccount: "prod@my-project.iam.gserviceaccount.com", Lifetime: "1 hour"}},
+			expErr:      `gcp: (lifetime: time: unknown unit " hour" in duration "1 hour".).`,
+		},
+		{
+			description: "not a service account",
+			input:       raw.Credentials{GCP: &raw.GCPCredentials{ServiceAccount: "prod"}},
+			expErr:      `gcp: (service_account: "prod" is not a service account email.).`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			err := c.input.Validate()
+			if c.expErr == "" {
+				Ok(t, err)
+			} else {
+				ErrEquals(t, c.expErr, err)
+			}
+		})
+	}
+}
+
+func TestCredentials_ToValid(t *testing.T) {
+	Equals(t, &valid.Credentials{
+		AWS: &valid.AWSCredentials{
+			RoleARN:         "arn:aws:iam::123456789012:role/prod",
+			SessionDuration: 30 * time.Minute,
+		},
+	}, raw.Credentials{
+		AWS: &raw.AWSCredentials{RoleARN: "arn:aws:iam::123456789012:role/prod", SessionDuration: "30m"},
+	}.ToValid())
+}
@@ -2,21 +2,64 @@ package raw
 
 import (
 	"fmt"
+	"reflect"
 	"regexp"
 	"strings"
 
 	validation "github.com/go-ozzo/ozzo-validation"
 	"github.com/pkg/errors"
 	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/i18n"
 )
 
 // GlobalCfg is the raw schema for server-side repo config.
 type GlobalCfg struct {
-	Repos      []Repo              `yaml:"repos" json:"repos"`
-	Workflows  map[string]Workflow `yaml:"workflows" json:"workflows"`
-	PolicySets PolicySets          `yaml:"policies" json:"policies"`
+	// Include is the paths of other server-side repo config files to merge
+	// into this one, relative to this file. It's only supported in files and
+	// is resolved by ParserValidator.
+	Include []string `yaml:"include,omitempty" json:"include,omitempty"`
+	// Templates are repo blocks with regex ids whose fields are defaults for
+	// the repos with exact ids that they match.
+	Templates               []Repo                            `yaml:"templates,omitempty" json:"templates,omitempty"`
+	Repos                   []Repo                            `yaml:"repos" json:"repos"`
+	Workflows               map[string]Workflow               `yaml:"workflows" json:"workflows"`
+	PolicySets              PolicySets                        `yaml:"policies" json:"policies"`
+	CustomApplyRequirements map[string]CustomApplyRequirement `yaml:"custom_apply_requirements" json:"custom_apply_requirements"`
+	Secrets                 map[string]Secret                 `yaml:"secrets" json:"secrets"`
+	Commands                map[string]CustomCommand          `yaml:"commands" json:"commands"`
 }
 
+// CustomApplyRequirement is the raw schema for an apply requirement defined
+// in the server-side repo config. The requirement passes if Run exits 0.
+type CustomApplyRequirement struct {
+	Run string `yaml:"run" json:"run"`
+}
+
+// CustomCommand is the raw schema for a comment command defined in the
+// server-side repo config, ex. atlantis deploy. It runs the plan stage of
+// Workflow in each project.
+type CustomCommand struct {
+	Workflow    string `yaml:"workflow" json:"workflow"`
+	Description string `yaml:"description" json:"description"`
+	// Requirements are the apply requirements that must pass before the
+	// command runs. If unset, the project's apply requirements are used.
+	Requirements []string `yaml:"requirements,omitempty" json:"requirements,omitempty"`
+}
+
+// Secret is the raw schema for a secret defined in the server-side repo
+// config. Its value is read from the Env environment variable or from File.
+type Secret struct {
+	Env  string `yaml:"env" json:"env"`
+	File string `yaml:"file" json:"file"`
+}
+
+// secretNameRegex matches valid secret names. Secrets are passed to run steps
+// as environment variables so their names must be valid variable names.
+var secretNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// commandNameRegex matches valid custom command names.
+var commandNameRegex = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
 // Repo is the raw schema for repos in the server-side repo config.
 type Repo struct {
 	ID                        string            `yaml:"id" json:"id"`
@@ -28,6 +71,23 @@ type Repo struct {
 	AllowedOverrides          []string          `yaml:"allowed_overrides" json:"allowed_overrides"`
 	AllowCustomWorkflows      *bool             `yaml:"allow_custom_workflows,omitempty" json:"allow_custom_workflows,omitempty"`
 	DeleteSourceBranchOnMerge *bool             `yaml:"delete_source_branch_on_merge,omitempty" json:"delete_source_branch_on_merge,omitempty"`
+	AllowedApplyTeams         []string          `yaml:"allowed_apply_teams,omitempty" json:"allowed_apply_teams,omitempty"`
+	AutoplanTriggers          []AutoplanTrigger `yaml:"autoplan_triggers,omitempty" json:"autoplan_triggers,omitempty"`
+	AllowedCommands           []string          `yaml:"allowed_commands,omitempty" json:"allowed_commands,omitempty"`
+	AllowDestroyPlans         *bool             `yaml:"allow_destroy_plans,omitempty" json:"allow_destroy_plans,omitempty"`
+	OnBaseBranchUpdate        string            `yaml:"on_base_branch_update,omitempty" json:"on_base_branch_update,omitempty"`
+	TerraformDistribution     string            `yaml:"terraform_distribution,omitempty" json:"terraform_distribution,omitempty"`
+	LockGranularity           string            `yaml:"lock_granularity,omitempty" json:"lock_granularity,omitempty"`
+	Credentials               *Credentials      `yaml:"credentials,omitempty" json:"credentials,omitempty"`
+	CheckoutSubmodules        *bool             `yaml:"checkout_submodules,omitempty" json:"checkout_submodules,omitempty"`
+	CheckoutLFS               *bool             `yaml:"checkout_lfs,omitempty" json:"checkout_lfs,omitempty"`
+	AutoApply                 *bool             `yaml:"auto_apply,omitempty" json:"auto_apply,omitempty"`
+	SilenceNoChangePlans      *bool             `yaml:"silence_no_change_plans,omitempty" json:"silence_no_change_plans,omitempty"`
+	CommentFormat             string            `yaml:"comment_format,omitempty" json:"comment_format,omitempty"`
+	ApplyAllMaxProjects       *int              `yaml:"apply_all_max_projects,omitempty" json:"apply_all_max_projects,omitempty"`
+	Locale                    string            `yaml:"locale,omitempty" json:"locale,omitempty"`
+	NoWorkspaceAutocreate     *bool             `yaml:"disable_workspace_autocreate,omitempty" json:"disable_workspace_autocreate,omitempty"`
+	DisableTargeting          *bool             `yaml:"disable_targeting,omitempty" json:"disable_targeting,omitempty"`
 }
 
 func (g GlobalCfg) Validate() error {
@@ -38,6 +98,60 @@ func (g GlobalCfg) Validate() error {
 		return err
 	}
 
+	// Check that custom apply requirements have a command and don't shadow
+	// the built-in requirements.
+	for name, req := range g.CustomApplyRequirements {
+		switch {
+		case name == valid.ApprovedApplyReq || name == valid.MergeableApplyReq || name == valid.UnDivergedApplyReq || name == valid.PoliciesPassedApplyReq || name == valid.CodeownersApprovedApplyReq:
+			return fmt.Errorf("custom apply requirement %q conflicts with the built-in apply requirement of the same name", name)
+		case strings.Contains(name, ":"):
+			return fmt.Errorf("custom apply requirement %q cannot contain ':'", name)
+		case req.Run == "":
+			return fmt.Errorf("custom apply requirement %q must set \"run\"", name)
+		}
+	}
+
+	// Check that secrets have valid names and exactly one source.
+	for name, secret := range g.Secrets {
+		switch {
+		case !secretNameRegex.MatchString(name):
+			return fmt.Errorf("secret %q must only contain letters, numbers and underscores and cannot start with a number", name)
+		case secret.Env == "" && secret.File == "":
+			return fmt.Errorf("secret %q must set one of \"env\" or \"file\"", name)
+		case secret.Env != "" && secret.File != "":
+			return fmt.Errorf("secret %q can only set one of \"env\" or \"file\", found both", name)
+		}
+	}
+
+	// Check that custom commands have valid names that don't shadow the
+	// built-in commands and run a defined workflow.
+	for name, cmd := range g.Commands {
+		switch {
+		case !commandNameRegex.MatchString(name):
+			return fmt.Errorf("command %q must only contain lowercase letters, numbers, '-' and '_' and must start with a letter", name)
+		case valid.IsBuiltinCommand(name):
+			return fmt.Errorf("command %q conflicts with the built-in command of the same name", name)
+		case cmd.Workflow == "":
+			return fmt.Errorf("command %q must set \"workflow\"", name)
+		case cmd.Workflow != valid.DefaultWorkflowName && !g.hasWorkflow(cmd.Workflow):
+			return fmt.Errorf("command %q: workflow %q is not defined", name, cmd.Workflow)
+		}
+	}
+
+	// Check that all apply requirements referenced by repos and commands
+	// exist.
+	customApplyReqs := g.customApplyReqs()
+	for _, repo := range g.Repos {
+		if err := valid.ValidateApplyReqs(repo.ApplyRequirements, customApplyReqs); err != nil {
+			return err
+		}
+	}
+	for name, cmd := range g.Commands {
+		if err := valid.ValidateApplyReqs(cmd.Requirements, customApplyReqs); err != nil {
+			return errors.Wrapf(err, "command %q", name)
+		}
+	}
+
 	// Check that all workflows referenced by repos are actually defined.
 	for _, repo := range g.Repos {
 		if repo.Workflow == nil {
@@ -85,6 +199,106 @@ func (g GlobalCfg) Validate() error {
 	return nil
 }
 
+// Merge merges the config of an included file into g. The included repos and
+// templates come first so that g's take precedence. Workflows, custom apply
+// requirements, secrets and policies can only be defined in one file.
+func (g GlobalCfg) Merge(included GlobalCfg) (GlobalCfg, error) {
+	g.Repos = append(append([]Repo{}, included.Repos...), g.Repos...)
+	g.Templates = append(append([]Repo{}, included.Templates...), g.Templates...)
+
+	workflows := make(map[string]Workflow)
+	for name, w := range g.Workflows {
+		workflows[name] = w
+	}
+	for name, w := range included.Workflows {
+		if _, ok := workflows[name]; ok {
+			return g, fmt.Errorf("workflow %q is defined more than once", name)
+		}
+		workflows[name] = w
+	}
+	g.Workflows = workflows
+
+	reqs := make(map[string]CustomApplyRequirement)
+	for name, req := range g.CustomApplyRequirements {
+		reqs[name] = req
+	}
+	for name, req := range included.CustomApplyRequirements {
+		if _, ok := reqs[name]; ok {
+			return g, fmt.Errorf("custom apply requirement %q is defined more than once", name)
+		}
+		reqs[name] = req
+	}
+	g.CustomApplyRequirements = reqs
+
+	secrets := make(map[string]Secret)
+	for name, secret := range g.Secrets {
+		secrets[name] = secret
+	}
+	for name, secret := range included.Secrets {
+		if _, ok := secrets[name]; ok {
+			return g, fmt.Errorf("secret %q is defined more than once", name)
+		}
+		secrets[name] = secret
+	}
+	g.Secrets = secrets
+
+	commands := make(map[string]CustomCommand)
+	for name, cmd := range g.Commands {
+		commands[name] = cmd
+	}
+	for name, cmd := range included.Commands {
+		if _, ok := commands[name]; ok {
+			return g, fmt.Errorf("command %q is defined more than once", name)
+		}
+		commands[name] = cmd
+	}
+	g.Commands = commands
+
+	if !reflect.DeepEqual(included.PolicySets, PolicySets{}) {
+		if !reflect.DeepEqual(g.PolicySets, PolicySets{}) {
+			return g, errors.New("policies can only be defined in one file")
+		}
+		g.PolicySets = included.PolicySets
+	}
+	return g, nil
+}
+
+// ApplyTemplates merges the templates into the repos with exact ids that
+// they match and removes them. A field set in a repo takes precedence over
+// its templates, and later templates take precedence over earlier ones. The
+// regex captures of a template's id can be used in its workflow,
+// allowed_workflows and allowed_apply_teams, ex. ${1} or ${team}.
+func (g GlobalCfg) ApplyTemplates() (GlobalCfg, error) {
+	var regexes []*regexp.Regexp
+	for _, t := range g.Templates {
+		if !t.HasRegexID() {
+			return g, fmt.Errorf("template id %q must be a regex, ex. /github.com/org/.*/", t.ID)
+		}
+		if err := t.Validate(); err != nil {
+			return g, errors.Wrapf(err, "template %s", t.ID)
+		}
+		// Safe to use MustCompile because we test it in Validate().
+		regexes = append(regexes, regexp.MustCompile(t.ID[1:len(t.ID)-1]))
+	}
+
+	repos := make([]Repo, 0, len(g.Repos))
+	for _, r := range g.Repos {
+		if !r.HasRegexID() {
+			for i := len(g.Templates) - 1; i >= 0; i-- {
+				match := regexes[i].FindStringSubmatchIndex(r.ID)
+				if match == nil {
+					continue
+				}
+				r = r.withDefaults(g.Templates[i].expand(regexes[i], r.ID, match))
+			}
+		}
+		repos = append(repos, r)
+	}
+	g.Repos = repos
+	g.Templates = nil
+	return g, nil
+}
+
 func (g GlobalCfg) ToValid(defaultCfg valid.GlobalCfg) valid.GlobalCfg {
 	workflows := make(map[string]valid.Workflow)
 
@@ -125,10 +339,99 @@ func (g GlobalCfg) ToValid(defaultCfg valid.GlobalCfg) valid.GlobalCfg {
 	repos = append(defaultCfg.Repos, repos...)
 
 	return valid.GlobalCfg{
-		Repos:      repos,
-		Workflows:  workflows,
-		PolicySets: g.PolicySets.ToValid(),
+		Repos:           repos,
+		Workflows:       workflows,
+		PolicySets:      g.PolicySets.ToValid(),
+		CustomApplyReqs: g.customApplyReqs(),
+		Secrets:         g.secrets(),
+		CustomCommands:  g.customCommands(workflows),
+	}
+}
+
+func (g GlobalCfg) customCommands(workflows map[string]valid.Workflow) map[string]valid.CustomCommand {
+	if len(g.Commands) == 0 {
+		return nil
+	}
+	cmds := make(map[string]valid.CustomCommand)
+	for name, cmd := range g.Commands {
+		// The workflow is guaranteed to exist because we test for it in
+		// Validate.
+		cmds[name] = valid.CustomCommand{
+			Name:         name,
+			Description:  cmd.Description,
+			Workflow:     workflows[cmd.Workflow],
+			Requirements: cmd.Requirements,
+		}
+	}
+	return cmds
+}
+
+func (g GlobalCfg) hasWorkflow(name string) bool {
+	_, ok := g.Workflows[name]
+	return ok
+}
+
+func (g GlobalCfg) secrets() map[string]valid.Secret {
+	if len(g.Secrets) == 0 {
+		return nil
+	}
+	secrets := make(map[string]valid.Secret)
+	for name, secret := range g.Secrets {
+		secrets[name] = valid.Secret{
+			Env:  secret.Env,
+			File: secret.File,
+		}
 	}
+	return secrets
+}
+
+func (g GlobalCfg) customApplyReqs() map[string]string {
+	if len(g.CustomApplyRequirements) == 0 {
+		return nil
+	}
+	reqs := make(map[string]string)
+	for name, req := range g.CustomApplyRequirements {
+		reqs[name] = req.Run
+	}
+	return reqs
+}
+
+// withDefaults returns r with the fields it doesn't set, other than its id,
+// set from defaults.
+func (r Repo) withDefaults(defaults Repo) Repo {
+	rv := reflect.ValueOf(&r).Elem()
+	dv := reflect.ValueOf(defaults)
+	for i := 0; i < rv.NumField(); i++ {
+		if rv.Type().Field(i).Name != "ID" && rv.Field(i).IsZero() {
+			rv.Field(i).Set(dv.Field(i))
+		}
+	}
+	return r
+}
+
+// expand returns the template r with the captures of idRegex matching id
+// substituted into the fields that support them.
+func (r Repo) expand(idRegex *regexp.Regexp, id string, match []int) Repo {
+	expand := func(s string) string {
+		return string(idRegex.ExpandString(nil, s, id, match))
+	}
+	expandAll := func(strs []string) []string {
+		if strs == nil {
+			return nil
+		}
+		expanded := make([]string, 0, len(strs))
+		for _, s := range strs {
+			expanded = append(expanded, expand(s))
+		}
+		return expanded
+	}
+	if r.Workflow != nil {
+		workflow := expand(*r.Workflow)
+		r.Workflow = &workflow
+	}
+	r.AllowedWorkflows = expandAll(r.AllowedWorkflows)
+	r.AllowedApplyTeams = expandAll(r.AllowedApplyTeams)
+	return r
 }
 
 // HasRegexID returns true if r is configured with a regex id instead of an
@@ -164,8 +467,8 @@ func (r Repo) Validate() error {
 	overridesValid := func(value interface{}) error {
 		overrides := value.([]string)
 		for _, o := range overrides {
-			if o != valid.ApplyRequirementsKey && o != valid.WorkflowKey && o != valid.DeleteSourceBranchOnMergeKey {
-				return fmt.Errorf("%q is not a valid override, only %q, %q and %q are supported", o, valid.ApplyRequirementsKey, valid.WorkflowKey, valid.DeleteSourceBranchOnMergeKey)
+			if o != valid.ApplyRequirementsKey && o != valid.WorkflowKey && o != valid.DeleteSourceBranchOnMergeKey && o != valid.CredentialsKey {
+				return fmt.Errorf("%q is not a valid override, only %q, %q, %q and %q are supported", o, valid.ApplyRequirementsKey, valid.WorkflowKey, valid.DeleteSourceBranchOnMergeKey, valid.CredentialsKey)
 			}
 		}
 		return nil
@@ -189,6 +492,15 @@ func (r Repo) Validate() error {
 		validation.Field(&r.ApplyRequirements, validation.By(validApplyReq)),
 		validation.Field(&r.Workflow, validation.By(workflowExists)),
 		validation.Field(&r.DeleteSourceBranchOnMerge, validation.By(deleteSourceBranchOnMergeValid)),
+		validation.Field(&r.AutoplanTriggers),
+		validation.Field(&r.AllowedCommands, validation.By(validAllowedCommands)),
+		validation.Field(&r.OnBaseBranchUpdate, validation.In(valid.InvalidateOnBaseBranchUpdate, valid.ReplanOnBaseBranchUpdate).Error("must be one of invalidate or replan")),
+		validation.Field(&r.TerraformDistribution, validation.In(valid.TerraformDistribution, valid.OpenTofuDistribution).Error("must be one of terraform or opentofu")),
+		validation.Field(&r.LockGranularity, validation.In(valid.DirWorkspaceLockGranularity, valid.DirLockGranularity, valid.ProjectLockGranularity).Error("must be one of dir_workspace, dir or project")),
+		validation.Field(&r.Credentials),
+		validation.Field(&r.CommentFormat, validation.In(valid.FullCommentFormat, valid.RollupCommentFormat).Error("must be one of full or rollup")),
+		validation.Field(&r.ApplyAllMaxProjects, validation.Min(0).Error("must not be negative")),
+		validation.Field(&r.Locale, validation.In(i18n.English, i18n.Japanese).Error("must be one of en or ja")),
 	)
 }
 
@@ -225,6 +537,16 @@ func (r Repo) ToValid(workflows map[string]valid.Workflow, globalApplyReqs []str
 		}
 	}
 
+	// An empty list is kept as non-nil so that it overrides the triggers of
+	// earlier matching repos.
+	var autoplanTriggers []valid.AutoplanTrigger
+	if r.AutoplanTriggers != nil {
+		autoplanTriggers = make([]valid.AutoplanTrigger, 0, len(r.AutoplanTriggers))
+		for _, t := range r.AutoplanTriggers {
+			autoplanTriggers = append(autoplanTriggers, t.ToValid())
+		}
+	}
+
 	var mergedApplyReqs []string
 
 	mergedApplyReqs = append(mergedApplyReqs, r.ApplyRequirements...)
@@ -240,6 +562,11 @@ OUTER:
 		mergedApplyReqs = append(mergedApplyReqs, globalReq)
 	}
 
+	var credentials *valid.Credentials
+	if r.Credentials != nil {
+		credentials = r.Credentials.ToValid()
+	}
+
 	return valid.Repo{
 		ID:                        id,
 		IDRegex:                   idRegex,
@@ -251,5 +578,22 @@ OUTER:
 		AllowedOverrides:          r.AllowedOverrides,
 		AllowCustomWorkflows:      r.AllowCustomWorkflows,
 		DeleteSourceBranchOnMerge: r.DeleteSourceBranchOnMerge,
+		AllowedApplyTeams:         r.AllowedApplyTeams,
+		AutoplanTriggers:          autoplanTriggers,
+		AllowedCommands:           r.AllowedCommands,
+		AllowDestroyPlans:         r.AllowDestroyPlans,
+		OnBaseBranchUpdate:        r.OnBaseBranchUpdate,
+		TerraformDistribution:     r.TerraformDistribution,
+		LockGranularity:           r.LockGranularity,
+		Credentials:               credentials,
+		CheckoutSubmodules:        r.CheckoutSubmodules,
+		CheckoutLFS:               r.CheckoutLFS,
+		AutoApply:                 r.AutoApply,
+		SilenceNoChangePlans:      r.SilenceNoChangePlans,
+		CommentFormat:             r.CommentFormat,
+		ApplyAllMaxProjects:       r.ApplyAllMaxProjects,
+		Locale:                    r.Locale,
+		NoWorkspaceAutocreate:     r.NoWorkspaceAutocreate,
+		DisableTargeting:          r.DisableTargeting,
 	}
 }
@@ -0,0 +1,49 @@
+package raw
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// InitOptions is the raw schema for the options terraform init is run with
+// for a project.
+type InitOptions struct {
+	// BackendConfig are the -backend-config values, either files relative to
+	// the project's dir or key=value pairs.
+	BackendConfig []string `yaml:"backend_config,omitempty" json:"backend_config,omitempty"`
+	Upgrade       *bool    `yaml:"upgrade,omitempty" json:"upgrade,omitempty"`
+}
+
+func (i InitOptions) Validate() error {
+	validBackendConfig := func(value interface{}) error {
+		for _, c := range value.([]string) {
+			if c == "" {
+				return errors.New("cannot be empty")
+			}
+			if strings.Contains(c, "=") {
+				continue
+			}
+			if filepath.IsAbs(c) || strings.Contains(c, "..") {
+				return errors.New("files must be relative to the project's dir and cannot contain '..'")
+			}
+		}
+		return nil
+	}
+	return validation.ValidateStruct(&i,
+		validation.Field(&i.BackendConfig, validation.By(validBackendConfig)),
+	)
+}
+
+func (i InitOptions) ToValid() valid.InitOptions {
+	v := valid.InitOptions{
+		BackendConfig: i.BackendConfig,
+	}
+	if i.Upgrade != nil {
+		v.Upgrade = *i.Upgrade
+	}
+	return v
+}
@@ -0,0 +1,82 @@
+package raw_test
+
+import (
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/yaml/raw"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestInitOptions_UnmarshalYAML(t *testing.T) {
+	var i raw.InitOptions
+	err := yaml.UnmarshalStrict([]byte(`
+backend_config:
+- backend/prod.hcl
+- key=prod/terraform.tfstate
+upgrade: true
+`), &i)
+	Ok(t, err)
+	upgrade := true
+	Equals(t, raw.InitOptions{
+		BackendConfig: []string{"backend/prod.hcl", "key=prod/terraform.tfstate"},
+		Upgrade:       &upgrade,
+	}, i)
+}
+
+func TestInitOptions_Validate(t *testing.T) {
+	cases := []struct {
+		description string
+		input       raw.InitOptions
+		expErr      string
+	}{
+		{
+			description: "valid",
+			input:       raw.InitOptions{BackendConfig: []string{"backend/prod.hcl", "bucket=../not-a-file"}},
+			expErr:      "",
+		},
+		{
+			description: "empty",
+			input:       raw.InitOptions{},
+			expErr:      "",
+		},
+		{
+			description: "empty backend config",
+			input:       raw.InitOptions{BackendConfig: []string{""}},
+			expErr:      "backend_config: cannot be empty.",
+		},
+		{
+			description: "file outside the project",
+			input:       raw.InitOptions{BackendConfig: []string{"../backend.hcl"}},
+			expErr:      "backend_config: files must be relative to the project's dir and cannot contain '..'.",
+		},
+		{
+			description: "absolute file",
+			input:       raw.InitOptions{BackendConfig: []string{"/etc/backend.hcl"}},
+			expErr:      "backend_config: files must be relative to the project's dir and cannot contain '..'.",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			err := c.input.Validate()
+			if c.expErr == "" {
+				Ok(t, err)
+			} else {
+				ErrEquals(t, c.expErr, err)
+			}
+		})
+	}
+}
+
+func TestInitOptions_ToValid(t *testing.T) {
+	upgrade := true
+	Equals(t, valid.InitOptions{
+		BackendConfig: []string{"backend/prod.hcl"},
+		Upgrade:       true,
+	}, raw.InitOptions{
+		BackendConfig: []string{"backend/prod.hcl"},
+		Upgrade:       &upgrade,
+	}.ToValid())
+	Equals(t, valid.InitOptions{}, raw.InitOptions{}.ToValid())
+}
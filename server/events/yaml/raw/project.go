@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/url"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	validation "github.com/go-ozzo/ozzo-validation"
@@ -13,21 +14,42 @@ import (
 )
 
 const (
-	DefaultWorkspace           = "default"
-	ApprovedApplyRequirement   = "approved"
-	MergeableApplyRequirement  = "mergeable"
-	UnDivergedApplyRequirement = "undiverged"
+	DefaultWorkspace                   = "default"
+	ApprovedApplyRequirement           = "approved"
+	MergeableApplyRequirement          = "mergeable"
+	UnDivergedApplyRequirement         = "undiverged"
+	CodeownersApprovedApplyRequirement = "codeowners_approved"
+	// ReservedWorkspace can't be used as a workspace because Atlantis clones
+	// each pull request into a dir with this name next to its workspaces.
+	ReservedWorkspace = ".atlantis-clone"
 )
 
+// tfeWorkspaceRegex matches tfe_workspace values, ex. my-org/my-workspace.
+var tfeWorkspaceRegex = regexp.MustCompile(`^[^/\s]+/[^/\s]+$`)
+
+// outputNameRegex matches terraform identifiers, which output names must be.
+var outputNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_-]*$`)
+
 type Project struct {
 	Name                      *string   `yaml:"name,omitempty"`
 	Dir                       *string   `yaml:"dir,omitempty"`
 	Workspace                 *string   `yaml:"workspace,omitempty"`
 	Workflow                  *string   `yaml:"workflow,omitempty"`
 	TerraformVersion          *string   `yaml:"terraform_version,omitempty"`
+	TerraformDistribution     *string   `yaml:"terraform_distribution,omitempty"`
+	TFEWorkspace              *string   `yaml:"tfe_workspace,omitempty"`
 	Autoplan                  *Autoplan `yaml:"autoplan,omitempty"`
 	ApplyRequirements         []string  `yaml:"apply_requirements,omitempty"`
 	DeleteSourceBranchOnMerge *bool     `yaml:"delete_source_branch_on_merge,omitempty"`
+	Automerge                 *bool     `yaml:"automerge,omitempty"`
+	AllowedCommands           []string  `yaml:"allowed_commands,omitempty"`
+	// Credentials, if set, are the cloud credentials obtained for the
+	// project before its steps are run.
+	Credentials *Credentials `yaml:"credentials,omitempty"`
+	// Init, if set, are the options terraform init is run with.
+	Init *InitOptions `yaml:"init,omitempty"`
+	// Outputs are the names of the outputs shown in the apply comment.
+	Outputs []string `yaml:"outputs,omitempty"`
 }
 
 func (p Project) Validate() error {
@@ -51,11 +73,37 @@ func (p Project) Validate() error {
 		}
 		return nil
 	}
+
+	validWorkspace := func(value interface{}) error {
+		strPtr := value.(*string)
+		if strPtr != nil && *strPtr == ReservedWorkspace {
+			return fmt.Errorf("%q is reserved by Atlantis", *strPtr)
+		}
+		return nil
+	}
+
+	validTFEWorkspace := func(value interface{}) error {
+		strPtr := value.(*string)
+		if strPtr == nil {
+			return nil
+		}
+		if !tfeWorkspaceRegex.MatchString(*strPtr) {
+			return fmt.Errorf("%q must be of the form <organization>/<workspace>", *strPtr)
+		}
+		return nil
+	}
 	return validation.ValidateStruct(&p,
 		validation.Field(&p.Dir, validation.Required, validation.By(hasDotDot)),
 		validation.Field(&p.ApplyRequirements, validation.By(validApplyReq)),
 		validation.Field(&p.TerraformVersion, validation.By(VersionValidator)),
+		validation.Field(&p.TerraformDistribution, validation.In(valid.TerraformDistribution, valid.OpenTofuDistribution).Error("must be one of terraform or opentofu")),
 		validation.Field(&p.Name, validation.By(validName)),
+		validation.Field(&p.Workspace, validation.By(validWorkspace)),
+		validation.Field(&p.TFEWorkspace, validation.By(validTFEWorkspace)),
+		validation.Field(&p.AllowedCommands, validation.By(validAllowedCommands)),
+		validation.Field(&p.Credentials),
+		validation.Field(&p.Init),
+		validation.Field(&p.Outputs, validation.By(validOutputs)),
 	)
 }
 
@@ -77,6 +125,10 @@ func (p Project) ToValid() valid.Project {
 	if p.TerraformVersion != nil {
 		v.TerraformVersion, _ = version.NewVersion(*p.TerraformVersion)
 	}
+	v.TerraformDistribution = p.TerraformDistribution
+	if p.TFEWorkspace != nil {
+		v.TFEWorkspace = *p.TFEWorkspace
+	}
 	if p.Autoplan == nil {
 		v.Autoplan = DefaultAutoPlan()
 	} else {
@@ -92,6 +144,16 @@ func (p Project) ToValid() valid.Project {
 		v.DeleteSourceBranchOnMerge = p.DeleteSourceBranchOnMerge
 	}
 
+	v.Automerge = p.Automerge
+	v.AllowedCommands = p.AllowedCommands
+	if p.Credentials != nil {
+		v.Credentials = p.Credentials.ToValid()
+	}
+	if p.Init != nil {
+		v.Init = p.Init.ToValid()
+	}
+	v.Outputs = p.Outputs
+
 	return v
 }
 
@@ -104,11 +166,45 @@ func validProjectName(name string) bool {
 	return nameWithoutSlashes == url.QueryEscape(nameWithoutSlashes)
 }
 
+// validApplyReq checks the syntax of apply requirements. Whether each
+// requirement exists is checked once the server-side config is known, see
+// valid.ValidateApplyReqs.
 func validApplyReq(value interface{}) error {
 	reqs := value.([]string)
 	for _, r := range reqs {
-		if r != ApprovedApplyRequirement && r != MergeableApplyRequirement && r != UnDivergedApplyRequirement {
-			return fmt.Errorf("%q is not a valid apply_requirement, only %q, %q and %q are supported", r, ApprovedApplyRequirement, MergeableApplyRequirement, UnDivergedApplyRequirement)
+		if r == "" {
+			return errors.New("apply_requirement cannot be empty")
+		}
+		if _, _, err := valid.ParsePlanNewerThanApplyReq(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validOutputs checks that outputs only lists valid output names.
+func validOutputs(value interface{}) error {
+	for _, o := range value.([]string) {
+		if !outputNameRegex.MatchString(o) {
+			return fmt.Errorf("%q is not a valid output name", o)
+		}
+	}
+	return nil
+}
+
+// validAllowedCommands checks that allowed_commands only lists commands that
+// can be restricted.
+func validAllowedCommands(value interface{}) error {
+	for _, c := range value.([]string) {
+		found := false
+		for _, r := range valid.RestrictableCommands {
+			if c == r {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%q is not a valid command, only %s are supported", c, strings.Join(valid.RestrictableCommands, ", "))
 		}
 	}
 	return nil
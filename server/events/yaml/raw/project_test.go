@@ -38,17 +38,21 @@ dir: mydir
 workspace: workspace
 workflow: workflow
 terraform_version: v0.11.0
+terraform_distribution: opentofu
+tfe_workspace: org/ws
 autoplan:
   when_modified: []
   enabled: false
 apply_requirements:
 - mergeable`,
 			exp: raw.Project{
-				Name:             String("myname"),
-				Dir:              String("mydir"),
-				Workspace:        String("workspace"),
-				Workflow:         String("workflow"),
-				TerraformVersion: String("v0.11.0"),
+				Name:                  String("myname"),
+				Dir:                   String("mydir"),
+				Workspace:             String("workspace"),
+				Workflow:              String("workflow"),
+				TerraformVersion:      String("v0.11.0"),
+				TerraformDistribution: String("opentofu"),
+				TFEWorkspace:          String("org/ws"),
 				Autoplan: &raw.Autoplan{
 					WhenModified: []string{},
 					Enabled:      Bool(false),
@@ -96,12 +100,70 @@ func TestProject_Validate(t *testing.T) {
 			expErr: "dir: cannot contain '..'.",
 		},
 		{
-			description: "apply reqs with unsupported",
+			// Whether a requirement exists depends on the server-side config
+			// so it's checked when the repo config is validated against it.
+			description: "apply reqs with custom requirement",
 			input: raw.Project{
 				Dir:               String("."),
-				ApplyRequirements: []string{"unsupported"},
+				ApplyRequirements: []string{"custom"},
 			},
-			expErr: "apply_requirements: \"unsupported\" is not a valid apply_requirement, only \"approved\", \"mergeable\" and \"undiverged\" are supported.",
+			expErr: "",
+		},
+		{
+			description: "apply reqs with plan_newer_than requirement",
+			input: raw.Project{
+				Dir:               String("."),
+				ApplyRequirements: []string{"plan_newer_than:24h"},
+			},
+			expErr: "",
+		},
+		{
+			description: "apply reqs with invalid plan_newer_than duration",
+			input: raw.Project{
+				Dir:               String("."),
+				ApplyRequirements: []string{"plan_newer_than:1day"},
+			},
+			expErr: "apply_requirements: \"plan_newer_than:1day\" is not a valid apply_requirement: time: unknown unit \"day\" in duration \"1day\".",
+		},
+		{
+			description: "reserved workspace",
+			input: raw.Project{
+				Dir:       String("."),
+				Workspace: String(".atlantis-clone"),
+			},
+			expErr: "workspace: \".atlantis-clone\" is reserved by Atlantis.",
+		},
+		{
+			description: "allowed commands",
+			input: raw.Project{
+				Dir:             String("."),
+				AllowedCommands: []string{"plan", "import"},
+			},
+			expErr: "",
+		},
+		{
+			description: "invalid allowed command",
+			input: raw.Project{
+				Dir:             String("."),
+				AllowedCommands: []string{"plan", "destroy"},
+			},
+			expErr: "allowed_commands: \"destroy\" is not a valid command, only plan, apply, import, state are supported.",
+		},
+		{
+			description: "outputs",
+			input: raw.Project{
+				Dir:     String("."),
+				Outputs: []string{"endpoint", "role_arn"},
+			},
+			expErr: "",
+		},
+		{
+			description: "invalid output name",
+			input: raw.Project{
+				Dir:     String("."),
+				Outputs: []string{"endpoint", "module.vpc.id"},
+			},
+			expErr: "outputs: \"module.vpc.id\" is not a valid output name.",
 		},
 		{
 			description: "apply reqs with approved requirement",
@@ -183,6 +245,30 @@ func TestProject_Validate(t *testing.T) {
 			},
 			expErr: "",
 		},
+		{
+			description: "invalid terraform distribution",
+			input: raw.Project{
+				Dir:                   String("."),
+				TerraformDistribution: String("terragrunt"),
+			},
+			expErr: "terraform_distribution: must be one of terraform or opentofu.",
+		},
+		{
+			description: "tfe workspace",
+			input: raw.Project{
+				Dir:          String("."),
+				TFEWorkspace: String("org/ws"),
+			},
+			expErr: "",
+		},
+		{
+			description: "invalid tfe workspace",
+			input: raw.Project{
+				Dir:          String("."),
+				TFEWorkspace: String("ws"),
+			},
+			expErr: "tfe_workspace: \"ws\" must be of the form <organization>/<workspace>.",
+		},
 		{
 			description: "empty string for project name",
 			input: raw.Project{
@@ -273,10 +359,12 @@ func TestProject_ToValid(t *testing.T) {
 		{
 			description: "all set",
 			input: raw.Project{
-				Dir:              String("."),
-				Workspace:        String("myworkspace"),
-				Workflow:         String("myworkflow"),
-				TerraformVersion: String("v0.11.0"),
+				Dir:                   String("."),
+				Workspace:             String("myworkspace"),
+				Workflow:              String("myworkflow"),
+				TerraformVersion:      String("v0.11.0"),
+				TerraformDistribution: String("opentofu"),
+				TFEWorkspace:          String("org/ws"),
 				Autoplan: &raw.Autoplan{
 					WhenModified: []string{"hi"},
 					Enabled:      Bool(false),
@@ -285,10 +373,12 @@ func TestProject_ToValid(t *testing.T) {
 				Name:              String("myname"),
 			},
 			exp: valid.Project{
-				Dir:              ".",
-				Workspace:        "myworkspace",
-				WorkflowName:     String("myworkflow"),
-				TerraformVersion: tfVersionPointEleven,
+				Dir:                   ".",
+				Workspace:             "myworkspace",
+				WorkflowName:          String("myworkflow"),
+				TerraformVersion:      tfVersionPointEleven,
+				TerraformDistribution: String("opentofu"),
+				TFEWorkspace:          "org/ws",
 				Autoplan: valid.Autoplan{
 					WhenModified: []string{"hi"},
 					Enabled:      false,
@@ -297,6 +387,28 @@ func TestProject_ToValid(t *testing.T) {
 				Name:              String("myname"),
 			},
 		},
+		{
+			description: "init options",
+			input: raw.Project{
+				Dir: String("."),
+				Init: &raw.InitOptions{
+					BackendConfig: []string{"backend/prod.hcl"},
+					Upgrade:       Bool(true),
+				},
+			},
+			exp: valid.Project{
+				Dir:       ".",
+				Workspace: "default",
+				Autoplan: valid.Autoplan{
+					WhenModified: []string{"**/*.tf*", "**/terragrunt.hcl"},
+					Enabled:      true,
+				},
+				Init: valid.InitOptions{
+					BackendConfig: []string{"backend/prod.hcl"},
+					Upgrade:       true,
+				},
+			},
+		},
 		{
 			description: "tf version without 'v'",
 			input: raw.Project{
@@ -2,8 +2,10 @@ package raw
 
 import (
 	"errors"
+	"text/template"
 
 	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 )
 
@@ -29,6 +31,8 @@ type RepoCfg struct {
 	Workflows                 map[string]Workflow `yaml:"workflows,omitempty"`
 	PolicySets                PolicySets          `yaml:"policies,omitempty"`
 	Automerge                 *bool               `yaml:"automerge,omitempty"`
+	AutomergeMethod           *string             `yaml:"automerge_method,omitempty"`
+	AutomergeCommitMessage    *string             `yaml:"automerge_commit_message,omitempty"`
 	ParallelApply             *bool               `yaml:"parallel_apply,omitempty"`
 	ParallelPlan              *bool               `yaml:"parallel_plan,omitempty"`
 	DeleteSourceBranchOnMerge *bool               `yaml:"delete_source_branch_on_merge,omitempty"`
@@ -49,9 +53,24 @@ func (r RepoCfg) Validate() error {
 		validation.Field(&r.Version, validation.By(equals2)),
 		validation.Field(&r.Projects),
 		validation.Field(&r.Workflows),
+		validation.Field(&r.AutomergeMethod, validation.In(models.MergeCommitMergeMethod, models.SquashMergeMethod, models.RebaseMergeMethod).Error("must be one of merge, squash or rebase")),
+		validation.Field(&r.AutomergeCommitMessage, validation.By(validCommitMessageTemplate)),
 	)
 }
 
+// validCommitMessageTemplate checks that the automerge commit message is a
+// valid text/template.
+func validCommitMessageTemplate(value interface{}) error {
+	msg := value.(*string)
+	if msg == nil {
+		return nil
+	}
+	if _, err := template.New("automerge_commit_message").Parse(*msg); err != nil {
+		return err
+	}
+	return nil
+}
+
 func (r RepoCfg) ToValid() valid.RepoCfg {
 	validWorkflows := make(map[string]valid.Workflow)
 	for k, v := range r.Workflows {
@@ -78,11 +97,23 @@ func (r RepoCfg) ToValid() valid.RepoCfg {
 		parallelPlan = *r.ParallelPlan
 	}
 
+	var automergeMethod string
+	if r.AutomergeMethod != nil {
+		automergeMethod = *r.AutomergeMethod
+	}
+
+	var automergeCommitMessage string
+	if r.AutomergeCommitMessage != nil {
+		automergeCommitMessage = *r.AutomergeCommitMessage
+	}
+
 	return valid.RepoCfg{
 		Version:                   *r.Version,
 		Projects:                  validProjects,
 		Workflows:                 validWorkflows,
 		Automerge:                 automerge,
+		AutomergeMethod:           automergeMethod,
+		AutomergeCommitMessage:    automergeCommitMessage,
 		ParallelApply:             parallelApply,
 		ParallelPlan:              parallelPlan,
 		ParallelPolicyCheck:       parallelPlan,
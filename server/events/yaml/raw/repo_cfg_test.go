@@ -213,6 +213,31 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			expErr: "version: only versions 2 and 3 are supported.",
 		},
+		{
+			description: "valid automerge settings",
+			input: raw.RepoCfg{
+				Version:                Int(3),
+				AutomergeMethod:        String("squash"),
+				AutomergeCommitMessage: String("Merge #{{ .PullNum }}"),
+			},
+			expErr: "",
+		},
+		{
+			description: "invalid automerge_method",
+			input: raw.RepoCfg{
+				Version:         Int(3),
+				AutomergeMethod: String("fast-forward"),
+			},
+			expErr: "automerge_method: must be one of merge, squash or rebase.",
+		},
+		{
+			description: "invalid automerge_commit_message",
+			input: raw.RepoCfg{
+				Version:                Int(3),
+				AutomergeCommitMessage: String("Merge #{{ .PullNum"),
+			},
+			expErr: "automerge_commit_message: template: automerge_commit_message:1: unclosed action.",
+		},
 	}
 	validation.ErrorTag = "yaml"
 	for _, c := range cases {
@@ -280,6 +305,39 @@ func TestConfig_ToValid(t *testing.T) {
 				Workflows:     map[string]valid.Workflow{},
 			},
 		},
+		{
+			description: "automerge method and commit message set",
+			input: raw.RepoCfg{
+				Version:                Int(3),
+				Automerge:              Bool(true),
+				AutomergeMethod:        String("squash"),
+				AutomergeCommitMessage: String("Merge #{{ .PullNum }}"),
+				Projects: []raw.Project{
+					{
+						Dir:       String("."),
+						Automerge: Bool(false),
+					},
+				},
+			},
+			exp: valid.RepoCfg{
+				Version:                3,
+				Automerge:              true,
+				AutomergeMethod:        "squash",
+				AutomergeCommitMessage: "Merge #{{ .PullNum }}",
+				Workflows:              map[string]valid.Workflow{},
+				Projects: []valid.Project{
+					{
+						Dir:       ".",
+						Workspace: "default",
+						Autoplan: valid.Autoplan{
+							WhenModified: []string{"**/*.tf*", "**/terragrunt.hcl"},
+							Enabled:      true,
+						},
+						Automerge: Bool(false),
+					},
+				},
+			},
+		},
 		{
 			description: "automerge and parallel_apply false",
 			input: raw.RepoCfg{
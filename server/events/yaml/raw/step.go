@@ -6,13 +6,16 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/hashicorp/go-version"
 	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 )
 
 const (
 	ExtraArgsKey        = "extra_args"
+	TimeoutKey          = "timeout"
 	NameArgKey          = "name"
 	CommandArgKey       = "command"
 	ValueArgKey         = "value"
@@ -23,23 +26,95 @@ const (
 	ApplyStepName       = "apply"
 	InitStepName        = "init"
 	EnvStepName         = "env"
+	MultiEnvStepName    = "multienv"
+	TerragruntStepName  = "terragrunt"
+	OutputStepName      = "output"
+	ScanStepName        = "scan"
 )
 
+// timeoutStepNames are the steps that can have a timeout. They're the steps
+// that run terraform since it's interrupted when the timeout passes.
+var timeoutStepNames = map[string]bool{
+	InitStepName:  true,
+	PlanStepName:  true,
+	ShowStepName:  true,
+	ApplyStepName: true,
+}
+
+// builtinStepArgs are the args of a built-in step with a timeout.
+type builtinStepArgs struct {
+	ExtraArgs []string `yaml:"extra_args,omitempty" json:"extra_args,omitempty"`
+	Timeout   string   `yaml:"timeout" json:"timeout"`
+}
+
+// ScanArgs are the args of a scan step.
+type ScanArgs struct {
+	Tool      string   `yaml:"tool,omitempty" json:"tool,omitempty"`
+	Version   string   `yaml:"version,omitempty" json:"version,omitempty"`
+	FailOn    string   `yaml:"fail_on,omitempty" json:"fail_on,omitempty"`
+	ExtraArgs []string `yaml:"extra_args,omitempty" json:"extra_args,omitempty"`
+}
+
+func (a ScanArgs) Validate() error {
+	validTool := func(value interface{}) error {
+		tool := value.(string)
+		for _, t := range valid.ScanTools {
+			if tool == t {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not a supported scanner, must be one of %s", tool, strings.Join(valid.ScanTools, ", "))
+	}
+	validVersion := func(value interface{}) error {
+		if value.(string) == "" {
+			return nil
+		}
+		if _, err := version.NewVersion(value.(string)); err != nil {
+			return fmt.Errorf("%q is not a valid version: %s", value, err)
+		}
+		return nil
+	}
+	validFailOn := func(value interface{}) error {
+		failOn := strings.ToUpper(value.(string))
+		if failOn == "" {
+			return nil
+		}
+		for _, s := range valid.ScanSeverities {
+			if failOn == s {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not a severity, must be one of low, medium, high or critical", value)
+	}
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Tool, validation.Required, validation.By(validTool)),
+		validation.Field(&a.Version, validation.By(validVersion)),
+		validation.Field(&a.FailOn, validation.By(validFailOn)),
+	)
+}
+
 // Step represents a single action/command to perform. In YAML, it can be set as
 // 1. A single string for a built-in command:
 //    - init
 //    - plan
 //    - policy_check
+//    - terragrunt
 // 2. A map for an env step with name and command or value
 //    - env:
 //        name: test
 //        command: echo 312
 //        value: value
-// 3. A map for a built-in command and extra_args:
+// 3. A map for a built-in command and extra_args and/or a timeout:
 //    - plan:
 //        extra_args: [-var-file=staging.tfvars]
-// 4. A map for a custom run command:
+//        timeout: 30m
+// 4. A map for a custom run command or a multienv command:
 //    - run: my custom command
+//    - multienv: my-command-that-outputs KEY=VALUE lines
+// 5. A map for a scan step with the scanner to run:
+//    - scan:
+//        tool: checkov
+//        fail_on: high
 // Here we parse step in the most generic fashion possible. See fields for more
 // details.
 type Step struct {
@@ -50,8 +125,12 @@ type Step struct {
 	Env map[string]map[string]string
 	// Map will be set in case #3 above.
 	Map map[string]map[string][]string
+	// Timeout will be set in case #3 above if the step has a timeout.
+	Timeout string
 	// StringVal will be set in case #4 above.
 	StringVal map[string]string
+	// Scan will be set in case #5 above.
+	Scan *ScanArgs
 }
 
 func (s *Step) UnmarshalYAML(unmarshal func(interface{}) error) error {
@@ -82,7 +161,10 @@ func (s Step) validStepName(stepName string) bool {
 		stepName == ApplyStepName ||
 		stepName == EnvStepName ||
 		stepName == ShowStepName ||
-		stepName == PolicyCheckStepName
+		stepName == PolicyCheckStepName ||
+		stepName == TerragruntStepName ||
+		stepName == OutputStepName ||
+		stepName == ScanStepName
 }
 
 func (s Step) Validate() error {
@@ -191,7 +273,7 @@ func (s Step) Validate() error {
 				len(keys), strings.Join(keys, ","))
 		}
 		for stepName := range elem {
-			if stepName != RunStepName {
+			if stepName != RunStepName && stepName != MultiEnvStepName {
 				return fmt.Errorf("%q is not a valid step type", stepName)
 			}
 		}
@@ -201,8 +283,27 @@ func (s Step) Validate() error {
 	if s.Key != nil {
 		return validation.Validate(s.Key, validation.By(validStep))
 	}
+	timeout := func(value interface{}) error {
+		str := value.(string)
+		if str == "" {
+			return nil
+		}
+		for stepName := range s.Map {
+			if !timeoutStepNames[stepName] {
+				return fmt.Errorf("%s steps don't support a %s, only init, plan, show and apply do", stepName, TimeoutKey)
+			}
+		}
+		if d, err := time.ParseDuration(str); err != nil || d <= 0 {
+			return fmt.Errorf("%s %q must be a positive duration, ex. 30m", TimeoutKey, str)
+		}
+		return nil
+	}
+
 	if len(s.Map) > 0 {
-		return validation.Validate(s.Map, validation.By(extraArgs))
+		if err := validation.Validate(s.Map, validation.By(extraArgs)); err != nil {
+			return err
+		}
+		return validation.Validate(s.Timeout, validation.By(timeout))
 	}
 	if len(s.Env) > 0 {
 		return validation.Validate(s.Env, validation.By(envStep))
@@ -210,15 +311,22 @@ func (s Step) Validate() error {
 	if len(s.StringVal) > 0 {
 		return validation.Validate(s.StringVal, validation.By(runStep))
 	}
+	if s.Scan != nil {
+		return s.Scan.Validate()
+	}
 	return errors.New("step element is empty")
 }
 
 func (s Step) ToValid() valid.Step {
 	// This will trigger in case #1 (see Step docs).
 	if s.Key != nil {
-		return valid.Step{
+		step := valid.Step{
 			StepName: *s.Key,
 		}
+		if *s.Key == ScanStepName {
+			step.Scan = &valid.ScanOptions{Tool: valid.DefaultScanTool}
+		}
+		return step
 	}
 
 	// This will trigger in case #2 (see Step docs).
@@ -240,10 +348,18 @@ func (s Step) ToValid() valid.Step {
 		// After validation we assume there's only one key and it's a valid
 		// step name so we just use the first one.
 		for stepName, stepArgs := range s.Map {
-			return valid.Step{
+			// The timeout is checked in Validate() so the error can be
+			// ignored.
+			timeout, _ := time.ParseDuration(s.Timeout)
+			step := valid.Step{
 				StepName:  stepName,
 				ExtraArgs: stepArgs[ExtraArgsKey],
+				Timeout:   timeout,
+			}
+			if stepName == ScanStepName {
+				step.Scan = &valid.ScanOptions{Tool: valid.DefaultScanTool}
 			}
+			return step
 		}
 	}
 
@@ -251,14 +367,32 @@ func (s Step) ToValid() valid.Step {
 	if len(s.StringVal) > 0 {
 		// After validation we assume there's only one key and it's a valid
 		// step name so we just use the first one.
-		for _, v := range s.StringVal {
+		for stepName, v := range s.StringVal {
 			return valid.Step{
-				StepName:   RunStepName,
+				StepName:   stepName,
 				RunCommand: v,
 			}
 		}
 	}
 
+	// This will trigger in case #5 (see Step docs).
+	if s.Scan != nil {
+		// The version is checked in Validate() so the error can be ignored.
+		v, _ := version.NewVersion(s.Scan.Version)
+		if s.Scan.Version == "" {
+			v = nil
+		}
+		return valid.Step{
+			StepName:  ScanStepName,
+			ExtraArgs: s.Scan.ExtraArgs,
+			Scan: &valid.ScanOptions{
+				Tool:    s.Scan.Tool,
+				Version: v,
+				FailOn:  strings.ToUpper(s.Scan.FailOn),
+			},
+		}
+	}
+
 	panic("step was not valid. This is a bug!")
 }
 
@@ -266,7 +400,8 @@ func (s Step) ToValid() valid.Step {
 // a step into one of its three forms. We need to implement a custom unmarshal
 // function because steps can either be:
 // 1. a built-in step: " - init"
-// 2. a built-in step with extra_args: " - init: {extra_args: [arg1] }"
+// 2. a built-in step with extra_args and/or a timeout:
+//    " - init: {extra_args: [arg1], timeout: 10m }"
 // 3. a custom run step: " - run: my custom command"
 // It takes a parameter unmarshal that is a function that tries to unmarshal
 // the current element into a given object.
@@ -296,6 +431,42 @@ func (s *Step) unmarshalGeneric(unmarshal func(interface{}) error) error {
 		return nil
 	}
 
+	// This represents a step with a timeout, ex:
+	//   init:
+	//     extra_args: [a, b] //optional
+	//     timeout: 10m
+	// The timeout isn't a list so it doesn't unmarshal into the map above.
+	var timeoutStep map[string]builtinStepArgs
+	err = unmarshal(&timeoutStep)
+	if err == nil && len(timeoutStep) == 1 {
+		for stepName, args := range timeoutStep {
+			if args.Timeout == "" {
+				break
+			}
+			stepArgs := map[string][]string{}
+			if args.ExtraArgs != nil {
+				stepArgs[ExtraArgsKey] = args.ExtraArgs
+			}
+			s.Map = map[string]map[string][]string{stepName: stepArgs}
+			s.Timeout = args.Timeout
+			return nil
+		}
+	}
+
+	// This represents a scan step, ex:
+	//   scan:
+	//     tool: checkov
+	//     version: 2.3.0 //optional
+	//     fail_on: high //optional
+	//     extra_args: [--skip-check, CKV_AWS_18] //optional
+	// The tool isn't a list so it doesn't unmarshal into the maps above.
+	var scanStep map[string]ScanArgs
+	err = unmarshal(&scanStep)
+	if args, ok := scanStep[ScanStepName]; err == nil && ok && len(scanStep) == 1 {
+		s.Scan = &args
+		return nil
+	}
+
 	// This represents an env step, ex:
 	//   env:
 	//     name: k
@@ -308,10 +479,11 @@ func (s *Step) unmarshalGeneric(unmarshal func(interface{}) error) error {
 		return nil
 	}
 
-	// Try to unmarshal as a custom run step, ex.
+	// Try to unmarshal as a custom run or multienv step, ex.
 	// steps:
 	// - run: my command
-	// We validate if the key is run later.
+	// - multienv: my command
+	// We validate if the key is run or multienv later.
 	var runStep map[string]string
 	err = unmarshal(&runStep)
 	if err == nil {
@@ -325,10 +497,18 @@ func (s *Step) unmarshalGeneric(unmarshal func(interface{}) error) error {
 func (s Step) marshalGeneric() (interface{}, error) {
 	if len(s.StringVal) != 0 {
 		return s.StringVal, nil
+	} else if len(s.Map) != 0 && s.Timeout != "" {
+		out := make(map[string]builtinStepArgs)
+		for stepName, args := range s.Map {
+			out[stepName] = builtinStepArgs{ExtraArgs: args[ExtraArgsKey], Timeout: s.Timeout}
+		}
+		return out, nil
 	} else if len(s.Map) != 0 {
 		return s.Map, nil
 	} else if len(s.Env) != 0 {
 		return s.Env, nil
+	} else if s.Scan != nil {
+		return map[string]ScanArgs{ScanStepName: *s.Scan}, nil
 	} else if s.Key != nil {
 		return s.Key, nil
 	}
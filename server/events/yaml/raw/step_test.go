@@ -2,7 +2,9 @@ package raw_test
 
 import (
 	"testing"
+	"time"
 
+	"github.com/hashicorp/go-version"
 	"github.com/runatlantis/atlantis/server/events/yaml/raw"
 	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 	. "github.com/runatlantis/atlantis/testing"
@@ -116,6 +118,16 @@ run: my command`,
 				},
 			},
 		},
+		{
+			description: "multienv step",
+			input: `
+multienv: my command`,
+			exp: raw.Step{
+				StringVal: map[string]string{
+					"multienv": "my command",
+				},
+			},
+		},
 		{
 			description: "run step multiple top-level keys",
 			input: `
@@ -141,6 +153,54 @@ key: value`,
 			},
 		},
 
+		// Built-in steps with a timeout
+		{
+			description: "extra_args and timeout",
+			input: `
+plan:
+  extra_args: [arg1, arg2]
+  timeout: 30m`,
+			exp: raw.Step{
+				Map: MapType{
+					"plan": {
+						"extra_args": {"arg1", "arg2"},
+					},
+				},
+				Timeout: "30m",
+			},
+		},
+		{
+			description: "timeout only",
+			input: `
+init:
+  timeout: 10m`,
+			exp: raw.Step{
+				Map: MapType{
+					"init": {},
+				},
+				Timeout: "10m",
+			},
+		},
+
+		// Scan steps
+		{
+			description: "scan step",
+			input: `
+scan:
+  tool: checkov
+  version: 2.3.0
+  fail_on: high
+  extra_args: [--skip-check, CKV_AWS_18]`,
+			exp: raw.Step{
+				Scan: &raw.ScanArgs{
+					Tool:      "checkov",
+					Version:   "2.3.0",
+					FailOn:    "high",
+					ExtraArgs: []string{"--skip-check", "CKV_AWS_18"},
+				},
+			},
+		},
+
 		// Errors
 		{
 			description: "extra args style no slice strings",
@@ -247,6 +307,55 @@ func TestStep_Validate(t *testing.T) {
 			},
 			expErr: "",
 		},
+		{
+			description: "terragrunt step",
+			input: raw.Step{
+				Key: String("terragrunt"),
+			},
+			expErr: "",
+		},
+		{
+			description: "output step",
+			input: raw.Step{
+				Key: String("output"),
+			},
+			expErr: "",
+		},
+		{
+			description: "scan step",
+			input: raw.Step{
+				Key: String("scan"),
+			},
+			expErr: "",
+		},
+		{
+			description: "scan step with tool",
+			input: raw.Step{
+				Scan: &raw.ScanArgs{Tool: "checkov", Version: "2.3.0", FailOn: "high"},
+			},
+			expErr: "",
+		},
+		{
+			description: "scan step without tool",
+			input: raw.Step{
+				Scan: &raw.ScanArgs{FailOn: "high"},
+			},
+			expErr: "tool: cannot be blank.",
+		},
+		{
+			description: "scan step with unsupported tool",
+			input: raw.Step{
+				Scan: &raw.ScanArgs{Tool: "trivy"},
+			},
+			expErr: "tool: \"trivy\" is not a supported scanner, must be one of tfsec, checkov.",
+		},
+		{
+			description: "scan step with invalid severity",
+			input: raw.Step{
+				Scan: &raw.ScanArgs{Tool: "tfsec", FailOn: "severe"},
+			},
+			expErr: "fail_on: \"severe\" is not a severity, must be one of low, medium, high or critical.",
+		},
 		{
 			description: "run step",
 			input: raw.Step{
@@ -256,6 +365,15 @@ func TestStep_Validate(t *testing.T) {
 			},
 			expErr: "",
 		},
+		{
+			description: "multienv step",
+			input: raw.Step{
+				StringVal: map[string]string{
+					"multienv": "my command",
+				},
+			},
+			expErr: "",
+		},
 
 		// Invalid inputs.
 		{
@@ -386,6 +504,37 @@ func TestStep_Validate(t *testing.T) {
 			},
 			expErr: "env steps only support one of the \"value\" or \"command\" keys, found both",
 		},
+		{
+			description: "step with timeout",
+			input: raw.Step{
+				Map: MapType{
+					"apply": {
+						"extra_args": {"arg1"},
+					},
+				},
+				Timeout: "1h",
+			},
+		},
+		{
+			description: "timeout on step that doesn't run terraform",
+			input: raw.Step{
+				Map: MapType{
+					"policy_check": {},
+				},
+				Timeout: "1h",
+			},
+			expErr: "policy_check steps don't support a timeout, only init, plan, show and apply do",
+		},
+		{
+			description: "invalid timeout",
+			input: raw.Step{
+				Map: MapType{
+					"plan": {},
+				},
+				Timeout: "forever",
+			},
+			expErr: "timeout \"forever\" must be a positive duration, ex. 30m",
+		},
 		{
 			// For atlantis.yaml v2, this wouldn't parse, but now there should
 			// be no error.
@@ -509,6 +658,20 @@ func TestStep_ToValid(t *testing.T) {
 				ExtraArgs: []string{"arg1", "arg2"},
 			},
 		},
+		{
+			description: "terragrunt extra_args",
+			input: raw.Step{
+				Map: MapType{
+					"terragrunt": {
+						"extra_args": []string{"arg1", "arg2"},
+					},
+				},
+			},
+			exp: valid.Step{
+				StepName:  "terragrunt",
+				ExtraArgs: []string{"arg1", "arg2"},
+			},
+		},
 		{
 			description: "apply extra_args",
 			input: raw.Step{
@@ -535,6 +698,56 @@ func TestStep_ToValid(t *testing.T) {
 				RunCommand: "my 'run command'",
 			},
 		},
+		{
+			description: "multienv step",
+			input: raw.Step{
+				StringVal: map[string]string{
+					"multienv": "my 'run command'",
+				},
+			},
+			exp: valid.Step{
+				StepName:   "multienv",
+				RunCommand: "my 'run command'",
+			},
+		},
+		{
+			description: "plan timeout",
+			input: raw.Step{
+				Map: MapType{
+					"plan": {},
+				},
+				Timeout: "30m",
+			},
+			exp: valid.Step{
+				StepName: "plan",
+				Timeout:  30 * time.Minute,
+			},
+		},
+		{
+			description: "scan step",
+			input: raw.Step{
+				Key: String("scan"),
+			},
+			exp: valid.Step{
+				StepName: "scan",
+				Scan:     &valid.ScanOptions{Tool: "tfsec"},
+			},
+		},
+		{
+			description: "scan step with options",
+			input: raw.Step{
+				Scan: &raw.ScanArgs{Tool: "checkov", Version: "2.3.0", FailOn: "high", ExtraArgs: []string{"--compact"}},
+			},
+			exp: valid.Step{
+				StepName:  "scan",
+				ExtraArgs: []string{"--compact"},
+				Scan: &valid.ScanOptions{
+					Tool:    "checkov",
+					Version: version.Must(version.NewVersion("2.3.0")),
+					FailOn:  "HIGH",
+				},
+			},
+		},
 	}
 	for _, c := range cases {
 		t.Run(c.description, func(t *testing.T) {
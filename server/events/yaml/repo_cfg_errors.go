@@ -0,0 +1,148 @@
+package yaml
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	yaml "gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// RepoCfgError is a single problem with a repo-level atlantis.yaml file.
+type RepoCfgError struct {
+	// Line is the line of the file the problem is on. It's 0 if the problem
+	// can't be traced back to a line.
+	Line int
+	// Key is the key the problem is with, ex. projects[0].dir. It's empty if
+	// the problem isn't with a single key.
+	Key string
+	Msg string
+}
+
+func (e RepoCfgError) String() string {
+	var prefix string
+	if e.Line > 0 {
+		prefix = fmt.Sprintf("line %d: ", e.Line)
+	}
+	if e.Key != "" {
+		prefix += fmt.Sprintf("%s: ", e.Key)
+	}
+	return prefix + e.Msg
+}
+
+// yamlLineErrRegex matches the errors from the yaml library that have a line,
+// ex. "yaml: line 3: did not find expected key" or the errors of a
+// yaml.TypeError, ex. "line 3: field dirr not found in type raw.Project".
+var yamlLineErrRegex = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// ValidateRepoCfgData parses and validates repoCfgData like ParseRepoCfgData
+// but instead of the first error it returns every problem it can find along
+// with the line it's on. It returns nil if repoCfgData is valid.
+func (p *ParserValidator) ValidateRepoCfgData(repoCfgData []byte, globalCfg valid.GlobalCfg, repoID string) []RepoCfgError {
+	_, err := p.ParseRepoCfgData(repoCfgData, globalCfg, repoID)
+	if err == nil {
+		return nil
+	}
+
+	var msgs []string
+	switch e := err.(type) {
+	case *yaml.TypeError:
+		msgs = e.Errors
+	case validation.Errors:
+		var cfgErrs []RepoCfgError
+		flattenValidationErrors(e, nil, repoCfgData, &cfgErrs)
+		sort.SliceStable(cfgErrs, func(i, j int) bool { return cfgErrs[i].Line < cfgErrs[j].Line })
+		return cfgErrs
+	default:
+		msgs = []string{err.Error()}
+	}
+
+	var cfgErrs []RepoCfgError
+	for _, msg := range msgs {
+		cfgErr := RepoCfgError{Msg: msg}
+		if match := yamlLineErrRegex.FindStringSubmatch(msg); match != nil {
+			cfgErr.Line, _ = strconv.Atoi(match[1])
+			cfgErr.Msg = match[2]
+		}
+		cfgErrs = append(cfgErrs, cfgErr)
+	}
+	return cfgErrs
+}
+
+// flattenValidationErrors appends an error to cfgErrs for each leaf of errs.
+// path is the keys of the parent errors.
+func flattenValidationErrors(errs validation.Errors, path []string, repoCfgData []byte, cfgErrs *[]RepoCfgError) {
+	var keys []string
+	for k := range errs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		keyPath := append(append([]string{}, path...), k)
+		if nested, ok := errs[k].(validation.Errors); ok {
+			flattenValidationErrors(nested, keyPath, repoCfgData, cfgErrs)
+			continue
+		}
+		*cfgErrs = append(*cfgErrs, RepoCfgError{
+			Line: keyLine(repoCfgData, keyPath),
+			Key:  formatKeyPath(keyPath),
+			Msg:  errs[k].Error(),
+		})
+	}
+}
+
+// keyLine returns the line of the key at path in repoCfgData. If the key isn't
+// in the file, ex. because it's required but missing, it returns the line of
+// the closest parent that is.
+func keyLine(repoCfgData []byte, path []string) int {
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal(repoCfgData, &doc); err != nil || len(doc.Content) == 0 {
+		return 0
+	}
+	node := doc.Content[0]
+	line := node.Line
+	for _, key := range path {
+		var next *yamlv3.Node
+		switch node.Kind {
+		case yamlv3.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == key {
+					line = node.Content[i].Line
+					next = node.Content[i+1]
+					break
+				}
+			}
+		case yamlv3.SequenceNode:
+			if i, err := strconv.Atoi(key); err == nil && i < len(node.Content) {
+				line = node.Content[i].Line
+				next = node.Content[i]
+			}
+		}
+		if next == nil {
+			break
+		}
+		node = next
+	}
+	return line
+}
+
+// formatKeyPath joins the keys of path, ex. projects[0].dir.
+func formatKeyPath(path []string) string {
+	var b strings.Builder
+	for _, key := range path {
+		if _, err := strconv.Atoi(key); err == nil {
+			fmt.Fprintf(&b, "[%s]", key)
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString(".")
+		}
+		b.WriteString(key)
+	}
+	return b.String()
+}
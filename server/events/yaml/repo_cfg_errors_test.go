@@ -0,0 +1,80 @@
+package yaml_test
+
+import (
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/yaml"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestValidateRepoCfgData(t *testing.T) {
+	globalCfg := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})
+	cases := []struct {
+		description string
+		input       string
+		exp         []string
+	}{
+		{
+			description: "valid",
+			input: `
+version: 3
+projects:
+- dir: .
+`,
+			exp: nil,
+		},
+		{
+			description: "syntax error",
+			input:       "version: 3\nprojects:\n- dir: .\n   workspace: a\n",
+			exp:         []string{"line 4: mapping values are not allowed in this context"},
+		},
+		{
+			description: "unknown keys",
+			input:       "version: 3\nprojects:\n- dir: .\n  dirr: a\n- dir: b\n  workspac: b\n",
+			exp: []string{
+				"line 4: field dirr not found in type raw.Project",
+				"line 6: field workspac not found in type raw.Project",
+			},
+		},
+		{
+			description: "invalid values",
+			input: `
+version: 3
+projects:
+- dir: .
+- dir: ../other
+  autoplan:
+    when_modified: ["*.tf"]
+    enabled: true
+  name: ''
+automerge_method: fast
+`,
+			exp: []string{
+				"line 5: projects[1].dir: cannot contain '..'",
+				"line 9: projects[1].name: if set cannot be empty",
+				"line 10: automerge_method: must be one of merge, squash or rebase",
+			},
+		},
+		{
+			description: "missing key is reported on its parent",
+			input:       "projects:\n- dir: .\n",
+			exp:         []string{"line 1: version: is required. If you've just upgraded Atlantis you need to rewrite your atlantis.yaml for version 3. See www.runatlantis.io/docs/upgrading-atlantis-yaml.html"},
+		},
+		{
+			description: "error after validation has no line",
+			input:       "version: 3\nprojects:\n- dir: .\n  workflow: custom\n",
+			exp:         []string{"repo config not allowed to set 'workflow' key: server-side config needs 'allowed_overrides: [workflow]'"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			p := &yaml.ParserValidator{}
+			var act []string
+			for _, e := range p.ValidateRepoCfgData([]byte(c.input), globalCfg, "github.com/owner/repo") {
+				act = append(act, e.String())
+			}
+			Equals(t, c.exp, act)
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package valid
+
+import "time"
+
+const CredentialsKey = "credentials"
+
+// Credentials are the cloud credentials that Atlantis obtains for a project
+// before running its steps. They let each project run with its own narrowly
+// scoped role instead of with the credentials of the Atlantis server.
+type Credentials struct {
+	AWS *AWSCredentials
+	GCP *GCPCredentials
+}
+
+// AWSCredentials is the AWS IAM role that's assumed for a project.
+type AWSCredentials struct {
+	RoleARN string
+	// SessionDuration is how long the credentials are valid for. If zero,
+	// STS's default of one hour is used.
+	SessionDuration time.Duration
+}
+
+// GCPCredentials is the GCP service account that's impersonated for a
+// project.
+type GCPCredentials struct {
+	ServiceAccount string
+	// Lifetime is how long the access token is valid for. If zero, the
+	// default of one hour is used.
+	Lifetime time.Duration
+}
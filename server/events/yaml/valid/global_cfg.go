@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"time"
 
 	version "github.com/hashicorp/go-version"
 	"github.com/runatlantis/atlantis/server/logging"
@@ -13,6 +14,15 @@ const MergeableApplyReq = "mergeable"
 const ApprovedApplyReq = "approved"
 const UnDivergedApplyReq = "undiverged"
 const PoliciesPassedApplyReq = "policies_passed"
+
+// CodeownersApprovedApplyReq requires that each file a project modifies was
+// approved by one of its owners in the repo's CODEOWNERS file.
+const CodeownersApprovedApplyReq = "codeowners_approved"
+
+// PlanNewerThanApplyReqPrefix is the prefix of the apply requirement that
+// the plan must have been generated within a duration, ex.
+// "plan_newer_than:24h".
+const PlanNewerThanApplyReqPrefix = "plan_newer_than:"
 const ApplyRequirementsKey = "apply_requirements"
 const PreWorkflowHooksKey = "pre_workflow_hooks"
 const WorkflowKey = "workflow"
@@ -21,6 +31,63 @@ const AllowedOverridesKey = "allowed_overrides"
 const AllowCustomWorkflowsKey = "allow_custom_workflows"
 const DefaultWorkflowName = "default"
 const DeleteSourceBranchOnMergeKey = "delete_source_branch_on_merge"
+const AllowedApplyTeamsKey = "allowed_apply_teams"
+const AutoplanTriggersKey = "autoplan_triggers"
+const AllowedCommandsKey = "allowed_commands"
+const AllowDestroyPlansKey = "allow_destroy_plans"
+const OnBaseBranchUpdateKey = "on_base_branch_update"
+const TerraformDistributionKey = "terraform_distribution"
+const AutoApplyKey = "auto_apply"
+const SilenceNoChangePlansKey = "silence_no_change_plans"
+const CommentFormatKey = "comment_format"
+const ApplyAllMaxProjectsKey = "apply_all_max_projects"
+const LocaleKey = "locale"
+const DisableWorkspaceAutocreateKey = "disable_workspace_autocreate"
+const DisableTargetingKey = "disable_targeting"
+
+// InvalidateOnBaseBranchUpdate and ReplanOnBaseBranchUpdate are the supported
+// values of on_base_branch_update.
+const (
+	InvalidateOnBaseBranchUpdate = "invalidate"
+	ReplanOnBaseBranchUpdate     = "replan"
+)
+
+// TerraformDistribution and OpenTofuDistribution are the supported values of
+// terraform_distribution.
+const (
+	TerraformDistribution = "terraform"
+	OpenTofuDistribution  = "opentofu"
+)
+
+// DirWorkspaceLockGranularity, DirLockGranularity and ProjectLockGranularity
+// are the supported values of lock_granularity. They lock a project's dir and
+// workspace, its dir in every workspace, and its name respectively.
+const (
+	DirWorkspaceLockGranularity = "dir_workspace"
+	DirLockGranularity          = "dir"
+	ProjectLockGranularity      = "project"
+)
+
+// FullCommentFormat and RollupCommentFormat are the supported values of
+// comment_format. Full comments include each project's output while rollup
+// comments summarize the projects in a table that links to their output.
+const (
+	FullCommentFormat   = "full"
+	RollupCommentFormat = "rollup"
+)
+
+// RestrictableCommands are the commands that allowed_commands can restrict.
+// Other commands, ex. version, are always allowed.
+var RestrictableCommands = []string{"plan", "apply", "import", "state"}
+
+// BuiltinCommands are the names of the comment commands built into Atlantis.
+// Custom commands can't use them.
+var BuiltinCommands = []string{"plan", "apply", "unlock", "approve_policies", "policy_check", "version", "import", "state", "validate", "cancel", "help"}
+
+// IsBuiltinCommand returns true if name is one of BuiltinCommands.
+func IsBuiltinCommand(name string) bool {
+	return sliceContainsF(BuiltinCommands, name)
+}
 
 // NonOverrideableApplyReqs will get applied across all "repos" in the server side config.
 // If repo config is allowed overrides, they can override this.
@@ -34,6 +101,28 @@ type GlobalCfg struct {
 	Repos      []Repo
 	Workflows  map[string]Workflow
 	PolicySets PolicySets
+	// CustomApplyReqs maps the names of custom apply requirements to the
+	// command that must succeed for the requirement to pass.
+	CustomApplyReqs map[string]string
+	// Secrets maps the names of secrets that run steps can reference as
+	// ${secrets.NAME} to where their values are read from.
+	Secrets map[string]Secret
+	// CustomCommands maps the names of custom comment commands, ex. deploy
+	// for atlantis deploy, to their config.
+	CustomCommands map[string]CustomCommand
+}
+
+// CustomCommand is a comment command defined in the server-side repo config,
+// ex. atlantis deploy.
+type CustomCommand struct {
+	Name string
+	// Description is shown in the output of atlantis help.
+	Description string
+	// Workflow is the workflow whose plan stage the command runs.
+	Workflow Workflow
+	// Requirements, if not nil, are the apply requirements that must pass
+	// before the command runs instead of the project's apply requirements.
+	Requirements []string
 }
 
 // Repo is the final parsed version of server-side repo config.
@@ -52,21 +141,135 @@ type Repo struct {
 	AllowedOverrides          []string
 	AllowCustomWorkflows      *bool
 	DeleteSourceBranchOnMerge *bool
+	// AllowedApplyTeams, if set, restricts who can run apply to members of
+	// these VCS teams (GitHub team slugs or GitLab group paths).
+	AllowedApplyTeams []string
+	// AutoplanTriggers are additional rules for deciding which projects to
+	// autoplan based on the files modified in a pull request.
+	AutoplanTriggers []AutoplanTrigger
+	// AllowedCommands, if set, restricts which of RestrictableCommands can
+	// be run on the repo's projects.
+	AllowedCommands []string
+	// AllowDestroyPlans is true if atlantis plan --destroy can be run on the
+	// repo's projects.
+	AllowDestroyPlans *bool
+	// OnBaseBranchUpdate, if set, is what to do with the plans of open pull
+	// requests when the branch they'll be merged into is updated. It's one
+	// of InvalidateOnBaseBranchUpdate or ReplanOnBaseBranchUpdate.
+	OnBaseBranchUpdate string
+	// TerraformDistribution, if set, is the distribution of terraform the
+	// repo's projects are run with unless they set their own. It's one of
+	// TerraformDistribution or OpenTofuDistribution.
+	TerraformDistribution string
+	// LockGranularity, if set, is what the repo's project locks are held on.
+	// It's one of DirWorkspaceLockGranularity, DirLockGranularity or
+	// ProjectLockGranularity.
+	LockGranularity string
+	// Credentials, if set, are the cloud credentials obtained for the repo's
+	// projects before their steps are run.
+	Credentials *Credentials
+	// CheckoutSubmodules is true if the repo's submodules are checked out,
+	// recursively, when it's cloned.
+	CheckoutSubmodules *bool
+	// CheckoutLFS is true if the repo's Git LFS files are downloaded when
+	// it's cloned. Otherwise they're left as pointer files.
+	CheckoutLFS *bool
+	// AutoApply is true if the repo's pull requests are applied, and then
+	// merged, as soon as they're approved, mergeable and planned
+	// successfully.
+	AutoApply *bool
+	// SilenceNoChangePlans, if set, overrides --silence-no-change-plans for
+	// the repo. If true, projects whose plans have no changes are left out of
+	// plan comments.
+	SilenceNoChangePlans *bool
+	// CommentFormat, if set, is how plan and apply results are commented on
+	// the repo's pull requests. It's one of FullCommentFormat or
+	// RollupCommentFormat.
+	CommentFormat string
+	// ApplyAllMaxProjects, if set, is the most projects that atlantis apply
+	// without -d, -w or -p flags can apply on the repo's pull requests.
+	ApplyAllMaxProjects *int
+	// Locale, if set, overrides --locale for the repo. It's the locale of
+	// the messages Atlantis comments on the repo's pull requests.
+	Locale string
+	// NoWorkspaceAutocreate is true if planning in a workspace that doesn't
+	// exist fails instead of creating the workspace. It's set by
+	// disable_workspace_autocreate.
+	NoWorkspaceAutocreate *bool
+	// DisableTargeting is true if --target, and -target passed as an extra
+	// argument, can't be used on the repo's projects.
+	DisableTargeting *bool
 }
 
 type MergedProjectCfg struct {
-	ApplyRequirements         []string
-	Workflow                  Workflow
-	AllowedWorkflows          []string
-	RepoRelDir                string
-	Workspace                 string
-	Name                      string
-	AutoplanEnabled           bool
-	AutoMergeDisabled         bool
-	TerraformVersion          *version.Version
+	ApplyRequirements []string
+	Workflow          Workflow
+	AllowedWorkflows  []string
+	RepoRelDir        string
+	Workspace         string
+	Name              string
+	AutoplanEnabled   bool
+	AutoMergeDisabled bool
+	TerraformVersion  *version.Version
+	// TerraformDistribution is the distribution of terraform to run, ex.
+	// opentofu. If empty the server's default distribution is used.
+	TerraformDistribution string
+	// TFEWorkspace, if set, is the Terraform Cloud/Enterprise workspace that
+	// plans and applies run in, ex. my-org/my-workspace.
+	TFEWorkspace              string
 	RepoCfgVersion            int
 	PolicySets                PolicySets
 	DeleteSourceBranchOnMerge bool
+	// Automerge overrides the repo's automerge setting for this project if
+	// set.
+	Automerge *bool
+	// AutomergeMethod and AutomergeCommitMessage are the repo's automerge
+	// settings, see RepoCfg.
+	AutomergeMethod        string
+	AutomergeCommitMessage string
+	// CustomApplyReqs maps the names of custom apply requirements to the
+	// command that must succeed for the requirement to pass.
+	CustomApplyReqs map[string]string
+	// AllowedCommands, if set, restricts which of RestrictableCommands can
+	// be run on the project.
+	AllowedCommands []string
+	// AllowDestroyPlans is true if destroy plans can be run on the project.
+	AllowDestroyPlans bool
+	// NoWorkspaceAutocreate is true if the project's workspace must already
+	// exist to be planned.
+	NoWorkspaceAutocreate bool
+	// DisableTargeting is true if the project can't be planned or applied
+	// with -target.
+	DisableTargeting bool
+	// LockGranularity is what the project's lock is held on. If empty, its
+	// dir and workspace are locked.
+	LockGranularity string
+	// Credentials, if set, are the cloud credentials obtained for the
+	// project before its steps are run.
+	Credentials *Credentials
+	// Init are the options terraform init is run with.
+	Init InitOptions
+	// Outputs are the names of the outputs shown in the apply comment.
+	Outputs []string
+}
+
+// CommandAllowed returns true if the command called name can be run on the
+// project.
+func (m MergedProjectCfg) CommandAllowed(name string) bool {
+	if m.AllowedCommands == nil || !sliceContainsF(RestrictableCommands, name) {
+		return true
+	}
+	return sliceContainsF(m.AllowedCommands, name)
+}
+
+// AutoplanTrigger causes projects to be autoplanned when files matching
+// WhenModified are modified. WhenModified patterns are relative to the repo
+// root. If Dirs is set, the projects in those dirs are planned. Otherwise the
+// modified files are treated as part of a local module and every project that
+// calls that module, directly or through other local modules, is planned.
+type AutoplanTrigger struct {
+	WhenModified []string
+	Dirs         []string
 }
 
 // PreWorkflowHook is a map of custom run commands to run before workflows.
@@ -196,6 +399,45 @@ func NewGlobalCfgFromArgs(args GlobalCfgArgs) GlobalCfg {
 	}
 }
 
+// ParsePlanNewerThanApplyReq returns the maximum age of the plan for a
+// plan_newer_than apply requirement. ok is false if req isn't a
+// plan_newer_than requirement.
+func ParsePlanNewerThanApplyReq(req string) (maxAge time.Duration, ok bool, err error) {
+	if !strings.HasPrefix(req, PlanNewerThanApplyReqPrefix) {
+		return 0, false, nil
+	}
+	maxAge, err = time.ParseDuration(strings.TrimPrefix(req, PlanNewerThanApplyReqPrefix))
+	if err != nil {
+		return 0, true, fmt.Errorf("%q is not a valid apply_requirement: %s", req, err)
+	}
+	if maxAge <= 0 {
+		return 0, true, fmt.Errorf("%q is not a valid apply_requirement: duration must be positive", req)
+	}
+	return maxAge, true, nil
+}
+
+// ValidateApplyReqs returns an error if any of reqs isn't a built-in apply
+// requirement or one of customReqs.
+func ValidateApplyReqs(reqs []string, customReqs map[string]string) error {
+	for _, r := range reqs {
+		switch r {
+		case ApprovedApplyReq, MergeableApplyReq, UnDivergedApplyReq, CodeownersApprovedApplyReq:
+			continue
+		}
+		if _, ok, err := ParsePlanNewerThanApplyReq(r); ok {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if _, ok := customReqs[r]; ok {
+			continue
+		}
+		return fmt.Errorf("%q is not a valid apply_requirement, only %q, %q, %q, %q, %q and custom_apply_requirements are supported", r, ApprovedApplyReq, MergeableApplyReq, UnDivergedApplyReq, CodeownersApprovedApplyReq, PlanNewerThanApplyReqPrefix+"<duration>")
+	}
+	return nil
+}
+
 // IDMatches returns true if the repo ID otherID matches this config.
 func (r Repo) IDMatches(otherID string) bool {
 	if r.ID != "" {
@@ -225,6 +467,7 @@ func (r Repo) IDString() string {
 func (g GlobalCfg) MergeProjectCfg(log logging.SimpleLogging, repoID string, proj Project, rCfg RepoCfg) MergedProjectCfg {
 	log.Debug("MergeProjectCfg started")
 	applyReqs, workflow, allowedOverrides, allowCustomWorkflows, deleteSourceBranchOnMerge := g.getMatchingCfg(log, repoID)
+	credentials := g.credentials(repoID)
 
 	// If repos are allowed to override certain keys then override them.
 	for _, key := range allowedOverrides {
@@ -270,10 +513,27 @@ func (g GlobalCfg) MergeProjectCfg(log logging.SimpleLogging, repoID string, pro
 				deleteSourceBranchOnMerge = *proj.DeleteSourceBranchOnMerge
 			}
 			log.Debug("merged deleteSourceBranchOnMerge: [%t]", deleteSourceBranchOnMerge)
+		case CredentialsKey:
+			if proj.Credentials != nil {
+				log.Debug("overriding server-defined %s with repo settings", CredentialsKey)
+				credentials = proj.Credentials
+			}
 		}
 		log.Debug("MergeProjectCfg completed")
 	}
 
+	// Projects can only restrict the server-side allowed commands further,
+	// which is checked in ValidateRepoCfg, so they don't need an override.
+	allowedCommands := g.allowedCommands(repoID)
+	if proj.AllowedCommands != nil {
+		allowedCommands = proj.AllowedCommands
+	}
+
+	tfDistribution := g.terraformDistribution(repoID)
+	if proj.TerraformDistribution != nil {
+		tfDistribution = *proj.TerraformDistribution
+	}
+
 	log.Debug("final settings: %s: [%s], %s: %s",
 		ApplyRequirementsKey, strings.Join(applyReqs, ","), WorkflowKey, workflow.Name)
 
@@ -285,9 +545,23 @@ func (g GlobalCfg) MergeProjectCfg(log logging.SimpleLogging, repoID string, pro
 		Name:                      proj.GetName(),
 		AutoplanEnabled:           proj.Autoplan.Enabled,
 		TerraformVersion:          proj.TerraformVersion,
+		TerraformDistribution:     tfDistribution,
+		TFEWorkspace:              proj.TFEWorkspace,
 		RepoCfgVersion:            rCfg.Version,
 		PolicySets:                g.PolicySets,
 		DeleteSourceBranchOnMerge: deleteSourceBranchOnMerge,
+		Automerge:                 proj.Automerge,
+		AutomergeMethod:           rCfg.AutomergeMethod,
+		AutomergeCommitMessage:    rCfg.AutomergeCommitMessage,
+		CustomApplyReqs:           g.CustomApplyReqs,
+		AllowedCommands:           allowedCommands,
+		AllowDestroyPlans:         g.allowDestroyPlans(repoID),
+		NoWorkspaceAutocreate:     g.noWorkspaceAutocreate(repoID),
+		DisableTargeting:          g.disableTargeting(repoID),
+		LockGranularity:           g.lockGranularity(repoID),
+		Credentials:               credentials,
+		Init:                      proj.Init,
+		Outputs:                   proj.Outputs,
 	}
 }
 
@@ -304,8 +578,16 @@ func (g GlobalCfg) DefaultProjCfg(log logging.SimpleLogging, repoID string, repo
 		Name:                      "",
 		AutoplanEnabled:           DefaultAutoPlanEnabled,
 		TerraformVersion:          nil,
+		TerraformDistribution:     g.terraformDistribution(repoID),
 		PolicySets:                g.PolicySets,
 		DeleteSourceBranchOnMerge: deleteSourceBranchOnMerge,
+		CustomApplyReqs:           g.CustomApplyReqs,
+		AllowedCommands:           g.allowedCommands(repoID),
+		AllowDestroyPlans:         g.allowDestroyPlans(repoID),
+		NoWorkspaceAutocreate:     g.noWorkspaceAutocreate(repoID),
+		DisableTargeting:          g.disableTargeting(repoID),
+		LockGranularity:           g.lockGranularity(repoID),
+		Credentials:               g.credentials(repoID),
 	}
 }
 
@@ -313,14 +595,6 @@ func (g GlobalCfg) DefaultProjCfg(log logging.SimpleLogging, repoID string, repo
 // on our global config.
 func (g GlobalCfg) ValidateRepoCfg(rCfg RepoCfg, repoID string) error {
 
-	sliceContainsF := func(slc []string, str string) bool {
-		for _, s := range slc {
-			if s == str {
-				return true
-			}
-		}
-		return false
-	}
 	mapContainsF := func(m map[string]Workflow, key string) bool {
 		for k := range m {
 			if k == key {
@@ -346,9 +620,26 @@ func (g GlobalCfg) ValidateRepoCfg(rCfg RepoCfg, repoID string) error {
 		if p.ApplyRequirements != nil && !sliceContainsF(allowedOverrides, ApplyRequirementsKey) {
 			return fmt.Errorf("repo config not allowed to set '%s' key: server-side config needs '%s: [%s]'", ApplyRequirementsKey, AllowedOverridesKey, ApplyRequirementsKey)
 		}
+		if err := ValidateApplyReqs(p.ApplyRequirements, g.CustomApplyReqs); err != nil {
+			return err
+		}
 		if p.DeleteSourceBranchOnMerge != nil && !sliceContainsF(allowedOverrides, DeleteSourceBranchOnMergeKey) {
 			return fmt.Errorf("repo config not allowed to set '%s' key: server-side config needs '%s: [%s]'", DeleteSourceBranchOnMergeKey, AllowedOverridesKey, DeleteSourceBranchOnMergeKey)
 		}
+		if p.Credentials != nil && !sliceContainsF(allowedOverrides, CredentialsKey) {
+			return fmt.Errorf("repo config not allowed to set '%s' key: server-side config needs '%s: [%s]'", CredentialsKey, AllowedOverridesKey, CredentialsKey)
+		}
+	}
+
+	// Check projects only restrict the server-side allowed commands.
+	if serverAllowed := g.allowedCommands(repoID); serverAllowed != nil {
+		for _, p := range rCfg.Projects {
+			for _, c := range p.AllowedCommands {
+				if !sliceContainsF(serverAllowed, c) {
+					return fmt.Errorf("project at dir %q is not allowed to run %q: server-side config sets '%s: [%s]'", p.Dir, c, AllowedCommandsKey, strings.Join(serverAllowed, ","))
+				}
+			}
+		}
 	}
 
 	// Check custom workflows.
@@ -406,6 +697,222 @@ func (g GlobalCfg) ValidateRepoCfg(rCfg RepoCfg, repoID string) error {
 	return nil
 }
 
+// AllowedApplyTeams returns the teams whose members are allowed to run apply
+// on pull requests against baseBranch of repoID. As with other repo settings,
+// the last matching repo that sets allowed_apply_teams wins. An empty result
+// means anyone can apply.
+func (g GlobalCfg) AllowedApplyTeams(repoID string, baseBranch string) []string {
+	var teams []string
+	for _, repo := range g.Repos {
+		if repo.IDMatches(repoID) && repo.BranchMatches(baseBranch) && repo.AllowedApplyTeams != nil {
+			teams = repo.AllowedApplyTeams
+		}
+	}
+	return teams
+}
+
+// OnBaseBranchUpdate returns what to do with the plans of repoID's open pull
+// requests into baseBranch when baseBranch is updated. The last matching repo
+// that sets on_base_branch_update wins. An empty result means nothing should
+// be done.
+func (g GlobalCfg) OnBaseBranchUpdate(repoID string, baseBranch string) string {
+	var action string
+	for _, repo := range g.Repos {
+		if repo.IDMatches(repoID) && repo.BranchMatches(baseBranch) && repo.OnBaseBranchUpdate != "" {
+			action = repo.OnBaseBranchUpdate
+		}
+	}
+	return action
+}
+
+// CommentFormat returns how plan and apply results should be commented on
+// repoID's pull requests into baseBranch. The last matching repo that sets
+// comment_format wins. It defaults to FullCommentFormat.
+func (g GlobalCfg) CommentFormat(repoID string, baseBranch string) string {
+	format := FullCommentFormat
+	for _, repo := range g.Repos {
+		if repo.IDMatches(repoID) && repo.BranchMatches(baseBranch) && repo.CommentFormat != "" {
+			format = repo.CommentFormat
+		}
+	}
+	return format
+}
+
+// ApplyAllMaxProjects returns the most projects that atlantis apply without
+// flags can apply on repoID's pull requests into baseBranch, and whether
+// there's a limit. The last matching repo that sets apply_all_max_projects
+// wins.
+func (g GlobalCfg) ApplyAllMaxProjects(repoID string, baseBranch string) (int, bool) {
+	var limit *int
+	for _, repo := range g.Repos {
+		if repo.IDMatches(repoID) && repo.BranchMatches(baseBranch) && repo.ApplyAllMaxProjects != nil {
+			limit = repo.ApplyAllMaxProjects
+		}
+	}
+	if limit == nil {
+		return 0, false
+	}
+	return *limit, true
+}
+
+// Locale returns the locale of the messages Atlantis comments on repoID's pull
+// requests into baseBranch. defaultLocale is returned unless a matching repo
+// sets locale, in which case the last one wins.
+func (g GlobalCfg) Locale(repoID string, baseBranch string, defaultLocale string) string {
+	locale := defaultLocale
+	for _, repo := range g.Repos {
+		if repo.IDMatches(repoID) && repo.BranchMatches(baseBranch) && repo.Locale != "" {
+			locale = repo.Locale
+		}
+	}
+	return locale
+}
+
+// CheckoutSubmodules returns true if the submodules of repoID should be
+// checked out when its pull requests into baseBranch are cloned. The last
+// matching repo that sets checkout_submodules wins.
+func (g GlobalCfg) CheckoutSubmodules(repoID string, baseBranch string) bool {
+	checkout := false
+	for _, repo := range g.Repos {
+		if repo.IDMatches(repoID) && repo.BranchMatches(baseBranch) && repo.CheckoutSubmodules != nil {
+			checkout = *repo.CheckoutSubmodules
+		}
+	}
+	return checkout
+}
+
+// CheckoutLFS returns true if the Git LFS files of repoID should be
+// downloaded when its pull requests into baseBranch are cloned. The last
+// matching repo that sets checkout_lfs wins.
+func (g GlobalCfg) CheckoutLFS(repoID string, baseBranch string) bool {
+	checkout := false
+	for _, repo := range g.Repos {
+		if repo.IDMatches(repoID) && repo.BranchMatches(baseBranch) && repo.CheckoutLFS != nil {
+			checkout = *repo.CheckoutLFS
+		}
+	}
+	return checkout
+}
+
+// AutoApply returns true if repoID's pull requests into baseBranch should be
+// applied, and then merged, once they're approved, mergeable and planned
+// successfully. The last matching repo that sets auto_apply wins.
+func (g GlobalCfg) AutoApply(repoID string, baseBranch string) bool {
+	autoApply := false
+	for _, repo := range g.Repos {
+		if repo.IDMatches(repoID) && repo.BranchMatches(baseBranch) && repo.AutoApply != nil {
+			autoApply = *repo.AutoApply
+		}
+	}
+	return autoApply
+}
+
+// SilenceNoChangePlans returns true if projects whose plans have no changes
+// should be left out of the plan comments of repoID's pull requests into
+// baseBranch. defaultSilence is returned unless a matching repo sets
+// silence_no_change_plans, in which case the last one wins.
+func (g GlobalCfg) SilenceNoChangePlans(repoID string, baseBranch string, defaultSilence bool) bool {
+	silence := defaultSilence
+	for _, repo := range g.Repos {
+		if repo.IDMatches(repoID) && repo.BranchMatches(baseBranch) && repo.SilenceNoChangePlans != nil {
+			silence = *repo.SilenceNoChangePlans
+		}
+	}
+	return silence
+}
+
+// allowedCommands returns the commands allowed for repoID's projects by the
+// server-side config. The last matching repo that sets allowed_commands wins.
+// A nil result means every command is allowed.
+func (g GlobalCfg) allowedCommands(repoID string) []string {
+	var cmds []string
+	for _, repo := range g.Repos {
+		if repo.IDMatches(repoID) && repo.AllowedCommands != nil {
+			cmds = repo.AllowedCommands
+		}
+	}
+	return cmds
+}
+
+// allowDestroyPlans returns true if the server-side config allows destroy
+// plans for repoID's projects. The last matching repo that sets
+// allow_destroy_plans wins.
+func (g GlobalCfg) allowDestroyPlans(repoID string) bool {
+	allow := false
+	for _, repo := range g.Repos {
+		if repo.IDMatches(repoID) && repo.AllowDestroyPlans != nil {
+			allow = *repo.AllowDestroyPlans
+		}
+	}
+	return allow
+}
+
+// noWorkspaceAutocreate returns true if the server-side config requires
+// repoID's workspaces to exist before they're planned. The last matching repo
+// that sets disable_workspace_autocreate wins.
+func (g GlobalCfg) noWorkspaceAutocreate(repoID string) bool {
+	disable := false
+	for _, repo := range g.Repos {
+		if repo.IDMatches(repoID) && repo.NoWorkspaceAutocreate != nil {
+			disable = *repo.NoWorkspaceAutocreate
+		}
+	}
+	return disable
+}
+
+// disableTargeting returns true if the server-side config doesn't allow
+// repoID's projects to be targeted. The last matching repo that sets
+// disable_targeting wins.
+func (g GlobalCfg) disableTargeting(repoID string) bool {
+	disable := false
+	for _, repo := range g.Repos {
+		if repo.IDMatches(repoID) && repo.DisableTargeting != nil {
+			disable = *repo.DisableTargeting
+		}
+	}
+	return disable
+}
+
+// lockGranularity returns what the server-side config sets repoID's project
+// locks to be held on. The last matching repo that sets lock_granularity
+// wins. An empty result means the projects' dirs and workspaces are locked.
+func (g GlobalCfg) lockGranularity(repoID string) string {
+	var granularity string
+	for _, repo := range g.Repos {
+		if repo.IDMatches(repoID) && repo.LockGranularity != "" {
+			granularity = repo.LockGranularity
+		}
+	}
+	return granularity
+}
+
+// credentials returns the cloud credentials the server-side config sets for
+// repoID's projects. The last matching repo that sets credentials wins. A nil
+// result means no credentials are obtained.
+func (g GlobalCfg) credentials(repoID string) *Credentials {
+	var c *Credentials
+	for _, repo := range g.Repos {
+		if repo.IDMatches(repoID) && repo.Credentials != nil {
+			c = repo.Credentials
+		}
+	}
+	return c
+}
+
+// terraformDistribution returns the distribution of terraform the server-side
+// config sets for repoID's projects. The last matching repo that sets
+// terraform_distribution wins. An empty result means the server's default
+// distribution should be used.
+func (g GlobalCfg) terraformDistribution(repoID string) string {
+	var d string
+	for _, repo := range g.Repos {
+		if repo.IDMatches(repoID) && repo.TerraformDistribution != "" {
+			d = repo.TerraformDistribution
+		}
+	}
+	return d
+}
+
 // getMatchingCfg returns the key settings for repoID.
 func (g GlobalCfg) getMatchingCfg(log logging.SimpleLogging, repoID string) (applyReqs []string, workflow Workflow, allowedOverrides []string, allowCustomWorkflows bool, deleteSourceBranchOnMerge bool) {
 	toLog := make(map[string]string)
@@ -467,3 +974,25 @@ func (g GlobalCfg) getMatchingCfg(log logging.SimpleLogging, repoID string) (app
 	}
 	return
 }
+
+// AutoplanTriggers returns the autoplan triggers for pull requests against
+// baseBranch of repoID. The last matching repo that sets autoplan_triggers
+// wins.
+func (g GlobalCfg) AutoplanTriggers(repoID string, baseBranch string) []AutoplanTrigger {
+	var triggers []AutoplanTrigger
+	for _, repo := range g.Repos {
+		if repo.IDMatches(repoID) && repo.BranchMatches(baseBranch) && repo.AutoplanTriggers != nil {
+			triggers = repo.AutoplanTriggers
+		}
+	}
+	return triggers
+}
+
+func sliceContainsF(slc []string, str string) bool {
+	for _, s := range slc {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,31 @@
+package valid
+
+import "sync"
+
+// GlobalCfgStore holds the current server-side repo config so that it can be
+// swapped out while the server is running, ex. when the config file is
+// reloaded.
+type GlobalCfgStore struct {
+	mutex sync.RWMutex
+	cfg   GlobalCfg
+}
+
+// NewGlobalCfgStore returns a store holding cfg.
+func NewGlobalCfgStore(cfg GlobalCfg) *GlobalCfgStore {
+	return &GlobalCfgStore{cfg: cfg}
+}
+
+// Get returns the current config. Callers should call Get once per operation
+// so that the whole operation sees the same config.
+func (s *GlobalCfgStore) Get() GlobalCfg {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.cfg
+}
+
+// Set replaces the current config with cfg.
+func (s *GlobalCfgStore) Set(cfg GlobalCfg) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.cfg = cfg
+}
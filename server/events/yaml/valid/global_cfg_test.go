@@ -200,6 +200,43 @@ func TestGlobalCfg_ValidateRepoCfg(t *testing.T) {
 		repoID string
 		expErr string
 	}{
+		"repo uses apply requirement that isn't defined": {
+			gCfg: valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{
+				AllowRepoCfg: true,
+			}),
+			rCfg: valid.RepoCfg{
+				Projects: []valid.Project{
+					{
+						Dir:               ".",
+						Workspace:         "default",
+						ApplyRequirements: []string{"change_window"},
+					},
+				},
+			},
+			repoID: "github.com/owner/repo",
+			expErr: "\"change_window\" is not a valid apply_requirement, only \"approved\", \"mergeable\", \"undiverged\", \"codeowners_approved\", \"plan_newer_than:<duration>\" and custom_apply_requirements are supported",
+		},
+		"repo uses custom apply requirement": {
+			gCfg: valid.GlobalCfg{
+				Repos: valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{
+					AllowRepoCfg: true,
+				}).Repos,
+				CustomApplyReqs: map[string]string{
+					"change_window": "./in-change-window.sh",
+				},
+			},
+			rCfg: valid.RepoCfg{
+				Projects: []valid.Project{
+					{
+						Dir:               ".",
+						Workspace:         "default",
+						ApplyRequirements: []string{"change_window", "plan_newer_than:1h"},
+					},
+				},
+			},
+			repoID: "github.com/owner/repo",
+			expErr: "",
+		},
 		"repo uses workflow that is defined server side but not allowed (with custom workflows)": {
 			gCfg: valid.GlobalCfg{
 				Repos: []valid.Repo{
@@ -892,6 +929,314 @@ func TestRepo_BranchMatches(t *testing.T) {
 	Equals(t, false, (valid.Repo{BranchRegex: regexp.MustCompile("release")}).BranchMatches("main"))
 }
 
+func TestGlobalCfg_AllowedApplyTeams(t *testing.T) {
+	cfg := valid.GlobalCfg{
+		Repos: []valid.Repo{
+			{IDRegex: regexp.MustCompile(".*"), AllowedApplyTeams: []string{"platform"}},
+			{ID: "github.com/owner/repo", BranchRegex: regexp.MustCompile("^main$"), AllowedApplyTeams: []string{"sre"}},
+			{ID: "github.com/owner/open"},
+			{ID: "github.com/owner/anyone", AllowedApplyTeams: []string{}},
+		},
+	}
+	Equals(t, []string{"platform"}, cfg.AllowedApplyTeams("github.com/owner/other", "main"))
+	Equals(t, []string{"sre"}, cfg.AllowedApplyTeams("github.com/owner/repo", "main"))
+	Equals(t, []string{"platform"}, cfg.AllowedApplyTeams("github.com/owner/repo", "dev"))
+	Equals(t, []string{"platform"}, cfg.AllowedApplyTeams("github.com/owner/open", "main"))
+	Equals(t, []string{}, cfg.AllowedApplyTeams("github.com/owner/anyone", "main"))
+}
+
+func TestGlobalCfg_AutoplanTriggers(t *testing.T) {
+	modules := valid.AutoplanTrigger{WhenModified: []string{"modules/**"}}
+	shared := valid.AutoplanTrigger{WhenModified: []string{"shared/*"}, Dirs: []string{"project1"}}
+	cfg := valid.GlobalCfg{
+		Repos: []valid.Repo{
+			{IDRegex: regexp.MustCompile(".*"), AutoplanTriggers: []valid.AutoplanTrigger{modules}},
+			{ID: "github.com/owner/repo", BranchRegex: regexp.MustCompile("^main$"), AutoplanTriggers: []valid.AutoplanTrigger{shared}},
+			{ID: "github.com/owner/unset"},
+			{ID: "github.com/owner/none", AutoplanTriggers: []valid.AutoplanTrigger{}},
+		},
+	}
+	Equals(t, []valid.AutoplanTrigger{modules}, cfg.AutoplanTriggers("github.com/owner/other", "main"))
+	Equals(t, []valid.AutoplanTrigger{shared}, cfg.AutoplanTriggers("github.com/owner/repo", "main"))
+	Equals(t, []valid.AutoplanTrigger{modules}, cfg.AutoplanTriggers("github.com/owner/repo", "dev"))
+	Equals(t, []valid.AutoplanTrigger{modules}, cfg.AutoplanTriggers("github.com/owner/unset", "main"))
+	Equals(t, []valid.AutoplanTrigger{}, cfg.AutoplanTriggers("github.com/owner/none", "main"))
+}
+
+func TestGlobalCfg_AllowedCommands(t *testing.T) {
+	logger := logging.NewNoopLogger(t)
+	cfg := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})
+	cfg.Repos = append(cfg.Repos, valid.Repo{
+		ID:              "github.com/owner/prod",
+		AllowedCommands: []string{"plan", "import"},
+	})
+
+	t.Log("every command is allowed by default")
+	pCfg := cfg.DefaultProjCfg(logger, "github.com/owner/other", ".", "default")
+	Equals(t, true, pCfg.CommandAllowed("apply"))
+
+	t.Log("the server-side config applies to every project in the repo")
+	pCfg = cfg.DefaultProjCfg(logger, "github.com/owner/prod", ".", "default")
+	Equals(t, true, pCfg.CommandAllowed("plan"))
+	Equals(t, false, pCfg.CommandAllowed("apply"))
+	Equals(t, false, pCfg.CommandAllowed("state"))
+	Equals(t, true, pCfg.CommandAllowed("version"))
+	pCfg = cfg.MergeProjectCfg(logger, "github.com/owner/prod", valid.Project{Dir: "."}, valid.RepoCfg{})
+	Equals(t, []string{"plan", "import"}, pCfg.AllowedCommands)
+
+	t.Log("projects can restrict the server-side config further")
+	proj := valid.Project{Dir: "restricted", AllowedCommands: []string{"plan"}}
+	rCfg := valid.RepoCfg{Projects: []valid.Project{proj}}
+	Ok(t, cfg.ValidateRepoCfg(rCfg, "github.com/owner/prod"))
+	pCfg = cfg.MergeProjectCfg(logger, "github.com/owner/prod", proj, rCfg)
+	Equals(t, false, pCfg.CommandAllowed("import"))
+
+	t.Log("projects can't allow commands the server-side config doesn't")
+	proj.AllowedCommands = []string{"plan", "apply"}
+	rCfg = valid.RepoCfg{Projects: []valid.Project{proj}}
+	ErrEquals(t, `project at dir "restricted" is not allowed to run "apply": server-side config sets 'allowed_commands: [plan,import]'`, cfg.ValidateRepoCfg(rCfg, "github.com/owner/prod"))
+	Ok(t, cfg.ValidateRepoCfg(rCfg, "github.com/owner/other"))
+}
+
+func TestGlobalCfg_AllowDestroyPlans(t *testing.T) {
+	logger := logging.NewNoopLogger(t)
+	cfg := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})
+	cfg.Repos = append(cfg.Repos,
+		valid.Repo{
+			IDRegex:           regexp.MustCompile(".*"),
+			AllowDestroyPlans: Bool(true),
+		},
+		valid.Repo{
+			ID:                "github.com/owner/prod",
+			AllowDestroyPlans: Bool(false),
+		},
+	)
+
+	t.Log("destroy plans aren't allowed by default")
+	defaultCfg := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})
+	Equals(t, false, defaultCfg.DefaultProjCfg(logger, "github.com/owner/repo", ".", "default").AllowDestroyPlans)
+
+	t.Log("the last matching repo wins")
+	Equals(t, true, cfg.DefaultProjCfg(logger, "github.com/owner/repo", ".", "default").AllowDestroyPlans)
+	Equals(t, false, cfg.DefaultProjCfg(logger, "github.com/owner/prod", ".", "default").AllowDestroyPlans)
+	Equals(t, true, cfg.MergeProjectCfg(logger, "github.com/owner/repo", valid.Project{Dir: "."}, valid.RepoCfg{}).AllowDestroyPlans)
+}
+
+func TestGlobalCfg_NoWorkspaceAutocreate(t *testing.T) {
+	logger := logging.NewNoopLogger(t)
+	cfg := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})
+	cfg.Repos = append(cfg.Repos,
+		valid.Repo{
+			IDRegex:               regexp.MustCompile(".*"),
+			NoWorkspaceAutocreate: Bool(true),
+		},
+		valid.Repo{
+			ID:                    "github.com/owner/sandbox",
+			NoWorkspaceAutocreate: Bool(false),
+		},
+	)
+
+	t.Log("workspaces are created by default")
+	defaultCfg := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})
+	Equals(t, false, defaultCfg.DefaultProjCfg(logger, "github.com/owner/repo", ".", "default").NoWorkspaceAutocreate)
+
+	t.Log("the last matching repo wins")
+	Equals(t, true, cfg.DefaultProjCfg(logger, "github.com/owner/repo", ".", "default").NoWorkspaceAutocreate)
+	Equals(t, false, cfg.DefaultProjCfg(logger, "github.com/owner/sandbox", ".", "default").NoWorkspaceAutocreate)
+	Equals(t, true, cfg.MergeProjectCfg(logger, "github.com/owner/repo", valid.Project{Dir: "."}, valid.RepoCfg{}).NoWorkspaceAutocreate)
+}
+
+func TestGlobalCfg_DisableTargeting(t *testing.T) {
+	logger := logging.NewNoopLogger(t)
+	cfg := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})
+	cfg.Repos = append(cfg.Repos,
+		valid.Repo{
+			IDRegex:          regexp.MustCompile(".*"),
+			DisableTargeting: Bool(true),
+		},
+		valid.Repo{
+			ID:               "github.com/owner/sandbox",
+			DisableTargeting: Bool(false),
+		},
+	)
+
+	t.Log("targeting is allowed by default")
+	defaultCfg := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})
+	Equals(t, false, defaultCfg.DefaultProjCfg(logger, "github.com/owner/repo", ".", "default").DisableTargeting)
+
+	t.Log("the last matching repo wins")
+	Equals(t, true, cfg.DefaultProjCfg(logger, "github.com/owner/repo", ".", "default").DisableTargeting)
+	Equals(t, false, cfg.DefaultProjCfg(logger, "github.com/owner/sandbox", ".", "default").DisableTargeting)
+	Equals(t, true, cfg.MergeProjectCfg(logger, "github.com/owner/repo", valid.Project{Dir: "."}, valid.RepoCfg{}).DisableTargeting)
+}
+
+func TestGlobalCfg_OnBaseBranchUpdate(t *testing.T) {
+	cfg := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})
+	Equals(t, "", cfg.OnBaseBranchUpdate("github.com/owner/repo", "main"))
+
+	cfg.Repos = append(cfg.Repos,
+		valid.Repo{
+			IDRegex:            regexp.MustCompile(".*"),
+			OnBaseBranchUpdate: valid.InvalidateOnBaseBranchUpdate,
+		},
+		valid.Repo{
+			ID:                 "github.com/owner/repo",
+			BranchRegex:        regexp.MustCompile("^main$"),
+			OnBaseBranchUpdate: valid.ReplanOnBaseBranchUpdate,
+		},
+	)
+	Equals(t, "invalidate", cfg.OnBaseBranchUpdate("github.com/owner/other", "main"))
+	Equals(t, "replan", cfg.OnBaseBranchUpdate("github.com/owner/repo", "main"))
+	Equals(t, "invalidate", cfg.OnBaseBranchUpdate("github.com/owner/repo", "develop"))
+}
+
+func TestGlobalCfg_CheckoutSubmodulesAndLFS(t *testing.T) {
+	cfg := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})
+	Equals(t, false, cfg.CheckoutSubmodules("github.com/owner/repo", "main"))
+	Equals(t, false, cfg.CheckoutLFS("github.com/owner/repo", "main"))
+
+	cfg.Repos = append(cfg.Repos,
+		valid.Repo{
+			IDRegex:            regexp.MustCompile(".*"),
+			CheckoutSubmodules: Bool(true),
+		},
+		valid.Repo{
+			ID:                 "github.com/owner/repo",
+			BranchRegex:        regexp.MustCompile("^main$"),
+			CheckoutSubmodules: Bool(false),
+			CheckoutLFS:        Bool(true),
+		},
+	)
+	Equals(t, true, cfg.CheckoutSubmodules("github.com/owner/other", "main"))
+	Equals(t, false, cfg.CheckoutLFS("github.com/owner/other", "main"))
+	Equals(t, false, cfg.CheckoutSubmodules("github.com/owner/repo", "main"))
+	Equals(t, true, cfg.CheckoutLFS("github.com/owner/repo", "main"))
+	Equals(t, true, cfg.CheckoutSubmodules("github.com/owner/repo", "develop"))
+	Equals(t, false, cfg.CheckoutLFS("github.com/owner/repo", "develop"))
+}
+
+func TestGlobalCfg_AutoApply(t *testing.T) {
+	cfg := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})
+	Equals(t, false, cfg.AutoApply("github.com/owner/repo", "main"))
+
+	cfg.Repos = append(cfg.Repos,
+		valid.Repo{
+			IDRegex:   regexp.MustCompile(".*"),
+			AutoApply: Bool(true),
+		},
+		valid.Repo{
+			ID:          "github.com/owner/repo",
+			BranchRegex: regexp.MustCompile("^main$"),
+			AutoApply:   Bool(false),
+		},
+	)
+	Equals(t, true, cfg.AutoApply("github.com/owner/other", "main"))
+	Equals(t, false, cfg.AutoApply("github.com/owner/repo", "main"))
+	Equals(t, true, cfg.AutoApply("github.com/owner/repo", "develop"))
+}
+
+func TestGlobalCfg_SilenceNoChangePlans(t *testing.T) {
+	cfg := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})
+	Equals(t, false, cfg.SilenceNoChangePlans("github.com/owner/repo", "main", false))
+	Equals(t, true, cfg.SilenceNoChangePlans("github.com/owner/repo", "main", true))
+
+	cfg.Repos = append(cfg.Repos, valid.Repo{
+		ID:                   "github.com/owner/repo",
+		SilenceNoChangePlans: Bool(false),
+	})
+	Equals(t, false, cfg.SilenceNoChangePlans("github.com/owner/repo", "main", true))
+	Equals(t, true, cfg.SilenceNoChangePlans("github.com/owner/other", "main", true))
+}
+
+func TestGlobalCfg_CommentFormat(t *testing.T) {
+	cfg := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})
+	Equals(t, valid.FullCommentFormat, cfg.CommentFormat("github.com/owner/repo", "main"))
+
+	cfg.Repos = append(cfg.Repos, valid.Repo{
+		ID:            "github.com/owner/repo",
+		CommentFormat: valid.RollupCommentFormat,
+	})
+	Equals(t, valid.RollupCommentFormat, cfg.CommentFormat("github.com/owner/repo", "main"))
+	Equals(t, valid.FullCommentFormat, cfg.CommentFormat("github.com/owner/other", "main"))
+}
+
+func TestGlobalCfg_ApplyAllMaxProjects(t *testing.T) {
+	cfg := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})
+	_, ok := cfg.ApplyAllMaxProjects("github.com/owner/repo", "main")
+	Equals(t, false, ok)
+
+	limit := 0
+	cfg.Repos = append(cfg.Repos, valid.Repo{
+		ID:                  "github.com/owner/repo",
+		ApplyAllMaxProjects: &limit,
+	})
+	got, ok := cfg.ApplyAllMaxProjects("github.com/owner/repo", "main")
+	Equals(t, true, ok)
+	Equals(t, 0, got)
+	_, ok = cfg.ApplyAllMaxProjects("github.com/owner/other", "main")
+	Equals(t, false, ok)
+}
+
+func TestGlobalCfg_Locale(t *testing.T) {
+	cfg := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})
+	Equals(t, "en", cfg.Locale("github.com/owner/repo", "main", "en"))
+
+	cfg.Repos = append(cfg.Repos,
+		valid.Repo{
+			IDRegex: regexp.MustCompile(".*"),
+			Locale:  "ja",
+		},
+		valid.Repo{
+			ID:     "github.com/owner/repo",
+			Locale: "en",
+		},
+	)
+	t.Log("the last matching repo wins")
+	Equals(t, "ja", cfg.Locale("github.com/owner/other", "main", "en"))
+	Equals(t, "en", cfg.Locale("github.com/owner/repo", "main", "ja"))
+}
+
+func TestGlobalCfg_TerraformDistribution(t *testing.T) {
+	logger := logging.NewNoopLogger(t)
+	cfg := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})
+	cfg.Repos = append(cfg.Repos, valid.Repo{
+		ID:                    "github.com/owner/repo",
+		TerraformDistribution: valid.OpenTofuDistribution,
+	})
+
+	Equals(t, "", cfg.DefaultProjCfg(logger, "github.com/owner/other", ".", "default").TerraformDistribution)
+	Equals(t, "opentofu", cfg.DefaultProjCfg(logger, "github.com/owner/repo", ".", "default").TerraformDistribution)
+	Equals(t, "opentofu", cfg.MergeProjectCfg(logger, "github.com/owner/repo", valid.Project{Dir: "."}, valid.RepoCfg{}).TerraformDistribution)
+
+	t.Log("projects can override the server-side distribution")
+	proj := valid.Project{Dir: ".", TerraformDistribution: String(valid.TerraformDistribution)}
+	Equals(t, "terraform", cfg.MergeProjectCfg(logger, "github.com/owner/repo", proj, valid.RepoCfg{}).TerraformDistribution)
+}
+
+func TestGlobalCfg_LockGranularity(t *testing.T) {
+	logger := logging.NewNoopLogger(t)
+	cfg := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})
+	cfg.Repos = append(cfg.Repos,
+		valid.Repo{
+			IDRegex:         regexp.MustCompile(".*"),
+			LockGranularity: valid.DirLockGranularity,
+		},
+		valid.Repo{
+			ID:              "github.com/owner/repo",
+			LockGranularity: valid.ProjectLockGranularity,
+		},
+	)
+
+	t.Log("projects are locked by dir and workspace by default")
+	defaultCfg := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})
+	Equals(t, "", defaultCfg.DefaultProjCfg(logger, "github.com/owner/repo", ".", "default").LockGranularity)
+
+	t.Log("the last matching repo wins")
+	Equals(t, "dir", cfg.DefaultProjCfg(logger, "github.com/owner/other", ".", "default").LockGranularity)
+	Equals(t, "project", cfg.DefaultProjCfg(logger, "github.com/owner/repo", ".", "default").LockGranularity)
+	Equals(t, "project", cfg.MergeProjectCfg(logger, "github.com/owner/repo", valid.Project{Dir: "."}, valid.RepoCfg{}).LockGranularity)
+}
+
 // String is a helper routine that allocates a new string value
 // to store v and returns a pointer to it.
 func String(v string) *string { return &v }
@@ -899,3 +1244,35 @@ func String(v string) *string { return &v }
 // Bool is a helper routine that allocates a new bool value
 // to store v and returns a pointer to it.
 func Bool(v bool) *bool { return &v }
+
+func TestGlobalCfg_Credentials(t *testing.T) {
+	logger := logging.NewNoopLogger(t)
+	serverCreds := &valid.Credentials{AWS: &valid.AWSCredentials{RoleARN: "arn:aws:iam::123456789012:role/repo"}}
+	projCreds := &valid.Credentials{AWS: &valid.AWSCredentials{RoleARN: "arn:aws:iam::123456789012:role/project"}}
+	cfg := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})
+	cfg.Repos = append(cfg.Repos,
+		valid.Repo{
+			ID:          "github.com/owner/repo",
+			Credentials: serverCreds,
+		},
+		valid.Repo{
+			ID:               "github.com/owner/overridable",
+			Credentials:      serverCreds,
+			AllowedOverrides: []string{valid.CredentialsKey},
+		},
+	)
+
+	t.Log("no credentials are obtained by default")
+	Assert(t, cfg.DefaultProjCfg(logger, "github.com/owner/other", ".", "default").Credentials == nil, "exp no credentials")
+
+	t.Log("the server-side credentials apply to every project in the repo")
+	Equals(t, serverCreds, cfg.DefaultProjCfg(logger, "github.com/owner/repo", ".", "default").Credentials)
+	Equals(t, serverCreds, cfg.MergeProjectCfg(logger, "github.com/owner/repo", valid.Project{Dir: "."}, valid.RepoCfg{}).Credentials)
+
+	t.Log("projects can only set credentials if it's an allowed override")
+	proj := valid.Project{Dir: ".", Credentials: projCreds}
+	rCfg := valid.RepoCfg{Projects: []valid.Project{proj}}
+	ErrEquals(t, "repo config not allowed to set 'credentials' key: server-side config needs 'allowed_overrides: [credentials]'", cfg.ValidateRepoCfg(rCfg, "github.com/owner/repo"))
+	Ok(t, cfg.ValidateRepoCfg(rCfg, "github.com/owner/overridable"))
+	Equals(t, projCreds, cfg.MergeProjectCfg(logger, "github.com/owner/overridable", proj, rCfg).Credentials)
+}
@@ -18,8 +18,11 @@ type PolicySets struct {
 	PolicySets []PolicySet
 }
 
+// PolicyOwners are the users and teams that can approve failing policies.
 type PolicyOwners struct {
 	Users []string
+	// Teams are checked with the VCS host, ex. GitHub teams or GitLab groups.
+	Teams []string
 }
 
 type PolicySet struct {
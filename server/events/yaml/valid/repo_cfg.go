@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"time"
 
 	version "github.com/hashicorp/go-version"
 )
@@ -13,11 +14,17 @@ import (
 // RepoCfg is the atlantis.yaml config after it's been parsed and validated.
 type RepoCfg struct {
 	// Version is the version of the atlantis YAML file.
-	Version                   int
-	Projects                  []Project
-	Workflows                 map[string]Workflow
-	PolicySets                PolicySets
-	Automerge                 bool
+	Version    int
+	Projects   []Project
+	Workflows  map[string]Workflow
+	PolicySets PolicySets
+	Automerge  bool
+	// AutomergeMethod is the merge method used when automerging, ex. squash.
+	// If empty, the VCS host's default is used.
+	AutomergeMethod string
+	// AutomergeCommitMessage is a text/template for the automerge commit
+	// message. If empty, a default message is used.
+	AutomergeCommitMessage    string
 	ParallelApply             bool
 	ParallelPlan              bool
 	ParallelPolicyCheck       bool
@@ -99,14 +106,46 @@ func (r RepoCfg) ValidateWorkspaceAllowed(repoRelDir string, workspace string) e
 }
 
 type Project struct {
-	Dir                       string
-	Workspace                 string
-	Name                      *string
-	WorkflowName              *string
-	TerraformVersion          *version.Version
+	Dir              string
+	Workspace        string
+	Name             *string
+	WorkflowName     *string
+	TerraformVersion *version.Version
+	// TerraformDistribution, if set, overrides the server-side distribution
+	// of terraform to run for this project.
+	TerraformDistribution *string
+	// TFEWorkspace, if set, is the Terraform Cloud/Enterprise workspace,
+	// ex. my-org/my-workspace, that plans and applies run in as remote runs
+	// instead of running terraform locally.
+	TFEWorkspace              string
 	Autoplan                  Autoplan
 	ApplyRequirements         []string
 	DeleteSourceBranchOnMerge *bool
+	// Automerge overrides the repo's automerge setting for this project if
+	// set.
+	Automerge *bool
+	// AllowedCommands, if set, restricts which of RestrictableCommands can
+	// be run on the project.
+	AllowedCommands []string
+	// Credentials, if set, override the server-side credentials obtained
+	// for the project.
+	Credentials *Credentials
+	// Init are the options terraform init is run with.
+	Init InitOptions
+	// Outputs are the names of the outputs shown in the apply comment.
+	Outputs []string
+}
+
+// InitOptions are the options terraform init is run with for a project, so
+// projects using the default workflow don't need a custom workflow to pass
+// them as extra_args.
+type InitOptions struct {
+	// BackendConfig are passed to init as -backend-config. Each is either a
+	// file relative to the project's dir or a key=value pair.
+	BackendConfig []string
+	// Upgrade causes init to be run with -upgrade even if the project has a
+	// dependency lock file.
+	Upgrade bool
 }
 
 // GetName returns the name of the project or an empty string if there is no
@@ -138,6 +177,33 @@ type Step struct {
 	EnvVarName string
 	// EnvVarValue is the value to set EnvVarName to.
 	EnvVarValue string
+	// Timeout is how long the step can run before it's interrupted. If zero,
+	// only the server's command timeout applies.
+	Timeout time.Duration
+	// Scan is set for scan steps.
+	Scan *ScanOptions
+}
+
+// ScanTools are the security scanners scan steps can run.
+var ScanTools = []string{"tfsec", "checkov"}
+
+// DefaultScanTool is the scanner scan steps run if they don't set one.
+const DefaultScanTool = "tfsec"
+
+// ScanSeverities are the severities of scan findings that plans can fail
+// on, from least to most severe.
+var ScanSeverities = []string{"LOW", "MEDIUM", "HIGH", "CRITICAL"}
+
+// ScanOptions configure the security scanner a scan step runs.
+type ScanOptions struct {
+	// Tool is the scanner, one of ScanTools.
+	Tool string
+	// Version is the version of Tool to download. If nil, the server's
+	// default version is used.
+	Version *version.Version
+	// FailOn is the severity at or above which findings fail the plan. If
+	// empty, findings are only shown.
+	FailOn string
 }
 
 type Workflow struct {
@@ -0,0 +1,34 @@
+package valid
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// Secret is a value defined on the server that run steps can reference
+// without it being committed to the repo. Only one of Env or File is set.
+type Secret struct {
+	// Env is the name of the environment variable holding the value.
+	Env string
+	// File is the path of the file holding the value.
+	File string
+}
+
+// Value reads the secret's value. Trailing newlines are removed from file
+// backed secrets since most editors add one.
+func (s Secret) Value() (string, error) {
+	if s.Env != "" {
+		val, ok := os.LookupEnv(s.Env)
+		if !ok {
+			return "", fmt.Errorf("environment variable %q is not set", s.Env)
+		}
+		return val, nil
+	}
+	contents, err := ioutil.ReadFile(s.File) // nolint: gosec
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(contents), "\r\n"), nil
+}
@@ -0,0 +1,350 @@
+package i18n
+
+// Message identifies a message in the catalog.
+type Message string
+
+// Messages are fmt format strings. Translations can reorder their arguments
+// with explicit indexes, ex. %[2]s.
+const (
+	// Comment replies.
+	ApplyDisabled            Message = "apply_disabled"
+	ApplyAllDisabled         Message = "apply_all_disabled"
+	ApplyAllMaxProjects      Message = "apply_all_max_projects"
+	ApplyNotConfirmed        Message = "apply_not_confirmed"
+	ApplyNotConfirmedTable   Message = "apply_not_confirmed_table"
+	ApplyNotConfirmedEarlier Message = "apply_not_confirmed_earlier"
+	ApplyNotConfirmedHowTo   Message = "apply_not_confirmed_how_to"
+	ApplyConfirmNotPending   Message = "apply_confirm_not_pending"
+	ApplyConfirmExpired      Message = "apply_confirm_expired"
+	ApplyConfirmPullUpdated  Message = "apply_confirm_pull_updated"
+	ApplyConfirmPlansChanged Message = "apply_confirm_plans_changed"
+	ApplyConfirmNotAllowed   Message = "apply_confirm_not_allowed"
+	Automerging              Message = "automerging"
+	AutomergeFailed          Message = "automerge_failed"
+	UserNotInApplyTeams      Message = "user_not_in_apply_teams"
+	CommandPlanOnly          Message = "command_plan_only"
+	RepoMaxParallel          Message = "repo_max_parallel"
+	LowDiskSpace             Message = "low_disk_space"
+	ProgressRunning          Message = "progress_running"
+	ProgressViewLogs         Message = "progress_view_logs"
+	ProgressFinished         Message = "progress_finished"
+	BaseBranchUpdated        Message = "base_branch_updated"
+	LockExpired              Message = "lock_expired"
+	LockDiscardedViaUI       Message = "lock_discarded_via_ui"
+	PullDiscardedViaUI       Message = "pull_discarded_via_ui"
+
+	// Comment parser errors.
+	ParseCommandError       Message = "parse_command_error"
+	UnknownCommand          Message = "unknown_command"
+	CommandUsageError       Message = "command_usage_error"
+	StateRequiresSubcommand Message = "state_requires_subcommand"
+	ImportRequiresArgs      Message = "import_requires_args"
+	StateRmRequiresArgs     Message = "state_rm_requires_args"
+	StateMvRequiresArgs     Message = "state_mv_requires_args"
+	UnknownArgs             Message = "unknown_args"
+
+	// Commit status descriptions.
+	StatusInProgress            Message = "status_in_progress"
+	StatusFailed                Message = "status_failed"
+	StatusSucceeded             Message = "status_succeeded"
+	StatusProjectsPlanned       Message = "status_projects_planned"
+	StatusProjectsPolicyChecked Message = "status_projects_policy_checked"
+	StatusProjectsApplied       Message = "status_projects_applied"
+	StatusProjectsUnknown       Message = "status_projects_unknown"
+	StatusMergeConflicts        Message = "status_merge_conflicts"
+	StatusPlanChanges           Message = "status_plan_changes"
+	StatusPlanNoChanges         Message = "status_plan_no_changes"
+	StatusApplyChanges          Message = "status_apply_changes"
+
+	// Command result comments.
+	RanForProject             Message = "ran_for_project"
+	RanForDir                 Message = "ran_for_dir"
+	RanForProjects            Message = "ran_for_projects"
+	RanForProjectsWithHistory Message = "ran_for_projects_with_history"
+	ApprovedPolicies          Message = "approved_policies"
+	ProjectDirWorkspace       Message = "project_dir_workspace"
+	DirWorkspace              Message = "dir_workspace"
+	TotalChanges              Message = "total_changes"
+	ApplyAllNextSteps         Message = "apply_all_next_steps"
+	RollupTableHeader         Message = "rollup_table_header"
+	RollupOutputHeader        Message = "rollup_output_header"
+	RollupView                Message = "rollup_view"
+	DestroyPlanWarning        Message = "destroy_plan_warning"
+	StepsRetried              Message = "steps_retried"
+	StepRetry                 Message = "step_retry"
+	ScanFoundIssues           Message = "scan_found_issues"
+	ScanFoundIssuesFailOn     Message = "scan_found_issues_fail_on"
+	ScanFinding               Message = "scan_finding"
+	ScanFindingResource       Message = "scan_finding_resource"
+	ScanNoIssues              Message = "scan_no_issues"
+	ShowOutput                Message = "show_output"
+	BranchDiverged            Message = "branch_diverged"
+	ResourcesCreate           Message = "resources_create"
+	ResourcesDestroy          Message = "resources_destroy"
+	ResourcesUpdate           Message = "resources_update"
+	ResourcesReplace          Message = "resources_replace"
+	PlanNotSaved              Message = "plan_not_saved"
+	ApplyPlanStep             Message = "apply_plan_step"
+	DeletePlanStep            Message = "delete_plan_step"
+	ReplanStep                Message = "replan_step"
+	PolicyReplanStep          Message = "policy_replan_step"
+	ApplyOutputs              Message = "apply_outputs"
+	SensitiveOutput           Message = "sensitive_output"
+	StateChangePlanDeleted    Message = "state_change_plan_deleted"
+	CommandError              Message = "command_error"
+	PolicyApproveHint         Message = "policy_approve_hint"
+	CommandFailed             Message = "command_failed"
+	LogSummary                Message = "log_summary"
+
+	// Project command failures.
+	DestroyPlansNotAllowed Message = "destroy_plans_not_allowed"
+	TargetingDisabled      Message = "targeting_disabled"
+	LockedByPull           Message = "locked_by_pull"
+	LockQueued             Message = "lock_queued"
+	PlanNotTargeted        Message = "plan_not_targeted"
+	PlanTargetsDiffer      Message = "plan_targets_differ"
+	PlanFromEarlierCommit  Message = "plan_from_earlier_commit"
+	ApplyReqApproved       Message = "apply_req_approved"
+	ApplyReqPoliciesPassed Message = "apply_req_policies_passed"
+	ApplyReqMergeable      Message = "apply_req_mergeable"
+	ApplyReqCodeowners     Message = "apply_req_codeowners"
+	ApplyReqUndiverged     Message = "apply_req_undiverged"
+	ApplyReqPlanNewerThan  Message = "apply_req_plan_newer_than"
+	ApplyReqCustom         Message = "apply_req_custom"
+	ApplyInProgress        Message = "apply_in_progress"
+	ApplyQueued            Message = "apply_queued"
+	ScanFailed             Message = "scan_failed"
+	StateLockTimedOut      Message = "state_lock_timed_out"
+	StateLockNotAcquired   Message = "state_lock_not_acquired"
+	StateLockHeldBy        Message = "state_lock_held_by"
+	StateLockOperation     Message = "state_lock_operation"
+	StateLockSince         Message = "state_lock_since"
+	StateLockRetry         Message = "state_lock_retry"
+	StateLockForceUnlock   Message = "state_lock_force_unlock"
+	PoliciesApproved       Message = "policies_approved"
+)
+
+var catalog = map[string]map[Message]string{
+	English: {
+		ApplyDisabled: "**Error:** Running `atlantis apply` is disabled.",
+		ApplyAllDisabled: "**Error:** Running `atlantis apply` without flags is disabled." +
+			" You must specify which project to apply via the `-d <dir>`, `-w <workspace>` or `-p <project name>` flags.",
+		ApplyAllMaxProjects: "**Error:** Running `atlantis apply` without flags is limited to %d projects but this pull request has %d plans to apply." +
+			" You must specify which project to apply via the `-d <dir>`, `-w <workspace>` or `-p <project name>` flags:",
+		ApplyNotConfirmed:           "**Apply Not Yet Confirmed**: these plans for commit `%s` would be applied:",
+		ApplyNotConfirmedTable:      "| Project | Dir | Workspace |",
+		ApplyNotConfirmedEarlier:    "(planned at an earlier commit)",
+		ApplyNotConfirmedHowTo:      "To apply them, comment within %s:",
+		ApplyConfirmNotPending:      "there's no apply waiting to be confirmed. Comment `atlantis apply` first to see which plans would be applied",
+		ApplyConfirmExpired:         "the apply waiting to be confirmed expired. Comment `atlantis apply` again",
+		ApplyConfirmPullUpdated:     "the pull request was updated from %s to %s since the apply was requested. Comment `atlantis apply` again",
+		ApplyConfirmPlansChanged:    "the plans that would be applied changed since the apply was requested. Comment `atlantis apply` again",
+		ApplyConfirmNotAllowed:      "the apply waiting to be confirmed was requested by %s. Only they or members of the teams allowed to apply on this repo can confirm it",
+		Automerging:                 "Automatically merging because all plans have been successfully applied.",
+		AutomergeFailed:             "Automerging failed:\n```\n%s\n```",
+		UserNotInApplyTeams:         "**Error:** User `%s` is not allowed to run `atlantis %s` on this repo. Only members of these teams can: `%s`.",
+		CommandPlanOnly:             "**Error:** `atlantis %s` is disabled for this repo. Atlantis is only allowed to plan it.",
+		RepoMaxParallel:             "**Error:** This repo can only run %d Atlantis command(s) at once and that many are already running. Try again once they've finished.",
+		LowDiskSpace:                "**Error:** Atlantis is low on disk space: its data dir is using %s which is over its limit of %s. Try again once the working dirs of closed pull requests have been cleaned up.",
+		ProgressRunning:             ":hourglass_flowing_sand: Running...\n\n`atlantis %s` was requested by @%s. This comment will be updated with the results.",
+		ProgressViewLogs:            "[View logs](%s)",
+		ProgressFinished:            ":heavy_check_mark: Finished\n\n`atlantis %s` requested by @%s has finished.",
+		BaseBranchUpdated:           "**Warning**: `%s` was updated so the plans for this pull request are stale and were **discarded**.\n\nTo `apply` you must run `plan` again.",
+		LockExpired:                 "**Warning**: The lock for dir: `%s` workspace: `%s` expired after being held for more than %s, so its plan was **discarded**.\n\nTo `apply` this plan you must run `plan` again.",
+		LockDiscardedViaUI:          "**Warning**: The plan for dir: `%s` workspace: `%s` was **discarded** via the Atlantis UI.\n\nTo `apply` this plan you must run `plan` again.",
+		PullDiscardedViaUI:          "**Warning**: All plans for this pull request were **discarded** and its locks deleted via the Atlantis UI.\n\nTo `apply` you must run `plan` again.",
+		ParseCommandError:           "```\nError parsing command: %s\n```",
+		UnknownCommand:              "```\nError: unknown command %q.\nRun 'atlantis --help' for usage.\n```",
+		CommandUsageError:           "```\nError: %s.\nUsage of %s:\n%s```",
+		StateRequiresSubcommand:     "state requires a subcommand: %s or %s",
+		ImportRequiresArgs:          "import requires exactly two arguments: ADDRESS and ID",
+		StateRmRequiresArgs:         "state rm requires at least one argument: ADDRESS",
+		StateMvRequiresArgs:         "state mv requires exactly two arguments: SOURCE and DESTINATION",
+		UnknownArgs:                 "unknown argument(s) – %s",
+		StatusInProgress:            "%s in progress...",
+		StatusFailed:                "%s failed.",
+		StatusSucceeded:             "%s succeeded.",
+		StatusProjectsPlanned:       "%d/%d projects planned successfully.",
+		StatusProjectsPolicyChecked: "%d/%d projects policies checked successfully.",
+		StatusProjectsApplied:       "%d/%d projects applied successfully.",
+		StatusProjectsUnknown:       "%d/%d projects unknown successfully.",
+		StatusMergeConflicts:        "%s failed: merge conflicts with %s, resolve them and push again.",
+		StatusPlanChanges:           "Plan: %d to add, %d to change, %d to destroy.",
+		StatusPlanNoChanges:         "Plan: no changes.",
+		StatusApplyChanges:          "Apply: %d added, %d changed, %d destroyed.",
+		RanForProject:               "Ran %s for project: `%s` dir: `%s` workspace: `%s`",
+		RanForDir:                   "Ran %s for dir: `%s` workspace: `%s`",
+		RanForProjects:              "Ran %s for %d projects:",
+		RanForProjectsWithHistory:   "Ran %s for %d projects. See the [full output](%s):",
+		ApprovedPolicies:            "Approved Policies for %d projects:",
+		ProjectDirWorkspace:         "project: `%s` dir: `%s` workspace: `%s`",
+		DirWorkspace:                "dir: `%s` workspace: `%s`",
+		TotalChanges:                "**Total:** `%s`",
+		ApplyAllNextSteps:           "* :fast_forward: To **apply** all unapplied plans from this pull request, comment:\n    * `atlantis apply`\n* :put_litter_in_its_place: To delete all plans and locks for the PR, comment:\n    * `atlantis unlock`",
+		RollupTableHeader:           "| Project | Dir | Workspace | Status | Add | Change | Destroy |",
+		RollupOutputHeader:          " Output |",
+		RollupView:                  "[view](%s)",
+		DestroyPlanWarning:          ":warning: **This is a destroy plan.** Applying it will destroy every resource in this project.",
+		StepsRetried:                ":recycle: Some steps failed with transient errors and were retried:",
+		StepRetry:                   "* `%s` attempt %d: `%s`",
+		ScanFoundIssues:             ":mag: **%s** found %d issue(s):",
+		ScanFoundIssuesFailOn:       ":mag: **%s** found %d issue(s), plans fail on `%s` or higher:",
+		ScanFinding:                 "* `%s` at `%s`: %s",
+		ScanFindingResource:         "* `%s` `%s` at `%s`: %s",
+		ScanNoIssues:                ":white_check_mark: **%s** found no issues.",
+		ShowOutput:                  "Show Output",
+		BranchDiverged:              ":warning: The branch we're merging into is ahead, it is recommended to pull new commits first.",
+		ResourcesCreate:             "Create",
+		ResourcesDestroy:            "Destroy",
+		ResourcesUpdate:             "Update in-place",
+		ResourcesReplace:            "Replace",
+		PlanNotSaved:                "This plan was not saved because one or more projects failed and automerge requires all plans pass.",
+		ApplyPlanStep:               "* :arrow_forward: To **apply** this plan, comment:\n    * `%s`",
+		DeletePlanStep:              "* :put_litter_in_its_place: To **delete** this plan click [here](%s)",
+		ReplanStep:                  "* :repeat: To **plan** this project again, comment:\n    * `%s`",
+		PolicyReplanStep:            "* :repeat: To re-run policies **plan** this project again by commenting:\n    * `%s`",
+		ApplyOutputs:                "**Outputs**",
+		SensitiveOutput:             "*(sensitive)*",
+		StateChangePlanDeleted:      ":put_litter_in_its_place: Any existing plan for this project was deleted since it's now stale.",
+		CommandError:                "**%s Error**",
+		PolicyApproveHint:           "* :heavy_check_mark: To **approve** failing policies either request an approval from approvers or address the failure by modifying the codebase.",
+		CommandFailed:               "**%s Failed**: %s",
+		LogSummary:                  "Log",
+		DestroyPlansNotAllowed:      "Destroy plans are not allowed for this project. To allow them, set `%s: true` in the server-side repo config.",
+		TargetingDisabled:           "Targeting resources with `-target` is not allowed for this project because `%s: true` is set in the server-side repo config.",
+		LockedByPull:                "This project is currently locked by an unapplied plan from pull %s. To continue, delete the lock from %s or apply that plan and merge the pull request.\n\nOnce the lock is released, comment `atlantis plan` here to re-plan.",
+		LockQueued:                  "This project is currently locked by an unapplied plan from pull #%d. This plan is queued behind it as number %d in the queue and will run automatically once the lock is released.",
+		PlanNotTargeted:             "This project's plan isn't targeted. To apply it, comment `atlantis apply` without `--target`.",
+		PlanTargetsDiffer:           "This project's plan was targeted at %s, not %s. Run plan again with these targets, or to apply the plan, comment `atlantis apply` without `--target`.",
+		PlanFromEarlierCommit:       "This plan was generated from an earlier commit than %s. Run plan again, or to apply it anyway, comment `atlantis apply --force`.",
+		ApplyReqApproved:            "Pull request must be approved by at least one person other than the author before running apply.",
+		ApplyReqPoliciesPassed:      "All policies must pass for project before running apply",
+		ApplyReqMergeable:           "Pull request must be mergeable before running apply.",
+		ApplyReqCodeowners:          "Each modified file must be approved by one of its code owners before running apply. Not approved: %s.",
+		ApplyReqUndiverged:          "Default branch must be rebased onto pull request before running apply.",
+		ApplyReqPlanNewerThan:       "Plan must have been generated in the last %s before running apply. Run plan again to generate a new plan.",
+		ApplyReqCustom:              "Apply requirement %q must pass before running apply: %s",
+		ApplyInProgress:             "This project is already being applied for this pull request. Wait until the apply is complete.",
+		ApplyQueued:                 "Another pull request is currently applying this project. This apply is number %d in the queue and will run automatically once the applies ahead of it are complete.",
+		ScanFailed:                  "The security scan found issues at or above the severity it fails on so the plan was discarded. Fix them and comment `%s` to plan again.",
+		StateLockTimedOut:           "Terraform timed out waiting for the state lock.",
+		StateLockNotAcquired:        "Terraform couldn't acquire the state lock.",
+		StateLockHeldBy:             "Terraform couldn't acquire the state lock because it's held by `%s`%s%s.",
+		StateLockOperation:          " for `%s`",
+		StateLockSince:              " since %s",
+		StateLockRetry:              "Another run is likely using this project's state, ex. a different pipeline or a local terraform command. Wait for it to finish and then comment `%s` to try again.",
+		StateLockForceUnlock:        "If nothing is using the state, its lock can be released with `terraform force-unlock %s`.",
+		PoliciesApproved:            "Policies approved",
+	},
+	Japanese: {
+		ApplyDisabled: "**エラー:** `atlantis apply` の実行は無効になっています。",
+		ApplyAllDisabled: "**エラー:** フラグなしの `atlantis apply` の実行は無効になっています。" +
+			"`-d <dir>`、`-w <workspace>` または `-p <project name>` フラグで apply するプロジェクトを指定してください。",
+		ApplyAllMaxProjects: "**エラー:** フラグなしの `atlantis apply` で apply できるのは %d 個のプロジェクトまでですが、このプルリクエストには apply するプランが %d 個あります。" +
+			"`-d <dir>`、`-w <workspace>` または `-p <project name>` フラグで apply するプロジェクトを指定してください:",
+		ApplyNotConfirmed:           "**Apply 未確認**: コミット `%s` の次のプランが apply されます:",
+		ApplyNotConfirmedTable:      "| プロジェクト | ディレクトリ | ワークスペース |",
+		ApplyNotConfirmedEarlier:    "(以前のコミットで plan 済み)",
+		ApplyNotConfirmedHowTo:      "apply するには、%s 以内に次のコメントをしてください:",
+		ApplyConfirmNotPending:      "確認待ちの apply はありません。まず `atlantis apply` をコメントして、apply されるプランを確認してください",
+		ApplyConfirmExpired:         "確認待ちの apply の期限が切れました。もう一度 `atlantis apply` をコメントしてください",
+		ApplyConfirmPullUpdated:     "apply が要求された後にプルリクエストが %s から %s に更新されました。もう一度 `atlantis apply` をコメントしてください",
+		ApplyConfirmPlansChanged:    "apply が要求された後に apply されるプランが変わりました。もう一度 `atlantis apply` をコメントしてください",
+		ApplyConfirmNotAllowed:      "確認待ちの apply は %s が要求したものです。確認できるのは本人か、このリポジトリで apply を許可されたチームのメンバーだけです",
+		Automerging:                 "すべてのプランが正常に apply されたため、自動的にマージします。",
+		AutomergeFailed:             "自動マージに失敗しました:\n```\n%s\n```",
+		UserNotInApplyTeams:         "**エラー:** ユーザー `%s` はこのリポジトリで `atlantis %s` を実行できません。実行できるのは次のチームのメンバーのみです: `%s`。",
+		CommandPlanOnly:             "**エラー:** このリポジトリでは `atlantis %s` は無効になっています。Atlantis はこのリポジトリの plan のみ許可されています。",
+		RepoMaxParallel:             "**エラー:** このリポジトリで同時に実行できる Atlantis コマンドは %d 個までで、すでにその数が実行中です。完了してからもう一度お試しください。",
+		LowDiskSpace:                "**エラー:** Atlantis のディスク容量が不足しています: データディレクトリの使用量 %s が上限の %s を超えています。クローズされたプルリクエストの作業ディレクトリが削除されてからもう一度お試しください。",
+		ProgressRunning:             ":hourglass_flowing_sand: 実行中...\n\n@%[2]s が `atlantis %[1]s` を要求しました。このコメントは結果で更新されます。",
+		ProgressViewLogs:            "[ログを表示](%s)",
+		ProgressFinished:            ":heavy_check_mark: 完了\n\n@%[2]s が要求した `atlantis %[1]s` が完了しました。",
+		BaseBranchUpdated:           "**警告**: `%s` が更新されたため、このプルリクエストのプランは古くなり**破棄**されました。\n\n`apply` するには、もう一度 `plan` を実行してください。",
+		LockExpired:                 "**警告**: ディレクトリ `%s`、ワークスペース `%s` のロックが %s 以上保持されて期限切れになったため、プランは**破棄**されました。\n\nこのプランを `apply` するには、もう一度 `plan` を実行してください。",
+		LockDiscardedViaUI:          "**警告**: ディレクトリ `%s`、ワークスペース `%s` のプランは Atlantis UI から**破棄**されました。\n\nこのプランを `apply` するには、もう一度 `plan` を実行してください。",
+		PullDiscardedViaUI:          "**警告**: このプルリクエストのすべてのプランは Atlantis UI から**破棄**され、ロックが削除されました。\n\n`apply` するには、もう一度 `plan` を実行してください。",
+		ParseCommandError:           "```\nコマンドの解析エラー: %s\n```",
+		UnknownCommand:              "```\nエラー: 不明なコマンド %q です。\n使い方は 'atlantis --help' を実行してください。\n```",
+		CommandUsageError:           "```\nエラー: %s。\n%s の使い方:\n%s```",
+		StateRequiresSubcommand:     "state にはサブコマンドが必要です: %s または %s",
+		ImportRequiresArgs:          "import には引数がちょうど 2 つ必要です: ADDRESS と ID",
+		StateRmRequiresArgs:         "state rm には引数が 1 つ以上必要です: ADDRESS",
+		StateMvRequiresArgs:         "state mv には引数がちょうど 2 つ必要です: SOURCE と DESTINATION",
+		UnknownArgs:                 "不明な引数 – %s",
+		StatusInProgress:            "%s 実行中...",
+		StatusFailed:                "%s に失敗しました。",
+		StatusSucceeded:             "%s に成功しました。",
+		StatusProjectsPlanned:       "%d/%d プロジェクトの plan に成功しました。",
+		StatusProjectsPolicyChecked: "%d/%d プロジェクトのポリシーチェックに成功しました。",
+		StatusProjectsApplied:       "%d/%d プロジェクトの apply に成功しました。",
+		StatusProjectsUnknown:       "%d/%d プロジェクトに成功しました。",
+		StatusMergeConflicts:        "%s に失敗しました: %s とコンフリクトしています。解消してからもう一度 push してください。",
+		StatusPlanChanges:           "Plan: 追加 %d、変更 %d、削除 %d。",
+		StatusPlanNoChanges:         "Plan: 変更なし。",
+		StatusApplyChanges:          "Apply: 追加 %d、変更 %d、削除 %d。",
+		RanForProject:               "プロジェクト `%[2]s`、ディレクトリ `%[3]s`、ワークスペース `%[4]s` で %[1]s を実行しました",
+		RanForDir:                   "ディレクトリ `%[2]s`、ワークスペース `%[3]s` で %[1]s を実行しました",
+		RanForProjects:              "%[2]d 個のプロジェクトで %[1]s を実行しました:",
+		RanForProjectsWithHistory:   "%[2]d 個のプロジェクトで %[1]s を実行しました。[すべての出力](%[3]s)を参照してください:",
+		ApprovedPolicies:            "%d 個のプロジェクトのポリシーを承認しました:",
+		ProjectDirWorkspace:         "プロジェクト: `%s` ディレクトリ: `%s` ワークスペース: `%s`",
+		DirWorkspace:                "ディレクトリ: `%s` ワークスペース: `%s`",
+		TotalChanges:                "**合計:** `%s`",
+		ApplyAllNextSteps:           "* :fast_forward: このプルリクエストの apply されていないプランをすべて **apply** するには、次のコメントをしてください:\n    * `atlantis apply`\n* :put_litter_in_its_place: このプルリクエストのすべてのプランとロックを削除するには、次のコメントをしてください:\n    * `atlantis unlock`",
+		RollupTableHeader:           "| プロジェクト | ディレクトリ | ワークスペース | ステータス | 追加 | 変更 | 削除 |",
+		RollupOutputHeader:          " 出力 |",
+		RollupView:                  "[表示](%s)",
+		DestroyPlanWarning:          ":warning: **これは destroy プランです。** apply するとこのプロジェクトのすべてのリソースが削除されます。",
+		StepsRetried:                ":recycle: 一時的なエラーで失敗したため再試行されたステップがあります:",
+		StepRetry:                   "* `%s` %d 回目の試行: `%s`",
+		ScanFoundIssues:             ":mag: **%s** が %d 件の問題を検出しました:",
+		ScanFoundIssuesFailOn:       ":mag: **%s** が %d 件の問題を検出しました。`%s` 以上の問題があるとプランは失敗します:",
+		ScanFinding:                 "* `%s` (`%s`): %s",
+		ScanFindingResource:         "* `%s` `%s` (`%s`): %s",
+		ScanNoIssues:                ":white_check_mark: **%s** は問題を検出しませんでした。",
+		ShowOutput:                  "出力を表示",
+		BranchDiverged:              ":warning: マージ先のブランチが先に進んでいます。先に新しいコミットを取り込むことをお勧めします。",
+		ResourcesCreate:             "作成",
+		ResourcesDestroy:            "削除",
+		ResourcesUpdate:             "インプレース更新",
+		ResourcesReplace:            "置き換え",
+		PlanNotSaved:                "1 つ以上のプロジェクトが失敗し、自動マージにはすべてのプランの成功が必要なため、このプランは保存されませんでした。",
+		ApplyPlanStep:               "* :arrow_forward: このプランを **apply** するには、次のコメントをしてください:\n    * `%s`",
+		DeletePlanStep:              "* :put_litter_in_its_place: このプランを**削除**するには[ここ](%s)をクリックしてください",
+		ReplanStep:                  "* :repeat: このプロジェクトをもう一度 **plan** するには、次のコメントをしてください:\n    * `%s`",
+		PolicyReplanStep:            "* :repeat: ポリシーを再実行するには、次のコメントでこのプロジェクトをもう一度 **plan** してください:\n    * `%s`",
+		ApplyOutputs:                "**出力値**",
+		SensitiveOutput:             "*(機密)*",
+		StateChangePlanDeleted:      ":put_litter_in_its_place: このプロジェクトの既存のプランは古くなったため削除されました。",
+		CommandError:                "**%s エラー**",
+		PolicyApproveHint:           "* :heavy_check_mark: 失敗したポリシーを**承認**するには、承認者に承認を依頼するか、コードを修正して失敗を解消してください。",
+		CommandFailed:               "**%s 失敗**: %s",
+		LogSummary:                  "ログ",
+		DestroyPlansNotAllowed:      "このプロジェクトでは destroy プランは許可されていません。許可するには、サーバー側のリポジトリ設定で `%s: true` を設定してください。",
+		TargetingDisabled:           "サーバー側のリポジトリ設定で `%s: true` が設定されているため、このプロジェクトでは `-target` でリソースを指定できません。",
+		LockedByPull:                "このプロジェクトはプル %s の apply されていないプランによってロックされています。続行するには、%s からロックを削除するか、そのプランを apply してプルリクエストをマージしてください。\n\nロックが解放されたら、ここで `atlantis plan` をコメントしてもう一度 plan してください。",
+		LockQueued:                  "このプロジェクトはプル #%d の apply されていないプランによってロックされています。このプランはキューの %d 番目で待機しており、ロックが解放されると自動的に実行されます。",
+		PlanNotTargeted:             "このプロジェクトのプランはターゲットが指定されていません。apply するには、`--target` を付けずに `atlantis apply` をコメントしてください。",
+		PlanTargetsDiffer:           "このプロジェクトのプランのターゲットは %s で、%s ではありません。これらのターゲットでもう一度 plan を実行するか、このプランを apply するには `--target` を付けずに `atlantis apply` をコメントしてください。",
+		PlanFromEarlierCommit:       "このプランは %s より前のコミットから生成されました。もう一度 plan を実行するか、それでも apply するには `atlantis apply --force` をコメントしてください。",
+		ApplyReqApproved:            "apply を実行する前に、作成者以外の少なくとも 1 人がプルリクエストを承認する必要があります。",
+		ApplyReqPoliciesPassed:      "apply を実行する前に、プロジェクトのすべてのポリシーが成功する必要があります",
+		ApplyReqMergeable:           "apply を実行する前に、プルリクエストがマージ可能である必要があります。",
+		ApplyReqCodeowners:          "apply を実行する前に、変更された各ファイルがそのコードオーナーのいずれかに承認される必要があります。未承認: %s。",
+		ApplyReqUndiverged:          "apply を実行する前に、デフォルトブランチをプルリクエストにリベースする必要があります。",
+		ApplyReqPlanNewerThan:       "apply を実行するには、プランが直近 %s 以内に生成されている必要があります。もう一度 plan を実行して新しいプランを生成してください。",
+		ApplyReqCustom:              "apply を実行する前に、apply 要件 %q が成功する必要があります: %s",
+		ApplyInProgress:             "このプルリクエストでこのプロジェクトはすでに apply 中です。apply が完了するまでお待ちください。",
+		ApplyQueued:                 "別のプルリクエストがこのプロジェクトを apply 中です。この apply はキューの %d 番目で、先の apply が完了すると自動的に実行されます。",
+		ScanFailed:                  "セキュリティスキャンが失敗の基準以上の重大度の問題を検出したため、プランは破棄されました。問題を修正し、`%s` をコメントしてもう一度 plan してください。",
+		StateLockTimedOut:           "Terraform が state ロックの待機中にタイムアウトしました。",
+		StateLockNotAcquired:        "Terraform が state ロックを取得できませんでした。",
+		StateLockHeldBy:             "Terraform が state ロックを取得できませんでした。`%[1]s` が%[3]s%[2]s保持しています。",
+		StateLockOperation:          " `%s` のために",
+		StateLockSince:              " %s から",
+		StateLockRetry:              "別の実行 (別のパイプラインやローカルの terraform コマンドなど) がこのプロジェクトの state を使用している可能性があります。完了するまで待ってから `%s` をコメントしてもう一度お試しください。",
+		StateLockForceUnlock:        "state を何も使用していない場合は、`terraform force-unlock %s` でロックを解除できます。",
+		PoliciesApproved:            "ポリシーが承認されました",
+	},
+}
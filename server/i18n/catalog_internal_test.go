@@ -0,0 +1,48 @@
+package i18n
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"testing"
+
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+var verbRegex = regexp.MustCompile(`%(?:\[(\d+)\])?[a-zA-Z]`)
+
+// verbs returns the verbs in format along with the index of the argument
+// each one formats, ex. [1]s, so translations that reorder their arguments
+// can be compared with English.
+func verbs(format string) []string {
+	var vs []string
+	arg := 0
+	for _, m := range verbRegex.FindAllStringSubmatch(format, -1) {
+		if m[1] != "" {
+			arg, _ = strconv.Atoi(m[1])
+		} else {
+			arg++
+		}
+		vs = append(vs, "["+strconv.Itoa(arg)+"]"+m[0][len(m[0])-1:])
+	}
+	sort.Strings(vs)
+	return vs
+}
+
+func TestCatalog_Complete(t *testing.T) {
+	for locale, messages := range catalog {
+		for msg, format := range catalog[English] {
+			translated, ok := messages[msg]
+			Assert(t, ok, "%s is missing %s", locale, msg)
+			Equals(t, verbs(format), verbs(translated))
+		}
+		Equals(t, len(catalog[English]), len(messages))
+	}
+}
+
+func TestCatalog_LocalesSupported(t *testing.T) {
+	Equals(t, len(Locales), len(catalog))
+	for _, locale := range Locales {
+		Assert(t, Supported(locale), "%s isn't in the catalog", locale)
+	}
+}
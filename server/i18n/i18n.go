@@ -0,0 +1,68 @@
+// Package i18n holds the catalog of user-facing messages, ex. comment replies
+// and commit status descriptions, in each locale Atlantis supports.
+package i18n
+
+import (
+	"fmt"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// English and Japanese are the supported locales.
+const (
+	English  = "en"
+	Japanese = "ja"
+)
+
+// Locales are the supported locales.
+var Locales = []string{English, Japanese}
+
+// Supported returns true if locale is one of Locales.
+func Supported(locale string) bool {
+	_, ok := catalog[locale]
+	return ok
+}
+
+// Sprintf formats msg in locale with args. If locale doesn't have msg, or
+// isn't supported, the English message is used.
+func Sprintf(locale string, msg Message, args ...interface{}) string {
+	format, ok := catalog[locale][msg]
+	if !ok {
+		format = catalog[English][msg]
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// Localizer picks the locale of the messages for a repo's pull requests. A
+// nil Localizer always picks English.
+type Localizer struct {
+	// Locale is the locale used unless the repo sets its own. It's set by
+	// --locale.
+	Locale string
+	// GlobalCfg, if set, is used to look up the locale that repos set in the
+	// server-side repo config.
+	GlobalCfg *valid.GlobalCfgStore
+}
+
+// For returns the locale of the messages for repo's pull requests into
+// baseBranch.
+func (l *Localizer) For(repo models.Repo, baseBranch string) string {
+	if l == nil {
+		return English
+	}
+	locale := l.Locale
+	if locale == "" {
+		locale = English
+	}
+	if l.GlobalCfg == nil {
+		return locale
+	}
+	return l.GlobalCfg.Get().Locale(repo.ID(), baseBranch, locale)
+}
+
+// Sprintf formats msg with args in the locale for repo's pull requests into
+// baseBranch.
+func (l *Localizer) Sprintf(repo models.Repo, baseBranch string, msg Message, args ...interface{}) string {
+	return Sprintf(l.For(repo, baseBranch), msg, args...)
+}
@@ -0,0 +1,40 @@
+package i18n_test
+
+import (
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/i18n"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestSprintf(t *testing.T) {
+	Equals(t, "Plan in progress...", i18n.Sprintf(i18n.English, i18n.StatusInProgress, "Plan"))
+	Equals(t, "Plan 実行中...", i18n.Sprintf(i18n.Japanese, i18n.StatusInProgress, "Plan"))
+
+	t.Log("unsupported locales fall back to English")
+	Equals(t, "Plan in progress...", i18n.Sprintf("fr", i18n.StatusInProgress, "Plan"))
+}
+
+func TestLocalizer_For(t *testing.T) {
+	repo := models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Hostname: "github.com"}}
+	other := models.Repo{FullName: "owner/other", VCSHost: models.VCSHost{Hostname: "github.com"}}
+
+	var nilLocalizer *i18n.Localizer
+	Equals(t, i18n.English, nilLocalizer.For(repo, "main"))
+	Equals(t, i18n.English, (&i18n.Localizer{}).For(repo, "main"))
+	Equals(t, i18n.Japanese, (&i18n.Localizer{Locale: i18n.Japanese}).For(repo, "main"))
+
+	t.Log("repos can override the server's locale")
+	globalCfg := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})
+	globalCfg.Repos = append(globalCfg.Repos, valid.Repo{
+		ID:     "github.com/owner/repo",
+		Locale: i18n.English,
+	})
+	l := &i18n.Localizer{Locale: i18n.Japanese, GlobalCfg: valid.NewGlobalCfgStore(globalCfg)}
+	Equals(t, i18n.English, l.For(repo, "main"))
+	Equals(t, i18n.Japanese, l.For(other, "main"))
+	Equals(t, "**警告**: `main` が更新されたため、このプルリクエストのプランは古くなり**破棄**されました。\n\n`apply` するには、もう一度 `plan` を実行してください。",
+		l.Sprintf(other, "main", i18n.BaseBranchUpdated, "main"))
+}
@@ -14,8 +14,18 @@
 package server
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/vcs/bitbucketcloud"
+	"github.com/runatlantis/atlantis/server/events/vcs/bitbucketserver"
 	"github.com/runatlantis/atlantis/server/logging"
 	"github.com/urfave/negroni"
 )
@@ -36,3 +46,165 @@ func (l *RequestLogger) ServeHTTP(rw http.ResponseWriter, r *http.Request, next
 	next(rw, r)
 	l.logger.Debug("%s %s – respond HTTP %d", r.Method, r.URL.RequestURI(), rw.(negroni.ResponseWriter).Status())
 }
+
+// drainRetryAfter is how many seconds we tell clients to wait before retrying
+// a request that was rejected because we're shutting down.
+const drainRetryAfter = 30
+
+// drainRejectedPaths are the routes that start new operations. They're
+// rejected once we're shutting down since they would fail anyway. Webhooks to
+// /events are only rejected if they could start a command, see
+// drainAcceptedEvent.
+var drainRejectedPaths = map[string]bool{
+	"/events":    true,
+	"/api/plan":  true,
+	"/api/apply": true,
+}
+
+// DrainMiddleware rejects requests that would start new operations while
+// Atlantis is shutting down. Other routes, ex. /status and /healthz, keep
+// working until the server stops.
+type DrainMiddleware struct {
+	Drainer *events.Drainer
+}
+
+// ServeHTTP implements the middleware function. Rejected requests get a 503
+// with a Retry-After header so they're retried, ideally against another
+// instance.
+func (d *DrainMiddleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if r.Method == http.MethodPost && drainRejectedPaths[r.URL.Path] && d.Drainer.GetStatus().ShuttingDown {
+		if r.URL.Path != "/events" || !drainAcceptedEvent(r) {
+			rw.Header().Set("Retry-After", strconv.Itoa(drainRetryAfter))
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintln(rw, "Atlantis is shutting down, please try again later")
+			return
+		}
+	}
+	next(rw, r)
+}
+
+// drainAcceptedEvent returns true if the webhook in r is a push or a pull
+// request being closed. They're still handled while shutting down because
+// they don't start commands, and VCS hosts don't always redeliver failed
+// webhooks so the locks and workspaces of closed pulls would never be
+// cleaned up. The body is read to find the event's action where the headers
+// don't say and is then restored for the next handler.
+func drainAcceptedEvent(r *http.Request) bool {
+	if event := r.Header.Get("X-Github-Event"); event != "" {
+		if event == "push" {
+			return true
+		}
+		var payload struct {
+			Action string `json:"action"`
+		}
+		return event == "pull_request" && readEventPayload(r, &payload) && payload.Action == "closed"
+	}
+	if event := r.Header.Get("X-Gitlab-Event"); event != "" {
+		if event == "Push Hook" {
+			return true
+		}
+		var payload struct {
+			ObjectAttributes struct {
+				Action string `json:"action"`
+			} `json:"object_attributes"`
+		}
+		if event != "Merge Request Hook" || !readEventPayload(r, &payload) {
+			return false
+		}
+		return payload.ObjectAttributes.Action == "close" || payload.ObjectAttributes.Action == "merge"
+	}
+	if event := r.Header.Get("X-Event-Key"); event != "" {
+		switch event {
+		case bitbucketcloud.PullFulfilledHeader, bitbucketcloud.PullRejectedHeader, "repo:push",
+			bitbucketserver.PullMergedHeader, bitbucketserver.PullDeclinedHeader, bitbucketserver.PullDeletedHeader, "repo:refs_changed":
+			return true
+		}
+		return false
+	}
+	if r.Header.Get("Request-Id") != "" {
+		var payload struct {
+			EventType string `json:"eventType"`
+			Resource  struct {
+				Status string `json:"status"`
+			} `json:"resource"`
+		}
+		if !readEventPayload(r, &payload) {
+			return false
+		}
+		if payload.EventType == "git.push" {
+			return true
+		}
+		return payload.EventType == "git.pullrequest.updated" && (payload.Resource.Status == "completed" || payload.Resource.Status == "abandoned")
+	}
+	return false
+}
+
+// readEventPayload unmarshals the JSON body of r into v and restores the
+// body. It returns false if the body isn't JSON.
+func readEventPayload(r *http.Request, v interface{}) bool {
+	if r.Body == nil {
+		return false
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	r.Body.Close() // nolint: errcheck
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	// GitHub can send webhooks form encoded with the JSON in the payload
+	// field.
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return false
+		}
+		body = []byte(form.Get("payload"))
+	}
+	return json.Unmarshal(body, v) == nil
+}
+
+// BasePathMiddleware serves Atlantis under the path of --atlantis-url, ex.
+// when it's behind an ingress at /atlantis that doesn't rewrite paths. The
+// base path is stripped from requests so routes, and the middleware after this
+// one, match as if Atlantis were served at the root. Requests that don't start
+// with the base path are passed through unchanged so reverse proxies that
+// already strip it keep working.
+type BasePathMiddleware struct {
+	// BasePath is the cleaned path of --atlantis-url, ex. /atlantis. If it's
+	// empty requests are passed through unchanged.
+	BasePath string
+}
+
+// ServeHTTP implements the middleware function.
+func (b *BasePathMiddleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	path, ok := stripBasePath(b.BasePath, r.URL.Path)
+	if !ok {
+		next(rw, r)
+		return
+	}
+	r2 := r.Clone(r.Context())
+	r2.URL.Path = path
+	if r.URL.RawPath != "" {
+		if rawPath, ok := stripBasePath(b.BasePath, r.URL.RawPath); ok {
+			r2.URL.RawPath = rawPath
+		} else {
+			r2.URL.RawPath = ""
+		}
+	}
+	next(rw, r2)
+}
+
+// stripBasePath returns path without the base path and true if path is the
+// base path or is under it.
+func stripBasePath(basePath string, path string) (string, bool) {
+	if basePath == "" || basePath == "/" {
+		return path, false
+	}
+	if path == basePath {
+		return "/", true
+	}
+	if strings.HasPrefix(path, basePath+"/") {
+		return strings.TrimPrefix(path, basePath), true
+	}
+	return path, false
+}
@@ -0,0 +1,103 @@
+package server_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/events"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestDrainMiddleware(t *testing.T) {
+	drainer := &events.Drainer{}
+	middleware := &server.DrainMiddleware{Drainer: drainer}
+	serve := func(method string, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(nil))
+		w := httptest.NewRecorder()
+		middleware.ServeHTTP(w, req, func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+		return w
+	}
+
+	t.Log("requests are passed through while running")
+	Equals(t, http.StatusOK, serve("POST", "/events").Code)
+
+	drainer.ShutdownBlocking()
+
+	t.Log("requests that start operations are rejected while shutting down")
+	for _, path := range []string{"/events", "/api/plan", "/api/apply"} {
+		w := serve("POST", path)
+		ResponseContains(t, w, http.StatusServiceUnavailable, "Atlantis is shutting down")
+		Equals(t, "30", w.Header().Get("Retry-After"))
+	}
+
+	t.Log("webhooks that clean up or don't start commands are passed through while shutting down")
+	serveEvent := func(header string, event string, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/events", bytes.NewBufferString(body))
+		req.Header.Set(header, event)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		var gotBody []byte
+		middleware.ServeHTTP(w, req, func(w http.ResponseWriter, r *http.Request) {
+			gotBody, _ = ioutil.ReadAll(r.Body)
+			w.WriteHeader(http.StatusOK)
+		})
+		if w.Code == http.StatusOK {
+			Equals(t, body, string(gotBody))
+		}
+		return w
+	}
+	Equals(t, http.StatusOK, serveEvent("X-Github-Event", "pull_request", `{"action":"closed"}`).Code)
+	Equals(t, http.StatusOK, serveEvent("X-Github-Event", "push", `{}`).Code)
+	Equals(t, http.StatusOK, serveEvent("X-Gitlab-Event", "Merge Request Hook", `{"object_attributes":{"action":"merge"}}`).Code)
+	Equals(t, http.StatusOK, serveEvent("X-Event-Key", "pullrequest:fulfilled", `{}`).Code)
+	Equals(t, http.StatusOK, serveEvent("X-Event-Key", "pr:declined", `{}`).Code)
+	Equals(t, http.StatusOK, serveEvent("Request-Id", "1", `{"eventType":"git.pullrequest.updated","resource":{"status":"abandoned"}}`).Code)
+	Equals(t, http.StatusServiceUnavailable, serveEvent("X-Github-Event", "pull_request", `{"action":"synchronize"}`).Code)
+	Equals(t, http.StatusServiceUnavailable, serveEvent("X-Github-Event", "issue_comment", `{"action":"created"}`).Code)
+	Equals(t, http.StatusServiceUnavailable, serveEvent("X-Gitlab-Event", "Note Hook", `{}`).Code)
+	Equals(t, http.StatusServiceUnavailable, serveEvent("X-Event-Key", "pr:comment:added", `{}`).Code)
+	Equals(t, http.StatusServiceUnavailable, serveEvent("Request-Id", "1", `{"eventType":"git.pullrequest.updated","resource":{"status":"active"}}`).Code)
+
+	t.Log("other requests are passed through while shutting down")
+	Equals(t, http.StatusOK, serve("GET", "/status").Code)
+	Equals(t, http.StatusOK, serve("GET", "/healthz").Code)
+	Equals(t, http.StatusOK, serve("GET", "/api/history").Code)
+}
+
+func TestBasePathMiddleware(t *testing.T) {
+	cases := []struct {
+		basePath string
+		path     string
+		expPath  string
+	}{
+		{"", "/events", "/events"},
+		{"/atlantis", "/atlantis/events", "/events"},
+		{"/atlantis", "/atlantis", "/"},
+		{"/atlantis", "/atlantis/", "/"},
+		{"/atlantis", "/atlantis/static/css/custom.css", "/static/css/custom.css"},
+		{"/path/1", "/path/1/lock", "/lock"},
+		// Requests from reverse proxies that already strip the base path.
+		{"/atlantis", "/events", "/events"},
+		{"/atlantis", "/atlantis-other/events", "/atlantis-other/events"},
+	}
+	for _, c := range cases {
+		t.Run(c.basePath+" "+c.path, func(t *testing.T) {
+			middleware := &server.BasePathMiddleware{BasePath: c.basePath}
+			req, _ := http.NewRequest("GET", c.path+"?id=1", nil)
+			var gotPath, gotQuery string
+			middleware.ServeHTTP(httptest.NewRecorder(), req, func(_ http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.Path
+				gotQuery = r.URL.RawQuery
+			})
+			Equals(t, c.expPath, gotPath)
+			Equals(t, "id=1", gotQuery)
+			Equals(t, c.path, req.URL.Path)
+		})
+	}
+}
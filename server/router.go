@@ -1,6 +1,7 @@
 package server
 
 import (
+	"fmt"
 	"net/url"
 
 	"github.com/gorilla/mux"
@@ -33,3 +34,23 @@ func (r *Router) GenerateLockURL(lockID string) string {
 	// golang likes to double escape the lockURL path when using url.Parse().
 	return r.AtlantisURL.String() + lockURL.String()
 }
+
+// GenerateHistoryURL returns a fully qualified URL to view the command history
+// of pull request pullNum in repoFullName.
+func (r *Router) GenerateHistoryURL(repoFullName string, pullNum int) string {
+	query := url.Values{}
+	query.Set("repo", repoFullName)
+	query.Set("pr", fmt.Sprintf("%d", pullNum))
+	return r.AtlantisURL.String() + "/history?" + query.Encode()
+}
+
+// GenerateProjectHistoryURL returns a fully qualified URL to view the command
+// history of the project at repoRelDir and workspace in pull request pullNum.
+func (r *Router) GenerateProjectHistoryURL(repoFullName string, pullNum int, repoRelDir string, workspace string) string {
+	query := url.Values{}
+	query.Set("repo", repoFullName)
+	query.Set("pr", fmt.Sprintf("%d", pullNum))
+	query.Set("dir", repoRelDir)
+	query.Set("workspace", workspace)
+	return r.AtlantisURL.String() + "/history?" + query.Encode()
+}
@@ -60,3 +60,11 @@ func TestRouter_GenerateLockURL(t *testing.T) {
 		})
 	}
 }
+
+func TestRouter_GenerateHistoryURL(t *testing.T) {
+	atlantisURL, err := server.ParseAtlantisURL("https://example.com/basepath/")
+	Ok(t, err)
+	router := &server.Router{AtlantisURL: atlantisURL}
+	Equals(t, "https://example.com/basepath/history?pr=1&repo=runatlantis%2Fatlantis", router.GenerateHistoryURL("runatlantis/atlantis", 1))
+	Equals(t, "https://example.com/basepath/history?dir=modules%2Fvpc&pr=1&repo=runatlantis%2Fatlantis&workspace=default", router.GenerateProjectHistoryURL("runatlantis/atlantis", 1, "modules/vpc", "default"))
+}
@@ -17,9 +17,11 @@ package server
 
 import (
 	"context"
+	"crypto/rand"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
@@ -34,18 +36,25 @@ import (
 	"github.com/mitchellh/go-homedir"
 	"github.com/runatlantis/atlantis/server/core/db"
 	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/i18n"
 
 	assetfs "github.com/elazarl/go-bindata-assetfs"
 	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-version"
 	"github.com/pkg/errors"
 	"github.com/runatlantis/atlantis/server/controllers"
 	events_controllers "github.com/runatlantis/atlantis/server/controllers/events"
 	"github.com/runatlantis/atlantis/server/controllers/templates"
+	"github.com/runatlantis/atlantis/server/core/credentials"
 	"github.com/runatlantis/atlantis/server/core/locking"
+	"github.com/runatlantis/atlantis/server/core/planstorage"
+	"github.com/runatlantis/atlantis/server/core/redis"
 	"github.com/runatlantis/atlantis/server/core/runtime"
 	"github.com/runatlantis/atlantis/server/core/runtime/policy"
 	"github.com/runatlantis/atlantis/server/core/terraform"
+	"github.com/runatlantis/atlantis/server/core/tfe"
 	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/audit"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/vcs"
 	"github.com/runatlantis/atlantis/server/events/vcs/bitbucketcloud"
@@ -54,6 +63,7 @@ import (
 	"github.com/runatlantis/atlantis/server/events/yaml"
 	"github.com/runatlantis/atlantis/server/logging"
 	"github.com/runatlantis/atlantis/server/static"
+	"github.com/runatlantis/atlantis/server/tracing"
 	"github.com/urfave/cli"
 	"github.com/urfave/negroni"
 )
@@ -75,6 +85,23 @@ const (
 	// terraformPluginCacheDir is the name of the dir inside our data dir
 	// where we tell terraform to cache plugins and modules.
 	TerraformPluginCacheDirName = "plugin-cache"
+
+	// WarmDirName is the name of the dir inside our data dir where
+	// providers are installed from when warming.
+	WarmDirName = "warm"
+
+	// CloneCacheDirName is the name of the dir inside our data dir where bare
+	// clones of repos are kept when --enable-clone-cache is set.
+	CloneCacheDirName = "clone-cache"
+
+	// lockReaperInterval is how often locks are checked for expiry when
+	// --lock-ttl is set.
+	lockReaperInterval = 5 * time.Minute
+
+	// repoRegistryInterval is how often repos registered through the API
+	// are reloaded so that repos registered on other instances sharing the
+	// locking backend are allowlisted.
+	repoRegistryInterval = 30 * time.Second
 )
 
 // Server runs the Atlantis web server.
@@ -91,12 +118,33 @@ type Server struct {
 	VCSEventsController           *events_controllers.VCSEventsController
 	GithubAppController           *controllers.GithubAppController
 	LocksController               *controllers.LocksController
+	APIController                 *controllers.APIController
+	HistoryController             *controllers.HistoryController
+	PullsController               *controllers.PullsController
+	TerraformVersionsController   *controllers.TerraformVersionsController
 	StatusController              *controllers.StatusController
 	IndexTemplate                 templates.TemplateWriter
 	LockDetailTemplate            templates.TemplateWriter
 	SSLCertFile                   string
 	SSLKeyFile                    string
 	Drainer                       *events.Drainer
+	// CommandQueue is nil unless --enable-command-queue is set.
+	CommandQueue        *events.CommandQueue
+	CommandQueueWorkers int
+	// LockReaper is nil unless --lock-ttl is set.
+	LockReaper   *events.LockReaper
+	RepoRegistry *events.RepoRegistry
+	// WorkingDirGC is only run periodically if WorkingDirGCInterval is set.
+	WorkingDirGC         *events.WorkingDirGC
+	WorkingDirGCInterval time.Duration
+	// WebAuth is nil unless web UI authentication is configured.
+	WebAuth *WebAuth
+	// GlobalCfgReloader is nil unless --repo-config is set.
+	GlobalCfgReloader *events.GlobalCfgReloader
+	// ShutdownTracing exports the spans that haven't been exported yet. It's
+	// nil unless --tracing-otlp-endpoint is set.
+	ShutdownTracing func(context.Context) error
+	Warmer          *terraform.Warmer
 }
 
 // Config holds config for server that isn't passed in by the user.
@@ -105,6 +153,7 @@ type Config struct {
 	AtlantisURLFlag         string
 	AtlantisVersion         string
 	DefaultTFVersionFlag    string
+	DefaultTGVersionFlag    string
 	RepoConfigJSONFlag      string
 	SilenceForkPRErrorsFlag string
 }
@@ -117,11 +166,15 @@ type WebhookConfig struct {
 	// that is being modified for this event. If the regex matches, we'll
 	// send the webhook, ex. "production.*".
 	WorkspaceRegex string `mapstructure:"workspace-regex"`
-	// Kind is the type of webhook we should send, ex. slack.
+	// Kind is the type of webhook we should send, ex. slack or http.
 	Kind string `mapstructure:"kind"`
 	// Channel is the channel to send this webhook to. It only applies to
 	// slack webhooks. Should be without '#'.
 	Channel string `mapstructure:"channel"`
+	// URL is the URL to POST the event to. It only applies to http webhooks.
+	URL string `mapstructure:"url"`
+	// Secret is used to sign the payload of http webhooks. Optional.
+	Secret string `mapstructure:"secret"`
 }
 
 // NewServer returns a new server. If there are issues starting the server or
@@ -134,6 +187,24 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		return nil, err
 	}
 
+	var shutdownTracing func(context.Context) error
+	if userConfig.TracingOTLPEndpoint != "" {
+		headers, err := tracing.ParseHeaders(userConfig.TracingOTLPHeaders)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing --tracing-otlp-headers")
+		}
+		shutdownTracing, err = tracing.Setup(tracing.Config{
+			Endpoint:        userConfig.TracingOTLPEndpoint,
+			Insecure:        userConfig.TracingOTLPInsecure,
+			Headers:         headers,
+			AtlantisVersion: config.AtlantisVersion,
+		})
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("exporting traces to %s", userConfig.TracingOTLPEndpoint)
+	}
+
 	var supportedVCSHosts []models.VCSHostType
 	var githubClient *vcs.GithubClient
 	var githubAppEnabled bool
@@ -142,6 +213,32 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 	var bitbucketCloudClient *bitbucketcloud.Client
 	var bitbucketServerClient *bitbucketserver.Client
 	var azuredevopsClient *vcs.AzureDevopsClient
+	// apiRetrier is shared by all the VCS clients so that its stats cover
+	// every host.
+	var apiRetrier *vcs.APIRetrier
+	if userConfig.VCSAPIMaxRetries > 0 {
+		apiRetrier = vcs.NewAPIRetrier(userConfig.VCSAPIMaxRetries, logger)
+	}
+	var ghMergeableIgnore []string
+	for _, c := range strings.Split(userConfig.GithubMergeableIgnore, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			ghMergeableIgnore = append(ghMergeableIgnore, c)
+		}
+	}
+	var ghRequireContexts []string
+	for _, c := range strings.Split(userConfig.GithubRequireContexts, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			ghRequireContexts = append(ghRequireContexts, c)
+		}
+	}
+	// githubStatusCheckRequirers maps the hostnames of GitHub hosts to their
+	// clients so new repos can have their branches protected.
+	githubStatusCheckRequirers := make(map[string]events.GithubStatusCheckRequirer)
+	// webhookCreators and webhookSecrets map the hostnames of GitHub and
+	// GitLab hosts to what's needed to create webhooks on repos registered
+	// through the API.
+	webhookCreators := make(map[string]events.WebhookCreator)
+	webhookSecrets := make(map[string]string)
 
 	policyChecksEnabled := false
 	if userConfig.EnablePolicyChecksFlag {
@@ -159,7 +256,8 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		} else if userConfig.GithubAppID != 0 {
 			githubCredentials = &vcs.GithubAppCredentials{
 				AppID:    userConfig.GithubAppID,
-				KeyPath:  userConfig.GithubAppKey,
+				Key:      []byte(userConfig.GithubAppKey),
+				KeyPath:  userConfig.GithubAppKeyFile,
 				Hostname: userConfig.GithubHostname,
 				AppSlug:  userConfig.GithubAppSlug,
 			}
@@ -167,24 +265,32 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		}
 
 		var err error
-		githubClient, err = vcs.NewGithubClient(userConfig.GithubHostname, githubCredentials, logger)
+		githubClient, err = vcs.NewGithubClient(userConfig.GithubHostname, githubCredentials, logger, apiRetrier)
 		if err != nil {
 			return nil, err
 		}
+		githubClient.UploadLargeComments = userConfig.UploadLargeComments
+		githubClient.MergeableIgnoreContexts = ghMergeableIgnore
+		githubStatusCheckRequirers[strings.ToLower(userConfig.GithubHostname)] = githubClient
+		webhookCreators[strings.ToLower(userConfig.GithubHostname)] = githubClient
+		webhookSecrets[strings.ToLower(userConfig.GithubHostname)] = userConfig.GithubWebhookSecret
 	}
 	if userConfig.GitlabUser != "" {
 		supportedVCSHosts = append(supportedVCSHosts, models.Gitlab)
 		var err error
-		gitlabClient, err = vcs.NewGitlabClient(userConfig.GitlabHostname, userConfig.GitlabToken, logger)
+		gitlabClient, err = vcs.NewGitlabClient(userConfig.GitlabHostname, userConfig.GitlabToken, logger, apiRetrier)
 		if err != nil {
 			return nil, err
 		}
+		gitlabClient.UploadLargeComments = userConfig.UploadLargeComments
+		webhookCreators[strings.ToLower(userConfig.GitlabHostname)] = gitlabClient
+		webhookSecrets[strings.ToLower(userConfig.GitlabHostname)] = userConfig.GitlabWebhookSecret
 	}
 	if userConfig.BitbucketUser != "" {
 		if userConfig.BitbucketBaseURL == bitbucketcloud.BaseURL {
 			supportedVCSHosts = append(supportedVCSHosts, models.BitbucketCloud)
 			bitbucketCloudClient = bitbucketcloud.NewClient(
-				http.DefaultClient,
+				&http.Client{Transport: apiRetrier.Transport(nil)},
 				userConfig.BitbucketUser,
 				userConfig.BitbucketToken,
 				userConfig.AtlantisURL)
@@ -192,7 +298,7 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 			supportedVCSHosts = append(supportedVCSHosts, models.BitbucketServer)
 			var err error
 			bitbucketServerClient, err = bitbucketserver.NewClient(
-				http.DefaultClient,
+				&http.Client{Transport: apiRetrier.Transport(nil)},
 				userConfig.BitbucketUser,
 				userConfig.BitbucketToken,
 				userConfig.BitbucketBaseURL,
@@ -205,11 +311,55 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 	if userConfig.AzureDevopsUser != "" {
 		supportedVCSHosts = append(supportedVCSHosts, models.AzureDevops)
 		var err error
-		azuredevopsClient, err = vcs.NewAzureDevopsClient("dev.azure.com", userConfig.AzureDevopsUser, userConfig.AzureDevopsToken)
+		azuredevopsClient, err = vcs.NewAzureDevopsClient(userConfig.AzureDevopsHostname, userConfig.AzureDevopsUser, userConfig.AzureDevopsToken, apiRetrier)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Additional GitHub and GitLab hosts get their own clients, which are
+	// used instead of the ones above for repos on those hosts.
+	var vcsHosts []vcs.HostConfig
+	hostClients := make(map[string]vcs.Client)
+	hostGithubPullGetters := make(map[string]events.GithubPullGetter)
+	hostGitlabMergeRequestGetters := make(map[string]events.GitlabMergeRequestGetter)
+	hostWebhookSecrets := make(map[string][]byte)
+	if userConfig.VCSHostsConfig != "" {
+		var err error
+		vcsHosts, err = vcs.ReadHostsConfig(userConfig.VCSHostsConfig)
 		if err != nil {
 			return nil, err
 		}
 	}
+	for _, h := range vcsHosts {
+		hostname := strings.ToLower(h.Hostname)
+		switch h.VCSHostType() {
+		case models.Github:
+			client, err := vcs.NewGithubClient(h.Hostname, &vcs.GithubUserCredentials{User: h.User, Token: h.Token}, logger, apiRetrier)
+			if err != nil {
+				return nil, errors.Wrapf(err, "setting up client for VCS host %q", h.Name)
+			}
+			client.UploadLargeComments = userConfig.UploadLargeComments
+			client.MergeableIgnoreContexts = ghMergeableIgnore
+			githubStatusCheckRequirers[hostname] = client
+			webhookCreators[hostname] = client
+			hostClients[hostname] = client
+			hostGithubPullGetters[hostname] = client
+		case models.Gitlab:
+			client, err := vcs.NewGitlabClient(h.Hostname, h.Token, logger, apiRetrier)
+			if err != nil {
+				return nil, errors.Wrapf(err, "setting up client for VCS host %q", h.Name)
+			}
+			client.UploadLargeComments = userConfig.UploadLargeComments
+			webhookCreators[hostname] = client
+			hostClients[hostname] = client
+			hostGitlabMergeRequestGetters[hostname] = client
+		}
+		hostWebhookSecrets[hostname] = []byte(h.WebhookSecret)
+		webhookSecrets[hostname] = h.WebhookSecret
+		supportedVCSHosts = append(supportedVCSHosts, h.VCSHostType())
+		logger.Info("serving additional %s host %q at %s", h.Type, h.Name, h.Hostname)
+	}
 
 	if userConfig.WriteGitCreds {
 		home, err := homedir.Dir()
@@ -238,7 +388,12 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 			}
 		}
 		if userConfig.AzureDevopsUser != "" {
-			if err := events.WriteGitCreds(userConfig.AzureDevopsUser, userConfig.AzureDevopsToken, "dev.azure.com", home, logger, false); err != nil {
+			if err := events.WriteGitCreds(userConfig.AzureDevopsUser, userConfig.AzureDevopsToken, azureDevopsGitHostname(userConfig.AzureDevopsHostname), home, logger, false); err != nil {
+				return nil, err
+			}
+		}
+		for _, h := range vcsHosts {
+			if err := events.WriteGitCreds(h.User, h.Token, h.Hostname, home, logger, false); err != nil {
 				return nil, err
 			}
 		}
@@ -251,6 +406,8 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 			Event:          c.Event,
 			Kind:           c.Kind,
 			WorkspaceRegex: c.WorkspaceRegex,
+			URL:            c.URL,
+			Secret:         c.Secret,
 		}
 		webhooksConfig = append(webhooksConfig, config)
 	}
@@ -259,6 +416,30 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		return nil, errors.Wrap(err, "initializing webhooks")
 	}
 	vcsClient := vcs.NewClientProxy(githubClient, gitlabClient, bitbucketCloudClient, bitbucketServerClient, azuredevopsClient)
+	// vcsPingers are keyed by hostname and checked by /readyz.
+	vcsPingers := make(map[string]vcs.Pinger)
+	if githubClient != nil {
+		vcsPingers[userConfig.GithubHostname] = githubClient
+	}
+	if gitlabClient != nil {
+		vcsPingers[userConfig.GitlabHostname] = gitlabClient
+	}
+	if bitbucketCloudClient != nil {
+		vcsPingers["bitbucket.org"] = bitbucketCloudClient
+	}
+	if bitbucketServerClient != nil {
+		if u, err := url.Parse(bitbucketServerClient.BaseURL); err == nil {
+			vcsPingers[u.Host] = bitbucketServerClient
+		}
+	}
+	for hostname, client := range hostClients {
+		if pinger, ok := client.(vcs.Pinger); ok {
+			vcsPingers[hostname] = pinger
+		}
+	}
+	for hostname, client := range hostClients {
+		vcsClient.AddHost(hostname, client)
+	}
 	commitStatusUpdater := &events.DefaultCommitStatusUpdater{Client: vcsClient, StatusName: userConfig.VCSStatusName}
 
 	binDir, err := mkSubDir(userConfig.DataDir, BinDirName)
@@ -273,23 +454,72 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		return nil, err
 	}
 
+	var commandTimeout time.Duration
+	if userConfig.CommandTimeout != "" {
+		commandTimeout, err = time.ParseDuration(userConfig.CommandTimeout)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing command timeout")
+		}
+	}
+	var stepRetryBackoff time.Duration
+	if userConfig.TFRetryBackoff != "" {
+		stepRetryBackoff, err = time.ParseDuration(userConfig.TFRetryBackoff)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing retry backoff")
+		}
+	}
+	registryCredentials, err := terraform.ParseRegistryCredentials(userConfig.TFRegistryCredentials)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing registry credentials")
+	}
+	netrcCredentials, err := terraform.ParseNetrcCredentials(userConfig.TFNetrcCredentials)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing netrc credentials")
+	}
 	terraformClient, err := terraform.NewClient(
 		logger,
 		binDir,
 		cacheDir,
 		userConfig.TFEToken,
 		userConfig.TFEHostname,
+		registryCredentials,
+		netrcCredentials,
 		userConfig.DefaultTFVersion,
 		config.DefaultTFVersionFlag,
+		userConfig.DefaultTFDistribution,
 		userConfig.TFDownloadURL,
+		userConfig.TofuDownloadURL,
+		userConfig.DefaultTofuVersion,
 		&terraform.DefaultDownloader{},
-		true)
+		true,
+		commandTimeout)
 	// The flag.Lookup call is to detect if we're running in a unit test. If we
 	// are, then we don't error out because we don't have/want terraform
 	// installed on our CI system where the unit tests run.
 	if err != nil && flag.Lookup("test.v") == nil {
 		return nil, errors.Wrap(err, "initializing terraform")
 	}
+	var warmVersions []*version.Version
+	if userConfig.WarmTFVersions != "" {
+		for _, v := range strings.Split(userConfig.WarmTFVersions, ",") {
+			warmVersion, err := version.NewVersion(strings.TrimSpace(v))
+			if err != nil {
+				return nil, errors.Wrap(err, "parsing --warm-tf-versions")
+			}
+			warmVersions = append(warmVersions, warmVersion)
+		}
+	}
+	warmProviders, err := terraform.ParseProviders(userConfig.WarmProviders)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing --warm-providers")
+	}
+	warmer := &terraform.Warmer{
+		Client:    terraformClient,
+		Versions:  warmVersions,
+		Providers: warmProviders,
+		Dir:       filepath.Join(userConfig.DataDir, WarmDirName),
+		Logger:    logger,
+	}
 	markdownRenderer := &events.MarkdownRenderer{
 		GitlabSupportsCommonMark: gitlabClient.SupportsCommonMark(),
 		DisableApplyAll:          userConfig.DisableApplyAll,
@@ -298,23 +528,137 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		DisableRepoLocking:       userConfig.DisableRepoLocking,
 	}
 
-	boltdb, err := db.New(userConfig.DataDir)
-	if err != nil {
-		return nil, err
+	var backend locking.Backend
+	var deliveryClaimer events_controllers.DeliveryClaimer
+	switch userConfig.LockingDBType {
+	case "redis":
+		logger.Info("Utilizing Redis DB")
+		var redisDB *redis.RedisDB
+		redisDB, err = redis.New(userConfig.RedisHost, userConfig.RedisPort, userConfig.RedisPassword, userConfig.RedisTLSEnabled, userConfig.RedisInsecureSkipVerify, userConfig.RedisDB)
+		if err != nil {
+			return nil, err
+		}
+		backend = redisDB
+		// Deliveries are claimed in Redis so in HA mode each is only handled
+		// by one instance.
+		deliveryClaimer = redisDB
+	default:
+		logger.Info("Utilizing BoltDB")
+		var boltDB *db.BoltDB
+		boltDB, err = db.New(userConfig.DataDir)
+		if err != nil {
+			return nil, err
+		}
+		backend = boltDB
+		deliveryClaimer = boltDB
+	}
+
+	var planStorage planstorage.PlanStorage
+	switch userConfig.PlanStorage {
+	case "s3":
+		logger.Info("Storing plans in S3 bucket %s", userConfig.PlanStorageBucket)
+		planStorage, err = planstorage.NewS3PlanStorage(userConfig.PlanStorageBucket, userConfig.PlanStoragePrefix)
+		if err != nil {
+			return nil, err
+		}
+	case "gcs":
+		logger.Info("Storing plans in GCS bucket %s", userConfig.PlanStorageBucket)
+		planStorage, err = planstorage.NewGCSPlanStorage(userConfig.PlanStorageBucket, userConfig.PlanStoragePrefix)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		planStorage = &planstorage.LocalPlanStorage{}
+	}
+	// If plans are stored remotely they can outlive the working dir so applies
+	// need to be able to clone it again.
+	_, localPlanStorage := planStorage.(*planstorage.LocalPlanStorage)
+	restoreWorkingDirOnApply := !localPlanStorage
+	var auditor *audit.MultiExporter
+	if userConfig.EnableAuditLog || userConfig.AuditWebhookURL != "" {
+		auditor = &audit.MultiExporter{}
+		if userConfig.EnableAuditLog {
+			auditor.Exporters = append(auditor.Exporters, &audit.DBExporter{DB: backend})
+		}
+		if userConfig.AuditWebhookURL != "" {
+			auditor.Exporters = append(auditor.Exporters, audit.NewWebhookExporter(userConfig.AuditWebhookURL))
+		}
 	}
 	var lockingClient locking.Locker
 	var applyLockingClient locking.ApplyLocker
+	var lockQueue *events.DefaultLockQueue
 	if userConfig.DisableRepoLocking {
 		lockingClient = locking.NewNoOpLocker()
 	} else {
-		lockingClient = locking.NewClient(boltdb)
+		lockingClient = locking.NewClient(backend)
+		if userConfig.EnableLockQueue {
+			lockQueue = events.NewDefaultLockQueue()
+			lockingClient = &events.LockQueueLocker{
+				Locker: lockingClient,
+				Queue:  lockQueue,
+			}
+		}
 	}
-	applyLockingClient = locking.NewApplyClient(boltdb, userConfig.DisableApply)
+	applyLockingClient = locking.NewApplyClient(backend, userConfig.DisableApply)
 	workingDirLocker := events.NewDefaultWorkingDirLocker()
+	if userConfig.WorkingDirLockTimeout != "" {
+		workingDirLocker.Timeout, err = time.ParseDuration(userConfig.WorkingDirLockTimeout)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing working dir lock timeout")
+		}
+	}
+
+	validator := &yaml.ParserValidator{}
+
+	globalCfgArgs := valid.GlobalCfgArgs{
+		AllowRepoCfg:       userConfig.AllowRepoConfig,
+		MergeableReq:       userConfig.RequireMergeable,
+		ApprovedReq:        userConfig.RequireApproval,
+		UnDivergedReq:      userConfig.RequireUnDiverged,
+		PolicyCheckEnabled: userConfig.EnablePolicyChecksFlag,
+	}
+	globalCfg := valid.NewGlobalCfgFromArgs(globalCfgArgs)
+	if userConfig.RepoConfig != "" {
+		globalCfg, err = validator.ParseGlobalCfg(userConfig.RepoConfig, globalCfg)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing %s file", userConfig.RepoConfig)
+		}
+	} else if userConfig.RepoConfigJSON != "" {
+		globalCfg, err = validator.ParseGlobalCfgJSON(userConfig.RepoConfigJSON, globalCfg)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing --%s", config.RepoConfigJSONFlag)
+		}
+	}
+	globalCfgStore := valid.NewGlobalCfgStore(globalCfg)
+	localizer := &i18n.Localizer{
+		Locale:    userConfig.Locale,
+		GlobalCfg: globalCfgStore,
+	}
+	commitStatusUpdater.Localizer = localizer
+	var globalCfgReloader *events.GlobalCfgReloader
+	if userConfig.RepoConfig != "" {
+		globalCfgReloader = &events.GlobalCfgReloader{
+			Path:            userConfig.RepoConfig,
+			Args:            globalCfgArgs,
+			ParserValidator: validator,
+			Store:           globalCfgStore,
+			Logger:          logger,
+		}
+	}
 
+	var cloneCacheDir string
+	if userConfig.EnableCloneCache {
+		cloneCacheDir, err = mkSubDir(userConfig.DataDir, CloneCacheDirName)
+		if err != nil {
+			return nil, err
+		}
+	}
 	var workingDir events.WorkingDir = &events.FileWorkspace{
 		DataDir:       userConfig.DataDir,
 		CheckoutMerge: userConfig.CheckoutStrategy == "merge",
+		CheckoutDepth: userConfig.CheckoutDepth,
+		CloneCacheDir: cloneCacheDir,
+		GlobalCfg:     globalCfgStore,
 	}
 	// provide fresh tokens before clone from the GitHub Apps integration, proxy workingDir
 	if githubAppEnabled {
@@ -331,13 +675,30 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 	projectLocker := &events.DefaultProjectLocker{
 		Locker:    lockingClient,
 		VCSClient: vcsClient,
+		Localizer: localizer,
 	}
 	deleteLockCommand := &events.DefaultDeleteLockCommand{
 		Locker:           lockingClient,
 		Logger:           logger,
 		WorkingDir:       workingDir,
 		WorkingDirLocker: workingDirLocker,
-		DB:               boltdb,
+		DB:               backend,
+		PlanStorage:      planStorage,
+	}
+	var lockReaper *events.LockReaper
+	if userConfig.LockTTL != "" {
+		lockTTL, err := time.ParseDuration(userConfig.LockTTL)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing lock ttl")
+		}
+		lockReaper = &events.LockReaper{
+			MaxAge:            lockTTL,
+			Locker:            lockingClient,
+			DeleteLockCommand: deleteLockCommand,
+			VCSClient:         vcsClient,
+			Localizer:         localizer,
+			Logger:            logger,
+		}
 	}
 
 	parsedURL, err := ParseAtlantisURL(userConfig.AtlantisURL)
@@ -345,28 +706,6 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		return nil, errors.Wrapf(err,
 			"parsing --%s flag %q", config.AtlantisURLFlag, userConfig.AtlantisURL)
 	}
-	validator := &yaml.ParserValidator{}
-
-	globalCfg := valid.NewGlobalCfgFromArgs(
-		valid.GlobalCfgArgs{
-			AllowRepoCfg:       userConfig.AllowRepoConfig,
-			MergeableReq:       userConfig.RequireMergeable,
-			ApprovedReq:        userConfig.RequireApproval,
-			UnDivergedReq:      userConfig.RequireUnDiverged,
-			PolicyCheckEnabled: userConfig.EnablePolicyChecksFlag,
-		})
-	if userConfig.RepoConfig != "" {
-		globalCfg, err = validator.ParseGlobalCfg(userConfig.RepoConfig, globalCfg)
-		if err != nil {
-			return nil, errors.Wrapf(err, "parsing %s file", userConfig.RepoConfig)
-		}
-	} else if userConfig.RepoConfigJSON != "" {
-		globalCfg, err = validator.ParseGlobalCfgJSON(userConfig.RepoConfigJSON, globalCfg)
-		if err != nil {
-			return nil, errors.Wrapf(err, "parsing --%s", config.RepoConfigJSONFlag)
-		}
-	}
-
 	underlyingRouter := mux.NewRouter()
 	router := &Router{
 		AtlantisURL:               parsedURL,
@@ -374,24 +713,29 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		LockViewRouteName:         LockViewRouteName,
 		Underlying:                underlyingRouter,
 	}
+	commitStatusUpdater.HistoryURLGenerator = router
 	pullClosedExecutor := &events.PullClosedExecutor{
-		VCSClient:  vcsClient,
-		Locker:     lockingClient,
-		WorkingDir: workingDir,
-		Logger:     logger,
-		DB:         boltdb,
+		VCSClient:        vcsClient,
+		Locker:           lockingClient,
+		WorkingDir:       workingDir,
+		Logger:           logger,
+		DB:               backend,
+		PlanStorage:      planStorage,
+		WorkingDirLocker: workingDirLocker,
 	}
 	eventParser := &events.EventParser{
-		GithubUser:         userConfig.GithubUser,
-		GithubToken:        userConfig.GithubToken,
-		GitlabUser:         userConfig.GitlabUser,
-		GitlabToken:        userConfig.GitlabToken,
-		AllowDraftPRs:      userConfig.PlanDrafts,
-		BitbucketUser:      userConfig.BitbucketUser,
-		BitbucketToken:     userConfig.BitbucketToken,
-		BitbucketServerURL: userConfig.BitbucketBaseURL,
-		AzureDevopsUser:    userConfig.AzureDevopsUser,
-		AzureDevopsToken:   userConfig.AzureDevopsToken,
+		GithubUser:          userConfig.GithubUser,
+		GithubToken:         userConfig.GithubToken,
+		GitlabUser:          userConfig.GitlabUser,
+		GitlabToken:         userConfig.GitlabToken,
+		AllowDraftPRs:       userConfig.PlanDrafts,
+		BitbucketUser:       userConfig.BitbucketUser,
+		BitbucketToken:      userConfig.BitbucketToken,
+		BitbucketServerURL:  userConfig.BitbucketBaseURL,
+		AzureDevopsUser:     userConfig.AzureDevopsUser,
+		AzureDevopsToken:    userConfig.AzureDevopsToken,
+		AzureDevopsHostname: userConfig.AzureDevopsHostname,
+		Hosts:               vcsHosts,
 	}
 	commentParser := &events.CommentParser{
 		GithubUser:      userConfig.GithubUser,
@@ -399,22 +743,69 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		BitbucketUser:   userConfig.BitbucketUser,
 		AzureDevopsUser: userConfig.AzureDevopsUser,
 		ApplyDisabled:   userConfig.DisableApply,
+		GlobalCfg:       globalCfgStore,
+		Locale:          userConfig.Locale,
 	}
 	defaultTfVersion := terraformClient.DefaultVersion()
+	defaultTfDistribution := terraformClient.DefaultDistribution()
 	pendingPlanFinder := &events.DefaultPendingPlanFinder{}
 	runStepRunner := &runtime.RunStepRunner{
-		TerraformExecutor: terraformClient,
-		DefaultTFVersion:  defaultTfVersion,
-		TerraformBinDir:   terraformClient.TerraformBinDir(),
+		TerraformExecutor:     terraformClient,
+		DefaultTFVersion:      defaultTfVersion,
+		DefaultTFDistribution: defaultTfDistribution,
+		TerraformBinDir:       terraformClient.TerraformBinDir(),
+		Secrets:               globalCfg.Secrets,
+	}
+	// The working dir garbage collector is only created if
+	// --workspace-gc-interval is set or if it's needed to free up space when
+	// --data-dir-max-size-mb is exceeded.
+	var workingDirGC *events.WorkingDirGC
+	var workingDirGCInterval time.Duration
+	if userConfig.WorkspaceGCInterval != "" || userConfig.DataDirMaxSizeMB > 0 {
+		if userConfig.WorkspaceGCInterval != "" {
+			workingDirGCInterval, err = time.ParseDuration(userConfig.WorkspaceGCInterval)
+			if err != nil {
+				return nil, errors.Wrap(err, "parsing workspace gc interval")
+			}
+		}
+		var maxAge time.Duration
+		if userConfig.WorkspaceGCMaxAge != "" {
+			maxAge, err = time.ParseDuration(userConfig.WorkspaceGCMaxAge)
+			if err != nil {
+				return nil, errors.Wrap(err, "parsing workspace gc max age")
+			}
+		}
+		workingDirGC = &events.WorkingDirGC{
+			DataDir:          userConfig.DataDir,
+			MaxAge:           maxAge,
+			VCSClient:        vcsClient,
+			PullCleaner:      pullClosedExecutor,
+			WorkingDirLocker: workingDirLocker,
+			DB:               backend,
+			Logger:           logger,
+		}
+	}
+	var diskUsageLimiter *events.DiskUsageLimiter
+	if userConfig.DataDirMaxSizeMB > 0 {
+		diskUsageLimiter = &events.DiskUsageLimiter{
+			DataDir:  userConfig.DataDir,
+			MaxBytes: int64(userConfig.DataDirMaxSizeMB) * 1024 * 1024,
+			GC:       workingDirGC,
+		}
 	}
 	drainer := &events.Drainer{}
+	stateLockTracker := &events.StateLockTracker{}
 	statusController := &controllers.StatusController{
-		Logger:  logger,
-		Drainer: drainer,
+		Logger:           logger,
+		Drainer:          drainer,
+		WorkingDirGC:     workingDirGC,
+		APIRetrier:       apiRetrier,
+		StateLocks:       stateLockTracker,
+		DependencyChecks: dependencyChecks(userConfig, backend, diskUsageLimiter, terraformClient, vcsPingers, logger),
 	}
 	preWorkflowHooksCommandRunner := &events.DefaultPreWorkflowHooksCommandRunner{
 		VCSClient:             vcsClient,
-		GlobalCfg:             globalCfg,
+		GlobalCfg:             globalCfgStore,
 		WorkingDirLocker:      workingDirLocker,
 		WorkingDir:            workingDir,
 		PreWorkflowHookRunner: runtime.DefaultPreWorkflowHookRunner{},
@@ -422,16 +813,18 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 	projectCommandBuilder := events.NewProjectCommandBuilder(
 		policyChecksEnabled,
 		validator,
-		&events.DefaultProjectFinder{},
+		&events.DefaultProjectFinder{AutoplanModules: userConfig.AutoplanModules},
 		vcsClient,
 		workingDir,
 		workingDirLocker,
-		globalCfg,
+		globalCfgStore,
 		pendingPlanFinder,
 		commentParser,
 		userConfig.SkipCloneNoChanges,
 		userConfig.EnableRegExpCmd,
 		userConfig.AutoplanFileList,
+		terraformClient,
+		restoreWorkingDirOnApply,
 	)
 
 	showStepRunner, err := runtime.NewShowStepRunner(terraformClient, defaultTfVersion)
@@ -449,53 +842,173 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		return nil, errors.Wrap(err, "initializing policy check runner")
 	}
 
+	var defaultTgVersion *version.Version
+	if userConfig.DefaultTGVersion != "" {
+		defaultTgVersion, err = version.NewVersion(userConfig.DefaultTGVersion)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing --%s", config.DefaultTGVersionFlag)
+		}
+	}
+	planStepRunner := &runtime.PlanStepRunner{
+		TerraformExecutor:    terraformClient,
+		DefaultTFVersion:     defaultTfVersion,
+		CommitStatusUpdater:  commitStatusUpdater,
+		AsyncTFExec:          terraformClient,
+		PlanStorage:          planStorage,
+		StructuredPlanOutput: userConfig.EnableStructuredPlanOutput,
+	}
+	applyStepRunner := &runtime.ApplyStepRunner{
+		TerraformExecutor:   terraformClient,
+		CommitStatusUpdater: commitStatusUpdater,
+		AsyncTFExec:         terraformClient,
+		PlanStorage:         planStorage,
+	}
+
+	// Projects that set tfe_workspace are planned and applied as remote runs
+	// using the API so the client is only created if there's a token.
+	var tfeClient tfe.Client
+	if userConfig.TFEToken != "" {
+		tfeClient = tfe.NewClient(userConfig.TFEHostname, userConfig.TFEToken)
+	}
+
+	// Credentials are only obtained for projects that configure them so the
+	// provider can always be created.
+	credentialsProvider, err := credentials.NewProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	applyQueue := events.NewDefaultApplyQueue()
 	projectCommandRunner := &events.DefaultProjectCommandRunner{
-		Locker:           projectLocker,
-		LockURLGenerator: router,
-		InitStepRunner: &runtime.InitStepRunner{
-			TerraformExecutor: terraformClient,
-			DefaultTFVersion:  defaultTfVersion,
-		},
-		PlanStepRunner: &runtime.PlanStepRunner{
-			TerraformExecutor:   terraformClient,
-			DefaultTFVersion:    defaultTfVersion,
-			CommitStatusUpdater: commitStatusUpdater,
-			AsyncTFExec:         terraformClient,
-		},
+		Locker:              projectLocker,
+		LockURLGenerator:    router,
+		HistoryURLGenerator: router,
+		InitStepRunner: runtime.NewRemoteRunStepRunnerDelegate(
+			&runtime.InitStepRunner{
+				TerraformExecutor: terraformClient,
+				DefaultTFVersion:  defaultTfVersion,
+			},
+			runtime.NullRunner{},
+		),
+		PlanStepRunner: runtime.NewRemoteRunStepRunnerDelegate(
+			planStepRunner,
+			&runtime.RemotePlanStepRunner{
+				TFEClient:           tfeClient,
+				CommitStatusUpdater: commitStatusUpdater,
+				Timeout:             commandTimeout,
+			},
+		),
 		ShowStepRunner:        showStepRunner,
 		PolicyCheckStepRunner: policyCheckRunner,
-		ApplyStepRunner: &runtime.ApplyStepRunner{
-			TerraformExecutor:   terraformClient,
-			CommitStatusUpdater: commitStatusUpdater,
-			AsyncTFExec:         terraformClient,
-		},
+		ApplyStepRunner: runtime.NewRemoteRunStepRunnerDelegate(
+			applyStepRunner,
+			&runtime.RemoteApplyStepRunner{
+				TFEClient:           tfeClient,
+				CommitStatusUpdater: commitStatusUpdater,
+				Timeout:             commandTimeout,
+			},
+		),
 		RunStepRunner: runStepRunner,
 		EnvStepRunner: &runtime.EnvStepRunner{
 			RunStepRunner: runStepRunner,
 		},
+		MultiEnvStepRunner: &runtime.MultiEnvStepRunner{
+			RunStepRunner: runStepRunner,
+		},
+		ScanStepRunner: runtime.NewScanStepRunner(binDir, &terraform.DefaultDownloader{}),
 		VersionStepRunner: &runtime.VersionStepRunner{
 			TerraformExecutor: terraformClient,
 			DefaultTFVersion:  defaultTfVersion,
 		},
-		PullApprovedChecker: vcsClient,
-		WorkingDir:          workingDir,
-		Webhooks:            webhooksManager,
-		WorkingDirLocker:    workingDirLocker,
+		ImportStepRunner: &runtime.ImportStepRunner{
+			TerraformExecutor: terraformClient,
+			DefaultTFVersion:  defaultTfVersion,
+		},
+		StateStepRunner: &runtime.StateStepRunner{
+			TerraformExecutor: terraformClient,
+			DefaultTFVersion:  defaultTfVersion,
+		},
+		ValidateStepRunner: &runtime.ValidateStepRunner{
+			TerraformExecutor: terraformClient,
+			DefaultTFVersion:  defaultTfVersion,
+		},
+		FmtStepRunner: &runtime.FmtStepRunner{
+			TerraformExecutor: terraformClient,
+			DefaultTFVersion:  defaultTfVersion,
+		},
+		OutputStepRunner: &runtime.OutputStepRunner{
+			TerraformExecutor: terraformClient,
+			DefaultTFVersion:  defaultTfVersion,
+		},
+		TerragruntStepRunner: runtime.NewTerragruntStepRunner(
+			terraformClient,
+			defaultTfVersion,
+			defaultTfDistribution,
+			terraformClient.TerraformBinDir(),
+			defaultTgVersion,
+			binDir,
+			&terraform.DefaultDownloader{},
+			planStepRunner,
+			applyStepRunner,
+		),
+		PullApprovedChecker:      vcsClient,
+		CodeownersChecker:        &events.DefaultCodeownersChecker{VCSClient: vcsClient},
+		WorkingDir:               workingDir,
+		Webhooks:                 webhooksManager,
+		WorkingDirLocker:         workingDirLocker,
+		ApplyQueue:               applyQueue,
+		RestoreWorkingDirOnApply: restoreWorkingDirOnApply,
+		PlanStorage:              planStorage,
+		StructuredPlanOutput:     userConfig.EnableStructuredPlanOutput,
+		CredentialsProvider:      credentialsProvider,
+		StateLocks:               stateLockTracker,
+		MaxStepRetries:           userConfig.TFMaxRetries,
+		StepRetryBackoff:         stepRetryBackoff,
+		Localizer:                localizer,
+	}
+	if userConfig.EnableProjectStatuses {
+		projectCommandRunner.CommitStatusUpdater = commitStatusUpdater
+	}
+	if userConfig.EnableGHDeployments {
+		if githubClient != nil {
+			projectCommandRunner.Deployments = &events.DeploymentsUpdater{
+				Client: githubClient,
+			}
+		} else {
+			logger.Warn("GitHub deployments require GitHub credentials, not creating deployments")
+		}
 	}
 
 	dbUpdater := &events.DBUpdater{
-		DB: boltdb,
+		DB: backend,
 	}
 
 	pullUpdater := &events.PullUpdater{
 		HidePrevPlanComments: userConfig.HidePrevPlanComments,
 		VCSClient:            vcsClient,
 		MarkdownRenderer:     markdownRenderer,
+		SilenceNoChangePlans: userConfig.SilenceNoChangePlans,
+		GlobalCfg:            globalCfgStore,
+		HistoryURLGenerator:  router,
+		Localizer:            localizer,
+	}
+	if userConfig.EnableGHChecks {
+		// The checks API is only available to GitHub Apps so we fall back to
+		// just commit statuses otherwise.
+		if githubAppEnabled {
+			pullUpdater.ChecksUpdater = &events.ChecksUpdater{
+				Client:     githubClient,
+				StatusName: userConfig.VCSStatusName,
+			}
+		} else {
+			logger.Warn("GitHub checks require GitHub App credentials, falling back to commit statuses")
+		}
 	}
 
 	autoMerger := &events.AutoMerger{
 		VCSClient:       vcsClient,
 		GlobalAutomerge: userConfig.Automerge,
+		Localizer:       localizer,
 	}
 
 	policyCheckCommandRunner := events.NewPolicyCheckCommandRunner(
@@ -522,7 +1035,7 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		autoMerger,
 		userConfig.ParallelPoolSize,
 		userConfig.SilenceNoProjects,
-		boltdb,
+		backend,
 	)
 
 	applyCommandRunner := events.NewApplyCommandRunner(
@@ -535,11 +1048,19 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		autoMerger,
 		pullUpdater,
 		dbUpdater,
-		boltdb,
+		backend,
 		userConfig.ParallelPoolSize,
 		userConfig.SilenceNoProjects,
 		userConfig.SilenceVCSStatusNoProjects,
 	)
+	applyCommandRunner.GlobalCfg = globalCfgStore
+	applyCommandRunner.Localizer = localizer
+	if userConfig.ApplyConfirmationWindow != "" {
+		applyCommandRunner.ConfirmationWindow, err = time.ParseDuration(userConfig.ApplyConfirmationWindow)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing apply confirmation window")
+		}
+	}
 
 	approvePoliciesCommandRunner := events.NewApprovePoliciesCommandRunner(
 		commitStatusUpdater,
@@ -547,6 +1068,7 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		projectCommandRunner,
 		pullUpdater,
 		dbUpdater,
+		vcsClient,
 		userConfig.SilenceNoProjects,
 		userConfig.SilenceVCSStatusNoPlans,
 	)
@@ -565,18 +1087,85 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		userConfig.SilenceNoProjects,
 	)
 
+	importCommandRunner := events.NewImportCommandRunner(
+		vcsClient,
+		applyLockingClient,
+		pullUpdater,
+		projectCommandBuilder,
+		projectCommandRunner,
+		backend,
+	)
+
+	stateCommandRunner := events.NewStateCommandRunner(
+		vcsClient,
+		applyLockingClient,
+		pullUpdater,
+		projectCommandBuilder,
+		projectCommandRunner,
+		backend,
+		userConfig.EnableStateCmd,
+	)
+
+	validateCommandRunner := events.NewValidateCommandRunner(
+		pullUpdater,
+		projectCommandBuilder,
+		projectCommandRunner,
+		userConfig.ParallelPoolSize,
+	)
+
+	customCommandRunner := events.NewCustomCommandRunner(
+		vcsClient,
+		pullUpdater,
+		projectCommandBuilder,
+		projectCommandRunner,
+		userConfig.ParallelPoolSize,
+	)
+
+	commandCanceller := events.NewCommandCanceller()
+	cancelCommandRunner := events.NewCancelCommandRunner(commandCanceller, vcsClient)
+
 	commentCommandRunnerByCmd := map[models.CommandName]events.CommentCommandRunner{
 		models.PlanCommand:            planCommandRunner,
 		models.ApplyCommand:           applyCommandRunner,
 		models.ApprovePoliciesCommand: approvePoliciesCommandRunner,
 		models.UnlockCommand:          unlockCommandRunner,
 		models.VersionCommand:         versionCommandRunner,
+		models.ImportCommand:          importCommandRunner,
+		models.StateCommand:           stateCommandRunner,
+		models.ValidateCommand:        validateCommandRunner,
+		models.CancelCommand:          cancelCommandRunner,
+		models.CustomCommand:          customCommandRunner,
 	}
 
+	repoAllowlist, err := events.NewRepoAllowlistChecker(userConfig.RepoAllowlist)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range vcsHosts {
+		if h.RepoAllowlist == "" {
+			continue
+		}
+		if err := repoAllowlist.SetHostAllowlist(h.Hostname, h.RepoAllowlist); err != nil {
+			return nil, errors.Wrapf(err, "parsing repo_allowlist of VCS host %q", h.Name)
+		}
+	}
+	repoRegistry := &events.RepoRegistry{
+		DB:               backend,
+		AllowlistChecker: repoAllowlist,
+		WebhookCreators:  webhookCreators,
+		WebhookSecrets:   webhookSecrets,
+		WebhookURL:       fmt.Sprintf("%s/events", parsedURL.String()),
+		Logger:           logger,
+	}
+	if err := repoRegistry.Load(); err != nil {
+		return nil, err
+	}
 	commandRunner := &events.DefaultCommandRunner{
 		VCSClient:                     vcsClient,
 		GithubPullGetter:              githubClient,
 		GitlabMergeRequestGetter:      gitlabClient,
+		HostGithubPullGetters:         hostGithubPullGetters,
+		HostGitlabMergeRequestGetters: hostGitlabMergeRequestGetters,
 		AzureDevopsPullGetter:         azuredevopsClient,
 		CommentCommandRunnerByCmd:     commentCommandRunnerByCmd,
 		EventParser:                   eventParser,
@@ -588,11 +1177,37 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		DisableAutoplan:               userConfig.DisableAutoplan,
 		Drainer:                       drainer,
 		PreWorkflowHooksCommandRunner: preWorkflowHooksCommandRunner,
-		PullStatusFetcher:             boltdb,
+		PullStatusFetcher:             backend,
+		Auditor:                       auditor,
+		GlobalCfg:                     globalCfgStore,
+		RepoAllowlistChecker:          repoAllowlist,
+		RepoOpLimiter:                 &events.RepoOpLimiter{},
+		DiskUsageLimiter:              diskUsageLimiter,
+		ProgressComments:              userConfig.EnableProgressComments,
+		HistoryURLGenerator:           router,
+		CommandCanceller:              commandCanceller,
+		Localizer:                     localizer,
+		RepoCfgValidator: &events.RepoCfgValidator{
+			VCSClient:       vcsClient,
+			ParserValidator: validator,
+			GlobalCfg:       globalCfgStore,
+			StatusName:      userConfig.VCSStatusName,
+		},
 	}
-	repoAllowlist, err := events.NewRepoAllowlistChecker(userConfig.RepoAllowlist)
-	if err != nil {
-		return nil, err
+	// Queued applies and plans are re-run as comment commands so the queues
+	// can only be wired up once the command runner exists.
+	applyQueue.CommandRunner = commandRunner
+	if lockQueue != nil {
+		lockQueue.CommandRunner = commandRunner
+		projectCommandRunner.LockQueue = lockQueue
+	}
+	// Webhooks run commands through the command queue if it's enabled so
+	// that they survive restarts.
+	var eventsCommandRunner events.CommandRunner = commandRunner
+	var commandQueue *events.CommandQueue
+	if userConfig.EnableCommandQueue {
+		commandQueue = events.NewCommandQueue(commandRunner, backend, commitStatusUpdater, drainer, logger)
+		eventsCommandRunner = commandQueue
 	}
 	locksController := &controllers.LocksController{
 		AtlantisVersion:    config.AtlantisVersion,
@@ -604,12 +1219,38 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		LockDetailTemplate: templates.LockTemplate,
 		WorkingDir:         workingDir,
 		WorkingDirLocker:   workingDirLocker,
-		DB:                 boltdb,
+		DB:                 backend,
 		DeleteLockCommand:  deleteLockCommand,
+		Localizer:          localizer,
+	}
+	baseBranchUpdater := &events.DefaultBaseBranchUpdater{
+		DB:               backend,
+		GlobalCfg:        globalCfgStore,
+		CommandRunner:    eventsCommandRunner,
+		VCSClient:        vcsClient,
+		WorkingDir:       workingDir,
+		WorkingDirLocker: workingDirLocker,
+		PlanStorage:      planStorage,
+		Localizer:        localizer,
+		Logger:           logger,
+	}
+	// Applies can't be run when they're disabled so neither can auto-applies.
+	var autoApplier events.AutoApplier
+	if !userConfig.DisableApply {
+		autoApplier = &events.DefaultAutoApplier{
+			DB:            backend,
+			GlobalCfg:     globalCfgStore,
+			VCSClient:     vcsClient,
+			CommandRunner: eventsCommandRunner,
+			Logger:        logger,
+		}
+		commandRunner.AutoApplier = autoApplier
 	}
 	eventsController := &events_controllers.VCSEventsController{
-		CommandRunner:                   commandRunner,
+		CommandRunner:                   eventsCommandRunner,
 		PullCleaner:                     pullClosedExecutor,
+		BaseBranchUpdater:               baseBranchUpdater,
+		AutoApplier:                     autoApplier,
 		Parser:                          eventParser,
 		CommentParser:                   commentParser,
 		Logger:                          logger,
@@ -618,6 +1259,7 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		GithubRequestValidator:          &events_controllers.DefaultGithubRequestValidator{},
 		GitlabRequestParserValidator:    &events_controllers.DefaultGitlabRequestParserValidator{},
 		GitlabWebhookSecret:             []byte(userConfig.GitlabWebhookSecret),
+		HostWebhookSecrets:              hostWebhookSecrets,
 		RepoAllowlistChecker:            repoAllowlist,
 		SilenceAllowlistErrors:          userConfig.SilenceAllowlistErrors,
 		SupportedVCSHosts:               supportedVCSHosts,
@@ -626,6 +1268,59 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		AzureDevopsWebhookBasicUser:     []byte(userConfig.AzureDevopsWebhookUser),
 		AzureDevopsWebhookBasicPassword: []byte(userConfig.AzureDevopsWebhookPassword),
 		AzureDevopsRequestValidator:     &events_controllers.DefaultAzureDevopsRequestValidator{},
+		DeliveryClaimer:                 deliveryClaimer,
+		ProgressComments:                userConfig.EnableProgressComments,
+	}
+	if len(ghRequireContexts) > 0 {
+		eventsController.GithubRepoOnboarder = &events.GithubRepoOnboarder{
+			Clients:  githubStatusCheckRequirers,
+			Contexts: ghRequireContexts,
+			Logger:   logger,
+		}
+	}
+	apiController := &controllers.APIController{
+		APISecret:                 []byte(userConfig.APISecret),
+		Locker:                    lockingClient,
+		Logger:                    logger,
+		Parser:                    eventParser,
+		ProjectCommandBuilder:     projectCommandBuilder,
+		ProjectPlanCommandRunner:  projectCommandRunner,
+		ProjectApplyCommandRunner: projectCommandRunner,
+		RepoAllowlistChecker:      repoAllowlist,
+		VCSClient:                 vcsClient,
+		Drainer:                   drainer,
+		DB:                        backend,
+		WorkingDir:                workingDir,
+		GlobalCfgReloader:         globalCfgReloader,
+		Warmer:                    warmer,
+		TerraformClient:           terraformClient,
+		RepoRegistry:              repoRegistry,
+		CommandRunner:             commandRunner,
+	}
+	historyController := &controllers.HistoryController{
+		AtlantisVersion: config.AtlantisVersion,
+		AtlantisURL:     parsedURL,
+		DB:              backend,
+		Logger:          logger,
+		HistoryTemplate: templates.HistoryTemplate,
+	}
+	terraformVersionsController := &controllers.TerraformVersionsController{
+		AtlantisVersion:           config.AtlantisVersion,
+		AtlantisURL:               parsedURL,
+		Logger:                    logger,
+		TerraformClient:           terraformClient,
+		TerraformVersionsTemplate: templates.TerraformVersionsTemplate,
+	}
+	pullsController := &controllers.PullsController{
+		AtlantisVersion:   config.AtlantisVersion,
+		AtlantisURL:       parsedURL,
+		DB:                backend,
+		Logger:            logger,
+		PullsTemplate:     templates.PullsTemplate,
+		CommandRunner:     commandRunner,
+		DeleteLockCommand: deleteLockCommand,
+		VCSClient:         vcsClient,
+		Localizer:         localizer,
 	}
 	githubAppController := &controllers.GithubAppController{
 		AtlantisURL:         parsedURL,
@@ -635,6 +1330,36 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		GithubOrg:           userConfig.GithubOrg,
 	}
 
+	var webAuth *WebAuth
+	if userConfig.WebOIDCIssuerURL != "" || userConfig.WebBasicAuth {
+		sessionSecret := []byte(userConfig.WebSessionSecret)
+		if len(sessionSecret) == 0 {
+			sessionSecret = make([]byte, 32)
+			if _, err := rand.Read(sessionSecret); err != nil {
+				return nil, errors.Wrap(err, "generating web session secret")
+			}
+		}
+		webAuth = &WebAuth{
+			Operators:     splitCommaList(userConfig.WebOperators),
+			Viewers:       splitCommaList(userConfig.WebViewers),
+			SessionSecret: sessionSecret,
+			AtlantisURL:   parsedURL,
+			Logger:        logger,
+		}
+		if userConfig.WebBasicAuth {
+			webAuth.Username = userConfig.WebUsername
+			webAuth.Password = userConfig.WebPassword
+		}
+		if userConfig.WebOIDCIssuerURL != "" {
+			webAuth.OIDC = &OIDCConfig{
+				IssuerURL:    userConfig.WebOIDCIssuerURL,
+				ClientID:     userConfig.WebOIDCClientID,
+				ClientSecret: userConfig.WebOIDCClientSecret,
+			}
+		}
+	}
+	pullsController.ActionsEnabled = webAuth != nil
+
 	return &Server{
 		AtlantisVersion:               config.AtlantisVersion,
 		AtlantisURL:                   parsedURL,
@@ -648,12 +1373,26 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		VCSEventsController:           eventsController,
 		GithubAppController:           githubAppController,
 		LocksController:               locksController,
+		APIController:                 apiController,
+		HistoryController:             historyController,
+		PullsController:               pullsController,
+		TerraformVersionsController:   terraformVersionsController,
 		StatusController:              statusController,
 		IndexTemplate:                 templates.IndexTemplate,
 		LockDetailTemplate:            templates.LockTemplate,
 		SSLKeyFile:                    userConfig.SSLKeyFile,
 		SSLCertFile:                   userConfig.SSLCertFile,
 		Drainer:                       drainer,
+		CommandQueue:                  commandQueue,
+		CommandQueueWorkers:           userConfig.CommandQueueWorkers,
+		WorkingDirGC:                  workingDirGC,
+		WorkingDirGCInterval:          workingDirGCInterval,
+		LockReaper:                    lockReaper,
+		RepoRegistry:                  repoRegistry,
+		WebAuth:                       webAuth,
+		GlobalCfgReloader:             globalCfgReloader,
+		ShutdownTracing:               shutdownTracing,
+		Warmer:                        warmer,
 	}, nil
 }
 
@@ -664,10 +1403,32 @@ func (s *Server) Start() error {
 	})
 	s.Router.HandleFunc("/healthz", s.Healthz).Methods("GET")
 	s.Router.HandleFunc("/status", s.StatusController.Get).Methods("GET")
+	s.Router.HandleFunc("/readyz", s.StatusController.Ready).Methods("GET")
 	s.Router.PathPrefix("/static/").Handler(http.FileServer(&assetfs.AssetFS{Asset: static.Asset, AssetDir: static.AssetDir, AssetInfo: static.AssetInfo}))
 	s.Router.HandleFunc("/events", s.VCSEventsController.Post).Methods("POST")
 	s.Router.HandleFunc("/github-app/exchange-code", s.GithubAppController.ExchangeCode).Methods("GET")
 	s.Router.HandleFunc("/github-app/setup", s.GithubAppController.New).Methods("GET")
+	s.Router.HandleFunc("/api/plan", s.APIController.Plan).Methods("POST")
+	s.Router.HandleFunc("/api/apply", s.APIController.Apply).Methods("POST")
+	s.Router.HandleFunc("/api/history", s.APIController.History).Methods("GET")
+	s.Router.HandleFunc("/api/audit", s.APIController.Audit).Methods("GET")
+	s.Router.HandleFunc("/api/depgraph", s.APIController.DepGraph).Methods("GET")
+	s.Router.HandleFunc("/api/pulls", s.APIController.Pulls).Methods("GET")
+	s.Router.HandleFunc("/api/pulls/{id}/results", s.APIController.PullResults).Methods("GET")
+	s.Router.HandleFunc("/api/locks", s.APIController.Locks).Methods("GET")
+	s.Router.HandleFunc("/api/repos", s.APIController.Repos).Methods("GET")
+	s.Router.HandleFunc("/api/repos", s.APIController.RegisterRepo).Methods("POST")
+	s.Router.HandleFunc("/api/repos", s.APIController.UnregisterRepo).Methods("DELETE")
+	s.Router.HandleFunc("/api/reload", s.APIController.Reload).Methods("POST")
+	s.Router.HandleFunc("/api/warm", s.APIController.Warm).Methods("POST")
+	s.Router.HandleFunc("/api/terraform-versions", s.APIController.TerraformVersions).Methods("GET")
+	s.Router.HandleFunc("/api/terraform-versions", s.APIController.InstallTerraformVersion).Methods("POST")
+	s.Router.HandleFunc("/history", s.HistoryController.Get).Methods("GET")
+	s.Router.HandleFunc("/pulls", s.PullsController.Get).Methods("GET")
+	s.Router.HandleFunc("/pulls", s.PullsController.Discard).Methods("DELETE")
+	s.Router.HandleFunc("/pulls/plan", s.PullsController.Plan).Methods("POST")
+	s.Router.HandleFunc("/terraform-versions", s.TerraformVersionsController.Get).Methods("GET")
+	s.Router.HandleFunc("/terraform-versions", s.TerraformVersionsController.Install).Methods("POST")
 	s.Router.HandleFunc("/apply/lock", s.LocksController.LockApply).Methods("POST").Queries()
 	s.Router.HandleFunc("/apply/unlock", s.LocksController.UnlockApply).Methods("DELETE").Queries()
 	s.Router.HandleFunc("/locks", s.LocksController.DeleteLock).Methods("DELETE").Queries("id", "{id:.*}")
@@ -678,11 +1439,42 @@ func (s *Server) Start() error {
 		PrintStack: false,
 		StackAll:   false,
 		StackSize:  1024 * 8,
-	}, NewRequestLogger(s.Logger))
+	}, NewRequestLogger(s.Logger), &BasePathMiddleware{BasePath: s.AtlantisURL.Path}, &DrainMiddleware{Drainer: s.Drainer})
+	if s.WebAuth != nil {
+		s.Router.HandleFunc("/auth/login", s.WebAuth.Login).Methods("GET")
+		s.Router.HandleFunc("/auth/callback", s.WebAuth.Callback).Methods("GET")
+		s.Router.HandleFunc("/auth/logout", s.WebAuth.Logout).Methods("GET")
+		n.Use(s.WebAuth)
+	}
 	n.UseHandler(s.Router)
 
 	defer s.Logger.Flush()
 
+	if s.CommandQueue != nil {
+		if err := s.CommandQueue.Start(s.CommandQueueWorkers); err != nil {
+			return errors.Wrap(err, "starting command queue")
+		}
+	}
+
+	gcStop := make(chan struct{})
+	defer close(gcStop)
+	if s.WorkingDirGC != nil && s.WorkingDirGCInterval > 0 {
+		s.WorkingDirGC.Start(s.WorkingDirGCInterval, gcStop)
+	}
+	if s.LockReaper != nil {
+		s.LockReaper.Start(lockReaperInterval, gcStop)
+	}
+	s.RepoRegistry.Start(repoRegistryInterval, gcStop)
+	if s.GlobalCfgReloader != nil {
+		s.reloadOnSIGHUP(gcStop)
+	}
+	// Warming downloads in the background so it doesn't delay startup.
+	go func() {
+		if err := s.Warmer.Warm(context.Background()); err != nil {
+			s.Logger.Warn("%s", err)
+		}
+	}()
+
 	// Ensure server gracefully drains connections when stopped.
 	stop := make(chan os.Signal, 1)
 	// Stop on SIGINTs and SIGTERMs.
@@ -711,9 +1503,34 @@ func (s *Server) Start() error {
 	if err := server.Shutdown(ctx); err != nil {
 		return cli.NewExitError(fmt.Sprintf("while shutting down: %s", err), 1)
 	}
+	if s.ShutdownTracing != nil {
+		if err := s.ShutdownTracing(ctx); err != nil {
+			s.Logger.Warn("unable to export remaining traces: %s", err)
+		}
+	}
 	return nil
 }
 
+// reloadOnSIGHUP reloads the server-side repo config each time the process
+// receives a SIGHUP until stop is closed.
+func (s *Server) reloadOnSIGHUP(stop <-chan struct{}) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-hup:
+				if err := s.GlobalCfgReloader.Reload(); err != nil {
+					s.Logger.Err("reloading server-side repo config: %s", err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
 // waitForDrain blocks until draining is complete.
 func (s *Server) waitForDrain() {
 	drainComplete := make(chan bool, 1)
@@ -785,6 +1602,29 @@ func (s *Server) Index(w http.ResponseWriter, _ *http.Request) {
 	}
 }
 
+// azureDevopsGitHostname returns the hostname that git credentials should be
+// written for. hostname may include the Azure DevOps Server virtual
+// directory, ex. ado.example.com/tfs, but git credentials are matched on the
+// host only.
+func azureDevopsGitHostname(hostname string) string {
+	if hostname == "" {
+		return "dev.azure.com"
+	}
+	return strings.SplitN(hostname, "/", 2)[0]
+}
+
+// splitCommaList splits a comma-separated flag value, ignoring whitespace and
+// empty entries.
+func splitCommaList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func mkSubDir(parentDir string, subDir string) (string, error) {
 	fullDir := filepath.Join(parentDir, subDir)
 	if err := os.MkdirAll(fullDir, 0700); err != nil {
@@ -794,6 +1634,51 @@ func mkSubDir(parentDir string, subDir string) (string, error) {
 	return fullDir, nil
 }
 
+// dependencyChecks returns the checks /readyz and /status run of the
+// dependencies Atlantis needs to run commands.
+func dependencyChecks(userConfig UserConfig, backend locking.Backend, diskUsageLimiter *events.DiskUsageLimiter, terraformClient *terraform.DefaultClient, vcsPingers map[string]vcs.Pinger, logger logging.SimpleLogging) []controllers.DependencyCheck {
+	checks := []controllers.DependencyCheck{
+		{
+			Name: "database",
+			Check: func() error {
+				_, err := backend.CheckCommandLock(models.PlanCommand)
+				return err
+			},
+		},
+		{
+			Name: "disk",
+			Check: func() error {
+				// Writing a file catches a full or read-only data dir.
+				f, err := ioutil.TempFile(userConfig.DataDir, ".readyz")
+				if err != nil {
+					return errors.Wrap(err, "writing to data dir")
+				}
+				f.Close()           // nolint: errcheck
+				os.Remove(f.Name()) // nolint: errcheck
+				if diskUsageLimiter != nil {
+					return diskUsageLimiter.Check()
+				}
+				return nil
+			},
+		},
+	}
+	if terraformClient != nil {
+		checks = append(checks, controllers.DependencyCheck{
+			Name: "terraform",
+			Check: func() error {
+				return terraformClient.EnsureVersion(logger, "", nil)
+			},
+		})
+	}
+	for hostname, pinger := range vcsPingers {
+		checks = append(checks, controllers.DependencyCheck{
+			Name:  "vcs:" + hostname,
+			Check: pinger.Ping,
+		})
+	}
+	return checks
+}
+
 // Healthz returns the health check response. It always returns a 200 currently.
 func (s *Server) Healthz(w http.ResponseWriter, _ *http.Request) {
 	data, err := json.MarshalIndent(&struct {
@@ -0,0 +1,159 @@
+// Package tracing exports OpenTelemetry traces of webhooks, commands,
+// projects and terraform runs so that it's possible to see where the time
+// goes, ex. cloning vs. init vs. plan.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the name of the instrumentation library spans are created
+// by.
+const tracerName = "github.com/runatlantis/atlantis"
+
+// Attributes recorded on spans.
+const (
+	RepoKey             = attribute.Key("atlantis.repo")
+	PullNumKey          = attribute.Key("atlantis.pull_num")
+	CommandKey          = attribute.Key("atlantis.command")
+	ProjectKey          = attribute.Key("atlantis.project")
+	DirKey              = attribute.Key("atlantis.dir")
+	WorkspaceKey        = attribute.Key("atlantis.workspace")
+	VCSHostKey          = attribute.Key("atlantis.vcs_host")
+	TerraformVersionKey = attribute.Key("atlantis.terraform_version")
+)
+
+// Config configures where traces are exported to.
+type Config struct {
+	// Endpoint is the host and port of the collector's OTLP HTTP receiver,
+	// ex. localhost:4318.
+	Endpoint string
+	// Insecure is true if traces should be exported over HTTP instead of
+	// HTTPS.
+	Insecure bool
+	// Headers are sent with every export request, ex. for authentication.
+	Headers map[string]string
+	// AtlantisVersion is recorded on every span's resource.
+	AtlantisVersion string
+}
+
+// Setup starts exporting the spans created by Start to the collector
+// configured by cfg. Until it's called, spans aren't recorded. The returned
+// func exports the spans that are still buffered and stops exporting.
+func Setup(cfg Config) (func(context.Context) error, error) {
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+	if cfg.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	if len(cfg.Headers) > 0 {
+		opts = append(opts, otlptracehttp.WithHeaders(cfg.Headers))
+	}
+	exporter, err := otlptracehttp.New(context.Background(), opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating OTLP exporter")
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceNameKey.String("atlantis"),
+			semconv.ServiceVersionKey.String(cfg.AtlantisVersion),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return provider.Shutdown, nil
+}
+
+// ParseHeaders parses headers in the form key1=val1,key2=val2.
+func ParseHeaders(s string) (map[string]string, error) {
+	headers := make(map[string]string)
+	if s == "" {
+		return headers, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("invalid header %q: must be in the form key=value", pair)
+		}
+		headers[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return headers, nil
+}
+
+// Start starts a span that's a child of the span in ctx, if there is one.
+// The span must be ended, usually with End.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End ends span, marking it as failed if err isn't nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Detach returns a context that carries ctx's span but isn't cancelled when
+// ctx is. It's used to continue a trace in work that outlives the request
+// that started it, ex. commands that run after their webhook is responded
+// to. If ctx doesn't have a span, ex. because tracing isn't enabled, it
+// returns context.Background().
+func Detach(ctx context.Context) context.Context {
+	spanCtx := trace.SpanContextFromContext(ctx)
+	if !spanCtx.IsValid() {
+		return context.Background()
+	}
+	return trace.ContextWithSpanContext(context.Background(), spanCtx)
+}
+
+// Inject returns the trace context of ctx's span in a form that can be
+// persisted, ex. with a queued command, and later passed to Extract.
+func Inject(ctx context.Context) map[string]string {
+	carrier := mapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return nil
+	}
+	return carrier
+}
+
+// Extract returns a context whose spans continue the trace that carrier was
+// injected from.
+func Extract(carrier map[string]string) context.Context {
+	return otel.GetTextMapPropagator().Extract(context.Background(), mapCarrier(carrier))
+}
+
+// mapCarrier stores trace context in a map.
+type mapCarrier map[string]string
+
+func (m mapCarrier) Get(key string) string {
+	return m[key]
+}
+
+func (m mapCarrier) Set(key string, value string) {
+	m[key] = value
+}
+
+func (m mapCarrier) Keys() []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -0,0 +1,97 @@
+package tracing_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/tracing"
+	. "github.com/runatlantis/atlantis/testing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestParseHeaders(t *testing.T) {
+	cases := []struct {
+		in     string
+		exp    map[string]string
+		expErr string
+	}{
+		{
+			in:  "",
+			exp: map[string]string{},
+		},
+		{
+			in:  "x-api-key=secret",
+			exp: map[string]string{"x-api-key": "secret"},
+		},
+		{
+			in:  "a=1, b = 2=3",
+			exp: map[string]string{"a": "1", "b": "2=3"},
+		},
+		{
+			in:     "a=1,b",
+			expErr: `invalid header "b": must be in the form key=value`,
+		},
+		{
+			in:     "=1",
+			expErr: `invalid header "=1": must be in the form key=value`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.in, func(t *testing.T) {
+			headers, err := tracing.ParseHeaders(c.in)
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			Equals(t, c.exp, headers)
+		})
+	}
+}
+
+func TestDetach_NoSpan(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	Equals(t, context.Background(), tracing.Detach(ctx))
+}
+
+func TestDetach_Span(t *testing.T) {
+	spanCtx := newSpanContext(t)
+	ctx, cancel := context.WithCancel(trace.ContextWithSpanContext(context.Background(), spanCtx))
+	cancel()
+
+	detached := tracing.Detach(ctx)
+	Ok(t, detached.Err())
+	Equals(t, spanCtx, trace.SpanContextFromContext(detached))
+}
+
+func TestInjectExtract(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+
+	t.Log("nothing is injected if there's no span")
+	Equals(t, map[string]string(nil), tracing.Inject(context.Background()))
+
+	spanCtx := newSpanContext(t)
+	carrier := tracing.Inject(trace.ContextWithSpanContext(context.Background(), spanCtx))
+	Equals(t, "00-0102030405060708090a0b0c0d0e0f10-0102030405060708-01", carrier["traceparent"])
+
+	extracted := trace.SpanContextFromContext(tracing.Extract(carrier))
+	Equals(t, spanCtx.TraceID(), extracted.TraceID())
+	Equals(t, spanCtx.SpanID(), extracted.SpanID())
+	Assert(t, extracted.IsRemote(), "exp extracted span context to be remote")
+}
+
+func newSpanContext(t *testing.T) trace.SpanContext {
+	traceID, err := trace.TraceIDFromHex("0102030405060708090a0b0c0d0e0f10")
+	Ok(t, err)
+	spanID, err := trace.SpanIDFromHex("0102030405060708")
+	Ok(t, err)
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	})
+}
@@ -10,9 +10,14 @@ import (
 type UserConfig struct {
 	AllowForkPRs               bool   `mapstructure:"allow-fork-prs"`
 	AllowRepoConfig            bool   `mapstructure:"allow-repo-config"`
+	APISecret                  string `mapstructure:"api-secret"`
+	ApplyConfirmationWindow    string `mapstructure:"apply-confirmation-window"`
 	AtlantisURL                string `mapstructure:"atlantis-url"`
+	AuditWebhookURL            string `mapstructure:"audit-webhook-url"`
 	Automerge                  bool   `mapstructure:"automerge"`
 	AutoplanFileList           string `mapstructure:"autoplan-file-list"`
+	AutoplanModules            bool   `mapstructure:"autoplan-modules"`
+	AzureDevopsHostname        string `mapstructure:"azuredevops-hostname"`
 	AzureDevopsToken           string `mapstructure:"azuredevops-token"`
 	AzureDevopsUser            string `mapstructure:"azuredevops-user"`
 	AzureDevopsWebhookPassword string `mapstructure:"azuredevops-webhook-password"`
@@ -21,32 +26,62 @@ type UserConfig struct {
 	BitbucketToken             string `mapstructure:"bitbucket-token"`
 	BitbucketUser              string `mapstructure:"bitbucket-user"`
 	BitbucketWebhookSecret     string `mapstructure:"bitbucket-webhook-secret"`
+	CheckoutDepth              int    `mapstructure:"checkout-depth"`
 	CheckoutStrategy           string `mapstructure:"checkout-strategy"`
+	CommandQueueWorkers        int    `mapstructure:"command-queue-workers"`
+	CommandTimeout             string `mapstructure:"command-timeout"`
 	DataDir                    string `mapstructure:"data-dir"`
+	DataDirMaxSizeMB           int    `mapstructure:"data-dir-max-size-mb"`
 	DisableApplyAll            bool   `mapstructure:"disable-apply-all"`
 	DisableApply               bool   `mapstructure:"disable-apply"`
 	DisableAutoplan            bool   `mapstructure:"disable-autoplan"`
 	DisableMarkdownFolding     bool   `mapstructure:"disable-markdown-folding"`
 	DisableRepoLocking         bool   `mapstructure:"disable-repo-locking"`
+	EnableAuditLog             bool   `mapstructure:"enable-audit-log"`
+	EnableCloneCache           bool   `mapstructure:"enable-clone-cache"`
+	EnableCommandQueue         bool   `mapstructure:"enable-command-queue"`
+	EnableGHChecks             bool   `mapstructure:"enable-gh-checks"`
+	EnableGHDeployments        bool   `mapstructure:"enable-gh-deployments"`
+	EnableHAMode               bool   `mapstructure:"enable-ha-mode"`
+	EnableLockQueue            bool   `mapstructure:"enable-lock-queue"`
 	EnablePolicyChecksFlag     bool   `mapstructure:"enable-policy-checks"`
+	EnableProgressComments     bool   `mapstructure:"enable-progress-comments"`
+	EnableProjectStatuses      bool   `mapstructure:"enable-project-statuses"`
 	EnableRegExpCmd            bool   `mapstructure:"enable-regexp-cmd"`
+	EnableStateCmd             bool   `mapstructure:"enable-state-cmd"`
+	EnableStructuredPlanOutput bool   `mapstructure:"enable-structured-plan-output"`
 	GithubHostname             string `mapstructure:"gh-hostname"`
+	GithubMergeableIgnore      string `mapstructure:"gh-mergeable-ignore-contexts"`
+	GithubRequireContexts      string `mapstructure:"gh-require-status-contexts"`
 	GithubToken                string `mapstructure:"gh-token"`
 	GithubUser                 string `mapstructure:"gh-user"`
 	GithubWebhookSecret        string `mapstructure:"gh-webhook-secret"`
 	GithubOrg                  string `mapstructure:"gh-org"`
 	GithubAppID                int64  `mapstructure:"gh-app-id"`
-	GithubAppKey               string `mapstructure:"gh-app-key-file"`
+	GithubAppKey               string `mapstructure:"gh-app-key"`
+	GithubAppKeyFile           string `mapstructure:"gh-app-key-file"`
 	GithubAppSlug              string `mapstructure:"gh-app-slug"`
 	GitlabHostname             string `mapstructure:"gitlab-hostname"`
 	GitlabToken                string `mapstructure:"gitlab-token"`
 	GitlabUser                 string `mapstructure:"gitlab-user"`
 	GitlabWebhookSecret        string `mapstructure:"gitlab-webhook-secret"`
 	HidePrevPlanComments       bool   `mapstructure:"hide-prev-plan-comments"`
+	Locale                     string `mapstructure:"locale"`
+	LockingDBType              string `mapstructure:"locking-db-type"`
+	LockTTL                    string `mapstructure:"lock-ttl"`
 	LogLevel                   string `mapstructure:"log-level"`
 	ParallelPoolSize           int    `mapstructure:"parallel-pool-size"`
 	PlanDrafts                 bool   `mapstructure:"allow-draft-prs"`
+	PlanStorage                string `mapstructure:"plan-storage"`
+	PlanStorageBucket          string `mapstructure:"plan-storage-bucket"`
+	PlanStoragePrefix          string `mapstructure:"plan-storage-prefix"`
 	Port                       int    `mapstructure:"port"`
+	RedisDB                    int    `mapstructure:"redis-db"`
+	RedisHost                  string `mapstructure:"redis-host"`
+	RedisInsecureSkipVerify    bool   `mapstructure:"redis-insecure-skip-verify"`
+	RedisPassword              string `mapstructure:"redis-password"`
+	RedisPort                  int    `mapstructure:"redis-port"`
+	RedisTLSEnabled            bool   `mapstructure:"redis-tls-enabled"`
 	RepoConfig                 string `mapstructure:"repo-config"`
 	RepoConfigJSON             string `mapstructure:"repo-config-json"`
 	RepoAllowlist              string `mapstructure:"repo-allowlist"`
@@ -61,6 +96,9 @@ type UserConfig struct {
 	RequireMergeable bool `mapstructure:"require-mergeable"`
 	// SilenceNoProjects is whether Atlantis should respond to a PR if no projects are found.
 	SilenceNoProjects bool `mapstructure:"silence-no-projects"`
+	// SilenceNoChangePlans is whether projects whose plans have no changes are
+	// left out of plan comments.
+	SilenceNoChangePlans bool `mapstructure:"silence-no-change-plans"`
 	// RequireUnDiverged is whether to require pull requests to rebase default branch before
 	// allowing terraform apply's to run.
 	RequireUnDiverged   bool `mapstructure:"require-undiverged"`
@@ -79,11 +117,39 @@ type UserConfig struct {
 	SSLCertFile            string          `mapstructure:"ssl-cert-file"`
 	SSLKeyFile             string          `mapstructure:"ssl-key-file"`
 	TFDownloadURL          string          `mapstructure:"tf-download-url"`
+	TFMaxRetries           int             `mapstructure:"tf-max-retries"`
+	TFNetrcCredentials     string          `mapstructure:"tf-netrc-credentials"`
+	TFRegistryCredentials  string          `mapstructure:"tf-registry-credentials"`
+	TFRetryBackoff         string          `mapstructure:"tf-retry-backoff"`
+	TofuDownloadURL        string          `mapstructure:"tofu-download-url"`
+	TracingOTLPEndpoint    string          `mapstructure:"tracing-otlp-endpoint"`
+	TracingOTLPHeaders     string          `mapstructure:"tracing-otlp-headers"`
+	TracingOTLPInsecure    bool            `mapstructure:"tracing-otlp-insecure"`
 	TFEHostname            string          `mapstructure:"tfe-hostname"`
 	TFEToken               string          `mapstructure:"tfe-token"`
+	UploadLargeComments    bool            `mapstructure:"upload-large-comments"`
 	VCSStatusName          string          `mapstructure:"vcs-status-name"`
+	VCSAPIMaxRetries       int             `mapstructure:"vcs-api-max-retries"`
+	VCSHostsConfig         string          `mapstructure:"vcs-hosts-config"`
+	WarmProviders          string          `mapstructure:"warm-providers"`
+	WarmTFVersions         string          `mapstructure:"warm-tf-versions"`
+	WebBasicAuth           bool            `mapstructure:"web-basic-auth"`
+	WebOIDCClientID        string          `mapstructure:"web-oidc-client-id"`
+	WebOIDCClientSecret    string          `mapstructure:"web-oidc-client-secret"`
+	WebOIDCIssuerURL       string          `mapstructure:"web-oidc-issuer-url"`
+	WebOperators           string          `mapstructure:"web-operators"`
+	WebPassword            string          `mapstructure:"web-password"`
+	WebSessionSecret       string          `mapstructure:"web-session-secret"`
+	WebUsername            string          `mapstructure:"web-username"`
+	WebViewers             string          `mapstructure:"web-viewers"`
+	DefaultTFDistribution  string          `mapstructure:"default-tf-distribution"`
 	DefaultTFVersion       string          `mapstructure:"default-tf-version"`
+	DefaultTofuVersion     string          `mapstructure:"default-tofu-version"`
+	DefaultTGVersion       string          `mapstructure:"default-tg-version"`
 	Webhooks               []WebhookConfig `mapstructure:"webhooks"`
+	WorkspaceGCInterval    string          `mapstructure:"workspace-gc-interval"`
+	WorkspaceGCMaxAge      string          `mapstructure:"workspace-gc-max-age"`
+	WorkingDirLockTimeout  string          `mapstructure:"working-dir-lock-timeout"`
 	WriteGitCreds          bool            `mapstructure:"write-git-creds"`
 }
 
@@ -0,0 +1,528 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// WebRole is what a user of the web UI is allowed to do.
+type WebRole string
+
+const (
+	// WebViewerRole can view locks, pulls, history and terraform versions.
+	WebViewerRole WebRole = "viewer"
+	// WebOperatorRole can also delete locks, discard and plan pulls, lock
+	// and unlock applies, and install terraform versions.
+	WebOperatorRole WebRole = "operator"
+)
+
+// allows returns true if r has at least the permissions of required.
+func (r WebRole) allows(required WebRole) bool {
+	switch r {
+	case WebOperatorRole:
+		return true
+	case WebViewerRole:
+		return required == WebViewerRole
+	}
+	return false
+}
+
+// webAuthRoutes are the role needed for each route of the web UI, keyed by
+// method and path. Routes that aren't listed, ex. /events and /api/plan, are
+// either public or have their own authentication.
+var webAuthRoutes = map[string]WebRole{
+	"GET /":                    WebViewerRole,
+	"GET /index.html":          WebViewerRole,
+	"GET /lock":                WebViewerRole,
+	"GET /history":             WebViewerRole,
+	"GET /pulls":               WebViewerRole,
+	"GET /terraform-versions":  WebViewerRole,
+	"DELETE /locks":            WebOperatorRole,
+	"DELETE /pulls":            WebOperatorRole,
+	"POST /pulls/plan":         WebOperatorRole,
+	"POST /apply/lock":         WebOperatorRole,
+	"DELETE /apply/unlock":     WebOperatorRole,
+	"POST /terraform-versions": WebOperatorRole,
+}
+
+const (
+	// webSessionCookie holds the signed session of a user that logged in
+	// with OIDC.
+	webSessionCookie = "atlantis_session"
+	// webStateCookie holds the signed state of an OIDC login in progress.
+	webStateCookie = "atlantis_oidc_state"
+	// webSessionDuration is how long users stay logged in for.
+	webSessionDuration = 8 * time.Hour
+	// webStateDuration is how long users have to log in with the OIDC
+	// provider.
+	webStateDuration = 10 * time.Minute
+	// webOIDCScopes are the scopes requested from the OIDC provider.
+	webOIDCScopes = "openid profile email"
+)
+
+// OIDCConfig configures logging in to the web UI with an OpenID Connect
+// provider.
+type OIDCConfig struct {
+	// IssuerURL is the provider's issuer, ex. https://accounts.google.com.
+	// Its endpoints are discovered from its /.well-known/openid-configuration.
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+}
+
+// WebAuth is middleware that authenticates requests to the web UI and checks
+// that the user has the role needed for the route. Users log in with OIDC or,
+// as a fallback, basic auth.
+type WebAuth struct {
+	// OIDC, if set, is used to log users in.
+	OIDC *OIDCConfig
+	// Username and Password, if set, let requests authenticate with basic
+	// auth. The basic auth user is always an operator.
+	Username string
+	Password string
+	// Operators are the users, by email or username, and groups that are
+	// operators. "*" matches every user.
+	Operators []string
+	// Viewers are the users and groups that are viewers. If empty, every user
+	// that can log in is a viewer.
+	Viewers []string
+	// SessionSecret is used to sign session cookies.
+	SessionSecret []byte
+	// AtlantisURL is used to build the OIDC redirect URL and to redirect
+	// users back after logging in.
+	AtlantisURL *url.URL
+	Logger      logging.SimpleLogging
+	// HTTPClient is used to make requests to the OIDC provider. If nil,
+	// http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	discoveryMutex sync.Mutex
+	discovery      *oidcDiscovery
+}
+
+// webSession is the payload of a session cookie.
+type webSession struct {
+	Name    string  `json:"n"`
+	Role    WebRole `json:"r"`
+	Expires int64   `json:"e"`
+}
+
+// webLoginState is the payload of the state cookie of an OIDC login.
+type webLoginState struct {
+	State string `json:"s"`
+	// Redirect is the page to return to after logging in. It's kept in the
+	// signed cookie so it can't be swapped for another site.
+	Redirect string `json:"r"`
+	Expires  int64  `json:"e"`
+}
+
+// oidcDiscovery is the part of the provider's discovery document we use.
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+}
+
+// oidcClaims are the ID token claims we use.
+type oidcClaims struct {
+	Issuer            string          `json:"iss"`
+	Audience          json.RawMessage `json:"aud"`
+	Expires           int64           `json:"exp"`
+	Subject           string          `json:"sub"`
+	Email             string          `json:"email"`
+	PreferredUsername string          `json:"preferred_username"`
+	Groups            []string        `json:"groups"`
+}
+
+// ServeHTTP implements the middleware function.
+func (a *WebAuth) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	required, ok := webAuthRoutes[r.Method+" "+r.URL.Path]
+	if !ok {
+		next(rw, r)
+		return
+	}
+	// Browsers send cookies and basic auth credentials with forms that other
+	// sites submit to Atlantis, so those requests are rejected.
+	if r.Method != http.MethodGet && a.crossSite(r) {
+		a.Logger.Warn("rejected cross-site request %s %s from %q", r.Method, r.URL.Path, r.Header.Get("Origin"))
+		http.Error(rw, "Cross-site requests aren't allowed.", http.StatusForbidden)
+		return
+	}
+	name, role := a.authenticate(r)
+	if name == "" {
+		a.challenge(rw, r)
+		return
+	}
+	if !role.allows(required) {
+		a.Logger.Info("user %q with role %q was denied %s %s", name, role, r.Method, r.URL.Path)
+		http.Error(rw, fmt.Sprintf("User %q needs the %s role to do this.", name, required), http.StatusForbidden)
+		return
+	}
+	next(rw, r)
+}
+
+// authenticate returns the name and role of the user that made r, or an
+// empty name if r isn't authenticated.
+func (a *WebAuth) authenticate(r *http.Request) (string, WebRole) {
+	if cookie, err := r.Cookie(webSessionCookie); err == nil {
+		var session webSession
+		if a.verify(cookie.Value, &session) && time.Now().Unix() < session.Expires {
+			return session.Name, session.Role
+		}
+	}
+	if a.Username != "" {
+		if user, pass, ok := r.BasicAuth(); ok &&
+			subtle.ConstantTimeCompare([]byte(user), []byte(a.Username)) == 1 &&
+			subtle.ConstantTimeCompare([]byte(pass), []byte(a.Password)) == 1 {
+			return user, WebOperatorRole
+		}
+	}
+	return "", ""
+}
+
+// crossSite returns true if r was sent from a page that isn't on Atlantis.
+func (a *WebAuth) crossSite(r *http.Request) bool {
+	if r.Header.Get("Sec-Fetch-Site") == "cross-site" {
+		return true
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+	u, err := url.Parse(origin)
+	return err != nil || !strings.EqualFold(u.Host, a.AtlantisURL.Host)
+}
+
+// challenge responds to an unauthenticated request. Pages are redirected to
+// the OIDC login if it's enabled, otherwise basic auth is requested.
+func (a *WebAuth) challenge(rw http.ResponseWriter, r *http.Request) {
+	if a.OIDC != nil && r.Method == http.MethodGet {
+		loginURL := a.urlFor("/auth/login") + "?" + url.Values{"redirect": {r.URL.RequestURI()}}.Encode()
+		http.Redirect(rw, r, loginURL, http.StatusFound)
+		return
+	}
+	if a.Username != "" {
+		rw.Header().Set("WWW-Authenticate", `Basic realm="Atlantis", charset="UTF-8"`)
+	}
+	http.Error(rw, "Unauthorized", http.StatusUnauthorized)
+}
+
+// Login redirects the user to the OIDC provider to log in.
+func (a *WebAuth) Login(w http.ResponseWriter, r *http.Request) {
+	if a.OIDC == nil {
+		http.NotFound(w, r)
+		return
+	}
+	discovery, err := a.discover()
+	if err != nil {
+		a.Logger.Err("discovering OIDC provider: %s", err)
+		http.Error(w, "Unable to reach the login provider.", http.StatusBadGateway)
+		return
+	}
+	stateBytes := make([]byte, 16)
+	if _, err := rand.Read(stateBytes); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	state := webLoginState{
+		State:    hex.EncodeToString(stateBytes),
+		Redirect: r.URL.Query().Get("redirect"),
+		Expires:  time.Now().Add(webStateDuration).Unix(),
+	}
+	// Only pages on Atlantis can be returned to. Browsers treat /\ like //.
+	if !strings.HasPrefix(state.Redirect, "/") || strings.HasPrefix(state.Redirect, "//") || strings.HasPrefix(state.Redirect, "/\\") {
+		state.Redirect = "/"
+	}
+	a.setCookie(w, webStateCookie, a.sign(state), webStateDuration)
+
+	authURL, err := url.Parse(discovery.AuthorizationEndpoint)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", a.OIDC.ClientID)
+	query.Set("redirect_uri", a.urlFor("/auth/callback"))
+	query.Set("scope", webOIDCScopes)
+	query.Set("state", state.State)
+	authURL.RawQuery = query.Encode()
+	http.Redirect(w, r, authURL.String(), http.StatusFound)
+}
+
+// Callback is where the OIDC provider redirects users after they log in. It
+// exchanges the code for an ID token and starts the user's session.
+func (a *WebAuth) Callback(w http.ResponseWriter, r *http.Request) {
+	if a.OIDC == nil {
+		http.NotFound(w, r)
+		return
+	}
+	var state webLoginState
+	cookie, err := r.Cookie(webStateCookie)
+	if err != nil || !a.verify(cookie.Value, &state) || time.Now().Unix() >= state.Expires ||
+		subtle.ConstantTimeCompare([]byte(state.State), []byte(r.URL.Query().Get("state"))) != 1 {
+		http.Error(w, "Login state is invalid or expired, please try logging in again.", http.StatusBadRequest)
+		return
+	}
+	a.setCookie(w, webStateCookie, "", -1)
+	if errMsg := r.URL.Query().Get("error"); errMsg != "" {
+		http.Error(w, fmt.Sprintf("Login failed: %s", errMsg), http.StatusUnauthorized)
+		return
+	}
+
+	claims, err := a.exchange(r.URL.Query().Get("code"))
+	if err != nil {
+		a.Logger.Err("OIDC login failed: %s", err)
+		http.Error(w, "Login failed.", http.StatusUnauthorized)
+		return
+	}
+	name := claims.Email
+	if name == "" {
+		name = claims.PreferredUsername
+	}
+	if name == "" {
+		name = claims.Subject
+	}
+	role := a.roleFor(claims)
+	if role == "" {
+		a.Logger.Info("user %q logged in but isn't a viewer or operator", name)
+		http.Error(w, fmt.Sprintf("User %q isn't allowed to use Atlantis.", name), http.StatusForbidden)
+		return
+	}
+	a.Logger.Info("user %q logged in with role %q", name, role)
+	a.setCookie(w, webSessionCookie, a.sign(webSession{
+		Name:    name,
+		Role:    role,
+		Expires: time.Now().Add(webSessionDuration).Unix(),
+	}), webSessionDuration)
+	http.Redirect(w, r, strings.TrimSuffix(a.AtlantisURL.Path, "/")+state.Redirect, http.StatusFound)
+}
+
+// Logout ends the user's session.
+func (a *WebAuth) Logout(w http.ResponseWriter, r *http.Request) {
+	a.setCookie(w, webSessionCookie, "", -1)
+	http.Redirect(w, r, a.urlFor("/"), http.StatusFound)
+}
+
+// roleFor returns the role of the user with claims, or an empty role if they
+// aren't allowed to use the web UI.
+func (a *WebAuth) roleFor(claims oidcClaims) WebRole {
+	ids := append([]string{claims.Email, claims.PreferredUsername, claims.Subject}, claims.Groups...)
+	if matchesAny(a.Operators, ids) {
+		return WebOperatorRole
+	}
+	if len(a.Viewers) == 0 || matchesAny(a.Viewers, ids) {
+		return WebViewerRole
+	}
+	return ""
+}
+
+// matchesAny returns true if any of ids is in allowed or allowed contains *.
+func matchesAny(allowed []string, ids []string) bool {
+	for _, a := range allowed {
+		if a == "*" {
+			return true
+		}
+		for _, id := range ids {
+			if id != "" && strings.EqualFold(a, id) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// discover fetches and caches the OIDC provider's discovery document.
+func (a *WebAuth) discover() (*oidcDiscovery, error) {
+	a.discoveryMutex.Lock()
+	defer a.discoveryMutex.Unlock()
+	if a.discovery != nil {
+		return a.discovery, nil
+	}
+	issuer := strings.TrimSuffix(a.OIDC.IssuerURL, "/")
+	resp, err := a.httpClient().Get(issuer + "/.well-known/openid-configuration")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d, body: %s", resp.StatusCode, string(body))
+	}
+	var discovery oidcDiscovery
+	if err := json.Unmarshal(body, &discovery); err != nil {
+		return nil, errors.Wrap(err, "parsing discovery document")
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("discovery document is for issuer %q, not %q", discovery.Issuer, a.OIDC.IssuerURL)
+	}
+	a.discovery = &discovery
+	return a.discovery, nil
+}
+
+// exchange exchanges code for an ID token and returns its claims. The token
+// comes straight from the provider's token endpoint so, as allowed by OpenID
+// Connect Core 3.1.3.7, the TLS connection is relied on instead of checking
+// its signature.
+func (a *WebAuth) exchange(code string) (oidcClaims, error) {
+	var claims oidcClaims
+	discovery, err := a.discover()
+	if err != nil {
+		return claims, err
+	}
+	form := url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {a.urlFor("/auth/callback")},
+	}
+	req, err := http.NewRequest(http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return claims, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(a.OIDC.ClientID), url.QueryEscape(a.OIDC.ClientSecret))
+	resp, err := a.httpClient().Do(req)
+	if err != nil {
+		return claims, err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return claims, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return claims, fmt.Errorf("token endpoint returned status code %d, body: %s", resp.StatusCode, string(body))
+	}
+	var tokenResp struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return claims, errors.Wrap(err, "parsing token response")
+	}
+
+	parts := strings.Split(tokenResp.IDToken, ".")
+	if len(parts) != 3 {
+		return claims, errors.New("token response didn't contain an ID token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return claims, errors.Wrap(err, "decoding ID token")
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return claims, errors.Wrap(err, "parsing ID token claims")
+	}
+	if claims.Issuer != discovery.Issuer {
+		return claims, fmt.Errorf("ID token was issued by %q, not %q", claims.Issuer, discovery.Issuer)
+	}
+	if !audienceContains(claims.Audience, a.OIDC.ClientID) {
+		return claims, fmt.Errorf("ID token audience %s doesn't contain the client id", string(claims.Audience))
+	}
+	if time.Now().Unix() >= claims.Expires {
+		return claims, errors.New("ID token has expired")
+	}
+	return claims, nil
+}
+
+// audienceContains returns true if the aud claim, which is either a string or
+// an array of strings, contains clientID.
+func audienceContains(aud json.RawMessage, clientID string) bool {
+	var single string
+	if err := json.Unmarshal(aud, &single); err == nil {
+		return single == clientID
+	}
+	var multiple []string
+	if err := json.Unmarshal(aud, &multiple); err != nil {
+		return false
+	}
+	for _, a := range multiple {
+		if a == clientID {
+			return true
+		}
+	}
+	return false
+}
+
+// sign returns v encoded and signed for use as a cookie value.
+func (a *WebAuth) sign(v interface{}) string {
+	payload, _ := json.Marshal(v) // nolint: errcheck
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(a.mac(encoded))
+}
+
+// verify decodes value into v and returns true if its signature is valid.
+func (a *WebAuth) verify(value string, v interface{}) bool {
+	parts := strings.Split(value, ".")
+	if len(parts) != 2 {
+		return false
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(sig, a.mac(parts[0])) {
+		return false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(payload, v) == nil
+}
+
+func (a *WebAuth) mac(data string) []byte {
+	h := hmac.New(sha256.New, a.SessionSecret)
+	h.Write([]byte(data)) // nolint: errcheck
+	return h.Sum(nil)
+}
+
+// setCookie sets the cookie name to value. A negative maxAge deletes it.
+// Cookies are SameSite=Lax so that other sites can't make requests that
+// change things, ex. delete a lock, with the user's session.
+func (a *WebAuth) setCookie(w http.ResponseWriter, name string, value string, maxAge time.Duration) {
+	path := a.AtlantisURL.Path
+	if path == "" {
+		path = "/"
+	}
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		HttpOnly: true,
+		Secure:   a.AtlantisURL.Scheme == "https",
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   int(maxAge.Seconds()),
+	}
+	if maxAge < 0 {
+		cookie.MaxAge = -1
+	}
+	http.SetCookie(w, cookie)
+}
+
+// urlFor returns the absolute URL of path on Atlantis.
+func (a *WebAuth) urlFor(path string) string {
+	u := *a.AtlantisURL
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	return u.String()
+}
+
+func (a *WebAuth) httpClient() *http.Client {
+	if a.HTTPClient != nil {
+		return a.HTTPClient
+	}
+	return http.DefaultClient
+}
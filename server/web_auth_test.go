@@ -0,0 +1,318 @@
+package server_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// newOIDCProvider returns a fake OIDC provider whose token endpoint returns
+// an ID token with claims for the code "code".
+func newOIDCProvider(t *testing.T, claims map[string]interface{}) *httptest.Server {
+	var provider *httptest.Server
+	provider = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			fmt.Fprintf(w, `{"issuer": %q, "authorization_endpoint": %q, "token_endpoint": %q}`,
+				provider.URL, provider.URL+"/authorize", provider.URL+"/token")
+		case "/token":
+			Ok(t, r.ParseForm())
+			Equals(t, "authorization_code", r.PostForm.Get("grant_type"))
+			Equals(t, "https://atlantis.example.com/auth/callback", r.PostForm.Get("redirect_uri"))
+			user, pass, _ := r.BasicAuth()
+			Equals(t, "client-id", user)
+			Equals(t, "client-secret", pass)
+			if r.PostForm.Get("code") != "code" {
+				http.Error(w, `{"error": "invalid_grant"}`, http.StatusBadRequest)
+				return
+			}
+			claims["iss"] = provider.URL
+			payload, err := json.Marshal(claims)
+			Ok(t, err)
+			idToken := "e30." + base64.RawURLEncoding.EncodeToString(payload) + ".sig"
+			fmt.Fprintf(w, `{"access_token": "token", "id_token": %q}`, idToken)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return provider
+}
+
+func newWebAuth(t *testing.T, issuerURL string) *server.WebAuth {
+	atlantisURL, err := url.Parse("https://atlantis.example.com")
+	Ok(t, err)
+	return &server.WebAuth{
+		OIDC: &server.OIDCConfig{
+			IssuerURL:    issuerURL,
+			ClientID:     "client-id",
+			ClientSecret: "client-secret",
+		},
+		Username:      "admin",
+		Password:      "password",
+		Operators:     []string{"platform-team"},
+		SessionSecret: []byte("secret"),
+		AtlantisURL:   atlantisURL,
+		Logger:        logging.NewNoopLogger(t),
+	}
+}
+
+// serveAuth runs req through the middleware and returns the response and
+// whether the request was passed through.
+func serveAuth(auth *server.WebAuth, req *http.Request) (*httptest.ResponseRecorder, bool) {
+	w := httptest.NewRecorder()
+	passed := false
+	auth.ServeHTTP(w, req, func(w http.ResponseWriter, _ *http.Request) {
+		passed = true
+		w.WriteHeader(http.StatusOK)
+	})
+	return w, passed
+}
+
+// login runs an OIDC login that starts from redirect and returns the session
+// cookie. It checks that the user is returned to expRedirect.
+func login(t *testing.T, auth *server.WebAuth, redirect string, expRedirect string) *http.Cookie {
+	w := httptest.NewRecorder()
+	auth.Login(w, httptest.NewRequest("GET", "/auth/login?"+url.Values{"redirect": {redirect}}.Encode(), nil))
+	Equals(t, http.StatusFound, w.Code)
+	authURL, err := url.Parse(w.Header().Get("Location"))
+	Ok(t, err)
+	Equals(t, "client-id", authURL.Query().Get("client_id"))
+	Equals(t, "https://atlantis.example.com/auth/callback", authURL.Query().Get("redirect_uri"))
+	state := authURL.Query().Get("state")
+	Assert(t, state != "", "exp state to be set")
+
+	req := httptest.NewRequest("GET", "/auth/callback?code=code&state="+state, nil)
+	for _, c := range w.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	w = httptest.NewRecorder()
+	auth.Callback(w, req)
+	Equals(t, http.StatusFound, w.Code)
+	Equals(t, expRedirect, w.Header().Get("Location"))
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "atlantis_session" {
+			Assert(t, c.HttpOnly && c.Secure, "exp session cookie to be http only and secure")
+			return c
+		}
+	}
+	t.Fatal("no session cookie was set")
+	return nil
+}
+
+func TestWebAuth_PublicRoutes(t *testing.T) {
+	auth := newWebAuth(t, "https://issuer.example.com")
+	for _, r := range []struct{ method, path string }{
+		{"GET", "/healthz"},
+		{"GET", "/status"},
+		{"POST", "/events"},
+		{"POST", "/api/plan"},
+		{"GET", "/static/atlantis-icon.png"},
+	} {
+		_, passed := serveAuth(auth, httptest.NewRequest(r.method, r.path, nil))
+		Assert(t, passed, "exp %s %s to be public", r.method, r.path)
+	}
+}
+
+func TestWebAuth_Unauthenticated(t *testing.T) {
+	auth := newWebAuth(t, "https://issuer.example.com")
+
+	t.Log("pages redirect to the OIDC login")
+	w, passed := serveAuth(auth, httptest.NewRequest("GET", "/lock?id=1", nil))
+	Assert(t, !passed, "exp request to be rejected")
+	Equals(t, http.StatusFound, w.Code)
+	Equals(t, "https://atlantis.example.com/auth/login?redirect=%2Flock%3Fid%3D1", w.Header().Get("Location"))
+
+	t.Log("other requests ask for basic auth")
+	w, passed = serveAuth(auth, httptest.NewRequest("DELETE", "/locks?id=1", nil))
+	Assert(t, !passed, "exp request to be rejected")
+	Equals(t, http.StatusUnauthorized, w.Code)
+	Equals(t, `Basic realm="Atlantis", charset="UTF-8"`, w.Header().Get("WWW-Authenticate"))
+
+	t.Log("wrong basic auth credentials are rejected")
+	req := httptest.NewRequest("DELETE", "/locks?id=1", nil)
+	req.SetBasicAuth("admin", "wrong")
+	w, passed = serveAuth(auth, req)
+	Assert(t, !passed, "exp request to be rejected")
+	Equals(t, http.StatusUnauthorized, w.Code)
+
+	t.Log("a forged session cookie is rejected")
+	req = httptest.NewRequest("DELETE", "/locks?id=1", nil)
+	req.AddCookie(&http.Cookie{Name: "atlantis_session", Value: "eyJuIjoiZXZlIiwiciI6Im9wZXJhdG9yIiwiZSI6OTk5OTk5OTk5OX0.c2ln"})
+	_, passed = serveAuth(auth, req)
+	Assert(t, !passed, "exp request to be rejected")
+}
+
+func TestWebAuth_BasicAuth(t *testing.T) {
+	auth := newWebAuth(t, "https://issuer.example.com")
+	req := httptest.NewRequest("DELETE", "/locks?id=1", nil)
+	req.SetBasicAuth("admin", "password")
+	_, passed := serveAuth(auth, req)
+	Assert(t, passed, "exp basic auth user to be an operator")
+}
+
+// Forms that other sites submit are rejected even though the browser sends
+// the user's credentials with them.
+func TestWebAuth_CrossSite(t *testing.T) {
+	auth := newWebAuth(t, "https://issuer.example.com")
+	cases := []struct {
+		description string
+		headers     map[string]string
+		expPassed   bool
+	}{
+		{"no origin", nil, true},
+		{"same origin", map[string]string{"Origin": "https://atlantis.example.com"}, true},
+		{"other origin", map[string]string{"Origin": "https://evil.example.com"}, false},
+		{"cross-site fetch", map[string]string{"Sec-Fetch-Site": "cross-site"}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/pulls/plan?repo=owner/repo&pr=1", nil)
+			req.SetBasicAuth("admin", "password")
+			for k, v := range c.headers {
+				req.Header.Set(k, v)
+			}
+			w, passed := serveAuth(auth, req)
+			Equals(t, c.expPassed, passed)
+			if !c.expPassed {
+				Equals(t, http.StatusForbidden, w.Code)
+			}
+		})
+	}
+}
+
+func TestWebAuth_OIDCRoles(t *testing.T) {
+	cases := []struct {
+		description string
+		viewers     []string
+		claims      map[string]interface{}
+		expView     bool
+		expOperate  bool
+	}{
+		{
+			description: "operator by group",
+			claims:      map[string]interface{}{"email": "alice@example.com", "groups": []string{"platform-team"}},
+			expView:     true,
+			expOperate:  true,
+		},
+		{
+			description: "viewer since viewers aren't restricted",
+			claims:      map[string]interface{}{"email": "bob@example.com"},
+			expView:     true,
+		},
+		{
+			description: "viewer by email",
+			viewers:     []string{"bob@example.com"},
+			claims:      map[string]interface{}{"email": "Bob@example.com"},
+			expView:     true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			c.claims["aud"] = "client-id"
+			c.claims["exp"] = time.Now().Add(time.Hour).Unix()
+			provider := newOIDCProvider(t, c.claims)
+			defer provider.Close()
+			auth := newWebAuth(t, provider.URL)
+			auth.Viewers = c.viewers
+			cookie := login(t, auth, "/lock?id=1", "/lock?id=1")
+
+			req := httptest.NewRequest("GET", "/lock?id=1", nil)
+			req.AddCookie(cookie)
+			_, passed := serveAuth(auth, req)
+			Equals(t, c.expView, passed)
+
+			req = httptest.NewRequest("DELETE", "/locks?id=1", nil)
+			req.AddCookie(cookie)
+			w, passed := serveAuth(auth, req)
+			Equals(t, c.expOperate, passed)
+			if !c.expOperate {
+				Equals(t, http.StatusForbidden, w.Code)
+			}
+		})
+	}
+}
+
+// Users can only be returned to pages on Atlantis after logging in.
+func TestWebAuth_OIDCRedirect(t *testing.T) {
+	for _, redirect := range []string{"https://evil.example.com", "//evil.example.com", "/\\evil.example.com"} {
+		provider := newOIDCProvider(t, map[string]interface{}{
+			"email": "alice@example.com",
+			"aud":   "client-id",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+		})
+		auth := newWebAuth(t, provider.URL)
+		login(t, auth, redirect, "/")
+		provider.Close()
+	}
+}
+
+func TestWebAuth_OIDCNotAllowed(t *testing.T) {
+	provider := newOIDCProvider(t, map[string]interface{}{
+		"email": "eve@example.com",
+		"aud":   []string{"client-id"},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	})
+	defer provider.Close()
+	auth := newWebAuth(t, provider.URL)
+	auth.Viewers = []string{"engineers"}
+
+	w := httptest.NewRecorder()
+	auth.Login(w, httptest.NewRequest("GET", "/auth/login", nil))
+	authURL, err := url.Parse(w.Header().Get("Location"))
+	Ok(t, err)
+	req := httptest.NewRequest("GET", "/auth/callback?code=code&state="+authURL.Query().Get("state"), nil)
+	for _, c := range w.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	w = httptest.NewRecorder()
+	auth.Callback(w, req)
+	ResponseContains(t, w, http.StatusForbidden, `User "eve@example.com" isn't allowed to use Atlantis.`)
+}
+
+func TestWebAuth_OIDCCallbackChecksState(t *testing.T) {
+	provider := newOIDCProvider(t, map[string]interface{}{})
+	defer provider.Close()
+	auth := newWebAuth(t, provider.URL)
+
+	w := httptest.NewRecorder()
+	auth.Login(w, httptest.NewRequest("GET", "/auth/login", nil))
+	Equals(t, http.StatusFound, w.Code)
+	req := httptest.NewRequest("GET", "/auth/callback?code=code&state=wrong", nil)
+	for _, c := range w.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	w = httptest.NewRecorder()
+	auth.Callback(w, req)
+	ResponseContains(t, w, http.StatusBadRequest, "Login state is invalid or expired")
+}
+
+func TestWebAuth_OIDCRejectsWrongAudience(t *testing.T) {
+	provider := newOIDCProvider(t, map[string]interface{}{
+		"email": "alice@example.com",
+		"aud":   "other-client",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	})
+	defer provider.Close()
+	auth := newWebAuth(t, provider.URL)
+
+	w := httptest.NewRecorder()
+	auth.Login(w, httptest.NewRequest("GET", "/auth/login", nil))
+	authURL, err := url.Parse(w.Header().Get("Location"))
+	Ok(t, err)
+	req := httptest.NewRequest("GET", "/auth/callback?code=code&state="+authURL.Query().Get("state"), nil)
+	for _, c := range w.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	w = httptest.NewRecorder()
+	auth.Callback(w, req)
+	Equals(t, http.StatusUnauthorized, w.Code)
+}